@@ -0,0 +1,536 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: backup/v1/backup.proto
+
+package backupv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	BackupService_StartBackup_FullMethodName = "/backup.v1.BackupService/StartBackup"
+	BackupService_GetBackup_FullMethodName   = "/backup.v1.BackupService/GetBackup"
+	BackupService_ListBackups_FullMethodName = "/backup.v1.BackupService/ListBackups"
+	BackupService_WatchBackup_FullMethodName = "/backup.v1.BackupService/WatchBackup"
+)
+
+// BackupServiceClient is the client API for BackupService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BackupServiceClient interface {
+	// StartBackup triggers a new backup run and returns immediately.
+	StartBackup(ctx context.Context, in *StartBackupRequest, opts ...grpc.CallOption) (*Run, error)
+	// GetBackup returns the current state of a backup run.
+	GetBackup(ctx context.Context, in *GetRunRequest, opts ...grpc.CallOption) (*Run, error)
+	// ListBackups returns the backup runs known to this server, newest first.
+	ListBackups(ctx context.Context, in *ListRunsRequest, opts ...grpc.CallOption) (*ListRunsResponse, error)
+	// WatchBackup streams progress updates for a backup run until it finishes.
+	WatchBackup(ctx context.Context, in *WatchRunRequest, opts ...grpc.CallOption) (BackupService_WatchBackupClient, error)
+}
+
+type backupServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBackupServiceClient(cc grpc.ClientConnInterface) BackupServiceClient {
+	return &backupServiceClient{cc}
+}
+
+func (c *backupServiceClient) StartBackup(ctx context.Context, in *StartBackupRequest, opts ...grpc.CallOption) (*Run, error) {
+	out := new(Run)
+	err := c.cc.Invoke(ctx, BackupService_StartBackup_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *backupServiceClient) GetBackup(ctx context.Context, in *GetRunRequest, opts ...grpc.CallOption) (*Run, error) {
+	out := new(Run)
+	err := c.cc.Invoke(ctx, BackupService_GetBackup_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *backupServiceClient) ListBackups(ctx context.Context, in *ListRunsRequest, opts ...grpc.CallOption) (*ListRunsResponse, error) {
+	out := new(ListRunsResponse)
+	err := c.cc.Invoke(ctx, BackupService_ListBackups_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *backupServiceClient) WatchBackup(ctx context.Context, in *WatchRunRequest, opts ...grpc.CallOption) (BackupService_WatchBackupClient, error) {
+	stream, err := c.cc.NewStream(ctx, &BackupService_ServiceDesc.Streams[0], BackupService_WatchBackup_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &backupServiceWatchBackupClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type BackupService_WatchBackupClient interface {
+	Recv() (*ProgressEvent, error)
+	grpc.ClientStream
+}
+
+type backupServiceWatchBackupClient struct {
+	grpc.ClientStream
+}
+
+func (x *backupServiceWatchBackupClient) Recv() (*ProgressEvent, error) {
+	m := new(ProgressEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// BackupServiceServer is the server API for BackupService service.
+// All implementations must embed UnimplementedBackupServiceServer
+// for forward compatibility
+type BackupServiceServer interface {
+	// StartBackup triggers a new backup run and returns immediately.
+	StartBackup(context.Context, *StartBackupRequest) (*Run, error)
+	// GetBackup returns the current state of a backup run.
+	GetBackup(context.Context, *GetRunRequest) (*Run, error)
+	// ListBackups returns the backup runs known to this server, newest first.
+	ListBackups(context.Context, *ListRunsRequest) (*ListRunsResponse, error)
+	// WatchBackup streams progress updates for a backup run until it finishes.
+	WatchBackup(*WatchRunRequest, BackupService_WatchBackupServer) error
+	mustEmbedUnimplementedBackupServiceServer()
+}
+
+// UnimplementedBackupServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedBackupServiceServer struct {
+}
+
+func (UnimplementedBackupServiceServer) StartBackup(context.Context, *StartBackupRequest) (*Run, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartBackup not implemented")
+}
+func (UnimplementedBackupServiceServer) GetBackup(context.Context, *GetRunRequest) (*Run, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBackup not implemented")
+}
+func (UnimplementedBackupServiceServer) ListBackups(context.Context, *ListRunsRequest) (*ListRunsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListBackups not implemented")
+}
+func (UnimplementedBackupServiceServer) WatchBackup(*WatchRunRequest, BackupService_WatchBackupServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchBackup not implemented")
+}
+func (UnimplementedBackupServiceServer) mustEmbedUnimplementedBackupServiceServer() {}
+
+// UnsafeBackupServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BackupServiceServer will
+// result in compilation errors.
+type UnsafeBackupServiceServer interface {
+	mustEmbedUnimplementedBackupServiceServer()
+}
+
+func RegisterBackupServiceServer(s grpc.ServiceRegistrar, srv BackupServiceServer) {
+	s.RegisterService(&BackupService_ServiceDesc, srv)
+}
+
+func _BackupService_StartBackup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartBackupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BackupServiceServer).StartBackup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BackupService_StartBackup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BackupServiceServer).StartBackup(ctx, req.(*StartBackupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BackupService_GetBackup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BackupServiceServer).GetBackup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BackupService_GetBackup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BackupServiceServer).GetBackup(ctx, req.(*GetRunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BackupService_ListBackups_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRunsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BackupServiceServer).ListBackups(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BackupService_ListBackups_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BackupServiceServer).ListBackups(ctx, req.(*ListRunsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BackupService_WatchBackup_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRunRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BackupServiceServer).WatchBackup(m, &backupServiceWatchBackupServer{stream})
+}
+
+type BackupService_WatchBackupServer interface {
+	Send(*ProgressEvent) error
+	grpc.ServerStream
+}
+
+type backupServiceWatchBackupServer struct {
+	grpc.ServerStream
+}
+
+func (x *backupServiceWatchBackupServer) Send(m *ProgressEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// BackupService_ServiceDesc is the grpc.ServiceDesc for BackupService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BackupService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "backup.v1.BackupService",
+	HandlerType: (*BackupServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "StartBackup",
+			Handler:    _BackupService_StartBackup_Handler,
+		},
+		{
+			MethodName: "GetBackup",
+			Handler:    _BackupService_GetBackup_Handler,
+		},
+		{
+			MethodName: "ListBackups",
+			Handler:    _BackupService_ListBackups_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchBackup",
+			Handler:       _BackupService_WatchBackup_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "backup/v1/backup.proto",
+}
+
+const (
+	RestoreService_StartRestore_FullMethodName       = "/backup.v1.RestoreService/StartRestore"
+	RestoreService_GetRestore_FullMethodName         = "/backup.v1.RestoreService/GetRestore"
+	RestoreService_WatchRestore_FullMethodName       = "/backup.v1.RestoreService/WatchRestore"
+	RestoreService_GetRestoreProgress_FullMethodName = "/backup.v1.RestoreService/GetRestoreProgress"
+	RestoreService_CancelRestore_FullMethodName      = "/backup.v1.RestoreService/CancelRestore"
+)
+
+// RestoreServiceClient is the client API for RestoreService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RestoreServiceClient interface {
+	// StartRestore triggers a new restore run and returns immediately.
+	StartRestore(ctx context.Context, in *StartRestoreRequest, opts ...grpc.CallOption) (*Run, error)
+	// GetRestore returns the current state of a restore run.
+	GetRestore(ctx context.Context, in *GetRunRequest, opts ...grpc.CallOption) (*Run, error)
+	// WatchRestore streams progress updates for a restore run until it finishes.
+	WatchRestore(ctx context.Context, in *WatchRunRequest, opts ...grpc.CallOption) (RestoreService_WatchRestoreClient, error)
+	// GetRestoreProgress returns the phase, percent and failed resources of a
+	// restore run.
+	GetRestoreProgress(ctx context.Context, in *GetRunRequest, opts ...grpc.CallOption) (*RestoreProgress, error)
+	// CancelRestore asks a restore run to stop. The run stops applying
+	// resources and ends as cancelled with what it restored so far.
+	CancelRestore(ctx context.Context, in *GetRunRequest, opts ...grpc.CallOption) (*Run, error)
+}
+
+type restoreServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRestoreServiceClient(cc grpc.ClientConnInterface) RestoreServiceClient {
+	return &restoreServiceClient{cc}
+}
+
+func (c *restoreServiceClient) StartRestore(ctx context.Context, in *StartRestoreRequest, opts ...grpc.CallOption) (*Run, error) {
+	out := new(Run)
+	err := c.cc.Invoke(ctx, RestoreService_StartRestore_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *restoreServiceClient) GetRestore(ctx context.Context, in *GetRunRequest, opts ...grpc.CallOption) (*Run, error) {
+	out := new(Run)
+	err := c.cc.Invoke(ctx, RestoreService_GetRestore_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *restoreServiceClient) WatchRestore(ctx context.Context, in *WatchRunRequest, opts ...grpc.CallOption) (RestoreService_WatchRestoreClient, error) {
+	stream, err := c.cc.NewStream(ctx, &RestoreService_ServiceDesc.Streams[0], RestoreService_WatchRestore_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &restoreServiceWatchRestoreClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type RestoreService_WatchRestoreClient interface {
+	Recv() (*ProgressEvent, error)
+	grpc.ClientStream
+}
+
+type restoreServiceWatchRestoreClient struct {
+	grpc.ClientStream
+}
+
+func (x *restoreServiceWatchRestoreClient) Recv() (*ProgressEvent, error) {
+	m := new(ProgressEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *restoreServiceClient) GetRestoreProgress(ctx context.Context, in *GetRunRequest, opts ...grpc.CallOption) (*RestoreProgress, error) {
+	out := new(RestoreProgress)
+	err := c.cc.Invoke(ctx, RestoreService_GetRestoreProgress_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *restoreServiceClient) CancelRestore(ctx context.Context, in *GetRunRequest, opts ...grpc.CallOption) (*Run, error) {
+	out := new(Run)
+	err := c.cc.Invoke(ctx, RestoreService_CancelRestore_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RestoreServiceServer is the server API for RestoreService service.
+// All implementations must embed UnimplementedRestoreServiceServer
+// for forward compatibility
+type RestoreServiceServer interface {
+	// StartRestore triggers a new restore run and returns immediately.
+	StartRestore(context.Context, *StartRestoreRequest) (*Run, error)
+	// GetRestore returns the current state of a restore run.
+	GetRestore(context.Context, *GetRunRequest) (*Run, error)
+	// WatchRestore streams progress updates for a restore run until it finishes.
+	WatchRestore(*WatchRunRequest, RestoreService_WatchRestoreServer) error
+	// GetRestoreProgress returns the phase, percent and failed resources of a
+	// restore run.
+	GetRestoreProgress(context.Context, *GetRunRequest) (*RestoreProgress, error)
+	// CancelRestore asks a restore run to stop. The run stops applying
+	// resources and ends as cancelled with what it restored so far.
+	CancelRestore(context.Context, *GetRunRequest) (*Run, error)
+	mustEmbedUnimplementedRestoreServiceServer()
+}
+
+// UnimplementedRestoreServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedRestoreServiceServer struct {
+}
+
+func (UnimplementedRestoreServiceServer) StartRestore(context.Context, *StartRestoreRequest) (*Run, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartRestore not implemented")
+}
+func (UnimplementedRestoreServiceServer) GetRestore(context.Context, *GetRunRequest) (*Run, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRestore not implemented")
+}
+func (UnimplementedRestoreServiceServer) WatchRestore(*WatchRunRequest, RestoreService_WatchRestoreServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchRestore not implemented")
+}
+func (UnimplementedRestoreServiceServer) GetRestoreProgress(context.Context, *GetRunRequest) (*RestoreProgress, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRestoreProgress not implemented")
+}
+func (UnimplementedRestoreServiceServer) CancelRestore(context.Context, *GetRunRequest) (*Run, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelRestore not implemented")
+}
+func (UnimplementedRestoreServiceServer) mustEmbedUnimplementedRestoreServiceServer() {}
+
+// UnsafeRestoreServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RestoreServiceServer will
+// result in compilation errors.
+type UnsafeRestoreServiceServer interface {
+	mustEmbedUnimplementedRestoreServiceServer()
+}
+
+func RegisterRestoreServiceServer(s grpc.ServiceRegistrar, srv RestoreServiceServer) {
+	s.RegisterService(&RestoreService_ServiceDesc, srv)
+}
+
+func _RestoreService_StartRestore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartRestoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RestoreServiceServer).StartRestore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RestoreService_StartRestore_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RestoreServiceServer).StartRestore(ctx, req.(*StartRestoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RestoreService_GetRestore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RestoreServiceServer).GetRestore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RestoreService_GetRestore_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RestoreServiceServer).GetRestore(ctx, req.(*GetRunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RestoreService_WatchRestore_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRunRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RestoreServiceServer).WatchRestore(m, &restoreServiceWatchRestoreServer{stream})
+}
+
+type RestoreService_WatchRestoreServer interface {
+	Send(*ProgressEvent) error
+	grpc.ServerStream
+}
+
+type restoreServiceWatchRestoreServer struct {
+	grpc.ServerStream
+}
+
+func (x *restoreServiceWatchRestoreServer) Send(m *ProgressEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _RestoreService_GetRestoreProgress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RestoreServiceServer).GetRestoreProgress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RestoreService_GetRestoreProgress_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RestoreServiceServer).GetRestoreProgress(ctx, req.(*GetRunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RestoreService_CancelRestore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RestoreServiceServer).CancelRestore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RestoreService_CancelRestore_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RestoreServiceServer).CancelRestore(ctx, req.(*GetRunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RestoreService_ServiceDesc is the grpc.ServiceDesc for RestoreService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RestoreService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "backup.v1.RestoreService",
+	HandlerType: (*RestoreServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "StartRestore",
+			Handler:    _RestoreService_StartRestore_Handler,
+		},
+		{
+			MethodName: "GetRestore",
+			Handler:    _RestoreService_GetRestore_Handler,
+		},
+		{
+			MethodName: "GetRestoreProgress",
+			Handler:    _RestoreService_GetRestoreProgress_Handler,
+		},
+		{
+			MethodName: "CancelRestore",
+			Handler:    _RestoreService_CancelRestore_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchRestore",
+			Handler:       _RestoreService_WatchRestore_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "backup/v1/backup.proto",
+}
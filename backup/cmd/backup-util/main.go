@@ -1,14 +1,49 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"shared-config/mtls"
+	"shared-config/plugins"
+
+	"cluster-backup/internal/alerting"
+	"cluster-backup/internal/api"
+	"cluster-backup/internal/backup"
+	"cluster-backup/internal/cleaning"
 	"cluster-backup/internal/cluster"
+	"cluster-backup/internal/compat"
+	"cluster-backup/internal/compliance"
 	"cluster-backup/internal/config"
+	"cluster-backup/internal/dashboard"
+	"cluster-backup/internal/diff"
+	"cluster-backup/internal/logging"
+	"cluster-backup/internal/manifests"
+	"cluster-backup/internal/metrics"
 	"cluster-backup/internal/orchestrator"
+	"cluster-backup/internal/priority"
+	"cluster-backup/internal/search"
+	"cluster-backup/internal/storage"
+	"cluster-backup/internal/tui"
+	"cluster-backup/internal/validation"
 )
 
 func main() {
@@ -23,11 +58,273 @@ func main() {
 	case "cluster-info":
 		showClusterInfo()
 	case "config-validate":
-		validateConfiguration()
+		output, opts := "text", config.ValidateOptions{LiveChecks: true}
+		args := os.Args[2:]
+		for i := 0; i < len(args); i++ {
+			switch arg := args[i]; {
+			case arg == "--output" && i+1 < len(args):
+				i++
+				output = args[i]
+			case strings.HasPrefix(arg, "--output="):
+				output = strings.TrimPrefix(arg, "--output=")
+			case arg == "--offline":
+				opts.LiveChecks = false
+			default:
+				output = ""
+			}
+			if output != "text" && output != "json" && output != "yaml" {
+				fmt.Println("Usage: backup-util config-validate [--output text|json|yaml] [--offline]")
+				os.Exit(1)
+			}
+		}
+		validateConfiguration(output, opts)
+	case "estimate":
+		estimateBackupSize()
 	case "estimate-cleanup":
-		estimateCleanup()
+		execute, yes := false, false
+		for _, arg := range os.Args[2:] {
+			switch arg {
+			case "--execute":
+				execute = true
+			case "--yes":
+				yes = true
+			default:
+				fmt.Println("Usage: backup-util estimate-cleanup [--execute [--yes]]")
+				os.Exit(1)
+			}
+		}
+		if yes && !execute {
+			fmt.Println("--yes only applies with --execute")
+			os.Exit(1)
+		}
+		if execute {
+			executeCleanup(yes)
+		} else {
+			estimateCleanup()
+		}
+	case "trash-restore":
+		if len(os.Args) > 3 {
+			fmt.Println("Usage: backup-util trash-restore [key-prefix]")
+			os.Exit(1)
+		}
+		prefix := ""
+		if len(os.Args) == 3 {
+			prefix = os.Args[2]
+		}
+		restoreFromTrash(prefix)
 	case "circuit-breaker-status":
 		showCircuitBreakerStatus()
+	case "diff":
+		if len(os.Args) != 4 {
+			fmt.Println("Usage: backup-util diff <backup-id-a> <backup-id-b>")
+			os.Exit(1)
+		}
+		diffBackups(os.Args[2], os.Args[3])
+	case "config-diff":
+		if len(os.Args) != 4 {
+			fmt.Println("Usage: backup-util config-diff <backup-id-a> <backup-id-b>")
+			os.Exit(1)
+		}
+		diffConfig(os.Args[2], os.Args[3])
+	case "ls":
+		if len(os.Args) < 3 || len(os.Args) > 4 {
+			fmt.Println("Usage: backup-util ls <backup-id> [namespace]")
+			os.Exit(1)
+		}
+		namespace := ""
+		if len(os.Args) == 4 {
+			namespace = os.Args[3]
+		}
+		listBackup(os.Args[2], namespace)
+	case "cat":
+		if len(os.Args) != 4 {
+			fmt.Println("Usage: backup-util cat <backup-id> <namespace>/<type>/<name>")
+			os.Exit(1)
+		}
+		catObject(os.Args[2], os.Args[3])
+	case "verify":
+		if len(os.Args) > 3 {
+			fmt.Println("Usage: backup-util verify [backup-id]")
+			os.Exit(1)
+		}
+		backupID := ""
+		if len(os.Args) == 3 {
+			backupID = os.Args[2]
+		}
+		verifyBackup(backupID)
+	case "rbac-check":
+		if len(os.Args) != 2 {
+			fmt.Println("Usage: backup-util rbac-check")
+			os.Exit(1)
+		}
+		checkPermissions()
+	case "generate-manifests":
+		opts := manifests.Options{Namespace: manifests.DefaultName}
+		args := os.Args[2:]
+		for i := 0; i < len(args); i++ {
+			if i+1 >= len(args) {
+				opts.Namespace = ""
+				break
+			}
+			switch args[i] {
+			case "--namespace":
+				opts.Namespace = args[i+1]
+			case "--image":
+				opts.Image = args[i+1]
+			case "--schedule":
+				opts.Schedule = args[i+1]
+			default:
+				opts.Namespace = ""
+			}
+			i++
+		}
+		if opts.Namespace == "" {
+			fmt.Println("Usage: backup-util generate-manifests [--namespace <namespace>] [--image <image>] [--schedule <cron>]")
+			os.Exit(1)
+		}
+		generateManifests(opts)
+	case "alert-rules":
+		if len(os.Args) > 3 {
+			fmt.Println("Usage: backup-util alert-rules [namespace]")
+			os.Exit(1)
+		}
+		namespace := "monitoring"
+		if len(os.Args) == 3 {
+			namespace = os.Args[2]
+		}
+		printAlertRules(namespace)
+	case "restore":
+		args := os.Args[2:]
+		valid := len(args) > 0 && !strings.HasPrefix(args[0], "--")
+		var req api.RestoreRequest
+		if valid {
+			req.BackupID, args = args[0], args[1:]
+		}
+		for i := 0; valid && i < len(args); i++ {
+			switch args[i] {
+			case "--dry-run":
+				req.DryRun = true
+			case "--force-unsafe":
+				req.ForceUnsafe = true
+			case "--namespace", "--resource-type", "--confirmation-token":
+				if i+1 >= len(args) {
+					valid = false
+					break
+				}
+				switch args[i] {
+				case "--namespace":
+					req.TargetNamespaces = append(req.TargetNamespaces, strings.Split(args[i+1], ",")...)
+				case "--resource-type":
+					req.ResourceTypes = append(req.ResourceTypes, strings.Split(args[i+1], ",")...)
+				default:
+					req.ConfirmationToken = args[i+1]
+				}
+				i++
+			default:
+				valid = false
+			}
+		}
+		// The token only means something with --force-unsafe
+		if !valid || req.ForceUnsafe != (req.ConfirmationToken != "") {
+			fmt.Println("Usage: backup-util restore <backup-id> [--namespace <name,...>] [--resource-type <type,...>] [--dry-run] [--force-unsafe --confirmation-token <token>]")
+			os.Exit(1)
+		}
+		startRestore(req)
+	case "restore-status":
+		if len(os.Args) != 3 {
+			fmt.Println("Usage: backup-util restore-status <run-id>")
+			os.Exit(1)
+		}
+		showRestoreProgress(os.Args[2])
+	case "restore-cancel":
+		if len(os.Args) != 3 {
+			fmt.Println("Usage: backup-util restore-cancel <run-id>")
+			os.Exit(1)
+		}
+		cancelRestore(os.Args[2])
+	case "restore-approve":
+		if len(os.Args) != 3 {
+			fmt.Println("Usage: backup-util restore-approve <run-id>")
+			os.Exit(1)
+		}
+		approveRestore(os.Args[2])
+	case "restore-check":
+		output := "text"
+		args := os.Args[2:]
+		if len(args) == 3 && args[1] == "--output" {
+			output = args[2]
+		} else if len(args) == 2 && strings.HasPrefix(args[1], "--output=") {
+			output = strings.TrimPrefix(args[1], "--output=")
+		} else if len(args) != 1 {
+			output = ""
+		}
+		if output != "text" && output != "json" && output != "yaml" {
+			fmt.Println("Usage: backup-util restore-check <backup-id> [--output text|json|yaml]")
+			os.Exit(1)
+		}
+		checkRestoreTarget(args[0], output)
+	case "browse":
+		if len(os.Args) != 2 {
+			fmt.Println("Usage: backup-util browse")
+			os.Exit(1)
+		}
+		browse()
+	case "search":
+		backupID, terms := "", os.Args[2:]
+		if len(terms) >= 2 && terms[0] == "--backup" {
+			backupID, terms = terms[1], terms[2:]
+		}
+		query, err := search.ParseQuery(terms)
+		if err != nil {
+			fmt.Println(err)
+			fmt.Println("Usage: backup-util search [--backup <backup-id>] <field=value>...")
+			os.Exit(1)
+		}
+		searchBackups(backupID, query)
+	case "migrate-paths":
+		dryRun := len(os.Args) == 3 && os.Args[2] == "--dry-run"
+		if len(os.Args) > 3 || (len(os.Args) == 3 && !dryRun) {
+			fmt.Println("Usage: backup-util migrate-paths [--dry-run]")
+			os.Exit(1)
+		}
+		migrateObjectPaths(dryRun)
+	case "export":
+		if len(os.Args) != 4 {
+			fmt.Println("Usage: backup-util export <backup-id> <dir|file.tar.gz>")
+			os.Exit(1)
+		}
+		exportBackup(os.Args[2], os.Args[3])
+	case "cluster-health":
+		output := "text"
+		var opts validation.Options
+		configPath := os.Getenv("VALIDATION_CONFIG")
+		args := os.Args[2:]
+		for i := 0; i < len(args); i++ {
+			if i+1 >= len(args) {
+				output = ""
+				break
+			}
+			switch args[i] {
+			case "--output":
+				output = args[i+1]
+			case "--category":
+				opts.Categories = append(opts.Categories, strings.Split(args[i+1], ",")...)
+			case "--gitops-repo":
+				opts.GitOpsRepo = args[i+1]
+			case "--backup-dir":
+				opts.BackupDir = args[i+1]
+			case "--config":
+				configPath = args[i+1]
+			default:
+				output = ""
+			}
+			i++
+		}
+		if output != "text" && output != "json" && output != "yaml" {
+			fmt.Println("Usage: backup-util cluster-health [--output text|json|yaml] [--category <name,...>] [--gitops-repo <dir>] [--backup-dir <dir>] [--config <file>]")
+			os.Exit(1)
+		}
+		checkClusterHealth(opts, configPath, output)
 	case "health-check":
 		fmt.Println("OK")
 	default:
@@ -40,9 +337,29 @@ func main() {
 func printUsage() {
 	fmt.Println("Backup Utility Commands:")
 	fmt.Println("  cluster-info          - Show detected cluster information")
-	fmt.Println("  config-validate       - Validate configuration")
-	fmt.Println("  estimate-cleanup      - Estimate cleanup impact without performing cleanup")
+	fmt.Println("  config-validate [--output text|json|yaml] [--offline] - Validate configuration, with live multi-cluster checks unless offline; exit 1 on errors, 2 on warnings only")
+	fmt.Println("  estimate              - Estimate objects and bytes per namespace a backup would store")
+	fmt.Println("  estimate-cleanup [--execute [--yes]] - Estimate cleanup impact; --execute lists the objects by run and deletes them once confirmed")
+	fmt.Println("  trash-restore [prefix] - Move objects cleanup trashed back into place (default: the whole trash)")
 	fmt.Println("  circuit-breaker-status - Show circuit breaker status")
+	fmt.Println("  diff <id-a> <id-b>    - Show resources added, removed or changed between two backups")
+	fmt.Println("  config-diff <id-a> <id-b> - Show settings changed between the configurations two backups were made with")
+	fmt.Println("  ls <id> [namespace]   - Show the namespaces, resource types and objects in a backup as a tree")
+	fmt.Println("  cat <id> <path>       - Print a backed-up resource's YAML; path is namespace/type/name, or type/name if cluster-scoped")
+	fmt.Println("  verify [backup-id]    - Check the manifest signature and stored objects' SHA-256 checksums (default: latest backup)")
+	fmt.Println("  rbac-check            - Check the service account may do what a backup needs and print a minimal ClusterRole")
+	fmt.Println("  generate-manifests [--namespace ns] [--image img] [--schedule cron] - Print a hardened ServiceAccount, ClusterRole, workload and NetworkPolicy for the current configuration")
+	fmt.Println("  alert-rules [namespace] - Print a PrometheusRule for the ALERT_* thresholds (default namespace: monitoring)")
+	fmt.Println("  restore <id> [--namespace ns,...] [--resource-type type,...] [--dry-run] [--force-unsafe --confirmation-token token] - Restore a backup through the REST API and wait for it; protected resources are skipped unless forced with the token the skipping restore printed")
+	fmt.Println("  restore-status <run-id> - Show a restore run's phase, progress and failed resources through the REST API")
+	fmt.Println("  restore-cancel <run-id> - Stop a restore run through the REST API and show how far it got")
+	fmt.Println("  restore-approve <run-id> - Approve a restore run another user requested, which then starts")
+	fmt.Println("  restore-check <id> [--output text|json|yaml] - Check this cluster can take a backup: version, removed APIs, missing CRDs and feature gates; exit 1 on errors, 2 on warnings only")
+	fmt.Println("  browse                - Browse backups, diff runs and start restores in a terminal UI")
+	fmt.Println("  search [--backup <id>] <field=value>... - Find resources by kind, name, namespace, resource, group or label across runs, e.g. label=app=payments kind=Deployment")
+	fmt.Println("  migrate-paths [--dry-run] - Copy resources stored before API groups were part of their keys to their new keys and update the manifests")
+	fmt.Println("  export <id> <dir|file.tar.gz> - Write a backup's manifest and objects, in the bucket's layout, for restores that cannot reach the bucket")
+	fmt.Println("  cluster-health [--output text|json|yaml] [--category name,...] [--gitops-repo dir] [--backup-dir dir] [--config file] - Check node, pod, quota, volume, GitOps, security and API health, the YAML of a GitOps checkout and the integrity of an export; exit 1 on failures, 2 on warnings only")
 	fmt.Println("  health-check          - Simple health check")
 }
 
@@ -61,33 +378,274 @@ func showClusterInfo() {
 	fmt.Printf("OpenShift Mode: %s\n", info.OpenShiftMode)
 }
 
-func validateConfiguration() {
+func validateConfiguration(output string, opts config.ValidateOptions) {
+	report := config.Validate(opts)
+
+	switch output {
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to encode validation report: %v", err)
+		}
+		fmt.Println(string(data))
+	case "yaml":
+		data, err := yaml.Marshal(report)
+		if err != nil {
+			log.Fatalf("Failed to encode validation report: %v", err)
+		}
+		fmt.Print(string(data))
+	default:
+		printValidationReport(report)
+	}
+	os.Exit(report.ExitCode())
+}
+
+func printValidationReport(report *config.ValidationReport) {
 	fmt.Println("=== Configuration Validation ===")
-	
-	// Load and validate main config
+	if report.ConfigFile != "" {
+		fmt.Printf("Config File:      %s\n", report.ConfigFile)
+	} else {
+		fmt.Println("Config File:      none, environment only")
+	}
+
+	for _, finding := range report.Errors {
+		fmt.Printf("❌ %s\n", formatFinding(finding))
+	}
+	for _, finding := range report.Warnings {
+		fmt.Printf("⚠️  %s\n", formatFinding(finding))
+	}
+
+	for _, cluster := range report.Clusters {
+		status := "✅"
+		if !cluster.Valid {
+			status = "❌"
+		}
+		details := "configuration checked"
+		if check := cluster.Connectivity; check != nil {
+			details = fmt.Sprintf("reachable=%v tls=%v authenticated=%v", check.Reachable, check.TLSValid, check.Authenticated)
+			if check.APIServerVersion != "" {
+				details += fmt.Sprintf(" (%s, %s)", check.APIServerVersion, check.ResponseTime)
+			}
+		}
+		fmt.Printf("%s Cluster %s: %s\n", status, cluster.Name, details)
+	}
+
+	// Show key configuration values
+	if cfg, backupCfg := report.Config, report.BackupConfig; cfg != nil {
+		fmt.Printf("Cluster Name:     %s\n", cfg.ClusterName)
+		fmt.Printf("MinIO Endpoint:   %s\n", cfg.MinIOEndpoint)
+		fmt.Printf("MinIO Bucket:     %s\n", cfg.MinIOBucket)
+		fmt.Printf("Retention Days:   %d\n", cfg.RetentionDays)
+		fmt.Printf("Batch Size:       %d\n", cfg.BatchSize)
+		fmt.Printf("OpenShift Mode:   %s\n", backupCfg.OpenShiftMode)
+		fmt.Printf("Cleanup Enabled:  %v\n", cfg.EnableCleanup)
+	}
+
+	switch report.ExitCode() {
+	case config.ValidationPassed:
+		fmt.Println("✅ Configuration valid")
+	case config.ValidationWarnings:
+		fmt.Printf("⚠️  Configuration valid with %d warning(s)\n", len(report.Warnings))
+	default:
+		fmt.Printf("❌ Configuration invalid: %d error(s), %d warning(s)\n", len(report.Errors), len(report.Warnings))
+	}
+}
+
+func formatFinding(finding config.ValidationFinding) string {
+	if finding.Field == "" {
+		return fmt.Sprintf("%s: %s", finding.Source, finding.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", finding.Source, finding.Field, finding.Message)
+}
+
+func estimateBackupSize() {
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		fmt.Printf("❌ Main configuration invalid: %v\n", err)
-		os.Exit(1)
+		log.Fatalf("Failed to load configuration: %v", err)
 	}
-	fmt.Println("✅ Main configuration valid")
-	
-	// Load and validate backup config
 	backupCfg, err := config.LoadBackupConfig()
 	if err != nil {
-		fmt.Printf("❌ Backup configuration invalid: %v\n", err)
+		log.Fatalf("Failed to load backup configuration: %v", err)
+	}
+
+	kubeConfig, err := rest.InClusterConfig()
+	if err != nil {
+		log.Fatalf("Failed to create Kubernetes config: %v", err)
+	}
+	kubeClient, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		log.Fatalf("Failed to create Kubernetes client: %v", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(kubeConfig)
+	if err != nil {
+		log.Fatalf("Failed to create dynamic client: %v", err)
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(kubeConfig)
+	if err != nil {
+		log.Fatalf("Failed to create discovery client: %v", err)
+	}
+
+	minioTransport, err := storage.NewTransport(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create MinIO client: %v", err)
+	}
+	minioClient, err := storage.NewClient(cfg, minioTransport)
+	if err != nil {
+		log.Fatalf("Failed to create MinIO client: %v", err)
+	}
+
+	ctx := context.Background()
+	logger := logging.NewStructuredLogger("backup-util", cfg.ClusterName)
+	clusterBackup := backup.NewClusterBackup(cfg, backupCfg, kubeClient, dynamicClient, discoveryClient, minioClient, logger, metrics.NewBackupMetrics(), ctx)
+	if cfg.CleaningProfilesConfigMap != "" {
+		profiles, err := cleaning.Load(ctx, kubeClient, cfg.CleaningProfilesNamespace, cfg.CleaningProfilesConfigMap)
+		if err != nil {
+			log.Fatalf("Failed to load cleaning profiles: %v", err)
+		}
+		clusterBackup.SetCleaningProfiles(profiles)
+	}
+	if len(cfg.ResourcePlugins) > 0 {
+		chain, err := plugins.Open(cfg.ResourcePlugins, cfg.ResourcePluginOptions)
+		if err != nil {
+			log.Fatalf("Failed to load resource plugins: %v", err)
+		}
+		clusterBackup.SetPlugins(chain)
+	}
+	// The remaining items are counted in pages of the run's batch sizes
+	priorities := priority.NewManager(kubeClient, priority.DefaultConfigMap, priority.DefaultNamespace)
+	if err := priorities.LoadConfig(); err == nil {
+		clusterBackup.SetBatchSizes(priorities)
+	}
+
+	estimate, err := clusterBackup.Estimate(ctx)
+	if err != nil {
+		log.Fatalf("Failed to estimate backup size: %v", err)
+	}
+
+	fmt.Println("=== Backup Size Estimate ===")
+	fmt.Printf("%-40s %10s %12s\n", "NAMESPACE", "OBJECTS", "SIZE (MB)")
+	scopes := estimate.Namespaces
+	if estimate.ClusterScope != nil {
+		scopes = append(scopes, *estimate.ClusterScope)
+	}
+	var warnings []string
+	for _, scope := range scopes {
+		name := scope.Namespace
+		if name == "" {
+			name = "(cluster-scoped)"
+		}
+		fmt.Printf("%-40s %10d %12.2f\n", name, scope.Items, float64(scope.Bytes)/(1024*1024))
+		for _, failure := range scope.Failed {
+			warnings = append(warnings, fmt.Sprintf("%s: not counted, %s", name, failure))
+		}
+	}
+	fmt.Printf("\nTotal Objects:        %d\n", estimate.Items)
+	fmt.Printf("Total Size (MB):      %.2f\n", float64(estimate.Bytes)/(1024*1024))
+
+	for _, warning := range warnings {
+		fmt.Printf("⚠️  %s\n", warning)
+	}
+}
+
+func checkPermissions() {
+	_, report, _ := permissionReport()
+
+	fmt.Println("=== RBAC Check ===")
+	for _, check := range report.Checks {
+		if check.Allowed {
+			fmt.Printf("✅ %s\n", check.Permission)
+		} else if check.Reason != "" {
+			fmt.Printf("❌ %s: %s\n", check.Permission, check.Reason)
+		} else {
+			fmt.Printf("❌ %s\n", check.Permission)
+		}
+	}
+	missing := report.Missing()
+	fmt.Printf("\nAllowed: %d  Missing: %d\n", len(report.Checks)-len(missing), len(missing))
+
+	role, err := report.ClusterRole("cluster-backup")
+	if err != nil {
+		log.Fatalf("Failed to generate ClusterRole: %v", err)
+	}
+	fmt.Println("\n# Minimal ClusterRole for the current configuration")
+	fmt.Print(string(role))
+
+	if len(missing) > 0 {
 		os.Exit(1)
 	}
-	fmt.Println("✅ Backup configuration valid")
-	
-	// Show key configuration values
-	fmt.Printf("Cluster Name:     %s\n", cfg.ClusterName)
-	fmt.Printf("MinIO Endpoint:   %s\n", cfg.MinIOEndpoint)
-	fmt.Printf("MinIO Bucket:     %s\n", cfg.MinIOBucket)
-	fmt.Printf("Retention Days:   %d\n", cfg.RetentionDays)
-	fmt.Printf("Batch Size:       %d\n", cfg.BatchSize)
-	fmt.Printf("OpenShift Mode:   %s\n", backupCfg.OpenShiftMode)
-	fmt.Printf("Cleanup Enabled:  %v\n", cfg.EnableCleanup)
+}
+
+// permissionReport checks, with the in-cluster service account, the
+// permissions a backup of the current configuration needs
+func permissionReport() (*config.Config, *backup.PermissionReport, kubernetes.Interface) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	backupCfg, err := config.LoadBackupConfig()
+	if err != nil {
+		log.Fatalf("Failed to load backup configuration: %v", err)
+	}
+
+	kubeConfig, err := rest.InClusterConfig()
+	if err != nil {
+		log.Fatalf("Failed to create Kubernetes config: %v", err)
+	}
+	kubeClient, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		log.Fatalf("Failed to create Kubernetes client: %v", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(kubeConfig)
+	if err != nil {
+		log.Fatalf("Failed to create dynamic client: %v", err)
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(kubeConfig)
+	if err != nil {
+		log.Fatalf("Failed to create discovery client: %v", err)
+	}
+
+	// Only the Kubernetes API is checked, MinIO is not used
+	ctx := context.Background()
+	logger := logging.NewStructuredLogger("backup-util", cfg.ClusterName)
+	clusterBackup := backup.NewClusterBackup(cfg, backupCfg, kubeClient, dynamicClient, discoveryClient, nil, logger, metrics.NewBackupMetrics(), ctx)
+
+	report, err := clusterBackup.CheckPermissions(ctx, priority.DefaultConfigMap, priority.DefaultNamespace)
+	if err != nil {
+		log.Fatalf("Failed to check permissions: %v", err)
+	}
+	return cfg, report, kubeClient
+}
+
+// generateManifests prints the manifests deploying the backup with the
+// current configuration, its ClusterRole granting what an RBAC check finds a
+// backup needs
+func generateManifests(opts manifests.Options) {
+	cfg, report, kubeClient := permissionReport()
+	opts.Rules = report.Rules()
+
+	// Egress to the API server is allowed to its endpoints, as policies see
+	// the connections after the service address is translated
+	endpoints, err := kubeClient.CoreV1().Endpoints("default").Get(context.Background(), "kubernetes", metav1.GetOptions{})
+	if err != nil {
+		log.Printf("Failed to read the Kubernetes API endpoints, allowing its ports anywhere: %v", err)
+	} else {
+		for _, subset := range endpoints.Subsets {
+			for _, address := range subset.Addresses {
+				for _, port := range subset.Ports {
+					opts.APIServer = append(opts.APIServer, net.JoinHostPort(address.IP, strconv.Itoa(int(port.Port))))
+				}
+			}
+		}
+	}
+
+	data, err := manifests.Generate(cfg, opts)
+	if err != nil {
+		log.Fatalf("Failed to generate manifests: %v", err)
+	}
+	if missing := report.Missing(); len(missing) > 0 {
+		fmt.Fprintf(os.Stderr, "⚠️  The service account lacks %d permissions the ClusterRole grants, see rbac-check\n", len(missing))
+	}
+	fmt.Print(string(data))
 }
 
 func estimateCleanup() {
@@ -111,6 +669,7 @@ func estimateCleanup() {
 	fmt.Printf("Total Files:          %v\n", summary["total_files"])
 	fmt.Printf("Files to Delete:      %v\n", summary["files_to_delete"])
 	fmt.Printf("Files to Keep:        %v\n", summary["files_to_keep"])
+	fmt.Printf("Kept for Last Runs:   %v\n", summary["files_kept_for_runs"])
 	fmt.Printf("Total Size (MB):      %v\n", summary["total_size_mb"])
 	fmt.Printf("Space to Free (MB):   %v\n", summary["space_to_free_mb"])
 	fmt.Printf("Retention Days:       %v\n", summary["retention_days"])
@@ -121,6 +680,83 @@ func estimateCleanup() {
 	}
 }
 
+// executeCleanup shows every object a cleanup would delete, grouped by run,
+// and deletes exactly those once confirmed
+func executeCleanup(yes bool) {
+	fmt.Println("=== Cleanup Plan ===")
+
+	config := orchestrator.DefaultOrchestratorConfig()
+	config.EnableMetricsServer = false // Don't start metrics server for utility
+
+	backupOrchestrator, err := orchestrator.NewBackupOrchestrator(config)
+	if err != nil {
+		log.Fatalf("Failed to create backup orchestrator: %v", err)
+	}
+
+	plan, err := backupOrchestrator.PlanCleanup()
+	if err != nil {
+		log.Fatalf("Failed to plan cleanup: %v", err)
+	}
+
+	fmt.Printf("Retention Days:       %d\n", plan.RetentionDays)
+	fmt.Printf("Cutoff Time:          %s\n", plan.CutoffTime.Format(time.RFC3339))
+	if len(plan.KeptRuns) > 0 {
+		fmt.Printf("Kept Runs:            %s (%d expired objects)\n", strings.Join(plan.KeptRuns, ", "), plan.FilesKept)
+	}
+	for _, run := range plan.Runs {
+		fmt.Println()
+		if run.BackupID == "" {
+			fmt.Printf("Not listed by any run: %d objects, %.1f MB\n", len(run.Objects), float64(run.Size)/(1024*1024))
+		} else {
+			fmt.Printf("%s %s: %d objects, %.1f MB\n", run.Cluster, run.BackupID, len(run.Objects), float64(run.Size)/(1024*1024))
+		}
+		for _, object := range run.Objects {
+			fmt.Printf("  %s/%s  %d bytes  %s\n", object.Bucket, object.Key, object.Size, object.LastModified.Format(time.RFC3339))
+		}
+	}
+	fmt.Println()
+
+	if plan.Objects() == 0 {
+		fmt.Println("Nothing to delete")
+		return
+	}
+	if !yes {
+		fmt.Printf("Delete %d objects (%.1f MB)? [y/N] ", plan.Objects(), float64(plan.Size())/(1024*1024))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			fmt.Println("Aborted, nothing deleted")
+			return
+		}
+	}
+
+	audit, auditKey, err := backupOrchestrator.ExecuteCleanupPlan(plan)
+	if audit != nil {
+		fmt.Printf("Deleted %d objects, audit log: %s\n", audit.Deleted, auditKey)
+		for _, key := range audit.Failed {
+			fmt.Printf("  FAILED %s\n", key)
+		}
+	}
+	if err != nil {
+		log.Fatalf("Cleanup failed: %v", err)
+	}
+}
+
+func restoreFromTrash(prefix string) {
+	config := orchestrator.DefaultOrchestratorConfig()
+	config.EnableMetricsServer = false // Don't start metrics server for utility
+
+	backupOrchestrator, err := orchestrator.NewBackupOrchestrator(config)
+	if err != nil {
+		log.Fatalf("Failed to create backup orchestrator: %v", err)
+	}
+
+	restored, err := backupOrchestrator.RestoreFromTrash(prefix)
+	fmt.Printf("Restored %d objects from the trash\n", restored)
+	if err != nil {
+		log.Fatalf("Failed to restore from the trash: %v", err)
+	}
+}
+
 func showCircuitBreakerStatus() {
 	fmt.Println("=== Circuit Breaker Status ===")
 	
@@ -147,4 +783,806 @@ func showCircuitBreakerStatus() {
 		}
 		fmt.Println()
 	}
-}
\ No newline at end of file
+}
+
+func diffBackups(fromID, toID string) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	minioTransport, err := storage.NewTransport(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create MinIO client: %v", err)
+	}
+	minioClient, err := storage.NewClient(cfg, minioTransport)
+	if err != nil {
+		log.Fatalf("Failed to create MinIO client: %v", err)
+	}
+
+	ctx := context.Background()
+	store := backup.NewManifestStore(minioClient, cfg.MinIOBucket, cfg.ClusterDomain, cfg.ClusterName)
+
+	fromManifest, err := store.Load(ctx, fromID)
+	if err != nil {
+		log.Fatalf("Failed to load backup %s: %v", fromID, err)
+	}
+	toManifest, err := store.Load(ctx, toID)
+	if err != nil {
+		log.Fatalf("Failed to load backup %s: %v", toID, err)
+	}
+
+	result := diff.CompareManifests(fromManifest, toManifest)
+	warnings := diff.PopulateDiffs(ctx, store, result)
+
+	fmt.Printf("=== Backup Diff: %s -> %s ===\n", fromID, toID)
+	for _, change := range result.Changes {
+		switch change.Type {
+		case diff.ChangeAdded:
+			fmt.Printf("+ %s\n", change.Identity())
+		case diff.ChangeRemoved:
+			fmt.Printf("- %s\n", change.Identity())
+		case diff.ChangeChanged:
+			fmt.Printf("~ %s\n", change.Identity())
+			if change.Diff != "" {
+				fmt.Println(change.Diff)
+			}
+		}
+	}
+
+	for _, warning := range warnings {
+		fmt.Printf("⚠️  %v\n", warning)
+	}
+
+	added, removed, changed := result.Counts()
+	fmt.Printf("\nAdded: %d  Removed: %d  Changed: %d  Unchanged: %d\n", added, removed, changed, result.Unchanged)
+
+	// Exit non-zero on drift so the command can gate scripts, like diff(1)
+	if len(result.Changes) > 0 {
+		os.Exit(1)
+	}
+}
+
+func diffConfig(fromID, toID string) {
+	ctx := context.Background()
+	store := openManifestStore()
+
+	var manifests [2]*backup.Manifest
+	for i, backupID := range []string{fromID, toID} {
+		manifest, err := store.Load(ctx, backupID)
+		if err != nil {
+			log.Fatalf("Failed to load backup %s: %v", backupID, err)
+		}
+		if manifest.Config == nil {
+			log.Fatalf("Backup %s has no configuration snapshot; it was made before snapshots were recorded", backupID)
+		}
+		manifests[i] = manifest
+	}
+
+	fmt.Printf("=== Config Diff: %s (%s) -> %s (%s) ===\n", fromID, manifests[0].ConfigVersion, toID, manifests[1].ConfigVersion)
+	changes := backup.DiffConfig(manifests[0].Config, manifests[1].Config)
+	for _, change := range changes {
+		fmt.Printf("~ %s: %q -> %q\n", change.Setting, change.Previous, change.Current)
+	}
+	fmt.Printf("\nChanged: %d\n", len(changes))
+
+	// Exit non-zero on drift, like diff
+	if len(changes) > 0 {
+		os.Exit(1)
+	}
+}
+
+func verifyBackup(backupID string) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	minioTransport, err := storage.NewTransport(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create MinIO client: %v", err)
+	}
+	minioClient, err := storage.NewClient(cfg, minioTransport)
+	if err != nil {
+		log.Fatalf("Failed to create MinIO client: %v", err)
+	}
+
+	ctx := context.Background()
+	store := backup.NewManifestStore(minioClient, cfg.MinIOBucket, cfg.ClusterDomain, cfg.ClusterName)
+
+	var manifest *backup.Manifest
+	if backupID == "" {
+		manifest, err = store.Latest(ctx)
+	} else {
+		manifest, err = store.Load(ctx, backupID)
+	}
+	if err != nil {
+		log.Fatalf("Failed to load backup: %v", err)
+	}
+
+	verifier, err := backup.NewManifestVerifier(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load manifest verification keys: %v", err)
+	}
+
+	fmt.Printf("=== Backup Verification: %s ===\n", manifest.BackupID)
+
+	// Check the manifest is authentic before trusting the checksums it lists
+	signatureOK := true
+	if verifier != nil {
+		if err := store.VerifySignature(ctx, manifest.BackupID, verifier); err != nil {
+			fmt.Printf("✗ manifest signature: %v\n", err)
+			signatureOK = false
+		} else {
+			fmt.Println("✓ manifest signature verified")
+		}
+	}
+
+	result := store.Verify(ctx, manifest)
+	for _, failure := range result.Failures {
+		fmt.Printf("✗ %s: %v\n", failure.Key, failure.Err)
+	}
+	fmt.Printf("\nChecked: %d  Failed: %d  Without checksum: %d\n", result.Checked, len(result.Failures), result.Unverified)
+	if result.Skipped > 0 {
+		fmt.Printf("Skipped %d objects in tenants' buckets\n", result.Skipped)
+	}
+
+	// Exit non-zero so scheduled verification can alert on corruption
+	if !result.OK() || !signatureOK {
+		os.Exit(1)
+	}
+}
+
+// openManifestStore connects to the bucket holding the configured cluster's
+// backups
+func openManifestStore() *backup.ManifestStore {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	minioTransport, err := storage.NewTransport(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create MinIO client: %v", err)
+	}
+	minioClient, err := storage.NewClient(cfg, minioTransport)
+	if err != nil {
+		log.Fatalf("Failed to create MinIO client: %v", err)
+	}
+	return backup.NewManifestStore(minioClient, cfg.MinIOBucket, cfg.ClusterDomain, cfg.ClusterName)
+}
+
+func listBackup(backupID, namespace string) {
+	manifest, err := openManifestStore().Load(context.Background(), backupID)
+	if err != nil {
+		log.Fatalf("Failed to load backup %s: %v", backupID, err)
+	}
+
+	tree := dashboard.BuildTree(manifest)
+	if namespace != "" {
+		var selected []*dashboard.TreeNode
+		for _, node := range tree {
+			if node.Name == namespace {
+				selected = append(selected, node)
+			}
+		}
+		if len(selected) == 0 {
+			log.Fatalf("Backup %s holds no objects in namespace %s", backupID, namespace)
+		}
+		tree = selected
+	}
+
+	summary := manifest.Summary()
+	fmt.Printf("%s (%s, %d objects)\n", summary.BackupID, summary.Status, summary.ObjectCount)
+	printTree(tree, "")
+}
+
+// printTree draws nodes and their children like tree(1), with object counts
+// on namespaces and resource types and sizes on objects
+func printTree(nodes []*dashboard.TreeNode, indent string) {
+	for i, node := range nodes {
+		branch, next := "├── ", "│   "
+		if i == len(nodes)-1 {
+			branch, next = "└── ", "    "
+		}
+		if len(node.Children) == 0 {
+			fmt.Printf("%s%s%s (%s)\n", indent, branch, node.Name, formatSize(node.Size))
+			continue
+		}
+		fmt.Printf("%s%s%s (%d)\n", indent, branch, node.Name, node.Count)
+		printTree(node.Children, indent+next)
+	}
+}
+
+func formatSize(bytes int64) string {
+	switch {
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(bytes)/(1<<20))
+	case bytes >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(bytes)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", bytes)
+	}
+}
+
+func catObject(backupID, path string) {
+	parts := strings.Split(path, "/")
+	namespace := ""
+	switch len(parts) {
+	case 3:
+		namespace, parts = parts[0], parts[1:]
+	case 2:
+	default:
+		log.Fatalf("Invalid path %q: expected <namespace>/<type>/<name>, or <type>/<name> for a cluster-scoped resource", path)
+	}
+
+	ctx := context.Background()
+	store := openManifestStore()
+	manifest, err := store.Load(ctx, backupID)
+	if err != nil {
+		log.Fatalf("Failed to load backup %s: %v", backupID, err)
+	}
+
+	objects := manifest.FindObject(namespace, parts[0], parts[1])
+	switch len(objects) {
+	case 0:
+		log.Fatalf("Backup %s holds no object %s", backupID, path)
+	case 1:
+	default:
+		var types []string
+		for _, object := range objects {
+			qualified := object.ResourceType
+			if object.Group != "" {
+				qualified += "." + object.Group
+			}
+			types = append(types, qualified)
+		}
+		log.Fatalf("%s matches several API groups in backup %s, qualify the type as one of: %s", path, backupID, strings.Join(types, ", "))
+	}
+
+	// The version ID pins the object this run wrote in a versioned bucket
+	data, err := store.GetObjectVersion(ctx, objects[0].Key, objects[0].VersionID)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", objects[0].Key, err)
+	}
+	os.Stdout.Write(data)
+}
+
+// printAlertRules prints the PrometheusRule for a backup service scraped by
+// Prometheus, which alerts from its metrics instead of through ALERTMANAGER_URL
+func printAlertRules(namespace string) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	rule, err := alerting.PrometheusRule("cluster-backup", namespace, alerting.RulesFromConfig(cfg))
+	if err != nil {
+		log.Fatalf("Failed to generate PrometheusRule: %v", err)
+	}
+	fmt.Print(string(rule))
+}
+
+// restoreCancelWait bounds how long restore-cancel waits for the run to stop
+const restoreCancelWait = time.Minute
+
+// apiRequest calls the REST API of the backup service at API_URL (default:
+// localhost on API_PORT) and decodes the response into out
+func apiRequest(method, path string, out interface{}) {
+	if err := callAPI(context.Background(), method, path, nil, out); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// callAPI sends body, if any, as JSON to the REST API of the backup service
+// and decodes the response into out
+func callAPI(ctx context.Context, method, path string, body, out interface{}) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %v", err)
+	}
+	if err := compliance.Enforce(cfg); err != nil {
+		return err
+	}
+
+	baseURL := os.Getenv("API_URL")
+	if baseURL == "" {
+		scheme := "http"
+		if cfg.APITLSCertFile != "" {
+			scheme = "https"
+		}
+		baseURL = fmt.Sprintf("%s://localhost:%d", scheme, cfg.APIPort)
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode API request: %v", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(baseURL, "/")+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create API request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.APIToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	// With mutual TLS the API requires a certificate of its CA, read from
+	// API_CLIENT_CERT_FILE and API_CLIENT_KEY_FILE, and the API's own is
+	// checked against the CA too
+	if cfg.APITLSCAFile != "" {
+		certificates, err := mtls.Load(mtls.Files{
+			CertFile: os.Getenv("API_CLIENT_CERT_FILE"),
+			KeyFile:  os.Getenv("API_CLIENT_KEY_FILE"),
+			CAFile:   cfg.APITLSCAFile,
+		}, 0)
+		if err != nil {
+			return fmt.Errorf("failed to load the API client certificate: %v", err)
+		}
+		if compliance.Enabled(cfg) {
+			certificates.Configure = compliance.Restrict
+		}
+		client.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: certificates.ClientConfig(),
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach the backup API at %s: %v", baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("backup API returned %s: %s", resp.Status, apiErr.Error)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode API response: %v", err)
+	}
+	return nil
+}
+
+// startRestore starts a restore run through the REST API and follows it until
+// it finishes or waits for approval, listing the resources it skipped, with
+// the confirmation token forcing those the guardrails protect
+func startRestore(req api.RestoreRequest) {
+	var run api.Run
+	if err := callAPI(context.Background(), http.MethodPost, "/restores", req, &run); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Restore run %s of backup %s started\n", run.ID, req.BackupID)
+
+	var progress api.RestoreRunProgress
+	for {
+		apiRequest(http.MethodGet, "/restores/"+run.ID+"/progress", &progress)
+		if progress.Status != api.RunStatusPending && progress.Status != api.RunStatusRunning {
+			break
+		}
+		time.Sleep(time.Second)
+	}
+	printRestoreProgress(&progress)
+	if progress.Status == api.RunStatusAwaitingApproval {
+		return
+	}
+
+	var finished struct {
+		Error  string `json:"error"`
+		Result struct {
+			SkippedResources []struct {
+				Kind      string `json:"kind"`
+				Namespace string `json:"namespace"`
+				Name      string `json:"name"`
+				Reason    string `json:"reason"`
+			} `json:"skipped_resources"`
+		} `json:"result"`
+	}
+	apiRequest(http.MethodGet, "/restores/"+run.ID, &finished)
+	if skipped := finished.Result.SkippedResources; len(skipped) > 0 {
+		fmt.Printf("\nSkipped resources (%d):\n", len(skipped))
+		for _, resource := range skipped {
+			name := resource.Kind + "/" + resource.Name
+			if resource.Namespace != "" {
+				name = resource.Namespace + "/" + name
+			}
+			fmt.Printf("- %s: %s\n", name, resource.Reason)
+		}
+	}
+	if progress.Status != api.RunStatusCompleted {
+		fmt.Printf("\nRestore run %s %s: %s\n", run.ID, progress.Status, finished.Error)
+		os.Exit(1)
+	}
+}
+
+func showRestoreProgress(runID string) {
+	var progress api.RestoreRunProgress
+	apiRequest(http.MethodGet, "/restores/"+runID+"/progress", &progress)
+	printRestoreProgress(&progress)
+}
+
+func cancelRestore(runID string) {
+	var run api.Run
+	apiRequest(http.MethodPost, "/restores/"+runID+"/cancel", &run)
+	fmt.Printf("Cancellation of restore run %s requested, waiting for it to stop...\n", runID)
+
+	// The run finishes the resource it is applying before it stops
+	var progress api.RestoreRunProgress
+	deadline := time.Now().Add(restoreCancelWait)
+	for {
+		apiRequest(http.MethodGet, "/restores/"+runID+"/progress", &progress)
+		if progress.Status != api.RunStatusPending && progress.Status != api.RunStatusRunning {
+			break
+		}
+		if time.Now().After(deadline) {
+			fmt.Printf("Restore run %s has not stopped after %s\n", runID, restoreCancelWait)
+			printRestoreProgress(&progress)
+			os.Exit(1)
+		}
+		time.Sleep(time.Second)
+	}
+	printRestoreProgress(&progress)
+}
+
+// approveRestore approves a restore run awaiting approval. The approver must
+// be a different user than the one who requested it.
+func approveRestore(runID string) {
+	var run api.Run
+	apiRequest(http.MethodPost, "/restores/"+runID+"/approve", &run)
+	fmt.Printf("Restore run %s approved, it is starting\n", runID)
+
+	var progress api.RestoreRunProgress
+	apiRequest(http.MethodGet, "/restores/"+runID+"/progress", &progress)
+	printRestoreProgress(&progress)
+}
+
+func printRestoreProgress(progress *api.RestoreRunProgress) {
+	fmt.Printf("=== Restore Run: %s ===\n", progress.ID)
+	status := string(progress.Status)
+	if progress.CancelRequested && progress.Status == api.RunStatusRunning {
+		status += " (cancelling)"
+	}
+	fmt.Printf("Status: %s\n", status)
+	if approval := progress.Approval; approval != nil {
+		fmt.Printf("Requested by: %s\n", approval.RequestedBy.ID)
+		if approval.ApprovedBy != nil {
+			fmt.Printf("Approved by: %s at %s\n", approval.ApprovedBy.ID, approval.ApprovedAt.Format(time.RFC3339))
+		} else if progress.Status == api.RunStatusAwaitingApproval {
+			fmt.Printf("Awaiting approval until %s (approve with: backup-util restore-approve %s)\n", approval.ExpiresAt.Format(time.RFC3339), progress.ID)
+		}
+	}
+	if progress.Progress == nil {
+		fmt.Println("No progress reported yet")
+		return
+	}
+
+	p := progress.Progress
+	fmt.Printf("Phase: %s\n", p.Phase)
+	fmt.Printf("Progress: %.1f%% (%d/%d resources)\n", p.Percent, p.ResourcesDone, p.ResourcesTotal)
+	if p.CurrentResource != "" {
+		fmt.Printf("Current: %s\n", p.CurrentResource)
+	}
+	if len(p.FailedResources) > 0 {
+		fmt.Printf("\nFailed resources (%d):\n", len(p.FailedResources))
+		for _, failed := range p.FailedResources {
+			resource := failed.Resource
+			if failed.Namespace != "" {
+				resource = failed.Namespace + "/" + resource
+			}
+			fmt.Printf("✗ %s: %s\n", resource, failed.Error)
+		}
+	}
+}
+
+// apiRestorer starts and follows restore runs through the REST API
+type apiRestorer struct{}
+
+func (apiRestorer) StartRestore(ctx context.Context, req api.RestoreRequest) (*api.Run, error) {
+	var run api.Run
+	if err := callAPI(ctx, http.MethodPost, "/restores", req, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (apiRestorer) RestoreProgress(ctx context.Context, runID string) (*api.RestoreRunProgress, error) {
+	var progress api.RestoreRunProgress
+	if err := callAPI(ctx, http.MethodGet, "/restores/"+runID+"/progress", nil, &progress); err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+func (apiRestorer) CancelRestore(ctx context.Context, runID string) error {
+	var run api.Run
+	return callAPI(ctx, http.MethodPost, "/restores/"+runID+"/cancel", nil, &run)
+}
+
+// browse reads backups from the bucket directly, so it works while the
+// backup service is down; only restores need its REST API
+func browse() {
+	ctx := context.Background()
+	browser, err := tui.NewBrowser(ctx, openManifestStore(), apiRestorer{})
+	if err != nil {
+		log.Fatalf("Failed to open backups: %v", err)
+	}
+	if err := tui.Run(ctx, browser, os.Stdin, os.Stdout); err != nil {
+		log.Fatalf("Failed to run the terminal UI: %v", err)
+	}
+}
+
+func checkRestoreTarget(backupID, output string) {
+	ctx := context.Background()
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	manifest, err := openManifestStore().Load(ctx, backupID)
+	if err != nil {
+		log.Fatalf("Failed to load backup %s: %v", backupID, err)
+	}
+
+	kubeConfig, err := rest.InClusterConfig()
+	if err != nil {
+		log.Fatalf("Failed to create Kubernetes config: %v", err)
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(kubeConfig)
+	if err != nil {
+		log.Fatalf("Failed to create discovery client: %v", err)
+	}
+	target, err := compat.DiscoverTarget(ctx, discoveryClient)
+	if err != nil {
+		log.Fatalf("Failed to discover the restore target: %v", err)
+	}
+
+	report := compat.Check(manifest, target, compat.Options{MinBackupVersion: cfg.RestoreMinBackupVersion})
+	switch output {
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to encode compatibility report: %v", err)
+		}
+		fmt.Println(string(data))
+	case "yaml":
+		data, err := yaml.Marshal(report)
+		if err != nil {
+			log.Fatalf("Failed to encode compatibility report: %v", err)
+		}
+		fmt.Print(string(data))
+	default:
+		printCompatibilityReport(report)
+	}
+	os.Exit(report.ExitCode())
+}
+
+func printCompatibilityReport(report *compat.Report) {
+	fmt.Printf("=== Restore Compatibility: %s ===\n", report.BackupID)
+	source := report.SourceVersion
+	if source == "" {
+		source = "not recorded"
+	}
+	fmt.Printf("Source Version:   %s\n", source)
+	fmt.Printf("Target Version:   %s\n", report.TargetVersion)
+	tool := "not recorded"
+	if report.Build != nil {
+		tool = report.Build.Version
+		if report.Build.Commit != "" {
+			tool += " (commit " + report.Build.Commit + ")"
+		}
+	}
+	fmt.Printf("Backup Tool:      %s\n", tool)
+	if !report.FeatureGatesChecked {
+		fmt.Println("Feature Gates:    not compared, the source or target did not report them")
+	}
+
+	for _, finding := range report.Errors {
+		fmt.Printf("❌ %s\n", formatCompatibilityFinding(finding))
+	}
+	for _, finding := range report.Warnings {
+		fmt.Printf("⚠️  %s\n", formatCompatibilityFinding(finding))
+	}
+
+	counts := make(map[string]int)
+	for _, check := range report.Types {
+		counts[check.Status]++
+	}
+	fmt.Printf("\nTypes: %d  Served: %d  Converted: %d  Installed by restore: %d  Missing: %d\n",
+		len(report.Types), counts[compat.TypeServed]+counts[compat.TypeAlternateVersion], counts[compat.TypeConverted],
+		counts[compat.TypeInstalledByRestore], counts[compat.TypeMissing])
+
+	switch report.ExitCode() {
+	case compat.CheckPassed:
+		fmt.Println("✅ Target compatible")
+	case compat.CheckWarnings:
+		fmt.Printf("⚠️  Target compatible with %d warning(s)\n", len(report.Warnings))
+	default:
+		fmt.Printf("❌ Target incompatible: %d error(s), %d warning(s)\n", len(report.Errors), len(report.Warnings))
+	}
+}
+
+func formatCompatibilityFinding(finding compat.Finding) string {
+	if finding.Resource == "" {
+		return fmt.Sprintf("%s: %s", finding.Check, finding.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", finding.Check, finding.Resource, finding.Message)
+}
+
+// checkClusterHealth runs the health checks, configured by the file at
+// configPath when it is set
+func checkClusterHealth(opts validation.Options, configPath, output string) {
+	if configPath != "" {
+		cfg, err := validation.LoadConfig(configPath)
+		if err != nil {
+			log.Fatalf("Failed to load health check configuration: %v", err)
+		}
+		if opts, err = cfg.Apply(opts); err != nil {
+			log.Fatalf("Failed to configure health checks: %v", err)
+		}
+	}
+
+	// Checks of a GitOps checkout or an export alone run without a cluster
+	if kubeConfig, err := rest.InClusterConfig(); err == nil {
+		if opts.KubeClient, err = kubernetes.NewForConfig(kubeConfig); err != nil {
+			log.Fatalf("Failed to create Kubernetes client: %v", err)
+		}
+		if opts.DynamicClient, err = dynamic.NewForConfig(kubeConfig); err != nil {
+			log.Fatalf("Failed to create dynamic client: %v", err)
+		}
+	}
+
+	results, err := validation.RunChecks(context.Background(), opts)
+	if err != nil {
+		log.Fatalf("Failed to run health checks: %v", err)
+	}
+	switch output {
+	case "json":
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to encode health check results: %v", err)
+		}
+		fmt.Println(string(data))
+	case "yaml":
+		data, err := yaml.Marshal(results)
+		if err != nil {
+			log.Fatalf("Failed to encode health check results: %v", err)
+		}
+		fmt.Print(string(data))
+	default:
+		printHealthResults(results)
+	}
+	os.Exit(results.ExitCode())
+}
+
+func printHealthResults(results validation.Results) {
+	fmt.Println("=== Cluster Health ===")
+	for _, check := range results.Checks {
+		icon := "✅"
+		switch check.Status {
+		case validation.StatusFailed:
+			icon = "❌"
+		case validation.StatusWarning:
+			icon = "⚠️ "
+		}
+		fmt.Printf("%s %s (%s, %s): %s\n", icon, check.Name, check.Category, check.Severity, check.Message)
+		if check.Status != validation.StatusPassed && check.Remediation != "" {
+			fmt.Printf("   %s\n", check.Remediation)
+		}
+	}
+
+	failed, warnings := results.Count(validation.StatusFailed), results.Count(validation.StatusWarning)
+	switch results.ExitCode() {
+	case validation.CheckPassed:
+		fmt.Printf("\n✅ All %d checks passed\n", len(results.Checks))
+	case validation.CheckWarnings:
+		fmt.Printf("\n⚠️  %d checks passed with %d warning(s)\n", len(results.Checks), warnings)
+	default:
+		fmt.Printf("\n❌ %d of %d checks failed, %d warning(s)\n", failed, len(results.Checks), warnings)
+	}
+}
+
+func migrateObjectPaths(dryRun bool) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	store := openManifestStore()
+	// Rewritten manifests are signed again like a run's
+	signer, err := backup.NewManifestSigner(cfg)
+	if err != nil {
+		log.Fatalf("Failed to configure manifest signing: %v", err)
+	}
+	if signer != nil {
+		store.SetSigner(signer)
+	}
+
+	migration, err := store.MigrateObjectPaths(context.Background(), cfg.ObjectPathVersion, dryRun)
+	if migration != nil {
+		verb := "Moved"
+		if dryRun {
+			verb = "Would move"
+		}
+		fmt.Printf("%s %d objects in %d runs\n", verb, migration.Objects, len(migration.Runs))
+		for _, run := range migration.Runs {
+			fmt.Printf("  %s\n", run)
+		}
+		for _, key := range migration.Skipped {
+			fmt.Printf("⚠️  %s: in a tenant's bucket, left in place\n", key)
+		}
+	}
+	if err != nil {
+		log.Fatalf("Failed to migrate object paths: %v", err)
+	}
+}
+
+func exportBackup(backupID, dest string) {
+	result, err := openManifestStore().Export(context.Background(), backupID, dest)
+	if err != nil {
+		log.Fatalf("Failed to export backup %s: %v", backupID, err)
+	}
+	fmt.Printf("Exported %d files (%s) of backup %s to %s\n", result.Files, formatSize(result.Bytes), result.BackupID, dest)
+	if result.Skipped > 0 {
+		fmt.Printf("⚠️  %d objects are in tenants' buckets and were left out\n", result.Skipped)
+	}
+}
+
+func searchBackups(backupID string, query []search.Term) {
+	ctx := context.Background()
+	store := openManifestStore()
+
+	backupIDs := []string{backupID}
+	if backupID == "" {
+		summaries, err := store.List(ctx)
+		if err != nil {
+			log.Fatalf("Failed to list backups: %v", err)
+		}
+		backupIDs = backupIDs[:0]
+		for _, summary := range summaries {
+			backupIDs = append(backupIDs, summary.BackupID)
+		}
+	}
+
+	found, runs, unindexed := 0, 0, 0
+	fmt.Printf("%-36s %-60s %s\n", "BACKUP", "RESOURCE", "KEY")
+	for _, id := range backupIDs {
+		manifest, err := store.Load(ctx, id)
+		if err != nil {
+			log.Fatalf("Failed to load backup %s: %v", id, err)
+		}
+		if manifest.Index == "" {
+			unindexed++
+			continue
+		}
+		index, err := store.LoadIndex(ctx, manifest)
+		if err != nil {
+			fmt.Printf("❌ %s: %v\n", id, err)
+			continue
+		}
+		documents := index.Search(query)
+		if len(documents) > 0 {
+			runs++
+		}
+		for _, doc := range documents {
+			namespace := doc.Namespace
+			if namespace == "" {
+				namespace = "(cluster-scoped)"
+			}
+			kind := doc.Kind
+			if kind == "" {
+				kind = doc.Resource
+			}
+			fmt.Printf("%-36s %-60s %s\n", id, namespace+"/"+kind+"/"+doc.Name, doc.Key)
+			found++
+		}
+	}
+	fmt.Printf("\n%d resources in %d of %d backups\n", found, runs, len(backupIDs))
+	if unindexed > 0 {
+		fmt.Printf("⚠️  %d backups have no search index, made before BACKUP_INDEX or with it off\n", unindexed)
+	}
+}
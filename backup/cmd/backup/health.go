@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"cluster-backup/internal/logging"
+	"cluster-backup/internal/validation"
+)
+
+// healthMonitor runs the cluster health checks of internal/validation on an
+// interval and exports their results on the metrics port
+type healthMonitor struct {
+	opts    validation.Options
+	metrics *validation.Metrics
+	logger  *logging.StructuredLogger
+}
+
+// newHealthMonitor returns a monitor of the cluster behind the clients,
+// configured by the check configuration at configPath when it is not empty,
+// whose metrics are registered with registerer
+func newHealthMonitor(kubeClient kubernetes.Interface, dynamicClient dynamic.Interface, configPath string, registerer prometheus.Registerer, logger *logging.StructuredLogger) (*healthMonitor, error) {
+	opts := validation.Options{
+		KubeClient:    kubeClient,
+		DynamicClient: dynamicClient,
+	}
+	if configPath != "" {
+		checks, err := validation.LoadConfig(configPath)
+		if err != nil {
+			return nil, err
+		}
+		if opts, err = checks.Apply(opts); err != nil {
+			return nil, err
+		}
+	}
+	return &healthMonitor{
+		opts:    opts,
+		metrics: validation.NewMetrics(registerer),
+		logger:  logger,
+	}, nil
+}
+
+// Run checks the cluster at once, then every interval until ctx is done
+func (m *healthMonitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check runs the checks once, records their results and logs the outcome
+func (m *healthMonitor) Check(ctx context.Context) {
+	results, err := validation.RunChecks(ctx, m.opts)
+	if err != nil {
+		m.logger.Error("cluster_health_check_failed", "Failed to run the cluster health checks", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	m.metrics.Record(results)
+
+	fields := map[string]interface{}{
+		"checks":   len(results.Checks),
+		"failed":   results.Count(validation.StatusFailed),
+		"warnings": results.Count(validation.StatusWarning),
+		"duration": results.Duration.String(),
+	}
+	if results.ExitCode() == validation.CheckFailed {
+		m.logger.Warning("cluster_health_failed", "Cluster health checks failed", fields)
+	} else {
+		m.logger.Info("cluster_health_checked", "Cluster health checks completed", fields)
+	}
+}
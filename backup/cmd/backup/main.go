@@ -2,28 +2,50 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
-	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/prometheus/client_golang/prometheus"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
+	"shared-config/eventbus"
+	"shared-config/mtls"
+	"shared-config/plugins"
+	"shared-config/restore"
+
+	"cluster-backup/internal/alerting"
+	"cluster-backup/internal/api"
+	"cluster-backup/internal/audit"
+	"cluster-backup/internal/auth"
 	"cluster-backup/internal/backup"
+	"cluster-backup/internal/buildinfo"
+	"cluster-backup/internal/compliance"
+	"cluster-backup/internal/cleaning"
 	"cluster-backup/internal/config"
+	"cluster-backup/internal/dashboard"
+	"cluster-backup/internal/diff"
+	"cluster-backup/internal/dump"
+	"cluster-backup/internal/etcd"
+	"cluster-backup/internal/filter"
+	"cluster-backup/internal/grpcapi"
+	"cluster-backup/internal/hooks"
+	"cluster-backup/internal/inventory"
 	"cluster-backup/internal/logging"
 	"cluster-backup/internal/metrics"
-)
-
-var (
-	version = "dev" // Set by build process
+	"cluster-backup/internal/policy"
+	"cluster-backup/internal/priority"
+	"cluster-backup/internal/server"
+	"cluster-backup/internal/storage"
+	"cluster-backup/internal/tenancy"
 )
 
 func main() {
@@ -31,11 +53,13 @@ func main() {
 		showVersion  = flag.Bool("version", false, "Show version and exit")
 		healthCheck  = flag.Bool("health-check", false, "Run health check and exit")
 		dryRun       = flag.Bool("dry-run", false, "Perform a dry run without making changes")
+		driftMode    = flag.Bool("drift", false, "Compare the live cluster against the latest backup and exit")
 	)
 	flag.Parse()
 
 	if *showVersion {
-		fmt.Printf("backup version %s\n", version)
+		build := buildinfo.Get()
+		fmt.Printf("backup version %s (commit %s, %s)\n", build.Version, build.Commit, build.GoVersion)
 		os.Exit(0)
 	}
 
@@ -48,30 +72,54 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Load configuration
-	cfg, err := config.LoadConfig()
+	// Load configuration from the shared file every component reads, with
+	// environment variables as overrides
+	cfg, backupCfg, err := loadConfig()
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	backupCfg, err := config.LoadBackupConfig()
-	if err != nil {
-		log.Fatalf("Failed to load backup configuration: %v", err)
-	}
-
 	// Initialize logger
 	logger := logging.NewStructuredLogger("backup", cfg.ClusterName)
+	build := buildinfo.Get()
 	
 	if *dryRun {
 		logger.Info("startup", "Starting backup in dry-run mode", map[string]interface{}{
-			"version": version,
-			"cluster": cfg.ClusterName,
+			"version":      build.Version,
+			"commit":       build.Commit,
+			"image_digest": build.ImageDigest,
+			"cluster":      cfg.ClusterName,
 		})
 	} else {
 		logger.Info("startup", "Starting cluster backup service", map[string]interface{}{
-			"version": version,
-			"cluster": cfg.ClusterName,
-			"bucket":  cfg.MinIOBucket,
+			"version":      build.Version,
+			"commit":       build.Commit,
+			"image_digest": build.ImageDigest,
+			"cluster":      cfg.ClusterName,
+			"bucket":       cfg.MinIOBucket,
+		})
+	}
+
+	// Lists the filtering mode ignores are not fatal, but usually a mistake
+	filterWarnings, _ := filter.Validate(backupCfg.FilterRules())
+	for _, warning := range filterWarnings {
+		logger.Warning("filter_config_warning", warning, map[string]interface{}{
+			"filtering_mode": backupCfg.FilteringMode,
+		})
+	}
+
+	// In FIPS mode a process that cannot use approved cryptography only stops
+	// before it connects anywhere
+	if err := compliance.Enforce(cfg); err != nil {
+		logger.Error("compliance_check_failed", "Compliance self-check failed", map[string]interface{}{
+			"mode":  cfg.ComplianceMode,
+			"error": err.Error(),
+		})
+		os.Exit(1)
+	}
+	if compliance.Enabled(cfg) {
+		logger.Info("compliance_mode", "Restricting TLS to FIPS-approved settings", map[string]interface{}{
+			"mode": cfg.ComplianceMode,
 		})
 	}
 
@@ -79,18 +127,12 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Handle signals for graceful shutdown
-	sigChan := make(chan os.Signal, 1)
+	// Handle signals for graceful shutdown once the backup is set up
+	sigChan := make(chan os.Signal, 2)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		sig := <-sigChan
-		logger.Info("shutdown", "Received signal, initiating graceful shutdown", map[string]interface{}{
-			"signal": sig.String(),
-		})
-		cancel()
-	}()
 
-	// Initialize Kubernetes clients
+	// Initialize Kubernetes clients. In-cluster clients re-read the projected
+	// service account token as the kubelet rotates it.
 	kubeConfig, err := rest.InClusterConfig()
 	if err != nil {
 		logger.Error("kubernetes_config_failed", "Failed to create Kubernetes config", map[string]interface{}{
@@ -123,11 +165,29 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Initialize MinIO client
-	minioClient, err := minio.New(cfg.MinIOEndpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(cfg.MinIOAccessKey, cfg.MinIOSecretKey, ""),
-		Secure: cfg.MinIOUseSSL,
-	})
+	// Initialize MinIO client. Rotated keys and certificates are picked up
+	// mid-run, which matters most in REST API mode where the process runs
+	// for days.
+	minioTransport, err := storage.NewReloadingTransport(cfg)
+	if err != nil {
+		logger.Error("minio_transport_failed", "Failed to create MinIO transport", map[string]interface{}{
+			"error": err.Error(),
+		})
+		os.Exit(1)
+	}
+	minioTransport.OnRotate = func(source string, err error) {
+		if err != nil {
+			logger.Error("credentials_reload_failed", "Failed to load rotated MinIO credentials, keeping the previous ones", map[string]interface{}{
+				"source": source,
+				"error":  err.Error(),
+			})
+			return
+		}
+		logger.Info("credentials_rotated", "Loaded rotated MinIO credentials", map[string]interface{}{
+			"source": source,
+		})
+	}
+	minioClient, err := storage.NewClient(cfg, minioTransport)
 	if err != nil {
 		logger.Error("minio_client_failed", "Failed to create MinIO client", map[string]interface{}{
 			"error":    err.Error(),
@@ -152,13 +212,306 @@ func main() {
 		ctx,
 	)
 
+	// The first signal drains the running backup; the context, and with it
+	// any upload still in flight, is cancelled at the end of the grace period
+	// or on a second signal
+	go func() {
+		sig := <-sigChan
+		logger.Info("shutdown", "Received signal, initiating graceful shutdown", map[string]interface{}{
+			"signal":       sig.String(),
+			"grace_period": cfg.ShutdownGracePeriod.String(),
+		})
+		drained := make(chan bool, 1)
+		go func() {
+			drained <- clusterBackup.Drain(cfg.ShutdownGracePeriod)
+		}()
+		select {
+		case ok := <-drained:
+			if !ok {
+				logger.Warning("shutdown_grace_expired", "Grace period expired, cancelling in-flight uploads", nil)
+			}
+		case sig := <-sigChan:
+			logger.Warning("shutdown_forced", "Received second signal, cancelling in-flight uploads", map[string]interface{}{
+				"signal": sig.String(),
+			})
+		}
+		cancel()
+	}()
+
+	podExecutor := hooks.NewPodExecutor(kubeConfig, kubeClient)
+	if cfg.HooksEnabled {
+		clusterBackup.SetHookRunner(hooks.NewRunner(kubeClient, podExecutor, cfg.HookTimeout, logger))
+	}
+
+	if len(cfg.DumpPlugins) > 0 {
+		var plugins []dump.Plugin
+		for _, name := range cfg.DumpPlugins {
+			plugin, err := dump.Builtin(name, "")
+			if err != nil {
+				logger.Error("dump_plugin_failed", "Failed to configure database dump plugin", map[string]interface{}{
+					"plugin": name,
+					"error":  err.Error(),
+				})
+				os.Exit(1)
+			}
+			plugins = append(plugins, plugin)
+		}
+		clusterBackup.SetDumper(dump.NewDumper(kubeClient, podExecutor, plugins, cfg.DumpTimeout, logger))
+	}
+
+	if cfg.PolicyURL != "" {
+		clusterBackup.SetPolicyEvaluator(policy.NewEvaluator(cfg.PolicyURL, cfg.PolicyTimeout))
+	}
+
+	if cfg.EtcdSnapshotEnabled {
+		clusterBackup.SetEtcdSnapshotter(etcd.NewSnapshotter(etcd.SnapshotConfig{
+			Endpoints:   cfg.EtcdEndpoints,
+			CACert:      cfg.EtcdCACert,
+			Cert:        cfg.EtcdCert,
+			Key:         cfg.EtcdKey,
+			EtcdctlPath: cfg.EtcdctlPath,
+			Timeout:     cfg.EtcdSnapshotTimeout,
+		}, logger))
+	}
+
+	manifestSigner, err := backup.NewManifestSigner(cfg)
+	if err != nil {
+		logger.Error("manifest_signer_failed", "Failed to configure manifest signing", map[string]interface{}{
+			"mode":  cfg.ManifestSigning,
+			"error": err.Error(),
+		})
+		os.Exit(1)
+	}
+	if manifestSigner != nil {
+		clusterBackup.SetManifestSigner(manifestSigner)
+	}
+
+	if alerter := alerting.NewFromConfig(cfg); alerter != nil {
+		clusterBackup.SetAlerter(alerter)
+	}
+
+	// Without its routing a tenant's namespaces would land in the default
+	// bucket, where the tenant cannot reach them and other teams can
+	if cfg.TenancyConfigMap != "" {
+		router, err := tenancy.Load(ctx, kubeClient, cfg.TenancyNamespace, cfg.TenancyConfigMap, cfg, minioTransport,
+			&tenancy.Target{Client: minioClient, Bucket: cfg.MinIOBucket})
+		if err != nil {
+			logger.Error("tenancy_load_failed", "Failed to load the tenancy configuration", map[string]interface{}{
+				"configmap": cfg.TenancyNamespace + "/" + cfg.TenancyConfigMap,
+				"error":     err.Error(),
+			})
+			os.Exit(1)
+		}
+		clusterBackup.SetTenancy(router)
+	}
+
+	// Profiles that fail to load would leave cluster-assigned fields in the
+	// manifests, which then fail to apply on restore
+	if cfg.CleaningProfilesConfigMap != "" {
+		profiles, err := cleaning.Load(ctx, kubeClient, cfg.CleaningProfilesNamespace, cfg.CleaningProfilesConfigMap)
+		if err != nil {
+			logger.Error("cleaning_profiles_load_failed", "Failed to load the cleaning profiles", map[string]interface{}{
+				"configmap": cfg.CleaningProfilesNamespace + "/" + cfg.CleaningProfilesConfigMap,
+				"error":     err.Error(),
+			})
+			os.Exit(1)
+		}
+		clusterBackup.SetCleaningProfiles(profiles)
+	}
+
+	// Without its inventory a run would not notice coming back short
+	if cfg.InventoryConfigMap != "" {
+		expected, err := inventory.Load(ctx, kubeClient, cfg.InventoryNamespace, cfg.InventoryConfigMap)
+		if err != nil {
+			logger.Error("inventory_load_failed", "Failed to load the expected inventory", map[string]interface{}{
+				"configmap": cfg.InventoryNamespace + "/" + cfg.InventoryConfigMap,
+				"error":     err.Error(),
+			})
+			os.Exit(1)
+		}
+		clusterBackup.SetInventory(expected)
+	}
+
+	// Resource types may be listed in pages of their own size, set in the
+	// priority ConfigMap; without it BATCH_SIZE applies to all
+	priorities := priority.NewManager(kubeClient, priority.DefaultConfigMap, priority.DefaultNamespace)
+	if err := priorities.LoadConfig(); err != nil {
+		logger.Warning("priority_config_load_failed", "Failed to load priority configuration, using defaults", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	clusterBackup.SetBatchSizes(priorities)
+
+	// Site rules in plugins may keep resources out of the backup or rewrite
+	// them, so a backup does not run without them
+	if len(cfg.ResourcePlugins) > 0 {
+		chain, err := plugins.Open(cfg.ResourcePlugins, cfg.ResourcePluginOptions)
+		if err != nil {
+			logger.Error("resource_plugins_load_failed", "Failed to load the resource plugins", map[string]interface{}{
+				"plugins": cfg.ResourcePlugins,
+				"error":   err.Error(),
+			})
+			os.Exit(1)
+		}
+		logger.Info("resource_plugins_loaded", "Loaded resource plugins", map[string]interface{}{
+			"plugins": chain.Names(),
+		})
+		clusterBackup.SetPlugins(chain)
+	}
+
+	// Every upload goes to the secondary targets too, so one that cannot be
+	// set up is a configuration error rather than something to skip
+	for _, target := range cfg.SecondaryTargets {
+		client, err := storage.NewTargetClient(cfg, target, minioTransport)
+		if err != nil {
+			logger.Error("secondary_target_failed", "Failed to create the client of a secondary storage target", map[string]interface{}{
+				"target":   target.Name,
+				"endpoint": target.Endpoint,
+				"error":    err.Error(),
+			})
+			os.Exit(1)
+		}
+		clusterBackup.AddSecondaryTarget(target.Name, client, target.Bucket)
+	}
+
+	// Replication verification only reports, so a replica that cannot be
+	// reached leaves the backup running without it
+	if cfg.ReplicaEndpoint != "" {
+		replicaClient, err := storage.NewReplicaClient(cfg, minioTransport)
+		if err != nil {
+			logger.Warning("replica_unavailable", "Failed to create the replica client, replication will not be verified", map[string]interface{}{
+				"endpoint": cfg.ReplicaEndpoint,
+				"error":    err.Error(),
+			})
+		} else {
+			clusterBackup.SetReplica(replicaClient)
+		}
+	}
+
+	// Lifecycle events are informational, so a broker that cannot be reached
+	// leaves the backup running without them
+	if cfg.EventBus != "" {
+		bus, err := eventbus.New(eventbus.Config{
+			Provider:    cfg.EventBus,
+			URL:         cfg.EventBusURL,
+			TopicPrefix: cfg.EventBusTopicPrefix,
+			Token:       cfg.EventBusToken,
+			Username:    cfg.EventBusUsername,
+			Password:    cfg.EventBusPassword,
+			ClientName:  "cluster-backup-" + cfg.ClusterName,
+		})
+		if err != nil {
+			logger.Warning("event_bus_unavailable", "Failed to connect to the event bus, lifecycle events will not be published", map[string]interface{}{
+				"provider": cfg.EventBus,
+				"url":      cfg.EventBusURL,
+				"error":    err.Error(),
+			})
+		} else {
+			defer bus.Close()
+			clusterBackup.SetEventBus(bus)
+		}
+	}
+
+	// Audit records are kept apart from the logs, in a bucket of their own
+	// and/or the SIEM's collector
+	if len(cfg.AuditSinks) > 0 {
+		var sinks []audit.Sink
+		for _, sink := range cfg.AuditSinks {
+			switch sink {
+			case config.AuditSinkBucket:
+				sinks = append(sinks, audit.NewBucketSink(minioClient, cfg.AuditBucket, func(r audit.Record) string {
+					return backup.OperationAuditPath(cfg.ClusterDomain, cfg.ClusterName, r.Time, r.ID)
+				}))
+			case config.AuditSinkWebhook:
+				sinks = append(sinks, audit.NewWebhookSink(cfg.AuditWebhookURL, cfg.AuditWebhookToken))
+			}
+		}
+		clusterBackup.SetAuditLog(audit.NewLog(cfg.ClusterName, logger, backupMetrics.AuditFailures, sinks...))
+	}
+
 	if *dryRun {
 		logger.Info("dry_run_complete", "Dry run completed successfully", nil)
 		os.Exit(0)
 	}
 
+	if *driftMode {
+		drifted, err := runDriftCheck(ctx, clusterBackup, backupMetrics, logger)
+		if err != nil {
+			logger.Error("drift_check_failed", "Drift check failed", map[string]interface{}{
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+		if drifted {
+			os.Exit(2)
+		}
+		return
+	}
+
+	// In continuous mode the live copies are kept fresh until shutdown,
+	// alongside the REST API when it is enabled
+	if cfg.ContinuousBackup {
+		runContinuous := func() error {
+			err := clusterBackup.RunContinuous(ctx)
+			if err != nil {
+				logger.Error("continuous_backup_failed", "Continuous backup failed", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+			return err
+		}
+		if cfg.RestAPIEnabled {
+			go runContinuous()
+		} else {
+			if err := runContinuous(); err != nil {
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	// In REST API mode, backups are triggered on demand instead of once at startup
+	if cfg.RestAPIEnabled {
+		// The process runs for days, so changes to the mounted configuration
+		// file apply to the next runs without a restart
+		if path, err := config.SharedConfigPath(); err == nil && path != "" && cfg.ConfigReload {
+			reloader := backup.NewConfigReloader(clusterBackup, path, loadConfig)
+			go reloader.Run(ctx, cfg.ConfigReloadInterval)
+		}
+		// The cluster's health is exported alongside the backup metrics, with
+		// the checks configured as for backup-util cluster-health
+		if cfg.HealthCheckInterval > 0 {
+			health, err := newHealthMonitor(kubeClient, dynamicClient, os.Getenv("VALIDATION_CONFIG"), prometheus.DefaultRegisterer, logger)
+			if err != nil {
+				logger.Error("health_checks_config_failed", "Failed to load the cluster health check configuration", map[string]interface{}{
+					"error": err.Error(),
+				})
+				os.Exit(1)
+			}
+			go health.Run(ctx, cfg.HealthCheckInterval)
+		}
+		restoreEngine, err := newRestoreEngine(cfg, minioClient)
+		if err != nil {
+			logger.Error("restore_engine_failed", "Failed to create restore engine", map[string]interface{}{
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+		err = runAPIServer(ctx, cfg, clusterBackup, restoreEngine, logger)
+		// Let a run cancelled by the shutdown write its checkpoint
+		clusterBackup.Drain(cfg.ShutdownGracePeriod)
+		if err != nil {
+			logger.Error("api_server_failed", "REST API server failed", map[string]interface{}{
+				"error": err.Error(),
+			})
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Execute backup
 	result, err := clusterBackup.ExecuteBackup()
+	clusterBackup.AuditLog().Record(audit.WithActor(ctx, audit.SystemActor("schedule")), backup.AuditRecord(result, err))
 	if err != nil {
 		logger.Error("backup_failed", "Backup operation failed", map[string]interface{}{
 			"error": err.Error(),
@@ -167,10 +520,11 @@ func main() {
 	}
 
 	logger.Info("backup_success", "Backup operation completed successfully", map[string]interface{}{
-		"namespaces_backed_up": result.NamespacesBackedUp,
-		"resources_backed_up":  result.ResourcesBackedUp,
-		"duration_seconds":     result.Duration.Seconds(),
-		"error_count":          len(result.Errors),
+		"namespaces_backed_up":        result.NamespacesBackedUp,
+		"resources_backed_up":         result.ResourcesBackedUp,
+		"cluster_resources_backed_up": result.ClusterResourcesBackedUp,
+		"duration_seconds":            result.Duration.Seconds(),
+		"error_count":                 len(result.Errors),
 	})
 
 	if len(result.Errors) > 0 {
@@ -182,6 +536,200 @@ func main() {
 	}
 }
 
+// loadConfig reads the shared configuration file, when there is one, and
+// the environment
+func loadConfig() (*config.Config, *config.BackupConfig, error) {
+	shared, err := config.LoadSharedConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg, err := config.LoadConfigFrom(shared)
+	if err != nil {
+		return nil, nil, err
+	}
+	backupCfg, err := config.LoadBackupConfigFrom(shared)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load backup configuration: %v", err)
+	}
+	return cfg, backupCfg, nil
+}
+
+// runAPIServer serves the REST and gRPC APIs, plus metrics and the optional
+// UI dashboard, until the context is cancelled. Restores run on
+// restoreEngine.
+func runAPIServer(ctx context.Context, cfg *config.Config, clusterBackup *backup.ClusterBackup, restoreEngine *restore.RestoreEngine, logger *logging.StructuredLogger) error {
+	// API_TOKEN stays the admin's, so it keeps every right it had before
+	// roles; OIDC callers get the role their groups grant
+	var oidc *auth.OIDCVerifier
+	if cfg.APIOIDCIssuer != "" {
+		oidc = auth.NewOIDCVerifier(auth.OIDCConfig{
+			Issuer:     cfg.APIOIDCIssuer,
+			Audience:   cfg.APIOIDCAudience,
+			RolesClaim: cfg.APIOIDCRolesClaim,
+			Roles: map[auth.Role][]string{
+				auth.RoleAdmin:    cfg.APIOIDCAdminGroups,
+				auth.RoleOperator: cfg.APIOIDCOperatorGroups,
+				auth.RoleViewer:   cfg.APIOIDCViewerGroups,
+				auth.RoleApprover: cfg.APIOIDCApproverGroups,
+			},
+		}, nil)
+	}
+	authenticator := auth.NewAuthenticator(map[auth.Role]string{
+		auth.RoleAdmin:    cfg.APIToken,
+		auth.RoleOperator: cfg.APIOperatorToken,
+		auth.RoleViewer:   cfg.APIViewerToken,
+		auth.RoleApprover: cfg.APIApproverToken,
+	}, oidc)
+
+	serverCfg := api.ServerConfig{
+		Port:          cfg.APIPort,
+		Auth:          authenticator,
+		ProtectHealth: cfg.APIAuthEnabled,
+	}
+	// Both APIs serve the same certificates, read again when they are
+	// rotated; with a CA, clients must present a certificate it signed
+	if cfg.APITLSCertFile != "" {
+		certificates, err := mtls.Load(mtls.Files{
+			CertFile: cfg.APITLSCertFile,
+			KeyFile:  cfg.APITLSKeyFile,
+			CAFile:   cfg.APITLSCAFile,
+		}, cfg.CredentialsRefreshInterval)
+		if err != nil {
+			return fmt.Errorf("failed to load API TLS certificates: %v", err)
+		}
+		certificates.OnReload = func(err error) {
+			if err != nil {
+				logger.Warning("api_tls_reload_failed", "Failed to reload API TLS certificates, keeping the previous ones", map[string]interface{}{
+					"error": err.Error(),
+				})
+				return
+			}
+			logger.Info("api_tls_reloaded", "Reloaded API TLS certificates", nil)
+		}
+		if compliance.Enabled(cfg) {
+			certificates.Configure = compliance.Restrict
+		}
+		serverCfg.TLS = certificates
+	}
+
+	// Both APIs share run tracking and progress so either can query runs started by the other
+	runs := api.NewRunRegistry(100)
+	runs.SetAuditLog(clusterBackup.AuditLog())
+	if cfg.RestoreApprovalRequired() {
+		runs.RequireRestoreApproval(cfg.RestoreApprovalTimeout)
+		logger.Info("restore_approval_required", "Restores of this cluster need a second person's approval", map[string]interface{}{
+			"selector": cfg.RestoreApprovalSelector,
+			"timeout":  cfg.RestoreApprovalTimeout.String(),
+		})
+	}
+	progress := grpcapi.NewProgressHub()
+	backupFn := func(ctx context.Context) (*backup.BackupResult, error) {
+		return clusterBackup.ExecuteBackupWithProgress(api.RecordProgress(ctx, runs, progress.Reporter(api.RunIDFromContext(ctx))))
+	}
+
+	restoreFn := restoreFunc(restoreEngine, cfg.ClusterName, runs)
+
+	restServer := api.NewServer(ctx, serverCfg, runs, backupFn, restoreFn, logger)
+	if cfg.DiscoveryCacheTTL > 0 {
+		restServer.SetDiscoveryCacheInvalidator(clusterBackup.InvalidateDiscoveryCache)
+	}
+
+	grpcCfg := serverCfg
+	grpcCfg.Port = cfg.GRPCPort
+	grpcServer, err := grpcapi.NewServer(ctx, grpcCfg, runs, progress, backupFn, restoreFn, logger)
+	if err != nil {
+		return err
+	}
+
+	// The time since the last successful backup keeps growing between runs
+	if monitor := clusterBackup.RPOMonitor(); monitor != nil {
+		go monitor.Run(ctx, cfg.RPOCheckInterval)
+	}
+	if verifier := clusterBackup.ReplicationVerifier(); verifier != nil {
+		go verifier.Run(ctx, cfg.ReplicaCheckInterval)
+	}
+
+	metricsServer := server.NewMetricsServer(cfg.MetricsPort, logger)
+	metricsServer.SetCircuitBreakers(clusterBackup.CircuitBreakerStats)
+	if cfg.APIAuthEnabled {
+		metricsServer.RequireAuth(authenticator)
+	}
+	if cfg.UIDashboardEnabled {
+		dash := dashboard.NewDashboard(ctx, authenticator, runs, clusterBackup.ManifestStore(), backupFn, restoreFn, logger)
+		metricsServer.Handle(dashboard.PathPrefix, dash.Handler())
+	}
+	if cfg.PprofEnabled {
+		metricsServer.EnablePprof(authenticator)
+	}
+
+	restErrChan := restServer.StartAsync()
+	grpcErrChan := grpcServer.StartAsync()
+	metricsErrChan := metricsServer.StartAsync()
+
+	var serveErr error
+	select {
+	case serveErr = <-restErrChan:
+	case serveErr = <-grpcErrChan:
+	case serveErr = <-metricsErrChan:
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := restServer.Stop(shutdownCtx); err != nil && serveErr == nil {
+		serveErr = err
+	}
+	if err := grpcServer.Stop(shutdownCtx); err != nil && serveErr == nil {
+		serveErr = err
+	}
+	if err := metricsServer.Stop(shutdownCtx); err != nil && serveErr == nil {
+		serveErr = err
+	}
+	return serveErr
+}
+
+// runDriftCheck compares the live cluster with the latest backup, writes the
+// drift report to stdout as JSON and reports whether any resource drifted
+func runDriftCheck(ctx context.Context, clusterBackup *backup.ClusterBackup, backupMetrics *metrics.BackupMetrics, logger *logging.StructuredLogger) (bool, error) {
+	latest, err := clusterBackup.ManifestStore().Latest(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to load latest backup: %v", err)
+	}
+
+	live, err := clusterBackup.SnapshotLive()
+	if err != nil {
+		return false, fmt.Errorf("failed to snapshot live cluster: %v", err)
+	}
+
+	report, warnings := diff.CompareLive(ctx, clusterBackup.ManifestStore(), latest, live)
+	for _, warning := range warnings {
+		logger.Warning("drift_check_warning", "Drift check incomplete for resource", map[string]interface{}{
+			"warning": warning.Error(),
+		})
+	}
+
+	added, removed, changed := report.Counts()
+	backupMetrics.DriftedResources.WithLabelValues(string(diff.ChangeAdded)).Set(float64(added))
+	backupMetrics.DriftedResources.WithLabelValues(string(diff.ChangeRemoved)).Set(float64(removed))
+	backupMetrics.DriftedResources.WithLabelValues(string(diff.ChangeChanged)).Set(float64(changed))
+
+	logger.Info("drift_check_complete", "Compared live cluster with latest backup", map[string]interface{}{
+		"backup_id": latest.BackupID,
+		"added":     added,
+		"removed":   removed,
+		"changed":   changed,
+		"unchanged": report.Unchanged,
+	})
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return false, fmt.Errorf("failed to write drift report: %v", err)
+	}
+
+	return len(report.Changes) > 0, nil
+}
+
 // performHealthCheck performs a basic health check
 func performHealthCheck() error {
 	// Load configuration to verify it's valid
@@ -191,10 +739,11 @@ func performHealthCheck() error {
 	}
 
 	// Test MinIO connectivity
-	minioClient, err := minio.New(cfg.MinIOEndpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(cfg.MinIOAccessKey, cfg.MinIOSecretKey, ""),
-		Secure: cfg.MinIOUseSSL,
-	})
+	minioTransport, err := storage.NewTransport(cfg)
+	if err != nil {
+		return fmt.Errorf("MinIO client creation failed: %v", err)
+	}
+	minioClient, err := storage.NewClient(cfg, minioTransport)
 	if err != nil {
 		return fmt.Errorf("MinIO client creation failed: %v", err)
 	}
@@ -202,6 +751,9 @@ func performHealthCheck() error {
 	ctx := context.Background()
 	_, err = minioClient.BucketExists(ctx, cfg.MinIOBucket)
 	if err != nil {
+		if reason := storage.CertificateError(err); reason != "" {
+			return fmt.Errorf("MinIO connectivity test failed: %s: %v", reason, err)
+		}
 		return fmt.Errorf("MinIO connectivity test failed: %v", err)
 	}
 
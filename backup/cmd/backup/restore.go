@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	sharedconfig "shared-config/config"
+	"shared-config/restore"
+
+	"cluster-backup/internal/api"
+	"cluster-backup/internal/config"
+)
+
+// restoreProgressInterval is how often a restore run's progress is updated
+// from its restore operation
+const restoreProgressInterval = time.Second
+
+// newRestoreEngine creates the engine the APIs restore backups of this
+// cluster with. It reads them with minioClient, the client backups are
+// written with, from the bucket and cluster the environment may override in
+// the shared configuration. When restores of the cluster need approval, the
+// engine starts only those approved through the APIs.
+func newRestoreEngine(cfg *config.Config, minioClient *minio.Client) (*restore.RestoreEngine, error) {
+	shared, err := config.LoadSharedConfig()
+	if err != nil {
+		return nil, err
+	}
+	if shared == nil {
+		shared = &sharedconfig.SharedConfig{}
+	}
+	shared.Cluster.Name = cfg.ClusterName
+	shared.Cluster.Domain = cfg.ClusterDomain
+	// The engine reads with minioClient rather than a client of its own
+	shared.Storage = sharedconfig.StorageConfig{Bucket: cfg.MinIOBucket}
+
+	engine, err := restore.NewRestoreEngine(shared, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create restore engine: %v", err)
+	}
+	engine.SetObjectStore(restore.NewMinIOStore(minioClient))
+	// The engine refuses restores the API run registry did not have approved
+	engine.RequireApproval(cfg.RestoreApprovalRequired())
+	return engine, nil
+}
+
+// restoreFunc returns the function API restore runs execute with. Each run
+// restores on engine as a restore with the run's ID, keeping the restore's
+// progress in runs, and cancelling the run cancels it. Resources that already
+// exist are left as they are. The run's approval, if any, is passed on to the
+// engine.
+func restoreFunc(engine *restore.RestoreEngine, clusterName string, runs *api.RunRegistry) api.RestoreFunc {
+	return func(ctx context.Context, req api.RestoreRequest) (interface{}, error) {
+		request := restore.RestoreRequest{
+			RestoreID:         api.RunIDFromContext(ctx),
+			BackupID:          req.BackupID,
+			ClusterName:       clusterName,
+			TargetNamespaces:  req.TargetNamespaces,
+			ResourceTypes:     req.ResourceTypes,
+			RestoreMode:       restore.RestoreModeComplete,
+			ValidationMode:    restore.ValidationModeStrict,
+			ConflictStrategy:  restore.ConflictStrategySkip,
+			DryRun:            req.DryRun,
+			ForceUnsafe:       req.ForceUnsafe,
+			ConfirmationToken: req.ConfirmationToken,
+		}
+		if len(req.TargetNamespaces) > 0 || len(req.ResourceTypes) > 0 {
+			request.RestoreMode = restore.RestoreModeSelective
+		}
+		if approval := api.ApprovalFromContext(ctx); approval != nil {
+			request.Approval = &restore.RestoreApproval{
+				RequestedBy: approval.RequestedBy.ID,
+				ApprovedBy:  approval.ApprovedBy.ID,
+				ApprovedAt:  *approval.ApprovedAt,
+			}
+		}
+
+		operation, err := engine.StartRestore(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+
+		record := api.RecordRestoreProgress(ctx, runs)
+		ticker := time.NewTicker(restoreProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-operation.Done():
+				record(restoreProgress(operation.Progress, operation.Results.FailedResources))
+				return restoreResult(operation)
+			case <-ticker.C:
+				if report, err := engine.GetRestoreProgress(request.RestoreID); err == nil {
+					record(restoreProgress(report.Progress, report.FailedResources))
+				}
+			}
+		}
+	}
+}
+
+// restoreProgress converts the progress of a restore operation into that of
+// its run
+func restoreProgress(progress restore.RestoreProgress, failed []restore.FailedResource) api.RestoreProgress {
+	runProgress := api.RestoreProgress{
+		Phase:           string(progress.Phase),
+		Percent:         progress.PercentComplete,
+		ResourcesTotal:  progress.TotalResources,
+		ResourcesDone:   progress.ProcessedResources,
+		CurrentResource: progress.CurrentResource,
+	}
+	for _, resource := range failed {
+		runProgress.FailedResources = append(runProgress.FailedResources, api.FailedResource{
+			Resource:  fmt.Sprintf("%s/%s", resource.Kind, resource.Name),
+			Namespace: resource.Namespace,
+			Error:     resource.Error,
+		})
+	}
+	return runProgress
+}
+
+// restoreResult returns the results of a finished restore operation, with
+// the error that ended it unless it completed
+func restoreResult(operation *restore.RestoreOperation) (interface{}, error) {
+	if operation.Status == restore.RestoreStatusCompleted {
+		return &operation.Results, nil
+	}
+	if len(operation.Errors) == 0 {
+		return &operation.Results, fmt.Errorf("restore %s", operation.Status)
+	}
+	return &operation.Results, fmt.Errorf("restore %s: %s", operation.Status, operation.Errors[len(operation.Errors)-1].Message)
+}
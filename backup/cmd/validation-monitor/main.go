@@ -0,0 +1,399 @@
+// Command validation-monitor runs the health checks of internal/validation
+// on an interval, for the production simulation, and serves their results:
+// the latest results on /validation-results and /status, whether a critical
+// check fails on /health, and the checks' metrics on /metrics, registered
+// with the default registry as the backup service does.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"cluster-backup/internal/logging"
+	"cluster-backup/internal/validation"
+)
+
+// maxResults bounds the results kept for the results and status endpoints
+const maxResults = 1000
+
+// monitorConfig is the configuration file of the validation monitor,
+// validation-config.yaml in the production simulation
+type monitorConfig struct {
+	ClusterName         string        `yaml:"cluster_name" json:"cluster_name"`
+	BackupLocation      string        `yaml:"backup_location" json:"backup_location"`
+	GitOpsRepoPath      string        `yaml:"gitops_repo_path" json:"gitops_repo_path"`
+	MetricsPort         int           `yaml:"metrics_port" json:"metrics_port"`
+	HealthCheckInterval time.Duration `yaml:"health_check_interval" json:"health_check_interval"`
+	// Validations enable the checks of groups of categories
+	Validations struct {
+		KubernetesValidation bool `yaml:"kubernetes_validation" json:"kubernetes_validation"`
+		GitOpsValidation     bool `yaml:"gitops_validation" json:"gitops_validation"`
+		DataIntegrity        bool `yaml:"data_integrity" json:"data_integrity"`
+		YAMLSyntax           bool `yaml:"yaml_syntax" json:"yaml_syntax"`
+		Performance          bool `yaml:"performance" json:"performance"`
+		Security             bool `yaml:"security" json:"security"`
+	} `yaml:"validations" json:"validations"`
+	// Config holds the checks, custom_checks and thresholds keys, as
+	// backup-util cluster-health --config reads them
+	validation.Config `yaml:",inline"`
+}
+
+// loadConfig reads the configuration at path over the defaults, which are
+// used alone when path is empty or does not exist
+func loadConfig(path string) (*monitorConfig, error) {
+	cfg := &monitorConfig{
+		ClusterName:         "crc",
+		BackupLocation:      "./backup-source",
+		GitOpsRepoPath:      "./",
+		MetricsPort:         8080,
+		HealthCheckInterval: 30 * time.Second,
+	}
+	cfg.Validations.KubernetesValidation = true
+	cfg.Validations.GitOpsValidation = true
+	cfg.Validations.DataIntegrity = true
+	cfg.Validations.YAMLSyntax = true
+	cfg.Validations.Performance = true
+	cfg.Validations.Security = true
+	cfg.Thresholds.CPUThreshold = validation.DefaultUsageThreshold
+	cfg.Thresholds.MemoryThreshold = validation.DefaultUsageThreshold
+	cfg.Thresholds.ResponseTime = validation.DefaultMaxAPILatency
+
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		log.Printf("Config file %s not found, using defaults", path)
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	if cfg.HealthCheckInterval <= 0 {
+		return nil, fmt.Errorf("health_check_interval must be positive")
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// categories returns the check categories the validations enable. Custom
+// checks have no switch and are disabled through their settings.
+func (cfg *monitorConfig) categories() []string {
+	categories := []string{validation.CategoryCustom}
+	if cfg.Validations.KubernetesValidation {
+		categories = append(categories, validation.CategoryInfrastructure, validation.CategoryWorkloads,
+			validation.CategoryResources, validation.CategoryStorage)
+	}
+	if cfg.Validations.GitOpsValidation {
+		categories = append(categories, validation.CategoryGitOps)
+	}
+	if cfg.Validations.DataIntegrity {
+		categories = append(categories, validation.CategoryData)
+	}
+	if cfg.Validations.YAMLSyntax {
+		categories = append(categories, validation.CategorySyntax)
+	}
+	if cfg.Validations.Security {
+		categories = append(categories, validation.CategorySecurity)
+	}
+	if cfg.Validations.Performance {
+		categories = append(categories, validation.CategoryPerformance)
+	}
+	return categories
+}
+
+// monitor runs the checks and keeps their latest results
+type monitor struct {
+	config    *monitorConfig
+	opts      validation.Options
+	metrics   *validation.Metrics
+	logger    *logging.StructuredLogger
+	startTime time.Time
+
+	mutex   sync.RWMutex
+	results []validation.Result
+}
+
+// run runs the enabled checks once and records their results
+func (m *monitor) run(ctx context.Context) {
+	results, err := validation.RunChecks(ctx, m.opts)
+	if err != nil {
+		m.logger.Error("validation_failed", "Failed to run health checks", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	m.metrics.Record(results)
+
+	m.mutex.Lock()
+	m.results = append(m.results, results.Checks...)
+	if len(m.results) > maxResults {
+		m.results = m.results[len(m.results)-maxResults:]
+	}
+	m.mutex.Unlock()
+
+	m.logger.Info("validation_completed", "Health checks completed", map[string]interface{}{
+		"checks":   len(results.Checks),
+		"failed":   results.Count(validation.StatusFailed),
+		"warnings": results.Count(validation.StatusWarning),
+		"duration": results.Duration.String(),
+	})
+}
+
+// runPeriodically runs the checks every health check interval until ctx is
+// done
+func (m *monitor) runPeriodically(ctx context.Context) {
+	ticker := time.NewTicker(m.config.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.run(ctx)
+		}
+	}
+}
+
+// recentResults returns the results of checks run within duration
+func (m *monitor) recentResults(duration time.Duration) []validation.Result {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	cutoff := time.Now().Add(-duration)
+	recent := make([]validation.Result, 0)
+	for _, result := range m.results {
+		if result.Timestamp.After(cutoff) {
+			recent = append(recent, result)
+		}
+	}
+	return recent
+}
+
+// handler serves the monitor's endpoints
+func (m *monitor) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/health", m.healthHandler)
+	mux.HandleFunc("/validation-results", m.resultsHandler)
+	mux.HandleFunc("/status", m.statusHandler)
+	return mux
+}
+
+// healthHandler answers 503 while a critical check failed in the last five
+// minutes or no check ran yet
+func (m *monitor) healthHandler(w http.ResponseWriter, r *http.Request) {
+	recent := m.recentResults(5 * time.Minute)
+	criticalFailures := 0
+	for _, result := range recent {
+		if result.Severity == validation.SeverityCritical && result.Status == validation.StatusFailed {
+			criticalFailures++
+		}
+	}
+
+	status, code := "healthy", http.StatusOK
+	switch {
+	case criticalFailures > 0:
+		status, code = "unhealthy", http.StatusServiceUnavailable
+	case len(recent) == 0:
+		status, code = "unknown", http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, map[string]interface{}{
+		"status":            status,
+		"timestamp":         time.Now(),
+		"total_checks":      len(recent),
+		"critical_failures": criticalFailures,
+		"framework_uptime":  time.Since(m.startTime).String(),
+	})
+}
+
+// resultsHandler lists the kept results, filtered by the since, category
+// and status query parameters
+func (m *monitor) resultsHandler(w http.ResponseWriter, r *http.Request) {
+	since := r.URL.Query().Get("since")
+	category := r.URL.Query().Get("category")
+	status := r.URL.Query().Get("status")
+
+	window := time.Duration(1<<63 - 1)
+	if duration, err := time.ParseDuration(since); err == nil {
+		window = duration
+	}
+	results := make([]validation.Result, 0)
+	for _, result := range m.recentResults(window) {
+		if (category == "" || result.Category == category) && (status == "" || result.Status == status) {
+			results = append(results, result)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"results":   results,
+		"count":     len(results),
+		"timestamp": time.Now(),
+		"filters": map[string]interface{}{
+			"since":    since,
+			"category": category,
+			"status":   status,
+		},
+	})
+}
+
+// statusHandler summarizes the results of the last hour
+func (m *monitor) statusHandler(w http.ResponseWriter, r *http.Request) {
+	recent := m.recentResults(time.Hour)
+	byStatus := make(map[string]int)
+	byCategory := make(map[string]int)
+	bySeverity := make(map[string]int)
+	for _, result := range recent {
+		byStatus[result.Status]++
+		byCategory[result.Category]++
+		bySeverity[result.Severity]++
+	}
+	var last *validation.Result
+	if len(recent) > 0 {
+		last = &recent[len(recent)-1]
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"framework_status": "active",
+		"timestamp":        time.Now(),
+		"config":           m.config,
+		"summary": map[string]interface{}{
+			"recent_results":     len(recent),
+			"status_breakdown":   byStatus,
+			"category_breakdown": byCategory,
+			"severity_breakdown": bySeverity,
+		},
+		"last_validation": last,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeReport writes the kept results to a timestamped JSON file in the
+// working directory
+func (m *monitor) writeReport() (string, error) {
+	m.mutex.RLock()
+	report := map[string]interface{}{
+		"timestamp":          time.Now(),
+		"framework_config":   m.config,
+		"validation_results": m.results,
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	m.mutex.RUnlock()
+	if err != nil {
+		return "", fmt.Errorf("failed to encode report: %v", err)
+	}
+	filename := fmt.Sprintf("validation-monitoring-report-%s.json", time.Now().Format("20060102-150405"))
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write report: %v", err)
+	}
+	return filename, nil
+}
+
+// newKubeClients connect with the kubeconfig kubectl would use, or the
+// service account when running in a pod
+func newKubeClients() (kubernetes.Interface, dynamic.Interface, error) {
+	kubeConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(), &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load kubeconfig: %v", err)
+	}
+	kubeClient, err := kubernetes.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	return kubeClient, dynamicClient, nil
+}
+
+func main() {
+	configPath := ""
+	if len(os.Args) > 1 {
+		configPath = os.Args[1]
+	}
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	logger := logging.NewStructuredLogger("validation-monitor", cfg.ClusterName)
+
+	kubeClient, dynamicClient, err := newKubeClients()
+	if err != nil {
+		log.Fatalf("Failed to create Kubernetes client: %v", err)
+	}
+
+	opts, err := cfg.Apply(validation.Options{
+		KubeClient:    kubeClient,
+		DynamicClient: dynamicClient,
+		Categories:    cfg.categories(),
+		BackupDir:     cfg.BackupLocation,
+		GitOpsRepo:    cfg.GitOpsRepoPath,
+	})
+	if err != nil {
+		log.Fatalf("Failed to configure checks: %v", err)
+	}
+
+	m := &monitor{
+		config:    cfg,
+		opts:      opts,
+		metrics:   validation.NewMetrics(prometheus.DefaultRegisterer),
+		logger:    logger,
+		startTime: time.Now(),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	httpServer := &http.Server{Addr: fmt.Sprintf(":%d", cfg.MetricsPort), Handler: m.handler()}
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Metrics server failed: %v", err)
+		}
+	}()
+	logger.Info("validation_monitor_start", "Validation monitor started", map[string]interface{}{
+		"port":       cfg.MetricsPort,
+		"interval":   cfg.HealthCheckInterval.String(),
+		"categories": m.opts.Categories,
+	})
+
+	m.run(ctx)
+	if report, err := m.writeReport(); err != nil {
+		logger.Warning("validation_report_failed", "Failed to write the validation report", map[string]interface{}{
+			"error": err.Error(),
+		})
+	} else {
+		logger.Info("validation_report_written", "Validation report written", map[string]interface{}{
+			"file": report,
+		})
+	}
+
+	m.runPeriodically(ctx)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	httpServer.Shutdown(shutdownCtx)
+}
@@ -0,0 +1,176 @@
+// Package adaptive scales how many resource types a backup lists at once, and
+// the size of the pages it lists them in, to the latency and throttling of
+// the API server.
+package adaptive
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// window is the number of list calls averaged before scaling on latency.
+// A throttled call scales down at once.
+const window = 5
+
+// Bounds are the limits the controller scales within
+type Bounds struct {
+	MinWorkers  int
+	MaxWorkers  int
+	MinPageSize int
+	MaxPageSize int
+	// TargetLatency is the list call latency scaled down from when exceeded
+	// on average, and up from below half of it
+	TargetLatency time.Duration
+}
+
+// Reasons given to OnChange
+const (
+	ReasonThrottled = "throttled"
+	ReasonSlow      = "slow"
+	ReasonFast      = "fast"
+)
+
+// Controller adjusts worker concurrency and page size additively up while
+// list calls are fast, and down while they are slow; a call throttled with
+// 429 Too Many Requests halves both. It is safe for concurrent use.
+type Controller struct {
+	bounds Bounds
+	// OnChange, when set, is called with the new settings after each
+	// adjustment
+	OnChange func(workers, pageSize int, reason string)
+
+	mu       sync.Mutex
+	workers  int
+	pageSize int
+	samples  int
+	total    time.Duration
+	active   int
+	// released is closed and replaced when a worker slot may have freed up
+	released chan struct{}
+}
+
+// New returns a controller starting at the fewest workers and pageSize,
+// clamped to bounds
+func New(bounds Bounds, pageSize int) *Controller {
+	if bounds.MinWorkers < 1 {
+		bounds.MinWorkers = 1
+	}
+	if bounds.MaxWorkers < bounds.MinWorkers {
+		bounds.MaxWorkers = bounds.MinWorkers
+	}
+	if bounds.MinPageSize < 1 {
+		bounds.MinPageSize = 1
+	}
+	if bounds.MaxPageSize < bounds.MinPageSize {
+		bounds.MaxPageSize = bounds.MinPageSize
+	}
+	return &Controller{
+		bounds:   bounds,
+		workers:  bounds.MinWorkers,
+		pageSize: clamp(pageSize, bounds.MinPageSize, bounds.MaxPageSize),
+		released: make(chan struct{}),
+	}
+}
+
+// Workers returns how many resource types may be listed at once
+func (c *Controller) Workers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.workers
+}
+
+// PageSize returns the number of items to list a page of
+func (c *Controller) PageSize() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pageSize
+}
+
+// Observe records the latency of a list call and whether the API server
+// throttled it, and adjusts the settings
+func (c *Controller) Observe(latency time.Duration, throttled bool) {
+	c.mu.Lock()
+	workers, pageSize, reason := c.observe(latency, throttled)
+	onChange := c.OnChange
+	c.mu.Unlock()
+	if reason != "" && onChange != nil {
+		onChange(workers, pageSize, reason)
+	}
+}
+
+func (c *Controller) observe(latency time.Duration, throttled bool) (int, int, string) {
+	if throttled {
+		c.samples, c.total = 0, 0
+		return c.adjust(c.workers/2, c.pageSize/2, ReasonThrottled)
+	}
+
+	c.samples++
+	c.total += latency
+	if c.samples < window {
+		return c.workers, c.pageSize, ""
+	}
+	average := c.total / time.Duration(c.samples)
+	c.samples, c.total = 0, 0
+	switch {
+	case average > c.bounds.TargetLatency:
+		return c.adjust(c.workers-1, c.pageSize*3/4, ReasonSlow)
+	case average < c.bounds.TargetLatency/2:
+		return c.adjust(c.workers+1, max(c.pageSize*5/4, c.pageSize+1), ReasonFast)
+	}
+	return c.workers, c.pageSize, ""
+}
+
+// adjust moves the settings within bounds, and returns them with reason when
+// they changed
+func (c *Controller) adjust(workers, pageSize int, reason string) (int, int, string) {
+	workers = clamp(workers, c.bounds.MinWorkers, c.bounds.MaxWorkers)
+	pageSize = clamp(pageSize, c.bounds.MinPageSize, c.bounds.MaxPageSize)
+	if workers == c.workers && pageSize == c.pageSize {
+		return workers, pageSize, ""
+	}
+	if workers > c.workers {
+		c.wake()
+	}
+	c.workers, c.pageSize = workers, pageSize
+	return workers, pageSize, reason
+}
+
+// Acquire waits for a worker slot, of which there are Workers(). A slot
+// taken before the workers were scaled down is kept until released.
+func (c *Controller) Acquire(ctx context.Context) error {
+	for {
+		c.mu.Lock()
+		if c.active < c.workers {
+			c.active++
+			c.mu.Unlock()
+			return nil
+		}
+		released := c.released
+		c.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Release frees a slot taken by Acquire
+func (c *Controller) Release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.active--
+	c.wake()
+}
+
+// wake lets the callers waiting in Acquire check for a slot again
+func (c *Controller) wake() {
+	close(c.released)
+	c.released = make(chan struct{})
+}
+
+func clamp(value, low, high int) int {
+	return max(low, min(value, high))
+}
@@ -0,0 +1,92 @@
+package adaptive
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var bounds = Bounds{MinWorkers: 1, MaxWorkers: 4, MinPageSize: 50, MaxPageSize: 500, TargetLatency: time.Second}
+
+func observe(c *Controller, latency time.Duration, calls int) {
+	for i := 0; i < calls; i++ {
+		c.Observe(latency, false)
+	}
+}
+
+func TestController_ScalesOnLatency(t *testing.T) {
+	c := New(bounds, 100)
+	var reasons []string
+	c.OnChange = func(_, _ int, reason string) { reasons = append(reasons, reason) }
+	assert.Equal(t, 1, c.Workers())
+	assert.Equal(t, 100, c.PageSize())
+
+	observe(c, 100*time.Millisecond, window-1)
+	assert.Equal(t, 1, c.Workers(), "settings only change once a window of calls is averaged")
+	observe(c, 100*time.Millisecond, 1)
+	assert.Equal(t, 2, c.Workers())
+	assert.Equal(t, 125, c.PageSize())
+
+	observe(c, 100*time.Millisecond, 10*window)
+	assert.Equal(t, 4, c.Workers(), "workers stop at MaxWorkers")
+	assert.Equal(t, 500, c.PageSize(), "pages stop at MaxPageSize")
+
+	observe(c, 700*time.Millisecond, window)
+	assert.Equal(t, 4, c.Workers(), "latency between half and the target holds the settings")
+
+	observe(c, 2*time.Second, window)
+	assert.Equal(t, 3, c.Workers())
+	assert.Equal(t, 375, c.PageSize())
+	assert.Equal(t, ReasonSlow, reasons[len(reasons)-1])
+}
+
+func TestController_Throttled(t *testing.T) {
+	c := New(bounds, 400)
+	observe(c, 10*time.Millisecond, 3*window)
+	require.Equal(t, 4, c.Workers())
+
+	c.Observe(time.Millisecond, true)
+	assert.Equal(t, 2, c.Workers(), "a throttled call halves the workers at once")
+	assert.Equal(t, 250, c.PageSize())
+
+	for i := 0; i < 5; i++ {
+		c.Observe(time.Millisecond, true)
+	}
+	assert.Equal(t, 1, c.Workers(), "workers stop at MinWorkers")
+	assert.Equal(t, 50, c.PageSize(), "pages stop at MinPageSize")
+}
+
+func TestController_Acquire(t *testing.T) {
+	c := New(bounds, 100)
+	ctx := context.Background()
+	require.NoError(t, c.Acquire(ctx))
+
+	acquired := make(chan struct{})
+	go func() {
+		if c.Acquire(ctx) == nil {
+			close(acquired)
+		}
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("a second slot was taken with one worker")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	observe(c, time.Millisecond, window)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("scaling up did not free a slot")
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, c.Acquire(cancelled), context.Canceled)
+
+	c.Release()
+	assert.NoError(t, c.Acquire(ctx), "a released slot is taken again")
+}
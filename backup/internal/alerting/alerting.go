@@ -0,0 +1,183 @@
+// Package alerting fires alerts for backup and cleanup failures. Alerts are
+// posted straight to Alertmanager's API, so a backup run as a CronJob can
+// alert without a Prometheus scraping it; for long-running deployments the
+// same conditions are available as a PrometheusRule.
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"cluster-backup/internal/config"
+)
+
+// requestTimeout bounds each post to Alertmanager
+const requestTimeout = 10 * time.Second
+
+// Alert names
+const (
+	AlertConsecutiveFailures = "ClusterBackupConsecutiveFailures"
+	AlertNoResources         = "ClusterBackupNoResources"
+	AlertCleanupErrors       = "ClusterBackupCleanupErrors"
+)
+
+// Rules holds the thresholds of the alerts
+type Rules struct {
+	// ConsecutiveFailures is how many backup runs in a row must fail before
+	// the failure alert fires
+	ConsecutiveFailures int
+	// NoResources fires an alert when a finished run stored no resources,
+	// which usually means its filters or permissions are wrong
+	NoResources bool
+	// CleanupErrors is how many errors a cleanup may have before the cleanup
+	// alert fires
+	CleanupErrors int
+}
+
+// Alert is an alert as Alertmanager's API v2 accepts it
+type Alert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	StartsAt    time.Time         `json:"startsAt"`
+	EndsAt      time.Time         `json:"endsAt"`
+}
+
+// BackupStatus describes a finished backup run
+type BackupStatus struct {
+	BackupID string
+	// Failures is the number of runs in a row that failed, this one included;
+	// 0 when this run succeeded
+	Failures  int
+	Resources int
+	// Finished is set when the run got as far as backing up namespaces
+	Finished bool
+	Error    string
+}
+
+// Alerter posts the alerts of the configured rules to Alertmanager. An alert
+// whose condition no longer holds is sent resolved, and a firing alert stays
+// active for ttl unless a later run refreshes or resolves it.
+type Alerter struct {
+	url     string
+	rules   Rules
+	labels  map[string]string
+	ttl     time.Duration
+	client  *http.Client
+	nowFunc func() time.Time
+}
+
+// NewAlerter returns an alerter posting to the Alertmanager at url. labels
+// are added to every alert, e.g. the cluster name and a team to route on.
+func NewAlerter(url string, rules Rules, labels map[string]string, ttl, timeout time.Duration) *Alerter {
+	return &Alerter{
+		url:     strings.TrimRight(url, "/"),
+		rules:   rules,
+		labels:  labels,
+		ttl:     ttl,
+		client:  &http.Client{Timeout: timeout},
+		nowFunc: time.Now,
+	}
+}
+
+// RulesFromConfig returns the thresholds set by the ALERT_* settings
+func RulesFromConfig(cfg *config.Config) Rules {
+	return Rules{
+		ConsecutiveFailures: cfg.AlertConsecutiveFailures,
+		NoResources:         cfg.AlertOnEmptyBackup,
+		CleanupErrors:       cfg.AlertCleanupErrors,
+	}
+}
+
+// NewFromConfig returns the alerter for ALERTMANAGER_URL, or nil when it is
+// not set. Alerts carry the cluster's name and the ALERT_LABELS.
+func NewFromConfig(cfg *config.Config) *Alerter {
+	if cfg.AlertmanagerURL == "" {
+		return nil
+	}
+	labels := map[string]string{"cluster": cfg.ClusterName}
+	for key, value := range cfg.AlertLabels {
+		labels[key] = value
+	}
+	return NewAlerter(cfg.AlertmanagerURL, RulesFromConfig(cfg), labels, cfg.AlertTTL, requestTimeout)
+}
+
+// Rules returns the thresholds the alerter fires on
+func (a *Alerter) Rules() Rules {
+	return a.rules
+}
+
+// CheckBackup fires or resolves the failure and empty backup alerts for a run
+func (a *Alerter) CheckBackup(ctx context.Context, status BackupStatus) error {
+	failing := a.alert(AlertConsecutiveFailures, "critical", status.Failures >= a.rules.ConsecutiveFailures)
+	failing.Annotations = map[string]string{
+		"summary":     fmt.Sprintf("%d backup runs of cluster %s failed in a row", status.Failures, a.labels["cluster"]),
+		"description": status.Error,
+	}
+	alerts := []Alert{failing}
+
+	// A run that stopped early says nothing about what it would have stored
+	if a.rules.NoResources && status.Finished {
+		empty := a.alert(AlertNoResources, "warning", status.Resources == 0)
+		empty.Annotations = map[string]string{
+			"summary":     fmt.Sprintf("Backup %s of cluster %s stored no resources", status.BackupID, a.labels["cluster"]),
+			"description": "Check the namespace and resource filters and the service account's permissions",
+		}
+		alerts = append(alerts, empty)
+	}
+	return a.send(ctx, alerts)
+}
+
+// CheckCleanup fires or resolves the cleanup alert for a cleanup run
+func (a *Alerter) CheckCleanup(ctx context.Context, errorCount int, lastError string) error {
+	alert := a.alert(AlertCleanupErrors, "warning", errorCount > a.rules.CleanupErrors)
+	alert.Annotations = map[string]string{
+		"summary":     fmt.Sprintf("Backup cleanup of cluster %s had %d errors", a.labels["cluster"], errorCount),
+		"description": lastError,
+	}
+	return a.send(ctx, []Alert{alert})
+}
+
+// alert returns the named alert, active for the alerter's TTL when firing
+// and ending now otherwise
+func (a *Alerter) alert(name, severity string, firing bool) Alert {
+	labels := map[string]string{"alertname": name, "severity": severity}
+	for key, value := range a.labels {
+		labels[key] = value
+	}
+	now := a.nowFunc().UTC()
+	alert := Alert{Labels: labels, StartsAt: now, EndsAt: now}
+	if firing {
+		alert.EndsAt = now.Add(a.ttl)
+	}
+	return alert
+}
+
+// send posts alerts to Alertmanager's API v2
+func (a *Alerter) send(ctx context.Context, alerts []Alert) error {
+	body, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url+"/api/v2/alerts", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post alerts to alertmanager: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("alertmanager returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestAlerter(t *testing.T) {
+	var posted [][]Alert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v2/alerts", r.URL.Path)
+		var alerts []Alert
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&alerts))
+		posted = append(posted, alerts)
+	}))
+	defer server.Close()
+
+	now := time.Date(2025, 3, 1, 2, 0, 0, 0, time.UTC)
+	alerter := NewAlerter(server.URL+"/", Rules{ConsecutiveFailures: 3, NoResources: true, CleanupErrors: 5},
+		map[string]string{"cluster": "prod", "team": "platform"}, 24*time.Hour, time.Second)
+	alerter.nowFunc = func() time.Time { return now }
+	ctx := context.Background()
+
+	// Two failures in a row stay below the threshold and send resolved alerts
+	require.NoError(t, alerter.CheckBackup(ctx, BackupStatus{BackupID: "b-2", Failures: 2, Error: "timeout"}))
+	require.Len(t, posted[0], 1, "a run that stopped early is not checked for resources")
+	assert.Equal(t, now, posted[0][0].EndsAt)
+
+	require.NoError(t, alerter.CheckBackup(ctx, BackupStatus{BackupID: "b-3", Failures: 3, Finished: true, Error: "timeout"}))
+	require.Len(t, posted[1], 2)
+	failing := posted[1][0]
+	assert.Equal(t, AlertConsecutiveFailures, failing.Labels["alertname"])
+	assert.Equal(t, "critical", failing.Labels["severity"])
+	assert.Equal(t, "platform", failing.Labels["team"])
+	assert.Equal(t, now.Add(24*time.Hour), failing.EndsAt)
+	assert.Equal(t, "timeout", failing.Annotations["description"])
+	assert.Equal(t, AlertNoResources, posted[1][1].Labels["alertname"])
+	assert.Equal(t, now.Add(24*time.Hour), posted[1][1].EndsAt)
+
+	require.NoError(t, alerter.CheckCleanup(ctx, 6, "failed to delete blob"))
+	assert.Equal(t, AlertCleanupErrors, posted[2][0].Labels["alertname"])
+	assert.True(t, posted[2][0].EndsAt.After(now))
+	require.NoError(t, alerter.CheckCleanup(ctx, 5, ""))
+	assert.Equal(t, now, posted[3][0].EndsAt, "errors up to the threshold resolve the alert")
+}
+
+func TestAlerterRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid alert", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	alerter := NewAlerter(server.URL, Rules{ConsecutiveFailures: 1}, nil, time.Hour, time.Second)
+	err := alerter.CheckBackup(context.Background(), BackupStatus{Failures: 1})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid alert")
+}
+
+func TestPrometheusRule(t *testing.T) {
+	data, err := PrometheusRule("cluster-backup", "monitoring", Rules{ConsecutiveFailures: 3, CleanupErrors: 5})
+	require.NoError(t, err)
+
+	var rule struct {
+		Kind     string
+		Metadata struct{ Name, Namespace string }
+		Spec     struct {
+			Groups []struct {
+				Rules []struct {
+					Alert  string
+					Expr   string
+					Labels map[string]string
+				}
+			}
+		}
+	}
+	require.NoError(t, yaml.Unmarshal(data, &rule))
+	assert.Equal(t, "PrometheusRule", rule.Kind)
+	assert.Equal(t, "monitoring", rule.Metadata.Namespace)
+	require.Len(t, rule.Spec.Groups, 1)
+	alerts := rule.Spec.Groups[0].Rules
+	require.Len(t, alerts, 2, "the empty backup alert is disabled")
+	assert.Equal(t, "cluster_backup_consecutive_failures >= 3", alerts[0].Expr)
+	assert.Equal(t, AlertCleanupErrors, alerts[1].Alert)
+	assert.Equal(t, "cluster_backup_cleanup_errors > 5", alerts[1].Expr)
+}
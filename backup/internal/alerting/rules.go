@@ -0,0 +1,65 @@
+package alerting
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PrometheusRule returns the YAML of a prometheus-operator PrometheusRule
+// alerting on the same conditions as the Alerter, from the metrics a
+// long-running backup service exports
+func PrometheusRule(name, namespace string, rules Rules) ([]byte, error) {
+	alerts := []map[string]interface{}{
+		{
+			"alert": AlertConsecutiveFailures,
+			"expr":  fmt.Sprintf("cluster_backup_consecutive_failures >= %d", rules.ConsecutiveFailures),
+			"labels": map[string]string{
+				"severity": "critical",
+			},
+			"annotations": map[string]string{
+				"summary":     "{{ $value }} backup runs failed in a row",
+				"description": "The last {{ $value }} backup runs of {{ $labels.instance }} failed.",
+			},
+		},
+	}
+	if rules.NoResources {
+		alerts = append(alerts, map[string]interface{}{
+			"alert": AlertNoResources,
+			"expr":  "cluster_backup_last_run_resources == 0",
+			"labels": map[string]string{
+				"severity": "warning",
+			},
+			"annotations": map[string]string{
+				"summary":     "The last backup stored no resources",
+				"description": "Check the namespace and resource filters and the service account's permissions.",
+			},
+		})
+	}
+	alerts = append(alerts, map[string]interface{}{
+		"alert": AlertCleanupErrors,
+		"expr":  fmt.Sprintf("cluster_backup_cleanup_errors > %d", rules.CleanupErrors),
+		"labels": map[string]string{
+			"severity": "warning",
+		},
+		"annotations": map[string]string{
+			"summary":     "Backup cleanup had {{ $value }} errors",
+			"description": "The last cleanup of {{ $labels.instance }} had {{ $value }} errors removing expired backups.",
+		},
+	})
+
+	rule := map[string]interface{}{
+		"apiVersion": "monitoring.coreos.com/v1",
+		"kind":       "PrometheusRule",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": namespace,
+		},
+		"spec": map[string]interface{}{
+			"groups": []map[string]interface{}{
+				{"name": "cluster-backup", "rules": alerts},
+			},
+		},
+	}
+	return yaml.Marshal(rule)
+}
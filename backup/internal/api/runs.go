@@ -0,0 +1,469 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"cluster-backup/internal/audit"
+	"cluster-backup/internal/auth"
+)
+
+// RunType identifies the kind of operation tracked by a Run
+type RunType string
+
+const (
+	RunTypeBackup  RunType = "backup"
+	RunTypeRestore RunType = "restore"
+)
+
+// RunStatus represents the lifecycle state of a Run
+type RunStatus string
+
+const (
+	RunStatusPending   RunStatus = "pending"
+	RunStatusRunning   RunStatus = "running"
+	RunStatusCompleted RunStatus = "completed"
+	RunStatusFailed    RunStatus = "failed"
+	RunStatusCancelled RunStatus = "cancelled"
+	// RunStatusAwaitingApproval is a restore that starts once a second
+	// person approves it
+	RunStatusAwaitingApproval RunStatus = "awaiting_approval"
+)
+
+// Run tracks a single backup or restore operation triggered through the API.
+// Progress holds the latest progress the operation reported.
+// CancelRequested is set once the run was asked to stop; it keeps running
+// until it does and is then cancelled.
+type Run struct {
+	ID              string      `json:"id"`
+	Type            RunType     `json:"type"`
+	Status          RunStatus   `json:"status"`
+	StartTime       time.Time   `json:"start_time"`
+	EndTime         *time.Time  `json:"end_time,omitempty"`
+	CancelRequested bool        `json:"cancel_requested,omitempty"`
+	Progress        interface{} `json:"progress,omitempty"`
+	Result          interface{} `json:"result,omitempty"`
+	Error           string      `json:"error,omitempty"`
+	Approval        *Approval   `json:"approval,omitempty"`
+}
+
+// Approval is who requested a restore that needed a second person's
+// approval, and who approved it
+type Approval struct {
+	RequestedBy audit.Actor  `json:"requested_by"`
+	ExpiresAt   time.Time    `json:"expires_at"`
+	ApprovedBy  *audit.Actor `json:"approved_by,omitempty"`
+	ApprovedAt  *time.Time   `json:"approved_at,omitempty"`
+}
+
+var (
+	// ErrRunInProgress is returned when a run of the same type is already active
+	ErrRunInProgress = errors.New("a run of this type is already in progress")
+	// ErrRunNotFound is returned for runs the registry does not know
+	ErrRunNotFound = errors.New("run not found")
+	// ErrRunFinished is returned when cancelling a run that already finished
+	ErrRunFinished = errors.New("run already finished")
+	// ErrRunNotCancellable is returned when cancelling a run that cannot be
+	// stopped
+	ErrRunNotCancellable = errors.New("run cannot be cancelled")
+	// ErrRunNotAwaitingApproval is returned when approving a run that does
+	// not wait for approval
+	ErrRunNotAwaitingApproval = errors.New("run is not awaiting approval")
+	// ErrSelfApproval is returned when the requester of a restore approves it
+	ErrSelfApproval = errors.New("a restore must be approved by someone other than its requester")
+	// ErrNotApprover is returned when a restore is approved with a static
+	// token other than the approver's, which cannot tell the approver from
+	// the requester
+	ErrNotApprover = errors.New("restores are approved with the approver's token or an OIDC token")
+)
+
+type runIDKey struct{}
+
+type approvalKey struct{}
+
+// RunIDFromContext returns the ID of the run executing with the given context
+func RunIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(runIDKey{}).(string)
+	return id
+}
+
+// ApprovalFromContext returns the approval of the restore run executing with
+// the given context, nil when it was not approved. Restore functions pass it
+// on to engines that require approval themselves.
+func ApprovalFromContext(ctx context.Context) *Approval {
+	approval, _ := ctx.Value(approvalKey{}).(*Approval)
+	return approval
+}
+
+// IsActive reports whether the run has not finished yet
+func (r *Run) IsActive() bool {
+	return r.Status == RunStatusPending || r.Status == RunStatusRunning || r.Status == RunStatusAwaitingApproval
+}
+
+// pendingApproval is what a restore awaiting approval starts with once
+// approved
+type pendingApproval struct {
+	start  func(id string, approver audit.Actor)
+	expiry *time.Timer
+}
+
+// RunRegistry keeps an in-memory record of API-triggered runs
+type RunRegistry struct {
+	runs    map[string]*Run
+	cancels map[string]context.CancelFunc
+	maxRuns int
+	mutex   sync.RWMutex
+	// auditLog records the runs started through every API sharing the
+	// registry, and who started them
+	auditLog *audit.Log
+	// approvalTimeout, when set, makes restores other than dry runs wait
+	// that long for a second person's approval
+	approvalTimeout time.Duration
+	approvals       map[string]*pendingApproval
+}
+
+// NewRunRegistry creates a registry that retains at most maxRuns finished runs
+func NewRunRegistry(maxRuns int) *RunRegistry {
+	if maxRuns <= 0 {
+		maxRuns = 100
+	}
+
+	return &RunRegistry{
+		runs:      make(map[string]*Run),
+		cancels:   make(map[string]context.CancelFunc),
+		maxRuns:   maxRuns,
+		approvals: make(map[string]*pendingApproval),
+	}
+}
+
+// RequireRestoreApproval makes restores started through every API sharing
+// the registry, dry runs aside, wait for the approval of someone other than
+// their requester. Requests not approved within timeout fail.
+func (rr *RunRegistry) RequireRestoreApproval(timeout time.Duration) {
+	rr.approvalTimeout = timeout
+}
+
+// RestoreApprovalRequired reports whether restores wait for approval
+func (rr *RunRegistry) RestoreApprovalRequired() bool {
+	return rr.approvalTimeout > 0
+}
+
+// SetAuditLog records every run and cancellation in log; nil records none
+func (rr *RunRegistry) SetAuditLog(log *audit.Log) {
+	rr.auditLog = log
+}
+
+// AuditLog returns the log runs are recorded in, nil when there is none
+func (rr *RunRegistry) AuditLog() *audit.Log {
+	return rr.auditLog
+}
+
+// Create registers a new pending run of the given type
+func (rr *RunRegistry) Create(runType RunType) *Run {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
+	run := &Run{
+		ID:        newRunID(runType),
+		Type:      runType,
+		Status:    RunStatusPending,
+		StartTime: time.Now().UTC(),
+	}
+	rr.runs[run.ID] = run
+	rr.evictLocked()
+
+	return rr.copyOf(run)
+}
+
+// TryCreate registers a new pending run unless one of the same type is active
+func (rr *RunRegistry) TryCreate(runType RunType) (*Run, error) {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
+	for _, run := range rr.runs {
+		if run.Type == runType && run.IsActive() {
+			return nil, ErrRunInProgress
+		}
+	}
+
+	run := &Run{
+		ID:        newRunID(runType),
+		Type:      runType,
+		Status:    RunStatusPending,
+		StartTime: time.Now().UTC(),
+	}
+	rr.runs[run.ID] = run
+	rr.evictLocked()
+
+	return rr.copyOf(run), nil
+}
+
+// MarkRunning moves a run into the running state
+func (rr *RunRegistry) MarkRunning(id string) {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
+	if run, ok := rr.runs[id]; ok {
+		run.Status = RunStatusRunning
+	}
+}
+
+// setCancel makes a run cancellable through cancel until it completes
+func (rr *RunRegistry) setCancel(id string, cancel context.CancelFunc) {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
+	if _, ok := rr.runs[id]; ok {
+		rr.cancels[id] = cancel
+	}
+}
+
+// Cancel asks an active run to stop and returns a copy of it. The run is
+// cancelled once its function returns.
+func (rr *RunRegistry) Cancel(id string) (*Run, error) {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
+	run, ok := rr.runs[id]
+	if !ok {
+		return nil, ErrRunNotFound
+	}
+	if !run.IsActive() {
+		return nil, ErrRunFinished
+	}
+	// Rejecting a restore awaiting approval, it never started
+	if pending, ok := rr.approvals[id]; ok {
+		pending.expiry.Stop()
+		delete(rr.approvals, id)
+		now := time.Now().UTC()
+		run.Status = RunStatusCancelled
+		run.EndTime = &now
+		run.CancelRequested = true
+		return rr.copyOf(run), nil
+	}
+	cancel, ok := rr.cancels[id]
+	if !ok {
+		return nil, ErrRunNotCancellable
+	}
+
+	cancel()
+	run.CancelRequested = true
+	return rr.copyOf(run), nil
+}
+
+// awaitApproval registers a restore requested by requester that calls start
+// with its ID and the approver once someone else approves it, and fails it
+// when nobody does in time. expired is called after it failed.
+func (rr *RunRegistry) awaitApproval(requester audit.Actor, start func(id string, approver audit.Actor), expired func(run *Run)) *Run {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
+	now := time.Now().UTC()
+	run := &Run{
+		ID:        newRunID(RunTypeRestore),
+		Type:      RunTypeRestore,
+		Status:    RunStatusAwaitingApproval,
+		StartTime: now,
+		Approval:  &Approval{RequestedBy: requester, ExpiresAt: now.Add(rr.approvalTimeout)},
+	}
+	rr.runs[run.ID] = run
+	rr.evictLocked()
+
+	id := run.ID
+	rr.approvals[id] = &pendingApproval{
+		start: start,
+		expiry: time.AfterFunc(rr.approvalTimeout, func() {
+			if run, ok := rr.expireApproval(id); ok {
+				expired(run)
+			}
+		}),
+	}
+	return rr.copyOf(run)
+}
+
+// expireApproval fails a restore still awaiting approval
+func (rr *RunRegistry) expireApproval(id string) (*Run, bool) {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
+	run, ok := rr.runs[id]
+	if _, pending := rr.approvals[id]; !ok || !pending {
+		return nil, false
+	}
+	delete(rr.approvals, id)
+	now := time.Now().UTC()
+	run.Status = RunStatusFailed
+	run.EndTime = &now
+	run.Error = fmt.Sprintf("not approved within %s", rr.approvalTimeout)
+	return rr.copyOf(run), true
+}
+
+// Approve starts a restore awaiting approval as approved by approver, who
+// must not be its requester and must be an identity auth.CanApprove accepts,
+// and returns a copy of it
+func (rr *RunRegistry) Approve(id string, approver audit.Actor) (*Run, error) {
+	rr.mutex.Lock()
+	run, ok := rr.runs[id]
+	if !ok {
+		rr.mutex.Unlock()
+		return nil, ErrRunNotFound
+	}
+	pending, ok := rr.approvals[id]
+	if !ok {
+		rr.mutex.Unlock()
+		return nil, ErrRunNotAwaitingApproval
+	}
+	if approver.ID == run.Approval.RequestedBy.ID {
+		rr.mutex.Unlock()
+		return nil, ErrSelfApproval
+	}
+	if !auth.CanApprove(approver) {
+		rr.mutex.Unlock()
+		return nil, ErrNotApprover
+	}
+
+	pending.expiry.Stop()
+	delete(rr.approvals, id)
+	now := time.Now().UTC()
+	approval := *run.Approval
+	approval.ApprovedBy = &approver
+	approval.ApprovedAt = &now
+	run.Approval = &approval
+	run.Status = RunStatusPending
+	snapshot := rr.copyOf(run)
+	rr.mutex.Unlock()
+
+	pending.start(id, approver)
+	return snapshot, nil
+}
+
+// SetProgress records the latest progress reported by a run
+func (rr *RunRegistry) SetProgress(id string, progress interface{}) {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
+	if run, ok := rr.runs[id]; ok {
+		run.Progress = progress
+	}
+}
+
+// Complete records the outcome of a run
+func (rr *RunRegistry) Complete(id string, result interface{}, err error) {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
+	run, ok := rr.runs[id]
+	if !ok {
+		return
+	}
+
+	if cancel, ok := rr.cancels[id]; ok {
+		cancel()
+		delete(rr.cancels, id)
+	}
+
+	// A cancelled run keeps the partial result it returned
+	now := time.Now().UTC()
+	run.EndTime = &now
+	run.Result = result
+	switch {
+	case run.CancelRequested:
+		run.Status = RunStatusCancelled
+		if err != nil && !errors.Is(err, context.Canceled) {
+			run.Error = err.Error()
+		}
+	case err != nil:
+		run.Status = RunStatusFailed
+		run.Error = err.Error()
+	default:
+		run.Status = RunStatusCompleted
+	}
+}
+
+// Get returns a copy of the run with the given ID
+func (rr *RunRegistry) Get(id string) (*Run, bool) {
+	rr.mutex.RLock()
+	defer rr.mutex.RUnlock()
+
+	run, ok := rr.runs[id]
+	if !ok {
+		return nil, false
+	}
+	return rr.copyOf(run), true
+}
+
+// List returns copies of all runs of the given type, newest first
+func (rr *RunRegistry) List(runType RunType) []*Run {
+	rr.mutex.RLock()
+	defer rr.mutex.RUnlock()
+
+	runs := make([]*Run, 0, len(rr.runs))
+	for _, run := range rr.runs {
+		if run.Type == runType {
+			runs = append(runs, rr.copyOf(run))
+		}
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].StartTime.After(runs[j].StartTime)
+	})
+	return runs
+}
+
+// HasActive reports whether a run of the given type is still in progress
+func (rr *RunRegistry) HasActive(runType RunType) bool {
+	rr.mutex.RLock()
+	defer rr.mutex.RUnlock()
+
+	for _, run := range rr.runs {
+		if run.Type == runType && run.IsActive() {
+			return true
+		}
+	}
+	return false
+}
+
+// evictLocked drops the oldest finished runs once the registry is over capacity
+func (rr *RunRegistry) evictLocked() {
+	if len(rr.runs) <= rr.maxRuns {
+		return
+	}
+
+	finished := make([]*Run, 0, len(rr.runs))
+	for _, run := range rr.runs {
+		if !run.IsActive() {
+			finished = append(finished, run)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].StartTime.Before(finished[j].StartTime)
+	})
+
+	for _, run := range finished {
+		if len(rr.runs) <= rr.maxRuns {
+			break
+		}
+		delete(rr.runs, run.ID)
+	}
+}
+
+// copyOf returns a snapshot of the run that is safe to hand out
+func (rr *RunRegistry) copyOf(run *Run) *Run {
+	snapshot := *run
+	return &snapshot
+}
+
+// newRunID generates a sortable, collision-resistant run identifier
+func newRunID(runType RunType) string {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Sprintf("%s-%d", runType, time.Now().UnixNano())
+	}
+	return fmt.Sprintf("%s-%s-%s", runType, time.Now().UTC().Format("20060102-150405"), hex.EncodeToString(suffix))
+}
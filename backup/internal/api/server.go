@@ -0,0 +1,528 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"shared-config/mtls"
+
+	"cluster-backup/internal/audit"
+	"cluster-backup/internal/auth"
+	"cluster-backup/internal/backup"
+	"cluster-backup/internal/logging"
+)
+
+// BackupFunc triggers a single backup run
+type BackupFunc func(ctx context.Context) (*backup.BackupResult, error)
+
+// RestoreFunc triggers a single restore run
+type RestoreFunc func(ctx context.Context, req RestoreRequest) (interface{}, error)
+
+// RestoreRequest describes a restore triggered through the API
+type RestoreRequest struct {
+	BackupID         string   `json:"backup_id"`
+	TargetNamespaces []string `json:"target_namespaces,omitempty"`
+	ResourceTypes    []string `json:"resource_types,omitempty"`
+	DryRun           bool     `json:"dry_run"`
+	// ForceUnsafe restores the resources the restore guardrails protect,
+	// given the ConfirmationToken a restore without it reported
+	ForceUnsafe       bool   `json:"force_unsafe,omitempty"`
+	ConfirmationToken string `json:"confirmation_token,omitempty"`
+}
+
+// RestoreProgress is how far a restore run got, kept as the run's progress
+type RestoreProgress struct {
+	// Phase is the step the restore is at, e.g. validating, crds, resources
+	// or waiting_for_ready
+	Phase           string           `json:"phase"`
+	Percent         float64          `json:"percent"`
+	ResourcesTotal  int              `json:"resources_total"`
+	ResourcesDone   int              `json:"resources_done"`
+	CurrentResource string           `json:"current_resource,omitempty"`
+	FailedResources []FailedResource `json:"failed_resources,omitempty"`
+}
+
+// FailedResource is a resource a restore run could not apply
+type FailedResource struct {
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace,omitempty"`
+	Error     string `json:"error"`
+}
+
+// RestoreRunProgress is the progress of a restore run returned by
+// GET /restores/{id}/progress
+type RestoreRunProgress struct {
+	ID              string           `json:"id"`
+	Status          RunStatus        `json:"status"`
+	CancelRequested bool             `json:"cancel_requested,omitempty"`
+	Approval        *Approval        `json:"approval,omitempty"`
+	Progress        *RestoreProgress `json:"progress,omitempty"`
+}
+
+// ServerConfig holds the settings for the REST API server
+type ServerConfig struct {
+	Port int
+	// Auth authenticates callers; routes are authorized by role
+	Auth *auth.Authenticator
+	// TLS, when set, serves the API over TLS with certificates reloaded on
+	// rotation, requiring client certificates when it has a CA
+	TLS *mtls.Certificates
+	// ProtectHealth requires the viewer role for the health endpoint too,
+	// which is otherwise open so it can back liveness probes
+	ProtectHealth bool
+}
+
+// Server exposes backup and restore operations over HTTP
+type Server struct {
+	server    *http.Server
+	logger    *logging.StructuredLogger
+	config    ServerConfig
+	runs      *RunRegistry
+	backupFn  BackupFunc
+	restoreFn RestoreFunc
+	ctx       context.Context
+
+	invalidateDiscovery func() error
+}
+
+// NewServer creates a new REST API server tracking runs in the given registry.
+// restoreFn may be nil, in which case restore requests are rejected as not
+// implemented.
+func NewServer(ctx context.Context, cfg ServerConfig, runs *RunRegistry, backupFn BackupFunc, restoreFn RestoreFunc, logger *logging.StructuredLogger) *Server {
+	if cfg.Port <= 0 {
+		cfg.Port = 8081 // Default port
+	}
+	if runs == nil {
+		runs = NewRunRegistry(100)
+	}
+
+	s := &Server{
+		logger:    logger,
+		config:    cfg,
+		runs:      runs,
+		backupFn:  backupFn,
+		restoreFn: restoreFn,
+		ctx:       ctx,
+	}
+
+	mux := http.NewServeMux()
+
+	// Health endpoint is unauthenticated unless protected, so it can back
+	// liveness probes
+	if cfg.ProtectHealth {
+		mux.Handle("GET /health", s.authorize(auth.RoleViewer, http.HandlerFunc(s.handleHealth)))
+	} else {
+		mux.HandleFunc("GET /health", s.handleHealth)
+	}
+
+	mux.Handle("POST /backups", s.authorize(auth.RoleOperator, http.HandlerFunc(s.handleCreateBackup)))
+	mux.Handle("GET /backups", s.authorize(auth.RoleViewer, http.HandlerFunc(s.handleListBackups)))
+	mux.Handle("GET /backups/{id}", s.authorize(auth.RoleViewer, http.HandlerFunc(s.handleGetBackup)))
+	mux.Handle("POST /restores", s.authorize(auth.RoleOperator, http.HandlerFunc(s.handleCreateRestore)))
+	mux.Handle("GET /restores/{id}", s.authorize(auth.RoleViewer, http.HandlerFunc(s.handleGetRestore)))
+	mux.Handle("GET /restores/{id}/progress", s.authorize(auth.RoleViewer, http.HandlerFunc(s.handleGetRestoreProgress)))
+	mux.Handle("POST /restores/{id}/cancel", s.authorize(auth.RoleOperator, http.HandlerFunc(s.handleCancelRestore)))
+	mux.Handle("POST /restores/{id}/approve", s.authorize(auth.RoleOperator, http.HandlerFunc(s.handleApproveRestore)))
+	mux.Handle("DELETE /admin/discovery-cache", s.authorize(auth.RoleAdmin, http.HandlerFunc(s.handleInvalidateDiscoveryCache)))
+
+	s.server = &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.Port),
+		Handler:      mux,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+	if cfg.TLS != nil {
+		s.server.TLSConfig = cfg.TLS.ServerConfig()
+	}
+
+	return s
+}
+
+// Start starts the API server in a blocking manner
+func (s *Server) Start() error {
+	s.logger.Info("api_server_start", "Starting REST API server", map[string]interface{}{
+		"port": s.config.Port,
+		"tls":  s.config.TLS != nil,
+		"mtls": s.config.TLS != nil && s.config.TLS.Mutual(),
+	})
+
+	var err error
+	if s.config.TLS != nil {
+		// The certificate comes from the TLS configuration
+		err = s.server.ListenAndServeTLS("", "")
+	} else {
+		err = s.server.ListenAndServe()
+	}
+
+	if err != nil && err != http.ErrServerClosed {
+		s.logger.Error("api_server_error", "REST API server failed", map[string]interface{}{
+			"error": err.Error(),
+			"port":  s.config.Port,
+		})
+		return fmt.Errorf("API server failed to start: %v", err)
+	}
+
+	return nil
+}
+
+// StartAsync starts the API server asynchronously and returns immediately
+func (s *Server) StartAsync() <-chan error {
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(errChan)
+		if err := s.Start(); err != nil {
+			errChan <- err
+		}
+	}()
+
+	return errChan
+}
+
+// Stop gracefully stops the API server
+func (s *Server) Stop(ctx context.Context) error {
+	s.logger.Info("api_server_stop", "Stopping REST API server", map[string]interface{}{
+		"port": s.config.Port,
+	})
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("error shutting down API server: %v", err)
+	}
+	return nil
+}
+
+// Handler returns the HTTP handler serving the API, mainly for tests
+func (s *Server) Handler() http.Handler {
+	return s.server.Handler
+}
+
+// SetDiscoveryCacheInvalidator enables DELETE /admin/discovery-cache, which
+// calls invalidate so the next run discovers the API resources again
+func (s *Server) SetDiscoveryCacheInvalidator(invalidate func() error) {
+	s.invalidateDiscovery = invalidate
+}
+
+// Runs returns the registry of API-triggered runs
+func (s *Server) Runs() *RunRegistry {
+	return s.runs
+}
+
+// authorize rejects requests whose bearer token is not valid or whose
+// holder lacks role, and records the others as made by the token's holder
+func (s *Server) authorize(role auth.Role, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := s.config.Auth.Authorize(r.Context(), r.Header.Get("Authorization"), role)
+		if err != nil {
+			s.logger.Warning("api_unauthorized", "Rejected API request", map[string]interface{}{
+				"method":      r.Method,
+				"path":        r.URL.Path,
+				"remote_addr": r.RemoteAddr,
+				"error":       err.Error(),
+			})
+			status, message := auth.HTTPStatus(err)
+			writeError(w, status, message)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(audit.WithActor(r.Context(), principal.Actor("rest", r.RemoteAddr))))
+	})
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":         "ok",
+		"backup_running": s.runs.HasActive(RunTypeBackup),
+	})
+}
+
+func (s *Server) handleCreateBackup(w http.ResponseWriter, r *http.Request) {
+	run, err := StartBackupRun(WithActorOf(s.ctx, r.Context()), s.runs, s.backupFn)
+	if err != nil {
+		writeError(w, http.StatusConflict, "a backup run is already in progress")
+		return
+	}
+
+	s.logger.Info("api_backup_triggered", "Backup triggered through REST API", map[string]interface{}{
+		"run_id": run.ID,
+	})
+	writeJSON(w, http.StatusAccepted, run)
+}
+
+func (s *Server) handleListBackups(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.runs.List(RunTypeBackup))
+}
+
+func (s *Server) handleGetBackup(w http.ResponseWriter, r *http.Request) {
+	s.writeRun(w, r.PathValue("id"), RunTypeBackup)
+}
+
+func (s *Server) handleCreateRestore(w http.ResponseWriter, r *http.Request) {
+	if s.restoreFn == nil {
+		writeError(w, http.StatusNotImplemented, "restore is not configured on this server")
+		return
+	}
+
+	var req RestoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if req.BackupID == "" {
+		writeError(w, http.StatusBadRequest, "backup_id is required")
+		return
+	}
+
+	run := StartRestoreRun(WithActorOf(s.ctx, r.Context()), s.runs, s.restoreFn, req)
+	s.logger.Info("api_restore_triggered", "Restore triggered through REST API", map[string]interface{}{
+		"run_id":    run.ID,
+		"backup_id": req.BackupID,
+		"dry_run":   req.DryRun,
+	})
+	writeJSON(w, http.StatusAccepted, run)
+}
+
+// WithActorOf returns ctx carrying the audit actor of the request made with
+// requestCtx, for runs that outlive the request
+func WithActorOf(ctx, requestCtx context.Context) context.Context {
+	return audit.WithActor(ctx, audit.ActorFrom(requestCtx))
+}
+
+// StartBackupRun registers a backup run and executes it in the background.
+// The run ID is available to backupFn through RunIDFromContext. The finished
+// run is audited as started by the actor of ctx.
+func StartBackupRun(ctx context.Context, runs *RunRegistry, backupFn BackupFunc) (*Run, error) {
+	run, err := runs.TryCreate(RunTypeBackup)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		runs.MarkRunning(run.ID)
+		result, err := backupFn(context.WithValue(ctx, runIDKey{}, run.ID))
+		if result != nil && err == nil && len(result.Errors) > 0 {
+			err = fmt.Errorf("backup completed with %d errors", len(result.Errors))
+		}
+		runs.Complete(run.ID, result, err)
+
+		record := backup.AuditRecord(result, err)
+		record.RunID = run.ID
+		runs.AuditLog().Record(ctx, record)
+	}()
+
+	return run, nil
+}
+
+// RecordProgress returns a progress callback that keeps the latest run-level
+// progress of the run executing with ctx in runs, so GET /backups/{id} shows
+// it, and passes every event on to next
+func RecordProgress(ctx context.Context, runs *RunRegistry, next backup.ProgressFunc) backup.ProgressFunc {
+	id := RunIDFromContext(ctx)
+	if id == "" {
+		return next
+	}
+	return func(event backup.ProgressEvent) {
+		if event.Run != nil {
+			runs.SetProgress(id, event.Run)
+		}
+		if next != nil {
+			next(event)
+		}
+	}
+}
+
+// StartRestoreRun registers a restore run and executes it in the background.
+// The run ID is available to restoreFn through RunIDFromContext. Cancelling
+// the run cancels the context restoreFn runs with; it should stop applying
+// resources and return what it restored so far. The finished run is audited
+// as started by the actor of ctx.
+//
+// When runs require restore approval, a restore other than a dry run waits
+// for ApproveRestoreRun instead; the request is audited at once.
+func StartRestoreRun(ctx context.Context, runs *RunRegistry, restoreFn RestoreFunc, req RestoreRequest) *Run {
+	if runs.RestoreApprovalRequired() && !req.DryRun {
+		run := runs.awaitApproval(audit.ActorFrom(ctx), func(id string, approver audit.Actor) {
+			executeRestoreRun(ctx, runs, restoreFn, req, id, &approver)
+		}, func(run *Run) {
+			runs.AuditLog().Record(ctx, restoreRecord(run.ID, req, &audit.Record{Outcome: audit.OutcomeFailed, Error: run.Error}))
+		})
+		runs.AuditLog().Record(ctx, restoreRecord(run.ID, req, &audit.Record{Outcome: audit.OutcomeAwaitingApproval}))
+		return run
+	}
+
+	run := runs.Create(RunTypeRestore)
+	executeRestoreRun(ctx, runs, restoreFn, req, run.ID, nil)
+	return run
+}
+
+// ApproveRestoreRun starts the restore run awaiting approval with the given
+// ID, approved by the actor of ctx, and audits the approval
+func ApproveRestoreRun(ctx context.Context, runs *RunRegistry, id string) (*Run, error) {
+	run, err := runs.Approve(id, audit.ActorFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	runs.AuditLog().Record(ctx, audit.Record{
+		Operation:   audit.OperationRestoreApprove,
+		Outcome:     audit.OutcomeSucceeded,
+		RunID:       id,
+		RequestedBy: &run.Approval.RequestedBy,
+	})
+	return run, nil
+}
+
+// executeRestoreRun executes the registered restore run id in the background
+// and audits it once finished
+func executeRestoreRun(ctx context.Context, runs *RunRegistry, restoreFn RestoreFunc, req RestoreRequest, id string, approver *audit.Actor) {
+	runCtx := context.WithValue(ctx, runIDKey{}, id)
+	if run, ok := runs.Get(id); ok && run.Approval != nil && run.Approval.ApprovedBy != nil {
+		runCtx = context.WithValue(runCtx, approvalKey{}, run.Approval)
+	}
+	runCtx, cancel := context.WithCancel(runCtx)
+	runs.setCancel(id, cancel)
+
+	go func() {
+		runs.MarkRunning(id)
+		result, err := restoreFn(runCtx, req)
+		runs.Complete(id, result, err)
+
+		record := &audit.Record{Outcome: audit.OutcomeSucceeded, ApprovedBy: approver}
+		if err != nil {
+			record.Outcome = audit.OutcomeFailed
+			record.Error = err.Error()
+		}
+		runs.AuditLog().Record(ctx, restoreRecord(id, req, record))
+	}()
+}
+
+// restoreRecord returns the audit record of the restore run id of req with
+// the outcome of record
+func restoreRecord(id string, req RestoreRequest, record *audit.Record) audit.Record {
+	record.Operation = audit.OperationRestore
+	record.RunID = id
+	record.BackupID = req.BackupID
+	record.DryRun = req.DryRun
+	record.Resources = &audit.Resources{Namespaces: req.TargetNamespaces, ResourceTypes: req.ResourceTypes}
+	return *record
+}
+
+// RecordRestoreProgress returns a callback keeping the latest progress of the
+// restore run executing with ctx in runs, so GET /restores/{id}/progress
+// shows it. It returns a no-op outside a run.
+func RecordRestoreProgress(ctx context.Context, runs *RunRegistry) func(RestoreProgress) {
+	id := RunIDFromContext(ctx)
+	if id == "" {
+		return func(RestoreProgress) {}
+	}
+	return func(progress RestoreProgress) {
+		runs.SetProgress(id, &progress)
+	}
+}
+
+func (s *Server) handleGetRestore(w http.ResponseWriter, r *http.Request) {
+	s.writeRun(w, r.PathValue("id"), RunTypeRestore)
+}
+
+func (s *Server) handleGetRestoreProgress(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	run, ok := s.runs.Get(id)
+	if !ok || run.Type != RunTypeRestore {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("%s run %s not found", RunTypeRestore, id))
+		return
+	}
+	writeJSON(w, http.StatusOK, RestoreProgressOf(run))
+}
+
+func (s *Server) handleCancelRestore(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if run, ok := s.runs.Get(id); !ok || run.Type != RunTypeRestore {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("%s run %s not found", RunTypeRestore, id))
+		return
+	}
+
+	run, err := s.runs.Cancel(id)
+	if err != nil {
+		writeError(w, http.StatusConflict, fmt.Sprintf("cannot cancel restore run %s: %v", id, err))
+		return
+	}
+	s.logger.Info("api_restore_cancelled", "Restore cancellation requested through REST API", map[string]interface{}{
+		"run_id": id,
+	})
+	s.runs.AuditLog().Record(r.Context(), audit.Record{Operation: audit.OperationRestoreCancel, Outcome: audit.OutcomeRequested, RunID: id})
+	writeJSON(w, http.StatusAccepted, run)
+}
+
+func (s *Server) handleApproveRestore(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if run, ok := s.runs.Get(id); !ok || run.Type != RunTypeRestore {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("%s run %s not found", RunTypeRestore, id))
+		return
+	}
+
+	run, err := ApproveRestoreRun(r.Context(), s.runs, id)
+	if err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, ErrSelfApproval) || errors.Is(err, ErrNotApprover) {
+			status = http.StatusForbidden
+		}
+		writeError(w, status, fmt.Sprintf("cannot approve restore run %s: %v", id, err))
+		return
+	}
+	s.logger.Info("api_restore_approved", "Restore approved through REST API", map[string]interface{}{
+		"run_id":       id,
+		"requested_by": run.Approval.RequestedBy.ID,
+		"approved_by":  run.Approval.ApprovedBy.ID,
+	})
+	writeJSON(w, http.StatusAccepted, run)
+}
+
+func (s *Server) handleInvalidateDiscoveryCache(w http.ResponseWriter, r *http.Request) {
+	if s.invalidateDiscovery == nil {
+		writeError(w, http.StatusNotImplemented, "the discovery cache is not enabled on this server")
+		return
+	}
+	if err := s.invalidateDiscovery(); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.logger.Info("api_discovery_cache_invalidated", "Discovery cache invalidated through REST API", map[string]interface{}{
+		"remote_addr": r.RemoteAddr,
+	})
+	writeJSON(w, http.StatusOK, map[string]string{"status": "invalidated"})
+}
+
+// RestoreProgressOf returns the progress of a restore run
+func RestoreProgressOf(run *Run) *RestoreRunProgress {
+	progress, _ := run.Progress.(*RestoreProgress)
+	return &RestoreRunProgress{
+		ID:              run.ID,
+		Status:          run.Status,
+		CancelRequested: run.CancelRequested,
+		Approval:        run.Approval,
+		Progress:        progress,
+	}
+}
+
+func (s *Server) writeRun(w http.ResponseWriter, id string, runType RunType) {
+	run, ok := s.runs.Get(id)
+	if !ok || run.Type != runType {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("%s run %s not found", runType, id))
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+// writeJSON writes a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, map[string]string{"error": message})
+}
@@ -0,0 +1,412 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/audit"
+	"cluster-backup/internal/auth"
+	"cluster-backup/internal/backup"
+	"cluster-backup/internal/logging"
+)
+
+const (
+	testToken       = "test-token"
+	testViewerToken = "viewer-token"
+)
+
+func newTestServer(backupFn BackupFunc, restoreFn RestoreFunc) *Server {
+	return newTestServerWithConfig(ServerConfig{}, backupFn, restoreFn)
+}
+
+func newTestServerWithConfig(cfg ServerConfig, backupFn BackupFunc, restoreFn RestoreFunc) *Server {
+	logger := logging.NewStructuredLogger("api-test", "test-cluster")
+	cfg.Auth = auth.NewAuthenticator(map[auth.Role]string{auth.RoleAdmin: testToken, auth.RoleViewer: testViewerToken}, nil)
+	return NewServer(context.Background(), cfg, nil, backupFn, restoreFn, logger)
+}
+
+func doRequest(s *Server, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServer_Authentication(t *testing.T) {
+	s := newTestServer(func(ctx context.Context) (*backup.BackupResult, error) {
+		return &backup.BackupResult{}, nil
+	}, nil)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{name: "health_without_token", method: http.MethodGet, path: "/health", expectedStatus: http.StatusOK},
+		{name: "list_without_token", method: http.MethodGet, path: "/backups", expectedStatus: http.StatusUnauthorized},
+		{name: "list_with_wrong_token", method: http.MethodGet, path: "/backups", token: "wrong", expectedStatus: http.StatusUnauthorized},
+		{name: "list_with_token", method: http.MethodGet, path: "/backups", token: testToken, expectedStatus: http.StatusOK},
+		{name: "trigger_without_token", method: http.MethodPost, path: "/backups", expectedStatus: http.StatusUnauthorized},
+		{name: "list_as_viewer", method: http.MethodGet, path: "/backups", token: testViewerToken, expectedStatus: http.StatusOK},
+		{name: "trigger_as_viewer", method: http.MethodPost, path: "/backups", token: testViewerToken, expectedStatus: http.StatusForbidden},
+		{name: "admin_as_viewer", method: http.MethodDelete, path: "/admin/discovery-cache", token: testViewerToken, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(s, tt.method, tt.path, tt.token, "")
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestServer_TriggerAndQueryBackup(t *testing.T) {
+	release := make(chan struct{})
+	s := newTestServer(func(ctx context.Context) (*backup.BackupResult, error) {
+		<-release
+		return &backup.BackupResult{NamespacesBackedUp: 2, ResourcesBackedUp: 10}, nil
+	}, nil)
+
+	rec := doRequest(s, http.MethodPost, "/backups", testToken, "")
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	var run Run
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &run))
+	assert.Equal(t, RunTypeBackup, run.Type)
+	assert.NotEmpty(t, run.ID)
+
+	// A second trigger while the first is in progress is rejected
+	rec = doRequest(s, http.MethodPost, "/backups", testToken, "")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	close(release)
+	require.Eventually(t, func() bool {
+		current, ok := s.Runs().Get(run.ID)
+		return ok && !current.IsActive()
+	}, time.Second, 10*time.Millisecond)
+
+	rec = doRequest(s, http.MethodGet, "/backups/"+run.ID, testToken, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &run))
+	assert.Equal(t, RunStatusCompleted, run.Status)
+	assert.NotNil(t, run.EndTime)
+
+	rec = doRequest(s, http.MethodGet, "/backups/unknown", testToken, "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServer_BackupProgress(t *testing.T) {
+	release := make(chan struct{})
+	var s *Server
+	s = newTestServer(func(ctx context.Context) (*backup.BackupResult, error) {
+		progress := RecordProgress(ctx, s.Runs(), nil)
+		progress(backup.ProgressEvent{Stage: backup.ProgressNamespaceStarted, Namespace: "default"})
+		progress(backup.ProgressEvent{Stage: backup.ProgressRunProgress, Run: &backup.RunProgress{
+			ItemsDone:      40,
+			ItemsExpected:  160,
+			Percent:        25,
+			ItemsPerSecond: 2,
+			ETASeconds:     60,
+			EstimateSource: backup.EstimatePreviousRun,
+		}})
+		<-release
+		return &backup.BackupResult{}, nil
+	}, nil)
+	defer close(release)
+
+	rec := doRequest(s, http.MethodPost, "/backups", testToken, "")
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	var run Run
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &run))
+
+	require.Eventually(t, func() bool {
+		current, ok := s.Runs().Get(run.ID)
+		return ok && current.Progress != nil
+	}, time.Second, 10*time.Millisecond)
+
+	rec = doRequest(s, http.MethodGet, "/backups/"+run.ID, testToken, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var body struct {
+		Progress backup.RunProgress `json:"progress"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, 25.0, body.Progress.Percent)
+	assert.Equal(t, 60.0, body.Progress.ETASeconds)
+	assert.Equal(t, backup.EstimatePreviousRun, body.Progress.EstimateSource)
+}
+
+func TestServer_Restore(t *testing.T) {
+	t.Run("not_configured", func(t *testing.T) {
+		s := newTestServer(nil, nil)
+		rec := doRequest(s, http.MethodPost, "/restores", testToken, `{"backup_id":"b1"}`)
+		assert.Equal(t, http.StatusNotImplemented, rec.Code)
+	})
+
+	t.Run("missing_backup_id", func(t *testing.T) {
+		s := newTestServer(nil, func(ctx context.Context, req RestoreRequest) (interface{}, error) {
+			return nil, nil
+		})
+		rec := doRequest(s, http.MethodPost, "/restores", testToken, `{}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("accepted", func(t *testing.T) {
+		received := make(chan RestoreRequest, 1)
+		s := newTestServer(nil, func(ctx context.Context, req RestoreRequest) (interface{}, error) {
+			received <- req
+			return nil, nil
+		})
+		rec := doRequest(s, http.MethodPost, "/restores", testToken, `{"backup_id":"b1","dry_run":true}`)
+		require.Equal(t, http.StatusAccepted, rec.Code)
+
+		req := <-received
+		assert.Equal(t, "b1", req.BackupID)
+		assert.True(t, req.DryRun)
+
+		rec = doRequest(s, http.MethodPost, "/restores", testToken, `{"backup_id":"b1","force_unsafe":true,"confirmation_token":"0123456789abcdef"}`)
+		require.Equal(t, http.StatusAccepted, rec.Code)
+		req = <-received
+		assert.True(t, req.ForceUnsafe, "guarded resources can be forced")
+		assert.Equal(t, "0123456789abcdef", req.ConfirmationToken)
+	})
+}
+
+func TestServer_RestoreProgressAndCancel(t *testing.T) {
+	var s *Server
+	s = newTestServer(nil, func(ctx context.Context, req RestoreRequest) (interface{}, error) {
+		progress := RecordRestoreProgress(ctx, s.Runs())
+		progress(RestoreProgress{
+			Phase:           "resources",
+			Percent:         40,
+			ResourcesTotal:  10,
+			ResourcesDone:   4,
+			CurrentResource: "deployments/web",
+			FailedResources: []FailedResource{{Resource: "configmaps/settings", Namespace: "shop", Error: "denied"}},
+		})
+		<-ctx.Done()
+		return map[string]int{"restored": 3}, ctx.Err()
+	})
+
+	rec := doRequest(s, http.MethodPost, "/restores", testToken, `{"backup_id":"b1"}`)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	var run Run
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &run))
+
+	require.Eventually(t, func() bool {
+		current, ok := s.Runs().Get(run.ID)
+		return ok && current.Progress != nil
+	}, time.Second, 10*time.Millisecond)
+
+	rec = doRequest(s, http.MethodGet, "/restores/"+run.ID+"/progress", testToken, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var progress RestoreRunProgress
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &progress))
+	assert.Equal(t, RunStatusRunning, progress.Status)
+	require.NotNil(t, progress.Progress)
+	assert.Equal(t, "resources", progress.Progress.Phase)
+	assert.Equal(t, 40.0, progress.Progress.Percent)
+	assert.Len(t, progress.Progress.FailedResources, 1)
+
+	rec = doRequest(s, http.MethodPost, "/restores/"+run.ID+"/cancel", testToken, "")
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	require.Eventually(t, func() bool {
+		current, ok := s.Runs().Get(run.ID)
+		return ok && !current.IsActive()
+	}, time.Second, 10*time.Millisecond)
+
+	rec = doRequest(s, http.MethodGet, "/restores/"+run.ID, testToken, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &run))
+	assert.Equal(t, RunStatusCancelled, run.Status)
+	assert.Empty(t, run.Error, "stopping on cancellation is not an error")
+	assert.NotNil(t, run.Result, "a cancelled run keeps its partial result")
+
+	// Finished and unknown runs cannot be cancelled
+	rec = doRequest(s, http.MethodPost, "/restores/"+run.ID+"/cancel", testToken, "")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	rec = doRequest(s, http.MethodPost, "/restores/unknown/cancel", testToken, "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = doRequest(s, http.MethodGet, "/restores/unknown/progress", testToken, "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServer_RestoreApproval(t *testing.T) {
+	const operatorToken = "operator-token"
+	const approverToken = "approver-token"
+	started := make(chan RestoreRequest, 1)
+	approvals := make(chan *Approval, 2)
+	s := NewServer(context.Background(), ServerConfig{
+		Auth: auth.NewAuthenticator(map[auth.Role]string{auth.RoleAdmin: testToken, auth.RoleOperator: operatorToken, auth.RoleApprover: approverToken}, nil),
+	}, nil, nil, func(ctx context.Context, req RestoreRequest) (interface{}, error) {
+		approvals <- ApprovalFromContext(ctx)
+		started <- req
+		return nil, nil
+	}, logging.NewStructuredLogger("api-test", "test-cluster"))
+	sink := &recordingSink{}
+	failures := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_approval_audit_failures_total"})
+	s.Runs().SetAuditLog(audit.NewLog("test-cluster", logging.NewStructuredLogger("api-test", "test-cluster"), failures, sink))
+	s.Runs().RequireRestoreApproval(time.Hour)
+
+	rec := doRequest(s, http.MethodPost, "/restores", testToken, `{"backup_id":"b1"}`)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	var run Run
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &run))
+	assert.Equal(t, RunStatusAwaitingApproval, run.Status)
+	require.NotNil(t, run.Approval)
+	assert.Equal(t, audit.TokenID(testToken), run.Approval.RequestedBy.ID)
+
+	// The requester cannot approve their own restore
+	rec = doRequest(s, http.MethodPost, "/restores/"+run.ID+"/approve", testToken, "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	select {
+	case <-started:
+		t.Fatal("restore started without approval")
+	default:
+	}
+
+	// Other static tokens may be shared with the requester
+	rec = doRequest(s, http.MethodPost, "/restores/"+run.ID+"/approve", operatorToken, "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = doRequest(s, http.MethodPost, "/restores/"+run.ID+"/approve", approverToken, "")
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "b1", (<-started).BackupID)
+	approval := <-approvals
+	require.NotNil(t, approval, "the restore function is passed the approval")
+	assert.Equal(t, audit.TokenID(testToken), approval.RequestedBy.ID)
+	assert.Equal(t, audit.TokenID(approverToken), approval.ApprovedBy.ID)
+	require.Eventually(t, func() bool {
+		current, ok := s.Runs().Get(run.ID)
+		return ok && current.Status == RunStatusCompleted
+	}, time.Second, 10*time.Millisecond)
+
+	rec = doRequest(s, http.MethodPost, "/restores/"+run.ID+"/approve", approverToken, "")
+	assert.Equal(t, http.StatusConflict, rec.Code, "a run is approved once")
+	rec = doRequest(s, http.MethodPost, "/restores/unknown/approve", approverToken, "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	require.Eventually(t, func() bool { return len(sink.Records()) == 3 }, time.Second, 10*time.Millisecond)
+	records := sink.Records()
+	assert.Equal(t, audit.OutcomeAwaitingApproval, records[0].Outcome)
+	assert.Equal(t, audit.OperationRestoreApprove, records[1].Operation)
+	assert.Equal(t, audit.TokenID(testToken), records[1].RequestedBy.ID)
+	assert.Equal(t, audit.OperationRestore, records[2].Operation)
+	assert.Equal(t, audit.OutcomeSucceeded, records[2].Outcome)
+	require.NotNil(t, records[2].ApprovedBy)
+	assert.Equal(t, audit.TokenID(approverToken), records[2].ApprovedBy.ID)
+
+	// Cancelling rejects a pending request, and dry runs need no approval
+	rec = doRequest(s, http.MethodPost, "/restores", testToken, `{"backup_id":"b2"}`)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &run))
+	rec = doRequest(s, http.MethodPost, "/restores/"+run.ID+"/cancel", operatorToken, "")
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	current, _ := s.Runs().Get(run.ID)
+	assert.Equal(t, RunStatusCancelled, current.Status)
+
+	rec = doRequest(s, http.MethodPost, "/restores", testToken, `{"backup_id":"b3","dry_run":true}`)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Equal(t, "b3", (<-started).BackupID)
+	assert.Nil(t, <-approvals)
+}
+
+func TestRunRegistry_ApprovalExpires(t *testing.T) {
+	runs := NewRunRegistry(10)
+	runs.RequireRestoreApproval(20 * time.Millisecond)
+	expired := make(chan *Run, 1)
+	run := runs.awaitApproval(audit.Actor{ID: "alice"}, func(string, audit.Actor) {
+		t.Error("an expired restore must not start")
+	}, func(run *Run) { expired <- run })
+
+	failed := <-expired
+	assert.Equal(t, RunStatusFailed, failed.Status)
+	assert.Contains(t, failed.Error, "not approved within")
+	_, err := runs.Approve(run.ID, audit.Actor{ID: "bob"})
+	assert.ErrorIs(t, err, ErrRunNotAwaitingApproval)
+}
+
+func TestServer_InvalidateDiscoveryCache(t *testing.T) {
+	s := newTestServer(nil, nil)
+
+	rec := doRequest(s, http.MethodDelete, "/admin/discovery-cache", testToken, "")
+	assert.Equal(t, http.StatusNotImplemented, rec.Code, "the cache is disabled")
+
+	invalidated := 0
+	s.SetDiscoveryCacheInvalidator(func() error {
+		invalidated++
+		return nil
+	})
+	rec = doRequest(s, http.MethodDelete, "/admin/discovery-cache", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	rec = doRequest(s, http.MethodDelete, "/admin/discovery-cache", testToken, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 1, invalidated)
+}
+
+type recordingSink struct {
+	mu      sync.Mutex
+	records []audit.Record
+}
+
+func (s *recordingSink) Name() string { return "recording" }
+
+func (s *recordingSink) Write(ctx context.Context, record audit.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+func (s *recordingSink) Records() []audit.Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]audit.Record(nil), s.records...)
+}
+
+func TestServer_AuditsBackupRuns(t *testing.T) {
+	s := newTestServer(func(ctx context.Context) (*backup.BackupResult, error) {
+		return &backup.BackupResult{BackupID: "backup-1", NamespacesBackedUp: 2, ResourcesBackedUp: 10}, nil
+	}, nil)
+	sink := &recordingSink{}
+	failures := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_audit_failures_total"})
+	s.Runs().SetAuditLog(audit.NewLog("test-cluster", logging.NewStructuredLogger("api-test", "test-cluster"), failures, sink))
+
+	rec := doRequest(s, http.MethodPost, "/backups", testToken, "")
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	var run Run
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &run))
+
+	require.Eventually(t, func() bool { return len(sink.Records()) == 1 }, time.Second, 10*time.Millisecond)
+	record := sink.Records()[0]
+	assert.Equal(t, audit.OperationBackup, record.Operation)
+	assert.Equal(t, audit.OutcomeSucceeded, record.Outcome)
+	assert.Equal(t, run.ID, record.RunID)
+	assert.Equal(t, "backup-1", record.BackupID)
+	assert.Equal(t, audit.TokenID(testToken), record.Actor.ID)
+	assert.Equal(t, string(auth.RoleAdmin), record.Actor.Role)
+	assert.Equal(t, "rest", record.Actor.Via)
+	assert.Equal(t, 10, record.Resources.ResourceCount)
+}
+
+func TestServer_ProtectHealth(t *testing.T) {
+	s := newTestServer(nil, nil)
+	assert.Equal(t, http.StatusOK, doRequest(s, http.MethodGet, "/health", "", "").Code, "open for liveness probes by default")
+
+	s = newTestServerWithConfig(ServerConfig{ProtectHealth: true}, nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, doRequest(s, http.MethodGet, "/health", "", "").Code)
+	assert.Equal(t, http.StatusOK, doRequest(s, http.MethodGet, "/health", testViewerToken, "").Code)
+}
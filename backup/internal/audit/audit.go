@@ -0,0 +1,256 @@
+// Package audit records who ran which backup and restore operations, when,
+// and what they touched, for compliance. Records go to sinks kept apart from
+// the service's own logs: an object of their own in a bucket, which is never
+// overwritten, or a SIEM's HTTP collector.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"cluster-backup/internal/logging"
+)
+
+// writeTimeout bounds writing a record to all sinks
+const writeTimeout = 10 * time.Second
+
+// Operations
+const (
+	OperationBackup        = "backup"
+	OperationRestore       = "restore"
+	OperationRestoreCancel = "restore.cancel"
+	// OperationRestoreApprove is the approval of a restore requested by
+	// someone else
+	OperationRestoreApprove = "restore.approve"
+)
+
+// Outcomes
+const (
+	// OutcomeSucceeded is an operation that finished without errors
+	OutcomeSucceeded = "succeeded"
+	// OutcomeFailed is an operation that failed or finished with errors
+	OutcomeFailed = "failed"
+	// OutcomeRequested is an operation that was accepted and carries on in
+	// the background
+	OutcomeRequested = "requested"
+	// OutcomeAwaitingApproval is a restore that waits for a second person's
+	// approval
+	OutcomeAwaitingApproval = "awaiting_approval"
+)
+
+// Actor is who started an operation
+type Actor struct {
+	// ID is token:{fingerprint} for callers of the APIs with a static token,
+	// identifying it without revealing it, oidc:{subject} for callers with an
+	// OIDC token, and system for the service's own runs
+	ID string `json:"id"`
+	// Role is the role the caller was authorized with
+	Role string `json:"role,omitempty"`
+	// Via is how the operation was started: rest, grpc, dashboard, or for
+	// the service's own runs e.g. schedule
+	Via     string `json:"via"`
+	Address string `json:"address,omitempty"`
+}
+
+// TokenID returns the actor ID of the holder of a static API token
+func TokenID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "token:" + hex.EncodeToString(sum[:])[:12]
+}
+
+// SystemActor returns the actor of an operation the service started itself
+func SystemActor(via string) Actor {
+	return Actor{ID: "system", Via: via}
+}
+
+type actorKey struct{}
+
+// WithActor returns a context the operations started with are recorded as
+// started by actor
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor of ctx, the service itself when there is none
+func ActorFrom(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorKey{}).(Actor); ok {
+		return actor
+	}
+	return SystemActor("service")
+}
+
+// Resources is what an operation touched. A backup records the namespaces
+// and resources it stored; a restore the namespaces and types requested.
+type Resources struct {
+	Namespaces     []string `json:"namespaces,omitempty"`
+	ResourceTypes  []string `json:"resource_types,omitempty"`
+	NamespaceCount int      `json:"namespace_count,omitempty"`
+	ResourceCount  int      `json:"resource_count,omitempty"`
+}
+
+// Record is the audit record of one operation
+type Record struct {
+	ID        string     `json:"id"`
+	Time      time.Time  `json:"time"`
+	Cluster   string     `json:"cluster"`
+	Operation string     `json:"operation"`
+	Actor     Actor      `json:"actor"`
+	Outcome   string     `json:"outcome"`
+	Error     string     `json:"error,omitempty"`
+	RunID     string     `json:"run_id,omitempty"`
+	BackupID  string     `json:"backup_id,omitempty"`
+	DryRun    bool       `json:"dry_run,omitempty"`
+	Resources *Resources `json:"resources,omitempty"`
+	// RequestedBy is who requested the restore an approval is of
+	RequestedBy *Actor `json:"requested_by,omitempty"`
+	// ApprovedBy is who approved a restore that needed approval
+	ApprovedBy *Actor `json:"approved_by,omitempty"`
+}
+
+// Sink stores audit records
+type Sink interface {
+	// Name identifies the sink in logs
+	Name() string
+	Write(ctx context.Context, record Record) error
+}
+
+// Log writes audit records to its sinks. A nil Log records nothing.
+type Log struct {
+	cluster  string
+	sinks    []Sink
+	logger   *logging.StructuredLogger
+	failures prometheus.Counter
+}
+
+// NewLog returns a log writing the records of cluster to sinks. A record a
+// sink cannot store is logged and counted in failures.
+func NewLog(cluster string, logger *logging.StructuredLogger, failures prometheus.Counter, sinks ...Sink) *Log {
+	return &Log{cluster: cluster, sinks: sinks, logger: logger, failures: failures}
+}
+
+// Record completes record with its ID, time, cluster and, unless set, the
+// actor of ctx, and writes it to every sink. Writing outlives ctx, so the
+// operations of a cancelled request are recorded too.
+func (l *Log) Record(ctx context.Context, record Record) {
+	if l == nil {
+		return
+	}
+	record.ID = newRecordID()
+	record.Time = time.Now().UTC()
+	record.Cluster = l.cluster
+	if record.Actor.ID == "" {
+		record.Actor = ActorFrom(ctx)
+	}
+
+	writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), writeTimeout)
+	defer cancel()
+	for _, sink := range l.sinks {
+		if err := sink.Write(writeCtx, record); err != nil {
+			l.failures.Inc()
+			l.logger.Error("audit_write_failed", "Failed to write an audit record", map[string]interface{}{
+				"sink":      sink.Name(),
+				"operation": record.Operation,
+				"record_id": record.ID,
+				"error":     err.Error(),
+			})
+		}
+	}
+}
+
+func newRecordID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(id)
+}
+
+// BucketSink stores each record as a JSON object of its own, at the key
+// returned by key. Objects are written only when absent, so a record is never
+// overwritten; object lock on the bucket keeps them from being deleted.
+type BucketSink struct {
+	client *minio.Client
+	bucket string
+	key    func(Record) string
+}
+
+// NewBucketSink returns a sink storing records in bucket
+func NewBucketSink(client *minio.Client, bucket string, key func(Record) string) *BucketSink {
+	return &BucketSink{client: client, bucket: bucket, key: key}
+}
+
+// Name identifies the sink in logs
+func (s *BucketSink) Name() string {
+	return "bucket"
+}
+
+// Write stores record
+func (s *BucketSink) Write(ctx context.Context, record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to serialize audit record: %v", err)
+	}
+	opts := minio.PutObjectOptions{ContentType: "application/json"}
+	opts.SetMatchETagExcept("*")
+	key := s.key(record)
+	if _, err := s.client.PutObject(ctx, s.bucket, key, bytes.NewReader(data), int64(len(data)), opts); err != nil {
+		return fmt.Errorf("failed to write %s: %v", key, err)
+	}
+	return nil
+}
+
+// WebhookSink posts each record as JSON to an HTTP endpoint, with the bearer
+// token when one is set
+type WebhookSink struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewWebhookSink returns a sink posting records to url
+func NewWebhookSink(url, token string) *WebhookSink {
+	return &WebhookSink{url: url, token: token, client: &http.Client{Timeout: writeTimeout}}
+}
+
+// Name identifies the sink in logs
+func (s *WebhookSink) Name() string {
+	return "webhook"
+}
+
+// Write posts record
+func (s *WebhookSink) Write(ctx context.Context, record Record) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to serialize audit record: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("audit endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
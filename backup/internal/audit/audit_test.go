@@ -0,0 +1,137 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/logging"
+)
+
+type fakeSink struct {
+	records []Record
+	err     error
+}
+
+func (s *fakeSink) Name() string { return "fake" }
+
+func (s *fakeSink) Write(ctx context.Context, record Record) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.records = append(s.records, record)
+	return nil
+}
+
+func TestTokenID(t *testing.T) {
+	id := TokenID("secret-token")
+	assert.True(t, strings.HasPrefix(id, "token:"))
+	assert.Len(t, id, len("token:")+12)
+	assert.NotContains(t, id, "secret")
+	assert.Equal(t, id, TokenID("secret-token"))
+	assert.NotEqual(t, id, TokenID("other-token"))
+
+	actor := Actor{ID: id, Role: "operator", Via: "rest", Address: "10.0.0.1:4321"}
+	assert.Equal(t, SystemActor("service"), ActorFrom(context.Background()))
+	assert.Equal(t, actor, ActorFrom(WithActor(context.Background(), actor)))
+}
+
+func TestLog_Record(t *testing.T) {
+	failures := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_audit_failures_total"})
+	sink, broken := &fakeSink{}, &fakeSink{err: errors.New("unreachable")}
+	log := NewLog("prod", logging.NewStructuredLogger("audit-test", "prod"), failures, broken, sink)
+
+	ctx, cancel := context.WithCancel(WithActor(context.Background(), SystemActor("schedule")))
+	cancel()
+	log.Record(ctx, Record{Operation: OperationBackup, Outcome: OutcomeSucceeded, BackupID: "backup-1"})
+
+	require.Len(t, sink.records, 1, "a cancelled request is recorded too")
+	record := sink.records[0]
+	assert.NotEmpty(t, record.ID)
+	assert.False(t, record.Time.IsZero())
+	assert.Equal(t, "prod", record.Cluster)
+	assert.Equal(t, SystemActor("schedule"), record.Actor)
+	assert.Equal(t, "backup-1", record.BackupID)
+	assert.Equal(t, 1.0, testutil.ToFloat64(failures))
+
+	var none *Log
+	none.Record(ctx, Record{Operation: OperationBackup})
+}
+
+func TestWebhookSink_Write(t *testing.T) {
+	var received Record
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer siem-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	record := Record{ID: "abc", Operation: OperationRestore, Actor: SystemActor("schedule")}
+	require.NoError(t, NewWebhookSink(server.URL, "siem-token").Write(context.Background(), record))
+	assert.Equal(t, record.ID, received.ID)
+	assert.Equal(t, record.Operation, received.Operation)
+
+	err := NewWebhookSink(server.URL, "wrong").Write(context.Background(), record)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+}
+
+// createOnlyBucket stores objects, refusing to overwrite one when asked
+// with If-None-Match
+type createOnlyBucket struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (b *createOnlyBucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	if _, exists := b.objects[r.URL.Path]; exists && r.Header.Get("If-None-Match") == "*" {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+	data, _ := io.ReadAll(r.Body)
+	b.objects[r.URL.Path] = data
+	w.Header().Set("ETag", `"etag"`)
+}
+
+func TestBucketSink_Write(t *testing.T) {
+	bucket := &createOnlyBucket{objects: map[string][]byte{}}
+	server := httptest.NewServer(bucket)
+	defer server.Close()
+	client, err := minio.New(strings.TrimPrefix(server.URL, "http://"), &minio.Options{
+		Creds:  credentials.NewStaticV4("key", "secret", ""),
+		Region: "us-east-1",
+	})
+	require.NoError(t, err)
+
+	sink := NewBucketSink(client, "audit", func(r Record) string { return "_audit/" + r.ID + ".json" })
+	record := Record{ID: "abc", Operation: OperationBackup, Outcome: OutcomeSucceeded}
+	require.NoError(t, sink.Write(context.Background(), record))
+	assert.Contains(t, string(bucket.objects["/audit/_audit/abc.json"]), `"operation":"backup"`)
+
+	record.Outcome = OutcomeFailed
+	assert.Error(t, sink.Write(context.Background(), record), "a record is never overwritten")
+	assert.Contains(t, string(bucket.objects["/audit/_audit/abc.json"]), `"outcome":"succeeded"`)
+}
@@ -0,0 +1,142 @@
+// Package auth authenticates callers of the REST, gRPC and metrics endpoints
+// by bearer token, either a static token configured for a role or an OIDC ID
+// token, and authorizes them by role.
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"cluster-backup/internal/audit"
+)
+
+// Role is what a caller may do. Each role may do everything the roles
+// below it may.
+type Role string
+
+const (
+	// RoleViewer reads runs, backups, metrics and health
+	RoleViewer Role = "viewer"
+	// RoleOperator also starts backups and restores and cancels restores
+	RoleOperator Role = "operator"
+	// RoleAdmin also manages the service: the discovery cache and profiling
+	RoleAdmin Role = "admin"
+	// RoleApprover may do what an operator may. Its static token is the one
+	// restores awaiting approval are approved with.
+	RoleApprover Role = "approver"
+)
+
+var roleRanks = map[Role]int{RoleViewer: 1, RoleOperator: 2, RoleApprover: 2, RoleAdmin: 3}
+
+// Allows reports whether r may do what required may
+func (r Role) Allows(required Role) bool {
+	rank, ok := roleRanks[r]
+	return ok && rank >= roleRanks[required]
+}
+
+// Errors returned by Authorize
+var (
+	// ErrUnauthenticated is a request without a valid token
+	ErrUnauthenticated = errors.New("unauthorized")
+	// ErrForbidden is a request whose caller lacks the route's role
+	ErrForbidden = errors.New("forbidden")
+)
+
+// HTTPStatus returns the status code and message of a request Authorize
+// rejected with err. The message leaves out why, which is for the logs.
+func HTTPStatus(err error) (int, string) {
+	if errors.Is(err, ErrForbidden) {
+		return http.StatusForbidden, ErrForbidden.Error()
+	}
+	return http.StatusUnauthorized, ErrUnauthenticated.Error()
+}
+
+// Principal is an authenticated caller
+type Principal struct {
+	// ID is token:{fingerprint} for static tokens and oidc:{subject} for
+	// OIDC tokens
+	ID   string
+	Role Role
+}
+
+// Actor returns the principal as the audit actor of the operations it starts
+func (p Principal) Actor(via, address string) audit.Actor {
+	return audit.Actor{ID: p.ID, Role: string(p.Role), Via: via, Address: address}
+}
+
+// CanApprove reports whether actor is an identity restores can be approved
+// by: the holder of the approver's token, or an OIDC caller, whose subject
+// tells people apart. Every other static token may be shared by everyone who
+// requests restores.
+func CanApprove(actor audit.Actor) bool {
+	return actor.Role == string(RoleApprover) || strings.HasPrefix(actor.ID, oidcIDPrefix)
+}
+
+type staticToken struct {
+	token []byte
+	role  Role
+}
+
+// Authenticator authenticates bearer tokens. A nil Authenticator rejects
+// every request.
+type Authenticator struct {
+	tokens []staticToken
+	oidc   *OIDCVerifier
+}
+
+// NewAuthenticator returns an authenticator accepting the static token of
+// each role in tokens, empty ones aside, and, when oidc is set, OIDC ID tokens
+func NewAuthenticator(tokens map[Role]string, oidc *OIDCVerifier) *Authenticator {
+	a := &Authenticator{oidc: oidc}
+	for role, token := range tokens {
+		if token != "" {
+			a.tokens = append(a.tokens, staticToken{token: []byte(token), role: role})
+		}
+	}
+	return a
+}
+
+// Authenticate returns the caller holding token
+func (a *Authenticator) Authenticate(ctx context.Context, token string) (Principal, error) {
+	if a == nil || token == "" {
+		return Principal{}, ErrUnauthenticated
+	}
+	// Every token is compared, so the time taken does not tell which matched
+	var principal Principal
+	for _, static := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), static.token) == 1 {
+			principal = Principal{ID: audit.TokenID(token), Role: static.role}
+		}
+	}
+	if principal.ID != "" {
+		return principal, nil
+	}
+	if a.oidc != nil && strings.Count(token, ".") == 2 {
+		principal, err := a.oidc.Verify(ctx, token)
+		if err != nil {
+			return Principal{}, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+		}
+		return principal, nil
+	}
+	return Principal{}, ErrUnauthenticated
+}
+
+// Authorize authenticates the bearer token of an Authorization header and
+// checks its holder has role
+func (a *Authenticator) Authorize(ctx context.Context, authorization string, role Role) (Principal, error) {
+	principal, err := a.Authenticate(ctx, strings.TrimPrefix(authorization, "Bearer "))
+	if err != nil {
+		return Principal{}, err
+	}
+	if !principal.Role.Allows(role) {
+		if principal.Role == "" {
+			return principal, fmt.Errorf("%w: %s has no role", ErrForbidden, principal.ID)
+		}
+		return principal, fmt.Errorf("%w: %s is %s, %s required", ErrForbidden, principal.ID, principal.Role, role)
+	}
+	return principal, nil
+}
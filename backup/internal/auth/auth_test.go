@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/audit"
+)
+
+func TestRole_Allows(t *testing.T) {
+	assert.True(t, RoleAdmin.Allows(RoleOperator))
+	assert.True(t, RoleOperator.Allows(RoleOperator))
+	assert.True(t, RoleOperator.Allows(RoleViewer))
+	assert.True(t, RoleApprover.Allows(RoleOperator))
+	assert.False(t, RoleApprover.Allows(RoleAdmin))
+	assert.False(t, RoleViewer.Allows(RoleOperator))
+	assert.False(t, RoleOperator.Allows(RoleAdmin))
+	assert.False(t, Role("").Allows(RoleViewer))
+	assert.False(t, Role("superuser").Allows(RoleViewer))
+}
+
+func TestCanApprove(t *testing.T) {
+	assert.True(t, CanApprove(Principal{ID: audit.TokenID("approver-token"), Role: RoleApprover}.Actor("rest", "")))
+	assert.True(t, CanApprove(Principal{ID: "oidc:alice", Role: RoleOperator}.Actor("rest", "")))
+	assert.False(t, CanApprove(Principal{ID: audit.TokenID("admin-token"), Role: RoleAdmin}.Actor("rest", "")),
+		"the admin's token may be the requester's too")
+	assert.False(t, CanApprove(Principal{ID: audit.TokenID("operator-token"), Role: RoleOperator}.Actor("rest", "")))
+}
+
+func TestAuthenticator_Authorize(t *testing.T) {
+	ctx := context.Background()
+	a := NewAuthenticator(map[Role]string{RoleAdmin: "admin-token", RoleViewer: "viewer-token", RoleOperator: ""}, nil)
+
+	principal, err := a.Authorize(ctx, "Bearer admin-token", RoleAdmin)
+	require.NoError(t, err)
+	assert.Equal(t, Principal{ID: audit.TokenID("admin-token"), Role: RoleAdmin}, principal)
+	assert.Equal(t, audit.Actor{ID: principal.ID, Role: "admin", Via: "rest", Address: "10.0.0.1:1234"}, principal.Actor("rest", "10.0.0.1:1234"))
+
+	_, err = a.Authorize(ctx, "Bearer viewer-token", RoleViewer)
+	assert.NoError(t, err)
+
+	_, err = a.Authorize(ctx, "Bearer viewer-token", RoleOperator)
+	assert.ErrorIs(t, err, ErrForbidden)
+	status, message := HTTPStatus(err)
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Equal(t, "forbidden", message)
+
+	for _, header := range []string{"", "Bearer ", "Bearer wrong", "admin-token-and-more"} {
+		_, err = a.Authorize(ctx, header, RoleViewer)
+		assert.ErrorIs(t, err, ErrUnauthenticated, header)
+	}
+	status, message = HTTPStatus(err)
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "unauthorized", message)
+
+	var none *Authenticator
+	_, err = none.Authorize(ctx, "Bearer admin-token", RoleViewer)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+}
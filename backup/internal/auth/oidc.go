@@ -0,0 +1,365 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// oidcIDPrefix starts the principal ID of OIDC callers, followed by
+	// their subject
+	oidcIDPrefix = "oidc:"
+	// clockSkew is how far the expiry and not-before times of a token may
+	// be off the local clock
+	clockSkew = time.Minute
+	// keysMaxAge is how long the issuer's signing keys are used before they
+	// are fetched again
+	keysMaxAge = time.Hour
+	// keysMinRefresh is how long after fetching them the keys are not
+	// fetched again for a token signed with an unknown key, so forged
+	// tokens cannot flood the issuer
+	keysMinRefresh = time.Minute
+)
+
+// signatureHashes are the JWS algorithms ID tokens may be signed with
+var signatureHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"PS256": crypto.SHA256, "PS384": crypto.SHA384, "PS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+}
+
+// roleOrder is the order roles are granted in when a caller's claim matches
+// several
+var roleOrder = []Role{RoleAdmin, RoleApprover, RoleOperator, RoleViewer}
+
+// OIDCConfig is how ID tokens are validated and given their role
+type OIDCConfig struct {
+	// Issuer is the issuer URL; the signing keys are found through its
+	// discovery document
+	Issuer string
+	// Audience must be one of the token's audiences, usually the client ID
+	Audience string
+	// RolesClaim is the claim listing the caller's groups or roles, a
+	// dotted path for nested claims such as realm_access.roles
+	RolesClaim string
+	// Roles lists the claim values granting each role
+	Roles map[Role][]string
+}
+
+// OIDCVerifier validates ID tokens signed by an OIDC issuer
+type OIDCVerifier struct {
+	config OIDCConfig
+	client *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// NewOIDCVerifier returns a verifier of the tokens of the issuer in cfg,
+// fetching its keys with client, or a default one when nil
+func NewOIDCVerifier(cfg OIDCConfig, client *http.Client) *OIDCVerifier {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+	return &OIDCVerifier{config: cfg, client: client}
+}
+
+// Verify checks the signature, issuer, audience and validity period of
+// token, and returns its subject with the role its roles claim grants
+func (v *OIDCVerifier) Verify(ctx context.Context, token string) (Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, fmt.Errorf("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Principal{}, fmt.Errorf("malformed token header: %v", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, fmt.Errorf("malformed token signature: %v", err)
+	}
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return Principal{}, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return Principal{}, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Principal{}, fmt.Errorf("malformed token claims: %v", err)
+	}
+	if issuer, _ := claims["iss"].(string); strings.TrimSuffix(issuer, "/") != v.config.Issuer {
+		return Principal{}, fmt.Errorf("token issued by %q, not %q", issuer, v.config.Issuer)
+	}
+	if !containsString(claimStrings(claims["aud"]), v.config.Audience) {
+		return Principal{}, fmt.Errorf("token not issued for audience %q", v.config.Audience)
+	}
+	now := time.Now()
+	expiry, ok := claims["exp"].(float64)
+	if !ok {
+		return Principal{}, fmt.Errorf("token has no expiry")
+	}
+	if now.After(time.Unix(int64(expiry), 0).Add(clockSkew)) {
+		return Principal{}, fmt.Errorf("token expired")
+	}
+	if notBefore, ok := claims["nbf"].(float64); ok && now.Add(clockSkew).Before(time.Unix(int64(notBefore), 0)) {
+		return Principal{}, fmt.Errorf("token not valid yet")
+	}
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return Principal{}, fmt.Errorf("token has no subject")
+	}
+
+	return Principal{ID: oidcIDPrefix + subject, Role: v.role(claims)}, nil
+}
+
+// role returns the highest role granted by a value of the roles claim, none
+// when no value grants one
+func (v *OIDCVerifier) role(claims map[string]interface{}) Role {
+	var value interface{} = claims
+	for _, name := range strings.Split(v.config.RolesClaim, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = object[name]
+	}
+	granted := claimStrings(value)
+	for _, role := range roleOrder {
+		for _, grant := range v.config.Roles[role] {
+			if containsString(granted, grant) {
+				return role
+			}
+		}
+	}
+	return ""
+}
+
+// key returns the issuer's signing key kid, fetching the keys when it is not
+// known or they are old
+func (v *OIDCVerifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	key, known := v.lookup(kid)
+	if known && time.Since(v.fetched) < keysMaxAge {
+		return key, nil
+	}
+	if known || v.fetched.IsZero() || time.Since(v.fetched) >= keysMinRefresh {
+		if err := v.fetchKeys(ctx); err != nil {
+			// A key fetched before is still good while the issuer is down
+			if known {
+				return key, nil
+			}
+			return nil, err
+		}
+		key, known = v.lookup(kid)
+	}
+	if !known {
+		return nil, fmt.Errorf("token signed with unknown key %q", kid)
+	}
+	return key, nil
+}
+
+// lookup returns key kid; a token without a kid uses the only key there is
+func (v *OIDCVerifier) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true
+		}
+	}
+	key, ok := v.keys[kid]
+	return key, ok
+}
+
+// fetchKeys reads the issuer's signing keys from the JWKS its discovery
+// document points to
+func (v *OIDCVerifier) fetchKeys(ctx context.Context) error {
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := v.getJSON(ctx, v.config.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return fmt.Errorf("failed to read the OIDC discovery document: %v", err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != v.config.Issuer {
+		return fmt.Errorf("OIDC discovery document is for issuer %q, not %q", discovery.Issuer, v.config.Issuer)
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := v.getJSON(ctx, discovery.JWKSURI, &set); err != nil {
+		return fmt.Errorf("failed to read the OIDC signing keys: %v", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// Keys of other types are of no use to verify tokens, not an error
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	v.keys = keys
+	v.fetched = time.Now()
+	return nil
+}
+
+func (v *OIDCVerifier) getJSON(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// jsonWebKey is an RSA or EC public key of a JWKS
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// verifySignature checks signature of signed with key, by the JWS algorithm alg
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	hash, ok := signatureHashes[alg]
+	if !ok {
+		return fmt.Errorf("unsupported token algorithm %q", alg)
+	}
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		switch {
+		case strings.HasPrefix(alg, "RS"):
+			if rsa.VerifyPKCS1v15(key, hash, digest, signature) == nil {
+				return nil
+			}
+		case strings.HasPrefix(alg, "PS"):
+			if rsa.VerifyPSS(key, hash, digest, signature, nil) == nil {
+				return nil
+			}
+		}
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if strings.HasPrefix(alg, "ES") && len(signature) == 2*size {
+			r := new(big.Int).SetBytes(signature[:size])
+			s := new(big.Int).SetBytes(signature[size:])
+			if ecdsa.Verify(key, digest, r, s) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("invalid token signature")
+}
+
+func decodeSegment(segment string, out interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func decodeInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// claimStrings returns a claim that is a string or a list of them as a list
+func claimStrings(claim interface{}) []string {
+	switch claim := claim.(type) {
+	case string:
+		return []string{claim}
+	case []interface{}:
+		values := make([]string, 0, len(claim))
+		for _, value := range claim {
+			if value, ok := value.(string); ok {
+				values = append(values, value)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testIssuer serves an OIDC discovery document and JWKS, and signs tokens
+// with its RSA and EC keys
+type testIssuer struct {
+	server     *httptest.Server
+	rsaKey     *rsa.PrivateKey
+	ecKey      *ecdsa.PrivateKey
+	keyFetches atomic.Int32
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	issuer := &testIssuer{rsaKey: rsaKey, ecKey: ecKey}
+
+	encode := func(n *big.Int) string { return base64.RawURLEncoding.EncodeToString(n.Bytes()) }
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": issuer.server.URL, "jwks_uri": issuer.server.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		issuer.keyFetches.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa", "use": "sig", "n": encode(rsaKey.N), "e": encode(big.NewInt(int64(rsaKey.E)))},
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": encode(ecKey.X), "y": encode(ecKey.Y)},
+			{"kty": "oct", "kid": "symmetric", "k": "c2VjcmV0"},
+		}})
+	})
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (i *testIssuer) sign(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	segment := func(value interface{}) string {
+		data, err := json.Marshal(value)
+		require.NoError(t, err)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := segment(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + segment(claims)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	var err error
+	switch alg {
+	case "RS256":
+		signature, err = rsa.SignPKCS1v15(rand.Reader, i.rsaKey, crypto.SHA256, digest[:])
+	case "ES256":
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, i.ecKey, digest[:])
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	default:
+		signature = []byte("unsigned")
+	}
+	require.NoError(t, err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func (i *testIssuer) claims(overrides map[string]interface{}) map[string]interface{} {
+	claims := map[string]interface{}{
+		"iss":    i.server.URL,
+		"aud":    []string{"cluster-backup", "other-client"},
+		"sub":    "alice",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"groups": []string{"developers", "sre"},
+	}
+	for name, value := range overrides {
+		if value == nil {
+			delete(claims, name)
+			continue
+		}
+		claims[name] = value
+	}
+	return claims
+}
+
+func newTestVerifier(issuer *testIssuer, rolesClaim string) *OIDCVerifier {
+	return NewOIDCVerifier(OIDCConfig{
+		Issuer:     issuer.server.URL + "/",
+		Audience:   "cluster-backup",
+		RolesClaim: rolesClaim,
+		Roles: map[Role][]string{
+			RoleAdmin:    {"platform-admins"},
+			RoleOperator: {"sre"},
+			RoleViewer:   {"developers"},
+			RoleApprover: {"restore-approvers"},
+		},
+	}, issuer.server.Client())
+}
+
+func TestOIDCVerifier_Verify(t *testing.T) {
+	ctx := context.Background()
+	issuer := newTestIssuer(t)
+	verifier := newTestVerifier(issuer, "groups")
+
+	principal, err := verifier.Verify(ctx, issuer.sign(t, "RS256", "rsa", issuer.claims(nil)))
+	require.NoError(t, err)
+	assert.Equal(t, Principal{ID: "oidc:alice", Role: RoleOperator}, principal, "the highest role granted")
+
+	principal, err = verifier.Verify(ctx, issuer.sign(t, "ES256", "ec", issuer.claims(map[string]interface{}{"groups": "developers"})))
+	require.NoError(t, err)
+	assert.Equal(t, RoleViewer, principal.Role)
+
+	principal, err = verifier.Verify(ctx, issuer.sign(t, "RS256", "rsa", issuer.claims(map[string]interface{}{"groups": []string{"sre", "restore-approvers"}})))
+	require.NoError(t, err)
+	assert.Equal(t, RoleApprover, principal.Role, "approvers may do what operators may")
+	assert.True(t, CanApprove(principal.Actor("rest", "")))
+
+	principal, err = verifier.Verify(ctx, issuer.sign(t, "RS256", "rsa", issuer.claims(map[string]interface{}{"groups": nil})))
+	require.NoError(t, err)
+	assert.Equal(t, Role(""), principal.Role, "a valid token without a granting group has no role")
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "other_audience", token: issuer.sign(t, "RS256", "rsa", issuer.claims(map[string]interface{}{"aud": "other-client"}))},
+		{name: "other_issuer", token: issuer.sign(t, "RS256", "rsa", issuer.claims(map[string]interface{}{"iss": "https://evil.example.com"}))},
+		{name: "expired", token: issuer.sign(t, "RS256", "rsa", issuer.claims(map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()}))},
+		{name: "without_expiry", token: issuer.sign(t, "RS256", "rsa", issuer.claims(map[string]interface{}{"exp": nil}))},
+		{name: "not_valid_yet", token: issuer.sign(t, "RS256", "rsa", issuer.claims(map[string]interface{}{"nbf": time.Now().Add(time.Hour).Unix()}))},
+		{name: "without_subject", token: issuer.sign(t, "RS256", "rsa", issuer.claims(map[string]interface{}{"sub": nil}))},
+		{name: "signed_with_other_key", token: issuer.sign(t, "ES256", "rsa", issuer.claims(nil))},
+		{name: "unsupported_algorithm", token: issuer.sign(t, "HS256", "rsa", issuer.claims(nil))},
+		{name: "malformed", token: "not.a.token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := verifier.Verify(ctx, tt.token)
+			assert.Error(t, err)
+		})
+	}
+
+	// A tampered payload breaks the signature
+	parts := strings.Split(issuer.sign(t, "RS256", "rsa", issuer.claims(nil)), ".")
+	forged := issuer.sign(t, "RS256", "rsa", issuer.claims(map[string]interface{}{"groups": []string{"platform-admins"}}))
+	parts[1] = strings.Split(forged, ".")[1]
+	_, err = verifier.Verify(ctx, strings.Join(parts, "."))
+	assert.Error(t, err)
+}
+
+func TestOIDCVerifier_NestedRolesClaim(t *testing.T) {
+	issuer := newTestIssuer(t)
+	verifier := newTestVerifier(issuer, "realm_access.roles")
+
+	token := issuer.sign(t, "RS256", "rsa", issuer.claims(map[string]interface{}{
+		"realm_access": map[string]interface{}{"roles": []string{"platform-admins"}},
+	}))
+	principal, err := verifier.Verify(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, RoleAdmin, principal.Role)
+}
+
+func TestOIDCVerifier_KeysAreCached(t *testing.T) {
+	ctx := context.Background()
+	issuer := newTestIssuer(t)
+	verifier := newTestVerifier(issuer, "groups")
+
+	for i := 0; i < 3; i++ {
+		_, err := verifier.Verify(ctx, issuer.sign(t, "RS256", "rsa", issuer.claims(nil)))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), issuer.keyFetches.Load())
+
+	// Tokens with unknown keys do not make every request fetch the keys
+	for i := 0; i < 3; i++ {
+		_, err := verifier.Verify(ctx, issuer.sign(t, "RS256", "rotated", issuer.claims(nil)))
+		assert.Error(t, err)
+	}
+	assert.Equal(t, int32(1), issuer.keyFetches.Load())
+}
+
+func TestAuthenticator_OIDC(t *testing.T) {
+	issuer := newTestIssuer(t)
+	a := NewAuthenticator(map[Role]string{RoleAdmin: "admin-token"}, newTestVerifier(issuer, "groups"))
+
+	principal, err := a.Authorize(context.Background(), "Bearer "+issuer.sign(t, "RS256", "rsa", issuer.claims(nil)), RoleOperator)
+	require.NoError(t, err)
+	assert.Equal(t, "oidc:alice", principal.ID)
+
+	_, err = a.Authorize(context.Background(), "Bearer "+issuer.sign(t, "RS256", "rsa", issuer.claims(nil)), RoleAdmin)
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	_, err = a.Authorize(context.Background(), "Bearer "+issuer.sign(t, "RS256", "rsa", issuer.claims(map[string]interface{}{"aud": "other"})), RoleViewer)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+}
@@ -0,0 +1,121 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"cluster-backup/internal/alerting"
+)
+
+// alertTimeout bounds posting a run's alerts, so an unreachable Alertmanager
+// delays the run's exit by seconds at most
+const alertTimeout = 10 * time.Second
+
+// backupAlerter is the part of the alerter a run reports to
+type backupAlerter interface {
+	CheckBackup(ctx context.Context, status alerting.BackupStatus) error
+}
+
+// failureStreak counts the runs in a row that failed. A process running once
+// per CronJob schedule starts from the failed runs recorded in the bucket.
+type failureStreak struct {
+	mu     sync.Mutex
+	count  int
+	seeded bool
+}
+
+// SetAlerter fires Alertmanager alerts when runs keep failing or a run
+// stores no resources
+func (cb *ClusterBackup) SetAlerter(alerter *alerting.Alerter) {
+	cb.alerter = alerter
+}
+
+// checkAlerts updates the failure streak and last run metrics for a finished
+// run and reports it to the alerter. Runs refused because another one holds
+// the lock, or because the process is shutting down, did not fail.
+func (cb *ClusterBackup) checkAlerts(result *BackupResult, err error) {
+	if errors.Is(err, ErrShuttingDown) || (result == nil && errors.Is(err, ErrBackupLocked)) {
+		return
+	}
+	failed := err != nil || result == nil || result.Interrupted || len(result.Errors) > 0
+
+	status := alerting.BackupStatus{Finished: result != nil && !result.Interrupted}
+	if result != nil {
+		status.BackupID = result.BackupID
+		status.Resources = result.ResourcesBackedUp + result.ClusterResourcesBackedUp
+		if len(result.Errors) > 0 {
+			status.Error = result.Errors[len(result.Errors)-1].Error()
+		}
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	status.Failures = cb.updateFailureStreak(status.BackupID, failed)
+
+	cb.metrics.FailureStreak.Set(float64(status.Failures))
+	if status.Finished {
+		cb.metrics.LastRunResources.Set(float64(status.Resources))
+	}
+
+	if cb.alerter == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), alertTimeout)
+	defer cancel()
+	if err := cb.alerter.CheckBackup(ctx, status); err != nil {
+		cb.logger.Warning("alert_send_failed", "Failed to send backup alerts to Alertmanager", map[string]interface{}{
+			"backup_id": status.BackupID,
+			"failures":  status.Failures,
+			"error":     err.Error(),
+		})
+	}
+}
+
+// updateFailureStreak records whether a run failed and returns the number of
+// runs in a row that failed, this one included
+func (cb *ClusterBackup) updateFailureStreak(backupID string, failed bool) int {
+	cb.failures.mu.Lock()
+	defer cb.failures.mu.Unlock()
+
+	if !cb.failures.seeded && cb.alerter != nil {
+		cb.failures.count = cb.recordedFailures(backupID)
+		cb.failures.seeded = true
+	}
+	if failed {
+		cb.failures.count++
+	} else {
+		cb.failures.count = 0
+	}
+	return cb.failures.count
+}
+
+// recordedFailures counts the stored runs since the last completed one,
+// leaving out the run being checked
+func (cb *ClusterBackup) recordedFailures(backupID string) int {
+	if cb.manifestStore == nil {
+		return 0
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), alertTimeout)
+	defer cancel()
+	summaries, err := cb.manifestStore.List(ctx)
+	if err != nil {
+		cb.logger.Warning("failure_history_unavailable", "Failed to read earlier runs, counting failures from this run", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return 0
+	}
+
+	count := 0
+	for _, summary := range summaries {
+		if summary.BackupID == backupID {
+			continue
+		}
+		if summary.Status == ManifestStatusCompleted && !summary.Interrupted {
+			break
+		}
+		count++
+	}
+	return count
+}
@@ -0,0 +1,64 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/alerting"
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/logging"
+	"cluster-backup/internal/metrics"
+)
+
+type recordingAlerter struct {
+	statuses []alerting.BackupStatus
+}
+
+func (a *recordingAlerter) CheckBackup(ctx context.Context, status alerting.BackupStatus) error {
+	a.statuses = append(a.statuses, status)
+	return errors.New("alertmanager unavailable")
+}
+
+func TestCheckAlerts(t *testing.T) {
+	alerter := &recordingAlerter{}
+	cb := &ClusterBackup{
+		config: &config.Config{ClusterName: "prod"},
+		logger: logging.NewStructuredLogger("test", "prod"),
+		metrics: &metrics.BackupMetrics{
+			FailureStreak:    prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_consecutive_failures"}),
+			LastRunResources: prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_last_run_resources"}),
+		},
+		alerter: alerter,
+	}
+
+	cb.checkAlerts(nil, errors.New("MinIO connectivity test failed"))
+	cb.checkAlerts(&BackupResult{BackupID: "b-2", ResourcesBackedUp: 10, Errors: []error{errors.New("failed to backup namespace shop")}}, nil)
+	// Runs that did not get to start are neither failures nor successes
+	cb.checkAlerts(nil, ErrShuttingDown)
+	cb.checkAlerts(nil, ErrBackupLocked)
+	cb.checkAlerts(&BackupResult{BackupID: "b-3", Interrupted: true}, nil)
+
+	require.Len(t, alerter.statuses, 3)
+	assert.Equal(t, 1, alerter.statuses[0].Failures)
+	assert.False(t, alerter.statuses[0].Finished)
+	assert.Equal(t, "MinIO connectivity test failed", alerter.statuses[0].Error)
+	assert.Equal(t, 2, alerter.statuses[1].Failures)
+	assert.True(t, alerter.statuses[1].Finished)
+	assert.Equal(t, "failed to backup namespace shop", alerter.statuses[1].Error)
+	assert.Equal(t, 3, alerter.statuses[2].Failures)
+	assert.False(t, alerter.statuses[2].Finished)
+	assert.Equal(t, 3.0, testutil.ToFloat64(cb.metrics.FailureStreak))
+	assert.Equal(t, 10.0, testutil.ToFloat64(cb.metrics.LastRunResources))
+
+	cb.checkAlerts(&BackupResult{BackupID: "b-4", ClusterResourcesBackedUp: 4}, nil)
+	assert.Equal(t, 0, alerter.statuses[3].Failures)
+	assert.Equal(t, 4, alerter.statuses[3].Resources)
+	assert.Equal(t, 0.0, testutil.ToFloat64(cb.metrics.FailureStreak))
+	assert.Equal(t, 4.0, testutil.ToFloat64(cb.metrics.LastRunResources))
+}
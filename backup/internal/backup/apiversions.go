@@ -0,0 +1,167 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/tenancy"
+)
+
+// alternateVersions maps resource types to the versions a run backs up
+// besides the preferred one, in the order the server lists them
+type alternateVersions map[schema.GroupResource][]string
+
+type alternateVersionsKey struct{}
+
+// withAlternateVersions returns a context the resource types of a run are
+// backed up in their alternate versions with
+func withAlternateVersions(ctx context.Context, versions alternateVersions) context.Context {
+	return context.WithValue(ctx, alternateVersionsKey{}, versions)
+}
+
+// alternateVersionsFrom returns the alternate versions of the run ctx
+// belongs to, nil outside a run or when there are none
+func alternateVersionsFrom(ctx context.Context) alternateVersions {
+	versions, _ := ctx.Value(alternateVersionsKey{}).(alternateVersions)
+	return versions
+}
+
+// discoverAlternateVersions finds the served versions to back up besides the
+// preferred ones: all of them with BACKUP_ALL_API_VERSIONS, or else those
+// listed in BACKUP_API_VERSIONS. This discovers every group version, so it is
+// only done when either is set. A failure leaves the run with the preferred
+// versions.
+func (cb *ClusterBackup) discoverAlternateVersions() alternateVersions {
+	if !cb.backupConfig.AllAPIVersions && len(cb.backupConfig.APIVersions) == 0 {
+		return nil
+	}
+
+	pinned := make(map[schema.GroupResource]map[string]bool)
+	for _, entry := range cb.backupConfig.APIVersions {
+		resource, version, err := config.ParseAPIVersion(entry)
+		if err != nil {
+			continue
+		}
+		if pinned[resource] == nil {
+			pinned[resource] = make(map[string]bool)
+		}
+		pinned[resource][version] = true
+	}
+
+	preferredLists, err := cb.preferredResources()
+	if err != nil && len(preferredLists) == 0 {
+		cb.logger.Warning("api_versions_discovery_failed", "Failed to discover served API versions, backing up preferred versions only", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil
+	}
+	preferred := make(map[schema.GroupResource]string)
+	forEachListable(preferredLists, func(gv schema.GroupVersion, resource v1.APIResource) {
+		preferred[schema.GroupResource{Group: gv.Group, Resource: resource.Name}] = gv.Version
+	})
+
+	_, servedLists, err := cb.discoveryClient.ServerGroupsAndResources()
+	if err != nil {
+		if len(servedLists) == 0 {
+			cb.logger.Warning("api_versions_discovery_failed", "Failed to discover served API versions, backing up preferred versions only", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return nil
+		}
+		cb.logger.Warning("api_versions_discovery_partial", "Some served API versions may not be available", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	versions := make(alternateVersions)
+	forEachListable(servedLists, func(gv schema.GroupVersion, resource v1.APIResource) {
+		groupResource := schema.GroupResource{Group: gv.Group, Resource: resource.Name}
+		preferredVersion, ok := preferred[groupResource]
+		if !ok || gv.Version == preferredVersion {
+			return
+		}
+		if cb.backupConfig.AllAPIVersions || pinned[groupResource][gv.Version] {
+			versions[groupResource] = append(versions[groupResource], gv.Version)
+			delete(pinned[groupResource], gv.Version)
+		}
+	})
+
+	for resource, pins := range pinned {
+		for version := range pins {
+			if preferred[resource] == version {
+				continue
+			}
+			cb.logger.Warning("api_version_not_served", "A version listed in BACKUP_API_VERSIONS is not served", map[string]interface{}{
+				"resource": resource.String(),
+				"version":  version,
+			})
+		}
+	}
+	return versions
+}
+
+// forEachListable calls fn with the listable resource types of lists, leaving
+// out subresources
+func forEachListable(lists []*v1.APIResourceList, fn func(gv schema.GroupVersion, resource v1.APIResource)) {
+	for _, list := range lists {
+		if list == nil {
+			continue
+		}
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, resource := range list.APIResources {
+			if containsVerb(resource.Verbs, "list") && !strings.Contains(resource.Name, "/") {
+				fn(gv, resource)
+			}
+		}
+	}
+}
+
+// backupAlternateVersions stores the resources of a type stored in its
+// preferred version again in each of the run's alternate versions of the
+// type. Only those in stored are, so filters, plugins and strict validation
+// keep out the same resources in every version. A version that fails is
+// logged and does not fail the resource type.
+func (cb *ClusterBackup) backupAlternateVersions(ctx context.Context, target *tenancy.Target, namespace string, gvr schema.GroupVersionResource, stored map[string]bool, manifest *Manifest) error {
+	versions := alternateVersionsFrom(ctx)[gvr.GroupResource()]
+	if len(versions) == 0 || len(stored) == 0 {
+		return nil
+	}
+	// Resources left out are in the run's report once, for the preferred version
+	ctx = withRunRecord(ctx, nil)
+
+	for _, version := range versions {
+		versioned := schema.GroupVersionResource{Group: gvr.Group, Version: version, Resource: gvr.Resource}
+		_, err := cb.forEachResource(ctx, namespace, versioned, func(name string, data *payload) error {
+			if !stored[name] {
+				return nil
+			}
+			key := target.Key(VersionedObjectPath(cb.config.ClusterDomain, cb.config.ClusterName, namespace, QualifiedResource(gvr, false), version, name))
+			entry, err := cb.storeObject(ctx, target, key, namespace, versioned, name, data)
+			if err != nil {
+				return err
+			}
+			manifest.AddAlternateVersion(entry)
+			return nil
+		})
+		if errors.Is(err, ErrInterrupted) || (err != nil && ctx.Err() != nil) {
+			return err
+		}
+		if err != nil {
+			cb.logger.Warning("api_version_backup_failed", "Failed to back up a served version besides the preferred one", map[string]interface{}{
+				"namespace": namespace,
+				"resource":  gvr.GroupResource().String(),
+				"version":   version,
+				"error":     err.Error(),
+			})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package backup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/logging"
+)
+
+var (
+	hpaV2 = schema.GroupVersionResource{Group: "autoscaling", Version: "v2", Resource: "horizontalpodautoscalers"}
+	hpaV1 = schema.GroupVersionResource{Group: "autoscaling", Version: "v1", Resource: "horizontalpodautoscalers"}
+)
+
+// versionedDiscovery serves autoscaling/v2 as the preferred version of
+// HorizontalPodAutoscalers, which autoscaling/v1 serves as well
+type versionedDiscovery struct {
+	*fakediscovery.FakeDiscovery
+}
+
+func (d *versionedDiscovery) ServerPreferredResources() ([]*v1.APIResourceList, error) {
+	return []*v1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []v1.APIResource{{Name: "configmaps", Namespaced: true, Verbs: []string{"list"}}}},
+		{GroupVersion: "autoscaling/v2", APIResources: []v1.APIResource{{Name: "horizontalpodautoscalers", Namespaced: true, Verbs: []string{"list"}}}},
+	}, nil
+}
+
+func (d *versionedDiscovery) ServerGroupsAndResources() ([]*v1.APIGroup, []*v1.APIResourceList, error) {
+	lists, _ := d.ServerPreferredResources()
+	return nil, append(lists, &v1.APIResourceList{GroupVersion: "autoscaling/v1", APIResources: []v1.APIResource{
+		{Name: "horizontalpodautoscalers", Namespaced: true, Verbs: []string{"list"}},
+		{Name: "horizontalpodautoscalers/status", Namespaced: true, Verbs: []string{"get"}},
+	}}), nil
+}
+
+func hpa(version, name string, controlled bool) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "autoscaling/" + version,
+		"kind":       "HorizontalPodAutoscaler",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "shop"},
+	}}
+	if controlled {
+		isController := true
+		obj.SetOwnerReferences([]v1.OwnerReference{{Kind: "Operator", Name: "autoscaler", Controller: &isController}})
+	}
+	return obj
+}
+
+func TestDiscoverAlternateVersions(t *testing.T) {
+	cb := &ClusterBackup{
+		backupConfig:    &config.BackupConfig{},
+		discoveryClient: &versionedDiscovery{FakeDiscovery: fake.NewSimpleClientset().Discovery().(*fakediscovery.FakeDiscovery)},
+		logger:          logging.NewStructuredLogger("apiversions-test", "prod"),
+	}
+	hpas := schema.GroupResource{Group: "autoscaling", Resource: "horizontalpodautoscalers"}
+
+	assert.Nil(t, cb.discoverAlternateVersions(), "preferred versions only by default")
+
+	cb.backupConfig.AllAPIVersions = true
+	assert.Equal(t, alternateVersions{hpas: {"v1"}}, cb.discoverAlternateVersions())
+
+	cb.backupConfig = &config.BackupConfig{APIVersions: []string{"horizontalpodautoscalers.autoscaling/v1"}}
+	assert.Equal(t, alternateVersions{hpas: {"v1"}}, cb.discoverAlternateVersions())
+
+	cb.backupConfig = &config.BackupConfig{APIVersions: []string{"horizontalpodautoscalers.autoscaling/v2beta2", "configmaps/v1"}}
+	assert.Empty(t, cb.discoverAlternateVersions(), "versions not served and preferred ones are left out")
+}
+
+func TestBackupResource_AlternateVersions(t *testing.T) {
+	cb, bucket := newFanOutTestBackup(t, "")
+	cb.metrics.ResourcesBackedUp = prometheus.NewCounter(prometheus.CounterOpts{Name: "test_resources_backed_up_total"})
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		hpaV2: "HorizontalPodAutoscalerList",
+		hpaV1: "HorizontalPodAutoscalerList",
+	})
+	for _, obj := range []*unstructured.Unstructured{hpa("v2", "web", false), hpa("v2", "managed", true)} {
+		require.NoError(t, dynamicClient.Tracker().Create(hpaV2, obj, "shop"))
+	}
+	for _, obj := range []*unstructured.Unstructured{hpa("v1", "web", false), hpa("v1", "managed", true), hpa("v1", "created-since", false)} {
+		require.NoError(t, dynamicClient.Tracker().Create(hpaV1, obj, "shop"))
+	}
+	cb.dynamicClient = dynamicClient
+
+	record := &runRecord{}
+	ctx := withAlternateVersions(withRunRecord(context.Background(), record), alternateVersions{hpaV2.GroupResource(): {"v1"}})
+	manifest := NewManifest("backup-1", "prod", "example.com", "backups", time.Now())
+	count, err := cb.backupResource(ctx, cb.defaultTarget(), "shop", hpaV2, manifest)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	require.Len(t, manifest.Objects, 1)
+	assert.Equal(t, "v2", manifest.Objects[0].Version)
+	assert.Equal(t, "example.com/prod/shop/horizontalpodautoscalers.autoscaling/web.yaml", manifest.Objects[0].Key)
+	require.Len(t, manifest.AlternateVersions, 1, "only the resources stored in the preferred version")
+	alternate := manifest.AlternateVersions[0]
+	assert.Equal(t, "web", alternate.Name)
+	assert.Equal(t, "v1", alternate.Version)
+	assert.Equal(t, "example.com/prod/shop/_versions/v1/horizontalpodautoscalers.autoscaling/web.yaml", alternate.Key)
+	assert.Contains(t, string(bucket.objects["/backups/"+alternate.Key]), "apiVersion: autoscaling/v1")
+	assert.Len(t, record.skipped, 1, "the controlled resource is reported once")
+	assert.Contains(t, manifest.StoredKeys()[""], alternate.Key)
+}
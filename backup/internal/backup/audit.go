@@ -0,0 +1,41 @@
+package backup
+
+import (
+	"fmt"
+
+	"cluster-backup/internal/audit"
+)
+
+// SetAuditLog records the snapshots continuous backup consolidates in log;
+// nil records none. Runs are recorded by whoever starts them, which knows
+// the actor.
+func (cb *ClusterBackup) SetAuditLog(log *audit.Log) {
+	cb.auditLog = log
+}
+
+// AuditLog returns the audit log, nil when operations are not audited
+func (cb *ClusterBackup) AuditLog() *audit.Log {
+	return cb.auditLog
+}
+
+// AuditRecord returns the audit record of a backup run that returned result
+// and err. result is nil when the run did not start; a run with errors is
+// recorded as failed.
+func AuditRecord(result *BackupResult, err error) audit.Record {
+	record := audit.Record{Operation: audit.OperationBackup, Outcome: audit.OutcomeSucceeded}
+	if result != nil {
+		record.BackupID = result.BackupID
+		record.Resources = &audit.Resources{
+			NamespaceCount: result.NamespacesBackedUp,
+			ResourceCount:  result.ResourcesBackedUp + result.ClusterResourcesBackedUp,
+		}
+		if err == nil && len(result.Errors) > 0 {
+			err = fmt.Errorf("backup completed with %d errors", len(result.Errors))
+		}
+	}
+	if err != nil {
+		record.Outcome = audit.OutcomeFailed
+		record.Error = err.Error()
+	}
+	return record
+}
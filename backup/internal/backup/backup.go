@@ -2,20 +2,47 @@ package backup
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/minio/minio-go/v7"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 
+	"shared-config/eventbus"
+	"shared-config/plugins"
+	"shared-config/signing"
+
+	"cluster-backup/internal/audit"
+	"cluster-backup/internal/cleaning"
 	"cluster-backup/internal/config"
+	"cluster-backup/internal/dump"
+	"cluster-backup/internal/etcd"
+	"cluster-backup/internal/failure"
+	"cluster-backup/internal/filter"
+	"cluster-backup/internal/hooks"
+	"cluster-backup/internal/inventory"
 	"cluster-backup/internal/logging"
 	"cluster-backup/internal/metrics"
+	"cluster-backup/internal/pathtemplate"
+	"cluster-backup/internal/policy"
+	"cluster-backup/internal/priority"
+	"cluster-backup/internal/resilience"
+	"cluster-backup/internal/search"
+	"cluster-backup/internal/storage"
+	"cluster-backup/internal/tenancy"
 )
 
 // ClusterBackup handles the main backup operations
@@ -29,18 +56,94 @@ type ClusterBackup struct {
 	logger           *logging.StructuredLogger
 	metrics          *metrics.BackupMetrics
 	ctx              context.Context
+	manifestStore       *ManifestStore
+	runLock             *RunLock
+	retryExecutor       *resilience.RetryExecutor
+	apiCircuitBreaker   *resilience.CircuitBreaker
+	minioCircuitBreaker *resilience.CircuitBreaker
+	hookRunner          *hooks.Runner
+	dumper              *dump.Dumper
+	etcdSnapshotter     *etcd.Snapshotter
+	policyEvaluator     *policy.Evaluator
+	filterEngine        *filter.Engine
+	eventBus            eventPublisher
+	alerter             backupAlerter
+	failures            failureStreak
+	rpoMonitor          *RPOMonitor
+	replication         *ReplicationVerifier
+	secondaries         []*secondaryTarget
+	tenancy             *tenancy.Router
+	cleaning            *cleaning.Profiles
+	inventory           *inventory.Config
+	auditLog            *audit.Log
+	plugins             *plugins.Chain
+	priorities          *priority.Manager
+	discoveryCache      *discoveryCache
+	shutdown            drainer
+	// Runs hold configMu for reading; a reloaded configuration waits in
+	// reloaded until no run uses the current one
+	configMu            sync.RWMutex
+	reloaded            reloadedConfig
 }
 
 // BackupResult represents the result of a backup operation
 type BackupResult struct {
+	BackupID           string
 	NamespacesBackedUp int
 	ResourcesBackedUp  int
 	Errors             []error
 	Duration           time.Duration
 	StartTime          time.Time
 	EndTime            time.Time
+
+	// ClusterResourcesBackedUp counts cluster-scoped resources, which are not
+	// part of ResourcesBackedUp
+	ClusterResourcesBackedUp int
+	// CRDsBackedUp counts CustomResourceDefinitions stored under _crds
+	CRDsBackedUp int
+	// Interrupted is set when shutdown, or BACKUP_TIMEOUT expiring, stopped
+	// the run before it finished
+	Interrupted bool
+	// PolicyViolations counts the policy violations recorded in the manifest
+	PolicyViolations int
+	// SecretFindings counts the suspected credentials recorded in the manifest
+	SecretFindings int
+	// OpenShiftObjects counts the settings stored under the _openshift prefix
+	OpenShiftObjects int
+	// WindowObjects counts the resources created or updated during the run
+	// and stored at its end, which are not part of ResourcesBackedUp
+	WindowObjects int
+	// SyntheticValidation is the outcome of restoring a sample of the run into
+	// a temporary namespace, nil unless SYNTHETIC_VALIDATION is enabled
+	SyntheticValidation *SyntheticValidation
 }
 
+// ProgressStage identifies the point in a backup run a ProgressEvent reports on
+type ProgressStage string
+
+const (
+	ProgressNamespaceStarted   ProgressStage = "namespace_started"
+	ProgressResourceCompleted  ProgressStage = "resource_completed"
+	ProgressResourceFailed     ProgressStage = "resource_failed"
+	ProgressNamespaceCompleted ProgressStage = "namespace_completed"
+	ProgressRunProgress        ProgressStage = "run_progress"
+)
+
+// ProgressEvent describes per-namespace and per-resource backup progress.
+// Run is set on the periodic ProgressRunProgress events only.
+type ProgressEvent struct {
+	Stage     ProgressStage
+	Namespace string
+	Resource  string
+	ItemCount int
+	Error     error
+	Timestamp time.Time
+	Run       *RunProgress
+}
+
+// ProgressFunc receives progress events while a backup is running
+type ProgressFunc func(event ProgressEvent)
+
 // NewClusterBackup creates a new ClusterBackup instance
 func NewClusterBackup(
 	config *config.Config,
@@ -53,7 +156,7 @@ func NewClusterBackup(
 	metrics *metrics.BackupMetrics,
 	ctx context.Context,
 ) *ClusterBackup {
-	return &ClusterBackup{
+	cb := &ClusterBackup{
 		config:          config,
 		backupConfig:    backupConfig,
 		kubeClient:      kubeClient,
@@ -63,86 +166,613 @@ func NewClusterBackup(
 		logger:          logger,
 		metrics:         metrics,
 		ctx:             ctx,
+		manifestStore:   NewManifestStore(minioClient, config.MinIOBucket, config.ClusterDomain, config.ClusterName),
+		runLock:         newRunLock(config, minioClient),
+		discoveryCache:  newDiscoveryCache(config.DiscoveryCacheDir, config.DiscoveryCacheTTL),
+		retryExecutor: resilience.NewRetryExecutor(resilience.RetryConfig{
+			MaxAttempts:  config.RetryAttempts,
+			InitialDelay: config.RetryDelay,
+			MaxDelay:     30 * time.Second,
+			Multiplier:   2.0,
+			// Denied or invalid requests fail the same way every time
+			Retryable: failure.Retryable,
+		}),
+	}
+	// Half-open breakers probe with cheap requests rather than letting a
+	// namespace's uploads or list calls through to test the waters
+	cb.apiCircuitBreaker = resilience.NewCircuitBreakerWithConfig(resilience.CircuitBreakerConfig{
+		Name:           "api",
+		MaxFailures:    config.APICircuitBreakerThreshold,
+		ResetTimeout:   config.APICircuitBreakerTimeout,
+		HalfOpenProbes: config.APICircuitBreakerProbes,
+		Probe: func() error {
+			_, err := discoveryClient.ServerVersion()
+			return err
+		},
+	})
+	cb.minioCircuitBreaker = resilience.NewCircuitBreakerWithConfig(resilience.CircuitBreakerConfig{
+		Name:           "minio",
+		MaxFailures:    config.MinIOCircuitBreakerThreshold,
+		ResetTimeout:   config.MinIOCircuitBreakerTimeout,
+		HalfOpenProbes: config.MinIOCircuitBreakerProbes,
+		Probe: func() error {
+			_, err := minioClient.BucketExists(ctx, config.MinIOBucket)
+			return err
+		},
+	})
+	cb.rpoMonitor = newRPOMonitor(config, cb.manifestStore, metrics, logger)
+	cb.exportCircuitState("api", cb.apiCircuitBreaker)
+	cb.exportCircuitState("minio", cb.minioCircuitBreaker)
+	return cb
+}
+
+// exportCircuitState keeps the breaker's state in the circuit state gauge, so
+// alerts can fire while calls are being short-circuited
+func (cb *ClusterBackup) exportCircuitState(name string, breaker *resilience.CircuitBreaker) {
+	gauge := cb.metrics.CircuitState.WithLabelValues(name)
+	state, _, _ := breaker.GetState()
+	gauge.Set(float64(state))
+	breaker.OnStateChange(func(from, to resilience.CircuitState) {
+		gauge.Set(float64(to))
+		cb.logger.Warning("circuit_breaker_state_change", "Circuit breaker changed state", map[string]interface{}{
+			"breaker": name,
+			"from":    from.String(),
+			"to":      to.String(),
+		})
+	})
+}
+
+// newRunLock returns the lease serializing runs against the cluster's prefix,
+// or nil when RUN_LOCK is off. The holder is the pod name.
+func newRunLock(cfg *config.Config, minioClient *minio.Client) *RunLock {
+	if !cfg.RunLockEnabled {
+		return nil
+	}
+	holder, err := os.Hostname()
+	if err != nil {
+		holder = "unknown"
+	}
+	return NewRunLock(minioClient, cfg.MinIOBucket, cfg.ClusterDomain, cfg.ClusterName, holder, cfg.RunLockTTL)
+}
+
+// SetHookRunner enables pre/post backup hooks declared by pod annotations
+func (cb *ClusterBackup) SetHookRunner(runner *hooks.Runner) {
+	cb.hookRunner = runner
+}
+
+// SetDumper enables database dumps of pods matched by the dumper's plugins
+func (cb *ClusterBackup) SetDumper(dumper *dump.Dumper) {
+	cb.dumper = dumper
+}
+
+// SetManifestSigner signs each run's manifest when it is uploaded
+func (cb *ClusterBackup) SetManifestSigner(signer signing.Signer) {
+	cb.manifestStore.SetSigner(signer)
+}
+
+// SetEtcdSnapshotter enables an etcd snapshot at the end of every backup run
+func (cb *ClusterBackup) SetEtcdSnapshotter(snapshotter *etcd.Snapshotter) {
+	cb.etcdSnapshotter = snapshotter
+}
+
+// CircuitBreakerStats returns the state of the MinIO, secondary target and
+// Kubernetes API circuit breakers, keyed by breaker name
+func (cb *ClusterBackup) CircuitBreakerStats() map[string]resilience.CircuitBreakerStats {
+	stats := map[string]resilience.CircuitBreakerStats{
+		"minio": cb.minioCircuitBreaker.GetStats(),
+		"api":   cb.apiCircuitBreaker.GetStats(),
+	}
+	for _, secondary := range cb.secondaries {
+		stats["secondary-"+secondary.name] = secondary.breaker.GetStats()
 	}
+	return stats
+}
+
+// ManifestStore returns the store holding this cluster's run manifests
+func (cb *ClusterBackup) ManifestStore() *ManifestStore {
+	return cb.manifestStore
 }
 
 // ExecuteBackup performs the complete backup operation
 func (cb *ClusterBackup) ExecuteBackup() (*BackupResult, error) {
+	return cb.ExecuteBackupWithProgress(nil)
+}
+
+// ExecuteBackupWithProgress performs the complete backup operation, reporting
+// progress to the given callback as namespaces and resources are processed
+func (cb *ClusterBackup) ExecuteBackupWithProgress(progress ProgressFunc) (*BackupResult, error) {
+	cb.applyReloadedConfig()
+	cb.configMu.RLock()
+	defer cb.configMu.RUnlock()
+
+	cb.checkReplication()
+	result, err := cb.executeBackup(progress)
+	cb.checkAlerts(result, err)
+	cb.checkRPO()
+	return result, err
+}
+
+// executeBackup runs one backup from the connectivity check to the manifest
+func (cb *ClusterBackup) executeBackup(progress ProgressFunc) (*BackupResult, error) {
+	if err := cb.shutdown.begin(); err != nil {
+		return nil, err
+	}
+	defer cb.shutdown.end()
+
 	startTime := time.Now()
 	cb.logger.Info("backup_start", "Starting cluster backup operation", map[string]interface{}{
 		"cluster": cb.config.ClusterName,
 		"bucket":  cb.config.MinIOBucket,
 	})
 
+	// Every phase runs within the run's deadline; namespaces and discovery
+	// also have their own
+	run := cb.ctx
+	if cb.config.BackupTimeout > 0 {
+		var cancelRun context.CancelFunc
+		run, cancelRun = context.WithTimeout(cb.ctx, cb.config.BackupTimeout)
+		defer cancelRun()
+	}
+
+	// Phase durations and the resources left out go to the run's report
+	record := &runRecord{}
+	run = withRunRecord(run, record)
+
+	backupID := NewBackupID(startTime)
+	manifest := NewManifest(backupID, cb.config.ClusterName, cb.config.ClusterDomain, cb.config.MinIOBucket, startTime)
 	result := &BackupResult{
+		BackupID:  backupID,
 		StartTime: startTime,
 		Errors:    []error{},
 	}
+	// Profiles cover the run up to its manifest; stop is a no-op once saved
+	profile := cb.startProfile(backupID)
+	defer profile.stop()
+	// Stored copies record the run, the listed objects are left as they are
+	if p := cb.newProvenance(manifest); p != nil {
+		run = withProvenance(run, p)
+	}
+	// Each run writes under a prefix of its own and refreshes latest/
+	layout := cb.newRunLayout(backupID)
+	if layout != nil {
+		run = withRunLayout(run, layout)
+	}
+	var index *search.Index
+	if cb.config.BackupIndex {
+		index = search.NewIndex(backupID)
+		run = withRunIndex(run, index)
+	}
+	if cb.config.ObjectPathTemplate != "" {
+		paths, err := pathtemplate.Parse(cb.config.ObjectPathTemplate)
+		if err != nil {
+			return nil, err
+		}
+		run = withObjectPaths(run, paths)
+	}
+	// Resource types are listed as many at once as the API server keeps up with
+	if controller := cb.newConcurrency(backupID); controller != nil {
+		run = withConcurrency(run, controller)
+	}
 
 	// Test MinIO connectivity
-	if err := cb.testMinIOConnectivity(); err != nil {
+	if err := cb.testMinIOConnectivity(run); err != nil {
+		if reason := storage.CertificateError(err); reason != "" {
+			cb.logger.Error("minio_certificate_error", reason, map[string]interface{}{
+				"endpoint": cb.config.MinIOEndpoint,
+				"error":    err.Error(),
+			})
+		}
 		cb.logger.Error("minio_connectivity_failed", "Failed to connect to MinIO", map[string]interface{}{
 			"error": err.Error(),
 		})
 		return nil, fmt.Errorf("MinIO connectivity test failed: %v", err)
 	}
 
-	// Get list of namespaces to backup
-	namespaces, err := cb.getNamespacesToBackup()
+	// Hold the cluster's run lease so a concurrently scheduled run cannot
+	// interleave its writes with this one's
+	if cb.runLock != nil {
+		if err := cb.runLock.Acquire(run, backupID); err != nil {
+			if errors.Is(err, ErrBackupLocked) {
+				cb.metrics.LockConflicts.Inc()
+			}
+			cb.logger.Error("backup_lock_failed", "Failed to acquire the backup run lock", map[string]interface{}{
+				"backup_id": backupID,
+				"error":     err.Error(),
+			})
+			return nil, err
+		}
+		lockCtx, stopRenewal := context.WithCancel(cb.ctx)
+		go cb.runLock.keepAlive(lockCtx, func(err error) {
+			cb.logger.Warning("backup_lock_renew_failed", "Failed to renew the backup run lock", map[string]interface{}{
+				"backup_id": backupID,
+				"error":     err.Error(),
+			})
+		})
+		defer func() {
+			stopRenewal()
+			releaseCtx, cancel := cb.checkpointContext()
+			defer cancel()
+			if err := cb.runLock.Release(releaseCtx); err != nil {
+				cb.logger.Warning("backup_lock_release_failed", "Failed to release the backup run lock; it expires on its own", map[string]interface{}{
+					"backup_id": backupID,
+					"error":     err.Error(),
+				})
+			}
+		}()
+	}
+
+	cb.publishEvent(eventbus.NewEvent(eventbus.BackupStarted, backupID, cb.config.ClusterName))
+
+	// Keep the settings the run was made with next to what it stored, and
+	// flag those changed since the previous run
+	cb.recordConfig(run, manifest)
+
+	// Discover the namespaces, and the resource types once for all of them.
+	// The discovery client takes no context, so an overrun is caught after
+	// each step.
+	var namespaces []string
+	var apiResources []v1.APIResource
+	var alternates alternateVersions
+	err := cb.runPhase(run, "discovery", "BACKUP_DISCOVERY_TIMEOUT", cb.config.BackupDiscoveryTimeout, func(ctx context.Context) error {
+		var err error
+		namespaces, err = cb.getNamespacesToBackup(ctx)
+		if err != nil {
+			cb.logger.Error("namespace_discovery_failed", "Failed to discover namespaces", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return fmt.Errorf("namespace discovery failed: %v", err)
+		}
+
+		cb.logger.Info("namespace_discovery_complete", "Discovered namespaces for backup", map[string]interface{}{
+			"namespace_count": len(namespaces),
+			"namespaces":      namespaces,
+		})
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		apiResources, err = cb.getAPIResources()
+		if err != nil {
+			cb.logger.Error("api_discovery_failed", "Failed to discover API resources", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return fmt.Errorf("API resource discovery failed: %v", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// Restores check their target against what the source served
+		cb.recordSource(ctx, manifest, apiResources)
+		alternates = cb.discoverAlternateVersions()
+		return ctx.Err()
+	})
+	if err != nil {
+		cb.logDeadline(backupID, err)
+		cb.publishFailure(backupID, err)
+		return nil, err
+	}
+	run = withAlternateVersions(run, alternates)
+
+	// Report percentage, rate and ETA against the items the previous run
+	// stored, or against a count taken now when there is none. Counting is
+	// bounded by the discovery timeout and never fails the run.
+	tracker := &progressTracker{manifest: manifest, namespacesTotal: len(namespaces)}
+	cb.runPhase(run, "progress-estimate", "BACKUP_DISCOVERY_TIMEOUT", cb.config.BackupDiscoveryTimeout, func(ctx context.Context) error {
+		tracker.expected, tracker.source = cb.estimateItems(ctx, namespaces, apiResources)
+		return nil
+	})
+	tracker.start = time.Now()
+	stopProgress := cb.reportRunProgress(backupID, tracker, progress)
+
+	// Changes made while the namespaces are listed are watched for and
+	// stored once they all are
+	window := cb.startWindow(run, namespaces, apiResources)
+	defer window.stop()
+
+	// CRDs are always captured so a restore can install them before the
+	// custom resources that need them
+	var crdCount int
+	err = cb.runPhase(run, "crds", "", 0, func(ctx context.Context) error {
+		var err error
+		crdCount, err = cb.captureCRDs(ctx, manifest)
+		return err
+	})
+	result.CRDsBackedUp = crdCount
 	if err != nil {
-		cb.logger.Error("namespace_discovery_failed", "Failed to discover namespaces", map[string]interface{}{
+		cb.logDeadline(backupID, err)
+		cb.logger.Error("crd_backup_failed", "Failed to capture CRDs", map[string]interface{}{
 			"error": err.Error(),
 		})
-		return nil, fmt.Errorf("namespace discovery failed: %v", err)
+		manifest.SetCRDError(err)
+		result.Errors = append(result.Errors, err)
+		cb.metrics.BackupErrors.Inc()
+		cb.recordFailure(run, err)
 	}
 
-	cb.logger.Info("namespace_discovery_complete", "Discovered namespaces for backup", map[string]interface{}{
-		"namespace_count": len(namespaces),
-		"namespaces":      namespaces,
-	})
+	// Cluster-scoped resources are backed up once per run, before the
+	// namespaced resources that may depend on them (e.g. CRDs)
+	var stopErr error
+	if cb.backupConfig.BackupClusterResources {
+		resourceCount := 0
+		err := cb.stopReason(run, "cluster-scope")
+		if err == nil {
+			err = cb.runPhase(run, "cluster-scope", "BACKUP_NAMESPACE_TIMEOUT", cb.config.BackupNamespaceTimeout, func(ctx context.Context) error {
+				var err error
+				resourceCount, err = cb.backupClusterScope(ctx, manifest, progress)
+				return err
+			})
+		}
+		cb.logDeadline(backupID, err)
+		manifest.SetClusterScope(resourceCount, err)
+		result.ClusterResourcesBackedUp = resourceCount
+		if stopped(err) {
+			stopErr = err
+			result.Interrupted = true
+		} else if err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("failed to backup cluster-scoped resources: %w", err))
+			cb.metrics.BackupErrors.Inc()
+		}
+	}
 
-	// Backup each namespace
+	// Backup each namespace. On shutdown, or once the run is out of time, the
+	// namespace being backed up and the ones after it are left for the next
+	// run. A namespace running out of its own time fails on its own.
 	totalResources := 0
-	for _, namespace := range namespaces {
-		resourceCount, err := cb.backupNamespace(namespace)
+	failedNamespaces := 0
+	var pending []string
+	for i, namespace := range namespaces {
+		phase := "namespace " + namespace
+		if err := cb.stopReason(run, phase); err != nil {
+			stopErr = err
+			pending = namespaces[i:]
+			break
+		}
+		var resourceCount int
+		err := cb.runPhase(run, phase, "BACKUP_NAMESPACE_TIMEOUT", cb.config.BackupNamespaceTimeout, func(ctx context.Context) error {
+			var err error
+			resourceCount, err = cb.backupNamespace(ctx, namespace, apiResources, manifest, progress)
+			return err
+		})
+		cb.logDeadline(backupID, err)
+		manifest.SetNamespace(namespace, resourceCount, err)
+		cb.publishEvent(eventbus.NewNamespaceEvent(backupID, cb.config.ClusterName, namespace, resourceCount, err))
+		if stopped(err) {
+			stopErr = err
+			pending = namespaces[i:]
+			totalResources += resourceCount
+			break
+		}
 		if err != nil {
-			result.Errors = append(result.Errors, fmt.Errorf("failed to backup namespace %s: %v", namespace, err))
+			result.Errors = append(result.Errors, fmt.Errorf("failed to backup namespace %s: %w", namespace, err))
 			cb.metrics.BackupErrors.Inc()
+			cb.recordFailure(run, err)
+			failedNamespaces++
 			continue
 		}
 		totalResources += resourceCount
 	}
 
+	if window != nil && stopErr == nil {
+		if err := cb.stopReason(run, "window-capture"); err != nil {
+			stopErr = err
+			result.Interrupted = true
+		} else if err := cb.runPhase(run, "window-capture", "BACKUP_NAMESPACE_TIMEOUT", cb.config.BackupNamespaceTimeout, func(ctx context.Context) error {
+			var err error
+			result.WindowObjects, err = cb.captureWindow(ctx, window, manifest)
+			return err
+		}); err != nil {
+			cb.logDeadline(backupID, err)
+			if stopped(err) {
+				stopErr = err
+				result.Interrupted = true
+			} else {
+				result.Errors = append(result.Errors, fmt.Errorf("failed to store the changes made during the backup: %w", err))
+				cb.metrics.BackupErrors.Inc()
+			}
+		}
+	}
+
 	// Update metrics
 	result.EndTime = time.Now()
 	result.Duration = result.EndTime.Sub(result.StartTime)
-	result.NamespacesBackedUp = len(namespaces) - len(result.Errors)
+	result.NamespacesBackedUp = len(namespaces) - failedNamespaces - len(pending)
 	result.ResourcesBackedUp = totalResources
+	if len(pending) > 0 {
+		result.Interrupted = true
+	}
+
+	// OpenShift keeps project settings, SCC grants, image tags and route
+	// certificates outside the namespaced resources backed up above
+	if cb.openShiftEnabled() {
+		if err := cb.stopReason(run, "openshift"); err != nil {
+			result.Interrupted = true
+			if stopErr == nil {
+				stopErr = err
+			}
+		} else if err := cb.runPhase(run, "openshift", "BACKUP_NAMESPACE_TIMEOUT", cb.config.BackupNamespaceTimeout, func(ctx context.Context) error {
+			var err error
+			result.OpenShiftObjects, err = cb.captureOpenShift(ctx, namespaces, manifest)
+			return err
+		}); err != nil {
+			cb.logDeadline(backupID, err)
+			manifest.SetOpenShiftError(err)
+			result.Errors = append(result.Errors, err)
+			cb.metrics.BackupErrors.Inc()
+			cb.recordFailure(run, err)
+		}
+	}
+
+	if cb.etcdSnapshotter != nil {
+		if err := cb.stopReason(run, "etcd-snapshot"); err != nil {
+			manifest.SetEtcdSnapshot(SnapshotEntry{Error: err.Error()})
+			result.Interrupted = true
+			if stopErr == nil {
+				stopErr = err
+			}
+		} else if err := cb.runPhase(run, "etcd-snapshot", "", 0, func(ctx context.Context) error {
+			return cb.captureEtcdSnapshot(ctx, manifest)
+		}); err != nil {
+			cb.logDeadline(backupID, err)
+			result.Errors = append(result.Errors, err)
+			cb.metrics.BackupErrors.Inc()
+			cb.recordFailure(run, err)
+		}
+	}
+
+	stopProgress(!result.Interrupted)
+	result.PolicyViolations = len(manifest.PolicyViolations)
+	result.SecretFindings = len(manifest.SecretFindings)
+
+	// An interrupted run is short by design; only finished ones are checked
+	if !result.Interrupted {
+		cb.checkInventory(manifest, result)
+	}
+
+	if result.Interrupted {
+		manifest.Interrupt(time.Now(), stopErr, pending)
+		result.Errors = append(result.Errors, fmt.Errorf("%w: %d namespaces not backed up", stopErr, len(pending)))
+		cb.logger.Warning("backup_interrupted", "Backup stopped before it finished, recording a partial manifest", map[string]interface{}{
+			"backup_id":          backupID,
+			"reason":             stopErr.Error(),
+			"pending_namespaces": pending,
+		})
+	}
+
+	// Record what this run wrote so it can be browsed, diffed and restored
+	// later. After a shutdown this is the checkpoint of where the run stopped.
+	manifest.Finish(result.EndTime)
+	saveCtx, cancelSave := cb.checkpointContext()
+	defer cancelSave()
+	if cb.runLock == nil || !cb.runLock.Lost() {
+		cb.saveReport(saveCtx, manifest, result, record)
+		cb.saveIndex(saveCtx, manifest, index)
+		cb.saveProfile(saveCtx, manifest, profile)
+	}
+	if cb.runLock != nil && cb.runLock.Lost() {
+		// Another run may own the prefix now; its manifest must not be clobbered
+		err := fmt.Errorf("%w: lease lost during the run, manifest not written", ErrBackupLocked)
+		cb.logger.Error("backup_lock_lost", "Backup run lock was lost, skipping manifest upload", map[string]interface{}{
+			"backup_id": backupID,
+		})
+		result.Errors = append(result.Errors, err)
+	} else if err := cb.manifestStore.Save(saveCtx, manifest); err != nil {
+		cb.logger.Error("manifest_upload_failed", "Failed to upload backup manifest", map[string]interface{}{
+			"backup_id": backupID,
+			"error":     err.Error(),
+		})
+		result.Errors = append(result.Errors, err)
+	} else {
+		if cb.rpoMonitor != nil {
+			cb.rpoMonitor.Record(saveCtx, manifest)
+		}
+		cb.pruneLatestAliases(saveCtx, layout, manifest)
+		if !result.Interrupted {
+			result.SyntheticValidation = cb.checkSyntheticRestore(manifest)
+		}
+	}
 
 	cb.metrics.BackupDuration.Observe(result.Duration.Seconds())
 	cb.metrics.NamespacesBackedUp.Set(float64(result.NamespacesBackedUp))
+	cb.metrics.ClusterResources.Set(float64(result.ClusterResourcesBackedUp))
+	cb.metrics.CRDs.Set(float64(result.CRDsBackedUp))
 	cb.metrics.LastBackupTime.SetToCurrentTime()
 
 	cb.logger.LogDuration("backup_complete", startTime, "Cluster backup completed", map[string]interface{}{
-		"namespaces_backed_up": result.NamespacesBackedUp,
-		"resources_backed_up":  result.ResourcesBackedUp,
-		"error_count":          len(result.Errors),
+		"namespaces_backed_up":        result.NamespacesBackedUp,
+		"resources_backed_up":         result.ResourcesBackedUp,
+		"cluster_resources_backed_up": result.ClusterResourcesBackedUp,
+		"crds_backed_up":              result.CRDsBackedUp,
+		"openshift_objects":           result.OpenShiftObjects,
+		"policy_violations":           result.PolicyViolations,
+		"secret_findings":             result.SecretFindings,
+		"error_count":                 len(result.Errors),
+		"backup_id":                   backupID,
 	})
+	cb.publishResult(result)
 
 	return result, nil
 }
 
+// captureEtcdSnapshot saves an etcd snapshot, uploads it next to the run's
+// other data and records the outcome in the manifest
+func (cb *ClusterBackup) captureEtcdSnapshot(ctx context.Context, manifest *Manifest) error {
+	entry, err := cb.uploadEtcdSnapshot(ctx, EtcdSnapshotPath(cb.config.ClusterDomain, cb.config.ClusterName, manifest.BackupID))
+	if err != nil {
+		cb.logger.Error("etcd_snapshot_failed", "Failed to capture etcd snapshot", map[string]interface{}{
+			"backup_id": manifest.BackupID,
+			"error":     err.Error(),
+		})
+		manifest.SetEtcdSnapshot(SnapshotEntry{Error: err.Error()})
+		return fmt.Errorf("etcd snapshot failed: %v", err)
+	}
+
+	manifest.SetEtcdSnapshot(entry)
+	cb.metrics.EtcdSnapshotBytes.Set(float64(entry.Size))
+	cb.logger.Info("etcd_snapshot_complete", "Uploaded etcd snapshot", map[string]interface{}{
+		"backup_id":  manifest.BackupID,
+		"key":        entry.Key,
+		"size_bytes": entry.Size,
+	})
+	return nil
+}
+
+func (cb *ClusterBackup) uploadEtcdSnapshot(ctx context.Context, key string) (SnapshotEntry, error) {
+	path, err := cb.etcdSnapshotter.Save(ctx, os.TempDir())
+	if err != nil {
+		return SnapshotEntry{}, err
+	}
+	defer os.Remove(path)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return SnapshotEntry{}, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return SnapshotEntry{}, fmt.Errorf("failed to read snapshot: %v", err)
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+
+	opts := minio.PutObjectOptions{ContentType: "application/octet-stream", UserMetadata: checksumMetadata(checksum)}
+	secondaries := cb.fanOut(ctx, cb.defaultTarget(), key, func(client *minio.Client, bucket string) error {
+		_, err := client.FPutObject(ctx, bucket, key, path, opts)
+		return err
+	})
+	var info minio.UploadInfo
+	err = cb.minioCircuitBreaker.Execute(func() error {
+		return cb.retryExecutor.ExecuteWithContext(ctx, func() error {
+			var err error
+			info, err = cb.minioClient.FPutObject(ctx, cb.config.MinIOBucket, key, path, opts)
+			return err
+		})
+	})
+	if secondaryErr := secondaries(); err == nil && secondaryErr != nil {
+		return SnapshotEntry{}, secondaryErr
+	}
+	if err != nil {
+		return SnapshotEntry{}, fmt.Errorf("failed to upload snapshot: %w", failure.Storage(err))
+	}
+
+	return SnapshotEntry{
+		Key:      key,
+		Size:     info.Size,
+		Checksum: checksum,
+	}, nil
+}
+
 // testMinIOConnectivity tests the connection to MinIO
-func (cb *ClusterBackup) testMinIOConnectivity() error {
+func (cb *ClusterBackup) testMinIOConnectivity(ctx context.Context) error {
 	// Check if bucket exists
-	exists, err := cb.minioClient.BucketExists(cb.ctx, cb.config.MinIOBucket)
+	exists, err := cb.minioClient.BucketExists(ctx, cb.config.MinIOBucket)
 	if err != nil {
-		return fmt.Errorf("failed to check bucket existence: %v", err)
+		return fmt.Errorf("failed to check bucket existence: %w", failure.Storage(err))
 	}
 
 	if !exists {
 		if cb.config.AutoCreateBucket {
-			err = cb.minioClient.MakeBucket(cb.ctx, cb.config.MinIOBucket, minio.MakeBucketOptions{})
+			err = cb.minioClient.MakeBucket(ctx, cb.config.MinIOBucket, minio.MakeBucketOptions{})
 			if err != nil {
 				return fmt.Errorf("failed to create bucket: %v", err)
 			}
@@ -158,9 +788,9 @@ func (cb *ClusterBackup) testMinIOConnectivity() error {
 }
 
 // getNamespacesToBackup returns the list of namespaces to backup based on configuration
-func (cb *ClusterBackup) getNamespacesToBackup() ([]string, error) {
+func (cb *ClusterBackup) getNamespacesToBackup(ctx context.Context) ([]string, error) {
 	// Get all namespaces
-	namespaceList, err := cb.kubeClient.CoreV1().Namespaces().List(cb.ctx, v1.ListOptions{})
+	namespaceList, err := cb.kubeClient.CoreV1().Namespaces().List(ctx, v1.ListOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list namespaces: %v", err)
 	}
@@ -174,124 +804,681 @@ func (cb *ClusterBackup) getNamespacesToBackup() ([]string, error) {
 	return cb.filterNamespaces(namespaces), nil
 }
 
-// filterNamespaces applies include/exclude filtering to namespaces
+// filterNamespaces applies the configured filtering mode to namespaces
 func (cb *ClusterBackup) filterNamespaces(namespaces []string) []string {
-	// If include list is specified, use it
-	if len(cb.backupConfig.IncludeNamespaces) > 0 {
-		return cb.intersectStringSlices(namespaces, cb.backupConfig.IncludeNamespaces)
+	return cb.filters().FilterNamespaces(namespaces)
+}
+
+// filters returns the filter engine for the backup configuration
+func (cb *ClusterBackup) filters() *filter.Engine {
+	if cb.filterEngine == nil {
+		cb.filterEngine = filter.NewEngine(cb.backupConfig.FilterRules())
 	}
+	return cb.filterEngine
+}
 
-	// Otherwise, exclude the specified namespaces
-	return cb.excludeStringSlices(namespaces, cb.backupConfig.ExcludeNamespaces)
+// getAPIResources discovers the namespaced resource types that should be backed up
+func (cb *ClusterBackup) getAPIResources() ([]v1.APIResource, error) {
+	resources, err := cb.discoverAPIResources(cb.preferredResources, func(resource v1.APIResource) bool {
+		return resource.Namespaced && (cb.shouldBackupResource(resource.Name) || cb.includeGovernance(resource.Name))
+	})
+	if err != nil {
+		return nil, err
+	}
+	resources = cb.orderGovernanceFirst(resources)
+
+	cb.logger.Info("api_discovery_complete", "Discovered API resources for backup", map[string]interface{}{
+		"resource_types": len(resources),
+	})
+	return resources, nil
+}
+
+// getClusterAPIResources discovers the cluster-scoped resource types named by
+// the cluster resource list
+func (cb *ClusterBackup) getClusterAPIResources() ([]v1.APIResource, error) {
+	resources, err := cb.discoverAPIResources(cb.preferredResources, func(resource v1.APIResource) bool {
+		// CRDs are captured separately by captureCRDs
+		if resource.Group == crdGVR.Group && resource.Name == crdGVR.Resource {
+			return false
+		}
+		return !resource.Namespaced && (cb.filters().IncludeClusterResource(resource.Name) || cb.includeGovernance(resource.Name))
+	})
+	if err != nil {
+		return nil, err
+	}
+	resources = cb.orderGovernanceFirst(resources)
+
+	cb.logger.Info("cluster_api_discovery_complete", "Discovered cluster-scoped API resources for backup", map[string]interface{}{
+		"resource_types": len(resources),
+	})
+	return resources, nil
+}
+
+// discoverAPIResources returns the listable resource types reported by discover
+// that include accepts, with their group and version filled in
+func (cb *ClusterBackup) discoverAPIResources(discover func() ([]*v1.APIResourceList, error), include func(v1.APIResource) bool) ([]v1.APIResource, error) {
+	resourceLists, err := discover()
+	if err != nil {
+		if len(resourceLists) == 0 {
+			return nil, fmt.Errorf("failed to discover API resources: %v", err)
+		}
+		// Partial discovery results are still usable, e.g. when an aggregated API is down
+		cb.logger.Warning("api_discovery_partial", "Some API resources may not be available", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	var resources []v1.APIResource
+	for _, list := range resourceLists {
+		if list == nil {
+			continue
+		}
+
+		// APIResourceList.GroupVersion is authoritative; the per-resource fields are often empty
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			cb.logger.Warning("api_groupversion_parse_error", "Failed to parse GroupVersion", map[string]interface{}{
+				"group_version": list.GroupVersion,
+				"error":         err.Error(),
+			})
+			continue
+		}
+
+		for _, resource := range list.APIResources {
+			// Must be listable and not a subresource
+			if !containsVerb(resource.Verbs, "list") || strings.Contains(resource.Name, "/") {
+				continue
+			}
+			resource.Group = gv.Group
+			resource.Version = gv.Version
+			if !include(resource) {
+				continue
+			}
+			resources = append(resources, resource)
+		}
+	}
+	return resources, nil
+}
+
+// backupClusterScope backs up the configured cluster-scoped resource types.
+// Unlike namespaces, a resource type that fails fails the whole phase.
+func (cb *ClusterBackup) backupClusterScope(ctx context.Context, manifest *Manifest, progress ProgressFunc) (int, error) {
+	cb.logger.Info("cluster_scope_backup_start", "Starting cluster-scoped resource backup", map[string]interface{}{
+		"cluster_resources": cb.backupConfig.ClusterResources,
+	})
+
+	apiResources, err := cb.getClusterAPIResources()
+	if err != nil {
+		err = fmt.Errorf("API resource discovery failed: %w", err)
+		cb.recordFailure(ctx, err)
+		return 0, err
+	}
+	manifest.AddAPIResources(apiResourceEntries(apiResources))
+
+	var failed []string
+	resourceCount, err := cb.backupResourceTypes(ctx, cb.defaultTarget(), "", apiResources, manifest, func(resource v1.APIResource, count int, err error) {
+		if err != nil {
+			cb.logger.Warning("cluster_resource_backup_failed", "Failed to backup cluster-scoped resource", map[string]interface{}{
+				"resource": resource.Name,
+				"category": string(failure.CategoryOf(err)),
+				"error":    err.Error(),
+			})
+			reportProgress(progress, ProgressEvent{Stage: ProgressResourceFailed, Resource: resource.Name, Error: err})
+			failed = append(failed, fmt.Sprintf("%s: %v", resource.Name, err))
+			return
+		}
+		reportProgress(progress, ProgressEvent{Stage: ProgressResourceCompleted, Resource: resource.Name, ItemCount: count})
+	})
+	if err != nil {
+		return resourceCount, err
+	}
+
+	if len(failed) > 0 {
+		return resourceCount, fmt.Errorf("%s", strings.Join(failed, "; "))
+	}
+
+	cb.logger.Info("cluster_scope_backup_complete", "Completed cluster-scoped resource backup", map[string]interface{}{
+		"resource_types": len(apiResources),
+		"resource_count": resourceCount,
+	})
+	return resourceCount, nil
 }
 
 // backupNamespace backs up all resources in a specific namespace
-func (cb *ClusterBackup) backupNamespace(namespace string) (int, error) {
+func (cb *ClusterBackup) backupNamespace(ctx context.Context, namespace string, apiResources []v1.APIResource, manifest *Manifest, progress ProgressFunc) (int, error) {
 	cb.logger.Info("namespace_backup_start", "Starting namespace backup", map[string]interface{}{
 		"namespace": namespace,
 	})
+	reportProgress(progress, ProgressEvent{Stage: ProgressNamespaceStarted, Namespace: namespace})
 
-	// Get API resources
-	apiResources, err := cb.discoveryClient.ServerPreferredNamespacedResources()
+	target, err := cb.targetFor(ctx, namespace)
 	if err != nil {
-		return 0, fmt.Errorf("failed to discover API resources: %v", err)
-	}
-
-	resourceCount := 0
-	for _, resourceList := range apiResources {
-		for _, resource := range resourceList.APIResources {
-			if cb.shouldBackupResource(resource.Name) {
-				count, err := cb.backupResource(namespace, schema.GroupVersionResource{
-					Group:    resourceList.GroupVersion,
-					Version:  "", // Will be set from GroupVersion
-					Resource: resource.Name,
-				}, resource)
-				if err != nil {
-					cb.logger.Warning("resource_backup_failed", "Failed to backup resource", map[string]interface{}{
-						"namespace": namespace,
-						"resource":  resource.Name,
-						"error":     err.Error(),
-					})
-					continue
-				}
-				resourceCount += count
-			}
+		return 0, err
+	}
+	if target.Tenant != "" {
+		manifest.SetTenant(namespace, target.Tenant)
+	}
+
+	// Pre hooks quiesce applications (e.g. flush and lock a database) before
+	// their resources are read. Post hooks always run so that pods whose pre
+	// hook already succeeded are released again.
+	if err := cb.runHooks(ctx, namespace, hooks.PhasePre); err != nil {
+		cb.runHooks(ctx, namespace, hooks.PhasePost)
+		return 0, fmt.Errorf("pre-backup hooks failed: %v", err)
+	}
+
+	resourceCount, err := cb.backupResourceTypes(ctx, target, namespace, apiResources, manifest, func(resource v1.APIResource, count int, err error) {
+		if err != nil {
+			cb.logger.Warning("resource_backup_failed", "Failed to backup resource", map[string]interface{}{
+				"namespace": namespace,
+				"resource":  resource.Name,
+				"category":  string(failure.CategoryOf(err)),
+				"error":     err.Error(),
+			})
+			reportProgress(progress, ProgressEvent{
+				Stage:     ProgressResourceFailed,
+				Namespace: namespace,
+				Resource:  resource.Name,
+				Error:     err,
+			})
+			return
 		}
+		reportProgress(progress, ProgressEvent{
+			Stage:     ProgressResourceCompleted,
+			Namespace: namespace,
+			Resource:  resource.Name,
+			ItemCount: count,
+		})
+	})
+	if err != nil {
+		// Release pods quiesced by the pre hooks before stopping, or
+		// before reporting the namespace out of time
+		cb.runHooks(ctx, namespace, hooks.PhasePost)
+		return resourceCount, err
+	}
+
+	dumpErr := cb.dumpDatabases(ctx, target, namespace, manifest)
+
+	if err := cb.runHooks(ctx, namespace, hooks.PhasePost); err != nil {
+		return resourceCount, fmt.Errorf("post-backup hooks failed: %v", err)
+	}
+	if dumpErr != nil {
+		return resourceCount, dumpErr
 	}
 
 	cb.logger.Info("namespace_backup_complete", "Completed namespace backup", map[string]interface{}{
 		"namespace":      namespace,
 		"resource_count": resourceCount,
 	})
+	reportProgress(progress, ProgressEvent{
+		Stage:     ProgressNamespaceCompleted,
+		Namespace: namespace,
+		ItemCount: resourceCount,
+	})
 
 	return resourceCount, nil
 }
 
+// runHooks runs the namespace's hooks for phase when hooks are enabled
+func (cb *ClusterBackup) runHooks(ctx context.Context, namespace string, phase hooks.Phase) error {
+	if cb.hookRunner == nil {
+		return nil
+	}
+
+	if phase == hooks.PhasePost {
+		// Post hooks release what the pre hooks quiesced, so they also run
+		// for a run cancelled by shutdown or out of time; each hook has its
+		// own timeout
+		ctx = context.WithoutCancel(ctx)
+	}
+	results, err := cb.hookRunner.Run(ctx, namespace, phase)
+	for _, result := range results {
+		status := "success"
+		if result.Err != nil {
+			status = "failure"
+		}
+		cb.metrics.HookExecutions.WithLabelValues(string(phase), status).Inc()
+	}
+	return err
+}
+
+// dumpDatabases uploads a dump for every pod in the namespace matched by a
+// dump plugin, when dumps are enabled
+func (cb *ClusterBackup) dumpDatabases(ctx context.Context, store *tenancy.Target, namespace string, manifest *Manifest) error {
+	if cb.dumper == nil {
+		return nil
+	}
+
+	targets, err := cb.dumper.Targets(ctx, namespace)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, target := range targets {
+		if cb.shutdown.stopping() {
+			return ErrInterrupted
+		}
+		key := store.Key(DataPath(cb.config.ClusterDomain, cb.config.ClusterName, manifest.BackupID, namespace, target.FileName()))
+		entry, err := cb.uploadDump(ctx, store, key, target)
+		if err != nil {
+			cb.metrics.DatabaseDumps.WithLabelValues(target.Plugin.Name(), "failure").Inc()
+			failed = append(failed, fmt.Sprintf("%s: %v", target.Pod, err))
+			continue
+		}
+		cb.metrics.DatabaseDumps.WithLabelValues(target.Plugin.Name(), "success").Inc()
+		manifest.AddDump(entry)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("database dumps failed: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// uploadDump streams a dump straight into the bucket without buffering it.
+// Dumps are not retried since the stream cannot be replayed.
+func (cb *ClusterBackup) uploadDump(ctx context.Context, store *tenancy.Target, key string, target dump.Target) (DumpEntry, error) {
+	reader, writer := io.Pipe()
+	hash := sha256.New()
+
+	dumpErr := make(chan error, 1)
+	go func() {
+		err := cb.dumper.Dump(ctx, target, io.MultiWriter(writer, hash))
+		writer.CloseWithError(err)
+		dumpErr <- err
+	}()
+
+	var info minio.UploadInfo
+	err := cb.minioCircuitBreaker.Execute(func() error {
+		var err error
+		info, err = store.Client.PutObject(ctx, store.Bucket, key, reader, -1,
+			minio.PutObjectOptions{ContentType: "application/sql"})
+		return err
+	})
+	// Unblock the dump if the upload gave up first
+	reader.CloseWithError(err)
+
+	if execErr := <-dumpErr; execErr != nil {
+		return DumpEntry{}, execErr
+	}
+	if err != nil {
+		return DumpEntry{}, fmt.Errorf("failed to upload dump: %w", failure.Storage(err))
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	// The dump cannot be replayed, so the secondary targets copy the upload
+	if err := cb.copyToSecondaries(ctx, store, key, minio.PutObjectOptions{
+		ContentType:  "application/sql",
+		UserMetadata: checksumMetadata(checksum),
+	}); err != nil {
+		return DumpEntry{}, err
+	}
+
+	return DumpEntry{
+		Namespace: target.Namespace,
+		Pod:       target.Pod,
+		Plugin:    target.Plugin.Name(),
+		Bucket:    tenantBucket(store),
+		Key:       key,
+		Size:      info.Size,
+		Checksum:  checksum,
+	}, nil
+}
+
 // shouldBackupResource determines if a resource type should be backed up
 func (cb *ClusterBackup) shouldBackupResource(resourceName string) bool {
-	// If include list is specified, check if resource is in it
-	if len(cb.backupConfig.IncludeResources) > 0 {
-		return cb.stringInSlice(resourceName, cb.backupConfig.IncludeResources)
+	return cb.filters().IncludeResource(resourceName)
+}
+
+// backupResource backs up all instances of a specific resource type in a
+// namespace, or of a cluster-scoped type when namespace is empty
+func (cb *ClusterBackup) backupResource(ctx context.Context, target *tenancy.Target, namespace string, gvr schema.GroupVersionResource, manifest *Manifest) (int, error) {
+	stored := make(map[string]bool)
+	count, err := cb.forEachResource(ctx, namespace, gvr, func(name string, data *payload) error {
+		entry, err := cb.storeResource(ctx, target, namespace, gvr, name, data, manifest)
+		if err != nil {
+			return err
+		}
+		manifest.AddObject(entry)
+		indexObject(ctx, entry, data)
+		stored[name] = true
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, cb.backupAlternateVersions(ctx, target, namespace, gvr, stored, manifest)
+}
+
+// storeResource checks a resource against the policies and secret scanning,
+// then stores it and returns its manifest entry
+func (cb *ClusterBackup) storeResource(ctx context.Context, target *tenancy.Target, namespace string, gvr schema.GroupVersionResource, name string, data *payload, manifest *Manifest) (ObjectEntry, error) {
+	if err := cb.validateResource(ctx, namespace, gvr, name, data, manifest); err != nil {
+		return ObjectEntry{}, err
+	}
+
+	key, err := cb.objectKey(ctx, manifest, namespace, gvr, name, data)
+	if err != nil {
+		return ObjectEntry{}, err
+	}
+	entry, err := cb.storeObject(ctx, target, target.Key(key), namespace, gvr, name, data)
+	if err != nil {
+		return ObjectEntry{}, err
+	}
+	cb.metrics.ResourcesBackedUp.Inc()
+	return entry, nil
+}
+
+// storeObject uploads a resource to key, within the run's prefix under the
+// run layout, or to its blob when storage is content-addressed, and returns
+// its manifest entry
+func (cb *ClusterBackup) storeObject(ctx context.Context, target *tenancy.Target, key, namespace string, gvr schema.GroupVersionResource, name string, data *payload) (ObjectEntry, error) {
+	data, contentChecksum, err := provenanceFrom(ctx).apply(gvr, data)
+	if err != nil {
+		return ObjectEntry{}, fmt.Errorf("failed to add provenance to %s/%s: %v", namespace, name, err)
 	}
 
-	// Otherwise, check if resource is not in exclude list
-	return !cb.stringInSlice(resourceName, cb.backupConfig.ExcludeResources)
+	var versionID string
+	if cb.config.ContentAddressed {
+		key = BlobPath(data.Checksum)
+		err = cb.uploadBlob(ctx, key, data)
+	} else {
+		key, versionID, err = cb.uploadRunObject(ctx, target, key, data)
+	}
+	if err != nil {
+		return ObjectEntry{}, fmt.Errorf("failed to upload %s/%s: %w", namespace, name, failure.Storage(err))
+	}
+
+	return ObjectEntry{
+		Namespace:    namespace,
+		Group:        gvr.Group,
+		Version:      gvr.Version,
+		ResourceType: gvr.Resource,
+		Name:         name,
+		Bucket:       tenantBucket(target),
+		Key:          key,
+		Size:         data.Size,
+		Checksum:     data.Checksum,
+		VersionID:    versionID,
+		ContentChecksum: contentChecksum,
+	}, nil
 }
 
-// backupResource backs up all instances of a specific resource type in a namespace
-func (cb *ClusterBackup) backupResource(namespace string, gvr schema.GroupVersionResource, resource interface{}) (int, error) {
-	// Note: This is a simplified implementation that integrates with the new architecture
-	// The full implementation from main.go would be moved here in a complete refactoring
-	
-	cb.logger.Info("resource_backup_start", "Starting resource backup", map[string]interface{}{
-		"namespace": namespace,
-		"resource":  gvr.Resource,
-		"group":     gvr.Group,
-		"version":   gvr.Version,
-	})
-	
-	// For now, return a placeholder count
-	// In the full refactoring, this would contain all the resource backup logic from main.go
-	resourceCount := 1
-	
-	cb.logger.Info("resource_backup_complete", "Completed resource backup", map[string]interface{}{
-		"namespace":      namespace,
-		"resource":       gvr.Resource,
-		"resource_count": resourceCount,
+// forEachResource lists all instances of a resource type in a namespace, applies
+// the backup filters and normalization, and passes each resource to fn ready to
+// be serialized. It returns the number of resources fn accepted.
+func (cb *ClusterBackup) forEachResource(ctx context.Context, namespace string, gvr schema.GroupVersionResource, fn func(name string, data *payload) error) (int, error) {
+	listOptions := v1.ListOptions{
+		LabelSelector: cb.filters().LabelSelector(),
+	}
+
+	record := runRecordFrom(ctx)
+	count := 0
+	skipped := 0
+	for {
+		// Paginate to keep memory bounded on large namespaces; under adaptive
+		// concurrency the page size may change from one page to the next
+		listOptions.Limit = cb.pageSize(ctx, gvr)
+		var resources *unstructured.UnstructuredList
+		err := cb.apiCircuitBreaker.Execute(func() error {
+			return cb.retryExecutor.ExecuteWithContext(ctx, func() error {
+				listCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+				defer cancel()
+
+				var listErr error
+				started := time.Now()
+				resources, listErr = cb.dynamicClient.Resource(gvr).Namespace(namespace).List(listCtx, listOptions)
+				observeList(ctx, started, listErr)
+				return listErr
+			})
+		})
+		if err != nil {
+			return count, fmt.Errorf("failed to list %s: %w", gvr.Resource, err)
+		}
+
+		for i := range resources.Items {
+			item := &resources.Items[i]
+			left := ReportResource{Namespace: namespace, ResourceType: gvr.Resource, Name: item.GetName()}
+			if reason := cb.skipReason(item); reason != "" {
+				left.Reason = reason
+				record.skip(left)
+				skipped++
+				continue
+			}
+			if dropped, err := cb.applyPlugins(gvr, item); err != nil {
+				return count, err
+			} else if dropped != "" {
+				left.Reason = "filtered out by plugin " + dropped
+				record.skip(left)
+				skipped++
+				continue
+			}
+
+			data, err := newPayload(cb.cleanResource(item))
+			if err != nil {
+				if cb.backupConfig.ValidateYAML && !cb.backupConfig.SkipInvalidResources {
+					return count, failure.Wrap(failure.ErrResourceInvalid, fmt.Errorf("invalid resource %s/%s: %v", namespace, item.GetName(), err))
+				}
+				cb.logger.Warning("resource_invalid_skipped", "Skipping resource that cannot be serialized", map[string]interface{}{
+					"namespace":     namespace,
+					"resource_type": gvr.Resource,
+					"resource_name": item.GetName(),
+					"error":         err.Error(),
+				})
+				left.Reason = err.Error()
+				record.invalidResource(left)
+				skipped++
+				continue
+			}
+
+			// Let the upload in flight finish but start no new one
+			if cb.shutdown.stopping() {
+				return count, ErrInterrupted
+			}
+			if err := fn(item.GetName(), data); errors.Is(err, errResourceBlocked) {
+				left.Reason = "blocked by strict validation"
+				record.skip(left)
+				skipped++
+				continue
+			} else if err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		// Check for pagination continuation
+		if resources.GetContinue() == "" {
+			break
+		}
+		listOptions.Continue = resources.GetContinue()
+	}
+
+	cb.logger.Debug("resource_type_summary", "Resource type processing completed", map[string]interface{}{
+		"namespace":     namespace,
+		"resource_type": gvr.Resource,
+		"processed":     count,
+		"skipped":       skipped,
 	})
-	
-	return resourceCount, nil
+
+	return count, nil
 }
 
-// Helper functions
-func (cb *ClusterBackup) intersectStringSlices(slice1, slice2 []string) []string {
-	var result []string
-	for _, item1 := range slice1 {
-		for _, item2 := range slice2 {
-			if item1 == item2 {
-				result = append(result, item1)
-				break
+// skipReason applies annotation and owner-reference filters to a resource and
+// returns why it is skipped, or "" when it is backed up
+func (cb *ClusterBackup) skipReason(resource *unstructured.Unstructured) string {
+	if !cb.filters().MatchAnnotations(resource.GetAnnotations()) {
+		return "annotation selector does not match"
+	}
+
+	// Skip resources managed by a controller unless owner references are followed
+	if !cb.backupConfig.FollowOwnerReferences {
+		for _, owner := range resource.GetOwnerReferences() {
+			if owner.Controller != nil && *owner.Controller {
+				return fmt.Sprintf("controlled by %s %s", owner.Kind, owner.Name)
 			}
 		}
 	}
-	return result
+
+	return ""
 }
 
-func (cb *ClusterBackup) excludeStringSlices(slice1, slice2 []string) []string {
-	var result []string
-	for _, item := range slice1 {
-		if !cb.stringInSlice(item, slice2) {
-			result = append(result, item)
+// cleanResource strips volatile fields so the stored manifest can be re-applied
+func (cb *ClusterBackup) cleanResource(resource *unstructured.Unstructured) map[string]interface{} {
+	cleaned := make(map[string]interface{})
+	for k, v := range resource.Object {
+		cleaned[k] = v
+	}
+
+	if !cb.backupConfig.IncludeStatus {
+		delete(cleaned, "status")
+	}
+
+	if metadata, ok := cleaned["metadata"].(map[string]interface{}); ok {
+		delete(metadata, "uid")
+		delete(metadata, "resourceVersion")
+		delete(metadata, "generation")
+		delete(metadata, "creationTimestamp")
+		delete(metadata, "selfLink")
+
+		if !cb.backupConfig.IncludeManagedFields {
+			delete(metadata, "managedFields")
 		}
 	}
-	return result
+
+	cb.cleaningProfiles().Apply(cleaned)
+
+	return cleaned
+}
+
+// uploadObject streams a resource into the backup bucket, and the secondary
+// targets at the same time, and returns the object version ID, which is empty
+// unless bucket versioning is enabled. Resources larger than a part are sent
+// as a multipart upload, one part in memory at a time; each retry encodes the
+// resource again.
+func (cb *ClusterBackup) uploadObject(ctx context.Context, target *tenancy.Target, key string, data *payload) (string, error) {
+	if maxSize := parseSize(cb.backupConfig.MaxResourceSize); maxSize > 0 && data.Size > int64(maxSize) {
+		return "", failure.Wrap(failure.ErrResourceInvalid, fmt.Errorf("resource too large: %d bytes, max: %d bytes", data.Size, maxSize))
+	}
+
+	secondaries := cb.fanOut(ctx, target, key, func(client *minio.Client, bucket string) error {
+		_, err := putPayload(ctx, client, bucket, key, data)
+		return err
+	})
+	var versionID string
+	err := cb.minioCircuitBreaker.Execute(func() error {
+		return cb.retryExecutor.ExecuteWithContext(ctx, func() error {
+			info, err := putPayload(ctx, target.Client, target.Bucket, key, data)
+			versionID = info.VersionID
+			return err
+		})
+	})
+	if secondaryErr := secondaries(); err == nil {
+		err = secondaryErr
+	}
+	return versionID, err
+}
+
+// uploadPartSize is the size of the parts of multipart uploads, and so the
+// memory one upload of a large resource takes
+const uploadPartSize = 16 << 20
+
+// putPayload encodes a resource straight into an upload
+func putPayload(ctx context.Context, client *minio.Client, bucket, key string, data *payload) (minio.UploadInfo, error) {
+	reader, writer := io.Pipe()
+	encodeErr := make(chan error, 1)
+	go func() {
+		_, err := data.WriteTo(writer)
+		writer.CloseWithError(err)
+		encodeErr <- err
+	}()
+
+	info, err := client.PutObject(ctx, bucket, key, reader, data.Size,
+		minio.PutObjectOptions{ContentType: "application/x-yaml", UserMetadata: checksumMetadata(data.Checksum), PartSize: uploadPartSize})
+	// Unblock the encoder if the upload gave up first
+	reader.CloseWithError(err)
+	<-encodeErr
+	return info, err
 }
 
-func (cb *ClusterBackup) stringInSlice(str string, slice []string) bool {
-	for _, item := range slice {
-		if strings.Contains(str, item) || item == str {
+// uploadBlob stores a content-addressed payload unless an identical one is
+// already in the bucket
+func (cb *ClusterBackup) uploadBlob(ctx context.Context, key string, data *payload) error {
+	var exists bool
+	err := cb.minioCircuitBreaker.Execute(func() error {
+		return cb.retryExecutor.ExecuteWithContext(ctx, func() error {
+			_, err := cb.minioClient.StatObject(ctx, cb.config.MinIOBucket, key, minio.StatObjectOptions{})
+			if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+				exists = false
+				return nil
+			}
+			exists = err == nil
+			return err
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check blob %s: %w", key, failure.Storage(err))
+	}
+
+	if exists {
+		cb.metrics.BlobsReused.Inc()
+		// A secondary target added later may still lack the blob
+		return cb.fanOut(ctx, cb.defaultTarget(), key, func(client *minio.Client, bucket string) error {
+			_, err := client.StatObject(ctx, bucket, key, minio.StatObjectOptions{})
+			if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+				_, err = putPayload(ctx, client, bucket, key, data)
+			}
+			return err
+		})()
+	}
+	_, err = cb.uploadObject(ctx, cb.defaultTarget(), key, data)
+	return err
+}
+
+// reportProgress delivers a progress event if a callback is registered
+func reportProgress(progress ProgressFunc, event ProgressEvent) {
+	if progress == nil {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	progress(event)
+}
+
+// Helper functions
+
+// containsVerb reports whether verb is in the list of supported verbs
+func containsVerb(verbs []string, verb string) bool {
+	for _, v := range verbs {
+		if v == verb {
 			return true
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
+
+// parseSize converts a Kubernetes-style size such as "10Mi" to bytes
+func parseSize(sizeStr string) int {
+	sizeStr = strings.TrimSpace(sizeStr)
+	if sizeStr == "" {
+		return 0
+	}
+
+	multiplier := 1
+	for _, unit := range []struct {
+		suffix string
+		factor int
+	}{{"Ki", 1024}, {"Mi", 1024 * 1024}, {"Gi", 1024 * 1024 * 1024}} {
+		if strings.HasSuffix(sizeStr, unit.suffix) {
+			multiplier = unit.factor
+			sizeStr = strings.TrimSuffix(sizeStr, unit.suffix)
+			break
+		}
+	}
+
+	value, err := strconv.Atoi(sizeStr)
+	if err != nil {
+		return 0
+	}
+	return value * multiplier
+}
@@ -2,9 +2,14 @@ package backup
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
-	"time"
 
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -14,6 +19,68 @@ import (
 	"cluster-backup/tests/mocks"
 )
 
+// backupMetrics are registered once, with the default registry
+var backupMetrics = metrics.NewBackupMetrics()
+
+// testBuckets is an S3 endpoint answering bucket requests for the buckets it
+// holds, and object requests from an in-memory fakeBucket. It logs the bucket
+// calls it gets and denies them while failing is set.
+type testBuckets struct {
+	mu      sync.Mutex
+	buckets map[string]bool
+	failing bool
+	calls   []string
+	objects *fakeBucket
+}
+
+func (b *testBuckets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if key != "" || r.URL.Query().Get("list-type") == "2" {
+		b.objects.ServeHTTP(w, r)
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch r.Method {
+	case http.MethodHead:
+		b.calls = append(b.calls, "BucketExists("+bucket+")")
+	case http.MethodPut:
+		b.calls = append(b.calls, "MakeBucket("+bucket+")")
+	}
+	switch {
+	case b.failing:
+		b.objects.fail(w, http.StatusForbidden, "AccessDenied")
+	case r.Method == http.MethodPut:
+		b.buckets[bucket] = true
+	case !b.buckets[bucket]:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (b *testBuckets) callLog() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.calls...)
+}
+
+// newBucketTestClient returns a client of a testBuckets endpoint holding
+// buckets
+func newBucketTestClient(t *testing.T, buckets ...string) (*minio.Client, *testBuckets) {
+	endpoint := &testBuckets{buckets: map[string]bool{}, objects: &fakeBucket{objects: map[string][]byte{}}}
+	for _, bucket := range buckets {
+		endpoint.buckets[bucket] = true
+	}
+	server := httptest.NewServer(endpoint)
+	t.Cleanup(server.Close)
+
+	client, err := minio.New(strings.TrimPrefix(server.URL, "http://"), &minio.Options{
+		Creds:  credentials.NewStaticV4("key", "secret", ""),
+		Region: "us-east-1",
+	})
+	require.NoError(t, err)
+	return client, endpoint
+}
+
 func TestNewClusterBackup(t *testing.T) {
 	cfg := &config.Config{
 		ClusterName:   "test-cluster",
@@ -26,9 +93,8 @@ func TestNewClusterBackup(t *testing.T) {
 	}
 	
 	mockClients := mocks.NewMockKubernetesClients()
-	mockMinio := mocks.NewMockMinioClient()
+	mockMinio, _ := newBucketTestClient(t)
 	logger := logging.NewStructuredLogger("test", "test-cluster")
-	backupMetrics := metrics.NewBackupMetrics()
 	ctx := context.Background()
 
 	backup := NewClusterBackup(
@@ -106,13 +172,12 @@ func TestClusterBackup_testMinIOConnectivity(t *testing.T) {
 				AutoCreateBucket: tt.autoCreateBucket,
 			}
 
-			mockMinio := mocks.NewMockMinioClient()
+			var buckets []string
 			if tt.bucketExists {
-				mockMinio.AddTestBucket("test-bucket")
-			}
-			if tt.minioError {
-				mockMinio.SetError(true, "MinIO connection failed")
+				buckets = append(buckets, "test-bucket")
 			}
+			mockMinio, endpoint := newBucketTestClient(t, buckets...)
+			endpoint.failing = tt.minioError
 
 			backup := &ClusterBackup{
 				config:      cfg,
@@ -121,7 +186,7 @@ func TestClusterBackup_testMinIOConnectivity(t *testing.T) {
 				logger:      logging.NewStructuredLogger("test", "test-cluster"),
 			}
 
-			err := backup.testMinIOConnectivity()
+			err := backup.testMinIOConnectivity(context.Background())
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -129,7 +194,7 @@ func TestClusterBackup_testMinIOConnectivity(t *testing.T) {
 				assert.NoError(t, err)
 			}
 
-			callLog := mockMinio.GetCallLog()
+			callLog := endpoint.callLog()
 			if tt.expectBucketCall {
 				assert.Contains(t, callLog, "BucketExists(test-bucket)")
 			}
@@ -152,7 +217,7 @@ func TestClusterBackup_getNamespacesToBackup(t *testing.T) {
 		},
 	}
 
-	namespaces, err := backup.getNamespacesToBackup()
+	namespaces, err := backup.getNamespacesToBackup(context.Background())
 	require.NoError(t, err)
 	
 	// Should get all namespaces from mock client
@@ -324,10 +389,11 @@ func TestClusterBackup_ExecuteBackup(t *testing.T) {
 			}
 
 			mockClients := mocks.NewMockKubernetesClients()
-			mockMinio := mocks.NewMockMinioClient()
+			var buckets []string
 			if tt.bucketExists {
-				mockMinio.AddTestBucket("test-bucket")
+				buckets = append(buckets, "test-bucket")
 			}
+			mockMinio, _ := newBucketTestClient(t, buckets...)
 
 			backup := NewClusterBackup(
 				cfg,
@@ -337,7 +403,7 @@ func TestClusterBackup_ExecuteBackup(t *testing.T) {
 				mockClients.DiscoveryClient,
 				mockMinio,
 				logging.NewStructuredLogger("test", "test-cluster"),
-				metrics.NewBackupMetrics(),
+				backupMetrics,
 				context.Background(),
 			)
 
@@ -358,41 +424,6 @@ func TestClusterBackup_ExecuteBackup(t *testing.T) {
 	}
 }
 
-func TestClusterBackup_HelperFunctions(t *testing.T) {
-	backup := &ClusterBackup{}
-
-	t.Run("intersectStringSlices", func(t *testing.T) {
-		slice1 := []string{"a", "b", "c", "d"}
-		slice2 := []string{"b", "d", "e", "f"}
-		expected := []string{"b", "d"}
-
-		result := backup.intersectStringSlices(slice1, slice2)
-		assert.ElementsMatch(t, expected, result)
-	})
-
-	t.Run("excludeStringSlices", func(t *testing.T) {
-		slice1 := []string{"a", "b", "c", "d"}
-		slice2 := []string{"b", "d"}
-		expected := []string{"a", "c"}
-
-		result := backup.excludeStringSlices(slice1, slice2)
-		assert.ElementsMatch(t, expected, result)
-	})
-
-	t.Run("stringInSlice", func(t *testing.T) {
-		slice := []string{"kube-system", "openshift", "default"}
-
-		// Exact match
-		assert.True(t, backup.stringInSlice("default", slice))
-		
-		// Partial match (contains)
-		assert.True(t, backup.stringInSlice("kube-proxy", slice)) // Contains "kube"
-		
-		// No match
-		assert.False(t, backup.stringInSlice("test-namespace", slice))
-	})
-}
-
 // Benchmark tests
 func BenchmarkClusterBackup_filterNamespaces(b *testing.B) {
 	backup := &ClusterBackup{
@@ -0,0 +1,38 @@
+package backup
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"cluster-backup/internal/priority"
+)
+
+// SetBatchSizes makes runs list the resource types given batch_sizes in the
+// priority configuration a page of that size at a time instead of BATCH_SIZE
+func (cb *ClusterBackup) SetBatchSizes(priorities *priority.Manager) {
+	cb.priorities = priorities
+}
+
+// batchSize returns the page size to list a resource type with
+func (cb *ClusterBackup) batchSize(gvr schema.GroupVersionResource) int64 {
+	if size := cb.batchSizeOverride(gvr); size > 0 {
+		return int64(size)
+	}
+	return int64(cb.config.BatchSize)
+}
+
+// batchSizeOverride returns the page size batch_sizes gives a resource
+// type, 0 without one
+func (cb *ClusterBackup) batchSizeOverride(gvr schema.GroupVersionResource) int {
+	if cb.priorities == nil {
+		return 0
+	}
+	return cb.priorities.GetBatchSize(gvr.Resource, gvr.Group)
+}
+
+// batchSizes returns the batch size overrides, for the run report
+func (cb *ClusterBackup) batchSizes() map[string]int {
+	if cb.priorities == nil {
+		return nil
+	}
+	return cb.priorities.GetBatchSizes()
+}
@@ -0,0 +1,76 @@
+package backup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"cluster-backup/internal/adaptive"
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/priority"
+)
+
+const testBatchSizes = `
+backup_config:
+  batch_sizes:
+    secrets: 100
+    events: 500
+    virtualmachines: 5
+    virtualmachines.kubevirt.io: 2
+    pods: 5000
+`
+
+func TestBatchSize(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: priority.DefaultConfigMap, Namespace: priority.DefaultNamespace},
+		Data:       map[string]string{"priority-config.yaml": testBatchSizes},
+	})
+	priorities := priority.NewManager(kubeClient, priority.DefaultConfigMap, priority.DefaultNamespace)
+	require.NoError(t, priorities.LoadConfig())
+
+	cb := &ClusterBackup{config: &config.Config{BatchSize: 50}}
+	secrets := schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+	assert.Equal(t, int64(50), cb.batchSize(secrets), "BATCH_SIZE applies without the priority configuration")
+	assert.Nil(t, cb.batchSizes())
+
+	cb.SetBatchSizes(priorities)
+	assert.Equal(t, int64(100), cb.batchSize(secrets))
+	assert.Equal(t, int64(500), cb.batchSize(schema.GroupVersionResource{Group: "events.k8s.io", Version: "v1", Resource: "events"}))
+	assert.Equal(t, int64(2), cb.batchSize(schema.GroupVersionResource{Group: "kubevirt.io", Version: "v1", Resource: "virtualmachines"}),
+		"the resource and group wins over the resource name")
+	assert.Equal(t, int64(5), cb.batchSize(schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "virtualmachines"}))
+	assert.Equal(t, int64(50), cb.batchSize(schema.GroupVersionResource{Version: "v1", Resource: "pods"}), "sizes above the limit are ignored")
+	assert.Equal(t, int64(50), cb.batchSize(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}))
+
+	assert.Equal(t, map[string]int{
+		"secrets": 100, "events": 500, "virtualmachines": 5, "virtualmachines.kubevirt.io": 2,
+	}, cb.batchSizes())
+}
+
+func TestPageSize_AdaptiveConcurrency(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: priority.DefaultConfigMap, Namespace: priority.DefaultNamespace},
+		Data:       map[string]string{"priority-config.yaml": testBatchSizes},
+	})
+	priorities := priority.NewManager(kubeClient, priority.DefaultConfigMap, priority.DefaultNamespace)
+	require.NoError(t, priorities.LoadConfig())
+
+	cb := &ClusterBackup{config: &config.Config{BatchSize: 50}}
+	cb.SetBatchSizes(priorities)
+	secrets := schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+	configmaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	assert.Equal(t, int64(50), cb.pageSize(context.Background(), configmaps), "BATCH_SIZE applies without adaptive concurrency")
+
+	controller := adaptive.New(adaptive.Bounds{MinWorkers: 1, MaxWorkers: 4, MinPageSize: 10, MaxPageSize: 500, TargetLatency: time.Second}, 50)
+	ctx := withConcurrency(context.Background(), controller)
+	controller.Observe(time.Millisecond, true)
+	assert.Equal(t, int64(25), cb.pageSize(ctx, configmaps), "the controller's page size replaces BATCH_SIZE")
+	assert.Equal(t, int64(100), cb.pageSize(ctx, secrets), "batch_sizes overrides stay fixed")
+}
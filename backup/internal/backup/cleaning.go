@@ -0,0 +1,23 @@
+package backup
+
+import (
+	"cluster-backup/internal/cleaning"
+)
+
+// builtinCleaning strips the fields of the built-in profiles when no
+// cleaning profiles are set
+var builtinCleaning = cleaning.Builtin()
+
+// SetCleaningProfiles replaces the built-in profiles cleanResource strips
+// per-kind fields with
+func (cb *ClusterBackup) SetCleaningProfiles(profiles *cleaning.Profiles) {
+	cb.cleaning = profiles
+}
+
+// cleaningProfiles returns the profiles set, or the built-in ones
+func (cb *ClusterBackup) cleaningProfiles() *cleaning.Profiles {
+	if cb.cleaning == nil {
+		return builtinCleaning
+	}
+	return cb.cleaning
+}
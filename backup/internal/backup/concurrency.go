@@ -0,0 +1,158 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"cluster-backup/internal/adaptive"
+	"cluster-backup/internal/tenancy"
+)
+
+type concurrencyKey struct{}
+
+// withConcurrency returns a context the resource types of a run are listed
+// under controller through
+func withConcurrency(ctx context.Context, controller *adaptive.Controller) context.Context {
+	return context.WithValue(ctx, concurrencyKey{}, controller)
+}
+
+// concurrencyFrom returns the adaptive concurrency controller of the run ctx
+// belongs to, nil when resource types are listed one at a time
+func concurrencyFrom(ctx context.Context) *adaptive.Controller {
+	controller, _ := ctx.Value(concurrencyKey{}).(*adaptive.Controller)
+	return controller
+}
+
+// newConcurrency returns the controller of run backupID under
+// ADAPTIVE_CONCURRENCY, or nil when it is off
+func (cb *ClusterBackup) newConcurrency(backupID string) *adaptive.Controller {
+	if !cb.config.AdaptiveConcurrency {
+		return nil
+	}
+	controller := adaptive.New(adaptive.Bounds{
+		MinWorkers:    cb.config.AdaptiveMinWorkers,
+		MaxWorkers:    cb.config.AdaptiveMaxWorkers,
+		MinPageSize:   cb.config.AdaptiveMinBatchSize,
+		MaxPageSize:   cb.config.AdaptiveMaxBatchSize,
+		TargetLatency: cb.config.AdaptiveTargetLatency,
+	}, cb.config.BatchSize)
+	controller.OnChange = func(workers, pageSize int, reason string) {
+		cb.metrics.AdaptiveWorkers.Set(float64(workers))
+		cb.metrics.AdaptivePageSize.Set(float64(pageSize))
+		cb.logger.Info("adaptive_concurrency_adjusted", "Adjusted list concurrency to the API server", map[string]interface{}{
+			"backup_id": backupID,
+			"workers":   workers,
+			"page_size": pageSize,
+			"reason":    reason,
+		})
+	}
+	cb.metrics.AdaptiveWorkers.Set(float64(controller.Workers()))
+	cb.metrics.AdaptivePageSize.Set(float64(controller.PageSize()))
+	return controller
+}
+
+// pageSize returns the page size to list the next page of a resource type
+// with: its batch_sizes override, else the controller's current page size,
+// else BATCH_SIZE
+func (cb *ClusterBackup) pageSize(ctx context.Context, gvr schema.GroupVersionResource) int64 {
+	if size := cb.batchSizeOverride(gvr); size > 0 {
+		return int64(size)
+	}
+	if controller := concurrencyFrom(ctx); controller != nil {
+		return int64(controller.PageSize())
+	}
+	return int64(cb.config.BatchSize)
+}
+
+// observeList feeds the latency of a list call, and whether it was
+// throttled, to the run's controller. Calls cut short by the run are left
+// out.
+func observeList(ctx context.Context, started time.Time, err error) {
+	controller := concurrencyFrom(ctx)
+	if controller == nil || ctx.Err() != nil {
+		return
+	}
+	controller.Observe(time.Since(started), apierrors.IsTooManyRequests(err))
+}
+
+// backupResourceTypes backs up the resource types of a namespace, or of the
+// cluster scope with namespace "", one at a time or, under adaptive
+// concurrency, as many at once as the controller allows. done is called,
+// one call at a time, with the result of each type that succeeded or failed.
+// A type stopped by shutdown or the end of the run starts no further types;
+// the first such error is returned with the number of resources stored by
+// the types that did not fail.
+func (cb *ClusterBackup) backupResourceTypes(ctx context.Context, target *tenancy.Target, namespace string, apiResources []v1.APIResource, manifest *Manifest, done func(resource v1.APIResource, count int, err error)) (int, error) {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		total   int
+		stopErr error
+	)
+	// backup stores one type and reports whether to go on with the next
+	backup := func(resource v1.APIResource) bool {
+		gvr := schema.GroupVersionResource{
+			Group:    resource.Group,
+			Version:  resource.Version,
+			Resource: resource.Name,
+		}
+		count, err := cb.backupResource(ctx, target, namespace, gvr, manifest)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if errors.Is(err, ErrInterrupted) || (err != nil && ctx.Err() != nil) {
+			total += count
+			if stopErr == nil {
+				stopErr = err
+			}
+			return false
+		}
+		if err == nil {
+			total += count
+		} else {
+			cb.recordFailure(ctx, err)
+		}
+		done(resource, count, err)
+		return stopErr == nil
+	}
+
+	controller := concurrencyFrom(ctx)
+	for _, resource := range apiResources {
+		if controller == nil {
+			if !backup(resource) {
+				break
+			}
+			continue
+		}
+
+		if err := controller.Acquire(ctx); err != nil {
+			mu.Lock()
+			if stopErr == nil {
+				stopErr = err
+			}
+			mu.Unlock()
+			break
+		}
+		mu.Lock()
+		stopped := stopErr != nil
+		mu.Unlock()
+		if stopped {
+			controller.Release()
+			break
+		}
+		wg.Add(1)
+		go func(resource v1.APIResource) {
+			defer wg.Done()
+			defer controller.Release()
+			backup(resource)
+		}(resource)
+	}
+	wg.Wait()
+	return total, stopErr
+}
@@ -0,0 +1,98 @@
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"cluster-backup/internal/config"
+)
+
+// ConfigChange is a setting whose value differs from the previous run's. An
+// empty Previous or Current means the setting was unset, or did not exist in
+// that run.
+type ConfigChange struct {
+	Setting  string `json:"setting"`
+	Previous string `json:"previous"`
+	Current  string `json:"current"`
+}
+
+// ConfigVersion hashes a configuration snapshot, so runs made with the same
+// settings share a version
+func ConfigVersion(snapshot map[string]string) string {
+	settings := make([]string, 0, len(snapshot))
+	for setting := range snapshot {
+		settings = append(settings, setting)
+	}
+	sort.Strings(settings)
+
+	hash := sha256.New()
+	for _, setting := range settings {
+		hash.Write([]byte(setting + "=" + snapshot[setting] + "\n"))
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// DiffConfig returns the settings that changed between two snapshots, sorted
+// by name
+func DiffConfig(previous, current map[string]string) []ConfigChange {
+	var changes []ConfigChange
+	for setting, value := range current {
+		if old, ok := previous[setting]; !ok || old != value {
+			changes = append(changes, ConfigChange{Setting: setting, Previous: old, Current: value})
+		}
+	}
+	for setting, old := range previous {
+		if _, ok := current[setting]; !ok {
+			changes = append(changes, ConfigChange{Setting: setting, Previous: old})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Setting < changes[j].Setting })
+	return changes
+}
+
+// recordConfig stores the run's configuration, secrets redacted, in its
+// manifest along with the settings changed since the previous run. A previous
+// run that cannot be read is not compared against.
+func (cb *ClusterBackup) recordConfig(ctx context.Context, manifest *Manifest) {
+	previous, err := cb.manifestStore.Latest(ctx)
+	if err != nil {
+		previous = nil
+	}
+	cb.compareConfig(manifest, previous)
+}
+
+// compareConfig records the run's configuration and its drift from previous,
+// which is logged for audit. Runs that predate configuration snapshots are
+// not compared against.
+func (cb *ClusterBackup) compareConfig(manifest, previous *Manifest) {
+	snapshot := config.Snapshot(cb.config, cb.backupConfig)
+	version := ConfigVersion(snapshot)
+
+	var previousID string
+	var changes []ConfigChange
+	if previous != nil && previous.Config != nil {
+		previousID = previous.BackupID
+		if previous.ConfigVersion != version {
+			changes = DiffConfig(previous.Config, snapshot)
+		}
+	}
+	manifest.SetConfig(snapshot, version, previousID, changes)
+
+	if len(changes) == 0 {
+		return
+	}
+	settings := make([]string, len(changes))
+	for i, change := range changes {
+		settings[i] = change.Setting
+	}
+	cb.logger.Warning("config_drift", "Configuration changed since the previous run", map[string]interface{}{
+		"backup_id":        manifest.BackupID,
+		"previous_backup":  previousID,
+		"config_version":   version,
+		"changed_settings": strings.Join(settings, ","),
+		"changes":          changes,
+	})
+}
@@ -0,0 +1,84 @@
+package backup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/logging"
+)
+
+func TestDiffConfig(t *testing.T) {
+	changes := DiffConfig(
+		map[string]string{"Config.BatchSize": "100", "Config.MinIOBucket": "backups", "Config.Removed": "x"},
+		map[string]string{"Config.BatchSize": "500", "Config.MinIOBucket": "backups", "Config.Added": "y"},
+	)
+	assert.Equal(t, []ConfigChange{
+		{Setting: "Config.Added", Current: "y"},
+		{Setting: "Config.BatchSize", Previous: "100", Current: "500"},
+		{Setting: "Config.Removed", Previous: "x"},
+	}, changes)
+}
+
+func TestConfigVersion(t *testing.T) {
+	a := map[string]string{"Config.BatchSize": "100", "Config.MinIOBucket": "backups"}
+	b := map[string]string{"Config.MinIOBucket": "backups", "Config.BatchSize": "100"}
+	assert.Equal(t, ConfigVersion(a), ConfigVersion(b))
+	assert.Len(t, ConfigVersion(a), 16)
+
+	b["Config.BatchSize"] = "500"
+	assert.NotEqual(t, ConfigVersion(a), ConfigVersion(b))
+}
+
+func TestCompareConfig(t *testing.T) {
+	cb := &ClusterBackup{
+		config:       &config.Config{ClusterName: "prod", BatchSize: 100, MinIOSecretKey: "secret"},
+		backupConfig: &config.BackupConfig{FilteringMode: "whitelist"},
+		logger:       logging.NewStructuredLogger("config-test", "prod"),
+	}
+
+	start := time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC)
+	first := NewManifest("20260301-020000", "prod", "example.com", "backups", start)
+	cb.compareConfig(first, nil)
+	assert.Equal(t, config.Redacted, first.Config["Config.MinIOSecretKey"])
+	assert.Empty(t, first.ConfigBaseline, "the first run has nothing to compare against")
+
+	same := NewManifest("20260302-020000", "prod", "example.com", "backups", start.Add(24*time.Hour))
+	cb.compareConfig(same, first)
+	assert.Equal(t, first.ConfigVersion, same.ConfigVersion)
+	assert.Equal(t, "20260301-020000", same.ConfigBaseline)
+	assert.Empty(t, same.ConfigChanges)
+
+	cb.config.BatchSize = 500
+	cb.config.MinIOSecretKey = "rotated"
+	changed := NewManifest("20260302-020000", "prod", "example.com", "backups", start.Add(24*time.Hour))
+	cb.compareConfig(changed, first)
+	assert.NotEqual(t, first.ConfigVersion, changed.ConfigVersion)
+	assert.Equal(t, []ConfigChange{{Setting: "Config.BatchSize", Previous: "100", Current: "500"}}, changed.ConfigChanges,
+		"rotated credentials are redacted on both sides and do not show as drift")
+
+	legacy := NewManifest("20260228-020000", "prod", "example.com", "backups", start.Add(-24*time.Hour))
+	unknown := NewManifest("20260303-020000", "prod", "example.com", "backups", start.Add(48*time.Hour))
+	cb.compareConfig(unknown, legacy)
+	assert.Empty(t, unknown.ConfigBaseline, "runs without a snapshot are not compared against")
+	assert.Empty(t, unknown.ConfigChanges)
+}
+
+func TestManifestRoundTrip_KeepsConfig(t *testing.T) {
+	client, _ := newLockTestClient(t)
+	store := NewManifestStore(client, "backups", "example.com", "prod")
+	cb := &ClusterBackup{config: &config.Config{BatchSize: 100}, backupConfig: &config.BackupConfig{}, logger: logging.NewStructuredLogger("config-test", "prod")}
+
+	manifest := NewManifest("20260301-020000", "prod", "example.com", "backups", time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC))
+	cb.compareConfig(manifest, nil)
+	require.NoError(t, store.Save(context.Background(), manifest))
+
+	loaded, err := store.Load(context.Background(), "20260301-020000")
+	require.NoError(t, err)
+	assert.Equal(t, manifest.Config, loaded.Config)
+	assert.Equal(t, manifest.ConfigVersion, loaded.Summary().ConfigVersion)
+}
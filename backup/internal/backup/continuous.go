@@ -0,0 +1,504 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"cluster-backup/internal/audit"
+	"cluster-backup/internal/failure"
+	"cluster-backup/internal/tenancy"
+)
+
+// continuousWorkers is how many live copies are stored at the same time
+const continuousWorkers = 4
+
+// liveCopy is the copy of a resource continuous backup keeps under the live
+// prefix
+type liveCopy struct {
+	target *tenancy.Target
+	// flat is the key of the resource without the live prefix, which the
+	// snapshots store it under within their own
+	flat  string
+	entry ObjectEntry
+	// The policy violations and suspected credentials of the copy, recorded
+	// in the snapshots it is part of
+	violations []PolicyViolation
+	findings   []SecretFinding
+}
+
+// continuousBackup keeps a copy of each included resource under the live
+// prefix, stored a debounce after it changes, and consolidates the copies
+// into a snapshot run every snapshot interval
+type continuousBackup struct {
+	cb      *ClusterBackup
+	listers map[schema.GroupVersionResource]cache.GenericLister
+	queue   workqueue.TypedRateLimitingInterface[windowKey]
+
+	// storing is held by the workers while they update a live copy, and by
+	// a snapshot for its whole consolidation, so a snapshot sees the copies
+	// as of one point in time
+	storing sync.RWMutex
+	mu      sync.Mutex
+	live    map[windowKey]liveCopy
+}
+
+func (cb *ClusterBackup) newContinuousBackup() *continuousBackup {
+	return &continuousBackup{
+		cb:      cb,
+		listers: make(map[schema.GroupVersionResource]cache.GenericLister),
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[windowKey](),
+			workqueue.TypedRateLimitingQueueConfig[windowKey]{Name: "continuous-backup"},
+		),
+		live: make(map[windowKey]liveCopy),
+	}
+}
+
+// RunContinuous runs informers on the included resource types under
+// CONTINUOUS_BACKUP until ctx is done. Every resource is stored under the
+// live prefix once the informers have listed it, and again a debounce after
+// it changes; changes made within the debounce of the first are stored
+// together. Deleted resources, and those the filters no longer include, are
+// removed from it. Every snapshot interval the live copies are consolidated
+// into a snapshot run with a manifest of its own, like any other run.
+func (cb *ClusterBackup) RunContinuous(ctx context.Context) error {
+	if err := cb.testMinIOConnectivity(ctx); err != nil {
+		return fmt.Errorf("MinIO connectivity test failed: %v", err)
+	}
+	resources, err := cb.getAPIResources()
+	if err != nil {
+		return err
+	}
+	if cb.backupConfig.BackupClusterResources {
+		clusterResources, err := cb.getClusterAPIResources()
+		if err != nil {
+			return err
+		}
+		resources = append(resources, clusterResources...)
+	}
+
+	c := cb.newContinuousBackup()
+	selector := cb.filters().LabelSelector()
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(cb.dynamicClient, 0, v1.NamespaceAll, func(options *v1.ListOptions) {
+		options.LabelSelector = selector
+	})
+	for _, resource := range resources {
+		if !containsVerb(resource.Verbs, "list") || !containsVerb(resource.Verbs, "watch") {
+			continue
+		}
+		gvr := schema.GroupVersionResource{Group: resource.Group, Version: resource.Version, Resource: resource.Name}
+		informer := factory.ForResource(gvr)
+		c.listers[gvr] = informer.Lister()
+		if _, err := informer.Informer().AddEventHandler(c.handler(gvr)); err != nil {
+			return fmt.Errorf("failed to watch %s: %v", QualifiedResource(gvr, false), err)
+		}
+	}
+
+	informerCtx, stopInformers := context.WithCancel(ctx)
+	defer stopInformers()
+	factory.Start(informerCtx.Done())
+	defer factory.Shutdown()
+	for gvr, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced && ctx.Err() == nil {
+			cb.logger.Warning("continuous_sync_failed", "Failed to list a resource type for continuous backup", map[string]interface{}{
+				"resource_type": QualifiedResource(gvr, false),
+			})
+		}
+	}
+	cb.logger.Info("continuous_backup_started", "Keeping live copies of the included resources", map[string]interface{}{
+		"resource_types":    len(c.listers),
+		"debounce":          cb.config.ContinuousDebounce.String(),
+		"snapshot_interval": cb.config.ContinuousSnapshotInterval.String(),
+	})
+
+	var workers sync.WaitGroup
+	for i := 0; i < continuousWorkers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for c.processNext(ctx) {
+			}
+		}()
+	}
+	defer func() {
+		c.queue.ShutDown()
+		workers.Wait()
+	}()
+
+	ticker := time.NewTicker(cb.config.ContinuousSnapshotInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			cb.logger.Info("continuous_backup_stopped", "Stopped continuous backup", nil)
+			return nil
+		case <-ticker.C:
+			if _, err := c.snapshot(ctx); errors.Is(err, ErrShuttingDown) {
+				return nil
+			}
+		}
+	}
+}
+
+// handler queues the resources of gvr that change. Those listed when the
+// informer starts are stored right away, the others after the debounce.
+func (c *continuousBackup) handler(gvr schema.GroupVersionResource) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			c.enqueue(gvr, obj, isInInitialList)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			c.enqueue(gvr, obj, false)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			c.enqueue(gvr, obj, false)
+		},
+	}
+}
+
+// enqueue queues a resource of an included namespace, or a cluster-scoped
+// one
+func (c *continuousBackup) enqueue(gvr schema.GroupVersionResource, obj interface{}, now bool) {
+	item, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+	if item.GetNamespace() != "" && !c.cb.filters().IncludeNamespace(item.GetNamespace()) {
+		return
+	}
+	key := windowKey{gvr: gvr, namespace: item.GetNamespace(), name: item.GetName()}
+	if now {
+		c.queue.Add(key)
+		return
+	}
+	c.queue.AddAfter(key, c.cb.config.ContinuousDebounce)
+}
+
+// processNext stores the next queued resource, and reports false once the
+// queue is shut down. A resource that cannot be stored is queued again with
+// backoff, unless trying again cannot help; its next change queues it anew.
+func (c *continuousBackup) processNext(ctx context.Context) bool {
+	key, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.sync(ctx, key); err != nil {
+		if ctx.Err() != nil {
+			return true
+		}
+		c.cb.recordFailure(ctx, err)
+		c.cb.logger.Warning("live_copy_failed", "Failed to update the live copy of a resource", map[string]interface{}{
+			"namespace":     key.namespace,
+			"resource_type": QualifiedResource(key.gvr, false),
+			"resource_name": key.name,
+			"error":         err.Error(),
+		})
+		if failure.Retryable(err) {
+			c.queue.AddRateLimited(key)
+			return true
+		}
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+// sync brings the live copy of a resource in line with the informer's cache:
+// it is stored when its content changed and removed when the resource is gone
+// or no longer backed up
+func (c *continuousBackup) sync(ctx context.Context, key windowKey) error {
+	c.storing.RLock()
+	defer c.storing.RUnlock()
+
+	obj, err := c.get(key)
+	if apierrors.IsNotFound(err) {
+		return c.remove(ctx, key)
+	} else if err != nil {
+		return err
+	}
+	item, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+	// The plugins and cleaning must not change the informer's copy
+	item = item.DeepCopy()
+	if c.cb.skipReason(item) != "" {
+		return c.remove(ctx, key)
+	}
+	if dropped, err := c.cb.applyPlugins(key.gvr, item); err != nil {
+		return err
+	} else if dropped != "" {
+		return c.remove(ctx, key)
+	}
+	data, err := newPayload(c.cb.cleanResource(item))
+	if err != nil {
+		return failure.Wrap(failure.ErrResourceInvalid, fmt.Errorf("invalid resource: %v", err))
+	}
+	c.mu.Lock()
+	existing, ok := c.live[key]
+	c.mu.Unlock()
+	if ok && existing.entry.Checksum == data.Checksum {
+		return nil
+	}
+
+	// Violations are collected on a manifest of their own and kept with the
+	// copy until a snapshot records them
+	findings := NewManifest(liveDir, c.cb.config.ClusterName, c.cb.config.ClusterDomain, c.cb.config.MinIOBucket, time.Now())
+	if err := c.cb.validateResource(ctx, key.namespace, key.gvr, key.name, data, findings); errors.Is(err, errResourceBlocked) {
+		return c.remove(ctx, key)
+	}
+
+	target, err := c.cb.targetFor(ctx, key.namespace)
+	if err != nil {
+		return err
+	}
+	flat := ObjectPath(c.cb.config.ClusterDomain, c.cb.config.ClusterName, key.namespace, QualifiedResource(key.gvr, c.cb.config.ObjectPathVersion), key.name)
+	liveKey := target.Key(LivePath(c.cb.config.ClusterDomain, c.cb.config.ClusterName, flat))
+	versionID, err := c.cb.uploadObject(ctx, target, liveKey, data)
+	if err != nil {
+		return fmt.Errorf("failed to upload %s/%s: %w", key.namespace, key.name, failure.Storage(err))
+	}
+
+	c.mu.Lock()
+	c.live[key] = liveCopy{
+		target: target,
+		flat:   flat,
+		entry: ObjectEntry{
+			Namespace:    key.namespace,
+			Group:        key.gvr.Group,
+			Version:      key.gvr.Version,
+			ResourceType: key.gvr.Resource,
+			Name:         key.name,
+			Bucket:       tenantBucket(target),
+			Key:          liveKey,
+			Size:         data.Size,
+			Checksum:     data.Checksum,
+			VersionID:    versionID,
+		},
+		violations: findings.PolicyViolations,
+		findings:   findings.SecretFindings,
+	}
+	c.mu.Unlock()
+	c.cb.metrics.LiveObjects.WithLabelValues("stored").Inc()
+	return nil
+}
+
+// get returns a resource from the informer's cache
+func (c *continuousBackup) get(key windowKey) (runtime.Object, error) {
+	lister, ok := c.listers[key.gvr]
+	if !ok {
+		return nil, apierrors.NewNotFound(key.gvr.GroupResource(), key.name)
+	}
+	if key.namespace == "" {
+		return lister.Get(key.name)
+	}
+	return lister.ByNamespace(key.namespace).Get(key.name)
+}
+
+// remove deletes the live copy of a resource, when there is one
+func (c *continuousBackup) remove(ctx context.Context, key windowKey) error {
+	c.mu.Lock()
+	existing, ok := c.live[key]
+	c.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	if err := c.cb.removeKeys(ctx, existing.target, []string{existing.entry.Key}); err != nil {
+		return failure.Storage(err)
+	}
+
+	c.mu.Lock()
+	delete(c.live, key)
+	c.mu.Unlock()
+	c.cb.metrics.LiveObjects.WithLabelValues("deleted").Inc()
+	return nil
+}
+
+// copies returns the live copies in the order of their type, namespace and
+// name
+func (c *continuousBackup) copies() []liveCopy {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	copies := make([]liveCopy, 0, len(c.live))
+	for _, object := range c.live {
+		copies = append(copies, object)
+	}
+	sort.Slice(copies, func(i, j int) bool {
+		return copies[i].entry.Key < copies[j].entry.Key
+	})
+	return copies
+}
+
+// snapshot copies the live copies, server-side, under the prefix of a new
+// run and saves its manifest. Live copies are not updated meanwhile. A copy
+// that fails fails its namespace, which leaves the snapshot partial. The
+// live copies of resources removed while the service was not running are
+// pruned afterwards.
+func (c *continuousBackup) snapshot(ctx context.Context) (*Manifest, error) {
+	cb := c.cb
+	if err := cb.shutdown.begin(); err != nil {
+		return nil, err
+	}
+	defer cb.shutdown.end()
+	c.storing.Lock()
+	defer c.storing.Unlock()
+
+	startTime := time.Now()
+	backupID := NewBackupID(startTime)
+	manifest := NewManifest(backupID, cb.config.ClusterName, cb.config.ClusterDomain, cb.config.MinIOBucket, startTime)
+	counts := make(map[string]int)
+	errs := make(map[string]error)
+	written := make(map[string]map[string]bool)
+	for _, object := range c.copies() {
+		namespace := object.entry.Namespace
+		if written[object.target.Bucket] == nil {
+			written[object.target.Bucket] = make(map[string]bool)
+		}
+		written[object.target.Bucket][object.entry.Key] = true
+		if errs[namespace] != nil {
+			continue
+		}
+
+		entry := object.entry
+		entry.Key = object.target.Key(RunPath(cb.config.ClusterDomain, cb.config.ClusterName, backupID, object.flat))
+		versionID, err := cb.copyObject(ctx, object.target, object.entry.Key, entry.Key)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			errs[namespace] = fmt.Errorf("failed to copy %s/%s: %w", namespace, entry.Name, failure.Storage(err))
+			continue
+		}
+		entry.VersionID = versionID
+		manifest.AddObject(entry)
+		if object.target.Tenant != "" {
+			manifest.SetTenant(namespace, object.target.Tenant)
+		}
+		for _, violation := range object.violations {
+			manifest.AddPolicyViolation(violation)
+		}
+		for _, finding := range object.findings {
+			manifest.AddSecretFinding(finding)
+		}
+		counts[namespace]++
+	}
+	for namespace, count := range counts {
+		if namespace == "" {
+			manifest.SetClusterScope(count, errs[namespace])
+		} else {
+			manifest.SetNamespace(namespace, count, errs[namespace])
+		}
+	}
+	for namespace, err := range errs {
+		if counts[namespace] == 0 {
+			if namespace == "" {
+				manifest.SetClusterScope(0, err)
+			} else {
+				manifest.SetNamespace(namespace, 0, err)
+			}
+		}
+	}
+	manifest.Finish(time.Now())
+
+	saveCtx, cancel := cb.checkpointContext()
+	defer cancel()
+	// Snapshots are audited like runs, as the service's own
+	result := &BackupResult{BackupID: backupID, NamespacesBackedUp: len(manifest.Namespaces), ResourcesBackedUp: len(manifest.Objects)}
+	for _, err := range errs {
+		result.Errors = append(result.Errors, err)
+	}
+	auditCtx := audit.WithActor(ctx, audit.SystemActor("continuous"))
+	if err := cb.manifestStore.Save(saveCtx, manifest); err != nil {
+		cb.logger.Error("live_snapshot_failed", "Failed to save the manifest of a live snapshot", map[string]interface{}{
+			"backup_id": backupID,
+			"error":     err.Error(),
+		})
+		cb.auditLog.Record(auditCtx, AuditRecord(result, err))
+		return nil, err
+	}
+	cb.auditLog.Record(auditCtx, AuditRecord(result, nil))
+	cb.metrics.LiveSnapshot.Set(float64(manifest.EndTime.Unix()))
+	cb.logger.Info("live_snapshot_complete", "Consolidated the live copies into a snapshot", map[string]interface{}{
+		"backup_id": backupID,
+		"status":    manifest.Status,
+		"objects":   len(manifest.Objects),
+	})
+
+	c.pruneLive(ctx, written)
+	return manifest, nil
+}
+
+// copyObject copies an object within target, server-side, and on the
+// secondary targets mirroring the default one, and returns the version ID
+// of the copy
+func (cb *ClusterBackup) copyObject(ctx context.Context, target *tenancy.Target, source, key string) (string, error) {
+	copyTo := func(client *minio.Client, bucket string) (minio.UploadInfo, error) {
+		return client.CopyObject(ctx,
+			minio.CopyDestOptions{Bucket: bucket, Object: key},
+			minio.CopySrcOptions{Bucket: bucket, Object: source})
+	}
+	secondaries := cb.fanOut(ctx, target, key, func(client *minio.Client, bucket string) error {
+		_, err := copyTo(client, bucket)
+		return err
+	})
+	var versionID string
+	err := cb.minioCircuitBreaker.Execute(func() error {
+		return cb.retryExecutor.ExecuteWithContext(ctx, func() error {
+			info, err := copyTo(target.Client, target.Bucket)
+			versionID = info.VersionID
+			return err
+		})
+	})
+	if secondaryErr := secondaries(); err == nil {
+		err = secondaryErr
+	}
+	return versionID, err
+}
+
+// pruneLive removes the keys under the live prefix that are not live copies,
+// such as those of resources deleted while the service was not running
+func (c *continuousBackup) pruneLive(ctx context.Context, written map[string]map[string]bool) {
+	cb := c.cb
+	targets := []*tenancy.Target{cb.defaultTarget()}
+	if cb.tenancy != nil {
+		targets = append(targets, cb.tenancy.Tenants()...)
+	}
+	prefix := LivePrefix(cb.config.ClusterDomain, cb.config.ClusterName)
+	for _, target := range targets {
+		stale, err := cb.staleKeys(ctx, target, target.Key(prefix), written[target.Bucket])
+		if err == nil {
+			err = cb.removeKeys(ctx, target, stale)
+		}
+		if err != nil {
+			cb.logger.Warning("live_prune_failed", "Failed to remove stale live copies", map[string]interface{}{
+				"bucket": target.Bucket,
+				"error":  err.Error(),
+			})
+			continue
+		}
+		if len(stale) > 0 {
+			cb.logger.Info("live_pruned", "Removed live copies of resources no longer backed up", map[string]interface{}{
+				"bucket":  target.Bucket,
+				"removed": len(stale),
+			})
+		}
+	}
+}
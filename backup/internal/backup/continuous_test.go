@@ -0,0 +1,77 @@
+package backup
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newContinuousTestBackup(t *testing.T) (*continuousBackup, cache.Indexer, *fakeBucket) {
+	cb, bucket := newFanOutTestBackup(t, "")
+	cb.metrics.Failures = prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_failures_total"}, []string{"category"})
+	cb.metrics.LiveObjects = prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_live_objects_total"}, []string{"operation"})
+	cb.metrics.LiveSnapshot = prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_live_snapshot_timestamp"})
+
+	c := cb.newContinuousBackup()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	c.listers[configMaps] = cache.NewGenericLister(indexer, configMaps.GroupResource())
+	return c, indexer, bucket
+}
+
+func TestContinuousBackup_Sync(t *testing.T) {
+	ctx := context.Background()
+	c, indexer, bucket := newContinuousTestBackup(t)
+	key := windowKey{gvr: configMaps, namespace: "shop", name: "settings"}
+	liveKey := "/backups/example.com/prod/_live/shop/configmaps/settings.yaml"
+
+	require.NoError(t, indexer.Add(windowConfigMap("shop", "settings", "1")))
+	require.NoError(t, c.sync(ctx, key))
+	require.NoError(t, c.sync(ctx, key))
+	assert.Contains(t, string(bucket.objects[liveKey]), "value: \"1\"")
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.cb.metrics.LiveObjects.WithLabelValues("stored")), "unchanged content is not stored again")
+
+	require.NoError(t, indexer.Update(windowConfigMap("shop", "settings", "2")))
+	require.NoError(t, c.sync(ctx, key))
+	assert.Contains(t, string(bucket.objects[liveKey]), "value: \"2\"")
+	assert.Equal(t, 2.0, testutil.ToFloat64(c.cb.metrics.LiveObjects.WithLabelValues("stored")))
+
+	require.NoError(t, indexer.Delete(windowConfigMap("shop", "settings", "2")))
+	require.NoError(t, c.sync(ctx, key))
+	assert.NotContains(t, bucket.objects, liveKey)
+	assert.Empty(t, c.copies())
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.cb.metrics.LiveObjects.WithLabelValues("deleted")))
+}
+
+func TestContinuousBackup_Snapshot(t *testing.T) {
+	ctx := context.Background()
+	c, indexer, bucket := newContinuousTestBackup(t)
+	for _, name := range []string{"settings", "features"} {
+		require.NoError(t, indexer.Add(windowConfigMap("shop", name, "1")))
+		require.NoError(t, c.sync(ctx, windowKey{gvr: configMaps, namespace: "shop", name: name}))
+	}
+	stale := "/backups/example.com/prod/_live/shop/configmaps/deleted-while-down.yaml"
+	bucket.objects[stale] = []byte("stale")
+
+	manifest, err := c.snapshot(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, ManifestStatusCompleted, manifest.Status)
+	require.Len(t, manifest.Objects, 2)
+	assert.Equal(t, "features", manifest.Objects[0].Name)
+	snapshotKey := RunPath("example.com", "prod", manifest.BackupID, ObjectPath("example.com", "prod", "shop", "configmaps", "features"))
+	assert.Equal(t, snapshotKey, manifest.Objects[0].Key)
+	assert.Equal(t, bucket.objects["/backups/example.com/prod/_live/shop/configmaps/features.yaml"], bucket.objects["/backups/"+snapshotKey])
+	assert.Equal(t, 2, manifest.Namespaces["shop"].ResourceCount)
+	assert.Contains(t, bucket.objects, "/backups/"+ManifestPath("example.com", "prod", manifest.BackupID))
+	assert.NotContains(t, bucket.objects, stale, "keys that are not live copies are pruned")
+	assert.Len(t, c.copies(), 2)
+
+	// The snapshot is immutable: later changes go to the live copy only
+	require.NoError(t, indexer.Update(windowConfigMap("shop", "features", "2")))
+	require.NoError(t, c.sync(ctx, windowKey{gvr: configMaps, namespace: "shop", name: "features"}))
+	assert.Contains(t, string(bucket.objects["/backups/"+snapshotKey]), "value: \"1\"")
+}
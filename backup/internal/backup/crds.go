@@ -0,0 +1,139 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"cluster-backup/internal/failure"
+)
+
+// crdGVR is the resource CustomResourceDefinitions are listed from
+var crdGVR = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+
+// captureCRDs stores every CustomResourceDefinition under the _crds prefix so
+// a restore can install them before any custom resource. Filters and selectors
+// do not apply: a custom resource is useless without its definition.
+func (cb *ClusterBackup) captureCRDs(ctx context.Context, manifest *Manifest) (int, error) {
+	listOptions := v1.ListOptions{Limit: cb.batchSize(crdGVR)}
+
+	count := 0
+	var failed []string
+	for {
+		var crds *unstructured.UnstructuredList
+		err := cb.apiCircuitBreaker.Execute(func() error {
+			return cb.retryExecutor.ExecuteWithContext(ctx, func() error {
+				listCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+				defer cancel()
+
+				var listErr error
+				crds, listErr = cb.dynamicClient.Resource(crdGVR).List(listCtx, listOptions)
+				return listErr
+			})
+		})
+		if err != nil {
+			return count, fmt.Errorf("failed to list CRDs: %w", err)
+		}
+
+		for i := range crds.Items {
+			crd := &crds.Items[i]
+			if err := cb.storeCRD(ctx, crd, manifest); err != nil {
+				cb.logger.Warning("crd_backup_failed", "Failed to backup CRD", map[string]interface{}{
+					"crd":   crd.GetName(),
+					"error": err.Error(),
+				})
+				failed = append(failed, fmt.Sprintf("%s: %v", crd.GetName(), err))
+				continue
+			}
+			count++
+		}
+
+		if crds.GetContinue() == "" {
+			break
+		}
+		listOptions.Continue = crds.GetContinue()
+	}
+
+	if len(failed) > 0 {
+		return count, fmt.Errorf("failed to backup CRDs: %s", strings.Join(failed, "; "))
+	}
+
+	cb.logger.Info("crd_backup_complete", "Captured CustomResourceDefinitions", map[string]interface{}{
+		"crd_count": count,
+	})
+	return count, nil
+}
+
+// storeCRD uploads one CRD and records it in the manifest
+func (cb *ClusterBackup) storeCRD(ctx context.Context, crd *unstructured.Unstructured, manifest *Manifest) error {
+	cleaned := cb.cleanResource(crd)
+	// The stored versions tell a restore which versions existing objects may
+	// still be persisted at, so keep them even when status is stripped
+	if storedVersions, found, _ := unstructured.NestedStringSlice(crd.Object, "status", "storedVersions"); found {
+		status, _ := cleaned["status"].(map[string]interface{})
+		if status == nil {
+			status = make(map[string]interface{})
+			cleaned["status"] = status
+		}
+		status["storedVersions"] = toInterfaceSlice(storedVersions)
+	}
+
+	data, err := newPayload(cleaned)
+	if err != nil {
+		return fmt.Errorf("failed to serialize: %v", err)
+	}
+
+	entry := NewCRDEntry(crd)
+	entry.Key = CRDPath(cb.config.ClusterDomain, cb.config.ClusterName, crd.GetName())
+	key, versionID, err := cb.uploadRunObject(ctx, cb.defaultTarget(), entry.Key, data)
+	if err != nil {
+		return fmt.Errorf("failed to upload: %w", failure.Storage(err))
+	}
+	entry.Key = key
+	entry.Size = data.Size
+	entry.Checksum = data.Checksum
+	entry.VersionID = versionID
+
+	manifest.AddCRD(entry)
+	return nil
+}
+
+// NewCRDEntry describes a CustomResourceDefinition for the manifest. The
+// bucket key, size and checksum are left for the caller to fill in.
+func NewCRDEntry(crd *unstructured.Unstructured) CRDEntry {
+	entry := CRDEntry{Name: crd.GetName()}
+	entry.Group, _, _ = unstructured.NestedString(crd.Object, "spec", "group")
+	entry.Kind, _, _ = unstructured.NestedString(crd.Object, "spec", "names", "kind")
+	entry.Plural, _, _ = unstructured.NestedString(crd.Object, "spec", "names", "plural")
+	entry.Scope, _, _ = unstructured.NestedString(crd.Object, "spec", "scope")
+	entry.StoredVersions, _, _ = unstructured.NestedStringSlice(crd.Object, "status", "storedVersions")
+
+	versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	for _, version := range versions {
+		versionMap, ok := version.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := versionMap["name"].(string)
+		if served, _ := versionMap["served"].(bool); served {
+			entry.Versions = append(entry.Versions, name)
+		}
+		if storage, _ := versionMap["storage"].(bool); storage {
+			entry.StorageVersion = name
+		}
+	}
+	return entry
+}
+
+func toInterfaceSlice(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, value := range values {
+		result[i] = value
+	}
+	return result
+}
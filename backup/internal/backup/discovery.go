@@ -0,0 +1,167 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// discoveryCacheFile is where the discovery cache is kept in its directory
+const discoveryCacheFile = "discovery.json"
+
+// discoveryCache holds the preferred API resources of the server between
+// runs for as long as its version stays the same and the TTL has not
+// expired. With a directory it survives restarts.
+type discoveryCache struct {
+	dir string
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entry   *discoveryCacheEntry
+	fromDir bool
+}
+
+// discoveryCacheEntry is the cached discovery of a server version
+type discoveryCacheEntry struct {
+	ServerVersion string                `json:"server_version"`
+	DiscoveredAt  time.Time             `json:"discovered_at"`
+	Resources     []*v1.APIResourceList `json:"resources"`
+}
+
+// newDiscoveryCache returns a cache keeping discovery results for ttl, in
+// dir when it is set, or nil when ttl is zero
+func newDiscoveryCache(dir string, ttl time.Duration) *discoveryCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &discoveryCache{dir: dir, ttl: ttl}
+}
+
+// get returns the resources discovered from the server version within the
+// TTL, reading them from the directory when they are not in memory yet
+func (c *discoveryCache) get(serverVersion string, now time.Time) ([]*v1.APIResourceList, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entry == nil && c.dir != "" && !c.fromDir {
+		// The directory is read once; later entries are written by this process
+		c.fromDir = true
+		if data, err := os.ReadFile(c.path()); err == nil {
+			var entry discoveryCacheEntry
+			if json.Unmarshal(data, &entry) == nil {
+				c.entry = &entry
+			}
+		}
+	}
+	if c.entry == nil || c.entry.ServerVersion != serverVersion || now.Sub(c.entry.DiscoveredAt) > c.ttl {
+		return nil, false
+	}
+	return c.entry.Resources, true
+}
+
+// put caches the resources discovered from the server version
+func (c *discoveryCache) put(serverVersion string, resources []*v1.APIResourceList, now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entry = &discoveryCacheEntry{ServerVersion: serverVersion, DiscoveredAt: now, Resources: resources}
+	if c.dir == "" {
+		return nil
+	}
+	data, err := json.Marshal(c.entry)
+	if err != nil {
+		return err
+	}
+	// Written aside and renamed so a crash never leaves a truncated cache
+	file, err := os.CreateTemp(c.dir, discoveryCacheFile+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write discovery cache: %v", err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write discovery cache: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write discovery cache: %v", err)
+	}
+	if err := os.Rename(file.Name(), c.path()); err != nil {
+		return fmt.Errorf("failed to write discovery cache: %v", err)
+	}
+	return nil
+}
+
+// invalidate drops the cached resources, in memory and in the directory
+func (c *discoveryCache) invalidate() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entry = nil
+	c.fromDir = true
+	if c.dir == "" {
+		return nil
+	}
+	if err := os.Remove(c.path()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove discovery cache: %v", err)
+	}
+	return nil
+}
+
+func (c *discoveryCache) path() string {
+	return filepath.Join(c.dir, discoveryCacheFile)
+}
+
+// preferredResources returns the server's preferred API resources from the
+// discovery cache while it is valid for the server's version, discovering
+// and caching them otherwise. Partial results are not cached, so the types
+// of an aggregated API that was down are discovered again by the next run.
+func (cb *ClusterBackup) preferredResources() ([]*v1.APIResourceList, error) {
+	if cb.discoveryCache == nil {
+		return cb.discoveryClient.ServerPreferredResources()
+	}
+
+	info, err := cb.discoveryClient.ServerVersion()
+	if err != nil {
+		return cb.discoveryClient.ServerPreferredResources()
+	}
+	if resources, ok := cb.discoveryCache.get(info.GitVersion, time.Now()); ok {
+		return resources, nil
+	}
+
+	resources, err := cb.discoveryClient.ServerPreferredResources()
+	if err != nil {
+		return resources, err
+	}
+	if err := cb.discoveryCache.put(info.GitVersion, resources, time.Now()); err != nil {
+		cb.logger.Warning("discovery_cache_write_failed", "Failed to persist the discovery cache", map[string]interface{}{
+			"dir":   cb.discoveryCache.dir,
+			"error": err.Error(),
+		})
+	}
+	cb.logger.Info("discovery_cache_refreshed", "Discovered API resources and cached them", map[string]interface{}{
+		"server_version": info.GitVersion,
+		"ttl":            cb.discoveryCache.ttl.String(),
+	})
+	return resources, nil
+}
+
+// InvalidateDiscoveryCache makes the next run discover the API resources
+// again, e.g. after CRDs were installed. It does nothing when the cache is
+// disabled.
+func (cb *ClusterBackup) InvalidateDiscoveryCache() error {
+	if cb.discoveryCache == nil {
+		return nil
+	}
+	if err := cb.discoveryCache.invalidate(); err != nil {
+		return err
+	}
+	cb.logger.Info("discovery_cache_invalidated", "Invalidated the discovery cache", map[string]interface{}{
+		"dir": cb.discoveryCache.dir,
+	})
+	return nil
+}
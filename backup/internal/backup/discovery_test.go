@@ -0,0 +1,105 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/logging"
+)
+
+// countingDiscovery serves fixed preferred resource lists and counts how
+// often they were discovered
+type countingDiscovery struct {
+	*fakediscovery.FakeDiscovery
+	lists       []*v1.APIResourceList
+	discoveries int
+}
+
+func (d *countingDiscovery) ServerPreferredResources() ([]*v1.APIResourceList, error) {
+	d.discoveries++
+	return d.lists, nil
+}
+
+func newDiscoveryTestBackup(dir string) (*ClusterBackup, *countingDiscovery) {
+	discoveryClient := &countingDiscovery{
+		FakeDiscovery: fake.NewSimpleClientset().Discovery().(*fakediscovery.FakeDiscovery),
+		lists: []*v1.APIResourceList{
+			{GroupVersion: "v1", APIResources: []v1.APIResource{
+				{Name: "configmaps", Namespaced: true, Verbs: []string{"list"}},
+				{Name: "persistentvolumes", Verbs: []string{"list"}},
+			}},
+		},
+	}
+	discoveryClient.FakedServerVersion = &version.Info{GitVersion: "v1.30.2"}
+	return &ClusterBackup{
+		backupConfig:    &config.BackupConfig{FilteringMode: "whitelist", ClusterResources: []string{"persistentvolumes"}},
+		logger:          logging.NewStructuredLogger("discovery-test", "prod"),
+		discoveryClient: discoveryClient,
+		discoveryCache:  newDiscoveryCache(dir, time.Hour),
+	}, discoveryClient
+}
+
+func TestDiscoveryCache_ReusedUntilInvalidated(t *testing.T) {
+	cb, discoveryClient := newDiscoveryTestBackup("")
+
+	resources, err := cb.getAPIResources()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"configmaps"}, resourceNames(resources))
+	clusterResources, err := cb.getClusterAPIResources()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"persistentvolumes"}, resourceNames(clusterResources))
+	assert.Equal(t, 1, discoveryClient.discoveries, "both phases use the cached discovery")
+
+	require.NoError(t, cb.InvalidateDiscoveryCache())
+	_, err = cb.getAPIResources()
+	require.NoError(t, err)
+	assert.Equal(t, 2, discoveryClient.discoveries)
+
+	discoveryClient.FakedServerVersion = &version.Info{GitVersion: "v1.31.0"}
+	_, err = cb.getAPIResources()
+	require.NoError(t, err)
+	assert.Equal(t, 3, discoveryClient.discoveries, "an upgraded server is discovered again")
+}
+
+func TestDiscoveryCache_SurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	cb, discoveryClient := newDiscoveryTestBackup(dir)
+	_, err := cb.getAPIResources()
+	require.NoError(t, err)
+	assert.Equal(t, 1, discoveryClient.discoveries)
+	assert.FileExists(t, filepath.Join(dir, discoveryCacheFile))
+
+	restarted, discoveryClient := newDiscoveryTestBackup(dir)
+	resources, err := restarted.getAPIResources()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"configmaps"}, resourceNames(resources))
+	assert.Equal(t, 0, discoveryClient.discoveries, "the restarted process reads the cache from disk")
+
+	require.NoError(t, restarted.InvalidateDiscoveryCache())
+	assert.NoFileExists(t, filepath.Join(dir, discoveryCacheFile))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestDiscoveryCache_Expires(t *testing.T) {
+	cache := newDiscoveryCache("", time.Hour)
+	now := time.Now()
+	require.NoError(t, cache.put("v1.30.2", []*v1.APIResourceList{{GroupVersion: "v1"}}, now))
+
+	_, ok := cache.get("v1.30.2", now.Add(30*time.Minute))
+	assert.True(t, ok)
+	_, ok = cache.get("v1.30.2", now.Add(2*time.Hour))
+	assert.False(t, ok)
+	assert.Nil(t, newDiscoveryCache("", 0), "a zero TTL disables the cache")
+}
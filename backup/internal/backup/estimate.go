@@ -0,0 +1,173 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// estimateSampleSize is how many items of each resource type and namespace an
+// estimate fetches and serializes to learn their average size
+const estimateSampleSize = 50
+
+// SizeEstimate is the expected size of a backup run, before running it
+type SizeEstimate struct {
+	Namespaces   []ScopeEstimate `json:"namespaces"`
+	ClusterScope *ScopeEstimate  `json:"cluster_scope,omitempty"`
+	Items        int             `json:"items"`
+	Bytes        int64           `json:"bytes"`
+}
+
+// ScopeEstimate is the expected number of stored objects and their serialized
+// size for one namespace, or for the cluster scope. Resource types whose items
+// could not be listed are in Failed and missing from the figures.
+type ScopeEstimate struct {
+	Namespace string   `json:"namespace,omitempty"`
+	Items     int      `json:"items"`
+	Bytes     int64    `json:"bytes"`
+	Failed    []string `json:"failed,omitempty"`
+}
+
+// resourceSample is what a limit-only list of one resource type in one
+// namespace tells about it
+type resourceSample struct {
+	// Items the resource type holds
+	Items int
+	// Items fetched, those of them a backup would store, and the serialized
+	// size of the latter
+	Sampled int
+	Kept    int
+	Bytes   int64
+}
+
+// estimate scales the sample up to all of the resource type's items
+func (s resourceSample) estimate() (int, int64) {
+	if s.Sampled == 0 {
+		return 0, 0
+	}
+	items := int(math.Round(float64(s.Items) * float64(s.Kept) / float64(s.Sampled)))
+	if s.Kept == 0 {
+		return items, 0
+	}
+	return items, int64(math.Round(float64(s.Bytes) / float64(s.Kept) * float64(items)))
+}
+
+// Estimate discovers what a run would back up and estimates the number of
+// objects and their serialized size per namespace, without reading every
+// item: each resource type is listed once with a limit, the count of the rest
+// taken from the list, and the fetched items cleaned and serialized to learn
+// their average size. CRDs, database dumps and etcd snapshots are not included.
+func (cb *ClusterBackup) Estimate(ctx context.Context) (*SizeEstimate, error) {
+	namespaces, err := cb.getNamespacesToBackup(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("namespace discovery failed: %v", err)
+	}
+	apiResources, err := cb.getAPIResources()
+	if err != nil {
+		return nil, fmt.Errorf("API resource discovery failed: %v", err)
+	}
+
+	estimate := &SizeEstimate{Namespaces: make([]ScopeEstimate, 0, len(namespaces))}
+	for _, namespace := range namespaces {
+		scope := cb.estimateScope(ctx, namespace, apiResources)
+		estimate.Namespaces = append(estimate.Namespaces, scope)
+		estimate.Items += scope.Items
+		estimate.Bytes += scope.Bytes
+	}
+
+	if cb.backupConfig.BackupClusterResources {
+		clusterResources, err := cb.getClusterAPIResources()
+		if err != nil {
+			return nil, fmt.Errorf("API resource discovery failed: %v", err)
+		}
+		scope := cb.estimateScope(ctx, "", clusterResources)
+		estimate.ClusterScope = &scope
+		estimate.Items += scope.Items
+		estimate.Bytes += scope.Bytes
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return estimate, nil
+}
+
+// estimateScope samples every resource type of one namespace, or of the
+// cluster scope when namespace is empty
+func (cb *ClusterBackup) estimateScope(ctx context.Context, namespace string, apiResources []v1.APIResource) ScopeEstimate {
+	scope := ScopeEstimate{Namespace: namespace}
+	for _, resource := range apiResources {
+		sample, err := cb.sampleResource(ctx, namespace, resource, estimateSampleSize, true)
+		if err != nil {
+			scope.Failed = append(scope.Failed, fmt.Sprintf("%s: %v", resource.Name, err))
+			continue
+		}
+		items, bytes := sample.estimate()
+		scope.Items += items
+		scope.Bytes += bytes
+	}
+	return scope
+}
+
+// sampleResource lists up to limit items of a resource type and reads how
+// many remain from the list. The API server leaves that count out when a
+// label selector is set, and the remaining pages are then listed only to
+// count them. With measure set, the fetched items are cleaned and serialized
+// as a backup would store them.
+func (cb *ClusterBackup) sampleResource(ctx context.Context, namespace string, resource v1.APIResource, limit int64, measure bool) (resourceSample, error) {
+	gvr := schema.GroupVersionResource{
+		Group:    resource.Group,
+		Version:  resource.Version,
+		Resource: resource.Name,
+	}
+	listOptions := v1.ListOptions{
+		LabelSelector: cb.filters().LabelSelector(),
+		Limit:         limit,
+	}
+
+	list, err := cb.dynamicClient.Resource(gvr).Namespace(namespace).List(ctx, listOptions)
+	if err != nil {
+		return resourceSample{}, fmt.Errorf("failed to list %s: %w", gvr.Resource, err)
+	}
+
+	sample := resourceSample{Items: len(list.Items), Sampled: len(list.Items)}
+	if measure {
+		for i := range list.Items {
+			item := &list.Items[i]
+			if cb.skipReason(item) != "" {
+				continue
+			}
+			if dropped, err := cb.applyPlugins(gvr, item); err != nil || dropped != "" {
+				continue
+			}
+			data, err := newPayload(cb.cleanResource(item))
+			if err != nil {
+				continue
+			}
+			sample.Kept++
+			sample.Bytes += data.Size
+		}
+	} else {
+		sample.Kept = sample.Sampled
+	}
+
+	if remaining := list.GetRemainingItemCount(); remaining != nil {
+		sample.Items += int(*remaining)
+		return sample, nil
+	}
+
+	// Count the rest a page at a time
+	listOptions.Limit = cb.batchSize(gvr)
+	for list.GetContinue() != "" {
+		listOptions.Continue = list.GetContinue()
+		list, err = cb.dynamicClient.Resource(gvr).Namespace(namespace).List(ctx, listOptions)
+		if err != nil {
+			return resourceSample{}, fmt.Errorf("failed to list %s: %w", gvr.Resource, err)
+		}
+		sample.Items += len(list.Items)
+	}
+	return sample, nil
+}
@@ -0,0 +1,94 @@
+package backup
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"cluster-backup/internal/config"
+)
+
+func newEstimateTestBackup(pages ...*unstructured.UnstructuredList) *ClusterBackup {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		gvr: "ConfigMapList",
+	})
+	calls := 0
+	client.PrependReactor("list", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		page := pages[calls]
+		calls++
+		return true, page, nil
+	})
+
+	return &ClusterBackup{
+		config:        &config.Config{BatchSize: 100},
+		backupConfig:  &config.BackupConfig{},
+		dynamicClient: client,
+	}
+}
+
+func configMap(name string, controlled bool) unstructured.Unstructured {
+	item := unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "default"},
+		"data":       map[string]interface{}{"key": "value"},
+	}}
+	if controlled {
+		isController := true
+		item.SetOwnerReferences([]v1.OwnerReference{{Name: "owner", Controller: &isController}})
+	}
+	return item
+}
+
+func TestSampleResource_ScalesSampleByRemainingCount(t *testing.T) {
+	remaining := int64(98)
+	page := &unstructured.UnstructuredList{Items: []unstructured.Unstructured{configMap("a", false), configMap("b", true)}}
+	page.SetRemainingItemCount(&remaining)
+	page.SetContinue("next")
+	cb := newEstimateTestBackup(page)
+
+	sample, err := cb.sampleResource(context.Background(), "default", v1.APIResource{Version: "v1", Name: "configmaps"}, 2, true)
+	require.NoError(t, err)
+	assert.Equal(t, 100, sample.Items)
+	assert.Equal(t, 2, sample.Sampled)
+	assert.Equal(t, 1, sample.Kept, "controller-owned items are not stored")
+
+	data, err := newPayload(cb.cleanResource(&page.Items[0]))
+	require.NoError(t, err)
+	items, bytes := sample.estimate()
+	assert.Equal(t, 50, items)
+	assert.Equal(t, 50*data.Size, bytes)
+}
+
+func TestSampleResource_CountsPagesWithoutRemainingCount(t *testing.T) {
+	first := &unstructured.UnstructuredList{Items: []unstructured.Unstructured{configMap("a", false)}}
+	first.SetContinue("page-2")
+	second := &unstructured.UnstructuredList{Items: []unstructured.Unstructured{configMap("b", false), configMap("c", false)}}
+	second.SetContinue("page-3")
+	third := &unstructured.UnstructuredList{Items: []unstructured.Unstructured{configMap("d", false)}}
+	cb := newEstimateTestBackup(first, second, third)
+
+	sample, err := cb.sampleResource(context.Background(), "default", v1.APIResource{Version: "v1", Name: "configmaps"}, 1, false)
+	require.NoError(t, err)
+	assert.Equal(t, 4, sample.Items)
+	assert.Equal(t, 1, sample.Sampled)
+}
+
+func TestResourceSample_EstimateWithoutSample(t *testing.T) {
+	items, bytes := resourceSample{}.estimate()
+	assert.Zero(t, items)
+	assert.Zero(t, bytes)
+
+	items, bytes = resourceSample{Items: 10, Sampled: 2}.estimate()
+	assert.Zero(t, items, "nothing sampled would be stored")
+	assert.Zero(t, bytes)
+}
@@ -0,0 +1,69 @@
+package backup
+
+import (
+	"context"
+	"time"
+
+	"shared-config/eventbus"
+)
+
+// eventPublishTimeout bounds each lifecycle event, so an unreachable broker
+// delays a run by seconds at most
+const eventPublishTimeout = 5 * time.Second
+
+// eventPublisher is the part of the event bus a run publishes to
+type eventPublisher interface {
+	Publish(ctx context.Context, event eventbus.Event) error
+}
+
+// SetEventBus publishes the lifecycle events of each run: backup.started once
+// the run holds its lock, backup.namespace_completed after every namespace,
+// and backup.completed or backup.failed at the end
+func (cb *ClusterBackup) SetEventBus(bus *eventbus.Bus) {
+	cb.eventBus = bus
+}
+
+// publishEvent sends a lifecycle event. Publishing is best effort: a failure
+// is logged and never fails the run.
+func (cb *ClusterBackup) publishEvent(event eventbus.Event) {
+	if cb.eventBus == nil {
+		return
+	}
+	event.Bucket = cb.config.MinIOBucket
+
+	// The run's context may already be cancelled by a shutdown, whose failed
+	// event still has to go out
+	ctx, cancel := context.WithTimeout(context.Background(), eventPublishTimeout)
+	defer cancel()
+	if err := cb.eventBus.Publish(ctx, event); err != nil {
+		cb.logger.Warning("event_publish_failed", "Failed to publish backup lifecycle event", map[string]interface{}{
+			"backup_id": event.BackupID,
+			"event":     event.Type,
+			"error":     err.Error(),
+		})
+	}
+}
+
+// publishResult sends backup.completed for a run that finished without
+// errors and backup.failed for any other
+func (cb *ClusterBackup) publishResult(result *BackupResult) {
+	eventType := eventbus.BackupCompleted
+	if result.Interrupted || len(result.Errors) > 0 {
+		eventType = eventbus.BackupFailed
+	}
+	event := eventbus.NewEvent(eventType, result.BackupID, cb.config.ClusterName)
+	event.ResourceCount = result.ResourcesBackedUp
+	event.NamespaceCount = result.NamespacesBackedUp
+	for _, err := range result.Errors {
+		event.Errors = append(event.Errors, err.Error())
+	}
+	cb.publishEvent(event)
+}
+
+// publishFailure sends backup.failed for a run that stopped before it could
+// record a result
+func (cb *ClusterBackup) publishFailure(backupID string, err error) {
+	event := eventbus.NewEvent(eventbus.BackupFailed, backupID, cb.config.ClusterName)
+	event.Errors = []string{err.Error()}
+	cb.publishEvent(event)
+}
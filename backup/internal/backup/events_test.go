@@ -0,0 +1,59 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"shared-config/eventbus"
+
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/logging"
+)
+
+type recordingPublisher struct {
+	events []eventbus.Event
+	err    error
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event eventbus.Event) error {
+	p.events = append(p.events, event)
+	return p.err
+}
+
+func TestPublishLifecycleEvents(t *testing.T) {
+	publisher := &recordingPublisher{}
+	cb := &ClusterBackup{
+		config:   &config.Config{ClusterName: "prod", MinIOBucket: "cluster-backups"},
+		logger:   logging.NewStructuredLogger("test", "prod"),
+		eventBus: publisher,
+	}
+
+	cb.publishEvent(eventbus.NewNamespaceEvent("backup-1", "prod", "payments", 4, errors.New("forbidden")))
+	cb.publishResult(&BackupResult{BackupID: "backup-1", NamespacesBackedUp: 2, ResourcesBackedUp: 16})
+	cb.publishResult(&BackupResult{BackupID: "backup-2", Errors: []error{errors.New("failed to backup namespace payments")}})
+	cb.publishResult(&BackupResult{BackupID: "backup-3", Interrupted: true})
+
+	require.Len(t, publisher.events, 4)
+	assert.Equal(t, "payments", publisher.events[0].Namespace)
+	assert.Equal(t, []string{"forbidden"}, publisher.events[0].Errors)
+	assert.Equal(t, "cluster-backups", publisher.events[0].Bucket)
+
+	completed := publisher.events[1]
+	assert.Equal(t, eventbus.BackupCompleted, completed.Type)
+	assert.Equal(t, "backup-1/completed", completed.ID)
+	assert.Equal(t, 16, completed.ResourceCount)
+	assert.Equal(t, 2, completed.NamespaceCount)
+
+	assert.Equal(t, eventbus.BackupFailed, publisher.events[2].Type)
+	assert.Equal(t, []string{"failed to backup namespace payments"}, publisher.events[2].Errors)
+	assert.Equal(t, eventbus.BackupFailed, publisher.events[3].Type, "an interrupted run is not complete")
+
+	// A broker failure is only logged
+	publisher.err = errors.New("connection refused")
+	assert.NotPanics(t, func() { cb.publishFailure("backup-4", errors.New("namespace discovery failed")) })
+	assert.Equal(t, eventbus.BackupFailed, publisher.events[4].Type)
+}
@@ -0,0 +1,264 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"shared-config/signing"
+)
+
+// ExportResult is the outcome of exporting a run
+type ExportResult struct {
+	BackupID string `json:"backup_id"`
+	// Files is the number of objects written, the manifest and its
+	// signature included
+	Files int   `json:"files"`
+	Bytes int64 `json:"bytes"`
+	// Skipped is the number of objects in tenants' buckets, which only the
+	// tenants' credentials can read and exports leave out
+	Skipped int `json:"skipped"`
+}
+
+// IsExportArchive reports whether dest names a gzipped tarball rather than a
+// directory to export to
+func IsExportArchive(dest string) bool {
+	return strings.HasSuffix(dest, ".tar.gz") || strings.HasSuffix(dest, ".tgz")
+}
+
+// Export writes the manifest of run backupID, its signature and every object
+// of the manifest's bucket it refers to, at their bucket keys, to dest: a
+// directory, or a gzipped tarball when dest ends in .tar.gz or .tgz. Restores
+// read the export in place of the bucket where the bucket cannot be reached.
+// Each object is checked against its recorded SHA-256 as it is copied, and a
+// tarball is only left at dest once complete.
+func (ms *ManifestStore) Export(ctx context.Context, backupID, dest string) (*ExportResult, error) {
+	key := ManifestPath(ms.clusterDomain, ms.clusterName, backupID)
+	document, err := ms.GetObject(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %v", key, err)
+	}
+	manifest := &Manifest{}
+	if err := json.Unmarshal(document, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %v", key, err)
+	}
+
+	writer, err := newExportWriter(dest)
+	if err != nil {
+		return nil, err
+	}
+	result, err := ms.export(ctx, writer, manifest, key, document)
+	if err != nil {
+		writer.abort()
+		return nil, err
+	}
+	if err := writer.close(); err != nil {
+		writer.abort()
+		return nil, fmt.Errorf("failed to write export %s: %v", dest, err)
+	}
+	return result, nil
+}
+
+func (ms *ManifestStore) export(ctx context.Context, writer exportWriter, manifest *Manifest, key string, document []byte) (*ExportResult, error) {
+	objects, skipped := exportObjects(manifest)
+	result := &ExportResult{BackupID: manifest.BackupID, Skipped: skipped}
+
+	if err := writer.write(key, int64(len(document)), bytes.NewReader(document)); err != nil {
+		return nil, err
+	}
+	result.Files++
+	result.Bytes += int64(len(document))
+
+	signature, err := ms.GetObject(ctx, key+signing.SignatureSuffix)
+	switch {
+	case err == nil:
+		if err := writer.write(key+signing.SignatureSuffix, int64(len(signature)), bytes.NewReader(signature)); err != nil {
+			return nil, err
+		}
+		result.Files++
+		result.Bytes += int64(len(signature))
+	case minio.ToErrorResponse(err).Code != "NoSuchKey":
+		return nil, fmt.Errorf("failed to read manifest signature: %v", err)
+	}
+
+	for _, object := range objects {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		size, err := ms.exportObject(ctx, writer, object)
+		if err != nil {
+			return nil, err
+		}
+		result.Files++
+		result.Bytes += size
+	}
+	return result, nil
+}
+
+// exportObjects lists the objects of the manifest's bucket an export holds:
+// those Verify reads back, and the OpenShift settings, reports and search
+// index of the run
+func exportObjects(manifest *Manifest) ([]storedObject, int) {
+	objects, skipped := manifest.storedObjects()
+	listed := make(map[string]bool, len(objects))
+	for _, object := range objects {
+		listed[object.key] = true
+	}
+	for _, key := range manifest.StoredKeys()[""] {
+		if !listed[key] {
+			listed[key] = true
+			objects = append(objects, storedObject{key: key})
+		}
+	}
+	return objects, skipped
+}
+
+// exportObject copies one object to writer, checking its content against the
+// checksums recorded for it
+func (ms *ManifestStore) exportObject(ctx context.Context, writer exportWriter, object storedObject) (int64, error) {
+	reader, err := ms.minioClient.GetObject(ctx, ms.bucket, object.key, minio.GetObjectOptions{VersionID: object.versionID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %v", object.key, err)
+	}
+	defer reader.Close()
+	info, err := reader.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %v", object.key, err)
+	}
+
+	hash := sha256.New()
+	if err := writer.write(object.key, info.Size, io.TeeReader(reader, hash)); err != nil {
+		return 0, err
+	}
+	actual := hex.EncodeToString(hash.Sum(nil))
+	if err := verifyChecksum(object.key, actual, object.checksum, info.UserMetadata[ChecksumMetadata]); err != nil {
+		return 0, err
+	}
+	return info.Size, nil
+}
+
+// exportWriter stores the objects of an export
+type exportWriter interface {
+	// write stores size bytes read from content at key
+	write(key string, size int64, content io.Reader) error
+	close() error
+	// abort removes what a failed export wrote
+	abort()
+}
+
+// newExportWriter returns the writer of an export to dest
+func newExportWriter(dest string) (exportWriter, error) {
+	if !IsExportArchive(dest) {
+		if err := os.MkdirAll(dest, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create export directory: %v", err)
+		}
+		return &dirExport{dir: dest}, nil
+	}
+
+	partial := dest + ".partial"
+	file, err := os.OpenFile(partial, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export: %v", err)
+	}
+	gz := gzip.NewWriter(file)
+	return &tarExport{dest: dest, file: file, gz: gz, tar: tar.NewWriter(gz)}, nil
+}
+
+// dirExport writes each object to a file at its key below dir
+type dirExport struct {
+	dir string
+	// written are the files of the export, removed on abort
+	written []string
+}
+
+func (d *dirExport) write(key string, size int64, content io.Reader) error {
+	if !filepath.IsLocal(filepath.FromSlash(key)) {
+		return fmt.Errorf("refusing to export %s outside the export directory", key)
+	}
+	path := filepath.Join(d.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	d.written = append(d.written, path)
+	written, err := io.Copy(file, content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && written != size {
+		err = fmt.Errorf("read %d bytes, expected %d", written, size)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return nil
+}
+
+func (d *dirExport) close() error {
+	return nil
+}
+
+func (d *dirExport) abort() {
+	for _, path := range d.written {
+		os.Remove(path)
+	}
+}
+
+// tarExport writes the objects to a gzipped tarball, built next to dest and
+// renamed into place when complete
+type tarExport struct {
+	dest string
+	file *os.File
+	gz   *gzip.Writer
+	tar  *tar.Writer
+}
+
+func (t *tarExport) write(key string, size int64, content io.Reader) error {
+	header := &tar.Header{
+		Name:     key,
+		Mode:     0o644,
+		Size:     size,
+		ModTime:  time.Now(),
+		Typeflag: tar.TypeReg,
+	}
+	if err := t.tar.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s: %v", key, err)
+	}
+	if _, err := io.Copy(t.tar, content); err != nil {
+		return fmt.Errorf("failed to write %s: %v", key, err)
+	}
+	return nil
+}
+
+func (t *tarExport) close() error {
+	if err := t.tar.Close(); err != nil {
+		return err
+	}
+	if err := t.gz.Close(); err != nil {
+		return err
+	}
+	if err := t.file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(t.file.Name(), t.dest)
+}
+
+func (t *tarExport) abort() {
+	t.file.Close()
+	os.Remove(t.file.Name())
+}
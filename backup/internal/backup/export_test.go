@@ -0,0 +1,93 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportObjects(t *testing.T) {
+	manifest := NewManifest("backup-1", "prod", "example.com", "backups", time.Now())
+	manifest.AddObject(ObjectEntry{Namespace: "shop", ResourceType: "configmaps", Name: "app",
+		Key: "example.com/prod/shop/configmaps/app.yaml", Checksum: "abc", VersionID: "v2"})
+	manifest.AddObject(ObjectEntry{Namespace: "billing", ResourceType: "secrets", Name: "token",
+		Bucket: "tenant-billing", Key: "example.com/prod/billing/secrets/token.yaml"})
+	manifest.SetIndex("example.com/prod/_reports/backup-1/index.json.gz")
+
+	objects, skipped := exportObjects(manifest)
+	assert.Equal(t, 1, skipped, "objects in tenants' buckets are left out")
+	assert.Equal(t, []storedObject{
+		{key: "example.com/prod/shop/configmaps/app.yaml", versionID: "v2", checksum: "abc"},
+		{key: "example.com/prod/_reports/backup-1/index.json.gz"},
+	}, objects)
+}
+
+func TestExportWriters(t *testing.T) {
+	files := map[string]string{
+		"example.com/prod/_manifests/backup-1.json": `{"backup_id":"backup-1"}`,
+		"example.com/prod/shop/configmaps/app.yaml": "kind: ConfigMap\n",
+	}
+	write := func(writer exportWriter) {
+		for key, content := range files {
+			require.NoError(t, writer.write(key, int64(len(content)), strings.NewReader(content)))
+		}
+		require.NoError(t, writer.close())
+	}
+
+	dir := filepath.Join(t.TempDir(), "backup-1")
+	writer, err := newExportWriter(dir)
+	require.NoError(t, err)
+	write(writer)
+	for key, content := range files {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(key)))
+		require.NoError(t, err)
+		assert.Equal(t, content, string(data))
+	}
+
+	archive := filepath.Join(t.TempDir(), "backup-1.tar.gz")
+	writer, err = newExportWriter(archive)
+	require.NoError(t, err)
+	write(writer)
+	assert.NoFileExists(t, archive+".partial")
+
+	file, err := os.Open(archive)
+	require.NoError(t, err)
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	require.NoError(t, err)
+	reader := tar.NewReader(gz)
+	read := map[string]string{}
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		read[header.Name] = string(data)
+	}
+	assert.Equal(t, files, read)
+}
+
+func TestExportWriters_Abort(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "backup-1.tar.gz")
+	writer, err := newExportWriter(archive)
+	require.NoError(t, err)
+	require.NoError(t, writer.write("a.yaml", 1, strings.NewReader("a")))
+	writer.abort()
+	assert.NoFileExists(t, archive)
+	assert.NoFileExists(t, archive+".partial", "a failed export leaves nothing behind")
+
+	writer, err = newExportWriter(t.TempDir())
+	require.NoError(t, err)
+	assert.ErrorContains(t, writer.write("../outside.yaml", 1, strings.NewReader("a")), "outside the export directory")
+}
@@ -0,0 +1,140 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/minio/minio-go/v7"
+
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/resilience"
+	"cluster-backup/internal/tenancy"
+)
+
+// secondaryTarget is a bucket every object of MINIO_BUCKET is also uploaded
+// to, behind a circuit breaker of its own so an unreachable target does not
+// slow down every upload
+type secondaryTarget struct {
+	name    string
+	client  *minio.Client
+	bucket  string
+	breaker *resilience.CircuitBreaker
+}
+
+// objectMirror uploads the copies of an object just written to MINIO_BUCKET
+type objectMirror func(ctx context.Context, key string, data []byte, opts minio.PutObjectOptions) error
+
+// AddSecondaryTarget uploads every object of MINIO_BUCKET, run manifests
+// included, to bucket as well. Tenants' objects stay in their own buckets.
+func (cb *ClusterBackup) AddSecondaryTarget(name string, client *minio.Client, bucket string) {
+	breakerName := "secondary-" + name
+	breaker := resilience.NewCircuitBreakerWithConfig(resilience.CircuitBreakerConfig{
+		Name:           breakerName,
+		MaxFailures:    cb.config.MinIOCircuitBreakerThreshold,
+		ResetTimeout:   cb.config.MinIOCircuitBreakerTimeout,
+		HalfOpenProbes: cb.config.MinIOCircuitBreakerProbes,
+		Probe: func() error {
+			_, err := client.BucketExists(cb.ctx, bucket)
+			return err
+		},
+	})
+	cb.secondaries = append(cb.secondaries, &secondaryTarget{name: name, client: client, bucket: bucket, breaker: breaker})
+	cb.exportCircuitState(breakerName, breaker)
+	cb.manifestStore.mirror = cb.mirrorObject
+}
+
+// fanOut starts uploading an object to every secondary target, alongside the
+// upload to target, and returns a wait for the outcome under
+// SECONDARY_FAILURE_POLICY. upload may be called again on retries.
+func (cb *ClusterBackup) fanOut(ctx context.Context, target *tenancy.Target, key string, upload func(client *minio.Client, bucket string) error) func() error {
+	if len(cb.secondaries) == 0 || target.Tenant != "" {
+		return func() error { return nil }
+	}
+
+	errs := make([]error, len(cb.secondaries))
+	var wg sync.WaitGroup
+	for i, secondary := range cb.secondaries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = secondary.breaker.Execute(func() error {
+				return cb.retryExecutor.ExecuteWithContext(ctx, func() error {
+					return upload(secondary.client, secondary.bucket)
+				})
+			})
+		}()
+	}
+	return func() error {
+		wg.Wait()
+		return cb.secondaryResult(key, errs)
+	}
+}
+
+// secondaryResult logs and counts the failed uploads of an object to the
+// secondary targets, and returns the first unless they only warn
+func (cb *ClusterBackup) secondaryResult(key string, errs []error) error {
+	var failure error
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		secondary := cb.secondaries[i]
+		cb.metrics.SecondaryFailures.WithLabelValues(secondary.name).Inc()
+		// An open breaker was logged once when it opened
+		if !resilience.IsCircuitBreakerError(err) {
+			cb.logger.Warning("secondary_upload_failed", "Failed to upload an object to a secondary storage target", map[string]interface{}{
+				"target":     secondary.name,
+				"bucket":     secondary.bucket,
+				"object_key": key,
+				"error":      err.Error(),
+			})
+		}
+		if failure == nil {
+			failure = fmt.Errorf("failed to upload %s to secondary target %s: %v", key, secondary.name, err)
+		}
+	}
+	if cb.config.SecondaryFailurePolicy == config.SecondaryFailureFail {
+		return failure
+	}
+	return nil
+}
+
+// mirrorObject uploads an object held in memory to the secondary targets
+func (cb *ClusterBackup) mirrorObject(ctx context.Context, key string, data []byte, opts minio.PutObjectOptions) error {
+	return cb.fanOut(ctx, cb.defaultTarget(), key, func(client *minio.Client, bucket string) error {
+		_, err := client.PutObject(ctx, bucket, key, bytes.NewReader(data), int64(len(data)), opts)
+		return err
+	})()
+}
+
+// copyToSecondaries streams an object already uploaded to target into the
+// secondary targets, for uploads that cannot be replayed
+func (cb *ClusterBackup) copyToSecondaries(ctx context.Context, target *tenancy.Target, key string, opts minio.PutObjectOptions) error {
+	return cb.fanOut(ctx, target, key, func(client *minio.Client, bucket string) error {
+		object, err := target.Client.GetObject(ctx, target.Bucket, key, minio.GetObjectOptions{})
+		if err != nil {
+			return err
+		}
+		defer object.Close()
+		info, err := object.Stat()
+		if err != nil {
+			return err
+		}
+		_, err = client.PutObject(ctx, bucket, key, object, info.Size, opts)
+		return err
+	})()
+}
+
+// put writes an object of the manifest store, then its copies on the
+// secondary targets
+func (ms *ManifestStore) put(ctx context.Context, key string, data []byte, opts minio.PutObjectOptions) error {
+	if _, err := ms.minioClient.PutObject(ctx, ms.bucket, key, bytes.NewReader(data), int64(len(data)), opts); err != nil {
+		return err
+	}
+	if ms.mirror != nil {
+		return ms.mirror(ctx, key, data, opts)
+	}
+	return nil
+}
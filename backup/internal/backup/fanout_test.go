@@ -0,0 +1,124 @@
+package backup
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/logging"
+	"cluster-backup/internal/metrics"
+	"cluster-backup/internal/resilience"
+	"cluster-backup/internal/tenancy"
+)
+
+func newFanOutTestBackup(t *testing.T, policy string) (*ClusterBackup, *fakeBucket) {
+	client, primary := newLockTestClient(t)
+	cfg := &config.Config{
+		MinIOBucket:                  "backups",
+		ClusterDomain:                "example.com",
+		ClusterName:                  "prod",
+		MinIOCircuitBreakerThreshold: 3,
+		MinIOCircuitBreakerTimeout:   time.Minute,
+		MinIOCircuitBreakerProbes:    1,
+		SecondaryFailurePolicy:       policy,
+	}
+	cb := &ClusterBackup{
+		config:       cfg,
+		backupConfig: &config.BackupConfig{},
+		minioClient:  client,
+		logger:       logging.NewStructuredLogger("fanout-test", "prod"),
+		metrics: &metrics.BackupMetrics{
+			CircuitState:      prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_circuit_state"}, []string{"breaker"}),
+			SecondaryFailures: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_secondary_upload_failures_total"}, []string{"target"}),
+		},
+		ctx:                 context.Background(),
+		manifestStore:       NewManifestStore(client, "backups", "example.com", "prod"),
+		retryExecutor:       resilience.NewRetryExecutor(resilience.RetryConfig{MaxAttempts: 1}),
+		minioCircuitBreaker: resilience.NewCircuitBreaker(3, time.Minute),
+		apiCircuitBreaker:   resilience.NewCircuitBreaker(3, time.Minute),
+	}
+	return cb, primary
+}
+
+// newDeniedClient returns a client of an endpoint refusing every request
+func newDeniedClient(t *testing.T) *minio.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("<Error><Code>AccessDenied</Code><Message>AccessDenied</Message></Error>"))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := minio.New(strings.TrimPrefix(server.URL, "http://"), &minio.Options{
+		Creds:  credentials.NewStaticV4("key", "secret", ""),
+		Region: "us-east-1",
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func fanOutPayload(t *testing.T) *payload {
+	data, err := newPayload(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "app", "namespace": "shop"},
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func TestFanOut_UploadsToSecondaries(t *testing.T) {
+	ctx := context.Background()
+	cb, primary := newFanOutTestBackup(t, config.SecondaryFailureWarn)
+	secondaryClient, secondary := newLockTestClient(t)
+	cb.AddSecondaryTarget("cloud", secondaryClient, "backups-dr")
+
+	data := fanOutPayload(t)
+	_, err := cb.uploadObject(ctx, cb.defaultTarget(), "example.com/prod/shop/configmaps/app.yaml", data)
+	require.NoError(t, err)
+	content, err := data.Bytes()
+	require.NoError(t, err)
+	assert.Equal(t, content, primary.objects["/backups/example.com/prod/shop/configmaps/app.yaml"])
+	assert.Equal(t, content, secondary.objects["/backups-dr/example.com/prod/shop/configmaps/app.yaml"])
+
+	// Tenants' objects stay out of the shared secondary buckets
+	tenant := &tenancy.Target{Tenant: "payments", Client: cb.minioClient, Bucket: "backups", Prefix: "tenants/payments"}
+	_, err = cb.uploadObject(ctx, tenant, "tenants/payments/example.com/prod/payments/secrets/db.yaml", data)
+	require.NoError(t, err)
+	assert.NotContains(t, secondary.objects, "/backups-dr/tenants/payments/example.com/prod/payments/secrets/db.yaml")
+
+	manifest := NewManifest("backup-1", "prod", "example.com", "backups", time.Now())
+	require.NoError(t, cb.manifestStore.Save(ctx, manifest))
+	assert.Contains(t, secondary.objects, "/backups-dr/"+ManifestPath("example.com", "prod", "backup-1"))
+
+	assert.Contains(t, cb.CircuitBreakerStats(), "secondary-cloud")
+}
+
+func TestFanOut_FailurePolicy(t *testing.T) {
+	ctx := context.Background()
+	key := "example.com/prod/shop/configmaps/app.yaml"
+
+	cb, primary := newFanOutTestBackup(t, config.SecondaryFailureWarn)
+	cb.AddSecondaryTarget("local", newDeniedClient(t), "backups")
+	_, err := cb.uploadObject(ctx, cb.defaultTarget(), key, fanOutPayload(t))
+	require.NoError(t, err, "a failed secondary upload only warns by default")
+	assert.Contains(t, primary.objects, "/backups/"+key)
+	assert.Equal(t, 1.0, testutil.ToFloat64(cb.metrics.SecondaryFailures.WithLabelValues("local")))
+
+	cb, _ = newFanOutTestBackup(t, config.SecondaryFailureFail)
+	cb.AddSecondaryTarget("local", newDeniedClient(t), "backups")
+	_, err = cb.uploadObject(ctx, cb.defaultTarget(), key, fanOutPayload(t))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "secondary target local")
+}
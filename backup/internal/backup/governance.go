@@ -0,0 +1,44 @@
+package backup
+
+import (
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// governanceResources are the resource types workloads are admitted and
+// scheduled against. PriorityClasses are cluster-scoped, the others belong to
+// a namespace.
+var governanceResources = map[string]bool{
+	"resourcequotas":  true,
+	"limitranges":     true,
+	"networkpolicies": true,
+	"priorityclasses": true,
+}
+
+// includeGovernance reports whether the governance-first phase backs up a
+// resource type the filters would leave out. Exclude lists still apply.
+func (cb *ClusterBackup) includeGovernance(resourceName string) bool {
+	return cb.backupConfig.GovernanceFirst && governanceResources[resourceName] &&
+		!cb.filters().ExcludeResource(resourceName)
+}
+
+// orderGovernanceFirst moves governance types ahead of the other resource
+// types when the governance-first phase is on, so a run cut short still
+// stored them. The discovery order is kept otherwise.
+func (cb *ClusterBackup) orderGovernanceFirst(resources []v1.APIResource) []v1.APIResource {
+	if !cb.backupConfig.GovernanceFirst {
+		return resources
+	}
+
+	ordered := make([]v1.APIResource, 0, len(resources))
+	for _, resource := range resources {
+		if governanceResources[resource.Name] {
+			ordered = append(ordered, resource)
+		}
+	}
+	for _, resource := range resources {
+		if !governanceResources[resource.Name] {
+			ordered = append(ordered, resource)
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,95 @@
+package backup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/logging"
+)
+
+// preferredDiscovery serves fixed preferred resource lists
+type preferredDiscovery struct {
+	discovery.DiscoveryInterface
+	lists []*v1.APIResourceList
+}
+
+func (d *preferredDiscovery) ServerPreferredResources() ([]*v1.APIResourceList, error) {
+	return d.lists, nil
+}
+
+func newGovernanceTestBackup(backupConfig *config.BackupConfig) *ClusterBackup {
+	listable := []string{"list"}
+	return &ClusterBackup{
+		backupConfig: backupConfig,
+		logger:       logging.NewStructuredLogger("governance-test", "prod"),
+		discoveryClient: &preferredDiscovery{
+			DiscoveryInterface: fake.NewSimpleClientset().Discovery(),
+			lists: []*v1.APIResourceList{
+				{GroupVersion: "v1", APIResources: []v1.APIResource{
+					{Name: "configmaps", Namespaced: true, Verbs: listable},
+					{Name: "limitranges", Namespaced: true, Verbs: listable},
+					{Name: "resourcequotas", Namespaced: true, Verbs: listable},
+					{Name: "persistentvolumes", Verbs: listable},
+				}},
+				{GroupVersion: "apps/v1", APIResources: []v1.APIResource{
+					{Name: "deployments", Namespaced: true, Verbs: listable},
+				}},
+				{GroupVersion: "networking.k8s.io/v1", APIResources: []v1.APIResource{
+					{Name: "networkpolicies", Namespaced: true, Verbs: listable},
+				}},
+				{GroupVersion: "scheduling.k8s.io/v1", APIResources: []v1.APIResource{
+					{Name: "priorityclasses", Verbs: listable},
+				}},
+			},
+		},
+	}
+}
+
+func resourceNames(resources []v1.APIResource) []string {
+	names := make([]string, 0, len(resources))
+	for _, resource := range resources {
+		names = append(names, resource.Name)
+	}
+	return names
+}
+
+func TestGovernanceFirst_BackupOrder(t *testing.T) {
+	cb := newGovernanceTestBackup(&config.BackupConfig{
+		FilteringMode:    "whitelist",
+		IncludeResources: []string{"deployments", "configmaps"},
+		ExcludeResources: []string{"limitranges"},
+		ClusterResources: []string{"persistentvolumes"},
+		GovernanceFirst:  true,
+	})
+
+	resources, err := cb.getAPIResources()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"resourcequotas", "networkpolicies", "configmaps", "deployments"}, resourceNames(resources),
+		"governance types come first even when the include list omits them, unless excluded")
+
+	clusterResources, err := cb.getClusterAPIResources()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"priorityclasses", "persistentvolumes"}, resourceNames(clusterResources))
+}
+
+func TestGovernanceFirst_Disabled(t *testing.T) {
+	cb := newGovernanceTestBackup(&config.BackupConfig{
+		FilteringMode:    "whitelist",
+		IncludeResources: []string{"deployments", "configmaps", "networkpolicies"},
+		ClusterResources: []string{"persistentvolumes"},
+	})
+
+	resources, err := cb.getAPIResources()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"configmaps", "deployments", "networkpolicies"}, resourceNames(resources))
+
+	clusterResources, err := cb.getClusterAPIResources()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"persistentvolumes"}, resourceNames(clusterResources))
+}
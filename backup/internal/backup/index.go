@@ -0,0 +1,118 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/minio/minio-go/v7"
+
+	"cluster-backup/internal/search"
+)
+
+// IndexFile is the name of a run's search index next to its data
+const IndexFile = "index.json.gz"
+
+type runIndexKey struct{}
+
+// withRunIndex returns a context the resources of a run are indexed in
+// index through
+func withRunIndex(ctx context.Context, index *search.Index) context.Context {
+	return context.WithValue(ctx, runIndexKey{}, index)
+}
+
+// runIndexFrom returns the search index of the run ctx belongs to, nil
+// when none is built
+func runIndexFrom(ctx context.Context) *search.Index {
+	index, _ := ctx.Value(runIndexKey{}).(*search.Index)
+	return index
+}
+
+// indexObject adds a stored resource to the run's search index, with the
+// labels of the resource as listed
+func indexObject(ctx context.Context, entry ObjectEntry, data *payload) {
+	if index := runIndexFrom(ctx); index != nil {
+		index.Add(objectDocument(entry, data))
+	}
+}
+
+// reindexObject indexes a resource stored again during the run in place of
+// its earlier copy
+func reindexObject(ctx context.Context, entry ObjectEntry, data *payload) {
+	if index := runIndexFrom(ctx); index != nil {
+		index.Replace(objectDocument(entry, data))
+	}
+}
+
+// objectDocument returns the index document of a stored resource
+func objectDocument(entry ObjectEntry, data *payload) search.Document {
+	kind, _ := data.object["kind"].(string)
+	var labels map[string]string
+	if metadata, ok := data.object["metadata"].(map[string]interface{}); ok {
+		listed, _ := metadata["labels"].(map[string]interface{})
+		for key, value := range listed {
+			if labels == nil {
+				labels = make(map[string]string, len(listed))
+			}
+			labels[key] = fmt.Sprint(value)
+		}
+	}
+	return search.Document{
+		Namespace: entry.Namespace,
+		Group:     entry.Group,
+		Version:   entry.Version,
+		Resource:  entry.ResourceType,
+		Kind:      kind,
+		Name:      entry.Name,
+		Labels:    labels,
+		Bucket:    entry.Bucket,
+		Key:       entry.Key,
+		VersionID: entry.VersionID,
+	}
+}
+
+// SaveIndex uploads the search index of a run and returns its key
+func (ms *ManifestStore) SaveIndex(ctx context.Context, index *search.Index) (string, error) {
+	data, err := index.Encode()
+	if err != nil {
+		return "", err
+	}
+	key := ReportPath(ms.clusterDomain, ms.clusterName, index.BackupID, IndexFile)
+	err = ms.put(ctx, key, data, minio.PutObjectOptions{
+		ContentType:  "application/gzip",
+		UserMetadata: checksumMetadata(Checksum(data)),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload index %s: %v", key, err)
+	}
+	return key, nil
+}
+
+// LoadIndex fetches the search index of the run of manifest
+func (ms *ManifestStore) LoadIndex(ctx context.Context, manifest *Manifest) (*search.Index, error) {
+	if manifest.Index == "" {
+		return nil, fmt.Errorf("backup %s has no search index", manifest.BackupID)
+	}
+	data, err := ms.GetObject(ctx, manifest.Index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index %s: %v", manifest.Index, err)
+	}
+	return search.Decode(data)
+}
+
+// saveIndex uploads the search index of a finished run and records its key
+// in the manifest. An index that cannot be uploaded is logged and does not
+// affect the run's status.
+func (cb *ClusterBackup) saveIndex(ctx context.Context, manifest *Manifest, index *search.Index) {
+	if index == nil {
+		return
+	}
+	key, err := cb.manifestStore.SaveIndex(ctx, index)
+	if err != nil {
+		cb.logger.Warning("index_upload_failed", "Failed to upload the backup search index", map[string]interface{}{
+			"backup_id": manifest.BackupID,
+			"error":     err.Error(),
+		})
+		return
+	}
+	manifest.SetIndex(key)
+}
@@ -0,0 +1,161 @@
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// ChecksumMetadata is the user metadata key holding the hex-encoded SHA-256 of
+// an object's content, sent as the X-Amz-Meta-Sha256 header. Dumps are
+// streamed, so their checksum is only known afterwards and is kept in the
+// manifest alone.
+const ChecksumMetadata = "Sha256"
+
+// ErrChecksumMismatch is returned when stored content no longer matches the
+// checksum recorded for it at upload
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// checksumMetadata returns the user metadata recording checksum on upload
+func checksumMetadata(checksum string) map[string]string {
+	return map[string]string{ChecksumMetadata: checksum}
+}
+
+// verifyChecksum compares the checksum of content read from key against each
+// recorded one. Empty checksums, from objects uploaded before checksums were
+// recorded, are skipped.
+func verifyChecksum(key, actual string, recorded ...string) error {
+	for _, expected := range recorded {
+		if expected != "" && expected != actual {
+			return fmt.Errorf("%w: %s has sha256 %s, expected %s", ErrChecksumMismatch, key, actual, expected)
+		}
+	}
+	return nil
+}
+
+// VerifyResult reports the objects of a backup whose content could not be
+// read or no longer matches its checksum
+type VerifyResult struct {
+	BackupID string
+	// Checked is the number of objects read back
+	Checked int
+	// Unverified is the number of objects with no recorded checksum
+	Unverified int
+	// Skipped is the number of objects in tenants' buckets, which only the
+	// tenants' credentials can read
+	Skipped  int
+	Failures []VerifyFailure
+}
+
+// VerifyFailure is an object that failed verification
+type VerifyFailure struct {
+	Key string
+	Err error
+}
+
+// OK reports whether every object of the backup verified
+func (r *VerifyResult) OK() bool {
+	return len(r.Failures) == 0
+}
+
+// storedObject is an object a manifest refers to
+type storedObject struct {
+	key       string
+	versionID string
+	checksum  string
+}
+
+// storedObjects lists the objects of the manifest's bucket it refers to, once
+// each, as resources deduplicated into the same blob share a key, and counts
+// the objects stored in tenants' buckets
+func (m *Manifest) storedObjects() ([]storedObject, int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var objects []storedObject
+	tenantObjects := 0
+	seen := make(map[storedObject]bool)
+	add := func(object storedObject) {
+		if object.key == "" || seen[object] {
+			return
+		}
+		seen[object] = true
+		objects = append(objects, object)
+	}
+	for _, entry := range m.CRDs {
+		add(storedObject{key: entry.Key, versionID: entry.VersionID, checksum: entry.Checksum})
+	}
+	for _, entries := range [][]ObjectEntry{m.Objects, m.AlternateVersions} {
+		for _, entry := range entries {
+			if entry.Bucket != "" {
+				tenantObjects++
+				continue
+			}
+			add(storedObject{key: entry.Key, versionID: entry.VersionID, checksum: entry.Checksum})
+		}
+	}
+	for _, entry := range m.Dumps {
+		if entry.Bucket != "" {
+			tenantObjects++
+			continue
+		}
+		add(storedObject{key: entry.Key, checksum: entry.Checksum})
+	}
+	if m.EtcdSnapshot != nil {
+		add(storedObject{key: m.EtcdSnapshot.Key, checksum: m.EtcdSnapshot.Checksum})
+	}
+	return objects, tenantObjects
+}
+
+// Verify reads back every object of a backup and checks its SHA-256 against the
+// manifest and the object's checksum metadata. Objects are streamed, so large
+// dumps and snapshots are never held in memory.
+func (ms *ManifestStore) Verify(ctx context.Context, manifest *Manifest) *VerifyResult {
+	objects, skipped := manifest.storedObjects()
+	result := &VerifyResult{BackupID: manifest.BackupID, Skipped: skipped}
+	for _, object := range objects {
+		if err := ctx.Err(); err != nil {
+			result.Failures = append(result.Failures, VerifyFailure{Key: object.key, Err: err})
+			break
+		}
+
+		recorded, err := ms.verifyObject(ctx, object)
+		result.Checked++
+		if err != nil {
+			result.Failures = append(result.Failures, VerifyFailure{Key: object.key, Err: err})
+		} else if !recorded {
+			result.Unverified++
+		}
+	}
+	return result
+}
+
+// verifyObject streams one object through SHA-256 and reports whether any
+// checksum was recorded for it
+func (ms *ManifestStore) verifyObject(ctx context.Context, object storedObject) (bool, error) {
+	reader, err := ms.minioClient.GetObject(ctx, ms.bucket, object.key, minio.GetObjectOptions{VersionID: object.versionID})
+	if err != nil {
+		return false, err
+	}
+	defer reader.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", object.key, err)
+	}
+	info, err := reader.Stat()
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", object.key, err)
+	}
+
+	metadata := info.UserMetadata[ChecksumMetadata]
+	if err := verifyChecksum(object.key, hex.EncodeToString(hash.Sum(nil)), object.checksum, metadata); err != nil {
+		return true, err
+	}
+	return object.checksum != "" || metadata != "", nil
+}
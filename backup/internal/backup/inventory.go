@@ -0,0 +1,71 @@
+package backup
+
+import (
+	"fmt"
+
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/inventory"
+)
+
+// InventoryCheck is the outcome of checking a run against the expected
+// inventory under Policy
+type InventoryCheck struct {
+	Policy     string                `json:"policy"`
+	Shortfalls []inventory.Shortfall `json:"shortfalls,omitempty"`
+}
+
+// Failed reports whether the check fails the run
+func (c *InventoryCheck) Failed() bool {
+	return c.Policy == config.InventoryPolicyFail && len(c.Shortfalls) > 0
+}
+
+// SetInventory sets the expected inventory finished runs are checked against;
+// nil disables the check
+func (cb *ClusterBackup) SetInventory(expected *inventory.Config) {
+	cb.inventory = expected
+}
+
+// inventoryCounts counts the resources stored in the manifest by namespace
+// and type. Namespaces that were backed up are counted even when empty.
+func inventoryCounts(manifest *Manifest) inventory.Counts {
+	manifest.mutex.Lock()
+	defer manifest.mutex.Unlock()
+
+	counts := inventory.Counts{}
+	for namespace := range manifest.Namespaces {
+		counts[namespace] = map[string]int{}
+	}
+	for _, object := range manifest.Objects {
+		counts.Add(object.Namespace, object.ResourceType, object.Group)
+	}
+	return counts
+}
+
+// checkInventory checks a run that was not interrupted against the expected
+// inventory and records the outcome in the manifest. Under
+// EXPECTED_INVENTORY_POLICY=fail, a shortfall also fails the run.
+func (cb *ClusterBackup) checkInventory(manifest *Manifest, result *BackupResult) {
+	if cb.inventory == nil {
+		return
+	}
+	check := InventoryCheck{
+		Policy:     cb.config.InventoryPolicy,
+		Shortfalls: cb.inventory.Check(inventoryCounts(manifest)),
+	}
+	manifest.SetInventory(check)
+	cb.metrics.InventoryShortfall.Set(float64(len(check.Shortfalls)))
+	if len(check.Shortfalls) == 0 {
+		return
+	}
+
+	summary := inventory.Summary(check.Shortfalls)
+	cb.logger.Warning("inventory_shortfall", "Backup fell short of the expected inventory", map[string]interface{}{
+		"backup_id":  manifest.BackupID,
+		"policy":     check.Policy,
+		"shortfalls": summary,
+	})
+	if check.Failed() {
+		result.Errors = append(result.Errors, fmt.Errorf("backup fell short of the expected inventory: %s", summary))
+		cb.metrics.BackupErrors.Inc()
+	}
+}
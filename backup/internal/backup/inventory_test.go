@@ -0,0 +1,86 @@
+package backup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/inventory"
+	"cluster-backup/internal/logging"
+	"cluster-backup/internal/metrics"
+)
+
+func newInventoryTestRun(t *testing.T, policy string) (*ClusterBackup, *Manifest) {
+	expected, err := inventory.Parse([]byte(`
+namespaces:
+  - name: shop
+    resources:
+      deployments.apps: 1
+      secrets: 2
+  - name: billing
+`))
+	require.NoError(t, err)
+	cb := &ClusterBackup{
+		config: &config.Config{InventoryPolicy: policy},
+		logger: logging.NewStructuredLogger("inventory-test", "prod"),
+		metrics: &metrics.BackupMetrics{
+			BackupErrors:       prometheus.NewCounter(prometheus.CounterOpts{Name: "test_backup_errors_total"}),
+			InventoryShortfall: prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_inventory_shortfalls"}),
+		},
+	}
+	cb.SetInventory(expected)
+
+	manifest := NewManifest("20260301-020000", "prod", "example.com", "backups", time.Now())
+	manifest.AddObject(ObjectEntry{Namespace: "shop", Group: "apps", ResourceType: "deployments", Name: "web", Key: "k1"})
+	manifest.AddObject(ObjectEntry{Namespace: "shop", ResourceType: "secrets", Name: "tls", Key: "k2"})
+	manifest.SetNamespace("shop", 2, nil)
+	return cb, manifest
+}
+
+func TestCheckInventory_Warn(t *testing.T) {
+	cb, manifest := newInventoryTestRun(t, config.InventoryPolicyWarn)
+	result := &BackupResult{}
+
+	cb.checkInventory(manifest, result)
+	manifest.Finish(time.Now())
+
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, ManifestStatusCompleted, manifest.Status)
+	require.NotNil(t, manifest.Inventory)
+	assert.Equal(t, []inventory.Shortfall{
+		{Namespace: "shop", Resource: "secrets", Expected: 2, Actual: 1},
+		{Namespace: "billing"},
+	}, manifest.Inventory.Shortfalls)
+	assert.Equal(t, 2.0, testutil.ToFloat64(cb.metrics.InventoryShortfall))
+}
+
+func TestCheckInventory_Fail(t *testing.T) {
+	cb, manifest := newInventoryTestRun(t, config.InventoryPolicyFail)
+	result := &BackupResult{}
+
+	cb.checkInventory(manifest, result)
+	manifest.Finish(time.Now())
+
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "backup fell short of the expected inventory: shop: 1 secrets, expected at least 2; namespace billing was not backed up",
+		result.Errors[0].Error())
+	assert.Equal(t, ManifestStatusFailed, manifest.Status)
+	assert.Equal(t, 1.0, testutil.ToFloat64(cb.metrics.BackupErrors))
+
+	// A run meeting the inventory passes under either policy
+	manifest.AddObject(ObjectEntry{Namespace: "shop", ResourceType: "secrets", Name: "db", Key: "k3"})
+	manifest.SetNamespace("billing", 0, nil)
+	result = &BackupResult{}
+	cb.checkInventory(manifest, result)
+	manifest.Finish(time.Now())
+
+	assert.Empty(t, result.Errors)
+	assert.Empty(t, manifest.Inventory.Shortfalls)
+	assert.Equal(t, ManifestStatusCompleted, manifest.Status)
+	assert.Equal(t, 0.0, testutil.ToFloat64(cb.metrics.InventoryShortfall))
+}
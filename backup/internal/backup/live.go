@@ -0,0 +1,120 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// LiveSnapshotID is the manifest ID used for snapshots of the live cluster
+const LiveSnapshotID = "live"
+
+// LiveSnapshot is the normalized state of the live cluster, shaped like a
+// backup manifest so it can be compared against stored runs
+type LiveSnapshot struct {
+	Manifest *Manifest
+	data     map[string][]byte
+}
+
+// NewLiveSnapshot creates an empty snapshot of the given cluster
+func NewLiveSnapshot(clusterName, clusterDomain, bucket string) *LiveSnapshot {
+	return &LiveSnapshot{
+		Manifest: NewManifest(LiveSnapshotID, clusterName, clusterDomain, bucket, time.Now()),
+		data:     make(map[string][]byte),
+	}
+}
+
+// Add records a serialized live resource, filling in its checksum
+func (ls *LiveSnapshot) Add(entry ObjectEntry, data []byte) {
+	entry.Size = int64(len(data))
+	entry.Checksum = Checksum(data)
+	ls.data[entry.Key] = data
+	ls.Manifest.AddObject(entry)
+}
+
+// GetObjectVersion returns the serialized live resource stored under key.
+// The version ID is ignored since a snapshot holds a single version.
+func (ls *LiveSnapshot) GetObjectVersion(ctx context.Context, key, versionID string) ([]byte, error) {
+	data, ok := ls.data[key]
+	if !ok {
+		return nil, fmt.Errorf("object %s is not part of the live snapshot", key)
+	}
+	return data, nil
+}
+
+// SnapshotLive lists the resources a backup would include, applying the same
+// filters and cleanResource normalization, without uploading anything
+func (cb *ClusterBackup) SnapshotLive() (*LiveSnapshot, error) {
+	snapshot := NewLiveSnapshot(cb.config.ClusterName, cb.config.ClusterDomain, cb.config.MinIOBucket)
+
+	namespaces, err := cb.getNamespacesToBackup(cb.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("namespace discovery failed: %v", err)
+	}
+
+	apiResources, err := cb.getAPIResources()
+	if err != nil {
+		return nil, fmt.Errorf("API resource discovery failed: %v", err)
+	}
+
+	if cb.backupConfig.BackupClusterResources {
+		clusterResources, err := cb.getClusterAPIResources()
+		if err != nil {
+			return nil, fmt.Errorf("cluster API resource discovery failed: %v", err)
+		}
+		resourceCount, err := cb.snapshotResources(snapshot, "", clusterResources)
+		snapshot.Manifest.SetClusterScope(resourceCount, err)
+	}
+
+	for _, namespace := range namespaces {
+		resourceCount, err := cb.snapshotResources(snapshot, namespace, apiResources)
+		snapshot.Manifest.SetNamespace(namespace, resourceCount, err)
+	}
+
+	snapshot.Manifest.Finish(time.Now())
+	return snapshot, nil
+}
+
+// snapshotResources adds the live resources of the given types in namespace, or
+// of cluster-scoped types when namespace is empty. It returns the last listing
+// error, if any, after trying every type.
+func (cb *ClusterBackup) snapshotResources(snapshot *LiveSnapshot, namespace string, apiResources []v1.APIResource) (int, error) {
+	resourceCount := 0
+	var lastErr error
+	for _, resource := range apiResources {
+		gvr := schema.GroupVersionResource{
+			Group:    resource.Group,
+			Version:  resource.Version,
+			Resource: resource.Name,
+		}
+
+		count, err := cb.forEachResource(cb.ctx, namespace, gvr, func(name string, data *payload) error {
+			yamlData, err := data.Bytes()
+			if err != nil {
+				return err
+			}
+			snapshot.Add(ObjectEntry{
+				Namespace:    namespace,
+				Group:        gvr.Group,
+				Version:      gvr.Version,
+				ResourceType: gvr.Resource,
+				Name:         name,
+				Key:          ObjectPath(cb.config.ClusterDomain, cb.config.ClusterName, namespace, QualifiedResource(gvr, cb.config.ObjectPathVersion), name),
+			}, yamlData)
+			return nil
+		})
+		if err != nil {
+			cb.logger.Warning("live_snapshot_resource_failed", "Failed to list live resources", map[string]interface{}{
+				"namespace": namespace,
+				"resource":  resource.Name,
+				"error":     err.Error(),
+			})
+			lastErr = err
+		}
+		resourceCount += count
+	}
+	return resourceCount, lastErr
+}
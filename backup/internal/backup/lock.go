@@ -0,0 +1,229 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// lockFile is the per-cluster object holding the lease of the running backup.
+// The underscore keeps it from colliding with a namespace name.
+const lockFile = "_lock.json"
+
+// ErrBackupLocked is returned when another run holds an unexpired lease on the
+// cluster's prefix in the bucket
+var ErrBackupLocked = errors.New("another backup run holds the lock")
+
+// RunLease is the content of the lock object
+type RunLease struct {
+	Holder     string    `json:"holder"`
+	BackupID   string    `json:"backup_id"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	RenewedAt  time.Time `json:"renewed_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// RunLock is a lease on one cluster's prefix in the bucket, so two backup runs
+// scheduled at the same time cannot interleave writes and corrupt the
+// manifest. The lease is created and taken over with conditional writes, which
+// make it safe across processes; a holder that dies lets it expire after the
+// TTL.
+type RunLock struct {
+	minioClient *minio.Client
+	bucket      string
+	key         string
+	holder      string
+	ttl         time.Duration
+
+	mu    sync.Mutex
+	etag  string
+	lease RunLease
+	lost  bool
+}
+
+// NewRunLock creates the lock for one cluster's backups. holder identifies
+// this process in the lease, e.g. the pod name.
+func NewRunLock(minioClient *minio.Client, bucket, clusterDomain, clusterName, holder string, ttl time.Duration) *RunLock {
+	return &RunLock{
+		minioClient: minioClient,
+		bucket:      bucket,
+		key:         fmt.Sprintf("%s/%s/%s", sanitizePath(clusterDomain), sanitizePath(clusterName), lockFile),
+		holder:      holder,
+		ttl:         ttl,
+	}
+}
+
+// Acquire takes the lease for a run. It fails with ErrBackupLocked while
+// another holder's lease is unexpired.
+func (l *RunLock) Acquire(ctx context.Context, backupID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	current, etag, err := l.read(ctx)
+	if err != nil {
+		return err
+	}
+	if current != nil && time.Now().Before(current.ExpiresAt) {
+		return fmt.Errorf("%w: %s is running backup %s, lease expires at %s",
+			ErrBackupLocked, current.Holder, current.BackupID, current.ExpiresAt.Format(time.RFC3339))
+	}
+
+	now := time.Now()
+	lease := RunLease{
+		Holder:     l.holder,
+		BackupID:   backupID,
+		AcquiredAt: now,
+		RenewedAt:  now,
+		ExpiresAt:  now.Add(l.ttl),
+	}
+	opts := minio.PutObjectOptions{ContentType: "application/json"}
+	if current == nil {
+		opts.SetMatchETagExcept("*")
+	} else {
+		// Take over the expired lease only if nobody else did first
+		opts.SetMatchETag(etag)
+	}
+	newETag, err := l.write(ctx, lease, opts)
+	if err != nil {
+		if isPreconditionFailed(err) {
+			return fmt.Errorf("%w: another run acquired it first", ErrBackupLocked)
+		}
+		return err
+	}
+
+	l.etag = newETag
+	l.lease = lease
+	l.lost = false
+	return nil
+}
+
+// Renew extends the lease by the TTL. It fails, and the lease counts as lost,
+// if another holder took it over.
+func (l *RunLock) Renew(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.lost {
+		return fmt.Errorf("%w: lease was lost", ErrBackupLocked)
+	}
+	lease := l.lease
+	lease.RenewedAt = time.Now()
+	lease.ExpiresAt = lease.RenewedAt.Add(l.ttl)
+
+	opts := minio.PutObjectOptions{ContentType: "application/json"}
+	opts.SetMatchETag(l.etag)
+	etag, err := l.write(ctx, lease, opts)
+	if err != nil {
+		if isPreconditionFailed(err) {
+			l.lost = true
+			return fmt.Errorf("%w: lease was taken over", ErrBackupLocked)
+		}
+		// A lease that could not be renewed in time may be taken over
+		if time.Now().After(l.lease.ExpiresAt) {
+			l.lost = true
+		}
+		return err
+	}
+	l.etag = etag
+	l.lease = lease
+	return nil
+}
+
+// Lost reports whether the lease expired or was taken over, after which the
+// run must not write its manifest
+func (l *RunLock) Lost() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lost || time.Now().After(l.lease.ExpiresAt)
+}
+
+// Release removes the lease unless another holder has taken it over
+func (l *RunLock) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, etag, err := l.read(ctx)
+	if err != nil {
+		return err
+	}
+	if etag != l.etag {
+		return nil
+	}
+	if err := l.minioClient.RemoveObject(ctx, l.bucket, l.key, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to release backup lock %s: %v", l.key, err)
+	}
+	l.etag = ""
+	return nil
+}
+
+// keepAlive renews the lease every third of its TTL until ctx is done
+func (l *RunLock) keepAlive(ctx context.Context, onError func(error)) {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.Renew(ctx); err != nil && ctx.Err() == nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// read returns the current lease and its ETag, or nil when there is none
+func (l *RunLock) read(ctx context.Context) (*RunLease, string, error) {
+	object, err := l.minioClient.GetObject(ctx, l.bucket, l.key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read backup lock %s: %v", l.key, err)
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, "", nil
+		}
+		return nil, "", fmt.Errorf("failed to read backup lock %s: %v", l.key, err)
+	}
+	info, err := object.Stat()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read backup lock %s: %v", l.key, err)
+	}
+
+	lease := &RunLease{}
+	if err := json.Unmarshal(data, lease); err != nil {
+		// An unreadable lease is treated as expired so it cannot block runs
+		return &RunLease{}, info.ETag, nil
+	}
+	return lease, info.ETag, nil
+}
+
+func (l *RunLock) write(ctx context.Context, lease RunLease, opts minio.PutObjectOptions) (string, error) {
+	data, err := json.Marshal(lease)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal backup lock: %v", err)
+	}
+	info, err := l.minioClient.PutObject(ctx, l.bucket, l.key, bytes.NewReader(data), int64(len(data)), opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to write backup lock %s: %w", l.key, err)
+	}
+	return info.ETag, nil
+}
+
+// isPreconditionFailed reports whether a conditional write lost a race
+func isPreconditionFailed(err error) bool {
+	var response minio.ErrorResponse
+	if errors.As(err, &response) {
+		return response.Code == "PreconditionFailed" || response.StatusCode == 412
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package backup
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBucket is an S3 endpoint holding objects in memory, honouring the
+// If-Match and If-None-Match conditions of PUT requests. It also copies
+// objects and lists them by prefix.
+type fakeBucket struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeBucket) etag(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (f *fakeBucket) fail(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+func (f *fakeBucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := r.URL.Path
+	data, exists := f.objects[key]
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if r.URL.Query().Get("list-type") == "2" {
+			f.list(w, strings.TrimSuffix(key, "/")+"/"+r.URL.Query().Get("prefix"))
+			return
+		}
+		if !exists {
+			f.fail(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", `"`+f.etag(data)+`"`)
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case http.MethodPut:
+		if match := r.Header.Get("If-None-Match"); match == "*" && exists {
+			f.fail(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+		if match := r.Header.Get("If-Match"); match != "" && (!exists || strings.Trim(match, `"`) != f.etag(data)) {
+			f.fail(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+		if source := r.Header.Get("X-Amz-Copy-Source"); source != "" {
+			source, _ = url.PathUnescape(source)
+			copied, ok := f.objects["/"+strings.TrimPrefix(source, "/")]
+			if !ok {
+				f.fail(w, http.StatusNotFound, "NoSuchKey")
+				return
+			}
+			f.objects[key] = copied
+			fmt.Fprintf(w, `<CopyObjectResult><ETag>"%s"</ETag><LastModified>%s</LastModified></CopyObjectResult>`,
+				f.etag(copied), time.Now().UTC().Format(time.RFC3339))
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			// The client gave up on the upload
+			f.fail(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+			body = decodeChunked(body)
+		}
+		f.objects[key] = body
+		w.Header().Set("ETag", `"`+f.etag(body)+`"`)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// list answers a ListObjectsV2 request with the objects whose path starts
+// with prefix, in one page
+func (f *fakeBucket) list(w http.ResponseWriter, prefix string) {
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	w.Header().Set("Content-Type", "application/xml")
+	fmt.Fprint(w, "<ListBucketResult><IsTruncated>false</IsTruncated>")
+	for _, key := range keys {
+		_, object, _ := strings.Cut(strings.TrimPrefix(key, "/"), "/")
+		fmt.Fprintf(w, "<Contents><Key>%s</Key><Size>%d</Size></Contents>", object, len(f.objects[key]))
+	}
+	fmt.Fprint(w, "</ListBucketResult>")
+}
+
+// decodeChunked strips the aws-chunked framing minio-go uses to stream
+// signed uploads over plain HTTP
+func decodeChunked(body []byte) []byte {
+	var data []byte
+	for len(body) > 0 {
+		header, rest, found := strings.Cut(string(body), "\r\n")
+		if !found {
+			break
+		}
+		size, _ := strconv.ParseInt(strings.SplitN(header, ";", 2)[0], 16, 64)
+		if size == 0 {
+			break
+		}
+		data = append(data, rest[:size]...)
+		body = []byte(rest[size+2:])
+	}
+	return data
+}
+
+func newLockTestClient(t *testing.T) (*minio.Client, *fakeBucket) {
+	bucket := &fakeBucket{objects: map[string][]byte{}}
+	server := httptest.NewServer(bucket)
+	t.Cleanup(server.Close)
+
+	client, err := minio.New(strings.TrimPrefix(server.URL, "http://"), &minio.Options{
+		Creds:  credentials.NewStaticV4("key", "secret", ""),
+		Region: "us-east-1",
+	})
+	require.NoError(t, err)
+	return client, bucket
+}
+
+func TestRunLock_ExcludesConcurrentRuns(t *testing.T) {
+	client, bucket := newLockTestClient(t)
+	ctx := context.Background()
+
+	first := NewRunLock(client, "backups", "example.com", "prod", "backup-job-a", time.Minute)
+	second := NewRunLock(client, "backups", "example.com", "prod", "backup-job-b", time.Minute)
+	other := NewRunLock(client, "backups", "example.com", "staging", "backup-job-c", time.Minute)
+
+	require.NoError(t, first.Acquire(ctx, "backup-1"))
+	err := second.Acquire(ctx, "backup-2")
+	require.ErrorIs(t, err, ErrBackupLocked)
+	assert.Contains(t, err.Error(), "backup-job-a")
+	assert.NoError(t, other.Acquire(ctx, "backup-3"), "other clusters have their own lock")
+
+	require.NoError(t, first.Renew(ctx))
+	assert.False(t, first.Lost())
+	require.NoError(t, first.Release(ctx))
+	assert.NotContains(t, bucket.objects, "/backups/example.com/prod/_lock.json")
+
+	assert.NoError(t, second.Acquire(ctx, "backup-2"))
+}
+
+func TestRunLock_ExpiredLeaseIsTakenOver(t *testing.T) {
+	client, _ := newLockTestClient(t)
+	ctx := context.Background()
+
+	stale := NewRunLock(client, "backups", "example.com", "prod", "crashed-job", time.Millisecond)
+	require.NoError(t, stale.Acquire(ctx, "backup-1"))
+	time.Sleep(5 * time.Millisecond)
+	assert.True(t, stale.Lost())
+
+	next := NewRunLock(client, "backups", "example.com", "prod", "backup-job", time.Minute)
+	require.NoError(t, next.Acquire(ctx, "backup-2"))
+
+	// The previous holder can neither renew nor remove the new lease
+	assert.ErrorIs(t, stale.Renew(ctx), ErrBackupLocked)
+	require.NoError(t, stale.Release(ctx))
+	require.NoError(t, next.Renew(ctx))
+}
@@ -0,0 +1,1078 @@
+package backup
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"shared-config/signing"
+
+	"cluster-backup/internal/buildinfo"
+)
+
+// manifestDir is the per-cluster prefix holding run manifests. The underscore
+// keeps it from colliding with a namespace name.
+const manifestDir = "_manifests"
+
+// dataDir is the per-cluster prefix holding application data, such as
+// database dumps, captured during a run
+const dataDir = "_data"
+
+// crdDir is the per-cluster prefix holding CustomResourceDefinitions, which
+// are captured on every run regardless of the filters
+const crdDir = "_crds"
+
+// openShiftDir is the per-cluster prefix holding the OpenShift settings a
+// restore needs besides the resources themselves. The underscore keeps it
+// from colliding with the openshift namespace.
+const openShiftDir = "_openshift"
+
+// versionsDir is the per-namespace prefix holding resources in the served
+// versions besides the preferred one
+const versionsDir = "_versions"
+
+// blobDir is the bucket-wide prefix holding content-addressed resource
+// payloads. It sits outside any cluster so identical resources from different
+// clusters are stored once.
+const blobDir = "_blobs"
+
+// BlobPrefix is the key prefix of all content-addressed payloads
+const BlobPrefix = blobDir + "/"
+
+// trashDir is the bucket-wide prefix cleanup moves expired objects to when
+// they are kept for a grace period before deletion
+const trashDir = "_trash"
+
+// TrashPrefix is the key prefix of all trashed objects
+const TrashPrefix = trashDir + "/"
+
+// clusterDir is the per-cluster prefix holding cluster-scoped resources, which
+// have no namespace
+const clusterDir = "_cluster"
+
+// auditDir is the per-cluster prefix holding the audit logs of cleanups
+// confirmed by an operator
+const auditDir = "_audit"
+
+// diagnosticsDir is the per-cluster prefix holding the profiles captured
+// during runs under PROFILE_BACKUP
+const diagnosticsDir = "_diagnostics"
+
+// latestDir is the per-cluster prefix holding, with RUN_PREFIXED_LAYOUT, a
+// copy of each object of the latest run at a key that does not change from
+// run to run
+const latestDir = "latest"
+
+// liveDir is the per-cluster prefix holding, under CONTINUOUS_BACKUP, the
+// copy of each resource stored shortly after its latest change
+const liveDir = "_live"
+
+// backupIDPrefix starts the identifier of every run
+const backupIDPrefix = "backup-"
+
+// Manifest status values
+const (
+	ManifestStatusRunning   = "running"
+	ManifestStatusCompleted = "completed"
+	ManifestStatusPartial   = "partial"
+	ManifestStatusFailed    = "failed"
+)
+
+// Manifest records what a single backup run wrote to the bucket
+type Manifest struct {
+	BackupID      string                     `json:"backup_id"`
+	ClusterName   string                     `json:"cluster_name"`
+	ClusterDomain string                     `json:"cluster_domain"`
+	Bucket        string                     `json:"bucket"`
+	Status        string                     `json:"status"`
+	StartTime     time.Time                  `json:"start_time"`
+	EndTime       time.Time                  `json:"end_time,omitempty"`
+	Namespaces    map[string]*NamespaceEntry `json:"namespaces"`
+	ClusterScope  *NamespaceEntry            `json:"cluster_scope,omitempty"`
+	CRDs          []CRDEntry                 `json:"crds,omitempty"`
+	CRDError      string                     `json:"crd_error,omitempty"`
+	Objects       []ObjectEntry              `json:"objects"`
+	// AlternateVersions are the objects also stored in a served version
+	// other than the preferred one, for restores to clusters without it
+	AlternateVersions []ObjectEntry  `json:"alternate_versions,omitempty"`
+	Dumps             []DumpEntry    `json:"dumps,omitempty"`
+	EtcdSnapshot      *SnapshotEntry `json:"etcd_snapshot,omitempty"`
+	Checkpoint        *Checkpoint    `json:"checkpoint,omitempty"`
+	// Resources breaking the policies checked during the run
+	PolicyViolations []PolicyViolation `json:"policy_violations,omitempty"`
+	// Suspected credentials found outside Secrets during the run
+	SecretFindings []SecretFinding `json:"secret_findings,omitempty"`
+	// OpenShift settings captured under the _openshift prefix
+	OpenShift      []OpenShiftEntry `json:"openshift,omitempty"`
+	OpenShiftError string           `json:"openshift_error,omitempty"`
+	// Tenants maps the namespaces stored in a tenant's bucket to the tenant
+	Tenants map[string]string `json:"tenants,omitempty"`
+	// Reports are the keys of the run's report.json and report.html
+	Reports []string `json:"reports,omitempty"`
+	// Index is the key of the run's search index
+	Index string `json:"index,omitempty"`
+	// Profiles are the keys of the CPU and heap profiles of the run
+	Profiles []string `json:"profiles,omitempty"`
+	// Inventory is the run's check against the expected inventory, set when
+	// one is configured
+	Inventory *InventoryCheck `json:"inventory,omitempty"`
+	// Window records the changes made during the run that it stored, set
+	// under BACKUP_WINDOW_CAPTURE
+	Window *WindowEntry `json:"window,omitempty"`
+	// Config is the run's effective configuration with credentials redacted,
+	// and ConfigVersion a hash of it. ConfigChanges lists the settings that
+	// differ from those of ConfigBaseline, the previous run.
+	Config         map[string]string `json:"config,omitempty"`
+	ConfigVersion  string            `json:"config_version,omitempty"`
+	ConfigBaseline string            `json:"config_baseline,omitempty"`
+	ConfigChanges  []ConfigChange    `json:"config_changes,omitempty"`
+	// Source describes the cluster the run backed up, for checking restore
+	// targets against
+	Source *SourceCluster `json:"source,omitempty"`
+	// Build is the version of the tool that made the run, covered by the
+	// manifest's signature like the rest
+	Build *buildinfo.Info `json:"build,omitempty"`
+
+	mutex sync.Mutex
+}
+
+// Checkpoint records where a run stopped when shutdown interrupted it or it
+// ran out of time. PendingNamespaces includes the namespace that was being
+// backed up.
+type Checkpoint struct {
+	InterruptedAt     time.Time `json:"interrupted_at"`
+	Reason            string    `json:"reason,omitempty"`
+	PendingNamespaces []string  `json:"pending_namespaces,omitempty"`
+}
+
+// NamespaceEntry summarizes the backup of one namespace, or of the cluster scope
+type NamespaceEntry struct {
+	Status        string   `json:"status"`
+	ResourceCount int      `json:"resource_count"`
+	Errors        []string `json:"errors,omitempty"`
+}
+
+// ObjectEntry describes a single resource stored in the bucket. Namespace is
+// empty for cluster-scoped resources.
+type ObjectEntry struct {
+	Namespace    string `json:"namespace"`
+	Group        string `json:"group,omitempty"`
+	Version      string `json:"version,omitempty"`
+	ResourceType string `json:"resource_type"`
+	Name         string `json:"name"`
+	// Bucket is set when the object is in a tenant's bucket rather than the
+	// manifest's
+	Bucket    string `json:"bucket,omitempty"`
+	Key       string `json:"key"`
+	Size      int64  `json:"size"`
+	Checksum  string `json:"checksum,omitempty"`
+	VersionID string `json:"version_id,omitempty"`
+	// ContentChecksum is the checksum of the resource before provenance was
+	// added to the stored copy, set only when some was
+	ContentChecksum string `json:"content_checksum,omitempty"`
+}
+
+// ResourceChecksum returns the checksum of the resource as listed, which
+// diffs and drift checks compare: Checksum covers the stored copy, with the
+// provenance that differs between runs
+func (e ObjectEntry) ResourceChecksum() string {
+	if e.ContentChecksum != "" {
+		return e.ContentChecksum
+	}
+	return e.Checksum
+}
+
+// CRDEntry describes a CustomResourceDefinition stored in the bucket
+type CRDEntry struct {
+	Name           string   `json:"name"`
+	Group          string   `json:"group"`
+	Kind           string   `json:"kind"`
+	Plural         string   `json:"plural"`
+	Scope          string   `json:"scope"`
+	Versions       []string `json:"versions"`
+	StorageVersion string   `json:"storage_version,omitempty"`
+	StoredVersions []string `json:"stored_versions,omitempty"`
+	Key            string   `json:"key"`
+	Size           int64    `json:"size"`
+	Checksum       string   `json:"checksum,omitempty"`
+	VersionID      string   `json:"version_id,omitempty"`
+}
+
+// DumpEntry describes an application data dump stored in the bucket
+type DumpEntry struct {
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Plugin    string `json:"plugin"`
+	Bucket    string `json:"bucket,omitempty"`
+	Key       string `json:"key"`
+	Size      int64  `json:"size"`
+	Checksum  string `json:"checksum,omitempty"`
+}
+
+// SnapshotEntry describes the etcd snapshot taken during a run
+type SnapshotEntry struct {
+	Key      string `json:"key,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+	Checksum string `json:"checksum,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ManifestSummary is a lightweight view of a manifest used for listings
+type ManifestSummary struct {
+	BackupID       string    `json:"backup_id"`
+	Status         string    `json:"status"`
+	StartTime      time.Time `json:"start_time"`
+	EndTime        time.Time `json:"end_time,omitempty"`
+	NamespaceCount int       `json:"namespace_count"`
+	ObjectCount    int       `json:"object_count"`
+	Interrupted    bool      `json:"interrupted,omitempty"`
+	ConfigVersion  string    `json:"config_version,omitempty"`
+}
+
+// NewManifest creates an empty manifest for a new backup run
+func NewManifest(backupID, clusterName, clusterDomain, bucket string, startTime time.Time) *Manifest {
+	build := buildinfo.Get()
+	return &Manifest{
+		BackupID:      backupID,
+		ClusterName:   clusterName,
+		ClusterDomain: clusterDomain,
+		Bucket:        bucket,
+		Status:        ManifestStatusRunning,
+		StartTime:     startTime.UTC(),
+		Namespaces:    make(map[string]*NamespaceEntry),
+		Objects:       []ObjectEntry{},
+		Build:         &build,
+	}
+}
+
+// AddObject records a stored object
+func (m *Manifest) AddObject(entry ObjectEntry) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.Objects = append(m.Objects, entry)
+}
+
+// SetObject records a stored object in place of the one of the same type,
+// namespace and name recorded before, and reports whether there was one
+func (m *Manifest) SetObject(entry ObjectEntry) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for i, object := range m.Objects {
+		if object.Namespace == entry.Namespace && object.Group == entry.Group && object.ResourceType == entry.ResourceType && object.Name == entry.Name {
+			m.Objects[i] = entry
+			return true
+		}
+	}
+	m.Objects = append(m.Objects, entry)
+	return false
+}
+
+// AddAlternateVersion records an object stored in a served version other
+// than the preferred one
+func (m *Manifest) AddAlternateVersion(entry ObjectEntry) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.AlternateVersions = append(m.AlternateVersions, entry)
+}
+
+// SetTenant records that a namespace is stored in a tenant's bucket
+func (m *Manifest) SetTenant(namespace, tenant string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.Tenants == nil {
+		m.Tenants = make(map[string]string)
+	}
+	m.Tenants[namespace] = tenant
+}
+
+// AddDump records a stored application data dump
+func (m *Manifest) AddDump(entry DumpEntry) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.Dumps = append(m.Dumps, entry)
+}
+
+// AddPolicyViolation records a resource breaking a policy
+func (m *Manifest) AddPolicyViolation(violation PolicyViolation) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.PolicyViolations = append(m.PolicyViolations, violation)
+}
+
+// AddSecretFinding records a suspected credential in a resource
+func (m *Manifest) AddSecretFinding(finding SecretFinding) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.SecretFindings = append(m.SecretFindings, finding)
+}
+
+// AddOpenShift records a stored OpenShift setting
+func (m *Manifest) AddOpenShift(entry OpenShiftEntry) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.OpenShift = append(m.OpenShift, entry)
+}
+
+// AddCRD records a stored CustomResourceDefinition
+func (m *Manifest) AddCRD(entry CRDEntry) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.CRDs = append(m.CRDs, entry)
+}
+
+// SetCRDError records why CustomResourceDefinitions could not all be captured
+func (m *Manifest) SetCRDError(err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.CRDError = err.Error()
+}
+
+// SetOpenShiftError records why OpenShift settings could not all be captured
+func (m *Manifest) SetOpenShiftError(err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.OpenShiftError = err.Error()
+}
+
+// SetEtcdSnapshot records the outcome of the etcd snapshot
+func (m *Manifest) SetEtcdSnapshot(entry SnapshotEntry) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.EtcdSnapshot = &entry
+}
+
+// SetConfig records the run's configuration and how it differs from that of
+// the previous run, previousID
+func (m *Manifest) SetConfig(snapshot map[string]string, version, previousID string, changes []ConfigChange) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.Config = snapshot
+	m.ConfigVersion = version
+	m.ConfigBaseline = previousID
+	m.ConfigChanges = changes
+}
+
+// SetSource records the version and feature gates of the cluster backed up
+func (m *Manifest) SetSource(version string, featureGates map[string]bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.Source == nil {
+		m.Source = &SourceCluster{}
+	}
+	m.Source.KubernetesVersion = version
+	m.Source.FeatureGates = featureGates
+}
+
+// AddAPIResources records types the run backs up. Finish keeps those
+// objects were stored of.
+func (m *Manifest) AddAPIResources(entries []APIResourceEntry) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.Source == nil {
+		m.Source = &SourceCluster{}
+	}
+	m.Source.APIResources = append(m.Source.APIResources, entries...)
+}
+
+// SetReport records the keys of the run's report
+func (m *Manifest) SetReport(keys []string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.Reports = keys
+}
+
+// SetIndex records the key of the run's search index
+func (m *Manifest) SetIndex(key string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.Index = key
+}
+
+// SetProfiles records the keys of the run's profiles
+func (m *Manifest) SetProfiles(keys []string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.Profiles = keys
+}
+
+// SetInventory records the run's check against the expected inventory
+func (m *Manifest) SetInventory(check InventoryCheck) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.Inventory = &check
+}
+
+// SetWindow records the changes made during the run that it stored
+func (m *Manifest) SetWindow(entry WindowEntry) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.Window = &entry
+}
+
+// completedNamespaces returns the namespaces backed up in full so far
+func (m *Manifest) completedNamespaces() map[string]bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	completed := make(map[string]bool, len(m.Namespaces))
+	for name, entry := range m.Namespaces {
+		if entry.Status == ManifestStatusCompleted {
+			completed[name] = true
+		}
+	}
+	return completed
+}
+
+// Interrupt records that reason, shutdown or the run's deadline, stopped the
+// run before pending namespaces were backed up
+func (m *Manifest) Interrupt(at time.Time, reason error, pending []string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.Checkpoint = &Checkpoint{InterruptedAt: at.UTC(), Reason: reason.Error(), PendingNamespaces: pending}
+}
+
+// SetNamespace records the outcome of a namespace backup
+func (m *Manifest) SetNamespace(namespace string, resourceCount int, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.Namespaces[namespace] = newNamespaceEntry(resourceCount, err)
+}
+
+// SetClusterScope records the outcome of the cluster-scoped resource backup
+func (m *Manifest) SetClusterScope(resourceCount int, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.ClusterScope = newNamespaceEntry(resourceCount, err)
+}
+
+// Progress returns how many objects the run stored and how many namespaces
+// it finished so far
+func (m *Manifest) Progress() (objects, namespaces int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return len(m.Objects), len(m.Namespaces)
+}
+
+func newNamespaceEntry(resourceCount int, err error) *NamespaceEntry {
+	entry := &NamespaceEntry{Status: ManifestStatusCompleted, ResourceCount: resourceCount}
+	if stopped(err) {
+		entry.Status = ManifestStatusPartial
+		entry.Errors = []string{err.Error()}
+	} else if err != nil {
+		entry.Status = ManifestStatusFailed
+		entry.Errors = []string{err.Error()}
+	}
+	return entry
+}
+
+// Finish marks the manifest complete, partial, or failed based on namespace outcomes
+func (m *Manifest) Finish(endTime time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.EndTime = endTime.UTC()
+	if m.Source != nil {
+		m.Source.APIResources = storedAPIResources(m.Source.APIResources, m.Objects, m.AlternateVersions)
+	}
+	failed := 0
+	for _, ns := range m.Namespaces {
+		if ns.Status == ManifestStatusFailed {
+			failed++
+		}
+	}
+
+	// A requested but failed etcd snapshot leaves the run short of a full backup
+	snapshotFailed := m.EtcdSnapshot != nil && m.EtcdSnapshot.Error != ""
+	clusterScopeFailed := m.ClusterScope != nil && m.ClusterScope.Status == ManifestStatusFailed
+
+	switch {
+	case m.Checkpoint != nil:
+		m.Status = ManifestStatusPartial
+	case m.Inventory != nil && m.Inventory.Failed():
+		m.Status = ManifestStatusFailed
+	case failed == 0 && !snapshotFailed && !clusterScopeFailed && m.CRDError == "" && m.OpenShiftError == "":
+		m.Status = ManifestStatusCompleted
+	case failed == len(m.Namespaces):
+		m.Status = ManifestStatusFailed
+	default:
+		m.Status = ManifestStatusPartial
+	}
+}
+
+// Summary returns the listing view of the manifest
+func (m *Manifest) Summary() ManifestSummary {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return ManifestSummary{
+		BackupID:       m.BackupID,
+		Status:         m.Status,
+		StartTime:      m.StartTime,
+		EndTime:        m.EndTime,
+		NamespaceCount: len(m.Namespaces),
+		ObjectCount:    len(m.Objects),
+		Interrupted:    m.Checkpoint != nil,
+		ConfigVersion:  m.ConfigVersion,
+	}
+}
+
+// FindObject returns the stored objects of a resource. resourceType is the
+// plural resource name, optionally qualified by its API group as in
+// "deployments.apps", and namespace is empty for cluster-scoped resources.
+// More than one object is returned when an unqualified type exists in several
+// API groups.
+func (m *Manifest) FindObject(namespace, resourceType, name string) []ObjectEntry {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var found []ObjectEntry
+	for _, object := range m.Objects {
+		if object.Namespace != namespace || object.Name != name {
+			continue
+		}
+		if object.ResourceType == resourceType || object.ResourceType+"."+object.Group == resourceType {
+			found = append(found, object)
+		}
+	}
+	return found
+}
+
+// StoredKeys returns the keys of everything the run stored, by bucket: its
+// resources, CRDs, OpenShift settings, dumps, etcd snapshot, report, index
+// and profiles. Keys in the manifest's own bucket are listed under "".
+func (m *Manifest) StoredKeys() map[string][]string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	keys := make(map[string][]string)
+	add := func(bucket, key string) {
+		if key != "" {
+			keys[bucket] = append(keys[bucket], key)
+		}
+	}
+	for _, entry := range m.Objects {
+		add(entry.Bucket, entry.Key)
+	}
+	for _, entry := range m.AlternateVersions {
+		add(entry.Bucket, entry.Key)
+	}
+	for _, entry := range m.CRDs {
+		add("", entry.Key)
+	}
+	for _, entry := range m.OpenShift {
+		add("", entry.Key)
+	}
+	for _, entry := range m.Dumps {
+		add(entry.Bucket, entry.Key)
+	}
+	if m.EtcdSnapshot != nil {
+		add("", m.EtcdSnapshot.Key)
+	}
+	for _, key := range m.Reports {
+		add("", key)
+	}
+	if m.Index != "" {
+		add("", m.Index)
+	}
+	for _, key := range m.Profiles {
+		add("", key)
+	}
+	return keys
+}
+
+// ObjectPath builds the bucket key for a resource:
+// {domain}/{cluster-name}/{namespace}/{resource-type}/{resource-name}.yaml
+// Cluster-scoped resources use _cluster in place of the namespace. The
+// resource type is qualified by QualifiedResource, so types of the same name
+// in different API groups do not share keys.
+func ObjectPath(clusterDomain, clusterName, namespace, resourceType, name string) string {
+	if namespace == "" {
+		namespace = clusterDir
+	}
+	return fmt.Sprintf("%s/%s/%s/%s/%s.yaml",
+		sanitizePath(clusterDomain),
+		sanitizePath(clusterName),
+		sanitizePath(namespace),
+		sanitizePath(resourceType),
+		sanitizePath(name),
+	)
+}
+
+// VersionedObjectPath builds the bucket key for a resource in a served
+// version other than the preferred one:
+// {domain}/{cluster-name}/{namespace}/_versions/{version}/{resource-type}/{resource-name}.yaml
+func VersionedObjectPath(clusterDomain, clusterName, namespace, resourceType, version, name string) string {
+	if namespace == "" {
+		namespace = clusterDir
+	}
+	return fmt.Sprintf("%s/%s/%s/%s/%s/%s/%s.yaml",
+		sanitizePath(clusterDomain),
+		sanitizePath(clusterName),
+		sanitizePath(namespace),
+		versionsDir,
+		sanitizePath(version),
+		sanitizePath(resourceType),
+		sanitizePath(name),
+	)
+}
+
+// QualifiedResource returns the resource type segment of a resource's key:
+// the resource and its API group, such as deployments.apps, or the resource
+// alone for the core group. With version the API version follows as a
+// directory of its own, such as deployments.apps/v1.
+func QualifiedResource(gvr schema.GroupVersionResource, version bool) string {
+	qualified := gvr.GroupResource().String()
+	if version && gvr.Version != "" {
+		qualified += "/" + gvr.Version
+	}
+	return qualified
+}
+
+// CRDPath builds the bucket key for a CustomResourceDefinition:
+// {domain}/{cluster-name}/_crds/{crd-name}.yaml
+func CRDPath(clusterDomain, clusterName, name string) string {
+	return fmt.Sprintf("%s/%s/%s/%s.yaml",
+		sanitizePath(clusterDomain),
+		sanitizePath(clusterName),
+		crdDir,
+		sanitizePath(name),
+	)
+}
+
+// OpenShiftPath builds the bucket key for a captured OpenShift setting:
+// {domain}/{cluster-name}/_openshift/{namespace}/{type}/{name}.yaml
+// Cluster-wide settings use _cluster in place of the namespace.
+func OpenShiftPath(clusterDomain, clusterName, namespace, captureType, name string) string {
+	if namespace == "" {
+		namespace = clusterDir
+	}
+	return fmt.Sprintf("%s/%s/%s/%s/%s/%s.yaml",
+		sanitizePath(clusterDomain),
+		sanitizePath(clusterName),
+		openShiftDir,
+		sanitizePath(namespace),
+		sanitizePath(captureType),
+		sanitizePath(name),
+	)
+}
+
+// BlobPath builds the bucket key for a content-addressed payload from its
+// SHA-256 checksum: _blobs/sha256/{first-two-hex-digits}/{checksum}
+func BlobPath(checksum string) string {
+	return fmt.Sprintf("%s/sha256/%s/%s", blobDir, checksum[:2], checksum)
+}
+
+// IsBlobPath reports whether key is a content-addressed payload
+func IsBlobPath(key string) bool {
+	return strings.HasPrefix(key, BlobPrefix)
+}
+
+// TrashPath builds the key an object is moved to by a cleanup in trash mode:
+// _trash/{key}
+func TrashPath(key string) string {
+	return TrashPrefix + key
+}
+
+// IsTrashPath reports whether key is a trashed object
+func IsTrashPath(key string) bool {
+	return strings.HasPrefix(key, TrashPrefix)
+}
+
+// UntrashPath returns the original key of a trashed object
+func UntrashPath(key string) string {
+	return strings.TrimPrefix(key, TrashPrefix)
+}
+
+// IsManifestPath reports whether key is a run manifest of any cluster
+func IsManifestPath(key string) bool {
+	parts := strings.Split(key, "/")
+	return len(parts) == 4 && parts[2] == manifestDir && strings.HasSuffix(parts[3], ".json")
+}
+
+// RunPath moves a key built by ObjectPath, VersionedObjectPath, CRDPath or
+// OpenShiftPath under the prefix of run backupID:
+// {domain}/{cluster-name}/{backup-id}/...
+func RunPath(clusterDomain, clusterName, backupID, key string) string {
+	return clusterSubPath(clusterDomain, clusterName, sanitizePath(backupID), key)
+}
+
+// LatestPath moves a key built by ObjectPath, VersionedObjectPath, CRDPath or
+// OpenShiftPath under the alias prefix of the latest run:
+// {domain}/{cluster-name}/latest/...
+func LatestPath(clusterDomain, clusterName, key string) string {
+	return clusterSubPath(clusterDomain, clusterName, latestDir, key)
+}
+
+// LatestPrefix is the key prefix of the latest run's alias objects
+func LatestPrefix(clusterDomain, clusterName string) string {
+	return fmt.Sprintf("%s/%s/%s/", sanitizePath(clusterDomain), sanitizePath(clusterName), latestDir)
+}
+
+// LivePath moves a key built by ObjectPath under the prefix of the live
+// copies kept by continuous backup: {domain}/{cluster-name}/_live/...
+func LivePath(clusterDomain, clusterName, key string) string {
+	return clusterSubPath(clusterDomain, clusterName, liveDir, key)
+}
+
+// LivePrefix is the key prefix of the live copies
+func LivePrefix(clusterDomain, clusterName string) string {
+	return fmt.Sprintf("%s/%s/%s/", sanitizePath(clusterDomain), sanitizePath(clusterName), liveDir)
+}
+
+// clusterSubPath inserts dir right after the {domain}/{cluster-name}/ prefix
+// of key
+func clusterSubPath(clusterDomain, clusterName, dir, key string) string {
+	prefix := sanitizePath(clusterDomain) + "/" + sanitizePath(clusterName) + "/"
+	return prefix + dir + "/" + strings.TrimPrefix(key, prefix)
+}
+
+// IsBackupID reports whether name is a run identifier made by NewBackupID
+func IsBackupID(name string) bool {
+	if !strings.HasPrefix(name, backupIDPrefix) {
+		return false
+	}
+	stamp := strings.TrimPrefix(name, backupIDPrefix)
+	if len(stamp) < len(backupIDLayout) {
+		return false
+	}
+	if _, err := time.Parse(backupIDLayout, stamp[:len(backupIDLayout)]); err != nil {
+		return false
+	}
+	return len(stamp) == len(backupIDLayout) || stamp[len(backupIDLayout)] == '-'
+}
+
+// PathNamespace returns the namespace whose backup key belongs to: resources,
+// OpenShift settings and dumps of the namespace. It returns "" for keys of
+// the cluster as a whole, such as manifests, CRDs, cluster-scoped resources
+// and etcd snapshots, and for keys of other clusters. Keys under the prefix
+// of a run, the latest/ alias or the live copies belong to the same
+// namespace as without it.
+func PathNamespace(clusterDomain, clusterName, key string) string {
+	prefix := sanitizePath(clusterDomain) + "/" + sanitizePath(clusterName) + "/"
+	if !strings.HasPrefix(key, prefix) {
+		return ""
+	}
+	parts := strings.Split(strings.TrimPrefix(key, prefix), "/")
+	if len(parts) > 1 && (parts[0] == latestDir || parts[0] == liveDir || IsBackupID(parts[0])) {
+		parts = parts[1:]
+	}
+
+	var namespace string
+	switch parts[0] {
+	case manifestDir, crdDir, clusterDir, auditDir, diagnosticsDir:
+		return ""
+	case openShiftDir:
+		// _openshift/{namespace}/{type}/{name}.yaml
+		if len(parts) > 3 {
+			namespace = parts[1]
+		}
+	case dataDir:
+		// _data/{backup-id}/{namespace}/{file-name}
+		if len(parts) > 3 {
+			namespace = parts[2]
+		}
+	default:
+		// {namespace}/{resource-type}/{resource-name}.yaml
+		if len(parts) > 2 {
+			namespace = parts[0]
+		}
+	}
+	if namespace == clusterDir {
+		return ""
+	}
+	return namespace
+}
+
+// ManifestPath builds the bucket key for a run manifest
+func ManifestPath(clusterDomain, clusterName, backupID string) string {
+	return fmt.Sprintf("%s/%s/%s/%s.json",
+		sanitizePath(clusterDomain),
+		sanitizePath(clusterName),
+		manifestDir,
+		sanitizePath(backupID),
+	)
+}
+
+// AuditPath builds the bucket key for the audit log of a cleanup:
+// {domain}/{cluster-name}/_audit/{name}.json
+func AuditPath(clusterDomain, clusterName, name string) string {
+	return fmt.Sprintf("%s/%s/%s/%s.json",
+		sanitizePath(clusterDomain),
+		sanitizePath(clusterName),
+		auditDir,
+		sanitizePath(name),
+	)
+}
+
+// OperationAuditPath builds the bucket key for the audit record of a backup
+// or restore operation:
+// {domain}/{cluster-name}/_audit/operations/{yyyy}/{mm}/{dd}/{hhmmss}-{record-id}.json
+func OperationAuditPath(clusterDomain, clusterName string, at time.Time, recordID string) string {
+	return AuditPath(clusterDomain, clusterName, "operations/"+at.UTC().Format("2006/01/02/150405")+"-"+recordID)
+}
+
+// DataPath builds the bucket key for application data captured by a run:
+// {domain}/{cluster-name}/_data/{backup-id}/{namespace}/{file-name}
+func DataPath(clusterDomain, clusterName, backupID, namespace, fileName string) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s/%s",
+		sanitizePath(clusterDomain),
+		sanitizePath(clusterName),
+		dataDir,
+		sanitizePath(backupID),
+		sanitizePath(namespace),
+		sanitizePath(fileName),
+	)
+}
+
+// EtcdSnapshotPath builds the bucket key for a run's etcd snapshot:
+// {domain}/{cluster-name}/_data/{backup-id}/etcd-snapshot.db
+func EtcdSnapshotPath(clusterDomain, clusterName, backupID string) string {
+	return fmt.Sprintf("%s/%s/%s/%s/etcd-snapshot.db",
+		sanitizePath(clusterDomain),
+		sanitizePath(clusterName),
+		dataDir,
+		sanitizePath(backupID),
+	)
+}
+
+// ReportPath builds the bucket key for a file of a run's report:
+// {domain}/{cluster-name}/_data/{backup-id}/{file-name}
+func ReportPath(clusterDomain, clusterName, backupID, fileName string) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s",
+		sanitizePath(clusterDomain),
+		sanitizePath(clusterName),
+		dataDir,
+		sanitizePath(backupID),
+		sanitizePath(fileName),
+	)
+}
+
+// DiagnosticsPath builds the bucket key for a profile of a run:
+// {domain}/{cluster-name}/_diagnostics/{backup-id}/{file-name}
+func DiagnosticsPath(clusterDomain, clusterName, backupID, fileName string) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s",
+		sanitizePath(clusterDomain),
+		sanitizePath(clusterName),
+		diagnosticsDir,
+		sanitizePath(backupID),
+		sanitizePath(fileName),
+	)
+}
+
+// Checksum returns the hex-encoded SHA-256 of stored object content
+func Checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// backupIDLayout is the time layout of the start of a run in its identifier
+const backupIDLayout = "20060102-150405"
+
+// NewBackupID generates a sortable, collision-resistant identifier for a
+// backup run: its start time and a random suffix, so two runs starting in
+// the same second never share a prefix
+func NewBackupID(startTime time.Time) string {
+	id := backupIDPrefix + startTime.UTC().Format(backupIDLayout)
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Sprintf("%s-%d", id, startTime.UnixNano()%1e9)
+	}
+	return id + "-" + hex.EncodeToString(suffix)
+}
+
+// sanitizePath removes path traversal attempts and invalid characters
+func sanitizePath(input string) string {
+	// Remove path traversal attempts
+	sanitized := strings.ReplaceAll(input, "..", "")
+	sanitized = strings.ReplaceAll(sanitized, "\\", "")
+	// Allow forward slashes for resource types like "deployments.apps"
+	// but ensure no leading/trailing slashes
+	sanitized = strings.Trim(sanitized, "/")
+	return sanitized
+}
+
+// AllManifestKeys lists the run manifests of every cluster in the bucket by
+// walking the {domain}/{cluster-name}/_manifests/ prefixes
+func AllManifestKeys(ctx context.Context, minioClient *minio.Client, bucket string) ([]string, error) {
+	var keys []string
+	domains, err := listPrefixes(ctx, minioClient, bucket, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, domain := range domains {
+		if domain == BlobPrefix || domain == TrashPrefix {
+			continue
+		}
+		clusters, err := listPrefixes(ctx, minioClient, bucket, domain)
+		if err != nil {
+			return nil, err
+		}
+		for _, cluster := range clusters {
+			for object := range minioClient.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: cluster + manifestDir + "/"}) {
+				if object.Err != nil {
+					return nil, fmt.Errorf("failed to list manifests: %v", object.Err)
+				}
+				if IsManifestPath(object.Key) {
+					keys = append(keys, object.Key)
+				}
+			}
+		}
+	}
+	return keys, nil
+}
+
+// listPrefixes returns the "directories" directly below prefix
+func listPrefixes(ctx context.Context, minioClient *minio.Client, bucket, prefix string) ([]string, error) {
+	var prefixes []string
+	for object := range minioClient.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("failed to list %q: %v", prefix, object.Err)
+		}
+		if strings.HasSuffix(object.Key, "/") {
+			prefixes = append(prefixes, object.Key)
+		}
+	}
+	return prefixes, nil
+}
+
+// ManifestStore reads and writes run manifests in the backup bucket
+type ManifestStore struct {
+	minioClient   *minio.Client
+	bucket        string
+	clusterDomain string
+	clusterName   string
+	signer        signing.Signer
+	// mirror uploads manifests and signatures to the secondary targets
+	mirror objectMirror
+}
+
+// NewManifestStore creates a manifest store for one cluster's backups
+func NewManifestStore(minioClient *minio.Client, bucket, clusterDomain, clusterName string) *ManifestStore {
+	return &ManifestStore{
+		minioClient:   minioClient,
+		bucket:        bucket,
+		clusterDomain: clusterDomain,
+		clusterName:   clusterName,
+	}
+}
+
+// Save uploads the manifest, replacing any previous version for the run, and
+// its signature when a signer is set
+func (ms *ManifestStore) Save(ctx context.Context, manifest *Manifest) error {
+	manifest.mutex.Lock()
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	manifest.mutex.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %v", err)
+	}
+
+	key := ManifestPath(ms.clusterDomain, ms.clusterName, manifest.BackupID)
+	err = ms.put(ctx, key, data, minio.PutObjectOptions{
+		ContentType:  "application/json",
+		UserMetadata: checksumMetadata(Checksum(data)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload manifest %s: %v", key, err)
+	}
+	if ms.signer != nil {
+		return ms.signManifest(ctx, key, data)
+	}
+	return nil
+}
+
+// Load fetches the manifest of a backup run
+func (ms *ManifestStore) Load(ctx context.Context, backupID string) (*Manifest, error) {
+	key := ManifestPath(ms.clusterDomain, ms.clusterName, backupID)
+	data, err := ms.GetObject(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %v", key, err)
+	}
+
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %v", key, err)
+	}
+	return manifest, nil
+}
+
+// List returns summaries of all stored manifests, newest first
+func (ms *ManifestStore) List(ctx context.Context) ([]ManifestSummary, error) {
+	prefix := fmt.Sprintf("%s/%s/%s/", sanitizePath(ms.clusterDomain), sanitizePath(ms.clusterName), manifestDir)
+
+	var summaries []ManifestSummary
+	for object := range ms.minioClient.ListObjects(ctx, ms.bucket, minio.ListObjectsOptions{Prefix: prefix}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("failed to list manifests: %v", object.Err)
+		}
+		if !strings.HasSuffix(object.Key, ".json") {
+			continue
+		}
+
+		manifest, err := ms.Load(ctx, strings.TrimSuffix(path.Base(object.Key), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, manifest.Summary())
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].StartTime.After(summaries[j].StartTime)
+	})
+	return summaries, nil
+}
+
+// Latest returns the manifest of the most recent run that finished with at
+// least some resources stored
+func (ms *ManifestStore) Latest(ctx context.Context) (*Manifest, error) {
+	summaries, err := ms.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, summary := range summaries {
+		if summary.Status == ManifestStatusCompleted || summary.Status == ManifestStatusPartial {
+			return ms.Load(ctx, summary.BackupID)
+		}
+	}
+	return nil, fmt.Errorf("no completed backup found for cluster %s", ms.clusterName)
+}
+
+// GetObject fetches the raw content of an object from the backup bucket
+func (ms *ManifestStore) GetObject(ctx context.Context, key string) ([]byte, error) {
+	return ms.GetObjectVersion(ctx, key, "")
+}
+
+// GetObjectVersion fetches a specific version of an object. An empty versionID
+// returns the latest version. Content that does not match the checksum stored
+// with the object is rejected with ErrChecksumMismatch.
+func (ms *ManifestStore) GetObjectVersion(ctx context.Context, key, versionID string) ([]byte, error) {
+	object, err := ms.minioClient.GetObject(ctx, ms.bucket, key, minio.GetObjectOptions{VersionID: versionID})
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return nil, err
+	}
+	info, err := object.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyChecksum(key, Checksum(data), info.UserMetadata[ChecksumMetadata]); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,120 @@
+package backup
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/search"
+)
+
+func TestManifest_FindObject(t *testing.T) {
+	manifest := NewManifest("backup-20250101-020000", "prod", "example.com", "backups", time.Now())
+	manifest.AddObject(ObjectEntry{Namespace: "default", Version: "v1", ResourceType: "events", Name: "web.1", Key: "core"})
+	manifest.AddObject(ObjectEntry{Namespace: "default", Group: "events.k8s.io", Version: "v1", ResourceType: "events", Name: "web.1", Key: "events"})
+	manifest.AddObject(ObjectEntry{Namespace: "default", Group: "apps", Version: "v1", ResourceType: "deployments", Name: "web", Key: "deployment"})
+	manifest.AddObject(ObjectEntry{Group: "storage.k8s.io", Version: "v1", ResourceType: "storageclasses", Name: "fast", Key: "storageclass"})
+
+	found := manifest.FindObject("default", "deployments", "web")
+	require.Len(t, found, 1)
+	assert.Equal(t, "deployment", found[0].Key)
+
+	assert.Len(t, manifest.FindObject("default", "events", "web.1"), 2, "the type exists in two API groups")
+	found = manifest.FindObject("default", "events.events.k8s.io", "web.1")
+	require.Len(t, found, 1)
+	assert.Equal(t, "events", found[0].Key)
+
+	found = manifest.FindObject("", "storageclasses", "fast")
+	require.Len(t, found, 1)
+	assert.Equal(t, "storageclass", found[0].Key)
+	assert.Empty(t, manifest.FindObject("default", "storageclasses", "fast"))
+}
+
+func TestManifest_StoredKeys(t *testing.T) {
+	manifest := NewManifest("backup-20250101-020000", "prod", "example.com", "backups", time.Now())
+	manifest.AddObject(ObjectEntry{Namespace: "shop", ResourceType: "configmaps", Name: "app", Key: "shop-app"})
+	manifest.AddObject(ObjectEntry{Namespace: "payments", ResourceType: "secrets", Name: "db", Bucket: "payments-backups", Key: "payments-db"})
+	manifest.AddCRD(CRDEntry{Name: "widgets.example.com", Key: "crd"})
+	manifest.AddOpenShift(OpenShiftEntry{Type: "routes", Name: "web", Key: "route"})
+	manifest.AddDump(DumpEntry{Namespace: "payments", Bucket: "payments-backups", Key: "payments-dump"})
+	manifest.SetEtcdSnapshot(SnapshotEntry{Error: "etcdctl not found"})
+
+	assert.Equal(t, map[string][]string{
+		"":                 {"shop-app", "crd", "route"},
+		"payments-backups": {"payments-db", "payments-dump"},
+	}, manifest.StoredKeys())
+}
+
+func TestManifest_SourceAPIResources(t *testing.T) {
+	manifest := NewManifest("backup-20250101-020000", "prod", "example.com", "backups", time.Now())
+	manifest.SetSource("v1.29.4", map[string]bool{"SidecarContainers": true})
+	manifest.AddAPIResources([]APIResourceEntry{
+		{Group: "autoscaling", Version: "v2", Kind: "HorizontalPodAutoscaler", Resource: "horizontalpodautoscalers", Namespaced: true},
+		{Version: "v1", Kind: "ConfigMap", Resource: "configmaps", Namespaced: true},
+		{Version: "v1", Kind: "Secret", Resource: "secrets", Namespaced: true},
+	})
+	manifest.AddObject(ObjectEntry{Namespace: "shop", Version: "v1", ResourceType: "configmaps", Name: "app"})
+	manifest.AddObject(ObjectEntry{Namespace: "shop", Group: "autoscaling", Version: "v2", ResourceType: "horizontalpodautoscalers", Name: "web"})
+	manifest.AddAlternateVersion(ObjectEntry{Namespace: "shop", Group: "autoscaling", Version: "v1", ResourceType: "horizontalpodautoscalers", Name: "web"})
+	manifest.Finish(time.Now())
+
+	require.NotNil(t, manifest.Source)
+	assert.Equal(t, "v1.29.4", manifest.Source.KubernetesVersion)
+	assert.Equal(t, []APIResourceEntry{
+		{Version: "v1", Kind: "ConfigMap", Resource: "configmaps", Namespaced: true},
+		{Group: "autoscaling", Version: "v1", Kind: "HorizontalPodAutoscaler", Resource: "horizontalpodautoscalers", Namespaced: true},
+		{Group: "autoscaling", Version: "v2", Kind: "HorizontalPodAutoscaler", Resource: "horizontalpodautoscalers", Namespaced: true},
+	}, manifest.Source.APIResources, "types without objects are left out")
+}
+
+func TestNewBackupID(t *testing.T) {
+	start := time.Date(2025, 1, 1, 2, 0, 0, 0, time.UTC)
+	first, second := NewBackupID(start), NewBackupID(start)
+	assert.NotEqual(t, first, second, "runs starting in the same second get their own IDs")
+	assert.Regexp(t, `^backup-20250101-020000-[0-9a-f]{8}$`, first)
+
+	assert.True(t, IsBackupID(first))
+	assert.True(t, IsBackupID("backup-20250101-020000"), "IDs made before the suffix")
+	assert.False(t, IsBackupID("backup-tools"))
+	assert.False(t, IsBackupID("backup-20250101-020000x"))
+	assert.False(t, IsBackupID("latest"))
+}
+
+func TestRunPath(t *testing.T) {
+	key := ObjectPath("example.com", "prod", "shop", "configmaps", "app")
+	assert.Equal(t, "example.com/prod/backup-20250101-020000-0a1b2c3d/shop/configmaps/app.yaml", RunPath("example.com", "prod", "backup-20250101-020000-0a1b2c3d", key))
+	assert.Equal(t, "example.com/prod/latest/shop/configmaps/app.yaml", LatestPath("example.com", "prod", key))
+	assert.Equal(t, "example.com/prod/latest/_crds/widgets.example.com.yaml", LatestPath("example.com", "prod", CRDPath("example.com", "prod", "widgets.example.com")))
+	assert.True(t, strings.HasPrefix(LatestPath("example.com", "prod", key), LatestPrefix("example.com", "prod")))
+	assert.Equal(t, "example.com/prod/_live/shop/configmaps/app.yaml", LivePath("example.com", "prod", key))
+	assert.True(t, strings.HasPrefix(LivePath("example.com", "prod", key), LivePrefix("example.com", "prod")))
+	assert.Equal(t, "shop", PathNamespace("example.com", "prod", LivePath("example.com", "prod", key)))
+}
+
+func TestIndexObject(t *testing.T) {
+	index := search.NewIndex("backup-20250101-020000")
+	ctx := withRunIndex(context.Background(), index)
+	data, err := newPayload(map[string]interface{}{
+		"kind":     "Deployment",
+		"metadata": map[string]interface{}{"name": "api", "labels": map[string]interface{}{"app": "payments"}},
+	})
+	require.NoError(t, err)
+
+	entry := ObjectEntry{Namespace: "payments", Group: "apps", Version: "v1", ResourceType: "deployments", Name: "api", Key: "payments/deployments.apps/api.yaml"}
+	indexObject(ctx, entry, data)
+	indexObject(context.Background(), entry, data)
+
+	terms, err := search.ParseQuery([]string{"label=app=payments", "kind=Deployment"})
+	require.NoError(t, err)
+	found := index.Search(terms)
+	require.Len(t, found, 1)
+	assert.Equal(t, "payments/deployments.apps/api.yaml", found[0].Key)
+
+	manifest := NewManifest("backup-20250101-020000", "prod", "example.com", "backups", time.Now())
+	manifest.SetIndex("example.com/prod/_data/backup-20250101-020000/index.json.gz")
+	assert.Contains(t, manifest.StoredKeys()[""], manifest.Index, "retention keeps the index with its run")
+}
@@ -0,0 +1,115 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// PathMigration is the outcome of moving the resources of a cluster's runs
+// from keys naming the resource type alone to keys with its API group
+type PathMigration struct {
+	// Runs are the runs whose manifests were, or in a dry run would be,
+	// pointed at the new keys
+	Runs    []string `json:"runs"`
+	Objects int      `json:"objects"`
+	// Skipped are the keys left in place because they are in a tenant's
+	// bucket, which the bucket's credentials may not reach
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// MigrateObjectPaths copies every resource of the cluster's runs stored at
+// the key it had before API groups were part of resource keys to the key it
+// has now, version included with withVersion, and saves each manifest
+// pointing at the copies. The old keys are left to expire with retention. A
+// dry run only reports what would move. Running it again moves nothing.
+func (ms *ManifestStore) MigrateObjectPaths(ctx context.Context, withVersion, dryRun bool) (*PathMigration, error) {
+	summaries, err := ms.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	migration := &PathMigration{}
+	for _, summary := range summaries {
+		manifest, err := ms.Load(ctx, summary.BackupID)
+		if err != nil {
+			return migration, err
+		}
+
+		moved := 0
+		for _, list := range []struct {
+			entries   []ObjectEntry
+			alternate bool
+		}{{manifest.Objects, false}, {manifest.AlternateVersions, true}} {
+			for i := range list.entries {
+				entry := &list.entries[i]
+				key, ok := migratedKey(ms.clusterDomain, ms.clusterName, manifest.BackupID, *entry, list.alternate, withVersion)
+				if !ok {
+					continue
+				}
+				if entry.Bucket != "" && entry.Bucket != ms.bucket {
+					migration.Skipped = append(migration.Skipped, entry.Bucket+"/"+entry.Key)
+					continue
+				}
+				moved++
+				if dryRun {
+					continue
+				}
+				info, err := ms.minioClient.CopyObject(ctx,
+					minio.CopyDestOptions{Bucket: ms.bucket, Object: key},
+					minio.CopySrcOptions{Bucket: ms.bucket, Object: entry.Key, VersionID: entry.VersionID})
+				if err != nil {
+					return migration, fmt.Errorf("failed to copy %s to %s: %v", entry.Key, key, err)
+				}
+				entry.Key = key
+				entry.VersionID = info.VersionID
+			}
+		}
+		if moved == 0 {
+			continue
+		}
+		if !dryRun {
+			if err := ms.Save(ctx, manifest); err != nil {
+				return migration, err
+			}
+		}
+		migration.Runs = append(migration.Runs, manifest.BackupID)
+		migration.Objects += moved
+	}
+	return migration, nil
+}
+
+// migratedKey returns the key entry has with its API group in the key, and
+// whether that differs from the key it is stored at. Only keys of the
+// built-in layout, in the run's prefix or not and below a tenant's prefix or
+// not, are moved; blobs and templated keys stay where they are.
+func migratedKey(clusterDomain, clusterName, backupID string, entry ObjectEntry, alternate, withVersion bool) (string, bool) {
+	gvr := schema.GroupVersionResource{Group: entry.Group, Version: entry.Version, Resource: entry.ResourceType}
+	var legacy, current string
+	if alternate {
+		legacy = VersionedObjectPath(clusterDomain, clusterName, entry.Namespace, entry.ResourceType, entry.Version, entry.Name)
+		current = VersionedObjectPath(clusterDomain, clusterName, entry.Namespace, QualifiedResource(gvr, false), entry.Version, entry.Name)
+	} else {
+		legacy = ObjectPath(clusterDomain, clusterName, entry.Namespace, entry.ResourceType, entry.Name)
+		current = ObjectPath(clusterDomain, clusterName, entry.Namespace, QualifiedResource(gvr, withVersion), entry.Name)
+	}
+	if legacy == current {
+		return "", false
+	}
+
+	for _, layout := range [][2]string{
+		{legacy, current},
+		{RunPath(clusterDomain, clusterName, backupID, legacy), RunPath(clusterDomain, clusterName, backupID, current)},
+	} {
+		if entry.Key == layout[0] {
+			return layout[1], true
+		}
+		if prefix, found := strings.CutSuffix(entry.Key, "/"+layout[0]); found {
+			return prefix + "/" + layout[1], true
+		}
+	}
+	return "", false
+}
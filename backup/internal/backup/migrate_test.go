@@ -0,0 +1,55 @@
+package backup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestQualifiedResource(t *testing.T) {
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	assert.Equal(t, "deployments.apps", QualifiedResource(deployments, false))
+	assert.Equal(t, "deployments.apps/v1", QualifiedResource(deployments, true))
+	assert.Equal(t, "configmaps", QualifiedResource(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, false))
+
+	custom := schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "deployments"}
+	assert.NotEqual(t,
+		ObjectPath("example.com", "prod", "shop", QualifiedResource(deployments, false), "web"),
+		ObjectPath("example.com", "prod", "shop", QualifiedResource(custom, false), "web"),
+		"types of the same name in different groups do not share keys")
+}
+
+func TestMigratedKey(t *testing.T) {
+	entry := ObjectEntry{Namespace: "shop", Group: "apps", Version: "v1", ResourceType: "deployments", Name: "web",
+		Key: "example.com/prod/shop/deployments/web.yaml"}
+
+	key, ok := migratedKey("example.com", "prod", "backup-1", entry, false, false)
+	assert.True(t, ok)
+	assert.Equal(t, "example.com/prod/shop/deployments.apps/web.yaml", key)
+
+	key, ok = migratedKey("example.com", "prod", "backup-1", entry, false, true)
+	assert.True(t, ok)
+	assert.Equal(t, "example.com/prod/shop/deployments.apps/v1/web.yaml", key)
+
+	entry.Key = "tenants/shop/example.com/prod/backup-1/shop/deployments/web.yaml"
+	key, ok = migratedKey("example.com", "prod", "backup-1", entry, false, false)
+	assert.True(t, ok)
+	assert.Equal(t, "tenants/shop/example.com/prod/backup-1/shop/deployments.apps/web.yaml", key)
+
+	entry.Key = "example.com/prod/shop/_versions/v1beta1/deployments/web.yaml"
+	entry.Version = "v1beta1"
+	key, ok = migratedKey("example.com", "prod", "backup-1", entry, true, true)
+	assert.True(t, ok)
+	assert.Equal(t, "example.com/prod/shop/_versions/v1beta1/deployments.apps/web.yaml", key)
+
+	for _, stored := range []string{"example.com/prod/shop/deployments.apps/web.yaml", BlobPath(Checksum([]byte("web"))), "prod/shop/Deployment/web.yaml"} {
+		entry.Key = stored
+		_, ok = migratedKey("example.com", "prod", "backup-1", entry, false, false)
+		assert.False(t, ok, stored)
+	}
+
+	core := ObjectEntry{Namespace: "shop", Version: "v1", ResourceType: "configmaps", Name: "app", Key: "example.com/prod/shop/configmaps/app.yaml"}
+	_, ok = migratedKey("example.com", "prod", "backup-1", core, false, false)
+	assert.False(t, ok, "core types keep their keys")
+}
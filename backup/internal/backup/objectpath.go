@@ -0,0 +1,48 @@
+package backup
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"cluster-backup/internal/pathtemplate"
+)
+
+type objectPathsKey struct{}
+
+// withObjectPaths returns a context the resources of a run are stored at the
+// keys paths renders through
+func withObjectPaths(ctx context.Context, paths *pathtemplate.Template) context.Context {
+	return context.WithValue(ctx, objectPathsKey{}, paths)
+}
+
+// objectPathsFrom returns the OBJECT_PATH_TEMPLATE of the run ctx belongs
+// to, nil with the built-in layout
+func objectPathsFrom(ctx context.Context) *pathtemplate.Template {
+	paths, _ := ctx.Value(objectPathsKey{}).(*pathtemplate.Template)
+	return paths
+}
+
+// objectKey returns the bucket key of a resource in its preferred version:
+// ObjectPath, or the key OBJECT_PATH_TEMPLATE renders when one is set
+func (cb *ClusterBackup) objectKey(ctx context.Context, manifest *Manifest, namespace string, gvr schema.GroupVersionResource, name string, data *payload) (string, error) {
+	paths := objectPathsFrom(ctx)
+	if paths == nil {
+		return ObjectPath(cb.config.ClusterDomain, cb.config.ClusterName, namespace, QualifiedResource(gvr, cb.config.ObjectPathVersion), name), nil
+	}
+	if namespace == "" {
+		namespace = clusterDir
+	}
+	kind, _ := data.object["kind"].(string)
+	return paths.Render(pathtemplate.Fields{
+		Domain:    cb.config.ClusterDomain,
+		Cluster:   cb.config.ClusterName,
+		Namespace: namespace,
+		Group:     gvr.Group,
+		Version:   gvr.Version,
+		Resource:  gvr.Resource,
+		Kind:      kind,
+		Name:      name,
+		BackupID:  manifest.BackupID,
+	})
+}
@@ -0,0 +1,474 @@
+package backup
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"cluster-backup/internal/failure"
+)
+
+// What is captured under the _openshift prefix, used as the type part of
+// the keys
+const (
+	OpenShiftProjects        = "projects"
+	OpenShiftSCCBindings     = "scc-bindings"
+	OpenShiftImageStreamTags = "imagestream-tags"
+	OpenShiftRouteTLS        = "route-tls"
+)
+
+// RouteAnnotation names the Route a captured TLS Secret belongs to
+const RouteAnnotation = "backup.cluster/route"
+
+// sccRolePrefix starts the names of the ClusterRoles OpenShift creates to
+// grant the use of each SecurityContextConstraints
+const sccRolePrefix = "system:openshift:scc:"
+
+var (
+	projectGVR     = schema.GroupVersionResource{Group: "project.openshift.io", Version: "v1", Resource: "projects"}
+	imageStreamGVR = schema.GroupVersionResource{Group: "image.openshift.io", Version: "v1", Resource: "imagestreams"}
+	routeGVR       = schema.GroupVersionResource{Group: "route.openshift.io", Version: "v1", Resource: "routes"}
+	sccGVR         = schema.GroupVersionResource{Group: "security.openshift.io", Version: "v1", Resource: "securitycontextconstraints"}
+	secretGVR      = schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+)
+
+// OpenShiftEntry describes an object stored under the _openshift prefix.
+// Namespace is empty for SCC bindings, which are cluster-wide.
+type OpenShiftEntry struct {
+	Namespace string `json:"namespace,omitempty"`
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Key       string `json:"key"`
+	Size      int64  `json:"size"`
+	Checksum  string `json:"checksum,omitempty"`
+	VersionID string `json:"version_id,omitempty"`
+}
+
+// openShiftObject is a document collected for the _openshift prefix
+type openShiftObject struct {
+	Namespace string
+	Type      string
+	Name      string
+	Object    map[string]interface{}
+}
+
+// openShiftEnabled reports whether OpenShift settings are captured:
+// always with OPENSHIFT_MODE=enabled and, when auto-detecting, if the API
+// server serves an openshift.io group
+func (cb *ClusterBackup) openShiftEnabled() bool {
+	switch cb.backupConfig.OpenShiftMode {
+	case "enabled":
+		return true
+	case "auto-detect":
+		groups, err := cb.discoveryClient.ServerGroups()
+		if err != nil {
+			cb.logger.Warning("openshift_detection_failed", "Failed to detect OpenShift; its settings are not captured", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return false
+		}
+		for _, group := range groups.Groups {
+			if strings.HasSuffix(group.Name, ".openshift.io") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// captureOpenShift stores, for the backed-up namespaces, what OpenShift keeps
+// outside their resources: project annotations, the service accounts granted
+// each SCC, the image each ImageStream tag points to and the certificates of
+// Routes. Objects that cannot be collected are reported and skipped.
+func (cb *ClusterBackup) captureOpenShift(ctx context.Context, namespaces []string, manifest *Manifest) (int, error) {
+	objects, failed := cb.collectOpenShift(ctx, namespaces)
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, object := range objects {
+		if err := cb.storeOpenShift(ctx, object, manifest); err != nil {
+			failed = append(failed, fmt.Sprintf("%s %s/%s: %v", object.Type, object.Namespace, object.Name, err))
+			continue
+		}
+		count++
+	}
+
+	for _, failure := range failed {
+		cb.logger.Warning("openshift_capture_failed", "Failed to capture OpenShift settings", map[string]interface{}{
+			"error": failure,
+		})
+	}
+	cb.logger.Info("openshift_capture_complete", "Captured OpenShift settings", map[string]interface{}{
+		"object_count": count,
+	})
+	if len(failed) > 0 {
+		return count, fmt.Errorf("failed to capture OpenShift settings: %s", strings.Join(failed, "; "))
+	}
+	return count, nil
+}
+
+// collectOpenShift gathers the objects to store, and a description of each
+// part that could not be read
+func (cb *ClusterBackup) collectOpenShift(ctx context.Context, namespaces []string) ([]openShiftObject, []string) {
+	var objects []openShiftObject
+	var failed []string
+	for _, namespace := range namespaces {
+		if ctx.Err() != nil {
+			break
+		}
+		for _, collect := range []func(context.Context, string) ([]openShiftObject, error){
+			cb.collectProject,
+			cb.collectImageStreamTags,
+			cb.collectRouteTLS,
+		} {
+			collected, err := collect(ctx, namespace)
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", namespace, err))
+			}
+			objects = append(objects, collected...)
+		}
+	}
+
+	bindings, err := cb.collectSCCBindings(ctx, namespaces)
+	if err != nil {
+		failed = append(failed, err.Error())
+	}
+	return append(objects, bindings...), failed
+}
+
+// storeOpenShift uploads one collected object and records it in the manifest
+func (cb *ClusterBackup) storeOpenShift(ctx context.Context, object openShiftObject, manifest *Manifest) error {
+	data, err := newPayload(object.Object)
+	if err != nil {
+		return fmt.Errorf("failed to serialize: %v", err)
+	}
+
+	entry := OpenShiftEntry{
+		Namespace: object.Namespace,
+		Type:      object.Type,
+		Name:      object.Name,
+		Key:       OpenShiftPath(cb.config.ClusterDomain, cb.config.ClusterName, object.Namespace, object.Type, object.Name),
+	}
+	key, versionID, err := cb.uploadRunObject(ctx, cb.defaultTarget(), entry.Key, data)
+	if err != nil {
+		return fmt.Errorf("failed to upload: %w", failure.Storage(err))
+	}
+	entry.Key = key
+	entry.Size = data.Size
+	entry.Checksum = data.Checksum
+	entry.VersionID = versionID
+
+	manifest.AddOpenShift(entry)
+	return nil
+}
+
+// collectProject keeps the namespace's Project, whose display name,
+// description, requester and node selector live in its annotations
+func (cb *ClusterBackup) collectProject(ctx context.Context, namespace string) ([]openShiftObject, error) {
+	var project *unstructured.Unstructured
+	err := cb.withAPIRetry(ctx, func(ctx context.Context) error {
+		var err error
+		project, err = cb.dynamicClient.Resource(projectGVR).Get(ctx, namespace, v1.GetOptions{})
+		// Not an error worth retrying or opening the breaker over
+		if apierrors.IsNotFound(err) {
+			project = nil
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project: %v", err)
+	}
+	if project == nil {
+		return nil, nil
+	}
+	return []openShiftObject{{Namespace: namespace, Type: OpenShiftProjects, Name: namespace, Object: cb.cleanResource(project)}}, nil
+}
+
+// collectImageStreamTags records, for each ImageStream, the image its tags
+// currently resolve to, which a restore into a cluster without the internal
+// registry's history cannot recompute
+func (cb *ClusterBackup) collectImageStreamTags(ctx context.Context, namespace string) ([]openShiftObject, error) {
+	imageStreams, err := cb.listAll(ctx, imageStreamGVR, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []openShiftObject
+	for _, imageStream := range imageStreams {
+		statusTags, _, _ := unstructured.NestedSlice(imageStream.Object, "status", "tags")
+		var tags []interface{}
+		for _, statusTag := range statusTags {
+			tag, _ := statusTag.(map[string]interface{})
+			items, _, _ := unstructured.NestedSlice(tag, "items")
+			if len(items) == 0 {
+				continue
+			}
+			// Items are newest first; the first is what the tag points to
+			latest, _ := items[0].(map[string]interface{})
+			name, _ := tag["tag"].(string)
+			reference, _ := latest["dockerImageReference"].(string)
+			image, _ := latest["image"].(string)
+			created, _ := latest["created"].(string)
+			tags = append(tags, map[string]interface{}{
+				"tag":                  name,
+				"dockerImageReference": reference,
+				"image":                image,
+				"created":              created,
+			})
+		}
+		if len(tags) == 0 {
+			continue
+		}
+
+		objects = append(objects, openShiftObject{
+			Namespace: namespace,
+			Type:      OpenShiftImageStreamTags,
+			Name:      imageStream.GetName(),
+			Object: map[string]interface{}{
+				"imageStream": imageStream.GetName(),
+				"namespace":   namespace,
+				"tags":        tags,
+			},
+		})
+	}
+	return objects, nil
+}
+
+// collectRouteTLS keeps the certificate of each Route serving its own: the
+// Secret an external certificate refers to, or a kubernetes.io/tls Secret
+// built from the certificate and key set inline on the Route
+func (cb *ClusterBackup) collectRouteTLS(ctx context.Context, namespace string) ([]openShiftObject, error) {
+	routes, err := cb.listAll(ctx, routeGVR, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []openShiftObject
+	var failed []string
+	for _, route := range routes {
+		tls, found, _ := unstructured.NestedMap(route.Object, "spec", "tls")
+		if !found {
+			continue
+		}
+
+		var secret map[string]interface{}
+		if name, _, _ := unstructured.NestedString(tls, "externalCertificate", "name"); name != "" {
+			var external *unstructured.Unstructured
+			err := cb.withAPIRetry(ctx, func(ctx context.Context) error {
+				var err error
+				external, err = cb.dynamicClient.Resource(secretGVR).Namespace(namespace).Get(ctx, name, v1.GetOptions{})
+				return err
+			})
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("route %s: failed to get secret %s: %v", route.GetName(), name, err))
+				continue
+			}
+			secret = cb.cleanResource(external)
+		} else {
+			certificate, _ := tls["certificate"].(string)
+			key, _ := tls["key"].(string)
+			if certificate == "" || key == "" {
+				// Served with the router's default certificate
+				continue
+			}
+			data := map[string]interface{}{
+				"tls.crt": base64.StdEncoding.EncodeToString([]byte(certificate)),
+				"tls.key": base64.StdEncoding.EncodeToString([]byte(key)),
+			}
+			if ca, _ := tls["caCertificate"].(string); ca != "" {
+				data["ca.crt"] = base64.StdEncoding.EncodeToString([]byte(ca))
+			}
+			secret = map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Secret",
+				"type":       "kubernetes.io/tls",
+				"metadata": map[string]interface{}{
+					"name":        route.GetName() + "-tls",
+					"namespace":   namespace,
+					"annotations": map[string]interface{}{RouteAnnotation: route.GetName()},
+				},
+				"data": data,
+			}
+		}
+
+		objects = append(objects, openShiftObject{Namespace: namespace, Type: OpenShiftRouteTLS, Name: route.GetName(), Object: secret})
+	}
+
+	if len(failed) > 0 {
+		return objects, fmt.Errorf("%s", strings.Join(failed, "; "))
+	}
+	return objects, nil
+}
+
+// collectSCCBindings records, per SCC, which service accounts of the
+// backed-up namespaces may use it, whether listed on the SCC itself or bound
+// to its system:openshift:scc: ClusterRole
+func (cb *ClusterBackup) collectSCCBindings(ctx context.Context, namespaces []string) ([]openShiftObject, error) {
+	included := make(map[string]bool, len(namespaces))
+	for _, namespace := range namespaces {
+		included[namespace] = true
+	}
+	subjects := make(map[string][]interface{})
+	add := func(scc, kind, namespace, name, source string) {
+		subject := map[string]interface{}{"kind": kind, "name": name, "source": source}
+		if namespace != "" {
+			subject["namespace"] = namespace
+		}
+		subjects[scc] = append(subjects[scc], subject)
+	}
+
+	sccs, err := cb.listAll(ctx, sccGVR, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, scc := range sccs {
+		users, _, _ := unstructured.NestedStringSlice(scc.Object, "users")
+		for _, user := range users {
+			if namespace, name, ok := serviceAccountUser(user); ok && included[namespace] {
+				add(scc.GetName(), rbacv1.ServiceAccountKind, namespace, name, "scc")
+			}
+		}
+		groups, _, _ := unstructured.NestedStringSlice(scc.Object, "groups")
+		for _, group := range groups {
+			if namespace, ok := serviceAccountsGroup(group); ok && included[namespace] {
+				add(scc.GetName(), rbacv1.GroupKind, "", group, "scc")
+			}
+		}
+	}
+
+	bindingSubjects := func(scc, source, bindingNamespace string, bindingSubjects []rbacv1.Subject) {
+		for _, subject := range bindingSubjects {
+			switch subject.Kind {
+			case rbacv1.ServiceAccountKind:
+				namespace := subject.Namespace
+				if namespace == "" {
+					namespace = bindingNamespace
+				}
+				if included[namespace] {
+					add(scc, subject.Kind, namespace, subject.Name, source)
+				}
+			case rbacv1.GroupKind:
+				if namespace, ok := serviceAccountsGroup(subject.Name); ok && included[namespace] {
+					add(scc, subject.Kind, "", subject.Name, source)
+				}
+			}
+		}
+	}
+
+	var clusterBindings *rbacv1.ClusterRoleBindingList
+	err = cb.withAPIRetry(ctx, func(ctx context.Context) error {
+		var err error
+		clusterBindings, err = cb.kubeClient.RbacV1().ClusterRoleBindings().List(ctx, v1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clusterrolebindings: %v", err)
+	}
+	for _, binding := range clusterBindings.Items {
+		if scc, ok := strings.CutPrefix(binding.RoleRef.Name, sccRolePrefix); ok && binding.RoleRef.Kind == "ClusterRole" {
+			bindingSubjects(scc, "clusterrolebinding/"+binding.Name, "", binding.Subjects)
+		}
+	}
+
+	for _, namespace := range namespaces {
+		var bindings *rbacv1.RoleBindingList
+		err := cb.withAPIRetry(ctx, func(ctx context.Context) error {
+			var err error
+			bindings, err = cb.kubeClient.RbacV1().RoleBindings(namespace).List(ctx, v1.ListOptions{})
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list rolebindings in %s: %v", namespace, err)
+		}
+		for _, binding := range bindings.Items {
+			if scc, ok := strings.CutPrefix(binding.RoleRef.Name, sccRolePrefix); ok && binding.RoleRef.Kind == "ClusterRole" {
+				bindingSubjects(scc, "rolebinding/"+namespace+"/"+binding.Name, namespace, binding.Subjects)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(subjects))
+	for scc := range subjects {
+		names = append(names, scc)
+	}
+	sort.Strings(names)
+
+	objects := make([]openShiftObject, 0, len(names))
+	for _, scc := range names {
+		objects = append(objects, openShiftObject{
+			Type: OpenShiftSCCBindings,
+			Name: scc,
+			Object: map[string]interface{}{
+				"scc":      scc,
+				"subjects": subjects[scc],
+			},
+		})
+	}
+	return objects, nil
+}
+
+// serviceAccountUser splits a system:serviceaccount:{namespace}:{name} user
+func serviceAccountUser(user string) (namespace, name string, ok bool) {
+	rest, ok := strings.CutPrefix(user, "system:serviceaccount:")
+	if !ok {
+		return "", "", false
+	}
+	namespace, name, ok = strings.Cut(rest, ":")
+	return namespace, name, ok
+}
+
+// serviceAccountsGroup reads the namespace of a system:serviceaccounts:{namespace}
+// group, the one all service accounts of a namespace belong to
+func serviceAccountsGroup(group string) (string, bool) {
+	namespace, ok := strings.CutPrefix(group, "system:serviceaccounts:")
+	return namespace, ok && namespace != ""
+}
+
+// listAll lists every item of a resource type in a namespace, or in the
+// cluster scope when namespace is empty, a page at a time
+func (cb *ClusterBackup) listAll(ctx context.Context, gvr schema.GroupVersionResource, namespace string) ([]unstructured.Unstructured, error) {
+	listOptions := v1.ListOptions{Limit: cb.batchSize(gvr)}
+
+	var items []unstructured.Unstructured
+	for {
+		var list *unstructured.UnstructuredList
+		err := cb.withAPIRetry(ctx, func(ctx context.Context) error {
+			var err error
+			list, err = cb.dynamicClient.Resource(gvr).Namespace(namespace).List(ctx, listOptions)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", gvr.Resource, err)
+		}
+		items = append(items, list.Items...)
+
+		if list.GetContinue() == "" {
+			return items, nil
+		}
+		listOptions.Continue = list.GetContinue()
+	}
+}
+
+// withAPIRetry runs one Kubernetes API call through the API circuit breaker
+// and retries, bounding each attempt like the resource lists
+func (cb *ClusterBackup) withAPIRetry(ctx context.Context, call func(ctx context.Context) error) error {
+	return cb.apiCircuitBreaker.Execute(func() error {
+		return cb.retryExecutor.ExecuteWithContext(ctx, func() error {
+			callCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+			defer cancel()
+			return call(callCtx)
+		})
+	})
+}
@@ -0,0 +1,194 @@
+package backup
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	rbacv1 "k8s.io/api/rbac/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/logging"
+	"cluster-backup/internal/resilience"
+)
+
+func newOpenShiftTestBackup(t *testing.T, objects ...runtime.Object) *ClusterBackup {
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		projectGVR:     "ProjectList",
+		imageStreamGVR: "ImageStreamList",
+		routeGVR:       "RouteList",
+		sccGVR:         "SecurityContextConstraintsList",
+		secretGVR:      "SecretList",
+	}, objects...)
+	// The fake cannot guess the plural of SecurityContextConstraints
+	require.NoError(t, dynamicClient.Tracker().Create(sccGVR, &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "security.openshift.io/v1",
+		"kind":       "SecurityContextConstraints",
+		"metadata":   map[string]interface{}{"name": "nonroot"},
+		"users":      []interface{}{"system:serviceaccount:shop:api", "system:admin"},
+		"groups":     []interface{}{"system:serviceaccounts:other"},
+	}}, ""))
+
+	kubeClient := fake.NewSimpleClientset(
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: v1.ObjectMeta{Name: "shop-anyuid"},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "system:openshift:scc:anyuid"},
+			Subjects: []rbacv1.Subject{
+				{Kind: rbacv1.ServiceAccountKind, Namespace: "shop", Name: "legacy"},
+				{Kind: rbacv1.ServiceAccountKind, Namespace: "other", Name: "legacy"},
+			},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: v1.ObjectMeta{Name: "builder-privileged", Namespace: "shop"},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "system:openshift:scc:privileged"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "builder"}},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: v1.ObjectMeta{Name: "view", Namespace: "shop"},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "view"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "builder"}},
+		},
+	)
+
+	return &ClusterBackup{
+		config:            &config.Config{BatchSize: 100},
+		backupConfig:      &config.BackupConfig{},
+		kubeClient:        kubeClient,
+		dynamicClient:     dynamicClient,
+		logger:            logging.NewStructuredLogger("openshift-test", "test-cluster"),
+		apiCircuitBreaker: resilience.NewCircuitBreaker(5, time.Minute),
+		retryExecutor:     resilience.NewRetryExecutor(resilience.RetryConfig{MaxAttempts: 1}),
+	}
+}
+
+func openShiftObjects() []runtime.Object {
+	return []runtime.Object{
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "project.openshift.io/v1",
+			"kind":       "Project",
+			"metadata": map[string]interface{}{
+				"name":        "shop",
+				"annotations": map[string]interface{}{"openshift.io/display-name": "Shop", "openshift.io/requester": "alice"},
+			},
+		}},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "image.openshift.io/v1",
+			"kind":       "ImageStream",
+			"metadata":   map[string]interface{}{"name": "api", "namespace": "shop"},
+			"status": map[string]interface{}{"tags": []interface{}{
+				map[string]interface{}{"tag": "latest", "items": []interface{}{
+					map[string]interface{}{
+						"dockerImageReference": "image-registry.openshift-image-registry.svc:5000/shop/api@sha256:4f3c",
+						"image":                "sha256:4f3c",
+						"created":              "2025-01-01T02:00:00Z",
+					},
+					map[string]interface{}{"dockerImageReference": "image-registry.openshift-image-registry.svc:5000/shop/api@sha256:0a1b", "image": "sha256:0a1b"},
+				}},
+				map[string]interface{}{"tag": "pending"},
+			}},
+		}},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "route.openshift.io/v1",
+			"kind":       "Route",
+			"metadata":   map[string]interface{}{"name": "api", "namespace": "shop"},
+			"spec": map[string]interface{}{
+				"host": "api.apps.example.com",
+				"tls":  map[string]interface{}{"termination": "edge", "certificate": "CERT", "key": "KEY"},
+			},
+		}},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "route.openshift.io/v1",
+			"kind":       "Route",
+			"metadata":   map[string]interface{}{"name": "web", "namespace": "shop"},
+			"spec": map[string]interface{}{
+				"tls": map[string]interface{}{"termination": "edge", "externalCertificate": map[string]interface{}{"name": "web-cert"}},
+			},
+		}},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "route.openshift.io/v1",
+			"kind":       "Route",
+			"metadata":   map[string]interface{}{"name": "default-cert", "namespace": "shop"},
+			"spec":       map[string]interface{}{"tls": map[string]interface{}{"termination": "edge"}},
+		}},
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"type":       "kubernetes.io/tls",
+			"metadata":   map[string]interface{}{"name": "web-cert", "namespace": "shop", "uid": "1234"},
+			"data":       map[string]interface{}{"tls.crt": "Q0VSVA==", "tls.key": "S0VZ"},
+		}},
+	}
+}
+
+func TestCollectOpenShift(t *testing.T) {
+	cb := newOpenShiftTestBackup(t, openShiftObjects()...)
+
+	objects, failed := cb.collectOpenShift(context.Background(), []string{"shop"})
+	assert.Empty(t, failed)
+
+	byKey := make(map[string]map[string]interface{})
+	for _, object := range objects {
+		byKey[object.Type+"/"+object.Namespace+"/"+object.Name] = object.Object
+	}
+	require.Len(t, byKey, 7)
+
+	project := byKey["projects/shop/shop"]
+	assert.Equal(t, "alice", project["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})["openshift.io/requester"])
+
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"tag":                  "latest",
+		"dockerImageReference": "image-registry.openshift-image-registry.svc:5000/shop/api@sha256:4f3c",
+		"image":                "sha256:4f3c",
+		"created":              "2025-01-01T02:00:00Z",
+	}}, byKey["imagestream-tags/shop/api"]["tags"])
+
+	inline := byKey["route-tls/shop/api"]
+	assert.Equal(t, "kubernetes.io/tls", inline["type"])
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("KEY")), inline["data"].(map[string]interface{})["tls.key"])
+	assert.Equal(t, map[string]interface{}{RouteAnnotation: "api"}, inline["metadata"].(map[string]interface{})["annotations"])
+	external := byKey["route-tls/shop/web"]
+	assert.Equal(t, "web-cert", external["metadata"].(map[string]interface{})["name"])
+	assert.NotContains(t, external["metadata"], "uid")
+
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"kind": "ServiceAccount", "namespace": "shop", "name": "api", "source": "scc"},
+	}, byKey["scc-bindings//nonroot"]["subjects"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"kind": "ServiceAccount", "namespace": "shop", "name": "legacy", "source": "clusterrolebinding/shop-anyuid"},
+	}, byKey["scc-bindings//anyuid"]["subjects"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"kind": "ServiceAccount", "namespace": "shop", "name": "builder", "source": "rolebinding/shop/builder-privileged"},
+	}, byKey["scc-bindings//privileged"]["subjects"])
+}
+
+func TestCollectOpenShift_ReportsMissingCertificate(t *testing.T) {
+	objects := openShiftObjects()
+	// Drop the external certificate Secret
+	cb := newOpenShiftTestBackup(t, objects[:len(objects)-1]...)
+
+	collected, failed := cb.collectOpenShift(context.Background(), []string{"shop"})
+	require.Len(t, failed, 1)
+	assert.Contains(t, failed[0], "route web: failed to get secret web-cert")
+
+	var routes []string
+	for _, object := range collected {
+		if object.Type == OpenShiftRouteTLS {
+			routes = append(routes, object.Name)
+		}
+	}
+	assert.Equal(t, []string{"api"}, routes)
+}
+
+func TestOpenShiftPath(t *testing.T) {
+	assert.Equal(t, "example.com/prod/_openshift/shop/route-tls/api.yaml", OpenShiftPath("example.com", "prod", "shop", OpenShiftRouteTLS, "api"))
+	assert.Equal(t, "example.com/prod/_openshift/_cluster/scc-bindings/anyuid.yaml", OpenShiftPath("example.com", "prod", "", OpenShiftSCCBindings, "anyuid"))
+}
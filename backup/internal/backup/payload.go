@@ -0,0 +1,70 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// payload is a cleaned resource that is serialized on demand. It is encoded
+// once up front to learn its size and checksum and again while uploading, so
+// large resources never sit in memory as YAML next to the decoded object.
+type payload struct {
+	object   map[string]interface{}
+	Size     int64
+	Checksum string
+}
+
+// newPayload measures the YAML encoding of object, failing if the object
+// cannot be serialized
+func newPayload(object map[string]interface{}) (*payload, error) {
+	hash := sha256.New()
+	size, err := encodeYAML(hash, object)
+	if err != nil {
+		return nil, err
+	}
+	return &payload{
+		object:   object,
+		Size:     size,
+		Checksum: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// WriteTo streams the YAML encoding of the resource to w
+func (p *payload) WriteTo(w io.Writer) (int64, error) {
+	return encodeYAML(w, p.object)
+}
+
+// Bytes returns the YAML encoding of the resource in full, for callers that
+// keep it in memory anyway
+func (p *payload) Bytes() ([]byte, error) {
+	return yaml.Marshal(p.object)
+}
+
+// encodeYAML writes the same bytes as yaml.Marshal without buffering the
+// whole document
+func encodeYAML(w io.Writer, object interface{}) (int64, error) {
+	counter := &countingWriter{w: w}
+	encoder := yaml.NewEncoder(counter)
+	if err := encoder.Encode(object); err != nil {
+		return counter.n, err
+	}
+	if err := encoder.Close(); err != nil {
+		return counter.n, err
+	}
+	return counter.n, nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,138 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func largeConfigMap() map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "large", "namespace": "shop"},
+		"data": map[string]interface{}{
+			"payload": strings.Repeat("0123456789abcdef", 64<<10),
+			"script":  "#!/bin/sh\necho 'multi-line'\n",
+		},
+	}
+}
+
+func TestNewPayload_MeasuresWrittenBytes(t *testing.T) {
+	object := largeConfigMap()
+	data, err := newPayload(object)
+	require.NoError(t, err)
+
+	var written bytes.Buffer
+	n, err := data.WriteTo(&written)
+	require.NoError(t, err)
+	assert.Equal(t, data.Size, n)
+	assert.Equal(t, data.Size, int64(written.Len()))
+	sum := sha256.Sum256(written.Bytes())
+	assert.Equal(t, hex.EncodeToString(sum[:]), data.Checksum)
+
+	marshaled, err := yaml.Marshal(object)
+	require.NoError(t, err)
+	assert.Equal(t, marshaled, written.Bytes(), "streaming writes what yaml.Marshal returns")
+	content, err := data.Bytes()
+	require.NoError(t, err)
+	assert.Equal(t, marshaled, content)
+}
+
+// unencodable fails to encode as YAML
+type unencodable struct{}
+
+func (unencodable) MarshalYAML() (interface{}, error) {
+	return nil, errors.New("cannot encode")
+}
+
+func TestNewPayload_UnserializableObject(t *testing.T) {
+	_, err := newPayload(map[string]interface{}{"data": unencodable{}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot encode")
+}
+
+// failingWriter accepts limit bytes and fails every write after them
+type failingWriter struct {
+	limit int
+}
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > f.limit {
+		n := f.limit
+		f.limit = 0
+		return n, errors.New("disk full")
+	}
+	f.limit -= len(p)
+	return len(p), nil
+}
+
+func TestPayload_WriteToFailingWriter(t *testing.T) {
+	data, err := newPayload(largeConfigMap())
+	require.NoError(t, err)
+
+	n, err := data.WriteTo(&failingWriter{limit: 100})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "disk full")
+	assert.Equal(t, int64(100), n)
+}
+
+// putWithin runs putPayload, failing the test if it does not return in time
+func putWithin(t *testing.T, client *minio.Client, key string, data *payload) error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := putPayload(context.Background(), client, "backups", key, data)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(10 * time.Second):
+		t.Fatal("putPayload did not return")
+		return nil
+	}
+}
+
+func TestPutPayload_UploadsWrittenBytes(t *testing.T) {
+	client, bucket := newLockTestClient(t)
+	data, err := newPayload(largeConfigMap())
+	require.NoError(t, err)
+
+	require.NoError(t, putWithin(t, client, "example.com/prod/shop/configmaps/large.yaml", data))
+	stored := bucket.objects["/backups/example.com/prod/shop/configmaps/large.yaml"]
+	assert.Equal(t, data.Size, int64(len(stored)))
+	sum := sha256.Sum256(stored)
+	assert.Equal(t, data.Checksum, hex.EncodeToString(sum[:]))
+}
+
+func TestPutPayload_EncoderFailureFailsUpload(t *testing.T) {
+	client, bucket := newLockTestClient(t)
+	// Measured before a field that cannot be encoded was added
+	data, err := newPayload(largeConfigMap())
+	require.NoError(t, err)
+	data.object["status"] = unencodable{}
+
+	err = putWithin(t, client, "example.com/prod/shop/configmaps/large.yaml", data)
+	require.Error(t, err, "the upload fails with the encoder rather than waiting for the rest of the object")
+	assert.Contains(t, err.Error(), "cannot encode")
+	assert.NotContains(t, bucket.objects, "/backups/example.com/prod/shop/configmaps/large.yaml")
+}
+
+func TestPutPayload_UploadFailureStopsEncoder(t *testing.T) {
+	data, err := newPayload(largeConfigMap())
+	require.NoError(t, err)
+
+	err = putWithin(t, newDeniedClient(t), "example.com/prod/shop/configmaps/large.yaml", data)
+	require.Error(t, err)
+	assert.Equal(t, "AccessDenied", minio.ToErrorResponse(err).Code)
+}
@@ -0,0 +1,32 @@
+package backup
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"shared-config/plugins"
+)
+
+// SetPlugins hands every listed resource to the plugins before it is cleaned
+// and stored; resources they filter out are skipped
+func (cb *ClusterBackup) SetPlugins(chain *plugins.Chain) {
+	cb.plugins = chain
+}
+
+// applyPlugins runs the plugins over a listed resource and returns the name
+// of the plugin that filtered it out, or "" when it is kept
+func (cb *ClusterBackup) applyPlugins(gvr schema.GroupVersionResource, item *unstructured.Unstructured) (string, error) {
+	dropped, err := cb.plugins.Apply(plugins.StageBackup, item)
+	if err != nil {
+		return "", err
+	}
+	if dropped != "" {
+		cb.logger.Debug("resource_plugin_filtered", "Resource filtered out by a plugin", map[string]interface{}{
+			"plugin":        dropped,
+			"namespace":     item.GetNamespace(),
+			"resource_type": gvr.Resource,
+			"resource_name": item.GetName(),
+		})
+	}
+	return dropped, nil
+}
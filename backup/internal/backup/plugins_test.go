@@ -0,0 +1,85 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"shared-config/plugins"
+
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/logging"
+	"cluster-backup/internal/resilience"
+)
+
+// siteRules keeps ConfigMaps named scratch-* out of backups and labels the
+// rest with their cost center
+type siteRules struct {
+	err error
+}
+
+func (s *siteRules) Name() string { return "site-rules" }
+
+func (s *siteRules) Filter(stage plugins.Stage, obj *unstructured.Unstructured) (bool, error) {
+	return obj.GetName() != "scratch-1", s.err
+}
+
+func (s *siteRules) Transform(stage plugins.Stage, obj *unstructured.Unstructured) error {
+	obj.SetLabels(map[string]string{"cost-center": "cc-1042", "stage": string(stage)})
+	return nil
+}
+
+func newPluginTestBackup(t *testing.T, rules *siteRules) *ClusterBackup {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	scratch, settings := configMap("scratch-1", false), configMap("settings", false)
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		gvr: "ConfigMapList",
+	}, &scratch, &settings)
+
+	chain, err := plugins.NewChain(rules)
+	require.NoError(t, err)
+	cb := &ClusterBackup{
+		config:            &config.Config{BatchSize: 100},
+		backupConfig:      &config.BackupConfig{},
+		dynamicClient:     client,
+		logger:            logging.NewStructuredLogger("plugin-test", "test-cluster"),
+		apiCircuitBreaker: resilience.NewCircuitBreaker(5, time.Minute),
+		retryExecutor:     resilience.NewRetryExecutor(resilience.RetryConfig{MaxAttempts: 1}),
+	}
+	cb.SetPlugins(chain)
+	return cb
+}
+
+func TestForEachResource_AppliesPlugins(t *testing.T) {
+	cb := newPluginTestBackup(t, &siteRules{})
+
+	stored := map[string]*payload{}
+	count, err := cb.forEachResource(context.Background(), "default", schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, func(name string, data *payload) error {
+		stored[name] = data
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	require.Contains(t, stored, "settings")
+	assert.NotContains(t, stored, "scratch-1", "resources a plugin filters out are not stored")
+
+	labels, _, _ := unstructured.NestedStringMap(stored["settings"].object, "metadata", "labels")
+	assert.Equal(t, map[string]string{"cost-center": "cc-1042", "stage": "backup"}, labels)
+}
+
+func TestForEachResource_PluginErrorFailsTheType(t *testing.T) {
+	cb := newPluginTestBackup(t, &siteRules{err: errors.New("cost center registry unavailable")})
+
+	_, err := cb.forEachResource(context.Background(), "default", schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, func(name string, data *payload) error {
+		return nil
+	})
+	assert.ErrorContains(t, err, "site-rules")
+}
@@ -0,0 +1,114 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/pprof"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// Files of a run's profiles under _diagnostics
+const (
+	CPUProfileFile  = "cpu.pprof"
+	HeapProfileFile = "heap.pprof"
+)
+
+// runProfile captures the CPU profile of a run in memory until it finishes
+type runProfile struct {
+	backupID string
+	cpu      bytes.Buffer
+	// profiling is set while the CPU profile runs
+	profiling bool
+}
+
+// startProfile starts profiling run backupID under PROFILE_BACKUP, or
+// returns nil when it is off. The CPU profile is left out when another one
+// runs already, e.g. one requested from /debug/pprof/profile.
+func (cb *ClusterBackup) startProfile(backupID string) *runProfile {
+	if !cb.config.ProfileBackup {
+		return nil
+	}
+	p := &runProfile{backupID: backupID}
+	if err := pprof.StartCPUProfile(&p.cpu); err != nil {
+		cb.logger.Warning("cpu_profile_unavailable", "Cannot profile the run's CPU usage, capturing the heap only", map[string]interface{}{
+			"backup_id": backupID,
+			"error":     err.Error(),
+		})
+		return p
+	}
+	p.profiling = true
+	return p
+}
+
+// stop ends the CPU profile; it may be called more than once
+func (p *runProfile) stop() {
+	if p == nil || !p.profiling {
+		return
+	}
+	pprof.StopCPUProfile()
+	p.profiling = false
+}
+
+// files stops the CPU profile and returns the profiles of the run by file
+// name, with the heap as of the end of the run
+func (p *runProfile) files() (map[string][]byte, error) {
+	p.stop()
+	files := make(map[string][]byte)
+	if p.cpu.Len() > 0 {
+		files[CPUProfileFile] = p.cpu.Bytes()
+	}
+
+	// Like /debug/pprof/heap?gc=1, so the profile is up to date
+	runtime.GC()
+	var heap bytes.Buffer
+	if err := pprof.Lookup("heap").WriteTo(&heap, 0); err != nil {
+		return files, fmt.Errorf("failed to write heap profile: %v", err)
+	}
+	files[HeapProfileFile] = heap.Bytes()
+	return files, nil
+}
+
+// SaveProfiles uploads the profiles of run backupID, by file name, under
+// _diagnostics and returns their keys
+func (ms *ManifestStore) SaveProfiles(ctx context.Context, backupID string, files map[string][]byte) ([]string, error) {
+	var keys []string
+	for _, name := range []string{CPUProfileFile, HeapProfileFile} {
+		data, ok := files[name]
+		if !ok {
+			continue
+		}
+		key := DiagnosticsPath(ms.clusterDomain, ms.clusterName, backupID, name)
+		err := ms.put(ctx, key, data, minio.PutObjectOptions{
+			ContentType:  "application/octet-stream",
+			UserMetadata: checksumMetadata(Checksum(data)),
+		})
+		if err != nil {
+			return keys, fmt.Errorf("failed to upload profile %s: %v", key, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// saveProfile uploads the profiles of a finished run and records their keys
+// in the manifest. Profiles that cannot be captured or uploaded are logged and
+// do not affect the run's status.
+func (cb *ClusterBackup) saveProfile(ctx context.Context, manifest *Manifest, p *runProfile) {
+	if p == nil {
+		return
+	}
+	files, captureErr := p.files()
+	keys, err := cb.manifestStore.SaveProfiles(ctx, p.backupID, files)
+	manifest.SetProfiles(keys)
+	for _, err := range []error{captureErr, err} {
+		if err != nil {
+			cb.logger.Warning("profile_upload_failed", "Failed to upload the backup profiles", map[string]interface{}{
+				"backup_id": manifest.BackupID,
+				"error":     err.Error(),
+			})
+		}
+	}
+}
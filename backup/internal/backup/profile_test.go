@@ -0,0 +1,38 @@
+package backup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/logging"
+)
+
+func TestRunProfile(t *testing.T) {
+	cb := &ClusterBackup{config: &config.Config{}, logger: logging.NewStructuredLogger("backup-test", "test-cluster")}
+	assert.Nil(t, cb.startProfile("backup-1"), "runs are not profiled without PROFILE_BACKUP")
+
+	cb.config.ProfileBackup = true
+	p := cb.startProfile("backup-1")
+	require.NotNil(t, p)
+	defer p.stop()
+	assert.False(t, cb.startProfile("backup-2").profiling, "a second CPU profile cannot run at once")
+
+	files, err := p.files()
+	require.NoError(t, err)
+	assert.NotEmpty(t, files[CPUProfileFile])
+	assert.NotEmpty(t, files[HeapProfileFile])
+}
+
+func TestDiagnosticsPath(t *testing.T) {
+	key := DiagnosticsPath("example.com", "prod", "backup-20250101-020000", HeapProfileFile)
+	assert.Equal(t, "example.com/prod/_diagnostics/backup-20250101-020000/heap.pprof", key)
+	assert.Empty(t, PathNamespace("example.com", "prod", key), "profiles belong to no namespace")
+
+	manifest := NewManifest("backup-20250101-020000", "prod", "example.com", "backups", time.Now())
+	manifest.SetProfiles([]string{key})
+	assert.Contains(t, manifest.StoredKeys()[""], key, "retention removes profiles with their run")
+}
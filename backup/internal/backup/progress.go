@@ -0,0 +1,197 @@
+package backup
+
+import (
+	"context"
+	"math"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// defaultProgressInterval applies when the configuration sets no interval
+const defaultProgressInterval = 30 * time.Second
+
+// Sources of RunProgress.ItemsExpected
+const (
+	EstimatePreviousRun = "previous_run"
+	EstimatePrecount    = "precount"
+)
+
+// RunProgress is how far a running backup got. Percent and ETASeconds are
+// zero while no estimate of the items to back up is known.
+type RunProgress struct {
+	ItemsDone       int     `json:"items_done"`
+	ItemsExpected   int     `json:"items_expected,omitempty"`
+	Percent         float64 `json:"percent,omitempty"`
+	ItemsPerSecond  float64 `json:"items_per_second"`
+	ETASeconds      float64 `json:"eta_seconds,omitempty"`
+	NamespacesDone  int     `json:"namespaces_done"`
+	NamespacesTotal int     `json:"namespaces_total"`
+	EstimateSource  string  `json:"estimate_source,omitempty"`
+}
+
+// progressTracker derives a run's progress from the objects and namespaces
+// its manifest recorded so far
+type progressTracker struct {
+	manifest        *Manifest
+	start           time.Time
+	expected        int
+	source          string
+	namespacesTotal int
+}
+
+// snapshot returns the run's progress at now. A finished run reports no ETA,
+// and 100 percent when it completed even if fewer items than expected existed.
+func (t *progressTracker) snapshot(now time.Time, finished, completed bool) RunProgress {
+	items, namespaces := t.manifest.Progress()
+	progress := RunProgress{
+		ItemsDone:       items,
+		ItemsExpected:   t.expected,
+		NamespacesDone:  namespaces,
+		NamespacesTotal: t.namespacesTotal,
+		EstimateSource:  t.source,
+	}
+
+	rate := 0.0
+	if elapsed := now.Sub(t.start).Seconds(); elapsed > 0 {
+		rate = float64(items) / elapsed
+		progress.ItemsPerSecond = math.Round(rate*100) / 100
+	}
+	if t.expected <= 0 {
+		return progress
+	}
+
+	progress.Percent = math.Min(100, math.Round(1000*float64(items)/float64(t.expected))/10)
+	if finished {
+		if completed {
+			progress.Percent = 100
+		}
+		return progress
+	}
+	if rate > 0 && items < t.expected {
+		progress.ETASeconds = math.Round(float64(t.expected-items) / rate)
+	}
+	return progress
+}
+
+// previousRunItems returns how many items the previous run stored for the
+// namespaces, and the cluster scope, this run backs up
+func previousRunItems(previous *Manifest, namespaces []string, clusterScope bool) int {
+	expected := 0
+	for _, namespace := range namespaces {
+		if entry, ok := previous.Namespaces[namespace]; ok {
+			expected += entry.ResourceCount
+		}
+	}
+	if clusterScope && previous.ClusterScope != nil {
+		expected += previous.ClusterScope.ResourceCount
+	}
+	return expected
+}
+
+// estimateItems returns how many items the run is expected to store and where
+// the figure comes from: the previous run, or else one list call per resource
+// type and namespace reading only the item count. It returns zero when
+// neither is available.
+func (cb *ClusterBackup) estimateItems(ctx context.Context, namespaces []string, apiResources []v1.APIResource) (int, string) {
+	if previous, err := cb.manifestStore.Latest(ctx); err == nil {
+		if expected := previousRunItems(previous, namespaces, cb.backupConfig.BackupClusterResources); expected > 0 {
+			return expected, EstimatePreviousRun
+		}
+	}
+	if !cb.config.ProgressPrecount {
+		return 0, ""
+	}
+
+	expected := 0
+	for _, namespace := range namespaces {
+		for _, resource := range apiResources {
+			expected += cb.countItems(ctx, namespace, resource)
+		}
+	}
+	if cb.backupConfig.BackupClusterResources {
+		clusterResources, err := cb.getClusterAPIResources()
+		if err == nil {
+			for _, resource := range clusterResources {
+				expected += cb.countItems(ctx, "", resource)
+			}
+		}
+	}
+	if ctx.Err() != nil || expected == 0 {
+		cb.logger.Warning("progress_estimate_unavailable", "Could not estimate the items to back up, reporting progress without percentage and ETA", map[string]interface{}{
+			"namespace_count": len(namespaces),
+		})
+		return 0, ""
+	}
+	return expected, EstimatePrecount
+}
+
+// countItems returns how many items of a resource type a namespace holds.
+// Failures count as zero; the estimate only drives progress reporting.
+func (cb *ClusterBackup) countItems(ctx context.Context, namespace string, resource v1.APIResource) int {
+	if ctx.Err() != nil {
+		return 0
+	}
+	sample, err := cb.sampleResource(ctx, namespace, resource, 1, false)
+	if err != nil {
+		return 0
+	}
+	return sample.Items
+}
+
+// reportRunProgress reports the run's progress every PROGRESS_INTERVAL until
+// the returned function is called, which reports the final figures
+func (cb *ClusterBackup) reportRunProgress(backupID string, tracker *progressTracker, progress ProgressFunc) func(completed bool) {
+	interval := cb.config.ProgressInterval
+	if interval <= 0 {
+		interval = defaultProgressInterval
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				cb.emitRunProgress(backupID, tracker.snapshot(now, false, false), progress)
+			}
+		}
+	}()
+
+	return func(completed bool) {
+		close(done)
+		<-stopped
+		cb.emitRunProgress(backupID, tracker.snapshot(time.Now(), true, completed), progress)
+	}
+}
+
+// emitRunProgress logs a progress snapshot, exports it as metrics and
+// delivers it to the progress callback
+func (cb *ClusterBackup) emitRunProgress(backupID string, snapshot RunProgress, progress ProgressFunc) {
+	cb.logger.Info("backup_progress", "Backup progress", map[string]interface{}{
+		"backup_id":        backupID,
+		"items_done":       snapshot.ItemsDone,
+		"items_expected":   snapshot.ItemsExpected,
+		"percent":          snapshot.Percent,
+		"items_per_second": snapshot.ItemsPerSecond,
+		"eta_seconds":      snapshot.ETASeconds,
+		"namespaces_done":  snapshot.NamespacesDone,
+		"namespaces_total": snapshot.NamespacesTotal,
+		"estimate_source":  snapshot.EstimateSource,
+	})
+
+	cb.metrics.ProgressRatio.Set(snapshot.Percent / 100)
+	cb.metrics.ItemsPerSecond.Set(snapshot.ItemsPerSecond)
+	cb.metrics.ETASeconds.Set(snapshot.ETASeconds)
+
+	reportProgress(progress, ProgressEvent{
+		Stage:     ProgressRunProgress,
+		ItemCount: snapshot.ItemsDone,
+		Run:       &snapshot,
+	})
+}
@@ -0,0 +1,59 @@
+package backup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressTracker_PercentRateAndETA(t *testing.T) {
+	start := time.Now()
+	manifest := NewManifest("backup-20250101-020000", "prod", "example.com", "backups", start)
+	for i := 0; i < 300; i++ {
+		manifest.AddObject(ObjectEntry{Namespace: "default", ResourceType: "configmaps"})
+	}
+	manifest.SetNamespace("default", 300, nil)
+	tracker := &progressTracker{manifest: manifest, start: start, expected: 1200, source: EstimatePreviousRun, namespacesTotal: 4}
+
+	progress := tracker.snapshot(start.Add(time.Minute), false, false)
+	assert.Equal(t, 300, progress.ItemsDone)
+	assert.Equal(t, 25.0, progress.Percent)
+	assert.Equal(t, 5.0, progress.ItemsPerSecond)
+	assert.Equal(t, 180.0, progress.ETASeconds)
+	assert.Equal(t, 1, progress.NamespacesDone)
+	assert.Equal(t, 4, progress.NamespacesTotal)
+
+	// A completed run that stored fewer items than expected is done
+	progress = tracker.snapshot(start.Add(time.Minute), true, true)
+	assert.Equal(t, 100.0, progress.Percent)
+	assert.Zero(t, progress.ETASeconds)
+	progress = tracker.snapshot(start.Add(time.Minute), true, false)
+	assert.Equal(t, 25.0, progress.Percent)
+	assert.Zero(t, progress.ETASeconds)
+
+	// More items than expected cap at 100 percent
+	tracker.expected = 200
+	progress = tracker.snapshot(start.Add(time.Minute), false, false)
+	assert.Equal(t, 100.0, progress.Percent)
+	assert.Zero(t, progress.ETASeconds)
+
+	// Without an estimate only the rate is known
+	tracker.expected, tracker.source = 0, ""
+	progress = tracker.snapshot(start.Add(time.Minute), false, false)
+	assert.Zero(t, progress.Percent)
+	assert.Zero(t, progress.ETASeconds)
+	assert.Equal(t, 5.0, progress.ItemsPerSecond)
+}
+
+func TestPreviousRunItems(t *testing.T) {
+	previous := NewManifest("backup-20241231-020000", "prod", "example.com", "backups", time.Now())
+	previous.SetNamespace("default", 120, nil)
+	previous.SetNamespace("payments", 80, nil)
+	previous.SetNamespace("retired", 500, nil)
+	previous.SetClusterScope(40, nil)
+
+	// Namespaces new since the previous run add nothing
+	assert.Equal(t, 200, previousRunItems(previous, []string{"default", "payments", "web"}, false))
+	assert.Equal(t, 240, previousRunItems(previous, []string{"default", "payments", "web"}, true))
+}
@@ -0,0 +1,144 @@
+package backup
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"cluster-backup/internal/config"
+)
+
+// Annotations, and with BACKUP_PROVENANCE_LABELS labels, recording in a
+// stored copy the run that stored it
+const (
+	ProvenanceBackupIDKey  = "cluster-backup.io/backup-id"
+	ProvenanceClusterKey   = "cluster-backup.io/backup-cluster"
+	ProvenanceTimestampKey = "cluster-backup.io/backup-timestamp"
+)
+
+// provenanceKeys are the annotation of each provenance field
+var provenanceKeys = map[string]string{
+	config.ProvenanceBackupID:  ProvenanceBackupIDKey,
+	config.ProvenanceCluster:   ProvenanceClusterKey,
+	config.ProvenanceTimestamp: ProvenanceTimestampKey,
+}
+
+// provenance is what a run adds to the stored copies of the types its
+// policy selects
+type provenance struct {
+	values map[string]string
+	// fields are the fields of each resource.group, resource and *
+	fields map[string][]string
+	labels bool
+}
+
+type provenanceKey struct{}
+
+// withProvenance returns a context the resources of a run are stored with
+// provenance through
+func withProvenance(ctx context.Context, p *provenance) context.Context {
+	return context.WithValue(ctx, provenanceKey{}, p)
+}
+
+// provenanceFrom returns the provenance of the run ctx belongs to, nil when
+// none is added
+func provenanceFrom(ctx context.Context) *provenance {
+	p, _ := ctx.Value(provenanceKey{}).(*provenance)
+	return p
+}
+
+// newProvenance returns the provenance of the run of manifest under the
+// BACKUP_PROVENANCE policy, or nil when the policy selects nothing
+func (cb *ClusterBackup) newProvenance(manifest *Manifest) *provenance {
+	if len(cb.backupConfig.Provenance) == 0 {
+		return nil
+	}
+	p := &provenance{
+		values: map[string]string{
+			config.ProvenanceBackupID:  manifest.BackupID,
+			config.ProvenanceCluster:   manifest.ClusterName,
+			config.ProvenanceTimestamp: manifest.StartTime.UTC().Format(time.RFC3339),
+		},
+		fields: make(map[string][]string),
+		labels: cb.backupConfig.ProvenanceLabels,
+	}
+	for _, entry := range cb.backupConfig.Provenance {
+		// Entries were validated with the configuration
+		resource, fields, err := config.ParseProvenance(entry)
+		if err == nil {
+			p.fields[resource] = fields
+		}
+	}
+	return p
+}
+
+// fieldsOf returns the fields added to resources of gvr: those of its
+// resource.group entry, else its resource entry, else *
+func (p *provenance) fieldsOf(gvr schema.GroupVersionResource) []string {
+	for _, key := range []string{gvr.GroupResource().String(), gvr.Resource, "*"} {
+		if fields, ok := p.fields[key]; ok {
+			return fields
+		}
+	}
+	return nil
+}
+
+// apply returns a payload of data's resource with the provenance of its type
+// added, and data's checksum as the checksum of the resource's content, or
+// data and "" when nothing is added. The annotations and labels maps are
+// copied, so the listed object is left as it was.
+func (p *provenance) apply(gvr schema.GroupVersionResource, data *payload) (*payload, string, error) {
+	if p == nil {
+		return data, "", nil
+	}
+	fields := p.fieldsOf(gvr)
+	if len(fields) == 0 {
+		return data, "", nil
+	}
+
+	object := make(map[string]interface{}, len(data.object))
+	for k, v := range data.object {
+		object[k] = v
+	}
+	metadata := copyObjectMap(data.object["metadata"])
+	object["metadata"] = metadata
+
+	annotations := copyObjectMap(metadata["annotations"])
+	for _, field := range fields {
+		annotations[provenanceKeys[field]] = p.values[field]
+	}
+	metadata["annotations"] = annotations
+
+	if p.labels {
+		labels := copyObjectMap(metadata["labels"])
+		for _, field := range fields {
+			// Timestamps, and IDs too long for a label, stay annotations
+			value := p.values[field]
+			if field != config.ProvenanceTimestamp && len(validation.IsValidLabelValue(value)) == 0 {
+				labels[provenanceKeys[field]] = value
+			}
+		}
+		if len(labels) > 0 {
+			metadata["labels"] = labels
+		}
+	}
+
+	annotated, err := newPayload(object)
+	if err != nil {
+		return nil, "", err
+	}
+	return annotated, data.Checksum, nil
+}
+
+// copyObjectMap returns a shallow copy of value when it is a map, and an
+// empty map otherwise
+func copyObjectMap(value interface{}) map[string]interface{} {
+	original, _ := value.(map[string]interface{})
+	copied := make(map[string]interface{}, len(original)+3)
+	for k, v := range original {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,78 @@
+package backup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"cluster-backup/internal/config"
+)
+
+func TestProvenance_Apply(t *testing.T) {
+	cb := &ClusterBackup{backupConfig: &config.BackupConfig{
+		Provenance:       []string{"*", "secrets=", "deployments.apps=backup-id+timestamp"},
+		ProvenanceLabels: true,
+	}}
+	manifest := NewManifest("backup-20250101-020000", "prod", "example.com", "backups", time.Date(2025, 1, 1, 2, 0, 0, 0, time.UTC))
+	p := cb.newProvenance(manifest)
+	require.NotNil(t, p)
+
+	listed := map[string]interface{}{
+		"kind": "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":        "settings",
+			"annotations": map[string]interface{}{"team": "shop"},
+		},
+	}
+	data, err := newPayload(listed)
+	require.NoError(t, err)
+
+	annotated, contentChecksum, err := p.apply(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, data)
+	require.NoError(t, err)
+	assert.Equal(t, data.Checksum, contentChecksum)
+	assert.NotEqual(t, data.Checksum, annotated.Checksum)
+	metadata := annotated.object["metadata"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"team":                 "shop",
+		ProvenanceBackupIDKey:  "backup-20250101-020000",
+		ProvenanceClusterKey:   "prod",
+		ProvenanceTimestampKey: "2025-01-01T02:00:00Z",
+	}, metadata["annotations"])
+	assert.Equal(t, map[string]interface{}{
+		ProvenanceBackupIDKey: "backup-20250101-020000",
+		ProvenanceClusterKey:  "prod",
+	}, metadata["labels"], "timestamps are not valid label values")
+	assert.Equal(t, map[string]interface{}{"team": "shop"}, listed["metadata"].(map[string]interface{})["annotations"], "the listed object is left as it was")
+	assert.NotContains(t, listed["metadata"], "labels")
+
+	annotated, _, err = p.apply(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, data)
+	require.NoError(t, err)
+	assert.NotContains(t, annotated.object["metadata"].(map[string]interface{})["annotations"], ProvenanceClusterKey)
+
+	unchanged, contentChecksum, err := p.apply(schema.GroupVersionResource{Version: "v1", Resource: "secrets"}, data)
+	require.NoError(t, err)
+	assert.Same(t, data, unchanged)
+	assert.Empty(t, contentChecksum)
+}
+
+func TestProvenance_Disabled(t *testing.T) {
+	cb := &ClusterBackup{backupConfig: &config.BackupConfig{}}
+	manifest := NewManifest("backup-20250101-020000", "prod", "example.com", "backups", time.Now())
+	assert.Nil(t, cb.newProvenance(manifest))
+
+	data, err := newPayload(map[string]interface{}{"kind": "ConfigMap"})
+	require.NoError(t, err)
+	unchanged, contentChecksum, err := provenanceFrom(context.Background()).apply(schema.GroupVersionResource{Resource: "configmaps"}, data)
+	require.NoError(t, err)
+	assert.Same(t, data, unchanged)
+	assert.Empty(t, contentChecksum)
+}
+
+func TestObjectEntry_ResourceChecksum(t *testing.T) {
+	assert.Equal(t, "stored", ObjectEntry{Checksum: "stored"}.ResourceChecksum())
+	assert.Equal(t, "content", ObjectEntry{Checksum: "stored", ContentChecksum: "content"}.ResourceChecksum())
+}
@@ -0,0 +1,269 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Permission is one access a backup run needs. An empty Namespace asks for
+// it in every namespace; Name limits it to a single object.
+type Permission struct {
+	Group     string `json:"group,omitempty"`
+	Resource  string `json:"resource"`
+	Verb      string `json:"verb"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+}
+
+func (p Permission) String() string {
+	resource := p.Resource
+	if p.Group != "" {
+		resource += "." + p.Group
+	}
+	if p.Name != "" {
+		resource += "/" + p.Name
+	}
+	if p.Namespace != "" {
+		return fmt.Sprintf("%s %s in %s", p.Verb, resource, p.Namespace)
+	}
+	return fmt.Sprintf("%s %s", p.Verb, resource)
+}
+
+// PermissionCheck is the API server's answer for one permission
+type PermissionCheck struct {
+	Permission
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// PermissionReport lists the permissions a backup run with the current
+// configuration needs and whether the caller has them
+type PermissionReport struct {
+	Checks []PermissionCheck `json:"checks"`
+}
+
+// Missing returns the checks that were denied
+func (r *PermissionReport) Missing() []PermissionCheck {
+	var missing []PermissionCheck
+	for _, check := range r.Checks {
+		if !check.Allowed {
+			missing = append(missing, check)
+		}
+	}
+	return missing
+}
+
+// CheckPermissions asks the API server, through SelfSubjectAccessReviews,
+// whether the caller may do what a backup run with the current configuration
+// does: list namespaces, CRDs and every included resource type in all
+// namespaces, and get or create the priority ConfigMap. When OpenShift
+// capture or window capture is on, the reads they make are checked as well,
+// and with synthetic validation the restores it makes.
+func (cb *ClusterBackup) CheckPermissions(ctx context.Context, priorityConfigMap, priorityNamespace string) (*PermissionReport, error) {
+	apiResources, err := cb.getAPIResources()
+	if err != nil {
+		return nil, fmt.Errorf("API resource discovery failed: %v", err)
+	}
+	var clusterResources []v1.APIResource
+	if cb.backupConfig.BackupClusterResources {
+		clusterResources, err = cb.getClusterAPIResources()
+		if err != nil {
+			return nil, fmt.Errorf("API resource discovery failed: %v", err)
+		}
+	}
+
+	required := requiredPermissions(append(apiResources, clusterResources...), priorityConfigMap, priorityNamespace)
+	if cb.openShiftEnabled() {
+		required = uniquePermissions(append(required, openShiftPermissions()...))
+	}
+	if cb.config.ContinuousBackup {
+		required = uniquePermissions(append(required, watchPermissions(append(apiResources, clusterResources...))...))
+	} else if cb.config.WindowCapture {
+		required = append(required, watchPermissions(apiResources)...)
+	}
+	if cb.config.SyntheticValidationEnabled {
+		required = uniquePermissions(append(required, syntheticValidationPermissions(apiResources, cb.config.SyntheticValidationResources)...))
+	}
+	return cb.checkPermissions(ctx, required)
+}
+
+// requiredPermissions returns the permissions to list the given resource
+// types and to read and create the priority ConfigMap
+func requiredPermissions(resources []v1.APIResource, priorityConfigMap, priorityNamespace string) []Permission {
+	required := []Permission{
+		{Resource: "namespaces", Verb: "list"},
+		{Group: crdGVR.Group, Resource: crdGVR.Resource, Verb: "list"},
+	}
+	for _, resource := range resources {
+		required = append(required, Permission{Group: resource.Group, Resource: resource.Name, Verb: "list"})
+	}
+	if priorityConfigMap != "" {
+		required = append(required,
+			Permission{Resource: "configmaps", Verb: "get", Namespace: priorityNamespace, Name: priorityConfigMap},
+			Permission{Resource: "configmaps", Verb: "create", Namespace: priorityNamespace},
+		)
+	}
+
+	// Discovery can report a type twice, e.g. events in core and events.k8s.io
+	return uniquePermissions(required)
+}
+
+// watchPermissions returns the watches window capture and continuous backup
+// start on the resource types that can be watched
+func watchPermissions(resources []v1.APIResource) []Permission {
+	var required []Permission
+	for _, resource := range resources {
+		if containsVerb(resource.Verbs, "watch") {
+			required = append(required, Permission{Group: resource.Group, Resource: resource.Name, Verb: "watch"})
+		}
+	}
+	return uniquePermissions(required)
+}
+
+// syntheticValidationPermissions returns what restoring the validated types
+// of resources into a validation namespace and deleting it again takes
+func syntheticValidationPermissions(resources []v1.APIResource, validated []string) []Permission {
+	types := make(map[string]bool, len(validated))
+	for _, resource := range validated {
+		types[resource] = true
+	}
+	required := []Permission{
+		{Resource: "namespaces", Verb: "create"},
+		{Resource: "namespaces", Verb: "delete"},
+	}
+	for _, resource := range resources {
+		if types[resource.Name] || (resource.Group != "" && types[resource.Name+"."+resource.Group]) {
+			required = append(required,
+				Permission{Group: resource.Group, Resource: resource.Name, Verb: "create"},
+				Permission{Group: resource.Group, Resource: resource.Name, Verb: "get"},
+			)
+		}
+	}
+	return uniquePermissions(required)
+}
+
+// openShiftPermissions returns the reads the OpenShift capture makes
+// beyond listing the included resource types
+func openShiftPermissions() []Permission {
+	return []Permission{
+		{Group: projectGVR.Group, Resource: projectGVR.Resource, Verb: "get"},
+		{Group: imageStreamGVR.Group, Resource: imageStreamGVR.Resource, Verb: "list"},
+		{Group: routeGVR.Group, Resource: routeGVR.Resource, Verb: "list"},
+		{Group: sccGVR.Group, Resource: sccGVR.Resource, Verb: "list"},
+		{Group: "rbac.authorization.k8s.io", Resource: "clusterrolebindings", Verb: "list"},
+		{Group: "rbac.authorization.k8s.io", Resource: "rolebindings", Verb: "list"},
+		{Resource: secretGVR.Resource, Verb: "get"},
+	}
+}
+
+// uniquePermissions drops repeated permissions, keeping the first occurrence
+func uniquePermissions(required []Permission) []Permission {
+	seen := make(map[Permission]bool)
+	unique := required[:0]
+	for _, permission := range required {
+		if !seen[permission] {
+			seen[permission] = true
+			unique = append(unique, permission)
+		}
+	}
+	return unique
+}
+
+// checkPermissions reviews each permission with the API server
+func (cb *ClusterBackup) checkPermissions(ctx context.Context, required []Permission) (*PermissionReport, error) {
+	report := &PermissionReport{Checks: make([]PermissionCheck, 0, len(required))}
+	for _, permission := range required {
+		review, err := cb.kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: permission.Namespace,
+					Verb:      permission.Verb,
+					Group:     permission.Group,
+					Resource:  permission.Resource,
+					Name:      permission.Name,
+				},
+			},
+		}, v1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to review %s: %v", permission, err)
+		}
+
+		reason := review.Status.Reason
+		if review.Status.EvaluationError != "" && reason == "" {
+			reason = review.Status.EvaluationError
+		}
+		report.Checks = append(report.Checks, PermissionCheck{
+			Permission: permission,
+			Allowed:    review.Status.Allowed,
+			Reason:     reason,
+		})
+	}
+	return report, nil
+}
+
+// ClusterRole returns the YAML of a ClusterRole granting every permission in
+// the report, allowed or not, and nothing more
+func (r *PermissionReport) ClusterRole(name string) ([]byte, error) {
+	role := &rbacv1.ClusterRole{
+		TypeMeta:   v1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+		ObjectMeta: v1.ObjectMeta{Name: name},
+		Rules:      r.Rules(),
+	}
+
+	// Through the unstructured form so the YAML uses the API field names
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert ClusterRole: %v", err)
+	}
+	delete(object["metadata"].(map[string]interface{}), "creationTimestamp")
+	return yaml.Marshal(object)
+}
+
+// Rules returns the rules of a role granting every permission in the report,
+// one per resource type or named object, sorted by group and resource
+func (r *PermissionReport) Rules() []rbacv1.PolicyRule {
+	type ruleKey struct {
+		group    string
+		resource string
+		name     string
+	}
+	verbs := make(map[ruleKey][]string)
+	var keys []ruleKey
+	for _, check := range r.Checks {
+		key := ruleKey{group: check.Group, resource: check.Resource, name: check.Name}
+		if _, ok := verbs[key]; !ok {
+			keys = append(keys, key)
+		}
+		verbs[key] = append(verbs[key], check.Verb)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].group != keys[j].group {
+			return keys[i].group < keys[j].group
+		}
+		if keys[i].resource != keys[j].resource {
+			return keys[i].resource < keys[j].resource
+		}
+		return keys[i].name < keys[j].name
+	})
+
+	rules := make([]rbacv1.PolicyRule, 0, len(keys))
+	for _, key := range keys {
+		rule := rbacv1.PolicyRule{
+			APIGroups: []string{key.group},
+			Resources: []string{key.resource},
+			Verbs:     verbs[key],
+		}
+		if key.name != "" {
+			rule.ResourceNames = []string{key.name}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
@@ -0,0 +1,131 @@
+package backup
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestRequiredPermissions(t *testing.T) {
+	required := requiredPermissions([]v1.APIResource{
+		{Version: "v1", Name: "configmaps"},
+		{Group: "apps", Version: "v1", Name: "deployments"},
+		{Group: "apps", Version: "v1", Name: "deployments"},
+	}, "backup-priority-config", "default")
+
+	assert.Equal(t, []Permission{
+		{Resource: "namespaces", Verb: "list"},
+		{Group: "apiextensions.k8s.io", Resource: "customresourcedefinitions", Verb: "list"},
+		{Resource: "configmaps", Verb: "list"},
+		{Group: "apps", Resource: "deployments", Verb: "list"},
+		{Resource: "configmaps", Verb: "get", Namespace: "default", Name: "backup-priority-config"},
+		{Resource: "configmaps", Verb: "create", Namespace: "default"},
+	}, required)
+}
+
+func TestSyntheticValidationPermissions(t *testing.T) {
+	assert.Equal(t, []Permission{
+		{Resource: "namespaces", Verb: "create"},
+		{Resource: "namespaces", Verb: "delete"},
+		{Resource: "configmaps", Verb: "create"},
+		{Resource: "configmaps", Verb: "get"},
+		{Group: "rbac.authorization.k8s.io", Resource: "roles", Verb: "create"},
+		{Group: "rbac.authorization.k8s.io", Resource: "roles", Verb: "get"},
+	}, syntheticValidationPermissions([]v1.APIResource{
+		{Version: "v1", Name: "configmaps"},
+		{Group: "rbac.authorization.k8s.io", Version: "v1", Name: "roles"},
+		{Group: "apps", Version: "v1", Name: "deployments"},
+	}, []string{"configmaps", "roles.rbac.authorization.k8s.io"}))
+}
+
+func TestWatchPermissions(t *testing.T) {
+	assert.Equal(t, []Permission{
+		{Resource: "configmaps", Verb: "watch"},
+	}, watchPermissions([]v1.APIResource{
+		{Version: "v1", Name: "configmaps", Verbs: []string{"list", "watch"}},
+		{Group: "metrics.k8s.io", Version: "v1beta1", Name: "pods", Verbs: []string{"list"}},
+	}))
+}
+
+func TestCheckPermissions_ReportsDenied(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	var reviewed []authorizationv1.ResourceAttributes
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		attributes := review.Spec.ResourceAttributes
+		reviewed = append(reviewed, *attributes)
+		if attributes.Verb == "create" {
+			review.Status.Reason = "no RBAC policy matched"
+			return true, review, nil
+		}
+		review.Status.Allowed = true
+		return true, review, nil
+	})
+	cb := &ClusterBackup{kubeClient: client}
+
+	report, err := cb.checkPermissions(context.Background(), requiredPermissions([]v1.APIResource{
+		{Version: "v1", Name: "configmaps"},
+	}, "backup-priority-config", "default"))
+	require.NoError(t, err)
+
+	assert.Len(t, reviewed, 5)
+	assert.Equal(t, authorizationv1.ResourceAttributes{Namespace: "default", Verb: "get", Resource: "configmaps", Name: "backup-priority-config"}, reviewed[3])
+	assert.Equal(t, []PermissionCheck{{
+		Permission: Permission{Resource: "configmaps", Verb: "create", Namespace: "default"},
+		Reason:     "no RBAC policy matched",
+	}}, report.Missing())
+	assert.Equal(t, "create configmaps in default", report.Missing()[0].String())
+}
+
+func TestPermissionReport_ClusterRole(t *testing.T) {
+	report := &PermissionReport{Checks: []PermissionCheck{
+		{Permission: Permission{Resource: "namespaces", Verb: "list"}, Allowed: true},
+		{Permission: Permission{Group: "apps", Resource: "deployments", Verb: "list"}},
+		{Permission: Permission{Resource: "configmaps", Verb: "list"}, Allowed: true},
+		{Permission: Permission{Resource: "configmaps", Verb: "get", Namespace: "default", Name: "backup-priority-config"}, Allowed: true},
+		{Permission: Permission{Resource: "configmaps", Verb: "create", Namespace: "default"}},
+	}}
+
+	role, err := report.ClusterRole("cluster-backup")
+	require.NoError(t, err)
+	assert.Equal(t, `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+    name: cluster-backup
+rules:
+    - apiGroups:
+        - ""
+      resources:
+        - configmaps
+      verbs:
+        - list
+        - create
+    - apiGroups:
+        - ""
+      resourceNames:
+        - backup-priority-config
+      resources:
+        - configmaps
+      verbs:
+        - get
+    - apiGroups:
+        - ""
+      resources:
+        - namespaces
+      verbs:
+        - list
+    - apiGroups:
+        - apps
+      resources:
+        - deployments
+      verbs:
+        - list
+`, string(role))
+}
@@ -0,0 +1,183 @@
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/filter"
+	"cluster-backup/internal/logging"
+	"cluster-backup/internal/metrics"
+)
+
+// ConfigLoader reads the configuration afresh, validating it
+type ConfigLoader func() (*config.Config, *config.BackupConfig, error)
+
+// reloadedConfig is a configuration waiting for the runs using the current
+// one to finish
+type reloadedConfig struct {
+	mu           sync.Mutex
+	config       *config.Config
+	backupConfig *config.BackupConfig
+}
+
+// Reconfigure sets the configuration of the next runs. Runs in progress
+// finish with the configuration they started with.
+func (cb *ClusterBackup) Reconfigure(cfg *config.Config, backupCfg *config.BackupConfig) {
+	cb.reloaded.mu.Lock()
+	cb.reloaded.config, cb.reloaded.backupConfig = cfg, backupCfg
+	cb.reloaded.mu.Unlock()
+	cb.applyReloadedConfig()
+}
+
+// applyReloadedConfig swaps in a reloaded configuration unless a run is
+// using the current one, in which case the next run to start applies it
+func (cb *ClusterBackup) applyReloadedConfig() {
+	if !cb.configMu.TryLock() {
+		return
+	}
+	defer cb.configMu.Unlock()
+
+	cb.reloaded.mu.Lock()
+	defer cb.reloaded.mu.Unlock()
+	if cb.reloaded.config == nil {
+		return
+	}
+	cb.config, cb.backupConfig = cb.reloaded.config, cb.reloaded.backupConfig
+	cb.reloaded.config, cb.reloaded.backupConfig = nil, nil
+	// The engine is built from the filters on first use
+	cb.filterEngine = nil
+}
+
+// nextConfig returns the configuration the next run will use
+func (cb *ClusterBackup) nextConfig() (*config.Config, *config.BackupConfig) {
+	cb.reloaded.mu.Lock()
+	defer cb.reloaded.mu.Unlock()
+	if cb.reloaded.config != nil {
+		return cb.reloaded.config, cb.reloaded.backupConfig
+	}
+	return cb.config, cb.backupConfig
+}
+
+// ConfigReloader watches the shared configuration file while the backup runs
+// as a service, and applies a changed configuration that is valid to the
+// next runs
+type ConfigReloader struct {
+	cb      *ClusterBackup
+	path    string
+	load    ConfigLoader
+	logger  *logging.StructuredLogger
+	metrics *metrics.BackupMetrics
+
+	mu       sync.Mutex
+	fileHash [sha256.Size]byte
+	version  string
+}
+
+// NewConfigReloader returns a reloader of the configuration file at path,
+// which load reads along with the environment
+func NewConfigReloader(cb *ClusterBackup, path string, load ConfigLoader) *ConfigReloader {
+	r := &ConfigReloader{cb: cb, path: path, load: load, logger: cb.logger, metrics: cb.metrics}
+	if data, err := os.ReadFile(path); err == nil {
+		r.fileHash = sha256.Sum256(data)
+	}
+	r.setVersion(ConfigVersion(config.Snapshot(cb.nextConfig())))
+	return r
+}
+
+// Run checks the file every interval until the context is cancelled
+func (r *ConfigReloader) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Check()
+		}
+	}
+}
+
+// Check reloads the configuration when the file changed and reports whether
+// the next runs use a new one. A configuration that fails validation is
+// rejected and the current one kept; settings that only take effect at
+// startup keep their current values until a restart.
+func (r *ConfigReloader) Check() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		r.logger.Warning("config_reload_failed", "Failed to read the configuration file", map[string]interface{}{
+			"file":  r.path,
+			"error": err.Error(),
+		})
+		return false
+	}
+	hash := sha256.Sum256(data)
+	if hash == r.fileHash {
+		return false
+	}
+	r.fileHash = hash
+
+	cfg, backupCfg, err := r.load()
+	if err != nil {
+		r.metrics.ConfigReloads.WithLabelValues("rejected").Inc()
+		r.logger.Error("config_reload_rejected", "Changed configuration is invalid, keeping the current one", map[string]interface{}{
+			"file":           r.path,
+			"config_version": r.version,
+			"error":          err.Error(),
+		})
+		return false
+	}
+
+	current, currentBackup := r.cb.nextConfig()
+	if kept := config.KeepStartupSettings(current, cfg); len(kept) > 0 {
+		r.logger.Warning("config_reload_restart_required", "Changed settings take effect after a restart", map[string]interface{}{
+			"file":     r.path,
+			"settings": strings.Join(kept, ","),
+		})
+	}
+	previous := config.Snapshot(current, currentBackup)
+	snapshot := config.Snapshot(cfg, backupCfg)
+	version := ConfigVersion(snapshot)
+	if version == r.version {
+		return false
+	}
+
+	// As at startup, lists the filtering mode ignores are not fatal
+	filterWarnings, _ := filter.Validate(backupCfg.FilterRules())
+	for _, warning := range filterWarnings {
+		r.logger.Warning("filter_config_warning", warning, map[string]interface{}{
+			"filtering_mode": backupCfg.FilteringMode,
+		})
+	}
+
+	r.cb.Reconfigure(cfg, backupCfg)
+	r.metrics.ConfigReloads.WithLabelValues("applied").Inc()
+	changes := DiffConfig(previous, snapshot)
+	settings := make([]string, len(changes))
+	for i, change := range changes {
+		settings[i] = change.Setting
+	}
+	r.logger.Info("config_reloaded", "Reloaded the configuration for the next runs", map[string]interface{}{
+		"file":             r.path,
+		"config_version":   version,
+		"previous_version": r.version,
+		"changed_settings": strings.Join(settings, ","),
+	})
+	r.setVersion(version)
+	return true
+}
+
+// setVersion exports the version of the configuration the next run uses
+func (r *ConfigReloader) setVersion(version string) {
+	r.metrics.ConfigInfo.Reset()
+	r.metrics.ConfigInfo.WithLabelValues(version).Set(1)
+	r.version = version
+}
@@ -0,0 +1,116 @@
+package backup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/logging"
+	"cluster-backup/internal/metrics"
+)
+
+// newReloadTest returns a backup whose configuration file holds
+// "bucket batch-size namespaces", and a loader reading it
+func newReloadTest(t *testing.T) (*ClusterBackup, string, ConfigLoader) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("backups 50 shop"), 0o600))
+
+	load := func() (*config.Config, *config.BackupConfig, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		fields := strings.Fields(string(data))
+		batchSize, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, nil, errors.New("BATCH_SIZE must be a number")
+		}
+		return &config.Config{MinIOBucket: fields[0], BatchSize: batchSize},
+			&config.BackupConfig{FilteringMode: "whitelist", IncludeNamespaces: strings.Split(fields[2], ",")}, nil
+	}
+	cfg, backupCfg, err := load()
+	require.NoError(t, err)
+
+	cb := &ClusterBackup{
+		config:       cfg,
+		backupConfig: backupCfg,
+		logger:       logging.NewStructuredLogger("reload-test", "prod"),
+		metrics: &metrics.BackupMetrics{
+			ConfigInfo:    prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_config_info"}, []string{"version"}),
+			ConfigReloads: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_config_reloads_total"}, []string{"result"}),
+		},
+	}
+	return cb, path, load
+}
+
+func TestConfigReloader_AppliesChanges(t *testing.T) {
+	cb, path, load := newReloadTest(t)
+	reloader := NewConfigReloader(cb, path, load)
+	initial := reloader.version
+	assert.Equal(t, 1.0, testutil.ToFloat64(cb.metrics.ConfigInfo.WithLabelValues(initial)))
+
+	assert.False(t, reloader.Check(), "the file has not changed")
+	assert.False(t, cb.filters().IncludeNamespace("billing"))
+
+	require.NoError(t, os.WriteFile(path, []byte("backups 200 shop,billing"), 0o600))
+	assert.True(t, reloader.Check())
+
+	assert.Equal(t, 200, cb.config.BatchSize)
+	assert.Equal(t, []string{"shop", "billing"}, cb.backupConfig.IncludeNamespaces)
+	assert.True(t, cb.filters().IncludeNamespace("billing"), "the filters are rebuilt")
+	assert.NotEqual(t, initial, reloader.version)
+	assert.Equal(t, 1, testutil.CollectAndCount(cb.metrics.ConfigInfo), "only the version in use is exported")
+	assert.Equal(t, 1.0, testutil.ToFloat64(cb.metrics.ConfigInfo.WithLabelValues(reloader.version)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(cb.metrics.ConfigReloads.WithLabelValues("applied")))
+}
+
+func TestConfigReloader_RejectsInvalidConfig(t *testing.T) {
+	cb, path, load := newReloadTest(t)
+	reloader := NewConfigReloader(cb, path, load)
+	current := cb.config
+
+	require.NoError(t, os.WriteFile(path, []byte("backups many shop"), 0o600))
+	assert.False(t, reloader.Check())
+
+	assert.Same(t, current, cb.config)
+	assert.Equal(t, 1.0, testutil.ToFloat64(cb.metrics.ConfigReloads.WithLabelValues("rejected")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(cb.metrics.ConfigReloads.WithLabelValues("applied")))
+}
+
+func TestConfigReloader_KeepsStartupSettings(t *testing.T) {
+	cb, path, load := newReloadTest(t)
+	reloader := NewConfigReloader(cb, path, load)
+
+	require.NoError(t, os.WriteFile(path, []byte("archive 50 shop"), 0o600))
+	assert.False(t, reloader.Check(), "nothing the next run could apply changed")
+	assert.Equal(t, "backups", cb.config.MinIOBucket)
+
+	require.NoError(t, os.WriteFile(path, []byte("archive 100 shop"), 0o600))
+	assert.True(t, reloader.Check())
+	assert.Equal(t, "backups", cb.config.MinIOBucket, "the clients were set up with the old bucket")
+	assert.Equal(t, 100, cb.config.BatchSize)
+}
+
+func TestReconfigure_WaitsForRunningBackup(t *testing.T) {
+	cb, _, _ := newReloadTest(t)
+	current := cb.config
+
+	cb.configMu.RLock()
+	cb.Reconfigure(&config.Config{BatchSize: 10}, &config.BackupConfig{})
+	assert.Same(t, current, cb.config, "the running backup keeps its configuration")
+	next, _ := cb.nextConfig()
+	assert.Equal(t, 10, next.BatchSize)
+	cb.configMu.RUnlock()
+
+	cb.applyReloadedConfig()
+	assert.Equal(t, 10, cb.config.BatchSize, "the next run applies it")
+}
@@ -0,0 +1,252 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/logging"
+	"cluster-backup/internal/metrics"
+)
+
+// replicationCheckTimeout bounds the check made before each run, so a slow
+// replica region does not hold the run back
+const replicationCheckTimeout = 2 * time.Minute
+
+// replicationSource is the part of the manifest store the replica is
+// checked against
+type replicationSource interface {
+	List(ctx context.Context) ([]ManifestSummary, error)
+	GetObject(ctx context.Context, key string) ([]byte, error)
+}
+
+// ReplicationStatus is the state of the latest finished run in the replica
+// bucket
+type ReplicationStatus struct {
+	BackupID string
+	// Replicated reports whether the manifest and every sampled object were
+	// found in the replica bucket with matching checksums
+	Replicated bool
+	// Lag is how long ago the run finished while it is not yet replicated,
+	// zero once it is
+	Lag time.Duration
+	// Sampled is the number of objects read back besides the manifest
+	Sampled int
+	// Missing lists the keys not in the replica bucket yet
+	Missing  []string
+	Failures []VerifyFailure
+}
+
+// ReplicationVerifier checks that the runs of the cluster reach the bucket
+// MINIO_BUCKET is replicated to in another region. It reads back the manifest
+// of the latest finished run and a random sample of its objects from the
+// replica and compares their checksums with the run's.
+type ReplicationVerifier struct {
+	source     replicationSource
+	replica    *ManifestStore
+	sampleSize int
+	domain     string
+	cluster    string
+	metrics    *metrics.BackupMetrics
+	logger     *logging.StructuredLogger
+	nowFunc    func() time.Time
+
+	mu sync.Mutex
+	// verified is the newest run found intact, which is not read again
+	verified string
+}
+
+func newReplicationVerifier(cfg *config.Config, source replicationSource, replica *ManifestStore, metrics *metrics.BackupMetrics, logger *logging.StructuredLogger) *ReplicationVerifier {
+	return &ReplicationVerifier{
+		source:     source,
+		replica:    replica,
+		sampleSize: cfg.ReplicaSampleSize,
+		domain:     cfg.ClusterDomain,
+		cluster:    cfg.ClusterName,
+		metrics:    metrics,
+		logger:     logger,
+		nowFunc:    time.Now,
+	}
+}
+
+// SetReplica verifies that runs reach REPLICA_BUCKET, read with
+// replicaClient, before each run and whenever the verifier is run
+func (cb *ClusterBackup) SetReplica(replicaClient *minio.Client) {
+	replica := NewManifestStore(replicaClient, cb.config.ReplicaBucket, cb.config.ClusterDomain, cb.config.ClusterName)
+	cb.replication = newReplicationVerifier(cb.config, cb.manifestStore, replica, cb.metrics, cb.logger)
+}
+
+// ReplicationVerifier returns the verifier of the replica bucket, or nil when
+// REPLICA_ENDPOINT is not set
+func (cb *ClusterBackup) ReplicationVerifier() *ReplicationVerifier {
+	return cb.replication
+}
+
+// checkReplication verifies the previous run before a new one starts, which
+// gives a job that exits after each run the whole schedule interval to
+// replicate
+func (cb *ClusterBackup) checkReplication() {
+	if cb.replication == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(cb.ctx, replicationCheckTimeout)
+	defer cancel()
+	cb.replication.Check(ctx)
+}
+
+// Run checks the replica every interval until ctx is done, so the lag keeps
+// growing while a run is not replicated
+func (v *ReplicationVerifier) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		v.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check verifies the latest finished run against the replica bucket and
+// updates the replication metrics. Missing objects only count as lag, as
+// replication is asynchronous; content that differs from the run's is a
+// failure. It returns nil when the runs cannot be read or none has finished.
+func (v *ReplicationVerifier) Check(ctx context.Context) *ReplicationStatus {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	previous := v.verified
+	status, err := v.check(ctx)
+	if err != nil {
+		v.logger.Warning("replication_check_failed", "Failed to check the replica bucket", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil
+	}
+	if status == nil {
+		v.metrics.ReplicationLag.Set(0)
+		v.metrics.ReplicationErrors.Set(0)
+		return nil
+	}
+
+	v.metrics.ReplicationLag.Set(status.Lag.Seconds())
+	v.metrics.ReplicationErrors.Set(float64(len(status.Failures)))
+	fields := map[string]interface{}{
+		"backup_id": status.BackupID,
+		"sampled":   status.Sampled,
+		"missing":   len(status.Missing),
+		"failed":    len(status.Failures),
+		"lag":       status.Lag.Round(time.Second).String(),
+	}
+	switch {
+	case len(status.Failures) > 0:
+		fields["error"] = status.Failures[0].Err.Error()
+		v.logger.Warning("replication_verification_failed", "Objects in the replica bucket do not match the run", fields)
+	case status.Replicated && v.verified != previous:
+		v.logger.Info("replication_verified", "Run found intact in the replica bucket", fields)
+	case !status.Replicated:
+		v.logger.Debug("replication_pending", "Run not fully replicated yet", fields)
+	}
+	return status
+}
+
+func (v *ReplicationVerifier) check(ctx context.Context) (*ReplicationStatus, error) {
+	summaries, err := v.source.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var latest *ManifestSummary
+	for i := range summaries {
+		if summaries[i].Status != ManifestStatusRunning {
+			latest = &summaries[i]
+			break
+		}
+	}
+	if latest == nil {
+		return nil, nil
+	}
+
+	status := &ReplicationStatus{BackupID: latest.BackupID}
+	if latest.BackupID == v.verified {
+		status.Replicated = true
+		return status, nil
+	}
+
+	key := ManifestPath(v.domain, v.cluster, latest.BackupID)
+	data, err := v.source.GetObject(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %v", key, err)
+	}
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %v", key, err)
+	}
+
+	replicaData, err := v.replica.GetObject(ctx, key)
+	switch {
+	case isMissing(err):
+		status.Missing = append(status.Missing, key)
+	case err != nil:
+		status.Failures = append(status.Failures, VerifyFailure{Key: key, Err: err})
+	default:
+		if err := verifyChecksum(key, Checksum(replicaData), Checksum(data)); err != nil {
+			status.Failures = append(status.Failures, VerifyFailure{Key: key, Err: err})
+		}
+	}
+
+	objects, _ := manifest.storedObjects()
+	for _, object := range sampleObjects(objects, v.sampleSize) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		status.Sampled++
+		if _, err := v.replica.verifyObject(ctx, object); isMissing(err) {
+			status.Missing = append(status.Missing, object.key)
+		} else if err != nil {
+			status.Failures = append(status.Failures, VerifyFailure{Key: object.key, Err: err})
+		}
+	}
+
+	status.Replicated = len(status.Missing) == 0 && len(status.Failures) == 0
+	if status.Replicated {
+		v.verified = latest.BackupID
+		return status, nil
+	}
+	finished := latest.EndTime
+	if finished.IsZero() {
+		finished = latest.StartTime
+	}
+	status.Lag = v.nowFunc().Sub(finished)
+	return status, nil
+}
+
+// sampleObjects picks up to n of objects at random, so every check covers
+// different objects of large runs
+func sampleObjects(objects []storedObject, n int) []storedObject {
+	if n >= len(objects) {
+		return objects
+	}
+	sample := make([]storedObject, len(objects))
+	copy(sample, objects)
+	rand.Shuffle(len(sample), func(i, j int) { sample[i], sample[j] = sample[j], sample[i] })
+	return sample[:n]
+}
+
+// isMissing reports whether err is the replica not having an object or
+// version yet
+func isMissing(err error) bool {
+	var response minio.ErrorResponse
+	if !errors.As(err, &response) {
+		return false
+	}
+	return response.Code == "NoSuchKey" || response.Code == "NoSuchVersion"
+}
@@ -0,0 +1,113 @@
+package backup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/logging"
+	"cluster-backup/internal/metrics"
+)
+
+// listedStore is a manifest store over a fakeBucket, which cannot list
+type listedStore struct {
+	*ManifestStore
+	summaries []ManifestSummary
+}
+
+func (s *listedStore) List(ctx context.Context) ([]ManifestSummary, error) {
+	return s.summaries, nil
+}
+
+func TestReplicationVerifier(t *testing.T) {
+	ctx := context.Background()
+	sourceClient, source := newLockTestClient(t)
+	replicaClient, replica := newLockTestClient(t)
+
+	objects := map[string][]byte{
+		"example.com/prod/shop/configmaps/app.yaml":  []byte("app"),
+		"example.com/prod/shop/secrets/db.yaml":      []byte("db"),
+		"example.com/prod/_crds/widgets.example.com": []byte("crd"),
+	}
+	ended := time.Date(2025, 3, 2, 12, 0, 0, 0, time.UTC)
+	manifest := NewManifest("backup-1", "prod", "example.com", "backups", ended.Add(-10*time.Minute))
+	for key, data := range objects {
+		source.objects["/backups/"+key] = data
+		manifest.AddObject(ObjectEntry{Namespace: "shop", Key: key, Checksum: Checksum(data)})
+	}
+	manifest.AddObject(ObjectEntry{Namespace: "payments", Bucket: "payments-backups", Key: "tenant-only"})
+	manifest.Finish(ended)
+
+	store := NewManifestStore(sourceClient, "backups", "example.com", "prod")
+	require.NoError(t, store.Save(ctx, manifest))
+	running := NewManifest("backup-2", "prod", "example.com", "backups", ended.Add(time.Hour))
+	listed := &listedStore{ManifestStore: store, summaries: []ManifestSummary{running.Summary(), manifest.Summary()}}
+
+	backupMetrics := &metrics.BackupMetrics{
+		ReplicationLag:    prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_replication_lag_seconds"}),
+		ReplicationErrors: prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_replication_errors"}),
+	}
+	cfg := &config.Config{ClusterDomain: "example.com", ClusterName: "prod", ReplicaSampleSize: 10}
+	verifier := newReplicationVerifier(cfg, listed, NewManifestStore(replicaClient, "backups", "example.com", "prod"),
+		backupMetrics, logging.NewStructuredLogger("test", "prod"))
+	now := ended.Add(30 * time.Minute)
+	verifier.nowFunc = func() time.Time { return now }
+
+	// Nothing replicated yet: the run is pending, not failed
+	status := verifier.Check(ctx)
+	require.NotNil(t, status)
+	assert.Equal(t, "backup-1", status.BackupID, "runs still in progress are not checked")
+	assert.False(t, status.Replicated)
+	assert.Equal(t, 3, status.Sampled, "objects in tenants' buckets are not sampled")
+	assert.Len(t, status.Missing, 4)
+	assert.Empty(t, status.Failures)
+	assert.Equal(t, 30*time.Minute, status.Lag)
+	assert.Equal(t, (30 * time.Minute).Seconds(), testutil.ToFloat64(backupMetrics.ReplicationLag))
+
+	// A replica whose content differs from the run is a failure
+	for key, data := range source.objects {
+		replica.objects[key] = data
+	}
+	replica.objects["/backups/example.com/prod/shop/secrets/db.yaml"] = []byte("stale")
+	status = verifier.Check(ctx)
+	require.NotNil(t, status)
+	assert.False(t, status.Replicated)
+	assert.Empty(t, status.Missing)
+	require.Len(t, status.Failures, 1)
+	assert.Equal(t, "example.com/prod/shop/secrets/db.yaml", status.Failures[0].Key)
+	assert.ErrorIs(t, status.Failures[0].Err, ErrChecksumMismatch)
+	assert.Equal(t, 1.0, testutil.ToFloat64(backupMetrics.ReplicationErrors))
+
+	replica.objects["/backups/example.com/prod/shop/secrets/db.yaml"] = []byte("db")
+	status = verifier.Check(ctx)
+	require.NotNil(t, status)
+	assert.True(t, status.Replicated)
+	assert.Zero(t, status.Lag)
+	assert.Equal(t, 0.0, testutil.ToFloat64(backupMetrics.ReplicationLag))
+	assert.Equal(t, 0.0, testutil.ToFloat64(backupMetrics.ReplicationErrors))
+
+	// A verified run is not read back again
+	delete(replica.objects, "/backups/example.com/prod/shop/secrets/db.yaml")
+	status = verifier.Check(ctx)
+	require.NotNil(t, status)
+	assert.True(t, status.Replicated)
+	assert.Zero(t, status.Sampled)
+}
+
+func TestSampleObjects(t *testing.T) {
+	objects := []storedObject{{key: "a"}, {key: "b"}, {key: "c"}, {key: "d"}}
+
+	assert.Equal(t, objects, sampleObjects(objects, 10))
+	sample := sampleObjects(objects, 2)
+	assert.Len(t, sample, 2)
+	assert.Subset(t, objects, sample)
+	assert.NotEqual(t, sample[0], sample[1])
+	assert.Empty(t, sampleObjects(objects, 0))
+	assert.Equal(t, []storedObject{{key: "a"}, {key: "b"}, {key: "c"}, {key: "d"}}, objects, "the manifest's order is kept")
+}
@@ -0,0 +1,414 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"cluster-backup/internal/failure"
+)
+
+// Names of the report files stored under a run's _data prefix
+const (
+	ReportJSONFile = "report.json"
+	ReportHTMLFile = "report.html"
+)
+
+// reportListLimit bounds the skipped and invalid resources a report lists;
+// the totals still count every one
+const reportListLimit = 1000
+
+//go:embed report.html.tmpl
+var reportTemplateText string
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"duration": formatReportDuration,
+	"time":     func(t time.Time) string { return t.UTC().Format(time.RFC3339) },
+	"join":     strings.Join,
+}).Parse(reportTemplateText))
+
+// Report summarizes a run for auditors and on-call: what was backed up, what
+// was left out and why, how long each phase took and what failed
+type Report struct {
+	BackupID      string       `json:"backup_id"`
+	ClusterName   string       `json:"cluster_name"`
+	ClusterDomain string       `json:"cluster_domain"`
+	Bucket        string       `json:"bucket"`
+	Status        string       `json:"status"`
+	StartTime     time.Time    `json:"start_time"`
+	EndTime       time.Time    `json:"end_time"`
+	Duration      float64      `json:"duration_seconds"`
+	Interruption  *Checkpoint  `json:"interruption,omitempty"`
+	Totals        ReportTotals `json:"totals"`
+	// Namespaces are sorted by name
+	Namespaces   []ReportNamespace `json:"namespaces"`
+	ClusterScope *ReportNamespace  `json:"cluster_scope,omitempty"`
+	Phases       []ReportPhase     `json:"phases"`
+	Skipped      []ReportResource  `json:"skipped,omitempty"`
+	Invalid      []ReportResource  `json:"invalid,omitempty"`
+	Errors       []string          `json:"errors,omitempty"`
+	// Failures counts the resource types and phases that failed, by
+	// category of the error
+	Failures map[failure.Category]int `json:"failures,omitempty"`
+	// Inventory is the run's check against the expected inventory
+	Inventory *InventoryCheck `json:"inventory,omitempty"`
+	Config    ReportConfig    `json:"config"`
+	// ConfigChanges are the settings changed since ConfigBaseline, the
+	// previous run
+	ConfigVersion  string         `json:"config_version,omitempty"`
+	ConfigBaseline string         `json:"config_baseline,omitempty"`
+	ConfigChanges  []ConfigChange `json:"config_changes,omitempty"`
+	GeneratedAt    time.Time      `json:"generated_at"`
+}
+
+// ReportTotals counts what a run stored and left out
+type ReportTotals struct {
+	Namespaces       int   `json:"namespaces"`
+	Resources        int   `json:"resources"`
+	ClusterResources int   `json:"cluster_resources"`
+	CRDs             int   `json:"crds"`
+	OpenShiftObjects int   `json:"openshift_objects"`
+	Dumps            int   `json:"dumps"`
+	Bytes            int64 `json:"bytes"`
+	Skipped          int   `json:"skipped"`
+	Invalid          int   `json:"invalid"`
+	PolicyViolations int   `json:"policy_violations"`
+	SecretFindings   int   `json:"secret_findings"`
+}
+
+// ReportNamespace is the outcome of one namespace, or of the cluster scope
+type ReportNamespace struct {
+	Name      string   `json:"name"`
+	Status    string   `json:"status"`
+	Resources int      `json:"resources"`
+	Bytes     int64    `json:"bytes"`
+	Duration  float64  `json:"duration_seconds"`
+	Tenant    string   `json:"tenant,omitempty"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// ReportPhase is how long a phase of the run took
+type ReportPhase struct {
+	Name     string  `json:"name"`
+	Duration float64 `json:"duration_seconds"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// ReportResource is a resource left out of the run, and why. Namespace is
+// empty for cluster-scoped resources.
+type ReportResource struct {
+	Namespace    string `json:"namespace,omitempty"`
+	ResourceType string `json:"resource_type"`
+	Name         string `json:"name"`
+	Reason       string `json:"reason"`
+}
+
+// ReportConfig is the configuration that decided what the run backed up. It
+// holds no credentials.
+type ReportConfig struct {
+	FilteringMode          string         `json:"filtering_mode"`
+	IncludeNamespaces      []string       `json:"include_namespaces,omitempty"`
+	ExcludeNamespaces      []string       `json:"exclude_namespaces,omitempty"`
+	IncludeResources       []string       `json:"include_resources,omitempty"`
+	ExcludeResources       []string       `json:"exclude_resources,omitempty"`
+	LabelSelector          string         `json:"label_selector,omitempty"`
+	AnnotationSelector     string         `json:"annotation_selector,omitempty"`
+	BackupClusterResources bool           `json:"backup_cluster_resources"`
+	ClusterResources       []string       `json:"cluster_resources,omitempty"`
+	FollowOwnerReferences  bool           `json:"follow_owner_references"`
+	IncludeStatus          bool           `json:"include_status"`
+	IncludeManagedFields   bool           `json:"include_managed_fields"`
+	MaxResourceSize        string         `json:"max_resource_size,omitempty"`
+	BatchSize              int            `json:"batch_size"`
+	BatchSizes             map[string]int `json:"batch_sizes,omitempty"`
+	BackupTimeout          string         `json:"backup_timeout,omitempty"`
+	ContentAddressed       bool           `json:"content_addressed"`
+	StrictValidation       bool           `json:"strict_validation"`
+	ResourcePlugins        []string       `json:"resource_plugins,omitempty"`
+}
+
+// runRecord collects what a report needs beyond the manifest while the run
+// goes: how long each phase took, which resources were left out and what
+// kinds of failures occurred
+type runRecord struct {
+	mu           sync.Mutex
+	phases       []ReportPhase
+	skipped      []ReportResource
+	invalid      []ReportResource
+	skippedTotal int
+	invalidTotal int
+	failures     map[failure.Category]int
+}
+
+type runRecordKey struct{}
+
+// withRunRecord returns a context the phases and resources of a run are
+// recorded through
+func withRunRecord(ctx context.Context, record *runRecord) context.Context {
+	return context.WithValue(ctx, runRecordKey{}, record)
+}
+
+// runRecordFrom returns the record of the run ctx belongs to, nil outside a
+// run. A nil record ignores what it is given.
+func runRecordFrom(ctx context.Context) *runRecord {
+	record, _ := ctx.Value(runRecordKey{}).(*runRecord)
+	return record
+}
+
+// phase records how long a phase took
+func (r *runRecord) phase(name string, duration time.Duration, err error) {
+	if r == nil {
+		return
+	}
+	entry := ReportPhase{Name: name, Duration: duration.Seconds()}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.phases = append(r.phases, entry)
+}
+
+// skip records a resource the filters, plugins or policies left out
+func (r *runRecord) skip(resource ReportResource) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.skippedTotal++
+	if len(r.skipped) < reportListLimit {
+		r.skipped = append(r.skipped, resource)
+	}
+}
+
+// invalidResource records a resource left out because it could not be
+// serialized
+func (r *runRecord) invalidResource(resource ReportResource) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.invalidTotal++
+	if len(r.invalid) < reportListLimit {
+		r.invalid = append(r.invalid, resource)
+	}
+}
+
+// fail records a failed resource type or phase by the category of err
+func (r *runRecord) fail(err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failures == nil {
+		r.failures = make(map[failure.Category]int)
+	}
+	r.failures[failure.CategoryOf(err)]++
+}
+
+// recordFailure counts a failed resource type or phase of the run ctx
+// belongs to, by the category of err, in the run's report and metrics
+func (cb *ClusterBackup) recordFailure(ctx context.Context, err error) {
+	category := failure.CategoryOf(err)
+	runRecordFrom(ctx).fail(err)
+	cb.metrics.Failures.WithLabelValues(string(category)).Inc()
+}
+
+// buildReport summarizes a finished run from its manifest, result and record
+func (cb *ClusterBackup) buildReport(manifest *Manifest, result *BackupResult, record *runRecord) *Report {
+	manifest.mutex.Lock()
+	defer manifest.mutex.Unlock()
+	record.mu.Lock()
+	defer record.mu.Unlock()
+
+	report := &Report{
+		BackupID:       manifest.BackupID,
+		ClusterName:    manifest.ClusterName,
+		ClusterDomain:  manifest.ClusterDomain,
+		Bucket:         manifest.Bucket,
+		Status:         manifest.Status,
+		StartTime:      manifest.StartTime,
+		EndTime:        manifest.EndTime,
+		Duration:       manifest.EndTime.Sub(manifest.StartTime).Seconds(),
+		Interruption:   manifest.Checkpoint,
+		Inventory:      manifest.Inventory,
+		Phases:         append([]ReportPhase{}, record.phases...),
+		Skipped:        append([]ReportResource(nil), record.skipped...),
+		Invalid:        append([]ReportResource(nil), record.invalid...),
+		Config:         cb.reportConfig(),
+		ConfigVersion:  manifest.ConfigVersion,
+		ConfigBaseline: manifest.ConfigBaseline,
+		ConfigChanges:  manifest.ConfigChanges,
+		GeneratedAt:    time.Now().UTC(),
+		Totals: ReportTotals{
+			Namespaces:       result.NamespacesBackedUp,
+			Resources:        result.ResourcesBackedUp,
+			ClusterResources: result.ClusterResourcesBackedUp,
+			CRDs:             len(manifest.CRDs),
+			OpenShiftObjects: len(manifest.OpenShift),
+			Dumps:            len(manifest.Dumps),
+			Skipped:          record.skippedTotal,
+			Invalid:          record.invalidTotal,
+			PolicyViolations: len(manifest.PolicyViolations),
+			SecretFindings:   len(manifest.SecretFindings),
+		},
+	}
+	for _, err := range result.Errors {
+		report.Errors = append(report.Errors, err.Error())
+	}
+	if len(record.failures) > 0 {
+		report.Failures = make(map[failure.Category]int, len(record.failures))
+		for category, count := range record.failures {
+			report.Failures[category] = count
+		}
+	}
+
+	phaseDurations := make(map[string]float64, len(record.phases))
+	for _, phase := range record.phases {
+		phaseDurations[phase.Name] = phase.Duration
+	}
+	namespaceBytes := make(map[string]int64)
+	for _, object := range manifest.Objects {
+		namespaceBytes[object.Namespace] += object.Size
+		report.Totals.Bytes += object.Size
+	}
+
+	for name, entry := range manifest.Namespaces {
+		report.Namespaces = append(report.Namespaces, ReportNamespace{
+			Name:      name,
+			Status:    entry.Status,
+			Resources: entry.ResourceCount,
+			Bytes:     namespaceBytes[name],
+			Duration:  phaseDurations["namespace "+name],
+			Tenant:    manifest.Tenants[name],
+			Errors:    entry.Errors,
+		})
+	}
+	sort.Slice(report.Namespaces, func(i, j int) bool {
+		return report.Namespaces[i].Name < report.Namespaces[j].Name
+	})
+	if entry := manifest.ClusterScope; entry != nil {
+		report.ClusterScope = &ReportNamespace{
+			Name:      "cluster-scope",
+			Status:    entry.Status,
+			Resources: entry.ResourceCount,
+			Bytes:     namespaceBytes[""],
+			Duration:  phaseDurations["cluster-scope"],
+			Errors:    entry.Errors,
+		}
+	}
+	return report
+}
+
+// reportConfig snapshots the configuration that decided what a run backed up
+func (cb *ClusterBackup) reportConfig() ReportConfig {
+	snapshot := ReportConfig{
+		FilteringMode:          cb.backupConfig.FilteringMode,
+		IncludeNamespaces:      cb.backupConfig.IncludeNamespaces,
+		ExcludeNamespaces:      cb.backupConfig.ExcludeNamespaces,
+		IncludeResources:       cb.backupConfig.IncludeResources,
+		ExcludeResources:       cb.backupConfig.ExcludeResources,
+		LabelSelector:          cb.backupConfig.LabelSelector,
+		AnnotationSelector:     cb.backupConfig.AnnotationSelector,
+		BackupClusterResources: cb.backupConfig.BackupClusterResources,
+		ClusterResources:       cb.backupConfig.ClusterResources,
+		FollowOwnerReferences:  cb.backupConfig.FollowOwnerReferences,
+		IncludeStatus:          cb.backupConfig.IncludeStatus,
+		IncludeManagedFields:   cb.backupConfig.IncludeManagedFields,
+		MaxResourceSize:        cb.backupConfig.MaxResourceSize,
+		BatchSize:              cb.config.BatchSize,
+		BatchSizes:             cb.batchSizes(),
+		ContentAddressed:       cb.config.ContentAddressed,
+		StrictValidation:       cb.config.ValidationStrictMode,
+		ResourcePlugins:        cb.plugins.Names(),
+	}
+	if cb.config.BackupTimeout > 0 {
+		snapshot.BackupTimeout = cb.config.BackupTimeout.String()
+	}
+	return snapshot
+}
+
+// RenderHTML renders the report as a standalone HTML page
+func (r *Report) RenderHTML() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := reportTemplate.Execute(&buf, r); err != nil {
+		return nil, fmt.Errorf("failed to render report: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// SaveReport uploads report.json and report.html of a run, and returns
+// their keys
+func (ms *ManifestStore) SaveReport(ctx context.Context, report *Report) ([]string, error) {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal report: %v", err)
+	}
+	page, err := report.RenderHTML()
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, file := range []struct {
+		name        string
+		contentType string
+		data        []byte
+	}{
+		{ReportJSONFile, "application/json", data},
+		{ReportHTMLFile, "text/html; charset=utf-8", page},
+	} {
+		key := ReportPath(ms.clusterDomain, ms.clusterName, report.BackupID, file.name)
+		err := ms.put(ctx, key, file.data, minio.PutObjectOptions{
+			ContentType:  file.contentType,
+			UserMetadata: checksumMetadata(Checksum(file.data)),
+		})
+		if err != nil {
+			return keys, fmt.Errorf("failed to upload report %s: %v", key, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// saveReport uploads the report of a finished run and records its keys in
+// the manifest. A report that cannot be uploaded is logged and does not
+// affect the run's status.
+func (cb *ClusterBackup) saveReport(ctx context.Context, manifest *Manifest, result *BackupResult, record *runRecord) {
+	if !cb.config.BackupReport {
+		return
+	}
+	keys, err := cb.manifestStore.SaveReport(ctx, cb.buildReport(manifest, result, record))
+	manifest.SetReport(keys)
+	if err != nil {
+		cb.logger.Warning("report_upload_failed", "Failed to upload the backup report", map[string]interface{}{
+			"backup_id": manifest.BackupID,
+			"error":     err.Error(),
+		})
+	}
+}
+
+// formatReportDuration renders seconds as a duration rounded for reading
+func formatReportDuration(seconds float64) string {
+	duration := time.Duration(seconds * float64(time.Second))
+	switch {
+	case duration >= time.Minute:
+		return duration.Round(time.Second).String()
+	case duration >= time.Second:
+		return duration.Round(10 * time.Millisecond).String()
+	default:
+		return duration.Round(time.Millisecond).String()
+	}
+}
@@ -0,0 +1,124 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/failure"
+	"cluster-backup/internal/logging"
+)
+
+func newReportTestRun() (*ClusterBackup, *Manifest, *BackupResult, *runRecord) {
+	cb := &ClusterBackup{
+		config:       &config.Config{BatchSize: 100, BackupReport: true, BackupTimeout: time.Hour},
+		backupConfig: &config.BackupConfig{FilteringMode: "whitelist", IncludeNamespaces: []string{"shop", "billing"}},
+		logger:       logging.NewStructuredLogger("report-test", "prod"),
+	}
+
+	start := time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC)
+	manifest := NewManifest("20260301-020000", "prod", "example.com", "backups", start)
+	manifest.AddObject(ObjectEntry{Namespace: "shop", ResourceType: "configmaps", Name: "settings", Key: "k1", Size: 120})
+	manifest.AddObject(ObjectEntry{Namespace: "shop", ResourceType: "secrets", Name: "tls", Key: "k2", Size: 80})
+	manifest.SetNamespace("shop", 2, nil)
+	manifest.SetNamespace("billing", 0, errors.New("failed to list configmaps: forbidden"))
+	manifest.Finish(start.Add(90 * time.Second))
+
+	result := &BackupResult{
+		BackupID:           manifest.BackupID,
+		NamespacesBackedUp: 1,
+		ResourcesBackedUp:  2,
+		Errors:             []error{errors.New("failed to backup namespace billing: failed to list configmaps: forbidden")},
+	}
+
+	record := &runRecord{}
+	record.phase("discovery", 2*time.Second, nil)
+	record.phase("namespace shop", 40*time.Second, nil)
+	record.phase("namespace billing", time.Second, errors.New("failed to list configmaps: forbidden"))
+	record.fail(failure.Wrap(failure.ErrPermissionDenied, errors.New("failed to list configmaps: forbidden")))
+	record.fail(failure.Wrap(failure.ErrResourceInvalid, errors.New("resource too large")))
+	record.fail(failure.Wrap(failure.ErrResourceInvalid, errors.New("resource too large")))
+	record.skip(ReportResource{Namespace: "shop", ResourceType: "pods", Name: "web-1", Reason: "controlled by ReplicaSet web"})
+	record.invalidResource(ReportResource{Namespace: "shop", ResourceType: "configmaps", Name: "<broken>", Reason: "json: unsupported value"})
+	return cb, manifest, result, record
+}
+
+func TestBuildReport(t *testing.T) {
+	cb, manifest, result, record := newReportTestRun()
+
+	report := cb.buildReport(manifest, result, record)
+
+	assert.Equal(t, ManifestStatusPartial, report.Status)
+	assert.Equal(t, 90.0, report.Duration)
+	assert.Equal(t, ReportTotals{Namespaces: 1, Resources: 2, Bytes: 200, Skipped: 1, Invalid: 1}, report.Totals)
+	require.Len(t, report.Namespaces, 2)
+	assert.Equal(t, "billing", report.Namespaces[0].Name)
+	assert.Equal(t, []string{"failed to list configmaps: forbidden"}, report.Namespaces[0].Errors)
+	assert.Equal(t, ReportNamespace{Name: "shop", Status: ManifestStatusCompleted, Resources: 2, Bytes: 200, Duration: 40}, report.Namespaces[1])
+	assert.Len(t, report.Phases, 3)
+	assert.Equal(t, "controlled by ReplicaSet web", report.Skipped[0].Reason)
+	assert.Len(t, report.Errors, 1)
+	assert.Equal(t, map[failure.Category]int{failure.CategoryPermission: 1, failure.CategoryInvalid: 2}, report.Failures)
+	assert.Equal(t, "whitelist", report.Config.FilteringMode)
+	assert.Equal(t, "1h0m0s", report.Config.BackupTimeout)
+
+	page, err := report.RenderHTML()
+	require.NoError(t, err)
+	assert.Contains(t, string(page), "Backup 20260301-020000")
+	assert.Contains(t, string(page), "controlled by ReplicaSet web")
+	assert.Contains(t, string(page), "&lt;broken&gt;", "resource names are escaped")
+	assert.Contains(t, string(page), "1m30s")
+	assert.Contains(t, string(page), "<tr><th>invalid</th><td class=\"num\">2</td></tr>")
+}
+
+func TestRunRecord_CapsLists(t *testing.T) {
+	record := &runRecord{}
+	for i := 0; i < reportListLimit+5; i++ {
+		record.skip(ReportResource{Name: fmt.Sprintf("cm-%d", i)})
+	}
+	assert.Len(t, record.skipped, reportListLimit)
+	assert.Equal(t, reportListLimit+5, record.skippedTotal)
+
+	var outside *runRecord
+	assert.NotPanics(t, func() { outside.skip(ReportResource{}) }, "runs without a record ignore it")
+	assert.Nil(t, runRecordFrom(context.Background()))
+}
+
+func TestSaveReport_UploadsJSONAndHTML(t *testing.T) {
+	client, bucket := newLockTestClient(t)
+	cb, manifest, result, record := newReportTestRun()
+	cb.manifestStore = NewManifestStore(client, "backups", "example.com", "prod")
+
+	cb.saveReport(context.Background(), manifest, result, record)
+
+	assert.Equal(t, []string{
+		"example.com/prod/_data/20260301-020000/report.json",
+		"example.com/prod/_data/20260301-020000/report.html",
+	}, manifest.Reports)
+	assert.Equal(t, manifest.Reports, manifest.StoredKeys()[""][2:], "retention removes the report with the run")
+
+	var stored Report
+	require.NoError(t, json.Unmarshal(bucket.objects["/backups/example.com/prod/_data/20260301-020000/report.json"], &stored))
+	assert.Equal(t, "20260301-020000", stored.BackupID)
+	assert.Equal(t, 1, stored.Totals.Skipped)
+	assert.Contains(t, string(bucket.objects["/backups/example.com/prod/_data/20260301-020000/report.html"]), "<!DOCTYPE html>")
+}
+
+func TestSaveReport_Disabled(t *testing.T) {
+	client, bucket := newLockTestClient(t)
+	cb, manifest, result, record := newReportTestRun()
+	cb.config.BackupReport = false
+	cb.manifestStore = NewManifestStore(client, "backups", "example.com", "prod")
+
+	cb.saveReport(context.Background(), manifest, result, record)
+
+	assert.Empty(t, manifest.Reports)
+	assert.Empty(t, bucket.objects)
+}
@@ -0,0 +1,260 @@
+package backup
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/logging"
+	"cluster-backup/internal/metrics"
+)
+
+// clusterScopeLabel is the namespace label of the RPO series covering the
+// whole cluster, which is met by runs that completed without errors
+const clusterScopeLabel = ""
+
+// manifestReader is the part of the manifest store the RPO monitor reads
+// the run history from
+type manifestReader interface {
+	List(ctx context.Context) ([]ManifestSummary, error)
+	Load(ctx context.Context, backupID string) (*Manifest, error)
+}
+
+// RPOStatus is the recovery point of the cluster, when Namespace is empty,
+// or of one namespace
+type RPOStatus struct {
+	Namespace string
+	// LastSuccess is when the last run that backed it up without errors
+	// ended, zero when there is none
+	LastSuccess time.Time
+	Age         time.Duration
+	Violated    bool
+}
+
+// RPOMonitor tracks how long ago the cluster and each namespace of the latest
+// run were last backed up successfully, and flags those older than the RPO
+// target. It starts from the manifests in the bucket and follows the runs of
+// this process from there.
+type RPOMonitor struct {
+	target  time.Duration
+	cluster string
+	store   manifestReader
+	metrics *metrics.BackupMetrics
+	logger  *logging.StructuredLogger
+	nowFunc func() time.Time
+
+	mu          sync.Mutex
+	loaded      bool
+	lastSuccess map[string]time.Time
+	namespaces  map[string]bool
+	// violated holds the state of every scope with RPO series
+	violated map[string]bool
+}
+
+// newRPOMonitor returns the monitor for RPO_TARGET, or nil when it is not set
+func newRPOMonitor(cfg *config.Config, store manifestReader, metrics *metrics.BackupMetrics, logger *logging.StructuredLogger) *RPOMonitor {
+	if cfg.RPOTarget <= 0 {
+		return nil
+	}
+	metrics.RPOTarget.Set(cfg.RPOTarget.Seconds())
+	return &RPOMonitor{
+		target:      cfg.RPOTarget,
+		cluster:     cfg.ClusterName,
+		store:       store,
+		metrics:     metrics,
+		logger:      logger,
+		nowFunc:     time.Now,
+		lastSuccess: make(map[string]time.Time),
+		namespaces:  make(map[string]bool),
+		violated:    make(map[string]bool),
+	}
+}
+
+// RPOMonitor returns the monitor of the cluster's recovery point objective,
+// or nil when RPO_TARGET is not set
+func (cb *ClusterBackup) RPOMonitor() *RPOMonitor {
+	return cb.rpoMonitor
+}
+
+// checkRPO updates the RPO metrics after a run, whatever its outcome
+func (cb *ClusterBackup) checkRPO() {
+	if cb.rpoMonitor == nil {
+		return
+	}
+	ctx, cancel := cb.checkpointContext()
+	defer cancel()
+	cb.rpoMonitor.Check(ctx)
+}
+
+// Record takes the successes of a run whose manifest was just saved
+func (m *RPOMonitor) Record(ctx context.Context, manifest *Manifest) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.load(ctx)
+	m.namespaces = make(map[string]bool)
+	m.apply(manifest, true)
+}
+
+// Run checks the RPO every interval until ctx is done, so the age keeps
+// growing between runs in a long-running process
+func (m *RPOMonitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check updates the age and violation gauges of the cluster and of each
+// namespace, logs those that breached the target or recovered since the last
+// check, and returns their status sorted by namespace
+func (m *RPOMonitor) Check(ctx context.Context) []RPOStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.load(ctx)
+	scopes := []string{clusterScopeLabel}
+	for namespace := range m.namespaces {
+		scopes = append(scopes, namespace)
+	}
+	sort.Strings(scopes)
+
+	now := m.nowFunc()
+	statuses := make([]RPOStatus, 0, len(scopes))
+	for _, scope := range scopes {
+		status := RPOStatus{Namespace: scope, LastSuccess: m.lastSuccess[scope]}
+		age := math.Inf(1)
+		if !status.LastSuccess.IsZero() {
+			status.Age = now.Sub(status.LastSuccess)
+			age = status.Age.Seconds()
+		}
+		// Never having been backed up breaches any target
+		status.Violated = status.LastSuccess.IsZero() || status.Age > m.target
+
+		m.metrics.RPOAge.WithLabelValues(m.cluster, scope).Set(age)
+		violation := 0.0
+		if status.Violated {
+			violation = 1
+		}
+		m.metrics.RPOViolation.WithLabelValues(m.cluster, scope).Set(violation)
+		m.logTransition(status)
+		statuses = append(statuses, status)
+	}
+
+	// Namespaces the latest run no longer backs up are not tracked
+	for scope := range m.violated {
+		if scope != clusterScopeLabel && !m.namespaces[scope] {
+			m.metrics.RPOAge.DeleteLabelValues(m.cluster, scope)
+			m.metrics.RPOViolation.DeleteLabelValues(m.cluster, scope)
+			delete(m.violated, scope)
+		}
+	}
+	return statuses
+}
+
+// logTransition logs a scope whose violation state changed since the last
+// check. A new process logs every scope in violation once.
+func (m *RPOMonitor) logTransition(status RPOStatus) {
+	violated, reported := m.violated[status.Namespace]
+	m.violated[status.Namespace] = status.Violated
+	if violated == status.Violated && (reported || !status.Violated) {
+		return
+	}
+
+	fields := map[string]interface{}{
+		"cluster":    m.cluster,
+		"namespace":  status.Namespace,
+		"rpo_target": m.target.String(),
+	}
+	if !status.LastSuccess.IsZero() {
+		fields["last_success"] = status.LastSuccess.Format(time.RFC3339)
+		fields["age"] = status.Age.Round(time.Second).String()
+	}
+	if status.Violated {
+		m.logger.Warning("rpo_violation", "Last successful backup is older than the RPO target", fields)
+	} else {
+		m.logger.Info("rpo_restored", "Last successful backup is within the RPO target again", fields)
+	}
+}
+
+// load reads the last successes from the stored manifests once, newest
+// first, until the cluster and every namespace of the latest run have one.
+// Until the bucket can be read the monitor only knows this process's runs.
+func (m *RPOMonitor) load(ctx context.Context) {
+	if m.loaded {
+		return
+	}
+	summaries, err := m.store.List(ctx)
+	if err != nil {
+		m.logger.Warning("rpo_history_unavailable", "Failed to read earlier runs for the RPO monitor", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	m.loaded = true
+
+	for i, summary := range summaries {
+		if i > 0 && m.complete() {
+			return
+		}
+		manifest, err := m.store.Load(ctx, summary.BackupID)
+		if err != nil {
+			m.logger.Warning("rpo_history_unavailable", "Failed to read a run manifest for the RPO monitor", map[string]interface{}{
+				"backup_id": summary.BackupID,
+				"error":     err.Error(),
+			})
+			continue
+		}
+		m.apply(manifest, i == 0 && len(m.namespaces) == 0)
+	}
+}
+
+// complete reports whether the cluster and every tracked namespace have a
+// last success
+func (m *RPOMonitor) complete() bool {
+	if m.lastSuccess[clusterScopeLabel].IsZero() {
+		return false
+	}
+	for namespace := range m.namespaces {
+		if m.lastSuccess[namespace].IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+// apply records the successes of a run that are newer than those known.
+// The namespaces of the latest run are the ones tracked.
+func (m *RPOMonitor) apply(manifest *Manifest, latest bool) {
+	ended := manifest.EndTime
+	if ended.IsZero() {
+		return
+	}
+	if manifest.Status == ManifestStatusCompleted && manifest.Checkpoint == nil {
+		m.succeeded(clusterScopeLabel, ended)
+	}
+	for namespace, entry := range manifest.Namespaces {
+		if latest {
+			m.namespaces[namespace] = true
+		}
+		if entry.Status == ManifestStatusCompleted {
+			m.succeeded(namespace, ended)
+		}
+	}
+}
+
+// succeeded records a success unless a later one is known
+func (m *RPOMonitor) succeeded(scope string, at time.Time) {
+	if at.After(m.lastSuccess[scope]) {
+		m.lastSuccess[scope] = at
+	}
+}
@@ -0,0 +1,100 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/logging"
+	"cluster-backup/internal/metrics"
+)
+
+type fakeManifestReader struct {
+	manifests []*Manifest
+	loads     int
+}
+
+func (r *fakeManifestReader) List(ctx context.Context) ([]ManifestSummary, error) {
+	var summaries []ManifestSummary
+	for _, manifest := range r.manifests {
+		summaries = append(summaries, manifest.Summary())
+	}
+	return summaries, nil
+}
+
+func (r *fakeManifestReader) Load(ctx context.Context, backupID string) (*Manifest, error) {
+	r.loads++
+	for _, manifest := range r.manifests {
+		if manifest.BackupID == backupID {
+			return manifest, nil
+		}
+	}
+	return nil, fmt.Errorf("manifest %s not found", backupID)
+}
+
+func rpoManifest(backupID string, ended time.Time, namespaces map[string]string) *Manifest {
+	manifest := NewManifest(backupID, "prod", "example.com", "backups", ended.Add(-10*time.Minute))
+	manifest.Namespaces = make(map[string]*NamespaceEntry)
+	for namespace, status := range namespaces {
+		manifest.Namespaces[namespace] = &NamespaceEntry{Status: status}
+	}
+	manifest.Finish(ended)
+	return manifest
+}
+
+func TestRPOMonitor(t *testing.T) {
+	now := time.Date(2025, 3, 2, 12, 0, 0, 0, time.UTC)
+	store := &fakeManifestReader{manifests: []*Manifest{
+		// Newest first, as the manifest store lists them
+		rpoManifest("backup-3", now.Add(-2*time.Hour), map[string]string{"shop": ManifestStatusCompleted, "payments": ManifestStatusFailed}),
+		rpoManifest("backup-2", now.Add(-26*time.Hour), map[string]string{"shop": ManifestStatusCompleted, "payments": ManifestStatusCompleted, "legacy": ManifestStatusCompleted}),
+		rpoManifest("backup-1", now.Add(-50*time.Hour), map[string]string{"shop": ManifestStatusCompleted, "payments": ManifestStatusCompleted}),
+	}}
+	backupMetrics := &metrics.BackupMetrics{
+		RPOAge:       prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_seconds_since_last_success"}, []string{"cluster", "namespace"}),
+		RPOViolation: prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_rpo_violation"}, []string{"cluster", "namespace"}),
+		RPOTarget:    prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_rpo_target_seconds"}),
+	}
+	monitor := newRPOMonitor(&config.Config{ClusterName: "prod", RPOTarget: 24 * time.Hour}, store, backupMetrics, logging.NewStructuredLogger("test", "prod"))
+	monitor.nowFunc = func() time.Time { return now }
+
+	statuses := monitor.Check(context.Background())
+	require.Len(t, statuses, 3, "the cluster and the namespaces of the latest run")
+	assert.Equal(t, 2, store.loads, "history is read until every scope has a success")
+
+	cluster, payments, shop := statuses[0], statuses[1], statuses[2]
+	assert.Equal(t, "", cluster.Namespace)
+	assert.Equal(t, 26*time.Hour, cluster.Age, "the latest run had a failed namespace")
+	assert.True(t, cluster.Violated)
+	assert.Equal(t, "payments", payments.Namespace)
+	assert.True(t, payments.Violated)
+	assert.Equal(t, 2*time.Hour, shop.Age)
+	assert.False(t, shop.Violated)
+	assert.Equal(t, 1.0, testutil.ToFloat64(backupMetrics.RPOViolation.WithLabelValues("prod", "payments")))
+	assert.Equal(t, 7200.0, testutil.ToFloat64(backupMetrics.RPOAge.WithLabelValues("prod", "shop")))
+	assert.Equal(t, 86400.0, testutil.ToFloat64(backupMetrics.RPOTarget))
+
+	// A run backing up payments again restores it, while a new namespace
+	// that failed keeps the cluster in violation
+	monitor.Record(context.Background(), rpoManifest("backup-4", now, map[string]string{"shop": ManifestStatusCompleted, "payments": ManifestStatusCompleted, "billing": ManifestStatusFailed}))
+	statuses = monitor.Check(context.Background())
+	require.Len(t, statuses, 4)
+	assert.True(t, statuses[0].Violated)
+	assert.Equal(t, "billing", statuses[1].Namespace)
+	assert.True(t, statuses[1].Violated)
+	assert.True(t, math.IsInf(testutil.ToFloat64(backupMetrics.RPOAge.WithLabelValues("prod", "billing")), 1))
+	assert.False(t, statuses[2].Violated)
+	assert.Equal(t, 4, testutil.CollectAndCount(backupMetrics.RPOViolation))
+}
+
+func TestRPOMonitorDisabled(t *testing.T) {
+	assert.Nil(t, newRPOMonitor(&config.Config{}, &fakeManifestReader{}, nil, nil))
+}
@@ -0,0 +1,170 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/minio/minio-go/v7"
+
+	"cluster-backup/internal/tenancy"
+)
+
+// runLayout stores the objects of a run under the run's own prefix, so no
+// run overwrites another's, and keeps the latest/ alias of each up to date
+type runLayout struct {
+	backupID string
+
+	mu sync.Mutex
+	// aliases are the alias keys the run wrote, by bucket
+	aliases map[string]map[string]bool
+	// failed is set when an alias could not be written, which leaves the
+	// alias of the run incomplete
+	failed bool
+}
+
+type runLayoutKey struct{}
+
+// withRunLayout returns a context the objects of a run are stored under its
+// prefix through
+func withRunLayout(ctx context.Context, layout *runLayout) context.Context {
+	return context.WithValue(ctx, runLayoutKey{}, layout)
+}
+
+// runLayoutFrom returns the layout of the run ctx belongs to, nil with the
+// flat layout
+func runLayoutFrom(ctx context.Context) *runLayout {
+	layout, _ := ctx.Value(runLayoutKey{}).(*runLayout)
+	return layout
+}
+
+// newRunLayout returns the layout of run backupID under
+// RUN_PREFIXED_LAYOUT, or nil when objects are stored at flat keys
+func (cb *ClusterBackup) newRunLayout(backupID string) *runLayout {
+	if !cb.config.RunPrefixedLayout {
+		return nil
+	}
+	return &runLayout{backupID: backupID, aliases: make(map[string]map[string]bool)}
+}
+
+func (l *runLayout) wroteAlias(bucket, key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.aliases[bucket] == nil {
+		l.aliases[bucket] = make(map[string]bool)
+	}
+	l.aliases[bucket][key] = true
+}
+
+func (l *runLayout) aliasFailed() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.failed = true
+}
+
+// uploadRunObject uploads an object built at flat key in target, and returns
+// the key it was stored at. Under the run layout that is the key within the
+// run's prefix, and a copy goes to the latest/ alias; an alias that fails is
+// logged and does not fail the object.
+func (cb *ClusterBackup) uploadRunObject(ctx context.Context, target *tenancy.Target, key string, data *payload) (string, string, error) {
+	layout := runLayoutFrom(ctx)
+	if layout == nil {
+		versionID, err := cb.uploadObject(ctx, target, key, data)
+		return key, versionID, err
+	}
+
+	flat := target.TrimKey(key)
+	runKey := target.Key(RunPath(cb.config.ClusterDomain, cb.config.ClusterName, layout.backupID, flat))
+	versionID, err := cb.uploadObject(ctx, target, runKey, data)
+	if err != nil {
+		return "", "", err
+	}
+
+	aliasKey := target.Key(LatestPath(cb.config.ClusterDomain, cb.config.ClusterName, flat))
+	if _, err := cb.uploadObject(ctx, target, aliasKey, data); err != nil {
+		layout.aliasFailed()
+		cb.logger.Warning("latest_alias_failed", "Failed to update the latest alias of an object", map[string]interface{}{
+			"backup_id": layout.backupID,
+			"key":       aliasKey,
+			"error":     err.Error(),
+		})
+		return runKey, versionID, nil
+	}
+	layout.wroteAlias(target.Bucket, aliasKey)
+	return runKey, versionID, nil
+}
+
+// pruneLatestAliases removes the latest/ aliases of objects the run of
+// manifest did not store, such as deleted resources. Only a completed run
+// whose aliases were all written is the whole of the latest state; after
+// any other the aliases of earlier runs are kept.
+func (cb *ClusterBackup) pruneLatestAliases(ctx context.Context, layout *runLayout, manifest *Manifest) {
+	if layout == nil || manifest.Status != ManifestStatusCompleted || layout.failed {
+		return
+	}
+
+	targets := []*tenancy.Target{cb.defaultTarget()}
+	if cb.tenancy != nil {
+		targets = append(targets, cb.tenancy.Tenants()...)
+	}
+	prefix := LatestPrefix(cb.config.ClusterDomain, cb.config.ClusterName)
+	removed := 0
+	for _, target := range targets {
+		stale, err := cb.staleKeys(ctx, target, target.Key(prefix), layout.aliases[target.Bucket])
+		if err == nil {
+			err = cb.removeKeys(ctx, target, stale)
+		}
+		if err != nil {
+			cb.logger.Warning("latest_alias_prune_failed", "Failed to remove stale latest aliases", map[string]interface{}{
+				"backup_id": layout.backupID,
+				"bucket":    target.Bucket,
+				"error":     err.Error(),
+			})
+			continue
+		}
+		removed += len(stale)
+	}
+	if removed > 0 {
+		cb.logger.Info("latest_aliases_pruned", "Removed latest aliases of objects no longer backed up", map[string]interface{}{
+			"backup_id": layout.backupID,
+			"removed":   removed,
+		})
+	}
+}
+
+// staleKeys lists the keys under prefix in target that are not in written
+func (cb *ClusterBackup) staleKeys(ctx context.Context, target *tenancy.Target, prefix string, written map[string]bool) ([]string, error) {
+	var stale []string
+	for object := range target.Client.ListObjects(ctx, target.Bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("failed to list %s: %v", prefix, object.Err)
+		}
+		if !written[object.Key] {
+			stale = append(stale, object.Key)
+		}
+	}
+	return stale, nil
+}
+
+// removeKeys deletes keys from target, and from the secondary targets
+// mirroring the default one
+func (cb *ClusterBackup) removeKeys(ctx context.Context, target *tenancy.Target, keys []string) error {
+	for _, key := range keys {
+		if err := target.Client.RemoveObject(ctx, target.Bucket, key, minio.RemoveObjectOptions{}); err != nil {
+			return fmt.Errorf("failed to remove %s: %v", key, err)
+		}
+		if target.Tenant != "" {
+			continue
+		}
+		for _, secondary := range cb.secondaries {
+			if err := secondary.client.RemoveObject(ctx, secondary.bucket, key, minio.RemoveObjectOptions{}); err != nil {
+				cb.logger.Warning("secondary_remove_failed", "Failed to remove an object from a secondary target", map[string]interface{}{
+					"target": secondary.name,
+					"key":    key,
+					"error":  err.Error(),
+				})
+			}
+		}
+	}
+	return nil
+}
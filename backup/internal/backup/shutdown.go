@@ -0,0 +1,100 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// checkpointTimeout bounds writing the manifest and releasing the run lock
+// once the run's context may already be cancelled
+const checkpointTimeout = 10 * time.Second
+
+// ErrShuttingDown is returned for runs started after shutdown began
+var ErrShuttingDown = errors.New("backup is shutting down")
+
+// ErrInterrupted marks work a run skipped because shutdown began before it
+// was done
+var ErrInterrupted = errors.New("interrupted by shutdown")
+
+// drainer tracks running backups so shutdown can let them finish what is in
+// flight instead of cancelling uploads halfway
+type drainer struct {
+	mu       sync.Mutex
+	draining bool
+	active   int
+	idle     chan struct{}
+}
+
+// begin registers a run, failing once draining started
+func (d *drainer) begin() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return ErrShuttingDown
+	}
+	d.active++
+	return nil
+}
+
+// end unregisters a run
+func (d *drainer) end() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.active--
+	if d.draining && d.active == 0 {
+		close(d.idle)
+	}
+}
+
+// stopping reports whether runs must stop starting new work
+func (d *drainer) stopping() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.draining
+}
+
+// drain stops runs from starting new work and waits up to grace for them to
+// return. It reports whether they did.
+func (d *drainer) drain(grace time.Duration) bool {
+	d.mu.Lock()
+	if !d.draining {
+		d.draining = true
+		d.idle = make(chan struct{})
+		if d.active == 0 {
+			close(d.idle)
+		}
+	}
+	idle := d.idle
+	d.mu.Unlock()
+
+	select {
+	case <-idle:
+		return true
+	default:
+	}
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-idle:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// Drain begins a graceful shutdown: a running backup finishes its in-flight
+// upload but starts no new ones, then records where it stopped in a partial
+// manifest. Drain waits up to grace for that and reports whether the run got
+// there; the caller cancels the context afterwards, aborting what is left.
+// Backups started after Drain fail with ErrShuttingDown.
+func (cb *ClusterBackup) Drain(grace time.Duration) bool {
+	return cb.shutdown.drain(grace)
+}
+
+// checkpointContext returns the context for writing a run's manifest, which
+// must succeed even when the run was cancelled at the end of the grace period
+func (cb *ClusterBackup) checkpointContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(cb.ctx), checkpointTimeout)
+}
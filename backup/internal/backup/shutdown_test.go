@@ -0,0 +1,50 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrainer_WaitsForRunningBackup(t *testing.T) {
+	var d drainer
+	require.NoError(t, d.begin())
+	assert.False(t, d.stopping())
+
+	// The run outlives the grace period
+	assert.False(t, d.drain(10*time.Millisecond))
+	assert.True(t, d.stopping())
+	assert.ErrorIs(t, d.begin(), ErrShuttingDown, "no run may start once draining")
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		d.end()
+	}()
+	assert.True(t, d.drain(time.Second))
+	assert.True(t, d.drain(0), "an idle drainer returns at once")
+}
+
+func TestManifest_InterruptRecordsCheckpoint(t *testing.T) {
+	manifest := NewManifest("backup-20250101-020000", "prod", "example.com", "backups", time.Now())
+	manifest.SetNamespace("default", 12, nil)
+	manifest.SetNamespace("payments", 3, fmt.Errorf("failed to list secrets: %w", ErrInterrupted))
+	manifest.Interrupt(time.Now(), ErrInterrupted, []string{"payments", "web"})
+	manifest.Finish(time.Now())
+
+	assert.Equal(t, ManifestStatusPartial, manifest.Status)
+	assert.Equal(t, ManifestStatusCompleted, manifest.Namespaces["default"].Status)
+	assert.Equal(t, ManifestStatusPartial, manifest.Namespaces["payments"].Status)
+	require.NotNil(t, manifest.Checkpoint)
+	assert.Equal(t, []string{"payments", "web"}, manifest.Checkpoint.PendingNamespaces)
+	assert.True(t, manifest.Summary().Interrupted)
+
+	failed := NewManifest("backup-20250101-030000", "prod", "example.com", "backups", time.Now())
+	failed.SetNamespace("default", 0, errors.New("forbidden"))
+	failed.Finish(time.Now())
+	assert.Equal(t, ManifestStatusFailed, failed.Status)
+	assert.Nil(t, failed.Checkpoint)
+}
@@ -0,0 +1,98 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/minio/minio-go/v7"
+
+	"shared-config/signing"
+
+	"cluster-backup/internal/config"
+)
+
+// NewManifestSigner returns the signer for the configured MANIFEST_SIGNING
+// mode, or nil when manifests are left unsigned
+func NewManifestSigner(cfg *config.Config) (signing.Signer, error) {
+	switch cfg.ManifestSigning {
+	case config.ManifestSigningNone, "":
+		return nil, nil
+	case config.ManifestSigningKey:
+		return signing.LoadKeySigner(cfg.ManifestSigningKey)
+	case config.ManifestSigningKeyless:
+		return &signing.KeylessSigner{
+			FulcioURL: cfg.SigstoreFulcioURL,
+			RekorURL:  cfg.SigstoreRekorURL,
+			TokenFile: cfg.SigstoreIdentityTokenFile,
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported manifest signing mode %q", cfg.ManifestSigning)
+}
+
+// NewManifestVerifier loads the configured trust anchors, returning nil when
+// none is set and signatures are not checked
+func NewManifestVerifier(cfg *config.Config) (*signing.Verifier, error) {
+	verifierConfig := signing.VerifierConfig{
+		PublicKeyFile: cfg.ManifestVerifyKey,
+		RootsFile:     cfg.ManifestVerifyRoots,
+		Identity:      cfg.ManifestVerifyIdentity,
+		Issuer:        cfg.ManifestVerifyIssuer,
+		RekorKeyFile:  cfg.SigstoreRekorPublicKey,
+	}
+	if !verifierConfig.Enabled() {
+		return nil, nil
+	}
+	return signing.LoadVerifier(verifierConfig)
+}
+
+// SetSigner makes Save sign every manifest it uploads
+func (ms *ManifestStore) SetSigner(signer signing.Signer) {
+	ms.signer = signer
+}
+
+// signManifest stores the signature of a manifest's uploaded bytes next to it
+func (ms *ManifestStore) signManifest(ctx context.Context, key string, data []byte) error {
+	signature, err := ms.signer.Sign(ctx, data)
+	if err != nil {
+		return fmt.Errorf("failed to sign manifest %s: %v", key, err)
+	}
+	document, err := json.MarshalIndent(signature, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest signature: %v", err)
+	}
+
+	signatureKey := key + signing.SignatureSuffix
+	err = ms.put(ctx, signatureKey, document, minio.PutObjectOptions{
+		ContentType:  "application/json",
+		UserMetadata: checksumMetadata(Checksum(document)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload manifest signature %s: %v", signatureKey, err)
+	}
+	return nil
+}
+
+// VerifySignature checks that a run's manifest was signed by a key or identity
+// the verifier trusts and has not been changed since. A manifest without a
+// signature fails.
+func (ms *ManifestStore) VerifySignature(ctx context.Context, backupID string, verifier *signing.Verifier) error {
+	key := ManifestPath(ms.clusterDomain, ms.clusterName, backupID)
+	data, err := ms.GetObject(ctx, key)
+	if err != nil {
+		return fmt.Errorf("failed to read manifest %s: %v", key, err)
+	}
+
+	document, err := ms.GetObject(ctx, key+signing.SignatureSuffix)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return verifier.Verify(data, nil)
+		}
+		return fmt.Errorf("failed to read manifest signature: %v", err)
+	}
+	signature := &signing.Signature{}
+	if err := json.Unmarshal(document, signature); err != nil {
+		return fmt.Errorf("%w: %v", signing.ErrInvalidSignature, err)
+	}
+	return verifier.Verify(data, signature)
+}
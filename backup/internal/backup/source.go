@@ -0,0 +1,116 @@
+package backup
+
+import (
+	"context"
+	"sort"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"cluster-backup/internal/cluster"
+)
+
+// SourceCluster describes the cluster a run backed up, so a restore target
+// can be checked against it before anything is applied
+type SourceCluster struct {
+	KubernetesVersion string `json:"kubernetes_version,omitempty"`
+	// FeatureGates are the API server's, when its metrics could be read
+	FeatureGates map[string]bool `json:"feature_gates,omitempty"`
+	// APIResources are the types of the objects the run stored, in the
+	// versions they were stored in
+	APIResources []APIResourceEntry `json:"api_resources,omitempty"`
+}
+
+// APIResourceEntry is a resource type objects were stored of
+type APIResourceEntry struct {
+	Group      string `json:"group,omitempty"`
+	Version    string `json:"version"`
+	Kind       string `json:"kind"`
+	Resource   string `json:"resource"`
+	Namespaced bool   `json:"namespaced"`
+}
+
+// GroupVersion returns the type's API version, e.g. apps/v1
+func (e APIResourceEntry) GroupVersion() string {
+	if e.Group == "" {
+		return e.Version
+	}
+	return e.Group + "/" + e.Version
+}
+
+// recordSource records the version and feature gates of the cluster and the
+// namespaced types discovered for the run. Feature gates need get on the
+// API server's /metrics and are left out when it cannot be read.
+func (cb *ClusterBackup) recordSource(ctx context.Context, manifest *Manifest, resources []v1.APIResource) {
+	version := ""
+	if info, err := cb.discoveryClient.ServerVersion(); err == nil {
+		version = info.GitVersion
+	} else {
+		cb.logger.Warning("source_version_failed", "Failed to read the cluster version for the manifest", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	gates, err := cluster.FeatureGates(ctx, cb.discoveryClient.RESTClient())
+	if err != nil {
+		cb.logger.Debug("source_feature_gates_unavailable", "Feature gates are not recorded in the manifest", map[string]interface{}{
+			"error": err.Error(),
+		})
+		gates = nil
+	}
+
+	manifest.SetSource(version, gates)
+	manifest.AddAPIResources(apiResourceEntries(resources))
+}
+
+// apiResourceEntries returns the manifest entries of discovered types
+func apiResourceEntries(resources []v1.APIResource) []APIResourceEntry {
+	entries := make([]APIResourceEntry, 0, len(resources))
+	for _, resource := range resources {
+		entries = append(entries, APIResourceEntry{
+			Group:      resource.Group,
+			Version:    resource.Version,
+			Kind:       resource.Kind,
+			Resource:   resource.Name,
+			Namespaced: resource.Namespaced,
+		})
+	}
+	return entries
+}
+
+// storedAPIResources returns the types of discovered that objects were
+// stored of, adding the alternate versions they were also stored in, sorted
+// by group, resource and version
+func storedAPIResources(discovered []APIResourceEntry, objects, alternates []ObjectEntry) []APIResourceEntry {
+	type typeKey struct{ group, version, resource string }
+	byResource := make(map[typeKey]APIResourceEntry, len(discovered))
+	for _, entry := range discovered {
+		byResource[typeKey{entry.Group, "", entry.Resource}] = entry
+	}
+
+	stored := make(map[typeKey]APIResourceEntry)
+	for _, list := range [][]ObjectEntry{objects, alternates} {
+		for _, object := range list {
+			entry, ok := byResource[typeKey{object.Group, "", object.ResourceType}]
+			if !ok {
+				continue
+			}
+			entry.Version = object.Version
+			stored[typeKey{object.Group, object.Version, object.ResourceType}] = entry
+		}
+	}
+
+	entries := make([]APIResourceEntry, 0, len(stored))
+	for _, entry := range stored {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Group != entries[j].Group {
+			return entries[i].Group < entries[j].Group
+		}
+		if entries[i].Resource != entries[j].Resource {
+			return entries[i].Resource < entries[j].Resource
+		}
+		return entries[i].Version < entries[j].Version
+	})
+	return entries
+}
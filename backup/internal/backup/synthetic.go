@@ -0,0 +1,291 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+)
+
+// syntheticValidationTimeout bounds the restore and comparison of a run's
+// sample, so a slow API server does not hold the run's result back
+const syntheticValidationTimeout = 5 * time.Minute
+
+// syntheticValidationLabel marks the temporary namespaces of synthetic
+// validation, which are safe to delete when one is left behind
+const syntheticValidationLabel = "cluster-backup.io/synthetic-validation"
+
+// objectSource is where the stored copies of a run's objects are read from
+type objectSource interface {
+	GetObjectVersion(ctx context.Context, key, versionID string) ([]byte, error)
+}
+
+// SyntheticValidation is the outcome of restoring a sample of a run's objects
+// into a temporary namespace and comparing what the API server kept with the
+// stored copies
+type SyntheticValidation struct {
+	BackupID  string
+	Namespace string
+	// Sampled is the number of objects picked, Matched those that came back
+	// as they were stored
+	Sampled int
+	Matched int
+	// Skipped counts objects the cluster creates in every namespace itself,
+	// such as the kube-root-ca.crt ConfigMap, which cannot be created again
+	Skipped    int
+	Mismatches []SyntheticMismatch
+	// Failures are the objects that could not be read or restored
+	Failures []VerifyFailure
+}
+
+// SyntheticMismatch is a sampled object that came back from the API server
+// different from its stored copy
+type SyntheticMismatch struct {
+	Key string
+	// Fields are the paths of the fields that differ, such as spec.ports
+	Fields []string
+}
+
+// OK reports whether every restored object matched its stored copy
+func (v *SyntheticValidation) OK() bool {
+	return len(v.Mismatches) == 0 && len(v.Failures) == 0
+}
+
+// checkSyntheticRestore restores a sample of the finished run of manifest
+// into a temporary namespace, compares the objects with their stored copies
+// and deletes the namespace again. It returns nil when synthetic validation
+// is disabled or the run has no object of the validated types.
+func (cb *ClusterBackup) checkSyntheticRestore(manifest *Manifest) *SyntheticValidation {
+	if !cb.config.SyntheticValidationEnabled {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(cb.ctx, syntheticValidationTimeout)
+	defer cancel()
+
+	validation, err := cb.validateRestore(ctx, cb.manifestStore, manifest)
+	if err != nil {
+		cb.logger.Warning("synthetic_validation_failed", "Failed to restore the validation sample", map[string]interface{}{
+			"backup_id": manifest.BackupID,
+			"error":     err.Error(),
+		})
+		return nil
+	}
+	if validation == nil {
+		return nil
+	}
+
+	fields := map[string]interface{}{
+		"backup_id":  validation.BackupID,
+		"namespace":  validation.Namespace,
+		"sampled":    validation.Sampled,
+		"matched":    validation.Matched,
+		"skipped":    validation.Skipped,
+		"mismatched": len(validation.Mismatches),
+		"failed":     len(validation.Failures),
+	}
+	switch {
+	case len(validation.Mismatches) > 0:
+		fields["key"] = validation.Mismatches[0].Key
+		fields["fields"] = validation.Mismatches[0].Fields
+		cb.logger.Warning("synthetic_validation_mismatch", "Restored objects differ from their stored copies", fields)
+	case len(validation.Failures) > 0:
+		fields["error"] = validation.Failures[0].Err.Error()
+		cb.logger.Warning("synthetic_validation_mismatch", "Stored objects could not be restored", fields)
+	default:
+		cb.logger.Info("synthetic_validation_passed", "Restored objects match their stored copies", fields)
+	}
+	return validation
+}
+
+// validateRestore restores the sample of manifest's objects read from source
+// into a new namespace and compares them with their stored copies, counting
+// the results in the synthetic validation metrics. Only namespaced objects of
+// the manifest's own bucket are sampled, as each is moved to the namespace.
+func (cb *ClusterBackup) validateRestore(ctx context.Context, source objectSource, manifest *Manifest) (*SyntheticValidation, error) {
+	entries := syntheticSample(manifest, cb.config.SyntheticValidationResources, cb.config.SyntheticValidationSampleSize)
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	namespace := &corev1.Namespace{ObjectMeta: v1.ObjectMeta{
+		Name:   cb.config.SyntheticValidationNamespacePrefix + "-" + utilrand.String(5),
+		Labels: map[string]string{syntheticValidationLabel: "true"},
+	}}
+	if _, err := cb.kubeClient.CoreV1().Namespaces().Create(ctx, namespace, v1.CreateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to create validation namespace %s: %v", namespace.Name, err)
+	}
+	defer cb.deleteValidationNamespace(ctx, namespace.Name)
+
+	validation := &SyntheticValidation{BackupID: manifest.BackupID, Namespace: namespace.Name}
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		validation.Sampled++
+		result := "matched"
+		fields, err := cb.restoreAndCompare(ctx, source, entry, namespace.Name)
+		switch {
+		case apierrors.IsAlreadyExists(err):
+			result = "skipped"
+			validation.Skipped++
+		case err != nil:
+			result = "failed"
+			validation.Failures = append(validation.Failures, VerifyFailure{Key: entry.Key, Err: err})
+		case len(fields) > 0:
+			result = "mismatched"
+			validation.Mismatches = append(validation.Mismatches, SyntheticMismatch{Key: entry.Key, Fields: fields})
+		default:
+			validation.Matched++
+		}
+		cb.metrics.SyntheticValidationObjects.WithLabelValues(result).Inc()
+	}
+	cb.metrics.SyntheticValidationMismatches.Set(float64(len(validation.Mismatches) + len(validation.Failures)))
+	return validation, nil
+}
+
+// restoreAndCompare creates the stored copy of entry in namespace, reads it
+// back and returns the paths of the fields that differ once both are
+// normalized the way resources are cleaned before they are stored
+func (cb *ClusterBackup) restoreAndCompare(ctx context.Context, source objectSource, entry ObjectEntry, namespace string) ([]string, error) {
+	data, err := source.GetObjectVersion(ctx, entry.Key, entry.VersionID)
+	if err != nil {
+		return nil, err
+	}
+	stored := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", entry.Key, err)
+	}
+
+	object := &unstructured.Unstructured{Object: normalizeValidated(stored, namespace)}
+	gvr := schema.GroupVersionResource{Group: entry.Group, Version: entry.Version, Resource: entry.ResourceType}
+	client := cb.dynamicClient.Resource(gvr).Namespace(namespace)
+	if _, err := client.Create(ctx, object, v1.CreateOptions{}); err != nil {
+		return nil, err
+	}
+	restored, err := client.Get(ctx, object.GetName(), v1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := roundTripYAML(normalizeValidated(cb.cleanResource(restored), entry.Namespace))
+	if err != nil {
+		return nil, err
+	}
+	expected, err := roundTripYAML(normalizeValidated(stored, entry.Namespace))
+	if err != nil {
+		return nil, err
+	}
+	return diffFields("", expected, applied), nil
+}
+
+// deleteValidationNamespace removes a validation namespace and everything
+// restored into it, even when ctx has expired
+func (cb *ClusterBackup) deleteValidationNamespace(ctx context.Context, name string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), checkpointTimeout)
+	defer cancel()
+	propagation := v1.DeletePropagationBackground
+	err := cb.kubeClient.CoreV1().Namespaces().Delete(ctx, name, v1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !apierrors.IsNotFound(err) {
+		cb.logger.Warning("synthetic_validation_cleanup_failed", "Failed to delete the validation namespace", map[string]interface{}{
+			"namespace": name,
+			"error":     err.Error(),
+		})
+	}
+}
+
+// syntheticSample picks up to n of the namespaced objects of manifest's own
+// bucket whose type is one of resources, given as a plural name or one
+// qualified with its group such as roles.rbac.authorization.k8s.io
+func syntheticSample(manifest *Manifest, resources []string, n int) []ObjectEntry {
+	types := make(map[string]bool, len(resources))
+	for _, resource := range resources {
+		types[resource] = true
+	}
+
+	manifest.mutex.Lock()
+	var candidates []ObjectEntry
+	for _, entry := range manifest.Objects {
+		if entry.Namespace == "" || entry.Bucket != "" {
+			continue
+		}
+		if types[entry.ResourceType] || (entry.Group != "" && types[entry.ResourceType+"."+entry.Group]) {
+			candidates = append(candidates, entry)
+		}
+	}
+	manifest.mutex.Unlock()
+
+	if n < len(candidates) {
+		rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+		candidates = candidates[:n]
+	}
+	return candidates
+}
+
+// normalizeValidated returns a copy of object moved to namespace, without the
+// owner references and managed fields a restore into another namespace
+// cannot keep
+func normalizeValidated(object map[string]interface{}, namespace string) map[string]interface{} {
+	normalized := make(map[string]interface{}, len(object))
+	for k, v := range object {
+		normalized[k] = v
+	}
+	metadata := copyObjectMap(object["metadata"])
+	metadata["namespace"] = namespace
+	delete(metadata, "ownerReferences")
+	delete(metadata, "managedFields")
+	normalized["metadata"] = metadata
+	return normalized
+}
+
+// roundTripYAML encodes object as it is stored and decodes it again, so
+// objects from the API server and from the bucket hold the same value types
+func roundTripYAML(object map[string]interface{}) (map[string]interface{}, error) {
+	data, err := yaml.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	decoded := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// diffFields returns the sorted paths below path at which expected and actual
+// differ, descending into the maps both have
+func diffFields(path string, expected, actual map[string]interface{}) []string {
+	var fields []string
+	for key, value := range expected {
+		fieldPath := strings.TrimPrefix(path+"."+key, ".")
+		other, ok := actual[key]
+		if !ok {
+			fields = append(fields, fieldPath)
+			continue
+		}
+		valueMap, isMap := value.(map[string]interface{})
+		otherMap, otherIsMap := other.(map[string]interface{})
+		if isMap && otherIsMap {
+			fields = append(fields, diffFields(fieldPath, valueMap, otherMap)...)
+		} else if !reflect.DeepEqual(value, other) {
+			fields = append(fields, fieldPath)
+		}
+	}
+	for key := range actual {
+		if _, ok := expected[key]; !ok {
+			fields = append(fields, strings.TrimPrefix(path+"."+key, "."))
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
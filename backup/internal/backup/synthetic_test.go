@@ -0,0 +1,142 @@
+package backup
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/logging"
+	"cluster-backup/internal/metrics"
+)
+
+func TestValidateRestore(t *testing.T) {
+	ctx := context.Background()
+	snapshot := NewLiveSnapshot("prod", "example.com", "backups")
+	store := func(resourceType, group, name string, object map[string]interface{}) {
+		data, err := yamlBytes(object)
+		require.NoError(t, err)
+		snapshot.Add(ObjectEntry{Namespace: "shop", Group: group, Version: "v1", ResourceType: resourceType, Name: name,
+			Key: "example.com/prod/shop/" + resourceType + "/" + name + ".yaml"}, data)
+	}
+	settings := configMap("settings", true)
+	settings.SetNamespace("shop")
+	store("configmaps", "", "settings", settings.Object)
+	store("configmaps", "", "kube-root-ca.crt", map[string]interface{}{
+		"apiVersion": "v1", "kind": "ConfigMap",
+		"metadata": map[string]interface{}{"name": "kube-root-ca.crt", "namespace": "shop"},
+	})
+	store("roles", "rbac.authorization.k8s.io", "reader", map[string]interface{}{
+		"apiVersion": "rbac.authorization.k8s.io/v1", "kind": "Role",
+		"metadata": map[string]interface{}{"name": "reader", "namespace": "shop"},
+		"rules":    []interface{}{map[string]interface{}{"verbs": []interface{}{"get"}}},
+	})
+	store("deployments", "apps", "web", map[string]interface{}{
+		"apiVersion": "apps/v1", "kind": "Deployment",
+		"metadata": map[string]interface{}{"name": "web", "namespace": "shop"},
+	})
+	snapshot.Manifest.AddObject(ObjectEntry{Namespace: "payments", ResourceType: "configmaps", Name: "tenant", Bucket: "payments-backups", Key: "tenant"})
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		{Version: "v1", Resource: "configmaps"}:                                "ConfigMapList",
+		{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "roles"}: "RoleList",
+	})
+	// The API server creates kube-root-ca.crt itself and drops unknown rule fields
+	dynamicClient.PrependReactor("create", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		object := action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured)
+		if object.GetName() == "kube-root-ca.crt" {
+			return true, nil, apierrors.NewAlreadyExists(schema.GroupResource{Resource: "configmaps"}, object.GetName())
+		}
+		assert.Empty(t, object.GetOwnerReferences(), "owners are not in the validation namespace")
+		return false, nil, nil
+	})
+	dynamicClient.PrependReactor("create", "roles", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		object := action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured)
+		require.NoError(t, unstructured.SetNestedSlice(object.Object, []interface{}{}, "rules"))
+		return false, nil, nil
+	})
+	kubeClient := fake.NewSimpleClientset()
+
+	backupMetrics := &metrics.BackupMetrics{
+		SyntheticValidationObjects:    prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_synthetic_validation_objects_total"}, []string{"result"}),
+		SyntheticValidationMismatches: prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_synthetic_validation_mismatches"}),
+	}
+	cb := &ClusterBackup{
+		ctx: ctx,
+		config: &config.Config{
+			SyntheticValidationEnabled:         true,
+			SyntheticValidationSampleSize:      10,
+			SyntheticValidationResources:       []string{"configmaps", "roles.rbac.authorization.k8s.io"},
+			SyntheticValidationNamespacePrefix: "backup-validation",
+		},
+		backupConfig:  &config.BackupConfig{},
+		kubeClient:    kubeClient,
+		dynamicClient: dynamicClient,
+		metrics:       backupMetrics,
+		logger:        logging.NewStructuredLogger("synthetic-test", "prod"),
+	}
+
+	validation, err := cb.validateRestore(ctx, snapshot, snapshot.Manifest)
+	require.NoError(t, err)
+	require.NotNil(t, validation)
+	assert.Regexp(t, `^backup-validation-[a-z0-9]{5}$`, validation.Namespace)
+	assert.Equal(t, 3, validation.Sampled, "other types and objects in tenants' buckets are not sampled")
+	assert.Equal(t, 1, validation.Matched)
+	assert.Equal(t, 1, validation.Skipped)
+	assert.Empty(t, validation.Failures)
+	require.Len(t, validation.Mismatches, 1)
+	assert.Equal(t, "example.com/prod/shop/roles/reader.yaml", validation.Mismatches[0].Key)
+	assert.Equal(t, []string{"rules"}, validation.Mismatches[0].Fields)
+	assert.False(t, validation.OK())
+	assert.Equal(t, float64(1), testutil.ToFloat64(backupMetrics.SyntheticValidationMismatches))
+	assert.Equal(t, float64(1), testutil.ToFloat64(backupMetrics.SyntheticValidationObjects.WithLabelValues("matched")))
+
+	restored, err := dynamicClient.Resource(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}).
+		Namespace(validation.Namespace).Get(ctx, "settings", v1.GetOptions{})
+	require.NoError(t, err, "objects are created in the validation namespace")
+	assert.Equal(t, validation.Namespace, restored.GetNamespace())
+
+	namespaces, err := kubeClient.CoreV1().Namespaces().List(ctx, v1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, namespaces.Items, "the validation namespace is deleted")
+
+	// Runs without an object of the validated types are not validated
+	cb.config.SyntheticValidationResources = []string{"secrets"}
+	validation, err = cb.validateRestore(ctx, snapshot, snapshot.Manifest)
+	require.NoError(t, err)
+	assert.Nil(t, validation)
+}
+
+func TestDiffFields(t *testing.T) {
+	expected := map[string]interface{}{
+		"data":     map[string]interface{}{"a": "1", "b": "2"},
+		"metadata": map[string]interface{}{"name": "settings"},
+	}
+	actual := map[string]interface{}{
+		"data":     map[string]interface{}{"a": "1", "b": "3", "c": "4"},
+		"metadata": map[string]interface{}{"name": "settings"},
+		"status":   map[string]interface{}{},
+	}
+	assert.Equal(t, []string{"data.b", "data.c", "status"}, diffFields("", expected, actual))
+	assert.Empty(t, diffFields("", expected, expected))
+}
+
+func yamlBytes(object map[string]interface{}) ([]byte, error) {
+	data, err := newPayload(object)
+	if err != nil {
+		return nil, err
+	}
+	return data.Bytes()
+}
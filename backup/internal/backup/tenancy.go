@@ -0,0 +1,41 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+
+	"cluster-backup/internal/tenancy"
+)
+
+// SetTenancy stores the resources and dumps of each tenant's namespaces in
+// the tenant's bucket with its credentials. The manifest, cluster-scoped
+// resources and unclaimed namespaces stay in the default bucket.
+func (cb *ClusterBackup) SetTenancy(router *tenancy.Router) {
+	cb.tenancy = router
+}
+
+// defaultTarget is the bucket and client of MINIO_BUCKET
+func (cb *ClusterBackup) defaultTarget() *tenancy.Target {
+	return &tenancy.Target{Client: cb.minioClient, Bucket: cb.config.MinIOBucket}
+}
+
+// targetFor returns where the objects of a namespace are stored
+func (cb *ClusterBackup) targetFor(ctx context.Context, namespace string) (*tenancy.Target, error) {
+	if cb.tenancy == nil {
+		return cb.defaultTarget(), nil
+	}
+	target, err := cb.tenancy.Route(ctx, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve the tenant of namespace %s: %v", namespace, err)
+	}
+	return target, nil
+}
+
+// tenantBucket returns the bucket recorded in the manifest for objects stored
+// in target, which is empty for the default bucket
+func tenantBucket(target *tenancy.Target) string {
+	if target.Tenant == "" {
+		return ""
+	}
+	return target.Bucket
+}
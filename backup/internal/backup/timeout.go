@@ -0,0 +1,91 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// runTimeoutSetting is the setting bounding a whole run
+const runTimeoutSetting = "BACKUP_TIMEOUT"
+
+// DeadlineError reports the phase a run was in when BACKUP_TIMEOUT, or the
+// phase's own timeout, expired. Setting names the timeout to raise.
+type DeadlineError struct {
+	Phase   string
+	Setting string
+	Timeout time.Duration
+}
+
+func (e *DeadlineError) Error() string {
+	return fmt.Sprintf("deadline exceeded in phase %s: %s of %s expired", e.Phase, e.Setting, e.Timeout)
+}
+
+// Unwrap lets errors.Is match context.DeadlineExceeded
+func (e *DeadlineError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// runPhase runs fn with a context bounded by the run's deadline and, unless
+// timeout is zero, by the phase's own. An error caused by either expiring is
+// replaced by a DeadlineError naming the phase and the timeout that expired.
+// The phase's duration and outcome go to the run's report.
+func (cb *ClusterBackup) runPhase(run context.Context, phase, setting string, timeout time.Duration, fn func(ctx context.Context) error) error {
+	ctx := run
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(run, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := fn(ctx)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = cb.deadlineError(run, phase, setting, timeout)
+	}
+	runRecordFrom(run).phase(phase, time.Since(start), err)
+	return err
+}
+
+// deadlineError blames BACKUP_TIMEOUT when the run itself is out of time,
+// and the phase's timeout otherwise
+func (cb *ClusterBackup) deadlineError(run context.Context, phase, setting string, timeout time.Duration) *DeadlineError {
+	if errors.Is(run.Err(), context.DeadlineExceeded) {
+		return &DeadlineError{Phase: phase, Setting: runTimeoutSetting, Timeout: cb.config.BackupTimeout}
+	}
+	return &DeadlineError{Phase: phase, Setting: setting, Timeout: timeout}
+}
+
+// stopReason returns why a run must not start phase: ErrInterrupted once
+// shutdown began, or a DeadlineError once the run is out of time
+func (cb *ClusterBackup) stopReason(run context.Context, phase string) error {
+	if cb.shutdown.stopping() {
+		return ErrInterrupted
+	}
+	if errors.Is(run.Err(), context.DeadlineExceeded) {
+		return cb.deadlineError(run, phase, "", 0)
+	}
+	return nil
+}
+
+// stopped reports whether err ended the run rather than just its phase, so
+// the remaining work is recorded as pending in the manifest's checkpoint
+func stopped(err error) bool {
+	var deadline *DeadlineError
+	return errors.Is(err, ErrInterrupted) || (errors.As(err, &deadline) && deadline.Setting == runTimeoutSetting)
+}
+
+// logDeadline logs which phase a deadline expired in, when err is one
+func (cb *ClusterBackup) logDeadline(backupID string, err error) {
+	var deadline *DeadlineError
+	if !errors.As(err, &deadline) {
+		return
+	}
+	cb.logger.Error("backup_deadline_exceeded", "Backup phase ran out of time", map[string]interface{}{
+		"backup_id": backupID,
+		"phase":     deadline.Phase,
+		"setting":   deadline.Setting,
+		"timeout":   deadline.Timeout.String(),
+	})
+}
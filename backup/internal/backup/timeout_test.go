@@ -0,0 +1,63 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/config"
+)
+
+func TestRunPhase_NamesExpiredTimeout(t *testing.T) {
+	cb := &ClusterBackup{config: &config.Config{BackupTimeout: time.Hour}}
+	waitForDeadline := func(ctx context.Context) error {
+		<-ctx.Done()
+		return fmt.Errorf("failed to list pods: %w", ctx.Err())
+	}
+
+	// The phase's own timeout expires first
+	err := cb.runPhase(context.Background(), "namespace payments", "BACKUP_NAMESPACE_TIMEOUT", 10*time.Millisecond, waitForDeadline)
+	var deadline *DeadlineError
+	require.ErrorAs(t, err, &deadline)
+	assert.Equal(t, "deadline exceeded in phase namespace payments: BACKUP_NAMESPACE_TIMEOUT of 10ms expired", err.Error())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, stopped(err), "a namespace out of time does not stop the run")
+
+	// The run runs out of time during the phase
+	run, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = cb.runPhase(run, "namespace payments", "BACKUP_NAMESPACE_TIMEOUT", time.Hour, waitForDeadline)
+	require.ErrorAs(t, err, &deadline)
+	assert.Equal(t, "BACKUP_TIMEOUT", deadline.Setting)
+	assert.Equal(t, time.Hour, deadline.Timeout)
+	assert.True(t, stopped(err))
+	assert.ErrorIs(t, cb.stopReason(run, "namespace web"), context.DeadlineExceeded)
+
+	// Other errors pass through
+	failure := errors.New("forbidden")
+	err = cb.runPhase(context.Background(), "discovery", "BACKUP_DISCOVERY_TIMEOUT", time.Hour, func(ctx context.Context) error {
+		return failure
+	})
+	assert.Equal(t, failure, err)
+	assert.NoError(t, cb.stopReason(context.Background(), "namespace web"))
+}
+
+func TestManifest_DeadlineOutcomes(t *testing.T) {
+	manifest := NewManifest("backup-20250101-020000", "prod", "example.com", "backups", time.Now())
+	runExpired := &DeadlineError{Phase: "namespace payments", Setting: "BACKUP_TIMEOUT", Timeout: 2 * time.Hour}
+	manifest.SetNamespace("default", 2, &DeadlineError{Phase: "namespace default", Setting: "BACKUP_NAMESPACE_TIMEOUT", Timeout: time.Hour})
+	manifest.SetNamespace("payments", 3, runExpired)
+	manifest.Interrupt(time.Now(), runExpired, []string{"payments", "web"})
+	manifest.Finish(time.Now())
+
+	assert.Equal(t, ManifestStatusPartial, manifest.Status)
+	assert.Equal(t, ManifestStatusFailed, manifest.Namespaces["default"].Status)
+	assert.Equal(t, ManifestStatusPartial, manifest.Namespaces["payments"].Status)
+	require.NotNil(t, manifest.Checkpoint)
+	assert.Equal(t, runExpired.Error(), manifest.Checkpoint.Reason)
+}
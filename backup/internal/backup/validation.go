@@ -0,0 +1,142 @@
+package backup
+
+import (
+	"context"
+	"errors"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"cluster-backup/internal/policy"
+	"cluster-backup/internal/secretscan"
+)
+
+// errResourceBlocked is returned by the per-resource callback of
+// forEachResource for a resource that strict validation keeps out of the backup
+var errResourceBlocked = errors.New("resource blocked by validation")
+
+// PolicyViolation records a backed-up resource breaking a Rego policy.
+// Blocked resources were left out of the backup by strict validation.
+type PolicyViolation struct {
+	Namespace    string `json:"namespace,omitempty"`
+	Group        string `json:"group,omitempty"`
+	Version      string `json:"version,omitempty"`
+	ResourceType string `json:"resource_type"`
+	Name         string `json:"name"`
+	Policy       string `json:"policy,omitempty"`
+	Message      string `json:"message"`
+	Blocked      bool   `json:"blocked,omitempty"`
+}
+
+// SecretFinding records a suspected credential stored outside a Secret.
+// Field is the path of the value within the resource.
+type SecretFinding struct {
+	Namespace    string `json:"namespace,omitempty"`
+	Group        string `json:"group,omitempty"`
+	Version      string `json:"version,omitempty"`
+	ResourceType string `json:"resource_type"`
+	Name         string `json:"name"`
+	Detector     string `json:"detector"`
+	Field        string `json:"field"`
+	Blocked      bool   `json:"blocked,omitempty"`
+}
+
+// SetPolicyEvaluator checks every resource against the evaluator's Rego
+// policies before it is stored
+func (cb *ClusterBackup) SetPolicyEvaluator(evaluator *policy.Evaluator) {
+	cb.policyEvaluator = evaluator
+}
+
+// validateResource checks a resource about to be stored against the
+// configured policies and, with secret scanning on, for credentials left
+// outside Secrets, recording what it finds in the manifest. It returns
+// errResourceBlocked when strict validation keeps the resource out of the
+// backup. A policy query that fails is logged and lets the resource through.
+func (cb *ClusterBackup) validateResource(ctx context.Context, namespace string, gvr schema.GroupVersionResource, name string, data *payload, manifest *Manifest) error {
+	strict := cb.config.ValidationStrictMode
+	violations := cb.checkPolicies(ctx, namespace, gvr, name, data, manifest, strict)
+	findings := cb.scanSecrets(namespace, gvr, name, data, manifest, strict)
+
+	if strict && (violations > 0 || findings > 0) {
+		return errResourceBlocked
+	}
+	return nil
+}
+
+// checkPolicies records the policy violations of a resource and returns
+// their number
+func (cb *ClusterBackup) checkPolicies(ctx context.Context, namespace string, gvr schema.GroupVersionResource, name string, data *payload, manifest *Manifest, blocked bool) int {
+	if cb.policyEvaluator == nil {
+		return 0
+	}
+
+	violations, err := cb.policyEvaluator.Evaluate(ctx, data.object)
+	if err != nil {
+		cb.metrics.PolicyErrors.Inc()
+		cb.logger.Warning("policy_evaluation_failed", "Failed to check resource against policies", map[string]interface{}{
+			"namespace":     namespace,
+			"resource_type": gvr.Resource,
+			"resource_name": name,
+			"error":         err.Error(),
+		})
+		return 0
+	}
+
+	for _, violation := range violations {
+		manifest.AddPolicyViolation(PolicyViolation{
+			Namespace:    namespace,
+			Group:        gvr.Group,
+			Version:      gvr.Version,
+			ResourceType: gvr.Resource,
+			Name:         name,
+			Policy:       violation.Policy,
+			Message:      violation.Message,
+			Blocked:      blocked,
+		})
+		label := violation.Policy
+		if label == "" {
+			label = "unnamed"
+		}
+		cb.metrics.PolicyViolations.WithLabelValues(label).Inc()
+		cb.logger.Warning("policy_violation", "Resource violates a backup policy", map[string]interface{}{
+			"namespace":     namespace,
+			"resource_type": gvr.Resource,
+			"resource_name": name,
+			"policy":        violation.Policy,
+			"message":       violation.Message,
+			"blocked":       blocked,
+		})
+	}
+	return len(violations)
+}
+
+// scanSecrets records the suspected credentials in a resource and returns
+// their number. Only the field and detector are kept, never the value.
+func (cb *ClusterBackup) scanSecrets(namespace string, gvr schema.GroupVersionResource, name string, data *payload, manifest *Manifest, blocked bool) int {
+	if !cb.config.ScanForSecrets {
+		return 0
+	}
+
+	findings := secretscan.Scan(data.object)
+	for _, finding := range findings {
+		manifest.AddSecretFinding(SecretFinding{
+			Namespace:    namespace,
+			Group:        gvr.Group,
+			Version:      gvr.Version,
+			ResourceType: gvr.Resource,
+			Name:         name,
+			Detector:     finding.Detector,
+			Field:        finding.Field,
+			Blocked:      blocked,
+		})
+		cb.metrics.SecretFindings.WithLabelValues(finding.Detector).Inc()
+		cb.logger.Warning("secret_detected", "Resource appears to contain a credential outside a Secret", map[string]interface{}{
+			"namespace":     namespace,
+			"resource_type": gvr.Resource,
+			"resource_name": name,
+			"detector":      finding.Detector,
+			"field":         finding.Field,
+			"blocked":       blocked,
+		})
+	}
+	return len(findings)
+}
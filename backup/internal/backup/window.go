@@ -0,0 +1,265 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+
+	"cluster-backup/internal/failure"
+)
+
+// WindowEntry records the resources a run stored from the changes made while
+// it was in progress
+type WindowEntry struct {
+	// Until is when the watches stopped: the resources of the namespaces
+	// backed up are stored as of then at the latest
+	Until time.Time `json:"until"`
+	// Objects counts the resources created or updated during the run and
+	// stored again at its end
+	Objects int `json:"objects"`
+	// Errors lists the resource types that could not be watched for the
+	// whole run, and the changes that could not be stored
+	Errors []string `json:"errors,omitempty"`
+}
+
+// windowKey identifies a changed resource
+type windowKey struct {
+	gvr       schema.GroupVersionResource
+	namespace string
+	name      string
+}
+
+// windowWatch collects the latest state of the resources created or updated
+// in the run's namespaces while the run lists them. Deleted resources are
+// dropped; the run keeps the copy it listed.
+type windowWatch struct {
+	namespaces map[string]bool
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+
+	mu      sync.Mutex
+	changed map[windowKey]*unstructured.Unstructured
+	errors  []string
+}
+
+// startWindow watches the namespaced resource types across the cluster from
+// now on, under BACKUP_WINDOW_CAPTURE, or returns nil when it is off. A type
+// that cannot be watched, e.g. without cluster-wide watch permission, is
+// logged and left out.
+func (cb *ClusterBackup) startWindow(ctx context.Context, namespaces []string, apiResources []v1.APIResource) *windowWatch {
+	if !cb.config.WindowCapture {
+		return nil
+	}
+	watchCtx, cancel := context.WithCancel(ctx)
+	w := &windowWatch{
+		namespaces: make(map[string]bool, len(namespaces)),
+		cancel:     cancel,
+		changed:    make(map[windowKey]*unstructured.Unstructured),
+	}
+	for _, namespace := range namespaces {
+		w.namespaces[namespace] = true
+	}
+
+	watched := 0
+	for _, resource := range apiResources {
+		if !containsVerb(resource.Verbs, "watch") {
+			continue
+		}
+		gvr := schema.GroupVersionResource{Group: resource.Group, Version: resource.Version, Resource: resource.Name}
+		watcher, err := cb.watchResource(watchCtx, gvr)
+		if err != nil {
+			w.fail(gvr, err)
+			continue
+		}
+		watched++
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			defer watcher.Stop()
+			w.collect(gvr, watcher.ResultChan())
+		}()
+	}
+
+	cb.logger.Info("window_capture_started", "Watching for changes made during the backup", map[string]interface{}{
+		"resource_types": watched,
+		"unwatched":      len(apiResources) - watched,
+	})
+	return w
+}
+
+// watchResource watches a resource type in all namespaces from its current
+// resource version, which a list of one item returns, so the resources that
+// already exist are not sent as added. Expired watches are started again.
+func (cb *ClusterBackup) watchResource(ctx context.Context, gvr schema.GroupVersionResource) (*watchtools.RetryWatcher, error) {
+	selector := cb.filters().LabelSelector()
+	client := cb.dynamicClient.Resource(gvr)
+	list, err := client.List(ctx, v1.ListOptions{LabelSelector: selector, Limit: 1})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", gvr.Resource, err)
+	}
+	return watchtools.NewRetryWatcherWithContext(ctx, list.GetResourceVersion(), &cache.ListWatch{
+		WatchFuncWithContext: func(ctx context.Context, options v1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = selector
+			return client.Watch(ctx, options)
+		},
+	})
+}
+
+// collect records the changes of a resource type until events is closed, or
+// a watch error ends it
+func (w *windowWatch) collect(gvr schema.GroupVersionResource, events <-chan watch.Event) {
+	for event := range events {
+		if event.Type == watch.Error {
+			w.fail(gvr, apierrors.FromObject(event.Object))
+			return
+		}
+		item, ok := event.Object.(*unstructured.Unstructured)
+		if !ok || !w.namespaces[item.GetNamespace()] {
+			continue
+		}
+		key := windowKey{gvr: gvr, namespace: item.GetNamespace(), name: item.GetName()}
+
+		w.mu.Lock()
+		switch event.Type {
+		case watch.Added, watch.Modified:
+			w.changed[key] = item
+		case watch.Deleted:
+			delete(w.changed, key)
+		}
+		w.mu.Unlock()
+	}
+}
+
+// fail records a resource type whose changes are missing from the capture
+func (w *windowWatch) fail(gvr schema.GroupVersionResource, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.errors = append(w.errors, fmt.Sprintf("%s: %v", QualifiedResource(gvr, false), err))
+}
+
+// stop ends the watches; it may be called more than once, and on nil
+func (w *windowWatch) stop() {
+	if w == nil {
+		return
+	}
+	w.cancel()
+	w.wg.Wait()
+}
+
+// changes stops the watches and returns the resources changed, in the order
+// of their type, namespace and name, with the errors recorded
+func (w *windowWatch) changes() ([]windowKey, map[windowKey]*unstructured.Unstructured, []string) {
+	w.stop()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	keys := make([]windowKey, 0, len(w.changed))
+	for key := range w.changed {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.gvr != b.gvr {
+			return a.gvr.String() < b.gvr.String()
+		}
+		if a.namespace != b.namespace {
+			return a.namespace < b.namespace
+		}
+		return a.name < b.name
+	})
+	return keys, w.changed, append([]string(nil), w.errors...)
+}
+
+// captureWindow stops the watches and stores the resources changed since the
+// run listed them in place of the copies it stored, and those created since,
+// for the namespaces the run backed up in full. Resources whose content did
+// not change are left as they were. A change that cannot be stored is
+// recorded in the manifest but does not fail the run, which holds the copy
+// it listed; only stopping does.
+func (cb *ClusterBackup) captureWindow(ctx context.Context, w *windowWatch, manifest *Manifest) (int, error) {
+	keys, changed, errs := w.changes()
+	entry := WindowEntry{Until: time.Now().UTC(), Errors: errs}
+	defer func() { manifest.SetWindow(entry) }()
+
+	completed := manifest.completedNamespaces()
+	for _, key := range keys {
+		if !completed[key.namespace] {
+			continue
+		}
+		if cb.shutdown.stopping() {
+			return entry.Objects, ErrInterrupted
+		}
+		stored, err := cb.storeChange(ctx, key, changed[key], manifest)
+		if err != nil {
+			if stopped(err) || ctx.Err() != nil {
+				return entry.Objects, err
+			}
+			entry.Errors = append(entry.Errors, fmt.Sprintf("%s/%s/%s: %v", key.namespace, QualifiedResource(key.gvr, false), key.name, err))
+			cb.recordFailure(ctx, err)
+			continue
+		}
+		if stored {
+			entry.Objects++
+			cb.metrics.WindowObjects.Inc()
+		}
+	}
+
+	for _, err := range entry.Errors {
+		cb.logger.Warning("window_capture_incomplete", "Changes made during the backup may be missing", map[string]interface{}{
+			"backup_id": manifest.BackupID,
+			"error":     err,
+		})
+	}
+	cb.logger.Info("window_capture_complete", "Stored the resources changed during the backup", map[string]interface{}{
+		"backup_id": manifest.BackupID,
+		"changed":   len(keys),
+		"stored":    entry.Objects,
+	})
+	return entry.Objects, nil
+}
+
+// storeChange stores a changed resource the way the run stored those it
+// listed, and reports whether it was stored: it is not when the filters skip
+// it or its content is that of the copy already stored
+func (cb *ClusterBackup) storeChange(ctx context.Context, key windowKey, item *unstructured.Unstructured, manifest *Manifest) (bool, error) {
+	if cb.skipReason(item) != "" {
+		return false, nil
+	}
+	if dropped, err := cb.applyPlugins(key.gvr, item); err != nil || dropped != "" {
+		return false, err
+	}
+	data, err := newPayload(cb.cleanResource(item))
+	if err != nil {
+		return false, failure.Wrap(failure.ErrResourceInvalid, fmt.Errorf("invalid resource: %v", err))
+	}
+	for _, existing := range manifest.FindObject(key.namespace, QualifiedResource(key.gvr, false), key.name) {
+		if existing.Group == key.gvr.Group && existing.ResourceChecksum() == data.Checksum {
+			return false, nil
+		}
+	}
+
+	target, err := cb.targetFor(ctx, key.namespace)
+	if err != nil {
+		return false, err
+	}
+	entry, err := cb.storeResource(ctx, target, key.namespace, key.gvr, key.name, data, manifest)
+	if errors.Is(err, errResourceBlocked) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	manifest.SetObject(entry)
+	reindexObject(ctx, entry, data)
+	return true, nil
+}
@@ -0,0 +1,114 @@
+package backup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"cluster-backup/internal/search"
+)
+
+var configMaps = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+func windowConfigMap(namespace, name, value string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace, "labels": map[string]interface{}{"app": name}},
+		"data":       map[string]interface{}{"value": value},
+	}}
+}
+
+func newTestWindow(namespaces ...string) *windowWatch {
+	w := &windowWatch{
+		namespaces: make(map[string]bool),
+		cancel:     func() {},
+		changed:    make(map[windowKey]*unstructured.Unstructured),
+	}
+	for _, namespace := range namespaces {
+		w.namespaces[namespace] = true
+	}
+	return w
+}
+
+func TestWindowWatch_Collect(t *testing.T) {
+	w := newTestWindow("shop")
+	events := make(chan watch.Event, 10)
+	events <- watch.Event{Type: watch.Added, Object: windowConfigMap("shop", "created", "1")}
+	events <- watch.Event{Type: watch.Modified, Object: windowConfigMap("shop", "settings", "1")}
+	events <- watch.Event{Type: watch.Modified, Object: windowConfigMap("shop", "settings", "2")}
+	events <- watch.Event{Type: watch.Added, Object: windowConfigMap("shop", "short-lived", "1")}
+	events <- watch.Event{Type: watch.Deleted, Object: windowConfigMap("shop", "short-lived", "1")}
+	events <- watch.Event{Type: watch.Modified, Object: windowConfigMap("kube-system", "ignored", "1")}
+	close(events)
+	w.collect(configMaps, events)
+
+	keys, changed, errs := w.changes()
+	assert.Equal(t, []windowKey{
+		{gvr: configMaps, namespace: "shop", name: "created"},
+		{gvr: configMaps, namespace: "shop", name: "settings"},
+	}, keys)
+	assert.Equal(t, "2", changed[keys[1]].Object["data"].(map[string]interface{})["value"], "the latest state is kept")
+	assert.Empty(t, errs)
+
+	errored := make(chan watch.Event, 2)
+	errored <- watch.Event{Type: watch.Error, Object: &apierrors.NewResourceExpired("too old resource version").ErrStatus}
+	errored <- watch.Event{Type: watch.Added, Object: windowConfigMap("shop", "after-error", "1")}
+	w.collect(configMaps, errored)
+	_, changed, errs = w.changes()
+	assert.Len(t, changed, 2, "events after an error are not collected")
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0], "configmaps: ")
+}
+
+func TestCaptureWindow(t *testing.T) {
+	cb, bucket := newFanOutTestBackup(t, "")
+	cb.metrics.ResourcesBackedUp = prometheus.NewCounter(prometheus.CounterOpts{Name: "test_resources_backed_up_total"})
+	cb.metrics.WindowObjects = prometheus.NewCounter(prometheus.CounterOpts{Name: "test_window_objects_total"})
+
+	index := search.NewIndex("backup-1")
+	ctx := withRunIndex(withRunRecord(context.Background(), &runRecord{}), index)
+	manifest := NewManifest("backup-1", "prod", "example.com", "backups", time.Now())
+	for _, item := range []*unstructured.Unstructured{windowConfigMap("shop", "settings", "1"), windowConfigMap("shop", "unchanged", "1")} {
+		data, err := newPayload(cb.cleanResource(item))
+		require.NoError(t, err)
+		entry, err := cb.storeResource(ctx, cb.defaultTarget(), "shop", configMaps, item.GetName(), data, manifest)
+		require.NoError(t, err)
+		manifest.AddObject(entry)
+		indexObject(ctx, entry, data)
+	}
+	manifest.SetNamespace("shop", 2, nil)
+	manifest.SetNamespace("billing", 0, context.DeadlineExceeded)
+
+	w := newTestWindow("shop", "billing")
+	events := make(chan watch.Event, 10)
+	events <- watch.Event{Type: watch.Modified, Object: windowConfigMap("shop", "settings", "2")}
+	events <- watch.Event{Type: watch.Modified, Object: windowConfigMap("shop", "unchanged", "1")}
+	events <- watch.Event{Type: watch.Added, Object: windowConfigMap("shop", "created", "1")}
+	events <- watch.Event{Type: watch.Added, Object: windowConfigMap("billing", "invoices", "1")}
+	close(events)
+	w.collect(configMaps, events)
+
+	stored, err := cb.captureWindow(ctx, w, manifest)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored, "only changed resources of completed namespaces are stored")
+	assert.Equal(t, 2.0, testutil.ToFloat64(cb.metrics.WindowObjects))
+
+	require.Len(t, manifest.Objects, 3)
+	assert.Equal(t, "settings", manifest.Objects[0].Name, "replaced in place")
+	assert.Equal(t, "created", manifest.Objects[2].Name)
+	assert.Contains(t, string(bucket.objects["/backups/"+manifest.Objects[0].Key]), "value: \"2\"")
+	require.NotNil(t, manifest.Window)
+	assert.Equal(t, 2, manifest.Window.Objects)
+	assert.Empty(t, manifest.Window.Errors)
+	assert.Equal(t, 3, index.Len())
+}
@@ -0,0 +1,64 @@
+// Package buildinfo describes the running binary: the version and commit it
+// was built from and the image it runs in. Every backup manifest records it,
+// so restores can tell which version of the tool produced the data.
+package buildinfo
+
+import (
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Set at build time with
+// -ldflags "-X cluster-backup/internal/buildinfo.version=v1.4.0 -X cluster-backup/internal/buildinfo.commit=<sha>"
+var (
+	version = DevVersion
+	commit  string
+)
+
+// DevVersion is the version of binaries built without one
+const DevVersion = "dev"
+
+// ImageDigestEnv is the environment variable holding the digest of the
+// image the process runs in. Pods cannot read their image ID through the
+// downward API, so it is passed from an annotation of the pod.
+const ImageDigestEnv = "IMAGE_DIGEST"
+
+// ImageDigestAnnotation is the pod annotation ImageDigestEnv is set from
+const ImageDigestAnnotation = "cluster-backup.io/image-digest"
+
+// Info is the build metadata of a binary
+type Info struct {
+	Version     string `json:"version" yaml:"version"`
+	Commit      string `json:"commit,omitempty" yaml:"commit,omitempty"`
+	GoVersion   string `json:"go_version,omitempty" yaml:"go_version,omitempty"`
+	ImageDigest string `json:"image_digest,omitempty" yaml:"image_digest,omitempty"`
+}
+
+var current = sync.OnceValue(func() Info {
+	info := Info{
+		Version:     version,
+		Commit:      commit,
+		GoVersion:   runtime.Version(),
+		ImageDigest: os.Getenv(ImageDigestEnv),
+	}
+	// Without linker flags, go install and module builds still carry the
+	// module version and the commit the toolchain stamped
+	if build, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == DevVersion && build.Main.Version != "" && build.Main.Version != "(devel)" {
+			info.Version = build.Main.Version
+		}
+		for _, setting := range build.Settings {
+			if setting.Key == "vcs.revision" && info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		}
+	}
+	return info
+})
+
+// Get returns the build metadata of the running binary
+func Get() Info {
+	return current()
+}
@@ -0,0 +1,15 @@
+package buildinfo
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	info := Get()
+	assert.Equal(t, DevVersion, info.Version, "test binaries carry no version")
+	assert.Equal(t, runtime.Version(), info.GoVersion)
+	assert.Equal(t, info, Get())
+}
@@ -0,0 +1,244 @@
+// Package cleaning strips the fields of backed-up resources that the source
+// cluster assigned and the target cluster would reject or assign again, such
+// as a Service's cluster IP or the node a Pod was scheduled to. Each kind has
+// a profile of fields to strip; built-in profiles cover the common kinds and
+// a ConfigMap may replace them or add more.
+package cleaning
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ConfigMapKey is the key of the cleaning profiles ConfigMap holding the
+// profiles
+const ConfigMapKey = "cleaning-profiles.yaml"
+
+// Config lists cleaning profiles
+type Config struct {
+	Profiles []Profile `yaml:"profiles"`
+}
+
+// Profile lists what to strip from every resource of a kind
+type Profile struct {
+	// Group is the API group of the kind, empty for the core group
+	Group string `yaml:"group"`
+	Kind  string `yaml:"kind"`
+	// Fields are dot-separated paths such as spec.clusterIP. A path element
+	// ending in [] continues through every item of a list, as in
+	// spec.containers[].resources.
+	Fields []Field `yaml:"fields"`
+	// Annotations are annotation keys to remove
+	Annotations []string `yaml:"annotations"`
+}
+
+// Field is a field path, stripped unless it holds one of the Unless values.
+// A profile may list a field as a plain path.
+type Field struct {
+	Path   string   `yaml:"path"`
+	Unless []string `yaml:"unless"`
+}
+
+// UnmarshalYAML accepts a field as a plain path or as a path with values to
+// keep
+func (f *Field) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		f.Path = node.Value
+		return nil
+	}
+	type field Field
+	return node.Decode((*field)(f))
+}
+
+// builtinProfiles are applied unless the ConfigMap has a profile of the same
+// kind. A headless Service keeps its clusterIP None, which the target cannot
+// assign on its own.
+var builtinProfiles = []Profile{
+	{Kind: "Service", Fields: []Field{
+		{Path: "spec.clusterIP", Unless: []string{"None"}},
+		{Path: "spec.clusterIPs", Unless: []string{"None"}},
+	}},
+	{Kind: "Pod", Fields: []Field{{Path: "spec.nodeName"}}},
+	{Group: "autoscaling", Kind: "HorizontalPodAutoscaler", Annotations: []string{
+		"autoscaling.alpha.kubernetes.io/conditions",
+		"autoscaling.alpha.kubernetes.io/current-metrics",
+	}},
+}
+
+// Parse reads and checks a cleaning profiles configuration
+func Parse(data []byte) (*Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse cleaning profiles: %v", err)
+	}
+
+	seen := make(map[schema.GroupKind]bool)
+	for i, profile := range cfg.Profiles {
+		if profile.Kind == "" {
+			return nil, fmt.Errorf("cleaning profile %d has no kind", i+1)
+		}
+		groupKind := schema.GroupKind{Group: profile.Group, Kind: profile.Kind}
+		if seen[groupKind] {
+			return nil, fmt.Errorf("cleaning profile of %s is listed twice", groupKind)
+		}
+		seen[groupKind] = true
+		for _, field := range profile.Fields {
+			if err := checkPath(field.Path); err != nil {
+				return nil, fmt.Errorf("cleaning profile of %s: %v", groupKind, err)
+			}
+		}
+	}
+	return &cfg, nil
+}
+
+// checkPath rejects empty paths and paths with empty elements
+func checkPath(path string) error {
+	for _, element := range strings.Split(path, ".") {
+		if strings.TrimSuffix(element, "[]") == "" {
+			return fmt.Errorf("invalid field path %q", path)
+		}
+	}
+	if path == "metadata" || strings.HasPrefix(path, "metadata.name") || strings.HasPrefix(path, "metadata.namespace") {
+		return fmt.Errorf("field path %q would remove the identity of the resource", path)
+	}
+	return nil
+}
+
+// Profiles strips the fields of each kind's profile from resources
+type Profiles struct {
+	profiles map[schema.GroupKind]Profile
+}
+
+// Builtin returns the built-in profiles
+func Builtin() *Profiles {
+	return New(nil)
+}
+
+// New returns the built-in profiles with those of cfg replacing or adding to
+// them. A profile with nothing to strip keeps its kind intact.
+func New(cfg *Config) *Profiles {
+	profiles := make(map[schema.GroupKind]Profile)
+	for _, profile := range builtinProfiles {
+		profiles[schema.GroupKind{Group: profile.Group, Kind: profile.Kind}] = profile
+	}
+	if cfg != nil {
+		for _, profile := range cfg.Profiles {
+			profiles[schema.GroupKind{Group: profile.Group, Kind: profile.Kind}] = profile
+		}
+	}
+	return &Profiles{profiles: profiles}
+}
+
+// Load reads the cleaning profiles ConfigMap
+func Load(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string) (*Profiles, error) {
+	configMap, err := kubeClient.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cleaning profiles ConfigMap %s/%s: %v", namespace, name, err)
+	}
+	data, ok := configMap.Data[ConfigMapKey]
+	if !ok {
+		return nil, fmt.Errorf("%s not found in ConfigMap %s/%s", ConfigMapKey, namespace, name)
+	}
+	cfg, err := Parse([]byte(data))
+	if err != nil {
+		return nil, err
+	}
+	return New(cfg), nil
+}
+
+// Apply strips the fields of the profile of obj's kind from obj, and returns
+// the paths it removed
+func (p *Profiles) Apply(obj map[string]interface{}) []string {
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return nil
+	}
+	profile, ok := p.profiles[schema.GroupKind{Group: gv.Group, Kind: kind}]
+	if !ok {
+		return nil
+	}
+
+	var removed []string
+	for _, field := range profile.Fields {
+		if removeField(obj, strings.Split(field.Path, "."), field.Unless) {
+			removed = append(removed, field.Path)
+		}
+	}
+
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			for _, key := range profile.Annotations {
+				if _, exists := annotations[key]; exists {
+					delete(annotations, key)
+					removed = append(removed, "metadata.annotations."+key)
+				}
+			}
+			if len(annotations) == 0 {
+				delete(metadata, "annotations")
+			}
+		}
+	}
+	return removed
+}
+
+// removeField deletes the field at path from obj, through every item of the
+// lists path marks with [], unless it holds one of the unless values. It
+// reports whether anything was deleted.
+func removeField(obj map[string]interface{}, path []string, unless []string) bool {
+	element := path[0]
+	if list := strings.TrimSuffix(element, "[]"); list != element {
+		items, ok := obj[list].([]interface{})
+		if !ok || len(path) == 1 {
+			return false
+		}
+		removed := false
+		for _, item := range items {
+			if itemMap, ok := item.(map[string]interface{}); ok && removeField(itemMap, path[1:], unless) {
+				removed = true
+			}
+		}
+		return removed
+	}
+
+	if len(path) > 1 {
+		nested, ok := obj[element].(map[string]interface{})
+		return ok && removeField(nested, path[1:], unless)
+	}
+
+	value, ok := obj[element]
+	if !ok || holdsAny(value, unless) {
+		return false
+	}
+	delete(obj, element)
+	return true
+}
+
+// holdsAny reports whether value is one of values, or a non-empty list of
+// them
+func holdsAny(value interface{}, values []string) bool {
+	if len(values) == 0 {
+		return false
+	}
+	if items, ok := value.([]interface{}); ok {
+		for _, item := range items {
+			if !holdsAny(item, values) {
+				return false
+			}
+		}
+		return len(items) > 0
+	}
+	for _, keep := range values {
+		if value == keep {
+			return true
+		}
+	}
+	return false
+}
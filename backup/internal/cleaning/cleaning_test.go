@@ -0,0 +1,123 @@
+package cleaning
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testProfiles = `
+profiles:
+  - kind: Pod
+    fields: []
+  - group: apps
+    kind: Deployment
+    fields:
+      - spec.replicas
+      - path: spec.template.spec.containers[].image
+        unless: [nginx:stable]
+    annotations: [deployment.kubernetes.io/revision]
+`
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse([]byte(testProfiles))
+	require.NoError(t, err)
+	require.Len(t, cfg.Profiles, 2)
+	assert.Empty(t, cfg.Profiles[0].Fields)
+	assert.Equal(t, []Field{
+		{Path: "spec.replicas"},
+		{Path: "spec.template.spec.containers[].image", Unless: []string{"nginx:stable"}},
+	}, cfg.Profiles[1].Fields)
+
+	invalid := map[string]string{
+		"no_kind":    "profiles: [{fields: [spec.replicas]}]",
+		"duplicate":  "profiles: [{kind: Pod}, {kind: Pod, fields: [spec.nodeName]}]",
+		"empty_path": "profiles: [{kind: Pod, fields: ['spec..nodeName']}]",
+		"identity":   "profiles: [{kind: Pod, fields: [metadata.name]}]",
+	}
+	for name, data := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse([]byte(data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestApply_Builtin(t *testing.T) {
+	profiles := Builtin()
+
+	service := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"spec":       map[string]interface{}{"clusterIP": "10.96.4.12", "clusterIPs": []interface{}{"10.96.4.12"}, "type": "ClusterIP"},
+	}
+	assert.Equal(t, []string{"spec.clusterIP", "spec.clusterIPs"}, profiles.Apply(service))
+	assert.Equal(t, map[string]interface{}{"type": "ClusterIP"}, service["spec"])
+
+	headless := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"spec":       map[string]interface{}{"clusterIP": "None", "clusterIPs": []interface{}{"None"}},
+	}
+	assert.Empty(t, profiles.Apply(headless), "headless services keep their clusterIP")
+
+	hpa := map[string]interface{}{
+		"apiVersion": "autoscaling/v1",
+		"kind":       "HorizontalPodAutoscaler",
+		"metadata": map[string]interface{}{"annotations": map[string]interface{}{
+			"autoscaling.alpha.kubernetes.io/conditions":      "[]",
+			"autoscaling.alpha.kubernetes.io/current-metrics": "[]",
+		}},
+	}
+	assert.Len(t, profiles.Apply(hpa), 2)
+	assert.NotContains(t, hpa["metadata"], "annotations")
+
+	deployment := map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"spec":       map[string]interface{}{"replicas": int64(3)},
+	}
+	assert.Empty(t, profiles.Apply(deployment), "kinds without a profile are left unchanged")
+}
+
+func TestLoad(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup-cleaning", Namespace: "backup-system"},
+		Data:       map[string]string{ConfigMapKey: testProfiles},
+	})
+	profiles, err := Load(context.Background(), kubeClient, "backup-system", "backup-cleaning")
+	require.NoError(t, err)
+
+	pod := map[string]interface{}{"apiVersion": "v1", "kind": "Pod", "spec": map[string]interface{}{"nodeName": "worker-1"}}
+	assert.Empty(t, profiles.Apply(pod), "an empty profile replaces the built-in one")
+
+	deployment := map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"annotations": map[string]interface{}{"deployment.kubernetes.io/revision": "4", "team": "shop"}},
+		"spec": map[string]interface{}{
+			"replicas": int64(3),
+			"template": map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+				map[string]interface{}{"name": "web", "image": "nginx:stable"},
+				map[string]interface{}{"name": "sidecar", "image": "envoy:1.29"},
+			}}},
+		},
+	}
+	assert.Equal(t, []string{"spec.replicas", "spec.template.spec.containers[].image", "metadata.annotations.deployment.kubernetes.io/revision"},
+		profiles.Apply(deployment))
+	containers := deployment["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})
+	assert.Equal(t, "nginx:stable", containers[0].(map[string]interface{})["image"])
+	assert.NotContains(t, containers[1], "image")
+	assert.Equal(t, map[string]interface{}{"team": "shop"}, deployment["metadata"].(map[string]interface{})["annotations"])
+
+	service := map[string]interface{}{"apiVersion": "v1", "kind": "Service", "spec": map[string]interface{}{"clusterIP": "10.96.4.12"}}
+	assert.Len(t, profiles.Apply(service), 1, "built-in profiles of other kinds still apply")
+
+	_, err = Load(context.Background(), kubeClient, "backup-system", "missing")
+	assert.Error(t, err)
+}
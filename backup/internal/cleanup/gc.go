@@ -0,0 +1,122 @@
+package cleanup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"cluster-backup/internal/backup"
+)
+
+// GCResult represents the result of a blob garbage collection pass
+type GCResult struct {
+	BlobsScanned     int
+	BlobsDeleted     int
+	ManifestsScanned int
+	SpaceFreed       int64
+	Errors           []error
+}
+
+// CollectGarbage removes content-addressed blobs that no run manifest in the
+// bucket references and that were written before cutoff. Blobs are shared by
+// every cluster using the bucket, so the manifests of all clusters are read;
+// nothing is deleted if any of them cannot be.
+func (cm *Manager) CollectGarbage(ctx context.Context, cutoff time.Time) (*GCResult, error) {
+	result := &GCResult{Errors: []error{}}
+
+	// Blobs are listed before manifests, so a blob a run starts referencing
+	// during the pass is found in its manifest
+	var blobs []minio.ObjectInfo
+	for object := range cm.minioClient.ListObjects(ctx, cm.config.MinIOBucket, minio.ListObjectsOptions{
+		Prefix:    backup.BlobPrefix,
+		Recursive: true,
+	}) {
+		if object.Err != nil {
+			return nil, fmt.Errorf("failed to list blobs: %v", object.Err)
+		}
+		blobs = append(blobs, object)
+	}
+	result.BlobsScanned = len(blobs)
+	if len(blobs) == 0 {
+		return result, nil
+	}
+
+	keys, err := backup.AllManifestKeys(ctx, cm.minioClient, cm.config.MinIOBucket)
+	if err != nil {
+		return nil, err
+	}
+	referenced := make(map[string]bool)
+	for _, key := range keys {
+		manifest, err := cm.loadManifest(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("refusing to collect blobs: %v", err)
+		}
+		for _, objects := range [][]backup.ObjectEntry{manifest.Objects, manifest.AlternateVersions} {
+			for _, object := range objects {
+				if backup.IsBlobPath(object.Key) {
+					referenced[object.Key] = true
+				}
+			}
+		}
+	}
+	result.ManifestsScanned = len(keys)
+
+	candidates, size := unreferencedBlobs(blobs, referenced, cutoff)
+	cm.logger.Info("blob_gc_scan_complete", "Completed scanning blobs for garbage collection", map[string]interface{}{
+		"blobs_scanned":     result.BlobsScanned,
+		"blobs_referenced":  len(referenced),
+		"manifests_scanned": result.ManifestsScanned,
+		"blobs_to_delete":   len(candidates),
+	})
+	if len(candidates) == 0 {
+		return result, nil
+	}
+
+	deletedCount, failedDeletes := cm.removeObjects(ctx, cm.defaultTarget(), candidates)
+	result.BlobsDeleted = deletedCount
+	result.SpaceFreed = size
+	for _, deleteErr := range failedDeletes {
+		result.Errors = append(result.Errors, fmt.Errorf("failed to delete blob: %s", deleteErr))
+	}
+	cm.metrics.BlobsCollected.Add(float64(deletedCount))
+
+	return result, nil
+}
+
+// loadManifest reads a run manifest by bucket key
+func (cm *Manager) loadManifest(ctx context.Context, key string) (*backup.Manifest, error) {
+	object, err := cm.minioClient.GetObject(ctx, cm.config.MinIOBucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %v", key, err)
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %v", key, err)
+	}
+	manifest := &backup.Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %v", key, err)
+	}
+	return manifest, nil
+}
+
+// unreferencedBlobs returns the keys and total size of blobs that are not
+// referenced and were last written before cutoff
+func unreferencedBlobs(blobs []minio.ObjectInfo, referenced map[string]bool, cutoff time.Time) ([]string, int64) {
+	var keys []string
+	var size int64
+	for _, blob := range blobs {
+		if referenced[blob.Key] || !blob.LastModified.Before(cutoff) {
+			continue
+		}
+		keys = append(keys, blob.Key)
+		size += blob.Size
+	}
+	return keys, size
+}
@@ -0,0 +1,42 @@
+package cleanup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+
+	"cluster-backup/internal/backup"
+)
+
+func TestUnreferencedBlobs(t *testing.T) {
+	cutoff := time.Now().Add(-24 * time.Hour)
+	old := cutoff.Add(-time.Hour)
+	recent := cutoff.Add(time.Hour)
+
+	referencedOld := backup.BlobPath("aa11")
+	unreferencedOld := backup.BlobPath("bb22")
+	unreferencedRecent := backup.BlobPath("cc33")
+
+	blobs := []minio.ObjectInfo{
+		{Key: referencedOld, LastModified: old, Size: 10},
+		{Key: unreferencedOld, LastModified: old, Size: 20},
+		{Key: unreferencedRecent, LastModified: recent, Size: 30},
+	}
+	referenced := map[string]bool{referencedOld: true}
+
+	keys, size := unreferencedBlobs(blobs, referenced, cutoff)
+	assert.Equal(t, []string{unreferencedOld}, keys)
+	assert.Equal(t, int64(20), size)
+}
+
+func TestBlobAndManifestPaths(t *testing.T) {
+	assert.Equal(t, "_blobs/sha256/ab/abcdef", backup.BlobPath("abcdef"))
+	assert.True(t, backup.IsBlobPath(backup.BlobPath("abcdef")))
+	assert.False(t, backup.IsBlobPath("example.com/prod/default/configmaps/app.yaml"))
+
+	assert.True(t, backup.IsManifestPath("example.com/prod/_manifests/run-1.json"))
+	assert.False(t, backup.IsManifestPath("example.com/prod/default/_manifests/run-1.json"))
+	assert.False(t, backup.IsManifestPath("example.com/prod/_manifests/run-1.yaml"))
+}
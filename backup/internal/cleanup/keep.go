@@ -0,0 +1,93 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+
+	"cluster-backup/internal/backup"
+)
+
+// keptRuns are the runs cleanup keeps however old they are, and the keys of
+// everything they reference by bucket
+type keptRuns struct {
+	backupIDs []string
+	keys      map[string]map[string]bool
+}
+
+func (k *keptRuns) add(bucket, key string) {
+	if k.keys[bucket] == nil {
+		k.keys[bucket] = make(map[string]bool)
+	}
+	k.keys[bucket][key] = true
+}
+
+// has reports whether a kept run references the object at key in bucket
+func (k *keptRuns) has(bucket, key string) bool {
+	return k.keys[bucket][key]
+}
+
+// loadKeptRuns finds the RETENTION_KEEP_LAST most recent completed runs of
+// each cluster in the bucket, so an outage longer than the retention does not
+// leave a cluster without a backup to restore. A manifest that cannot be read
+// is passed over, which only keeps older runs as well; when the manifests
+// cannot be listed nothing is known to be safe to delete.
+func (cm *Manager) loadKeptRuns(ctx context.Context) (*keptRuns, error) {
+	kept := &keptRuns{keys: make(map[string]map[string]bool)}
+	if cm.config.RetentionKeepLast <= 0 {
+		return kept, nil
+	}
+
+	keys, err := backup.AllManifestKeys(ctx, cm.minioClient, cm.config.MinIOBucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the runs to keep: %v", err)
+	}
+	for _, manifestKeys := range manifestsByCluster(keys) {
+		completed := 0
+		for _, key := range manifestKeys {
+			if completed >= cm.config.RetentionKeepLast {
+				break
+			}
+			manifest, err := cm.loadManifest(ctx, key)
+			if err != nil {
+				cm.logger.Warning("cleanup_manifest_unreadable", "Skipping unreadable manifest when picking the runs to keep", map[string]interface{}{
+					"manifest": key,
+					"error":    err.Error(),
+				})
+				continue
+			}
+			if manifest.Status != backup.ManifestStatusCompleted {
+				continue
+			}
+			completed++
+
+			kept.backupIDs = append(kept.backupIDs, manifest.BackupID)
+			kept.add(cm.config.MinIOBucket, key)
+			for bucket, objectKeys := range manifest.StoredKeys() {
+				if bucket == "" {
+					bucket = cm.config.MinIOBucket
+				}
+				for _, objectKey := range objectKeys {
+					kept.add(bucket, objectKey)
+				}
+			}
+		}
+	}
+	return kept, nil
+}
+
+// manifestsByCluster groups manifest keys by the cluster prefix they are
+// under, newest first: backup IDs start with the run's start time, so they
+// sort by age
+func manifestsByCluster(keys []string) map[string][]string {
+	clusters := make(map[string][]string)
+	for _, key := range keys {
+		cluster := path.Dir(path.Dir(key))
+		clusters[cluster] = append(clusters[cluster], key)
+	}
+	for _, manifestKeys := range clusters {
+		sort.Sort(sort.Reverse(sort.StringSlice(manifestKeys)))
+	}
+	return clusters
+}
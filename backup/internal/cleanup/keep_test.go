@@ -0,0 +1,36 @@
+package cleanup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"cluster-backup/internal/backup"
+)
+
+func TestManifestsByCluster(t *testing.T) {
+	keys := []string{
+		backup.ManifestPath("example.com", "prod", "backup-20250102-020000"),
+		backup.ManifestPath("example.com", "staging", "backup-20250101-020000"),
+		backup.ManifestPath("example.com", "prod", "backup-20250103-020000"),
+		backup.ManifestPath("example.com", "prod", "backup-20250101-020000"),
+	}
+
+	assert.Equal(t, map[string][]string{
+		"example.com/prod": {
+			backup.ManifestPath("example.com", "prod", "backup-20250103-020000"),
+			backup.ManifestPath("example.com", "prod", "backup-20250102-020000"),
+			backup.ManifestPath("example.com", "prod", "backup-20250101-020000"),
+		},
+		"example.com/staging": {backup.ManifestPath("example.com", "staging", "backup-20250101-020000")},
+	}, manifestsByCluster(keys))
+}
+
+func TestKeptRuns(t *testing.T) {
+	kept := &keptRuns{keys: make(map[string]map[string]bool)}
+	kept.add("cluster-backups", "example.com/prod/shop/configmaps/app.yaml")
+
+	assert.True(t, kept.has("cluster-backups", "example.com/prod/shop/configmaps/app.yaml"))
+	assert.False(t, kept.has("payments-backups", "example.com/prod/shop/configmaps/app.yaml"))
+	assert.False(t, kept.has("cluster-backups", "example.com/prod/shop/configmaps/web.yaml"))
+}
@@ -2,15 +2,22 @@ package cleanup
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/minio/minio-go/v7"
+	"k8s.io/client-go/kubernetes"
 
+	"cluster-backup/internal/alerting"
+	"cluster-backup/internal/backup"
 	"cluster-backup/internal/config"
 	"cluster-backup/internal/logging"
 	"cluster-backup/internal/metrics"
+	"cluster-backup/internal/priority"
+	"cluster-backup/internal/tenancy"
 )
 
 // Manager handles cleanup operations for old backup files
@@ -19,12 +26,21 @@ type Manager struct {
 	minioClient *minio.Client
 	logger      *logging.StructuredLogger
 	metrics     *metrics.BackupMetrics
+	alerter     *alerting.Alerter
+	tenancy     *tenancy.Router
+	kubeClient  kubernetes.Interface
+	priorities  *priority.Manager
 	ctx         context.Context
 }
 
 // CleanupResult represents the result of a cleanup operation
 type CleanupResult struct {
 	FilesDeleted  int
+	// FilesKept are past retention but referenced by a kept run
+	FilesKept     int
+	// TrashPurged are trashed objects deleted after the grace period
+	TrashPurged   int
+	BlobsDeleted  int
 	FilesScanned  int
 	SpaceFreed    int64
 	Errors        []error
@@ -50,8 +66,28 @@ func NewManager(
 	}
 }
 
-// PerformCleanup performs cleanup of old backup files based on retention policy
+// SetTenancy extends the cleanup to the buckets of the tenants
+func (cm *Manager) SetTenancy(router *tenancy.Router) {
+	cm.tenancy = router
+}
+
+// SetAlerter fires an Alertmanager alert when a cleanup has more errors than
+// ALERT_CLEANUP_ERRORS
+func (cm *Manager) SetAlerter(alerter *alerting.Alerter) {
+	cm.alerter = alerter
+}
+
+// PerformCleanup performs cleanup of old backup files based on retention
+// policy. Once BACKUP_CLEANUP_TIMEOUT expires it stops and fails with a
+// deadline error; what was deleted until then stays deleted.
 func (cm *Manager) PerformCleanup() (*CleanupResult, error) {
+	ctx := cm.ctx
+	if cm.config.BackupCleanupTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(cm.ctx, cm.config.BackupCleanupTimeout)
+		defer cancel()
+	}
+
 	startTime := time.Now()
 	cm.logger.Info("cleanup_start", "Starting backup cleanup operation", map[string]interface{}{
 		"retention_days": cm.config.RetentionDays,
@@ -63,20 +99,117 @@ func (cm *Manager) PerformCleanup() (*CleanupResult, error) {
 		Errors:    []error{},
 	}
 
-	// Calculate cutoff time for retention
-	cutoffTime := time.Now().AddDate(0, 0, -cm.config.RetentionDays)
+	// Calculate cutoff time for retention. Deleting on RETENTION_DAYS alone
+	// could remove backups a namespace is meant to keep longer, or the last
+	// restorable runs, so nothing is deleted when either cannot be read.
+	retention, err := cm.loadRetention(ctx, startTime)
+	var kept *keptRuns
+	if err == nil {
+		kept, err = cm.loadKeptRuns(ctx)
+	}
+	if err != nil {
+		cm.logger.Error("cleanup_skipped", "Failed to read what cleanup must keep, skipping cleanup", map[string]interface{}{
+			"error": err.Error(),
+		})
+		result.Errors = append(result.Errors, err)
+		result.EndTime = time.Now()
+		result.Duration = result.EndTime.Sub(result.StartTime)
+		cm.checkAlerts(result)
+		return result, err
+	}
+	cutoffTime := retention.defaultCutoff()
 	cm.logger.Info("cleanup_cutoff", "Cleanup cutoff time calculated", map[string]interface{}{
-		"cutoff_time":    cutoffTime.Format(time.RFC3339),
-		"retention_days": cm.config.RetentionDays,
+		"cutoff_time":         cutoffTime.Format(time.RFC3339),
+		"retention_days":      cm.config.RetentionDays,
+		"namespace_overrides": len(retention.annotations),
+		"kept_runs":           kept.backupIDs,
 	})
 
-	// List all objects in the backup bucket
-	objectCh := cm.minioClient.ListObjects(cm.ctx, cm.config.MinIOBucket, minio.ListObjectsOptions{
+	// The default bucket, then the tenants' buckets
+	targets := cm.targets()
+	for _, target := range targets {
+		cm.cleanupTarget(ctx, target, nestedPrefixes(target, targets), retention, kept, result)
+		cm.emptyTrash(ctx, target, startTime, result)
+	}
+
+	// Runs past retention are gone now, so their blobs are unreferenced
+	gc, err := cm.CollectGarbage(ctx, cutoffTime)
+	if err != nil {
+		cm.logger.Error("blob_gc_failed", "Blob garbage collection failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+		result.Errors = append(result.Errors, err)
+	} else {
+		result.BlobsDeleted = gc.BlobsDeleted
+		result.SpaceFreed += gc.SpaceFreed
+		result.Errors = append(result.Errors, gc.Errors...)
+	}
+
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+
+	cm.logger.Info("cleanup_complete", "Completed backup cleanup operation", map[string]interface{}{
+		"files_scanned":   result.FilesScanned,
+		"files_deleted":   result.FilesDeleted,
+		"files_kept":      result.FilesKept,
+		"trash_purged":    result.TrashPurged,
+		"mode":            cm.config.CleanupMode,
+		"blobs_deleted":   result.BlobsDeleted,
+		"space_freed_mb":  result.SpaceFreed / (1024 * 1024),
+		"error_count":     len(result.Errors),
+		"duration_ms":     result.Duration.Milliseconds(),
+	})
+	cm.checkAlerts(result)
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err := &backup.DeadlineError{Phase: "cleanup", Setting: "BACKUP_CLEANUP_TIMEOUT", Timeout: cm.config.BackupCleanupTimeout}
+		cm.logger.Error("cleanup_deadline_exceeded", "Backup cleanup ran out of time", map[string]interface{}{
+			"timeout":       cm.config.BackupCleanupTimeout.String(),
+			"files_deleted": result.FilesDeleted,
+		})
+		return result, err
+	}
+	return result, nil
+}
+
+// cleanupTarget deletes the objects of one bucket, or of a tenant's prefix in
+// it, that are past their retention and no kept run references
+func (cm *Manager) cleanupTarget(ctx context.Context, target *tenancy.Target, skipPrefixes []string, retention *retentionPolicy, kept *keptRuns, result *CleanupResult) {
+	expired := cm.expiredObjects(ctx, target, skipPrefixes, retention, kept, result)
+	if len(expired) == 0 {
+		return
+	}
+
+	objectsToDelete := make([]string, 0, len(expired))
+	var totalSize int64
+	for _, object := range expired {
+		objectsToDelete = append(objectsToDelete, object.Key)
+		totalSize += object.Size
+	}
+
+	// Delete, or trash, objects in batches for better performance
+	deletedCount, failedDeletes := cm.removeObjects(ctx, target, objectsToDelete)
+	result.FilesDeleted += deletedCount
+	result.SpaceFreed += totalSize // This is an estimate
+
+	// Add any delete errors to the result
+	for _, deleteErr := range failedDeletes {
+		result.Errors = append(result.Errors, fmt.Errorf("failed to delete object: %s", deleteErr))
+	}
+}
+
+// expiredObjects lists the objects of target that are past their retention
+// and no kept run references. Keys under skipPrefixes belong to tenants
+// cleaned up separately.
+func (cm *Manager) expiredObjects(ctx context.Context, target *tenancy.Target, skipPrefixes []string, retention *retentionPolicy, kept *keptRuns, result *CleanupResult) []minio.ObjectInfo {
+	objectCh := target.Client.ListObjects(ctx, target.Bucket, minio.ListObjectsOptions{
+		Prefix:    target.Prefix,
 		Recursive: true,
 	})
 
-	var objectsToDelete []string
+	var expired []minio.ObjectInfo
 	var totalSize int64
+	scanned := 0
 
 	for object := range objectCh {
 		if object.Err != nil {
@@ -84,11 +217,22 @@ func (cm *Manager) PerformCleanup() (*CleanupResult, error) {
 			continue
 		}
 
+		// Blobs may be referenced by recent runs however old they are; they
+		// are collected by reference below. The trash has its own grace period.
+		if backup.IsBlobPath(object.Key) || backup.IsTrashPath(target.TrimKey(object.Key)) || hasAnyPrefix(object.Key, skipPrefixes) {
+			continue
+		}
+
 		result.FilesScanned++
+		scanned++
 
-		// Check if object is older than retention period
-		if object.LastModified.Before(cutoffTime) {
-			objectsToDelete = append(objectsToDelete, object.Key)
+		// Check if object is older than the retention of its namespace
+		if object.LastModified.Before(retention.cutoff(target.TrimKey(object.Key))) {
+			if kept.has(target.Bucket, object.Key) {
+				result.FilesKept++
+				continue
+			}
+			expired = append(expired, object)
 			totalSize += object.Size
 
 			cm.logger.Debug("cleanup_candidate", "Found object candidate for deletion", map[string]interface{}{
@@ -101,47 +245,89 @@ func (cm *Manager) PerformCleanup() (*CleanupResult, error) {
 	}
 
 	cm.logger.Info("cleanup_scan_complete", "Completed scanning objects for cleanup", map[string]interface{}{
-		"files_scanned":        result.FilesScanned,
-		"files_to_delete":      len(objectsToDelete),
+		"bucket":               target.Bucket,
+		"tenant":               target.Tenant,
+		"files_scanned":        scanned,
+		"files_to_delete":      len(expired),
 		"estimated_space_mb":   totalSize / (1024 * 1024),
 	})
+	return expired
+}
 
-	if len(objectsToDelete) == 0 {
-		result.EndTime = time.Now()
-		result.Duration = result.EndTime.Sub(result.StartTime)
-		cm.logger.Info("cleanup_complete", "No files to cleanup", map[string]interface{}{
-			"files_scanned": result.FilesScanned,
-			"duration_ms":   result.Duration.Milliseconds(),
-		})
-		return result, nil
+// targets returns the default bucket and the tenants' buckets and prefixes.
+// A tenant stored in the default bucket without a prefix of its own is
+// covered by its cleanup.
+func (cm *Manager) targets() []*tenancy.Target {
+	targets := []*tenancy.Target{cm.defaultTarget()}
+	if cm.tenancy == nil {
+		return targets
+	}
+	seen := make(map[string]bool)
+	for _, target := range cm.tenancy.Tenants() {
+		location := target.Bucket + "/" + target.Prefix
+		if (target.Bucket == cm.config.MinIOBucket && target.Prefix == "") || seen[location] {
+			continue
+		}
+		seen[location] = true
+		targets = append(targets, target)
 	}
+	return targets
+}
 
-	// Delete objects in batches for better performance
-	deletedCount, failedDeletes := cm.batchDeleteObjects(objectsToDelete)
-	result.FilesDeleted = deletedCount
-	result.SpaceFreed = totalSize // This is an estimate
-	
-	// Add any delete errors to the result
-	for _, deleteErr := range failedDeletes {
-		result.Errors = append(result.Errors, fmt.Errorf("failed to delete object: %s", deleteErr))
+// nestedPrefixes returns the prefixes of the other targets inside target's
+// bucket, which a scan of the whole bucket must leave to them
+func nestedPrefixes(target *tenancy.Target, targets []*tenancy.Target) []string {
+	if target.Prefix != "" {
+		return nil
 	}
+	var prefixes []string
+	for _, other := range targets {
+		if other != target && other.Bucket == target.Bucket && other.Prefix != "" {
+			prefixes = append(prefixes, strings.TrimSuffix(other.Prefix, "/")+"/")
+		}
+	}
+	return prefixes
+}
 
-	result.EndTime = time.Now()
-	result.Duration = result.EndTime.Sub(result.StartTime)
+func hasAnyPrefix(key string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
 
-	cm.logger.Info("cleanup_complete", "Completed backup cleanup operation", map[string]interface{}{
-		"files_scanned":   result.FilesScanned,
-		"files_deleted":   result.FilesDeleted,
-		"space_freed_mb":  result.SpaceFreed / (1024 * 1024),
-		"error_count":     len(result.Errors),
-		"duration_ms":     result.Duration.Milliseconds(),
-	})
+// defaultTarget is the bucket and client of MINIO_BUCKET
+func (cm *Manager) defaultTarget() *tenancy.Target {
+	return &tenancy.Target{Client: cm.minioClient, Bucket: cm.config.MinIOBucket}
+}
 
-	return result, nil
+// checkAlerts records a cleanup's error count and fires or resolves the
+// cleanup alert
+func (cm *Manager) checkAlerts(result *CleanupResult) {
+	cm.metrics.CleanupErrors.Set(float64(len(result.Errors)))
+	if cm.alerter == nil {
+		return
+	}
+
+	lastError := ""
+	if len(result.Errors) > 0 {
+		lastError = result.Errors[len(result.Errors)-1].Error()
+	}
+	// The cleanup's own context may have run out
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := cm.alerter.CheckCleanup(ctx, len(result.Errors), lastError); err != nil {
+		cm.logger.Warning("alert_send_failed", "Failed to send cleanup alerts to Alertmanager", map[string]interface{}{
+			"error_count": len(result.Errors),
+			"error":       err.Error(),
+		})
+	}
 }
 
 // batchDeleteObjects deletes objects in batches for better performance
-func (cm *Manager) batchDeleteObjects(objectKeys []string) (int, []string) {
+func (cm *Manager) batchDeleteObjects(ctx context.Context, target *tenancy.Target, objectKeys []string) (int, []string) {
 	const batchSize = 1000
 	deletedCount := 0
 	var failedDeletes []string
@@ -170,8 +356,8 @@ func (cm *Manager) batchDeleteObjects(objectKeys []string) (int, []string) {
 		close(objectsCh)
 
 		// Perform batch deletion
-		ctx, cancel := context.WithTimeout(cm.ctx, 5*time.Minute)
-		errorCh := cm.minioClient.RemoveObjects(ctx, cm.config.MinIOBucket, objectsCh, minio.RemoveObjectsOptions{})
+		batchCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		errorCh := target.Client.RemoveObjects(batchCtx, target.Bucket, objectsCh, minio.RemoveObjectsOptions{})
 
 		// Process deletion results
 		batchDeletedCount := 0
@@ -235,7 +421,15 @@ func (cm *Manager) getCleanupTiming() string {
 
 // EstimateCleanupImpact estimates how many files would be deleted without actually deleting them
 func (cm *Manager) EstimateCleanupImpact() (*CleanupEstimate, error) {
-	cutoffTime := time.Now().AddDate(0, 0, -cm.config.RetentionDays)
+	retention, err := cm.loadRetention(cm.ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	kept, err := cm.loadKeptRuns(cm.ctx)
+	if err != nil {
+		return nil, err
+	}
+	cutoffTime := retention.defaultCutoff()
 	
 	objectCh := cm.minioClient.ListObjects(cm.ctx, cm.config.MinIOBucket, minio.ListObjectsOptions{
 		Recursive: true,
@@ -250,10 +444,17 @@ func (cm *Manager) EstimateCleanupImpact() (*CleanupEstimate, error) {
 			return nil, fmt.Errorf("error listing object for estimate: %v", object.Err)
 		}
 
+		if backup.IsBlobPath(object.Key) || backup.IsTrashPath(object.Key) {
+			continue
+		}
+
 		estimate.TotalFiles++
 		estimate.TotalSize += object.Size
 
-		if object.LastModified.Before(cutoffTime) {
+		expired := object.LastModified.Before(retention.cutoff(object.Key))
+		if expired && kept.has(cm.config.MinIOBucket, object.Key) {
+			estimate.FilesKept++
+		} else if expired {
 			estimate.FilesToDelete++
 			estimate.SpaceToFree += object.Size
 			
@@ -276,6 +477,7 @@ func (cm *Manager) EstimateCleanupImpact() (*CleanupEstimate, error) {
 type CleanupEstimate struct {
 	TotalFiles         int
 	FilesToDelete      int
+	FilesKept          int
 	TotalSize          int64
 	SpaceToFree        int64
 	CutoffTime         time.Time
@@ -291,6 +493,7 @@ func (ce *CleanupEstimate) GetSummary() map[string]interface{} {
 		"total_files":           ce.TotalFiles,
 		"files_to_delete":       ce.FilesToDelete,
 		"files_to_keep":         ce.TotalFiles - ce.FilesToDelete,
+		"files_kept_for_runs":   ce.FilesKept,
 		"total_size_mb":         ce.TotalSize / (1024 * 1024),
 		"space_to_free_mb":      ce.SpaceToFree / (1024 * 1024),
 		"retention_days":        retentionDays,
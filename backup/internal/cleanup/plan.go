@@ -0,0 +1,287 @@
+package cleanup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"cluster-backup/internal/backup"
+	"cluster-backup/internal/tenancy"
+)
+
+// Audit log status values
+const (
+	AuditStatusStarted   = "started"
+	AuditStatusCompleted = "completed"
+)
+
+// CleanupPlan lists exactly what a cleanup would delete, grouped by the run
+// each object belongs to
+type CleanupPlan struct {
+	CreatedAt     time.Time    `json:"created_at"`
+	CutoffTime    time.Time    `json:"cutoff_time"`
+	RetentionDays int          `json:"retention_days"`
+	KeptRuns      []string     `json:"kept_runs,omitempty"`
+	FilesKept     int          `json:"files_kept"`
+	Runs          []PlannedRun `json:"runs"`
+}
+
+// PlannedRun is the part of a cleanup plan belonging to one run. BackupID is
+// empty for objects no manifest in the bucket references.
+type PlannedRun struct {
+	BackupID string          `json:"backup_id,omitempty"`
+	Cluster  string          `json:"cluster,omitempty"`
+	Size     int64           `json:"size"`
+	Objects  []PlannedObject `json:"objects"`
+}
+
+// PlannedObject is an object a cleanup plan deletes
+type PlannedObject struct {
+	Bucket       string    `json:"bucket"`
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+
+	target *tenancy.Target
+}
+
+// Objects returns the number of objects the plan deletes
+func (p *CleanupPlan) Objects() int {
+	count := 0
+	for _, run := range p.Runs {
+		count += len(run.Objects)
+	}
+	return count
+}
+
+// Size returns the bytes the plan frees
+func (p *CleanupPlan) Size() int64 {
+	var size int64
+	for _, run := range p.Runs {
+		size += run.Size
+	}
+	return size
+}
+
+// CleanupAudit is the record of a confirmed cleanup kept in the bucket. It is
+// written before anything is deleted and rewritten with the outcome.
+type CleanupAudit struct {
+	Status     string       `json:"status"`
+	Mode       string       `json:"mode"`
+	Host       string       `json:"host,omitempty"`
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at,omitempty"`
+	Deleted    int          `json:"deleted"`
+	Failed     []string     `json:"failed,omitempty"`
+	Plan       *CleanupPlan `json:"plan"`
+}
+
+// PlanCleanup selects the objects a cleanup would delete now, as
+// PerformCleanup does, without deleting anything. Objects are attributed to
+// the newest run whose manifest lists them. Content-addressed blobs are left
+// to the garbage collection of scheduled cleanups.
+func (cm *Manager) PlanCleanup() (*CleanupPlan, error) {
+	ctx := cm.ctx
+	now := time.Now()
+
+	retention, err := cm.loadRetention(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	kept, err := cm.loadKeptRuns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	runs, err := cm.indexRuns(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &CleanupPlan{
+		CreatedAt:     now,
+		CutoffTime:    retention.defaultCutoff(),
+		RetentionDays: cm.config.RetentionDays,
+		KeptRuns:      kept.backupIDs,
+	}
+	scan := &CleanupResult{}
+	var objects []PlannedObject
+	targets := cm.targets()
+	for _, target := range targets {
+		for _, object := range cm.expiredObjects(ctx, target, nestedPrefixes(target, targets), retention, kept, scan) {
+			objects = append(objects, PlannedObject{
+				Bucket:       target.Bucket,
+				Key:          object.Key,
+				Size:         object.Size,
+				LastModified: object.LastModified,
+				target:       target,
+			})
+		}
+	}
+	if len(scan.Errors) > 0 {
+		return nil, fmt.Errorf("failed to list the objects to delete: %v", scan.Errors[0])
+	}
+	plan.FilesKept = scan.FilesKept
+	plan.Runs = groupByRun(objects, runs)
+	return plan, nil
+}
+
+// groupByRun groups objects by the run runs attributes them to, ordered by
+// cluster and the time runs were taken, with unattributed objects last
+func groupByRun(objects []PlannedObject, runs map[string]runRef) []PlannedRun {
+	byRun := make(map[runRef]*PlannedRun)
+	for _, object := range objects {
+		ref := runs[object.Bucket+"/"+object.Key]
+		run, ok := byRun[ref]
+		if !ok {
+			run = &PlannedRun{BackupID: ref.backupID, Cluster: ref.cluster}
+			byRun[ref] = run
+		}
+		run.Size += object.Size
+		run.Objects = append(run.Objects, object)
+	}
+
+	grouped := make([]PlannedRun, 0, len(byRun))
+	for _, run := range byRun {
+		grouped = append(grouped, *run)
+	}
+	sort.Slice(grouped, func(i, j int) bool {
+		a, b := grouped[i], grouped[j]
+		if (a.BackupID == "") != (b.BackupID == "") {
+			return b.BackupID == ""
+		}
+		if a.Cluster != b.Cluster {
+			return a.Cluster < b.Cluster
+		}
+		return a.BackupID < b.BackupID
+	})
+	return grouped
+}
+
+// ExecutePlan deletes the objects of a plan and nothing else, or moves them to
+// the trash in trash mode, recording the
+// plan and its outcome in an audit log object in the bucket. Nothing is
+// deleted when the audit log cannot be written. It returns the audit log and
+// its key.
+func (cm *Manager) ExecutePlan(plan *CleanupPlan) (*CleanupAudit, string, error) {
+	ctx := cm.ctx
+	if cm.config.BackupCleanupTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(cm.ctx, cm.config.BackupCleanupTimeout)
+		defer cancel()
+	}
+
+	audit := &CleanupAudit{Status: AuditStatusStarted, Mode: cm.config.CleanupMode, StartedAt: time.Now(), Plan: plan}
+	audit.Host, _ = os.Hostname()
+	auditKey := backup.AuditPath(cm.config.ClusterDomain, cm.config.ClusterName, "cleanup-"+audit.StartedAt.UTC().Format("20060102-150405"))
+	if err := cm.writeAudit(ctx, auditKey, audit); err != nil {
+		return nil, "", err
+	}
+	cm.logger.Info("cleanup_execute_start", "Deleting the objects of a confirmed cleanup plan", map[string]interface{}{
+		"objects":   plan.Objects(),
+		"size_mb":   plan.Size() / (1024 * 1024),
+		"audit_log": auditKey,
+	})
+
+	// Delete per bucket with the credentials the objects were listed with
+	var targets []*tenancy.Target
+	keys := make(map[*tenancy.Target][]string)
+	for _, run := range plan.Runs {
+		for _, object := range run.Objects {
+			if object.target == nil {
+				return nil, "", fmt.Errorf("object %s is not from a plan of this cleanup manager", object.Key)
+			}
+			if _, ok := keys[object.target]; !ok {
+				targets = append(targets, object.target)
+			}
+			keys[object.target] = append(keys[object.target], object.Key)
+		}
+	}
+	for _, target := range targets {
+		deleted, failed := cm.removeObjects(ctx, target, keys[target])
+		audit.Deleted += deleted
+		audit.Failed = append(audit.Failed, failed...)
+	}
+
+	audit.Status = AuditStatusCompleted
+	audit.FinishedAt = time.Now()
+	cm.logger.Info("cleanup_execute_complete", "Deleted the objects of a confirmed cleanup plan", map[string]interface{}{
+		"deleted":   audit.Deleted,
+		"failed":    len(audit.Failed),
+		"audit_log": auditKey,
+	})
+	if err := cm.writeAudit(ctx, auditKey, audit); err != nil {
+		return audit, auditKey, err
+	}
+	if len(audit.Failed) > 0 {
+		return audit, auditKey, fmt.Errorf("failed to delete %d of %d objects", len(audit.Failed), plan.Objects())
+	}
+	return audit, auditKey, nil
+}
+
+// writeAudit stores the audit log of a cleanup in the default bucket
+func (cm *Manager) writeAudit(ctx context.Context, key string, audit *CleanupAudit) error {
+	data, err := json.MarshalIndent(audit, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize audit log: %v", err)
+	}
+	_, err = cm.minioClient.PutObject(ctx, cm.config.MinIOBucket, key, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: "application/json",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write audit log %s: %v", key, err)
+	}
+	return nil
+}
+
+// runRef identifies the run an object belongs to
+type runRef struct {
+	cluster  string
+	backupID string
+}
+
+// indexRuns maps the bucket and key of every object a run manifest in the
+// bucket lists, and of the manifest itself, to the newest run listing it.
+// Resources are stored at the same key by every run, so only the newest
+// listing still describes the object.
+func (cm *Manager) indexRuns(ctx context.Context) (map[string]runRef, error) {
+	keys, err := backup.AllManifestKeys(ctx, cm.minioClient, cm.config.MinIOBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]runRef)
+	add := func(bucket, key string, ref runRef) {
+		if _, ok := index[bucket+"/"+key]; !ok {
+			index[bucket+"/"+key] = ref
+		}
+	}
+	for cluster, manifestKeys := range manifestsByCluster(keys) {
+		for _, key := range manifestKeys {
+			manifest, err := cm.loadManifest(ctx, key)
+			if err != nil {
+				cm.logger.Warning("cleanup_manifest_unreadable", "Skipping unreadable manifest when grouping objects by run", map[string]interface{}{
+					"manifest": key,
+					"error":    err.Error(),
+				})
+				continue
+			}
+			ref := runRef{cluster: cluster, backupID: manifest.BackupID}
+			add(cm.config.MinIOBucket, key, ref)
+			for bucket, objectKeys := range manifest.StoredKeys() {
+				if bucket == "" {
+					bucket = cm.config.MinIOBucket
+				}
+				for _, objectKey := range objectKeys {
+					add(bucket, objectKey, ref)
+				}
+			}
+		}
+	}
+	return index, nil
+}
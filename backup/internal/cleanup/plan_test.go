@@ -0,0 +1,48 @@
+package cleanup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/backup"
+)
+
+func TestGroupByRun(t *testing.T) {
+	prod1 := runRef{cluster: "example.com/prod", backupID: "backup-20250101-020000"}
+	prod2 := runRef{cluster: "example.com/prod", backupID: "backup-20250102-020000"}
+	runs := map[string]runRef{
+		"cluster-backups/" + backup.ManifestPath("example.com", "prod", prod2.backupID): prod2,
+		"cluster-backups/" + backup.ManifestPath("example.com", "prod", prod1.backupID): prod1,
+		"cluster-backups/example.com/prod/shop/configmaps/app.yaml":                     prod1,
+		"payments-backups/example.com/prod/payments/secrets/db.yaml":                    prod2,
+	}
+	objects := []PlannedObject{
+		{Bucket: "cluster-backups", Key: "example.com/prod/old/configmaps/stale.yaml", Size: 1},
+		{Bucket: "payments-backups", Key: "example.com/prod/payments/secrets/db.yaml", Size: 2},
+		{Bucket: "cluster-backups", Key: backup.ManifestPath("example.com", "prod", prod2.backupID), Size: 4},
+		{Bucket: "cluster-backups", Key: "example.com/prod/shop/configmaps/app.yaml", Size: 8},
+		{Bucket: "cluster-backups", Key: backup.ManifestPath("example.com", "prod", prod1.backupID), Size: 16},
+	}
+
+	grouped := groupByRun(objects, runs)
+	require.Len(t, grouped, 3)
+	assert.Equal(t, prod1.backupID, grouped[0].BackupID)
+	assert.Equal(t, "example.com/prod", grouped[0].Cluster)
+	assert.Equal(t, int64(24), grouped[0].Size)
+	assert.Len(t, grouped[0].Objects, 2)
+	assert.Equal(t, prod2.backupID, grouped[1].BackupID)
+	assert.Equal(t, int64(6), grouped[1].Size, "objects in tenants' buckets belong to the run too")
+	assert.Equal(t, "", grouped[2].BackupID, "objects no run lists come last")
+
+	plan := &CleanupPlan{Runs: grouped}
+	assert.Equal(t, 5, plan.Objects())
+	assert.Equal(t, int64(31), plan.Size())
+}
+
+func TestAuditPath(t *testing.T) {
+	key := backup.AuditPath("example.com", "prod", "cleanup-20250101-020000")
+	assert.Equal(t, "example.com/prod/_audit/cleanup-20250101-020000.json", key)
+	assert.Equal(t, "", backup.PathNamespace("example.com", "prod", key))
+}
@@ -0,0 +1,109 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"cluster-backup/internal/backup"
+	"cluster-backup/internal/priority"
+)
+
+// RetentionAnnotation on a namespace replaces RETENTION_DAYS for the
+// namespace's backups, e.g. backup.cluster/retention-days: "90"
+const RetentionAnnotation = "backup.cluster/retention-days"
+
+// maxRetentionDays bounds the per-namespace overrides, as
+// ValidateRetentionPolicy does for RETENTION_DAYS
+const maxRetentionDays = 3650
+
+// retentionPolicy decides the cutoff of each object: objects of a namespace
+// with an override expire after its retention, everything else after
+// RETENTION_DAYS
+type retentionPolicy struct {
+	now           time.Time
+	defaultDays   int
+	clusterDomain string
+	clusterName   string
+	// annotations holds the namespaces annotated with RetentionAnnotation,
+	// which take precedence over the priority ConfigMap
+	annotations map[string]int
+	priorities  *priority.Manager
+}
+
+// SetRetentionOverrides makes the cleanup honor the retention of namespaces
+// annotated with RetentionAnnotation and the retention_days namespace
+// overrides of the priority ConfigMap. Either may be nil.
+func (cm *Manager) SetRetentionOverrides(kubeClient kubernetes.Interface, priorities *priority.Manager) {
+	cm.kubeClient = kubeClient
+	cm.priorities = priorities
+}
+
+// loadRetention reads the retention annotations of the cluster's namespaces
+func (cm *Manager) loadRetention(ctx context.Context, now time.Time) (*retentionPolicy, error) {
+	policy := &retentionPolicy{
+		now:           now,
+		defaultDays:   cm.config.RetentionDays,
+		clusterDomain: cm.config.ClusterDomain,
+		clusterName:   cm.config.ClusterName,
+		annotations:   make(map[string]int),
+		priorities:    cm.priorities,
+	}
+	if cm.kubeClient == nil {
+		return policy, nil
+	}
+
+	namespaces, err := cm.kubeClient.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces for retention overrides: %v", err)
+	}
+	for _, ns := range namespaces.Items {
+		value, ok := ns.Annotations[RetentionAnnotation]
+		if !ok {
+			continue
+		}
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 1 || days > maxRetentionDays {
+			cm.logger.Warning("retention_override_invalid", "Ignoring invalid retention annotation", map[string]interface{}{
+				"namespace":  ns.Name,
+				"annotation": RetentionAnnotation,
+				"value":      value,
+			})
+			continue
+		}
+		policy.annotations[ns.Name] = days
+	}
+	return policy, nil
+}
+
+// days returns the retention of a namespace's backups
+func (p *retentionPolicy) days(namespace string) int {
+	if namespace == "" {
+		return p.defaultDays
+	}
+	if days, ok := p.annotations[namespace]; ok {
+		return days
+	}
+	if p.priorities != nil {
+		if days := p.priorities.GetNamespaceRetentionDays(namespace); days > 0 && days <= maxRetentionDays {
+			return days
+		}
+	}
+	return p.defaultDays
+}
+
+// defaultCutoff is the cutoff of RETENTION_DAYS
+func (p *retentionPolicy) defaultCutoff() time.Time {
+	return p.now.AddDate(0, 0, -p.defaultDays)
+}
+
+// cutoff returns the time before which the object at key expires. Keys of
+// other clusters in the bucket follow RETENTION_DAYS.
+func (p *retentionPolicy) cutoff(key string) time.Time {
+	namespace := backup.PathNamespace(p.clusterDomain, p.clusterName, key)
+	return p.now.AddDate(0, 0, -p.days(namespace))
+}
@@ -0,0 +1,106 @@
+package cleanup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"cluster-backup/internal/backup"
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/logging"
+	"cluster-backup/internal/priority"
+	"cluster-backup/internal/tenancy"
+)
+
+const testPriorityConfig = `
+special_handling:
+  namespace_overrides:
+    payments:
+      retention_days: 30
+    audit-*:
+      retention_days: 365
+    shop:
+      priority_boost: 10
+`
+
+func namespace(name string, annotations map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations}}
+}
+
+func TestRetentionOverrides(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(
+		namespace("payments", map[string]string{RetentionAnnotation: "90"}),
+		namespace("scratch", map[string]string{RetentionAnnotation: "1"}),
+		namespace("broken", map[string]string{RetentionAnnotation: "forever"}),
+		namespace("shop", nil),
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: priority.DefaultConfigMap, Namespace: priority.DefaultNamespace},
+			Data:       map[string]string{"priority-config.yaml": testPriorityConfig},
+		},
+	)
+	priorities := priority.NewManager(kubeClient, priority.DefaultConfigMap, priority.DefaultNamespace)
+	require.NoError(t, priorities.LoadConfig())
+
+	cfg := &config.Config{ClusterDomain: "example.com", ClusterName: "prod", RetentionDays: 7}
+	manager := NewManager(cfg, nil, logging.NewStructuredLogger("test", "prod"), nil, context.Background())
+	manager.SetRetentionOverrides(kubeClient, priorities)
+
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	retention, err := manager.loadRetention(context.Background(), now)
+	require.NoError(t, err)
+
+	assert.Equal(t, 90, retention.days("payments"), "the annotation wins over the ConfigMap")
+	assert.Equal(t, 1, retention.days("scratch"))
+	assert.Equal(t, 7, retention.days("broken"), "invalid annotations are ignored")
+	assert.Equal(t, 365, retention.days("audit-eu"), "ConfigMap overrides may be patterns")
+	assert.Equal(t, 7, retention.days("shop"))
+
+	assert.Equal(t, now.AddDate(0, 0, -90), retention.cutoff(backup.ObjectPath("example.com", "prod", "payments", "secrets", "db")))
+	assert.Equal(t, now.AddDate(0, 0, -90), retention.cutoff(backup.DataPath("example.com", "prod", "backup-1", "payments", "db.postgresql.sql")))
+	assert.Equal(t, now.AddDate(0, 0, -1), retention.cutoff(backup.OpenShiftPath("example.com", "prod", "scratch", "routes", "web")))
+	assert.Equal(t, now.AddDate(0, 0, -7), retention.cutoff(backup.ManifestPath("example.com", "prod", "backup-1")))
+	assert.Equal(t, now.AddDate(0, 0, -7), retention.cutoff(backup.ObjectPath("example.com", "staging", "payments", "secrets", "db")),
+		"overrides only apply to this cluster's namespaces")
+	assert.Equal(t, now.AddDate(0, 0, -7), retention.defaultCutoff())
+}
+
+func TestRetentionWithoutOverrides(t *testing.T) {
+	cfg := &config.Config{ClusterDomain: "example.com", ClusterName: "prod", RetentionDays: 7}
+	manager := NewManager(cfg, nil, logging.NewStructuredLogger("test", "prod"), nil, context.Background())
+
+	now := time.Now()
+	retention, err := manager.loadRetention(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, -7), retention.cutoff(backup.ObjectPath("example.com", "prod", "payments", "secrets", "db")))
+}
+
+func TestPathNamespace(t *testing.T) {
+	assert.Equal(t, "shop", backup.PathNamespace("example.com", "prod", backup.ObjectPath("example.com", "prod", "shop", "configmaps", "app")))
+	assert.Equal(t, "", backup.PathNamespace("example.com", "prod", backup.ObjectPath("example.com", "prod", "", "nodes", "node-1")))
+	assert.Equal(t, "", backup.PathNamespace("example.com", "prod", backup.CRDPath("example.com", "prod", "widgets.example.com")))
+	assert.Equal(t, "", backup.PathNamespace("example.com", "prod", backup.EtcdSnapshotPath("example.com", "prod", "backup-1")))
+	assert.Equal(t, "", backup.PathNamespace("example.com", "prod", backup.OpenShiftPath("example.com", "prod", "", "scc-bindings", "restricted")))
+
+	key := backup.ObjectPath("example.com", "prod", "shop", "configmaps", "app")
+	assert.Equal(t, "shop", backup.PathNamespace("example.com", "prod", backup.RunPath("example.com", "prod", "backup-20250101-020000-0a1b2c3d", key)))
+	assert.Equal(t, "shop", backup.PathNamespace("example.com", "prod", backup.LatestPath("example.com", "prod", key)))
+	assert.Equal(t, "", backup.PathNamespace("example.com", "prod", backup.RunPath("example.com", "prod", "backup-20250101-020000-0a1b2c3d", backup.CRDPath("example.com", "prod", "widgets.example.com"))))
+}
+
+func TestNestedPrefixes(t *testing.T) {
+	defaultTarget := &tenancy.Target{Bucket: "cluster-backups"}
+	shop := &tenancy.Target{Tenant: "shop", Bucket: "cluster-backups", Prefix: "tenants/shop"}
+	payments := &tenancy.Target{Tenant: "payments", Bucket: "payments-backups"}
+	targets := []*tenancy.Target{defaultTarget, shop, payments}
+
+	assert.Equal(t, []string{"tenants/shop/"}, nestedPrefixes(defaultTarget, targets))
+	assert.Empty(t, nestedPrefixes(shop, targets))
+	assert.Empty(t, nestedPrefixes(payments, targets))
+	assert.Equal(t, "example.com/prod/shop/configmaps/app.yaml", shop.TrimKey("tenants/shop/example.com/prod/shop/configmaps/app.yaml"))
+}
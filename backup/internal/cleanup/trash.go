@@ -0,0 +1,136 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+
+	"cluster-backup/internal/backup"
+	"cluster-backup/internal/config"
+	"cluster-backup/internal/tenancy"
+)
+
+// removeObjects deletes expired objects, or moves them to the trash in trash
+// mode. It returns how many were removed and the keys that were not.
+func (cm *Manager) removeObjects(ctx context.Context, target *tenancy.Target, keys []string) (int, []string) {
+	if cm.config.CleanupMode == config.CleanupModeTrash {
+		return cm.trashObjects(ctx, target, keys)
+	}
+	// Only failures are reported for each key, so count the rest
+	_, failed := cm.batchDeleteObjects(ctx, target, keys)
+	return len(keys) - len(failed), failed
+}
+
+// trashKey is where an object of target is kept in the trash. Tenants' trash
+// stays under their prefix, within reach of their credentials.
+func trashKey(target *tenancy.Target, key string) string {
+	return target.Key(backup.TrashPath(target.TrimKey(key)))
+}
+
+// trashObjects copies objects to the trash and deletes the originals. An
+// object whose copy fails is left in place. The copies are written now, so
+// the grace period runs from when an object was trashed.
+func (cm *Manager) trashObjects(ctx context.Context, target *tenancy.Target, keys []string) (int, []string) {
+	var moved, failed []string
+	for _, key := range keys {
+		_, err := target.Client.CopyObject(ctx,
+			minio.CopyDestOptions{Bucket: target.Bucket, Object: trashKey(target, key)},
+			minio.CopySrcOptions{Bucket: target.Bucket, Object: key})
+		if err != nil {
+			cm.logger.Warning("cleanup_trash_failed", "Failed to move object to the trash", map[string]interface{}{
+				"object_key": key,
+				"error":      err.Error(),
+			})
+			failed = append(failed, key)
+			continue
+		}
+		moved = append(moved, key)
+	}
+	if len(moved) == 0 {
+		return 0, failed
+	}
+
+	_, deleteFailed := cm.batchDeleteObjects(ctx, target, moved)
+	return len(moved) - len(deleteFailed), append(failed, deleteFailed...)
+}
+
+// emptyTrash deletes the objects of target trashed more than
+// CLEANUP_TRASH_GRACE_PERIOD ago. It runs in either mode, so switching back to
+// delete mode still empties the trash.
+func (cm *Manager) emptyTrash(ctx context.Context, target *tenancy.Target, now time.Time, result *CleanupResult) {
+	cutoff := now.Add(-cm.config.CleanupTrashGracePeriod)
+	var expired []string
+	for object := range target.Client.ListObjects(ctx, target.Bucket, minio.ListObjectsOptions{
+		Prefix:    target.Key(backup.TrashPrefix),
+		Recursive: true,
+	}) {
+		if object.Err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("error listing trash: %v", object.Err))
+			return
+		}
+		if object.LastModified.Before(cutoff) {
+			expired = append(expired, object.Key)
+			result.SpaceFreed += object.Size
+		}
+	}
+	if len(expired) == 0 {
+		return
+	}
+
+	_, failed := cm.batchDeleteObjects(ctx, target, expired)
+	result.TrashPurged += len(expired) - len(failed)
+	for _, key := range failed {
+		result.Errors = append(result.Errors, fmt.Errorf("failed to purge trashed object: %s", key))
+	}
+}
+
+// RestoreFromTrash moves trashed objects whose original key starts with
+// prefix back into place, in the default bucket and the tenants' buckets. An
+// empty prefix restores the whole trash. It returns how many were restored.
+func (cm *Manager) RestoreFromTrash(prefix string) (int, error) {
+	ctx := cm.ctx
+	restored := 0
+	var failed []string
+	for _, target := range cm.targets() {
+		var keys []string
+		for object := range target.Client.ListObjects(ctx, target.Bucket, minio.ListObjectsOptions{
+			Prefix:    target.Key(backup.TrashPath(prefix)),
+			Recursive: true,
+		}) {
+			if object.Err != nil {
+				return restored, fmt.Errorf("failed to list trash of bucket %s: %v", target.Bucket, object.Err)
+			}
+			keys = append(keys, object.Key)
+		}
+
+		var moved []string
+		for _, key := range keys {
+			original := target.Key(backup.UntrashPath(target.TrimKey(key)))
+			_, err := target.Client.CopyObject(ctx,
+				minio.CopyDestOptions{Bucket: target.Bucket, Object: original},
+				minio.CopySrcOptions{Bucket: target.Bucket, Object: key})
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", original, err))
+				continue
+			}
+			moved = append(moved, key)
+		}
+		restored += len(moved)
+		if len(moved) > 0 {
+			// A copy left behind in the trash is only purged later
+			cm.batchDeleteObjects(ctx, target, moved)
+		}
+	}
+
+	cm.logger.Info("trash_restore_complete", "Restored objects from the trash", map[string]interface{}{
+		"prefix":   prefix,
+		"restored": restored,
+		"failed":   len(failed),
+	})
+	if len(failed) > 0 {
+		return restored, fmt.Errorf("failed to restore %d objects: %v", len(failed), failed[0])
+	}
+	return restored, nil
+}
@@ -0,0 +1,32 @@
+package cleanup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"cluster-backup/internal/backup"
+	"cluster-backup/internal/tenancy"
+)
+
+func TestTrashKey(t *testing.T) {
+	key := backup.ObjectPath("example.com", "prod", "shop", "configmaps", "app")
+
+	defaultTarget := &tenancy.Target{Bucket: "cluster-backups"}
+	assert.Equal(t, "_trash/example.com/prod/shop/configmaps/app.yaml", trashKey(defaultTarget, key))
+
+	shop := &tenancy.Target{Tenant: "shop", Bucket: "cluster-backups", Prefix: "tenants/shop"}
+	trashed := trashKey(shop, shop.Key(key))
+	assert.Equal(t, "tenants/shop/_trash/example.com/prod/shop/configmaps/app.yaml", trashed,
+		"tenants' trash stays under their prefix")
+	assert.True(t, backup.IsTrashPath(shop.TrimKey(trashed)))
+	assert.Equal(t, shop.Key(key), shop.Key(backup.UntrashPath(shop.TrimKey(trashed))))
+}
+
+func TestTrashPaths(t *testing.T) {
+	key := backup.TrashPath(backup.ManifestPath("example.com", "prod", "backup-20250101-020000"))
+	assert.True(t, backup.IsTrashPath(key))
+	assert.False(t, backup.IsManifestPath(key), "trashed manifests are not listed as runs")
+	assert.Equal(t, "", backup.PathNamespace("example.com", "prod", key))
+	assert.Equal(t, "example.com/prod/_manifests/backup-20250101-020000.json", backup.UntrashPath(key))
+}
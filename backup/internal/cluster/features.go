@@ -0,0 +1,68 @@
+package cluster
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/client-go/rest"
+)
+
+// featureMetric is the API server metric reporting each feature gate, since
+// Kubernetes 1.26
+const featureMetric = "kubernetes_feature_enabled"
+
+// FeatureGates reads the API server's feature gates from its metrics. It
+// needs get on the /metrics non-resource URL; servers before 1.26 report no
+// gates.
+func FeatureGates(ctx context.Context, client rest.Interface) (map[string]bool, error) {
+	if client == nil {
+		return nil, fmt.Errorf("no REST client to read the API server metrics with")
+	}
+	data, err := client.Get().AbsPath("/metrics").DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API server metrics: %v", err)
+	}
+	return ParseFeatureGates(bytes.NewReader(data))
+}
+
+// ParseFeatureGates returns the feature gates in Prometheus metrics text,
+// keyed by name
+func ParseFeatureGates(r io.Reader) (map[string]bool, error) {
+	gates := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, featureMetric+"{") {
+			continue
+		}
+		labels, value, found := strings.Cut(strings.TrimPrefix(line, featureMetric+"{"), "} ")
+		if !found {
+			continue
+		}
+		name := metricLabel(labels, "name")
+		if name == "" {
+			continue
+		}
+		gates[name] = strings.TrimSpace(value) == "1"
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse API server metrics: %v", err)
+	}
+	return gates, nil
+}
+
+// metricLabel returns the value of a label in the label set of a metric line
+func metricLabel(labels, name string) string {
+	for _, pair := range strings.Split(labels, ",") {
+		key, value, found := strings.Cut(pair, "=")
+		if found && strings.TrimSpace(key) == name {
+			return strings.Trim(value, `"`)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,475 @@
+package compat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/discovery"
+
+	"cluster-backup/internal/backup"
+	"cluster-backup/internal/buildinfo"
+	"cluster-backup/internal/cluster"
+)
+
+// Exit codes of a compatibility check, as for configuration validation
+const (
+	CheckPassed   = 0
+	CheckFailed   = 1
+	CheckWarnings = 2
+)
+
+// Checks a finding can come from
+const (
+	CheckSource       = "source"
+	CheckVersion      = "kubernetes_version"
+	CheckRemovedAPI   = "removed_api"
+	CheckOtherVersion = "other_version"
+	CheckMissingAPI   = "missing_api"
+	CheckMissingCRD   = "missing_crd"
+	CheckDiscovery    = "discovery"
+	CheckFeatureGate  = "feature_gate"
+	CheckToolVersion  = "tool_version"
+)
+
+// How the target serves a type of the backup
+const (
+	TypeServed             = "served"
+	TypeAlternateVersion   = "alternate_version"
+	TypeConverted          = "converted"
+	TypeOtherVersion       = "other_version"
+	TypeInstalledByRestore = "installed_by_restore"
+	TypeMissing            = "missing"
+	TypeUnknown            = "unknown"
+)
+
+// Target is what a restore target serves
+type Target struct {
+	KubernetesVersion string
+	// Resources are the types of every group version served, not only the
+	// preferred ones
+	Resources []*v1.APIResourceList
+	// Unavailable are the group versions whose discovery failed
+	Unavailable []string
+	// FeatureGates are nil when the API server's metrics cannot be read
+	FeatureGates map[string]bool
+}
+
+// Options are the restore policies a check enforces besides compatibility
+type Options struct {
+	// MinBackupVersion fails backups made by an older version of the tool,
+	// or by one that does not record its version
+	MinBackupVersion string
+}
+
+// Report is the outcome of checking a backup against a restore target
+type Report struct {
+	BackupID      string `json:"backup_id" yaml:"backup_id"`
+	Compatible    bool   `json:"compatible" yaml:"compatible"`
+	SourceVersion string `json:"source_version,omitempty" yaml:"source_version,omitempty"`
+	TargetVersion string `json:"target_version,omitempty" yaml:"target_version,omitempty"`
+	// Build is the version of the tool that made the backup
+	Build *buildinfo.Info `json:"build,omitempty" yaml:"build,omitempty"`
+	// FeatureGatesChecked is false when either cluster's gates are unknown
+	FeatureGatesChecked bool        `json:"feature_gates_checked" yaml:"feature_gates_checked"`
+	Types               []TypeCheck `json:"types" yaml:"types"`
+	Errors              []Finding   `json:"errors" yaml:"errors"`
+	Warnings            []Finding   `json:"warnings" yaml:"warnings"`
+}
+
+// TypeCheck is how the target serves a type objects of the backup are stored
+// as
+type TypeCheck struct {
+	Group    string `json:"group,omitempty" yaml:"group,omitempty"`
+	Version  string `json:"version" yaml:"version"`
+	Kind     string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	Resource string `json:"resource" yaml:"resource"`
+	Objects  int    `json:"objects" yaml:"objects"`
+	Status   string `json:"status" yaml:"status"`
+	// ServedVersions are the target's versions of the type
+	ServedVersions []string `json:"served_versions,omitempty" yaml:"served_versions,omitempty"`
+}
+
+// Finding is an error or warning about restoring the backup to the target
+type Finding struct {
+	Check    string `json:"check" yaml:"check"`
+	Resource string `json:"resource,omitempty" yaml:"resource,omitempty"`
+	Message  string `json:"message" yaml:"message"`
+}
+
+// ExitCode returns CheckFailed when the backup cannot be fully restored,
+// CheckWarnings when it can with warnings, and CheckPassed otherwise
+func (r *Report) ExitCode() int {
+	switch {
+	case len(r.Errors) > 0:
+		return CheckFailed
+	case len(r.Warnings) > 0:
+		return CheckWarnings
+	default:
+		return CheckPassed
+	}
+}
+
+func (r *Report) addError(check, resource, message string) {
+	r.Errors = append(r.Errors, Finding{Check: check, Resource: resource, Message: message})
+}
+
+func (r *Report) addWarning(check, resource, message string) {
+	r.Warnings = append(r.Warnings, Finding{Check: check, Resource: resource, Message: message})
+}
+
+// removedAPI is a built-in API version Kubernetes removed
+type removedAPI struct {
+	removedIn string
+	// replacement is the group version replacing it, empty when none does
+	replacement string
+}
+
+// removedAPIs are the removed versions of built-in types, from the
+// Kubernetes deprecated API migration guide
+var removedAPIs = func() map[schema.GroupVersionResource]removedAPI {
+	apis := make(map[schema.GroupVersionResource]removedAPI)
+	add := func(groupVersion string, resources []string, removed removedAPI) {
+		gv, _ := schema.ParseGroupVersion(groupVersion)
+		for _, resource := range resources {
+			apis[gv.WithResource(resource)] = removed
+		}
+	}
+
+	add("extensions/v1beta1", []string{"deployments", "daemonsets", "replicasets"}, removedAPI{"1.16", "apps/v1"})
+	add("extensions/v1beta1", []string{"networkpolicies"}, removedAPI{"1.16", "networking.k8s.io/v1"})
+	add("extensions/v1beta1", []string{"podsecuritypolicies"}, removedAPI{"1.16", ""})
+	add("extensions/v1beta1", []string{"ingresses"}, removedAPI{"1.22", "networking.k8s.io/v1"})
+	add("apps/v1beta1", []string{"deployments", "statefulsets"}, removedAPI{"1.16", "apps/v1"})
+	add("apps/v1beta2", []string{"deployments", "statefulsets", "daemonsets", "replicasets"}, removedAPI{"1.16", "apps/v1"})
+	add("networking.k8s.io/v1beta1", []string{"ingresses", "ingressclasses"}, removedAPI{"1.22", "networking.k8s.io/v1"})
+	add("rbac.authorization.k8s.io/v1beta1", []string{"roles", "clusterroles", "rolebindings", "clusterrolebindings"}, removedAPI{"1.22", "rbac.authorization.k8s.io/v1"})
+	add("admissionregistration.k8s.io/v1beta1", []string{"mutatingwebhookconfigurations", "validatingwebhookconfigurations"}, removedAPI{"1.22", "admissionregistration.k8s.io/v1"})
+	add("apiextensions.k8s.io/v1beta1", []string{"customresourcedefinitions"}, removedAPI{"1.22", "apiextensions.k8s.io/v1"})
+	add("certificates.k8s.io/v1beta1", []string{"certificatesigningrequests"}, removedAPI{"1.22", "certificates.k8s.io/v1"})
+	add("coordination.k8s.io/v1beta1", []string{"leases"}, removedAPI{"1.22", "coordination.k8s.io/v1"})
+	add("scheduling.k8s.io/v1beta1", []string{"priorityclasses"}, removedAPI{"1.22", "scheduling.k8s.io/v1"})
+	add("storage.k8s.io/v1beta1", []string{"csidrivers", "csinodes", "storageclasses", "volumeattachments"}, removedAPI{"1.22", "storage.k8s.io/v1"})
+	add("batch/v1beta1", []string{"cronjobs"}, removedAPI{"1.25", "batch/v1"})
+	add("discovery.k8s.io/v1beta1", []string{"endpointslices"}, removedAPI{"1.25", "discovery.k8s.io/v1"})
+	add("events.k8s.io/v1beta1", []string{"events"}, removedAPI{"1.25", "events.k8s.io/v1"})
+	add("autoscaling/v2beta1", []string{"horizontalpodautoscalers"}, removedAPI{"1.25", "autoscaling/v2"})
+	add("policy/v1beta1", []string{"poddisruptionbudgets"}, removedAPI{"1.25", "policy/v1"})
+	add("policy/v1beta1", []string{"podsecuritypolicies"}, removedAPI{"1.25", ""})
+	add("node.k8s.io/v1beta1", []string{"runtimeclasses"}, removedAPI{"1.25", "node.k8s.io/v1"})
+	add("autoscaling/v2beta2", []string{"horizontalpodautoscalers"}, removedAPI{"1.26", "autoscaling/v2"})
+	add("flowcontrol.apiserver.k8s.io/v1beta1", []string{"flowschemas", "prioritylevelconfigurations"}, removedAPI{"1.26", "flowcontrol.apiserver.k8s.io/v1"})
+	add("storage.k8s.io/v1beta1", []string{"csistoragecapacities"}, removedAPI{"1.27", "storage.k8s.io/v1"})
+	add("flowcontrol.apiserver.k8s.io/v1beta2", []string{"flowschemas", "prioritylevelconfigurations"}, removedAPI{"1.29", "flowcontrol.apiserver.k8s.io/v1"})
+	add("flowcontrol.apiserver.k8s.io/v1beta3", []string{"flowschemas", "prioritylevelconfigurations"}, removedAPI{"1.32", "flowcontrol.apiserver.k8s.io/v1"})
+	return apis
+}()
+
+// DiscoverTarget reads the version, served types and feature gates of the
+// cluster client talks to. Group versions whose discovery fails are listed
+// as unavailable rather than failing the check.
+func DiscoverTarget(ctx context.Context, client discovery.DiscoveryInterface) (*Target, error) {
+	info, err := client.ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the target's version: %v", err)
+	}
+	target := &Target{KubernetesVersion: info.GitVersion}
+
+	_, target.Resources, err = client.ServerGroupsAndResources()
+	if err != nil {
+		var failed *discovery.ErrGroupDiscoveryFailed
+		if !errors.As(err, &failed) || len(target.Resources) == 0 {
+			return nil, fmt.Errorf("failed to discover the target's API resources: %v", err)
+		}
+		for gv := range failed.Groups {
+			target.Unavailable = append(target.Unavailable, gv.String())
+		}
+		sort.Strings(target.Unavailable)
+	}
+
+	// Feature gates are compared only when both clusters report them
+	if gates, err := cluster.FeatureGates(ctx, client.RESTClient()); err == nil {
+		target.FeatureGates = gates
+	}
+	return target, nil
+}
+
+// Check compares the source cluster and types recorded in manifest with what
+// target serves. Types not served in any version stored are errors unless
+// the backup holds their CRD; removed API versions are warnings when the
+// target serves their replacement, which restores convert to.
+func Check(manifest *backup.Manifest, target *Target, opts Options) *Report {
+	report := &Report{
+		BackupID:      manifest.BackupID,
+		TargetVersion: target.KubernetesVersion,
+		Build:         manifest.Build,
+		Errors:        []Finding{},
+		Warnings:      []Finding{},
+	}
+	checkBuild(report, manifest.Build, buildinfo.Get().Version, opts.MinBackupVersion)
+	source := manifest.Source
+	if source == nil || source.KubernetesVersion == "" {
+		report.addWarning(CheckSource, "", "the backup does not record its source cluster's version, only the stored types are checked")
+	} else {
+		report.SourceVersion = source.KubernetesVersion
+		checkVersions(report, source.KubernetesVersion, target.KubernetesVersion)
+	}
+
+	checkTypes(report, manifest, target)
+	if source != nil && source.FeatureGates != nil && target.FeatureGates != nil {
+		report.FeatureGatesChecked = true
+		checkFeatureGates(report, source.FeatureGates, target.FeatureGates)
+	}
+
+	report.Compatible = len(report.Errors) == 0
+	return report
+}
+
+// checkVersions warns about restores to an older minor version, which may
+// reject fields and types added since
+func checkVersions(report *Report, source, target string) {
+	sourceVersion, err := version.ParseGeneric(source)
+	if err != nil {
+		report.addWarning(CheckVersion, "", fmt.Sprintf("cannot parse the source version %q", source))
+		return
+	}
+	targetVersion, err := version.ParseGeneric(target)
+	if err != nil {
+		report.addWarning(CheckVersion, "", fmt.Sprintf("cannot parse the target version %q", target))
+		return
+	}
+
+	if targetVersion.Major() < sourceVersion.Major() ||
+		(targetVersion.Major() == sourceVersion.Major() && targetVersion.Minor() < sourceVersion.Minor()) {
+		report.addWarning(CheckVersion, "", fmt.Sprintf("the target runs %s, older than the source's %s: fields and types added since may be dropped or rejected", target, source))
+	}
+}
+
+// checkBuild fails backups made by a version of the tool older than minimum,
+// when set, and warns about backups made by one newer than running, which
+// may record what running does not know about
+func checkBuild(report *Report, build *buildinfo.Info, running, minimum string) {
+	if build == nil || build.Version == "" {
+		if minimum != "" {
+			report.addError(CheckToolVersion, "", fmt.Sprintf("the backup does not record the version of the tool that made it, and at least %s is required", minimum))
+		}
+		return
+	}
+
+	made, err := version.ParseGeneric(build.Version)
+	if minimum != "" {
+		required, requiredErr := version.ParseGeneric(minimum)
+		switch {
+		case requiredErr != nil:
+			report.addError(CheckToolVersion, "", fmt.Sprintf("cannot parse the required version %q", minimum))
+		case err != nil:
+			report.addError(CheckToolVersion, "", fmt.Sprintf("the backup was made by version %q, which cannot be compared with the required %s", build.Version, minimum))
+		case made.LessThan(required):
+			report.addError(CheckToolVersion, "", fmt.Sprintf("the backup was made by version %s, older than the required %s", build.Version, minimum))
+		}
+	}
+	if err != nil {
+		return
+	}
+	if current, err := version.ParseGeneric(running); err == nil && current.LessThan(made) {
+		report.addWarning(CheckToolVersion, "", fmt.Sprintf("the backup was made by version %s, newer than this %s: what it added since may be ignored", build.Version, running))
+	}
+}
+
+// storedType is a group and resource of the backup with the versions its
+// objects are stored in, the preferred one first
+type storedType struct {
+	group, resource, kind string
+	versions              []string
+	objects               int
+}
+
+// checkTypes checks how the target serves each type the backup stores
+// objects of
+func checkTypes(report *Report, manifest *backup.Manifest, target *Target) {
+	served := make(map[schema.GroupResource][]string)
+	for _, list := range target.Resources {
+		if list == nil {
+			continue
+		}
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, resource := range list.APIResources {
+			if strings.Contains(resource.Name, "/") {
+				continue
+			}
+			groupResource := schema.GroupResource{Group: gv.Group, Resource: resource.Name}
+			served[groupResource] = append(served[groupResource], gv.Version)
+		}
+	}
+	unavailable := make(map[string]bool, len(target.Unavailable))
+	for _, gv := range target.Unavailable {
+		unavailable[gv] = true
+		report.addWarning(CheckDiscovery, gv, "discovery failed on the target, its types could not be checked")
+	}
+	crds := make(map[schema.GroupResource]bool, len(manifest.CRDs))
+	for _, crd := range manifest.CRDs {
+		crds[schema.GroupResource{Group: crd.Group, Resource: crd.Plural}] = true
+	}
+
+	for _, stored := range storedTypes(manifest) {
+		groupResource := schema.GroupResource{Group: stored.group, Resource: stored.resource}
+		gvr := groupResource.WithVersion(stored.versions[0])
+		name := gvr.Resource + "." + gvr.GroupVersion().String()
+		if gvr.Group == "" {
+			name = gvr.Resource + "/" + gvr.Version
+		}
+		check := TypeCheck{
+			Group:          stored.group,
+			Version:        stored.versions[0],
+			Kind:           stored.kind,
+			Resource:       stored.resource,
+			Objects:        stored.objects,
+			ServedVersions: served[groupResource],
+		}
+		replacement, removed := removedAPIs[gvr]
+
+		switch {
+		case contains(check.ServedVersions, stored.versions[0]):
+			check.Status = TypeServed
+		case unavailable[gvr.GroupVersion().String()]:
+			check.Status = TypeUnknown
+		case containsAny(check.ServedVersions, stored.versions[1:]):
+			check.Status = TypeAlternateVersion
+		case removed && servesReplacement(served, gvr, replacement):
+			check.Status = TypeConverted
+			report.addWarning(CheckRemovedAPI, name, fmt.Sprintf("removed in Kubernetes %s, restored as %s", replacement.removedIn, replacement.replacement))
+		case len(check.ServedVersions) > 0:
+			check.Status = TypeOtherVersion
+			report.addWarning(CheckOtherVersion, name, fmt.Sprintf("not served in %s, the target serves %s", gvr.Version, strings.Join(check.ServedVersions, ", ")))
+		case crds[groupResource]:
+			check.Status = TypeInstalledByRestore
+		default:
+			check.Status = TypeMissing
+			if removed {
+				message := fmt.Sprintf("removed in Kubernetes %s without a replacement", replacement.removedIn)
+				if replacement.replacement != "" {
+					message = fmt.Sprintf("removed in Kubernetes %s, and the target does not serve %s either", replacement.removedIn, replacement.replacement)
+				}
+				report.addError(CheckRemovedAPI, name, message)
+			} else if builtinGroup(stored.group) {
+				report.addError(CheckMissingAPI, name, "not served by the target")
+			} else {
+				report.addError(CheckMissingCRD, name, "no CustomResourceDefinition serves it on the target, and the backup holds none")
+			}
+		}
+		report.Types = append(report.Types, check)
+	}
+}
+
+// storedTypes returns the types the backup stores objects of, from the
+// recorded source types when there are any and the objects otherwise,
+// sorted by group and resource
+func storedTypes(manifest *backup.Manifest) []storedType {
+	types := make(map[schema.GroupResource]*storedType)
+	add := func(group, version, resource, kind string) *storedType {
+		key := schema.GroupResource{Group: group, Resource: resource}
+		stored, ok := types[key]
+		if !ok {
+			stored = &storedType{group: group, resource: resource}
+			types[key] = stored
+		}
+		if kind != "" {
+			stored.kind = kind
+		}
+		if version != "" && !contains(stored.versions, version) {
+			stored.versions = append(stored.versions, version)
+		}
+		return stored
+	}
+
+	for _, object := range manifest.Objects {
+		add(object.Group, object.Version, object.ResourceType, "").objects++
+	}
+	for _, object := range manifest.AlternateVersions {
+		add(object.Group, object.Version, object.ResourceType, "")
+	}
+	if manifest.Source != nil {
+		for _, entry := range manifest.Source.APIResources {
+			if stored, ok := types[schema.GroupResource{Group: entry.Group, Resource: entry.Resource}]; ok && entry.Kind != "" {
+				stored.kind = entry.Kind
+			}
+		}
+	}
+
+	result := make([]storedType, 0, len(types))
+	for _, stored := range types {
+		// Objects of runs before versions were recorded
+		if len(stored.versions) == 0 {
+			continue
+		}
+		result = append(result, *stored)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].group != result[j].group {
+			return result[i].group < result[j].group
+		}
+		return result[i].resource < result[j].resource
+	})
+	return result
+}
+
+// checkFeatureGates warns about gates enabled on the source but disabled on
+// the target, whose fields the target may drop or reject. Gates the target
+// does not know have usually graduated and are left out.
+func checkFeatureGates(report *Report, source, target map[string]bool) {
+	names := make([]string, 0, len(source))
+	for name, enabled := range source {
+		if enabledOnTarget, known := target[name]; enabled && known && !enabledOnTarget {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		report.addWarning(CheckFeatureGate, name, "enabled on the source but disabled on the target, fields depending on it may be dropped")
+	}
+}
+
+// servesReplacement reports whether served holds the replacement of a
+// removed API version
+func servesReplacement(served map[schema.GroupResource][]string, gvr schema.GroupVersionResource, removed removedAPI) bool {
+	if removed.replacement == "" {
+		return false
+	}
+	gv, err := schema.ParseGroupVersion(removed.replacement)
+	if err != nil {
+		return false
+	}
+	return contains(served[gv.WithResource(gvr.Resource).GroupResource()], gv.Version)
+}
+
+// builtinGroup reports whether group is served by Kubernetes itself rather
+// than a CRD or aggregated API
+func builtinGroup(group string) bool {
+	return !strings.Contains(group, ".") || strings.HasSuffix(group, ".k8s.io")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(values, candidates []string) bool {
+	for _, candidate := range candidates {
+		if contains(values, candidate) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package compat
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"cluster-backup/internal/backup"
+	"cluster-backup/internal/buildinfo"
+	"cluster-backup/internal/cluster"
+)
+
+func newManifest(version string, gates map[string]bool, objects ...backup.ObjectEntry) *backup.Manifest {
+	m := backup.NewManifest("backup-1", "test-cluster", "cluster.local", "bucket", time.Now())
+	if version != "" {
+		m.SetSource(version, gates)
+	}
+	for _, object := range objects {
+		m.AddObject(object)
+	}
+	return m
+}
+
+func object(group, version, resource, name string) backup.ObjectEntry {
+	return backup.ObjectEntry{Namespace: "shop", Group: group, Version: version, ResourceType: resource, Name: name}
+}
+
+func newTarget(version string, gates map[string]bool) *Target {
+	return &Target{
+		KubernetesVersion: version,
+		FeatureGates:      gates,
+		Resources: []*v1.APIResourceList{
+			{GroupVersion: "v1", APIResources: []v1.APIResource{{Name: "configmaps", Kind: "ConfigMap"}, {Name: "pods/log"}}},
+			{GroupVersion: "apps/v1", APIResources: []v1.APIResource{{Name: "deployments", Kind: "Deployment"}}},
+			{GroupVersion: "policy/v1", APIResources: []v1.APIResource{{Name: "poddisruptionbudgets", Kind: "PodDisruptionBudget"}}},
+			{GroupVersion: "autoscaling/v2", APIResources: []v1.APIResource{{Name: "horizontalpodautoscalers", Kind: "HorizontalPodAutoscaler"}}},
+			{GroupVersion: "example.com/v2", APIResources: []v1.APIResource{{Name: "widgets", Kind: "Widget"}}},
+		},
+	}
+}
+
+func checks(findings []Finding) map[string]string {
+	result := make(map[string]string, len(findings))
+	for _, finding := range findings {
+		result[finding.Resource] = finding.Check
+	}
+	return result
+}
+
+func TestCheck_Types(t *testing.T) {
+	manifest := newManifest("v1.29.4", nil,
+		object("", "v1", "configmaps", "settings"),
+		object("", "v1", "configmaps", "flags"),
+		object("extensions", "v1beta1", "deployments", "web"),
+		object("policy", "v1beta1", "poddisruptionbudgets", "web"),
+		object("policy", "v1beta1", "podsecuritypolicies", "restricted"),
+		object("example.com", "v1", "widgets", "gear"),
+		object("stable.example.com", "v1", "crontabs", "nightly"),
+		object("other.example.com", "v1", "gadgets", "spring"),
+		object("snapshot.storage.k8s.io", "v1", "volumesnapshots", "daily"),
+	)
+	manifest.AddCRD(backup.CRDEntry{Name: "crontabs.stable.example.com", Group: "stable.example.com", Plural: "crontabs", Versions: []string{"v1"}})
+
+	report := Check(manifest, newTarget("v1.30.1", nil), Options{})
+
+	statuses := make(map[string]string)
+	for _, check := range report.Types {
+		statuses[check.Resource+"."+check.Group] = check.Status
+	}
+	assert.Equal(t, map[string]string{
+		"configmaps.":                             TypeServed,
+		"deployments.extensions":                  TypeConverted,
+		"poddisruptionbudgets.policy":             TypeConverted,
+		"podsecuritypolicies.policy":              TypeMissing,
+		"widgets.example.com":                     TypeOtherVersion,
+		"crontabs.stable.example.com":             TypeInstalledByRestore,
+		"gadgets.other.example.com":               TypeMissing,
+		"volumesnapshots.snapshot.storage.k8s.io": TypeMissing,
+	}, statuses)
+	assert.Equal(t, 2, report.Types[0].Objects, "types are sorted with the core group first")
+
+	assert.Equal(t, map[string]string{
+		"podsecuritypolicies.policy/v1beta1":         CheckRemovedAPI,
+		"gadgets.other.example.com/v1":               CheckMissingCRD,
+		"volumesnapshots.snapshot.storage.k8s.io/v1": CheckMissingAPI,
+	}, checks(report.Errors))
+	assert.Equal(t, map[string]string{
+		"deployments.extensions/v1beta1":      CheckRemovedAPI,
+		"poddisruptionbudgets.policy/v1beta1": CheckRemovedAPI,
+		"widgets.example.com/v1":              CheckOtherVersion,
+	}, checks(report.Warnings))
+	assert.False(t, report.Compatible)
+	assert.Equal(t, CheckFailed, report.ExitCode())
+	assert.False(t, report.FeatureGatesChecked, "the source recorded no gates")
+}
+
+func TestCheck_AlternateVersions(t *testing.T) {
+	manifest := newManifest("v1.26.0", nil, object("autoscaling", "v2beta2", "horizontalpodautoscalers", "web"))
+	manifest.AddAlternateVersion(object("autoscaling", "v2", "horizontalpodautoscalers", "web"))
+
+	report := Check(manifest, newTarget("v1.27.0", nil), Options{})
+	require.Len(t, report.Types, 1)
+	assert.Equal(t, TypeAlternateVersion, report.Types[0].Status)
+	assert.Equal(t, []string{"v2"}, report.Types[0].ServedVersions)
+	assert.Empty(t, report.Warnings)
+	assert.True(t, report.Compatible)
+	assert.Equal(t, CheckPassed, report.ExitCode())
+}
+
+func TestCheck_Versions(t *testing.T) {
+	report := Check(newManifest("v1.29.4", nil), newTarget("v1.28.9-gke.100", nil), Options{})
+	require.Len(t, report.Warnings, 1)
+	assert.Equal(t, CheckVersion, report.Warnings[0].Check)
+	assert.True(t, report.Compatible)
+	assert.Equal(t, CheckWarnings, report.ExitCode())
+
+	report = Check(newManifest("v1.29.4", nil), newTarget("v1.29.0", nil), Options{})
+	assert.Empty(t, report.Warnings)
+	assert.Equal(t, "v1.29.4", report.SourceVersion)
+
+	report = Check(newManifest("", nil, object("", "v1", "configmaps", "settings")), newTarget("v1.29.0", nil), Options{})
+	require.Len(t, report.Warnings, 1)
+	assert.Equal(t, CheckSource, report.Warnings[0].Check)
+	assert.Equal(t, TypeServed, report.Types[0].Status, "objects are checked without a recorded source")
+}
+
+func TestCheck_FeatureGates(t *testing.T) {
+	source := map[string]bool{"InPlacePodVerticalScaling": true, "SidecarContainers": true, "GracefulNodeShutdown": true, "UserNamespacesSupport": false}
+	target := map[string]bool{"InPlacePodVerticalScaling": false, "SidecarContainers": true, "UserNamespacesSupport": true}
+
+	report := Check(newManifest("v1.29.4", source), newTarget("v1.29.4", target), Options{})
+	assert.True(t, report.FeatureGatesChecked)
+	assert.Equal(t, map[string]string{"InPlacePodVerticalScaling": CheckFeatureGate}, checks(report.Warnings))
+
+	report = Check(newManifest("v1.29.4", source), newTarget("v1.29.4", nil), Options{})
+	assert.False(t, report.FeatureGatesChecked)
+	assert.Empty(t, report.Warnings)
+}
+
+func TestCheck_UnavailableDiscovery(t *testing.T) {
+	target := newTarget("v1.29.0", nil)
+	target.Unavailable = []string{"metrics.k8s.io/v1beta1"}
+
+	report := Check(newManifest("v1.29.0", nil, object("metrics.k8s.io", "v1beta1", "pods", "web")), target, Options{})
+	assert.Equal(t, TypeUnknown, report.Types[0].Status)
+	assert.Equal(t, map[string]string{"metrics.k8s.io/v1beta1": CheckDiscovery}, checks(report.Warnings))
+	assert.Empty(t, report.Errors)
+}
+
+func TestCheck_ToolVersion(t *testing.T) {
+	manifest := newManifest("v1.29.0", nil)
+	manifest.Build = &buildinfo.Info{Version: "v1.3.2"}
+
+	report := Check(manifest, newTarget("v1.29.0", nil), Options{})
+	assert.Equal(t, "v1.3.2", report.Build.Version)
+	assert.True(t, report.Compatible)
+
+	report = Check(manifest, newTarget("v1.29.0", nil), Options{MinBackupVersion: "v1.4.0"})
+	assert.False(t, report.Compatible)
+	require.Len(t, report.Errors, 1)
+	assert.Equal(t, CheckToolVersion, report.Errors[0].Check)
+	assert.Contains(t, report.Errors[0].Message, "older than the required v1.4.0")
+
+	manifest.Build.Version = "v1.4.1"
+	assert.True(t, Check(manifest, newTarget("v1.29.0", nil), Options{MinBackupVersion: "v1.4.0"}).Compatible)
+
+	// Versions that cannot be compared fail the policy rather than pass it
+	manifest.Build.Version = buildinfo.DevVersion
+	assert.False(t, Check(manifest, newTarget("v1.29.0", nil), Options{MinBackupVersion: "v1.4.0"}).Compatible)
+	manifest.Build = nil
+	assert.False(t, Check(manifest, newTarget("v1.29.0", nil), Options{MinBackupVersion: "v1.4.0"}).Compatible)
+	assert.True(t, Check(manifest, newTarget("v1.29.0", nil), Options{}).Compatible)
+}
+
+func TestCheckBuild_NewerBackup(t *testing.T) {
+	report := &Report{Compatible: true}
+	checkBuild(report, &buildinfo.Info{Version: "v1.5.0"}, "v1.4.0", "")
+	require.Len(t, report.Warnings, 1)
+	assert.Equal(t, CheckToolVersion, report.Warnings[0].Check)
+	assert.True(t, report.Compatible)
+
+	report = &Report{Compatible: true}
+	checkBuild(report, &buildinfo.Info{Version: "v1.5.0"}, buildinfo.DevVersion, "")
+	assert.Empty(t, report.Warnings, "development builds are not compared")
+}
+
+func TestParseFeatureGates(t *testing.T) {
+	metrics := `# HELP kubernetes_feature_enabled [BETA] This metric records the data about the stage and enablement of a k8s feature.
+# TYPE kubernetes_feature_enabled gauge
+kubernetes_feature_enabled{name="SidecarContainers",stage="BETA"} 1
+kubernetes_feature_enabled{name="InPlacePodVerticalScaling",stage="ALPHA"} 0
+apiserver_request_total{code="200",verb="GET"} 12
+`
+	gates, err := cluster.ParseFeatureGates(strings.NewReader(metrics))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"SidecarContainers": true, "InPlacePodVerticalScaling": false}, gates)
+}
@@ -0,0 +1,118 @@
+// Package compliance restricts the backup's cryptography to what FIPS 140-3
+// approves when COMPLIANCE_MODE is fips: TLS 1.2 or later with AES-GCM
+// cipher suites and NIST curves for MinIO, the REST and gRPC APIs and the
+// webhooks, on top of Go's validated FIPS 140-3 module.
+package compliance
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/fips140"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+
+	"cluster-backup/internal/config"
+)
+
+// CipherSuites are the approved TLS 1.2 cipher suites. TLS 1.3 suites cannot
+// be chosen in crypto/tls; with the FIPS module enabled it only negotiates
+// the AES-GCM ones.
+var CipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+// Curves are the approved key exchange curves
+var Curves = []tls.CurveID{tls.CurveP256, tls.CurveP384, tls.CurveP521}
+
+// Enabled reports whether cfg asks for approved cryptography only
+func Enabled(cfg *config.Config) bool {
+	return cfg.ComplianceMode == config.ComplianceModeFIPS
+}
+
+// Restrict limits cfg to the approved TLS versions, cipher suites and curves
+func Restrict(cfg *tls.Config) {
+	if cfg.MinVersion < tls.VersionTLS12 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+	cfg.CipherSuites = CipherSuites
+	cfg.CurvePreferences = Curves
+}
+
+// Enforce checks, when cfg enables FIPS mode, that the process can meet it,
+// and restricts the HTTP clients that use the default transport, such as
+// the audit webhook, Alertmanager, policy and OIDC clients. MinIO clients
+// and the API servers are restricted where their TLS is configured.
+func Enforce(cfg *config.Config) error {
+	if !Enabled(cfg) {
+		return nil
+	}
+	if err := SelfCheck(cfg); err != nil {
+		return err
+	}
+
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return fmt.Errorf("the default HTTP transport cannot be restricted to approved TLS settings")
+	}
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	Restrict(transport.TLSClientConfig)
+	return nil
+}
+
+// SelfCheck returns why the process cannot run in FIPS mode: Go's FIPS
+// module is not enabled, or the API certificate has a key of an algorithm
+// or size that is not approved
+func SelfCheck(cfg *config.Config) error {
+	if !fips140.Enabled() {
+		return fmt.Errorf("COMPLIANCE_MODE=fips requires the Go FIPS 140-3 module; run with GODEBUG=fips140=on or build with GOFIPS140")
+	}
+	if cfg.APITLSCertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(cfg.APITLSCertFile, cfg.APITLSKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load API TLS certificate: %v", err)
+		}
+		if err := CheckCertificate(&certificate); err != nil {
+			return fmt.Errorf("API TLS certificate %s: %v", cfg.APITLSCertFile, err)
+		}
+	}
+	return nil
+}
+
+// CheckCertificate returns an error unless the certificate's key is RSA of
+// at least 2048 bits or ECDSA on P-256, P-384 or P-521
+func CheckCertificate(certificate *tls.Certificate) error {
+	leaf := certificate.Leaf
+	if leaf == nil {
+		if len(certificate.Certificate) == 0 {
+			return fmt.Errorf("no certificate")
+		}
+		var err error
+		if leaf, err = x509.ParseCertificate(certificate.Certificate[0]); err != nil {
+			return fmt.Errorf("invalid certificate: %v", err)
+		}
+	}
+
+	switch key := leaf.PublicKey.(type) {
+	case *rsa.PublicKey:
+		if key.N.BitLen() < 2048 {
+			return fmt.Errorf("RSA key of %d bits is below the approved 2048", key.N.BitLen())
+		}
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256(), elliptic.P384(), elliptic.P521():
+		default:
+			return fmt.Errorf("ECDSA curve %s is not approved", key.Curve.Params().Name)
+		}
+	default:
+		return fmt.Errorf("%s keys are not approved", leaf.PublicKeyAlgorithm)
+	}
+	return nil
+}
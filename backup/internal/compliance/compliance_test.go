@@ -0,0 +1,124 @@
+package compliance
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/fips140"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/config"
+)
+
+// selfSigned returns a certificate of key for 127.0.0.1
+func selfSigned(t *testing.T, key crypto.Signer) tls.Certificate {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "backup"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestCheckCertificate(t *testing.T) {
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	p224, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	require.NoError(t, err)
+	rsa2048, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, ed, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	certificate := selfSigned(t, p256)
+	assert.NoError(t, CheckCertificate(&certificate))
+	certificate = selfSigned(t, rsa2048)
+	assert.NoError(t, CheckCertificate(&certificate))
+
+	certificate = selfSigned(t, p224)
+	assert.ErrorContains(t, CheckCertificate(&certificate), "P-224")
+	certificate = selfSigned(t, ed)
+	assert.ErrorContains(t, CheckCertificate(&certificate), "Ed25519")
+	assert.Error(t, CheckCertificate(&tls.Certificate{}))
+
+	// Without a parsed leaf the certificate is parsed first
+	certificate = selfSigned(t, p224)
+	certificate.Leaf = nil
+	assert.Error(t, CheckCertificate(&certificate))
+}
+
+func TestRestrict(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	certificate := selfSigned(t, key)
+
+	server := &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS10}
+	Restrict(server)
+	assert.Equal(t, uint16(tls.VersionTLS12), server.MinVersion)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", server)
+	require.NoError(t, err)
+	httpServer := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})}
+	go httpServer.Serve(listener)
+	t.Cleanup(func() { httpServer.Close() })
+
+	pool := x509.NewCertPool()
+	pool.AddCert(certificate.Leaf)
+	dial := func(client *tls.Config) (*tls.ConnectionState, error) {
+		client.RootCAs = pool
+		conn, err := tls.Dial("tcp", listener.Addr().String(), client)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		state := conn.ConnectionState()
+		return &state, nil
+	}
+
+	restricted := &tls.Config{MaxVersion: tls.VersionTLS12}
+	Restrict(restricted)
+	state, err := dial(restricted)
+	require.NoError(t, err)
+	assert.Contains(t, CipherSuites, state.CipherSuite)
+
+	_, err = dial(&tls.Config{MaxVersion: tls.VersionTLS12, CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256}})
+	assert.Error(t, err, "ChaCha20 is not approved")
+	_, err = dial(&tls.Config{MaxVersion: tls.VersionTLS11})
+	assert.Error(t, err, "TLS 1.1 is not approved")
+	_, err = dial(&tls.Config{MaxVersion: tls.VersionTLS12, CurvePreferences: []tls.CurveID{tls.X25519}})
+	assert.Error(t, err, "X25519 is not approved")
+}
+
+func TestEnforce(t *testing.T) {
+	assert.NoError(t, Enforce(&config.Config{ComplianceMode: config.ComplianceModeNone}))
+
+	cfg := &config.Config{ComplianceMode: config.ComplianceModeFIPS}
+	if fips140.Enabled() {
+		assert.NoError(t, SelfCheck(cfg))
+		cfg.APITLSCertFile, cfg.APITLSKeyFile = "missing.crt", "missing.key"
+		assert.Error(t, SelfCheck(cfg))
+		return
+	}
+	assert.ErrorContains(t, Enforce(cfg), "GODEBUG=fips140=on", "the process must fail fast without the module")
+}
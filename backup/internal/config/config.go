@@ -1,12 +1,24 @@
 package config
 
 import (
+	"fmt"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 	
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/version"
+	sharedconfig "shared-config/config"
+	"shared-config/eventbus"
 	sharedErrors "shared-errors"
+
+	"cluster-backup/internal/filter"
+	"cluster-backup/internal/pathtemplate"
 )
 
 // Config holds the main backup configuration
@@ -16,22 +28,384 @@ type Config struct {
 	MinIOEndpoint     string
 	MinIOAccessKey    string
 	MinIOSecretKey    string
+	// Files holding the keys, e.g. a mounted Secret. They are re-read when
+	// they change, so rotated keys apply without a restart.
+	MinIOAccessKeyFile string
+	MinIOSecretKeyFile string
+	// How often credential files and the Vault secret are checked for
+	// rotation
+	CredentialsRefreshInterval time.Duration
+	// How MinIO credentials are obtained, see the StorageAuth constants. Only
+	// static uses the access and secret keys.
+	StorageAuthMethod           string
+	StorageRoleARN              string
+	StorageWebIdentityTokenFile string
+	StorageSTSEndpoint          string
+	// Where static MinIO keys come from, see the SecretProvider constants.
+	// With vault they are read from the KV secret at VaultPath.
+	SecretProvider    string
+	VaultAddress      string
+	VaultToken        string
+	VaultRole         string
+	VaultPath         string
 	MinIOBucket       string
 	MinIOUseSSL       bool
+	// HTTP transport shared by every MinIO client in the process
+	MinIOMaxIdleConns        int
+	MinIOIdleConnTimeout     time.Duration
+	MinIOTLSHandshakeTimeout time.Duration
+	MinIOCABundle            string
+	// Client certificate for mutual TLS; like MinIOCABundle, each is a file
+	// path or inline PEM
+	MinIOClientCert          string
+	MinIOClientKey           string
+	// Compliance mode, see the ComplianceMode constants. In fips mode MinIO,
+	// API and webhook connections use only approved TLS settings.
+	ComplianceMode           string
+	// Bytes per second sent to object storage by backups and read from it by
+	// restores, exports and verification, such as 10Mi; empty is unlimited
+	BackupBandwidthLimit     string
+	RestoreBandwidthLimit    string
 	BatchSize         int
+	// Scale the resource types listed at once and their page size, which
+	// starts at BatchSize, to the API server's latency and throttling
+	AdaptiveConcurrency   bool
+	AdaptiveMinWorkers    int
+	AdaptiveMaxWorkers    int
+	AdaptiveMinBatchSize  int
+	AdaptiveMaxBatchSize  int
+	AdaptiveTargetLatency time.Duration
 	RetryAttempts     int
 	RetryDelay        time.Duration
+	// Circuit breakers on MinIO and the Kubernetes API: consecutive failures
+	// that open them, how long they stay open and the probes needed to close
+	MinIOCircuitBreakerThreshold int
+	MinIOCircuitBreakerTimeout   time.Duration
+	MinIOCircuitBreakerProbes    int
+	APICircuitBreakerThreshold   int
+	APICircuitBreakerTimeout     time.Duration
+	APICircuitBreakerProbes      int
 	// Cleanup configuration
 	EnableCleanup     bool
 	RetentionDays     int
 	CleanupOnStartup  bool
+	// The most recent completed runs of each cluster cleanup keeps, with
+	// everything they reference, however old they are; zero keeps none
+	RetentionKeepLast int
+	// Whether expired objects are deleted or moved to the trash, where they
+	// stay for CleanupTrashGracePeriod before they are deleted
+	CleanupMode             string
+	CleanupTrashGracePeriod time.Duration
 	// Advanced bucket management
 	AutoCreateBucket  bool
 	FallbackBuckets   []string
 	BucketRetryAttempts int
 	BucketRetryDelay    time.Duration
+	// REST API server (preview feature)
+	RestAPIEnabled    bool
+	APIPort           int
+	GRPCPort          int
+	APIToken          string
+	APITLSCertFile    string
+	APITLSKeyFile     string
+	// Clients must present a certificate this CA signed (mutual TLS)
+	APITLSCAFile      string
+	// Static tokens of the viewer and operator roles; API_TOKEN is admin
+	APIViewerToken    string
+	APIOperatorToken  string
+	// Static token of the approver role, the one restores awaiting approval
+	// can be approved with besides OIDC tokens
+	APIApproverToken  string
+	// OIDC ID tokens of the issuer are accepted too, with the role the first
+	// matching value of their roles claim grants
+	APIOIDCIssuer         string
+	APIOIDCAudience       string
+	APIOIDCRolesClaim     string
+	APIOIDCAdminGroups    []string
+	APIOIDCOperatorGroups []string
+	APIOIDCViewerGroups   []string
+	APIOIDCApproverGroups []string
+	// Require the viewer role for the metrics and health endpoints too
+	APIAuthEnabled    bool
+	// Web UI dashboard (preview feature), served from the metrics port
+	UIDashboardEnabled bool
+	MetricsPort        int
+	// net/http/pprof on the metrics port, behind the API token
+	PprofEnabled       bool
+	// Capture CPU and heap profiles of each run and upload them under
+	// _diagnostics
+	ProfileBackup      bool
+	// Pre/post backup hooks exec'd in annotated pods
+	HooksEnabled       bool
+	HookTimeout        time.Duration
+	// Database dump plugins run in pods labelled backup.cluster/dump=<plugin>
+	DumpPlugins        []string
+	DumpTimeout        time.Duration
+	// etcd snapshots for full control-plane backups (kubeadm paths by default)
+	EtcdSnapshotEnabled bool
+	EtcdEndpoints       []string
+	EtcdCACert          string
+	EtcdCert            string
+	EtcdKey             string
+	EtcdctlPath         string
+	EtcdSnapshotTimeout time.Duration
+	// Store resources once per content hash under _blobs, shared by all runs
+	// and clusters writing to the bucket
+	ContentAddressed    bool
+	// Store each run's objects under a prefix of its own, keeping a copy of
+	// the latest run's under latest/ at the keys of the flat layout
+	RunPrefixedLayout bool
+	// Go template of the bucket key of each resource, in place of
+	// {domain}/{cluster}/{namespace}/{resource-type}/{name}.yaml
+	ObjectPathTemplate string
+	// Add the API version below the resource type in resource keys
+	ObjectPathVersion bool
+	// Upload report.json and report.html summarizing each run next to its data
+	BackupReport bool
+	// Upload a search index of each run's resources next to its data
+	BackupIndex bool
+	// Lease on the cluster's prefix in the bucket held for the length of a
+	// run; a holder that dies releases it after RunLockTTL
+	RunLockEnabled bool
+	RunLockTTL     time.Duration
+	// In REST API mode, re-read the shared configuration file every
+	// ConfigReloadInterval and apply changed settings to the next runs
+	ConfigReload         bool
+	ConfigReloadInterval time.Duration
+	// Reuse the discovered API resources for DiscoveryCacheTTL while the
+	// server version stays the same, keeping them in DiscoveryCacheDir, when
+	// set, across restarts; a zero TTL discovers them on every run
+	DiscoveryCacheTTL time.Duration
+	DiscoveryCacheDir string
+	// How long a run may finish in-flight uploads and write its checkpoint
+	// after SIGTERM before they are cancelled
+	ShutdownGracePeriod time.Duration
+	// Deadline of a whole run and of its phases; a phase that runs out of time
+	// fails with an error naming it
+	BackupTimeout          time.Duration
+	BackupDiscoveryTimeout time.Duration
+	BackupNamespaceTimeout time.Duration
+	BackupCleanupTimeout   time.Duration
+	// How often a run reports its percentage, rate and ETA, and whether the
+	// items of a cluster without a previous run are counted up front
+	ProgressInterval time.Duration
+	ProgressPrecount bool
+	// Watch the namespaced resource types while a run lists them, and store
+	// the resources created or updated meanwhile at the end of the run
+	WindowCapture bool
+	// Run informers on the included resource types and keep a live copy of
+	// each resource in the bucket, stored a debounce after its last change,
+	// consolidated into a snapshot run every snapshot interval
+	ContinuousBackup           bool
+	ContinuousDebounce         time.Duration
+	ContinuousSnapshotInterval time.Duration
+	// OPA Data API document listing the Rego policy violations of each
+	// backed-up resource; empty disables policy checks
+	PolicyURL     string
+	PolicyTimeout time.Duration
+	// Scan resources other than Secrets for credentials: private keys, AWS
+	// keys and high-entropy tokens in ConfigMaps, annotations and env values
+	ScanForSecrets bool
+	// Leave resources that fail content validation out of the backup instead
+	// of only recording the findings in the manifest
+	ValidationStrictMode bool
+	// Manifest signing, see the ManifestSigning constants. Keyless signing
+	// gets a Fulcio certificate for the identity token and logs to Rekor.
+	ManifestSigning           string
+	ManifestSigningKey        string
+	SigstoreFulcioURL         string
+	SigstoreRekorURL          string
+	SigstoreIdentityTokenFile string
+	// Trust anchors for checking manifest signatures; signatures are only
+	// checked when a key or Fulcio roots are set
+	ManifestVerifyKey      string
+	ManifestVerifyRoots    string
+	ManifestVerifyIdentity string
+	ManifestVerifyIssuer   string
+	SigstoreRekorPublicKey string
+	// Restore checks fail for backups made by a version of the tool older
+	// than this one, or by an unknown version
+	RestoreMinBackupVersion string
+	// Labels of the cluster, e.g. environment=production
+	ClusterLabels map[string]string
+	// Restores of a cluster whose labels match this selector wait for a
+	// second person's approval, for at most RestoreApprovalTimeout
+	RestoreApprovalSelector string
+	RestoreApprovalTimeout  time.Duration
+	// Broker the backup lifecycle events are published to, nats or kafka;
+	// empty disables publishing. Kafka is reached through its REST Proxy.
+	EventBus            string
+	EventBusURL         string
+	EventBusTopicPrefix string
+	EventBusToken       string
+	EventBusUsername    string
+	EventBusPassword    string
+	// Alertmanager the failure alerts are posted to; empty disables them.
+	// Firing alerts stay active for AlertTTL unless a later run resolves them.
+	AlertmanagerURL          string
+	AlertConsecutiveFailures int
+	AlertCleanupErrors       int
+	AlertOnEmptyBackup       bool
+	AlertTTL                 time.Duration
+	AlertLabels              map[string]string
+	// Recovery point objective: the longest the cluster or a namespace may go
+	// without a successful backup; zero disables RPO monitoring
+	RPOTarget        time.Duration
+	RPOCheckInterval time.Duration
+	// How often the API server mode runs the cluster health checks of
+	// internal/validation and exports their results; zero disables them
+	HealthCheckInterval time.Duration
+	// ConfigMap routing the namespaces of each tenant to its own bucket and
+	// credentials; empty keeps every namespace in MINIO_BUCKET
+	TenancyConfigMap string
+	TenancyNamespace string
+	// ConfigMap of the per-kind fields stripped from backed-up resources;
+	// empty strips those of the built-in profiles only
+	CleaningProfilesConfigMap string
+	CleaningProfilesNamespace string
+	// Go plugins handed every resource before it is stored, see the plugins
+	// package of shared-config, and the key=value options they are created with
+	ResourcePlugins       []string
+	ResourcePluginOptions map[string]string
+	// Bucket in another region MINIO_BUCKET is replicated to; empty
+	// REPLICA_ENDPOINT disables replication verification. Each check reads
+	// back the manifest and ReplicaSampleSize objects of the latest run.
+	ReplicaEndpoint      string
+	ReplicaBucket        string
+	ReplicaAccessKey     string
+	ReplicaSecretKey     string
+	ReplicaUseSSL        bool
+	ReplicaSampleSize    int
+	ReplicaCheckInterval time.Duration
+	// Buckets every object of MINIO_BUCKET is also uploaded to, such as a
+	// local MinIO next to cloud S3. SecondaryFailurePolicy decides whether a
+	// failed upload to one of them fails the backup or is only logged.
+	SecondaryTargets       []StorageTarget
+	SecondaryFailurePolicy string
+	// ConfigMap of the namespaces and least resource counts every run is
+	// expected to back up; empty disables the check. InventoryPolicy decides
+	// whether a run falling short fails or is only logged.
+	InventoryConfigMap string
+	InventoryNamespace string
+	InventoryPolicy    string
+	// Synthetic validation restores SyntheticValidationSampleSize objects of
+	// the SyntheticValidationResources types of each finished run into a
+	// temporary namespace named after SyntheticValidationNamespacePrefix,
+	// compares what the API server kept with the stored copies and deletes
+	// the namespace again
+	SyntheticValidationEnabled         bool
+	SyntheticValidationSampleSize      int
+	SyntheticValidationResources       []string
+	SyntheticValidationNamespacePrefix string
+	// Sinks the audit records of backup and restore operations are written
+	// to, see the AuditSink constants; none disables them. The bucket sink
+	// writes to AuditBucket, MINIO_BUCKET unless set.
+	AuditSinks        []string
+	AuditBucket       string
+	AuditWebhookURL   string
+	AuditWebhookToken string
+}
+
+// StorageTarget is a secondary bucket uploads are fanned out to, given in
+// SECONDARY_TARGETS as name=[http[s]://]endpoint/bucket. Its credentials come
+// from SECONDARY_<NAME>_ACCESS_KEY and SECONDARY_<NAME>_SECRET_KEY, or are
+// those of MINIO_BUCKET when unset.
+type StorageTarget struct {
+	Name      string
+	Endpoint  string
+	Bucket    string
+	UseSSL    bool
+	AccessKey string
+	SecretKey string
 }
 
+// Storage authentication methods
+const (
+	// StorageAuthStatic signs requests with MINIO_ACCESS_KEY and MINIO_SECRET_KEY
+	StorageAuthStatic = "static"
+	// StorageAuthIRSA exchanges the EKS projected service account token for
+	// temporary AWS credentials (IAM roles for service accounts)
+	StorageAuthIRSA = "irsa"
+	// StorageAuthGCPWorkloadIdentity sends OAuth tokens for the GKE workload
+	// identity to the GCS XML API
+	StorageAuthGCPWorkloadIdentity = "gcp-workload-identity"
+	// StorageAuthSTSWebIdentity exchanges a service account token for
+	// temporary credentials with MinIO's AssumeRoleWithWebIdentity
+	StorageAuthSTSWebIdentity = "sts-web-identity"
+)
+
+// Secret providers for the MinIO keys
+const (
+	// SecretProviderEnv reads MINIO_ACCESS_KEY and MINIO_SECRET_KEY from the
+	// environment
+	SecretProviderEnv = "env"
+	// SecretProviderVault reads minio_access_key and minio_secret_key from a
+	// Vault KV secret, falling back to the environment for missing keys
+	SecretProviderVault = "vault"
+)
+
+// Secondary target failure policies
+const (
+	// SecondaryFailureWarn logs a failed upload to a secondary target and
+	// carries on
+	SecondaryFailureWarn = "warn"
+	// SecondaryFailureFail fails the upload, as a failure of MINIO_BUCKET
+	// would
+	SecondaryFailureFail = "fail"
+)
+
+// Expected inventory policies
+const (
+	// InventoryPolicyWarn logs a run falling short of the expected inventory
+	// and records the shortfall in its manifest
+	InventoryPolicyWarn = "warn"
+	// InventoryPolicyFail also fails the run
+	InventoryPolicyFail = "fail"
+)
+
+// Audit log sinks
+const (
+	// AuditSinkBucket writes each audit record to an object of its own under
+	// _audit/operations/, never overwritten
+	AuditSinkBucket = "bucket"
+	// AuditSinkWebhook posts each audit record as JSON to AuditWebhookURL,
+	// e.g. the HTTP event collector of a SIEM
+	AuditSinkWebhook = "webhook"
+)
+
+// Cleanup modes
+const (
+	// CleanupModeDelete deletes expired objects
+	CleanupModeDelete = "delete"
+	// CleanupModeTrash moves expired objects under the _trash prefix, from
+	// where they can be restored until CLEANUP_TRASH_GRACE_PERIOD passes
+	CleanupModeTrash = "trash"
+)
+
+// Compliance modes
+const (
+	// ComplianceModeNone uses Go's default TLS settings
+	ComplianceModeNone = "none"
+	// ComplianceModeFIPS restricts TLS to FIPS 140-3 approved versions,
+	// cipher suites and curves, and requires Go's FIPS module at startup
+	ComplianceModeFIPS = "fips"
+)
+
+// Manifest signing modes
+const (
+	// ManifestSigningNone leaves manifests unsigned
+	ManifestSigningNone = "none"
+	// ManifestSigningKey signs with the private key at MANIFEST_SIGNING_KEY
+	ManifestSigningKey = "key"
+	// ManifestSigningKeyless signs with a short-lived Fulcio certificate issued
+	// to the pod's OIDC identity, cosign-style
+	ManifestSigningKeyless = "keyless"
+)
+
+// DefaultClusterResources are the cluster-scoped resource types backed up when
+// CLUSTER_RESOURCES is not set. CRDs are always captured separately.
+const DefaultClusterResources = "clusterroles,clusterrolebindings,storageclasses,persistentvolumes,priorityclasses"
+
 // BackupConfig holds the backup-specific configuration
 type BackupConfig struct {
 	FilteringMode           string
@@ -40,6 +414,22 @@ type BackupConfig struct {
 	IncludeNamespaces       []string
 	ExcludeNamespaces       []string
 	IncludeCRDs             []string
+	// Cluster-scoped resource types backed up once per run
+	BackupClusterResources  bool
+	ClusterResources        []string
+	// Back up ResourceQuotas, LimitRanges, NetworkPolicies and PriorityClasses
+	// ahead of the other types of their scope, whatever the include lists say
+	GovernanceFirst         bool
+	// Also back up served versions of a resource type other than the
+	// preferred one: all of them, or those listed as resource.group/version
+	AllAPIVersions          bool
+	APIVersions             []string
+	// Annotate the stored copies of resources, never the live objects, with
+	// the run that stored them: per type as resource[.group][=field+field],
+	// or * for every type
+	Provenance              []string
+	// Also label the stored copies with the backup ID and cluster
+	ProvenanceLabels        bool
 	LabelSelector           string
 	AnnotationSelector      string
 	MaxResourceSize         string
@@ -55,34 +445,213 @@ type BackupConfig struct {
 	RetentionDays           int
 }
 
-// LoadConfig loads the main configuration from environment variables
+// LoadConfig loads the main configuration from the shared configuration
+// file, when there is one, with environment variables overriding it
 func LoadConfig() (*Config, error) {
+	shared, err := LoadSharedConfig()
+	if err != nil {
+		return nil, err
+	}
+	return LoadConfigFrom(shared)
+}
+
+// LoadConfigFrom loads the main configuration from shared, which may be nil,
+// with environment variables overriding its settings
+func LoadConfigFrom(shared *sharedconfig.SharedConfig) (*Config, error) {
+	s := newSettings(shared)
 	config := &Config{
-		ClusterDomain:     getConfigValue("CLUSTER_DOMAIN"),
-		ClusterName:       getConfigValue("CLUSTER_NAME"),
-		MinIOEndpoint:     getConfigValueWithWarning("MINIO_ENDPOINT", "", "MinIO connection"),
-		MinIOAccessKey:    getConfigValueWithWarning("MINIO_ACCESS_KEY", "", "MinIO authentication"),
-		MinIOSecretKey:    getConfigValueWithWarning("MINIO_SECRET_KEY", "", "MinIO authentication"),
-		MinIOBucket:       getConfigValueWithWarning("MINIO_BUCKET", "cluster-backups", "MinIO storage"),
-		MinIOUseSSL:       getConfigValueWithWarning("MINIO_USE_SSL", "true", "MinIO security") == "true",
+		ClusterDomain:     s.getConfigValue("CLUSTER_DOMAIN"),
+		ClusterName:       s.getConfigValue("CLUSTER_NAME"),
+		MinIOEndpoint:     s.getConfigValueWithWarning("MINIO_ENDPOINT", "", "MinIO connection"),
+		MinIOAccessKey:    s.getConfigValueWithWarning("MINIO_ACCESS_KEY", "", "MinIO authentication"),
+		MinIOSecretKey:    s.getConfigValueWithWarning("MINIO_SECRET_KEY", "", "MinIO authentication"),
+		MinIOAccessKeyFile: s.getConfigValue("MINIO_ACCESS_KEY_FILE"),
+		MinIOSecretKeyFile: s.getConfigValue("MINIO_SECRET_KEY_FILE"),
+		CredentialsRefreshInterval: time.Minute,
+		StorageAuthMethod:           s.getConfigValueWithWarning("STORAGE_AUTH_METHOD", StorageAuthStatic, "MinIO authentication"),
+		StorageRoleARN:              s.getConfigValue("STORAGE_ROLE_ARN"),
+		StorageWebIdentityTokenFile: s.getConfigValue("STORAGE_WEB_IDENTITY_TOKEN_FILE"),
+		StorageSTSEndpoint:          s.getConfigValue("STORAGE_STS_ENDPOINT"),
+		SecretProvider:    s.getConfigValueWithWarning("SECRET_PROVIDER", SecretProviderEnv, "MinIO authentication"),
+		VaultAddress:      s.getConfigValue("VAULT_ADDR"),
+		VaultToken:        s.getConfigValue("VAULT_TOKEN"),
+		VaultRole:         s.getConfigValue("VAULT_ROLE"),
+		VaultPath:         s.getConfigValueWithWarning("VAULT_PATH", "secret/backup", "MinIO authentication"),
+		MinIOBucket:       s.getConfigValueWithWarning("MINIO_BUCKET", "cluster-backups", "MinIO storage"),
+		MinIOUseSSL:       s.getConfigValueWithWarning("MINIO_USE_SSL", "true", "MinIO security") == "true",
+		MinIOMaxIdleConns:        100,
+		MinIOIdleConnTimeout:     90 * time.Second,
+		MinIOTLSHandshakeTimeout: 10 * time.Second,
+		MinIOCABundle:            s.getConfigValue("CA_BUNDLE_PATH"),
+		MinIOClientCert:          s.getConfigValue("CLIENT_CERT_PATH"),
+		MinIOClientKey:           s.getConfigValue("CLIENT_KEY_PATH"),
+		ComplianceMode:           s.getConfigValueWithWarning("COMPLIANCE_MODE", ComplianceModeNone, "compliance mode"),
+		BackupBandwidthLimit:     s.getConfigValueWithWarning("BACKUP_BANDWIDTH_LIMIT", "", "bandwidth throttling"),
+		RestoreBandwidthLimit:    s.getConfigValueWithWarning("RESTORE_BANDWIDTH_LIMIT", "", "bandwidth throttling"),
 		BatchSize:         50,
+		AdaptiveConcurrency:   s.getConfigValueWithWarning("ADAPTIVE_CONCURRENCY", "false", "adaptive concurrency") == "true",
+		AdaptiveMinWorkers:    1,
+		AdaptiveMaxWorkers:    4,
+		AdaptiveMinBatchSize:  10,
+		AdaptiveMaxBatchSize:  500,
+		AdaptiveTargetLatency: time.Second,
 		RetryAttempts:     3,
 		RetryDelay:        5 * time.Second,
-		EnableCleanup:     getConfigValueWithWarning("ENABLE_CLEANUP", "true", "cleanup policy") == "true",
+		MinIOCircuitBreakerThreshold: 3,
+		MinIOCircuitBreakerTimeout:   60 * time.Second,
+		MinIOCircuitBreakerProbes:    3,
+		APICircuitBreakerThreshold:   5,
+		APICircuitBreakerTimeout:     30 * time.Second,
+		APICircuitBreakerProbes:      3,
+		EnableCleanup:     s.getConfigValueWithWarning("ENABLE_CLEANUP", "true", "cleanup policy") == "true",
 		RetentionDays:     7,
-		CleanupOnStartup:  getConfigValueWithWarning("CLEANUP_ON_STARTUP", "false", "cleanup timing") == "true",
-		AutoCreateBucket:  getConfigValueWithWarning("AUTO_CREATE_BUCKET", "false", "bucket management") == "true",
+		CleanupOnStartup:  s.getConfigValueWithWarning("CLEANUP_ON_STARTUP", "false", "cleanup timing") == "true",
+		RetentionKeepLast: 3,
+		CleanupMode:       s.getConfigValueWithWarning("CLEANUP_MODE", CleanupModeDelete, "cleanup policy"),
+		CleanupTrashGracePeriod: 7 * 24 * time.Hour,
+		AutoCreateBucket:  s.getConfigValueWithWarning("AUTO_CREATE_BUCKET", "false", "bucket management") == "true",
 		BucketRetryAttempts: 3,
 		BucketRetryDelay:    2 * time.Second,
+		RestAPIEnabled:    s.getConfigValueWithWarning("REST_API", "false", "REST API") == "true",
+		APIPort:           8081,
+		GRPCPort:          9090,
+		APIToken:          s.getConfigValue("API_TOKEN"),
+		APITLSCertFile:    s.getConfigValue("API_TLS_CERT_FILE"),
+		APITLSKeyFile:     s.getConfigValue("API_TLS_KEY_FILE"),
+		APITLSCAFile:      s.getConfigValue("API_TLS_CA_FILE"),
+		APIViewerToken:    s.getConfigValue("API_VIEWER_TOKEN"),
+		APIOperatorToken:  s.getConfigValue("API_OPERATOR_TOKEN"),
+		APIApproverToken:  s.getConfigValue("API_APPROVER_TOKEN"),
+		APIOIDCIssuer:         s.getConfigValue("API_OIDC_ISSUER"),
+		APIOIDCAudience:       s.getConfigValue("API_OIDC_AUDIENCE"),
+		APIOIDCRolesClaim:     s.getConfigValueWithWarning("API_OIDC_ROLES_CLAIM", "groups", "API authentication"),
+		APIOIDCAdminGroups:    parseCommaSeparated(s.getConfigValue("API_OIDC_ADMIN_GROUPS")),
+		APIOIDCOperatorGroups: parseCommaSeparated(s.getConfigValue("API_OIDC_OPERATOR_GROUPS")),
+		APIOIDCViewerGroups:   parseCommaSeparated(s.getConfigValue("API_OIDC_VIEWER_GROUPS")),
+		APIOIDCApproverGroups: parseCommaSeparated(s.getConfigValue("API_OIDC_APPROVER_GROUPS")),
+		APIAuthEnabled:    s.getConfigValueWithWarning("API_AUTH", "false", "API authentication") == "true",
+		UIDashboardEnabled: s.getConfigValueWithWarning("UI_DASHBOARD", "false", "UI dashboard") == "true",
+		MetricsPort:        8080,
+		PprofEnabled:       s.getConfigValueWithWarning("PPROF", "false", "profiling") == "true",
+		ProfileBackup:      s.getConfigValueWithWarning("PROFILE_BACKUP", "false", "profiling") == "true",
+		HooksEnabled:       s.getConfigValueWithWarning("ENABLE_BACKUP_HOOKS", "false", "backup hooks") == "true",
+		HookTimeout:        30 * time.Second,
+		DumpPlugins:        parseCommaSeparated(s.getConfigValueWithWarning("DATABASE_DUMPS", "", "database dumps")),
+		DumpTimeout:        30 * time.Minute,
+		EtcdSnapshotEnabled: s.getConfigValueWithWarning("ETCD_SNAPSHOT", "false", "etcd snapshot") == "true",
+		EtcdEndpoints:       parseCommaSeparated(s.getConfigValueWithWarning("ETCD_ENDPOINTS", "https://127.0.0.1:2379", "etcd snapshot")),
+		EtcdCACert:          s.getConfigValueWithWarning("ETCD_CACERT", "/etc/kubernetes/pki/etcd/ca.crt", "etcd snapshot"),
+		EtcdCert:            s.getConfigValueWithWarning("ETCD_CERT", "/etc/kubernetes/pki/etcd/server.crt", "etcd snapshot"),
+		EtcdKey:             s.getConfigValueWithWarning("ETCD_KEY", "/etc/kubernetes/pki/etcd/server.key", "etcd snapshot"),
+		EtcdctlPath:         s.getConfigValueWithWarning("ETCDCTL_PATH", "etcdctl", "etcd snapshot"),
+		EtcdSnapshotTimeout: 5 * time.Minute,
+		ContentAddressed:    s.getConfigValueWithWarning("CONTENT_ADDRESSED_STORAGE", "false", "deduplication") == "true",
+		RunPrefixedLayout:   s.getConfigValueWithWarning("RUN_PREFIXED_LAYOUT", "false", "run prefixed layout") == "true",
+		ObjectPathTemplate:  s.getConfigValueWithWarning("OBJECT_PATH_TEMPLATE", "", "object path template"),
+		ObjectPathVersion:   s.getConfigValueWithWarning("OBJECT_PATH_VERSION", "false", "object path version") == "true",
+		BackupReport:        s.getConfigValueWithWarning("BACKUP_REPORT", "true", "run reports") == "true",
+		BackupIndex:         s.getConfigValueWithWarning("BACKUP_INDEX", "true", "search index") == "true",
+		RunLockEnabled:      s.getConfigValueWithWarning("RUN_LOCK", "true", "run locking") == "true",
+		RunLockTTL:          10 * time.Minute,
+		ConfigReload:         s.getConfigValueWithWarning("CONFIG_RELOAD", "true", "configuration reload") == "true",
+		ConfigReloadInterval: 30 * time.Second,
+		DiscoveryCacheDir:    s.getConfigValue("DISCOVERY_CACHE_DIR"),
+		ShutdownGracePeriod: 20 * time.Second,
+		BackupTimeout:          2 * time.Hour,
+		BackupDiscoveryTimeout: 5 * time.Minute,
+		BackupNamespaceTimeout: time.Hour,
+		BackupCleanupTimeout:   15 * time.Minute,
+		ProgressInterval:       30 * time.Second,
+		ProgressPrecount:       s.getConfigValueWithWarning("PROGRESS_PRECOUNT", "true", "progress reporting") == "true",
+		WindowCapture:          s.getConfigValueWithWarning("BACKUP_WINDOW_CAPTURE", "false", "window capture") == "true",
+		ContinuousBackup:           s.getConfigValueWithWarning("CONTINUOUS_BACKUP", "false", "continuous backup") == "true",
+		ContinuousDebounce:         10 * time.Second,
+		ContinuousSnapshotInterval: time.Hour,
+		PolicyURL:              s.getConfigValue("POLICY_OPA_URL"),
+		PolicyTimeout:          5 * time.Second,
+		ScanForSecrets:         s.getConfigValueWithWarning("SCAN_SECRETS", "false", "content validation") == "true",
+		ValidationStrictMode:   s.getConfigValueWithWarning("STRICT_VALIDATION", "false", "content validation") == "true",
+		ManifestSigning:           s.getConfigValueWithWarning("MANIFEST_SIGNING", ManifestSigningNone, "manifest signing"),
+		ManifestSigningKey:        s.getConfigValue("MANIFEST_SIGNING_KEY"),
+		SigstoreFulcioURL:         s.getConfigValueWithWarning("SIGSTORE_FULCIO_URL", "https://fulcio.sigstore.dev", "manifest signing"),
+		SigstoreRekorURL:          s.getConfigValueWithWarning("SIGSTORE_REKOR_URL", "https://rekor.sigstore.dev", "manifest signing"),
+		SigstoreIdentityTokenFile: s.getConfigValueWithWarning("SIGSTORE_IDENTITY_TOKEN_FILE", "/var/run/sigstore/cosign/oidc-token", "manifest signing"),
+		ManifestVerifyKey:         s.getConfigValue("MANIFEST_VERIFY_KEY"),
+		ManifestVerifyRoots:       s.getConfigValue("MANIFEST_VERIFY_ROOTS"),
+		ManifestVerifyIdentity:    s.getConfigValue("MANIFEST_VERIFY_IDENTITY"),
+		ManifestVerifyIssuer:      s.getConfigValue("MANIFEST_VERIFY_ISSUER"),
+		SigstoreRekorPublicKey:    s.getConfigValue("SIGSTORE_REKOR_PUBLIC_KEY"),
+		RestoreMinBackupVersion:   s.getConfigValue("RESTORE_MIN_BACKUP_VERSION"),
+		ClusterLabels:             parseLabels(s.getConfigValue("CLUSTER_LABELS")),
+		RestoreApprovalSelector:   s.getConfigValueWithWarning("RESTORE_APPROVAL_SELECTOR", "environment=production", "restore approval"),
+		RestoreApprovalTimeout:    24 * time.Hour,
+		EventBus:                  s.getConfigValue("EVENT_BUS"),
+		EventBusURL:               s.getConfigValue("EVENT_BUS_URL"),
+		EventBusTopicPrefix:       s.getConfigValue("EVENT_BUS_TOPIC_PREFIX"),
+		EventBusToken:             s.getConfigValue("EVENT_BUS_TOKEN"),
+		EventBusUsername:          s.getConfigValue("EVENT_BUS_USERNAME"),
+		EventBusPassword:          s.getConfigValue("EVENT_BUS_PASSWORD"),
+		AlertmanagerURL:           s.getConfigValue("ALERTMANAGER_URL"),
+		AlertConsecutiveFailures:  3,
+		AlertCleanupErrors:        5,
+		AlertOnEmptyBackup:        s.getConfigValueWithWarning("ALERT_ON_EMPTY_BACKUP", "true", "alerting") == "true",
+		AlertTTL:                  24 * time.Hour,
+		AlertLabels:               parseLabels(s.getConfigValue("ALERT_LABELS")),
+		RPOCheckInterval:          time.Minute,
+		TenancyConfigMap:          s.getConfigValue("TENANCY_CONFIGMAP"),
+		TenancyNamespace:          s.getConfigValueWithWarning("TENANCY_NAMESPACE", "default", "multi-tenancy"),
+		CleaningProfilesConfigMap: s.getConfigValue("CLEANING_PROFILES_CONFIGMAP"),
+		CleaningProfilesNamespace: s.getConfigValueWithWarning("CLEANING_PROFILES_NAMESPACE", "default", "cleaning profiles"),
+		ResourcePlugins:           parseCommaSeparated(s.getConfigValue("RESOURCE_PLUGINS")),
+		ResourcePluginOptions:     parseLabels(s.getConfigValue("RESOURCE_PLUGIN_OPTIONS")),
+		ReplicaEndpoint:           s.getConfigValue("REPLICA_ENDPOINT"),
+		ReplicaBucket:             s.getConfigValue("REPLICA_BUCKET"),
+		ReplicaAccessKey:          s.getConfigValue("REPLICA_ACCESS_KEY"),
+		ReplicaSecretKey:          s.getConfigValue("REPLICA_SECRET_KEY"),
+		ReplicaSampleSize:         10,
+		ReplicaCheckInterval:      5 * time.Minute,
+		SecondaryFailurePolicy:    s.getConfigValueWithWarning("SECONDARY_FAILURE_POLICY", SecondaryFailureWarn, "storage fan-out"),
+		InventoryConfigMap:        s.getConfigValue("EXPECTED_INVENTORY_CONFIGMAP"),
+		InventoryNamespace:        s.getConfigValueWithWarning("EXPECTED_INVENTORY_NAMESPACE", "default", "expected inventory"),
+		InventoryPolicy:           s.getConfigValueWithWarning("EXPECTED_INVENTORY_POLICY", InventoryPolicyWarn, "expected inventory"),
+		SyntheticValidationEnabled:         s.getConfigValueWithWarning("SYNTHETIC_VALIDATION", "false", "synthetic validation") == "true",
+		SyntheticValidationSampleSize:      10,
+		SyntheticValidationResources:       parseCommaSeparated(s.getConfigValueWithWarning("SYNTHETIC_VALIDATION_RESOURCES", "configmaps,secrets,serviceaccounts,roles,rolebindings", "synthetic validation")),
+		SyntheticValidationNamespacePrefix: s.getConfigValueWithWarning("SYNTHETIC_VALIDATION_NAMESPACE_PREFIX", "backup-validation", "synthetic validation"),
+		AuditSinks:                parseCommaSeparated(s.getConfigValue("AUDIT_LOG")),
+		AuditBucket:               s.getConfigValue("AUDIT_BUCKET"),
+		AuditWebhookURL:           s.getConfigValue("AUDIT_WEBHOOK_URL"),
+		AuditWebhookToken:         s.getConfigValue("AUDIT_WEBHOOK_TOKEN"),
+	}
+	if config.AuditBucket == "" {
+		config.AuditBucket = config.MinIOBucket
+	}
+
+	// The replica defaults to the name and TLS setting of the primary bucket
+	if config.ReplicaBucket == "" {
+		config.ReplicaBucket = config.MinIOBucket
+	}
+	config.ReplicaUseSSL = config.MinIOUseSSL
+	if sslStr := s.getConfigValue("REPLICA_USE_SSL"); sslStr != "" {
+		config.ReplicaUseSSL = sslStr == "true"
+	}
+
+	// Parse the secondary targets uploads are fanned out to
+	config.SecondaryTargets = parseStorageTargets(s.getConfigValue("SECONDARY_TARGETS"), config.MinIOUseSSL)
+	for i := range config.SecondaryTargets {
+		target := &config.SecondaryTargets[i]
+		prefix := "SECONDARY_" + strings.ToUpper(strings.ReplaceAll(target.Name, "-", "_"))
+		target.AccessKey = s.getConfigValue(prefix + "_ACCESS_KEY")
+		target.SecretKey = s.getConfigValue(prefix + "_SECRET_KEY")
 	}
 
 	// Parse fallback buckets
-	if fallbackStr := getConfigValueWithWarning("FALLBACK_BUCKETS", "", "bucket fallback"); fallbackStr != "" {
+	if fallbackStr := s.getConfigValueWithWarning("FALLBACK_BUCKETS", "", "bucket fallback"); fallbackStr != "" {
 		config.FallbackBuckets = parseCommaSeparated(fallbackStr)
 	}
 
 	// Parse batch size with validation
-	if batchStr := getConfigValueWithWarning("BATCH_SIZE", "50", "performance tuning"); batchStr != "" {
+	if batchStr := s.getConfigValueWithWarning("BATCH_SIZE", "50", "performance tuning"); batchStr != "" {
 		if batch, err := strconv.Atoi(batchStr); err == nil {
 			if batch > 0 && batch <= 1000 {
 				config.BatchSize = batch
@@ -90,8 +659,45 @@ func LoadConfig() (*Config, error) {
 		}
 	}
 
+	// Parse adaptive concurrency bounds
+	if workersStr := s.getConfigValueWithWarning("ADAPTIVE_MIN_WORKERS", "1", "adaptive concurrency"); workersStr != "" {
+		if workers, err := strconv.Atoi(workersStr); err == nil {
+			if workers >= 1 && workers <= 32 {
+				config.AdaptiveMinWorkers = workers
+			}
+		}
+	}
+	if workersStr := s.getConfigValueWithWarning("ADAPTIVE_MAX_WORKERS", "4", "adaptive concurrency"); workersStr != "" {
+		if workers, err := strconv.Atoi(workersStr); err == nil {
+			if workers >= 1 && workers <= 32 {
+				config.AdaptiveMaxWorkers = workers
+			}
+		}
+	}
+	if sizeStr := s.getConfigValueWithWarning("ADAPTIVE_MIN_BATCH_SIZE", "10", "adaptive concurrency"); sizeStr != "" {
+		if size, err := strconv.Atoi(sizeStr); err == nil {
+			if size >= 1 && size <= 1000 {
+				config.AdaptiveMinBatchSize = size
+			}
+		}
+	}
+	if sizeStr := s.getConfigValueWithWarning("ADAPTIVE_MAX_BATCH_SIZE", "500", "adaptive concurrency"); sizeStr != "" {
+		if size, err := strconv.Atoi(sizeStr); err == nil {
+			if size >= 1 && size <= 1000 {
+				config.AdaptiveMaxBatchSize = size
+			}
+		}
+	}
+	if latencyStr := s.getConfigValueWithWarning("ADAPTIVE_TARGET_LATENCY", "1s", "adaptive concurrency"); latencyStr != "" {
+		if latency, err := time.ParseDuration(latencyStr); err == nil {
+			if latency >= 10*time.Millisecond && latency <= time.Minute {
+				config.AdaptiveTargetLatency = latency
+			}
+		}
+	}
+
 	// Parse retry attempts with validation
-	if retryStr := getConfigValueWithWarning("RETRY_ATTEMPTS", "3", "retry policy"); retryStr != "" {
+	if retryStr := s.getConfigValueWithWarning("RETRY_ATTEMPTS", "3", "retry policy"); retryStr != "" {
 		if retry, err := strconv.Atoi(retryStr); err == nil {
 			if retry >= 0 && retry <= 10 {
 				config.RetryAttempts = retry
@@ -100,7 +706,7 @@ func LoadConfig() (*Config, error) {
 	}
 
 	// Parse retry delay with validation
-	if delayStr := getConfigValueWithWarning("RETRY_DELAY", "5s", "retry timing"); delayStr != "" {
+	if delayStr := s.getConfigValueWithWarning("RETRY_DELAY", "5s", "retry timing"); delayStr != "" {
 		if delay, err := time.ParseDuration(delayStr); err == nil {
 			if delay >= time.Second && delay <= 5*time.Minute {
 				config.RetryDelay = delay
@@ -108,8 +714,207 @@ func LoadConfig() (*Config, error) {
 		}
 	}
 
+	// Parse circuit breaker settings
+	s.parseCircuitBreaker("MINIO", "3", "60s", "3",
+		&config.MinIOCircuitBreakerThreshold, &config.MinIOCircuitBreakerTimeout, &config.MinIOCircuitBreakerProbes)
+	s.parseCircuitBreaker("API", "5", "30s", "3",
+		&config.APICircuitBreakerThreshold, &config.APICircuitBreakerTimeout, &config.APICircuitBreakerProbes)
+
+	// Parse run lock TTL
+	if ttlStr := s.getConfigValueWithWarning("RUN_LOCK_TTL", "10m", "run locking"); ttlStr != "" {
+		if ttl, err := time.ParseDuration(ttlStr); err == nil {
+			if ttl >= 30*time.Second && ttl <= 24*time.Hour {
+				config.RunLockTTL = ttl
+			}
+		}
+	}
+
+	// Parse how long restores wait for approval
+	if timeoutStr := s.getConfigValueWithWarning("RESTORE_APPROVAL_TIMEOUT", "24h", "restore approval"); timeoutStr != "" {
+		if timeout, err := time.ParseDuration(timeoutStr); err == nil {
+			if timeout >= time.Minute && timeout <= 7*24*time.Hour {
+				config.RestoreApprovalTimeout = timeout
+			}
+		}
+	}
+
+	// Parse configuration reload interval
+	if intervalStr := s.getConfigValueWithWarning("CONFIG_RELOAD_INTERVAL", "30s", "configuration reload"); intervalStr != "" {
+		if interval, err := time.ParseDuration(intervalStr); err == nil {
+			if interval >= 5*time.Second && interval <= time.Hour {
+				config.ConfigReloadInterval = interval
+			}
+		}
+	}
+
+	// Parse discovery cache TTL
+	if ttlStr := s.getConfigValueWithWarning("DISCOVERY_CACHE_TTL", "0", "discovery cache"); ttlStr != "" {
+		if ttl, err := time.ParseDuration(ttlStr); err == nil {
+			if ttl >= 0 && ttl <= 24*time.Hour {
+				config.DiscoveryCacheTTL = ttl
+			}
+		}
+	}
+
+	// Parse shutdown grace period
+	if graceStr := s.getConfigValueWithWarning("SHUTDOWN_GRACE_PERIOD", "20s", "graceful shutdown"); graceStr != "" {
+		if grace, err := time.ParseDuration(graceStr); err == nil {
+			if grace >= time.Second && grace <= time.Hour {
+				config.ShutdownGracePeriod = grace
+			}
+		}
+	}
+
+	// Parse backup run and phase timeouts
+	if timeoutStr := s.getConfigValueWithWarning("BACKUP_TIMEOUT", "2h", "backup timeouts"); timeoutStr != "" {
+		if timeout, err := time.ParseDuration(timeoutStr); err == nil {
+			if timeout >= time.Minute && timeout <= 48*time.Hour {
+				config.BackupTimeout = timeout
+			}
+		}
+	}
+	if timeoutStr := s.getConfigValueWithWarning("BACKUP_DISCOVERY_TIMEOUT", "5m", "backup timeouts"); timeoutStr != "" {
+		if timeout, err := time.ParseDuration(timeoutStr); err == nil {
+			if timeout >= 10*time.Second && timeout <= time.Hour {
+				config.BackupDiscoveryTimeout = timeout
+			}
+		}
+	}
+	if timeoutStr := s.getConfigValueWithWarning("BACKUP_NAMESPACE_TIMEOUT", "1h", "backup timeouts"); timeoutStr != "" {
+		if timeout, err := time.ParseDuration(timeoutStr); err == nil {
+			if timeout >= time.Minute && timeout <= 48*time.Hour {
+				config.BackupNamespaceTimeout = timeout
+			}
+		}
+	}
+	if timeoutStr := s.getConfigValueWithWarning("BACKUP_CLEANUP_TIMEOUT", "15m", "backup timeouts"); timeoutStr != "" {
+		if timeout, err := time.ParseDuration(timeoutStr); err == nil {
+			if timeout >= time.Minute && timeout <= 24*time.Hour {
+				config.BackupCleanupTimeout = timeout
+			}
+		}
+	}
+
+	// Parse progress reporting interval
+	if intervalStr := s.getConfigValueWithWarning("PROGRESS_INTERVAL", "30s", "progress reporting"); intervalStr != "" {
+		if interval, err := time.ParseDuration(intervalStr); err == nil {
+			if interval >= 5*time.Second && interval <= 10*time.Minute {
+				config.ProgressInterval = interval
+			}
+		}
+	}
+
+	// Parse continuous backup debounce and snapshot interval
+	if debounceStr := s.getConfigValueWithWarning("CONTINUOUS_DEBOUNCE", "10s", "continuous backup"); debounceStr != "" {
+		if debounce, err := time.ParseDuration(debounceStr); err == nil {
+			if debounce >= time.Second && debounce <= 10*time.Minute {
+				config.ContinuousDebounce = debounce
+			}
+		}
+	}
+	if intervalStr := s.getConfigValueWithWarning("CONTINUOUS_SNAPSHOT_INTERVAL", "1h", "continuous backup"); intervalStr != "" {
+		if interval, err := time.ParseDuration(intervalStr); err == nil {
+			if interval >= 5*time.Minute && interval <= 7*24*time.Hour {
+				config.ContinuousSnapshotInterval = interval
+			}
+		}
+	}
+
+	// Parse policy query timeout
+	if timeoutStr := s.getConfigValueWithWarning("POLICY_TIMEOUT", "5s", "policy checks"); timeoutStr != "" {
+		if timeout, err := time.ParseDuration(timeoutStr); err == nil {
+			if timeout >= time.Second && timeout <= time.Minute {
+				config.PolicyTimeout = timeout
+			}
+		}
+	}
+
+	// Parse alert thresholds
+	if failuresStr := s.getConfigValueWithWarning("ALERT_CONSECUTIVE_FAILURES", "3", "alerting"); failuresStr != "" {
+		if failures, err := strconv.Atoi(failuresStr); err == nil {
+			if failures > 0 && failures <= 100 {
+				config.AlertConsecutiveFailures = failures
+			}
+		}
+	}
+	if errorsStr := s.getConfigValueWithWarning("ALERT_CLEANUP_ERRORS", "5", "alerting"); errorsStr != "" {
+		if cleanupErrors, err := strconv.Atoi(errorsStr); err == nil {
+			if cleanupErrors >= 0 && cleanupErrors <= 10000 {
+				config.AlertCleanupErrors = cleanupErrors
+			}
+		}
+	}
+	if ttlStr := s.getConfigValueWithWarning("ALERT_TTL", "24h", "alerting"); ttlStr != "" {
+		if ttl, err := time.ParseDuration(ttlStr); err == nil {
+			if ttl >= 5*time.Minute && ttl <= 7*24*time.Hour {
+				config.AlertTTL = ttl
+			}
+		}
+	}
+
+	// Parse the RPO target and how often a long-running process checks it
+	if targetStr := s.getConfigValue("RPO_TARGET"); targetStr != "" {
+		if target, err := time.ParseDuration(targetStr); err == nil {
+			if target >= time.Minute && target <= 90*24*time.Hour {
+				config.RPOTarget = target
+			}
+		}
+	}
+	if intervalStr := s.getConfigValueWithWarning("RPO_CHECK_INTERVAL", "1m", "RPO monitoring"); intervalStr != "" {
+		if interval, err := time.ParseDuration(intervalStr); err == nil {
+			if interval >= 10*time.Second && interval <= time.Hour {
+				config.RPOCheckInterval = interval
+			}
+		}
+	}
+
+	// Parse how often the API server mode runs the cluster health checks
+	if intervalStr := s.getConfigValue("HEALTH_CHECK_INTERVAL"); intervalStr != "" {
+		if interval, err := time.ParseDuration(intervalStr); err == nil {
+			if interval >= time.Minute && interval <= 24*time.Hour {
+				config.HealthCheckInterval = interval
+			}
+		}
+	}
+
+	// Parse MinIO connection pool size
+	if connsStr := s.getConfigValueWithWarning("MINIO_MAX_IDLE_CONNS", "100", "MinIO connection"); connsStr != "" {
+		if conns, err := strconv.Atoi(connsStr); err == nil {
+			if conns > 0 && conns <= 1000 {
+				config.MinIOMaxIdleConns = conns
+			}
+		}
+	}
+
+	// Parse MinIO idle connection timeout
+	if timeoutStr := s.getConfigValueWithWarning("MINIO_IDLE_CONN_TIMEOUT", "90s", "MinIO connection"); timeoutStr != "" {
+		if timeout, err := time.ParseDuration(timeoutStr); err == nil {
+			if timeout >= time.Second && timeout <= time.Hour {
+				config.MinIOIdleConnTimeout = timeout
+			}
+		}
+	}
+
+	// Parse MinIO TLS handshake timeout
+	if timeoutStr := s.getConfigValueWithWarning("MINIO_TLS_HANDSHAKE_TIMEOUT", "10s", "MinIO connection"); timeoutStr != "" {
+		if timeout, err := time.ParseDuration(timeoutStr); err == nil {
+			if timeout >= time.Second && timeout <= 5*time.Minute {
+				config.MinIOTLSHandshakeTimeout = timeout
+			}
+		}
+	}
+
+	// Parse credentials refresh interval
+	if intervalStr := s.getConfigValueWithWarning("CREDENTIALS_REFRESH_INTERVAL", "1m", "credential rotation"); intervalStr != "" {
+		if interval, err := time.ParseDuration(intervalStr); err == nil {
+			if interval >= time.Second && interval <= time.Hour {
+				config.CredentialsRefreshInterval = interval
+			}
+		}
+	}
+
 	// Parse retention days
-	if retentionStr := getConfigValueWithWarning("RETENTION_DAYS", "7", "cleanup retention"); retentionStr != "" {
+	if retentionStr := s.getConfigValueWithWarning("RETENTION_DAYS", "7", "cleanup retention"); retentionStr != "" {
 		if retention, err := strconv.Atoi(retentionStr); err == nil {
 			if retention > 0 && retention <= 365 {
 				config.RetentionDays = retention
@@ -117,6 +922,103 @@ func LoadConfig() (*Config, error) {
 		}
 	}
 
+	// Parse the number of completed runs kept regardless of age
+	if keepStr := s.getConfigValueWithWarning("RETENTION_KEEP_LAST", "3", "cleanup retention"); keepStr != "" {
+		if keep, err := strconv.Atoi(keepStr); err == nil {
+			if keep >= 0 && keep <= 100 {
+				config.RetentionKeepLast = keep
+			}
+		}
+	}
+
+	// Parse how long trashed objects can still be restored
+	if graceStr := s.getConfigValueWithWarning("CLEANUP_TRASH_GRACE_PERIOD", "168h", "cleanup policy"); graceStr != "" {
+		if grace, err := time.ParseDuration(graceStr); err == nil {
+			if grace >= time.Hour && grace <= 2160*time.Hour {
+				config.CleanupTrashGracePeriod = grace
+			}
+		}
+	}
+
+	// Parse how many objects of the latest run are read back from the replica
+	if sampleStr := s.getConfigValueWithWarning("REPLICA_SAMPLE_SIZE", "10", "replication verification"); sampleStr != "" {
+		if sample, err := strconv.Atoi(sampleStr); err == nil {
+			if sample >= 0 && sample <= 1000 {
+				config.ReplicaSampleSize = sample
+			}
+		}
+	}
+	if intervalStr := s.getConfigValueWithWarning("REPLICA_CHECK_INTERVAL", "5m", "replication verification"); intervalStr != "" {
+		if interval, err := time.ParseDuration(intervalStr); err == nil {
+			if interval >= 10*time.Second && interval <= 24*time.Hour {
+				config.ReplicaCheckInterval = interval
+			}
+		}
+	}
+
+	// Parse how many objects of each run synthetic validation restores
+	if sampleStr := s.getConfigValueWithWarning("SYNTHETIC_VALIDATION_SAMPLE_SIZE", "10", "synthetic validation"); sampleStr != "" {
+		if sample, err := strconv.Atoi(sampleStr); err == nil {
+			if sample >= 1 && sample <= 500 {
+				config.SyntheticValidationSampleSize = sample
+			}
+		}
+	}
+
+	// Parse REST API port
+	if portStr := s.getConfigValueWithWarning("API_PORT", "8081", "REST API"); portStr != "" {
+		if port, err := strconv.Atoi(portStr); err == nil {
+			if port > 0 && port <= 65535 {
+				config.APIPort = port
+			}
+		}
+	}
+
+	// Parse gRPC API port
+	if portStr := s.getConfigValueWithWarning("GRPC_PORT", "9090", "REST API"); portStr != "" {
+		if port, err := strconv.Atoi(portStr); err == nil {
+			if port > 0 && port <= 65535 {
+				config.GRPCPort = port
+			}
+		}
+	}
+
+	// Parse metrics port
+	if portStr := s.getConfigValueWithWarning("METRICS_PORT", "8080", "metrics"); portStr != "" {
+		if port, err := strconv.Atoi(portStr); err == nil {
+			if port > 0 && port <= 65535 {
+				config.MetricsPort = port
+			}
+		}
+	}
+
+	// Parse default hook timeout
+	if timeoutStr := s.getConfigValueWithWarning("HOOK_TIMEOUT", "30s", "backup hooks"); timeoutStr != "" {
+		if timeout, err := time.ParseDuration(timeoutStr); err == nil {
+			if timeout >= time.Second && timeout <= time.Hour {
+				config.HookTimeout = timeout
+			}
+		}
+	}
+
+	// Parse database dump timeout
+	if timeoutStr := s.getConfigValueWithWarning("DUMP_TIMEOUT", "30m", "database dumps"); timeoutStr != "" {
+		if timeout, err := time.ParseDuration(timeoutStr); err == nil {
+			if timeout >= time.Minute && timeout <= 12*time.Hour {
+				config.DumpTimeout = timeout
+			}
+		}
+	}
+
+	// Parse etcd snapshot timeout
+	if timeoutStr := s.getConfigValueWithWarning("ETCD_SNAPSHOT_TIMEOUT", "5m", "etcd snapshot"); timeoutStr != "" {
+		if timeout, err := time.ParseDuration(timeoutStr); err == nil {
+			if timeout >= 10*time.Second && timeout <= time.Hour {
+				config.EtcdSnapshotTimeout = timeout
+			}
+		}
+	}
+
 	// Validate required fields
 	if err := config.Validate(); err != nil {
 		return nil, sharedErrors.NewConfigurationError("config", "load", "configuration validation failed", err)
@@ -125,6 +1027,16 @@ func LoadConfig() (*Config, error) {
 	return config, nil
 }
 
+// RestoreApprovalRequired reports whether restores of the cluster wait for a
+// second person's approval. A selector that does not parse requires it.
+func (c *Config) RestoreApprovalRequired() bool {
+	selector, err := labels.Parse(c.RestoreApprovalSelector)
+	if err != nil {
+		return true
+	}
+	return !selector.Empty() && selector.Matches(labels.Set(c.ClusterLabels))
+}
+
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
 	validator := sharedErrors.NewValidationHelper("config")
@@ -134,17 +1046,78 @@ func (c *Config) Validate() error {
 	if err := validator.Required("MINIO_ENDPOINT", c.MinIOEndpoint); err != nil {
 		multiErr.Add(err)
 	}
-	if err := validator.Required("MINIO_ACCESS_KEY", c.MinIOAccessKey); err != nil {
-		multiErr.Add(err)
+
+	// Keys are only needed when they sign requests; the other methods fetch
+	// short-lived credentials at runtime
+	switch c.StorageAuthMethod {
+	case StorageAuthStatic, "":
+		// Keys kept in Vault are only known once it is reached
+		if c.SecretProvider != SecretProviderVault {
+			if c.MinIOAccessKeyFile == "" {
+				if err := validator.Required("MINIO_ACCESS_KEY", c.MinIOAccessKey); err != nil {
+					multiErr.Add(err)
+				}
+			}
+			if c.MinIOSecretKeyFile == "" {
+				if err := validator.Required("MINIO_SECRET_KEY", c.MinIOSecretKey); err != nil {
+					multiErr.Add(err)
+				}
+			}
+		}
+	case StorageAuthIRSA, StorageAuthSTSWebIdentity:
+		// Role and token default to what the platform injects into the pod
+	case StorageAuthGCPWorkloadIdentity:
+		// Bearer tokens must not travel in plain text
+		if !c.MinIOUseSSL {
+			multiErr.Add(sharedErrors.NewValidationError("config", "STORAGE_AUTH_METHOD",
+				"gcp-workload-identity requires MINIO_USE_SSL to be enabled"))
+		}
+	default:
+		multiErr.Add(sharedErrors.NewValidationError("config", "STORAGE_AUTH_METHOD",
+			"STORAGE_AUTH_METHOD must be one of static, irsa, gcp-workload-identity, sts-web-identity"))
 	}
-	if err := validator.Required("MINIO_SECRET_KEY", c.MinIOSecretKey); err != nil {
-		multiErr.Add(err)
+
+	switch c.SecretProvider {
+	case SecretProviderEnv, "":
+	case SecretProviderVault:
+		if err := validator.Required("VAULT_ADDR", c.VaultAddress); err != nil {
+			multiErr.Add(err)
+		}
+		if c.VaultToken == "" && c.VaultRole == "" {
+			multiErr.Add(sharedErrors.NewValidationError("config", "VAULT_TOKEN",
+				"VAULT_TOKEN or VAULT_ROLE is required when SECRET_PROVIDER is vault"))
+		}
+	default:
+		multiErr.Add(sharedErrors.NewValidationError("config", "SECRET_PROVIDER",
+			"SECRET_PROVIDER must be one of env, vault"))
 	}
 	
+	if c.MinIOCABundle != "" && !c.MinIOUseSSL {
+		multiErr.Add(sharedErrors.NewValidationError("config", "CA_BUNDLE_PATH",
+			"CA_BUNDLE_PATH requires MINIO_USE_SSL to be enabled"))
+	}
+	if (c.MinIOClientCert == "") != (c.MinIOClientKey == "") {
+		multiErr.Add(sharedErrors.NewValidationError("config", "CLIENT_CERT_PATH",
+			"CLIENT_CERT_PATH and CLIENT_KEY_PATH must be set together"))
+	} else if c.MinIOClientCert != "" && !c.MinIOUseSSL {
+		multiErr.Add(sharedErrors.NewValidationError("config", "CLIENT_CERT_PATH",
+			"CLIENT_CERT_PATH requires MINIO_USE_SSL to be enabled"))
+	}
+
 	// Range validations
 	if err := validator.Range("batch_size", c.BatchSize, 1, 1000); err != nil {
 		multiErr.Add(err)
 	}
+	if c.AdaptiveConcurrency {
+		if c.AdaptiveMinWorkers > c.AdaptiveMaxWorkers {
+			multiErr.Add(sharedErrors.NewValidationError("config", "ADAPTIVE_MIN_WORKERS",
+				"ADAPTIVE_MIN_WORKERS cannot exceed ADAPTIVE_MAX_WORKERS"))
+		}
+		if c.AdaptiveMinBatchSize > c.AdaptiveMaxBatchSize {
+			multiErr.Add(sharedErrors.NewValidationError("config", "ADAPTIVE_MIN_BATCH_SIZE",
+				"ADAPTIVE_MIN_BATCH_SIZE cannot exceed ADAPTIVE_MAX_BATCH_SIZE"))
+		}
+	}
 	if err := validator.Range("retry_attempts", c.RetryAttempts, 0, 10); err != nil {
 		multiErr.Add(err)
 	}
@@ -152,42 +1125,412 @@ func (c *Config) Validate() error {
 		multiErr.Add(err)
 	}
 	
+	// The REST API can trigger backups, so it must never run unauthenticated
+	if c.RestAPIEnabled {
+		if err := validator.Required("API_TOKEN", c.APIToken); err != nil {
+			multiErr.Add(err)
+		}
+		if (c.APITLSCertFile == "") != (c.APITLSKeyFile == "") {
+			multiErr.Add(sharedErrors.NewValidationError("config", "API_TLS_CERT_FILE",
+				"API_TLS_CERT_FILE and API_TLS_KEY_FILE must be set together"))
+		}
+		if c.APITLSCAFile != "" && c.APITLSCertFile == "" {
+			multiErr.Add(sharedErrors.NewValidationError("config", "API_TLS_CA_FILE",
+				"API_TLS_CA_FILE requires API_TLS_CERT_FILE and API_TLS_KEY_FILE"))
+		}
+	}
+	// The issuer's keys are fetched through its discovery document, which
+	// must not be open to tampering
+	if c.APIOIDCIssuer != "" {
+		if u, err := url.Parse(c.APIOIDCIssuer); err != nil || u.Scheme != "https" || u.Host == "" {
+			multiErr.Add(sharedErrors.NewValidationError("config", "API_OIDC_ISSUER",
+				"API_OIDC_ISSUER must be an https URL"))
+		}
+		if err := validator.Required("API_OIDC_AUDIENCE", c.APIOIDCAudience); err != nil {
+			multiErr.Add(err)
+		}
+	} else if len(c.APIOIDCAdminGroups)+len(c.APIOIDCOperatorGroups)+len(c.APIOIDCViewerGroups)+len(c.APIOIDCApproverGroups) > 0 {
+		multiErr.Add(sharedErrors.NewValidationError("config", "API_OIDC_ISSUER",
+			"API_OIDC_ISSUER must be set with the API_OIDC_*_GROUPS settings"))
+	}
+	// The metrics and health endpoints it protects are served with the APIs
+	if c.APIAuthEnabled && !c.RestAPIEnabled {
+		multiErr.Add(sharedErrors.NewValidationError("config", "API_AUTH",
+			"API_AUTH requires REST_API to be enabled"))
+	}
+
+	if c.EtcdSnapshotEnabled && len(c.EtcdEndpoints) == 0 {
+		multiErr.Add(sharedErrors.NewValidationError("config", "ETCD_ENDPOINTS",
+			"ETCD_ENDPOINTS must not be empty when ETCD_SNAPSHOT is enabled"))
+	}
+
+	switch c.CleanupMode {
+	case CleanupModeDelete, CleanupModeTrash, "":
+	default:
+		multiErr.Add(sharedErrors.NewValidationError("config", "CLEANUP_MODE",
+			"CLEANUP_MODE must be one of delete, trash"))
+	}
+
+	switch c.ManifestSigning {
+	case ManifestSigningNone, "":
+	case ManifestSigningKey:
+		if err := validator.Required("MANIFEST_SIGNING_KEY", c.ManifestSigningKey); err != nil {
+			multiErr.Add(err)
+		}
+	case ManifestSigningKeyless:
+		if err := validator.Required("SIGSTORE_FULCIO_URL", c.SigstoreFulcioURL); err != nil {
+			multiErr.Add(err)
+		}
+	default:
+		multiErr.Add(sharedErrors.NewValidationError("config", "MANIFEST_SIGNING",
+			"MANIFEST_SIGNING must be one of none, key, keyless"))
+	}
+	// Any Fulcio certificate chains to the roots, so the signer must be pinned
+	if c.ManifestVerifyRoots != "" && c.ManifestVerifyIdentity == "" {
+		multiErr.Add(sharedErrors.NewValidationError("config", "MANIFEST_VERIFY_IDENTITY",
+			"MANIFEST_VERIFY_IDENTITY is required when MANIFEST_VERIFY_ROOTS is set"))
+	}
+	if c.RestoreMinBackupVersion != "" {
+		if _, err := version.ParseGeneric(c.RestoreMinBackupVersion); err != nil {
+			multiErr.Add(sharedErrors.NewValidationError("config", "RESTORE_MIN_BACKUP_VERSION",
+				"RESTORE_MIN_BACKUP_VERSION must be a version such as v1.4.0"))
+		}
+	}
+	if _, err := labels.Parse(c.RestoreApprovalSelector); err != nil {
+		multiErr.Add(sharedErrors.NewValidationError("config", "RESTORE_APPROVAL_SELECTOR",
+			fmt.Sprintf("RESTORE_APPROVAL_SELECTOR must be a label selector such as environment=production: %v", err)))
+	} else if c.RestAPIEnabled && c.RestoreApprovalRequired() && c.APIApproverToken == "" && c.APIOIDCIssuer == "" {
+		// Otherwise nobody could approve a restore
+		multiErr.Add(sharedErrors.NewValidationError("config", "API_APPROVER_TOKEN",
+			"API_APPROVER_TOKEN or API_OIDC_ISSUER is required when restores need approval"))
+	}
+
+	if c.PolicyURL != "" {
+		if u, err := url.Parse(c.PolicyURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			multiErr.Add(sharedErrors.NewValidationError("config", "POLICY_OPA_URL",
+				"POLICY_OPA_URL must be an http or https URL"))
+		}
+	}
+
+	switch c.EventBus {
+	case "":
+	case eventbus.ProviderNATS, eventbus.ProviderKafka:
+		if err := validator.Required("EVENT_BUS_URL", c.EventBusURL); err != nil {
+			multiErr.Add(err)
+		}
+	default:
+		multiErr.Add(sharedErrors.NewValidationError("config", "EVENT_BUS",
+			"EVENT_BUS must be one of nats, kafka"))
+	}
+
+	if c.AlertmanagerURL != "" {
+		if u, err := url.Parse(c.AlertmanagerURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			multiErr.Add(sharedErrors.NewValidationError("config", "ALERTMANAGER_URL",
+				"ALERTMANAGER_URL must be an http or https URL"))
+		}
+	}
+	for key, value := range c.AlertLabels {
+		if key == "" || value == "" || strings.Contains(key, " ") {
+			multiErr.Add(sharedErrors.NewValidationError("config", "ALERT_LABELS",
+				"ALERT_LABELS must be a comma-separated list of name=value pairs"))
+			break
+		}
+	}
+
+	for setting, value := range map[string]string{
+		"BACKUP_BANDWIDTH_LIMIT":  c.BackupBandwidthLimit,
+		"RESTORE_BANDWIDTH_LIMIT": c.RestoreBandwidthLimit,
+	} {
+		if _, err := ParseBandwidth(value); err != nil {
+			multiErr.Add(sharedErrors.NewValidationError("config", setting, fmt.Sprintf("%s: %v", setting, err)))
+		}
+	}
+
+	// Blobs are shared by every run writing to a bucket, so a tenant could
+	// read another's data through them
+	if c.TenancyConfigMap != "" && c.ContentAddressed {
+		multiErr.Add(sharedErrors.NewValidationError("config", "TENANCY_CONFIGMAP",
+			"TENANCY_CONFIGMAP cannot be combined with CONTENT_ADDRESSED_STORAGE"))
+	}
+
+	// Blobs are already never overwritten, and are shared by every run
+	if c.RunPrefixedLayout && c.ContentAddressed {
+		multiErr.Add(sharedErrors.NewValidationError("config", "RUN_PREFIXED_LAYOUT",
+			"RUN_PREFIXED_LAYOUT cannot be combined with CONTENT_ADDRESSED_STORAGE"))
+	}
+
+	if c.ObjectPathTemplate != "" {
+		if _, err := pathtemplate.Parse(c.ObjectPathTemplate); err != nil {
+			multiErr.Add(sharedErrors.NewValidationError("config", "OBJECT_PATH_TEMPLATE", err.Error()))
+		}
+		// The template places the whole key; .BackupID gives each run a prefix
+		if c.RunPrefixedLayout {
+			multiErr.Add(sharedErrors.NewValidationError("config", "OBJECT_PATH_TEMPLATE",
+				"OBJECT_PATH_TEMPLATE cannot be combined with RUN_PREFIXED_LAYOUT, use {{.BackupID}} in the template instead"))
+		}
+		if c.ContentAddressed {
+			multiErr.Add(sharedErrors.NewValidationError("config", "OBJECT_PATH_TEMPLATE",
+				"OBJECT_PATH_TEMPLATE cannot be combined with CONTENT_ADDRESSED_STORAGE, which stores resources by content hash"))
+		}
+	}
+
+	// Verifying a bucket against itself would always succeed
+	if c.ReplicaEndpoint != "" {
+		if c.ReplicaEndpoint == c.MinIOEndpoint && c.ReplicaBucket == c.MinIOBucket {
+			multiErr.Add(sharedErrors.NewValidationError("config", "REPLICA_BUCKET",
+				"REPLICA_ENDPOINT and REPLICA_BUCKET must name a bucket other than MINIO_BUCKET"))
+		}
+		if (c.ReplicaAccessKey == "") != (c.ReplicaSecretKey == "") {
+			multiErr.Add(sharedErrors.NewValidationError("config", "REPLICA_ACCESS_KEY",
+				"REPLICA_ACCESS_KEY and REPLICA_SECRET_KEY must be set together"))
+		}
+	}
+
+	// Fanning out to MINIO_BUCKET itself would upload every object twice
+	names := make(map[string]bool)
+	for _, target := range c.SecondaryTargets {
+		if target.Name == "" || target.Endpoint == "" || target.Bucket == "" {
+			multiErr.Add(sharedErrors.NewValidationError("config", "SECONDARY_TARGETS",
+				"SECONDARY_TARGETS must be a comma-separated list of name=endpoint/bucket entries"))
+			break
+		}
+		if names[target.Name] {
+			multiErr.Add(sharedErrors.NewValidationError("config", "SECONDARY_TARGETS",
+				"secondary target "+target.Name+" is listed twice"))
+		}
+		names[target.Name] = true
+		if target.Endpoint == c.MinIOEndpoint && target.Bucket == c.MinIOBucket {
+			multiErr.Add(sharedErrors.NewValidationError("config", "SECONDARY_TARGETS",
+				"secondary target "+target.Name+" must name a bucket other than MINIO_BUCKET"))
+		}
+		if (target.AccessKey == "") != (target.SecretKey == "") {
+			multiErr.Add(sharedErrors.NewValidationError("config", "SECONDARY_TARGETS",
+				"the access and secret key of secondary target "+target.Name+" must be set together"))
+		}
+	}
+	switch c.SecondaryFailurePolicy {
+	case SecondaryFailureWarn, SecondaryFailureFail, "":
+	default:
+		multiErr.Add(sharedErrors.NewValidationError("config", "SECONDARY_FAILURE_POLICY",
+			"SECONDARY_FAILURE_POLICY must be warn or fail"))
+	}
+	if c.SyntheticValidationEnabled {
+		// The namespace gets a random suffix of 6 characters
+		if errs := validation.IsDNS1123Label(c.SyntheticValidationNamespacePrefix + "-xxxxx"); len(errs) > 0 {
+			multiErr.Add(sharedErrors.NewValidationError("config", "SYNTHETIC_VALIDATION_NAMESPACE_PREFIX",
+				"SYNTHETIC_VALIDATION_NAMESPACE_PREFIX must be a DNS label of at most 57 characters: "+strings.Join(errs, ", ")))
+		}
+		if len(c.SyntheticValidationResources) == 0 {
+			multiErr.Add(sharedErrors.NewValidationError("config", "SYNTHETIC_VALIDATION_RESOURCES",
+				"SYNTHETIC_VALIDATION_RESOURCES must list at least one resource type"))
+		}
+	}
+	switch c.InventoryPolicy {
+	case InventoryPolicyWarn, InventoryPolicyFail, "":
+	default:
+		multiErr.Add(sharedErrors.NewValidationError("config", "EXPECTED_INVENTORY_POLICY",
+			"EXPECTED_INVENTORY_POLICY must be warn or fail"))
+	}
+
+	for _, sink := range c.AuditSinks {
+		switch sink {
+		case AuditSinkBucket:
+		case AuditSinkWebhook:
+			if u, err := url.Parse(c.AuditWebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				multiErr.Add(sharedErrors.NewValidationError("config", "AUDIT_WEBHOOK_URL",
+					"AUDIT_WEBHOOK_URL must be an http or https URL with the webhook audit sink"))
+			}
+		default:
+			multiErr.Add(sharedErrors.NewValidationError("config", "AUDIT_LOG",
+				"AUDIT_LOG must list bucket, webhook or both"))
+		}
+	}
+
+	// The dashboard drives backups through the API server's run tracking
+	if c.UIDashboardEnabled && !c.RestAPIEnabled {
+		multiErr.Add(sharedErrors.NewValidationError("config", "UI_DASHBOARD",
+			"UI_DASHBOARD requires REST_API to be enabled"))
+	}
+	// Profiles expose memory contents, so they are never served unauthenticated
+	if c.PprofEnabled && c.APIToken == "" {
+		multiErr.Add(sharedErrors.NewValidationError("config", "PPROF",
+			"PPROF requires API_TOKEN to be set"))
+	}
+
+	switch c.ComplianceMode {
+	case ComplianceModeNone, "":
+	case ComplianceModeFIPS:
+		// Approved cipher suites mean nothing for connections without TLS
+		if !c.MinIOUseSSL {
+			multiErr.Add(sharedErrors.NewValidationError("config", "MINIO_USE_SSL",
+				"COMPLIANCE_MODE=fips requires MINIO_USE_SSL to be enabled"))
+		}
+		if c.ReplicaEndpoint != "" && !c.ReplicaUseSSL {
+			multiErr.Add(sharedErrors.NewValidationError("config", "REPLICA_USE_SSL",
+				"COMPLIANCE_MODE=fips requires REPLICA_USE_SSL to be enabled"))
+		}
+		for _, target := range c.SecondaryTargets {
+			if !target.UseSSL {
+				multiErr.Add(sharedErrors.NewValidationError("config", "SECONDARY_TARGETS",
+					"COMPLIANCE_MODE=fips requires secondary target "+target.Name+" to use TLS"))
+			}
+		}
+		if c.RestAPIEnabled && c.APITLSCertFile == "" {
+			multiErr.Add(sharedErrors.NewValidationError("config", "API_TLS_CERT_FILE",
+				"COMPLIANCE_MODE=fips requires API_TLS_CERT_FILE with REST_API"))
+		}
+		webhooks := map[string]string{"ALERTMANAGER_URL": c.AlertmanagerURL, "POLICY_OPA_URL": c.PolicyURL}
+		for _, sink := range c.AuditSinks {
+			if sink == AuditSinkWebhook {
+				webhooks["AUDIT_WEBHOOK_URL"] = c.AuditWebhookURL
+			}
+		}
+		for _, key := range []string{"ALERTMANAGER_URL", "AUDIT_WEBHOOK_URL", "POLICY_OPA_URL"} {
+			if webhooks[key] != "" && !strings.HasPrefix(webhooks[key], "https://") {
+				multiErr.Add(sharedErrors.NewValidationError("config", key,
+					"COMPLIANCE_MODE=fips requires "+key+" to be an https URL"))
+			}
+		}
+	default:
+		multiErr.Add(sharedErrors.NewValidationError("config", "COMPLIANCE_MODE",
+			"COMPLIANCE_MODE must be one of none, fips"))
+	}
+	
 	return multiErr.ToError()
 }
 
-// LoadBackupConfig loads backup-specific configuration
+// LoadBackupConfig loads backup-specific configuration from the shared
+// configuration file, when there is one, with environment variables
+// overriding it
 func LoadBackupConfig() (*BackupConfig, error) {
+	shared, err := LoadSharedConfig()
+	if err != nil {
+		return nil, err
+	}
+	return LoadBackupConfigFrom(shared)
+}
+
+// LoadBackupConfigFrom loads backup-specific configuration from shared, which
+// may be nil, with environment variables overriding its settings
+func LoadBackupConfigFrom(shared *sharedconfig.SharedConfig) (*BackupConfig, error) {
+	s := newSettings(shared)
 	config := &BackupConfig{
-		FilteringMode:           "whitelist",
-		IncludeResources:        parseCommaSeparated(getConfigValueWithWarning("INCLUDE_RESOURCES", "", "resource inclusion")),
-		ExcludeResources:        parseCommaSeparated(getConfigValueWithWarning("EXCLUDE_RESOURCES", "", "resource exclusion")),
-		IncludeNamespaces:       parseCommaSeparated(getConfigValueWithWarning("INCLUDE_NAMESPACES", "", "namespace inclusion")),
-		ExcludeNamespaces:       parseCommaSeparated(getConfigValueWithWarning("EXCLUDE_NAMESPACES", "", "namespace exclusion")),
-		LabelSelector:           getConfigValueWithWarning("LABEL_SELECTOR", "", "label filtering"),
-		AnnotationSelector:      getConfigValueWithWarning("ANNOTATION_SELECTOR", "", "annotation filtering"),
-		MaxResourceSize:         getConfigValueWithWarning("MAX_RESOURCE_SIZE", "10Mi", "resource size limit"),
-		FollowOwnerReferences:   getConfigValueWithWarning("FOLLOW_OWNER_REFERENCES", "false", "owner reference tracking") == "true",
-		IncludeManagedFields:    getConfigValueWithWarning("INCLUDE_MANAGED_FIELDS", "false", "managed fields") == "true",
-		IncludeStatus:           getConfigValueWithWarning("INCLUDE_STATUS", "false", "resource status") == "true",
-		OpenShiftMode:           getConfigValueWithWarning("OPENSHIFT_MODE", "auto-detect", "OpenShift detection"),
-		IncludeOpenShiftRes:     getConfigValueWithWarning("INCLUDE_OPENSHIFT_RESOURCES", "true", "OpenShift resources") == "true",
-		ValidateYAML:            getConfigValueWithWarning("VALIDATE_YAML", "true", "YAML validation") == "true",
-		SkipInvalidResources:    getConfigValueWithWarning("SKIP_INVALID_RESOURCES", "true", "invalid resource handling") == "true",
-		EnableCleanup:           getConfigValueWithWarning("ENABLE_CLEANUP", "true", "cleanup policy") == "true",
-		CleanupOnStartup:        getConfigValueWithWarning("CLEANUP_ON_STARTUP", "false", "startup cleanup") == "true",
+		FilteringMode:           s.getConfigValueWithWarning("FILTERING_MODE", "whitelist", "filtering mode"),
+		IncludeResources:        parseCommaSeparated(s.getConfigValueWithWarning("INCLUDE_RESOURCES", "", "resource inclusion")),
+		ExcludeResources:        parseCommaSeparated(s.getConfigValueWithWarning("EXCLUDE_RESOURCES", "", "resource exclusion")),
+		IncludeNamespaces:       parseCommaSeparated(s.getConfigValueWithWarning("INCLUDE_NAMESPACES", "", "namespace inclusion")),
+		ExcludeNamespaces:       parseCommaSeparated(s.getConfigValueWithWarning("EXCLUDE_NAMESPACES", "", "namespace exclusion")),
+		BackupClusterResources:  s.getConfigValueWithWarning("BACKUP_CLUSTER_RESOURCES", "true", "cluster-scoped resources") == "true",
+		ClusterResources:        parseCommaSeparated(s.getConfigValueWithWarning("CLUSTER_RESOURCES", DefaultClusterResources, "cluster-scoped resources")),
+		GovernanceFirst:         s.getConfigValueWithWarning("GOVERNANCE_FIRST", "true", "governance resources") == "true",
+		AllAPIVersions:          s.getConfigValueWithWarning("BACKUP_ALL_API_VERSIONS", "false", "API versions") == "true",
+		APIVersions:             parseCommaSeparated(s.getConfigValueWithWarning("BACKUP_API_VERSIONS", "", "API versions")),
+		Provenance:              parseCommaSeparated(s.getConfigValueWithWarning("BACKUP_PROVENANCE", "", "backup provenance")),
+		ProvenanceLabels:        s.getConfigValueWithWarning("BACKUP_PROVENANCE_LABELS", "false", "backup provenance") == "true",
+		LabelSelector:           s.getConfigValueWithWarning("LABEL_SELECTOR", "", "label filtering"),
+		AnnotationSelector:      s.getConfigValueWithWarning("ANNOTATION_SELECTOR", "", "annotation filtering"),
+		MaxResourceSize:         s.getConfigValueWithWarning("MAX_RESOURCE_SIZE", "10Mi", "resource size limit"),
+		FollowOwnerReferences:   s.getConfigValueWithWarning("FOLLOW_OWNER_REFERENCES", "false", "owner reference tracking") == "true",
+		IncludeManagedFields:    s.getConfigValueWithWarning("INCLUDE_MANAGED_FIELDS", "false", "managed fields") == "true",
+		IncludeStatus:           s.getConfigValueWithWarning("INCLUDE_STATUS", "false", "resource status") == "true",
+		OpenShiftMode:           s.getConfigValueWithWarning("OPENSHIFT_MODE", "auto-detect", "OpenShift detection"),
+		IncludeOpenShiftRes:     s.getConfigValueWithWarning("INCLUDE_OPENSHIFT_RESOURCES", "true", "OpenShift resources") == "true",
+		ValidateYAML:            s.getConfigValueWithWarning("VALIDATE_YAML", "true", "YAML validation") == "true",
+		SkipInvalidResources:    s.getConfigValueWithWarning("SKIP_INVALID_RESOURCES", "true", "invalid resource handling") == "true",
+		EnableCleanup:           s.getConfigValueWithWarning("ENABLE_CLEANUP", "true", "cleanup policy") == "true",
+		CleanupOnStartup:        s.getConfigValueWithWarning("CLEANUP_ON_STARTUP", "false", "startup cleanup") == "true",
 		RetentionDays:           7,
 	}
 
 	// Parse retention days
-	if retentionStr := getConfigValueWithWarning("RETENTION_DAYS", "7", "cleanup retention"); retentionStr != "" {
+	if retentionStr := s.getConfigValueWithWarning("RETENTION_DAYS", "7", "cleanup retention"); retentionStr != "" {
 		if retention, err := strconv.Atoi(retentionStr); err == nil && retention > 0 && retention <= 365 {
 			config.RetentionDays = retention
 		}
 	}
 
+	if err := config.Validate(); err != nil {
+		return nil, sharedErrors.NewConfigurationError("backup_config", "load", "backup configuration validation failed", err)
+	}
+
 	return config, nil
 }
 
+// FilterRules returns the namespace and resource filters of the configuration
+func (c *BackupConfig) FilterRules() filter.Rules {
+	return filter.Rules{
+		Mode:               filter.Mode(c.FilteringMode),
+		IncludeNamespaces:  c.IncludeNamespaces,
+		ExcludeNamespaces:  c.ExcludeNamespaces,
+		IncludeResources:   c.IncludeResources,
+		ExcludeResources:   c.ExcludeResources,
+		ClusterResources:   c.ClusterResources,
+		LabelSelector:      c.LabelSelector,
+		AnnotationSelector: c.AnnotationSelector,
+	}
+}
+
+// Validate checks the filtering rules, selectors, API versions and
+// provenance entries
+func (c *BackupConfig) Validate() error {
+	if _, err := filter.Validate(c.FilterRules()); err != nil {
+		return err
+	}
+	for _, entry := range c.APIVersions {
+		if _, _, err := ParseAPIVersion(entry); err != nil {
+			return err
+		}
+	}
+	for _, entry := range c.Provenance {
+		if _, _, err := ParseProvenance(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Provenance fields a BACKUP_PROVENANCE entry can select
+const (
+	ProvenanceBackupID  = "backup-id"
+	ProvenanceCluster   = "cluster"
+	ProvenanceTimestamp = "timestamp"
+)
+
+// ParseProvenance parses a BACKUP_PROVENANCE entry such as deployments.apps,
+// * or secrets=backup-id+cluster. An entry without fields selects all of
+// them, and one with an empty list, such as secrets=, none.
+func ParseProvenance(entry string) (string, []string, error) {
+	resource, list, found := strings.Cut(entry, "=")
+	if resource == "" || strings.ContainsAny(resource, "/ ") {
+		return "", nil, fmt.Errorf("invalid entry %q in BACKUP_PROVENANCE, expected resource.group[=field+field]", entry)
+	}
+	if !found {
+		return resource, []string{ProvenanceBackupID, ProvenanceCluster, ProvenanceTimestamp}, nil
+	}
+
+	fields := []string{}
+	for _, field := range strings.Split(list, "+") {
+		switch field {
+		case "":
+		case ProvenanceBackupID, ProvenanceCluster, ProvenanceTimestamp:
+			fields = append(fields, field)
+		default:
+			return "", nil, fmt.Errorf("invalid field %q in BACKUP_PROVENANCE entry %q, expected %s, %s or %s", field, entry, ProvenanceBackupID, ProvenanceCluster, ProvenanceTimestamp)
+		}
+	}
+	return resource, fields, nil
+}
+
+// ParseAPIVersion parses a BACKUP_API_VERSIONS entry such as
+// horizontalpodautoscalers.autoscaling/v1, or pods/v1 for the core group
+func ParseAPIVersion(entry string) (schema.GroupResource, string, error) {
+	qualified, version, found := strings.Cut(entry, "/")
+	if !found || qualified == "" || version == "" || strings.Contains(version, "/") {
+		return schema.GroupResource{}, "", fmt.Errorf("invalid API version %q in BACKUP_API_VERSIONS, expected resource.group/version", entry)
+	}
+	return schema.ParseGroupResource(qualified), version, nil
+}
+
 // GetSecretValue retrieves a value from environment variables with fallback
 func getSecretValue(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -196,20 +1539,61 @@ func getSecretValue(key, defaultValue string) string {
 	return defaultValue
 }
 
-// GetConfigValue retrieves a configuration value from environment
-func getConfigValue(key string) string {
-	return os.Getenv(key)
+// ParseCommaSeparated parses comma-separated string into slice
+// parseLabels parses a comma-separated list of name=value pairs. An entry
+// without a value is kept with an empty one for Validate to reject.
+func parseLabels(input string) map[string]string {
+	labels := make(map[string]string)
+	for _, entry := range parseCommaSeparated(input) {
+		key, value, _ := strings.Cut(entry, "=")
+		labels[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return labels
 }
 
-// GetConfigValueWithWarning retrieves config value with warning for missing values
-func getConfigValueWithWarning(key, defaultValue, configType string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// parseStorageTargets parses SECONDARY_TARGETS. An http:// or https:// scheme
+// overrides useSSL; malformed entries are kept with the parts found for
+// Validate to reject.
+func parseStorageTargets(input string, useSSL bool) []StorageTarget {
+	var targets []StorageTarget
+	for _, entry := range parseCommaSeparated(input) {
+		name, location, _ := strings.Cut(entry, "=")
+		target := StorageTarget{Name: strings.TrimSpace(name), UseSSL: useSSL}
+		location = strings.TrimSpace(location)
+		if rest, ok := strings.CutPrefix(location, "https://"); ok {
+			location, target.UseSSL = rest, true
+		} else if rest, ok := strings.CutPrefix(location, "http://"); ok {
+			location, target.UseSSL = rest, false
+		}
+		target.Endpoint, target.Bucket, _ = strings.Cut(location, "/")
+		target.Bucket = strings.Trim(target.Bucket, "/")
+		targets = append(targets, target)
 	}
-	return defaultValue
+	return targets
+}
+
+// MinBandwidthLimit is the lowest bandwidth limit, in bytes per second, below
+// which requests to object storage would time out
+const MinBandwidthLimit = 1024
+
+// ParseBandwidth returns the bytes per second of a bandwidth limit such as
+// 500Ki, 10Mi or 1048576, or 0 for an empty one, which leaves traffic
+// unlimited
+func ParseBandwidth(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bandwidth %q, expected bytes per second such as 10Mi", value)
+	}
+	bytes, ok := quantity.AsInt64()
+	if !ok || bytes < MinBandwidthLimit {
+		return 0, fmt.Errorf("bandwidth %q must be a whole number of bytes per second, at least %d", value, MinBandwidthLimit)
+	}
+	return bytes, nil
 }
 
-// ParseCommaSeparated parses comma-separated string into slice
 func parseCommaSeparated(input string) []string {
 	if input == "" {
 		return []string{}
@@ -224,4 +1608,29 @@ func parseCommaSeparated(input string) []string {
 		}
 	}
 	return result
-}
\ No newline at end of file
+}
+// ParseCircuitBreaker reads the {prefix}_CIRCUIT_BREAKER_* settings of one
+// circuit breaker, keeping the defaults for values out of range
+func (s settings) parseCircuitBreaker(prefix, defaultThreshold, defaultTimeout, defaultProbes string, threshold *int, timeout *time.Duration, probes *int) {
+	if thresholdStr := s.getConfigValueWithWarning(prefix+"_CIRCUIT_BREAKER_THRESHOLD", defaultThreshold, "circuit breaker"); thresholdStr != "" {
+		if value, err := strconv.Atoi(thresholdStr); err == nil {
+			if value >= 1 && value <= 100 {
+				*threshold = value
+			}
+		}
+	}
+	if timeoutStr := s.getConfigValueWithWarning(prefix+"_CIRCUIT_BREAKER_TIMEOUT", defaultTimeout, "circuit breaker"); timeoutStr != "" {
+		if value, err := time.ParseDuration(timeoutStr); err == nil {
+			if value >= time.Second && value <= time.Hour {
+				*timeout = value
+			}
+		}
+	}
+	if probesStr := s.getConfigValueWithWarning(prefix+"_CIRCUIT_BREAKER_PROBES", defaultProbes, "circuit breaker"); probesStr != "" {
+		if value, err := strconv.Atoi(probesStr); err == nil {
+			if value >= 1 && value <= 10 {
+				*probes = value
+			}
+		}
+	}
+}
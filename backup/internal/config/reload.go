@@ -0,0 +1,49 @@
+package config
+
+import "reflect"
+
+// reloadableSettings are read afresh by every run, so a reloaded
+// configuration applies them to the next run, as it does every BackupConfig
+// setting. The cleanup settings are among them since backup-util reads them
+// on each cleanup. The other settings configure the clients, servers and
+// plugins set up at startup and take a restart.
+var reloadableSettings = map[string]bool{
+	"BatchSize":               true,
+	"BackupReport":            true,
+	"BackupTimeout":           true,
+	"BackupDiscoveryTimeout":  true,
+	"BackupNamespaceTimeout":  true,
+	"ProgressInterval":        true,
+	"ProgressPrecount":        true,
+	"ScanForSecrets":          true,
+	"ValidationStrictMode":    true,
+	"AutoCreateBucket":        true,
+	"SecondaryFailurePolicy":  true,
+	"EnableCleanup":           true,
+	"CleanupOnStartup":        true,
+	"RetentionDays":           true,
+	"RetentionKeepLast":       true,
+	"CleanupMode":             true,
+	"CleanupTrashGracePeriod": true,
+	"BackupCleanupTimeout":    true,
+}
+
+// KeepStartupSettings sets the settings of next that only take effect at
+// startup back to those of current, so a reload does not claim values it
+// cannot apply. It returns the names of those that differed, e.g.
+// Config.MinIOBucket, in the order the fields are declared.
+func KeepStartupSettings(current, next *Config) []string {
+	var kept []string
+	currentValue, nextValue := reflect.ValueOf(current).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < currentValue.NumField(); i++ {
+		field := currentValue.Type().Field(i)
+		if !field.IsExported() || reloadableSettings[field.Name] {
+			continue
+		}
+		if !reflect.DeepEqual(currentValue.Field(i).Interface(), nextValue.Field(i).Interface()) {
+			nextValue.Field(i).Set(currentValue.Field(i))
+			kept = append(kept, "Config."+field.Name)
+		}
+	}
+	return kept
+}
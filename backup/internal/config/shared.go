@@ -0,0 +1,242 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	sharedconfig "shared-config/config"
+)
+
+// SharedConfigFileEnv names the shared configuration file read by the backup,
+// GitOps and integration components. When it is unset the shared loader's
+// default paths are tried.
+const SharedConfigFileEnv = "SHARED_CONFIG_FILE"
+
+// LoadSharedConfig reads the shared configuration file through the shared
+// loader, which also applies its own environment overrides and Vault secrets.
+// It returns nil when SHARED_CONFIG_FILE is unset and no file exists at the
+// default paths, leaving the environment as the only source.
+func LoadSharedConfig() (*sharedconfig.SharedConfig, error) {
+	path, err := SharedConfigPath()
+	if err != nil || path == "" {
+		return nil, err
+	}
+
+	shared, err := sharedconfig.NewConfigLoader(path).Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load shared configuration %s: %v", path, err)
+	}
+	return shared, nil
+}
+
+// SharedConfigPath returns the shared configuration file to read: the one
+// named by SHARED_CONFIG_FILE, which must exist, or else the first of the
+// default paths that does. It is empty when there is none.
+func SharedConfigPath() (string, error) {
+	if path := os.Getenv(SharedConfigFileEnv); path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("failed to read %s: %v", SharedConfigFileEnv, err)
+		}
+		return path, nil
+	}
+	for _, candidate := range sharedconfig.DefaultConfigPaths() {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", nil
+}
+
+// settings resolves configuration values: an environment variable wins, then
+// the setting of the same name from the shared configuration file, then the
+// built-in default
+type settings struct {
+	file map[string]string
+}
+
+func newSettings(shared *sharedconfig.SharedConfig) settings {
+	return settings{file: sharedSettings(shared)}
+}
+
+// getConfigValue retrieves a configuration value from the environment or the
+// shared configuration file
+func (s settings) getConfigValue(key string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return s.file[key]
+}
+
+// getConfigValueWithWarning retrieves a configuration value, falling back to
+// defaultValue when neither the environment nor the file sets it
+func (s settings) getConfigValueWithWarning(key, defaultValue, configType string) string {
+	if value := s.getConfigValue(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// sharedSettings maps the shared configuration to the environment variables
+// of the settings it holds. Empty strings, lists and zero numbers are left
+// out so the backup's own defaults apply; booleans are always set, and the
+// shared loader's defaults for them match the backup's.
+func sharedSettings(sc *sharedconfig.SharedConfig) map[string]string {
+	values := make(map[string]string)
+	if sc == nil {
+		return values
+	}
+	set := func(key string, value interface{}) {
+		var text string
+		switch v := value.(type) {
+		case string:
+			text = v
+		case bool:
+			text = strconv.FormatBool(v)
+		case int:
+			if v != 0 {
+				text = strconv.Itoa(v)
+			}
+		case time.Duration:
+			if v != 0 {
+				text = v.String()
+			}
+		case []string:
+			text = strings.Join(v, ",")
+		case map[string]string:
+			pairs := make([]string, 0, len(v))
+			for name, val := range v {
+				pairs = append(pairs, name+"="+val)
+			}
+			sort.Strings(pairs)
+			text = strings.Join(pairs, ",")
+		}
+		if text != "" {
+			values[key] = text
+		}
+	}
+
+	storage := sc.Storage
+	set("MINIO_ENDPOINT", storage.Endpoint)
+	set("MINIO_ACCESS_KEY", storage.AccessKey)
+	set("MINIO_SECRET_KEY", storage.SecretKey)
+	set("MINIO_ACCESS_KEY_FILE", storage.AccessKeyFile)
+	set("MINIO_SECRET_KEY_FILE", storage.SecretKeyFile)
+	set("MINIO_BUCKET", storage.Bucket)
+	set("MINIO_USE_SSL", storage.UseSSL)
+	set("AUTO_CREATE_BUCKET", storage.AutoCreateBucket)
+	set("FALLBACK_BUCKETS", storage.FallbackBuckets)
+	set("MINIO_MAX_IDLE_CONNS", storage.Connection.MaxIdleConns)
+	set("MINIO_IDLE_CONN_TIMEOUT", storage.Connection.IdleConnTimeout)
+	set("MINIO_TLS_HANDSHAKE_TIMEOUT", storage.Connection.TLSHandshakeTimeout)
+	set("RETRY_ATTEMPTS", storage.Connection.MaxRetries)
+	set("RETRY_DELAY", storage.Connection.RetryDelay)
+	set("STORAGE_AUTH_METHOD", storage.Auth.Method)
+	set("STORAGE_ROLE_ARN", storage.Auth.RoleARN)
+	set("STORAGE_WEB_IDENTITY_TOKEN_FILE", storage.Auth.WebIdentityTokenFile)
+	set("STORAGE_STS_ENDPOINT", storage.Auth.STSEndpoint)
+
+	set("CLUSTER_NAME", sc.Cluster.Name)
+	set("CLUSTER_DOMAIN", sc.Cluster.Domain)
+	set("OPENSHIFT_MODE", sc.Cluster.OpenShift.Mode)
+	set("INCLUDE_OPENSHIFT_RESOURCES", sc.Cluster.OpenShift.IncludeResources)
+
+	filtering := sc.Backup.Filtering
+	set("FILTERING_MODE", filtering.Mode)
+	set("INCLUDE_RESOURCES", filtering.Resources.Include)
+	set("EXCLUDE_RESOURCES", filtering.Resources.Exclude)
+	set("INCLUDE_NAMESPACES", filtering.Namespaces.Include)
+	set("EXCLUDE_NAMESPACES", filtering.Namespaces.Exclude)
+	set("LABEL_SELECTOR", filtering.LabelSelector)
+	set("ANNOTATION_SELECTOR", filtering.AnnotationSelector)
+
+	behavior := sc.Backup.Behavior
+	set("BATCH_SIZE", behavior.BatchSize)
+	set("VALIDATE_YAML", behavior.ValidateYAML)
+	set("SKIP_INVALID_RESOURCES", behavior.SkipInvalidResources)
+	set("INCLUDE_MANAGED_FIELDS", behavior.IncludeManagedFields)
+	set("INCLUDE_STATUS", behavior.IncludeStatus)
+	set("MAX_RESOURCE_SIZE", behavior.MaxResourceSize)
+	set("FOLLOW_OWNER_REFERENCES", behavior.FollowOwnerReferences)
+
+	set("ENABLE_CLEANUP", sc.Backup.Cleanup.Enabled)
+	set("RETENTION_DAYS", sc.Backup.Cleanup.RetentionDays)
+	set("CLEANUP_ON_STARTUP", sc.Backup.Cleanup.CleanupOnStartup)
+
+	secrets := sc.Security.Secrets
+	set("SECRET_PROVIDER", secrets.Provider)
+	set("CREDENTIALS_REFRESH_INTERVAL", secrets.RefreshInterval)
+	set("VAULT_ADDR", secrets.Vault.Address)
+	set("VAULT_TOKEN", secrets.Vault.Token)
+	set("VAULT_ROLE", secrets.Vault.Role)
+	set("VAULT_PATH", secrets.Vault.Path)
+
+	set("CA_BUNDLE_PATH", sc.Security.Network.CABundle)
+	set("CLIENT_CERT_PATH", sc.Security.Network.ClientCert)
+	set("CLIENT_KEY_PATH", sc.Security.Network.ClientKey)
+
+	validation := sc.Security.Validation
+	set("STRICT_VALIDATION", validation.StrictMode)
+	set("SCAN_SECRETS", validation.ScanForSecrets)
+	set("POLICY_OPA_URL", validation.Policies.OPAURL)
+	set("POLICY_TIMEOUT", validation.Policies.Timeout)
+
+	api := sc.Security.API
+	set("API_PORT", api.Port)
+	set("GRPC_PORT", api.GRPCPort)
+	set("API_TOKEN", api.Token)
+	// Mutual TLS between the components covers the APIs too, unless they
+	// have certificates of their own
+	apiTLS := api.TLS
+	if communication := sc.Integration.Communication.Authentication; communication.MutualTLS() && apiTLS.CertFile == "" {
+		apiTLS = communication.TLS
+	}
+	set("API_TLS_CERT_FILE", apiTLS.CertFile)
+	set("API_TLS_KEY_FILE", apiTLS.KeyFile)
+	set("API_TLS_CA_FILE", apiTLS.CAFile)
+	set("API_VIEWER_TOKEN", api.ViewerToken)
+	set("API_OPERATOR_TOKEN", api.OperatorToken)
+	set("API_APPROVER_TOKEN", api.ApproverToken)
+	set("API_OIDC_ISSUER", api.OIDC.Issuer)
+	set("API_OIDC_AUDIENCE", api.OIDC.Audience)
+	set("API_OIDC_ROLES_CLAIM", api.OIDC.RolesClaim)
+	set("API_OIDC_ADMIN_GROUPS", api.OIDC.AdminGroups)
+	set("API_OIDC_OPERATOR_GROUPS", api.OIDC.OperatorGroups)
+	set("API_OIDC_VIEWER_GROUPS", api.OIDC.ViewerGroups)
+	set("API_OIDC_APPROVER_GROUPS", api.OIDC.ApproverGroups)
+	set("API_AUTH", sc.Security.EnableAuth)
+
+	signing := sc.Security.Signing
+	set("MANIFEST_SIGNING", signing.Mode)
+	set("MANIFEST_SIGNING_KEY", signing.Key)
+	set("SIGSTORE_FULCIO_URL", signing.FulcioURL)
+	set("SIGSTORE_REKOR_URL", signing.RekorURL)
+	set("SIGSTORE_IDENTITY_TOKEN_FILE", signing.IdentityTokenFile)
+	set("MANIFEST_VERIFY_KEY", signing.Verify.Key)
+	set("MANIFEST_VERIFY_ROOTS", signing.Verify.FulcioRoots)
+	set("MANIFEST_VERIFY_IDENTITY", signing.Verify.Identity)
+	set("MANIFEST_VERIFY_ISSUER", signing.Verify.Issuer)
+	set("SIGSTORE_REKOR_PUBLIC_KEY", signing.Verify.RekorPublicKey)
+
+	set("METRICS_PORT", sc.Observability.Metrics.Port)
+	set("REST_API", sc.Features.Preview.RestAPI)
+	set("UI_DASHBOARD", sc.Features.Preview.UIDashboard)
+
+	// The broker is only used when the components talk through it
+	if communication := sc.Integration.Communication; communication.Method == "event-bus" {
+		bus := communication.EventBus
+		set("EVENT_BUS", bus.Provider)
+		set("EVENT_BUS_URL", bus.URL)
+		set("EVENT_BUS_TOPIC_PREFIX", bus.TopicPrefix)
+		set("EVENT_BUS_TOKEN", bus.Token)
+		set("EVENT_BUS_USERNAME", bus.Username)
+		set("EVENT_BUS_PASSWORD", bus.Password)
+	}
+
+	set("RESOURCE_PLUGINS", sc.Plugins.Paths)
+	set("RESOURCE_PLUGIN_OPTIONS", sc.Plugins.Options)
+	return values
+}
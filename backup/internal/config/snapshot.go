@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Redacted replaces the value of a credential in a snapshot
+const Redacted = "[redacted]"
+
+// redactedSettings hold credentials, or private keys given inline, and are
+// never written out in full
+var redactedSettings = map[string]bool{
+	"MinIOAccessKey":     true,
+	"MinIOSecretKey":     true,
+	"MinIOClientKey":     true,
+	"VaultToken":         true,
+	"APIToken":           true,
+	"APIViewerToken":     true,
+	"APIOperatorToken":   true,
+	"APIApproverToken":   true,
+	"ManifestSigningKey": true,
+	"EventBusToken":      true,
+	"EventBusPassword":   true,
+	"AuditWebhookToken":  true,
+	"ReplicaAccessKey":   true,
+	"ReplicaSecretKey":   true,
+}
+
+// sensitiveOptionWords mark the key=value options, such as those of resource
+// plugins, whose values are redacted
+var sensitiveOptionWords = []string{"password", "secret", "token", "key", "credential"}
+
+// Snapshot returns the effective configuration of a run as setting names,
+// e.g. Config.MinIOBucket or BackupConfig.FilteringMode, mapped to their
+// values. Credentials are replaced by Redacted when set, so a snapshot can be
+// stored with the backup and compared between runs.
+func Snapshot(cfg *Config, backupCfg *BackupConfig) map[string]string {
+	snapshot := make(map[string]string)
+	if cfg != nil {
+		addSettings(snapshot, "Config", reflect.ValueOf(*cfg))
+	}
+	if backupCfg != nil {
+		addSettings(snapshot, "BackupConfig", reflect.ValueOf(*backupCfg))
+	}
+	return snapshot
+}
+
+func addSettings(snapshot map[string]string, prefix string, value reflect.Value) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		setting := formatSetting(value.Field(i).Interface())
+		if redactedSettings[field.Name] && setting != "" {
+			setting = Redacted
+		}
+		snapshot[prefix+"."+field.Name] = setting
+	}
+}
+
+// formatSetting renders a setting the way it is given in the environment
+func formatSetting(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case time.Duration:
+		return v.String()
+	case []string:
+		return strings.Join(v, ",")
+	case map[string]string:
+		pairs := make([]string, 0, len(v))
+		for key, val := range v {
+			if sensitiveOption(key) {
+				val = Redacted
+			}
+			pairs = append(pairs, key+"="+val)
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ",")
+	case []StorageTarget:
+		targets := make([]string, 0, len(v))
+		for _, target := range v {
+			scheme := "http"
+			if target.UseSSL {
+				scheme = "https"
+			}
+			targets = append(targets, fmt.Sprintf("%s=%s://%s/%s", target.Name, scheme, target.Endpoint, target.Bucket))
+		}
+		return strings.Join(targets, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func sensitiveOption(key string) bool {
+	key = strings.ToLower(key)
+	for _, word := range sensitiveOptionWords {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
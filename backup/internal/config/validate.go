@@ -0,0 +1,222 @@
+package config
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	sharedconfig "shared-config/config"
+	sharedErrors "shared-errors"
+)
+
+// Exit codes of a validation, so CI pipelines and admission checks can tell
+// a configuration that must not be used from one that only has warnings
+const (
+	ValidationPassed   = 0
+	ValidationFailed   = 1
+	ValidationWarnings = 2
+)
+
+// ValidateOptions configures Validate
+type ValidateOptions struct {
+	// LiveChecks connects to the multi-cluster API servers and storage
+	// endpoints; without it only the configuration itself is checked
+	LiveChecks bool
+	// Timeout bounds the checks of each cluster
+	Timeout time.Duration
+}
+
+// ValidationReport is the result of validating the backup's configuration
+type ValidationReport struct {
+	Valid      bool                `json:"valid" yaml:"valid"`
+	ConfigFile string              `json:"config_file,omitempty" yaml:"config_file,omitempty"`
+	Errors     []ValidationFinding `json:"errors" yaml:"errors"`
+	Warnings   []ValidationFinding `json:"warnings" yaml:"warnings"`
+	Clusters   []ClusterValidation `json:"clusters,omitempty" yaml:"clusters,omitempty"`
+
+	// The loaded configuration, when it is valid
+	Config       *Config       `json:"-" yaml:"-"`
+	BackupConfig *BackupConfig `json:"-" yaml:"-"`
+}
+
+// ValidationFinding is an error or warning. Source is shared for the shared
+// configuration file, config and backup-config for the backup's settings,
+// multi-cluster for checks across clusters, or cluster/<name>.
+type ValidationFinding struct {
+	Source  string `json:"source" yaml:"source"`
+	Field   string `json:"field,omitempty" yaml:"field,omitempty"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// ClusterValidation is the outcome of the checks of one cluster of the
+// multi-cluster configuration. Its errors and warnings are in the report's,
+// with the source cluster/<name>.
+type ClusterValidation struct {
+	Name         string             `json:"name" yaml:"name"`
+	Valid        bool               `json:"valid" yaml:"valid"`
+	Token        *TokenCheck        `json:"token,omitempty" yaml:"token,omitempty"`
+	Connectivity *ConnectivityCheck `json:"connectivity,omitempty" yaml:"connectivity,omitempty"`
+}
+
+// TokenCheck is the validation of a cluster's credentials
+type TokenCheck struct {
+	Method    string     `json:"method" yaml:"method"`
+	Valid     bool       `json:"valid" yaml:"valid"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+	Error     string     `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// ConnectivityCheck is the result of connecting to a cluster's API server
+type ConnectivityCheck struct {
+	Reachable        bool   `json:"reachable" yaml:"reachable"`
+	TLSValid         bool   `json:"tls_valid" yaml:"tls_valid"`
+	Authenticated    bool   `json:"authenticated" yaml:"authenticated"`
+	APIServerVersion string `json:"api_server_version,omitempty" yaml:"api_server_version,omitempty"`
+	ResponseTime     string `json:"response_time" yaml:"response_time"`
+	Error            string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// ExitCode returns ValidationFailed when there are errors, ValidationWarnings
+// when there are only warnings, and ValidationPassed otherwise
+func (r *ValidationReport) ExitCode() int {
+	switch {
+	case len(r.Errors) > 0:
+		return ValidationFailed
+	case len(r.Warnings) > 0:
+		return ValidationWarnings
+	default:
+		return ValidationPassed
+	}
+}
+
+func (r *ValidationReport) addError(source, field, message string) {
+	r.Errors = append(r.Errors, ValidationFinding{Source: source, Field: field, Message: message})
+}
+
+func (r *ValidationReport) addWarning(source, field, message string) {
+	r.Warnings = append(r.Warnings, ValidationFinding{Source: source, Field: field, Message: message})
+}
+
+// addLoadError records the failure to load the backup's settings, one
+// finding per invalid setting when the error lists them
+func (r *ValidationReport) addLoadError(source string, err error) {
+	var multiErr *sharedErrors.MultiError
+	if !errors.As(err, &multiErr) {
+		r.addError(source, "", err.Error())
+		return
+	}
+	for _, settingErr := range multiErr.Errors {
+		field, _ := settingErr.Context["field"].(string)
+		r.addError(source, field, settingErr.Message)
+	}
+}
+
+// Validate checks the configuration the backup would run with: the shared
+// configuration file, if there is one, the settings read from it and the
+// environment, and, when the file enables it, the multi-cluster
+// configuration with the token, connectivity and storage checks of the
+// enhanced validator
+func Validate(opts ValidateOptions) *ValidationReport {
+	report := &ValidationReport{Errors: []ValidationFinding{}, Warnings: []ValidationFinding{}}
+	defer func() { report.Valid = len(report.Errors) == 0 }()
+
+	path, err := SharedConfigPath()
+	if err != nil {
+		report.addError("shared", "", err.Error())
+		return report
+	}
+
+	var shared *sharedconfig.SharedConfig
+	if path != "" {
+		report.ConfigFile = path
+		shared, err = sharedconfig.NewConfigLoader(path).WithoutValidation().Load()
+		if err != nil {
+			report.addError("shared", "", err.Error())
+			return report
+		}
+		result := sharedconfig.NewConfigValidator(shared).Validate()
+		for _, finding := range result.Errors {
+			report.addError("shared", finding.Field, finding.Message)
+		}
+		for _, finding := range result.Warnings {
+			report.addWarning("shared", finding.Field, finding.Message)
+		}
+	}
+
+	cfg, err := LoadConfigFrom(shared)
+	if err != nil {
+		report.addLoadError("config", err)
+	}
+	backupCfg, err := LoadBackupConfigFrom(shared)
+	if err != nil {
+		report.addLoadError("backup-config", err)
+	}
+	if cfg != nil && backupCfg != nil {
+		report.Config, report.BackupConfig = cfg, backupCfg
+	}
+
+	if shared != nil && shared.MultiCluster.Enabled {
+		validateMultiCluster(report, &shared.MultiCluster, opts)
+	}
+	return report
+}
+
+// validateMultiCluster runs the enhanced multi-cluster validator and adds
+// its findings to the report
+func validateMultiCluster(report *ValidationReport, multiCluster *sharedconfig.MultiClusterConfig, opts ValidateOptions) {
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	validator := sharedconfig.NewEnhancedMultiClusterValidator(&sharedconfig.EnhancedValidationOptions{
+		EnableConnectivityChecks: opts.LiveChecks,
+		EnableTokenValidation:    true,
+		ValidationTimeout:        timeout,
+		MaxConcurrentChecks:      5,
+	})
+	result := validator.ValidateMultiClusterConfigurationWithLiveChecks(multiCluster)
+
+	for _, finding := range result.GlobalErrors {
+		report.addError("multi-cluster", finding.Field, finding.Message)
+	}
+	for _, finding := range result.GlobalWarnings {
+		report.addWarning("multi-cluster", finding.Field, finding.Message)
+	}
+
+	names := make([]string, 0, len(result.ClusterResults))
+	for name := range result.ClusterResults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		clusterResult := result.ClusterResults[name]
+		source := "cluster/" + name
+		for _, finding := range clusterResult.Errors {
+			report.addError(source, finding.Field, finding.Message)
+		}
+		for _, finding := range clusterResult.Warnings {
+			report.addWarning(source, finding.Field, finding.Message)
+		}
+
+		cluster := ClusterValidation{Name: name, Valid: clusterResult.Valid}
+		if token := clusterResult.TokenValidation; token != nil {
+			cluster.Token = &TokenCheck{
+				Method:    token.ValidationMethod,
+				Valid:     token.Valid,
+				ExpiresAt: token.ExpiresAt,
+				Error:     token.ErrorDetails,
+			}
+		}
+		if status := clusterResult.ConnectivityStatus; status != nil {
+			cluster.Connectivity = &ConnectivityCheck{
+				Reachable:        status.Reachable,
+				TLSValid:         status.TLSValid,
+				Authenticated:    status.AuthenticationValid,
+				APIServerVersion: status.APIServerVersion,
+				ResponseTime:     status.ResponseTime.Round(time.Millisecond).String(),
+				Error:            status.ErrorDetails,
+			}
+		}
+		report.Clusters = append(report.Clusters, cluster)
+	}
+}
@@ -0,0 +1,254 @@
+package dashboard
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+
+	"cluster-backup/internal/api"
+	"cluster-backup/internal/audit"
+	"cluster-backup/internal/auth"
+	"cluster-backup/internal/backup"
+	"cluster-backup/internal/logging"
+)
+
+//go:embed static
+var staticFiles embed.FS
+
+// PathPrefix is where the dashboard is mounted on the metrics server
+const PathPrefix = "/ui/"
+
+// ManifestSource provides access to stored backup manifests and objects
+type ManifestSource interface {
+	List(ctx context.Context) ([]backup.ManifestSummary, error)
+	Load(ctx context.Context, backupID string) (*backup.Manifest, error)
+	GetObject(ctx context.Context, key string) ([]byte, error)
+}
+
+// Dashboard serves the web UI and the JSON endpoints it uses
+type Dashboard struct {
+	ctx       context.Context
+	auth      *auth.Authenticator
+	runs      *api.RunRegistry
+	manifests ManifestSource
+	backupFn  api.BackupFunc
+	restoreFn api.RestoreFunc
+	logger    *logging.StructuredLogger
+}
+
+// NewDashboard creates a dashboard backed by the API server's run registry.
+// restoreFn may be nil, in which case restore requests are rejected.
+func NewDashboard(ctx context.Context, authenticator *auth.Authenticator, runs *api.RunRegistry, manifests ManifestSource, backupFn api.BackupFunc, restoreFn api.RestoreFunc, logger *logging.StructuredLogger) *Dashboard {
+	return &Dashboard{
+		ctx:       ctx,
+		auth:      authenticator,
+		runs:      runs,
+		manifests: manifests,
+		backupFn:  backupFn,
+		restoreFn: restoreFn,
+		logger:    logger,
+	}
+}
+
+// Handler returns the HTTP handler serving everything under PathPrefix
+func (d *Dashboard) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	static, _ := fs.Sub(staticFiles, "static")
+	mux.Handle("GET /ui/", http.StripPrefix("/ui/", http.FileServer(http.FS(static))))
+
+	// Data endpoints take the same bearer tokens and roles as the REST API
+	mux.Handle("GET /ui/api/runs", d.authorize(auth.RoleViewer, http.HandlerFunc(d.handleRuns)))
+	mux.Handle("GET /ui/api/backups", d.authorize(auth.RoleViewer, http.HandlerFunc(d.handleListBackups)))
+	mux.Handle("GET /ui/api/backups/{id}", d.authorize(auth.RoleViewer, http.HandlerFunc(d.handleGetBackup)))
+	mux.Handle("GET /ui/api/backups/{id}/object", d.authorize(auth.RoleViewer, http.HandlerFunc(d.handleGetObject)))
+	mux.Handle("POST /ui/api/backups", d.authorize(auth.RoleOperator, http.HandlerFunc(d.handleStartBackup)))
+	mux.Handle("POST /ui/api/restores", d.authorize(auth.RoleOperator, http.HandlerFunc(d.handleStartRestore)))
+	mux.Handle("POST /ui/api/restores/{id}/approve", d.authorize(auth.RoleOperator, http.HandlerFunc(d.handleApproveRestore)))
+	mux.Handle("POST /ui/api/restores/{id}/cancel", d.authorize(auth.RoleOperator, http.HandlerFunc(d.handleCancelRestore)))
+
+	return mux
+}
+
+// authorize rejects requests whose bearer token is not valid or whose
+// holder lacks role, and records the others as made by the token's holder
+func (d *Dashboard) authorize(role auth.Role, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := d.auth.Authorize(r.Context(), r.Header.Get("Authorization"), role)
+		if err != nil {
+			status, message := auth.HTTPStatus(err)
+			writeError(w, status, message)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(audit.WithActor(r.Context(), principal.Actor("dashboard", r.RemoteAddr))))
+	})
+}
+
+func (d *Dashboard) handleRuns(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"backups":  d.runs.List(api.RunTypeBackup),
+		"restores": d.runs.List(api.RunTypeRestore),
+	})
+}
+
+func (d *Dashboard) handleListBackups(w http.ResponseWriter, r *http.Request) {
+	summaries, err := d.manifests.List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	if summaries == nil {
+		summaries = []backup.ManifestSummary{}
+	}
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+func (d *Dashboard) handleGetBackup(w http.ResponseWriter, r *http.Request) {
+	manifest, err := d.manifests.Load(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"summary":    manifest.Summary(),
+		"namespaces": manifest.Namespaces,
+		"tree":       BuildTree(manifest),
+	})
+}
+
+func (d *Dashboard) handleGetObject(w http.ResponseWriter, r *http.Request) {
+	manifest, err := d.manifests.Load(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	// Only serve objects recorded in the manifest, never arbitrary bucket keys
+	key := r.URL.Query().Get("key")
+	if !manifestContains(manifest, key) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("object %q is not part of backup %s", key, manifest.BackupID))
+		return
+	}
+
+	data, err := d.manifests.GetObject(r.Context(), key)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-yaml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+func (d *Dashboard) handleStartBackup(w http.ResponseWriter, r *http.Request) {
+	run, err := api.StartBackupRun(api.WithActorOf(d.ctx, r.Context()), d.runs, d.backupFn)
+	if err != nil {
+		writeError(w, http.StatusConflict, "a backup run is already in progress")
+		return
+	}
+
+	d.logger.Info("dashboard_backup_triggered", "Backup triggered from UI dashboard", map[string]interface{}{
+		"run_id": run.ID,
+	})
+	writeJSON(w, http.StatusAccepted, run)
+}
+
+func (d *Dashboard) handleStartRestore(w http.ResponseWriter, r *http.Request) {
+	if d.restoreFn == nil {
+		writeError(w, http.StatusNotImplemented, "restore is not configured on this server")
+		return
+	}
+
+	var req api.RestoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if req.BackupID == "" {
+		writeError(w, http.StatusBadRequest, "backup_id is required")
+		return
+	}
+
+	run := api.StartRestoreRun(api.WithActorOf(d.ctx, r.Context()), d.runs, d.restoreFn, req)
+	d.logger.Info("dashboard_restore_triggered", "Restore triggered from UI dashboard", map[string]interface{}{
+		"run_id":    run.ID,
+		"backup_id": req.BackupID,
+		"dry_run":   req.DryRun,
+	})
+	writeJSON(w, http.StatusAccepted, run)
+}
+
+func (d *Dashboard) handleApproveRestore(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if run, ok := d.runs.Get(id); !ok || run.Type != api.RunTypeRestore {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("restore run %s not found", id))
+		return
+	}
+
+	run, err := api.ApproveRestoreRun(r.Context(), d.runs, id)
+	if err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, api.ErrSelfApproval) || errors.Is(err, api.ErrNotApprover) {
+			status = http.StatusForbidden
+		}
+		writeError(w, status, fmt.Sprintf("cannot approve restore run %s: %v", id, err))
+		return
+	}
+	d.logger.Info("dashboard_restore_approved", "Restore approved from UI dashboard", map[string]interface{}{
+		"run_id":       id,
+		"requested_by": run.Approval.RequestedBy.ID,
+		"approved_by":  run.Approval.ApprovedBy.ID,
+	})
+	writeJSON(w, http.StatusAccepted, run)
+}
+
+// handleCancelRestore stops a restore run, or rejects one awaiting approval
+func (d *Dashboard) handleCancelRestore(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if run, ok := d.runs.Get(id); !ok || run.Type != api.RunTypeRestore {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("restore run %s not found", id))
+		return
+	}
+
+	run, err := d.runs.Cancel(id)
+	if err != nil {
+		writeError(w, http.StatusConflict, fmt.Sprintf("cannot cancel restore run %s: %v", id, err))
+		return
+	}
+	d.logger.Info("dashboard_restore_cancelled", "Restore cancellation requested from UI dashboard", map[string]interface{}{
+		"run_id": id,
+	})
+	d.runs.AuditLog().Record(r.Context(), audit.Record{Operation: audit.OperationRestoreCancel, Outcome: audit.OutcomeRequested, RunID: id})
+	writeJSON(w, http.StatusAccepted, run)
+}
+
+// manifestContains reports whether key is one of the manifest's objects
+func manifestContains(manifest *backup.Manifest, key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, object := range manifest.Objects {
+		if object.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// writeJSON writes a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, map[string]string{"error": message})
+}
@@ -0,0 +1,193 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/api"
+	"cluster-backup/internal/auth"
+	"cluster-backup/internal/backup"
+	"cluster-backup/internal/logging"
+)
+
+const testToken = "test-token"
+
+type fakeManifests struct {
+	manifests map[string]*backup.Manifest
+	objects   map[string][]byte
+}
+
+func (f *fakeManifests) List(ctx context.Context) ([]backup.ManifestSummary, error) {
+	var summaries []backup.ManifestSummary
+	for _, m := range f.manifests {
+		summaries = append(summaries, m.Summary())
+	}
+	return summaries, nil
+}
+
+func (f *fakeManifests) Load(ctx context.Context, backupID string) (*backup.Manifest, error) {
+	m, ok := f.manifests[backupID]
+	if !ok {
+		return nil, fmt.Errorf("manifest %s not found", backupID)
+	}
+	return m, nil
+}
+
+func (f *fakeManifests) GetObject(ctx context.Context, key string) ([]byte, error) {
+	data, ok := f.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("object %s not found", key)
+	}
+	return data, nil
+}
+
+func newTestManifest() *backup.Manifest {
+	m := backup.NewManifest("backup-20250101-000000", "test-cluster", "cluster.local", "bucket", time.Now())
+	for _, obj := range []backup.ObjectEntry{
+		{Namespace: "web", ResourceType: "services", Name: "frontend"},
+		{Namespace: "web", ResourceType: "deployments", Name: "frontend"},
+		{Namespace: "web", ResourceType: "deployments", Name: "api"},
+		{Namespace: "db", ResourceType: "statefulsets", Name: "postgres"},
+	} {
+		obj.Key = backup.ObjectPath("cluster.local", "test-cluster", obj.Namespace, obj.ResourceType, obj.Name)
+		m.AddObject(obj)
+	}
+	m.SetNamespace("web", 3, nil)
+	m.SetNamespace("db", 1, nil)
+	m.Finish(time.Now())
+	return m
+}
+
+func newTestDashboard(restoreFn api.RestoreFunc) (*Dashboard, *backup.Manifest) {
+	manifest := newTestManifest()
+	source := &fakeManifests{
+		manifests: map[string]*backup.Manifest{manifest.BackupID: manifest},
+		objects: map[string][]byte{
+			manifest.Objects[0].Key: []byte("kind: Service\n"),
+			"other/secret.yaml":     []byte("kind: Secret\n"),
+		},
+	}
+	logger := logging.NewStructuredLogger("dashboard-test", "test-cluster")
+	backupFn := func(ctx context.Context) (*backup.BackupResult, error) {
+		return &backup.BackupResult{}, nil
+	}
+	return NewDashboard(context.Background(), auth.NewAuthenticator(map[auth.Role]string{auth.RoleOperator: testToken}, nil), api.NewRunRegistry(10), source, backupFn, restoreFn, logger), manifest
+}
+
+func serve(d *Dashboard, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	d.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestBuildTree(t *testing.T) {
+	tree := BuildTree(newTestManifest())
+
+	require.Len(t, tree, 2)
+	assert.Equal(t, "db", tree[0].Name)
+	assert.Equal(t, "web", tree[1].Name)
+	assert.Equal(t, 3, tree[1].Count)
+
+	require.Len(t, tree[1].Children, 2)
+	deployments := tree[1].Children[0]
+	assert.Equal(t, "deployments", deployments.Name)
+	assert.Equal(t, 2, deployments.Count)
+	assert.Equal(t, "api", deployments.Children[0].Name)
+	assert.NotEmpty(t, deployments.Children[0].Key)
+}
+
+func TestBuildTree_ClusterScoped(t *testing.T) {
+	manifest := backup.NewManifest("backup-1", "test-cluster", "cluster.local", "bucket", time.Now())
+	manifest.AddObject(backup.ObjectEntry{ResourceType: "storageclasses", Name: "standard"})
+
+	tree := BuildTree(manifest)
+	require.Len(t, tree, 1)
+	assert.Equal(t, clusterScopeNode, tree[0].Name)
+	assert.Equal(t, 1, tree[0].Count)
+}
+
+func TestDashboard_Endpoints(t *testing.T) {
+	d, manifest := newTestDashboard(nil)
+	objectPath := "/ui/api/backups/" + manifest.BackupID + "/object?key="
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{name: "static_index_without_token", method: http.MethodGet, path: "/ui/", expectedStatus: http.StatusOK},
+		{name: "api_without_token", method: http.MethodGet, path: "/ui/api/backups", expectedStatus: http.StatusUnauthorized},
+		{name: "list_backups", method: http.MethodGet, path: "/ui/api/backups", token: testToken, expectedStatus: http.StatusOK},
+		{name: "list_runs", method: http.MethodGet, path: "/ui/api/runs", token: testToken, expectedStatus: http.StatusOK},
+		{name: "unknown_backup", method: http.MethodGet, path: "/ui/api/backups/missing", token: testToken, expectedStatus: http.StatusNotFound},
+		{name: "object_in_manifest", method: http.MethodGet, path: objectPath + manifest.Objects[0].Key, token: testToken, expectedStatus: http.StatusOK},
+		{name: "object_outside_manifest", method: http.MethodGet, path: objectPath + "other/secret.yaml", token: testToken, expectedStatus: http.StatusNotFound},
+		{name: "restore_not_configured", method: http.MethodPost, path: "/ui/api/restores", token: testToken, expectedStatus: http.StatusNotImplemented},
+		{name: "trigger_backup", method: http.MethodPost, path: "/ui/api/backups", token: testToken, expectedStatus: http.StatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(d, tt.method, tt.path, tt.token)
+			assert.Equal(t, tt.expectedStatus, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestDashboard_GetBackup(t *testing.T) {
+	d, manifest := newTestDashboard(nil)
+
+	rec := serve(d, http.MethodGet, "/ui/api/backups/"+manifest.BackupID, testToken)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var body struct {
+		Summary    backup.ManifestSummary            `json:"summary"`
+		Namespaces map[string]*backup.NamespaceEntry `json:"namespaces"`
+		Tree       []*TreeNode                       `json:"tree"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, 4, body.Summary.ObjectCount)
+	assert.Equal(t, backup.ManifestStatusCompleted, body.Namespaces["web"].Status)
+	assert.Len(t, body.Tree, 2)
+}
+
+func TestDashboard_RestoreApproval(t *testing.T) {
+	d, manifest := newTestDashboard(func(ctx context.Context, req api.RestoreRequest) (interface{}, error) {
+		t.Error("a restore awaiting approval must not start")
+		return nil, nil
+	})
+	d.runs.RequireRestoreApproval(time.Hour)
+
+	req := httptest.NewRequest(http.MethodPost, "/ui/api/restores", strings.NewReader(`{"backup_id":"`+manifest.BackupID+`"}`))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rec := httptest.NewRecorder()
+	d.Handler().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	var run api.Run
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &run))
+	assert.Equal(t, api.RunStatusAwaitingApproval, run.Status)
+
+	rec = serve(d, http.MethodPost, "/ui/api/restores/"+run.ID+"/approve", testToken)
+	assert.Equal(t, http.StatusForbidden, rec.Code, "requesters cannot approve their own restores")
+	rec = serve(d, http.MethodPost, "/ui/api/restores/"+run.ID+"/cancel", testToken)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	rec = serve(d, http.MethodPost, "/ui/api/restores/"+run.ID+"/approve", testToken)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	rec = serve(d, http.MethodPost, "/ui/api/restores/unknown/approve", testToken)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,246 @@
+(function () {
+    'use strict';
+
+    var tokenKey = 'backup-dashboard-token';
+    var currentBackup = null;
+
+    function token() {
+        var value = sessionStorage.getItem(tokenKey);
+        if (!value) {
+            value = window.prompt('API token');
+            if (value) {
+                sessionStorage.setItem(tokenKey, value);
+            }
+        }
+        return value || '';
+    }
+
+    function request(method, path, body) {
+        var options = { method: method, headers: { 'Authorization': 'Bearer ' + token() } };
+        if (body !== undefined) {
+            options.headers['Content-Type'] = 'application/json';
+            options.body = JSON.stringify(body);
+        }
+        return fetch('api/' + path, options).then(function (resp) {
+            if (resp.status === 401) {
+                sessionStorage.removeItem(tokenKey);
+            }
+            var isJSON = (resp.headers.get('Content-Type') || '').indexOf('application/json') === 0;
+            return (isJSON ? resp.json() : resp.text()).then(function (data) {
+                if (!resp.ok) {
+                    throw new Error((data && data.error) || resp.statusText);
+                }
+                return data;
+            });
+        });
+    }
+
+    function showMessage(text) {
+        var el = document.getElementById('message');
+        el.textContent = text;
+        el.hidden = !text;
+    }
+
+    function cell(row, text, className) {
+        var td = document.createElement('td');
+        td.textContent = text === undefined || text === null ? '' : String(text);
+        if (className) {
+            td.className = className;
+        }
+        row.appendChild(td);
+        return td;
+    }
+
+    function formatTime(value) {
+        if (!value || value.indexOf('0001-') === 0) {
+            return '';
+        }
+        return new Date(value).toLocaleString();
+    }
+
+    function button(label, className, onClick) {
+        var b = document.createElement('button');
+        b.textContent = label;
+        b.className = className || '';
+        b.addEventListener('click', onClick);
+        return b;
+    }
+
+    function loadRuns() {
+        return request('GET', 'runs').then(function (data) {
+            var body = document.querySelector('#runs tbody');
+            body.innerHTML = '';
+            data.backups.concat(data.restores).forEach(function (run) {
+                var row = document.createElement('tr');
+                cell(row, run.id);
+                cell(row, run.type);
+                cell(row, run.status, 'status-' + run.status);
+                cell(row, formatTime(run.start_time));
+                cell(row, formatTime(run.end_time));
+                cell(row, run.error);
+                cell(row, approvalText(run.approval));
+                var actions = cell(row, '');
+                if (run.status === 'awaiting_approval') {
+                    actions.appendChild(button('Approve', 'link', function () {
+                        decideRestore(run.id, 'approve', 'Approve');
+                    }));
+                    actions.appendChild(document.createTextNode(' '));
+                    actions.appendChild(button('Reject', 'link', function () {
+                        decideRestore(run.id, 'cancel', 'Reject');
+                    }));
+                }
+                body.appendChild(row);
+            });
+        });
+    }
+
+    function approvalText(approval) {
+        if (!approval) {
+            return '';
+        }
+        var text = 'requested by ' + approval.requested_by.id;
+        if (approval.approved_by) {
+            text += ', approved by ' + approval.approved_by.id;
+        }
+        return text;
+    }
+
+    // decideRestore approves or rejects a restore awaiting approval
+    function decideRestore(id, action, label) {
+        if (!window.confirm(label + ' restore ' + id + '?')) {
+            return;
+        }
+        request('POST', 'restores/' + encodeURIComponent(id) + '/' + action).then(function () {
+            return loadRuns();
+        }).catch(function (err) { showMessage(err.message); });
+    }
+
+    function loadBackups() {
+        return request('GET', 'backups').then(function (backups) {
+            var body = document.querySelector('#backups tbody');
+            body.innerHTML = '';
+            backups.forEach(function (b) {
+                var row = document.createElement('tr');
+                cell(row, b.backup_id);
+                cell(row, b.status, 'status-' + b.status);
+                cell(row, formatTime(b.start_time));
+                cell(row, b.namespace_count);
+                cell(row, b.object_count);
+                cell(row, '').appendChild(button('Browse', 'link', function () {
+                    loadBackup(b.backup_id).catch(function (err) { showMessage(err.message); });
+                }));
+                body.appendChild(row);
+            });
+        });
+    }
+
+    function loadBackup(id) {
+        return request('GET', 'backups/' + encodeURIComponent(id)).then(function (data) {
+            currentBackup = id;
+            document.getElementById('detail').hidden = false;
+            document.getElementById('detail-id').textContent = id;
+            document.getElementById('object-title').textContent = 'Select an object';
+            document.getElementById('object-content').textContent = '';
+
+            var body = document.querySelector('#namespaces tbody');
+            body.innerHTML = '';
+            Object.keys(data.namespaces || {}).sort().forEach(function (name) {
+                var ns = data.namespaces[name];
+                var row = document.createElement('tr');
+                cell(row, name);
+                cell(row, ns.status, 'status-' + ns.status);
+                cell(row, ns.resource_count);
+                cell(row, (ns.errors || []).join('; '));
+                cell(row, '').appendChild(button('Restore', 'link', function () {
+                    startRestore([name]);
+                }));
+                body.appendChild(row);
+            });
+
+            renderTree(data.tree || []);
+        });
+    }
+
+    function renderTree(tree) {
+        var root = document.getElementById('tree');
+        root.innerHTML = '';
+        tree.forEach(function (ns) {
+            var nsEl = document.createElement('details');
+            var nsSummary = document.createElement('summary');
+            nsSummary.textContent = ns.name + ' (' + ns.count + ')';
+            nsEl.appendChild(nsSummary);
+
+            (ns.children || []).forEach(function (type) {
+                var typeEl = document.createElement('details');
+                var typeSummary = document.createElement('summary');
+                typeSummary.textContent = type.name + ' (' + type.count + ')';
+                typeEl.appendChild(typeSummary);
+
+                (type.children || []).forEach(function (object) {
+                    typeEl.appendChild(button(object.name, 'link object', function () {
+                        showObject(object);
+                    }));
+                });
+                nsEl.appendChild(typeEl);
+            });
+            root.appendChild(nsEl);
+        });
+    }
+
+    function showObject(object) {
+        var path = 'backups/' + encodeURIComponent(currentBackup) + '/object?key=' + encodeURIComponent(object.key);
+        request('GET', path).then(function (content) {
+            document.getElementById('object-title').textContent = object.key;
+            document.getElementById('object-content').textContent = content;
+        }).catch(function (err) { showMessage(err.message); });
+    }
+
+    function startRestore(namespaces) {
+        var dryRun = document.getElementById('restore-dry-run').checked;
+        // A confirmation token forces the protected resources it was issued for
+        var confirmationToken = document.getElementById('restore-confirmation-token').value.trim();
+        var scope = namespaces ? 'namespace ' + namespaces.join(', ') : 'all namespaces';
+        if (confirmationToken) {
+            scope += ' including protected resources';
+        }
+        if (!window.confirm('Restore ' + scope + ' from ' + currentBackup + (dryRun ? ' (dry run)' : '') + '?')) {
+            return;
+        }
+        request('POST', 'restores', {
+            backup_id: currentBackup,
+            target_namespaces: namespaces || [],
+            dry_run: dryRun,
+            force_unsafe: confirmationToken !== '',
+            confirmation_token: confirmationToken
+        }).then(function (run) {
+            if (run.status === 'awaiting_approval') {
+                showMessage('Restore ' + run.id + ' is awaiting approval by someone else');
+            } else {
+                showMessage('Restore started: ' + run.id);
+            }
+            return loadRuns();
+        }).catch(function (err) { showMessage(err.message); });
+    }
+
+    function refresh() {
+        Promise.all([loadRuns(), loadBackups()]).catch(function (err) { showMessage(err.message); });
+    }
+
+    document.getElementById('backup-now').addEventListener('click', function () {
+        request('POST', 'backups').then(function (run) {
+            showMessage('Backup started: ' + run.id);
+            return loadRuns();
+        }).catch(function (err) { showMessage(err.message); });
+    });
+    document.getElementById('restore-backup').addEventListener('click', function () {
+        startRestore(null);
+    });
+    document.getElementById('set-token').addEventListener('click', function () {
+        sessionStorage.removeItem(tokenKey);
+        token();
+        refresh();
+    });
+
+    refresh();
+    setInterval(loadRuns, 5000);
+})();
@@ -0,0 +1,66 @@
+package dashboard
+
+import (
+	"sort"
+
+	"cluster-backup/internal/backup"
+)
+
+// clusterScopeNode names the top-level node holding cluster-scoped objects
+const clusterScopeNode = "(cluster-scoped)"
+
+// TreeNode is one level of the namespace/resource-type/object hierarchy
+type TreeNode struct {
+	Name     string      `json:"name"`
+	Key      string      `json:"key,omitempty"`
+	Size     int64       `json:"size,omitempty"`
+	Count    int         `json:"count"`
+	Children []*TreeNode `json:"children,omitempty"`
+}
+
+// BuildTree groups a manifest's objects by namespace and resource type, sorted by name
+func BuildTree(manifest *backup.Manifest) []*TreeNode {
+	namespaces := make(map[string]map[string][]*TreeNode)
+	for _, object := range manifest.Objects {
+		namespace := object.Namespace
+		if namespace == "" {
+			namespace = clusterScopeNode
+		}
+		types, ok := namespaces[namespace]
+		if !ok {
+			types = make(map[string][]*TreeNode)
+			namespaces[namespace] = types
+		}
+		types[object.ResourceType] = append(types[object.ResourceType], &TreeNode{
+			Name:  object.Name,
+			Key:   object.Key,
+			Size:  object.Size,
+			Count: 1,
+		})
+	}
+
+	tree := make([]*TreeNode, 0, len(namespaces))
+	for namespace, types := range namespaces {
+		nsNode := &TreeNode{Name: namespace}
+		for resourceType, objects := range types {
+			sortNodes(objects)
+			nsNode.Children = append(nsNode.Children, &TreeNode{
+				Name:     resourceType,
+				Count:    len(objects),
+				Children: objects,
+			})
+			nsNode.Count += len(objects)
+		}
+		sortNodes(nsNode.Children)
+		tree = append(tree, nsNode)
+	}
+	sortNodes(tree)
+
+	return tree
+}
+
+func sortNodes(nodes []*TreeNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+}
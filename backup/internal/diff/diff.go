@@ -0,0 +1,221 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	"cluster-backup/internal/backup"
+)
+
+// ChangeType describes how a resource differs between two backup runs
+type ChangeType string
+
+const (
+	ChangeAdded   ChangeType = "added"
+	ChangeRemoved ChangeType = "removed"
+	ChangeChanged ChangeType = "changed"
+)
+
+// ObjectFetcher retrieves a specific version of a stored object
+type ObjectFetcher interface {
+	GetObjectVersion(ctx context.Context, key, versionID string) ([]byte, error)
+}
+
+// ResourceChange is a single resource that differs between two runs
+type ResourceChange struct {
+	Type ChangeType          `json:"type"`
+	From *backup.ObjectEntry `json:"from,omitempty"`
+	To   *backup.ObjectEntry `json:"to,omitempty"`
+	// Diff is the unified YAML diff for changed resources. It stays empty when
+	// the older content is no longer retrievable from the bucket.
+	Diff string `json:"diff,omitempty"`
+}
+
+// Identity returns the namespace/resource-type/name of the changed resource
+func (c ResourceChange) Identity() string {
+	entry := c.To
+	if entry == nil {
+		entry = c.From
+	}
+	return identity(*entry)
+}
+
+// Result is the outcome of comparing two backup runs
+type Result struct {
+	FromID    string           `json:"from_id"`
+	ToID      string           `json:"to_id"`
+	Changes   []ResourceChange `json:"changes"`
+	Unchanged int              `json:"unchanged"`
+}
+
+// Counts returns the number of added, removed and changed resources
+func (r *Result) Counts() (added, removed, changed int) {
+	for _, change := range r.Changes {
+		switch change.Type {
+		case ChangeAdded:
+			added++
+		case ChangeRemoved:
+			removed++
+		case ChangeChanged:
+			changed++
+		}
+	}
+	return added, removed, changed
+}
+
+// CompareManifests reports added, removed and changed resources between two
+// manifests, sorted by identity. Changed resources are detected by checksum
+// and have no Diff yet; see PopulateDiffs.
+func CompareManifests(from, to *backup.Manifest) *Result {
+	result := &Result{FromID: from.BackupID, ToID: to.BackupID}
+
+	fromObjects := indexObjects(from)
+	toObjects := indexObjects(to)
+
+	for id, toEntry := range toObjects {
+		fromEntry, ok := fromObjects[id]
+		switch {
+		case !ok:
+			result.Changes = append(result.Changes, ResourceChange{Type: ChangeAdded, To: toEntry})
+		case fromEntry.ResourceChecksum() != toEntry.ResourceChecksum() || fromEntry.Checksum == "":
+			// Manifests written before checksums were recorded cannot be
+			// compared by hash, so treat them as changed and let the content
+			// diff decide. Provenance added to stored copies is not a change.
+			result.Changes = append(result.Changes, ResourceChange{Type: ChangeChanged, From: fromEntry, To: toEntry})
+		default:
+			result.Unchanged++
+		}
+	}
+	for id, fromEntry := range fromObjects {
+		if _, ok := toObjects[id]; !ok {
+			result.Changes = append(result.Changes, ResourceChange{Type: ChangeRemoved, From: fromEntry})
+		}
+	}
+
+	sort.Slice(result.Changes, func(i, j int) bool {
+		return result.Changes[i].Identity() < result.Changes[j].Identity()
+	})
+	return result
+}
+
+// PopulateDiffs fetches both versions of every changed resource and fills in
+// its unified diff. Resources whose content turns out to be identical are
+// moved to the unchanged count. Fetch failures are returned as warnings so one
+// missing object does not abort the whole comparison.
+func PopulateDiffs(ctx context.Context, fetcher ObjectFetcher, result *Result) []error {
+	return populateDiffs(ctx, fetcher, fetcher, result, true)
+}
+
+// CompareLive reports drift between a stored backup and a snapshot of the live
+// cluster, with unified diffs for changed resources. Objects in namespaces, or
+// the cluster scope, the snapshot could not fully list are not reported as
+// removed, since their absence may only mean the listing failed.
+func CompareLive(ctx context.Context, store ObjectFetcher, latest *backup.Manifest, live *backup.LiveSnapshot) (*Result, []error) {
+	result := CompareManifests(latest, live.Manifest)
+
+	var warnings []error
+	changes := result.Changes[:0]
+	for _, change := range result.Changes {
+		if change.Type == ChangeRemoved {
+			if change.From.Namespace == "" {
+				if scope := live.Manifest.ClusterScope; scope != nil && scope.Status == backup.ManifestStatusFailed {
+					warnings = append(warnings, fmt.Errorf("%s: not found live, but cluster-scoped resources could not be fully listed", change.Identity()))
+					continue
+				}
+			} else if ns, ok := live.Manifest.Namespaces[change.From.Namespace]; ok && ns.Status == backup.ManifestStatusFailed {
+				warnings = append(warnings, fmt.Errorf("%s: not found live, but namespace %s could not be fully listed", change.Identity(), change.From.Namespace))
+				continue
+			}
+		}
+		changes = append(changes, change)
+	}
+	result.Changes = changes
+
+	// The latest backup is also the latest object version, so contents can be
+	// diffed even when the bucket is not versioned
+	warnings = append(warnings, populateDiffs(ctx, store, live, result, false)...)
+	return result, warnings
+}
+
+func populateDiffs(ctx context.Context, fromFetcher, toFetcher ObjectFetcher, result *Result, requireVersions bool) []error {
+	var warnings []error
+	changes := result.Changes[:0]
+
+	for _, change := range result.Changes {
+		if change.Type != ChangeChanged {
+			changes = append(changes, change)
+			continue
+		}
+
+		// Without version IDs both entries point at the latest object, so the
+		// older content cannot be recovered
+		if requireVersions && change.From.VersionID == "" && change.From.Key == change.To.Key {
+			warnings = append(warnings, fmt.Errorf("%s: previous content not retained, enable bucket versioning to diff contents", change.Identity()))
+			changes = append(changes, change)
+			continue
+		}
+
+		fromData, err := fromFetcher.GetObjectVersion(ctx, change.From.Key, change.From.VersionID)
+		if err != nil {
+			warnings = append(warnings, fmt.Errorf("%s: failed to fetch %s version: %v", change.Identity(), result.FromID, err))
+			changes = append(changes, change)
+			continue
+		}
+		toData, err := toFetcher.GetObjectVersion(ctx, change.To.Key, change.To.VersionID)
+		if err != nil {
+			warnings = append(warnings, fmt.Errorf("%s: failed to fetch %s version: %v", change.Identity(), result.ToID, err))
+			changes = append(changes, change)
+			continue
+		}
+
+		change.Diff = UnifiedDiff(change.Identity(), result.FromID, result.ToID, fromData, toData)
+		if change.Diff == "" {
+			result.Unchanged++
+			continue
+		}
+		changes = append(changes, change)
+	}
+
+	result.Changes = changes
+	return warnings
+}
+
+// UnifiedDiff renders a unified diff of two YAML documents, labelling each
+// side with the backup it came from. It returns an empty string when the
+// documents are identical.
+func UnifiedDiff(name, fromLabel, toLabel string, from, to []byte) string {
+	text, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(from)),
+		B:        difflib.SplitLines(string(to)),
+		FromFile: fromLabel + "/" + name,
+		ToFile:   toLabel + "/" + name,
+		Context:  3,
+	})
+	if err != nil {
+		return ""
+	}
+	return text
+}
+
+// indexObjects keys a manifest's objects by resource identity
+func indexObjects(manifest *backup.Manifest) map[string]*backup.ObjectEntry {
+	objects := make(map[string]*backup.ObjectEntry, len(manifest.Objects))
+	for i := range manifest.Objects {
+		entry := &manifest.Objects[i]
+		objects[identity(*entry)] = entry
+	}
+	return objects
+}
+
+// identity names a resource independently of where it is stored
+func identity(entry backup.ObjectEntry) string {
+	resourceType := entry.ResourceType
+	if entry.Group != "" && !strings.Contains(resourceType, ".") {
+		resourceType += "." + entry.Group
+	}
+	return fmt.Sprintf("%s/%s/%s", entry.Namespace, resourceType, entry.Name)
+}
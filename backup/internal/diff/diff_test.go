@@ -0,0 +1,182 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/backup"
+)
+
+type fakeFetcher map[string][]byte
+
+func (f fakeFetcher) GetObjectVersion(ctx context.Context, key, versionID string) ([]byte, error) {
+	data, ok := f[key+"@"+versionID]
+	if !ok {
+		return nil, fmt.Errorf("object %s@%s not found", key, versionID)
+	}
+	return data, nil
+}
+
+func newManifest(id string, objects map[string]string, versions map[string]string) *backup.Manifest {
+	m := backup.NewManifest(id, "test-cluster", "cluster.local", "bucket", time.Now())
+	for name, content := range objects {
+		m.AddObject(backup.ObjectEntry{
+			Namespace:    "default",
+			ResourceType: "configmaps",
+			Name:         name,
+			Key:          backup.ObjectPath("cluster.local", "test-cluster", "default", "configmaps", name),
+			Checksum:     backup.Checksum([]byte(content)),
+			VersionID:    versions[name],
+		})
+	}
+	return m
+}
+
+func TestCompareManifests(t *testing.T) {
+	from := newManifest("backup-a", map[string]string{"kept": "a", "edited": "v1", "deleted": "x"}, nil)
+	to := newManifest("backup-b", map[string]string{"kept": "a", "edited": "v2", "created": "y"}, nil)
+
+	result := CompareManifests(from, to)
+
+	added, removed, changed := result.Counts()
+	assert.Equal(t, 1, added)
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, 1, changed)
+	assert.Equal(t, 1, result.Unchanged)
+
+	require.Len(t, result.Changes, 3)
+	assert.Equal(t, "default/configmaps/created", result.Changes[0].Identity())
+	assert.Equal(t, ChangeAdded, result.Changes[0].Type)
+	assert.Equal(t, "default/configmaps/deleted", result.Changes[1].Identity())
+	assert.Equal(t, ChangeRemoved, result.Changes[1].Type)
+	assert.Equal(t, "default/configmaps/edited", result.Changes[2].Identity())
+	assert.Equal(t, ChangeChanged, result.Changes[2].Type)
+}
+
+func TestPopulateDiffs(t *testing.T) {
+	key := backup.ObjectPath("cluster.local", "test-cluster", "default", "configmaps", "edited")
+
+	tests := []struct {
+		name             string
+		fromVersion      string
+		fetcher          fakeFetcher
+		expectedDiff     []string
+		expectedWarnings int
+	}{
+		{
+			name:        "versioned_bucket",
+			fromVersion: "v1",
+			fetcher: fakeFetcher{
+				key + "@v1": []byte("data:\n  value: one\n"),
+				key + "@v2": []byte("data:\n  value: two\n"),
+			},
+			expectedDiff: []string{"--- backup-a/default/configmaps/edited", "+++ backup-b/default/configmaps/edited", "-  value: one", "+  value: two"},
+		},
+		{
+			name:             "unversioned_bucket",
+			fromVersion:      "",
+			fetcher:          fakeFetcher{},
+			expectedWarnings: 1,
+		},
+		{
+			name:             "missing_object",
+			fromVersion:      "v1",
+			fetcher:          fakeFetcher{},
+			expectedWarnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from := newManifest("backup-a", map[string]string{"edited": "v1"}, map[string]string{"edited": tt.fromVersion})
+			to := newManifest("backup-b", map[string]string{"edited": "v2"}, map[string]string{"edited": "v2"})
+			result := CompareManifests(from, to)
+
+			warnings := PopulateDiffs(context.Background(), tt.fetcher, result)
+
+			assert.Len(t, warnings, tt.expectedWarnings)
+			require.Len(t, result.Changes, 1)
+			for _, line := range tt.expectedDiff {
+				assert.Contains(t, result.Changes[0].Diff, line)
+			}
+		})
+	}
+}
+
+func TestPopulateDiffs_IdenticalContent(t *testing.T) {
+	// Manifests without checksums are always reported as changed until the
+	// content comparison proves otherwise
+	from := newManifest("backup-a", map[string]string{"same": ""}, map[string]string{"same": "v1"})
+	to := newManifest("backup-b", map[string]string{"same": ""}, map[string]string{"same": "v2"})
+	from.Objects[0].Checksum = ""
+	to.Objects[0].Checksum = ""
+	key := from.Objects[0].Key
+
+	result := CompareManifests(from, to)
+	require.Len(t, result.Changes, 1)
+
+	warnings := PopulateDiffs(context.Background(), fakeFetcher{
+		key + "@v1": []byte("kind: ConfigMap\n"),
+		key + "@v2": []byte("kind: ConfigMap\n"),
+	}, result)
+
+	assert.Empty(t, warnings)
+	assert.Empty(t, result.Changes)
+	assert.Equal(t, 1, result.Unchanged)
+}
+
+func TestCompareLive(t *testing.T) {
+	latest := newManifest("backup-a", map[string]string{"edited": "data:\n  value: one\n", "deleted": "x"}, nil)
+	latest.AddObject(backup.ObjectEntry{Namespace: "unreachable", ResourceType: "secrets", Name: "token", Key: "unreachable/secrets/token.yaml"})
+	key := backup.ObjectPath("cluster.local", "test-cluster", "default", "configmaps", "edited")
+	store := fakeFetcher{key + "@": []byte("data:\n  value: one\n")}
+
+	live := backup.NewLiveSnapshot("test-cluster", "cluster.local", "bucket")
+	live.Add(backup.ObjectEntry{Namespace: "default", ResourceType: "configmaps", Name: "edited", Key: key}, []byte("data:\n  value: two\n"))
+	live.Manifest.SetNamespace("default", 1, nil)
+	live.Manifest.SetNamespace("unreachable", 0, fmt.Errorf("forbidden"))
+
+	result, warnings := CompareLive(context.Background(), store, latest, live)
+
+	// The unreachable namespace is reported as a warning rather than a removal
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0].Error(), "unreachable/secrets/token")
+
+	require.Len(t, result.Changes, 2)
+	assert.Equal(t, "default/configmaps/deleted", result.Changes[0].Identity())
+	assert.Equal(t, ChangeRemoved, result.Changes[0].Type)
+	assert.Equal(t, ChangeChanged, result.Changes[1].Type)
+	assert.Contains(t, result.Changes[1].Diff, "+++ live/default/configmaps/edited")
+	assert.Contains(t, result.Changes[1].Diff, "+  value: two")
+}
+
+func TestCompareLive_ClusterScopeNotListed(t *testing.T) {
+	latest := newManifest("backup-a", nil, nil)
+	latest.AddObject(backup.ObjectEntry{ResourceType: "clusterroles", Name: "viewer", Key: "_cluster/clusterroles/viewer.yaml"})
+
+	live := backup.NewLiveSnapshot("test-cluster", "cluster.local", "bucket")
+	live.Manifest.SetClusterScope(0, fmt.Errorf("forbidden"))
+
+	result, warnings := CompareLive(context.Background(), fakeFetcher{}, latest, live)
+
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0].Error(), "cluster-scoped resources could not be fully listed")
+	assert.Empty(t, result.Changes)
+}
+
+func TestCompareManifests_Provenance(t *testing.T) {
+	from := backup.NewManifest("backup-1", "test-cluster", "cluster.local", "bucket", time.Now())
+	to := backup.NewManifest("backup-2", "test-cluster", "cluster.local", "bucket", time.Now())
+	for _, m := range []*backup.Manifest{from, to} {
+		m.AddObject(backup.ObjectEntry{Namespace: "default", ResourceType: "configmaps", Name: "app", Checksum: "stored-" + m.BackupID, ContentChecksum: "content"})
+	}
+
+	result := CompareManifests(from, to)
+	assert.Empty(t, result.Changes, "provenance of the stored copies is not a change")
+	assert.Equal(t, 1, result.Unchanged)
+}
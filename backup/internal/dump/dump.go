@@ -0,0 +1,157 @@
+package dump
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+
+	"cluster-backup/internal/hooks"
+	"cluster-backup/internal/logging"
+)
+
+// ContainerAnnotation selects the container to exec the dump in. It defaults
+// to the pod's first container.
+const ContainerAnnotation = "backup.cluster/dump-container"
+
+// DatabaseAnnotation limits the dump to a single database
+const DatabaseAnnotation = "backup.cluster/dump-database"
+
+// maxStderr caps how much dump stderr is kept for errors
+const maxStderr = 1024
+
+// Plugin produces a logical dump of an application running in a pod
+type Plugin interface {
+	// Name identifies the plugin, e.g. "postgresql"
+	Name() string
+	// Selector matches the pods the plugin dumps
+	Selector() labels.Selector
+	// Command returns the command that writes the dump to stdout
+	Command(pod *corev1.Pod) []string
+	// Extension is the file extension of the dump, e.g. ".sql"
+	Extension() string
+}
+
+// Target is a pod selected for a dump by a plugin
+type Target struct {
+	Plugin    Plugin
+	Namespace string
+	Pod       string
+	Container string
+	Command   []string
+}
+
+// FileName returns the name the dump is stored under
+func (t Target) FileName() string {
+	return t.Pod + "." + t.Plugin.Name() + t.Plugin.Extension()
+}
+
+// Dumper finds pods matching its plugins and streams their dumps
+type Dumper struct {
+	kubeClient kubernetes.Interface
+	executor   hooks.PodExecutor
+	plugins    []Plugin
+	timeout    time.Duration
+	logger     *logging.StructuredLogger
+}
+
+// NewDumper creates a dumper running the given plugins. timeout bounds each dump.
+func NewDumper(kubeClient kubernetes.Interface, executor hooks.PodExecutor, plugins []Plugin, timeout time.Duration, logger *logging.StructuredLogger) *Dumper {
+	return &Dumper{
+		kubeClient: kubeClient,
+		executor:   executor,
+		plugins:    plugins,
+		timeout:    timeout,
+		logger:     logger,
+	}
+}
+
+// Targets returns the running pods of the namespace to dump, in pod name order.
+// A pod matched by several plugins is dumped by the first one.
+func (d *Dumper) Targets(ctx context.Context, namespace string) ([]Target, error) {
+	pods, err := d.kubeClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods for database dumps: %v", err)
+	}
+
+	items := pods.Items
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+
+	var targets []Target
+	for i := range items {
+		pod := &items[i]
+		if pod.Status.Phase != corev1.PodRunning || len(pod.Spec.Containers) == 0 {
+			continue
+		}
+
+		for _, plugin := range d.plugins {
+			if !plugin.Selector().Matches(labels.Set(pod.Labels)) {
+				continue
+			}
+
+			container := pod.Annotations[ContainerAnnotation]
+			if container == "" {
+				container = pod.Spec.Containers[0].Name
+			}
+			targets = append(targets, Target{
+				Plugin:    plugin,
+				Namespace: namespace,
+				Pod:       pod.Name,
+				Container: container,
+				Command:   plugin.Command(pod),
+			})
+			break
+		}
+	}
+	return targets, nil
+}
+
+// Dump execs the target's dump command and streams its output to w
+func (d *Dumper) Dump(ctx context.Context, target Target, w io.Writer) error {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	fields := map[string]interface{}{
+		"namespace": target.Namespace,
+		"pod":       target.Pod,
+		"container": target.Container,
+		"plugin":    target.Plugin.Name(),
+	}
+	d.logger.Info("database_dump_start", "Starting database dump", fields)
+
+	start := time.Now()
+	var stderr bytes.Buffer
+	err := d.executor.Exec(ctx, target.Namespace, target.Pod, target.Container, target.Command, w, &stderr)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %v", d.timeout)
+		}
+		if output := tail(stderr.String()); output != "" {
+			err = fmt.Errorf("%v: %s", err, output)
+		}
+		fields["error"] = err.Error()
+		d.logger.Error("database_dump_failed", "Database dump failed", fields)
+		return err
+	}
+
+	fields["duration_ms"] = time.Since(start).Milliseconds()
+	d.logger.Info("database_dump_complete", "Database dump completed", fields)
+	return nil
+}
+
+// tail keeps the end of dump stderr, where the error usually is
+func tail(output string) string {
+	output = strings.TrimSpace(output)
+	if len(output) <= maxStderr {
+		return output
+	}
+	return "..." + output[len(output)-maxStderr:]
+}
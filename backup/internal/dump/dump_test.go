@@ -0,0 +1,125 @@
+package dump
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"cluster-backup/internal/logging"
+)
+
+// fakeExecutor writes output to stdout, or stderr and err when err is set
+type fakeExecutor struct {
+	output  string
+	err     error
+	command []string
+}
+
+func (f *fakeExecutor) Exec(ctx context.Context, namespace, pod, container string, command []string, stdout, stderr io.Writer) error {
+	f.command = command
+	if f.err != nil {
+		fmt.Fprint(stderr, f.output)
+		return f.err
+	}
+	fmt.Fprint(stdout, f.output)
+	return nil
+}
+
+func newPod(name string, labels, annotations map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop", Labels: labels, Annotations: annotations},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "db"}, {Name: "exporter"}}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func mustBuiltin(t *testing.T, name, selector string) Plugin {
+	plugin, err := Builtin(name, selector)
+	require.NoError(t, err)
+	return plugin
+}
+
+func TestBuiltin(t *testing.T) {
+	_, err := Builtin("mongodb", "")
+	assert.Error(t, err)
+
+	_, err = Builtin("mysql", "app in (")
+	assert.Error(t, err)
+
+	plugin := mustBuiltin(t, "postgresql", "")
+	assert.Equal(t, "backup.cluster/dump=postgresql", plugin.Selector().String())
+}
+
+func TestPluginCommands(t *testing.T) {
+	postgres := mustBuiltin(t, "postgresql", "")
+	command := postgres.Command(newPod("pg-0", nil, nil))
+	assert.Contains(t, command[2], `pg_dump --clean --if-exists --no-password -U "${POSTGRES_USER:-postgres}" "${POSTGRES_DB:-postgres}"`)
+
+	command = postgres.Command(newPod("pg-0", nil, map[string]string{DatabaseAnnotation: "o'reilly"}))
+	require.Len(t, command, 3)
+	assert.Equal(t, "/bin/sh", command[0])
+	assert.Contains(t, command[2], `'o'\''reilly'`)
+
+	mysql := mustBuiltin(t, "mysql", "")
+	assert.Contains(t, mysql.Command(newPod("mysql-0", nil, nil))[2], "--single-transaction --routines --triggers -u root --all-databases")
+	assert.Contains(t, mysql.Command(newPod("mysql-0", nil, map[string]string{DatabaseAnnotation: "shop"}))[2], "--databases 'shop'")
+}
+
+func TestDumper_Targets(t *testing.T) {
+	stopped := newPod("pg-stopped", map[string]string{"backup.cluster/dump": "postgresql"}, nil)
+	stopped.Status.Phase = corev1.PodFailed
+
+	client := fake.NewSimpleClientset(
+		newPod("pg-1", map[string]string{"backup.cluster/dump": "postgresql"}, map[string]string{ContainerAnnotation: "exporter"}),
+		newPod("pg-0", map[string]string{"backup.cluster/dump": "postgresql"}, nil),
+		newPod("mariadb-0", map[string]string{"app.kubernetes.io/name": "mariadb"}, nil),
+		newPod("web", map[string]string{"app": "web"}, nil),
+		stopped,
+	)
+	dumper := NewDumper(client, &fakeExecutor{}, []Plugin{
+		mustBuiltin(t, "postgresql", ""),
+		mustBuiltin(t, "mysql", "app.kubernetes.io/name in (mysql,mariadb)"),
+	}, time.Minute, logging.NewStructuredLogger("test", "test-cluster"))
+
+	targets, err := dumper.Targets(context.Background(), "shop")
+	require.NoError(t, err)
+	require.Len(t, targets, 3)
+
+	assert.Equal(t, "mariadb-0", targets[0].Pod)
+	assert.Equal(t, "mariadb-0.mysql.sql", targets[0].FileName())
+	assert.Equal(t, "pg-0", targets[1].Pod)
+	assert.Equal(t, "db", targets[1].Container)
+	assert.Equal(t, "pg-1", targets[2].Pod)
+	assert.Equal(t, "exporter", targets[2].Container)
+}
+
+func TestDumper_Dump(t *testing.T) {
+	target := Target{
+		Plugin:    mustBuiltin(t, "postgresql", ""),
+		Namespace: "shop",
+		Pod:       "pg-0",
+		Container: "db",
+		Command:   []string{"pg_dump"},
+	}
+	logger := logging.NewStructuredLogger("test", "test-cluster")
+
+	executor := &fakeExecutor{output: "CREATE TABLE orders ();"}
+	var out bytes.Buffer
+	require.NoError(t, NewDumper(nil, executor, nil, time.Minute, logger).Dump(context.Background(), target, &out))
+	assert.Equal(t, "CREATE TABLE orders ();", out.String())
+	assert.Equal(t, []string{"pg_dump"}, executor.command)
+
+	executor = &fakeExecutor{output: "pg_dump: error: connection refused", err: fmt.Errorf("command terminated with exit code 1")}
+	err := NewDumper(nil, executor, nil, time.Minute, logger).Dump(context.Background(), target, &out)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exit code 1: pg_dump: error: connection refused")
+}
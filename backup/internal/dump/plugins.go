@@ -0,0 +1,83 @@
+package dump
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// SelectorLabel is the pod label built-in plugins select on by default,
+// e.g. backup.cluster/dump=postgresql
+const SelectorLabel = "backup.cluster/dump"
+
+// Builtin returns the built-in plugin with the given name. An empty selector
+// selects pods labelled backup.cluster/dump=<name>.
+func Builtin(name, selector string) (Plugin, error) {
+	if selector == "" {
+		selector = SelectorLabel + "=" + name
+	}
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector for dump plugin %s: %v", name, err)
+	}
+
+	switch name {
+	case "postgresql":
+		return &postgreSQLPlugin{selector: parsed}, nil
+	case "mysql":
+		return &mySQLPlugin{selector: parsed}, nil
+	default:
+		return nil, fmt.Errorf("unknown dump plugin: %s", name)
+	}
+}
+
+// postgreSQLPlugin runs pg_dump using the credentials of the official image
+type postgreSQLPlugin struct {
+	selector labels.Selector
+}
+
+func (p *postgreSQLPlugin) Name() string { return "postgresql" }
+
+func (p *postgreSQLPlugin) Selector() labels.Selector { return p.selector }
+
+func (p *postgreSQLPlugin) Extension() string { return ".sql" }
+
+// Command dumps the annotated database, or POSTGRES_DB, as plain SQL that
+// drops and recreates objects on restore
+func (p *postgreSQLPlugin) Command(pod *corev1.Pod) []string {
+	database := `"${POSTGRES_DB:-postgres}"`
+	if name := pod.Annotations[DatabaseAnnotation]; name != "" {
+		database = shellQuote(name)
+	}
+	return []string{"/bin/sh", "-c",
+		`PGPASSWORD="${POSTGRES_PASSWORD:-$PGPASSWORD}" pg_dump --clean --if-exists --no-password -U "${POSTGRES_USER:-postgres}" ` + database}
+}
+
+// mySQLPlugin runs mysqldump using the credentials of the official image
+type mySQLPlugin struct {
+	selector labels.Selector
+}
+
+func (p *mySQLPlugin) Name() string { return "mysql" }
+
+func (p *mySQLPlugin) Selector() labels.Selector { return p.selector }
+
+func (p *mySQLPlugin) Extension() string { return ".sql" }
+
+// Command dumps the annotated database, or all databases, in a single
+// transaction so InnoDB tables are consistent without locking
+func (p *mySQLPlugin) Command(pod *corev1.Pod) []string {
+	databases := "--all-databases"
+	if name := pod.Annotations[DatabaseAnnotation]; name != "" {
+		databases = "--databases " + shellQuote(name)
+	}
+	return []string{"/bin/sh", "-c",
+		`MYSQL_PWD="${MYSQL_ROOT_PASSWORD:-$MYSQL_PWD}" mysqldump --single-transaction --routines --triggers -u root ` + databases}
+}
+
+// shellQuote quotes s as a single shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,102 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"cluster-backup/internal/logging"
+)
+
+// SnapshotConfig describes how to reach etcd and where etcdctl is installed
+type SnapshotConfig struct {
+	Endpoints   []string
+	CACert      string
+	Cert        string
+	Key         string
+	EtcdctlPath string
+	Timeout     time.Duration
+}
+
+// commandRunner runs a command and returns its combined output
+type commandRunner func(ctx context.Context, env []string, name string, args ...string) ([]byte, error)
+
+// Snapshotter saves etcd snapshots with etcdctl. The backup pod needs network
+// access to the etcd endpoints and the client certificates, which usually
+// means running on a control-plane node with hostNetwork and a hostPath mount.
+type Snapshotter struct {
+	config SnapshotConfig
+	logger *logging.StructuredLogger
+	run    commandRunner
+}
+
+// NewSnapshotter creates a snapshotter for the configured etcd endpoints
+func NewSnapshotter(config SnapshotConfig, logger *logging.StructuredLogger) *Snapshotter {
+	return &Snapshotter{
+		config: config,
+		logger: logger,
+		run:    runCommand,
+	}
+}
+
+// Save writes a snapshot to a new file in dir and returns its path. The caller
+// removes the file once it has been uploaded.
+func (s *Snapshotter) Save(ctx context.Context, dir string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	file, err := os.CreateTemp(dir, "etcd-snapshot-*.db")
+	if err != nil {
+		return "", fmt.Errorf("failed to create snapshot file: %v", err)
+	}
+	path := file.Name()
+	file.Close()
+	// etcdctl refuses to overwrite, so only reserve the name
+	os.Remove(path)
+
+	args := []string{
+		"--endpoints=" + strings.Join(s.config.Endpoints, ","),
+		"--cacert=" + s.config.CACert,
+		"--cert=" + s.config.Cert,
+		"--key=" + s.config.Key,
+		"snapshot", "save", path,
+	}
+
+	s.logger.Info("etcd_snapshot_start", "Saving etcd snapshot", map[string]interface{}{
+		"endpoints": s.config.Endpoints,
+	})
+
+	start := time.Now()
+	output, err := s.run(ctx, []string{"ETCDCTL_API=3"}, s.config.EtcdctlPath, args...)
+	if err != nil {
+		os.Remove(path)
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("etcd snapshot timed out after %v", s.config.Timeout)
+		}
+		return "", fmt.Errorf("etcdctl snapshot save failed: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("etcdctl did not write a snapshot: %v", err)
+	}
+	if info.Size() == 0 {
+		os.Remove(path)
+		return "", fmt.Errorf("etcdctl wrote an empty snapshot")
+	}
+
+	s.logger.Info("etcd_snapshot_saved", "Saved etcd snapshot", map[string]interface{}{
+		"size_bytes":  info.Size(),
+		"duration_ms": time.Since(start).Milliseconds(),
+	})
+	return path, nil
+}
+
+func runCommand(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	return cmd.CombinedOutput()
+}
@@ -0,0 +1,95 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/logging"
+)
+
+func newTestSnapshotter(run commandRunner) *Snapshotter {
+	s := NewSnapshotter(SnapshotConfig{
+		Endpoints:   []string{"https://10.0.0.1:2379", "https://10.0.0.2:2379"},
+		CACert:      "/pki/ca.crt",
+		Cert:        "/pki/server.crt",
+		Key:         "/pki/server.key",
+		EtcdctlPath: "etcdctl",
+		Timeout:     time.Second,
+	}, logging.NewStructuredLogger("test", "test-cluster"))
+	s.run = run
+	return s
+}
+
+func TestSnapshotter_Save(t *testing.T) {
+	var gotEnv, gotArgs []string
+	s := newTestSnapshotter(func(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
+		gotEnv, gotArgs = env, args
+		// etcdctl writes the snapshot to the last argument
+		return nil, os.WriteFile(args[len(args)-1], []byte("snapshot-data"), 0600)
+	})
+
+	path, err := s.Save(context.Background(), t.TempDir())
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "snapshot-data", string(data))
+	assert.Equal(t, []string{"ETCDCTL_API=3"}, gotEnv)
+	assert.Equal(t, []string{
+		"--endpoints=https://10.0.0.1:2379,https://10.0.0.2:2379",
+		"--cacert=/pki/ca.crt",
+		"--cert=/pki/server.crt",
+		"--key=/pki/server.key",
+		"snapshot", "save", path,
+	}, gotArgs)
+}
+
+func TestSnapshotter_SaveErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     commandRunner
+		message string
+	}{
+		{
+			name: "etcdctl_failure",
+			run: func(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
+				return []byte("context deadline exceeded\n"), fmt.Errorf("exit status 1")
+			},
+			message: "etcdctl snapshot save failed: exit status 1: context deadline exceeded",
+		},
+		{
+			name: "empty_snapshot",
+			run: func(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
+				return nil, os.WriteFile(args[len(args)-1], nil, 0600)
+			},
+			message: "empty snapshot",
+		},
+		{
+			name: "timeout",
+			run: func(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+			message: "timed out after 1s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			_, err := newTestSnapshotter(tt.run).Save(context.Background(), dir)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.message)
+
+			// Failed snapshots leave nothing behind
+			entries, _ := os.ReadDir(dir)
+			assert.Empty(t, entries)
+		})
+	}
+}
@@ -0,0 +1,159 @@
+// Package failure classifies the errors of backup runs into a small taxonomy,
+// so retries, metrics and run reports treat failures of the same kind alike
+// whatever component raised them.
+package failure
+
+import (
+	"context"
+	"errors"
+
+	"github.com/minio/minio-go/v7"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Category names a kind of failure in metrics and reports
+type Category string
+
+const (
+	CategoryStorage    Category = "storage"
+	CategoryThrottled  Category = "throttled"
+	CategoryInvalid    Category = "invalid"
+	CategoryPermission Category = "permission"
+	// CategoryUnknown is every error the taxonomy does not cover
+	CategoryUnknown Category = "unknown"
+)
+
+// Categories lists the categories in the order reports show them
+var Categories = []Category{CategoryStorage, CategoryThrottled, CategoryInvalid, CategoryPermission, CategoryUnknown}
+
+// Kind is a class of failure. The kinds below are sentinels to match with
+// errors.Is against errors wrapped with Wrap; Classify also finds them for
+// errors that were not.
+type Kind struct {
+	Category Category
+	// Retryable is set when trying the operation again may succeed
+	Retryable bool
+	message   string
+}
+
+func (k *Kind) Error() string {
+	return k.message
+}
+
+var (
+	// ErrStorageUnavailable is an object store that could not be reached or
+	// failed the request
+	ErrStorageUnavailable = &Kind{Category: CategoryStorage, Retryable: true, message: "storage unavailable"}
+	// ErrAPIThrottled is a request the Kubernetes API server or the object
+	// store turned away to shed load
+	ErrAPIThrottled = &Kind{Category: CategoryThrottled, Retryable: true, message: "API throttled"}
+	// ErrResourceInvalid is a resource that cannot be backed up as it is,
+	// e.g. one that cannot be serialized or exceeds MAX_RESOURCE_SIZE
+	ErrResourceInvalid = &Kind{Category: CategoryInvalid, message: "resource invalid"}
+	// ErrPermissionDenied is a request the credentials in use are not allowed
+	ErrPermissionDenied = &Kind{Category: CategoryPermission, message: "permission denied"}
+)
+
+// Error is an error of a known kind. Its message is that of the error it
+// wraps, so wrapping changes what code sees but not what people read.
+type Error struct {
+	Kind *Kind
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap lets errors.Is match both the kind and the errors wrapped
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// Wrap marks err as of kind; a nil err stays nil
+func Wrap(kind *Kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// Storage marks an error of the object store: as the kind Classify finds,
+// such as ErrPermissionDenied for rejected credentials, and otherwise as
+// ErrStorageUnavailable. A nil err stays nil.
+func Storage(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	kind := Classify(err)
+	if kind == nil {
+		kind = ErrStorageUnavailable
+	}
+	return Wrap(kind, err)
+}
+
+// Classify returns the kind of err: the one it was wrapped with, else the
+// one its Kubernetes API status or object store error code indicates, or nil
+// when it is of no known kind
+func Classify(err error) *Kind {
+	if err == nil {
+		return nil
+	}
+	var kind *Kind
+	if errors.As(err, &kind) {
+		return kind
+	}
+
+	switch {
+	case apierrors.IsTooManyRequests(err):
+		return ErrAPIThrottled
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err):
+		return ErrPermissionDenied
+	case apierrors.IsInvalid(err), apierrors.IsRequestEntityTooLargeError(err):
+		return ErrResourceInvalid
+	}
+
+	switch storageCode(err) {
+	case "SlowDown", "RequestLimitExceeded", "TooManyRequests":
+		return ErrAPIThrottled
+	case "AccessDenied", "InvalidAccessKeyId", "SignatureDoesNotMatch", "ExpiredToken", "InvalidToken":
+		return ErrPermissionDenied
+	case "EntityTooLarge", "InvalidArgument":
+		return ErrResourceInvalid
+	case "InternalError", "ServiceUnavailable", "XMinioServerNotInitialized":
+		return ErrStorageUnavailable
+	}
+	return nil
+}
+
+// storageCode returns the S3 error code of the first object store error in
+// err's chain, if any
+func storageCode(err error) string {
+	var response minio.ErrorResponse
+	if errors.As(err, &response) {
+		return response.Code
+	}
+	var pointer *minio.ErrorResponse
+	if errors.As(err, &pointer) && pointer != nil {
+		return pointer.Code
+	}
+	return ""
+}
+
+// CategoryOf returns the category of err, CategoryUnknown when it is of no
+// known kind
+func CategoryOf(err error) Category {
+	if kind := Classify(err); kind != nil {
+		return kind.Category
+	}
+	return CategoryUnknown
+}
+
+// Retryable reports whether trying again after err may succeed. Errors of no
+// known kind, such as a call that timed out, are retried.
+func Retryable(err error) bool {
+	if kind := Classify(err); kind != nil {
+		return kind.Retryable
+	}
+	return true
+}
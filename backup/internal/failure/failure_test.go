@@ -0,0 +1,66 @@
+package failure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestWrap(t *testing.T) {
+	cause := errors.New("cannot marshal")
+	err := fmt.Errorf("failed to back up namespace shop: %w", Wrap(ErrResourceInvalid, cause))
+
+	assert.Equal(t, "failed to back up namespace shop: cannot marshal", err.Error(), "wrapping keeps the message")
+	assert.ErrorIs(t, err, ErrResourceInvalid)
+	assert.ErrorIs(t, err, cause)
+	assert.NotErrorIs(t, err, ErrStorageUnavailable)
+	assert.Equal(t, CategoryInvalid, CategoryOf(err))
+	assert.False(t, Retryable(err))
+	assert.Nil(t, Wrap(ErrResourceInvalid, nil))
+}
+
+func TestClassify(t *testing.T) {
+	pods := schema.GroupResource{Resource: "pods"}
+	for _, test := range []struct {
+		err  error
+		want *Kind
+	}{
+		{apierrors.NewTooManyRequests("slow down", 1), ErrAPIThrottled},
+		{fmt.Errorf("failed to list pods: %w", apierrors.NewForbidden(pods, "", errors.New("no RBAC"))), ErrPermissionDenied},
+		{apierrors.NewUnauthorized("expired"), ErrPermissionDenied},
+		{minio.ErrorResponse{Code: "SlowDown"}, ErrAPIThrottled},
+		{fmt.Errorf("failed to upload: %w", minio.ErrorResponse{Code: "AccessDenied"}), ErrPermissionDenied},
+		{minio.ErrorResponse{Code: "ServiceUnavailable"}, ErrStorageUnavailable},
+		{apierrors.NewNotFound(pods, "web"), nil},
+		{errors.New("connection refused"), nil},
+	} {
+		assert.Equal(t, test.want, Classify(test.err), test.err.Error())
+	}
+}
+
+func TestStorage(t *testing.T) {
+	err := Storage(errors.New("dial tcp: connection refused"))
+	assert.ErrorIs(t, err, ErrStorageUnavailable, "store errors of no known kind are unavailability")
+	assert.True(t, Retryable(err))
+
+	err = Storage(minio.ErrorResponse{Code: "InvalidAccessKeyId"})
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	assert.False(t, Retryable(err))
+
+	assert.Equal(t, context.Canceled, Storage(context.Canceled))
+	assert.Nil(t, Storage(nil))
+}
+
+func TestRetryable(t *testing.T) {
+	assert.True(t, Retryable(errors.New("unexpected EOF")), "errors of no known kind are retried")
+	assert.True(t, Retryable(apierrors.NewTooManyRequests("slow down", 1)))
+	assert.False(t, Retryable(apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "", errors.New("no RBAC"))))
+	assert.True(t, Retryable(fmt.Errorf("list: %w", context.DeadlineExceeded)), "a call that timed out may succeed again")
+	assert.Equal(t, CategoryUnknown, CategoryOf(errors.New("unexpected EOF")))
+}
@@ -0,0 +1,226 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Mode selects how include and exclude lists combine
+type Mode string
+
+const (
+	// ModeWhitelist backs up only what the include lists name. A dimension
+	// without an include list falls back to its exclude list.
+	ModeWhitelist Mode = "whitelist"
+	// ModeBlacklist backs up everything the exclude lists do not name
+	ModeBlacklist Mode = "blacklist"
+	// ModeHybrid applies the include lists, then removes anything named by
+	// the exclude lists
+	ModeHybrid Mode = "hybrid"
+)
+
+// Rules are the namespace and resource filters of a backup
+type Rules struct {
+	Mode              Mode
+	IncludeNamespaces []string
+	ExcludeNamespaces []string
+	IncludeResources  []string
+	ExcludeResources  []string
+	// ClusterResources lists the cluster-scoped resource types backed up by
+	// the cluster-scope phase. It is independent of the mode.
+	ClusterResources []string
+	// LabelSelector and AnnotationSelector use Kubernetes label selector
+	// syntax, e.g. "tier in (web,api),!legacy"
+	LabelSelector      string
+	AnnotationSelector string
+}
+
+// Validate checks rules for conflicts. The error reports rules that cannot be
+// applied; warnings report lists the mode ignores.
+func Validate(rules Rules) ([]string, error) {
+	var warnings, problems []string
+
+	for _, list := range []struct {
+		name     string
+		patterns []string
+	}{
+		{"INCLUDE_NAMESPACES", rules.IncludeNamespaces},
+		{"EXCLUDE_NAMESPACES", rules.ExcludeNamespaces},
+		{"INCLUDE_RESOURCES", rules.IncludeResources},
+		{"EXCLUDE_RESOURCES", rules.ExcludeResources},
+		{"CLUSTER_RESOURCES", rules.ClusterResources},
+	} {
+		if _, err := CompileMatcher(list.patterns, false); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", list.name, err))
+		}
+	}
+
+	if _, err := labels.Parse(rules.LabelSelector); err != nil {
+		problems = append(problems, fmt.Sprintf("LABEL_SELECTOR: %v", err))
+	}
+	if _, err := labels.Parse(rules.AnnotationSelector); err != nil {
+		problems = append(problems, fmt.Sprintf("ANNOTATION_SELECTOR: %v", err))
+	}
+
+	switch rules.Mode {
+	case ModeWhitelist:
+		if len(rules.IncludeNamespaces) > 0 && len(rules.ExcludeNamespaces) > 0 {
+			warnings = append(warnings, "EXCLUDE_NAMESPACES is ignored in whitelist mode when INCLUDE_NAMESPACES is set")
+		}
+		if len(rules.IncludeResources) > 0 && len(rules.ExcludeResources) > 0 {
+			warnings = append(warnings, "EXCLUDE_RESOURCES is ignored in whitelist mode when INCLUDE_RESOURCES is set")
+		}
+	case ModeBlacklist:
+		if len(rules.IncludeNamespaces) > 0 {
+			problems = append(problems, "INCLUDE_NAMESPACES cannot be used in blacklist mode")
+		}
+		if len(rules.IncludeResources) > 0 {
+			problems = append(problems, "INCLUDE_RESOURCES cannot be used in blacklist mode")
+		}
+	case ModeHybrid:
+		for _, namespace := range overlap(rules.IncludeNamespaces, rules.ExcludeNamespaces) {
+			problems = append(problems, fmt.Sprintf("namespace %s is both included and excluded", namespace))
+		}
+		for _, resource := range overlap(rules.IncludeResources, rules.ExcludeResources) {
+			problems = append(problems, fmt.Sprintf("resource %s is both included and excluded", resource))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("unknown filtering mode %q (expected %s, %s or %s)",
+			rules.Mode, ModeWhitelist, ModeBlacklist, ModeHybrid))
+	}
+
+	if len(problems) > 0 {
+		return warnings, fmt.Errorf("invalid filtering rules: %s", strings.Join(problems, "; "))
+	}
+	return warnings, nil
+}
+
+// Engine decides which namespaces and resource types are backed up
+type Engine struct {
+	mode              Mode
+	includeNamespaces *Matcher
+	excludeNamespaces *Matcher
+	includeResources  *Matcher
+	excludeResources  *Matcher
+	clusterResources  *Matcher
+	labelSelector     string
+	annotations       labels.Selector
+}
+
+// NewEngine compiles rules into a filter engine. Rules are expected to have
+// passed Validate; invalid patterns never match and an invalid annotation
+// selector matches nothing.
+//
+// Literal entries keep their historical meaning: included namespaces must
+// match exactly, while included resources and all excludes also match names
+// containing them. Cluster resource entries always match exactly.
+func NewEngine(rules Rules) *Engine {
+	includeNamespaces, _ := CompileMatcher(rules.IncludeNamespaces, false)
+	excludeNamespaces, _ := CompileMatcher(rules.ExcludeNamespaces, true)
+	includeResources, _ := CompileMatcher(rules.IncludeResources, true)
+	excludeResources, _ := CompileMatcher(rules.ExcludeResources, true)
+	clusterResources, _ := CompileMatcher(rules.ClusterResources, false)
+
+	annotationSelector, err := labels.Parse(rules.AnnotationSelector)
+	if err != nil {
+		annotationSelector = labels.Nothing()
+	}
+
+	return &Engine{
+		mode:              rules.Mode,
+		includeNamespaces: includeNamespaces,
+		excludeNamespaces: excludeNamespaces,
+		includeResources:  includeResources,
+		excludeResources:  excludeResources,
+		clusterResources:  clusterResources,
+		labelSelector:     rules.LabelSelector,
+		annotations:       annotationSelector,
+	}
+}
+
+// Mode returns the filtering mode in effect
+func (e *Engine) Mode() Mode {
+	return e.mode
+}
+
+// IncludeNamespace reports whether a namespace should be backed up
+func (e *Engine) IncludeNamespace(namespace string) bool {
+	return e.include(namespace, e.includeNamespaces, e.excludeNamespaces)
+}
+
+// IncludeResource reports whether a resource type should be backed up
+func (e *Engine) IncludeResource(resource string) bool {
+	return e.include(resource, e.includeResources, e.excludeResources)
+}
+
+// ExcludeResource reports whether an exclude list names a resource type,
+// whatever the mode
+func (e *Engine) ExcludeResource(resource string) bool {
+	return e.excludeResources.Match(resource)
+}
+
+// IncludeClusterResource reports whether a cluster-scoped resource type should
+// be backed up. Only types named by the cluster resource list are included.
+func (e *Engine) IncludeClusterResource(resource string) bool {
+	return e.clusterResources.Match(resource)
+}
+
+// LabelSelector returns the label selector to apply server-side in list calls
+func (e *Engine) LabelSelector() string {
+	return e.labelSelector
+}
+
+// MatchAnnotations reports whether annotations satisfy the annotation selector
+func (e *Engine) MatchAnnotations(annotations map[string]string) bool {
+	return e.annotations.Matches(labels.Set(annotations))
+}
+
+func (e *Engine) include(name string, include, exclude *Matcher) bool {
+	switch e.mode {
+	case ModeBlacklist:
+		return !exclude.Match(name)
+	case ModeHybrid:
+		if !include.Empty() && !include.Match(name) {
+			return false
+		}
+		return !exclude.Match(name)
+	default:
+		if !include.Empty() {
+			return include.Match(name)
+		}
+		return !exclude.Match(name)
+	}
+}
+
+// FilterNamespaces returns the namespaces that should be backed up
+func (e *Engine) FilterNamespaces(namespaces []string) []string {
+	var result []string
+	for _, namespace := range namespaces {
+		if e.IncludeNamespace(namespace) {
+			result = append(result, namespace)
+		}
+	}
+	return result
+}
+
+func contains(list []string, name string) bool {
+	for _, item := range list {
+		if item == name {
+			return true
+		}
+	}
+	return false
+}
+
+// overlap returns the entries present in both lists
+func overlap(a, b []string) []string {
+	var both []string
+	for _, item := range a {
+		if contains(b, item) {
+			both = append(both, item)
+		}
+	}
+	return both
+}
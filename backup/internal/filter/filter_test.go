@@ -0,0 +1,183 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var allNamespaces = []string{"default", "kube-system", "prod-shop", "prod-billing", "prod-legacy", "team-a"}
+
+func TestEngine_FilterNamespaces(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    Rules
+		expected []string
+	}{
+		{
+			name:     "whitelist_include_list",
+			rules:    Rules{Mode: ModeWhitelist, IncludeNamespaces: []string{"team-a", "prod-shop"}, ExcludeNamespaces: []string{"team-a"}},
+			expected: []string{"prod-shop", "team-a"},
+		},
+		{
+			name:     "whitelist_falls_back_to_exclude_list",
+			rules:    Rules{Mode: ModeWhitelist, ExcludeNamespaces: []string{"kube", "default"}},
+			expected: []string{"prod-shop", "prod-billing", "prod-legacy", "team-a"},
+		},
+		{
+			name:     "empty_mode_behaves_as_whitelist",
+			rules:    Rules{IncludeNamespaces: []string{"default"}},
+			expected: []string{"default"},
+		},
+		{
+			name:     "whitelist_glob_and_regex",
+			rules:    Rules{Mode: ModeWhitelist, IncludeNamespaces: []string{"team-*", "~^prod-(shop|billing)$"}},
+			expected: []string{"prod-shop", "prod-billing", "team-a"},
+		},
+		{
+			name:     "blacklist",
+			rules:    Rules{Mode: ModeBlacklist, ExcludeNamespaces: []string{"kube-system", "prod-"}},
+			expected: []string{"default", "team-a"},
+		},
+		{
+			name:     "hybrid_patterns_minus_excludes",
+			rules:    Rules{Mode: ModeHybrid, IncludeNamespaces: []string{"~^prod-.*$", "team-[a-z]"}, ExcludeNamespaces: []string{"prod-legacy"}},
+			expected: []string{"prod-shop", "prod-billing", "team-a"},
+		},
+		{
+			name:     "hybrid_literal_matches_whole_name",
+			rules:    Rules{Mode: ModeHybrid, IncludeNamespaces: []string{"prod"}},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, NewEngine(tt.rules).FilterNamespaces(allNamespaces))
+		})
+	}
+}
+
+func TestEngine_IncludeResource(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    Rules
+		resource string
+		expected bool
+	}{
+		{name: "whitelist_listed", rules: Rules{Mode: ModeWhitelist, IncludeResources: []string{"deployments"}}, resource: "deployments", expected: true},
+		{name: "whitelist_unlisted", rules: Rules{Mode: ModeWhitelist, IncludeResources: []string{"deployments"}}, resource: "secrets"},
+		{name: "whitelist_exclude_fallback", rules: Rules{Mode: ModeWhitelist, ExcludeResources: []string{"events"}}, resource: "events"},
+		{name: "blacklist_excluded", rules: Rules{Mode: ModeBlacklist, ExcludeResources: []string{"secrets"}}, resource: "secrets"},
+		{name: "blacklist_other", rules: Rules{Mode: ModeBlacklist, ExcludeResources: []string{"secrets"}}, resource: "configmaps", expected: true},
+		{name: "hybrid_all_minus_excludes", rules: Rules{Mode: ModeHybrid, ExcludeResources: []string{"events"}}, resource: "services", expected: true},
+		{name: "hybrid_included_and_excluded", rules: Rules{Mode: ModeHybrid, IncludeResources: []string{"secrets", "configmaps"}, ExcludeResources: []string{"secret"}}, resource: "secrets"},
+		{name: "hybrid_not_included", rules: Rules{Mode: ModeHybrid, IncludeResources: []string{"configmaps"}}, resource: "services"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, NewEngine(tt.rules).IncludeResource(tt.resource))
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    Rules
+		warnings int
+		errText  string
+	}{
+		{name: "whitelist_defaults", rules: Rules{Mode: ModeWhitelist}},
+		{
+			name:     "whitelist_ignored_excludes",
+			rules:    Rules{Mode: ModeWhitelist, IncludeNamespaces: []string{"a"}, ExcludeNamespaces: []string{"b"}, IncludeResources: []string{"pods"}, ExcludeResources: []string{"events"}},
+			warnings: 2,
+		},
+		{name: "unknown_mode", rules: Rules{Mode: "greylist"}, errText: `unknown filtering mode "greylist"`},
+		{name: "blacklist_with_includes", rules: Rules{Mode: ModeBlacklist, IncludeResources: []string{"pods"}}, errText: "INCLUDE_RESOURCES cannot be used in blacklist mode"},
+		{name: "invalid_regex", rules: Rules{Mode: ModeHybrid, IncludeNamespaces: []string{"~prod-("}}, errText: `INCLUDE_NAMESPACES: invalid patterns "~prod-("`},
+		{name: "invalid_glob", rules: Rules{Mode: ModeBlacklist, ExcludeResources: []string{"secret[s"}}, errText: `EXCLUDE_RESOURCES: invalid patterns "secret[s"`},
+		{name: "hybrid_conflicting_resource", rules: Rules{Mode: ModeHybrid, IncludeResources: []string{"secrets"}, ExcludeResources: []string{"secrets"}}, errText: "resource secrets is both included and excluded"},
+		{name: "hybrid_valid", rules: Rules{Mode: ModeHybrid, IncludeNamespaces: []string{"prod-*"}, ExcludeNamespaces: []string{"prod-legacy"}, ExcludeResources: []string{"events"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings, err := Validate(tt.rules)
+			assert.Len(t, warnings, tt.warnings)
+			if tt.errText == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errText)
+		})
+	}
+}
+
+func TestEngine_MatchAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		selector    string
+		annotations map[string]string
+		expected    bool
+	}{
+		{name: "empty_selector", annotations: nil, expected: true},
+		{name: "equality", selector: "backup=enabled", annotations: map[string]string{"backup": "enabled"}, expected: true},
+		{name: "equality_missing", selector: "backup=enabled", annotations: nil},
+		{name: "inequality", selector: "tier!=cache", annotations: map[string]string{"tier": "web"}, expected: true},
+		{name: "inequality_absent_key", selector: "tier!=cache", annotations: nil, expected: true},
+		{name: "in", selector: "tier in (web,api)", annotations: map[string]string{"tier": "api"}, expected: true},
+		{name: "notin", selector: "tier notin (web,api)", annotations: map[string]string{"tier": "api"}},
+		{name: "exists", selector: "backup", annotations: map[string]string{"backup": ""}, expected: true},
+		{name: "does_not_exist", selector: "!skip-backup", annotations: map[string]string{"skip-backup": "true"}},
+		{
+			name:        "multiple_requirements",
+			selector:    "backup=enabled,tier in (web,api),!legacy",
+			annotations: map[string]string{"backup": "enabled", "tier": "web"},
+			expected:    true,
+		},
+		{
+			name:        "multiple_requirements_one_fails",
+			selector:    "backup=enabled,tier in (web,api),!legacy",
+			annotations: map[string]string{"backup": "enabled", "tier": "web", "legacy": "true"},
+		},
+		{name: "invalid_matches_nothing", selector: "tier in (web", annotations: map[string]string{"tier": "web"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine(Rules{Mode: ModeWhitelist, AnnotationSelector: tt.selector})
+			assert.Equal(t, tt.expected, engine.MatchAnnotations(tt.annotations))
+		})
+	}
+}
+
+func TestValidate_Selectors(t *testing.T) {
+	_, err := Validate(Rules{Mode: ModeWhitelist, LabelSelector: "app in (web,api),tier!=cache", AnnotationSelector: "!skip-backup"})
+	assert.NoError(t, err)
+
+	_, err = Validate(Rules{Mode: ModeWhitelist, LabelSelector: "app in (web", AnnotationSelector: "a==b==c"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "LABEL_SELECTOR")
+	assert.Contains(t, err.Error(), "ANNOTATION_SELECTOR")
+}
+
+func TestEngine_IncludeClusterResource(t *testing.T) {
+	engine := NewEngine(Rules{
+		Mode:             ModeBlacklist,
+		ExcludeResources: []string{"clusterroles"},
+		ClusterResources: []string{"customresourcedefinitions", "clusterroles", "storage*"},
+	})
+
+	assert.True(t, engine.IncludeClusterResource("customresourcedefinitions"))
+	assert.True(t, engine.IncludeClusterResource("clusterroles"), "exclude lists do not apply to the cluster scope")
+	assert.True(t, engine.IncludeClusterResource("storageclasses"))
+	assert.False(t, engine.IncludeClusterResource("clusterrolebindings"), "literals match exactly")
+	assert.False(t, engine.IncludeClusterResource("nodes"))
+
+	assert.False(t, NewEngine(Rules{Mode: ModeWhitelist}).IncludeClusterResource("customresourcedefinitions"))
+}
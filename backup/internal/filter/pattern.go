@@ -0,0 +1,79 @@
+package filter
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// RegexPrefix marks a list entry as a regular expression, e.g. ~^prod-.*$
+const RegexPrefix = "~"
+
+// Matcher matches names against a compiled list of patterns. Entries starting
+// with ~ are regular expressions, entries containing *, ? or [ are globs and
+// anything else is a literal name.
+type Matcher struct {
+	literals  []string
+	substring bool
+	globs     []string
+	regexps   []*regexp.Regexp
+}
+
+// CompileMatcher compiles patterns into a matcher. With substring set, literal
+// entries also match names that contain them. Invalid patterns are skipped and
+// reported in the error.
+func CompileMatcher(patterns []string, substring bool) (*Matcher, error) {
+	matcher := &Matcher{substring: substring}
+	var problems []string
+
+	for _, pattern := range patterns {
+		switch {
+		case strings.HasPrefix(pattern, RegexPrefix):
+			re, err := regexp.Compile(strings.TrimPrefix(pattern, RegexPrefix))
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%q: %v", pattern, err))
+				continue
+			}
+			matcher.regexps = append(matcher.regexps, re)
+		case strings.ContainsAny(pattern, "*?["):
+			if _, err := path.Match(pattern, ""); err != nil {
+				problems = append(problems, fmt.Sprintf("%q: %v", pattern, err))
+				continue
+			}
+			matcher.globs = append(matcher.globs, pattern)
+		default:
+			matcher.literals = append(matcher.literals, pattern)
+		}
+	}
+
+	if len(problems) > 0 {
+		return matcher, fmt.Errorf("invalid patterns %s", strings.Join(problems, ", "))
+	}
+	return matcher, nil
+}
+
+// Empty reports whether the matcher has no patterns
+func (m *Matcher) Empty() bool {
+	return len(m.literals) == 0 && len(m.globs) == 0 && len(m.regexps) == 0
+}
+
+// Match reports whether name matches any pattern
+func (m *Matcher) Match(name string) bool {
+	for _, literal := range m.literals {
+		if literal == name || (m.substring && strings.Contains(name, literal)) {
+			return true
+		}
+	}
+	for _, glob := range m.globs {
+		if ok, _ := path.Match(glob, name); ok {
+			return true
+		}
+	}
+	for _, re := range m.regexps {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatcher_Match(t *testing.T) {
+	tests := []struct {
+		name      string
+		patterns  []string
+		substring bool
+		input     string
+		expected  bool
+	}{
+		{name: "literal_exact", patterns: []string{"team-a"}, input: "team-a", expected: true},
+		{name: "literal_no_substring", patterns: []string{"team"}, input: "team-a"},
+		{name: "literal_substring", patterns: []string{"team"}, substring: true, input: "my-team-a", expected: true},
+		{name: "glob_star", patterns: []string{"team-*"}, input: "team-payments", expected: true},
+		{name: "glob_star_no_match", patterns: []string{"team-*"}, input: "teams"},
+		{name: "glob_not_substring", patterns: []string{"team-*"}, substring: true, input: "my-team-a"},
+		{name: "glob_question_mark", patterns: []string{"ns-?"}, input: "ns-1", expected: true},
+		{name: "glob_class", patterns: []string{"prod-[ab]"}, input: "prod-b", expected: true},
+		{name: "regex_anchored", patterns: []string{"~^prod-.*$"}, input: "prod-shop", expected: true},
+		{name: "regex_anchored_no_match", patterns: []string{"~^prod-.*$"}, input: "preprod-shop"},
+		{name: "regex_unanchored", patterns: []string{"~prod"}, input: "preprod-shop", expected: true},
+		{name: "any_pattern", patterns: []string{"default", "team-*", "~^ci-[0-9]+$"}, input: "ci-42", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher, err := CompileMatcher(tt.patterns, tt.substring)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, matcher.Match(tt.input))
+		})
+	}
+}
+
+func TestCompileMatcher_Invalid(t *testing.T) {
+	matcher, err := CompileMatcher([]string{"~(", "team-[", "default"}, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"~("`)
+	assert.Contains(t, err.Error(), `"team-["`)
+
+	// Valid patterns still apply
+	assert.True(t, matcher.Match("default"))
+	assert.False(t, matcher.Empty())
+
+	empty, err := CompileMatcher(nil, false)
+	require.NoError(t, err)
+	assert.True(t, empty.Empty())
+	assert.False(t, empty.Match("default"))
+}
@@ -0,0 +1,98 @@
+package grpcapi
+
+import (
+	"sync"
+
+	"cluster-backup/internal/backup"
+)
+
+const (
+	// maxEventsPerRun bounds the replay history kept for late subscribers
+	maxEventsPerRun = 1000
+	// maxTrackedRuns bounds the number of runs whose history is retained
+	maxTrackedRuns = 100
+)
+
+// runEvent is a progress event tagged with the run that produced it
+type runEvent struct {
+	RunID string
+	backup.ProgressEvent
+}
+
+// ProgressHub fans out progress events from running operations to watchers
+type ProgressHub struct {
+	history     map[string][]runEvent
+	order       []string
+	subscribers map[string]map[chan runEvent]struct{}
+	mutex       sync.Mutex
+}
+
+// NewProgressHub creates an empty progress hub
+func NewProgressHub() *ProgressHub {
+	return &ProgressHub{
+		history:     make(map[string][]runEvent),
+		subscribers: make(map[string]map[chan runEvent]struct{}),
+	}
+}
+
+// Reporter returns a progress callback that publishes events for the given run.
+// It returns nil when runID is empty so callers can pass it straight through.
+func (h *ProgressHub) Reporter(runID string) backup.ProgressFunc {
+	if runID == "" {
+		return nil
+	}
+	return func(event backup.ProgressEvent) {
+		h.publish(runEvent{RunID: runID, ProgressEvent: event})
+	}
+}
+
+// publish records an event and delivers it to current subscribers
+func (h *ProgressHub) publish(event runEvent) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	events, tracked := h.history[event.RunID]
+	if !tracked {
+		h.order = append(h.order, event.RunID)
+		if len(h.order) > maxTrackedRuns {
+			delete(h.history, h.order[0])
+			h.order = h.order[1:]
+		}
+	}
+	if len(events) < maxEventsPerRun {
+		h.history[event.RunID] = append(events, event)
+	}
+
+	for ch := range h.subscribers[event.RunID] {
+		select {
+		case ch <- event:
+		default:
+			// Slow watchers miss events rather than blocking the backup
+		}
+	}
+}
+
+// subscribe returns the events recorded so far for a run and a channel for
+// subsequent events. The returned function must be called to unsubscribe.
+func (h *ProgressHub) subscribe(runID string) ([]runEvent, <-chan runEvent, func()) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	ch := make(chan runEvent, 256)
+	if h.subscribers[runID] == nil {
+		h.subscribers[runID] = make(map[chan runEvent]struct{})
+	}
+	h.subscribers[runID][ch] = struct{}{}
+
+	history := make([]runEvent, len(h.history[runID]))
+	copy(history, h.history[runID])
+
+	return history, ch, func() {
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+		delete(h.subscribers[runID], ch)
+		if len(h.subscribers[runID]) == 0 {
+			delete(h.subscribers, runID)
+		}
+	}
+}
@@ -0,0 +1,505 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	backupv1 "cluster-backup/api/proto/backup/v1"
+	"cluster-backup/internal/api"
+	"cluster-backup/internal/audit"
+	"cluster-backup/internal/auth"
+	"cluster-backup/internal/backup"
+	"cluster-backup/internal/logging"
+)
+
+// runPollInterval controls how often watchers check whether a run has finished
+const runPollInterval = 500 * time.Millisecond
+
+// methodRoles is the role each method requires, as for its REST route
+var methodRoles = map[string]auth.Role{
+	backupv1.BackupService_StartBackup_FullMethodName:         auth.RoleOperator,
+	backupv1.BackupService_GetBackup_FullMethodName:           auth.RoleViewer,
+	backupv1.BackupService_ListBackups_FullMethodName:         auth.RoleViewer,
+	backupv1.BackupService_WatchBackup_FullMethodName:         auth.RoleViewer,
+	backupv1.RestoreService_StartRestore_FullMethodName:       auth.RoleOperator,
+	backupv1.RestoreService_GetRestore_FullMethodName:         auth.RoleViewer,
+	backupv1.RestoreService_WatchRestore_FullMethodName:       auth.RoleViewer,
+	backupv1.RestoreService_GetRestoreProgress_FullMethodName: auth.RoleViewer,
+	backupv1.RestoreService_CancelRestore_FullMethodName:      auth.RoleOperator,
+}
+
+// Server exposes backup and restore operations over gRPC
+type Server struct {
+	grpcServer *grpc.Server
+	logger     *logging.StructuredLogger
+	config     api.ServerConfig
+	runs       *api.RunRegistry
+	hub        *ProgressHub
+	backupFn   api.BackupFunc
+	restoreFn  api.RestoreFunc
+	ctx        context.Context
+}
+
+// NewServer creates a new gRPC API server. It shares authentication settings
+// and the run registry with the REST API; progress for every run is read from hub.
+func NewServer(ctx context.Context, cfg api.ServerConfig, runs *api.RunRegistry, hub *ProgressHub, backupFn api.BackupFunc, restoreFn api.RestoreFunc, logger *logging.StructuredLogger) (*Server, error) {
+	if cfg.Port <= 0 {
+		cfg.Port = 9090 // Default port
+	}
+
+	s := &Server{
+		logger:    logger,
+		config:    cfg,
+		runs:      runs,
+		hub:       hub,
+		backupFn:  backupFn,
+		restoreFn: restoreFn,
+		ctx:       ctx,
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(s.unaryAuth),
+		grpc.StreamInterceptor(s.streamAuth),
+	}
+	if cfg.TLS != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(cfg.TLS.ServerConfig())))
+	}
+
+	s.grpcServer = grpc.NewServer(opts...)
+	backupv1.RegisterBackupServiceServer(s.grpcServer, &backupService{server: s})
+	backupv1.RegisterRestoreServiceServer(s.grpcServer, &restoreService{server: s})
+
+	return s, nil
+}
+
+// Serve serves gRPC requests on the given listener until Stop is called
+func (s *Server) Serve(listener net.Listener) error {
+	s.logger.Info("grpc_server_start", "Starting gRPC API server", map[string]interface{}{
+		"address": listener.Addr().String(),
+		"tls":     s.config.TLS != nil,
+		"mtls":    s.config.TLS != nil && s.config.TLS.Mutual(),
+	})
+
+	if err := s.grpcServer.Serve(listener); err != nil && err != grpc.ErrServerStopped {
+		s.logger.Error("grpc_server_error", "gRPC API server failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return fmt.Errorf("gRPC server failed: %v", err)
+	}
+	return nil
+}
+
+// StartAsync listens on the configured port and serves in the background
+func (s *Server) StartAsync() <-chan error {
+	errChan := make(chan error, 1)
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.config.Port))
+	if err != nil {
+		errChan <- fmt.Errorf("gRPC server failed to listen: %v", err)
+		close(errChan)
+		return errChan
+	}
+
+	go func() {
+		defer close(errChan)
+		if err := s.Serve(listener); err != nil {
+			errChan <- err
+		}
+	}()
+
+	return errChan
+}
+
+// Stop gracefully stops the gRPC server, forcing it closed if ctx expires first
+func (s *Server) Stop(ctx context.Context) error {
+	s.logger.Info("grpc_server_stop", "Stopping gRPC API server", map[string]interface{}{
+		"port": s.config.Port,
+	})
+
+	done := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+		return fmt.Errorf("error shutting down gRPC server: %v", ctx.Err())
+	}
+}
+
+// authorize checks the bearer token carried in the request metadata and
+// that its holder has the method's role, and returns ctx carrying the
+// holder as the audit actor. Methods without a role require admin.
+func (s *Server) authorize(ctx context.Context, method string) (context.Context, error) {
+	var authorization string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			authorization = values[0]
+		}
+	}
+
+	role, ok := methodRoles[method]
+	if !ok {
+		role = auth.RoleAdmin
+	}
+	principal, err := s.config.Auth.Authorize(ctx, authorization, role)
+	if err != nil {
+		s.logger.Warning("grpc_unauthorized", "Rejected gRPC request", map[string]interface{}{
+			"method": method,
+			"error":  err.Error(),
+		})
+		if errors.Is(err, auth.ErrForbidden) {
+			return nil, status.Error(codes.PermissionDenied, auth.ErrForbidden.Error())
+		}
+		return nil, status.Error(codes.Unauthenticated, auth.ErrUnauthenticated.Error())
+	}
+	var address string
+	if p, ok := peer.FromContext(ctx); ok {
+		address = p.Addr.String()
+	}
+	return audit.WithActor(ctx, principal.Actor("grpc", address)), nil
+}
+
+func (s *Server) unaryAuth(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) streamAuth(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if _, err := s.authorize(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// getRun looks up a run of the expected type
+func (s *Server) getRun(id string, runType api.RunType) (*api.Run, error) {
+	run, ok := s.runs.Get(id)
+	if !ok || run.Type != runType {
+		return nil, status.Errorf(codes.NotFound, "%s run %s not found", runType, id)
+	}
+	return run, nil
+}
+
+// watch streams recorded and live progress events until the run finishes
+func (s *Server) watch(id string, runType api.RunType, stream grpc.ServerStream) error {
+	if _, err := s.getRun(id, runType); err != nil {
+		return err
+	}
+
+	history, events, unsubscribe := s.hub.subscribe(id)
+	defer unsubscribe()
+
+	for _, event := range history {
+		if err := stream.SendMsg(toProtoEvent(event)); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(runPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event := <-events:
+			if err := stream.SendMsg(toProtoEvent(event)); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			run, ok := s.runs.Get(id)
+			if !ok {
+				return status.Errorf(codes.NotFound, "%s run %s no longer tracked", runType, id)
+			}
+			if run.IsActive() {
+				continue
+			}
+			// Flush anything published before the run completed
+			if err := drainEvents(events, stream); err != nil {
+				return err
+			}
+			return stream.SendMsg(finalEvent(run))
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// drainEvents sends any events already queued on the channel
+func drainEvents(events <-chan runEvent, stream grpc.ServerStream) error {
+	for {
+		select {
+		case event := <-events:
+			if err := stream.SendMsg(toProtoEvent(event)); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// backupService implements backupv1.BackupServiceServer
+type backupService struct {
+	backupv1.UnimplementedBackupServiceServer
+	server *Server
+}
+
+func (b *backupService) StartBackup(ctx context.Context, req *backupv1.StartBackupRequest) (*backupv1.Run, error) {
+	run, err := api.StartBackupRun(api.WithActorOf(b.server.ctx, ctx), b.server.runs, b.server.backupFn)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, "a backup run is already in progress")
+	}
+
+	b.server.logger.Info("grpc_backup_triggered", "Backup triggered through gRPC API", map[string]interface{}{
+		"run_id": run.ID,
+	})
+	return toProtoRun(run), nil
+}
+
+func (b *backupService) GetBackup(ctx context.Context, req *backupv1.GetRunRequest) (*backupv1.Run, error) {
+	run, err := b.server.getRun(req.GetId(), api.RunTypeBackup)
+	if err != nil {
+		return nil, err
+	}
+	return toProtoRun(run), nil
+}
+
+func (b *backupService) ListBackups(ctx context.Context, req *backupv1.ListRunsRequest) (*backupv1.ListRunsResponse, error) {
+	runs := b.server.runs.List(api.RunTypeBackup)
+	resp := &backupv1.ListRunsResponse{Runs: make([]*backupv1.Run, 0, len(runs))}
+	for _, run := range runs {
+		resp.Runs = append(resp.Runs, toProtoRun(run))
+	}
+	return resp, nil
+}
+
+func (b *backupService) WatchBackup(req *backupv1.WatchRunRequest, stream backupv1.BackupService_WatchBackupServer) error {
+	return b.server.watch(req.GetId(), api.RunTypeBackup, stream)
+}
+
+// restoreService implements backupv1.RestoreServiceServer
+type restoreService struct {
+	backupv1.UnimplementedRestoreServiceServer
+	server *Server
+}
+
+func (r *restoreService) StartRestore(ctx context.Context, req *backupv1.StartRestoreRequest) (*backupv1.Run, error) {
+	if r.server.restoreFn == nil {
+		return nil, status.Error(codes.Unimplemented, "restore is not configured on this server")
+	}
+	if req.GetBackupId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "backup_id is required")
+	}
+
+	run := api.StartRestoreRun(api.WithActorOf(r.server.ctx, ctx), r.server.runs, r.server.restoreFn, api.RestoreRequest{
+		BackupID:          req.GetBackupId(),
+		TargetNamespaces:  req.GetTargetNamespaces(),
+		ResourceTypes:     req.GetResourceTypes(),
+		DryRun:            req.GetDryRun(),
+		ForceUnsafe:       req.GetForceUnsafe(),
+		ConfirmationToken: req.GetConfirmationToken(),
+	})
+
+	r.server.logger.Info("grpc_restore_triggered", "Restore triggered through gRPC API", map[string]interface{}{
+		"run_id":    run.ID,
+		"backup_id": req.GetBackupId(),
+		"dry_run":   req.GetDryRun(),
+	})
+	return toProtoRun(run), nil
+}
+
+func (r *restoreService) GetRestore(ctx context.Context, req *backupv1.GetRunRequest) (*backupv1.Run, error) {
+	run, err := r.server.getRun(req.GetId(), api.RunTypeRestore)
+	if err != nil {
+		return nil, err
+	}
+	return toProtoRun(run), nil
+}
+
+func (r *restoreService) WatchRestore(req *backupv1.WatchRunRequest, stream backupv1.RestoreService_WatchRestoreServer) error {
+	return r.server.watch(req.GetId(), api.RunTypeRestore, stream)
+}
+
+func (r *restoreService) GetRestoreProgress(ctx context.Context, req *backupv1.GetRunRequest) (*backupv1.RestoreProgress, error) {
+	run, err := r.server.getRun(req.GetId(), api.RunTypeRestore)
+	if err != nil {
+		return nil, err
+	}
+	return toProtoRestoreProgress(api.RestoreProgressOf(run)), nil
+}
+
+func (r *restoreService) CancelRestore(ctx context.Context, req *backupv1.GetRunRequest) (*backupv1.Run, error) {
+	if _, err := r.server.getRun(req.GetId(), api.RunTypeRestore); err != nil {
+		return nil, err
+	}
+
+	run, err := r.server.runs.Cancel(req.GetId())
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "cannot cancel restore run %s: %v", req.GetId(), err)
+	}
+	r.server.logger.Info("grpc_restore_cancelled", "Restore cancellation requested through gRPC API", map[string]interface{}{
+		"run_id": run.ID,
+	})
+	r.server.runs.AuditLog().Record(ctx, audit.Record{Operation: audit.OperationRestoreCancel, Outcome: audit.OutcomeRequested, RunID: run.ID})
+	return toProtoRun(run), nil
+}
+
+// toProtoRun converts a tracked run into its protobuf representation
+func toProtoRun(run *api.Run) *backupv1.Run {
+	pb := &backupv1.Run{
+		Id:              run.ID,
+		Type:            string(run.Type),
+		Status:          toProtoStatus(run.Status),
+		StartTime:       timestamppb.New(run.StartTime),
+		Error:           run.Error,
+		CancelRequested: run.CancelRequested,
+	}
+	if run.EndTime != nil {
+		pb.EndTime = timestamppb.New(*run.EndTime)
+	}
+	if result, ok := run.Result.(*backup.BackupResult); ok && result != nil {
+		pb.NamespacesProcessed = int32(result.NamespacesBackedUp)
+		pb.ResourcesProcessed = int32(result.ResourcesBackedUp + result.ClusterResourcesBackedUp)
+	}
+	if progress, ok := run.Progress.(*backup.RunProgress); ok && progress != nil {
+		pb.Progress = toProtoProgress(progress)
+	}
+	if run.Type == api.RunTypeRestore {
+		if progress := api.RestoreProgressOf(run); progress.Progress != nil {
+			pb.RestoreProgress = toProtoRestoreProgress(progress)
+		}
+	}
+	return pb
+}
+
+func toProtoStatus(runStatus api.RunStatus) backupv1.RunStatus {
+	switch runStatus {
+	case api.RunStatusPending:
+		return backupv1.RunStatus_RUN_STATUS_PENDING
+	case api.RunStatusAwaitingApproval:
+		// Not started yet; approvals go through the REST API or the dashboard
+		return backupv1.RunStatus_RUN_STATUS_PENDING
+	case api.RunStatusRunning:
+		return backupv1.RunStatus_RUN_STATUS_RUNNING
+	case api.RunStatusCompleted:
+		return backupv1.RunStatus_RUN_STATUS_COMPLETED
+	case api.RunStatusFailed:
+		return backupv1.RunStatus_RUN_STATUS_FAILED
+	case api.RunStatusCancelled:
+		return backupv1.RunStatus_RUN_STATUS_CANCELLED
+	default:
+		return backupv1.RunStatus_RUN_STATUS_UNSPECIFIED
+	}
+}
+
+// toProtoEvent converts a progress event into its protobuf representation
+func toProtoEvent(event runEvent) *backupv1.ProgressEvent {
+	pb := &backupv1.ProgressEvent{
+		RunId:     event.RunID,
+		Namespace: event.Namespace,
+		Resource:  event.Resource,
+		ItemCount: int32(event.ItemCount),
+		Timestamp: timestamppb.New(event.Timestamp),
+	}
+	if event.Error != nil {
+		pb.Error = event.Error.Error()
+	}
+
+	switch event.Stage {
+	case backup.ProgressNamespaceStarted:
+		pb.Stage = backupv1.ProgressStage_PROGRESS_STAGE_NAMESPACE_STARTED
+	case backup.ProgressResourceCompleted:
+		pb.Stage = backupv1.ProgressStage_PROGRESS_STAGE_RESOURCE_COMPLETED
+	case backup.ProgressResourceFailed:
+		pb.Stage = backupv1.ProgressStage_PROGRESS_STAGE_RESOURCE_FAILED
+	case backup.ProgressNamespaceCompleted:
+		pb.Stage = backupv1.ProgressStage_PROGRESS_STAGE_NAMESPACE_COMPLETED
+	case backup.ProgressRunProgress:
+		pb.Stage = backupv1.ProgressStage_PROGRESS_STAGE_RUN_PROGRESS
+	}
+	if event.Run != nil {
+		pb.Progress = toProtoProgress(event.Run)
+	}
+	return pb
+}
+
+// toProtoProgress converts a run's progress into its protobuf representation
+func toProtoProgress(progress *backup.RunProgress) *backupv1.RunProgress {
+	return &backupv1.RunProgress{
+		ItemsDone:       int64(progress.ItemsDone),
+		ItemsExpected:   int64(progress.ItemsExpected),
+		Percent:         progress.Percent,
+		ItemsPerSecond:  progress.ItemsPerSecond,
+		EtaSeconds:      progress.ETASeconds,
+		NamespacesDone:  int32(progress.NamespacesDone),
+		NamespacesTotal: int32(progress.NamespacesTotal),
+		EstimateSource:  progress.EstimateSource,
+	}
+}
+
+// toProtoRestoreProgress converts a restore run's progress into its protobuf
+// representation
+func toProtoRestoreProgress(progress *api.RestoreRunProgress) *backupv1.RestoreProgress {
+	pb := &backupv1.RestoreProgress{
+		RunId:           progress.ID,
+		Status:          toProtoStatus(progress.Status),
+		CancelRequested: progress.CancelRequested,
+	}
+	if progress.Progress == nil {
+		return pb
+	}
+	pb.Phase = progress.Progress.Phase
+	pb.Percent = progress.Progress.Percent
+	pb.ResourcesTotal = int32(progress.Progress.ResourcesTotal)
+	pb.ResourcesDone = int32(progress.Progress.ResourcesDone)
+	pb.CurrentResource = progress.Progress.CurrentResource
+	for _, failed := range progress.Progress.FailedResources {
+		pb.FailedResources = append(pb.FailedResources, &backupv1.FailedResource{
+			Resource:  failed.Resource,
+			Namespace: failed.Namespace,
+			Error:     failed.Error,
+		})
+	}
+	return pb
+}
+
+// finalEvent builds the terminal event sent when a watched run finishes
+func finalEvent(run *api.Run) *backupv1.ProgressEvent {
+	event := &backupv1.ProgressEvent{
+		RunId:     run.ID,
+		Stage:     backupv1.ProgressStage_PROGRESS_STAGE_RUN_COMPLETED,
+		Error:     run.Error,
+		Timestamp: timestamppb.Now(),
+	}
+	if run.EndTime != nil {
+		event.Timestamp = timestamppb.New(*run.EndTime)
+	}
+	switch run.Status {
+	case api.RunStatusFailed:
+		event.Stage = backupv1.ProgressStage_PROGRESS_STAGE_RUN_FAILED
+	case api.RunStatusCancelled:
+		event.Stage = backupv1.ProgressStage_PROGRESS_STAGE_RUN_CANCELLED
+	}
+	if result, ok := run.Result.(*backup.BackupResult); ok && result != nil {
+		event.ItemCount = int32(result.ResourcesBackedUp + result.ClusterResourcesBackedUp)
+	}
+	return event
+}
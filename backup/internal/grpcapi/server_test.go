@@ -0,0 +1,234 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	backupv1 "cluster-backup/api/proto/backup/v1"
+	"cluster-backup/internal/api"
+	"cluster-backup/internal/auth"
+	"cluster-backup/internal/backup"
+	"cluster-backup/internal/logging"
+)
+
+const (
+	testToken       = "test-token"
+	testViewerToken = "viewer-token"
+)
+
+func startTestServer(t *testing.T, backupFn api.BackupFunc, hub *ProgressHub) *grpc.ClientConn {
+	return startRestoreTestServer(t, backupFn, nil, api.NewRunRegistry(10), hub)
+}
+
+func startRestoreTestServer(t *testing.T, backupFn api.BackupFunc, restoreFn api.RestoreFunc, runs *api.RunRegistry, hub *ProgressHub) *grpc.ClientConn {
+	logger := logging.NewStructuredLogger("grpc-test", "test-cluster")
+	server, err := NewServer(context.Background(), api.ServerConfig{
+		Auth: auth.NewAuthenticator(map[auth.Role]string{auth.RoleAdmin: testToken, auth.RoleViewer: testViewerToken}, nil),
+	}, runs, hub, backupFn, restoreFn, logger)
+	require.NoError(t, err)
+
+	listener := bufconn.Listen(1024 * 1024)
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Stop(context.Background()) })
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func authContext() context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+testToken)
+}
+
+func TestServer_RequiresToken(t *testing.T) {
+	conn := startTestServer(t, nil, NewProgressHub())
+	client := backupv1.NewBackupServiceClient(conn)
+
+	_, err := client.ListBackups(context.Background(), &backupv1.ListRunsRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.ListBackups(authContext(), &backupv1.ListRunsRequest{})
+	assert.NoError(t, err)
+
+	viewer := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+testViewerToken)
+	_, err = client.ListBackups(viewer, &backupv1.ListRunsRequest{})
+	assert.NoError(t, err)
+	_, err = client.StartBackup(viewer, &backupv1.StartBackupRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "viewers cannot start backups")
+}
+
+func TestServer_WatchBackupStreamsProgress(t *testing.T) {
+	hub := NewProgressHub()
+	release := make(chan struct{})
+	backupFn := func(ctx context.Context) (*backup.BackupResult, error) {
+		report := hub.Reporter(api.RunIDFromContext(ctx))
+		report(backup.ProgressEvent{Stage: backup.ProgressNamespaceStarted, Namespace: "default"})
+		<-release
+		report(backup.ProgressEvent{Stage: backup.ProgressResourceCompleted, Namespace: "default", Resource: "configmaps", ItemCount: 3})
+		return &backup.BackupResult{NamespacesBackedUp: 1, ResourcesBackedUp: 3}, nil
+	}
+
+	conn := startTestServer(t, backupFn, hub)
+	client := backupv1.NewBackupServiceClient(conn)
+
+	run, err := client.StartBackup(authContext(), &backupv1.StartBackupRequest{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, run.GetId())
+
+	_, err = client.StartBackup(authContext(), &backupv1.StartBackupRequest{})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	ctx, cancel := context.WithTimeout(authContext(), 5*time.Second)
+	defer cancel()
+	stream, err := client.WatchBackup(ctx, &backupv1.WatchRunRequest{Id: run.GetId()})
+	require.NoError(t, err)
+
+	first, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, backupv1.ProgressStage_PROGRESS_STAGE_NAMESPACE_STARTED, first.GetStage())
+	close(release)
+
+	var stages []backupv1.ProgressStage
+	for {
+		event, err := stream.Recv()
+		require.NoError(t, err)
+		stages = append(stages, event.GetStage())
+		if event.GetStage() == backupv1.ProgressStage_PROGRESS_STAGE_RUN_COMPLETED {
+			assert.Equal(t, int32(3), event.GetItemCount())
+			break
+		}
+	}
+	assert.Equal(t, []backupv1.ProgressStage{
+		backupv1.ProgressStage_PROGRESS_STAGE_RESOURCE_COMPLETED,
+		backupv1.ProgressStage_PROGRESS_STAGE_RUN_COMPLETED,
+	}, stages)
+
+	got, err := client.GetBackup(authContext(), &backupv1.GetRunRequest{Id: run.GetId()})
+	require.NoError(t, err)
+	assert.Equal(t, backupv1.RunStatus_RUN_STATUS_COMPLETED, got.GetStatus())
+	assert.Equal(t, int32(3), got.GetResourcesProcessed())
+}
+
+func TestToProto_RunProgress(t *testing.T) {
+	progress := &backup.RunProgress{
+		ItemsDone:       1200,
+		ItemsExpected:   4800,
+		Percent:         25,
+		ItemsPerSecond:  10,
+		ETASeconds:      360,
+		NamespacesDone:  3,
+		NamespacesTotal: 12,
+		EstimateSource:  backup.EstimatePrecount,
+	}
+
+	event := toProtoEvent(runEvent{RunID: "backup-1", ProgressEvent: backup.ProgressEvent{
+		Stage:     backup.ProgressRunProgress,
+		ItemCount: progress.ItemsDone,
+		Run:       progress,
+		Timestamp: time.Now(),
+	}})
+	assert.Equal(t, backupv1.ProgressStage_PROGRESS_STAGE_RUN_PROGRESS, event.GetStage())
+	assert.Equal(t, 25.0, event.GetProgress().GetPercent())
+	assert.Equal(t, 360.0, event.GetProgress().GetEtaSeconds())
+	assert.Equal(t, int64(4800), event.GetProgress().GetItemsExpected())
+
+	run := toProtoRun(&api.Run{ID: "backup-1", Status: api.RunStatusRunning, StartTime: time.Now(), Progress: progress})
+	assert.Equal(t, int32(12), run.GetProgress().GetNamespacesTotal())
+	assert.Equal(t, backup.EstimatePrecount, run.GetProgress().GetEstimateSource())
+	assert.Nil(t, toProtoRun(&api.Run{ID: "backup-2", StartTime: time.Now()}).GetProgress())
+}
+
+func TestServer_RestoreNotConfigured(t *testing.T) {
+	conn := startTestServer(t, nil, NewProgressHub())
+	client := backupv1.NewRestoreServiceClient(conn)
+
+	_, err := client.StartRestore(authContext(), &backupv1.StartRestoreRequest{BackupId: "b1"})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	_, err = client.GetRestore(authContext(), &backupv1.GetRunRequest{Id: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_StartRestore(t *testing.T) {
+	received := make(chan api.RestoreRequest, 1)
+	conn := startRestoreTestServer(t, nil, func(ctx context.Context, req api.RestoreRequest) (interface{}, error) {
+		received <- req
+		return nil, nil
+	}, api.NewRunRegistry(10), NewProgressHub())
+	client := backupv1.NewRestoreServiceClient(conn)
+
+	_, err := client.StartRestore(authContext(), &backupv1.StartRestoreRequest{
+		BackupId:          "b1",
+		TargetNamespaces:  []string{"kube-system"},
+		ForceUnsafe:       true,
+		ConfirmationToken: "0123456789abcdef",
+	})
+	require.NoError(t, err)
+	req := <-received
+	assert.Equal(t, "b1", req.BackupID)
+	assert.Equal(t, []string{"kube-system"}, req.TargetNamespaces)
+	assert.True(t, req.ForceUnsafe)
+	assert.Equal(t, "0123456789abcdef", req.ConfirmationToken)
+}
+
+func TestServer_RestoreProgressAndCancel(t *testing.T) {
+	runs := api.NewRunRegistry(10)
+	conn := startRestoreTestServer(t, nil, func(ctx context.Context, req api.RestoreRequest) (interface{}, error) {
+		api.RecordRestoreProgress(ctx, runs)(api.RestoreProgress{
+			Phase:           "resources",
+			Percent:         50,
+			ResourcesTotal:  8,
+			ResourcesDone:   4,
+			FailedResources: []api.FailedResource{{Resource: "configmaps/settings", Namespace: "shop", Error: "denied"}},
+		})
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, runs, NewProgressHub())
+	client := backupv1.NewRestoreServiceClient(conn)
+
+	run, err := client.StartRestore(authContext(), &backupv1.StartRestoreRequest{BackupId: "b1"})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		current, ok := runs.Get(run.GetId())
+		return ok && current.Progress != nil
+	}, time.Second, 10*time.Millisecond)
+
+	progress, err := client.GetRestoreProgress(authContext(), &backupv1.GetRunRequest{Id: run.GetId()})
+	require.NoError(t, err)
+	assert.Equal(t, backupv1.RunStatus_RUN_STATUS_RUNNING, progress.GetStatus())
+	assert.Equal(t, "resources", progress.GetPhase())
+	assert.Equal(t, 50.0, progress.GetPercent())
+	require.Len(t, progress.GetFailedResources(), 1)
+	assert.Equal(t, "shop", progress.GetFailedResources()[0].GetNamespace())
+
+	cancelled, err := client.CancelRestore(authContext(), &backupv1.GetRunRequest{Id: run.GetId()})
+	require.NoError(t, err)
+	assert.True(t, cancelled.GetCancelRequested())
+	require.Eventually(t, func() bool {
+		got, err := client.GetRestore(authContext(), &backupv1.GetRunRequest{Id: run.GetId()})
+		return err == nil && got.GetStatus() == backupv1.RunStatus_RUN_STATUS_CANCELLED
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = client.CancelRestore(authContext(), &backupv1.GetRunRequest{Id: run.GetId()})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = client.GetRestoreProgress(authContext(), &backupv1.GetRunRequest{Id: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,54 @@
+package hooks
+
+import (
+	"context"
+	"io"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// PodExecutor runs a command in a pod container
+type PodExecutor interface {
+	Exec(ctx context.Context, namespace, pod, container string, command []string, stdout, stderr io.Writer) error
+}
+
+// spdyExecutor execs through the API server's pods/exec subresource
+type spdyExecutor struct {
+	config     *rest.Config
+	kubeClient kubernetes.Interface
+}
+
+// NewPodExecutor creates an executor using the pods/exec subresource. The
+// service account needs the create verb on pods/exec.
+func NewPodExecutor(config *rest.Config, kubeClient kubernetes.Interface) PodExecutor {
+	return &spdyExecutor{config: config, kubeClient: kubeClient}
+}
+
+// Exec runs command and streams its output until it exits or ctx is done
+func (e *spdyExecutor) Exec(ctx context.Context, namespace, pod, container string, command []string, stdout, stderr io.Writer) error {
+	req := e.kubeClient.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(e.config, "POST", req.URL())
+	if err != nil {
+		return err
+	}
+
+	return executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+}
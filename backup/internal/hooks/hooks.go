@@ -0,0 +1,261 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"cluster-backup/internal/logging"
+)
+
+// Phase identifies when a hook runs relative to a namespace backup
+type Phase string
+
+const (
+	PhasePre  Phase = "pre"
+	PhasePost Phase = "post"
+)
+
+// OnError decides what a failed hook means for the namespace backup
+type OnError string
+
+const (
+	// OnErrorFail fails the namespace backup
+	OnErrorFail OnError = "Fail"
+	// OnErrorContinue logs the failure and carries on
+	OnErrorContinue OnError = "Continue"
+)
+
+// AnnotationPrefix is the prefix of all hook annotations. For each phase a pod
+// can set <prefix><phase>-hook-command, -container, -timeout and -on-error.
+const AnnotationPrefix = "backup.cluster/"
+
+// maxOutput caps how much hook output is kept for logs and errors
+const maxOutput = 1024
+
+// Hook is a command to exec in a pod container
+type Hook struct {
+	Phase     Phase
+	Namespace string
+	Pod       string
+	Container string
+	Command   []string
+	Timeout   time.Duration
+	OnError   OnError
+}
+
+// Result is the outcome of running one hook
+type Result struct {
+	Hook     Hook
+	Stdout   string
+	Stderr   string
+	Duration time.Duration
+	Err      error
+}
+
+// annotation returns the full annotation name for a phase and setting
+func annotation(phase Phase, setting string) string {
+	return AnnotationPrefix + string(phase) + "-hook-" + setting
+}
+
+// ParseHook reads the hook for phase from the pod's annotations.
+// It returns nil when the pod has no hook command for that phase.
+//
+// The command is either a JSON array, e.g. ["/bin/sh", "-c", "sync"], or a
+// plain string that is run with /bin/sh -c. The container defaults to the
+// pod's first container and the failure policy defaults to Fail.
+func ParseHook(pod *corev1.Pod, phase Phase, defaultTimeout time.Duration) (*Hook, error) {
+	raw := strings.TrimSpace(pod.Annotations[annotation(phase, "command")])
+	if raw == "" {
+		return nil, nil
+	}
+
+	hook := &Hook{
+		Phase:     phase,
+		Namespace: pod.Namespace,
+		Pod:       pod.Name,
+		Container: pod.Annotations[annotation(phase, "container")],
+		Timeout:   defaultTimeout,
+		OnError:   OnErrorFail,
+	}
+
+	if strings.HasPrefix(raw, "[") {
+		if err := json.Unmarshal([]byte(raw), &hook.Command); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", annotation(phase, "command"), err)
+		}
+		if len(hook.Command) == 0 {
+			return nil, fmt.Errorf("invalid %s: empty command", annotation(phase, "command"))
+		}
+	} else {
+		hook.Command = []string{"/bin/sh", "-c", raw}
+	}
+
+	if hook.Container == "" {
+		if len(pod.Spec.Containers) == 0 {
+			return nil, fmt.Errorf("pod has no containers")
+		}
+		hook.Container = pod.Spec.Containers[0].Name
+	} else if !hasContainer(pod, hook.Container) {
+		return nil, fmt.Errorf("container %s not found in pod", hook.Container)
+	}
+
+	if value := pod.Annotations[annotation(phase, "timeout")]; value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid %s: %q", annotation(phase, "timeout"), value)
+		}
+		hook.Timeout = timeout
+	}
+
+	if value := pod.Annotations[annotation(phase, "on-error")]; value != "" {
+		switch OnError(value) {
+		case OnErrorFail, OnErrorContinue:
+			hook.OnError = OnError(value)
+		default:
+			return nil, fmt.Errorf("invalid %s: %q (expected %s or %s)",
+				annotation(phase, "on-error"), value, OnErrorFail, OnErrorContinue)
+		}
+	}
+
+	return hook, nil
+}
+
+func hasContainer(pod *corev1.Pod, name string) bool {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Runner finds annotated pods in a namespace and runs their hooks
+type Runner struct {
+	kubeClient     kubernetes.Interface
+	executor       PodExecutor
+	defaultTimeout time.Duration
+	logger         *logging.StructuredLogger
+}
+
+// NewRunner creates a hook runner. defaultTimeout applies to hooks without a
+// timeout annotation.
+func NewRunner(kubeClient kubernetes.Interface, executor PodExecutor, defaultTimeout time.Duration, logger *logging.StructuredLogger) *Runner {
+	return &Runner{
+		kubeClient:     kubeClient,
+		executor:       executor,
+		defaultTimeout: defaultTimeout,
+		logger:         logger,
+	}
+}
+
+// Run executes the phase's hooks in every running pod of the namespace, in pod
+// name order. A failed hook with the Fail policy stops the remaining hooks and
+// is returned as the error; failures with the Continue policy are only logged.
+func (r *Runner) Run(ctx context.Context, namespace string, phase Phase) ([]Result, error) {
+	pods, err := r.kubeClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods for %s hooks: %v", phase, err)
+	}
+
+	items := pods.Items
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+
+	var results []Result
+	for i := range items {
+		pod := &items[i]
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+
+		hook, err := ParseHook(pod, phase, r.defaultTimeout)
+		if err != nil {
+			// A malformed hook cannot state its policy, so treat it as fatal
+			err = fmt.Errorf("pod %s/%s: %v", namespace, pod.Name, err)
+			results = append(results, Result{
+				Hook: Hook{Phase: phase, Namespace: namespace, Pod: pod.Name, OnError: OnErrorFail},
+				Err:  err,
+			})
+			r.logger.Error("hook_invalid", "Invalid backup hook annotations", map[string]interface{}{
+				"namespace": namespace,
+				"pod":       pod.Name,
+				"phase":     string(phase),
+				"error":     err.Error(),
+			})
+			return results, err
+		}
+		if hook == nil {
+			continue
+		}
+
+		result := r.execute(ctx, *hook)
+		results = append(results, result)
+		if result.Err != nil && hook.OnError == OnErrorFail {
+			return results, fmt.Errorf("%s hook failed in pod %s/%s: %v", phase, namespace, pod.Name, result.Err)
+		}
+	}
+
+	return results, nil
+}
+
+// execute runs a single hook bounded by its timeout
+func (r *Runner) execute(ctx context.Context, hook Hook) Result {
+	ctx, cancel := context.WithTimeout(ctx, hook.Timeout)
+	defer cancel()
+
+	fields := map[string]interface{}{
+		"namespace": hook.Namespace,
+		"pod":       hook.Pod,
+		"container": hook.Container,
+		"phase":     string(hook.Phase),
+		"command":   hook.Command,
+	}
+	r.logger.Info("hook_start", "Running backup hook", fields)
+
+	var stdout, stderr bytes.Buffer
+	start := time.Now()
+	err := r.executor.Exec(ctx, hook.Namespace, hook.Pod, hook.Container, hook.Command, &stdout, &stderr)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %v", hook.Timeout)
+	}
+
+	result := Result{
+		Hook:     hook,
+		Stdout:   truncate(stdout.String()),
+		Stderr:   truncate(stderr.String()),
+		Duration: time.Since(start),
+		Err:      err,
+	}
+
+	fields["duration_ms"] = result.Duration.Milliseconds()
+	if err != nil {
+		fields["error"] = err.Error()
+		fields["stderr"] = result.Stderr
+		fields["on_error"] = string(hook.OnError)
+		if hook.OnError == OnErrorContinue {
+			r.logger.Warning("hook_failed", "Backup hook failed, continuing", fields)
+		} else {
+			r.logger.Error("hook_failed", "Backup hook failed", fields)
+		}
+		return result
+	}
+
+	r.logger.Info("hook_complete", "Backup hook completed", fields)
+	return result
+}
+
+// truncate keeps the tail of hook output, where errors usually are
+func truncate(output string) string {
+	output = strings.TrimSpace(output)
+	if len(output) <= maxOutput {
+		return output
+	}
+	return "..." + output[len(output)-maxOutput:]
+}
@@ -0,0 +1,190 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"cluster-backup/internal/logging"
+)
+
+type execCall struct {
+	pod       string
+	container string
+	command   []string
+}
+
+// fakeExecutor records calls and fails for pods listed in failures
+type fakeExecutor struct {
+	calls    []execCall
+	failures map[string]error
+	block    bool
+}
+
+func (f *fakeExecutor) Exec(ctx context.Context, namespace, pod, container string, command []string, stdout, stderr io.Writer) error {
+	f.calls = append(f.calls, execCall{pod: pod, container: container, command: command})
+	if f.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if err := f.failures[pod]; err != nil {
+		fmt.Fprint(stderr, "lock failed")
+		return err
+	}
+	fmt.Fprint(stdout, "ok")
+	return nil
+}
+
+func newPod(name string, phase corev1.PodPhase, annotations map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop", Annotations: annotations},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "app"},
+			{Name: "sidecar"},
+		}},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func TestParseHook(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expected    *Hook
+		wantErr     bool
+	}{
+		{
+			name:        "no_hook",
+			annotations: map[string]string{"backup.cluster/post-hook-command": "true"},
+		},
+		{
+			name:        "shell_command_defaults",
+			annotations: map[string]string{"backup.cluster/pre-hook-command": "psql -c CHECKPOINT"},
+			expected: &Hook{
+				Phase: PhasePre, Namespace: "shop", Pod: "db-0", Container: "app",
+				Command: []string{"/bin/sh", "-c", "psql -c CHECKPOINT"},
+				Timeout: 30 * time.Second, OnError: OnErrorFail,
+			},
+		},
+		{
+			name: "json_command_with_settings",
+			annotations: map[string]string{
+				"backup.cluster/pre-hook-command":   `["fsfreeze", "--freeze", "/data"]`,
+				"backup.cluster/pre-hook-container": "sidecar",
+				"backup.cluster/pre-hook-timeout":   "2m",
+				"backup.cluster/pre-hook-on-error":  "Continue",
+			},
+			expected: &Hook{
+				Phase: PhasePre, Namespace: "shop", Pod: "db-0", Container: "sidecar",
+				Command: []string{"fsfreeze", "--freeze", "/data"},
+				Timeout: 2 * time.Minute, OnError: OnErrorContinue,
+			},
+		},
+		{
+			name:        "invalid_json",
+			annotations: map[string]string{"backup.cluster/pre-hook-command": `["unterminated`},
+			wantErr:     true,
+		},
+		{
+			name: "unknown_container",
+			annotations: map[string]string{
+				"backup.cluster/pre-hook-command":   "sync",
+				"backup.cluster/pre-hook-container": "missing",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid_on_error",
+			annotations: map[string]string{
+				"backup.cluster/pre-hook-command":  "sync",
+				"backup.cluster/pre-hook-on-error": "Ignore",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook, err := ParseHook(newPod("db-0", corev1.PodRunning, tt.annotations), PhasePre, 30*time.Second)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, hook)
+		})
+	}
+}
+
+func TestRunner_Run(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newPod("db-1", corev1.PodRunning, map[string]string{"backup.cluster/pre-hook-command": "lock"}),
+		newPod("db-0", corev1.PodRunning, map[string]string{"backup.cluster/pre-hook-command": "lock"}),
+		newPod("web", corev1.PodRunning, nil),
+		newPod("db-pending", corev1.PodPending, map[string]string{"backup.cluster/pre-hook-command": "lock"}),
+	)
+	executor := &fakeExecutor{}
+	runner := NewRunner(client, executor, time.Second, logging.NewStructuredLogger("test", "test-cluster"))
+
+	results, err := runner.Run(context.Background(), "shop", PhasePre)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "db-0", results[0].Hook.Pod)
+	assert.Equal(t, "db-1", results[1].Hook.Pod)
+	assert.Equal(t, "ok", results[0].Stdout)
+	require.Len(t, executor.calls, 2)
+	assert.Equal(t, "app", executor.calls[0].container)
+
+	results, err = runner.Run(context.Background(), "shop", PhasePost)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestRunner_FailurePolicies(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newPod("a-cache", corev1.PodRunning, map[string]string{
+			"backup.cluster/pre-hook-command":  "flush",
+			"backup.cluster/pre-hook-on-error": "Continue",
+		}),
+		newPod("b-db", corev1.PodRunning, map[string]string{"backup.cluster/pre-hook-command": "lock"}),
+		newPod("c-db", corev1.PodRunning, map[string]string{"backup.cluster/pre-hook-command": "lock"}),
+	)
+	executor := &fakeExecutor{failures: map[string]error{
+		"a-cache": fmt.Errorf("exit code 1"),
+		"b-db":    fmt.Errorf("exit code 2"),
+	}}
+	runner := NewRunner(client, executor, time.Second, logging.NewStructuredLogger("test", "test-cluster"))
+
+	results, err := runner.Run(context.Background(), "shop", PhasePre)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "b-db")
+
+	// The Continue failure is kept, the Fail failure stops before c-db
+	require.Len(t, results, 2)
+	assert.Error(t, results[0].Err)
+	assert.Equal(t, "lock failed", results[1].Stderr)
+	assert.Len(t, executor.calls, 2)
+}
+
+func TestRunner_Timeout(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newPod("db-0", corev1.PodRunning, map[string]string{
+			"backup.cluster/pre-hook-command": "lock",
+			"backup.cluster/pre-hook-timeout": "50ms",
+		}),
+	)
+	runner := NewRunner(client, &fakeExecutor{block: true}, time.Minute, logging.NewStructuredLogger("test", "test-cluster"))
+
+	results, err := runner.Run(context.Background(), "shop", PhasePre)
+	require.Error(t, err)
+	require.Len(t, results, 1)
+	assert.Contains(t, results[0].Err.Error(), "timed out after 50ms")
+}
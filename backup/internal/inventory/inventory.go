@@ -0,0 +1,145 @@
+// Package inventory declares what a cluster's backups are expected to hold,
+// namespaces and the least number of resources of each type in them, and
+// checks runs against it. A backup whose service account lost access to a
+// namespace or a resource type still succeeds, only emptier; the check turns
+// that into a warning or a failure.
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ConfigMapKey is the key of the expected inventory ConfigMap holding the
+// inventory
+const ConfigMapKey = "expected-inventory.yaml"
+
+// Config is the expected inventory
+type Config struct {
+	Namespaces []Namespace `yaml:"namespaces"`
+}
+
+// Namespace is a namespace every run is expected to back up
+type Namespace struct {
+	Name string `yaml:"name"`
+	// Resources are the least number of resources of each type the run
+	// stores of the namespace. Types are named by resource, such as
+	// deployments, or by resource and group, such as deployments.apps.
+	Resources map[string]int `yaml:"resources"`
+}
+
+// Parse reads and checks an expected inventory
+func Parse(data []byte) (*Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse expected inventory: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for i, namespace := range cfg.Namespaces {
+		if namespace.Name == "" {
+			return nil, fmt.Errorf("expected namespace %d has no name", i+1)
+		}
+		if seen[namespace.Name] {
+			return nil, fmt.Errorf("expected namespace %s is listed twice", namespace.Name)
+		}
+		seen[namespace.Name] = true
+		for resource, count := range namespace.Resources {
+			if resource == "" || count < 1 {
+				return nil, fmt.Errorf("expected namespace %s: %q needs a resource type and a count of at least 1", namespace.Name, resource)
+			}
+		}
+	}
+	return &cfg, nil
+}
+
+// Load reads the expected inventory ConfigMap
+func Load(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string) (*Config, error) {
+	configMap, err := kubeClient.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load expected inventory ConfigMap %s/%s: %v", namespace, name, err)
+	}
+	data, ok := configMap.Data[ConfigMapKey]
+	if !ok {
+		return nil, fmt.Errorf("%s not found in ConfigMap %s/%s", ConfigMapKey, namespace, name)
+	}
+	return Parse([]byte(data))
+}
+
+// Counts are the resources a run stored, by namespace and then by resource
+// type, under both the resource and the resource.group name. A namespace the
+// run backed up is present even when it stored nothing.
+type Counts map[string]map[string]int
+
+// Add counts a stored resource
+func (c Counts) Add(namespace, resource, group string) {
+	types := c[namespace]
+	if types == nil {
+		types = make(map[string]int)
+		c[namespace] = types
+	}
+	types[resource]++
+	if group != "" {
+		types[resource+"."+group]++
+	}
+}
+
+// Shortfall is an expectation a run did not meet. Resource is empty for a
+// namespace that was not backed up at all.
+type Shortfall struct {
+	Namespace string `json:"namespace"`
+	Resource  string `json:"resource,omitempty"`
+	Expected  int    `json:"expected,omitempty"`
+	Actual    int    `json:"actual"`
+}
+
+func (s Shortfall) String() string {
+	if s.Resource == "" {
+		return fmt.Sprintf("namespace %s was not backed up", s.Namespace)
+	}
+	return fmt.Sprintf("%s: %d %s, expected at least %d", s.Namespace, s.Actual, s.Resource, s.Expected)
+}
+
+// Check returns the expectations of cfg that counts fall short of, by
+// namespace and resource type
+func (cfg *Config) Check(counts Counts) []Shortfall {
+	var shortfalls []Shortfall
+	for _, namespace := range cfg.Namespaces {
+		types, ok := counts[namespace.Name]
+		if !ok {
+			shortfalls = append(shortfalls, Shortfall{Namespace: namespace.Name})
+			continue
+		}
+		resources := make([]string, 0, len(namespace.Resources))
+		for resource := range namespace.Resources {
+			resources = append(resources, resource)
+		}
+		sort.Strings(resources)
+		for _, resource := range resources {
+			if expected := namespace.Resources[resource]; types[resource] < expected {
+				shortfalls = append(shortfalls, Shortfall{
+					Namespace: namespace.Name,
+					Resource:  resource,
+					Expected:  expected,
+					Actual:    types[resource],
+				})
+			}
+		}
+	}
+	return shortfalls
+}
+
+// Summary joins shortfalls into one line
+func Summary(shortfalls []Shortfall) string {
+	lines := make([]string, len(shortfalls))
+	for i, shortfall := range shortfalls {
+		lines[i] = shortfall.String()
+	}
+	return strings.Join(lines, "; ")
+}
@@ -0,0 +1,82 @@
+package inventory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testInventory = `
+namespaces:
+  - name: shop
+    resources:
+      deployments.apps: 2
+      secrets: 1
+  - name: billing
+  - name: payments
+    resources:
+      configmaps: 1
+`
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse([]byte(testInventory))
+	require.NoError(t, err)
+	require.Len(t, cfg.Namespaces, 3)
+	assert.Equal(t, map[string]int{"deployments.apps": 2, "secrets": 1}, cfg.Namespaces[0].Resources)
+	assert.Empty(t, cfg.Namespaces[1].Resources)
+
+	invalid := map[string]string{
+		"no_name":   "namespaces: [{resources: {secrets: 1}}]",
+		"duplicate": "namespaces: [{name: shop}, {name: shop}]",
+		"zero":      "namespaces: [{name: shop, resources: {secrets: 0}}]",
+	}
+	for name, data := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse([]byte(data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	cfg, err := Parse([]byte(testInventory))
+	require.NoError(t, err)
+
+	counts := Counts{}
+	counts.Add("shop", "deployments", "apps")
+	counts.Add("shop", "secrets", "")
+	counts.Add("shop", "secrets", "")
+	counts["payments"] = map[string]int{}
+
+	shortfalls := cfg.Check(counts)
+	assert.Equal(t, []Shortfall{
+		{Namespace: "shop", Resource: "deployments.apps", Expected: 2, Actual: 1},
+		{Namespace: "billing"},
+		{Namespace: "payments", Resource: "configmaps", Expected: 1, Actual: 0},
+	}, shortfalls)
+	assert.Equal(t, "shop: 1 deployments.apps, expected at least 2; namespace billing was not backed up; payments: 0 configmaps, expected at least 1",
+		Summary(shortfalls))
+
+	counts.Add("shop", "deployments", "apps")
+	counts.Add("billing", "secrets", "")
+	counts.Add("payments", "configmaps", "")
+	assert.Empty(t, cfg.Check(counts))
+}
+
+func TestLoad(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup-inventory", Namespace: "backup-system"},
+		Data:       map[string]string{ConfigMapKey: testInventory},
+	})
+	cfg, err := Load(context.Background(), kubeClient, "backup-system", "backup-inventory")
+	require.NoError(t, err)
+	assert.Len(t, cfg.Namespaces, 3)
+
+	_, err = Load(context.Background(), kubeClient, "backup-system", "missing")
+	assert.ErrorContains(t, err, "failed to load expected inventory ConfigMap backup-system/missing")
+}
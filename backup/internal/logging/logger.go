@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"time"
+
+	"cluster-backup/internal/buildinfo"
 )
 
 // StructuredLogger provides structured logging capabilities
@@ -18,6 +20,8 @@ type LogEntry struct {
 	Timestamp   time.Time              `json:"timestamp"`
 	Level       string                 `json:"level"`
 	Service     string                 `json:"service"`
+	// Version of the binary that wrote the entry
+	Version     string                 `json:"version,omitempty"`
 	Cluster     string                 `json:"cluster"`
 	Operation   string                 `json:"operation"`
 	Message     string                 `json:"message"`
@@ -65,6 +69,7 @@ func (sl *StructuredLogger) log(level, operation, message string, data map[strin
 		Timestamp: time.Now().UTC(),
 		Level:     level,
 		Service:   sl.service,
+		Version:   buildinfo.Get().Version,
 		Cluster:   sl.clusterName,
 		Operation: operation,
 		Message:   message,
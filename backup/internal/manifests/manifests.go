@@ -0,0 +1,492 @@
+// Package manifests generates the Kubernetes manifests that deploy the backup
+// with the current configuration, hardened to what it needs: a ServiceAccount
+// bound to a ClusterRole of the permissions an RBAC check found, a pod that
+// passes the restricted Pod Security Standard, and a NetworkPolicy letting in
+// only the ports it serves and out only to the services it is configured to
+// reach.
+package manifests
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"cluster-backup/internal/buildinfo"
+	"cluster-backup/internal/compliance"
+	"cluster-backup/internal/config"
+)
+
+// Defaults of the options left empty
+const (
+	DefaultName     = "cluster-backup"
+	DefaultImage    = "cluster-backup:latest"
+	DefaultSchedule = "0 2 * * *"
+)
+
+// Options are the settings of the manifests that do not come from the
+// configuration
+type Options struct {
+	// Name of every object, DefaultName when empty
+	Name string
+	// Namespace the backup runs in
+	Namespace string
+	// Image of the backup, DefaultImage when empty
+	Image string
+	// Schedule of the CronJob of one-shot backups, DefaultSchedule when empty
+	Schedule string
+	// EnvSecret is the Secret the pod's environment is read from, so no
+	// setting or credential is written into the manifests; Name-env when empty
+	EnvSecret string
+	// Rules of the ClusterRole, usually from an RBAC check
+	Rules []rbacv1.PolicyRule
+	// APIServer lists the host:port addresses of the Kubernetes API server's
+	// endpoints. Network policies apply after the service address is
+	// translated, so egress is allowed to these rather than the service's
+	// address; when empty, to ports 443 and 6443 anywhere.
+	APIServer []string
+}
+
+// Generate returns the YAML documents of the ServiceAccount, ClusterRole and
+// its binding, the workload and the NetworkPolicy. The workload is a
+// Deployment, with a Service for its ports, when the backup runs as a
+// long-lived process (REST API or continuous backup), and a
+// CronJob of one-shot runs otherwise.
+func Generate(cfg *config.Config, opts Options) ([]byte, error) {
+	if opts.Namespace == "" {
+		return nil, fmt.Errorf("a namespace is required")
+	}
+	if opts.Name == "" {
+		opts.Name = DefaultName
+	}
+	if opts.Image == "" {
+		opts.Image = DefaultImage
+	}
+	if opts.Schedule == "" {
+		opts.Schedule = DefaultSchedule
+	}
+	if opts.EnvSecret == "" {
+		opts.EnvSecret = opts.Name + "-env"
+	}
+
+	labels := map[string]string{
+		"app.kubernetes.io/name":     "cluster-backup",
+		"app.kubernetes.io/instance": opts.Name,
+	}
+	meta := metav1.ObjectMeta{Name: opts.Name, Namespace: opts.Namespace, Labels: labels}
+	clusterMeta := metav1.ObjectMeta{Name: opts.Name, Labels: labels}
+	ports := servedPorts(cfg)
+
+	objects := []runtime.Object{
+		&corev1.ServiceAccount{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+			ObjectMeta: meta,
+		},
+		&rbacv1.ClusterRole{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+			ObjectMeta: clusterMeta,
+			Rules:      opts.Rules,
+		},
+		&rbacv1.ClusterRoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRoleBinding"},
+			ObjectMeta: clusterMeta,
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: opts.Name},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: opts.Name, Namespace: opts.Namespace}},
+		},
+	}
+
+	pod := podTemplate(cfg, opts, labels, ports)
+	if LongRunning(cfg) {
+		replicas := int32(1)
+		objects = append(objects, &appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+			ObjectMeta: meta,
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				// A single process at a time holds the informers and runs
+				Strategy: appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
+				Template: pod,
+			},
+		}, service(meta, labels, ports))
+	} else {
+		pod.Spec.RestartPolicy = corev1.RestartPolicyNever
+		backoffLimit := int32(1)
+		job := batchv1.JobSpec{BackoffLimit: &backoffLimit, Template: pod}
+		if cfg.BackupTimeout > 0 {
+			// The run stops itself at the timeout; the deadline only catches
+			// a pod that hangs past it and its grace period
+			deadline := int64((cfg.BackupTimeout + cfg.ShutdownGracePeriod).Seconds()) + 60
+			job.ActiveDeadlineSeconds = &deadline
+		}
+		objects = append(objects, &batchv1.CronJob{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "CronJob"},
+			ObjectMeta: meta,
+			Spec: batchv1.CronJobSpec{
+				Schedule:          opts.Schedule,
+				ConcurrencyPolicy: batchv1.ForbidConcurrent,
+				JobTemplate:       batchv1.JobTemplateSpec{Spec: job},
+			},
+		})
+	}
+	objects = append(objects, networkPolicy(cfg, opts, meta, labels, ports))
+
+	var out bytes.Buffer
+	for i, object := range objects {
+		data, err := toYAML(object)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			out.WriteString("---\n")
+		}
+		out.Write(data)
+	}
+	return out.Bytes(), nil
+}
+
+// LongRunning reports whether the backup runs as a long-lived process rather
+// than once per start
+func LongRunning(cfg *config.Config) bool {
+	return cfg.RestAPIEnabled || cfg.ContinuousBackup
+}
+
+// servedPorts returns the ports the backup listens on: metrics, health and
+// the dashboard, and the REST and gRPC APIs when enabled
+func servedPorts(cfg *config.Config) []corev1.ContainerPort {
+	ports := []corev1.ContainerPort{{Name: "metrics", ContainerPort: int32(cfg.MetricsPort), Protocol: corev1.ProtocolTCP}}
+	if cfg.RestAPIEnabled {
+		ports = append(ports,
+			corev1.ContainerPort{Name: "rest", ContainerPort: int32(cfg.APIPort), Protocol: corev1.ProtocolTCP},
+			corev1.ContainerPort{Name: "grpc", ContainerPort: int32(cfg.GRPCPort), Protocol: corev1.ProtocolTCP},
+		)
+	}
+	return ports
+}
+
+// podTemplate returns a pod of the restricted Pod Security Standard: not
+// root, no privilege escalation or capabilities, the runtime's seccomp
+// profile and a read-only root file system with a writable /tmp. No user ID
+// is set, so OpenShift can assign one from the namespace's range.
+func podTemplate(cfg *config.Config, opts Options, labels map[string]string, ports []corev1.ContainerPort) corev1.PodTemplateSpec {
+	yes, no := true, false
+	container := corev1.Container{
+		Name:  "backup",
+		Image: opts.Image,
+		EnvFrom: []corev1.EnvFromSource{{
+			SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: opts.EnvSecret}},
+		}},
+		// Manifests and logs record the image digest, which the downward API
+		// only exposes through an annotation of the pod
+		Env: []corev1.EnvVar{{
+			Name: buildinfo.ImageDigestEnv,
+			ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{
+				FieldPath: fmt.Sprintf("metadata.annotations['%s']", buildinfo.ImageDigestAnnotation),
+			}},
+		}},
+		Ports: ports,
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("100m"),
+				corev1.ResourceMemory: resource.MustParse("256Mi"),
+			},
+			Limits: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{{Name: "tmp", MountPath: "/tmp"}},
+		SecurityContext: &corev1.SecurityContext{
+			AllowPrivilegeEscalation: &no,
+			ReadOnlyRootFilesystem:   &yes,
+			RunAsNonRoot:             &yes,
+			Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+			SeccompProfile:           &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+		},
+	}
+	// The environment comes from the Secret, but the FIPS module must be on
+	// for the compliance self-check to pass
+	if compliance.Enabled(cfg) {
+		container.Env = append(container.Env, corev1.EnvVar{Name: "GODEBUG", Value: "fips140=on"})
+	}
+	if LongRunning(cfg) {
+		metrics := intstr.FromString("metrics")
+		container.ReadinessProbe = &corev1.Probe{
+			ProbeHandler:  corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/readyz", Port: metrics}},
+			PeriodSeconds: 10,
+		}
+		// With API_AUTH the health endpoints need a token, which probes do
+		// not have, so liveness is only checked by connecting
+		liveness := corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/healthz", Port: metrics}}
+		if cfg.APIAuthEnabled {
+			liveness = corev1.ProbeHandler{TCPSocket: &corev1.TCPSocketAction{Port: metrics}}
+		}
+		container.LivenessProbe = &corev1.Probe{ProbeHandler: liveness, PeriodSeconds: 30, FailureThreshold: 3}
+	}
+
+	// Only images pinned by digest name it; for tags the annotation can be
+	// set by whatever resolves them, such as an admission webhook
+	var annotations map[string]string
+	if _, digest, ok := strings.Cut(opts.Image, "@"); ok {
+		annotations = map[string]string{buildinfo.ImageDigestAnnotation: digest}
+	}
+
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: labels, Annotations: annotations},
+		Spec: corev1.PodSpec{
+			ServiceAccountName:           opts.Name,
+			AutomountServiceAccountToken: &yes,
+			SecurityContext: &corev1.PodSecurityContext{
+				RunAsNonRoot:   &yes,
+				SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+			},
+			Containers: []corev1.Container{container},
+			Volumes: []corev1.Volume{{
+				Name:         "tmp",
+				VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+			}},
+		},
+	}
+}
+
+func service(meta metav1.ObjectMeta, labels map[string]string, ports []corev1.ContainerPort) *corev1.Service {
+	svc := &corev1.Service{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: meta,
+		Spec:       corev1.ServiceSpec{Selector: labels},
+	}
+	for _, port := range ports {
+		svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{
+			Name:       port.Name,
+			Port:       port.ContainerPort,
+			TargetPort: intstr.FromString(port.Name),
+			Protocol:   corev1.ProtocolTCP,
+		})
+	}
+	return svc
+}
+
+// networkPolicy lets in connections to the served ports from anywhere, since
+// Prometheus and API clients can live in any namespace, and lets out DNS, the
+// Kubernetes API and the endpoints the configuration names
+func networkPolicy(cfg *config.Config, opts Options, meta metav1.ObjectMeta, labels map[string]string, ports []corev1.ContainerPort) *networkingv1.NetworkPolicy {
+	policy := &networkingv1.NetworkPolicy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "NetworkPolicy"},
+		ObjectMeta: meta,
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: labels},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+		},
+	}
+
+	ingress := networkingv1.NetworkPolicyIngressRule{}
+	for _, port := range ports {
+		ingress.Ports = append(ingress.Ports, policyPort(corev1.ProtocolTCP, int(port.ContainerPort)))
+	}
+	policy.Spec.Ingress = []networkingv1.NetworkPolicyIngressRule{ingress}
+
+	// DNS servers differ between distributions, so only the port is pinned
+	policy.Spec.Egress = []networkingv1.NetworkPolicyEgressRule{{
+		Ports: []networkingv1.NetworkPolicyPort{policyPort(corev1.ProtocolUDP, 53), policyPort(corev1.ProtocolTCP, 53)},
+	}}
+	apiServer := opts.APIServer
+	if len(apiServer) == 0 {
+		apiServer = []string{":443", ":6443"}
+	}
+	var rules []networkingv1.NetworkPolicyEgressRule
+	for _, endpoint := range append(apiServer, egressEndpoints(cfg)...) {
+		if rule, ok := egressRule(endpoint); ok {
+			rules = appendEgress(rules, rule)
+		}
+	}
+	policy.Spec.Egress = append(policy.Spec.Egress, rules...)
+	return policy
+}
+
+// egressEndpoints returns the host:port of every service the configuration
+// sends requests to, sorted
+func egressEndpoints(cfg *config.Config) []string {
+	seen := make(map[string]bool)
+	add := func(endpoint string, useSSL bool) {
+		if endpoint == "" {
+			return
+		}
+		if !strings.Contains(endpoint, "://") {
+			scheme := "http"
+			if useSSL {
+				scheme = "https"
+			}
+			endpoint = scheme + "://" + endpoint
+		}
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Hostname() == "" {
+			return
+		}
+		port := u.Port()
+		if port == "" {
+			port = "80"
+			if u.Scheme == "https" || u.Scheme == "tls" {
+				port = "443"
+			}
+		}
+		seen[net.JoinHostPort(u.Hostname(), port)] = true
+	}
+
+	add(cfg.MinIOEndpoint, cfg.MinIOUseSSL)
+	for _, target := range cfg.SecondaryTargets {
+		add(target.Endpoint, target.UseSSL)
+	}
+	add(cfg.ReplicaEndpoint, cfg.ReplicaUseSSL)
+	add(cfg.StorageSTSEndpoint, true)
+	add(cfg.VaultAddress, true)
+	add(cfg.PolicyURL, false)
+	add(cfg.AlertmanagerURL, false)
+	add(cfg.EventBusURL, false)
+	add(cfg.AuditWebhookURL, true)
+	add(cfg.APIOIDCIssuer, true)
+	if cfg.ManifestSigning == config.ManifestSigningKeyless {
+		add(cfg.SigstoreFulcioURL, true)
+		add(cfg.SigstoreRekorURL, true)
+	}
+
+	endpoints := make([]string, 0, len(seen))
+	for endpoint := range seen {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	return endpoints
+}
+
+// egressRule allows connections to endpoint: to the address itself when it
+// is an IP, to its namespace when it is a cluster service name such as
+// minio.storage.svc, and to its port anywhere otherwise, since policies
+// cannot match DNS names
+func egressRule(endpoint string) (networkingv1.NetworkPolicyEgressRule, bool) {
+	host, portText, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return networkingv1.NetworkPolicyEgressRule{}, false
+	}
+	port, err := strconv.Atoi(portText)
+	if err != nil {
+		return networkingv1.NetworkPolicyEgressRule{}, false
+	}
+	rule := networkingv1.NetworkPolicyEgressRule{
+		Ports: []networkingv1.NetworkPolicyPort{policyPort(corev1.ProtocolTCP, port)},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		bits := 32
+		if ip.To4() == nil {
+			bits = 128
+		}
+		rule.To = []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: fmt.Sprintf("%s/%d", ip, bits)}}}
+	} else if namespace := serviceNamespace(host); namespace != "" {
+		rule.To = []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"kubernetes.io/metadata.name": namespace},
+		}}}
+	}
+	return rule, true
+}
+
+// serviceNamespace returns the namespace of a cluster service name of the
+// form name.namespace.svc[.cluster-domain], or "" for other hosts
+func serviceNamespace(host string) string {
+	labels := strings.Split(host, ".")
+	if len(labels) >= 3 && labels[2] == "svc" {
+		return labels[1]
+	}
+	return ""
+}
+
+// appendEgress adds rule, merged into a rule with the same peers if any
+func appendEgress(rules []networkingv1.NetworkPolicyEgressRule, rule networkingv1.NetworkPolicyEgressRule) []networkingv1.NetworkPolicyEgressRule {
+	for i := range rules {
+		if peersEqual(rules[i].To, rule.To) {
+			for _, port := range rule.Ports {
+				if !containsPort(rules[i].Ports, port) {
+					rules[i].Ports = append(rules[i].Ports, port)
+				}
+			}
+			return rules
+		}
+	}
+	return append(rules, rule)
+}
+
+func peersEqual(a, b []networkingv1.NetworkPolicyPeer) bool {
+	return fmt.Sprint(peerKeys(a)) == fmt.Sprint(peerKeys(b))
+}
+
+func peerKeys(peers []networkingv1.NetworkPolicyPeer) []string {
+	keys := make([]string, 0, len(peers))
+	for _, peer := range peers {
+		switch {
+		case peer.IPBlock != nil:
+			keys = append(keys, "ip:"+peer.IPBlock.CIDR)
+		case peer.NamespaceSelector != nil:
+			keys = append(keys, "namespace:"+peer.NamespaceSelector.MatchLabels["kubernetes.io/metadata.name"])
+		}
+	}
+	return keys
+}
+
+func containsPort(ports []networkingv1.NetworkPolicyPort, port networkingv1.NetworkPolicyPort) bool {
+	for _, p := range ports {
+		if *p.Protocol == *port.Protocol && p.Port.String() == port.Port.String() {
+			return true
+		}
+	}
+	return false
+}
+
+func policyPort(protocol corev1.Protocol, port int) networkingv1.NetworkPolicyPort {
+	number := intstr.FromInt32(int32(port))
+	return networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &number}
+}
+
+// toYAML renders object with the API field names, through its unstructured
+// form, leaving out the unset timestamps and statuses the types carry
+func toYAML(object runtime.Object) ([]byte, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert %s: %v", object.GetObjectKind().GroupVersionKind().Kind, err)
+	}
+	delete(content, "status")
+	prune(content)
+	return yaml.Marshal(content)
+}
+
+// prune removes null values, such as unset creation timestamps, throughout
+// content, and the empty objects types leave behind for unset structs that
+// are not meaningful when empty
+func prune(content map[string]interface{}) {
+	for key, value := range content {
+		switch value := value.(type) {
+		case nil:
+			delete(content, key)
+		case map[string]interface{}:
+			prune(value)
+			if len(value) == 0 && key != "emptyDir" {
+				delete(content, key)
+			}
+		case []interface{}:
+			for _, item := range value {
+				if item, ok := item.(map[string]interface{}); ok {
+					prune(item)
+				}
+			}
+		}
+	}
+}
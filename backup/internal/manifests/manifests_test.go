@@ -0,0 +1,171 @@
+package manifests
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+	rbacv1 "k8s.io/api/rbac/v1"
+
+	"cluster-backup/internal/config"
+)
+
+// documents returns the YAML documents of data by kind
+func documents(t *testing.T, data []byte) map[string]map[string]interface{} {
+	docs := make(map[string]map[string]interface{})
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc map[string]interface{}
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return docs
+		}
+		require.NoError(t, err)
+		docs[doc["kind"].(string)] = doc
+	}
+}
+
+// field returns the value at path in doc, indexing lists with ints
+func field(t *testing.T, doc interface{}, path ...interface{}) interface{} {
+	for _, key := range path {
+		switch key := key.(type) {
+		case string:
+			m, ok := doc.(map[string]interface{})
+			require.True(t, ok, "%v is not an object", key)
+			doc = m[key]
+		case int:
+			l, ok := doc.([]interface{})
+			require.True(t, ok, "%v is not a list", key)
+			require.Greater(t, len(l), key)
+			doc = l[key]
+		}
+	}
+	return doc
+}
+
+func testConfig() *config.Config {
+	return &config.Config{
+		MetricsPort:         8080,
+		APIPort:             8081,
+		GRPCPort:            9090,
+		MinIOEndpoint:       "minio.storage.svc.cluster.local:9000",
+		AlertmanagerURL:     "http://10.0.0.5:9093",
+		VaultAddress:        "https://vault.example.com",
+		BackupTimeout:       time.Hour,
+		ShutdownGracePeriod: 30 * time.Second,
+	}
+}
+
+func TestGenerate_CronJob(t *testing.T) {
+	rules := []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get", "list"}}}
+	data, err := Generate(testConfig(), Options{Namespace: "backup", Rules: rules, APIServer: []string{"172.18.0.2:6443"}})
+	require.NoError(t, err)
+	docs := documents(t, data)
+
+	assert.Contains(t, docs, "ServiceAccount")
+	assert.Equal(t, "configmaps", field(t, docs["ClusterRole"], "rules", 0, "resources", 0))
+	assert.Equal(t, "backup", field(t, docs["ClusterRoleBinding"], "subjects", 0, "namespace"))
+	assert.NotContains(t, docs, "Deployment")
+	assert.NotContains(t, docs, "Service")
+
+	cronJob := docs["CronJob"]
+	require.NotNil(t, cronJob)
+	assert.Equal(t, DefaultSchedule, field(t, cronJob, "spec", "schedule"))
+	assert.Equal(t, "Forbid", field(t, cronJob, "spec", "concurrencyPolicy"))
+	assert.Equal(t, 3690, field(t, cronJob, "spec", "jobTemplate", "spec", "activeDeadlineSeconds"))
+	assert.NotContains(t, cronJob["metadata"], "creationTimestamp")
+
+	pod := field(t, cronJob, "spec", "jobTemplate", "spec", "template", "spec")
+	assert.Equal(t, "Never", field(t, pod, "restartPolicy"))
+	assert.Equal(t, "cluster-backup", field(t, pod, "serviceAccountName"))
+	assert.Equal(t, true, field(t, pod, "securityContext", "runAsNonRoot"))
+	container := field(t, pod, "containers", 0)
+	assert.Equal(t, DefaultImage, field(t, container, "image"))
+	assert.Equal(t, "cluster-backup-env", field(t, container, "envFrom", 0, "secretRef", "name"))
+	assert.Equal(t, false, field(t, container, "securityContext", "allowPrivilegeEscalation"))
+	assert.Equal(t, true, field(t, container, "securityContext", "readOnlyRootFilesystem"))
+	assert.Equal(t, "ALL", field(t, container, "securityContext", "capabilities", "drop", 0))
+	assert.Equal(t, "RuntimeDefault", field(t, container, "securityContext", "seccompProfile", "type"))
+	assert.Equal(t, map[string]interface{}{}, field(t, pod, "volumes", 0, "emptyDir"))
+	assert.Len(t, field(t, container, "ports"), 1)
+	assert.Nil(t, field(t, container, "livenessProbe"), "one-shot runs are not probed")
+	assert.Equal(t, "IMAGE_DIGEST", field(t, container, "env", 0, "name"))
+	assert.Equal(t, "metadata.annotations['cluster-backup.io/image-digest']", field(t, container, "env", 0, "valueFrom", "fieldRef", "fieldPath"))
+	assert.Len(t, field(t, container, "env"), 1)
+	assert.Nil(t, field(t, cronJob, "spec", "jobTemplate", "spec", "template", "metadata", "annotations"), "tags do not name a digest")
+}
+
+func TestGenerate_ComplianceMode(t *testing.T) {
+	cfg := testConfig()
+	cfg.ComplianceMode = config.ComplianceModeFIPS
+	data, err := Generate(cfg, Options{Namespace: "backup"})
+	require.NoError(t, err)
+
+	container := field(t, documents(t, data)["CronJob"], "spec", "jobTemplate", "spec", "template", "spec", "containers", 0)
+	assert.Equal(t, "GODEBUG", field(t, container, "env", 1, "name"))
+	assert.Equal(t, "fips140=on", field(t, container, "env", 1, "value"))
+}
+
+func TestGenerate_NetworkPolicy(t *testing.T) {
+	data, err := Generate(testConfig(), Options{Namespace: "backup", APIServer: []string{"172.18.0.2:6443"}})
+	require.NoError(t, err)
+	policy := documents(t, data)["NetworkPolicy"]
+	require.NotNil(t, policy)
+
+	assert.Equal(t, 8080, field(t, policy, "spec", "ingress", 0, "ports", 0, "port"))
+	egress := field(t, policy, "spec", "egress").([]interface{})
+	require.Len(t, egress, 5)
+	assert.Equal(t, 53, field(t, egress[0], "ports", 0, "port"))
+	assert.Nil(t, field(t, egress[0], "to"), "DNS servers are not pinned")
+	assert.Equal(t, "172.18.0.2/32", field(t, egress[1], "to", 0, "ipBlock", "cidr"))
+	assert.Equal(t, 6443, field(t, egress[1], "ports", 0, "port"))
+	assert.Equal(t, "10.0.0.5/32", field(t, egress[2], "to", 0, "ipBlock", "cidr"))
+	assert.Equal(t, 9093, field(t, egress[2], "ports", 0, "port"))
+	assert.Equal(t, "storage", field(t, egress[3], "to", 0, "namespaceSelector", "matchLabels", "kubernetes.io/metadata.name"))
+	assert.Equal(t, 9000, field(t, egress[3], "ports", 0, "port"))
+	assert.Nil(t, field(t, egress[4], "to"), "external names are only matched by port")
+	assert.Equal(t, 443, field(t, egress[4], "ports", 0, "port"))
+
+	// Without the API server's endpoints its usual ports are allowed anywhere
+	data, err = Generate(testConfig(), Options{Namespace: "backup"})
+	require.NoError(t, err)
+	egress = field(t, documents(t, data)["NetworkPolicy"], "spec", "egress").([]interface{})
+	assert.Nil(t, field(t, egress[1], "to"))
+	assert.Len(t, field(t, egress[1], "ports"), 2)
+}
+
+func TestGenerate_Deployment(t *testing.T) {
+	cfg := testConfig()
+	cfg.RestAPIEnabled = true
+	cfg.APIAuthEnabled = true
+	data, err := Generate(cfg, Options{Name: "backup", Namespace: "ops", Image: "registry.example.com/backup:v2"})
+	require.NoError(t, err)
+	docs := documents(t, data)
+
+	assert.NotContains(t, docs, "CronJob")
+	deployment := docs["Deployment"]
+	require.NotNil(t, deployment)
+	assert.Equal(t, "Recreate", field(t, deployment, "spec", "strategy", "type"))
+	assert.NotContains(t, deployment, "status")
+	container := field(t, deployment, "spec", "template", "spec", "containers", 0)
+	assert.Equal(t, "registry.example.com/backup:v2", field(t, container, "image"))
+	assert.Len(t, field(t, container, "ports"), 3)
+	assert.Equal(t, "/readyz", field(t, container, "readinessProbe", "httpGet", "path"))
+	assert.Equal(t, "metrics", field(t, container, "livenessProbe", "tcpSocket", "port"), "health needs a token with API_AUTH")
+
+	assert.Len(t, field(t, docs["Service"], "spec", "ports"), 3)
+	assert.Len(t, field(t, docs["NetworkPolicy"], "spec", "ingress", 0, "ports"), 3)
+
+	_, err = Generate(cfg, Options{})
+	assert.Error(t, err)
+
+	data, err = Generate(cfg, Options{Namespace: "ops", Image: "registry.example.com/backup@sha256:0123abcd"})
+	require.NoError(t, err)
+	annotations := field(t, documents(t, data)["Deployment"], "spec", "template", "metadata", "annotations")
+	assert.Equal(t, map[string]interface{}{"cluster-backup.io/image-digest": "sha256:0123abcd"}, annotations)
+}
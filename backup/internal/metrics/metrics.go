@@ -12,6 +12,45 @@ type BackupMetrics struct {
 	ResourcesBackedUp  prometheus.Counter
 	LastBackupTime     prometheus.Gauge
 	NamespacesBackedUp prometheus.Gauge
+	ClusterResources   prometheus.Gauge
+	CRDs               prometheus.Gauge
+	BlobsReused        prometheus.Counter
+	BlobsCollected     prometheus.Counter
+	DriftedResources   *prometheus.GaugeVec
+	HookExecutions     *prometheus.CounterVec
+	DatabaseDumps      *prometheus.CounterVec
+	EtcdSnapshotBytes  prometheus.Gauge
+	LockConflicts      prometheus.Counter
+	CircuitState       *prometheus.GaugeVec
+	ProgressRatio      prometheus.Gauge
+	ItemsPerSecond     prometheus.Gauge
+	ETASeconds         prometheus.Gauge
+	PolicyViolations   *prometheus.CounterVec
+	PolicyErrors       prometheus.Counter
+	SecretFindings     *prometheus.CounterVec
+	FailureStreak      prometheus.Gauge
+	LastRunResources   prometheus.Gauge
+	CleanupErrors      prometheus.Gauge
+	RPOAge             *prometheus.GaugeVec
+	RPOViolation       *prometheus.GaugeVec
+	RPOTarget          prometheus.Gauge
+	ReplicationLag     prometheus.Gauge
+	ReplicationErrors  prometheus.Gauge
+	SecondaryFailures  *prometheus.CounterVec
+	ConfigInfo         *prometheus.GaugeVec
+	ConfigReloads      *prometheus.CounterVec
+	AdaptiveWorkers    prometheus.Gauge
+	AdaptivePageSize   prometheus.Gauge
+	Failures           *prometheus.CounterVec
+	InventoryShortfall prometheus.Gauge
+	WindowObjects      prometheus.Counter
+	LiveObjects        *prometheus.CounterVec
+	LiveSnapshot       prometheus.Gauge
+	AuditFailures      prometheus.Counter
+	// Objects restored by synthetic validation, by result, and the objects of
+	// the latest validated run that came back different from their copies
+	SyntheticValidationObjects    *prometheus.CounterVec
+	SyntheticValidationMismatches prometheus.Gauge
 }
 
 // NewBackupMetrics creates a new set of backup metrics
@@ -38,6 +77,154 @@ func NewBackupMetrics() *BackupMetrics {
 			Name: "cluster_backup_namespaces_total",
 			Help: "Number of namespaces backed up in the last operation",
 		}),
+		ClusterResources: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "cluster_backup_cluster_resources_total",
+			Help: "Number of cluster-scoped resources backed up in the last operation",
+		}),
+		CRDs: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "cluster_backup_crds_total",
+			Help: "Number of CustomResourceDefinitions captured in the last operation",
+		}),
+		BlobsReused: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "cluster_backup_blobs_reused_total",
+			Help: "Resources whose content was already stored and not uploaded again",
+		}),
+		BlobsCollected: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "cluster_backup_blobs_collected_total",
+			Help: "Unreferenced content-addressed blobs removed by garbage collection",
+		}),
+		DriftedResources: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cluster_backup_drifted_resources",
+			Help: "Resources that differ between the live cluster and the latest backup, by change type",
+		}, []string{"change"}),
+		HookExecutions: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "cluster_backup_hook_executions_total",
+			Help: "Pre/post backup hook executions by phase and result",
+		}, []string{"phase", "result"}),
+		DatabaseDumps: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "cluster_backup_database_dumps_total",
+			Help: "Database dumps uploaded by dump plugin and result",
+		}, []string{"plugin", "result"}),
+		EtcdSnapshotBytes: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "cluster_backup_etcd_snapshot_bytes",
+			Help: "Size of the last uploaded etcd snapshot",
+		}),
+		LockConflicts: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "cluster_backup_lock_conflicts_total",
+			Help: "Backup runs refused because another run held the cluster's lock",
+		}),
+		CircuitState: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cluster_backup_circuit_state",
+			Help: "Circuit breaker state by breaker: 0 closed, 1 open, 2 half-open",
+		}, []string{"breaker"}),
+		ProgressRatio: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "cluster_backup_progress_ratio",
+			Help: "Fraction of the expected items the running backup has stored, 0 when no estimate is known",
+		}),
+		ItemsPerSecond: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "cluster_backup_items_per_second",
+			Help: "Rate at which the running backup stores items",
+		}),
+		ETASeconds: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "cluster_backup_eta_seconds",
+			Help: "Estimated seconds until the running backup finishes, 0 when unknown or done",
+		}),
+		PolicyViolations: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "cluster_backup_policy_violations_total",
+			Help: "Total number of policy violations found in backed-up resources, by policy",
+		}, []string{"policy"}),
+		PolicyErrors: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "cluster_backup_policy_evaluation_errors_total",
+			Help: "Total number of resources that could not be checked against the policies",
+		}),
+		SecretFindings: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "cluster_backup_secret_findings_total",
+			Help: "Total number of suspected credentials found outside Secrets, by detector",
+		}, []string{"detector"}),
+		FailureStreak: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "cluster_backup_consecutive_failures",
+			Help: "Number of backup runs in a row that failed, 0 after a successful run",
+		}),
+		LastRunResources: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "cluster_backup_last_run_resources",
+			Help: "Number of resources the last finished backup run stored",
+		}),
+		CleanupErrors: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "cluster_backup_cleanup_errors",
+			Help: "Number of errors in the last cleanup operation",
+		}),
+		RPOAge: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cluster_backup_seconds_since_last_success",
+			Help: "Seconds since the last successful backup of the cluster, namespace empty, or of a namespace; +Inf when there is none",
+		}, []string{"cluster", "namespace"}),
+		RPOViolation: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cluster_backup_rpo_violation",
+			Help: "1 when the last successful backup of the cluster or namespace is older than the RPO target, 0 otherwise",
+		}, []string{"cluster", "namespace"}),
+		RPOTarget: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "cluster_backup_rpo_target_seconds",
+			Help: "Recovery point objective the last successful backups are checked against",
+		}),
+		ReplicationLag: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "cluster_backup_replication_lag_seconds",
+			Help: "Seconds the latest finished run has been waiting to be found intact in the replica bucket, 0 once it is",
+		}),
+		ReplicationErrors: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "cluster_backup_replication_errors",
+			Help: "Number of objects of the latest finished run whose replica could not be read or does not match its checksum",
+		}),
+		SecondaryFailures: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "cluster_backup_secondary_upload_failures_total",
+			Help: "Uploads to a secondary storage target that failed",
+		}, []string{"target"}),
+		ConfigInfo: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cluster_backup_config_info",
+			Help: "1 for the version, a hash of the settings, of the configuration the next run uses",
+		}, []string{"version"}),
+		ConfigReloads: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "cluster_backup_config_reloads_total",
+			Help: "Reloads of the shared configuration file, by result: applied, or rejected when invalid",
+		}, []string{"result"}),
+		AdaptiveWorkers: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "cluster_backup_adaptive_workers",
+			Help: "Number of resource types the running backup lists at once under adaptive concurrency",
+		}),
+		AdaptivePageSize: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "cluster_backup_adaptive_page_size",
+			Help: "Page size the running backup lists resources with under adaptive concurrency",
+		}),
+		Failures: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "cluster_backup_failures_total",
+			Help: "Resource types and phases of backup runs that failed, by category: storage, throttled, invalid, permission or unknown",
+		}, []string{"category"}),
+		InventoryShortfall: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "cluster_backup_inventory_shortfalls",
+			Help: "Number of expectations of the expected inventory the latest finished run fell short of",
+		}),
+		WindowObjects: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "cluster_backup_window_objects_total",
+			Help: "Resources created or updated while a backup ran and stored at its end under window capture",
+		}),
+		LiveObjects: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "cluster_backup_live_objects_total",
+			Help: "Live copies stored or deleted under continuous backup",
+		}, []string{"operation"}),
+		LiveSnapshot: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "cluster_backup_live_snapshot_timestamp",
+			Help: "Unix timestamp of the latest snapshot consolidated from the live copies",
+		}),
+		AuditFailures: promauto.NewCounter(prometheus.CounterOpts{
+			Name: "cluster_backup_audit_write_failures_total",
+			Help: "Audit records a sink could not store",
+		}),
+		SyntheticValidationObjects: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "cluster_backup_synthetic_validation_objects_total",
+			Help: "Stored objects restored into a validation namespace, by result: matched, mismatched, failed or skipped",
+		}, []string{"result"}),
+		SyntheticValidationMismatches: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "cluster_backup_synthetic_validation_mismatches",
+			Help: "Number of sampled objects of the latest validated run that could not be restored or came back different from the stored copy",
+		}),
 	}
 }
 
@@ -3,24 +3,34 @@ package orchestrator
 import (
 	"context"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/prometheus/client_golang/prometheus"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
+	"cluster-backup/internal/alerting"
 	"cluster-backup/internal/backup"
+	"cluster-backup/internal/cleaning"
 	"cluster-backup/internal/cleanup"
 	"cluster-backup/internal/cluster"
 	"cluster-backup/internal/config"
+	"cluster-backup/internal/inventory"
 	"cluster-backup/internal/logging"
 	"cluster-backup/internal/metrics"
 	"cluster-backup/internal/priority"
 	"cluster-backup/internal/resilience"
 	"cluster-backup/internal/server"
+	"cluster-backup/internal/storage"
+	"cluster-backup/internal/tenancy"
+	"cluster-backup/internal/validation"
+
+	sharedconfig "shared-config/config"
+	"shared-config/plugins"
 )
 
 // BackupOrchestrator coordinates all backup-related operations
@@ -45,6 +55,8 @@ type BackupOrchestrator struct {
 	backupManager   *backup.ClusterBackup
 	cleanupManager  *cleanup.Manager
 	metricsManager  *metrics.BackupMetrics
+	healthMetrics   *validation.Metrics
+	healthChecks    *validation.Config
 	metricsServer   *server.MetricsServer
 	
 	// Resilience components
@@ -56,16 +68,22 @@ type BackupOrchestrator struct {
 // OrchestratorConfig holds configuration for the orchestrator
 type OrchestratorConfig struct {
 	MetricsPort        int
-	ContextTimeout     time.Duration
 	EnableMetricsServer bool
+	// Shared configuration the backup settings are read from, with
+	// environment variables overriding it; nil reads the shared file, if any
+	SharedConfig *sharedconfig.SharedConfig
+	// HealthChecksFile is the check configuration CheckClusterHealth applies,
+	// VALIDATION_CONFIG by default as for backup-util cluster-health; empty
+	// runs the built-in checks with their defaults
+	HealthChecksFile string
 }
 
 // DefaultOrchestratorConfig returns sensible defaults
 func DefaultOrchestratorConfig() *OrchestratorConfig {
 	return &OrchestratorConfig{
 		MetricsPort:         8080,
-		ContextTimeout:      30 * time.Minute,
 		EnableMetricsServer: true,
+		HealthChecksFile:    os.Getenv("VALIDATION_CONFIG"),
 	}
 }
 
@@ -76,19 +94,33 @@ func NewBackupOrchestrator(orchestratorConfig *OrchestratorConfig) (*BackupOrche
 	}
 	
 	// Load configuration
-	cfg, err := config.LoadConfig()
+	shared := orchestratorConfig.SharedConfig
+	if shared == nil {
+		var err error
+		if shared, err = config.LoadSharedConfig(); err != nil {
+			return nil, fmt.Errorf("failed to load config: %v", err)
+		}
+	}
+	cfg, err := config.LoadConfigFrom(shared)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config: %v", err)
 	}
 	
-	backupCfg, err := config.LoadBackupConfig()
+	backupCfg, err := config.LoadBackupConfigFrom(shared)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load backup config: %v", err)
 	}
 	
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), orchestratorConfig.ContextTimeout)
-	_ = cancel // Keep the cancel function available if needed later
+	var healthChecks *validation.Config
+	if orchestratorConfig.HealthChecksFile != "" {
+		if healthChecks, err = validation.LoadConfig(orchestratorConfig.HealthChecksFile); err != nil {
+			return nil, err
+		}
+	}
+	
+	// Backup runs and cleanup bound themselves with BACKUP_TIMEOUT and
+	// BACKUP_CLEANUP_TIMEOUT
+	ctx := context.Background()
 	
 	// Initialize logger
 	logger := logging.NewStructuredLogger("backup-orchestrator", cfg.ClusterName)
@@ -100,7 +132,11 @@ func NewBackupOrchestrator(orchestratorConfig *OrchestratorConfig) (*BackupOrche
 	}
 	
 	// Create MinIO client
-	minioClient, err := createMinIOClient(cfg)
+	transport, err := storage.NewTransport(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MinIO client: %v", err)
+	}
+	minioClient, err := storage.NewClient(cfg, transport)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create MinIO client: %v", err)
 	}
@@ -110,7 +146,7 @@ func NewBackupOrchestrator(orchestratorConfig *OrchestratorConfig) (*BackupOrche
 	updateConfigWithDetectedValues(cfg, clusterDetector)
 	
 	// Create specialized managers
-	priorityManager := priority.NewManager(kubeClient, "backup-priority-config", "default")
+	priorityManager := priority.NewManager(kubeClient, priority.DefaultConfigMap, priority.DefaultNamespace)
 	metricsManager := metrics.NewBackupMetrics()
 	
 	backupManager := backup.NewClusterBackup(
@@ -126,6 +162,49 @@ func NewBackupOrchestrator(orchestratorConfig *OrchestratorConfig) (*BackupOrche
 	)
 	
 	cleanupManager := cleanup.NewManager(cfg, minioClient, logger, metricsManager, ctx)
+	cleanupManager.SetRetentionOverrides(kubeClient, priorityManager)
+	backupManager.SetBatchSizes(priorityManager)
+	if alerter := alerting.NewFromConfig(cfg); alerter != nil {
+		backupManager.SetAlerter(alerter)
+		cleanupManager.SetAlerter(alerter)
+	}
+	if cfg.TenancyConfigMap != "" {
+		router, err := tenancy.Load(ctx, kubeClient, cfg.TenancyNamespace, cfg.TenancyConfigMap, cfg, transport,
+			&tenancy.Target{Client: minioClient, Bucket: cfg.MinIOBucket})
+		if err != nil {
+			return nil, err
+		}
+		backupManager.SetTenancy(router)
+		cleanupManager.SetTenancy(router)
+	}
+	if cfg.CleaningProfilesConfigMap != "" {
+		profiles, err := cleaning.Load(ctx, kubeClient, cfg.CleaningProfilesNamespace, cfg.CleaningProfilesConfigMap)
+		if err != nil {
+			return nil, err
+		}
+		backupManager.SetCleaningProfiles(profiles)
+	}
+	if cfg.InventoryConfigMap != "" {
+		expected, err := inventory.Load(ctx, kubeClient, cfg.InventoryNamespace, cfg.InventoryConfigMap)
+		if err != nil {
+			return nil, err
+		}
+		backupManager.SetInventory(expected)
+	}
+	if len(cfg.ResourcePlugins) > 0 {
+		chain, err := plugins.Open(cfg.ResourcePlugins, cfg.ResourcePluginOptions)
+		if err != nil {
+			return nil, err
+		}
+		backupManager.SetPlugins(chain)
+	}
+	for _, target := range cfg.SecondaryTargets {
+		client, err := storage.NewTargetClient(cfg, target, transport)
+		if err != nil {
+			return nil, err
+		}
+		backupManager.AddSecondaryTarget(target.Name, client, target.Bucket)
+	}
 	
 	// Create resilience components
 	minioCircuitBreaker := resilience.NewCircuitBreaker(5, 1*time.Minute)
@@ -152,6 +231,8 @@ func NewBackupOrchestrator(orchestratorConfig *OrchestratorConfig) (*BackupOrche
 		backupManager:       backupManager,
 		cleanupManager:      cleanupManager,
 		metricsManager:      metricsManager,
+		healthMetrics:       validation.NewMetrics(prometheus.DefaultRegisterer),
+		healthChecks:        healthChecks,
 		metricsServer:       metricsServer,
 		minioCircuitBreaker: minioCircuitBreaker,
 		apiCircuitBreaker:   apiCircuitBreaker,
@@ -212,10 +293,11 @@ func (bo *BackupOrchestrator) Run() error {
 	}
 	
 	bo.logger.Info("backup_result", "Backup completed", map[string]interface{}{
-		"namespaces_backed_up": backupResult.NamespacesBackedUp,
-		"resources_backed_up":  backupResult.ResourcesBackedUp,
-		"duration_seconds":     backupResult.Duration.Seconds(),
-		"error_count":          len(backupResult.Errors),
+		"namespaces_backed_up":        backupResult.NamespacesBackedUp,
+		"resources_backed_up":         backupResult.ResourcesBackedUp,
+		"cluster_resources_backed_up": backupResult.ClusterResourcesBackedUp,
+		"duration_seconds":            backupResult.Duration.Seconds(),
+		"error_count":                 len(backupResult.Errors),
 	})
 	
 	// Perform post-backup cleanup if configured
@@ -291,6 +373,54 @@ func (bo *BackupOrchestrator) EstimateCleanupImpact() (*cleanup.CleanupEstimate,
 	return bo.cleanupManager.EstimateCleanupImpact()
 }
 
+// PlanCleanup lists the objects a cleanup would delete now, by run
+func (bo *BackupOrchestrator) PlanCleanup() (*cleanup.CleanupPlan, error) {
+	return bo.cleanupManager.PlanCleanup()
+}
+
+// ExecuteCleanupPlan deletes the objects of a confirmed cleanup plan and
+// returns its audit log and the audit log's key in the bucket
+func (bo *BackupOrchestrator) ExecuteCleanupPlan(plan *cleanup.CleanupPlan) (*cleanup.CleanupAudit, string, error) {
+	return bo.cleanupManager.ExecutePlan(plan)
+}
+
+// RestoreFromTrash moves trashed objects under prefix back into place
+func (bo *BackupOrchestrator) RestoreFromTrash(prefix string) (int, error) {
+	return bo.cleanupManager.RestoreFromTrash(prefix)
+}
+
+// CheckClusterHealth runs the health checks selected by opts, configured by
+// the orchestrator's HealthChecksFile, against the cluster being backed up
+// and exports their results with the backup metrics
+func (bo *BackupOrchestrator) CheckClusterHealth(ctx context.Context, opts validation.Options) (validation.Results, error) {
+	opts.KubeClient = bo.kubeClient
+	opts.DynamicClient = bo.dynamicClient
+	if bo.healthChecks != nil {
+		var err error
+		if opts, err = bo.healthChecks.Apply(opts); err != nil {
+			return validation.Results{}, err
+		}
+	}
+	results, err := validation.RunChecks(ctx, opts)
+	if err != nil {
+		return results, err
+	}
+	bo.healthMetrics.Record(results)
+
+	fields := map[string]interface{}{
+		"checks":   len(results.Checks),
+		"failed":   results.Count(validation.StatusFailed),
+		"warnings": results.Count(validation.StatusWarning),
+		"duration": results.Duration.String(),
+	}
+	if results.ExitCode() == validation.CheckFailed {
+		bo.logger.Warning("cluster_health_failed", "Cluster health checks failed", fields)
+	} else {
+		bo.logger.Info("cluster_health_checked", "Cluster health checks completed", fields)
+	}
+	return results, nil
+}
+
 // GetCircuitBreakerStats returns statistics about circuit breakers
 func (bo *BackupOrchestrator) GetCircuitBreakerStats() map[string]resilience.CircuitBreakerStats {
 	return map[string]resilience.CircuitBreakerStats{
@@ -337,19 +467,6 @@ func createKubernetesClients() (kubernetes.Interface, dynamic.Interface, discove
 	return kubeClient, dynamicClient, discoveryClient, nil
 }
 
-// createMinIOClient creates and returns a MinIO client
-func createMinIOClient(cfg *config.Config) (*minio.Client, error) {
-	minioClient, err := minio.New(cfg.MinIOEndpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(cfg.MinIOAccessKey, cfg.MinIOSecretKey, ""),
-		Secure: cfg.MinIOUseSSL,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create MinIO client: %v", err)
-	}
-	
-	return minioClient, nil
-}
-
 // updateConfigWithDetectedValues updates configuration with cluster detection results
 func updateConfigWithDetectedValues(cfg *config.Config, detector *cluster.Detector) {
 	if cfg.ClusterName == "" {
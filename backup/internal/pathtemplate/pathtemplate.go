@@ -0,0 +1,142 @@
+package pathtemplate
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Fields are the values an OBJECT_PATH_TEMPLATE can use
+type Fields struct {
+	Domain  string
+	Cluster string
+	// Namespace is _cluster for cluster-scoped resources
+	Namespace string
+	// Group is empty for the core group; the empty path segment it leaves
+	// is dropped
+	Group    string
+	Version  string
+	Resource string
+	Kind     string
+	Name     string
+	BackupID string
+}
+
+// reservedPrefixes are the bucket-wide prefixes no resource may be stored
+// under
+var reservedPrefixes = []string{"_blobs", "_trash"}
+
+// Template renders the bucket key of a resource
+type Template struct {
+	text string
+	tmpl *template.Template
+}
+
+// sample is what a template is checked with when parsed
+var sample = Fields{
+	Domain:    "cluster.local",
+	Cluster:   "cluster",
+	Namespace: "namespace",
+	Group:     "apps",
+	Version:   "v1",
+	Resource:  "deployments",
+	Kind:      "Deployment",
+	Name:      "name",
+	BackupID:  "backup-20250101-020000-0a1b2c3d",
+}
+
+// Parse parses text, such as {{.Cluster}}/{{.Namespace}}/{{.Kind}}/{{.Name}}.yaml,
+// and checks that it renders a relative key that tells apart resources of
+// different names, namespaces and types, including types of the same kind in
+// different groups, such as the Ingresses of networking.k8s.io and extensions
+func Parse(text string) (*Template, error) {
+	tmpl, err := template.New("OBJECT_PATH_TEMPLATE").
+		Funcs(template.FuncMap{"lower": strings.ToLower, "upper": strings.ToUpper}).
+		Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OBJECT_PATH_TEMPLATE: %v", err)
+	}
+	t := &Template{text: text, tmpl: tmpl}
+
+	key, err := t.Render(sample)
+	if err != nil {
+		return nil, err
+	}
+	variants := []Fields{sample, sample, sample, sample}
+	variants[0].Name = "other-name"
+	variants[1].Namespace = "other-namespace"
+	variants[2].Resource, variants[2].Kind = "statefulsets", "StatefulSet"
+	variants[3].Group = "extensions"
+	for _, variant := range variants {
+		other, err := t.Render(variant)
+		if err != nil {
+			return nil, err
+		}
+		if other == key {
+			return nil, fmt.Errorf("invalid OBJECT_PATH_TEMPLATE %q: it must use .Name, .Namespace, .Group and .Resource or .Kind, or resources overwrite each other", text)
+		}
+	}
+	return t, nil
+}
+
+// String returns the text the template was parsed from
+func (t *Template) String() string {
+	return t.text
+}
+
+// Render returns the key of the resource described by fields. Slashes and
+// dots that could leave the key's directory are removed from the values,
+// and a key the template itself makes absolute, or places under .. or a
+// bucket-wide prefix, is an error.
+func (t *Template) Render(fields Fields) (string, error) {
+	var out strings.Builder
+	if err := t.tmpl.Execute(&out, sanitize(fields)); err != nil {
+		return "", fmt.Errorf("failed to render OBJECT_PATH_TEMPLATE: %v", err)
+	}
+	rendered := out.String()
+
+	if strings.HasPrefix(rendered, "/") || strings.Contains(rendered, "\\") {
+		return "", fmt.Errorf("OBJECT_PATH_TEMPLATE rendered %q, keys must be relative and use / to separate segments", rendered)
+	}
+	var segments []string
+	for _, segment := range strings.Split(rendered, "/") {
+		switch segment {
+		case "":
+			continue
+		case ".", "..":
+			return "", fmt.Errorf("OBJECT_PATH_TEMPLATE rendered %q, keys cannot contain . or .. segments", rendered)
+		}
+		segments = append(segments, segment)
+	}
+	if len(segments) == 0 {
+		return "", fmt.Errorf("OBJECT_PATH_TEMPLATE rendered an empty key")
+	}
+	for _, reserved := range reservedPrefixes {
+		if segments[0] == reserved {
+			return "", fmt.Errorf("OBJECT_PATH_TEMPLATE rendered %q, keys cannot start with %s", rendered, reserved)
+		}
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// sanitize removes from each value what could add or leave a directory
+func sanitize(fields Fields) Fields {
+	clean := func(value string) string {
+		value = strings.NewReplacer("/", "", "\\", "").Replace(value)
+		for strings.Contains(value, "..") {
+			value = strings.ReplaceAll(value, "..", "")
+		}
+		return value
+	}
+	return Fields{
+		Domain:    clean(fields.Domain),
+		Cluster:   clean(fields.Cluster),
+		Namespace: clean(fields.Namespace),
+		Group:     clean(fields.Group),
+		Version:   clean(fields.Version),
+		Resource:  clean(fields.Resource),
+		Kind:      clean(fields.Kind),
+		Name:      clean(fields.Name),
+		BackupID:  clean(fields.BackupID),
+	}
+}
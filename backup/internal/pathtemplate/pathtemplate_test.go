@@ -0,0 +1,68 @@
+package pathtemplate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_Render(t *testing.T) {
+	tmpl, err := Parse("{{.Cluster}}/{{.Namespace}}/{{.Group}}/{{.Kind}}/{{.Name}}.yaml")
+	require.NoError(t, err)
+
+	key, err := tmpl.Render(Fields{Cluster: "prod", Namespace: "shop", Group: "apps", Kind: "Deployment", Name: "web"})
+	require.NoError(t, err)
+	assert.Equal(t, "prod/shop/apps/Deployment/web.yaml", key)
+
+	key, err = tmpl.Render(Fields{Cluster: "prod", Namespace: "shop", Kind: "ConfigMap", Name: "settings"})
+	require.NoError(t, err)
+	assert.Equal(t, "prod/shop/ConfigMap/settings.yaml", key, "the core group leaves no empty segment")
+
+	tmpl, err = Parse("backups/{{.BackupID}}/{{.Namespace}}/{{lower .Kind}}.{{.Group}}-{{.Name}}.yaml")
+	require.NoError(t, err)
+	key, err = tmpl.Render(Fields{BackupID: "backup-1", Namespace: "shop", Group: "networking.k8s.io", Kind: "Ingress", Name: "web"})
+	require.NoError(t, err)
+	assert.Equal(t, "backups/backup-1/shop/ingress.networking.k8s.io-web.yaml", key)
+}
+
+func TestRender_Traversal(t *testing.T) {
+	tmpl, err := Parse("{{.Namespace}}/{{.Group}}/{{.Resource}}/{{.Name}}.yaml")
+	require.NoError(t, err)
+
+	key, err := tmpl.Render(Fields{Namespace: "../../etc", Resource: "configmaps", Name: "a/../b"})
+	require.NoError(t, err)
+	assert.Equal(t, "etc/configmaps/ab.yaml", key, "values cannot add or leave directories")
+
+	_, err = tmpl.Render(Fields{Namespace: "_trash", Resource: "configmaps", Name: "a"})
+	assert.ErrorContains(t, err, "cannot start with _trash")
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for text, message := range map[string]string{
+		"{{.Namespace}/{{.Name}}":                    "invalid OBJECT_PATH_TEMPLATE",
+		"{{.Namespace}}/{{.Owner}}/{{.Name}}.yaml":   "can't evaluate field Owner",
+		"/{{.Namespace}}/{{.Kind}}/{{.Name}}.yaml":   "keys must be relative",
+		"../{{.Namespace}}/{{.Kind}}/{{.Name}}":      "cannot contain . or .. segments",
+		"_blobs/{{.Namespace}}/{{.Kind}}/{{.Name}}":  "cannot start with _blobs",
+		"{{.Cluster}}/{{.Namespace}}/{{.Name}}.yaml": "resources overwrite each other",
+		"{{.Cluster}}/{{.Kind}}/{{.Name}}.yaml":      "resources overwrite each other",
+		"{{.Namespace}}/{{.Kind}}/{{.Name}}.yaml":    "resources overwrite each other",
+	} {
+		_, err := Parse(text)
+		assert.ErrorContains(t, err, message, text)
+	}
+}
+
+func TestParse_GroupCollision(t *testing.T) {
+	_, err := Parse("{{.Namespace}}/{{.Resource}}/{{.Name}}.yaml")
+	require.ErrorContains(t, err, ".Group", "Ingresses of networking.k8s.io and extensions would share a key")
+
+	tmpl, err := Parse("{{.Namespace}}/{{.Resource}}.{{.Group}}/{{.Name}}.yaml")
+	require.NoError(t, err)
+	networking, err := tmpl.Render(Fields{Namespace: "shop", Group: "networking.k8s.io", Resource: "ingresses", Name: "web"})
+	require.NoError(t, err)
+	extensions, err := tmpl.Render(Fields{Namespace: "shop", Group: "extensions", Resource: "ingresses", Name: "web"})
+	require.NoError(t, err)
+	assert.NotEqual(t, networking, extensions)
+}
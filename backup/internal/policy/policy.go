@@ -0,0 +1,131 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// maxResponse caps how much of an OPA response is read
+const maxResponse = 1 << 20
+
+// Violation is one policy a resource breaks. Policy is empty unless the rule
+// names it.
+type Violation struct {
+	Policy  string `json:"policy,omitempty"`
+	Message string `json:"msg"`
+}
+
+// Evaluator checks resources against Rego policies loaded into an OPA server.
+//
+// URL is the Data API document of a rule that collects violations, e.g.
+// http://opa:8181/v1/data/backup/violation. Resources are passed the way
+// Gatekeeper passes admission requests, as input.review.object, so the rule
+// can be a set of strings (deny[msg]) or of objects with msg and optionally
+// policy (violation[{"msg": msg}]).
+type Evaluator struct {
+	url    string
+	client *http.Client
+}
+
+// NewEvaluator returns an evaluator querying url, giving up on a resource
+// after timeout
+func NewEvaluator(url string, timeout time.Duration) *Evaluator {
+	return &Evaluator{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// review is Gatekeeper's input.review for an object
+type review struct {
+	Kind      reviewKind             `json:"kind"`
+	Name      string                 `json:"name"`
+	Namespace string                 `json:"namespace,omitempty"`
+	Object    map[string]interface{} `json:"object"`
+}
+
+type reviewKind struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+}
+
+// Evaluate returns the violations of object, as it will be stored
+func (e *Evaluator) Evaluate(ctx context.Context, object map[string]interface{}) ([]Violation, error) {
+	apiVersion, _ := object["apiVersion"].(string)
+	kind, _ := object["kind"].(string)
+	gvk := schema.FromAPIVersionAndKind(apiVersion, kind)
+	metadata, _ := object["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	namespace, _ := metadata["namespace"].(string)
+
+	body, err := json.Marshal(map[string]interface{}{
+		"input": map[string]interface{}{
+			"review": review{
+				Kind:      reviewKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind},
+				Name:      name,
+				Namespace: namespace,
+				Object:    object,
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode policy input: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("policy query failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("policy query returned %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	return parseResult(data)
+}
+
+// parseResult reads the violations from a Data API response. An undefined
+// rule has no result and no violations.
+func parseResult(data []byte) ([]Violation, error) {
+	var response struct {
+		Result []json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("policy result is not a set of violations: %v", err)
+	}
+
+	violations := make([]Violation, 0, len(response.Result))
+	for _, raw := range response.Result {
+		var message string
+		if err := json.Unmarshal(raw, &message); err == nil {
+			violations = append(violations, Violation{Message: message})
+			continue
+		}
+
+		var violation Violation
+		if err := json.Unmarshal(raw, &violation); err != nil || violation.Message == "" {
+			return nil, fmt.Errorf("policy violation %s has no msg", raw)
+		}
+		violations = append(violations, violation)
+	}
+	return violations, nil
+}
@@ -0,0 +1,74 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func privilegedPod() map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "debug", "namespace": "shop"},
+		"spec": map[string]interface{}{"containers": []interface{}{
+			map[string]interface{}{"name": "shell", "securityContext": map[string]interface{}{"privileged": true}},
+		}},
+	}
+}
+
+func TestEvaluate_SendsGatekeeperReview(t *testing.T) {
+	var input struct {
+		Input struct {
+			Review review `json:"review"`
+		} `json:"input"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/data/backup/violation", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&input))
+		w.Write([]byte(`{"result": [{"msg": "privileged containers are not allowed", "policy": "no-privileged"}]}`))
+	}))
+	defer server.Close()
+
+	violations, err := NewEvaluator(server.URL+"/v1/data/backup/violation", time.Second).Evaluate(context.Background(), privilegedPod())
+	require.NoError(t, err)
+	assert.Equal(t, []Violation{{Policy: "no-privileged", Message: "privileged containers are not allowed"}}, violations)
+
+	assert.Equal(t, reviewKind{Version: "v1", Kind: "Pod"}, input.Input.Review.Kind)
+	assert.Equal(t, "debug", input.Input.Review.Name)
+	assert.Equal(t, "shop", input.Input.Review.Namespace)
+	assert.Equal(t, "Pod", input.Input.Review.Object["kind"])
+}
+
+func TestEvaluate_QueryFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"code": "internal_error"}`, http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	_, err := NewEvaluator(server.URL, time.Second).Evaluate(context.Background(), privilegedPod())
+	assert.ErrorContains(t, err, "500")
+}
+
+func TestParseResult(t *testing.T) {
+	// deny[msg] rules are sets of strings
+	violations, err := parseResult([]byte(`{"result": ["dockercfg Secrets are not allowed"]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []Violation{{Message: "dockercfg Secrets are not allowed"}}, violations)
+
+	// A rule that is undefined for the input has no result
+	violations, err = parseResult([]byte(`{}`))
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+
+	_, err = parseResult([]byte(`{"result": [{"details": {}}]}`))
+	assert.Error(t, err)
+	_, err = parseResult([]byte(`{"result": true}`))
+	assert.Error(t, err)
+}
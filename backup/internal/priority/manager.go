@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"path"
 	"strconv"
 	"strings"
 	"sync"
@@ -18,6 +19,7 @@ import (
 
 // ResourcePriorityConfig holds the priority configuration for different resource types
 type ResourcePriorityConfig struct {
+	GovernanceResources map[string]int          `yaml:"governance_resources"`
 	CoreResources     map[string]int            `yaml:"core_resources"`
 	RBACResources     map[string]int            `yaml:"rbac_resources"`
 	NetworkResources  map[string]int            `yaml:"network_resources"`
@@ -44,9 +46,12 @@ type EventsConfig struct {
 	RetentionHours int `yaml:"retention_hours"`
 }
 
-// NamespaceOverride allows overriding priority for specific namespaces
+// NamespaceOverride allows overriding priority and retention for specific
+// namespaces
 type NamespaceOverride struct {
 	PriorityBoost int `yaml:"priority_boost"`
+	// RetentionDays replaces RETENTION_DAYS for the namespace's backups
+	RetentionDays int `yaml:"retention_days"`
 }
 
 // DynamicRulesConfig defines dynamic priority rules based on labels and size
@@ -70,6 +75,10 @@ type LargeResourceConfig struct {
 type BackupBehaviorConfig struct {
 	MaxConcurrentPerType int                    `yaml:"max_concurrent_per_type"`
 	RetryConfig          map[string]RetryConfig `yaml:"retry_config"`
+	// BatchSizes replaces BATCH_SIZE as the page size resource types are
+	// listed with, by resource name such as secrets, or by resource and
+	// group such as virtualmachines.kubevirt.io
+	BatchSizes map[string]int `yaml:"batch_sizes"`
 }
 
 // RetryConfig defines retry behavior for different priority levels
@@ -89,6 +98,15 @@ type Manager struct {
 	clientset   kubernetes.Interface
 }
 
+// Where the backup service reads its priority configuration from
+const (
+	DefaultConfigMap = "backup-priority-config"
+	DefaultNamespace = "default"
+)
+
+// MaxBatchSize bounds the batch_sizes overrides as BATCH_SIZE is bounded
+const MaxBatchSize = 1000
+
 // NewManager creates a new priority manager
 func NewManager(clientset kubernetes.Interface, configMap, namespace string) *Manager {
 	return &Manager{
@@ -124,6 +142,12 @@ func (pm *Manager) LoadConfig() error {
 			return fmt.Errorf("failed to parse priority configuration: %v", err)
 		}
 
+		for name, size := range config.BackupConfig.BatchSizes {
+			if size < 1 || size > MaxBatchSize {
+				log.Printf("Ignoring batch size %d of %s, must be between 1 and %d", size, name, MaxBatchSize)
+			}
+		}
+
 		pm.config = &config
 		pm.lastUpdate = time.Now()
 		log.Printf("Successfully loaded priority configuration from %s/%s", pm.namespace, pm.configMap)
@@ -155,10 +179,33 @@ func (pm *Manager) GetResourcePriority(resourceName, namespace string, labels ma
 	return basePriority
 }
 
+// GetNamespaceRetentionDays returns the retention_days override of a
+// namespace, or 0 when it has none. Overrides may be named by shell patterns
+// such as openshift-*; an exact name wins, and among matching patterns the
+// longest retention does.
+func (pm *Manager) GetNamespaceRetentionDays(namespace string) int {
+	pm.lock.RLock()
+	defer pm.lock.RUnlock()
+
+	overrides := pm.config.SpecialHandling.NamespaceOverrides
+	if nsOverride, exists := overrides[namespace]; exists && nsOverride.RetentionDays > 0 {
+		return nsOverride.RetentionDays
+	}
+
+	days := 0
+	for pattern, nsOverride := range overrides {
+		if matched, _ := path.Match(pattern, namespace); matched && nsOverride.RetentionDays > days {
+			days = nsOverride.RetentionDays
+		}
+	}
+	return days
+}
+
 // getBasePriority returns the base priority for a resource type
 func (pm *Manager) getBasePriority(resourceName string) int {
 	// Check all priority categories
 	priorityMaps := []map[string]int{
+		pm.config.GovernanceResources,
 		pm.config.CoreResources,
 		pm.config.RBACResources,
 		pm.config.NetworkResources,
@@ -261,6 +308,39 @@ func (pm *Manager) IsLargeResource(sizeStr string) bool {
 	return resourceSize > thresholdSize
 }
 
+// GetBatchSize returns the batch_sizes override of a resource type, or 0 when
+// it has none. An override of the resource and its group wins over one of
+// the resource name; values outside 1-MaxBatchSize are ignored.
+func (pm *Manager) GetBatchSize(resourceName, group string) int {
+	pm.lock.RLock()
+	defer pm.lock.RUnlock()
+
+	names := []string{resourceName}
+	if group != "" {
+		names = []string{resourceName + "." + group, resourceName}
+	}
+	for _, name := range names {
+		if size, exists := pm.config.BackupConfig.BatchSizes[name]; exists && size >= 1 && size <= MaxBatchSize {
+			return size
+		}
+	}
+	return 0
+}
+
+// GetBatchSizes returns the valid batch_sizes overrides
+func (pm *Manager) GetBatchSizes() map[string]int {
+	pm.lock.RLock()
+	defer pm.lock.RUnlock()
+
+	sizes := make(map[string]int)
+	for name, size := range pm.config.BackupConfig.BatchSizes {
+		if size >= 1 && size <= MaxBatchSize {
+			sizes[name] = size
+		}
+	}
+	return sizes
+}
+
 // GetLargResourcePriorityPenalty returns the priority penalty for large resources
 func (pm *Manager) GetLargeResourcePriorityPenalty() int {
 	pm.lock.RLock()
@@ -304,6 +384,14 @@ func parseSize(sizeStr string) int {
 // getDefaultPriorityConfig returns a sensible default priority configuration
 func getDefaultPriorityConfig() *ResourcePriorityConfig {
 	return &ResourcePriorityConfig{
+		// Quotas, limits and policies come first so that workloads are
+		// never restored without the constraints they were admitted under
+		GovernanceResources: map[string]int{
+			"resourcequotas":           98,
+			"limitranges":              98,
+			"networkpolicies":          97,
+			"priorityclasses":          97,
+		},
 		CoreResources: map[string]int{
 			"namespaces":                100,
 			"nodes":                     95,
@@ -323,7 +411,6 @@ func getDefaultPriorityConfig() *ResourcePriorityConfig {
 			"rolebindings":             85,
 		},
 		NetworkResources: map[string]int{
-			"networkpolicies":          80,
 			"ingresses":                75,
 		},
 		WorkloadResources: map[string]int{
@@ -30,8 +30,26 @@ func (cs CircuitState) String() string {
 }
 
 
-// CircuitBreaker implements the circuit breaker pattern
+// CircuitBreakerConfig defines circuit breaker behavior configuration
+type CircuitBreakerConfig struct {
+	Name string `yaml:"name"`
+	// Consecutive failures that open the circuit
+	MaxFailures int `yaml:"max_failures"`
+	// How long the circuit stays open before it is probed
+	ResetTimeout time.Duration `yaml:"reset_timeout"`
+	// Probes that must succeed in a row to close the circuit again
+	HalfOpenProbes int `yaml:"half_open_probes"`
+	// Probe is a cheap request checking the protected dependency. Without one,
+	// the operations let through one at a time while half-open are the probes.
+	Probe func() error `yaml:"-"`
+}
+
+// CircuitBreaker implements the circuit breaker pattern. Once open it rejects
+// operations until the reset timeout has passed, then probes the dependency
+// and closes only after enough probes in a row succeeded. Results of
+// operations that were started while closed do not count as probes.
 type CircuitBreaker struct {
+	name          string
 	maxFailures   int
 	resetTimeout  time.Duration
 	state         CircuitState
@@ -40,37 +58,105 @@ type CircuitBreaker struct {
 	mutex         sync.RWMutex
 	successCount  int
 	halfOpenLimit int
+	probe         func() error
+	probing       bool
+	onStateChange func(from, to CircuitState)
 }
 
 // NewCircuitBreaker creates a new circuit breaker with the specified parameters
 func NewCircuitBreaker(maxFailures int, resetTimeout time.Duration) *CircuitBreaker {
+	return NewCircuitBreakerWithConfig(CircuitBreakerConfig{
+		MaxFailures:    maxFailures,
+		ResetTimeout:   resetTimeout,
+		HalfOpenProbes: 3,
+	})
+}
+
+// NewCircuitBreakerWithConfig creates a new circuit breaker with the given configuration
+func NewCircuitBreakerWithConfig(config CircuitBreakerConfig) *CircuitBreaker {
+	if config.Name == "" {
+		config.Name = "circuit_breaker"
+	}
+	if config.MaxFailures <= 0 {
+		config.MaxFailures = 5
+	}
+	if config.HalfOpenProbes <= 0 {
+		config.HalfOpenProbes = 1
+	}
 	return &CircuitBreaker{
-		maxFailures:   maxFailures,
-		resetTimeout:  resetTimeout,
+		name:          config.Name,
+		maxFailures:   config.MaxFailures,
+		resetTimeout:  config.ResetTimeout,
 		state:         CircuitClosed,
-		halfOpenLimit: 3, // Allow 3 attempts in half-open state
+		halfOpenLimit: config.HalfOpenProbes,
+		probe:         config.Probe,
 	}
 }
 
-// Execute runs the given operation with circuit breaker protection
-func (cb *CircuitBreaker) Execute(operation func() error) error {
+// OnStateChange registers a function called on every state transition, e.g.
+// to export the state as a metric. It runs with the breaker locked and must
+// not call back into it.
+func (cb *CircuitBreaker) OnStateChange(fn func(from, to CircuitState)) {
 	cb.mutex.Lock()
 	defer cb.mutex.Unlock()
+	cb.onStateChange = fn
+}
+
+// setState moves the breaker to a new state and notifies the listener
+func (cb *CircuitBreaker) setState(state CircuitState) {
+	if cb.state == state {
+		return
+	}
+	from := cb.state
+	cb.state = state
+	if cb.onStateChange != nil {
+		cb.onStateChange(from, state)
+	}
+}
+
+// Execute runs the given operation with circuit breaker protection. The
+// breaker is not locked while the operation runs, so operations run
+// concurrently.
+func (cb *CircuitBreaker) Execute(operation func() error) error {
+	cb.mutex.Lock()
 
 	// Check if we should move from open to half-open
 	if cb.state == CircuitOpen && time.Since(cb.lastFailTime) > cb.resetTimeout {
-		cb.state = CircuitHalfOpen
+		cb.setState(CircuitHalfOpen)
 		cb.successCount = 0
 	}
 
-	// Reject operation if circuit is open
-	if cb.state == CircuitOpen {
-		return NewCircuitBreakerError("circuit_breaker", cb.state, cb.failures, cb.lastFailTime)
+	// Reject operation if circuit is open or another caller is probing
+	if cb.state == CircuitOpen || (cb.state == CircuitHalfOpen && cb.probing) {
+		err := cb.rejection()
+		cb.mutex.Unlock()
+		return err
+	}
+
+	if cb.state == CircuitHalfOpen {
+		cb.probing = true
+		cb.mutex.Unlock()
+
+		if cb.probe == nil {
+			err := operation()
+			cb.mutex.Lock()
+			cb.recordProbe(err)
+			cb.probing = false
+			cb.mutex.Unlock()
+			return err
+		}
+		if err := cb.runProbes(); err != nil {
+			return err
+		}
+		cb.mutex.Lock()
 	}
+	cb.mutex.Unlock()
 
 	// Execute the operation
 	err := operation()
-	
+
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
 	if err != nil {
 		cb.recordFailure()
 	} else {
@@ -80,32 +166,76 @@ func (cb *CircuitBreaker) Execute(operation func() error) error {
 	return err
 }
 
-// recordFailure handles failure recording and state transitions
+// runProbes probes the dependency until the circuit closes or opens again,
+// returning the rejection in the latter case
+func (cb *CircuitBreaker) runProbes() error {
+	defer func() {
+		cb.mutex.Lock()
+		cb.probing = false
+		cb.mutex.Unlock()
+	}()
+
+	for {
+		err := cb.probe()
+
+		cb.mutex.Lock()
+		cb.recordProbe(err)
+		state := cb.state
+		rejection := cb.rejection()
+		cb.mutex.Unlock()
+
+		switch state {
+		case CircuitClosed:
+			return nil
+		case CircuitOpen:
+			return rejection
+		}
+	}
+}
+
+// rejection returns the error for an operation the breaker did not let through
+func (cb *CircuitBreaker) rejection() error {
+	return NewCircuitBreakerError(cb.name, cb.state, cb.failures, cb.lastFailTime)
+}
+
+// recordFailure handles failure recording and state transitions. Failures of
+// operations finishing after the circuit opened only move the reset timeout.
 func (cb *CircuitBreaker) recordFailure() {
 	cb.failures++
 	cb.lastFailTime = time.Now()
 
-	if cb.state == CircuitHalfOpen || cb.failures >= cb.maxFailures {
-		cb.state = CircuitOpen
+	if cb.state == CircuitClosed && cb.failures >= cb.maxFailures {
+		cb.setState(CircuitOpen)
 	}
 }
 
-// recordSuccess handles success recording and state transitions
+// recordSuccess handles success recording; only probes close the circuit
 func (cb *CircuitBreaker) recordSuccess() {
 	if cb.state == CircuitClosed {
 		// Reset failure count on success in closed state
 		cb.failures = 0
+	}
+}
+
+// recordProbe handles the outcome of a probe in half-open state
+func (cb *CircuitBreaker) recordProbe(err error) {
+	if cb.state != CircuitHalfOpen {
+		// Reset while probing
+		return
+	}
+	if err != nil {
+		cb.failures++
+		cb.lastFailTime = time.Now()
+		cb.setState(CircuitOpen)
 		return
 	}
 
-	if cb.state == CircuitHalfOpen {
-		cb.successCount++
-		if cb.successCount >= cb.halfOpenLimit {
-			// Move to closed state after enough successes
-			cb.state = CircuitClosed
-			cb.failures = 0
-			cb.successCount = 0
-		}
+	cb.successCount++
+	if cb.successCount >= cb.halfOpenLimit {
+		// Move to closed state after enough successful probes
+		cb.setState(CircuitClosed)
+		cb.failures = 0
+		cb.successCount = 0
 	}
 }
 
@@ -137,7 +267,7 @@ func (cb *CircuitBreaker) Reset() {
 	cb.mutex.Lock()
 	defer cb.mutex.Unlock()
 	
-	cb.state = CircuitClosed
+	cb.setState(CircuitClosed)
 	cb.failures = 0
 	cb.successCount = 0
 	cb.lastFailTime = time.Time{}
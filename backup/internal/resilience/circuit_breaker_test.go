@@ -161,4 +161,151 @@ func TestCircuitBreaker_Reset(t *testing.T) {
 	if failures != 0 {
 		t.Errorf("Expected failures to be reset to 0, got: %d", failures)
 	}
-}
\ No newline at end of file
+}
+func TestCircuitBreaker_OnStateChange(t *testing.T) {
+	cb := NewCircuitBreaker(1, 50*time.Millisecond)
+	
+	var transitions []string
+	cb.OnStateChange(func(from, to CircuitState) {
+		transitions = append(transitions, from.String()+"->"+to.String())
+	})
+	
+	cb.Execute(func() error { return errors.New("test error") })
+	time.Sleep(60 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		cb.Execute(func() error { return nil })
+	}
+	cb.Reset()
+	
+	expected := []string{"CLOSED->OPEN", "OPEN->HALF_OPEN", "HALF_OPEN->CLOSED"}
+	if len(transitions) != len(expected) {
+		t.Fatalf("Expected transitions %v, got: %v", expected, transitions)
+	}
+	for i := range expected {
+		if transitions[i] != expected[i] {
+			t.Errorf("Expected transition %d to be %s, got: %s", i, expected[i], transitions[i])
+		}
+	}
+}
+
+func TestCircuitBreaker_HalfOpenProbes(t *testing.T) {
+	probes := 0
+	probeErr := errors.New("probe failed")
+	cb := NewCircuitBreakerWithConfig(CircuitBreakerConfig{
+		Name:           "minio",
+		MaxFailures:    1,
+		ResetTimeout:   50 * time.Millisecond,
+		HalfOpenProbes: 2,
+		Probe: func() error {
+			probes++
+			return probeErr
+		},
+	})
+	
+	cb.Execute(func() error { return errors.New("test error") })
+	time.Sleep(60 * time.Millisecond)
+	
+	// A failing probe opens the circuit again without running the operation
+	executed := false
+	err := cb.Execute(func() error {
+		executed = true
+		return nil
+	})
+	if !IsCircuitBreakerError(err) {
+		t.Fatalf("Expected circuit breaker error after failed probe, got: %v", err)
+	}
+	if executed {
+		t.Error("Expected operation not to run while the probe fails")
+	}
+	if probes != 1 {
+		t.Errorf("Expected 1 probe, got: %d", probes)
+	}
+	if state, _, _ := cb.GetState(); state != CircuitOpen {
+		t.Errorf("Expected circuit to be open after failed probe, got: %v", state)
+	}
+	
+	// Once the probes succeed the circuit closes and the operation runs
+	probeErr = nil
+	time.Sleep(60 * time.Millisecond)
+	err = cb.Execute(func() error {
+		executed = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected success after probes recovered, got: %v", err)
+	}
+	if !executed {
+		t.Error("Expected operation to run after the circuit closed")
+	}
+	if probes != 3 {
+		t.Errorf("Expected 2 more probes, got: %d in total", probes-1)
+	}
+	if state, _, _ := cb.GetState(); state != CircuitClosed {
+		t.Errorf("Expected circuit to be closed after successful probes, got: %v", state)
+	}
+}
+
+func TestCircuitBreaker_RejectsWhileProbing(t *testing.T) {
+	probing := make(chan struct{})
+	release := make(chan struct{})
+	cb := NewCircuitBreakerWithConfig(CircuitBreakerConfig{
+		MaxFailures:    1,
+		ResetTimeout:   10 * time.Millisecond,
+		HalfOpenProbes: 1,
+		Probe: func() error {
+			close(probing)
+			<-release
+			return nil
+		},
+	})
+	
+	cb.Execute(func() error { return errors.New("test error") })
+	time.Sleep(20 * time.Millisecond)
+	
+	done := make(chan error)
+	go func() {
+		done <- cb.Execute(func() error { return nil })
+	}()
+	<-probing
+	
+	if err := cb.Execute(func() error { return nil }); !IsCircuitBreakerError(err) {
+		t.Errorf("Expected circuit breaker error while another caller probes, got: %v", err)
+	}
+	
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("Expected probing caller to succeed, got: %v", err)
+	}
+}
+
+func TestCircuitBreaker_LateResultsAreNotProbes(t *testing.T) {
+	cb := NewCircuitBreaker(1, 10*time.Millisecond)
+	
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- cb.Execute(func() error {
+			close(started)
+			<-finish
+			return nil
+		})
+	}()
+	<-started
+	
+	// Open the circuit while the slow operation is still running
+	cb.Execute(func() error { return errors.New("test error") })
+	time.Sleep(20 * time.Millisecond)
+	cb.Execute(func() error { return nil })
+	
+	close(finish)
+	<-done
+	
+	stats := cb.GetStats()
+	if stats.State != CircuitHalfOpen {
+		t.Errorf("Expected circuit to stay half-open, got: %v", stats.State)
+	}
+	if stats.SuccessCount != 1 {
+		t.Errorf("Expected only the probe to count, got: %d successes", stats.SuccessCount)
+	}
+}
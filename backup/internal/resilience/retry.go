@@ -13,6 +13,10 @@ type RetryConfig struct {
 	InitialDelay time.Duration `yaml:"initial_delay"`
 	MaxDelay     time.Duration `yaml:"max_delay"`
 	Multiplier   float64       `yaml:"multiplier"`
+	// Retryable, when set, decides whether an error is worth another
+	// attempt; the others are returned at once. All errors are retried
+	// without it.
+	Retryable func(error) bool `yaml:"-"`
 }
 
 // DefaultRetryConfig returns a sensible default retry configuration
@@ -77,6 +81,9 @@ func (r *RetryExecutor) ExecuteWithContext(ctx context.Context, operation Retrya
 		}
 		
 		lastErr = err
+		if r.config.Retryable != nil && !r.config.Retryable(err) {
+			return err
+		}
 		
 		// Don't wait after the last attempt
 		if attempt == r.config.MaxAttempts {
@@ -0,0 +1,35 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryExecutor_Retryable(t *testing.T) {
+	permanent := errors.New("forbidden")
+	executor := NewRetryExecutor(RetryConfig{
+		MaxAttempts:  3,
+		InitialDelay: time.Millisecond,
+		Retryable:    func(err error) bool { return !errors.Is(err, permanent) },
+	})
+
+	attempts := 0
+	err := executor.ExecuteWithContext(context.Background(), func() error {
+		attempts++
+		return permanent
+	})
+	assert.Equal(t, permanent, err, "an error not worth retrying is returned as it is")
+	assert.Equal(t, 1, attempts)
+
+	attempts = 0
+	err = executor.ExecuteWithContext(context.Background(), func() error {
+		attempts++
+		return errors.New("connection reset")
+	})
+	assert.True(t, IsRetryExhaustedError(err))
+	assert.Equal(t, 3, attempts)
+}
@@ -0,0 +1,271 @@
+package search
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Fields a query term can match
+const (
+	FieldKind      = "kind"
+	FieldName      = "name"
+	FieldNamespace = "namespace"
+	FieldResource  = "resource"
+	FieldGroup     = "group"
+	FieldLabel     = "label"
+)
+
+// Document is a resource of a run as the index knows it
+type Document struct {
+	Namespace string            `json:"namespace,omitempty"`
+	Group     string            `json:"group,omitempty"`
+	Version   string            `json:"version,omitempty"`
+	Resource  string            `json:"resource"`
+	Kind      string            `json:"kind,omitempty"`
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Bucket    string            `json:"bucket,omitempty"`
+	Key       string            `json:"key"`
+	VersionID string            `json:"version_id,omitempty"`
+}
+
+// Index maps the names, kinds, namespaces, types and labels of a run's
+// resources to the resources. It is safe for concurrent use.
+type Index struct {
+	BackupID  string     `json:"backup_id"`
+	Documents []Document `json:"documents"`
+	// Terms are the positions in Documents of the resources each term,
+	// such as kind=Deployment or label=app=payments, matches
+	Terms map[string][]int `json:"terms"`
+
+	mu sync.Mutex
+}
+
+// NewIndex returns an empty index of run backupID
+func NewIndex(backupID string) *Index {
+	return &Index{BackupID: backupID, Terms: make(map[string][]int)}
+}
+
+// Add indexes a resource
+func (ix *Index) Add(doc Document) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.add(doc)
+}
+
+func (ix *Index) add(doc Document) {
+	position := len(ix.Documents)
+	ix.Documents = append(ix.Documents, doc)
+	for _, term := range documentTerms(doc) {
+		ix.Terms[term] = append(ix.Terms[term], position)
+	}
+}
+
+// Replace indexes a resource in place of the one of the same type, namespace
+// and name indexed before, or adds it when there is none. It looks through
+// every resource, so it suits the few resources stored again in a run.
+func (ix *Index) Replace(doc Document) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	position := -1
+	for i, indexed := range ix.Documents {
+		if indexed.Namespace == doc.Namespace && indexed.Group == doc.Group && indexed.Resource == doc.Resource && indexed.Name == doc.Name {
+			position = i
+			break
+		}
+	}
+	if position < 0 {
+		ix.add(doc)
+		return
+	}
+
+	for _, term := range documentTerms(ix.Documents[position]) {
+		postings := ix.Terms[term]
+		for i, indexed := range postings {
+			if indexed == position {
+				postings = append(postings[:i], postings[i+1:]...)
+				break
+			}
+		}
+		if len(postings) == 0 {
+			delete(ix.Terms, term)
+		} else {
+			ix.Terms[term] = postings
+		}
+	}
+	ix.Documents[position] = doc
+	for _, term := range documentTerms(doc) {
+		ix.Terms[term] = append(ix.Terms[term], position)
+	}
+}
+
+// Len returns the number of resources indexed
+func (ix *Index) Len() int {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return len(ix.Documents)
+}
+
+// documentTerms returns the terms doc is found by
+func documentTerms(doc Document) []string {
+	resource := doc.Resource
+	if doc.Group != "" {
+		resource += "." + doc.Group
+	}
+	terms := []string{
+		FieldName + "=" + doc.Name,
+		FieldResource + "=" + resource,
+		FieldGroup + "=" + doc.Group,
+	}
+	if doc.Namespace != "" {
+		terms = append(terms, FieldNamespace+"="+doc.Namespace)
+	}
+	if doc.Kind != "" {
+		terms = append(terms, FieldKind+"="+doc.Kind)
+	}
+	for key, value := range doc.Labels {
+		terms = append(terms, FieldLabel+"="+key, FieldLabel+"="+key+"="+value)
+	}
+	return terms
+}
+
+// Term is one condition of a query: field=value, or for labels
+// label=key=value and label=key, the latter matching any value. Values may
+// use the wildcards of path.Match, such as payments-*.
+type Term struct {
+	Field string
+	Value string
+}
+
+// ParseQuery parses terms such as kind=Deployment and label=app=payments.
+// A query matches the resources every term matches.
+func ParseQuery(args []string) ([]Term, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("a query needs at least one term")
+	}
+	var query []Term
+	for _, arg := range args {
+		field, value, found := strings.Cut(arg, "=")
+		if !found || value == "" {
+			return nil, fmt.Errorf("invalid term %q, expected field=value", arg)
+		}
+		switch field {
+		case FieldKind, FieldName, FieldNamespace, FieldResource, FieldGroup, FieldLabel:
+		default:
+			return nil, fmt.Errorf("invalid term %q, fields are %s, %s, %s, %s, %s and %s", arg,
+				FieldKind, FieldName, FieldNamespace, FieldResource, FieldGroup, FieldLabel)
+		}
+		if _, err := path.Match(value, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern in term %q: %v", arg, err)
+		}
+		query = append(query, Term{Field: field, Value: value})
+	}
+	return query, nil
+}
+
+// Search returns the resources every term of query matches, in the order
+// they were indexed
+func (ix *Index) Search(query []Term) []Document {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	var matched map[int]bool
+	for _, term := range query {
+		positions := ix.lookup(term)
+		if matched == nil {
+			matched = positions
+			continue
+		}
+		for position := range matched {
+			if !positions[position] {
+				delete(matched, position)
+			}
+		}
+	}
+
+	ordered := make([]int, 0, len(matched))
+	for position := range matched {
+		ordered = append(ordered, position)
+	}
+	sort.Ints(ordered)
+	documents := make([]Document, 0, len(ordered))
+	for _, position := range ordered {
+		documents = append(documents, ix.Documents[position])
+	}
+	return documents
+}
+
+// lookup returns the positions of the resources term matches: those of its
+// own term, or with wildcards of every term its pattern matches
+func (ix *Index) lookup(term Term) map[int]bool {
+	positions := make(map[int]bool)
+	want := term.Field + "=" + term.Value
+	if !strings.ContainsAny(term.Value, "*?[\\") {
+		for _, position := range ix.Terms[want] {
+			positions[position] = true
+		}
+		return positions
+	}
+
+	for indexed, postings := range ix.Terms {
+		if !strings.HasPrefix(indexed, term.Field+"=") {
+			continue
+		}
+		// label=key=pattern only matches terms carrying a value
+		if term.Field == FieldLabel && strings.Count(want, "=") != strings.Count(indexed, "=") {
+			continue
+		}
+		if ok, _ := path.Match(want, indexed); !ok {
+			continue
+		}
+		for _, position := range postings {
+			positions[position] = true
+		}
+	}
+	return positions
+}
+
+// Encode returns the index as gzipped JSON, the form it is stored in
+func (ix *Index) Encode() ([]byte, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(writer).Encode(ix); err != nil {
+		return nil, fmt.Errorf("failed to encode index: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress index: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode reads an index stored by Encode
+func Decode(data []byte) (*Index, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress index: %v", err)
+	}
+	defer reader.Close()
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress index: %v", err)
+	}
+
+	ix := &Index{}
+	if err := json.Unmarshal(decoded, ix); err != nil {
+		return nil, fmt.Errorf("failed to decode index: %v", err)
+	}
+	if ix.Terms == nil {
+		ix.Terms = make(map[string][]int)
+	}
+	return ix, nil
+}
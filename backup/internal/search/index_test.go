@@ -0,0 +1,102 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestIndex() *Index {
+	index := NewIndex("backup-1")
+	index.Add(Document{Namespace: "payments", Group: "apps", Version: "v1", Resource: "deployments", Kind: "Deployment", Name: "api",
+		Labels: map[string]string{"app": "payments", "tier": "web"}, Key: "payments/deployments.apps/api.yaml"})
+	index.Add(Document{Namespace: "payments", Version: "v1", Resource: "configmaps", Kind: "ConfigMap", Name: "api-settings",
+		Labels: map[string]string{"app": "payments"}, Key: "payments/configmaps/api-settings.yaml"})
+	index.Add(Document{Namespace: "shop", Group: "apps", Version: "v1", Resource: "deployments", Kind: "Deployment", Name: "web",
+		Labels: map[string]string{"app": "shop", "tier": "web"}, Key: "shop/deployments.apps/web.yaml"})
+	index.Add(Document{Group: "storage.k8s.io", Version: "v1", Resource: "storageclasses", Kind: "StorageClass", Name: "fast", Key: "_cluster/storageclasses.storage.k8s.io/fast.yaml"})
+	return index
+}
+
+func names(documents []Document) []string {
+	var found []string
+	for _, doc := range documents {
+		found = append(found, doc.Name)
+	}
+	return found
+}
+
+func TestIndex_Search(t *testing.T) {
+	index := newTestIndex()
+	require.Equal(t, 4, index.Len())
+
+	for query, expected := range map[string][]string{
+		"label=app=payments kind=Deployment": {"api"},
+		"label=app=payments":                 {"api", "api-settings"},
+		"label=tier":                         {"api", "web"},
+		"label=app=pay*":                     {"api", "api-settings"},
+		"name=api*":                          {"api", "api-settings"},
+		"resource=deployments.apps":          {"api", "web"},
+		"group=storage.k8s.io":               {"fast"},
+		"namespace=shop label=tier=web":      {"web"},
+		"kind=Deployment namespace=billing":  nil,
+		"label=app=payments label=tier=db":   nil,
+	} {
+		terms, err := ParseQuery(strings.Fields(query))
+		require.NoError(t, err, query)
+		assert.Equal(t, expected, names(index.Search(terms)), query)
+	}
+}
+
+func TestIndex_Replace(t *testing.T) {
+	index := newTestIndex()
+	index.Replace(Document{Namespace: "shop", Group: "apps", Version: "v1", Resource: "deployments", Kind: "Deployment", Name: "web",
+		Labels: map[string]string{"app": "shop", "tier": "api"}, Key: "shop/deployments.apps/web.yaml"})
+	index.Replace(Document{Namespace: "shop", Version: "v1", Resource: "configmaps", Kind: "ConfigMap", Name: "web-settings",
+		Labels: map[string]string{"app": "shop"}, Key: "shop/configmaps/web-settings.yaml"})
+	assert.Equal(t, 5, index.Len(), "replaced in place, added when new")
+
+	for query, expected := range map[string][]string{
+		"label=tier=web":          {"api"},
+		"label=tier=api":          {"web"},
+		"label=app=shop":          {"web", "web-settings"},
+		"kind=Deployment":         {"api", "web"},
+		"namespace=shop name=web": {"web"},
+	} {
+		terms, err := ParseQuery(strings.Fields(query))
+		require.NoError(t, err, query)
+		assert.Equal(t, expected, names(index.Search(terms)), query)
+	}
+}
+
+func TestParseQuery_Invalid(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"Deployment"},
+		{"kind="},
+		{"owner=payments"},
+		{"name=[api"},
+	} {
+		_, err := ParseQuery(args)
+		assert.Error(t, err, args)
+	}
+}
+
+func TestIndex_EncodeDecode(t *testing.T) {
+	index := newTestIndex()
+	data, err := index.Encode()
+	require.NoError(t, err)
+
+	decoded, err := Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, "backup-1", decoded.BackupID)
+	assert.Equal(t, index.Documents, decoded.Documents)
+	terms, err := ParseQuery([]string{"label=app=payments", "kind=Deployment"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api"}, names(decoded.Search(terms)))
+
+	_, err = Decode([]byte("not gzip"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,173 @@
+package secretscan
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Detector names, as recorded in findings
+const (
+	DetectorPrivateKey   = "private_key"
+	DetectorAWSAccessKey = "aws_access_key"
+	DetectorAWSSecretKey = "aws_secret_key"
+	DetectorHighEntropy  = "high_entropy"
+)
+
+// lastAppliedAnnotation repeats the fields kubectl applied, which are scanned
+// where they are set
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+var (
+	privateKeyHeader = regexp.MustCompile(`-----BEGIN ((RSA|EC|DSA|OPENSSH|ENCRYPTED|PGP) )?PRIVATE KEY( BLOCK)?-----`)
+	awsAccessKey     = regexp.MustCompile(`\b(AKIA|ASIA|AIDA|AROA)[0-9A-Z]{16}\b`)
+	awsSecretKey     = regexp.MustCompile(`(?i)aws.{0,20}(secret|key).{0,20}?['"=:\s]+([A-Za-z0-9/+]{40})\b`)
+	pemBlock         = regexp.MustCompile(`(?s)-----BEGIN [A-Z0-9 ]+-----.*?-----END [A-Z0-9 ]+-----`)
+	base64Token      = regexp.MustCompile(`[A-Za-z0-9+/_\-]{20,}={0,2}`)
+)
+
+// Entropy threshold in bits per character above which a base64 token is
+// taken for a generated credential. Hex strings, such as digests, stay below
+// it as they carry at most 4 bits per character.
+const entropyThreshold = 4.5
+
+// Finding is a suspected credential in a resource. Field is the path of the
+// value it was found in; the value itself is never recorded.
+type Finding struct {
+	Detector string `json:"detector"`
+	Field    string `json:"field"`
+}
+
+// Scan looks for credentials in the fields of object that commonly end up
+// holding them by mistake: ConfigMap data, annotations and container
+// environment variables. Secrets are expected to hold credentials and are
+// not scanned.
+func Scan(object map[string]interface{}) []Finding {
+	apiVersion, _ := object["apiVersion"].(string)
+	kind, _ := object["kind"].(string)
+	if apiVersion == "v1" && kind == "Secret" {
+		return nil
+	}
+
+	var findings []Finding
+	if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			for _, key := range sortedKeys(annotations) {
+				if key == lastAppliedAnnotation {
+					continue
+				}
+				findings = append(findings, scanValue("metadata.annotations."+key, annotations[key])...)
+			}
+		}
+	}
+
+	if apiVersion == "v1" && kind == "ConfigMap" {
+		if data, ok := object["data"].(map[string]interface{}); ok {
+			for _, key := range sortedKeys(data) {
+				findings = append(findings, scanValue("data."+key, data[key])...)
+			}
+		}
+	}
+
+	findings = append(findings, scanEnv("", object)...)
+	return findings
+}
+
+// scanEnv scans the value of every env entry under node, wherever a
+// workload's pod template keeps its containers
+func scanEnv(path string, node interface{}) []Finding {
+	var findings []Finding
+	switch node := node.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(node) {
+			if key == "metadata" || (path == "" && key == "data") {
+				continue
+			}
+			child := joinPath(path, key)
+			if env, ok := node[key].([]interface{}); ok && key == "env" {
+				for i, entry := range env {
+					variable, _ := entry.(map[string]interface{})
+					findings = append(findings, scanValue(fmt.Sprintf("%s[%d].value", child, i), variable["value"])...)
+				}
+				continue
+			}
+			findings = append(findings, scanEnv(child, node[key])...)
+		}
+	case []interface{}:
+		for i, item := range node {
+			findings = append(findings, scanEnv(fmt.Sprintf("%s[%d]", path, i), item)...)
+		}
+	}
+	return findings
+}
+
+// scanValue runs the detectors over a string value, reporting each detector
+// at most once per field
+func scanValue(field string, value interface{}) []Finding {
+	text, ok := value.(string)
+	if !ok || text == "" {
+		return nil
+	}
+
+	var findings []Finding
+	if privateKeyHeader.MatchString(text) {
+		findings = append(findings, Finding{Detector: DetectorPrivateKey, Field: field})
+	}
+	if awsAccessKey.MatchString(text) {
+		findings = append(findings, Finding{Detector: DetectorAWSAccessKey, Field: field})
+	}
+	secretKey := awsSecretKey.FindStringSubmatch(text)
+	if secretKey != nil {
+		findings = append(findings, Finding{Detector: DetectorAWSSecretKey, Field: field})
+	}
+
+	// Certificates and keys are base64 too; keys are reported above and
+	// certificates are public
+	for _, token := range base64Token.FindAllString(pemBlock.ReplaceAllString(text, ""), -1) {
+		if secretKey != nil && strings.Contains(token, secretKey[2]) {
+			continue
+		}
+		if awsAccessKey.MatchString(token) {
+			continue
+		}
+		if entropy(token) > entropyThreshold {
+			findings = append(findings, Finding{Detector: DetectorHighEntropy, Field: field})
+			break
+		}
+	}
+	return findings
+}
+
+// entropy is the Shannon entropy of s in bits per character
+func entropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+
+	var bits float64
+	length := float64(len(s))
+	for _, count := range counts {
+		p := float64(count) / length
+		bits -= p * math.Log2(p)
+	}
+	return bits
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -2,20 +2,30 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"cluster-backup/internal/auth"
 	"cluster-backup/internal/logging"
+	"cluster-backup/internal/resilience"
 )
 
+// CircuitBreakerStatsFunc returns the current circuit breaker stats by name
+type CircuitBreakerStatsFunc func() map[string]resilience.CircuitBreakerStats
+
 // MetricsServer handles the Prometheus metrics HTTP server
 type MetricsServer struct {
-	server *http.Server
-	logger *logging.StructuredLogger
-	port   int
+	server          *http.Server
+	mux             *http.ServeMux
+	logger          *logging.StructuredLogger
+	port            int
+	circuitBreakers CircuitBreakerStatsFunc
+	// auth, when set, protects the metrics and health endpoints
+	auth *auth.Authenticator
 }
 
 // NewMetricsServer creates a new metrics server
@@ -25,20 +35,26 @@ func NewMetricsServer(port int, logger *logging.StructuredLogger) *MetricsServer
 	}
 	
 	mux := http.NewServeMux()
+	ms := &MetricsServer{
+		mux:    mux,
+		logger: logger,
+		port:   port,
+	}
 	
 	// Register Prometheus metrics endpoint
-	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/metrics", ms.protect(promhttp.Handler()))
 	
-	// Register health check endpoint
-	mux.HandleFunc("/health", healthCheckHandler)
-	mux.HandleFunc("/healthz", healthCheckHandler)
+	// Register health check endpoint. Readiness stays open for probes
+	// when the others are protected; it tells nothing about the service.
+	mux.Handle("/health", ms.protect(http.HandlerFunc(ms.healthCheckHandler)))
+	mux.Handle("/healthz", ms.protect(http.HandlerFunc(ms.healthCheckHandler)))
 	mux.HandleFunc("/ready", readinessCheckHandler)
 	mux.HandleFunc("/readyz", readinessCheckHandler)
 	
 	// Register root endpoint with basic info
 	mux.HandleFunc("/", rootHandler)
 
-	server := &http.Server{
+	ms.server = &http.Server{
 		Addr:         fmt.Sprintf(":%d", port),
 		Handler:      mux,
 		ReadTimeout:  30 * time.Second,
@@ -46,11 +62,37 @@ func NewMetricsServer(port int, logger *logging.StructuredLogger) *MetricsServer
 		IdleTimeout:  60 * time.Second,
 	}
 
-	return &MetricsServer{
-		server: server,
-		logger: logger,
-		port:   port,
-	}
+	return ms
+}
+
+// Handle registers an additional handler on the metrics port, e.g. the UI dashboard.
+// It must be called before the server is started.
+func (ms *MetricsServer) Handle(pattern string, handler http.Handler) {
+	ms.mux.Handle(pattern, handler)
+}
+
+// RequireAuth requires the viewer role of authenticator for the metrics and
+// health endpoints. It must be called before the server is started.
+func (ms *MetricsServer) RequireAuth(authenticator *auth.Authenticator) {
+	ms.auth = authenticator
+}
+
+// protect passes requests to next unless RequireAuth was called and the
+// caller lacks the viewer role
+func (ms *MetricsServer) protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ms.auth == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		requireRole(ms.auth, auth.RoleViewer, next).ServeHTTP(w, r)
+	})
+}
+
+// SetCircuitBreakers includes the state of the given circuit breakers in the
+// health check response. It must be called before the server is started.
+func (ms *MetricsServer) SetCircuitBreakers(stats CircuitBreakerStatsFunc) {
+	ms.circuitBreakers = stats
 }
 
 // Start starts the metrics server in a blocking manner
@@ -114,11 +156,52 @@ func (ms *MetricsServer) GetAddr() string {
 	return ms.server.Addr
 }
 
-// healthCheckHandler handles health check requests
-func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/plain")
+// circuitBreakerStatus is the health check detail of one circuit breaker
+type circuitBreakerStatus struct {
+	State        string     `json:"state"`
+	Failures     int        `json:"failures"`
+	MaxFailures  int        `json:"max_failures"`
+	ResetTimeout string     `json:"reset_timeout"`
+	LastFailure  *time.Time `json:"last_failure,omitempty"`
+}
+
+// healthCheckHandler handles health check requests. Without circuit breakers
+// it answers a plain OK; with them it reports their state as JSON and a
+// "degraded" status while any is not closed. The status code stays 200 either
+// way, since a short-circuited dependency is no reason to restart the pod.
+func (ms *MetricsServer) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
+	if ms.circuitBreakers == nil {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "OK")
+		return
+	}
+
+	status := "ok"
+	breakers := make(map[string]circuitBreakerStatus)
+	for name, stats := range ms.circuitBreakers() {
+		breaker := circuitBreakerStatus{
+			State:        stats.State.String(),
+			Failures:     stats.Failures,
+			MaxFailures:  stats.MaxFailures,
+			ResetTimeout: stats.ResetTimeout.String(),
+		}
+		if !stats.LastFailTime.IsZero() {
+			lastFailure := stats.LastFailTime
+			breaker.LastFailure = &lastFailure
+		}
+		if stats.State != resilience.CircuitClosed {
+			status = "degraded"
+		}
+		breakers[name] = breaker
+	}
+
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	fmt.Fprint(w, "OK")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":           status,
+		"circuit_breakers": breakers,
+	})
 }
 
 // readinessCheckHandler handles readiness check requests
@@ -161,7 +244,7 @@ func rootHandler(w http.ResponseWriter, r *http.Request) {
         
         <div class="endpoint">
             <strong><a href="/health">/health</a></strong><br>
-            Basic health check endpoint. Returns 200 OK if the service is running.
+            Basic health check endpoint. Returns 200 OK if the service is running, with the state of the MinIO and Kubernetes API circuit breakers when available.
         </div>
         
         <div class="endpoint">
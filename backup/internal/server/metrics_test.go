@@ -0,0 +1,70 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/auth"
+	"cluster-backup/internal/logging"
+	"cluster-backup/internal/resilience"
+)
+
+func TestMetricsServer_HealthReportsCircuitBreakers(t *testing.T) {
+	ms := NewMetricsServer(0, logging.NewStructuredLogger("server-test", "test-cluster"))
+
+	rec := httptest.NewRecorder()
+	ms.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "OK", rec.Body.String())
+
+	minio := resilience.NewCircuitBreaker(1, time.Minute)
+	api := resilience.NewCircuitBreaker(5, time.Minute)
+	ms.SetCircuitBreakers(func() map[string]resilience.CircuitBreakerStats {
+		return map[string]resilience.CircuitBreakerStats{"minio": minio.GetStats(), "api": api.GetStats()}
+	})
+	minio.Execute(func() error { return errors.New("connection refused") })
+
+	rec = httptest.NewRecorder()
+	ms.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusOK, rec.Code, "an open breaker must not fail liveness probes")
+
+	var body struct {
+		Status          string                          `json:"status"`
+		CircuitBreakers map[string]circuitBreakerStatus `json:"circuit_breakers"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "degraded", body.Status)
+	assert.Equal(t, "OPEN", body.CircuitBreakers["minio"].State)
+	assert.Equal(t, 1, body.CircuitBreakers["minio"].Failures)
+	assert.NotNil(t, body.CircuitBreakers["minio"].LastFailure)
+	assert.Equal(t, "CLOSED", body.CircuitBreakers["api"].State)
+	assert.Nil(t, body.CircuitBreakers["api"].LastFailure)
+}
+
+func TestMetricsServer_RequireAuth(t *testing.T) {
+	ms := NewMetricsServer(0, logging.NewStructuredLogger("server-test", "test-cluster"))
+	get := func(path, token string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		ms.mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusOK, get("/metrics", ""), "open unless required")
+
+	ms.RequireAuth(auth.NewAuthenticator(map[auth.Role]string{auth.RoleViewer: "viewer"}, nil))
+	for _, path := range []string{"/metrics", "/health", "/healthz"} {
+		assert.Equal(t, http.StatusUnauthorized, get(path, ""), path)
+		assert.Equal(t, http.StatusOK, get(path, "viewer"), path)
+	}
+	assert.Equal(t, http.StatusOK, get("/readyz", ""), "readiness stays open for probes")
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"net/http"
+	"net/http/pprof"
+
+	"cluster-backup/internal/auth"
+)
+
+// PprofPrefix is the path the profiling endpoints are served under
+const PprofPrefix = "/debug/pprof/"
+
+// EnablePprof serves the net/http/pprof endpoints under PprofPrefix to
+// callers authenticator gives the admin role. CPU profiles and traces are
+// capped by the server's write timeout, so ?seconds= must stay below 30. It
+// must be called before the server is started.
+func (ms *MetricsServer) EnablePprof(authenticator *auth.Authenticator) {
+	ms.mux.Handle(PprofPrefix, requireRole(authenticator, auth.RoleAdmin, http.HandlerFunc(pprof.Index)))
+	ms.mux.Handle(PprofPrefix+"cmdline", requireRole(authenticator, auth.RoleAdmin, http.HandlerFunc(pprof.Cmdline)))
+	ms.mux.Handle(PprofPrefix+"profile", requireRole(authenticator, auth.RoleAdmin, http.HandlerFunc(pprof.Profile)))
+	ms.mux.Handle(PprofPrefix+"symbol", requireRole(authenticator, auth.RoleAdmin, http.HandlerFunc(pprof.Symbol)))
+	ms.mux.Handle(PprofPrefix+"trace", requireRole(authenticator, auth.RoleAdmin, http.HandlerFunc(pprof.Trace)))
+}
+
+// requireRole rejects requests whose bearer token is not valid or whose
+// holder lacks role
+func requireRole(authenticator *auth.Authenticator, role auth.Role, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := authenticator.Authorize(r.Context(), r.Header.Get("Authorization"), role); err != nil {
+			status, message := auth.HTTPStatus(err)
+			http.Error(w, message, status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
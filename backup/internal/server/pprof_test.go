@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"cluster-backup/internal/auth"
+	"cluster-backup/internal/logging"
+)
+
+func TestMetricsServer_Pprof(t *testing.T) {
+	ms := NewMetricsServer(0, logging.NewStructuredLogger("server-test", "test-cluster"))
+	get := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		ms.mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.NotContains(t, get("/debug/pprof/heap", "secret").Body.String(), "heap profile", "pprof is off unless enabled")
+
+	ms.EnablePprof(auth.NewAuthenticator(map[auth.Role]string{auth.RoleAdmin: "secret", auth.RoleOperator: "operator"}, nil))
+	assert.Equal(t, http.StatusUnauthorized, get("/debug/pprof/heap", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, get("/debug/pprof/goroutine", "wrong").Code)
+	assert.Equal(t, http.StatusForbidden, get("/debug/pprof/goroutine", "operator").Code)
+
+	rec := get("/debug/pprof/goroutine?debug=1", "secret")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "goroutine profile")
+	assert.Equal(t, http.StatusOK, get("/debug/pprof/cmdline", "secret").Code)
+}
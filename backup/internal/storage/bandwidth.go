@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"golang.org/x/time/rate"
+
+	"cluster-backup/internal/config"
+)
+
+// maxBandwidthBurst bounds the bytes a connection sends or reads at once
+// under a bandwidth limit, so a high limit still spreads traffic over time
+const maxBandwidthBurst = 1 << 20
+
+var (
+	limitersMu sync.Mutex
+	// limiters are shared by every transport of the process, so transports
+	// rebuilt on credential rotation and clients of secondary targets draw
+	// from the same budget
+	limiters = make(map[string]*rate.Limiter)
+)
+
+// bandwidthLimiter returns the process-wide limiter of a direction of traffic,
+// or nil without a limit
+func bandwidthLimiter(direction string, bytesPerSecond int64) *rate.Limiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	key := fmt.Sprintf("%s/%d", direction, bytesPerSecond)
+	if limiter, ok := limiters[key]; ok {
+		return limiter
+	}
+	burst := bytesPerSecond
+	if burst > maxBandwidthBurst {
+		burst = maxBandwidthBurst
+	}
+	limiter := rate.NewLimiter(rate.Limit(bytesPerSecond), int(burst))
+	limiters[key] = limiter
+	return limiter
+}
+
+// throttle makes the connections of transport send no faster than
+// BACKUP_BANDWIDTH_LIMIT and read no faster than RESTORE_BANDWIDTH_LIMIT.
+// Limits apply to the bytes on the wire, TLS included.
+func throttle(cfg *config.Config, transport *http.Transport) error {
+	upload, err := config.ParseBandwidth(cfg.BackupBandwidthLimit)
+	if err != nil {
+		return fmt.Errorf("invalid BACKUP_BANDWIDTH_LIMIT: %v", err)
+	}
+	download, err := config.ParseBandwidth(cfg.RestoreBandwidthLimit)
+	if err != nil {
+		return fmt.Errorf("invalid RESTORE_BANDWIDTH_LIMIT: %v", err)
+	}
+	if upload == 0 && download == 0 {
+		return nil
+	}
+
+	write := bandwidthLimiter("upload", upload)
+	read := bandwidthLimiter("download", download)
+	dial := transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, err := dial(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		return &throttledConn{Conn: conn, read: read, write: write}, nil
+	}
+	return nil
+}
+
+// throttledConn waits on its limiters for every chunk it sends or reads
+type throttledConn struct {
+	net.Conn
+	read  *rate.Limiter
+	write *rate.Limiter
+}
+
+func (c *throttledConn) Read(p []byte) (int, error) {
+	if c.read == nil {
+		return c.Conn.Read(p)
+	}
+	if len(p) > c.read.Burst() {
+		p = p[:c.read.Burst()]
+	}
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		if waitErr := c.read.WaitN(context.Background(), n); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+func (c *throttledConn) Write(p []byte) (int, error) {
+	if c.write == nil {
+		return c.Conn.Write(p)
+	}
+	written := 0
+	for written < len(p) {
+		chunk := p[written:]
+		if len(chunk) > c.write.Burst() {
+			chunk = chunk[:c.write.Burst()]
+		}
+		if err := c.write.WaitN(context.Background(), len(chunk)); err != nil {
+			return written, err
+		}
+		n, err := c.Conn.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
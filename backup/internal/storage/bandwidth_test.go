@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTransport_BandwidthLimits(t *testing.T) {
+	payload := bytes.Repeat([]byte("x"), 128<<10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			io.Copy(io.Discard, r.Body)
+			return
+		}
+		w.Write(payload)
+	}))
+	defer server.Close()
+
+	cfg := testConfig()
+	cfg.MinIOUseSSL = false
+	cfg.BackupBandwidthLimit = "64Ki"
+	cfg.RestoreBandwidthLimit = "96Ki"
+	transport, err := NewTransport(cfg)
+	require.NoError(t, err)
+	client := &http.Client{Transport: transport}
+
+	// A burst of one second's worth goes at once, the rest at the limit
+	start := time.Now()
+	req, err := http.NewRequest(http.MethodPut, server.URL, bytes.NewReader(payload))
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.GreaterOrEqual(t, time.Since(start), 800*time.Millisecond, "uploads are held to BACKUP_BANDWIDTH_LIMIT")
+
+	start = time.Now()
+	resp, err = client.Get(server.URL)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Len(t, body, len(payload))
+	assert.GreaterOrEqual(t, time.Since(start), 250*time.Millisecond, "downloads are held to RESTORE_BANDWIDTH_LIMIT")
+
+	cfg.BackupBandwidthLimit = "fast"
+	_, err = NewTransport(cfg)
+	assert.ErrorContains(t, err, "invalid BACKUP_BANDWIDTH_LIMIT")
+}
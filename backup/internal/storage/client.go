@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"cluster-backup/internal/compliance"
+	"cluster-backup/internal/config"
+)
+
+// NewTransport builds the HTTP transport for MinIO clients from the connection
+// settings. Build it once per process and pass it to every client so backup,
+// cleanup and the API server draw from the same connection pool.
+func NewTransport(cfg *config.Config) (*http.Transport, error) {
+	transport, err := minio.DefaultTransport(cfg.MinIOUseSSL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MinIO transport: %v", err)
+	}
+
+	// Every client talks to the single MinIO endpoint, so the per-host limit
+	// is the one that bounds the pool
+	transport.MaxIdleConns = cfg.MinIOMaxIdleConns
+	transport.MaxIdleConnsPerHost = cfg.MinIOMaxIdleConns
+	transport.IdleConnTimeout = cfg.MinIOIdleConnTimeout
+	transport.TLSHandshakeTimeout = cfg.MinIOTLSHandshakeTimeout
+	if err := throttle(cfg, transport); err != nil {
+		return nil, err
+	}
+
+	// Replica and secondary targets may use TLS even when MINIO_ENDPOINT
+	// does not, so the restricted settings must exist for them too
+	if compliance.Enabled(cfg) {
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		compliance.Restrict(transport.TLSClientConfig)
+	}
+
+	if transport.TLSClientConfig == nil {
+		return transport, nil
+	}
+
+	if cfg.MinIOCABundle != "" {
+		pem, err := readPEM(cfg.MinIOCABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle %s: %v", describePEM(cfg.MinIOCABundle), err)
+		}
+		rootCAs := transport.TLSClientConfig.RootCAs
+		if rootCAs == nil {
+			if rootCAs, err = x509.SystemCertPool(); err != nil {
+				rootCAs = x509.NewCertPool()
+			}
+		}
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", describePEM(cfg.MinIOCABundle))
+		}
+		transport.TLSClientConfig.RootCAs = rootCAs
+	}
+
+	// Client certificate for MinIO deployments that require mutual TLS
+	if cfg.MinIOClientCert != "" {
+		certPEM, err := readPEM(cfg.MinIOClientCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client certificate %s: %v", describePEM(cfg.MinIOClientCert), err)
+		}
+		keyPEM, err := readPEM(cfg.MinIOClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client key %s: %v", describePEM(cfg.MinIOClientKey), err)
+		}
+		certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate %s: %v", describePEM(cfg.MinIOClientCert), err)
+		}
+		if compliance.Enabled(cfg) {
+			if err := compliance.CheckCertificate(&certificate); err != nil {
+				return nil, fmt.Errorf("client certificate %s: %v", describePEM(cfg.MinIOClientCert), err)
+			}
+		}
+		transport.TLSClientConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return transport, nil
+}
+
+// NewClient creates a MinIO client that sends its requests over transport,
+// authenticated with the configured STORAGE_AUTH_METHOD
+func NewClient(cfg *config.Config, transport http.RoundTripper) (*minio.Client, error) {
+	creds, transport, err := newCredentials(cfg, transport)
+	if err != nil {
+		return nil, err
+	}
+
+	minioClient, err := minio.New(cfg.MinIOEndpoint, &minio.Options{
+		Creds:     creds,
+		Secure:    cfg.MinIOUseSSL,
+		Transport: transport,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MinIO client: %v", err)
+	}
+	return minioClient, nil
+}
+
+// NewReplicaClient creates a MinIO client for the replica bucket of
+// REPLICA_ENDPOINT. It signs with REPLICA_ACCESS_KEY and REPLICA_SECRET_KEY
+// when they are set, and otherwise authenticates as NewClient does.
+func NewReplicaClient(cfg *config.Config, transport http.RoundTripper) (*minio.Client, error) {
+	client, err := newEndpointClient(cfg, cfg.ReplicaEndpoint, cfg.ReplicaUseSSL, cfg.ReplicaAccessKey, cfg.ReplicaSecretKey, transport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create replica MinIO client: %v", err)
+	}
+	return client, nil
+}
+
+// NewTargetClient creates a MinIO client for a secondary storage target. It
+// signs with the target's keys when they are set, and otherwise
+// authenticates as NewClient does.
+func NewTargetClient(cfg *config.Config, target config.StorageTarget, transport http.RoundTripper) (*minio.Client, error) {
+	client, err := newEndpointClient(cfg, target.Endpoint, target.UseSSL, target.AccessKey, target.SecretKey, transport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MinIO client for secondary target %s: %v", target.Name, err)
+	}
+	return client, nil
+}
+
+// newEndpointClient creates a client for another endpoint than
+// MINIO_ENDPOINT, signing with static keys when accessKey is set
+func newEndpointClient(cfg *config.Config, endpoint string, useSSL bool, accessKey, secretKey string, transport http.RoundTripper) (*minio.Client, error) {
+	var creds *credentials.Credentials
+	if accessKey != "" {
+		creds = credentials.NewStaticV4(accessKey, secretKey, "")
+	} else {
+		var err error
+		if creds, transport, err = newCredentials(cfg, transport); err != nil {
+			return nil, err
+		}
+	}
+
+	return minio.New(endpoint, &minio.Options{
+		Creds:     creds,
+		Secure:    useSSL,
+		Transport: transport,
+	})
+}
+
+// CertificateError explains a TLS failure talking to MinIO and how to fix it.
+// It returns an empty string for errors that are not about certificates.
+func CertificateError(err error) string {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	// Alerts from the server arrive as unexported error types, so the client
+	// certificate cases can only be told apart by message
+	message := err.Error()
+
+	switch {
+	case errors.As(err, &unknownAuthority):
+		issuer := "unknown issuer"
+		if unknownAuthority.Cert != nil {
+			issuer = fmt.Sprintf("%q", unknownAuthority.Cert.Issuer.String())
+		}
+		return fmt.Sprintf("MinIO server certificate is signed by an untrusted authority (%s); add its CA to CA_BUNDLE_PATH", issuer)
+	case errors.As(err, &hostname):
+		return fmt.Sprintf("MinIO server certificate does not match the MINIO_ENDPOINT host: %v", hostname)
+	case errors.As(err, &invalid):
+		return fmt.Sprintf("MinIO server certificate is invalid: %v", invalid)
+	case strings.Contains(message, "server gave HTTP response to HTTPS client"):
+		return "MinIO endpoint did not answer with TLS; check MINIO_USE_SSL"
+	case strings.Contains(message, "remote error: tls: certificate required"):
+		return "MinIO requires a client certificate; set CLIENT_CERT_PATH and CLIENT_KEY_PATH"
+	case strings.Contains(message, "remote error: tls:") && strings.Contains(message, "certificate"):
+		return "MinIO rejected the client certificate; check CLIENT_CERT_PATH and the CAs MinIO trusts for clients"
+	}
+	return ""
+}
+
+// readPEM returns PEM data given either inline or as the path of a file
+// holding it
+func readPEM(source string) ([]byte, error) {
+	if isInlinePEM(source) {
+		return []byte(source), nil
+	}
+	return os.ReadFile(source)
+}
+
+// describePEM names a PEM source in errors without echoing inline content
+func describePEM(source string) string {
+	if isInlinePEM(source) {
+		return "(inline PEM)"
+	}
+	return source
+}
+
+func isInlinePEM(source string) bool {
+	return strings.HasPrefix(strings.TrimSpace(source), "-----BEGIN")
+}
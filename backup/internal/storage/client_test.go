@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/config"
+)
+
+func testConfig() *config.Config {
+	return &config.Config{
+		MinIOEndpoint:            "localhost:9000",
+		MinIOAccessKey:           "testkey",
+		MinIOSecretKey:           "testsecret",
+		MinIOUseSSL:              true,
+		MinIOMaxIdleConns:        32,
+		MinIOIdleConnTimeout:     2 * time.Minute,
+		MinIOTLSHandshakeTimeout: 5 * time.Second,
+	}
+}
+
+// newCertificate creates a self-signed CA certificate and returns it with the
+// PEM encoding of the certificate and its key
+func newCertificate(t *testing.T) (*x509.Certificate, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-minio-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return cert,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeCABundle writes a self-signed CA certificate to a temporary file
+func writeCABundle(t *testing.T) (string, *x509.Certificate) {
+	cert, certPEM, _ := newCertificate(t)
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(path, certPEM, 0600))
+	return path, cert
+}
+
+// serverCAPEM returns the PEM encoding of a test server's certificate
+func serverCAPEM(server *httptest.Server) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+}
+
+func TestNewTransport(t *testing.T) {
+	transport, err := NewTransport(testConfig())
+	require.NoError(t, err)
+
+	assert.Equal(t, 32, transport.MaxIdleConns)
+	assert.Equal(t, 32, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 2*time.Minute, transport.IdleConnTimeout)
+	assert.Equal(t, 5*time.Second, transport.TLSHandshakeTimeout)
+}
+
+func TestNewTransport_CABundle(t *testing.T) {
+	path, cert := writeCABundle(t)
+	cfg := testConfig()
+	cfg.MinIOCABundle = path
+
+	transport, err := NewTransport(cfg)
+	require.NoError(t, err)
+	require.NotNil(t, transport.TLSClientConfig.RootCAs)
+
+	_, err = cert.Verify(x509.VerifyOptions{Roots: transport.TLSClientConfig.RootCAs})
+	assert.NoError(t, err, "the bundle's CA is trusted")
+
+	cfg.MinIOCABundle = filepath.Join(t.TempDir(), "missing.pem")
+	_, err = NewTransport(cfg)
+	assert.Error(t, err)
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	require.NoError(t, os.WriteFile(empty, []byte("not a certificate"), 0600))
+	cfg.MinIOCABundle = empty
+	_, err = NewTransport(cfg)
+	assert.Error(t, err)
+}
+
+func TestNewClient_SharesTransport(t *testing.T) {
+	cfg := testConfig()
+	transport, err := NewTransport(cfg)
+	require.NoError(t, err)
+
+	backupClient, err := NewClient(cfg, transport)
+	require.NoError(t, err)
+	cleanupClient, err := NewClient(cfg, transport)
+	require.NoError(t, err)
+
+	assert.Equal(t, "localhost:9000", backupClient.EndpointURL().Host)
+	assert.Equal(t, backupClient.EndpointURL(), cleanupClient.EndpointURL())
+}
+
+func TestNewTransport_InlineCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	cfg := testConfig()
+	transport, err := NewTransport(cfg)
+	require.NoError(t, err)
+	_, err = (&http.Client{Transport: transport}).Get(server.URL)
+	require.Error(t, err)
+	assert.Contains(t, CertificateError(err), "untrusted authority")
+
+	cfg.MinIOCABundle = serverCAPEM(server)
+	transport, err = NewTransport(cfg)
+	require.NoError(t, err)
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+}
+
+func TestNewTransport_MutualTLS(t *testing.T) {
+	clientCert, certPEM, keyPEM := newCertificate(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	cfg := testConfig()
+	cfg.MinIOCABundle = serverCAPEM(server)
+	transport, err := NewTransport(cfg)
+	require.NoError(t, err)
+	_, err = (&http.Client{Transport: transport}).Get(server.URL)
+	require.Error(t, err)
+	assert.Contains(t, CertificateError(err), "requires a client certificate", "%v", err)
+
+	// Certificate from a file, key inline
+	certPath := filepath.Join(t.TempDir(), "client.crt")
+	require.NoError(t, os.WriteFile(certPath, certPEM, 0600))
+	cfg.MinIOClientCert = certPath
+	cfg.MinIOClientKey = string(keyPEM)
+	transport, err = NewTransport(cfg)
+	require.NoError(t, err)
+	require.Len(t, transport.TLSClientConfig.Certificates, 1)
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	_, _, otherKeyPEM := newCertificate(t)
+	cfg.MinIOClientKey = string(otherKeyPEM)
+	_, err = NewTransport(cfg)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "PRIVATE KEY", "inline PEM is not echoed")
+}
+
+func TestCertificateError(t *testing.T) {
+	assert.Empty(t, CertificateError(errors.New("connection refused")))
+	assert.Contains(t, CertificateError(errors.New("http: server gave HTTP response to HTTPS client")), "MINIO_USE_SSL")
+	assert.Contains(t, CertificateError(errors.New("remote error: tls: certificate required")), "CLIENT_CERT_PATH")
+	assert.Contains(t, CertificateError(errors.New("remote error: tls: bad certificate")), "rejected the client certificate")
+}
@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"cluster-backup/internal/config"
+)
+
+// serviceAccountTokenFile is the pod's own service account token, used for
+// AssumeRoleWithWebIdentity when no projected token is configured
+const serviceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// roleSessionName identifies backup sessions in STS audit logs
+const roleSessionName = "cluster-backup"
+
+// newCredentials returns the credentials for the configured authentication
+// method and the transport requests must go through. Only GCP workload
+// identity changes the transport, to attach its bearer tokens.
+func newCredentials(cfg *config.Config, transport http.RoundTripper) (*credentials.Credentials, http.RoundTripper, error) {
+	switch cfg.StorageAuthMethod {
+	case config.StorageAuthStatic, "":
+		if cfg.SecretProvider == config.SecretProviderVault {
+			provider, err := newVaultProvider(cfg, rotationHook(transport))
+			if err != nil {
+				return nil, nil, err
+			}
+			return credentials.New(provider), transport, nil
+		}
+		if cfg.MinIOAccessKeyFile != "" || cfg.MinIOSecretKeyFile != "" {
+			creds := credentials.New(newFileProvider(cfg, rotationHook(transport)))
+			// Read the files now so a missing one fails at startup
+			if _, err := creds.Get(); err != nil {
+				return nil, nil, err
+			}
+			return creds, transport, nil
+		}
+		return credentials.NewStaticV4(cfg.MinIOAccessKey, cfg.MinIOSecretKey, ""), transport, nil
+
+	case config.StorageAuthIRSA:
+		// The EKS pod identity webhook injects AWS_ROLE_ARN and
+		// AWS_WEB_IDENTITY_TOKEN_FILE, which take precedence over these
+		iam := &credentials.IAM{Endpoint: cfg.StorageSTSEndpoint}
+		iam.EKSIdentity.RoleARN = cfg.StorageRoleARN
+		iam.EKSIdentity.TokenFile = cfg.StorageWebIdentityTokenFile
+		iam.EKSIdentity.RoleSessionName = roleSessionName
+		return credentials.New(iam), transport, nil
+
+	case config.StorageAuthSTSWebIdentity:
+		tokenFile := cfg.StorageWebIdentityTokenFile
+		if tokenFile == "" {
+			tokenFile = serviceAccountTokenFile
+		}
+		stsEndpoint := cfg.StorageSTSEndpoint
+		if stsEndpoint == "" {
+			stsEndpoint = endpointURL(cfg)
+		}
+		creds, err := credentials.NewSTSWebIdentity(stsEndpoint, func() (*credentials.WebIdentityToken, error) {
+			token, err := os.ReadFile(tokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read web identity token: %v", err)
+			}
+			return &credentials.WebIdentityToken{Token: strings.TrimSpace(string(token))}, nil
+		}, func(identity *credentials.STSWebIdentity) {
+			// The STS endpoint is normally MinIO itself, reached with the same
+			// CA bundle and client certificate
+			identity.Client = &http.Client{Transport: transport}
+			identity.RoleARN = cfg.StorageRoleARN
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to configure STS web identity: %v", err)
+		}
+		return creds, transport, nil
+
+	case config.StorageAuthGCPWorkloadIdentity:
+		anonymous := credentials.NewStatic("", "", "", credentials.SignatureAnonymous)
+		return anonymous, &bearerTransport{base: transport, tokens: newMetadataTokenSource()}, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported storage auth method %q", cfg.StorageAuthMethod)
+}
+
+// endpointURL returns the MinIO endpoint with its scheme
+func endpointURL(cfg *config.Config) string {
+	if cfg.MinIOUseSSL {
+		return "https://" + cfg.MinIOEndpoint
+	}
+	return "http://" + cfg.MinIOEndpoint
+}
+
+// bearerTransport authenticates unsigned requests with an OAuth access token,
+// which the GCS XML API accepts in place of a signature
+type bearerTransport struct {
+	base   http.RoundTripper
+	tokens *metadataTokenSource
+}
+
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.tokens.Token()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
+
+// metadataTokenSource fetches access tokens for the workload's service account
+// from the GKE metadata server and caches them until shortly before expiry
+type metadataTokenSource struct {
+	url    string
+	client *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func newMetadataTokenSource() *metadataTokenSource {
+	// GCE_METADATA_HOST is the override honored by Google's client libraries
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+	return &metadataTokenSource{
+		url:    "http://" + host + "/computeMetadata/v1/instance/service-accounts/default/token",
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Token returns a valid access token, refreshing it when it is about to expire
+func (s *metadataTokenSource) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Now().Before(s.expires) {
+		return s.token, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch workload identity token: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch workload identity token: metadata server returned %s", resp.Status)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode workload identity token: %v", err)
+	}
+	if body.AccessToken == "" {
+		return "", fmt.Errorf("metadata server returned an empty workload identity token")
+	}
+
+	s.token = body.AccessToken
+	// Refresh a minute early so a token never expires mid-request
+	s.expires = time.Now().Add(time.Duration(body.ExpiresIn)*time.Second - time.Minute)
+	return s.token, nil
+}
@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/config"
+)
+
+func TestNewCredentials_Static(t *testing.T) {
+	cfg := testConfig()
+	creds, _, err := newCredentials(cfg, http.DefaultTransport)
+	require.NoError(t, err)
+
+	value, err := creds.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "testkey", value.AccessKeyID)
+	assert.Equal(t, "testsecret", value.SecretAccessKey)
+}
+
+func TestNewCredentials_STSWebIdentity(t *testing.T) {
+	var form url.Values
+	sts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		form = r.PostForm
+		fmt.Fprint(w, `<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+<AssumeRoleWithWebIdentityResult><Credentials>
+<AccessKeyId>temp-access</AccessKeyId><SecretAccessKey>temp-secret</SecretAccessKey>
+<SessionToken>temp-session</SessionToken><Expiration>2099-01-01T00:00:00Z</Expiration>
+</Credentials></AssumeRoleWithWebIdentityResult></AssumeRoleWithWebIdentityResponse>`)
+	}))
+	defer sts.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("service-account-jwt\n"), 0600))
+
+	cfg := testConfig()
+	cfg.MinIOAccessKey, cfg.MinIOSecretKey = "", ""
+	cfg.StorageAuthMethod = config.StorageAuthSTSWebIdentity
+	cfg.StorageSTSEndpoint = sts.URL
+	cfg.StorageWebIdentityTokenFile = tokenFile
+	cfg.StorageRoleARN = "arn:minio:iam:::role/backup"
+
+	creds, _, err := newCredentials(cfg, http.DefaultTransport)
+	require.NoError(t, err)
+	value, err := creds.Get()
+	require.NoError(t, err)
+
+	assert.Equal(t, "temp-access", value.AccessKeyID)
+	assert.Equal(t, "temp-session", value.SessionToken)
+	assert.Equal(t, "AssumeRoleWithWebIdentity", form.Get("Action"))
+	assert.Equal(t, "service-account-jwt", form.Get("WebIdentityToken"))
+	assert.Equal(t, "arn:minio:iam:::role/backup", form.Get("RoleArn"))
+}
+
+func TestNewCredentials_GCPWorkloadIdentity(t *testing.T) {
+	tokenRequests := 0
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Google", r.Header.Get("Metadata-Flavor"))
+		tokenRequests++
+		fmt.Fprint(w, `{"access_token":"ya29.token","expires_in":3600,"token_type":"Bearer"}`)
+	}))
+	defer metadata.Close()
+	t.Setenv("GCE_METADATA_HOST", metadata.Listener.Addr().String())
+
+	var authorization []string
+	gcs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = append(authorization, r.Header.Get("Authorization"))
+	}))
+	defer gcs.Close()
+
+	cfg := testConfig()
+	cfg.StorageAuthMethod = config.StorageAuthGCPWorkloadIdentity
+	creds, transport, err := newCredentials(cfg, http.DefaultTransport)
+	require.NoError(t, err)
+	value, err := creds.Get()
+	require.NoError(t, err)
+	assert.True(t, value.SignerType.IsAnonymous(), "requests are not signed")
+
+	client := &http.Client{Transport: transport}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(gcs.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	assert.Equal(t, []string{"Bearer ya29.token", "Bearer ya29.token"}, authorization)
+	assert.Equal(t, 1, tokenRequests, "the token is cached until it expires")
+}
+
+func TestNewCredentials_GCPWorkloadIdentityUnavailable(t *testing.T) {
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	}))
+	defer metadata.Close()
+	t.Setenv("GCE_METADATA_HOST", metadata.Listener.Addr().String())
+
+	cfg := testConfig()
+	cfg.StorageAuthMethod = config.StorageAuthGCPWorkloadIdentity
+	_, transport, err := newCredentials(cfg, http.DefaultTransport)
+	require.NoError(t, err)
+
+	_, err = (&http.Client{Transport: transport}).Get("http://127.0.0.1:1")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "workload identity token")
+}
+
+func TestNewCredentials_Vault(t *testing.T) {
+	secret := map[string]string{"minio_access_key": "vault-access", "minio_secret_key": "vault-secret"}
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "vault-token", r.Header.Get("X-Vault-Token"))
+		switch r.URL.Path {
+		case "/v1/auth/token/lookup-self":
+			fmt.Fprint(w, `{"data":{"ttl":0}}`)
+		case "/v1/secret/data/backup":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": secret}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer vault.Close()
+
+	cfg := testConfig()
+	cfg.SecretProvider = config.SecretProviderVault
+	cfg.VaultAddress = vault.URL
+	cfg.VaultToken = "vault-token"
+	cfg.VaultPath = "secret/backup"
+
+	creds, _, err := newCredentials(cfg, http.DefaultTransport)
+	require.NoError(t, err)
+	value, err := creds.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "vault-access", value.AccessKeyID)
+	assert.Equal(t, "vault-secret", value.SecretAccessKey)
+
+	// Rotated keys are read at the next refresh
+	secret["minio_secret_key"] = "rotated-secret"
+	value, err = creds.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "rotated-secret", value.SecretAccessKey)
+
+	// Keys missing from the secret fall back to the environment
+	delete(secret, "minio_access_key")
+	provider, err := newVaultProvider(cfg, rotationHook(http.DefaultTransport))
+	require.NoError(t, err)
+	value, err = provider.Retrieve()
+	require.NoError(t, err)
+	assert.Equal(t, "testkey", value.AccessKeyID)
+
+	cfg.MinIOAccessKey = ""
+	_, _, err = newCredentials(cfg, http.DefaultTransport)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "minio_access_key")
+}
+
+func TestNewCredentials_Unsupported(t *testing.T) {
+	cfg := testConfig()
+	cfg.StorageAuthMethod = "kerberos"
+	_, _, err := newCredentials(cfg, http.DefaultTransport)
+	assert.Error(t, err)
+}
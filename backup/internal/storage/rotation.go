@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"cluster-backup/internal/config"
+)
+
+// ReloadingTransport is the MinIO transport for long-running processes. It
+// rebuilds the underlying transport when the CA bundle or client certificate
+// files change, and clients created on it re-read rotated keys, so credentials
+// rotated mid-run apply without a restart.
+type ReloadingTransport struct {
+	// OnRotate, when set, is called for every rotation that is detected,
+	// with the error if the new credentials could not be applied
+	OnRotate func(source string, err error)
+
+	cfg   *config.Config
+	watch *fileWatch
+
+	mu      sync.RWMutex
+	current *http.Transport
+}
+
+// NewReloadingTransport builds the transport with NewTransport and watches the
+// TLS files it was built from
+func NewReloadingTransport(cfg *config.Config) (*ReloadingTransport, error) {
+	transport, err := NewTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, source := range []string{cfg.MinIOCABundle, cfg.MinIOClientCert, cfg.MinIOClientKey} {
+		if source != "" && !isInlinePEM(source) {
+			files = append(files, source)
+		}
+	}
+	return &ReloadingTransport{
+		cfg:     cfg,
+		watch:   newFileWatch(cfg.CredentialsRefreshInterval, files...),
+		current: transport,
+	}, nil
+}
+
+// RoundTrip sends the request over the current transport, rebuilding it first
+// if the TLS files changed
+func (t *ReloadingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if changed := t.watch.Changed(); len(changed) > 0 {
+		t.reload(changed)
+	}
+	t.mu.RLock()
+	current := t.current
+	t.mu.RUnlock()
+	return current.RoundTrip(req)
+}
+
+// reload replaces the transport. Requests in flight finish on the old one,
+// whose idle connections are closed so none is reused with stale certificates.
+func (t *ReloadingTransport) reload(changed []string) {
+	source := strings.Join(changed, ", ")
+	transport, err := NewTransport(t.cfg)
+	if err != nil {
+		// Keep the working transport and try again at the next check, in
+		// case the files were caught halfway through an update
+		t.watch.Forget(changed...)
+		t.notify(source, err)
+		return
+	}
+
+	t.mu.Lock()
+	previous := t.current
+	t.current = transport
+	t.mu.Unlock()
+	previous.CloseIdleConnections()
+	t.notify(source, nil)
+}
+
+func (t *ReloadingTransport) notify(source string, err error) {
+	if t.OnRotate != nil {
+		t.OnRotate(source, err)
+	}
+}
+
+// rotationHook returns where providers report rotated keys: the OnRotate hook
+// of a ReloadingTransport, or nowhere for one-shot clients
+func rotationHook(transport http.RoundTripper) func(source string, err error) {
+	if reloading, ok := transport.(*ReloadingTransport); ok {
+		return reloading.notify
+	}
+	return func(string, error) {}
+}
+
+// fileProvider supplies static keys read from files, typically a mounted
+// Secret, and reads them again when the files change. A key without a file
+// comes from MINIO_ACCESS_KEY or MINIO_SECRET_KEY.
+type fileProvider struct {
+	accessKeyFile string
+	secretKeyFile string
+	accessKey     string
+	secretKey     string
+	watch         *fileWatch
+	onRotate      func(source string, err error)
+	current       credentials.Value
+}
+
+func newFileProvider(cfg *config.Config, onRotate func(string, error)) *fileProvider {
+	var files []string
+	for _, file := range []string{cfg.MinIOAccessKeyFile, cfg.MinIOSecretKeyFile} {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return &fileProvider{
+		accessKeyFile: cfg.MinIOAccessKeyFile,
+		secretKeyFile: cfg.MinIOSecretKeyFile,
+		accessKey:     cfg.MinIOAccessKey,
+		secretKey:     cfg.MinIOSecretKey,
+		watch:         newFileWatch(cfg.CredentialsRefreshInterval, files...),
+		onRotate:      onRotate,
+	}
+}
+
+// Retrieve reads the keys from their files. Once keys were read, a failed
+// read keeps them in use and the files are read again at the next check.
+func (p *fileProvider) Retrieve() (credentials.Value, error) {
+	value, err := p.read()
+	if err != nil {
+		if p.current.AccessKeyID == "" {
+			return credentials.Value{}, err
+		}
+		p.watch.Forget(p.watch.paths...)
+		p.onRotate(strings.Join(p.watch.paths, ", "), err)
+		return p.current, nil
+	}
+	if p.current.AccessKeyID != "" && value != p.current {
+		p.onRotate(strings.Join(p.watch.paths, ", "), nil)
+	}
+	p.current = value
+	return value, nil
+}
+
+func (p *fileProvider) read() (credentials.Value, error) {
+	accessKey, err := readKey(p.accessKeyFile, p.accessKey)
+	if err != nil {
+		return credentials.Value{}, err
+	}
+	secretKey, err := readKey(p.secretKeyFile, p.secretKey)
+	if err != nil {
+		return credentials.Value{}, err
+	}
+	if accessKey == "" || secretKey == "" {
+		return credentials.Value{}, fmt.Errorf("MinIO access and secret keys must not be empty")
+	}
+	return credentials.Value{
+		AccessKeyID:     accessKey,
+		SecretAccessKey: secretKey,
+		SignerType:      credentials.SignatureV4,
+	}, nil
+}
+
+// RetrieveWithCredContext reads the keys from their files
+func (p *fileProvider) RetrieveWithCredContext(_ *credentials.CredContext) (credentials.Value, error) {
+	return p.Retrieve()
+}
+
+// IsExpired reports whether the key files changed since they were read
+func (p *fileProvider) IsExpired() bool {
+	return len(p.watch.Changed()) > 0
+}
+
+// readKey returns the trimmed content of file, or fallback when no file is set
+func readKey(file, fallback string) (string, error) {
+	if file == "" {
+		return fallback, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read MinIO key file: %v", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// fileWatch reports when any of a set of files changes, checking at most once
+// per interval. Kubernetes updates a mounted Secret by swapping a symlink, so
+// the file a path resolves to gets a new modification time.
+type fileWatch struct {
+	paths    []string
+	interval time.Duration
+
+	mu     sync.Mutex
+	next   time.Time
+	stamps map[string]fileStamp
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func newFileWatch(interval time.Duration, paths ...string) *fileWatch {
+	w := &fileWatch{
+		paths:    paths,
+		interval: interval,
+		next:     time.Now().Add(interval),
+		stamps:   make(map[string]fileStamp, len(paths)),
+	}
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			w.stamps[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return w
+}
+
+// Changed returns the files that changed since the last check. A file that
+// cannot be read is skipped until it can.
+func (w *fileWatch) Changed() []string {
+	if len(w.paths) == 0 {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if time.Now().Before(w.next) {
+		return nil
+	}
+	w.next = time.Now().Add(w.interval)
+
+	var changed []string
+	for _, path := range w.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		stamp := fileStamp{modTime: info.ModTime(), size: info.Size()}
+		previous, ok := w.stamps[path]
+		if !ok || !previous.modTime.Equal(stamp.modTime) || previous.size != stamp.size {
+			w.stamps[path] = stamp
+			changed = append(changed, path)
+		}
+	}
+	return changed
+}
+
+// Forget makes the next check report paths as changed
+func (w *fileWatch) Forget(paths ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, path := range paths {
+		delete(w.stamps, path)
+	}
+}
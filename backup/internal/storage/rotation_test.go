@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rotateFile rewrites a file with a later modification time, as a Secret
+// volume update does
+func rotateFile(t *testing.T, path string, data []byte) {
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+}
+
+func TestFileProvider_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	accessKeyFile := filepath.Join(dir, "accesskey")
+	secretKeyFile := filepath.Join(dir, "secretkey")
+	require.NoError(t, os.WriteFile(accessKeyFile, []byte("old-access\n"), 0600))
+	require.NoError(t, os.WriteFile(secretKeyFile, []byte("old-secret\n"), 0600))
+
+	cfg := testConfig()
+	cfg.MinIOAccessKey, cfg.MinIOSecretKey = "", ""
+	cfg.MinIOAccessKeyFile = accessKeyFile
+	cfg.MinIOSecretKeyFile = secretKeyFile
+	transport, err := NewReloadingTransport(cfg)
+	require.NoError(t, err)
+	var rotations []string
+	var rotationErrors []error
+	transport.OnRotate = func(source string, err error) {
+		rotations = append(rotations, source)
+		rotationErrors = append(rotationErrors, err)
+	}
+
+	creds, _, err := newCredentials(cfg, transport)
+	require.NoError(t, err)
+	value, err := creds.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "old-access", value.AccessKeyID)
+	assert.Equal(t, "old-secret", value.SecretAccessKey)
+
+	rotateFile(t, accessKeyFile, []byte("new-access"))
+	rotateFile(t, secretKeyFile, []byte("new-secret"))
+	value, err = creds.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "new-access", value.AccessKeyID)
+	assert.Equal(t, "new-secret", value.SecretAccessKey)
+	require.Len(t, rotations, 1)
+	assert.NoError(t, rotationErrors[0])
+
+	// A key file emptied mid-update keeps the previous keys in use
+	rotateFile(t, secretKeyFile, nil)
+	value, err = creds.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "new-secret", value.SecretAccessKey)
+	require.Len(t, rotations, 2)
+	assert.Error(t, rotationErrors[1])
+}
+
+func TestFileProvider_MissingFile(t *testing.T) {
+	cfg := testConfig()
+	cfg.MinIOSecretKeyFile = filepath.Join(t.TempDir(), "missing")
+	_, _, err := newCredentials(cfg, http.DefaultTransport)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "key file")
+}
+
+func TestReloadingTransport_ClientCertificateRotation(t *testing.T) {
+	trustedCert, trustedCertPEM, trustedKeyPEM := newCertificate(t)
+	_, otherCertPEM, otherKeyPEM := newCertificate(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(trustedCert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "tls.crt")
+	keyPath := filepath.Join(dir, "tls.key")
+	require.NoError(t, os.WriteFile(certPath, otherCertPEM, 0600))
+	require.NoError(t, os.WriteFile(keyPath, otherKeyPEM, 0600))
+
+	cfg := testConfig()
+	cfg.MinIOCABundle = serverCAPEM(server)
+	cfg.MinIOClientCert = certPath
+	cfg.MinIOClientKey = keyPath
+	transport, err := NewReloadingTransport(cfg)
+	require.NoError(t, err)
+	var rotated []string
+	transport.OnRotate = func(source string, err error) {
+		require.NoError(t, err)
+		rotated = append(rotated, source)
+	}
+	client := &http.Client{Transport: transport}
+
+	_, err = client.Get(server.URL)
+	require.Error(t, err, "the server does not trust the first certificate")
+
+	rotateFile(t, certPath, trustedCertPEM)
+	rotateFile(t, keyPath, trustedKeyPEM)
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, []string{certPath + ", " + keyPath}, rotated)
+}
+
+func TestReloadingTransport_KeepsTransportOnBadFiles(t *testing.T) {
+	caPath, _ := writeCABundle(t)
+	cfg := testConfig()
+	cfg.MinIOCABundle = caPath
+	transport, err := NewReloadingTransport(cfg)
+	require.NoError(t, err)
+	previous := transport.current
+
+	var failures int
+	transport.OnRotate = func(source string, err error) {
+		assert.Equal(t, caPath, source)
+		if err != nil {
+			failures++
+		}
+	}
+
+	rotateFile(t, caPath, []byte("not a certificate"))
+	transport.RoundTrip(httptest.NewRequest(http.MethodGet, "http://127.0.0.1:1", nil))
+	assert.Equal(t, 1, failures)
+	assert.Same(t, previous, transport.current)
+
+	// The broken file is retried at the next check
+	transport.RoundTrip(httptest.NewRequest(http.MethodGet, "http://127.0.0.1:1", nil))
+	assert.Equal(t, 2, failures)
+}
+
+func TestFileWatch_Interval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(path, []byte("a"), 0600))
+
+	watch := newFileWatch(time.Hour, path)
+	rotateFile(t, path, []byte("b"))
+	assert.Empty(t, watch.Changed(), "files are not checked before the interval elapses")
+
+	watch.next = time.Now()
+	assert.Equal(t, []string{path}, watch.Changed())
+	watch.next = time.Now()
+	assert.Empty(t, watch.Changed())
+}
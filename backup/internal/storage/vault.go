@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"shared-config/vault"
+
+	"cluster-backup/internal/config"
+)
+
+// vaultProvider supplies static MinIO keys kept in a Vault KV secret. Keys the
+// secret does not hold fall back to MINIO_ACCESS_KEY and MINIO_SECRET_KEY. The
+// secret is read again every CREDENTIALS_REFRESH_INTERVAL, so long-running
+// processes pick up rotated keys.
+type vaultProvider struct {
+	credentials.Expiry
+	client   *vault.Client
+	path     string
+	interval time.Duration
+	onRotate func(source string, err error)
+	current  credentials.Value
+
+	fallbackAccessKey string
+	fallbackSecretKey string
+}
+
+// newVaultProvider connects to Vault and reads the keys once, so a rejected
+// token or an incomplete secret fails at startup
+func newVaultProvider(cfg *config.Config, onRotate func(string, error)) (*vaultProvider, error) {
+	client, err := vault.NewClient(vault.Config{
+		Address: cfg.VaultAddress,
+		Token:   cfg.VaultToken,
+		Role:    cfg.VaultRole,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to vault: %v", err)
+	}
+	provider := &vaultProvider{
+		client:            client,
+		path:              cfg.VaultPath,
+		interval:          cfg.CredentialsRefreshInterval,
+		onRotate:          onRotate,
+		fallbackAccessKey: cfg.MinIOAccessKey,
+		fallbackSecretKey: cfg.MinIOSecretKey,
+	}
+	if _, err := provider.Retrieve(); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// Retrieve reads the keys from Vault. The client renews its token as needed.
+// Once keys were read, a failed read keeps them in use until the next refresh.
+func (p *vaultProvider) Retrieve() (credentials.Value, error) {
+	value, err := p.read()
+	source := "vault " + p.path
+	if err != nil {
+		if p.current.AccessKeyID == "" {
+			return credentials.Value{}, err
+		}
+		p.onRotate(source, err)
+		value = p.current
+	} else if p.current.AccessKeyID != "" && value != p.current {
+		p.onRotate(source, nil)
+	}
+	p.current = value
+	p.SetExpiration(time.Now().Add(p.interval), 0)
+	return value, nil
+}
+
+func (p *vaultProvider) read() (credentials.Value, error) {
+	secret, err := p.client.Read(p.path)
+	if err != nil {
+		return credentials.Value{}, err
+	}
+
+	accessKey := secret["minio_access_key"]
+	if accessKey == "" {
+		accessKey = p.fallbackAccessKey
+	}
+	secretKey := secret["minio_secret_key"]
+	if secretKey == "" {
+		secretKey = p.fallbackSecretKey
+	}
+	if accessKey == "" || secretKey == "" {
+		return credentials.Value{}, fmt.Errorf("vault secret %s must hold minio_access_key and minio_secret_key", p.path)
+	}
+	return credentials.Value{
+		AccessKeyID:     accessKey,
+		SecretAccessKey: secretKey,
+		SignerType:      credentials.SignatureV4,
+	}, nil
+}
+
+// RetrieveWithCredContext reads the keys from Vault; Vault is reached with its
+// own client, not the MinIO transport
+func (p *vaultProvider) RetrieveWithCredContext(_ *credentials.CredContext) (credentials.Value, error) {
+	return p.Retrieve()
+}
@@ -0,0 +1,216 @@
+// Package tenancy routes the backup of each namespace to the bucket and
+// credentials of the team owning it, so teams can only reach their own
+// backup data. Routing comes from a ConfigMap listing the tenants; the
+// namespaces no tenant claims go to the default bucket.
+package tenancy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"cluster-backup/internal/config"
+)
+
+// ConfigMapKey is the key of the tenancy ConfigMap holding the tenants
+const ConfigMapKey = "tenancy.yaml"
+
+// Keys of a tenant's credentials Secret
+const (
+	AccessKeyField = "accessKey"
+	SecretKeyField = "secretKey"
+)
+
+// Config lists the tenants of a cluster
+type Config struct {
+	// NamespaceLabel, when set, matches tenants against the value of this
+	// namespace label instead of the namespace name
+	NamespaceLabel string   `yaml:"namespace_label"`
+	Tenants        []Tenant `yaml:"tenants"`
+}
+
+// Tenant is a team owning the backups of some namespaces
+type Tenant struct {
+	Name string `yaml:"name"`
+	// Namespaces are the namespace names, or label values, of the tenant.
+	// Shell patterns such as payments-* are allowed; the first tenant
+	// matching a namespace owns it.
+	Namespaces []string `yaml:"namespaces"`
+	// Bucket defaults to MINIO_BUCKET
+	Bucket string `yaml:"bucket"`
+	// Prefix is prepended to the keys of the tenant's objects
+	Prefix string `yaml:"prefix"`
+	// Endpoint defaults to MINIO_ENDPOINT
+	Endpoint string `yaml:"endpoint"`
+	// CredentialsSecret names a Secret next to the ConfigMap holding the
+	// tenant's accessKey and secretKey
+	CredentialsSecret string `yaml:"credentials_secret"`
+}
+
+// Parse reads and checks a tenancy configuration
+func Parse(data []byte) (*Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse tenancy configuration: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for i, tenant := range cfg.Tenants {
+		if tenant.Name == "" {
+			return nil, fmt.Errorf("tenant %d has no name", i+1)
+		}
+		if seen[tenant.Name] {
+			return nil, fmt.Errorf("tenant %s is listed twice", tenant.Name)
+		}
+		seen[tenant.Name] = true
+		if len(tenant.Namespaces) == 0 {
+			return nil, fmt.Errorf("tenant %s has no namespaces", tenant.Name)
+		}
+		for _, pattern := range tenant.Namespaces {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("tenant %s has an invalid namespace pattern %q", tenant.Name, pattern)
+			}
+		}
+		if tenant.CredentialsSecret == "" {
+			return nil, fmt.Errorf("tenant %s has no credentials_secret", tenant.Name)
+		}
+		if tenant.Bucket == "" && tenant.Prefix == "" {
+			return nil, fmt.Errorf("tenant %s needs a bucket or a prefix of its own", tenant.Name)
+		}
+	}
+	return &cfg, nil
+}
+
+// Target is where the objects of a namespace are stored
+type Target struct {
+	// Tenant is empty for the default target
+	Tenant string
+	Client *minio.Client
+	Bucket string
+	Prefix string
+}
+
+// Key returns the bucket key of an object under the target's prefix
+func (t *Target) Key(key string) string {
+	if t.Prefix == "" {
+		return key
+	}
+	return strings.TrimSuffix(t.Prefix, "/") + "/" + key
+}
+
+// TrimKey returns the key of an object relative to the target's prefix, the
+// reverse of Key
+func (t *Target) TrimKey(key string) string {
+	if t.Prefix == "" {
+		return key
+	}
+	return strings.TrimPrefix(key, strings.TrimSuffix(t.Prefix, "/")+"/")
+}
+
+// Router resolves the target of each namespace
+type Router struct {
+	config        *Config
+	kubeClient    kubernetes.Interface
+	defaultTarget *Target
+	targets       map[string]*Target
+}
+
+// Load reads the tenancy ConfigMap and the credentials of every tenant from
+// namespace. Tenant clients use transport, so they trust the same CAs as the
+// default one. Namespaces no tenant claims are routed to defaultTarget.
+func Load(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string, cfg *config.Config, transport http.RoundTripper, defaultTarget *Target) (*Router, error) {
+	configMap, err := kubeClient.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tenancy ConfigMap %s/%s: %v", namespace, name, err)
+	}
+	data, ok := configMap.Data[ConfigMapKey]
+	if !ok {
+		return nil, fmt.Errorf("%s not found in ConfigMap %s/%s", ConfigMapKey, namespace, name)
+	}
+	tenancyConfig, err := Parse([]byte(data))
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]*Target)
+	for _, tenant := range tenancyConfig.Tenants {
+		secret, err := kubeClient.CoreV1().Secrets(namespace).Get(ctx, tenant.CredentialsSecret, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load credentials of tenant %s: %v", tenant.Name, err)
+		}
+		accessKey, secretKey := string(secret.Data[AccessKeyField]), string(secret.Data[SecretKeyField])
+		if accessKey == "" || secretKey == "" {
+			return nil, fmt.Errorf("secret %s/%s of tenant %s needs %s and %s", namespace, tenant.CredentialsSecret, tenant.Name, AccessKeyField, SecretKeyField)
+		}
+
+		endpoint := tenant.Endpoint
+		if endpoint == "" {
+			endpoint = cfg.MinIOEndpoint
+		}
+		client, err := minio.New(endpoint, &minio.Options{
+			Creds:     credentials.NewStaticV4(accessKey, secretKey, ""),
+			Secure:    cfg.MinIOUseSSL,
+			Transport: transport,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create MinIO client of tenant %s: %v", tenant.Name, err)
+		}
+
+		bucket := tenant.Bucket
+		if bucket == "" {
+			bucket = defaultTarget.Bucket
+		}
+		targets[tenant.Name] = &Target{Tenant: tenant.Name, Client: client, Bucket: bucket, Prefix: tenant.Prefix}
+	}
+	return newRouter(tenancyConfig, kubeClient, defaultTarget, targets), nil
+}
+
+func newRouter(cfg *Config, kubeClient kubernetes.Interface, defaultTarget *Target, targets map[string]*Target) *Router {
+	return &Router{
+		config:        cfg,
+		kubeClient:    kubeClient,
+		defaultTarget: defaultTarget,
+		targets:       targets,
+	}
+}
+
+// Route returns the target of a namespace
+func (r *Router) Route(ctx context.Context, namespace string) (*Target, error) {
+	value := namespace
+	if r.config.NamespaceLabel != "" {
+		ns, err := r.kubeClient.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read namespace %s: %v", namespace, err)
+		}
+		var ok bool
+		if value, ok = ns.Labels[r.config.NamespaceLabel]; !ok {
+			return r.defaultTarget, nil
+		}
+	}
+
+	for _, tenant := range r.config.Tenants {
+		for _, pattern := range tenant.Namespaces {
+			if matched, _ := path.Match(pattern, value); matched {
+				return r.targets[tenant.Name], nil
+			}
+		}
+	}
+	return r.defaultTarget, nil
+}
+
+// Tenants returns the targets of the tenants in the order they are listed
+func (r *Router) Tenants() []*Target {
+	targets := make([]*Target, 0, len(r.config.Tenants))
+	for _, tenant := range r.config.Tenants {
+		targets = append(targets, r.targets[tenant.Name])
+	}
+	return targets
+}
@@ -0,0 +1,127 @@
+package tenancy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"cluster-backup/internal/config"
+)
+
+const testTenancy = `
+tenants:
+  - name: payments
+    namespaces: [payments-*, billing]
+    bucket: payments-backups
+    credentials_secret: payments-backup-credentials
+  - name: shop
+    namespaces: ["*"]
+    prefix: tenants/shop
+    credentials_secret: shop-backup-credentials
+`
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse([]byte(testTenancy))
+	require.NoError(t, err)
+	require.Len(t, cfg.Tenants, 2)
+	assert.Equal(t, []string{"payments-*", "billing"}, cfg.Tenants[0].Namespaces)
+	assert.Equal(t, "payments-backup-credentials", cfg.Tenants[0].CredentialsSecret)
+
+	invalid := map[string]string{
+		"no_name":         "tenants: [{namespaces: [a], bucket: b, credentials_secret: s}]",
+		"duplicate":       "tenants: [{name: a, namespaces: [a], bucket: b, credentials_secret: s}, {name: a, namespaces: [b], bucket: b, credentials_secret: s}]",
+		"no_namespaces":   "tenants: [{name: a, bucket: b, credentials_secret: s}]",
+		"bad_pattern":     "tenants: [{name: a, namespaces: ['[a'], bucket: b, credentials_secret: s}]",
+		"no_credentials":  "tenants: [{name: a, namespaces: [a], bucket: b}]",
+		"shared_location": "tenants: [{name: a, namespaces: [a], credentials_secret: s}]",
+	}
+	for name, data := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse([]byte(data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func credentialsSecret(name string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "backup-system"},
+		Data:       map[string][]byte{AccessKeyField: []byte("access"), SecretKeyField: []byte("secret")},
+	}
+}
+
+func TestLoadAndRoute(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "backup-tenants", Namespace: "backup-system"},
+			Data:       map[string]string{ConfigMapKey: testTenancy},
+		},
+		credentialsSecret("payments-backup-credentials"),
+		credentialsSecret("shop-backup-credentials"),
+	)
+	defaultTarget := &Target{Bucket: "cluster-backups"}
+	cfg := &config.Config{MinIOEndpoint: "minio:9000"}
+
+	router, err := Load(context.Background(), kubeClient, "backup-system", "backup-tenants", cfg, nil, defaultTarget)
+	require.NoError(t, err)
+
+	target, err := router.Route(context.Background(), "payments-eu")
+	require.NoError(t, err)
+	assert.Equal(t, "payments", target.Tenant)
+	assert.Equal(t, "payments-backups", target.Bucket)
+	assert.NotNil(t, target.Client)
+
+	target, err = router.Route(context.Background(), "storefront")
+	require.NoError(t, err)
+	assert.Equal(t, "shop", target.Tenant, "the first matching tenant owns a namespace")
+	assert.Equal(t, "cluster-backups", target.Bucket, "the bucket defaults to the default target's")
+	assert.Equal(t, "tenants/shop/prod/ns/cm.yaml", target.Key("prod/ns/cm.yaml"))
+
+	tenants := router.Tenants()
+	require.Len(t, tenants, 2)
+	assert.Equal(t, "payments", tenants[0].Tenant)
+}
+
+func TestLoadMissingCredentials(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup-tenants", Namespace: "backup-system"},
+		Data:       map[string]string{ConfigMapKey: testTenancy},
+	})
+	_, err := Load(context.Background(), kubeClient, "backup-system", "backup-tenants", &config.Config{MinIOEndpoint: "minio:9000"}, nil, &Target{Bucket: "cluster-backups"})
+	assert.ErrorContains(t, err, "payments")
+}
+
+func TestRouteByLabel(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "checkout", Labels: map[string]string{"team": "payments"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "monitoring"}},
+	)
+	cfg := &Config{
+		NamespaceLabel: "team",
+		Tenants:        []Tenant{{Name: "payments", Namespaces: []string{"payments"}}},
+	}
+	defaultTarget := &Target{Bucket: "cluster-backups"}
+	payments := &Target{Tenant: "payments", Bucket: "payments-backups"}
+	router := newRouter(cfg, kubeClient, defaultTarget, map[string]*Target{"payments": payments})
+
+	target, err := router.Route(context.Background(), "checkout")
+	require.NoError(t, err)
+	assert.Same(t, payments, target)
+
+	target, err = router.Route(context.Background(), "monitoring")
+	require.NoError(t, err)
+	assert.Same(t, defaultTarget, target, "namespaces without the label stay in the default bucket")
+
+	_, err = router.Route(context.Background(), "missing")
+	assert.Error(t, err)
+}
+
+func TestTargetKey(t *testing.T) {
+	assert.Equal(t, "prod/ns/cm.yaml", (&Target{}).Key("prod/ns/cm.yaml"))
+	assert.Equal(t, "team-a/prod/ns/cm.yaml", (&Target{Prefix: "team-a/"}).Key("prod/ns/cm.yaml"))
+}
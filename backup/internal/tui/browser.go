@@ -0,0 +1,654 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"cluster-backup/internal/api"
+	"cluster-backup/internal/backup"
+	"cluster-backup/internal/dashboard"
+	"cluster-backup/internal/diff"
+)
+
+// Store provides the backup runs and stored objects the browser shows
+type Store interface {
+	List(ctx context.Context) ([]backup.ManifestSummary, error)
+	Load(ctx context.Context, backupID string) (*backup.Manifest, error)
+	GetObjectVersion(ctx context.Context, key, versionID string) ([]byte, error)
+}
+
+// Restorer starts and follows restore runs through the backup service
+type Restorer interface {
+	StartRestore(ctx context.Context, req api.RestoreRequest) (*api.Run, error)
+	RestoreProgress(ctx context.Context, runID string) (*api.RestoreRunProgress, error)
+	CancelRestore(ctx context.Context, runID string) error
+}
+
+// Key is a key press, either one of the named keys or the character typed
+type Key string
+
+const (
+	KeyUp       Key = "up"
+	KeyDown     Key = "down"
+	KeyPageUp   Key = "pgup"
+	KeyPageDown Key = "pgdown"
+	KeyEnter    Key = "enter"
+	KeyBack     Key = "back"
+	KeySpace    Key = "space"
+	KeyQuit     Key = "quit"
+)
+
+// chromeLines are the lines of a screen around its list: title, rules,
+// status and help
+const chromeLines = 5
+
+type viewKind int
+
+const (
+	runsView viewKind = iota
+	treeView
+	textView
+	diffView
+	restoreSelectView
+	restoreConfirmView
+	restoreProgressView
+)
+
+// view is one screen of the browser. Back returns to the view below it.
+type view struct {
+	kind   viewKind
+	title  string
+	help   string
+	items  []item
+	lines  []string
+	cursor int
+	offset int
+
+	manifest *backup.Manifest
+	changes  []diff.ResourceChange
+	wizard   *restoreWizard
+	// step is the wizard step a selection view is for
+	step     restoreStep
+	runID    string
+	progress *api.RestoreRunProgress
+}
+
+// item is a row of a list view
+type item struct {
+	label    string
+	value    string
+	selected bool
+	node     *dashboard.TreeNode
+}
+
+type restoreStep int
+
+const (
+	stepNamespaces restoreStep = iota
+	stepTypes
+)
+
+// restoreWizard collects a restore request over its steps. depth is the
+// number of views below the wizard, returned to once the restore starts.
+type restoreWizard struct {
+	manifest *backup.Manifest
+	request  api.RestoreRequest
+	depth    int
+}
+
+// Browser is the state of the terminal UI: a stack of views over the runs
+// of a cluster. It is driven by HandleKey and Tick and drawn with Render,
+// so it does not depend on a terminal.
+type Browser struct {
+	ctx      context.Context
+	store    Store
+	restorer Restorer
+	height   int
+	views    []*view
+	status   string
+	// diffFrom is the run marked as the older side of a diff
+	diffFrom string
+	done     bool
+}
+
+// NewBrowser lists the runs in store. restorer may be nil, in which case
+// restores cannot be started.
+func NewBrowser(ctx context.Context, store Store, restorer Restorer) (*Browser, error) {
+	b := &Browser{ctx: ctx, store: store, restorer: restorer, height: 24}
+	if err := b.loadRuns(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// SetHeight sets the number of terminal lines the browser renders to
+func (b *Browser) SetHeight(height int) {
+	b.height = height
+}
+
+// Done reports whether the user quit
+func (b *Browser) Done() bool {
+	return b.done
+}
+
+func (b *Browser) loadRuns() error {
+	summaries, err := b.store.List(b.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list backups: %v", err)
+	}
+
+	runs := &view{kind: runsView, title: "Backups", help: "enter: browse  d: diff  r: restore  g: refresh  q: quit"}
+	for _, summary := range summaries {
+		label := fmt.Sprintf("%-32s %-10s %s  %d namespaces, %d objects", summary.BackupID, summary.Status,
+			summary.StartTime.Local().Format("2006-01-02 15:04"), summary.NamespaceCount, summary.ObjectCount)
+		if summary.Interrupted {
+			label += " (interrupted)"
+		}
+		runs.items = append(runs.items, item{label: label, value: summary.BackupID})
+	}
+	b.views = []*view{runs}
+	return nil
+}
+
+func (b *Browser) top() *view {
+	return b.views[len(b.views)-1]
+}
+
+func (b *Browser) push(v *view) {
+	b.views = append(b.views, v)
+}
+
+// HandleKey applies a key press to the current view
+func (b *Browser) HandleKey(key Key) {
+	v := b.top()
+	b.status = ""
+
+	switch key {
+	case KeyQuit:
+		b.done = true
+		return
+	case KeyUp:
+		b.move(v, -1)
+		return
+	case KeyDown:
+		b.move(v, 1)
+		return
+	case KeyPageUp:
+		b.move(v, -b.listHeight())
+		return
+	case KeyPageDown:
+		b.move(v, b.listHeight())
+		return
+	case KeyBack:
+		if len(b.views) > 1 {
+			b.views = b.views[:len(b.views)-1]
+		}
+		return
+	case "q":
+		if v.kind == runsView {
+			b.done = true
+		} else if len(b.views) > 1 {
+			b.views = b.views[:len(b.views)-1]
+		}
+		return
+	}
+
+	switch v.kind {
+	case runsView:
+		b.handleRuns(v, key)
+	case treeView:
+		b.handleTree(v, key)
+	case diffView:
+		if key == KeyEnter {
+			b.openChange(v)
+		}
+	case restoreSelectView:
+		b.handleRestoreSelect(v, key)
+	case restoreConfirmView:
+		b.handleRestoreConfirm(v, key)
+	case restoreProgressView:
+		if key == "c" {
+			b.cancelRestore(v)
+		}
+	}
+}
+
+// move moves the cursor of a list view, or scrolls a text view
+func (b *Browser) move(v *view, delta int) {
+	if len(v.items) == 0 {
+		v.offset = clamp(v.offset+delta, 0, len(v.lines)-b.listHeight())
+		return
+	}
+	v.cursor = clamp(v.cursor+delta, 0, len(v.items)-1)
+}
+
+func (b *Browser) selected(v *view) *item {
+	if len(v.items) == 0 {
+		return nil
+	}
+	return &v.items[v.cursor]
+}
+
+func (b *Browser) handleRuns(v *view, key Key) {
+	if key == "g" {
+		if err := b.loadRuns(); err != nil {
+			b.status = err.Error()
+		}
+		return
+	}
+	run := b.selected(v)
+	if run == nil {
+		return
+	}
+
+	switch key {
+	case KeyEnter:
+		manifest := b.load(run.value)
+		if manifest == nil {
+			return
+		}
+		b.push(treeViewOf(manifest, manifest.BackupID, dashboard.BuildTree(manifest)))
+	case "d":
+		if b.diffFrom == "" || b.diffFrom == run.value {
+			b.diffFrom = run.value
+			b.status = fmt.Sprintf("Diff from %s: select another backup and press d", run.value)
+			return
+		}
+		b.openDiff(b.diffFrom, run.value)
+		b.diffFrom = ""
+	case "r":
+		if manifest := b.load(run.value); manifest != nil {
+			b.startWizard(manifest)
+		}
+	}
+}
+
+func (b *Browser) load(backupID string) *backup.Manifest {
+	manifest, err := b.store.Load(b.ctx, backupID)
+	if err != nil {
+		b.status = fmt.Sprintf("Failed to load backup %s: %v", backupID, err)
+		return nil
+	}
+	return manifest
+}
+
+// treeViewOf lists namespaces, the resource types of a namespace or the
+// objects of a type
+func treeViewOf(manifest *backup.Manifest, title string, nodes []*dashboard.TreeNode) *view {
+	v := &view{kind: treeView, title: title, manifest: manifest, help: "enter: open  r: restore  esc: back  q: back"}
+	for _, node := range nodes {
+		label := fmt.Sprintf("%-48s %d", node.Name, node.Count)
+		if len(node.Children) == 0 {
+			label = fmt.Sprintf("%-48s %s", node.Name, formatSize(node.Size))
+		}
+		v.items = append(v.items, item{label: label, value: node.Name, node: node})
+	}
+	return v
+}
+
+func (b *Browser) handleTree(v *view, key Key) {
+	switch key {
+	case KeyEnter:
+		selected := b.selected(v)
+		if selected == nil {
+			return
+		}
+		node := selected.node
+		if len(node.Children) > 0 {
+			b.push(treeViewOf(v.manifest, v.title+"/"+node.Name, node.Children))
+			return
+		}
+		object := findObject(v.manifest, node.Key)
+		if object == nil {
+			b.status = fmt.Sprintf("%s is not in backup %s", node.Key, v.manifest.BackupID)
+			return
+		}
+		// The version ID pins the object this run wrote in a versioned bucket
+		data, err := b.store.GetObjectVersion(b.ctx, object.Key, object.VersionID)
+		if err != nil {
+			b.status = fmt.Sprintf("Failed to read %s: %v", object.Key, err)
+			return
+		}
+		b.push(textViewOf(v.title+"/"+node.Name, string(data)))
+	case "r":
+		b.startWizard(v.manifest)
+	}
+}
+
+func textViewOf(title, text string) *view {
+	return &view{kind: textView, title: title, lines: strings.Split(strings.TrimRight(text, "\n"), "\n"), help: "↑/↓: scroll  esc: back"}
+}
+
+func findObject(manifest *backup.Manifest, key string) *backup.ObjectEntry {
+	for i := range manifest.Objects {
+		if manifest.Objects[i].Key == key {
+			return &manifest.Objects[i]
+		}
+	}
+	return nil
+}
+
+// openDiff compares two runs, older first
+func (b *Browser) openDiff(fromID, toID string) {
+	from := b.load(fromID)
+	to := b.load(toID)
+	if from == nil || to == nil {
+		return
+	}
+	if to.StartTime.Before(from.StartTime) {
+		from, to = to, from
+	}
+
+	result := diff.CompareManifests(from, to)
+	added, removed, changed := result.Counts()
+	v := &view{
+		kind:    diffView,
+		title:   fmt.Sprintf("Diff %s -> %s: %d added, %d removed, %d changed, %d unchanged", from.BackupID, to.BackupID, added, removed, changed, result.Unchanged),
+		help:    "enter: show  esc: back",
+		changes: result.Changes,
+	}
+	marks := map[diff.ChangeType]string{diff.ChangeAdded: "+", diff.ChangeRemoved: "-", diff.ChangeChanged: "~"}
+	for _, change := range result.Changes {
+		v.items = append(v.items, item{label: marks[change.Type] + " " + change.Identity()})
+	}
+	b.push(v)
+	b.status = fmt.Sprintf("%d differences", len(result.Changes))
+}
+
+// openChange shows the YAML diff of a changed resource, or the content of
+// an added or removed one
+func (b *Browser) openChange(v *view) {
+	if len(v.changes) == 0 {
+		return
+	}
+	change := v.changes[v.cursor]
+
+	switch change.Type {
+	case diff.ChangeChanged:
+		result := &diff.Result{Changes: []diff.ResourceChange{change}}
+		if warnings := diff.PopulateDiffs(b.ctx, b.store, result); len(warnings) > 0 {
+			b.status = warnings[0].Error()
+			return
+		}
+		b.push(textViewOf(change.Identity(), result.Changes[0].Diff))
+	default:
+		entry := change.To
+		if entry == nil {
+			entry = change.From
+		}
+		data, err := b.store.GetObjectVersion(b.ctx, entry.Key, entry.VersionID)
+		if err != nil {
+			b.status = fmt.Sprintf("Failed to read %s: %v", entry.Key, err)
+			return
+		}
+		b.push(textViewOf(change.Identity(), string(data)))
+	}
+}
+
+// startWizard starts the restore wizard: namespaces, then resource types,
+// then a confirmation defaulting to a dry run
+func (b *Browser) startWizard(manifest *backup.Manifest) {
+	if b.restorer == nil {
+		b.status = "Restores need the backup service's REST API"
+		return
+	}
+	wizard := &restoreWizard{manifest: manifest, request: api.RestoreRequest{BackupID: manifest.BackupID, DryRun: true}, depth: len(b.views)}
+
+	var namespaces []string
+	seen := make(map[string]bool)
+	for _, object := range manifest.Objects {
+		if object.Namespace != "" && !seen[object.Namespace] {
+			seen[object.Namespace] = true
+			namespaces = append(namespaces, object.Namespace)
+		}
+	}
+	sort.Strings(namespaces)
+	b.push(selectViewOf(wizard, stepNamespaces, "Restore "+manifest.BackupID+": namespaces (none selected restores all)", namespaces))
+}
+
+func selectViewOf(wizard *restoreWizard, step restoreStep, title string, values []string) *view {
+	v := &view{kind: restoreSelectView, title: title, wizard: wizard, step: step, help: "space: select  a: all/none  enter: next  esc: back"}
+	for _, value := range values {
+		v.items = append(v.items, item{label: value, value: value})
+	}
+	return v
+}
+
+func (b *Browser) handleRestoreSelect(v *view, key Key) {
+	switch key {
+	case KeySpace:
+		if selected := b.selected(v); selected != nil {
+			selected.selected = !selected.selected
+			b.move(v, 1)
+		}
+	case "a":
+		all := false
+		for _, item := range v.items {
+			if !item.selected {
+				all = true
+			}
+		}
+		for i := range v.items {
+			v.items[i].selected = all
+		}
+	case KeyEnter:
+		var values []string
+		for _, item := range v.items {
+			if item.selected {
+				values = append(values, item.value)
+			}
+		}
+		wizard := v.wizard
+		if v.step == stepNamespaces {
+			wizard.request.TargetNamespaces = values
+			b.push(selectViewOf(wizard, stepTypes, "Restore "+wizard.manifest.BackupID+": resource types (none selected restores all)", resourceTypes(wizard.manifest, values)))
+			return
+		}
+		wizard.request.ResourceTypes = values
+		b.push(confirmViewOf(wizard))
+	}
+}
+
+// resourceTypes returns the resource types stored in namespaces, or in the
+// whole backup when none are given
+func resourceTypes(manifest *backup.Manifest, namespaces []string) []string {
+	selected := make(map[string]bool, len(namespaces))
+	for _, namespace := range namespaces {
+		selected[namespace] = true
+	}
+	seen := make(map[string]bool)
+	var types []string
+	for _, object := range manifest.Objects {
+		if len(selected) > 0 && object.Namespace != "" && !selected[object.Namespace] {
+			continue
+		}
+		if !seen[object.ResourceType] {
+			seen[object.ResourceType] = true
+			types = append(types, object.ResourceType)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+func confirmViewOf(wizard *restoreWizard) *view {
+	v := &view{kind: restoreConfirmView, title: "Restore " + wizard.manifest.BackupID, wizard: wizard, help: "t: toggle dry run  enter: start  esc: back"}
+	v.lines = wizard.summary()
+	return v
+}
+
+func (w *restoreWizard) summary() []string {
+	all := func(values []string) string {
+		if len(values) == 0 {
+			return "all"
+		}
+		return strings.Join(values, ", ")
+	}
+	mode := "dry run, nothing is applied"
+	if !w.request.DryRun {
+		mode = "APPLY to the cluster"
+	}
+	return []string{
+		"Backup:          " + w.request.BackupID,
+		"Namespaces:      " + all(w.request.TargetNamespaces),
+		"Resource types:  " + all(w.request.ResourceTypes),
+		"Mode:            " + mode,
+	}
+}
+
+func (b *Browser) handleRestoreConfirm(v *view, key Key) {
+	switch key {
+	case "t":
+		v.wizard.request.DryRun = !v.wizard.request.DryRun
+		v.lines = v.wizard.summary()
+	case KeyEnter:
+		run, err := b.restorer.StartRestore(b.ctx, v.wizard.request)
+		if err != nil {
+			b.status = fmt.Sprintf("Failed to start restore: %v", err)
+			return
+		}
+		// The wizard's steps are left behind, back returns to where it started
+		b.views = b.views[:v.wizard.depth]
+		progress := &view{kind: restoreProgressView, title: "Restore run " + run.ID, runID: run.ID, help: "c: cancel  esc: back"}
+		progress.progress = &api.RestoreRunProgress{ID: run.ID, Status: run.Status}
+		progress.lines = progressLines(progress.progress)
+		b.push(progress)
+		b.status = fmt.Sprintf("Restore run %s started", run.ID)
+	}
+}
+
+func (b *Browser) cancelRestore(v *view) {
+	if err := b.restorer.CancelRestore(b.ctx, v.runID); err != nil {
+		b.status = fmt.Sprintf("Failed to cancel restore: %v", err)
+		return
+	}
+	b.status = fmt.Sprintf("Cancellation of restore run %s requested", v.runID)
+}
+
+// Tick refreshes the progress of a restore run being shown until it ends
+func (b *Browser) Tick() {
+	v := b.top()
+	if v.kind != restoreProgressView || !running(v.progress) {
+		return
+	}
+	progress, err := b.restorer.RestoreProgress(b.ctx, v.runID)
+	if err != nil {
+		b.status = fmt.Sprintf("Failed to read restore progress: %v", err)
+		return
+	}
+	v.progress = progress
+	v.lines = progressLines(progress)
+}
+
+func running(progress *api.RestoreRunProgress) bool {
+	return progress == nil || progress.Status == api.RunStatusPending || progress.Status == api.RunStatusRunning
+}
+
+func progressLines(progress *api.RestoreRunProgress) []string {
+	status := string(progress.Status)
+	if progress.CancelRequested && progress.Status == api.RunStatusRunning {
+		status += " (cancelling)"
+	}
+	lines := []string{"Status:   " + status}
+	p := progress.Progress
+	if p == nil {
+		return append(lines, "No progress reported yet")
+	}
+
+	lines = append(lines,
+		"Phase:    "+p.Phase,
+		fmt.Sprintf("Progress: %.1f%% (%d/%d resources)", p.Percent, p.ResourcesDone, p.ResourcesTotal))
+	if p.CurrentResource != "" {
+		lines = append(lines, "Current:  "+p.CurrentResource)
+	}
+	if len(p.FailedResources) > 0 {
+		lines = append(lines, "", fmt.Sprintf("Failed resources (%d):", len(p.FailedResources)))
+		for _, failed := range p.FailedResources {
+			resource := failed.Resource
+			if failed.Namespace != "" {
+				resource = failed.Namespace + "/" + resource
+			}
+			lines = append(lines, fmt.Sprintf("✗ %s: %s", resource, failed.Error))
+		}
+	}
+	return lines
+}
+
+// listHeight is the number of list or text lines a screen has room for
+func (b *Browser) listHeight() int {
+	if b.height-chromeLines < 3 {
+		return 3
+	}
+	return b.height - chromeLines
+}
+
+// Render returns the lines of the current screen
+func (b *Browser) Render() []string {
+	v := b.top()
+	height := b.listHeight()
+
+	lines := []string{"backup-util browse — " + v.title, strings.Repeat("─", 40)}
+
+	var body []string
+	if len(v.items) > 0 {
+		// Keep the cursor on screen
+		if v.cursor < v.offset {
+			v.offset = v.cursor
+		} else if v.cursor >= v.offset+height {
+			v.offset = v.cursor - height + 1
+		}
+		for i := v.offset; i < len(v.items) && i < v.offset+height; i++ {
+			prefix := "  "
+			if i == v.cursor {
+				prefix = "› "
+			}
+			if v.kind == restoreSelectView {
+				if v.items[i].selected {
+					prefix += "[x] "
+				} else {
+					prefix += "[ ] "
+				}
+			} else if v.kind == runsView && v.items[i].value == b.diffFrom {
+				prefix += "* "
+			}
+			body = append(body, prefix+v.items[i].label)
+		}
+	} else if len(v.lines) > 0 {
+		end := v.offset + height
+		if end > len(v.lines) {
+			end = len(v.lines)
+		}
+		body = append(body, v.lines[v.offset:end]...)
+	} else {
+		body = []string{"  (empty)"}
+	}
+	for len(body) < height {
+		body = append(body, "")
+	}
+
+	lines = append(lines, body...)
+	return append(lines, strings.Repeat("─", 40), b.status, v.help)
+}
+
+func formatSize(bytes int64) string {
+	switch {
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(bytes)/(1<<20))
+	case bytes >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(bytes)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", bytes)
+	}
+}
+
+func clamp(value, min, max int) int {
+	if value > max {
+		value = max
+	}
+	if value < min {
+		value = min
+	}
+	return value
+}
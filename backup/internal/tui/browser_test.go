@@ -0,0 +1,183 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/api"
+	"cluster-backup/internal/backup"
+)
+
+type fakeStore struct {
+	manifests []*backup.Manifest
+	objects   map[string]string
+}
+
+func (f *fakeStore) List(ctx context.Context) ([]backup.ManifestSummary, error) {
+	var summaries []backup.ManifestSummary
+	for _, manifest := range f.manifests {
+		summaries = append(summaries, manifest.Summary())
+	}
+	return summaries, nil
+}
+
+func (f *fakeStore) Load(ctx context.Context, backupID string) (*backup.Manifest, error) {
+	for _, manifest := range f.manifests {
+		if manifest.BackupID == backupID {
+			return manifest, nil
+		}
+	}
+	return nil, fmt.Errorf("backup %s not found", backupID)
+}
+
+func (f *fakeStore) GetObjectVersion(ctx context.Context, key, versionID string) ([]byte, error) {
+	data, ok := f.objects[key+"@"+versionID]
+	if !ok {
+		return nil, fmt.Errorf("object %s@%s not found", key, versionID)
+	}
+	return []byte(data), nil
+}
+
+type fakeRestorer struct {
+	requests  []api.RestoreRequest
+	progress  *api.RestoreRunProgress
+	cancelled []string
+}
+
+func (f *fakeRestorer) StartRestore(ctx context.Context, req api.RestoreRequest) (*api.Run, error) {
+	f.requests = append(f.requests, req)
+	return &api.Run{ID: "restore-1", Status: api.RunStatusRunning}, nil
+}
+
+func (f *fakeRestorer) RestoreProgress(ctx context.Context, runID string) (*api.RestoreRunProgress, error) {
+	return f.progress, nil
+}
+
+func (f *fakeRestorer) CancelRestore(ctx context.Context, runID string) error {
+	f.cancelled = append(f.cancelled, runID)
+	return nil
+}
+
+func newTestStore() *fakeStore {
+	older := backup.NewManifest("backup-1", "test-cluster", "cluster.local", "bucket", time.Now().Add(-time.Hour))
+	older.AddObject(backup.ObjectEntry{Namespace: "shop", ResourceType: "configmaps", Name: "settings", Key: "shop/configmaps/settings", VersionID: "v1", Checksum: "a"})
+	older.AddObject(backup.ObjectEntry{Namespace: "shop", ResourceType: "secrets", Name: "db", Key: "shop/secrets/db", VersionID: "v1", Checksum: "b"})
+
+	newer := backup.NewManifest("backup-2", "test-cluster", "cluster.local", "bucket", time.Now())
+	newer.AddObject(backup.ObjectEntry{Namespace: "shop", ResourceType: "configmaps", Name: "settings", Key: "shop/configmaps/settings", VersionID: "v2", Checksum: "c"})
+	newer.AddObject(backup.ObjectEntry{Namespace: "billing", ResourceType: "deployments", Name: "api", Key: "billing/deployments/api", VersionID: "v1", Checksum: "d"})
+	newer.AddObject(backup.ObjectEntry{ResourceType: "storageclasses", Name: "fast", Key: "storageclasses/fast", VersionID: "v1", Checksum: "e"})
+
+	return &fakeStore{
+		manifests: []*backup.Manifest{newer, older},
+		objects: map[string]string{
+			"shop/configmaps/settings@v1": "data:\n  mode: old\n",
+			"shop/configmaps/settings@v2": "data:\n  mode: new\n",
+			"billing/deployments/api@v1":  "kind: Deployment\n",
+		},
+	}
+}
+
+func press(b *Browser, keys ...Key) {
+	for _, key := range keys {
+		b.HandleKey(key)
+	}
+}
+
+func screen(b *Browser) string {
+	return strings.Join(b.Render(), "\n")
+}
+
+func TestBrowser_BrowseObjects(t *testing.T) {
+	b, err := NewBrowser(context.Background(), newTestStore(), nil)
+	require.NoError(t, err)
+	b.SetHeight(12)
+	require.Len(t, b.Render(), 12)
+	assert.Contains(t, b.Render()[2], "› backup-2")
+
+	press(b, KeyEnter)
+	assert.Contains(t, screen(b), "billing")
+	assert.Contains(t, screen(b), "(cluster-scoped)")
+
+	press(b, KeyDown, KeyEnter, KeyEnter, KeyEnter)
+	assert.Contains(t, screen(b), "backup-2/billing/deployments/api")
+	assert.Contains(t, screen(b), "kind: Deployment")
+
+	press(b, KeyBack, KeyBack, KeyBack, KeyBack)
+	assert.Contains(t, screen(b), "Backups")
+	press(b, "q")
+	assert.True(t, b.Done())
+}
+
+func TestBrowser_Diff(t *testing.T) {
+	b, err := NewBrowser(context.Background(), newTestStore(), nil)
+	require.NoError(t, err)
+
+	press(b, "d", KeyDown, "d")
+	assert.Contains(t, screen(b), "Diff backup-1 -> backup-2: 2 added, 1 removed, 1 changed")
+	assert.Contains(t, screen(b), "- shop/secrets/db")
+
+	for i, line := range b.top().items {
+		if strings.HasPrefix(line.label, "~") {
+			b.top().cursor = i
+		}
+	}
+	press(b, KeyEnter)
+	assert.Contains(t, screen(b), "-  mode: old")
+	assert.Contains(t, screen(b), "+  mode: new")
+}
+
+func TestBrowser_RestoreWizard(t *testing.T) {
+	restorer := &fakeRestorer{}
+	b, err := NewBrowser(context.Background(), newTestStore(), restorer)
+	require.NoError(t, err)
+
+	press(b, "r")
+	assert.Contains(t, screen(b), "[ ] billing")
+	press(b, KeyDown, KeySpace, KeyEnter)
+	assert.Contains(t, screen(b), "[ ] configmaps")
+	assert.Contains(t, screen(b), "[ ] storageclasses", "cluster-scoped types are offered with any namespace")
+	assert.NotContains(t, screen(b), "deployments")
+	press(b, KeySpace, KeyEnter)
+	assert.Contains(t, screen(b), "dry run, nothing is applied")
+
+	press(b, "t")
+	assert.Contains(t, screen(b), "APPLY to the cluster")
+	press(b, KeyEnter)
+	require.Len(t, restorer.requests, 1)
+	assert.Equal(t, api.RestoreRequest{BackupID: "backup-2", TargetNamespaces: []string{"shop"}, ResourceTypes: []string{"configmaps"}}, restorer.requests[0])
+
+	assert.Contains(t, screen(b), "Restore run restore-1")
+	restorer.progress = &api.RestoreRunProgress{ID: "restore-1", Status: api.RunStatusRunning, Progress: &api.RestoreProgress{
+		Phase: "resources", Percent: 50, ResourcesTotal: 2, ResourcesDone: 1,
+		FailedResources: []api.FailedResource{{Resource: "configmaps/settings", Namespace: "shop", Error: "denied"}},
+	}}
+	b.Tick()
+	assert.Contains(t, screen(b), "Progress: 50.0% (1/2 resources)")
+	assert.Contains(t, screen(b), "✗ shop/configmaps/settings: denied")
+
+	press(b, "c")
+	assert.Equal(t, []string{"restore-1"}, restorer.cancelled)
+	press(b, KeyBack)
+	assert.Contains(t, screen(b), "Backups", "the wizard's steps are left behind")
+}
+
+func TestBrowser_RestoreWithoutAPI(t *testing.T) {
+	b, err := NewBrowser(context.Background(), newTestStore(), nil)
+	require.NoError(t, err)
+	press(b, "r")
+	assert.Contains(t, screen(b), "Restores need the backup service's REST API")
+}
+
+func TestParseKeys(t *testing.T) {
+	assert.Equal(t, []Key{KeyUp, KeyDown, KeyEnter, KeyBack, KeyPageDown, KeyEnter, KeySpace, "d", KeyBack, KeyQuit},
+		ParseKeys([]byte("\x1b[A\x1bOB\x1b[C\x1b[D\x1b[6~\r d\x7f\x03")))
+	assert.Equal(t, []Key{KeyBack}, ParseKeys([]byte("\x1b")))
+	assert.Empty(t, ParseKeys([]byte("\x1b[1;5H")), "unknown sequences are dropped")
+}
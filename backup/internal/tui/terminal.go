@@ -0,0 +1,159 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// refreshInterval is how often the screen is redrawn, and restore progress
+// polled, without a key press
+const refreshInterval = time.Second
+
+// Run draws browser on the terminal of in and out until the user quits or
+// ctx is done. The terminal is switched to raw mode and its alternate
+// screen, and restored on return.
+func Run(ctx context.Context, browser *Browser, in, out *os.File) error {
+	fd := int(in.Fd())
+	if !term.IsTerminal(fd) {
+		return fmt.Errorf("browse needs an interactive terminal")
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to switch the terminal to raw mode: %v", err)
+	}
+	defer term.Restore(fd, state)
+
+	// Alternate screen without a cursor, like less(1)
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	keys := make(chan Key)
+	go readKeys(in, keys)
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	for !browser.Done() {
+		width := 0
+		if w, h, err := term.GetSize(int(out.Fd())); err == nil {
+			width = w
+			browser.SetHeight(h)
+		}
+		draw(out, browser.Render(), width)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case key, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			browser.HandleKey(key)
+		case <-ticker.C:
+			browser.Tick()
+		}
+	}
+	return nil
+}
+
+// draw writes lines over the whole screen, cut to width when it is known
+func draw(out io.Writer, lines []string, width int) {
+	var screen strings.Builder
+	screen.WriteString("\x1b[H\x1b[2J")
+	for i, line := range lines {
+		if width > 0 && utf8.RuneCountInString(line) > width {
+			line = string([]rune(line)[:width])
+		}
+		if i > 0 {
+			screen.WriteString("\r\n")
+		}
+		screen.WriteString(line)
+	}
+	io.WriteString(out, screen.String())
+}
+
+// readKeys sends the keys read from in until it fails
+func readKeys(in io.Reader, keys chan<- Key) {
+	defer close(keys)
+	buf := make([]byte, 64)
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, key := range ParseKeys(buf[:n]) {
+			keys <- key
+		}
+	}
+}
+
+// escapeKeys are the escape sequences of the named keys. Right opens like
+// enter and left goes back.
+var escapeKeys = map[string]Key{
+	"\x1b[A":  KeyUp,
+	"\x1b[B":  KeyDown,
+	"\x1b[C":  KeyEnter,
+	"\x1b[D":  KeyBack,
+	"\x1bOA":  KeyUp,
+	"\x1bOB":  KeyDown,
+	"\x1bOC":  KeyEnter,
+	"\x1bOD":  KeyBack,
+	"\x1b[5~": KeyPageUp,
+	"\x1b[6~": KeyPageDown,
+}
+
+// ParseKeys decodes the keys in input read from a raw terminal. Unknown
+// escape sequences are dropped.
+func ParseKeys(input []byte) []Key {
+	var keys []Key
+	for len(input) > 0 {
+		if input[0] == 0x1b {
+			if len(input) == 1 || (input[1] != '[' && input[1] != 'O') {
+				keys = append(keys, KeyBack)
+				input = input[1:]
+				continue
+			}
+			// A sequence ends with its first letter or ~ after the prefix
+			end := 2
+			for end < len(input) && !(input[end] >= 'A' && input[end] <= 'Z' || input[end] == '~') {
+				end++
+			}
+			if end < len(input) {
+				end++
+			}
+			if key, ok := escapeKeys[string(input[:end])]; ok {
+				keys = append(keys, key)
+			}
+			input = input[end:]
+			continue
+		}
+
+		r, size := utf8.DecodeRune(input)
+		input = input[size:]
+		switch r {
+		case '\r', '\n':
+			keys = append(keys, KeyEnter)
+		case 0x7f, '\b':
+			keys = append(keys, KeyBack)
+		case ' ':
+			keys = append(keys, KeySpace)
+		case 0x03, 0x04:
+			keys = append(keys, KeyQuit)
+		case 'k':
+			keys = append(keys, KeyUp)
+		case 'j':
+			keys = append(keys, KeyDown)
+		default:
+			if r >= ' ' && r != utf8.RuneError {
+				keys = append(keys, Key(string(r)))
+			}
+		}
+	}
+	return keys
+}
@@ -0,0 +1,98 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DefaultCommandTimeout bounds a custom check's command when its
+// configuration sets no timeout
+const DefaultCommandTimeout = 30 * time.Second
+
+// maxCommandOutput bounds the command output kept as a check's message
+const maxCommandOutput = 1000
+
+// CommandCheckConfig defines a custom check running a command. Exit code 0
+// passes, 2 warns and any other code fails, with the command's output as the
+// result's message.
+type CommandCheckConfig struct {
+	Name string `yaml:"name" json:"name"`
+	// Category defaults to CategoryCustom and Severity to SeverityMedium
+	Category    string        `yaml:"category" json:"category,omitempty"`
+	Severity    string        `yaml:"severity" json:"severity,omitempty"`
+	Command     []string      `yaml:"command" json:"command"`
+	Timeout     time.Duration `yaml:"timeout" json:"timeout,omitempty"`
+	Remediation string        `yaml:"remediation" json:"remediation,omitempty"`
+}
+
+// commandCheck is a custom check defined in the configuration
+type commandCheck struct {
+	config CommandCheckConfig
+}
+
+// newCommandCheck validates config and fills in its defaults
+func newCommandCheck(config CommandCheckConfig) (*commandCheck, error) {
+	if config.Name == "" {
+		return nil, fmt.Errorf("custom check without a name")
+	}
+	if len(config.Command) == 0 {
+		return nil, fmt.Errorf("custom check %s has no command", config.Name)
+	}
+	if err := validSeverity(config.Severity); err != nil {
+		return nil, fmt.Errorf("custom check %s: %v", config.Name, err)
+	}
+	if config.Category == "" {
+		config.Category = CategoryCustom
+	}
+	if config.Severity == "" {
+		config.Severity = SeverityMedium
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultCommandTimeout
+	}
+	return &commandCheck{config: config}, nil
+}
+
+func (c *commandCheck) Name() string     { return c.config.Name }
+func (c *commandCheck) Category() string { return c.config.Category }
+func (c *commandCheck) Severity() string { return c.config.Severity }
+
+func (c *commandCheck) Run(ctx context.Context, opts Options, result *Result) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, c.config.Command[0], c.config.Command[1:]...).CombinedOutput()
+	message := strings.TrimSpace(string(output))
+	if len(message) > maxCommandOutput {
+		message = message[:maxCommandOutput] + "..."
+	}
+	exitCode := 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	}
+	result.Details = map[string]interface{}{
+		"command":   c.config.Command,
+		"exit_code": exitCode,
+	}
+	if message == "" && err != nil {
+		message = err.Error()
+	}
+	if message == "" {
+		message = fmt.Sprintf("Command exited with %d", exitCode)
+	}
+
+	switch {
+	case err == nil:
+		result.Status = StatusPassed
+		result.Message = message
+	case exitCode == 2:
+		warn(result, message, c.config.Remediation)
+	default:
+		fail(result, message, c.config.Remediation)
+	}
+}
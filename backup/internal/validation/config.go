@@ -0,0 +1,150 @@
+package validation
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the YAML configuration of the checks, read by backup-util
+// cluster-health --config, the orchestrator from VALIDATION_CONFIG and
+// cmd/validation-monitor from validation-config.yaml:
+//
+//	checks:
+//	  security_validation:
+//	    severity: critical
+//	  kubernetes_pod_usage:
+//	    enabled: false
+//	custom_checks:
+//	  - name: ingress_reachable
+//	    command: ["curl", "-fsS", "https://shop.example.com/healthz"]
+//	    timeout: 10s
+//	thresholds:
+//	  cpu_threshold: 90
+type Config struct {
+	// Checks enable, disable or configure checks by name
+	Checks map[string]CheckSettings `yaml:"checks" json:"checks,omitempty"`
+	// CustomChecks are checks running a command, run after the built-in ones
+	CustomChecks []CommandCheckConfig `yaml:"custom_checks" json:"custom_checks,omitempty"`
+	Thresholds   Thresholds           `yaml:"thresholds" json:"thresholds"`
+}
+
+// CheckSettings enable, disable or configure a check by name
+type CheckSettings struct {
+	// Enabled runs or skips the check whatever categories are selected, nil
+	// leaving it to them
+	Enabled *bool `yaml:"enabled" json:"enabled,omitempty"`
+	// Severity overrides the severity the check reports
+	Severity string `yaml:"severity" json:"severity,omitempty"`
+	// Options are passed to checks implementing ConfigurableCheck
+	Options map[string]interface{} `yaml:"options" json:"options,omitempty"`
+}
+
+// Thresholds are the limits the usage and performance checks apply, their
+// defaults when zero
+type Thresholds struct {
+	CPUThreshold    float64       `yaml:"cpu_threshold" json:"cpu_threshold,omitempty"`
+	MemoryThreshold float64       `yaml:"memory_threshold" json:"memory_threshold,omitempty"`
+	ResponseTime    time.Duration `yaml:"response_time" json:"response_time,omitempty"`
+}
+
+// LoadConfig reads and validates the configuration at path
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read check configuration: %v", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse check configuration %s: %v", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid check configuration %s: %v", path, err)
+	}
+	return &cfg, nil
+}
+
+// Validate checks the severities, custom checks and thresholds of the
+// configuration
+func (c *Config) Validate() error {
+	for name, settings := range c.Checks {
+		if err := validSeverity(settings.Severity); err != nil {
+			return fmt.Errorf("check %s: %v", name, err)
+		}
+	}
+	for _, custom := range c.CustomChecks {
+		if _, err := newCommandCheck(custom); err != nil {
+			return err
+		}
+	}
+	if c.Thresholds.CPUThreshold < 0 || c.Thresholds.MemoryThreshold < 0 || c.Thresholds.ResponseTime < 0 {
+		return fmt.Errorf("thresholds must not be negative")
+	}
+	return nil
+}
+
+// Apply returns opts with the configured checks and thresholds. The custom
+// checks are added to opts.Registry, or to the built-in checks when it is
+// nil, and checks implementing ConfigurableCheck are passed their options.
+// Thresholds set in opts take precedence. Settings naming no registered
+// check fail, as they are most likely misspelt.
+func (c *Config) Apply(opts Options) (Options, error) {
+	if err := c.Validate(); err != nil {
+		return opts, err
+	}
+	if opts.Registry == nil {
+		opts.Registry = NewRegistry()
+	}
+	for _, custom := range c.CustomChecks {
+		// Applying the configuration again to the same registry keeps the
+		// custom checks it added the first time
+		if _, ok := opts.Registry.Lookup(custom.Name).(*commandCheck); ok {
+			continue
+		}
+		check, _ := newCommandCheck(custom)
+		if err := opts.Registry.Register(check); err != nil {
+			return opts, err
+		}
+	}
+	for name, settings := range c.Checks {
+		check := opts.Registry.Lookup(name)
+		if check == nil {
+			return opts, fmt.Errorf("settings for unknown check %s", name)
+		}
+		if len(settings.Options) == 0 {
+			continue
+		}
+		configurable, ok := check.(ConfigurableCheck)
+		if !ok {
+			return opts, fmt.Errorf("check %s takes no options", name)
+		}
+		if err := configurable.Configure(settings.Options); err != nil {
+			return opts, fmt.Errorf("failed to configure check %s: %v", name, err)
+		}
+	}
+	opts.Settings = c.Checks
+
+	if opts.CPUThreshold == 0 {
+		opts.CPUThreshold = c.Thresholds.CPUThreshold
+	}
+	if opts.MemoryThreshold == 0 {
+		opts.MemoryThreshold = c.Thresholds.MemoryThreshold
+	}
+	if opts.MaxAPILatency == 0 {
+		opts.MaxAPILatency = c.Thresholds.ResponseTime
+	}
+	return opts, nil
+}
+
+// validSeverity accepts the severities of checks, and empty for the check's
+// own
+func validSeverity(severity string) error {
+	switch severity {
+	case "", SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow:
+		return nil
+	default:
+		return fmt.Errorf("unknown severity %q", severity)
+	}
+}
@@ -0,0 +1,171 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// thresholdCheck is a check taking a threshold from its options
+type thresholdCheck struct {
+	threshold float64
+}
+
+func (c *thresholdCheck) Name() string     { return "queue_depth" }
+func (c *thresholdCheck) Category() string { return CategoryWorkloads }
+func (c *thresholdCheck) Severity() string { return SeverityHigh }
+
+func (c *thresholdCheck) Run(ctx context.Context, opts Options, result *Result) {
+	result.Status = StatusPassed
+	result.Message = fmt.Sprintf("Queue depth is below %.0f", c.threshold)
+}
+
+func (c *thresholdCheck) Configure(options map[string]interface{}) error {
+	threshold, ok := options["threshold"].(int)
+	if !ok {
+		return fmt.Errorf("threshold must be a number")
+	}
+	c.threshold = float64(threshold)
+	return nil
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checks.yaml")
+	writeFile(t, path, `
+checks:
+  security_validation:
+    severity: critical
+  kubernetes_pod_usage:
+    enabled: false
+  queue_depth:
+    options:
+      threshold: 100
+custom_checks:
+  - name: ingress_reachable
+    command: ["sh", "-c", "exit 0"]
+    timeout: 10s
+thresholds:
+  cpu_threshold: 90
+  response_time: 2s
+`)
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, SeverityCritical, cfg.Checks["security_validation"].Severity)
+	assert.Equal(t, 10*time.Second, cfg.CustomChecks[0].Timeout)
+
+	registry := NewRegistry()
+	custom := &thresholdCheck{}
+	require.NoError(t, registry.Register(custom))
+	opts, err := cfg.Apply(Options{Registry: registry, MemoryThreshold: 70})
+	require.NoError(t, err)
+	assert.Equal(t, float64(100), custom.threshold, "configurable checks take their options")
+	assert.Equal(t, float64(90), opts.CPUThreshold)
+	assert.Equal(t, float64(70), opts.MemoryThreshold, "options set by the caller take precedence")
+	assert.Equal(t, 2*time.Second, opts.MaxAPILatency)
+	assert.NotNil(t, registry.Lookup("ingress_reachable"))
+	_, err = cfg.Apply(Options{Registry: registry})
+	assert.NoError(t, err, "applying the configuration again keeps its custom checks")
+
+	for name, content := range map[string]string{
+		"unknown severity":         "checks:\n  security_validation:\n    severity: urgent\n",
+		"custom check without one": "custom_checks:\n  - name: empty\n",
+		"negative threshold":       "thresholds:\n  cpu_threshold: -1\n",
+	} {
+		writeFile(t, path, content)
+		_, err := LoadConfig(path)
+		assert.Error(t, err, name)
+	}
+
+	_, err = (&Config{Checks: map[string]CheckSettings{"securty_validation": {}}}).Apply(Options{})
+	assert.Error(t, err, "settings for an unknown check")
+	_, err = (&Config{Checks: map[string]CheckSettings{"security_validation": {Options: map[string]interface{}{"x": 1}}}}).Apply(Options{})
+	assert.Error(t, err, "options for a check taking none")
+	_, err = (&Config{CustomChecks: []CommandCheckConfig{{Name: "security_validation", Command: []string{"true"}}}}).Apply(Options{})
+	assert.Error(t, err, "a custom check named as a built-in one")
+}
+
+func TestRunChecks_Config(t *testing.T) {
+	enabled, disabled := true, false
+	cfg := &Config{
+		Checks: map[string]CheckSettings{
+			"kubernetes_node_health":     {Severity: SeverityLow},
+			"kubernetes_resource_quotas": {Enabled: &disabled},
+			"security_validation":        {Enabled: &enabled},
+			"flaky":                      {Enabled: &disabled},
+		},
+		CustomChecks: []CommandCheckConfig{
+			{Name: "smoke_test", Command: []string{"sh", "-c", "echo degraded; exit 2"}, Remediation: "Check the smoke test"},
+			{Name: "flaky", Command: []string{"false"}},
+		},
+	}
+	opts, err := cfg.Apply(Options{
+		Categories: []string{CategoryInfrastructure, CategoryResources, CategoryCustom},
+		KubeClient: fake.NewSimpleClientset(),
+	})
+	require.NoError(t, err)
+
+	results, err := RunChecks(context.Background(), opts)
+	require.NoError(t, err)
+	byName := make(map[string]Result)
+	var names []string
+	for _, check := range results.Checks {
+		byName[check.Name] = check
+		names = append(names, check.Name)
+	}
+	assert.Equal(t, []string{"kubernetes_cluster_connectivity", "kubernetes_node_health", "security_validation", "smoke_test"}, names,
+		"settings enable checks outside the selected categories and disable checks inside them")
+	assert.Equal(t, SeverityLow, byName["kubernetes_node_health"].Severity)
+	smoke := byName["smoke_test"]
+	assert.Equal(t, StatusWarning, smoke.Status)
+	assert.Equal(t, "degraded", smoke.Message)
+	assert.Equal(t, "Check the smoke test", smoke.Remediation)
+	assert.Equal(t, CategoryCustom, smoke.Category)
+	assert.Equal(t, SeverityMedium, smoke.Severity)
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	assert.Error(t, registry.Register(&check{name: "kubernetes_node_health"}), "names are unique")
+	require.NoError(t, registry.Register(&check{name: "panics", category: CategoryCustom, severity: SeverityLow,
+		run: func(context.Context, Options, *Result) { panic("boom") }}))
+	checks := registry.Checks()
+	assert.Equal(t, "panics", checks[len(checks)-1].Name(), "checks run in the order they are registered")
+
+	results, err := RunChecks(context.Background(), Options{Registry: registry, Categories: []string{CategoryCustom}})
+	require.NoError(t, err)
+	require.Len(t, results.Checks, 1)
+	assert.Equal(t, StatusFailed, results.Checks[0].Status, "a panicking check fails")
+	assert.Contains(t, results.Checks[0].Message, "boom")
+}
+
+func TestCommandCheck(t *testing.T) {
+	for _, test := range []struct {
+		command []string
+		status  string
+		message string
+	}{
+		{[]string{"sh", "-c", "echo all good"}, StatusPassed, "all good"},
+		{[]string{"sh", "-c", "exit 2"}, StatusWarning, "exit status 2"},
+		{[]string{"sh", "-c", "echo broken >&2; exit 1"}, StatusFailed, "broken"},
+		{[]string{"/nonexistent/check"}, StatusFailed, "no such file or directory"},
+	} {
+		check, err := newCommandCheck(CommandCheckConfig{Name: "custom", Command: test.command})
+		require.NoError(t, err)
+		var result Result
+		check.Run(context.Background(), Options{}, &result)
+		assert.Equal(t, test.status, result.Status, test.command)
+		assert.Contains(t, result.Message, test.message, test.command)
+	}
+
+	check, err := newCommandCheck(CommandCheckConfig{Name: "slow", Command: []string{"sleep", "5"}, Timeout: 50 * time.Millisecond})
+	require.NoError(t, err)
+	var result Result
+	check.Run(context.Background(), Options{}, &result)
+	assert.Equal(t, StatusFailed, result.Status, "commands running past their timeout fail")
+}
@@ -0,0 +1,244 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// argoCDApplications is the resource of Argo CD's Application custom
+// resources
+var argoCDApplications = schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "applications"}
+
+// ArgoCDApplication is the sync and health status Argo CD reports for an
+// Application
+type ArgoCDApplication struct {
+	Name         string `json:"name" yaml:"name"`
+	Namespace    string `json:"namespace" yaml:"namespace"`
+	SyncStatus   string `json:"sync_status" yaml:"sync_status"`
+	HealthStatus string `json:"health_status" yaml:"health_status"`
+	// Revision is the revision the application was last synced to
+	Revision string `json:"revision,omitempty" yaml:"revision,omitempty"`
+	// LastSynced is when the latest sync operation finished, zero when the
+	// application has not been synced
+	LastSynced time.Time `json:"last_synced,omitempty" yaml:"last_synced,omitempty"`
+	// HealthMessage explains a health status other than Healthy
+	HealthMessage string `json:"health_message,omitempty" yaml:"health_message,omitempty"`
+}
+
+// listArgoCDApplications reads the status of every Argo CD Application in
+// the cluster from the Application custom resources the controller updates
+func listArgoCDApplications(ctx context.Context, opts Options) ([]ArgoCDApplication, error) {
+	list, err := opts.DynamicClient.Resource(argoCDApplications).Namespace("").List(ctx, v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	applications := make([]ArgoCDApplication, 0, len(list.Items))
+	for _, item := range list.Items {
+		application := ArgoCDApplication{Name: item.GetName(), Namespace: item.GetNamespace()}
+		application.SyncStatus, _, _ = unstructured.NestedString(item.Object, "status", "sync", "status")
+		application.Revision, _, _ = unstructured.NestedString(item.Object, "status", "sync", "revision")
+		application.HealthStatus, _, _ = unstructured.NestedString(item.Object, "status", "health", "status")
+		application.HealthMessage, _, _ = unstructured.NestedString(item.Object, "status", "health", "message")
+		if finishedAt, _, _ := unstructured.NestedString(item.Object, "status", "operationState", "finishedAt"); finishedAt != "" {
+			application.LastSynced, _ = time.Parse(time.RFC3339, finishedAt)
+		}
+		if application.SyncStatus == "" {
+			application.SyncStatus = "Unknown"
+		}
+		if application.HealthStatus == "" {
+			application.HealthStatus = "Unknown"
+		}
+		applications = append(applications, application)
+	}
+	sort.Slice(applications, func(i, j int) bool {
+		if applications[i].Namespace != applications[j].Namespace {
+			return applications[i].Namespace < applications[j].Namespace
+		}
+		return applications[i].Name < applications[j].Name
+	})
+	return applications, nil
+}
+
+// checkArgoCDApplications fails when an Argo CD Application is Degraded or
+// Missing, and warns when one is out of sync or not yet healthy. It does
+// not apply to clusters without the Application CRD.
+func checkArgoCDApplications(ctx context.Context, opts Options, result *Result) {
+	applications, err := listArgoCDApplications(ctx, opts)
+	switch {
+	case apierrors.IsNotFound(err):
+		return
+	case err != nil:
+		warn(result, fmt.Sprintf("Failed to list Argo CD applications: %v", err),
+			"Grant list access to applications.argoproj.io")
+		return
+	}
+
+	var degraded, outOfSync, unsettled []string
+	for _, application := range applications {
+		name := application.Namespace + "/" + application.Name
+		switch application.HealthStatus {
+		case "Healthy", "Suspended":
+		case "Degraded", "Missing":
+			degraded = append(degraded, name+":"+application.HealthStatus)
+		default:
+			unsettled = append(unsettled, name+":"+application.HealthStatus)
+		}
+		if application.SyncStatus != "Synced" {
+			outOfSync = append(outOfSync, name+":"+application.SyncStatus)
+		}
+	}
+	result.Details = map[string]interface{}{
+		"applications":             applications,
+		"degraded_applications":    degraded,
+		"out_of_sync_applications": outOfSync,
+		"unsettled_applications":   unsettled,
+	}
+	switch {
+	case len(degraded) > 0:
+		fail(result, fmt.Sprintf("%d/%d Argo CD applications are degraded or missing", len(degraded), len(applications)),
+			"Check the degraded applications' resources with 'argocd app get <name>'")
+	case len(outOfSync) > 0 || len(unsettled) > 0:
+		warn(result, fmt.Sprintf("%d/%d Argo CD applications are out of sync, %d not yet healthy", len(outOfSync), len(applications), len(unsettled)),
+			"Sync the applications or check why their sync is not converging")
+	default:
+		result.Status = StatusPassed
+		result.Message = fmt.Sprintf("All %d Argo CD applications are synced and healthy", len(applications))
+	}
+}
+
+// fluxKinds are the Flux custom resources whose reconciliation is checked,
+// with the status field holding the revision each last reconciled
+var fluxKinds = []struct {
+	kind     string
+	resource schema.GroupVersionResource
+	revision []string
+}{
+	{"GitRepository", schema.GroupVersionResource{Group: "source.toolkit.fluxcd.io", Version: "v1", Resource: "gitrepositories"}, []string{"status", "artifact", "revision"}},
+	{"Kustomization", schema.GroupVersionResource{Group: "kustomize.toolkit.fluxcd.io", Version: "v1", Resource: "kustomizations"}, []string{"status", "lastAppliedRevision"}},
+}
+
+// FluxResource is the reconciliation status Flux reports for one of its
+// custom resources
+type FluxResource struct {
+	Kind      string `json:"kind" yaml:"kind"`
+	Name      string `json:"name" yaml:"name"`
+	Namespace string `json:"namespace" yaml:"namespace"`
+	// Ready is the status of the Ready condition: True, False or Unknown
+	// while reconciling
+	Ready string `json:"ready" yaml:"ready"`
+	// Reason and Message explain the Ready condition, such as the error of a
+	// failed reconciliation
+	Reason  string `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// Revision is the source revision last fetched or applied
+	Revision string `json:"revision,omitempty" yaml:"revision,omitempty"`
+	// LastTransition is when the Ready condition last changed
+	LastTransition time.Time `json:"last_transition,omitempty" yaml:"last_transition,omitempty"`
+	Suspended      bool      `json:"suspended,omitempty" yaml:"suspended,omitempty"`
+}
+
+// listFluxResources reads the status of every Flux resource of kind in the
+// cluster from its Ready condition
+func listFluxResources(ctx context.Context, opts Options, kind string, resource schema.GroupVersionResource, revisionField []string) ([]FluxResource, error) {
+	list, err := opts.DynamicClient.Resource(resource).Namespace("").List(ctx, v1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]FluxResource, 0, len(list.Items))
+	for _, item := range list.Items {
+		fluxResource := FluxResource{Kind: kind, Name: item.GetName(), Namespace: item.GetNamespace(), Ready: "Unknown"}
+		fluxResource.Revision, _, _ = unstructured.NestedString(item.Object, revisionField...)
+		fluxResource.Suspended, _, _ = unstructured.NestedBool(item.Object, "spec", "suspend")
+
+		conditions, _, _ := unstructured.NestedSlice(item.Object, "status", "conditions")
+		for _, c := range conditions {
+			condition, ok := c.(map[string]interface{})
+			if !ok || condition["type"] != "Ready" {
+				continue
+			}
+			fluxResource.Ready, _ = condition["status"].(string)
+			fluxResource.Reason, _ = condition["reason"].(string)
+			fluxResource.Message, _ = condition["message"].(string)
+			if transition, ok := condition["lastTransitionTime"].(string); ok {
+				fluxResource.LastTransition, _ = time.Parse(time.RFC3339, transition)
+			}
+		}
+		resources = append(resources, fluxResource)
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].Namespace != resources[j].Namespace {
+			return resources[i].Namespace < resources[j].Namespace
+		}
+		return resources[i].Name < resources[j].Name
+	})
+	return resources, nil
+}
+
+// checkFluxResources fails when a Flux GitRepository or Kustomization failed
+// to reconcile, and warns while one is still reconciling or suspended, or
+// when a kind cannot be read. Kinds whose CRD is not installed are skipped,
+// and the check does not apply when none is.
+func checkFluxResources(ctx context.Context, opts Options, result *Result) {
+	var resources []FluxResource
+	var failing, reconciling, suspended, unreadable []string
+	installed := 0
+	for _, kind := range fluxKinds {
+		listed, err := listFluxResources(ctx, opts, kind.kind, kind.resource, kind.revision)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		installed++
+		if err != nil {
+			unreadable = append(unreadable, fmt.Sprintf("%s: %v", kind.resource.GroupResource(), err))
+			continue
+		}
+		for _, fluxResource := range listed {
+			name := fmt.Sprintf("%s %s/%s", fluxResource.Kind, fluxResource.Namespace, fluxResource.Name)
+			switch {
+			case fluxResource.Suspended:
+				suspended = append(suspended, name)
+			case fluxResource.Ready == "False":
+				failing = append(failing, fmt.Sprintf("%s: %s", name, fluxResource.Message))
+			case fluxResource.Ready != "True":
+				reconciling = append(reconciling, name)
+			}
+		}
+		resources = append(resources, listed...)
+	}
+	if installed == 0 {
+		return
+	}
+
+	result.Details = map[string]interface{}{
+		"resources":             resources,
+		"failing_resources":     failing,
+		"reconciling_resources": reconciling,
+		"suspended_resources":   suspended,
+	}
+	if len(unreadable) > 0 {
+		result.Details["unreadable_kinds"] = unreadable
+	}
+	switch {
+	case len(failing) > 0:
+		fail(result, fmt.Sprintf("%d/%d Flux resources failed to reconcile", len(failing), len(resources)),
+			"Check the reconciliation errors with 'flux get all -A' and fix the sources or manifests")
+	case len(unreadable) > 0:
+		warn(result, fmt.Sprintf("%d Flux resource kinds cannot be read", len(unreadable)),
+			"Grant list access to the Flux resources")
+	case len(reconciling) > 0 || len(suspended) > 0:
+		warn(result, fmt.Sprintf("%d/%d Flux resources are still reconciling, %d suspended", len(reconciling), len(resources), len(suspended)),
+			"Wait for reconciliation to finish or resume suspended resources with 'flux resume'")
+	default:
+		result.Status = StatusPassed
+		result.Message = fmt.Sprintf("All %d Flux resources are ready", len(resources))
+	}
+}
@@ -0,0 +1,139 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func argoCDApplication(namespace, name, sync, health, finishedAt string) *unstructured.Unstructured {
+	status := map[string]interface{}{
+		"sync":   map[string]interface{}{"status": sync, "revision": "abc123"},
+		"health": map[string]interface{}{"status": health},
+	}
+	if finishedAt != "" {
+		status["operationState"] = map[string]interface{}{"finishedAt": finishedAt}
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Application",
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+		"status":     status,
+	}}
+}
+
+func TestCheckArgoCDApplications(t *testing.T) {
+	ctx := context.Background()
+	opts := Options{DynamicClient: dynamicServer(t,
+		argoCDApplication("argocd", "shop", "Synced", "Healthy", "2025-09-25T16:56:34Z"),
+		argoCDApplication("argocd", "billing", "OutOfSync", "Progressing", ""),
+	)}
+
+	var result Result
+	checkArgoCDApplications(ctx, opts, &result)
+	assert.Equal(t, StatusWarning, result.Status)
+	assert.Equal(t, []ArgoCDApplication{
+		{Name: "billing", Namespace: "argocd", SyncStatus: "OutOfSync", HealthStatus: "Progressing", Revision: "abc123"},
+		{Name: "shop", Namespace: "argocd", SyncStatus: "Synced", HealthStatus: "Healthy", Revision: "abc123",
+			LastSynced: time.Date(2025, 9, 25, 16, 56, 34, 0, time.UTC)},
+	}, result.Details["applications"])
+	assert.Equal(t, []string{"argocd/billing:OutOfSync"}, result.Details["out_of_sync_applications"])
+
+	metrics := NewMetrics(prometheus.NewRegistry())
+	result.Name = "argocd_applications"
+	metrics.Record(Results{Checks: []Result{result}})
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.appSynced.WithLabelValues("shop", "argocd")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.appHealthy.WithLabelValues("billing", "argocd")))
+	assert.Equal(t, float64(1758819394), testutil.ToFloat64(metrics.appLastSync.WithLabelValues("shop", "argocd")))
+
+	opts.DynamicClient = dynamicServer(t, argoCDApplication("argocd", "shop", "Synced", "Degraded", ""))
+	result = Result{}
+	checkArgoCDApplications(ctx, opts, &result)
+	assert.Equal(t, StatusFailed, result.Status)
+	assert.Equal(t, []string{"argocd/shop:Degraded"}, result.Details["degraded_applications"])
+}
+
+func TestCheckArgoCDApplications_NotInstalled(t *testing.T) {
+	var result Result
+	checkArgoCDApplications(context.Background(), Options{DynamicClient: withoutCRD(dynamicServer(t), argoCDApplications)}, &result)
+	assert.Empty(t, result.Status, "the check does not apply")
+
+	dynamicClient := dynamicServer(t)
+	dynamicClient.PrependReactor("list", "applications", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("forbidden")
+	})
+	checkArgoCDApplications(context.Background(), Options{DynamicClient: dynamicClient}, &result)
+	assert.Equal(t, StatusWarning, result.Status)
+}
+
+func fluxResource(kind, namespace, name, ready, message string) *unstructured.Unstructured {
+	apiVersion := fluxKinds[0].resource.GroupVersion().String()
+	if kind == "Kustomization" {
+		apiVersion = fluxKinds[1].resource.GroupVersion().String()
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+		"status": map[string]interface{}{
+			"lastAppliedRevision": "main@sha1:abc123",
+			"conditions": []interface{}{map[string]interface{}{
+				"type":               "Ready",
+				"status":             ready,
+				"message":            message,
+				"lastTransitionTime": "2025-09-25T16:56:34Z",
+			}},
+		},
+	}}
+}
+
+func TestCheckFluxResources(t *testing.T) {
+	ctx := context.Background()
+	suspended := fluxResource("GitRepository", "flux-system", "paused", "True", "")
+	suspended.Object["spec"] = map[string]interface{}{"suspend": true}
+	opts := Options{DynamicClient: dynamicServer(t,
+		fluxResource("Kustomization", "flux-system", "apps", "True", ""),
+		fluxResource("Kustomization", "flux-system", "infra", "Unknown", "reconciliation in progress"),
+		suspended,
+	)}
+
+	var result Result
+	checkFluxResources(ctx, opts, &result)
+	assert.Equal(t, StatusWarning, result.Status)
+	assert.Equal(t, []string{"Kustomization flux-system/infra"}, result.Details["reconciling_resources"])
+	assert.Equal(t, []string{"GitRepository flux-system/paused"}, result.Details["suspended_resources"])
+	resources := result.Details["resources"].([]FluxResource)
+	assert.Len(t, resources, 3)
+	assert.Equal(t, FluxResource{Kind: "Kustomization", Name: "apps", Namespace: "flux-system", Ready: "True",
+		Revision: "main@sha1:abc123", LastTransition: time.Date(2025, 9, 25, 16, 56, 34, 0, time.UTC)}, resources[1])
+
+	metrics := NewMetrics(prometheus.NewRegistry())
+	result.Name = "flux_resources"
+	metrics.Record(Results{Checks: []Result{result}})
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.fluxReady.WithLabelValues("Kustomization", "apps", "flux-system")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.fluxReady.WithLabelValues("Kustomization", "infra", "flux-system")))
+	assert.Equal(t, float64(1758819394), testutil.ToFloat64(metrics.fluxChanged.WithLabelValues("Kustomization", "apps", "flux-system")))
+
+	// A Kustomization that failed to apply fails the check with its error;
+	// kinds without a CRD are skipped
+	opts.DynamicClient = withoutCRD(dynamicServer(t, fluxResource("Kustomization", "flux-system", "apps", "False", "kustomize build failed")),
+		fluxKinds[0].resource)
+	result = Result{}
+	checkFluxResources(ctx, opts, &result)
+	assert.Equal(t, StatusFailed, result.Status)
+	assert.Equal(t, []string{"Kustomization flux-system/apps: kustomize build failed"}, result.Details["failing_resources"])
+}
+
+func TestCheckFluxResources_NotInstalled(t *testing.T) {
+	var result Result
+	checkFluxResources(context.Background(), Options{DynamicClient: withoutCRD(dynamicServer(t), fluxKinds[0].resource, fluxKinds[1].resource)}, &result)
+	assert.Empty(t, result.Status, "the check does not apply")
+}
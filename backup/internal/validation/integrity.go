@@ -0,0 +1,170 @@
+package validation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+	"shared-config/restore"
+
+	"cluster-backup/internal/backup"
+)
+
+// Why an object of a backup run is not intact
+const (
+	IntegrityMissing          = "missing"
+	IntegrityChecksumMismatch = "checksum_mismatch"
+	IntegrityInvalidYAML      = "invalid_yaml"
+)
+
+// clusterScope is the namespace cluster-scoped objects are scored under
+const clusterScope = "_cluster"
+
+// errNoManifest is returned by latestManifest for directories without a run
+// manifest, such as plain resource dumps
+var errNoManifest = errors.New("no backup manifest found")
+
+// IntegrityProblem is an object of a backup run that is not intact
+type IntegrityProblem struct {
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Key       string `json:"key" yaml:"key"`
+	// Problem is IntegrityMissing, IntegrityChecksumMismatch or
+	// IntegrityInvalidYAML
+	Problem string `json:"problem" yaml:"problem"`
+	Error   string `json:"error" yaml:"error"`
+}
+
+// latestManifest returns the path of the latest run manifest under dir, laid
+// out as backup-util export writes it:
+// {domain}/{cluster-name}/_manifests/{backup-id}.json. Backup IDs start with
+// the run's start time, so the latest sorts last.
+func latestManifest(dir string) (string, error) {
+	var manifests []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && filepath.Ext(path) == ".json" && filepath.Base(filepath.Dir(path)) == "_manifests" {
+			manifests = append(manifests, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(manifests) == 0 {
+		return "", errNoManifest
+	}
+	sort.Slice(manifests, func(i, j int) bool {
+		return filepath.Base(manifests[i]) < filepath.Base(manifests[j])
+	})
+	return manifests[len(manifests)-1], nil
+}
+
+// checkObject returns why the stored copy of object under dir is not intact,
+// or "" when it is
+func checkObject(dir string, object backup.ObjectEntry) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(object.Key)))
+	if err != nil {
+		return IntegrityMissing, err
+	}
+	if err := restore.VerifyChecksum(object.Key, data, object.Checksum); err != nil {
+		return IntegrityChecksumMismatch, err
+	}
+	var document interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return IntegrityInvalidYAML, err
+	}
+	return "", nil
+}
+
+// checkBackupData checks every object the latest run manifest under the
+// backup directory lists is there, matches its checksum and parses as YAML,
+// and scores each namespace by the share of its objects that are intact.
+// Objects in tenants' buckets are left out of exports and are not checked.
+// Directories without a manifest have their YAML documents checked instead.
+func checkBackupData(ctx context.Context, opts Options, result *Result) {
+	path, err := latestManifest(opts.BackupDir)
+	switch {
+	case errors.Is(err, errNoManifest):
+		checkBackupFiles(ctx, opts, result)
+		return
+	case err != nil:
+		fail(result, fmt.Sprintf("Failed to read backup directory %s: %v", opts.BackupDir, err),
+			"Ensure the backup directory exists and holds an exported backup")
+		return
+	}
+	var manifest backup.Manifest
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, &manifest)
+	}
+	if err != nil {
+		fail(result, fmt.Sprintf("Failed to read backup manifest %s: %v", path, err),
+			"Export the backup again with 'backup-util export'")
+		return
+	}
+
+	total, intact, tenantObjects := 0, 0, 0
+	totals := make(map[string]int)
+	intacts := make(map[string]int)
+	problems := []IntegrityProblem{}
+	for _, objects := range [][]backup.ObjectEntry{manifest.Objects, manifest.AlternateVersions} {
+		for _, object := range objects {
+			if object.Bucket != "" {
+				tenantObjects++
+				continue
+			}
+			namespace := object.Namespace
+			if namespace == "" {
+				namespace = clusterScope
+			}
+			total++
+			totals[namespace]++
+
+			problem, err := checkObject(opts.BackupDir, object)
+			if problem == "" {
+				intact++
+				intacts[namespace]++
+				continue
+			}
+			problems = append(problems, IntegrityProblem{Namespace: object.Namespace, Key: object.Key, Problem: problem, Error: err.Error()})
+		}
+	}
+
+	scores := make(map[string]float64, len(totals))
+	for namespace, count := range totals {
+		scores[namespace] = float64(intacts[namespace]) / float64(count) * 100
+	}
+	score := 100.0
+	if total > 0 {
+		score = float64(intact) / float64(total) * 100
+	}
+	result.Details = map[string]interface{}{
+		"backup_id":        manifest.BackupID,
+		"manifest":         path,
+		"total_objects":    total,
+		"intact_objects":   intact,
+		"tenant_objects":   tenantObjects,
+		"integrity_score":  score,
+		"namespace_scores": scores,
+		"problems":         problems,
+	}
+	switch {
+	case total == 0:
+		warn(result, fmt.Sprintf("Backup %s lists no objects to check", manifest.BackupID),
+			"Check the backup's filters and that it finished")
+	case intact < total:
+		fail(result, fmt.Sprintf("%d/%d objects of backup %s are intact", intact, total, manifest.BackupID),
+			"Export the backup again, or verify the bucket copy with 'backup-util verify <backup-id>'")
+	default:
+		result.Status = StatusPassed
+		result.Message = fmt.Sprintf("All %d objects of backup %s are intact", total, manifest.BackupID)
+	}
+}
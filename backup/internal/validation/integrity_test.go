@@ -0,0 +1,84 @@
+package validation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"cluster-backup/internal/backup"
+)
+
+// exportObject writes content at key under dir and returns its manifest
+// entry
+func exportObject(t *testing.T, dir, namespace, key, content string) backup.ObjectEntry {
+	t.Helper()
+	writeFile(t, filepath.Join(dir, filepath.FromSlash(key)), content)
+	sum := sha256.Sum256([]byte(content))
+	return backup.ObjectEntry{Namespace: namespace, ResourceType: "configmaps", Name: filepath.Base(key), Key: key, Checksum: hex.EncodeToString(sum[:])}
+}
+
+func writeManifest(t *testing.T, dir string, manifest *backup.Manifest) {
+	t.Helper()
+	data, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	writeFile(t, filepath.Join(dir, "example.com", "prod", "_manifests", manifest.BackupID+".json"), string(data))
+}
+
+func TestCheckBackupData(t *testing.T) {
+	dir := t.TempDir()
+	settings := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n"
+	intact := exportObject(t, dir, "shop", "example.com/prod/shop/configmaps/settings.yaml", settings)
+	tampered := exportObject(t, dir, "shop", "example.com/prod/shop/configmaps/tampered.yaml", settings)
+	writeFile(t, filepath.Join(dir, "example.com", "prod", "shop", "configmaps", "tampered.yaml"), settings+"  namespace: other\n")
+	missing := backup.ObjectEntry{Namespace: "shop", Name: "gone", Key: "example.com/prod/shop/configmaps/gone.yaml"}
+	broken := exportObject(t, dir, "", "example.com/prod/_cluster/namespaces/shop.yaml", "key: [unclosed\n")
+	node := exportObject(t, dir, "", "example.com/prod/_cluster/nodes/node-1.yaml", settings)
+	tenant := backup.ObjectEntry{Namespace: "billing", Name: "ledger", Bucket: "tenant-billing", Key: "example.com/prod/billing/configmaps/ledger.yaml"}
+
+	// An older run is ignored
+	writeManifest(t, dir, &backup.Manifest{BackupID: "backup-20250924-120000", Objects: []backup.ObjectEntry{missing}})
+	writeManifest(t, dir, &backup.Manifest{
+		BackupID:          "backup-20250925-120000",
+		Objects:           []backup.ObjectEntry{intact, tampered, missing, broken, node, tenant},
+		AlternateVersions: []backup.ObjectEntry{intact},
+	})
+
+	var result Result
+	checkBackupData(context.Background(), Options{BackupDir: dir}, &result)
+	assert.Equal(t, StatusFailed, result.Status)
+	assert.Equal(t, "backup-20250925-120000", result.Details["backup_id"])
+	assert.Equal(t, 6, result.Details["total_objects"])
+	assert.Equal(t, 3, result.Details["intact_objects"])
+	assert.Equal(t, 1, result.Details["tenant_objects"], "objects in tenants' buckets are not exported")
+	assert.Equal(t, map[string]float64{"shop": 50, clusterScope: 50}, result.Details["namespace_scores"])
+	assert.InDelta(t, 50, result.Details["integrity_score"], 0.01)
+	problems := make(map[string]string)
+	for _, problem := range result.Details["problems"].([]IntegrityProblem) {
+		problems[problem.Key] = problem.Problem
+	}
+	assert.Equal(t, map[string]string{
+		tampered.Key: IntegrityChecksumMismatch,
+		missing.Key:  IntegrityMissing,
+		broken.Key:   IntegrityInvalidYAML,
+	}, problems)
+
+	metrics := NewMetrics(prometheus.NewRegistry())
+	result.Name = "data_integrity_validation"
+	metrics.Record(Results{Checks: []Result{result}})
+	assert.Equal(t, float64(50), testutil.ToFloat64(metrics.integrity))
+	assert.Equal(t, float64(50), testutil.ToFloat64(metrics.nsIntegrity.WithLabelValues("shop")))
+
+	writeManifest(t, dir, &backup.Manifest{BackupID: "backup-20250926-120000", Objects: []backup.ObjectEntry{intact, node}})
+	result = Result{}
+	checkBackupData(context.Background(), Options{BackupDir: dir}, &result)
+	assert.Equal(t, StatusPassed, result.Status)
+	assert.Equal(t, map[string]float64{"shop": 100, clusterScope: 100}, result.Details["namespace_scores"])
+}
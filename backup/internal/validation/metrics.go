@@ -0,0 +1,149 @@
+package validation
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// checkLabels are the labels every check metric carries
+var checkLabels = []string{"check", "category", "severity"}
+
+// Metrics exports the outcome of checks, per check name, category and
+// severity: whether the latest run passed, how long it took and when it ran,
+// and the runs by status. It also exports the usage of each node the node
+// usage check read, the status of each Argo CD application, the readiness
+// of each Flux resource and the integrity of the latest exported backup.
+type Metrics struct {
+	passed      *prometheus.GaugeVec
+	runs        *prometheus.CounterVec
+	duration    *prometheus.GaugeVec
+	lastRun     *prometheus.GaugeVec
+	nodeCPU     *prometheus.GaugeVec
+	nodeMemory  *prometheus.GaugeVec
+	appSynced   *prometheus.GaugeVec
+	appHealthy  *prometheus.GaugeVec
+	appLastSync *prometheus.GaugeVec
+	fluxReady   *prometheus.GaugeVec
+	fluxChanged *prometheus.GaugeVec
+	integrity   prometheus.Gauge
+	nsIntegrity *prometheus.GaugeVec
+}
+
+// NewMetrics registers the check metrics with registerer, such as
+// prometheus.DefaultRegisterer to serve them with the backup metrics
+func NewMetrics(registerer prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		passed: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "validation_check_passed",
+			Help: "1 when the latest run of the health check passed, 0 when it failed or warned",
+		}, checkLabels),
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "validation_check_runs_total",
+			Help: "Runs of the health check, by status: passed, failed or warning",
+		}, append(append([]string{}, checkLabels...), "status")),
+		duration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "validation_check_duration_seconds",
+			Help: "How long the latest run of the health check took",
+		}, checkLabels),
+		lastRun: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "validation_check_last_run_timestamp_seconds",
+			Help: "Unix time of the latest run of the health check",
+		}, checkLabels),
+		nodeCPU: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "validation_node_cpu_usage_percent",
+			Help: "CPU the node used in percent of its allocatable CPU, as of the latest node usage check",
+		}, []string{"node"}),
+		nodeMemory: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "validation_node_memory_usage_percent",
+			Help: "Memory the node used in percent of its allocatable memory, as of the latest node usage check",
+		}, []string{"node"}),
+		appSynced: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "validation_argocd_application_synced",
+			Help: "1 when the Argo CD application is synced, as of the latest Argo CD applications check",
+		}, []string{"application", "namespace"}),
+		appHealthy: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "validation_argocd_application_healthy",
+			Help: "1 when the Argo CD application is healthy, as of the latest Argo CD applications check",
+		}, []string{"application", "namespace"}),
+		appLastSync: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "validation_argocd_application_last_sync_timestamp_seconds",
+			Help: "Unix time the latest sync of the Argo CD application finished",
+		}, []string{"application", "namespace"}),
+		fluxReady: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "validation_flux_resource_ready",
+			Help: "1 when the Flux resource's Ready condition is True, as of the latest Flux resources check",
+		}, []string{"kind", "name", "namespace"}),
+		fluxChanged: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "validation_flux_resource_last_transition_timestamp_seconds",
+			Help: "Unix time the Flux resource's Ready condition last changed",
+		}, []string{"kind", "name", "namespace"}),
+		integrity: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "validation_data_integrity_score",
+			Help: "Share in percent of the latest exported backup's objects that are present, match their checksum and parse as YAML",
+		}),
+		nsIntegrity: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "validation_data_integrity_namespace_score",
+			Help: "Share in percent of the namespace's objects in the latest exported backup that are intact",
+		}, []string{"namespace"}),
+	}
+	registerer.MustRegister(m.passed, m.runs, m.duration, m.lastRun, m.nodeCPU, m.nodeMemory,
+		m.appSynced, m.appHealthy, m.appLastSync, m.fluxReady, m.fluxChanged, m.integrity, m.nsIntegrity)
+	return m
+}
+
+// Record updates the metrics of every check in results
+func (m *Metrics) Record(results Results) {
+	for _, check := range results.Checks {
+		m.passed.WithLabelValues(check.Name, check.Category, check.Severity).Set(boolValue(check.Status == StatusPassed))
+		m.runs.WithLabelValues(check.Name, check.Category, check.Severity, check.Status).Inc()
+		m.duration.WithLabelValues(check.Name, check.Category, check.Severity).Set(check.Duration.Seconds())
+		m.lastRun.WithLabelValues(check.Name, check.Category, check.Severity).Set(float64(check.Timestamp.Unix()))
+
+		// Nodes gone since the last check drop out
+		if nodes, ok := check.Details["nodes"].([]NodeUsage); ok {
+			m.nodeCPU.Reset()
+			m.nodeMemory.Reset()
+			for _, node := range nodes {
+				m.nodeCPU.WithLabelValues(node.Node).Set(node.CPUPercent)
+				m.nodeMemory.WithLabelValues(node.Node).Set(node.MemoryPercent)
+			}
+		}
+		if applications, ok := check.Details["applications"].([]ArgoCDApplication); ok {
+			m.appSynced.Reset()
+			m.appHealthy.Reset()
+			m.appLastSync.Reset()
+			for _, application := range applications {
+				m.appSynced.WithLabelValues(application.Name, application.Namespace).Set(boolValue(application.SyncStatus == "Synced"))
+				m.appHealthy.WithLabelValues(application.Name, application.Namespace).Set(boolValue(application.HealthStatus == "Healthy"))
+				if !application.LastSynced.IsZero() {
+					m.appLastSync.WithLabelValues(application.Name, application.Namespace).Set(float64(application.LastSynced.Unix()))
+				}
+			}
+		}
+		if resources, ok := check.Details["resources"].([]FluxResource); ok {
+			m.fluxReady.Reset()
+			m.fluxChanged.Reset()
+			for _, resource := range resources {
+				m.fluxReady.WithLabelValues(resource.Kind, resource.Name, resource.Namespace).Set(boolValue(resource.Ready == "True"))
+				if !resource.LastTransition.IsZero() {
+					m.fluxChanged.WithLabelValues(resource.Kind, resource.Name, resource.Namespace).Set(float64(resource.LastTransition.Unix()))
+				}
+			}
+		}
+		if scores, ok := check.Details["namespace_scores"].(map[string]float64); ok {
+			score, _ := check.Details["integrity_score"].(float64)
+			m.integrity.Set(score)
+			m.nsIntegrity.Reset()
+			for namespace, score := range scores {
+				m.nsIntegrity.WithLabelValues(namespace).Set(score)
+			}
+		}
+	}
+}
+
+// boolValue is 1 for true and 0 for false
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
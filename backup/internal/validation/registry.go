@@ -0,0 +1,116 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Check is a check RunChecks can run. Run sets the status, message,
+// remediation and details of result, whose name, category, severity and
+// timing RunChecks fills in. A check leaves the status unset when it finds
+// nothing it applies to, such as the CRDs of a tool that is not installed,
+// and is then left out of the results.
+type Check interface {
+	Name() string
+	Category() string
+	Severity() string
+	Run(ctx context.Context, opts Options, result *Result)
+}
+
+// ConfigurableCheck is a Check taking the options of its settings in the
+// configuration
+type ConfigurableCheck interface {
+	Check
+	Configure(options map[string]interface{}) error
+}
+
+// Registry holds the checks RunChecks can run, in the order they run
+type Registry struct {
+	mutex  sync.RWMutex
+	checks []Check
+}
+
+// NewRegistry returns a registry of the built-in checks, to which programs
+// can add their own
+func NewRegistry() *Registry {
+	builtins := []*check{
+		{"kubernetes_cluster_connectivity", CategoryInfrastructure, SeverityCritical, true, false, nil, checkConnectivity},
+		{"kubernetes_node_health", CategoryInfrastructure, SeverityCritical, true, false, nil, checkNodes},
+		{"kubernetes_pod_health", CategoryWorkloads, SeverityHigh, true, false, nil, checkPods},
+		{"kubernetes_resource_quotas", CategoryResources, SeverityMedium, true, false, nil, checkQuotas},
+		{"kubernetes_persistent_volumes", CategoryStorage, SeverityMedium, true, false, nil, checkVolumes},
+		{"gitops_controllers", CategoryGitOps, SeverityHigh, true, false, nil, checkGitOpsControllers},
+		{"argocd_applications", CategoryGitOps, SeverityHigh, true, true, nil, checkArgoCDApplications},
+		{"flux_resources", CategoryGitOps, SeverityHigh, true, true, nil, checkFluxResources},
+		{"gitops_repository_structure", CategoryGitOps, SeverityMedium, false, false, needsRepo, checkRepoLayout},
+		{"data_integrity_validation", CategoryData, SeverityCritical, false, false, needsBackupDir, checkBackupData},
+		{"yaml_syntax_validation", CategorySyntax, SeverityMedium, false, false, needsRepo, checkRepoSyntax},
+		{"security_validation", CategorySecurity, SeverityHigh, true, false, nil, checkSecurity},
+		{"performance_check", CategoryPerformance, SeverityMedium, true, false, nil, checkPerformance},
+		{"kubernetes_node_usage", CategoryPerformance, SeverityMedium, true, true, nil, checkNodeUsage},
+		{"kubernetes_pod_usage", CategoryPerformance, SeverityMedium, true, true, nil, checkPodUsage},
+	}
+	r := &Registry{checks: make([]Check, 0, len(builtins))}
+	for _, builtin := range builtins {
+		r.checks = append(r.checks, builtin)
+	}
+	return r
+}
+
+// Register adds c to the checks, after those already registered, failing
+// when a check of the same name is registered
+func (r *Registry) Register(c Check) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, registered := range r.checks {
+		if registered.Name() == c.Name() {
+			return fmt.Errorf("check %s is already registered", c.Name())
+		}
+	}
+	r.checks = append(r.checks, c)
+	return nil
+}
+
+// Lookup returns the check named name, nil when none is registered
+func (r *Registry) Lookup(name string) Check {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for _, registered := range r.checks {
+		if registered.Name() == name {
+			return registered
+		}
+	}
+	return nil
+}
+
+// Checks returns the registered checks in the order they run
+func (r *Registry) Checks() []Check {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return append([]Check(nil), r.checks...)
+}
+
+// check is a built-in check, running a function
+type check struct {
+	name     string
+	category string
+	severity string
+	// cluster is whether the check reads the cluster through the KubeClient
+	cluster bool
+	// dynamic is whether it also reads it through the DynamicClient
+	dynamic bool
+	// needs reports whether the options give the check something to check,
+	// nil when it always has
+	needs func(opts Options) bool
+	run   func(ctx context.Context, opts Options, result *Result)
+}
+
+func (c *check) Name() string     { return c.name }
+func (c *check) Category() string { return c.category }
+func (c *check) Severity() string { return c.severity }
+
+func (c *check) Run(ctx context.Context, opts Options, result *Result) { c.run(ctx, opts, result) }
+
+func needsRepo(opts Options) bool      { return opts.GitOpsRepo != "" }
+func needsBackupDir(opts Options) bool { return opts.BackupDir != "" }
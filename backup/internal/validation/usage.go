@@ -0,0 +1,216 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// DefaultUsageThreshold is the percentage of CPU or memory the usage checks
+// warn above when Options.CPUThreshold or Options.MemoryThreshold is not set
+const DefaultUsageThreshold = 80.0
+
+// The resources of metrics-server's API, read through the dynamic client
+var (
+	nodeMetricsResource = schema.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "nodes"}
+	podMetricsResource  = schema.GroupVersionResource{Group: "metrics.k8s.io", Version: "v1beta1", Resource: "pods"}
+)
+
+// NodeUsage is the CPU and memory a node uses in percent of what it can
+// allocate to pods
+type NodeUsage struct {
+	Node          string  `json:"node" yaml:"node"`
+	CPUPercent    float64 `json:"cpu_percent" yaml:"cpu_percent"`
+	MemoryPercent float64 `json:"memory_percent" yaml:"memory_percent"`
+}
+
+// ContainerUsage is the CPU and memory a container uses in percent of its
+// limits, 0 for a resource it has no limit for
+type ContainerUsage struct {
+	Namespace     string  `json:"namespace" yaml:"namespace"`
+	Pod           string  `json:"pod" yaml:"pod"`
+	Container     string  `json:"container" yaml:"container"`
+	CPUPercent    float64 `json:"cpu_percent" yaml:"cpu_percent"`
+	MemoryPercent float64 `json:"memory_percent" yaml:"memory_percent"`
+}
+
+// usageThresholds returns the CPU and memory thresholds of opts
+func usageThresholds(opts Options) (cpu, memory float64) {
+	cpu, memory = opts.CPUThreshold, opts.MemoryThreshold
+	if cpu <= 0 {
+		cpu = DefaultUsageThreshold
+	}
+	if memory <= 0 {
+		memory = DefaultUsageThreshold
+	}
+	return cpu, memory
+}
+
+// checkNodeUsage warns about nodes using more CPU or memory than the
+// thresholds allow, in percent of their allocatable resources, as reported
+// by metrics-server. Nodes it has not sampled yet are left out.
+func checkNodeUsage(ctx context.Context, opts Options, result *Result) {
+	metrics, err := opts.DynamicClient.Resource(nodeMetricsResource).List(ctx, v1.ListOptions{})
+	if err != nil {
+		warn(result, fmt.Sprintf("Failed to read node usage from metrics-server: %v", err),
+			"Check metrics-server is installed and serves metrics.k8s.io")
+		return
+	}
+	nodes, err := opts.KubeClient.CoreV1().Nodes().List(ctx, v1.ListOptions{})
+	if err != nil {
+		warn(result, fmt.Sprintf("Failed to list nodes: %v", err), "")
+		return
+	}
+	allocatable := make(map[string]corev1.ResourceList, len(nodes.Items))
+	for _, node := range nodes.Items {
+		allocatable[node.Name] = node.Status.Allocatable
+	}
+
+	cpuThreshold, memoryThreshold := usageThresholds(opts)
+	usage := make([]NodeUsage, 0, len(metrics.Items))
+	var high []string
+	for _, item := range metrics.Items {
+		capacity, ok := allocatable[item.GetName()]
+		if !ok {
+			continue
+		}
+		used, err := usageOf(item.Object, "usage")
+		if err != nil {
+			warn(result, fmt.Sprintf("Failed to read the usage of node %s: %v", item.GetName(), err), "")
+			return
+		}
+		node := NodeUsage{
+			Node:          item.GetName(),
+			CPUPercent:    percentOf(used.Cpu(), capacity.Cpu()),
+			MemoryPercent: percentOf(used.Memory(), capacity.Memory()),
+		}
+		usage = append(usage, node)
+		if node.CPUPercent > cpuThreshold {
+			high = append(high, fmt.Sprintf("%s CPU %.1f%%", node.Node, node.CPUPercent))
+		}
+		if node.MemoryPercent > memoryThreshold {
+			high = append(high, fmt.Sprintf("%s memory %.1f%%", node.Node, node.MemoryPercent))
+		}
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Node < usage[j].Node })
+
+	result.Details = map[string]interface{}{
+		"nodes":            usage,
+		"high_usage":       high,
+		"cpu_threshold":    cpuThreshold,
+		"memory_threshold": memoryThreshold,
+	}
+	switch {
+	case len(usage) == 0:
+		warn(result, "metrics-server reported no node usage", "Check metrics-server is running and has sampled the nodes")
+	case len(high) > 0:
+		warn(result, fmt.Sprintf("%d node resources are above the usage thresholds", len(high)),
+			"Add capacity or move workloads off the busy nodes")
+	default:
+		result.Status = StatusPassed
+		result.Message = fmt.Sprintf("All %d nodes are within the usage thresholds", len(usage))
+	}
+}
+
+// checkPodUsage warns about containers using more CPU or memory than the
+// thresholds allow, in percent of their limits, as reported by
+// metrics-server. Resources a container has no limit for are not checked.
+func checkPodUsage(ctx context.Context, opts Options, result *Result) {
+	metrics, err := opts.DynamicClient.Resource(podMetricsResource).Namespace("").List(ctx, v1.ListOptions{})
+	if err != nil {
+		warn(result, fmt.Sprintf("Failed to read pod usage from metrics-server: %v", err),
+			"Check metrics-server is installed and serves metrics.k8s.io")
+		return
+	}
+	pods, err := opts.KubeClient.CoreV1().Pods("").List(ctx, v1.ListOptions{})
+	if err != nil {
+		warn(result, fmt.Sprintf("Failed to list pods: %v", err), "")
+		return
+	}
+	limits := make(map[string]corev1.ResourceList)
+	for _, pod := range pods.Items {
+		for _, container := range pod.Spec.Containers {
+			limits[pod.Namespace+"/"+pod.Name+"/"+container.Name] = container.Resources.Limits
+		}
+	}
+
+	cpuThreshold, memoryThreshold := usageThresholds(opts)
+	var high []ContainerUsage
+	sampled := 0
+	for _, item := range metrics.Items {
+		containers, _, _ := unstructured.NestedSlice(item.Object, "containers")
+		for _, entry := range containers {
+			container, ok := entry.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _, _ := unstructured.NestedString(container, "name")
+			limit, ok := limits[item.GetNamespace()+"/"+item.GetName()+"/"+name]
+			if !ok || (limit.Cpu().IsZero() && limit.Memory().IsZero()) {
+				continue
+			}
+			used, err := usageOf(container, "usage")
+			if err != nil {
+				warn(result, fmt.Sprintf("Failed to read the usage of pod %s/%s: %v", item.GetNamespace(), item.GetName(), err), "")
+				return
+			}
+			sampled++
+			usage := ContainerUsage{
+				Namespace:     item.GetNamespace(),
+				Pod:           item.GetName(),
+				Container:     name,
+				CPUPercent:    percentOf(used.Cpu(), limit.Cpu()),
+				MemoryPercent: percentOf(used.Memory(), limit.Memory()),
+			}
+			if usage.CPUPercent > cpuThreshold || usage.MemoryPercent > memoryThreshold {
+				high = append(high, usage)
+			}
+		}
+	}
+
+	result.Details = map[string]interface{}{
+		"containers":       sampled,
+		"high_usage":       high,
+		"cpu_threshold":    cpuThreshold,
+		"memory_threshold": memoryThreshold,
+	}
+	if len(high) > 0 {
+		warn(result, fmt.Sprintf("%d/%d containers are above the usage thresholds of their limits", len(high), sampled),
+			"Raise the limits of the listed containers or reduce their load")
+		return
+	}
+	result.Status = StatusPassed
+	result.Message = fmt.Sprintf("All %d containers are within the usage thresholds of their limits", sampled)
+}
+
+// usageOf parses the cpu and memory quantities under field of a metrics
+// object
+func usageOf(object map[string]interface{}, field string) (corev1.ResourceList, error) {
+	values, _, err := unstructured.NestedStringMap(object, field)
+	if err != nil {
+		return nil, err
+	}
+	usage := make(corev1.ResourceList, len(values))
+	for name, value := range values {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s usage %q: %v", name, value, err)
+		}
+		usage[corev1.ResourceName(name)] = quantity
+	}
+	return usage, nil
+}
+
+// percentOf returns used in percent of total, 0 when total is not set
+func percentOf(used, total *resource.Quantity) float64 {
+	if total.IsZero() {
+		return 0
+	}
+	return float64(used.MilliValue()) / float64(total.MilliValue()) * 100
+}
@@ -0,0 +1,130 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func nodeMetrics(name, cpu, memory string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "metrics.k8s.io/v1beta1",
+		"kind":       "NodeMetrics",
+		"metadata":   map[string]interface{}{"name": name},
+		"usage":      map[string]interface{}{"cpu": cpu, "memory": memory},
+	}}
+}
+
+func podMetrics(namespace, name, container, cpu, memory string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "metrics.k8s.io/v1beta1",
+		"kind":       "PodMetrics",
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+		"containers": []interface{}{map[string]interface{}{
+			"name":  container,
+			"usage": map[string]interface{}{"cpu": cpu, "memory": memory},
+		}},
+	}}
+}
+
+func allocatableNode(name, cpu, memory string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: v1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(cpu),
+			corev1.ResourceMemory: resource.MustParse(memory),
+		}},
+	}
+}
+
+func TestCheckNodeUsage(t *testing.T) {
+	ctx := context.Background()
+	opts := Options{
+		KubeClient: fake.NewSimpleClientset(allocatableNode("node-1", "2", "4Gi"), allocatableNode("node-2", "4", "8Gi")),
+		DynamicClient: dynamicServer(t,
+			nodeMetrics("node-1", "500m", "1Gi"),
+			nodeMetrics("node-2", "3600m", "2Gi"),
+			nodeMetrics("node-gone", "1", "1Gi"),
+		),
+	}
+
+	var result Result
+	checkNodeUsage(ctx, opts, &result)
+	assert.Equal(t, StatusWarning, result.Status)
+	assert.Equal(t, []NodeUsage{
+		{Node: "node-1", CPUPercent: 25, MemoryPercent: 25},
+		{Node: "node-2", CPUPercent: 90, MemoryPercent: 25},
+	}, result.Details["nodes"], "nodes no longer in the cluster are left out")
+	assert.Equal(t, []string{"node-2 CPU 90.0%"}, result.Details["high_usage"])
+
+	metrics := NewMetrics(prometheus.NewRegistry())
+	result.Name = "kubernetes_node_usage"
+	metrics.Record(Results{Checks: []Result{result}})
+	assert.Equal(t, float64(90), testutil.ToFloat64(metrics.nodeCPU.WithLabelValues("node-2")))
+	assert.Equal(t, float64(25), testutil.ToFloat64(metrics.nodeMemory.WithLabelValues("node-1")))
+
+	opts.CPUThreshold = 95
+	result = Result{}
+	checkNodeUsage(ctx, opts, &result)
+	assert.Equal(t, StatusPassed, result.Status, "the configured threshold applies")
+}
+
+func TestCheckNodeUsage_NoMetricsServer(t *testing.T) {
+	dynamicClient := dynamicServer(t)
+	dynamicClient.PrependReactor("list", "nodes", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("the server could not find the requested resource")
+	})
+
+	var result Result
+	checkNodeUsage(context.Background(), Options{KubeClient: fake.NewSimpleClientset(), DynamicClient: dynamicClient}, &result)
+	assert.Equal(t, StatusWarning, result.Status)
+	assert.Contains(t, result.Message, "metrics-server")
+}
+
+func TestCheckPodUsage(t *testing.T) {
+	limited := func(name, cpu, memory string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: v1.ObjectMeta{Name: name, Namespace: "shop"},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Name: "app",
+				Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse(cpu),
+					corev1.ResourceMemory: resource.MustParse(memory),
+				}},
+			}}},
+		}
+	}
+	unlimited := &corev1.Pod{
+		ObjectMeta: v1.ObjectMeta{Name: "batch", Namespace: "shop"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+	opts := Options{
+		KubeClient: fake.NewSimpleClientset(limited("web", "1", "1Gi"), limited("cache", "1", "1Gi"), unlimited),
+		DynamicClient: dynamicServer(t,
+			podMetrics("shop", "web", "app", "200m", "256Mi"),
+			podMetrics("shop", "cache", "app", "100m", "972Mi"),
+			podMetrics("shop", "batch", "app", "4", "16Gi"),
+		),
+	}
+
+	var result Result
+	checkPodUsage(context.Background(), opts, &result)
+	assert.Equal(t, StatusWarning, result.Status)
+	assert.Equal(t, 2, result.Details["containers"], "containers without limits are not checked")
+	high := result.Details["high_usage"].([]ContainerUsage)
+	require.Len(t, high, 1)
+	assert.Equal(t, "cache", high[0].Pod)
+	assert.InDelta(t, 94.9, high[0].MemoryPercent, 0.1)
+}
@@ -0,0 +1,681 @@
+// Package validation runs health checks against a cluster and the files a
+// GitOps pipeline produces from its backups: node, pod, quota and volume
+// health, node and container usage from metrics-server, the GitOps
+// controllers, the sync and health of Argo CD applications and the
+// reconciliation of Flux sources and Kustomizations, the integrity of
+// exported backups, the syntax of repository YAML, basic security posture and
+// API server latency. Programs add their own checks to a Registry, and a
+// Config enables, disables and configures checks and adds commands as custom
+// checks.
+package validation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Exit codes of a health check run, as for configuration validation
+const (
+	CheckPassed   = 0
+	CheckFailed   = 1
+	CheckWarnings = 2
+)
+
+// Outcomes of a check
+const (
+	StatusPassed  = "passed"
+	StatusWarning = "warning"
+	StatusFailed  = "failed"
+)
+
+// Severities of a check failing
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+)
+
+// Categories of checks, which Options.Categories selects from
+const (
+	CategoryInfrastructure = "infrastructure"
+	CategoryWorkloads      = "workloads"
+	CategoryResources      = "resources"
+	CategoryStorage        = "storage"
+	CategoryGitOps         = "gitops"
+	CategoryData           = "data"
+	CategorySyntax         = "syntax"
+	CategorySecurity       = "security"
+	CategoryPerformance    = "performance"
+	// CategoryCustom is the default category of custom checks
+	CategoryCustom = "custom"
+)
+
+// DefaultMaxAPILatency is the API server latency the performance check warns
+// above when Options.MaxAPILatency is not set
+const DefaultMaxAPILatency = 5 * time.Second
+
+// gitOpsLayout are the directories a GitOps repository generated from
+// backups is expected to have
+var gitOpsLayout = []string{"base", "overlays", "argocd", "flux"}
+
+// Options select the checks RunChecks runs and what they check
+type Options struct {
+	// KubeClient is the cluster checked. It is required unless only checks of
+	// the backup directory and the GitOps repository are selected.
+	KubeClient kubernetes.Interface
+	// DynamicClient reads the node and pod usage metrics-server serves. It
+	// is required with the KubeClient for the usage checks.
+	DynamicClient dynamic.Interface
+	// Categories limits the checks to these categories; empty runs them all
+	Categories []string
+	// BackupDir is a backup exported by backup-util export, whose latest run
+	// must have every object its manifest lists intact, or a directory of
+	// resources whose YAML documents must each parse and name their
+	// apiVersion, kind and name; empty skips it
+	BackupDir string
+	// GitOpsRepo is a checkout of the GitOps repository whose layout and YAML
+	// syntax are checked; empty skips those checks
+	GitOpsRepo string
+	// MaxAPILatency is the latency of listing pods above which the
+	// performance check warns, DefaultMaxAPILatency when zero
+	MaxAPILatency time.Duration
+	// CPUThreshold and MemoryThreshold are the percentages of their
+	// allocatable resources, or of a container's limits, above which the
+	// usage checks warn, DefaultUsageThreshold when zero
+	CPUThreshold    float64
+	MemoryThreshold float64
+	// Registry holds the checks to run, NewRegistry's built-in checks when
+	// nil. Config.Apply adds the configured custom checks to it.
+	Registry *Registry
+	// Settings enable, disable or set the severity of checks by name
+	Settings map[string]CheckSettings
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name        string                 `json:"name" yaml:"name"`
+	Category    string                 `json:"category" yaml:"category"`
+	Severity    string                 `json:"severity" yaml:"severity"`
+	Status      string                 `json:"status" yaml:"status"`
+	Message     string                 `json:"message" yaml:"message"`
+	Remediation string                 `json:"remediation,omitempty" yaml:"remediation,omitempty"`
+	Timestamp   time.Time              `json:"timestamp" yaml:"timestamp"`
+	Duration    time.Duration          `json:"duration" yaml:"duration"`
+	Details     map[string]interface{} `json:"details,omitempty" yaml:"details,omitempty"`
+}
+
+// Results are the outcomes of the checks of a RunChecks call, in the order
+// they ran
+type Results struct {
+	StartTime time.Time     `json:"start_time" yaml:"start_time"`
+	Duration  time.Duration `json:"duration" yaml:"duration"`
+	Checks    []Result      `json:"checks" yaml:"checks"`
+}
+
+// Count returns the number of checks that ended with status
+func (r Results) Count(status string) int {
+	count := 0
+	for _, check := range r.Checks {
+		if check.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// ExitCode returns CheckFailed when a check failed, CheckWarnings when some
+// warned, and CheckPassed otherwise
+func (r Results) ExitCode() int {
+	switch {
+	case r.Count(StatusFailed) > 0:
+		return CheckFailed
+	case r.Count(StatusWarning) > 0:
+		return CheckWarnings
+	default:
+		return CheckPassed
+	}
+}
+
+// RunChecks runs the checks of the selected categories one after the other
+// and returns their results. A check that cannot reach what it checks fails
+// or warns in its result, as does one that panics; RunChecks only returns an
+// error for invalid options. Checks that do not apply to the cluster are left
+// out of the results.
+func RunChecks(ctx context.Context, opts Options) (Results, error) {
+	selected, err := selectChecks(opts)
+	if err != nil {
+		return Results{}, err
+	}
+
+	results := Results{StartTime: time.Now()}
+	for _, c := range selected {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		result := Result{Name: c.Name(), Category: c.Category(), Severity: c.Severity(), Timestamp: time.Now()}
+		if severity := opts.Settings[c.Name()].Severity; severity != "" {
+			result.Severity = severity
+		}
+		runCheck(ctx, c, opts, &result)
+		if result.Status == "" {
+			continue
+		}
+		result.Duration = time.Since(result.Timestamp)
+		results.Checks = append(results.Checks, result)
+	}
+	results.Duration = time.Since(results.StartTime)
+	return results, nil
+}
+
+// runCheck runs c, failing it when it panics
+func runCheck(ctx context.Context, c Check, opts Options, result *Result) {
+	defer func() {
+		if r := recover(); r != nil {
+			fail(result, fmt.Sprintf("Check panicked: %v", r), "")
+		}
+	}()
+	c.Run(ctx, opts, result)
+}
+
+// selectChecks returns the enabled checks of opts' registry that have
+// something to check. A check runs when its settings enable it, or else when
+// they do not disable it and its category is selected.
+func selectChecks(opts Options) ([]Check, error) {
+	registry := opts.Registry
+	if registry == nil {
+		registry = NewRegistry()
+	}
+	registered := registry.Checks()
+
+	categories := make(map[string]bool, len(opts.Categories))
+	for _, category := range opts.Categories {
+		categories[category] = true
+	}
+	known := map[string]bool{CategoryCustom: true}
+	for _, c := range registered {
+		known[c.Category()] = true
+	}
+	for category := range categories {
+		if !known[category] {
+			return nil, fmt.Errorf("unknown check category %q", category)
+		}
+	}
+
+	var selected []Check
+	for _, c := range registered {
+		enabled := len(categories) == 0 || categories[c.Category()]
+		if setting := opts.Settings[c.Name()].Enabled; setting != nil {
+			enabled = *setting
+		}
+		if !enabled {
+			continue
+		}
+		if builtin, ok := c.(*check); ok {
+			if builtin.needs != nil && !builtin.needs(opts) {
+				continue
+			}
+			if builtin.cluster && opts.KubeClient == nil {
+				return nil, fmt.Errorf("check %s needs a Kubernetes client", c.Name())
+			}
+			if builtin.dynamic && opts.DynamicClient == nil {
+				return nil, fmt.Errorf("check %s needs a dynamic Kubernetes client", c.Name())
+			}
+		}
+		selected = append(selected, c)
+	}
+	return selected, nil
+}
+
+func checkConnectivity(ctx context.Context, opts Options, result *Result) {
+	version, err := opts.KubeClient.Discovery().ServerVersion()
+	if err != nil {
+		fail(result, fmt.Sprintf("Failed to connect to cluster: %v", err), "Check cluster connectivity and kubeconfig")
+		return
+	}
+	result.Status = StatusPassed
+	result.Message = "Connected to Kubernetes " + version.GitVersion
+}
+
+func checkNodes(ctx context.Context, opts Options, result *Result) {
+	nodes, err := opts.KubeClient.CoreV1().Nodes().List(ctx, v1.ListOptions{})
+	if err != nil {
+		fail(result, fmt.Sprintf("Failed to list nodes: %v", err), "")
+		return
+	}
+
+	var notReady []string
+	for _, node := range nodes.Items {
+		if !nodeReady(node) {
+			notReady = append(notReady, node.Name)
+		}
+	}
+	result.Details = map[string]interface{}{
+		"total_nodes":     len(nodes.Items),
+		"healthy_nodes":   len(nodes.Items) - len(notReady),
+		"not_ready_nodes": notReady,
+	}
+	switch {
+	case len(nodes.Items) == 0:
+		fail(result, "No nodes found", "Check the cluster has schedulable nodes")
+	case len(notReady) > 0:
+		fail(result, fmt.Sprintf("Only %d/%d nodes are ready", len(nodes.Items)-len(notReady), len(nodes.Items)),
+			"Check node status and resolve any issues")
+	default:
+		result.Status = StatusPassed
+		result.Message = fmt.Sprintf("All %d nodes are ready", len(nodes.Items))
+	}
+}
+
+func nodeReady(node corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// checkPods fails when fewer than 90% of the pods are running or completed
+func checkPods(ctx context.Context, opts Options, result *Result) {
+	pods, err := opts.KubeClient.CoreV1().Pods("").List(ctx, v1.ListOptions{})
+	if err != nil {
+		fail(result, fmt.Sprintf("Failed to list pods: %v", err), "")
+		return
+	}
+
+	byPhase := make(map[string]int)
+	var unhealthy []string
+	for _, pod := range pods.Items {
+		byPhase[string(pod.Status.Phase)]++
+		if pod.Status.Phase != corev1.PodRunning && pod.Status.Phase != corev1.PodSucceeded {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s/%s:%s", pod.Namespace, pod.Name, pod.Status.Phase))
+		}
+	}
+	healthy := 100.0
+	if len(pods.Items) > 0 {
+		healthy = float64(len(pods.Items)-len(unhealthy)) / float64(len(pods.Items)) * 100
+	}
+	result.Details = map[string]interface{}{
+		"total_pods":        len(pods.Items),
+		"health_percentage": healthy,
+		"pods_by_phase":     byPhase,
+		"unhealthy_pods":    unhealthy,
+	}
+	if healthy < 90 {
+		fail(result, fmt.Sprintf("Pod health is poor: %.1f%% of %d pods running or completed", healthy, len(pods.Items)),
+			"Investigate failing pods and resolve issues")
+		return
+	}
+	result.Status = StatusPassed
+	result.Message = fmt.Sprintf("Pod health is good: %.1f%% of %d pods running or completed", healthy, len(pods.Items))
+}
+
+// checkQuotas warns about quotas more than 90% used
+func checkQuotas(ctx context.Context, opts Options, result *Result) {
+	quotas, err := opts.KubeClient.CoreV1().ResourceQuotas("").List(ctx, v1.ListOptions{})
+	if err != nil {
+		warn(result, fmt.Sprintf("Failed to list resource quotas: %v", err), "")
+		return
+	}
+
+	var nearLimit []string
+	for _, quota := range quotas.Items {
+		for resource, hard := range quota.Status.Hard {
+			used, ok := quota.Status.Used[resource]
+			if !ok || hard.IsZero() {
+				continue
+			}
+			if used.AsApproximateFloat64()/hard.AsApproximateFloat64() > 0.9 {
+				nearLimit = append(nearLimit, fmt.Sprintf("%s/%s %s: %s/%s", quota.Namespace, quota.Name, resource, used.String(), hard.String()))
+			}
+		}
+	}
+	result.Details = map[string]interface{}{
+		"total_quotas": len(quotas.Items),
+		"near_limit":   nearLimit,
+	}
+	if len(nearLimit) > 0 {
+		warn(result, fmt.Sprintf("%d quota resources are more than 90%% used", len(nearLimit)),
+			"Review resource usage and consider increasing quotas or optimizing workloads")
+		return
+	}
+	result.Status = StatusPassed
+	result.Message = fmt.Sprintf("All %d resource quotas are within limits", len(quotas.Items))
+}
+
+// checkVolumes warns about persistent volumes neither available nor bound
+func checkVolumes(ctx context.Context, opts Options, result *Result) {
+	pvs, err := opts.KubeClient.CoreV1().PersistentVolumes().List(ctx, v1.ListOptions{})
+	if err != nil {
+		warn(result, fmt.Sprintf("Failed to list persistent volumes: %v", err), "")
+		return
+	}
+
+	byPhase := make(map[string]int)
+	var unhealthy []string
+	for _, pv := range pvs.Items {
+		byPhase[string(pv.Status.Phase)]++
+		if pv.Status.Phase != corev1.VolumeAvailable && pv.Status.Phase != corev1.VolumeBound {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s:%s", pv.Name, pv.Status.Phase))
+		}
+	}
+	result.Details = map[string]interface{}{
+		"total_pvs":     len(pvs.Items),
+		"pvs_by_phase":  byPhase,
+		"unhealthy_pvs": unhealthy,
+	}
+	if len(unhealthy) > 0 {
+		warn(result, fmt.Sprintf("%d/%d persistent volumes are healthy", len(pvs.Items)-len(unhealthy), len(pvs.Items)),
+			"Check PV status and resolve any storage issues")
+		return
+	}
+	result.Status = StatusPassed
+	result.Message = fmt.Sprintf("All %d persistent volumes are healthy", len(pvs.Items))
+}
+
+// checkGitOpsControllers checks the pods of Argo CD, in the argocd
+// namespace, or else Flux, in flux-system, are running
+func checkGitOpsControllers(ctx context.Context, opts Options, result *Result) {
+	tools := []struct {
+		name      string
+		namespace string
+		selector  string
+	}{
+		{"Argo CD", "argocd", "app.kubernetes.io/part-of=argocd"},
+		{"Flux", "flux-system", ""},
+	}
+	for _, tool := range tools {
+		if _, err := opts.KubeClient.CoreV1().Namespaces().Get(ctx, tool.namespace, v1.GetOptions{}); err != nil {
+			continue
+		}
+		pods, err := opts.KubeClient.CoreV1().Pods(tool.namespace).List(ctx, v1.ListOptions{LabelSelector: tool.selector})
+		if err != nil {
+			fail(result, fmt.Sprintf("Failed to check %s pods: %v", tool.name, err), "")
+			return
+		}
+		running := 0
+		for _, pod := range pods.Items {
+			if pod.Status.Phase == corev1.PodRunning {
+				running++
+			}
+		}
+		result.Details = map[string]interface{}{
+			"tool":         tool.name,
+			"total_pods":   len(pods.Items),
+			"running_pods": running,
+		}
+		if running == 0 {
+			fail(result, tool.name+" pods are not running", "Check the "+tool.name+" deployment and pods status")
+			return
+		}
+		result.Status = StatusPassed
+		result.Message = fmt.Sprintf("%s is running with %d pods", tool.name, running)
+		return
+	}
+	warn(result, "No GitOps tool (Argo CD or Flux) detected", "Install and configure a GitOps tool")
+}
+
+func checkRepoLayout(ctx context.Context, opts Options, result *Result) {
+	var missing []string
+	for _, dir := range gitOpsLayout {
+		if _, err := os.Stat(filepath.Join(opts.GitOpsRepo, dir)); err != nil {
+			missing = append(missing, dir)
+		}
+	}
+	result.Details = map[string]interface{}{
+		"required_paths": gitOpsLayout,
+		"missing_paths":  missing,
+	}
+	if len(missing) > 0 {
+		warn(result, "Missing GitOps paths: "+strings.Join(missing, ", "), "Create the missing GitOps directory structure")
+		return
+	}
+	result.Status = StatusPassed
+	result.Message = "GitOps repository structure is complete"
+}
+
+// checkBackupFiles parses every YAML file under the backup directory and
+// fails when a document does not parse or lacks apiVersion, kind or name
+func checkBackupFiles(ctx context.Context, opts Options, result *Result) {
+	files, err := yamlFiles(opts.BackupDir)
+	if err != nil {
+		fail(result, fmt.Sprintf("Failed to read backup directory %s: %v", opts.BackupDir, err),
+			"Ensure the backup directory exists and holds the exported resources")
+		return
+	}
+
+	invalid := make(map[string]string)
+	resources := 0
+	for _, file := range files {
+		count, err := parseResources(file)
+		if err != nil {
+			invalid[file] = err.Error()
+			continue
+		}
+		resources += count
+	}
+	result.Details = map[string]interface{}{
+		"total_files":   len(files),
+		"resources":     resources,
+		"invalid_files": invalid,
+	}
+	switch {
+	case len(files) == 0:
+		fail(result, "No YAML files found in "+opts.BackupDir, "Ensure the backup directory holds the exported resources")
+	case len(invalid) > 0:
+		fail(result, fmt.Sprintf("%d/%d backup files are invalid", len(invalid), len(files)),
+			"Check backup integrity and export the invalid files again")
+	default:
+		result.Status = StatusPassed
+		result.Message = fmt.Sprintf("All %d backup files are valid, holding %d resources", len(files), resources)
+	}
+}
+
+func checkRepoSyntax(ctx context.Context, opts Options, result *Result) {
+	files, err := yamlFiles(opts.GitOpsRepo)
+	if err != nil {
+		fail(result, fmt.Sprintf("Failed to find YAML files: %v", err), "")
+		return
+	}
+
+	invalid := make(map[string]string)
+	for _, file := range files {
+		if err := parseYAML(file); err != nil {
+			invalid[file] = err.Error()
+		}
+	}
+	result.Details = map[string]interface{}{
+		"total_files":   len(files),
+		"invalid_files": invalid,
+	}
+	switch {
+	case len(files) == 0:
+		warn(result, "No YAML files found", "")
+	case len(invalid) > 0:
+		fail(result, fmt.Sprintf("%d/%d YAML files have invalid syntax", len(invalid), len(files)),
+			"Fix the YAML syntax errors in the listed files")
+	default:
+		result.Status = StatusPassed
+		result.Message = fmt.Sprintf("All %d YAML files have valid syntax", len(files))
+	}
+}
+
+// yamlFiles lists the .yaml and .yml files under dir, skipping hidden
+// directories such as .git
+func yamlFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && path != dir && strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if ext := filepath.Ext(path); !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// parseYAML checks every document of file parses
+func parseYAML(file string) error {
+	_, err := decodeDocuments(file)
+	return err
+}
+
+// parseResources returns the number of resources in file, failing when a
+// document is not a resource
+func parseResources(file string) (int, error) {
+	documents, err := decodeDocuments(file)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for i, document := range documents {
+		if len(document) == 0 {
+			continue
+		}
+		if items, ok := document["items"].([]interface{}); ok && strings.HasSuffix(fmt.Sprint(document["kind"]), "List") {
+			count += len(items)
+			continue
+		}
+		metadata, _ := document["metadata"].(map[string]interface{})
+		if document["apiVersion"] == nil || document["kind"] == nil || metadata["name"] == nil {
+			return 0, fmt.Errorf("document %d lacks apiVersion, kind or metadata.name", i+1)
+		}
+		count++
+	}
+	return count, nil
+}
+
+func decodeDocuments(file string) ([]map[string]interface{}, error) {
+	data, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer data.Close()
+
+	var documents []map[string]interface{}
+	decoder := yaml.NewDecoder(data)
+	for {
+		document := map[string]interface{}{}
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			return documents, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+}
+
+// checkSecurity warns about privileged containers, pods running as the
+// default service account, a cluster without network policies and
+// namespaces without an enforced pod security standard
+func checkSecurity(ctx context.Context, opts Options, result *Result) {
+	var issues []string
+	if pods, err := opts.KubeClient.CoreV1().Pods("").List(ctx, v1.ListOptions{}); err != nil {
+		issues = append(issues, "Failed to check pods")
+	} else {
+		privileged, defaultAccount := 0, 0
+		for _, pod := range pods.Items {
+			for _, container := range pod.Spec.Containers {
+				if sc := container.SecurityContext; sc != nil && sc.Privileged != nil && *sc.Privileged {
+					privileged++
+				}
+			}
+			if pod.Spec.ServiceAccountName == "" || pod.Spec.ServiceAccountName == "default" {
+				defaultAccount++
+			}
+		}
+		if privileged > 0 {
+			issues = append(issues, fmt.Sprintf("Found %d privileged containers", privileged))
+		}
+		if defaultAccount > 0 {
+			issues = append(issues, fmt.Sprintf("Found %d pods using the default service account", defaultAccount))
+		}
+	}
+
+	if policies, err := opts.KubeClient.NetworkingV1().NetworkPolicies("").List(ctx, v1.ListOptions{}); err != nil {
+		issues = append(issues, "Failed to check network policies")
+	} else if len(policies.Items) == 0 {
+		issues = append(issues, "No network policies found")
+	}
+
+	if namespaces, err := opts.KubeClient.CoreV1().Namespaces().List(ctx, v1.ListOptions{}); err != nil {
+		issues = append(issues, "Failed to check pod security standards")
+	} else {
+		unenforced := 0
+		for _, namespace := range namespaces.Items {
+			if _, ok := namespace.Labels["pod-security.kubernetes.io/enforce"]; !ok {
+				unenforced++
+			}
+		}
+		if unenforced > 0 {
+			issues = append(issues, fmt.Sprintf("Found %d namespaces without an enforced pod security standard", unenforced))
+		}
+	}
+
+	result.Details = map[string]interface{}{"security_issues": issues}
+	if len(issues) > 0 {
+		warn(result, fmt.Sprintf("Found %d security issues", len(issues)), "Address security issues: "+strings.Join(issues, "; "))
+		return
+	}
+	result.Status = StatusPassed
+	result.Message = "No security issues detected"
+}
+
+// checkPerformance warns when listing a page of pods takes longer than the
+// latency allowed
+func checkPerformance(ctx context.Context, opts Options, result *Result) {
+	limit := opts.MaxAPILatency
+	if limit <= 0 {
+		limit = DefaultMaxAPILatency
+	}
+	start := time.Now()
+	_, err := opts.KubeClient.CoreV1().Pods("").List(ctx, v1.ListOptions{Limit: 100})
+	latency := time.Since(start)
+	result.Details = map[string]interface{}{
+		"api_latency_ms":     latency.Milliseconds(),
+		"max_api_latency_ms": limit.Milliseconds(),
+	}
+	switch {
+	case err != nil:
+		warn(result, fmt.Sprintf("Failed to list pods: %v", err), "")
+	case latency > limit:
+		warn(result, fmt.Sprintf("API response time high: %s", latency.Round(time.Millisecond)),
+			"Investigate API server load and performance bottlenecks")
+	default:
+		result.Status = StatusPassed
+		result.Message = fmt.Sprintf("API responded in %s", latency.Round(time.Millisecond))
+	}
+}
+
+func fail(result *Result, message, remediation string) {
+	result.Status = StatusFailed
+	result.Message = message
+	result.Remediation = remediation
+}
+
+func warn(result *Result, message, remediation string) {
+	result.Status = StatusWarning
+	result.Message = message
+	result.Remediation = remediation
+}
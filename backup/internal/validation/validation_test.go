@@ -0,0 +1,186 @@
+package validation
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestRunChecks(t *testing.T) {
+	ctx := context.Background()
+	privileged := true
+	kubeClient := fake.NewSimpleClientset(
+		&corev1.Node{
+			ObjectMeta: v1.ObjectMeta{Name: "node-1"},
+			Status: corev1.NodeStatus{
+				Conditions:  []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+				Allocatable: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2"), corev1.ResourceMemory: resource.MustParse("4Gi")},
+			},
+		},
+		&corev1.Namespace{ObjectMeta: v1.ObjectMeta{Name: "argocd", Labels: map[string]string{"pod-security.kubernetes.io/enforce": "baseline"}}},
+		&corev1.Pod{
+			ObjectMeta: v1.ObjectMeta{Name: "argocd-server", Namespace: "argocd", Labels: map[string]string{"app.kubernetes.io/part-of": "argocd"}},
+			Spec:       corev1.PodSpec{ServiceAccountName: "argocd-server"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+		&corev1.Pod{
+			ObjectMeta: v1.ObjectMeta{Name: "agent", Namespace: "argocd"},
+			Spec: corev1.PodSpec{ServiceAccountName: "agent", Containers: []corev1.Container{{
+				Name: "agent", SecurityContext: &corev1.SecurityContext{Privileged: &privileged},
+			}}},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+		&corev1.ResourceQuota{
+			ObjectMeta: v1.ObjectMeta{Name: "compute", Namespace: "argocd"},
+			Status: corev1.ResourceQuotaStatus{
+				Hard: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")},
+				Used: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("2")},
+			},
+		},
+		&networkingv1.NetworkPolicy{ObjectMeta: v1.ObjectMeta{Name: "deny-all", Namespace: "argocd"}},
+	)
+
+	backupDir := t.TempDir()
+	writeFile(t, filepath.Join(backupDir, "argocd", "configmaps", "settings.yaml"),
+		"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n")
+	repo := t.TempDir()
+	for _, dir := range gitOpsLayout {
+		require.NoError(t, os.MkdirAll(filepath.Join(repo, dir), 0755))
+	}
+	writeFile(t, filepath.Join(repo, "base", "kustomization.yaml"), "resources:\n- settings.yaml\n")
+	writeFile(t, filepath.Join(repo, ".git", "broken.yaml"), "key: [unclosed\n")
+
+	dynamicClient := dynamicServer(t,
+		nodeMetrics("node-1", "500m", "1Gi"),
+		podMetrics("argocd", "argocd-server", "argocd-server", "10m", "64Mi"),
+		argoCDApplication("argocd", "shop", "Synced", "Healthy", "2025-09-25T16:56:34Z"),
+		fluxResource("Kustomization", "flux-system", "apps", "True", ""),
+	)
+
+	results, err := RunChecks(ctx, Options{KubeClient: kubeClient, DynamicClient: dynamicClient, BackupDir: backupDir, GitOpsRepo: repo})
+	require.NoError(t, err)
+	require.Len(t, results.Checks, len(NewRegistry().Checks()))
+	statuses := make(map[string]string)
+	for _, check := range results.Checks {
+		statuses[check.Name] = check.Status
+	}
+	assert.Equal(t, map[string]string{
+		"kubernetes_cluster_connectivity": StatusPassed,
+		"kubernetes_node_health":          StatusPassed,
+		"kubernetes_pod_health":           StatusPassed,
+		"kubernetes_resource_quotas":      StatusPassed,
+		"kubernetes_persistent_volumes":   StatusPassed,
+		"gitops_controllers":              StatusPassed,
+		"argocd_applications":             StatusPassed,
+		"flux_resources":                  StatusPassed,
+		"gitops_repository_structure":     StatusPassed,
+		"data_integrity_validation":       StatusPassed,
+		"yaml_syntax_validation":          StatusPassed,
+		"security_validation":             StatusWarning,
+		"performance_check":               StatusPassed,
+		"kubernetes_node_usage":           StatusPassed,
+		"kubernetes_pod_usage":            StatusPassed,
+	}, statuses)
+	assert.Equal(t, CheckWarnings, results.ExitCode())
+
+	metrics := NewMetrics(prometheus.NewRegistry())
+	metrics.Record(results)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.passed.WithLabelValues("kubernetes_node_health", CategoryInfrastructure, SeverityCritical)))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.passed.WithLabelValues("security_validation", CategorySecurity, SeverityHigh)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.runs.WithLabelValues("security_validation", CategorySecurity, SeverityHigh, StatusWarning)))
+}
+
+func TestRunChecksSelection(t *testing.T) {
+	ctx := context.Background()
+	backupDir := t.TempDir()
+	writeFile(t, filepath.Join(backupDir, "valid.yaml"), "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n")
+	writeFile(t, filepath.Join(backupDir, "nameless.yaml"), "apiVersion: v1\nkind: ConfigMap\nmetadata: {}\n")
+
+	// Checks of the backup directory run without a cluster
+	results, err := RunChecks(ctx, Options{Categories: []string{CategoryData}, BackupDir: backupDir})
+	require.NoError(t, err)
+	require.Len(t, results.Checks, 1)
+	assert.Equal(t, StatusFailed, results.Checks[0].Status)
+	assert.Contains(t, results.Checks[0].Details["invalid_files"], filepath.Join(backupDir, "nameless.yaml"))
+	assert.Equal(t, CheckFailed, results.ExitCode())
+
+	_, err = RunChecks(ctx, Options{Categories: []string{CategoryWorkloads}})
+	assert.Error(t, err, "cluster checks need a client")
+
+	_, err = RunChecks(ctx, Options{Categories: []string{"latency"}, KubeClient: fake.NewSimpleClientset()})
+	assert.Error(t, err, "unknown category")
+
+	// Without a repository or backup directory their checks are skipped, as
+	// are the checks of GitOps tools whose CRDs are not installed
+	results, err = RunChecks(ctx, Options{
+		Categories:    []string{CategoryGitOps, CategoryData},
+		KubeClient:    fake.NewSimpleClientset(),
+		DynamicClient: withoutCRD(dynamicServer(t), argoCDApplications, fluxKinds[0].resource, fluxKinds[1].resource),
+	})
+	require.NoError(t, err)
+	require.Len(t, results.Checks, 1)
+	assert.Equal(t, "gitops_controllers", results.Checks[0].Name)
+	assert.Equal(t, StatusWarning, results.Checks[0].Status, "no GitOps tool is installed")
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+// dynamicResources are the resources of the objects dynamicServer serves, by
+// kind
+var dynamicResources = map[string]schema.GroupVersionResource{
+	"NodeMetrics":   nodeMetricsResource,
+	"PodMetrics":    podMetricsResource,
+	"Application":   argoCDApplications,
+	"GitRepository": fluxKinds[0].resource,
+	"Kustomization": fluxKinds[1].resource,
+}
+
+// dynamicServer returns a dynamic client serving objects, metrics-server's
+// NodeMetrics and PodMetrics and the custom resources of GitOps tools
+func dynamicServer(t *testing.T, objects ...*unstructured.Unstructured) *dynamicfake.FakeDynamicClient {
+	t.Helper()
+	listKinds := make(map[schema.GroupVersionResource]string, len(dynamicResources))
+	for kind, resource := range dynamicResources {
+		listKinds[resource] = kind + "List"
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds)
+	for _, object := range objects {
+		resource, ok := dynamicResources[object.GetKind()]
+		require.True(t, ok, "no resource for kind %s", object.GetKind())
+		require.NoError(t, client.Tracker().Create(resource, object, object.GetNamespace()))
+	}
+	return client
+}
+
+// withoutCRD makes client answer lists of resources as an API server without
+// their CRDs does
+func withoutCRD(client *dynamicfake.FakeDynamicClient, resources ...schema.GroupVersionResource) *dynamicfake.FakeDynamicClient {
+	for _, resource := range resources {
+		resource := resource
+		client.PrependReactor("list", resource.Resource, func(k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrors.NewNotFound(resource.GroupResource(), "")
+		})
+	}
+	return client
+}
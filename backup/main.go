@@ -199,8 +199,12 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Create main context with timeout (30 minutes max for backup operation)
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	// Create main context bounded by BACKUP_TIMEOUT (2 hours by default)
+	backupTimeout := 2 * time.Hour
+	if timeout, err := time.ParseDuration(getSecretValue("BACKUP_TIMEOUT", "2h")); err == nil && timeout > 0 {
+		backupTimeout = timeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), backupTimeout)
 	defer cancel()
 
 	config, err := loadConfig(ctx)
@@ -2249,20 +2253,6 @@ func (cb *ClusterBackup) batchDeleteObjects(objectKeys []string) (int, []string)
 	return deletedCount, errors
 }
 
-func (cb *ClusterBackup) addBackupMetadata(obj *unstructured.Unstructured) {
-	// Add backup metadata to the object
-	annotations := obj.GetAnnotations()
-	if annotations == nil {
-		annotations = make(map[string]string)
-	}
-	
-	annotations["backup.cluster/timestamp"] = time.Now().Format(time.RFC3339)
-	annotations["backup.cluster/cluster"] = cb.config.ClusterName
-	annotations["backup.cluster/version"] = "1.0.0"
-	
-	obj.SetAnnotations(annotations)
-}
-
 func (cb *ClusterBackup) shouldCleanupOnStartup() bool {
 	return cb.backupConfig.EnableCleanup && cb.backupConfig.CleanupOnStartup
 }
@@ -27,6 +27,8 @@ type SharedConfig struct {
 	Integration   IntegrationConfig       `yaml:"integration"`
 	Timeouts      TimeoutConfig           `yaml:"timeouts"`
 	Retries       RetryConfig             `yaml:"retries"`
+	Plugins       PluginsConfig           `yaml:"plugins"`
+	Restore       RestoreConfig           `yaml:"restore"`
 }
 
 // StorageConfig defines storage backend configuration
@@ -35,12 +37,26 @@ type StorageConfig struct {
 	Endpoint         string                  `yaml:"endpoint"`
 	AccessKey        string                  `yaml:"access_key"`
 	SecretKey        string                  `yaml:"secret_key"`
+	// Files holding the keys, re-read when they change
+	AccessKeyFile    string                  `yaml:"access_key_file"`
+	SecretKeyFile    string                  `yaml:"secret_key_file"`
 	Bucket           string                  `yaml:"bucket"`
 	UseSSL           bool                    `yaml:"use_ssl"`
 	Region           string                  `yaml:"region"`
 	AutoCreateBucket bool                    `yaml:"auto_create_bucket"`
 	FallbackBuckets  []string                `yaml:"fallback_buckets"`
 	Connection       ConnectionConfig        `yaml:"connection"`
+	Auth             StorageAuthConfig       `yaml:"auth"`
+}
+
+// StorageAuthConfig selects how object storage credentials are obtained.
+// Method is static (access/secret keys), irsa, gcp-workload-identity or
+// sts-web-identity; the others fetch short-lived credentials at runtime.
+type StorageAuthConfig struct {
+	Method               string `yaml:"method"`
+	RoleARN              string `yaml:"role_arn"`
+	WebIdentityTokenFile string `yaml:"web_identity_token_file"`
+	STSEndpoint          string `yaml:"sts_endpoint"`
 }
 
 // ConnectionConfig defines connection parameters
@@ -48,6 +64,10 @@ type ConnectionConfig struct {
 	Timeout    int           `yaml:"timeout"`
 	MaxRetries int           `yaml:"max_retries"`
 	RetryDelay time.Duration `yaml:"retry_delay"`
+	// HTTP transport tuning, shared by every client of the storage endpoint
+	MaxIdleConns        int           `yaml:"max_idle_conns"`
+	IdleConnTimeout     time.Duration `yaml:"idle_conn_timeout"`
+	TLSHandshakeTimeout time.Duration `yaml:"tls_handshake_timeout"`
 }
 
 // ClusterConfig defines cluster-specific settings
@@ -204,6 +224,8 @@ type AutomationConfig struct {
 	FileTrigger              FileTriggerConfig    `yaml:"file_trigger"`
 	ProcessTrigger           ProcessTriggerConfig `yaml:"process_trigger"`
 	WebhookTrigger           WebhookTriggerConfig `yaml:"webhook_trigger"`
+	TektonTrigger            TektonTriggerConfig  `yaml:"tekton_trigger"`
+	ArgoTrigger              ArgoTriggerConfig    `yaml:"argo_trigger"`
 }
 
 // FileTriggerConfig defines file-based trigger settings
@@ -227,13 +249,49 @@ type WebhookTriggerConfig struct {
 	ServerPort     int                      `yaml:"server_port"`
 	EndpointPath   string                   `yaml:"endpoint_path"`
 	Authentication WebhookAuthConfig        `yaml:"authentication"`
+	// MaxConcurrent limits how many GitOps generations run at once
+	MaxConcurrent  int                      `yaml:"max_concurrent"`
+	// DedupWindow is how long a backup ID is remembered to drop redeliveries
+	DedupWindow    time.Duration            `yaml:"dedup_window"`
 }
 
-// WebhookAuthConfig defines webhook authentication settings
+// WebhookAuthConfig defines webhook authentication settings. A request is
+// accepted with the token in the header or with an HMAC-SHA256 signature of
+// its body in the signature header.
 type WebhookAuthConfig struct {
-	Enabled    bool   `yaml:"enabled"`
-	Token      string `yaml:"token"`
-	HeaderName string `yaml:"header_name"`
+	Enabled         bool   `yaml:"enabled"`
+	Token           string `yaml:"token"`
+	HeaderName      string `yaml:"header_name"`
+	HMACSecret      string `yaml:"hmac_secret"`
+	SignatureHeader string `yaml:"signature_header"`
+}
+
+// TektonTriggerConfig defines the Tekton PipelineRun started after a backup
+type TektonTriggerConfig struct {
+	Namespace      string                `yaml:"namespace"`
+	Pipeline       string                `yaml:"pipeline"`
+	ServiceAccount string                `yaml:"service_account"`
+	Params         PipelineTriggerParams `yaml:"params"`
+}
+
+// ArgoTriggerConfig defines the Argo Workflow submitted after a backup
+type ArgoTriggerConfig struct {
+	// ServerURL is the Argo Server, e.g. https://argo-server.argo:2746
+	ServerURL        string `yaml:"server_url"`
+	Token            string `yaml:"token"`
+	Namespace        string `yaml:"namespace"`
+	WorkflowTemplate string `yaml:"workflow_template"`
+	// ClusterScope submits a ClusterWorkflowTemplate instead
+	ClusterScope   bool                  `yaml:"cluster_scope"`
+	ServiceAccount string                `yaml:"service_account"`
+	Params         PipelineTriggerParams `yaml:"params"`
+}
+
+// PipelineTriggerParams names the parameters a triggered pipeline receives
+// the backup in; empty names default to backup-id and backup-path
+type PipelineTriggerParams struct {
+	BackupID   string `yaml:"backup_id"`
+	BackupPath string `yaml:"backup_path"`
 }
 
 // NotificationsConfig defines notification settings
@@ -296,11 +354,70 @@ type SecurityConfig struct {
 	Secrets    SecretsConfig    `yaml:"secrets"`
 	Network    NetworkConfig    `yaml:"network"`
 	Validation ValidationConfig `yaml:"validation"`
+	API        APISecurityConfig `yaml:"api"`
+	Signing    SigningConfig    `yaml:"signing"`
+	// EnableAuth requires the viewer role for the metrics and health
+	// endpoints too, not only for the API
+	EnableAuth bool             `yaml:"enable_auth"`
+}
+
+// SigningConfig defines how backup manifests are signed and which signatures
+// restores trust
+type SigningConfig struct {
+	// Mode is none, key or keyless
+	Mode              string              `yaml:"mode"`
+	Key               string              `yaml:"key"`
+	FulcioURL         string              `yaml:"fulcio_url"`
+	RekorURL          string              `yaml:"rekor_url"`
+	IdentityTokenFile string              `yaml:"identity_token_file"`
+	Verify            SigningVerifyConfig `yaml:"verify"`
+}
+
+// SigningVerifyConfig defines the trust anchors manifest signatures are
+// checked against
+type SigningVerifyConfig struct {
+	Key            string `yaml:"key"`
+	FulcioRoots    string `yaml:"fulcio_roots"`
+	Identity       string `yaml:"identity"`
+	Issuer         string `yaml:"issuer"`
+	RekorPublicKey string `yaml:"rekor_public_key"`
+}
+
+// APISecurityConfig defines access control for the backup REST API
+type APISecurityConfig struct {
+	Port     int       `yaml:"port"`
+	GRPCPort int       `yaml:"grpc_port"`
+	// Token is the static token of the admin role
+	Token         string        `yaml:"token"`
+	ViewerToken   string        `yaml:"viewer_token"`
+	OperatorToken string        `yaml:"operator_token"`
+	// ApproverToken is the static token restores awaiting approval are
+	// approved with
+	ApproverToken string        `yaml:"approver_token"`
+	OIDC          APIOIDCConfig `yaml:"oidc"`
+	TLS      TLSConfig `yaml:"tls"`
+}
+
+// APIOIDCConfig defines which OIDC ID tokens the backup APIs accept and the
+// roles they grant
+type APIOIDCConfig struct {
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+	// RolesClaim lists the caller's groups or roles, groups unless set
+	RolesClaim     string   `yaml:"roles_claim"`
+	AdminGroups    []string `yaml:"admin_groups"`
+	OperatorGroups []string `yaml:"operator_groups"`
+	ViewerGroups   []string `yaml:"viewer_groups"`
+	// ApproverGroups grant the approver role, which restores awaiting
+	// approval can be approved with
+	ApproverGroups []string `yaml:"approver_groups"`
 }
 
 // SecretsConfig defines secret management
 type SecretsConfig struct {
 	Provider      string             `yaml:"provider"`
+	// How often credential files and the provider are checked for rotation
+	RefreshInterval time.Duration    `yaml:"refresh_interval"`
 	Vault         VaultConfig        `yaml:"vault"`
 	AWSSecrets    AWSSecretsConfig   `yaml:"aws_secrets"`
 	AzureKeyVault AzureKeyVaultConf  `yaml:"azure_keyvault"`
@@ -309,7 +426,10 @@ type SecretsConfig struct {
 // VaultConfig defines HashiCorp Vault settings
 type VaultConfig struct {
 	Address string `yaml:"address"`
+	// Token authenticates directly; without one the Kubernetes auth method
+	// is used with Role
 	Token   string `yaml:"token"`
+	Role    string `yaml:"role"`
 	Path    string `yaml:"path"`
 }
 
@@ -338,6 +458,15 @@ type ValidationConfig struct {
 	StrictMode      bool   `yaml:"strict_mode"`
 	ScanForSecrets  bool   `yaml:"scan_for_secrets"`
 	MaxFileSize     string `yaml:"max_file_size"`
+	Policies        PolicyConfig `yaml:"policies"`
+}
+
+// PolicyConfig defines the Rego policies backed-up resources are checked
+// against. OPAURL is the OPA Data API document of the rule listing
+// violations; empty disables policy checks.
+type PolicyConfig struct {
+	OPAURL  string        `yaml:"opa_url"`
+	Timeout time.Duration `yaml:"timeout"`
 }
 
 // PerformanceConfig defines performance settings
@@ -496,6 +625,20 @@ type CommunicationConfig struct {
 	Endpoints     EndpointsConfig        `yaml:"endpoints"`
 	Authentication AuthenticationConfig  `yaml:"authentication"`
 	Retry         RetryConfig            `yaml:"retry"`
+	EventBus      EventBusConfig         `yaml:"event_bus"`
+}
+
+// EventBusConfig defines the broker used when the communication method is
+// event-bus. Backup lifecycle events are published to <topic_prefix>.backup.started,
+// .backup.namespace_completed, .backup.completed and .backup.failed.
+type EventBusConfig struct {
+	Provider      string `yaml:"provider"` // nats, kafka
+	URL           string `yaml:"url"`      // nats:// or tls:// server, or the Kafka REST Proxy
+	TopicPrefix   string `yaml:"topic_prefix"`
+	Token         string `yaml:"token"`
+	Username      string `yaml:"username"`
+	Password      string `yaml:"password"`
+	ConsumerGroup string `yaml:"consumer_group"`
 }
 
 // EndpointsConfig defines component endpoints
@@ -513,6 +656,12 @@ type AuthenticationConfig struct {
 	TLS     TLSConfig `yaml:"tls"`
 }
 
+// MutualTLS reports whether the components authenticate each other with
+// certificates signed by the CA of TLS
+func (a AuthenticationConfig) MutualTLS() bool {
+	return a.Enabled && a.Method == "mutual-tls"
+}
+
 // TLSConfig defines TLS settings
 type TLSConfig struct {
 	CertFile string `yaml:"cert_file"`
@@ -520,6 +669,48 @@ type TLSConfig struct {
 	CAFile   string `yaml:"ca_file"`
 }
 
+// PluginsConfig lists the Go plugins the restore engine hands every resource
+// to before applying it, and the options they are created with
+type PluginsConfig struct {
+	Paths   []string          `yaml:"paths"`
+	Options map[string]string `yaml:"options"`
+}
+
+// RestoreConfig defines settings of the restore engine
+type RestoreConfig struct {
+	Guardrails RestoreGuardrailsConfig `yaml:"guardrails"`
+	Drills     RestoreDrillConfig      `yaml:"drills"`
+}
+
+// RestoreDrillConfig schedules restore rehearsals: every interval the latest
+// backup is restored into scratch namespaces, its workloads are checked for
+// readiness, and the recovery time and point achieved are compared with the
+// objectives. Objectives left at zero are reported but not enforced.
+type RestoreDrillConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Interval time.Duration `yaml:"interval"`
+	// Namespaces of the backup each drill restores. Without a prefix, empty
+	// restores them all.
+	Namespaces []string `yaml:"namespaces"`
+	// NamespacePrefix names the scratch namespace each namespace is restored
+	// into. Empty restores into the namespaces as backed up, for a restore
+	// engine running in an ephemeral cluster.
+	NamespacePrefix string `yaml:"namespace_prefix"`
+	// KeepNamespaces leaves the scratch namespaces behind for inspection
+	KeepNamespaces bool          `yaml:"keep_namespaces"`
+	RTOObjective   time.Duration `yaml:"rto_objective"`
+	RPOObjective   time.Duration `yaml:"rpo_objective"`
+}
+
+// RestoreGuardrailsConfig lists what restores never touch unless forced with
+// a confirmation token. Namespaces may be glob patterns such as openshift-*.
+// Lists left unset protect the restore package's defaults; empty lists
+// protect nothing.
+type RestoreGuardrailsConfig struct {
+	ProtectedNamespaces []string `yaml:"protected_namespaces"`
+	ProtectedKinds      []string `yaml:"protected_kinds"`
+}
+
 // RetryConfig defines retry behavior
 type RetryConfig struct {
 	// General retry settings
@@ -548,6 +739,36 @@ type RetryConfig struct {
 	CircuitBreakerThreshold    int           `yaml:"circuit_breaker_threshold"`
 	CircuitBreakerTimeout      time.Duration `yaml:"circuit_breaker_timeout"`
 	CircuitBreakerRecoveryTime time.Duration `yaml:"circuit_breaker_recovery_time"`
+	CircuitBreakerProbes       int           `yaml:"circuit_breaker_probes"`
+	// Per-breaker overrides of the settings above, keyed by breaker name
+	// ("minio", "api")
+	CircuitBreakers map[string]CircuitBreakerConfig `yaml:"circuit_breakers"`
+}
+
+// CircuitBreakerConfig defines the settings of one circuit breaker. Zero
+// values fall back to the general RetryConfig.CircuitBreaker* settings.
+type CircuitBreakerConfig struct {
+	// Consecutive failures that open the circuit
+	Threshold int `yaml:"threshold"`
+	// How long the circuit stays open before it is probed
+	Timeout time.Duration `yaml:"timeout"`
+	// Probes that must succeed in a row to close the circuit again
+	Probes int `yaml:"probes"`
+}
+
+// CircuitBreakerFor returns the settings of the named circuit breaker
+func (rc RetryConfig) CircuitBreakerFor(name string) CircuitBreakerConfig {
+	breaker := rc.CircuitBreakers[name]
+	if breaker.Threshold <= 0 {
+		breaker.Threshold = rc.CircuitBreakerThreshold
+	}
+	if breaker.Timeout <= 0 {
+		breaker.Timeout = rc.CircuitBreakerTimeout
+	}
+	if breaker.Probes <= 0 {
+		breaker.Probes = rc.CircuitBreakerProbes
+	}
+	return breaker
 }
 
 // TriggerIntegrationConfig defines trigger integration settings
@@ -583,6 +804,13 @@ func NewConfigLoaderForTesting(paths ...string) *ConfigLoader {
 	}
 }
 
+// WithoutValidation makes Load return the configuration without validating
+// it, for callers that run the validator themselves to report its findings
+func (cl *ConfigLoader) WithoutValidation() *ConfigLoader {
+	cl.skipValidation = true
+	return cl
+}
+
 // Load loads and merges configuration from multiple sources
 func (cl *ConfigLoader) Load() (*SharedConfig, error) {
 	config := &SharedConfig{
@@ -595,16 +823,26 @@ func (cl *ConfigLoader) Load() (*SharedConfig, error) {
 				Timeout:    30,
 				MaxRetries: 3,
 				RetryDelay: 5 * time.Second,
+				MaxIdleConns:        100,
+				IdleConnTimeout:     90 * time.Second,
+				TLSHandshakeTimeout: 10 * time.Second,
+			},
+			Auth: StorageAuthConfig{
+				Method: "static",
 			},
 		},
 		Cluster: SingleClusterConfig{
 			Domain: "cluster.local",
 			Type:   "kubernetes",
+			OpenShift: OpenShiftConfig{
+				IncludeResources: true,
+			},
 		},
 		Backup: BackupConfig{
 			Behavior: BehaviorConfig{
-				BatchSize:    50,
-				ValidateYAML: true,
+				BatchSize:            50,
+				ValidateYAML:         true,
+				SkipInvalidResources: true,
 			},
 			Cleanup: CleanupConfig{
 				Enabled:       true,
@@ -653,6 +891,11 @@ func (cl *ConfigLoader) Load() (*SharedConfig, error) {
 		return nil, fmt.Errorf("failed to expand environment variables: %v", err)
 	}
 	
+	// Fetch credentials kept in the secret provider
+	if err := cl.resolveVaultSecrets(config); err != nil {
+		return nil, fmt.Errorf("failed to resolve secrets: %v", err)
+	}
+	
 	// Validate the final configuration (skip if testing)
 	if !cl.skipValidation {
 		if err := cl.validate(config); err != nil {
@@ -685,6 +928,12 @@ func (cl *ConfigLoader) applyEnvironmentOverrides(config *SharedConfig) {
 	if v := os.Getenv("MINIO_SECRET_KEY"); v != "" {
 		config.Storage.SecretKey = v
 	}
+	if v := os.Getenv("MINIO_ACCESS_KEY_FILE"); v != "" {
+		config.Storage.AccessKeyFile = v
+	}
+	if v := os.Getenv("MINIO_SECRET_KEY_FILE"); v != "" {
+		config.Storage.SecretKeyFile = v
+	}
 	if v := os.Getenv("MINIO_BUCKET"); v != "" {
 		config.Storage.Bucket = v
 	}
@@ -727,6 +976,127 @@ func (cl *ConfigLoader) applyEnvironmentOverrides(config *SharedConfig) {
 	if v := os.Getenv("LOG_LEVEL"); v != "" {
 		config.Observability.Logging.Level = v
 	}
+	
+	// REST API configuration
+	if v := os.Getenv("REST_API"); v != "" {
+		config.Features.Preview.RestAPI = v == "true"
+	}
+	if v := os.Getenv("UI_DASHBOARD"); v != "" {
+		config.Features.Preview.UIDashboard = v == "true"
+	}
+	if v := os.Getenv("METRICS_PORT"); v != "" {
+		if port, err := strconv.Atoi(v); err == nil {
+			config.Observability.Metrics.Port = port
+		}
+	}
+	if v := os.Getenv("API_PORT"); v != "" {
+		if port, err := strconv.Atoi(v); err == nil {
+			config.Security.API.Port = port
+		}
+	}
+	if v := os.Getenv("GRPC_PORT"); v != "" {
+		if port, err := strconv.Atoi(v); err == nil {
+			config.Security.API.GRPCPort = port
+		}
+	}
+	if v := os.Getenv("API_TOKEN"); v != "" {
+		config.Security.API.Token = v
+	}
+	if v := os.Getenv("API_TLS_CERT_FILE"); v != "" {
+		config.Security.API.TLS.CertFile = v
+	}
+	if v := os.Getenv("API_TLS_KEY_FILE"); v != "" {
+		config.Security.API.TLS.KeyFile = v
+	}
+	if v := os.Getenv("API_TLS_CA_FILE"); v != "" {
+		config.Security.API.TLS.CAFile = v
+	}
+	if v := os.Getenv("API_VIEWER_TOKEN"); v != "" {
+		config.Security.API.ViewerToken = v
+	}
+	if v := os.Getenv("API_OPERATOR_TOKEN"); v != "" {
+		config.Security.API.OperatorToken = v
+	}
+	if v := os.Getenv("API_APPROVER_TOKEN"); v != "" {
+		config.Security.API.ApproverToken = v
+	}
+	if v := os.Getenv("API_OIDC_ISSUER"); v != "" {
+		config.Security.API.OIDC.Issuer = v
+	}
+	if v := os.Getenv("API_OIDC_AUDIENCE"); v != "" {
+		config.Security.API.OIDC.Audience = v
+	}
+	if v := os.Getenv("API_OIDC_ROLES_CLAIM"); v != "" {
+		config.Security.API.OIDC.RolesClaim = v
+	}
+	if v := os.Getenv("API_AUTH"); v != "" {
+		config.Security.EnableAuth = v == "true"
+	}
+	
+	// Secret provider configuration
+	if v := os.Getenv("SECRET_PROVIDER"); v != "" {
+		config.Security.Secrets.Provider = v
+	}
+	if v := os.Getenv("VAULT_ADDR"); v != "" {
+		config.Security.Secrets.Vault.Address = v
+	}
+	if v := os.Getenv("VAULT_TOKEN"); v != "" {
+		config.Security.Secrets.Vault.Token = v
+	}
+	if v := os.Getenv("VAULT_ROLE"); v != "" {
+		config.Security.Secrets.Vault.Role = v
+	}
+	if v := os.Getenv("VAULT_PATH"); v != "" {
+		config.Security.Secrets.Vault.Path = v
+	}
+	
+	// Manifest signing configuration
+	if v := os.Getenv("MANIFEST_SIGNING"); v != "" {
+		config.Security.Signing.Mode = v
+	}
+	if v := os.Getenv("MANIFEST_SIGNING_KEY"); v != "" {
+		config.Security.Signing.Key = v
+	}
+	if v := os.Getenv("SIGSTORE_FULCIO_URL"); v != "" {
+		config.Security.Signing.FulcioURL = v
+	}
+	if v := os.Getenv("SIGSTORE_REKOR_URL"); v != "" {
+		config.Security.Signing.RekorURL = v
+	}
+	if v := os.Getenv("SIGSTORE_IDENTITY_TOKEN_FILE"); v != "" {
+		config.Security.Signing.IdentityTokenFile = v
+	}
+	if v := os.Getenv("MANIFEST_VERIFY_KEY"); v != "" {
+		config.Security.Signing.Verify.Key = v
+	}
+	if v := os.Getenv("MANIFEST_VERIFY_ROOTS"); v != "" {
+		config.Security.Signing.Verify.FulcioRoots = v
+	}
+	if v := os.Getenv("MANIFEST_VERIFY_IDENTITY"); v != "" {
+		config.Security.Signing.Verify.Identity = v
+	}
+	if v := os.Getenv("MANIFEST_VERIFY_ISSUER"); v != "" {
+		config.Security.Signing.Verify.Issuer = v
+	}
+	if v := os.Getenv("SIGSTORE_REKOR_PUBLIC_KEY"); v != "" {
+		config.Security.Signing.Verify.RekorPublicKey = v
+	}
+	
+	// Backup content validation
+	if v := os.Getenv("STRICT_VALIDATION"); v != "" {
+		config.Security.Validation.StrictMode = v == "true"
+	}
+	if v := os.Getenv("SCAN_SECRETS"); v != "" {
+		config.Security.Validation.ScanForSecrets = v == "true"
+	}
+	if v := os.Getenv("POLICY_OPA_URL"); v != "" {
+		config.Security.Validation.Policies.OPAURL = v
+	}
+	if v := os.Getenv("POLICY_TIMEOUT"); v != "" {
+		if timeout, err := time.ParseDuration(v); err == nil {
+			config.Security.Validation.Policies.Timeout = timeout
+		}
+	}
 }
 
 // expandEnvironmentVariables expands ${VAR} references in string fields
@@ -745,6 +1115,31 @@ func (cl *ConfigLoader) expandEnvironmentVariables(config *SharedConfig) error {
 	
 	config.GitOps.Repository.URL = os.ExpandEnv(config.GitOps.Repository.URL)
 	
+	config.Pipeline.Automation.WebhookTrigger.Authentication.Token = os.ExpandEnv(config.Pipeline.Automation.WebhookTrigger.Authentication.Token)
+	config.Pipeline.Automation.WebhookTrigger.Authentication.HMACSecret = os.ExpandEnv(config.Pipeline.Automation.WebhookTrigger.Authentication.HMACSecret)
+	config.Pipeline.Automation.ArgoTrigger.ServerURL = os.ExpandEnv(config.Pipeline.Automation.ArgoTrigger.ServerURL)
+	config.Pipeline.Automation.ArgoTrigger.Token = os.ExpandEnv(config.Pipeline.Automation.ArgoTrigger.Token)
+	config.Integration.Communication.EventBus.URL = os.ExpandEnv(config.Integration.Communication.EventBus.URL)
+	config.Integration.Communication.EventBus.Token = os.ExpandEnv(config.Integration.Communication.EventBus.Token)
+	config.Integration.Communication.EventBus.Username = os.ExpandEnv(config.Integration.Communication.EventBus.Username)
+	config.Integration.Communication.EventBus.Password = os.ExpandEnv(config.Integration.Communication.EventBus.Password)
+	config.Integration.Communication.Authentication.Method = os.ExpandEnv(config.Integration.Communication.Authentication.Method)
+	config.Integration.Communication.Authentication.TLS.CertFile = os.ExpandEnv(config.Integration.Communication.Authentication.TLS.CertFile)
+	config.Integration.Communication.Authentication.TLS.KeyFile = os.ExpandEnv(config.Integration.Communication.Authentication.TLS.KeyFile)
+	config.Integration.Communication.Authentication.TLS.CAFile = os.ExpandEnv(config.Integration.Communication.Authentication.TLS.CAFile)
+	
+	config.Security.API.Token = os.ExpandEnv(config.Security.API.Token)
+	config.Security.API.ViewerToken = os.ExpandEnv(config.Security.API.ViewerToken)
+	config.Security.API.OperatorToken = os.ExpandEnv(config.Security.API.OperatorToken)
+	config.Security.API.ApproverToken = os.ExpandEnv(config.Security.API.ApproverToken)
+	config.Security.Secrets.Vault.Address = os.ExpandEnv(config.Security.Secrets.Vault.Address)
+	config.Security.Secrets.Vault.Token = os.ExpandEnv(config.Security.Secrets.Vault.Token)
+	config.Security.Secrets.Vault.Role = os.ExpandEnv(config.Security.Secrets.Vault.Role)
+	config.Security.Secrets.Vault.Path = os.ExpandEnv(config.Security.Secrets.Vault.Path)
+	config.Security.Signing.Key = os.ExpandEnv(config.Security.Signing.Key)
+	config.Security.Signing.IdentityTokenFile = os.ExpandEnv(config.Security.Signing.IdentityTokenFile)
+	config.Security.Signing.Verify.Identity = os.ExpandEnv(config.Security.Signing.Verify.Identity)
+	
 	// Expand multi-cluster configuration
 	for i := range config.MultiCluster.Clusters {
 		cluster := &config.MultiCluster.Clusters[i]
@@ -816,20 +1211,68 @@ func (cl *ConfigLoader) validate(config *SharedConfig) error {
 
 // GetBackupToolConfig converts shared config to backup tool specific config
 func (sc *SharedConfig) GetBackupToolConfig() map[string]interface{} {
+	minioBreaker := sc.Retries.CircuitBreakerFor("minio")
+	apiBreaker := sc.Retries.CircuitBreakerFor("api")
 	return map[string]interface{}{
 		"ClusterName":       sc.Cluster.Name,
 		"ClusterDomain":     sc.Cluster.Domain,
 		"MinIOEndpoint":     sc.Storage.Endpoint,
 		"MinIOAccessKey":    sc.Storage.AccessKey,
 		"MinIOSecretKey":    sc.Storage.SecretKey,
+		"MinIOAccessKeyFile": sc.Storage.AccessKeyFile,
+		"MinIOSecretKeyFile": sc.Storage.SecretKeyFile,
+		"CredentialsRefreshInterval": sc.Security.Secrets.RefreshInterval,
 		"MinIOBucket":       sc.Storage.Bucket,
 		"MinIOUseSSL":       sc.Storage.UseSSL,
+		"MinIOMaxIdleConns":        sc.Storage.Connection.MaxIdleConns,
+		"MinIOIdleConnTimeout":     sc.Storage.Connection.IdleConnTimeout,
+		"MinIOTLSHandshakeTimeout": sc.Storage.Connection.TLSHandshakeTimeout,
+		"MinIOCABundle":            sc.Security.Network.CABundle,
+		"MinIOClientCert":          sc.Security.Network.ClientCert,
+		"MinIOClientKey":           sc.Security.Network.ClientKey,
+		"StorageAuthMethod":           sc.Storage.Auth.Method,
+		"StorageRoleARN":              sc.Storage.Auth.RoleARN,
+		"StorageWebIdentityTokenFile": sc.Storage.Auth.WebIdentityTokenFile,
+		"StorageSTSEndpoint":          sc.Storage.Auth.STSEndpoint,
+		"SecretProvider":    sc.Security.Secrets.Provider,
+		"VaultAddress":      sc.Security.Secrets.Vault.Address,
+		"VaultToken":        sc.Security.Secrets.Vault.Token,
+		"VaultRole":         sc.Security.Secrets.Vault.Role,
+		"VaultPath":         sc.Security.Secrets.Vault.Path,
+		"ManifestSigning":           sc.Security.Signing.Mode,
+		"ManifestSigningKey":        sc.Security.Signing.Key,
+		"SigstoreFulcioURL":         sc.Security.Signing.FulcioURL,
+		"SigstoreRekorURL":          sc.Security.Signing.RekorURL,
+		"SigstoreIdentityTokenFile": sc.Security.Signing.IdentityTokenFile,
+		"ManifestVerifyKey":         sc.Security.Signing.Verify.Key,
+		"ManifestVerifyRoots":       sc.Security.Signing.Verify.FulcioRoots,
+		"ManifestVerifyIdentity":    sc.Security.Signing.Verify.Identity,
+		"ManifestVerifyIssuer":      sc.Security.Signing.Verify.Issuer,
+		"SigstoreRekorPublicKey":    sc.Security.Signing.Verify.RekorPublicKey,
 		"BatchSize":         sc.Backup.Behavior.BatchSize,
 		"RetryAttempts":     sc.Storage.Connection.MaxRetries,
+		"MinIOCircuitBreakerThreshold": minioBreaker.Threshold,
+		"MinIOCircuitBreakerTimeout":   minioBreaker.Timeout,
+		"MinIOCircuitBreakerProbes":    minioBreaker.Probes,
+		"APICircuitBreakerThreshold":   apiBreaker.Threshold,
+		"APICircuitBreakerTimeout":     apiBreaker.Timeout,
+		"APICircuitBreakerProbes":      apiBreaker.Probes,
+		"BackupTimeout":          sc.Timeouts.BackupTimeout,
+		"BackupDiscoveryTimeout": sc.Timeouts.BackupDiscoveryTimeout,
+		"BackupNamespaceTimeout": sc.Timeouts.BackupNamespaceTimeout,
+		"BackupCleanupTimeout":   sc.Timeouts.BackupCleanupTimeout,
 		"RetentionDays":     sc.Backup.Cleanup.RetentionDays,
 		"EnableCleanup":     sc.Backup.Cleanup.Enabled,
 		"CleanupOnStartup":  sc.Backup.Cleanup.CleanupOnStartup,
 		"AutoCreateBucket":  sc.Storage.AutoCreateBucket,
+		"RestAPIEnabled":    sc.Features.Preview.RestAPI,
+		"UIDashboardEnabled": sc.Features.Preview.UIDashboard,
+		"MetricsPort":       sc.Observability.Metrics.Port,
+		"APIPort":           sc.Security.API.Port,
+		"GRPCPort":          sc.Security.API.GRPCPort,
+		"APIToken":          sc.Security.API.Token,
+		"APITLSCertFile":    sc.Security.API.TLS.CertFile,
+		"APITLSKeyFile":     sc.Security.API.TLS.KeyFile,
 	}
 }
 
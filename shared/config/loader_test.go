@@ -1,9 +1,13 @@
 package sharedconfig
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestConfigLoader_Load(t *testing.T) {
@@ -156,6 +160,76 @@ backup:
 	}
 }
 
+func TestConfigLoader_VaultSecrets(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/auth/token/lookup-self":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"ttl": 0}})
+		case "/v1/kv/data/backup":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": map[string]string{
+				"minio_secret_key":   "vault-secret",
+				"git_pat_token":      "vault-pat",
+				"webhook_auth_token": "vault-webhook",
+			}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer vault.Close()
+
+	os.Setenv("SECRET_PROVIDER", "vault")
+	os.Setenv("VAULT_ADDR", vault.URL)
+	os.Setenv("VAULT_TOKEN", "vault-token")
+	os.Setenv("VAULT_PATH", "kv/backup")
+	defer func() {
+		os.Unsetenv("SECRET_PROVIDER")
+		os.Unsetenv("VAULT_ADDR")
+		os.Unsetenv("VAULT_TOKEN")
+		os.Unsetenv("VAULT_PATH")
+	}()
+
+	configPath := filepath.Join(t.TempDir(), "test-config.yaml")
+	configContent := `
+storage:
+  endpoint: "localhost:9000"
+  access_key: "file-key"
+  secret_key: "file-secret"
+  bucket: "test-bucket"
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	config, err := NewConfigLoaderForTesting(configPath).Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Keys missing from the secret keep the value from the file
+	if config.Storage.AccessKey != "file-key" {
+		t.Errorf("Expected access key from file, got '%s'", config.Storage.AccessKey)
+	}
+	if config.Storage.SecretKey != "vault-secret" {
+		t.Errorf("Expected secret key from Vault, got '%s'", config.Storage.SecretKey)
+	}
+	if config.GitOps.Repository.Auth.PAT.Token != "vault-pat" {
+		t.Errorf("Expected Git token from Vault, got '%s'", config.GitOps.Repository.Auth.PAT.Token)
+	}
+	if config.Pipeline.Automation.WebhookTrigger.Authentication.Token != "vault-webhook" {
+		t.Errorf("Expected webhook token from Vault, got '%s'", config.Pipeline.Automation.WebhookTrigger.Authentication.Token)
+	}
+
+	// An unreachable secret fails the load rather than leaving credentials empty
+	os.Setenv("VAULT_TOKEN", "wrong-token")
+	if _, err := NewConfigLoaderForTesting(configPath).Load(); err == nil {
+		t.Error("Expected error when Vault rejects the token")
+	}
+}
+
 func TestConfigLoader_Validation(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -269,11 +343,22 @@ func TestSharedConfig_GetBackupToolConfig(t *testing.T) {
 			SecretKey: "testsecret",
 			Bucket:    "test-bucket",
 			UseSSL:    false,
+			Connection: ConnectionConfig{
+				MaxIdleConns:    32,
+				IdleConnTimeout: 2 * time.Minute,
+			},
 		},
 		Cluster: SingleClusterConfig{
 			Name:   "test-cluster",
 			Domain: "cluster.local",
 		},
+		Security: SecurityConfig{
+			Network: NetworkConfig{
+				CABundle:   "/etc/ssl/minio-ca.pem",
+				ClientCert: "/etc/ssl/client.crt",
+				ClientKey:  "/etc/ssl/client.key",
+			},
+		},
 		Backup: BackupConfig{
 			Behavior: BehaviorConfig{
 				BatchSize: 25,
@@ -303,6 +388,90 @@ func TestSharedConfig_GetBackupToolConfig(t *testing.T) {
 	if backupConfig["RetentionDays"] != 14 {
 		t.Errorf("Expected RetentionDays 14, got %v", backupConfig["RetentionDays"])
 	}
+
+	if backupConfig["MinIOMaxIdleConns"] != 32 {
+		t.Errorf("Expected MinIOMaxIdleConns 32, got %v", backupConfig["MinIOMaxIdleConns"])
+	}
+
+	if backupConfig["MinIOIdleConnTimeout"] != 2*time.Minute {
+		t.Errorf("Expected MinIOIdleConnTimeout 2m, got %v", backupConfig["MinIOIdleConnTimeout"])
+	}
+
+	if backupConfig["MinIOCABundle"] != "/etc/ssl/minio-ca.pem" {
+		t.Errorf("Expected MinIOCABundle '/etc/ssl/minio-ca.pem', got '%v'", backupConfig["MinIOCABundle"])
+	}
+
+	if backupConfig["CredentialsRefreshInterval"] != time.Duration(0) {
+		t.Errorf("Expected CredentialsRefreshInterval to be unset, got '%v'", backupConfig["CredentialsRefreshInterval"])
+	}
+
+	if backupConfig["StorageAuthMethod"] != "" {
+		t.Errorf("Expected StorageAuthMethod to be unset, got '%v'", backupConfig["StorageAuthMethod"])
+	}
+
+	if backupConfig["MinIOClientCert"] != "/etc/ssl/client.crt" || backupConfig["MinIOClientKey"] != "/etc/ssl/client.key" {
+		t.Errorf("Expected client certificate paths, got '%v' and '%v'", backupConfig["MinIOClientCert"], backupConfig["MinIOClientKey"])
+	}
+}
+
+func TestRetryConfig_CircuitBreakerFor(t *testing.T) {
+	os.Setenv("CIRCUIT_BREAKER_PROBES", "2")
+	os.Setenv("MINIO_CIRCUIT_BREAKER_TIMEOUT", "2m")
+	os.Setenv("API_CIRCUIT_BREAKER_THRESHOLD", "10")
+	defer func() {
+		os.Unsetenv("CIRCUIT_BREAKER_PROBES")
+		os.Unsetenv("MINIO_CIRCUIT_BREAKER_TIMEOUT")
+		os.Unsetenv("API_CIRCUIT_BREAKER_THRESHOLD")
+	}()
+
+	retries := LoadRetryConfigFromEnv()
+
+	minio := retries.CircuitBreakerFor("minio")
+	if minio.Threshold != 3 || minio.Timeout != 2*time.Minute || minio.Probes != 2 {
+		t.Errorf("Expected minio breaker {3 2m0s 2}, got %+v", minio)
+	}
+	api := retries.CircuitBreakerFor("api")
+	if api.Threshold != 10 || api.Timeout != 30*time.Second || api.Probes != 2 {
+		t.Errorf("Expected api breaker {10 30s 2}, got %+v", api)
+	}
+	// Breakers without overrides use the general settings
+	other := retries.CircuitBreakerFor("git")
+	if other.Threshold != 5 || other.Timeout != time.Minute || other.Probes != 2 {
+		t.Errorf("Expected git breaker {5 1m0s 2}, got %+v", other)
+	}
+
+	backupConfig := (&SharedConfig{Retries: retries}).GetBackupToolConfig()
+	if backupConfig["MinIOCircuitBreakerTimeout"] != 2*time.Minute {
+		t.Errorf("Expected MinIOCircuitBreakerTimeout 2m, got %v", backupConfig["MinIOCircuitBreakerTimeout"])
+	}
+	if backupConfig["APICircuitBreakerThreshold"] != 10 {
+		t.Errorf("Expected APICircuitBreakerThreshold 10, got %v", backupConfig["APICircuitBreakerThreshold"])
+	}
+}
+
+func TestLoadTimeoutConfigFromEnv_BackupTimeouts(t *testing.T) {
+	os.Setenv("BACKUP_TIMEOUT", "6h")
+	os.Setenv("BACKUP_NAMESPACE_TIMEOUT", "invalid")
+	defer func() {
+		os.Unsetenv("BACKUP_TIMEOUT")
+		os.Unsetenv("BACKUP_NAMESPACE_TIMEOUT")
+	}()
+
+	timeouts := LoadTimeoutConfigFromEnv()
+	if timeouts.BackupTimeout != 6*time.Hour {
+		t.Errorf("Expected BackupTimeout 6h, got %v", timeouts.BackupTimeout)
+	}
+	if timeouts.BackupNamespaceTimeout != time.Hour {
+		t.Errorf("Expected invalid BackupNamespaceTimeout to keep the 1h default, got %v", timeouts.BackupNamespaceTimeout)
+	}
+	if timeouts.BackupDiscoveryTimeout != 5*time.Minute || timeouts.BackupCleanupTimeout != 15*time.Minute {
+		t.Errorf("Expected default discovery 5m and cleanup 15m timeouts, got %v and %v", timeouts.BackupDiscoveryTimeout, timeouts.BackupCleanupTimeout)
+	}
+
+	backupConfig := (&SharedConfig{Timeouts: timeouts}).GetBackupToolConfig()
+	if backupConfig["BackupTimeout"] != 6*time.Hour {
+		t.Errorf("Expected BackupTimeout 6h, got %v", backupConfig["BackupTimeout"])
+	}
 }
 
 func TestConfigLoader_SaveToFile(t *testing.T) {
@@ -32,6 +32,12 @@ type TimeoutConfig struct {
 	BackupClientTimeout     time.Duration `yaml:"backup_client_timeout"`
 	BackupPollingInterval   time.Duration `yaml:"backup_polling_interval"`
 
+	// Backup run timeouts: the whole run, and each of its phases within it
+	BackupTimeout           time.Duration `yaml:"backup_timeout"`
+	BackupDiscoveryTimeout  time.Duration `yaml:"backup_discovery_timeout"`
+	BackupNamespaceTimeout  time.Duration `yaml:"backup_namespace_timeout"`
+	BackupCleanupTimeout    time.Duration `yaml:"backup_cleanup_timeout"`
+
 	// GitOps operation timeouts
 	GitOpsCloneTimeout      time.Duration `yaml:"gitops_clone_timeout"`
 	GitOpsSyncTimeout       time.Duration `yaml:"gitops_sync_timeout"`
@@ -70,6 +76,12 @@ func DefaultTimeoutConfig() TimeoutConfig {
 		BackupClientTimeout:   30 * time.Second,
 		BackupPollingInterval: 5 * time.Second,
 
+		// Backup run defaults
+		BackupTimeout:          2 * time.Hour,
+		BackupDiscoveryTimeout: 5 * time.Minute,
+		BackupNamespaceTimeout: time.Hour,
+		BackupCleanupTimeout:   15 * time.Minute,
+
 		// GitOps operation defaults
 		GitOpsCloneTimeout:  10 * time.Minute,
 		GitOpsSyncTimeout:   15 * time.Minute,
@@ -110,6 +122,11 @@ func DefaultRetryConfig() RetryConfig {
 		CircuitBreakerThreshold:    5,
 		CircuitBreakerTimeout:      60 * time.Second,
 		CircuitBreakerRecoveryTime: 300 * time.Second,
+		CircuitBreakerProbes:       3,
+		CircuitBreakers: map[string]CircuitBreakerConfig{
+			"minio": {Threshold: 3},
+			"api":   {Timeout: 30 * time.Second},
+		},
 	}
 }
 
@@ -169,6 +186,20 @@ func LoadTimeoutConfigFromEnv() TimeoutConfig {
 		config.BackupPollingInterval = val
 	}
 
+	// Backup run timeouts
+	if val := getEnvDuration("BACKUP_TIMEOUT"); val > 0 {
+		config.BackupTimeout = val
+	}
+	if val := getEnvDuration("BACKUP_DISCOVERY_TIMEOUT"); val > 0 {
+		config.BackupDiscoveryTimeout = val
+	}
+	if val := getEnvDuration("BACKUP_NAMESPACE_TIMEOUT"); val > 0 {
+		config.BackupNamespaceTimeout = val
+	}
+	if val := getEnvDuration("BACKUP_CLEANUP_TIMEOUT"); val > 0 {
+		config.BackupCleanupTimeout = val
+	}
+
 	// GitOps operation timeouts
 	if val := getEnvDuration("GITOPS_CLONE_TIMEOUT"); val > 0 {
 		config.GitOpsCloneTimeout = val
@@ -263,6 +294,22 @@ func LoadRetryConfigFromEnv() RetryConfig {
 	if val := getEnvDuration("CIRCUIT_BREAKER_RECOVERY_TIME"); val > 0 {
 		config.CircuitBreakerRecoveryTime = val
 	}
+	if val := getEnvInt("CIRCUIT_BREAKER_PROBES"); val > 0 {
+		config.CircuitBreakerProbes = val
+	}
+	for name, prefix := range map[string]string{"minio": "MINIO", "api": "API"} {
+		breaker := config.CircuitBreakers[name]
+		if val := getEnvInt(prefix + "_CIRCUIT_BREAKER_THRESHOLD"); val > 0 {
+			breaker.Threshold = val
+		}
+		if val := getEnvDuration(prefix + "_CIRCUIT_BREAKER_TIMEOUT"); val > 0 {
+			breaker.Timeout = val
+		}
+		if val := getEnvInt(prefix + "_CIRCUIT_BREAKER_PROBES"); val > 0 {
+			breaker.Probes = val
+		}
+		config.CircuitBreakers[name] = breaker
+	}
 
 	return config
 }
@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"regexp"
 	"strings"
+	"time"
 )
 
 // ValidationError represents a configuration validation error
@@ -66,6 +67,9 @@ func (cv *ConfigValidator) Validate() *ValidationResult {
 	// Performance validation
 	cv.validatePerformance()
 	
+	// Restore validation
+	cv.validateRestore()
+	
 	// Cross-field validation
 	cv.validateCrossFieldRules()
 	
@@ -100,12 +104,19 @@ func (cv *ConfigValidator) validateStorage() {
 		cv.addError("storage.endpoint", s.Endpoint, "Invalid endpoint format (expected host:port or URL)")
 	}
 	
-	// Validate credentials
-	if s.AccessKey == "" {
-		cv.addError("storage.access_key", "", "Access key is required")
-	}
-	if s.SecretKey == "" {
-		cv.addError("storage.secret_key", "", "Secret key is required")
+	// Validate credentials; only static auth uses keys
+	validAuthMethods := []string{"", "static", "irsa", "gcp-workload-identity", "sts-web-identity"}
+	if !contains(validAuthMethods, s.Auth.Method) {
+		cv.addError("storage.auth.method", s.Auth.Method, "Auth method must be 'static', 'irsa', 'gcp-workload-identity', or 'sts-web-identity'")
+	} else if s.Auth.Method == "" || s.Auth.Method == "static" {
+		if s.AccessKey == "" && s.AccessKeyFile == "" {
+			cv.addError("storage.access_key", "", "Access key is required")
+		}
+		if s.SecretKey == "" && s.SecretKeyFile == "" {
+			cv.addError("storage.secret_key", "", "Secret key is required")
+		}
+	} else if s.AccessKey != "" || s.SecretKey != "" {
+		cv.addWarning("storage.access_key", "", fmt.Sprintf("Access and secret keys are ignored with auth method '%s'", s.Auth.Method))
 	}
 	
 	// Validate bucket name
@@ -130,6 +141,15 @@ func (cv *ConfigValidator) validateStorage() {
 	if s.Connection.RetryDelay < 0 {
 		cv.addError("storage.connection.retry_delay", s.Connection.RetryDelay, "Retry delay cannot be negative")
 	}
+	if s.Connection.MaxIdleConns < 0 {
+		cv.addError("storage.connection.max_idle_conns", s.Connection.MaxIdleConns, "Max idle connections cannot be negative")
+	}
+	if s.Connection.IdleConnTimeout < 0 {
+		cv.addError("storage.connection.idle_conn_timeout", s.Connection.IdleConnTimeout, "Idle connection timeout cannot be negative")
+	}
+	if s.Connection.TLSHandshakeTimeout < 0 {
+		cv.addError("storage.connection.tls_handshake_timeout", s.Connection.TLSHandshakeTimeout, "TLS handshake timeout cannot be negative")
+	}
 	
 	// Validate fallback buckets
 	for i, bucket := range s.FallbackBuckets {
@@ -296,18 +316,51 @@ func (cv *ConfigValidator) validatePipeline() {
 		}
 		
 		// Validate trigger methods
-		validTriggerMethods := []string{"file", "process", "webhook", "script"}
+		validTriggerMethods := []string{"file", "process", "webhook", "script", "tekton", "argo"}
 		for i, method := range p.Automation.TriggerMethods {
 			if !contains(validTriggerMethods, method) {
 				cv.addError(fmt.Sprintf("pipeline.automation.trigger_methods[%d]", i), method, "Invalid trigger method")
 			}
 		}
 		
+		// Validate the pipelines to start when their trigger method is used
+		if contains(p.Automation.TriggerMethods, "tekton") {
+			if p.Automation.TektonTrigger.Namespace == "" {
+				cv.addError("pipeline.automation.tekton_trigger.namespace", "", "Tekton namespace is required when the tekton trigger method is used")
+			}
+			if p.Automation.TektonTrigger.Pipeline == "" {
+				cv.addError("pipeline.automation.tekton_trigger.pipeline", "", "Tekton pipeline is required when the tekton trigger method is used")
+			}
+		}
+		if contains(p.Automation.TriggerMethods, "argo") {
+			if p.Automation.ArgoTrigger.ServerURL == "" {
+				cv.addError("pipeline.automation.argo_trigger.server_url", "", "Argo Server URL is required when the argo trigger method is used")
+			} else if u, err := url.Parse(p.Automation.ArgoTrigger.ServerURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				cv.addError("pipeline.automation.argo_trigger.server_url", p.Automation.ArgoTrigger.ServerURL, "Argo Server URL must be an http or https URL")
+			}
+			if p.Automation.ArgoTrigger.Namespace == "" {
+				cv.addError("pipeline.automation.argo_trigger.namespace", "", "Argo namespace is required when the argo trigger method is used")
+			}
+			if p.Automation.ArgoTrigger.WorkflowTemplate == "" {
+				cv.addError("pipeline.automation.argo_trigger.workflow_template", "", "Argo workflow template is required when the argo trigger method is used")
+			}
+		}
+		
 		// Validate webhook settings if webhook trigger is enabled
 		if p.Automation.WebhookTrigger.Enabled {
 			if p.Automation.WebhookTrigger.ServerPort <= 0 || p.Automation.WebhookTrigger.ServerPort > 65535 {
 				cv.addError("pipeline.automation.webhook_trigger.server_port", p.Automation.WebhookTrigger.ServerPort, "Invalid webhook server port")
 			}
+			auth := p.Automation.WebhookTrigger.Authentication
+			if auth.Enabled && auth.Token == "" && auth.HMACSecret == "" {
+				cv.addError("pipeline.automation.webhook_trigger.authentication", "", "Webhook authentication requires a token or an HMAC secret")
+			}
+			if p.Automation.WebhookTrigger.MaxConcurrent < 0 {
+				cv.addError("pipeline.automation.webhook_trigger.max_concurrent", p.Automation.WebhookTrigger.MaxConcurrent, "Max concurrent generations cannot be negative")
+			}
+			if p.Automation.WebhookTrigger.DedupWindow < 0 {
+				cv.addError("pipeline.automation.webhook_trigger.dedup_window", p.Automation.WebhookTrigger.DedupWindow, "Dedup window cannot be negative")
+			}
 		}
 	}
 	
@@ -336,8 +389,8 @@ func (cv *ConfigValidator) validateSecurity() {
 		if s.Secrets.Vault.Address == "" {
 			cv.addError("security.secrets.vault.address", "", "Vault address is required for Vault provider")
 		}
-		if s.Secrets.Vault.Token == "" {
-			cv.addError("security.secrets.vault.token", "", "Vault token is required for Vault provider")
+		if s.Secrets.Vault.Token == "" && s.Secrets.Vault.Role == "" {
+			cv.addError("security.secrets.vault.token", "", "Vault token or Kubernetes auth role is required for Vault provider")
 		}
 	case "aws-secrets":
 		if s.Secrets.AWSSecrets.Region == "" {
@@ -355,10 +408,58 @@ func (cv *ConfigValidator) validateSecurity() {
 		}
 	}
 	
+	// Validate manifest signing
+	switch s.Signing.Mode {
+	case "", "none":
+	case "key":
+		if s.Signing.Key == "" {
+			cv.addError("security.signing.key", "", "Signing key is required for key signing")
+		}
+	case "keyless":
+		if s.Signing.FulcioURL == "" {
+			cv.addError("security.signing.fulcio_url", "", "Fulcio URL is required for keyless signing")
+		}
+		if s.Signing.RekorURL == "" {
+			cv.addWarning("security.signing.rekor_url", "", "Keyless signatures are not logged to Rekor and cannot be checked after their certificate expires")
+		}
+	default:
+		cv.addError("security.signing.mode", s.Signing.Mode, "Signing mode must be none, key or keyless")
+	}
+	// Any Fulcio certificate chains to the roots, so the signer must be pinned
+	if s.Signing.Verify.FulcioRoots != "" && s.Signing.Verify.Identity == "" {
+		cv.addError("security.signing.verify.identity", "", "Signer identity is required to verify keyless signatures")
+	}
+	
+	// Components authenticating each other with certificates need their own
+	// and the CA the others' must chain to
+	if auth := cv.config.Integration.Communication.Authentication; auth.Enabled {
+		switch auth.Method {
+		case "token", "none":
+		case "mutual-tls":
+			if auth.TLS.CertFile == "" || auth.TLS.KeyFile == "" {
+				cv.addError("integration.communication.authentication.tls.cert_file", "", "Certificate and key files are required for mutual TLS")
+			}
+			if auth.TLS.CAFile == "" {
+				cv.addError("integration.communication.authentication.tls.ca_file", "", "CA file is required for mutual TLS")
+			}
+		default:
+			cv.addError("integration.communication.authentication.method", auth.Method, "Authentication method must be token, mutual-tls or none")
+		}
+	}
+	
 	// Validate max file size
 	if s.Validation.MaxFileSize != "" && !isValidSize(s.Validation.MaxFileSize) {
 		cv.addError("security.validation.max_file_size", s.Validation.MaxFileSize, "Invalid size format")
 	}
+	
+	if policies := s.Validation.Policies; policies.OPAURL != "" {
+		if u, err := url.Parse(policies.OPAURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			cv.addError("security.validation.policies.opa_url", policies.OPAURL, "OPA URL must be an http or https URL")
+		}
+		if policies.Timeout != 0 && (policies.Timeout < time.Second || policies.Timeout > time.Minute) {
+			cv.addError("security.validation.policies.timeout", policies.Timeout, "Policy timeout must be between 1s and 1m")
+		}
+	}
 }
 
 // validatePerformance validates performance configuration
@@ -386,6 +487,33 @@ func (cv *ConfigValidator) validatePerformance() {
 	}
 }
 
+// validateRestore validates restore configuration
+func (cv *ConfigValidator) validateRestore() {
+	d := &cv.config.Restore.Drills
+	if !d.Enabled {
+		return
+	}
+	
+	if d.Interval < 0 {
+		cv.addError("restore.drills.interval", d.Interval, "Drill interval cannot be negative")
+	} else if d.Interval > 0 && d.Interval < time.Hour {
+		cv.addWarning("restore.drills.interval", d.Interval, "Drills more often than hourly restore the backup over and over")
+	}
+	if d.RTOObjective < 0 {
+		cv.addError("restore.drills.rto_objective", d.RTOObjective, "RTO objective cannot be negative")
+	}
+	if d.RPOObjective < 0 {
+		cv.addError("restore.drills.rpo_objective", d.RPOObjective, "RPO objective cannot be negative")
+	}
+	if d.NamespacePrefix == "" {
+		cv.addWarning("restore.drills.namespace_prefix", d.NamespacePrefix, "Drills restore into the backed-up namespaces; run them only in an ephemeral cluster")
+	} else if !isValidDNSName(d.NamespacePrefix + "x") {
+		cv.addError("restore.drills.namespace_prefix", d.NamespacePrefix, "Namespace prefix must start a valid namespace name")
+	} else if len(d.Namespaces) == 0 {
+		cv.addError("restore.drills.namespaces", d.Namespaces, "Drills into scratch namespaces need the namespaces to restore")
+	}
+}
+
 // validateCrossFieldRules validates cross-field dependencies and rules
 func (cv *ConfigValidator) validateCrossFieldRules() {
 	// Rule: If OpenShift cluster type, OpenShift settings should be configured
@@ -125,6 +125,58 @@ func TestConfigValidator_ValidateStorage(t *testing.T) {
 			expectError: true,
 			errorCount:  2, // region + endpoint format
 		},
+		{
+			name: "IRSA without keys",
+			storage: StorageConfig{
+				Type:     "s3",
+				Endpoint: "s3.us-west-2.amazonaws.com:443",
+				Bucket:   "my-bucket",
+				UseSSL:   true,
+				Region:   "us-west-2",
+				Connection: ConnectionConfig{
+					Timeout:    30,
+					MaxRetries: 3,
+				},
+				Auth: StorageAuthConfig{
+					Method:  "irsa",
+					RoleARN: "arn:aws:iam::123456789012:role/cluster-backup",
+				},
+			},
+			expectError: false,
+			errorCount:  0,
+		},
+		{
+			name: "Keys from mounted files",
+			storage: StorageConfig{
+				Type:          "minio",
+				Endpoint:      "localhost:9000",
+				AccessKeyFile: "/etc/minio/accesskey",
+				SecretKeyFile: "/etc/minio/secretkey",
+				Bucket:        "test-bucket",
+				Connection: ConnectionConfig{
+					Timeout:    30,
+					MaxRetries: 3,
+				},
+			},
+			expectError: false,
+			errorCount:  0,
+		},
+		{
+			name: "Unknown auth method",
+			storage: StorageConfig{
+				Type:     "minio",
+				Endpoint: "localhost:9000",
+				Bucket:   "test-bucket",
+				Connection: ConnectionConfig{
+					Timeout: 30,
+				},
+				Auth: StorageAuthConfig{
+					Method: "kerberos",
+				},
+			},
+			expectError: true,
+			errorCount:  1,
+		},
 	}
 
 	for _, tt := range tests {
@@ -145,6 +197,203 @@ func TestConfigValidator_ValidateStorage(t *testing.T) {
 	}
 }
 
+func TestConfigValidator_ValidateSigning(t *testing.T) {
+	tests := []struct {
+		name       string
+		signing    SigningConfig
+		errorCount int
+	}{
+		{"Unsigned", SigningConfig{Mode: "none"}, 0},
+		{"Key without key file", SigningConfig{Mode: "key"}, 1},
+		{"Keyless", SigningConfig{Mode: "keyless", FulcioURL: "https://fulcio.sigstore.dev", RekorURL: "https://rekor.sigstore.dev"}, 0},
+		{"Unknown mode", SigningConfig{Mode: "gpg"}, 1},
+		{
+			name: "Fulcio roots without identity",
+			signing: SigningConfig{
+				Mode:   "none",
+				Verify: SigningVerifyConfig{FulcioRoots: "/etc/sigstore/fulcio.pem"},
+			},
+			errorCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &SharedConfig{Security: SecurityConfig{
+				Secrets: SecretsConfig{Provider: "env"},
+				Signing: tt.signing,
+			}}
+			validator := NewConfigValidator(config)
+			validator.validateSecurity()
+
+			if len(validator.result.Errors) != tt.errorCount {
+				t.Errorf("Expected %d errors, got %d: %v", tt.errorCount, len(validator.result.Errors), validator.result.Errors)
+			}
+		})
+	}
+}
+
+func TestConfigValidator_ValidatePolicies(t *testing.T) {
+	tests := []struct {
+		name       string
+		policies   PolicyConfig
+		errorCount int
+	}{
+		{"Disabled", PolicyConfig{}, 0},
+		{"OPA service", PolicyConfig{OPAURL: "http://opa:8181/v1/data/backup/violation", Timeout: 5 * time.Second}, 0},
+		{"URL without scheme", PolicyConfig{OPAURL: "opa:8181/v1/data/backup/violation"}, 1},
+		{"Timeout too long", PolicyConfig{OPAURL: "https://opa.example.com/v1/data/backup/deny", Timeout: time.Hour}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &SharedConfig{Security: SecurityConfig{
+				Secrets:    SecretsConfig{Provider: "env"},
+				Signing:    SigningConfig{Mode: "none"},
+				Validation: ValidationConfig{Policies: tt.policies},
+			}}
+			validator := NewConfigValidator(config)
+			validator.validateSecurity()
+
+			if len(validator.result.Errors) != tt.errorCount {
+				t.Errorf("Expected %d errors, got %d: %v", tt.errorCount, len(validator.result.Errors), validator.result.Errors)
+			}
+		})
+	}
+}
+
+func TestConfigValidator_ValidateCommunicationAuthentication(t *testing.T) {
+	files := TLSConfig{CertFile: "/etc/tls/tls.crt", KeyFile: "/etc/tls/tls.key", CAFile: "/etc/tls/ca.crt"}
+	tests := []struct {
+		name           string
+		authentication AuthenticationConfig
+		errorCount     int
+	}{
+		{"Disabled", AuthenticationConfig{Method: "mutual-tls"}, 0},
+		{"Mutual TLS", AuthenticationConfig{Enabled: true, Method: "mutual-tls", TLS: files}, 0},
+		{"Mutual TLS without CA", AuthenticationConfig{Enabled: true, Method: "mutual-tls", TLS: TLSConfig{CertFile: files.CertFile, KeyFile: files.KeyFile}}, 1},
+		{"Mutual TLS without certificate", AuthenticationConfig{Enabled: true, Method: "mutual-tls", TLS: TLSConfig{CAFile: files.CAFile}}, 1},
+		{"Unknown method", AuthenticationConfig{Enabled: true, Method: "kerberos"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &SharedConfig{
+				Security: SecurityConfig{
+					Secrets: SecretsConfig{Provider: "env"},
+					Signing: SigningConfig{Mode: "none"},
+				},
+				Integration: IntegrationConfig{Communication: CommunicationConfig{Authentication: tt.authentication}},
+			}
+			validator := NewConfigValidator(config)
+			validator.validateSecurity()
+
+			if len(validator.result.Errors) != tt.errorCount {
+				t.Errorf("Expected %d errors, got %d: %v", tt.errorCount, len(validator.result.Errors), validator.result.Errors)
+			}
+		})
+	}
+}
+
+func TestConfigValidator_ValidatePipelineTriggers(t *testing.T) {
+	tests := []struct {
+		name       string
+		automation AutomationConfig
+		errorCount int
+	}{
+		{"File only", AutomationConfig{TriggerMethods: []string{"file"}}, 0},
+		{
+			name: "Tekton",
+			automation: AutomationConfig{
+				TriggerMethods: []string{"tekton"},
+				TektonTrigger:  TektonTriggerConfig{Namespace: "ci", Pipeline: "restore-test"},
+			},
+			errorCount: 0,
+		},
+		{"Tekton without pipeline", AutomationConfig{TriggerMethods: []string{"tekton"}, TektonTrigger: TektonTriggerConfig{Namespace: "ci"}}, 1},
+		{
+			name: "Argo",
+			automation: AutomationConfig{
+				TriggerMethods: []string{"argo", "file"},
+				ArgoTrigger:    ArgoTriggerConfig{ServerURL: "https://argo-server.argo:2746", Namespace: "argo", WorkflowTemplate: "restore-test"},
+			},
+			errorCount: 0,
+		},
+		{
+			name: "Argo URL without scheme",
+			automation: AutomationConfig{
+				TriggerMethods: []string{"argo"},
+				ArgoTrigger:    ArgoTriggerConfig{ServerURL: "argo-server.argo:2746", Namespace: "argo", WorkflowTemplate: "restore-test"},
+			},
+			errorCount: 1,
+		},
+		{"Argo unconfigured", AutomationConfig{TriggerMethods: []string{"argo"}}, 3},
+		{"Unknown method", AutomationConfig{TriggerMethods: []string{"jenkins"}}, 1},
+		{
+			name: "Webhook with HMAC secret",
+			automation: AutomationConfig{
+				TriggerMethods: []string{"webhook"},
+				WebhookTrigger: WebhookTriggerConfig{Enabled: true, ServerPort: 9090, Authentication: WebhookAuthConfig{Enabled: true, HMACSecret: "s3cret"}},
+			},
+			errorCount: 0,
+		},
+		{
+			name: "Webhook authentication without credentials",
+			automation: AutomationConfig{
+				TriggerMethods: []string{"webhook"},
+				WebhookTrigger: WebhookTriggerConfig{Enabled: true, ServerPort: 9090, MaxConcurrent: -1, Authentication: WebhookAuthConfig{Enabled: true}},
+			},
+			errorCount: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.automation.Enabled = true
+			tt.automation.MaxWaitTime = 300
+			config := &SharedConfig{Pipeline: PipelineConfig{Mode: "sequential", Automation: tt.automation}}
+			validator := NewConfigValidator(config)
+			validator.validatePipeline()
+
+			if len(validator.result.Errors) != tt.errorCount {
+				t.Errorf("Expected %d errors, got %d: %v", tt.errorCount, len(validator.result.Errors), validator.result.Errors)
+			}
+		})
+	}
+}
+
+func TestConfigValidator_ValidateRestoreDrills(t *testing.T) {
+	tests := []struct {
+		name         string
+		drills       RestoreDrillConfig
+		errorCount   int
+		warningCount int
+	}{
+		{"Disabled", RestoreDrillConfig{Interval: -time.Hour}, 0, 0},
+		{"Weekly into scratch namespaces", RestoreDrillConfig{Enabled: true, Interval: 168 * time.Hour, Namespaces: []string{"shop"}, NamespacePrefix: "drill-", RTOObjective: time.Hour}, 0, 0},
+		{"In place", RestoreDrillConfig{Enabled: true, Interval: 24 * time.Hour}, 0, 1},
+		{"Too frequent", RestoreDrillConfig{Enabled: true, Interval: time.Minute, Namespaces: []string{"shop"}, NamespacePrefix: "drill-"}, 0, 1},
+		{"Invalid prefix", RestoreDrillConfig{Enabled: true, Namespaces: []string{"shop"}, NamespacePrefix: "Drill_"}, 1, 0},
+		{"Prefix without namespaces", RestoreDrillConfig{Enabled: true, NamespacePrefix: "drill-"}, 1, 0},
+		{"Negative objectives", RestoreDrillConfig{Enabled: true, Namespaces: []string{"shop"}, NamespacePrefix: "drill-", RTOObjective: -time.Hour, RPOObjective: -time.Hour}, 2, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &SharedConfig{Restore: RestoreConfig{Drills: tt.drills}}
+			validator := NewConfigValidator(config)
+			validator.validateRestore()
+
+			if len(validator.result.Errors) != tt.errorCount {
+				t.Errorf("Expected %d errors, got %d: %v", tt.errorCount, len(validator.result.Errors), validator.result.Errors)
+			}
+			if len(validator.result.Warnings) != tt.warningCount {
+				t.Errorf("Expected %d warnings, got %d: %v", tt.warningCount, len(validator.result.Warnings), validator.result.Warnings)
+			}
+		})
+	}
+}
+
 func TestConfigValidator_ValidateCluster(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -0,0 +1,66 @@
+package sharedconfig
+
+import (
+	"fmt"
+
+	"shared-config/vault"
+)
+
+// defaultVaultPath is read when security.secrets.vault.path is not set
+const defaultVaultPath = "secret/backup"
+
+// Keys of the Vault secret that hold credentials. Each is the lowercase name of
+// the environment variable it replaces.
+const (
+	VaultKeyMinIOAccessKey    = "minio_access_key"
+	VaultKeyMinIOSecretKey    = "minio_secret_key"
+	VaultKeyGitPATToken       = "git_pat_token"
+	VaultKeyGitPassword       = "git_password"
+	VaultKeyWebhookAuthToken  = "webhook_auth_token"
+	VaultKeyWebhookHMACSecret = "webhook_hmac_secret"
+	VaultKeyArgoToken         = "argo_token"
+)
+
+// resolveVaultSecrets fills the storage, Git, webhook and Argo credentials from the
+// configured Vault secret when Vault is the secret provider. Keys the secret
+// holds take precedence over the file and environment; the rest are left as
+// they are.
+func (cl *ConfigLoader) resolveVaultSecrets(config *SharedConfig) error {
+	secrets := &config.Security.Secrets
+	if secrets.Provider != "vault" {
+		return nil
+	}
+
+	client, err := vault.NewClient(vault.Config{
+		Address: secrets.Vault.Address,
+		Token:   secrets.Vault.Token,
+		Role:    secrets.Vault.Role,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to vault: %v", err)
+	}
+	path := secrets.Vault.Path
+	if path == "" {
+		path = defaultVaultPath
+	}
+	secret, err := client.Read(path)
+	if err != nil {
+		return err
+	}
+
+	targets := map[string]*string{
+		VaultKeyMinIOAccessKey:    &config.Storage.AccessKey,
+		VaultKeyMinIOSecretKey:    &config.Storage.SecretKey,
+		VaultKeyGitPATToken:       &config.GitOps.Repository.Auth.PAT.Token,
+		VaultKeyGitPassword:       &config.GitOps.Repository.Auth.Basic.Password,
+		VaultKeyWebhookAuthToken:  &config.Pipeline.Automation.WebhookTrigger.Authentication.Token,
+		VaultKeyWebhookHMACSecret: &config.Pipeline.Automation.WebhookTrigger.Authentication.HMACSecret,
+		VaultKeyArgoToken:         &config.Pipeline.Automation.ArgoTrigger.Token,
+	}
+	for key, target := range targets {
+		if value := secret[key]; value != "" {
+			*target = value
+		}
+	}
+	return nil
+}
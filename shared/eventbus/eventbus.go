@@ -0,0 +1,189 @@
+// Package eventbus carries backup lifecycle events between the backup tool,
+// the integration bridge and the restore and GitOps components over NATS or
+// Kafka. NATS is spoken natively; Kafka is reached through its REST Proxy
+// (API v2), so neither needs a client library.
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Providers of the event bus
+const (
+	ProviderNATS  = "nats"
+	ProviderKafka = "kafka"
+)
+
+// Backup lifecycle event types. Each is published to the topic of the same
+// name, behind the configured prefix.
+const (
+	BackupStarted            = "backup.started"
+	BackupNamespaceCompleted = "backup.namespace_completed"
+	BackupCompleted          = "backup.completed"
+	BackupFailed             = "backup.failed"
+)
+
+// LifecycleEvents lists every backup lifecycle event type
+var LifecycleEvents = []string{BackupStarted, BackupNamespaceCompleted, BackupCompleted, BackupFailed}
+
+// Event is a backup lifecycle event
+type Event struct {
+	// ID is unique per event, so consumers can drop redeliveries
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	BackupID  string    `json:"backup_id"`
+	Cluster   string    `json:"cluster"`
+	Bucket    string    `json:"bucket,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	// Namespace is set on namespace_completed events
+	Namespace string `json:"namespace,omitempty"`
+	// ResourceCount is the resources backed up in the namespace or the run
+	ResourceCount int `json:"resource_count,omitempty"`
+	// NamespaceCount is the namespaces backed up by a finished run
+	NamespaceCount int      `json:"namespace_count,omitempty"`
+	Errors         []string `json:"errors,omitempty"`
+}
+
+// NewEvent returns an event of the given type for a backup run
+func NewEvent(eventType, backupID, cluster string) Event {
+	return Event{
+		ID:        eventID(eventType, backupID, ""),
+		Type:      eventType,
+		BackupID:  backupID,
+		Cluster:   cluster,
+		Timestamp: time.Now().UTC(),
+	}
+}
+
+// NewNamespaceEvent returns the namespace_completed event of a namespace
+func NewNamespaceEvent(backupID, cluster, namespace string, resourceCount int, err error) Event {
+	event := NewEvent(BackupNamespaceCompleted, backupID, cluster)
+	event.ID = eventID(BackupNamespaceCompleted, backupID, namespace)
+	event.Namespace = namespace
+	event.ResourceCount = resourceCount
+	if err != nil {
+		event.Errors = []string{err.Error()}
+	}
+	return event
+}
+
+func eventID(eventType, backupID, namespace string) string {
+	id := backupID + "/" + strings.TrimPrefix(eventType, "backup.")
+	if namespace != "" {
+		id += "/" + namespace
+	}
+	return id
+}
+
+// Config holds the settings for connecting to the event bus
+type Config struct {
+	// Provider is nats or kafka
+	Provider string
+	// URL is the NATS server (nats:// or tls://) or the Kafka REST Proxy
+	// (http:// or https://)
+	URL string
+	// TopicPrefix is prepended to every topic, as in <prefix>.backup.completed
+	TopicPrefix string
+	// Credentials: a token, or a username and password
+	Token    string
+	Username string
+	Password string
+	// Group shares the events of a topic among the subscribers in it: the NATS
+	// queue group or the Kafka consumer group
+	Group string
+	// ClientName identifies the connection to the broker
+	ClientName string
+	HTTPClient *http.Client
+}
+
+// Handler receives the events of a subscription. Events of one subscription
+// are delivered one at a time.
+type Handler func(ctx context.Context, event Event)
+
+// broker moves raw messages between topics and subscribers
+type broker interface {
+	publish(ctx context.Context, topic, key string, data []byte) error
+	// subscribe delivers the topic's messages until ctx is done
+	subscribe(ctx context.Context, topic, group string, handler func(data []byte)) error
+	close() error
+}
+
+// Bus publishes and subscribes to backup lifecycle events
+type Bus struct {
+	broker      broker
+	topicPrefix string
+	group       string
+}
+
+// New connects to the configured event bus
+func New(config Config) (*Bus, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("event bus URL is required")
+	}
+	if config.ClientName == "" {
+		config.ClientName = "cluster-backup"
+	}
+
+	var b broker
+	var err error
+	switch config.Provider {
+	case ProviderNATS:
+		b, err = dialNATS(config)
+	case ProviderKafka:
+		b, err = newKafkaProxy(config)
+	default:
+		return nil, fmt.Errorf("unsupported event bus provider %q, must be nats or kafka", config.Provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Bus{broker: b, topicPrefix: config.TopicPrefix, group: config.Group}, nil
+}
+
+// Topic returns the topic events of the given type are published to
+func (b *Bus) Topic(eventType string) string {
+	if b.topicPrefix == "" {
+		return eventType
+	}
+	return strings.TrimSuffix(b.topicPrefix, ".") + "." + eventType
+}
+
+// Publish sends an event to the topic of its type
+func (b *Bus) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s event: %v", event.Type, err)
+	}
+	if err := b.broker.publish(ctx, b.Topic(event.Type), event.BackupID, data); err != nil {
+		return fmt.Errorf("failed to publish %s event: %v", event.Type, err)
+	}
+	return nil
+}
+
+// Subscribe delivers the events of a type to the handler until ctx is done.
+// It returns once the subscription is established; messages that are not
+// events are dropped.
+func (b *Bus) Subscribe(ctx context.Context, eventType string, handler Handler) error {
+	topic := b.Topic(eventType)
+	err := b.broker.subscribe(ctx, topic, b.group, func(data []byte) {
+		var event Event
+		if err := json.Unmarshal(data, &event); err != nil || event.Type == "" {
+			return
+		}
+		handler(ctx, event)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %v", topic, err)
+	}
+	return nil
+}
+
+// Close disconnects from the event bus
+func (b *Bus) Close() error {
+	return b.broker.close()
+}
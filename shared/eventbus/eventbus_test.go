@@ -0,0 +1,372 @@
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNATS serves the subset of the NATS protocol the client uses
+type fakeNATS struct {
+	listener net.Listener
+	token    string
+	mu       sync.Mutex
+	conns    map[net.Conn]*fakeNATSConn
+}
+
+type fakeNATSConn struct {
+	mu   sync.Mutex
+	conn net.Conn
+	subs map[string]string // sid -> subject
+}
+
+func newFakeNATS(t *testing.T, token string) *fakeNATS {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	f := &fakeNATS{listener: listener, token: token, conns: make(map[net.Conn]*fakeNATSConn)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	t.Cleanup(func() {
+		listener.Close()
+		f.dropConnections()
+	})
+	return f
+}
+
+func (f *fakeNATS) url() string {
+	return "nats://" + f.listener.Addr().String()
+}
+
+func (f *fakeNATS) serve(conn net.Conn) {
+	c := &fakeNATSConn{conn: conn, subs: make(map[string]string)}
+	f.mu.Lock()
+	f.conns[conn] = c
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		delete(f.conns, conn)
+		f.mu.Unlock()
+		conn.Close()
+	}()
+
+	c.write(`INFO {"server_id":"fake","auth_required":true}` + "\r\n")
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "CONNECT":
+			var options map[string]interface{}
+			json.Unmarshal([]byte(strings.TrimPrefix(strings.TrimSpace(line), "CONNECT ")), &options)
+			if options["auth_token"] != f.token {
+				c.write("-ERR 'Authorization Violation'\r\n")
+				return
+			}
+		case "PING":
+			c.write("PONG\r\n")
+		case "SUB":
+			c.mu.Lock()
+			c.subs[fields[len(fields)-1]] = fields[1]
+			c.mu.Unlock()
+		case "UNSUB":
+			c.mu.Lock()
+			delete(c.subs, fields[1])
+			c.mu.Unlock()
+		case "PUB":
+			size, _ := strconv.Atoi(fields[len(fields)-1])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			f.deliver(fields[1], payload[:size])
+		}
+	}
+}
+
+func (f *fakeNATS) deliver(subject string, payload []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.conns {
+		c.mu.Lock()
+		for sid, subscribed := range c.subs {
+			if subscribed == subject {
+				c.conn.Write([]byte(fmt.Sprintf("MSG %s %s %d\r\n%s\r\n", subject, sid, len(payload), payload)))
+			}
+		}
+		c.mu.Unlock()
+	}
+}
+
+func (c *fakeNATSConn) write(s string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.Write([]byte(s))
+}
+
+// dropConnections closes every client connection, as a server restart would
+func (f *fakeNATS) dropConnections() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for conn := range f.conns {
+		conn.Close()
+	}
+}
+
+func receive(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for event")
+		return Event{}
+	}
+}
+
+func TestNATS(t *testing.T) {
+	t.Run("PublishSubscribe", func(t *testing.T) {
+		server := newFakeNATS(t, "s3cret")
+		config := Config{Provider: ProviderNATS, URL: server.url(), Token: "s3cret", TopicPrefix: "prod"}
+
+		subscriber, err := New(config)
+		if err != nil {
+			t.Fatalf("Failed to connect subscriber: %v", err)
+		}
+		defer subscriber.Close()
+		publisher, err := New(config)
+		if err != nil {
+			t.Fatalf("Failed to connect publisher: %v", err)
+		}
+		defer publisher.Close()
+
+		if topic := publisher.Topic(BackupCompleted); topic != "prod.backup.completed" {
+			t.Errorf("Expected topic prod.backup.completed, got %s", topic)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		events := make(chan Event, 4)
+		if err := subscriber.Subscribe(ctx, BackupCompleted, func(ctx context.Context, event Event) {
+			events <- event
+		}); err != nil {
+			t.Fatalf("Failed to subscribe: %v", err)
+		}
+
+		if err := publisher.Publish(ctx, NewNamespaceEvent("prod-1", "prod", "default", 12, nil)); err != nil {
+			t.Fatalf("Failed to publish namespace event: %v", err)
+		}
+		completed := NewEvent(BackupCompleted, "prod-1", "prod")
+		completed.Bucket = "cluster-backups"
+		completed.ResourceCount = 12
+		if err := publisher.Publish(ctx, completed); err != nil {
+			t.Fatalf("Failed to publish: %v", err)
+		}
+
+		event := receive(t, events)
+		if event.Type != BackupCompleted || event.ID != "prod-1/completed" || event.Bucket != "cluster-backups" || event.ResourceCount != 12 {
+			t.Errorf("Unexpected event: %+v", event)
+		}
+		select {
+		case event := <-events:
+			t.Errorf("Received event of another topic: %+v", event)
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("RejectsInvalidToken", func(t *testing.T) {
+		server := newFakeNATS(t, "s3cret")
+		if _, err := New(Config{Provider: ProviderNATS, URL: server.url(), Token: "wrong"}); err == nil {
+			t.Error("Expected connecting with an invalid token to fail")
+		}
+	})
+
+	t.Run("ResubscribesAfterReconnect", func(t *testing.T) {
+		server := newFakeNATS(t, "s3cret")
+		config := Config{Provider: ProviderNATS, URL: server.url(), Token: "s3cret"}
+		subscriber, err := New(config)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer subscriber.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		events := make(chan Event, 4)
+		subscriber.Subscribe(ctx, BackupFailed, func(ctx context.Context, event Event) {
+			events <- event
+		})
+
+		server.dropConnections()
+		deadline := time.Now().Add(5 * time.Second)
+		for subscriber.Publish(ctx, NewEvent(BackupFailed, "prod-2", "prod")) != nil {
+			if time.Now().After(deadline) {
+				t.Fatalf("Client did not reconnect")
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		if event := receive(t, events); event.BackupID != "prod-2" {
+			t.Errorf("Unexpected event: %+v", event)
+		}
+	})
+}
+
+// fakeKafkaProxy serves the subset of the Kafka REST Proxy API v2 the client
+// uses. Consumers read the records published after they subscribed.
+type fakeKafkaProxy struct {
+	mu        sync.Mutex
+	server    *httptest.Server
+	records   map[string][]json.RawMessage
+	consumers map[string]*fakeKafkaConsumer
+	deleted   int
+}
+
+type fakeKafkaConsumer struct {
+	topic  string
+	offset int
+}
+
+func newFakeKafkaProxy(t *testing.T) *fakeKafkaProxy {
+	f := &fakeKafkaProxy{records: make(map[string][]json.RawMessage), consumers: make(map[string]*fakeKafkaConsumer)}
+	f.server = httptest.NewServer(f)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeKafkaProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if user, pass, ok := r.BasicAuth(); !ok || user != "backup" || pass != "s3cret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error_code":40101,"message":"Unauthorized"}`))
+		return
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[0] == "topics":
+		if r.Header.Get("Content-Type") != kafkaJSONContentType {
+			http.Error(w, `{"error_code":415,"message":"Unsupported Media Type"}`, http.StatusUnsupportedMediaType)
+			return
+		}
+		var request struct {
+			Records []struct {
+				Key   string          `json:"key"`
+				Value json.RawMessage `json:"value"`
+			} `json:"records"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		offsets := []map[string]interface{}{}
+		for _, record := range request.Records {
+			f.records[parts[1]] = append(f.records[parts[1]], record.Value)
+			offsets = append(offsets, map[string]interface{}{"partition": 0, "offset": len(f.records[parts[1]]) - 1})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"offsets": offsets})
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[0] == "consumers":
+		var request map[string]string
+		json.NewDecoder(r.Body).Decode(&request)
+		f.consumers[request["name"]] = &fakeKafkaConsumer{}
+		json.NewEncoder(w).Encode(map[string]string{
+			"instance_id": request["name"],
+			"base_uri":    f.server.URL + "/consumers/" + parts[1] + "/instances/" + request["name"],
+		})
+	case len(parts) >= 4 && parts[2] == "instances":
+		consumer, ok := f.consumers[parts[3]]
+		if !ok {
+			http.Error(w, `{"error_code":40403,"message":"Consumer instance not found."}`, http.StatusNotFound)
+			return
+		}
+		switch {
+		case r.Method == http.MethodDelete && len(parts) == 4:
+			delete(f.consumers, parts[3])
+			f.deleted++
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost && len(parts) == 5 && parts[4] == "subscription":
+			var request map[string][]string
+			json.NewDecoder(r.Body).Decode(&request)
+			consumer.topic = request["topics"][0]
+			consumer.offset = len(f.records[consumer.topic])
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodGet && len(parts) == 5 && parts[4] == "records":
+			records := []map[string]interface{}{}
+			for _, value := range f.records[consumer.topic][consumer.offset:] {
+				records = append(records, map[string]interface{}{"topic": consumer.topic, "value": value})
+			}
+			consumer.offset = len(f.records[consumer.topic])
+			json.NewEncoder(w).Encode(records)
+		default:
+			http.NotFound(w, r)
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestKafkaProxy(t *testing.T) {
+	proxy := newFakeKafkaProxy(t)
+	config := Config{Provider: ProviderKafka, URL: proxy.server.URL, Username: "backup", Password: "s3cret", Group: "gitops"}
+	bus, err := New(config)
+	if err != nil {
+		t.Fatalf("Failed to create bus: %v", err)
+	}
+	defer bus.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan Event, 4)
+	if err := bus.Subscribe(ctx, BackupStarted, func(ctx context.Context, event Event) {
+		events <- event
+	}); err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	if err := bus.Publish(ctx, NewEvent(BackupStarted, "prod-1", "prod")); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	if event := receive(t, events); event.ID != "prod-1/started" || event.Cluster != "prod" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		proxy.mu.Lock()
+		deleted := proxy.deleted
+		proxy.mu.Unlock()
+		if deleted == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Consumer was not deleted after the subscription ended")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	config.Password = "wrong"
+	unauthorized, _ := New(config)
+	if err := unauthorized.Publish(context.Background(), NewEvent(BackupStarted, "prod-2", "prod")); err == nil || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Errorf("Expected unauthorized error, got %v", err)
+	}
+}
@@ -0,0 +1,246 @@
+package eventbus
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Media types of the Kafka REST Proxy API v2 with JSON embedded data
+const (
+	kafkaJSONContentType = "application/vnd.kafka.json.v2+json"
+	kafkaContentType     = "application/vnd.kafka.v2+json"
+	kafkaPollTimeout     = time.Second
+	kafkaMaxBackoff      = 30 * time.Second
+)
+
+// errConsumerGone reports that the proxy no longer knows a consumer
+// instance, which it drops after it has been idle too long
+var errConsumerGone = fmt.Errorf("kafka consumer instance not found")
+
+// kafkaProxy publishes and consumes through the Kafka REST Proxy
+type kafkaProxy struct {
+	config Config
+	url    string
+	http   *http.Client
+	// closed ends every subscription when the proxy client is closed
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// kafkaConsumer is a consumer instance created on the proxy
+type kafkaConsumer struct {
+	InstanceID string `json:"instance_id"`
+	BaseURI    string `json:"base_uri"`
+}
+
+func newKafkaProxy(config Config) (*kafkaProxy, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("kafka REST proxy URL must use http:// or https://")
+	}
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &kafkaProxy{
+		config: config,
+		url:    strings.TrimRight(config.URL, "/"),
+		http:   httpClient,
+		closed: make(chan struct{}),
+	}, nil
+}
+
+func (k *kafkaProxy) publish(ctx context.Context, topic, key string, data []byte) error {
+	payload := map[string]interface{}{
+		"records": []map[string]interface{}{{"key": key, "value": json.RawMessage(data)}},
+	}
+	var response struct {
+		Offsets []struct {
+			Partition int    `json:"partition"`
+			Offset    int64  `json:"offset"`
+			ErrorCode int    `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+	if err := k.do(ctx, http.MethodPost, k.url+"/topics/"+url.PathEscape(topic), kafkaJSONContentType, payload, &response); err != nil {
+		return err
+	}
+	for _, offset := range response.Offsets {
+		if offset.Error != "" || offset.ErrorCode != 0 {
+			return fmt.Errorf("kafka rejected the record: %s (code %d)", offset.Error, offset.ErrorCode)
+		}
+	}
+	return nil
+}
+
+func (k *kafkaProxy) subscribe(ctx context.Context, topic, group string, handler func(data []byte)) error {
+	if group == "" {
+		group = k.config.ClientName
+	}
+	consumer, err := k.createConsumer(ctx, topic, group)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-k.closed:
+			stop()
+		case <-ctx.Done():
+		}
+	}()
+	go func() {
+		defer stop()
+		defer func() {
+			// The consumer leaves the group so its partitions are reassigned
+			// right away rather than after the session timeout
+			cleanup, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			k.do(cleanup, http.MethodDelete, consumer.BaseURI, kafkaContentType, nil, nil)
+		}()
+
+		delay := time.Second
+		for ctx.Err() == nil {
+			records, err := k.poll(ctx, consumer)
+			if err == nil {
+				delay = time.Second
+				for _, record := range records {
+					handler(record)
+				}
+				continue
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			if delay *= 2; delay > kafkaMaxBackoff {
+				delay = kafkaMaxBackoff
+			}
+			if err == errConsumerGone {
+				if recreated, err := k.createConsumer(ctx, topic, group); err == nil {
+					consumer = recreated
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (k *kafkaProxy) close() error {
+	k.closeOnce.Do(func() { close(k.closed) })
+	return nil
+}
+
+// createConsumer creates a consumer instance in the group and subscribes it
+// to the topic. A new group starts at the latest offset, so enabling the
+// event bus does not replay the topic's history.
+func (k *kafkaProxy) createConsumer(ctx context.Context, topic, group string) (*kafkaConsumer, error) {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	var consumer kafkaConsumer
+	err := k.do(ctx, http.MethodPost, k.url+"/consumers/"+url.PathEscape(group), kafkaContentType, map[string]string{
+		"name":               k.config.ClientName + "-" + hex.EncodeToString(suffix),
+		"format":             "json",
+		"auto.offset.reset":  "latest",
+		"auto.commit.enable": "true",
+	}, &consumer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka consumer: %v", err)
+	}
+	if consumer.BaseURI == "" {
+		return nil, fmt.Errorf("kafka REST proxy returned no consumer base_uri")
+	}
+	err = k.do(ctx, http.MethodPost, consumer.BaseURI+"/subscription", kafkaContentType, map[string][]string{"topics": {topic}}, nil)
+	if err != nil {
+		k.do(ctx, http.MethodDelete, consumer.BaseURI, kafkaContentType, nil, nil)
+		return nil, fmt.Errorf("failed to subscribe kafka consumer: %v", err)
+	}
+	return &consumer, nil
+}
+
+// poll fetches the records that arrived since the last poll
+func (k *kafkaProxy) poll(ctx context.Context, consumer *kafkaConsumer) ([][]byte, error) {
+	var records []struct {
+		Value json.RawMessage `json:"value"`
+	}
+	endpoint := fmt.Sprintf("%s/records?timeout=%d", consumer.BaseURI, kafkaPollTimeout.Milliseconds())
+	if err := k.do(ctx, http.MethodGet, endpoint, kafkaJSONContentType, nil, &records); err != nil {
+		return nil, err
+	}
+	values := make([][]byte, 0, len(records))
+	for _, record := range records {
+		values = append(values, record.Value)
+	}
+	return values, nil
+}
+
+// do sends a request to the proxy and decodes the response into out, which
+// may be nil. contentType is sent as the request body type, or as the
+// accepted response type of a request without body.
+func (k *kafkaProxy) do(ctx context.Context, method, endpoint, contentType string, payload, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Accept", kafkaContentType)
+	} else {
+		req.Header.Set("Accept", contentType)
+	}
+	if k.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+k.config.Token)
+	} else if k.config.Username != "" {
+		req.SetBasicAuth(k.config.Username, k.config.Password)
+	}
+
+	resp, err := k.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound && strings.Contains(endpoint, "/instances/") {
+		return errConsumerGone
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var failure struct {
+			ErrorCode int    `json:"error_code"`
+			Message   string `json:"message"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&failure) == nil && failure.Message != "" {
+			return fmt.Errorf("kafka REST proxy returned %s: %s", resp.Status, failure.Message)
+		}
+		return fmt.Errorf("kafka REST proxy returned %s", resp.Status)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode kafka REST proxy response: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,376 @@
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	natsDialTimeout    = 10 * time.Second
+	natsRequestTimeout = 10 * time.Second
+	natsMaxReconnect   = 30 * time.Second
+	// natsPendingMessages is buffered per subscription; like NATS clients
+	// treat slow consumers, messages beyond it are dropped
+	natsPendingMessages = 256
+)
+
+var errNATSClosed = errors.New("nats connection closed")
+
+// natsConn speaks the NATS core protocol. It reconnects when the connection
+// drops and subscribes again to the subjects it had subscribed to.
+type natsConn struct {
+	config   Config
+	addr     string
+	host     string
+	secure   bool
+	mu       sync.Mutex
+	conn     net.Conn
+	writer   *bufio.Writer
+	pongs    []chan error
+	asyncErr error
+	subs     map[int]*natsSub
+	nextSID  int
+	closed   bool
+}
+
+type natsSub struct {
+	subject string
+	queue   string
+	msgs    chan []byte
+	dropped int
+}
+
+// natsInfo is the part of the server's INFO message the client needs
+type natsInfo struct {
+	TLSRequired bool `json:"tls_required"`
+}
+
+func dialNATS(config Config) (*natsConn, error) {
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NATS URL: %v", err)
+	}
+	switch u.Scheme {
+	case "nats", "tls":
+	default:
+		return nil, fmt.Errorf("NATS URL must use nats:// or tls://")
+	}
+	host := u.Hostname()
+	port := u.Port()
+	if port == "" {
+		port = "4222"
+	}
+	if u.User != nil && config.Username == "" {
+		config.Username = u.User.Username()
+		config.Password, _ = u.User.Password()
+	}
+
+	nc := &natsConn{
+		config: config,
+		addr:   net.JoinHostPort(host, port),
+		host:   host,
+		secure: u.Scheme == "tls",
+		subs:   make(map[int]*natsSub),
+	}
+	if err := nc.connect(); err != nil {
+		return nil, err
+	}
+	return nc, nil
+}
+
+// connect dials the server, authenticates and subscribes again to every
+// subject of the connection
+func (nc *natsConn) connect() error {
+	conn, err := net.DialTimeout("tcp", nc.addr, natsDialTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to NATS at %s: %v", nc.addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(natsDialTimeout))
+	reader := bufio.NewReader(conn)
+
+	line, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("failed to read NATS server info: %v", err)
+	}
+	var info natsInfo
+	json.Unmarshal([]byte(strings.TrimPrefix(strings.TrimSpace(line), "INFO ")), &info)
+	if nc.secure || info.TLSRequired {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: nc.host, MinVersion: tls.VersionTLS12})
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return fmt.Errorf("NATS TLS handshake failed: %v", err)
+		}
+		conn = tlsConn
+		reader = bufio.NewReader(conn)
+	}
+
+	connect, _ := json.Marshal(map[string]interface{}{
+		"verbose":    false,
+		"pedantic":   false,
+		"name":       nc.config.ClientName,
+		"lang":       "go",
+		"version":    "1.0.0",
+		"protocol":   1,
+		"auth_token": nc.config.Token,
+		"user":       nc.config.Username,
+		"pass":       nc.config.Password,
+	})
+	writer := bufio.NewWriter(conn)
+	fmt.Fprintf(writer, "CONNECT %s\r\nPING\r\n", connect)
+	if err := writer.Flush(); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to send NATS connect: %v", err)
+	}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			conn.Close()
+			return fmt.Errorf("NATS connect failed: %v", err)
+		}
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "-ERR") {
+			conn.Close()
+			return fmt.Errorf("NATS connect rejected: %s", natsErrorText(line))
+		}
+		if line == "PONG" {
+			break
+		}
+	}
+	conn.SetDeadline(time.Time{})
+
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	if nc.closed {
+		conn.Close()
+		return errNATSClosed
+	}
+	nc.conn = conn
+	nc.writer = writer
+	nc.asyncErr = nil
+	for sid, sub := range nc.subs {
+		nc.writeSub(sid, sub)
+	}
+	if err := nc.writer.Flush(); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to subscribe again after reconnect: %v", err)
+	}
+	go nc.readLoop(conn, reader)
+	return nil
+}
+
+// readLoop handles the messages of a connection until it drops, then
+// reconnects with backoff
+func (nc *natsConn) readLoop(conn net.Conn, reader *bufio.Reader) {
+	err := nc.read(reader)
+
+	nc.mu.Lock()
+	if nc.conn == conn {
+		nc.conn = nil
+		nc.writer = nil
+	}
+	for _, pong := range nc.pongs {
+		pong <- fmt.Errorf("nats connection lost: %v", err)
+	}
+	nc.pongs = nil
+	closed := nc.closed
+	nc.mu.Unlock()
+	conn.Close()
+
+	delay := time.Second
+	for !closed {
+		time.Sleep(delay)
+		if err := nc.connect(); err == nil || errors.Is(err, errNATSClosed) {
+			return
+		}
+		if delay *= 2; delay > natsMaxReconnect {
+			delay = natsMaxReconnect
+		}
+		nc.mu.Lock()
+		closed = nc.closed
+		nc.mu.Unlock()
+	}
+}
+
+func (nc *natsConn) read(reader *bufio.Reader) error {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(line, "MSG "):
+			if err := nc.readMsg(reader, strings.Fields(line)[1:]); err != nil {
+				return err
+			}
+		case line == "PING":
+			nc.mu.Lock()
+			if nc.writer != nil {
+				nc.writer.WriteString("PONG\r\n")
+				nc.writer.Flush()
+			}
+			nc.mu.Unlock()
+		case line == "PONG":
+			nc.mu.Lock()
+			if len(nc.pongs) > 0 {
+				nc.pongs[0] <- nc.asyncErr
+				nc.pongs = nc.pongs[1:]
+				nc.asyncErr = nil
+			}
+			nc.mu.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			// Reported to whoever waits for the next PONG; errors that
+			// close the connection surface as the read failing
+			nc.mu.Lock()
+			nc.asyncErr = errors.New(natsErrorText(line))
+			nc.mu.Unlock()
+		}
+	}
+}
+
+// readMsg reads the payload of "MSG <subject> <sid> [reply-to] <#bytes>"
+func (nc *natsConn) readMsg(reader *bufio.Reader, args []string) error {
+	if len(args) < 3 {
+		return fmt.Errorf("malformed NATS message header %v", args)
+	}
+	size, err := strconv.Atoi(args[len(args)-1])
+	if err != nil {
+		return fmt.Errorf("malformed NATS message size: %v", err)
+	}
+	payload := make([]byte, size+2)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return err
+	}
+	sid, _ := strconv.Atoi(args[1])
+
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	if sub, ok := nc.subs[sid]; ok {
+		select {
+		case sub.msgs <- payload[:size]:
+		default:
+			sub.dropped++
+		}
+	}
+	return nil
+}
+
+// request writes a command followed by a PING and waits for the server to
+// answer it, so errors the command caused are reported
+func (nc *natsConn) request(ctx context.Context, write func(w *bufio.Writer)) error {
+	nc.mu.Lock()
+	if nc.closed {
+		nc.mu.Unlock()
+		return errNATSClosed
+	}
+	if nc.writer == nil {
+		nc.mu.Unlock()
+		return fmt.Errorf("not connected to NATS at %s", nc.addr)
+	}
+	write(nc.writer)
+	nc.writer.WriteString("PING\r\n")
+	if err := nc.writer.Flush(); err != nil {
+		nc.mu.Unlock()
+		return err
+	}
+	pong := make(chan error, 1)
+	nc.pongs = append(nc.pongs, pong)
+	nc.mu.Unlock()
+
+	timer := time.NewTimer(natsRequestTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-pong:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return fmt.Errorf("timed out waiting for NATS at %s", nc.addr)
+	}
+}
+
+func (nc *natsConn) publish(ctx context.Context, topic, key string, data []byte) error {
+	return nc.request(ctx, func(w *bufio.Writer) {
+		fmt.Fprintf(w, "PUB %s %d\r\n", topic, len(data))
+		w.Write(data)
+		w.WriteString("\r\n")
+	})
+}
+
+func (nc *natsConn) subscribe(ctx context.Context, topic, group string, handler func(data []byte)) error {
+	sub := &natsSub{subject: topic, queue: group, msgs: make(chan []byte, natsPendingMessages)}
+	nc.mu.Lock()
+	nc.nextSID++
+	sid := nc.nextSID
+	nc.subs[sid] = sub
+	nc.mu.Unlock()
+
+	err := nc.request(ctx, func(w *bufio.Writer) {
+		nc.writeSub(sid, sub)
+	})
+	if err != nil {
+		nc.mu.Lock()
+		delete(nc.subs, sid)
+		nc.mu.Unlock()
+		return err
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				nc.mu.Lock()
+				delete(nc.subs, sid)
+				if nc.writer != nil {
+					fmt.Fprintf(nc.writer, "UNSUB %d\r\n", sid)
+					nc.writer.Flush()
+				}
+				nc.mu.Unlock()
+				return
+			case data := <-sub.msgs:
+				handler(data)
+			}
+		}
+	}()
+	return nil
+}
+
+// writeSub writes the SUB command of a subscription; callers hold nc.mu
+func (nc *natsConn) writeSub(sid int, sub *natsSub) {
+	if sub.queue != "" {
+		fmt.Fprintf(nc.writer, "SUB %s %s %d\r\n", sub.subject, sub.queue, sid)
+	} else {
+		fmt.Fprintf(nc.writer, "SUB %s %d\r\n", sub.subject, sid)
+	}
+}
+
+func (nc *natsConn) close() error {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	if nc.closed {
+		return nil
+	}
+	nc.closed = true
+	if nc.conn != nil {
+		nc.writer.Flush()
+		return nc.conn.Close()
+	}
+	return nil
+}
+
+// natsErrorText returns the message of a "-ERR 'message'" line
+func natsErrorText(line string) string {
+	return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")), "'")
+}
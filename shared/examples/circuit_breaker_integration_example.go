@@ -23,7 +23,7 @@ type CircuitBreakerIntegrationExample struct {
 	monitoring            monitoring.MetricsCollector
 	
 	// Service clients with circuit breaker protection
-	httpClientPool        *http.HTTPClientPool
+	httpClientPool        *http.ResilientHTTPClientPool
 	minioClient          *storage.ResilientMinIOClient
 	gitClient            *gitops.ResilientGitClient
 }
@@ -62,7 +62,7 @@ func NewCircuitBreakerIntegrationExample() (*CircuitBreakerIntegrationExample, e
 	}
 	
 	// Create service clients with circuit breaker protection
-	httpClientPool := http.NewHTTPClientPool(config, circuitBreakerManager, monitoring)
+	httpClientPool := http.NewResilientHTTPClientPool(config, circuitBreakerManager, monitoring)
 	
 	minioClient, err := storage.NewResilientMinIOClientFromSharedConfig(
 		config, circuitBreakerManager, monitoring)
@@ -772,7 +772,7 @@ main() {
 cleanup() {
     log_info "Cleaning up verification environment..."
     # Stop any background processes
-    pkill -f "validation-monitoring-framework" 2>/dev/null || true
+    pkill -f "\./validation-framework" 2>/dev/null || true
 }
 
 trap cleanup EXIT
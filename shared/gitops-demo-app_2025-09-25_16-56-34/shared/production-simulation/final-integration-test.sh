@@ -109,7 +109,6 @@ test_go_compilation() {
     
     local go_files=(
         "enhanced-backup-executor.go"
-        "validation-monitoring-framework.go"
     )
     
     for go_file in "${go_files[@]}"; do
@@ -119,6 +118,10 @@ test_go_compilation() {
             rm -f "/tmp/test-$go_file" 2>/dev/null || true
         fi
     done
+    
+    # The validation framework is the backup tool's validation-monitor
+    run_test "Go compilation: validation-monitor" "cd $SCRIPT_DIR/../../../../backup && go build -o /tmp/test-validation-monitor ./cmd/validation-monitor"
+    rm -f /tmp/test-validation-monitor 2>/dev/null || true
 }
 
 test_yaml_syntax() {
@@ -143,7 +146,6 @@ test_directory_structure() {
         "enhanced-backup-executor.go"
         "gitops-pipeline-orchestrator.sh"
         "disaster-recovery-simulator.sh"
-        "validation-monitoring-framework.go"
         "start-validation-framework.sh"
         "master-orchestrator.sh"
         "validate-setup.sh"
@@ -9,6 +9,8 @@ set -euo pipefail
 # Configuration
 SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
 CONFIG_FILE="${CONFIG_FILE:-$SCRIPT_DIR/validation-config.yaml}"
+# The framework is the backup tool's validation-monitor command
+BACKUP_DIR="${BACKUP_DIR:-$SCRIPT_DIR/../../../../backup}"
 LOG_FILE="${LOG_FILE:-validation-framework-$(date +%Y%m%d-%H%M%S).log}"
 PID_FILE="${PID_FILE:-validation-framework.pid}"
 METRICS_PORT="${METRICS_PORT:-8080}"
@@ -83,25 +85,13 @@ check_prerequisites() {
 install_dependencies() {
     log_info "📦 Installing Go dependencies..."
     
-    cd "$SCRIPT_DIR"
-    
-    # Initialize go module if not exists
-    if [[ ! -f "go.mod" ]]; then
-        log_info "Initializing Go module..."
-        go mod init validation-monitoring-framework
+    if [[ ! -f "$BACKUP_DIR/go.mod" ]]; then
+        log_error "Backup tool not found in $BACKUP_DIR, set BACKUP_DIR"
+        exit 1
     fi
     
-    # Install required dependencies
-    log_info "Installing Kubernetes client dependencies..."
-    go get k8s.io/api/core/v1@latest
-    go get k8s.io/apimachinery/pkg/apis/meta/v1@latest
-    go get k8s.io/client-go/kubernetes@latest
-    go get k8s.io/client-go/tools/clientcmd@latest
-    go get k8s.io/metrics/pkg/client/clientset/versioned@latest
-    go get gopkg.in/yaml.v2@latest
-    
-    # Tidy up dependencies
-    go mod tidy
+    # Download the backup tool's module dependencies
+    (cd "$BACKUP_DIR" && go mod download)
     
     log_success "Dependencies installed successfully"
 }
@@ -112,8 +102,8 @@ build_framework() {
     
     cd "$SCRIPT_DIR"
     
-    # Build the Go binary
-    if go build -o validation-framework validation-monitoring-framework.go; then
+    # Build the backup tool's validation monitor
+    if (cd "$BACKUP_DIR" && go build -o "$SCRIPT_DIR/validation-framework" ./cmd/validation-monitor); then
         log_success "Validation framework built successfully"
     else
         log_error "Failed to build validation framework"
@@ -533,6 +523,7 @@ EXAMPLES:
 
 ENVIRONMENT VARIABLES:
     CONFIG_FILE                 Configuration file path
+    BACKUP_DIR                 Backup tool checkout the framework is built from
     METRICS_PORT               Port for metrics endpoint
     LOG_FILE                   Log file path
     CLEANUP_MONITORING         Clean up monitoring resources on cleanup
@@ -97,7 +97,6 @@ required_files=(
     "enhanced-backup-executor.go"
     "gitops-pipeline-orchestrator.sh"
     "disaster-recovery-simulator.sh"
-    "validation-monitoring-framework.go"
     "start-validation-framework.sh"
     "master-orchestrator.sh"
     "validation-config.yaml"
@@ -212,6 +212,12 @@ func NewHTTPClient(config *HTTPClientConfig) *HTTPClient {
 
 // NewHTTPClientFromConfig creates HTTP client from shared configuration
 func NewHTTPClientFromConfig(sharedConfig *sharedconfig.SharedConfig, profile string) *HTTPClient {
+	return NewHTTPClient(configFromShared(sharedConfig, profile))
+}
+
+// configFromShared returns the client configuration of profile with the
+// overrides of the shared configuration
+func configFromShared(sharedConfig *sharedconfig.SharedConfig, profile string) *HTTPClientConfig {
 	config := DefaultHTTPClientConfig()
 
 	// Apply configuration overrides based on profile
@@ -270,7 +276,7 @@ func NewHTTPClientFromConfig(sharedConfig *sharedconfig.SharedConfig, profile st
 		}
 	}
 
-	return NewHTTPClient(config)
+	return config
 }
 
 // Do performs HTTP request with retry logic and circuit breaker
@@ -2,6 +2,7 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net/http"
@@ -56,6 +57,15 @@ func NewMonitoredHTTPClientFromConfig(sharedConfig *sharedconfig.SharedConfig, p
 	}
 }
 
+// NewMonitoredHTTPClientWithTLS creates a monitored HTTP client from shared
+// configuration that dials TLS with tlsConfig, such as the mutual TLS
+// configuration of the integration components
+func NewMonitoredHTTPClientWithTLS(sharedConfig *sharedconfig.SharedConfig, profile string, logger monitoring.Logger, tlsConfig *tls.Config) *MonitoredHTTPClient {
+	config := configFromShared(sharedConfig, profile)
+	config.TLSConfig = tlsConfig
+	return NewMonitoredHTTPClient(config, profile, logger)
+}
+
 // Implement MonitoredComponent interface
 
 func (mc *MonitoredHTTPClient) GetComponentName() string {
@@ -2,6 +2,7 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -347,8 +348,8 @@ func (rc *ResilientHTTPClient) recordMetric(metricName string, value float64) {
 	rc.monitoring.IncCounter(metricName, labels, value)
 }
 
-// HTTPClientPool manages multiple resilient HTTP clients for different services
-type HTTPClientPool struct {
+// ResilientHTTPClientPool manages multiple resilient HTTP clients for different services
+type ResilientHTTPClientPool struct {
 	clients               map[string]*ResilientHTTPClient
 	circuitBreakerManager *resilience.CircuitBreakerManager
 	sharedConfig          *sharedconfig.SharedConfig
@@ -356,13 +357,13 @@ type HTTPClientPool struct {
 	mu                    sync.RWMutex
 }
 
-// NewHTTPClientPool creates a new pool of resilient HTTP clients
-func NewHTTPClientPool(
+// NewResilientHTTPClientPool creates a new pool of resilient HTTP clients
+func NewResilientHTTPClientPool(
 	sharedConfig *sharedconfig.SharedConfig,
 	circuitBreakerManager *resilience.CircuitBreakerManager,
 	monitoring monitoring.MetricsCollector,
-) *HTTPClientPool {
-	return &HTTPClientPool{
+) *ResilientHTTPClientPool {
+	return &ResilientHTTPClientPool{
 		clients:               make(map[string]*ResilientHTTPClient),
 		circuitBreakerManager: circuitBreakerManager,
 		sharedConfig:          sharedConfig,
@@ -371,7 +372,7 @@ func NewHTTPClientPool(
 }
 
 // GetClient returns a resilient HTTP client for a specific service
-func (pool *HTTPClientPool) GetClient(serviceName, profile string) *ResilientHTTPClient {
+func (pool *ResilientHTTPClientPool) GetClient(serviceName, profile string) *ResilientHTTPClient {
 	pool.mu.RLock()
 	if client, exists := pool.clients[serviceName]; exists {
 		pool.mu.RUnlock()
@@ -401,7 +402,7 @@ func (pool *HTTPClientPool) GetClient(serviceName, profile string) *ResilientHTT
 }
 
 // GetAllClients returns all HTTP clients in the pool
-func (pool *HTTPClientPool) GetAllClients() map[string]*ResilientHTTPClient {
+func (pool *ResilientHTTPClientPool) GetAllClients() map[string]*ResilientHTTPClient {
 	pool.mu.RLock()
 	defer pool.mu.RUnlock()
 	
@@ -414,7 +415,7 @@ func (pool *HTTPClientPool) GetAllClients() map[string]*ResilientHTTPClient {
 }
 
 // GetPoolHealthStatus returns health status for all clients in the pool
-func (pool *HTTPClientPool) GetPoolHealthStatus() map[string]interface{} {
+func (pool *ResilientHTTPClientPool) GetPoolHealthStatus() map[string]interface{} {
 	pool.mu.RLock()
 	defer pool.mu.RUnlock()
 	
@@ -442,7 +443,7 @@ func (pool *HTTPClientPool) GetPoolHealthStatus() map[string]interface{} {
 }
 
 // CloseAll closes all HTTP clients in the pool
-func (pool *HTTPClientPool) CloseAll() error {
+func (pool *ResilientHTTPClientPool) CloseAll() error {
 	pool.mu.RLock()
 	defer pool.mu.RUnlock()
 	
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"time"
 
@@ -82,6 +83,17 @@ func NewBackupClient(config *sharedconfig.SharedConfig, monitoring monitoring.Me
 	client := &http.Client{
 		Timeout: timeout,
 	}
+	// With mutual TLS the client presents the component's certificate
+	if config != nil && config.Integration.Communication.Authentication.MutualTLS() {
+		if certificates, err := loadCommunicationTLS(config); err != nil {
+			log.Printf("Warning: backup client cannot use mutual TLS: %v", err)
+		} else {
+			client.Transport = &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: certificates.ClientConfig(),
+			}
+		}
+	}
 
 	return &BackupClient{
 		config:     config,
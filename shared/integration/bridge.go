@@ -11,6 +11,7 @@ import (
 	sharedconfig "shared-config/config"
 	"shared-config/http"
 	"shared-config/monitoring"
+	"shared-config/mtls"
 	"shared-config/security"
 	"shared-config/triggers"
 	"shared-config/restore"
@@ -23,11 +24,16 @@ type IntegrationBridge struct {
 	securityManager  *security.SecurityManager
 	httpClient       *http.MonitoredHTTPClient
 	triggerSystem    *triggers.MonitoredAutoTrigger
+	// certificates authenticate the components to each other, nil unless
+	// communication uses mutual TLS
+	certificates     *mtls.Certificates
 	
 	// Component systems
 	restoreEngine    *restore.RestoreEngine
 	restoreAPI       *restore.RestoreAPI
 	httpServer       *HTTPServer
+	// drills rehearse restores of the latest backup, nil unless enabled
+	drills           *restore.DrillScheduler
 	
 	// Component status tracking
 	backupStatus   ComponentStatus
@@ -38,6 +44,8 @@ type IntegrationBridge struct {
 	// Event coordination
 	eventBus       *EventBus
 	webhookHandler *WebhookHandler
+	webhookTrigger *WebhookTriggerServer
+	eventBusSubscriber *EventBusSubscriber
 	
 	// Integrated monitoring
 	monitoringIntegration *MonitoringIntegration
@@ -97,23 +105,47 @@ type GitOpsGenerationRequest struct {
 	Configuration map[string]interface{} `json:"configuration"`
 }
 
+// newBridgeSecurityManager returns the security manager validating the input
+// and auditing the requests of the bridge. Secrets come from the shared
+// configuration, and the components authenticate each other with webhook
+// tokens, signatures and mutual TLS, so its secrets, authentication, TLS and
+// scanning components stay off.
+func newBridgeSecurityManager(logger security.Logger) (*security.SecurityManager, error) {
+	securityConfig := security.DefaultSecurityConfig()
+	securityConfig.SecretsManagement.Provider = ""
+	securityConfig.Authentication.Enabled = false
+	securityConfig.TLS.Enabled = false
+	securityConfig.VulnerabilityScanning.Enabled = false
+	return security.NewSecurityManager(securityConfig, logger)
+}
+
 // NewIntegrationBridge creates a new integration bridge
 func NewIntegrationBridge(config *sharedconfig.SharedConfig) (*IntegrationBridge, error) {
 	if config == nil {
 		return nil, fmt.Errorf("shared configuration is required")
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	// Load the certificates of mutual TLS between the components
+	certificates, err := loadCommunicationTLS(config)
+	if err != nil {
+		return nil, err
+	}
 
 	// Initialize monitoring system
 	logger := monitoring.NewLogger("integration_bridge")
 	monitoringSystem := monitoring.NewMonitoringSystem(config, logger)
 
 	// Initialize security manager
-	securityManager := security.NewSecurityManager(config)
+	securityManager, err := newBridgeSecurityManager(logger)
+	if err != nil {
+		return nil, err
+	}
 
 	// Initialize HTTP client
 	httpClient := http.NewMonitoredHTTPClientFromConfig(config, "integration_bridge", logger)
+	if certificates != nil {
+		httpClient = http.NewMonitoredHTTPClientWithTLS(config, "integration_bridge", logger, certificates.ClientConfig())
+	}
 
 	// Initialize trigger system
 	triggerSystem := triggers.NewMonitoredAutoTrigger(config, logger)
@@ -127,12 +159,14 @@ func NewIntegrationBridge(config *sharedconfig.SharedConfig) (*IntegrationBridge
 	// Initialize restore API
 	restoreAPI := restore.NewRestoreAPI(restoreEngine, securityManager, monitoringSystem, config)
 
+	ctx, cancel := context.WithCancel(context.Background())
 	bridge := &IntegrationBridge{
 		config:           config,
 		monitoringSystem: monitoringSystem,
 		securityManager:  securityManager,
 		httpClient:       httpClient,
 		triggerSystem:    triggerSystem,
+		certificates:     certificates,
 		restoreEngine:    restoreEngine,
 		restoreAPI:       restoreAPI,
 		eventBus:         NewEventBus(config),
@@ -154,9 +188,25 @@ func NewIntegrationBridge(config *sharedconfig.SharedConfig) (*IntegrationBridge
 		},
 	}
 
+	// Rehearse restores of the backups the bridge is told about
+	if config.Restore.Drills.Enabled {
+		bridge.drills = restore.NewDrillScheduler(restoreEngine, config.Restore.Drills)
+		restoreAPI.SetDrillScheduler(bridge.drills)
+	}
+
 	// Initialize webhook handler
 	bridge.webhookHandler = NewWebhookHandler(bridge)
 
+	// Initialize webhook trigger listener
+	if config.Pipeline.Automation.WebhookTrigger.Enabled {
+		bridge.webhookTrigger = NewWebhookTriggerServer(bridge)
+	}
+
+	// Initialize event bus subscriber
+	if config.Integration.Communication.Method == "event-bus" {
+		bridge.eventBusSubscriber = NewEventBusSubscriber(bridge)
+	}
+
 	// Initialize monitoring integration
 	bridge.monitoringIntegration = NewMonitoringIntegration(bridge)
 
@@ -169,6 +219,32 @@ func NewIntegrationBridge(config *sharedconfig.SharedConfig) (*IntegrationBridge
 	return bridge, nil
 }
 
+// loadCommunicationTLS loads the certificate, key and CA of mutual TLS when
+// the communication authentication method is mutual-tls. The files are read
+// again when they are rotated.
+func loadCommunicationTLS(config *sharedconfig.SharedConfig) (*mtls.Certificates, error) {
+	auth := config.Integration.Communication.Authentication
+	if !auth.MutualTLS() {
+		return nil, nil
+	}
+	certificates, err := mtls.Load(mtls.Files{
+		CertFile: auth.TLS.CertFile,
+		KeyFile:  auth.TLS.KeyFile,
+		CAFile:   auth.TLS.CAFile,
+	}, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load mutual TLS certificates: %v", err)
+	}
+	certificates.OnReload = func(err error) {
+		if err != nil {
+			log.Printf("Warning: failed to reload mutual TLS certificates, keeping the previous ones: %v", err)
+			return
+		}
+		log.Printf("Reloaded mutual TLS certificates")
+	}
+	return certificates, nil
+}
+
 // Start initializes and starts the integration bridge
 func (ib *IntegrationBridge) Start(ctx context.Context) error {
 	ib.mu.Lock()
@@ -195,6 +271,20 @@ func (ib *IntegrationBridge) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to start webhook handler: %v", err)
 	}
 
+	// Start webhook trigger listener
+	if ib.webhookTrigger != nil {
+		if err := ib.webhookTrigger.Start(ctx); err != nil {
+			return fmt.Errorf("failed to start webhook trigger server: %v", err)
+		}
+	}
+
+	// Subscribe to backup lifecycle events
+	if ib.eventBusSubscriber != nil {
+		if err := ib.eventBusSubscriber.Start(ctx); err != nil {
+			return fmt.Errorf("failed to start event bus subscriber: %v", err)
+		}
+	}
+
 	// Start monitoring integration
 	if err := ib.monitoringIntegration.Start(ctx); err != nil {
 		log.Printf("Warning: failed to start monitoring integration: %v", err)
@@ -215,6 +305,12 @@ func (ib *IntegrationBridge) Start(ctx context.Context) error {
 	// Start background health monitoring
 	go ib.monitorComponentHealth(ctx)
 
+	// Start scheduled restore drills
+	if ib.drills != nil {
+		go ib.drills.Run(ctx)
+		log.Printf("Restore drills scheduled every %s", ib.drills.Interval())
+	}
+
 	log.Printf("Integration bridge with restore capabilities started successfully")
 	return nil
 }
@@ -235,6 +331,20 @@ func (ib *IntegrationBridge) Stop() error {
 		log.Printf("Warning: error stopping webhook handler: %v", err)
 	}
 
+	// Stop webhook trigger listener
+	if ib.webhookTrigger != nil {
+		if err := ib.webhookTrigger.Stop(); err != nil {
+			log.Printf("Warning: error stopping webhook trigger server: %v", err)
+		}
+	}
+
+	// Stop event bus subscriber
+	if ib.eventBusSubscriber != nil {
+		if err := ib.eventBusSubscriber.Stop(); err != nil {
+			log.Printf("Warning: error stopping event bus subscriber: %v", err)
+		}
+	}
+
 	// Stop HTTP server
 	if err := ib.httpServer.Stop(ib.ctx); err != nil {
 		log.Printf("Warning: error stopping HTTP server: %v", err)
@@ -270,6 +380,11 @@ func (ib *IntegrationBridge) TriggerGitOpsGeneration(ctx context.Context, event
 		return nil
 	}
 
+	// The next drill restores the latest successful backup
+	if ib.drills != nil {
+		ib.drills.RecordBackup(restore.DrillBackup{BackupID: event.BackupID, ClusterName: event.ClusterName, Timestamp: event.Timestamp})
+	}
+
 	log.Printf("Triggering GitOps generation for backup %s", event.BackupID)
 
 	// Create GitOps generation request
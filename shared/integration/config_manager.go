@@ -7,6 +7,7 @@ import (
 
 	"gopkg.in/yaml.v3"
 	sharedconfig "shared-config/config"
+	"shared-config/eventbus"
 )
 
 // ConfigManager provides unified configuration management across all components
@@ -84,9 +85,9 @@ func (cm *ConfigManager) GetStorageConfig() sharedconfig.StorageConfig {
 }
 
 // GetClusterConfig returns cluster-specific configuration
-func (cm *ConfigManager) GetClusterConfig() sharedconfig.ClusterConfig {
+func (cm *ConfigManager) GetClusterConfig() sharedconfig.SingleClusterConfig {
 	if cm.config == nil {
-		return sharedconfig.ClusterConfig{}
+		return sharedconfig.SingleClusterConfig{}
 	}
 	return cm.config.Cluster
 }
@@ -255,6 +256,16 @@ func (cm *ConfigManager) validateIntegrationConfig() error {
 		return fmt.Errorf("invalid communication method: %s", integration.Communication.Method)
 	}
 
+	if integration.Communication.Method == "event-bus" {
+		eventBus := integration.Communication.EventBus
+		if eventBus.Provider != eventbus.ProviderNATS && eventBus.Provider != eventbus.ProviderKafka {
+			return fmt.Errorf("invalid event bus provider: %q, must be nats or kafka", eventBus.Provider)
+		}
+		if eventBus.URL == "" {
+			return fmt.Errorf("event bus URL is required")
+		}
+	}
+
 	return nil
 }
 
@@ -271,6 +282,8 @@ func (cm *ConfigManager) CreateBackupToolConfig() map[string]interface{} {
 		"integration": map[string]interface{}{
 			"bridge_endpoint": cm.config.Integration.Communication.Endpoints.IntegrationBridge,
 			"webhook_enabled": cm.config.Integration.Triggers.AutoTrigger,
+			"method":          cm.config.Integration.Communication.Method,
+			"event_bus":       cm.config.Integration.Communication.EventBus,
 		},
 		"observability": cm.config.Observability,
 		"security":      cm.config.Security,
@@ -291,6 +304,8 @@ func (cm *ConfigManager) CreateGitOpsConfig() map[string]interface{} {
 			"bridge_endpoint": cm.config.Integration.Communication.Endpoints.IntegrationBridge,
 			"auto_trigger":    cm.config.Integration.Triggers.AutoTrigger,
 			"delay_after_backup": cm.config.Integration.Triggers.DelayAfterBackup,
+			"method":          cm.config.Integration.Communication.Method,
+			"event_bus":       cm.config.Integration.Communication.EventBus,
 		},
 		"observability": cm.config.Observability,
 		"security":      cm.config.Security,
@@ -0,0 +1,201 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	sharedconfig "shared-config/config"
+	"shared-config/eventbus"
+)
+
+const (
+	// defaultEventBusGroup shares the events among bridge replicas, so each
+	// backup is handled by one of them
+	defaultEventBusGroup = "integration-bridge"
+	eventBusDedupWindow  = 24 * time.Hour
+)
+
+// EventBusSubscriber connects the bridge to the external event bus when
+// integration.communication.method is event-bus. It forwards the backup
+// lifecycle events the backup tool publishes to the bridge's event bus as
+// backup_started, backup_namespace_completed, backup_completed and
+// backup_failed, and launches GitOps generation for every completed backup,
+// once per backup even when the broker delivers it again.
+type EventBusSubscriber struct {
+	config   sharedconfig.EventBusConfig
+	local    *EventBus
+	generate func(ctx context.Context, event *BackupCompletionEvent) error
+	bus      *eventbus.Bus
+
+	// accepted maps the IDs of completed events to when they were accepted
+	accepted map[string]time.Time
+	cancel   context.CancelFunc
+	mu       sync.Mutex
+}
+
+// NewEventBusSubscriber creates the event bus subscriber of the bridge
+func NewEventBusSubscriber(bridge *IntegrationBridge) *EventBusSubscriber {
+	return newEventBusSubscriber(bridge.config.Integration.Communication.EventBus, bridge.eventBus, bridge.TriggerGitOpsGeneration)
+}
+
+func newEventBusSubscriber(config sharedconfig.EventBusConfig, local *EventBus, generate func(ctx context.Context, event *BackupCompletionEvent) error) *EventBusSubscriber {
+	if config.ConsumerGroup == "" {
+		config.ConsumerGroup = defaultEventBusGroup
+	}
+	return &EventBusSubscriber{
+		config:   config,
+		local:    local,
+		generate: generate,
+		accepted: make(map[string]time.Time),
+	}
+}
+
+// Start connects to the broker and subscribes to the backup lifecycle events
+func (s *EventBusSubscriber) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.bus != nil {
+		return fmt.Errorf("event bus subscriber is already running")
+	}
+
+	bus, err := eventbus.New(eventbus.Config{
+		Provider:    s.config.Provider,
+		URL:         s.config.URL,
+		TopicPrefix: s.config.TopicPrefix,
+		Token:       s.config.Token,
+		Username:    s.config.Username,
+		Password:    s.config.Password,
+		Group:       s.config.ConsumerGroup,
+		ClientName:  "integration-bridge",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to event bus: %v", err)
+	}
+
+	subscriptionCtx, cancel := context.WithCancel(context.Background())
+	for _, eventType := range eventbus.LifecycleEvents {
+		if err := bus.Subscribe(subscriptionCtx, eventType, s.handleEvent); err != nil {
+			cancel()
+			bus.Close()
+			return err
+		}
+	}
+	s.bus = bus
+	s.cancel = cancel
+
+	log.Printf("Subscribed to backup lifecycle events on %s event bus %s", s.config.Provider, s.config.URL)
+	return nil
+}
+
+// Stop ends the subscriptions and disconnects from the broker
+func (s *EventBusSubscriber) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.bus == nil {
+		return nil
+	}
+	s.cancel()
+	err := s.bus.Close()
+	s.bus = nil
+	if err != nil {
+		return fmt.Errorf("failed to close event bus: %v", err)
+	}
+	log.Printf("Event bus subscriber stopped")
+	return nil
+}
+
+// handleEvent forwards a lifecycle event to the bridge and generates GitOps
+// manifests for a completed backup. A failed generation forgets the event so
+// a redelivery can try again.
+func (s *EventBusSubscriber) handleEvent(ctx context.Context, event eventbus.Event) {
+	if err := s.local.Publish(ctx, lifecycleIntegrationEvent(event)); err != nil {
+		log.Printf("Failed to forward %s event of backup %s: %v", event.Type, event.BackupID, err)
+	}
+
+	if event.Type != eventbus.BackupCompleted {
+		return
+	}
+	if !s.accept(event.ID) {
+		log.Printf("Backup %s already handled, duplicate event ignored", event.BackupID)
+		return
+	}
+	if err := s.generate(ctx, busBackupEvent(event)); err != nil {
+		log.Printf("GitOps generation for backup %s failed: %v", event.BackupID, err)
+		s.mu.Lock()
+		delete(s.accepted, event.ID)
+		s.mu.Unlock()
+	}
+}
+
+// accept records the event ID, reporting false for an event already accepted
+// within the dedup window
+func (s *EventBusSubscriber) accept(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for acceptedID, acceptedAt := range s.accepted {
+		if now.Sub(acceptedAt) > eventBusDedupWindow {
+			delete(s.accepted, acceptedID)
+		}
+	}
+	if _, ok := s.accepted[id]; ok {
+		return false
+	}
+	s.accepted[id] = now
+	return true
+}
+
+// lifecycleIntegrationEvent converts a lifecycle event for the bridge's event
+// bus, where backup.completed becomes backup_completed
+func lifecycleIntegrationEvent(event eventbus.Event) *IntegrationEvent {
+	data := map[string]interface{}{
+		"backup_id":      event.BackupID,
+		"cluster_name":   event.Cluster,
+		"bucket":         event.Bucket,
+		"resource_count": event.ResourceCount,
+	}
+	if event.Namespace != "" {
+		data["namespace"] = event.Namespace
+	}
+	if event.NamespaceCount > 0 {
+		data["namespace_count"] = event.NamespaceCount
+	}
+	if len(event.Errors) > 0 {
+		data["errors"] = event.Errors
+	}
+	return &IntegrationEvent{
+		ID:        event.ID,
+		Type:      strings.ReplaceAll(event.Type, ".", "_"),
+		Source:    "backup-tool",
+		Target:    "integration-bridge",
+		Timestamp: event.Timestamp,
+		Data:      data,
+		Metadata: map[string]interface{}{
+			"transport": "event-bus",
+		},
+	}
+}
+
+// busBackupEvent converts a backup.completed event
+func busBackupEvent(event eventbus.Event) *BackupCompletionEvent {
+	minioPath := ""
+	if event.Bucket != "" {
+		minioPath = event.Bucket + "/" + event.Cluster
+	}
+	return &BackupCompletionEvent{
+		BackupID:      event.BackupID,
+		ClusterName:   event.Cluster,
+		Timestamp:     event.Timestamp,
+		ResourceCount: event.ResourceCount,
+		Success:       event.Type == eventbus.BackupCompleted,
+		ErrorMessage:  strings.Join(event.Errors, "; "),
+		MinIOPath:     minioPath,
+	}
+}
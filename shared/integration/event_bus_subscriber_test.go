@@ -0,0 +1,64 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	sharedconfig "shared-config/config"
+	"shared-config/eventbus"
+)
+
+func TestEventBusSubscriber(t *testing.T) {
+	var mu sync.Mutex
+	var generated []*BackupCompletionEvent
+	failNext := true
+	local := NewEventBus(nil)
+	subscriber := newEventBusSubscriber(sharedconfig.EventBusConfig{Provider: eventbus.ProviderNATS}, local, func(ctx context.Context, event *BackupCompletionEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if failNext {
+			failNext = false
+			return fmt.Errorf("git push rejected")
+		}
+		generated = append(generated, event)
+		return nil
+	})
+	if subscriber.config.ConsumerGroup != defaultEventBusGroup {
+		t.Errorf("Expected consumer group %s, got %s", defaultEventBusGroup, subscriber.config.ConsumerGroup)
+	}
+
+	var forwarded []*IntegrationEvent
+	for _, eventType := range []string{"backup_namespace_completed", "backup_completed"} {
+		local.Subscribe(eventType, func(ctx context.Context, event *IntegrationEvent) error {
+			mu.Lock()
+			defer mu.Unlock()
+			forwarded = append(forwarded, event)
+			return nil
+		})
+	}
+
+	ctx := context.Background()
+	subscriber.handleEvent(ctx, eventbus.NewNamespaceEvent("prod-1", "prod", "default", 12, nil))
+	completed := eventbus.NewEvent(eventbus.BackupCompleted, "prod-1", "prod")
+	completed.Bucket = "cluster-backups"
+	completed.ResourceCount = 12
+	subscriber.handleEvent(ctx, completed)
+	// The first generation fails, so the redelivery generates again
+	subscriber.handleEvent(ctx, completed)
+	subscriber.handleEvent(ctx, completed)
+
+	if len(forwarded) != 4 {
+		t.Fatalf("Expected 4 forwarded events, got %d", len(forwarded))
+	}
+	if forwarded[0].Type != "backup_namespace_completed" || forwarded[0].Data["namespace"] != "default" {
+		t.Errorf("Unexpected namespace event: %+v", forwarded[0])
+	}
+	if len(generated) != 1 {
+		t.Fatalf("Expected 1 generation, got %d", len(generated))
+	}
+	if event := generated[0]; event.BackupID != "prod-1" || !event.Success || event.MinIOPath != "cluster-backups/prod" || event.ResourceCount != 12 {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+}
@@ -11,7 +11,6 @@ import (
 	"time"
 
 	sharedconfig "shared-config/config"
-	"shared-config/monitoring"
 )
 
 // Test configuration
@@ -26,14 +25,15 @@ var testConfig = &sharedconfig.SharedConfig{
 		Bucket:    "test-bucket",
 		UseSSL:    false,
 	},
-	Cluster: sharedconfig.ClusterConfig{
+	Cluster: sharedconfig.SingleClusterConfig{
 		Name:   "test-cluster",
 		Domain: "cluster.local",
 	},
 	GitOps: sharedconfig.GitOpsConfig{
-		Repository: "https://github.com/test/repo",
-		Branch:     "main",
-		Path:       "clusters/test",
+		Repository: sharedconfig.RepositoryConfig{
+			URL:    "https://github.com/test/repo",
+			Branch: "main",
+		},
 	},
 	Integration: sharedconfig.IntegrationConfig{
 		Enabled:     true,
@@ -109,6 +109,25 @@ func TestConfigManager(t *testing.T) {
 		}
 	})
 
+	t.Run("ValidateEventBus", func(t *testing.T) {
+		config := *testConfig
+		config.Integration.Communication.Method = "event-bus"
+		cm := NewConfigManager()
+		cm.config = &config
+
+		if err := cm.ValidateConfiguration(); err == nil {
+			t.Error("Expected event bus without provider to fail validation")
+		}
+		config.Integration.Communication.EventBus = sharedconfig.EventBusConfig{Provider: "kafka"}
+		if err := cm.ValidateConfiguration(); err == nil {
+			t.Error("Expected event bus without URL to fail validation")
+		}
+		config.Integration.Communication.EventBus.URL = "http://kafka-rest:8082"
+		if err := cm.ValidateConfiguration(); err != nil {
+			t.Errorf("Expected event bus configuration to be valid: %v", err)
+		}
+	})
+
 	t.Run("CreateBackupToolConfig", func(t *testing.T) {
 		cm := NewConfigManager()
 		cm.config = testConfig
@@ -242,7 +261,7 @@ func TestIntegrationBridge(t *testing.T) {
 // TestEventBus tests the event bus functionality
 func TestEventBus(t *testing.T) {
 	t.Run("SubscribePublish", func(t *testing.T) {
-		eventBus := NewEventBus()
+		eventBus := NewEventBus(testConfig)
 		eventReceived := make(chan bool, 1)
 
 		// Subscribe to events
@@ -281,7 +300,7 @@ func TestEventBus(t *testing.T) {
 	})
 
 	t.Run("GetSubscriberCount", func(t *testing.T) {
-		eventBus := NewEventBus()
+		eventBus := NewEventBus(testConfig)
 
 		count := eventBus.GetSubscriberCount("nonexistent")
 		if count != 0 {
@@ -45,6 +45,10 @@ func NewHTTPServer(bridge *IntegrationBridge, config *sharedconfig.SharedConfig)
 		WriteTimeout: config.Timeouts.HTTPWriteTimeout,
 		IdleTimeout:  config.Timeouts.HTTPIdleTimeout,
 	}
+	// With mutual TLS only clients with a certificate of the CA are served
+	if bridge.certificates != nil {
+		httpServer.TLSConfig = bridge.certificates.ServerConfig()
+	}
 	
 	server.server = httpServer
 	
@@ -82,10 +86,17 @@ func (hs *HTTPServer) Start(ctx context.Context) error {
 	if port == 0 {
 		port = 8080
 	}
-	log.Printf("Starting HTTP server on port %d", port)
+	log.Printf("Starting HTTP server on port %d (mutual TLS: %v)", port, hs.server.TLSConfig != nil)
 	
 	go func() {
-		if err := hs.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if hs.server.TLSConfig != nil {
+			// The certificate comes from the TLS configuration
+			err = hs.server.ListenAndServeTLS("", "")
+		} else {
+			err = hs.server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Printf("HTTP server error: %v", err)
 		}
 	}()
@@ -0,0 +1,324 @@
+package integration
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	sharedconfig "shared-config/config"
+	"shared-config/triggers"
+)
+
+// Defaults for settings webhook_trigger leaves unset
+const (
+	defaultWebhookTriggerPort     = 8080
+	defaultWebhookTriggerPath     = "/webhook/backup-complete"
+	defaultWebhookMaxConcurrent   = 2
+	defaultWebhookDedupWindow     = 24 * time.Hour
+	maxWebhookTriggerRequestBytes = 1 << 20
+)
+
+// WebhookTriggerServer is the listener of pipeline.automation.webhook_trigger.
+// It accepts the backup-complete events the backup tool's webhook trigger
+// sends, authenticates them with the configured token or HMAC signature,
+// drops redeliveries of a backup it has already accepted and launches GitOps
+// generation, running at most max_concurrent generations at a time.
+type WebhookTriggerServer struct {
+	bridge   *IntegrationBridge
+	config   sharedconfig.WebhookTriggerConfig
+	generate func(ctx context.Context, event *BackupCompletionEvent) error
+	mux      *http.ServeMux
+	server   *http.Server
+
+	// slots holds a token per running generation
+	slots chan struct{}
+	// accepted maps backup IDs to when they were accepted
+	accepted map[string]time.Time
+	inflight sync.WaitGroup
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running bool
+	mu      sync.Mutex
+}
+
+// webhookTriggerPayload is the body sent by the backup tool's webhook trigger
+type webhookTriggerPayload struct {
+	EventType string                          `json:"event_type"`
+	Backup    *triggers.BackupCompletionEvent `json:"backup"`
+}
+
+// NewWebhookTriggerServer creates the webhook trigger listener of the bridge
+func NewWebhookTriggerServer(bridge *IntegrationBridge) *WebhookTriggerServer {
+	return newWebhookTriggerServer(bridge.config.Pipeline.Automation.WebhookTrigger, bridge, bridge.TriggerGitOpsGeneration)
+}
+
+func newWebhookTriggerServer(config sharedconfig.WebhookTriggerConfig, bridge *IntegrationBridge, generate func(ctx context.Context, event *BackupCompletionEvent) error) *WebhookTriggerServer {
+	if config.ServerPort == 0 {
+		config.ServerPort = defaultWebhookTriggerPort
+	}
+	if config.EndpointPath == "" {
+		config.EndpointPath = defaultWebhookTriggerPath
+	}
+	if config.MaxConcurrent <= 0 {
+		config.MaxConcurrent = defaultWebhookMaxConcurrent
+	}
+	if config.DedupWindow <= 0 {
+		config.DedupWindow = defaultWebhookDedupWindow
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wts := &WebhookTriggerServer{
+		bridge:   bridge,
+		config:   config,
+		generate: generate,
+		mux:      http.NewServeMux(),
+		slots:    make(chan struct{}, config.MaxConcurrent),
+		accepted: make(map[string]time.Time),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	wts.mux.HandleFunc(config.EndpointPath, wts.handleBackupComplete)
+	return wts
+}
+
+// Start binds the listener and serves webhook triggers in the background
+func (wts *WebhookTriggerServer) Start(ctx context.Context) error {
+	wts.mu.Lock()
+	defer wts.mu.Unlock()
+
+	if wts.running {
+		return fmt.Errorf("webhook trigger server is already running")
+	}
+
+	addr := net.JoinHostPort(wts.config.ServerHost, fmt.Sprintf("%d", wts.config.ServerPort))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", addr, err)
+	}
+
+	wts.server = &http.Server{
+		Handler:      wts.mux,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+	if wts.bridge != nil && wts.bridge.config != nil {
+		wts.server.ReadTimeout = wts.bridge.config.Timeouts.HTTPReadTimeout
+		wts.server.WriteTimeout = wts.bridge.config.Timeouts.HTTPWriteTimeout
+		wts.server.IdleTimeout = wts.bridge.config.Timeouts.HTTPIdleTimeout
+	}
+	// With mutual TLS only the components with a certificate of the CA can
+	// trigger generations
+	if wts.bridge != nil && wts.bridge.certificates != nil {
+		listener = tls.NewListener(listener, wts.bridge.certificates.ServerConfig())
+	}
+	wts.running = true
+
+	go func() {
+		log.Printf("Starting webhook trigger server on %s%s", addr, wts.config.EndpointPath)
+		if err := wts.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("Webhook trigger server error: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops accepting webhooks and waits for running generations to finish
+// until the shutdown timeout, after which they are cancelled
+func (wts *WebhookTriggerServer) Stop() error {
+	wts.mu.Lock()
+	if !wts.running {
+		wts.mu.Unlock()
+		return nil
+	}
+	wts.running = false
+	wts.mu.Unlock()
+
+	shutdownTimeout := 10 * time.Second
+	if wts.bridge != nil && wts.bridge.config != nil {
+		shutdownTimeout = wts.bridge.config.Timeouts.HTTPShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := wts.server.Shutdown(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		wts.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Printf("Cancelling GitOps generations still running at webhook trigger shutdown")
+	}
+	wts.cancel()
+
+	if err != nil {
+		return fmt.Errorf("failed to shutdown webhook trigger server: %v", err)
+	}
+	log.Printf("Webhook trigger server stopped")
+	return nil
+}
+
+// handleBackupComplete accepts a backup-complete event and launches GitOps
+// generation for it in the background
+func (wts *WebhookTriggerServer) handleBackupComplete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookTriggerRequestBytes))
+	if err != nil {
+		wts.sendError(w, http.StatusBadRequest, "Failed to read request body", err)
+		return
+	}
+	if !triggers.VerifyWebhookAuth(r, wts.config.Authentication, body) {
+		wts.sendError(w, http.StatusUnauthorized, "Unauthorized", fmt.Errorf("missing or invalid webhook token or signature"))
+		return
+	}
+
+	var payload webhookTriggerPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		wts.sendError(w, http.StatusBadRequest, "Invalid JSON payload", err)
+		return
+	}
+	if payload.Backup == nil || payload.Backup.BackupID == "" {
+		wts.sendError(w, http.StatusBadRequest, "Invalid backup event format", fmt.Errorf("backup.backup_id is required"))
+		return
+	}
+	event := bridgeBackupEvent(payload.Backup)
+
+	if !event.Success {
+		wts.sendResponse(w, http.StatusOK, "Backup did not succeed, GitOps generation skipped", event.BackupID, map[string]interface{}{
+			"backup_id": event.BackupID,
+			"triggered": false,
+		})
+		return
+	}
+
+	status, message := wts.accept(event.BackupID)
+	switch status {
+	case http.StatusOK:
+		wts.sendResponse(w, status, message, event.BackupID, map[string]interface{}{
+			"backup_id": event.BackupID,
+			"triggered": false,
+			"duplicate": true,
+		})
+		return
+	case http.StatusTooManyRequests:
+		w.Header().Set("Retry-After", "30")
+		wts.sendError(w, status, message, fmt.Errorf("%d GitOps generations already running", wts.config.MaxConcurrent))
+		return
+	}
+
+	wts.inflight.Add(1)
+	go wts.run(event)
+
+	wts.sendResponse(w, http.StatusAccepted, message, event.BackupID, map[string]interface{}{
+		"backup_id": event.BackupID,
+		"triggered": true,
+	})
+}
+
+// accept records the backup ID and takes a generation slot. A backup already
+// accepted within the dedup window is reported as a duplicate, and a request
+// arriving while all slots are taken is rejected so the sender retries later.
+func (wts *WebhookTriggerServer) accept(backupID string) (int, string) {
+	wts.mu.Lock()
+	defer wts.mu.Unlock()
+
+	now := time.Now()
+	for id, acceptedAt := range wts.accepted {
+		if now.Sub(acceptedAt) > wts.config.DedupWindow {
+			delete(wts.accepted, id)
+		}
+	}
+	if _, ok := wts.accepted[backupID]; ok {
+		return http.StatusOK, "Backup already accepted, duplicate ignored"
+	}
+
+	select {
+	case wts.slots <- struct{}{}:
+	default:
+		return http.StatusTooManyRequests, "Too many GitOps generations running"
+	}
+	wts.accepted[backupID] = now
+	return http.StatusAccepted, "GitOps generation started"
+}
+
+// run generates GitOps manifests for an accepted backup. A failed generation
+// forgets the backup ID so a redelivered webhook can try again.
+func (wts *WebhookTriggerServer) run(event *BackupCompletionEvent) {
+	defer wts.inflight.Done()
+	defer func() { <-wts.slots }()
+
+	if err := wts.generate(wts.ctx, event); err != nil {
+		log.Printf("GitOps generation for backup %s failed: %v", event.BackupID, err)
+		wts.mu.Lock()
+		delete(wts.accepted, event.BackupID)
+		wts.mu.Unlock()
+		wts.incCounter("webhook_requests_total", map[string]string{"endpoint": "backup_trigger", "status": "failure"})
+		return
+	}
+	wts.incCounter("webhook_requests_total", map[string]string{"endpoint": "backup_trigger", "status": "success"})
+}
+
+// bridgeBackupEvent converts the event of the shared trigger system
+func bridgeBackupEvent(event *triggers.BackupCompletionEvent) *BackupCompletionEvent {
+	minioPath := event.BackupLocation
+	if minioPath == "" && event.MinIOBucket != "" {
+		minioPath = event.MinIOBucket + "/" + event.ClusterName
+	}
+	return &BackupCompletionEvent{
+		BackupID:      event.BackupID,
+		ClusterName:   event.ClusterName,
+		Timestamp:     event.Timestamp,
+		ResourceCount: event.ResourcesCount,
+		Size:          event.BackupSize,
+		Success:       event.Success,
+		ErrorMessage:  strings.Join(event.Errors, "; "),
+		MinIOPath:     minioPath,
+	}
+}
+
+func (wts *WebhookTriggerServer) incCounter(name string, labels map[string]string) {
+	if wts.bridge == nil || wts.bridge.monitoringSystem == nil {
+		return
+	}
+	wts.bridge.monitoringSystem.GetMonitoringHub().GetMetricsCollector().IncCounter(name, labels, 1)
+}
+
+func (wts *WebhookTriggerServer) sendResponse(w http.ResponseWriter, statusCode int, message, requestID string, data interface{}) {
+	response := WebhookResponse{
+		Success:   statusCode < 300,
+		Message:   message,
+		RequestID: requestID,
+		Timestamp: time.Now(),
+		Data:      data,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
+
+func (wts *WebhookTriggerServer) sendError(w http.ResponseWriter, statusCode int, message string, err error) {
+	wts.incCounter("webhook_errors_total", map[string]string{"status_code": fmt.Sprintf("%d", statusCode)})
+	wts.sendResponse(w, statusCode, message, fmt.Sprintf("error-%d", time.Now().Unix()), map[string]interface{}{
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,160 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	sharedconfig "shared-config/config"
+	"shared-config/triggers"
+)
+
+func webhookTriggerBody(t *testing.T, backupID string, success bool) []byte {
+	body, err := json.Marshal(map[string]interface{}{
+		"event_type": "backup_complete",
+		"backup": triggers.BackupCompletionEvent{
+			BackupID:       backupID,
+			ClusterName:    "prod",
+			Success:        success,
+			MinIOBucket:    "cluster-backups",
+			BackupLocation: "cluster-backups/prod",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+	return body
+}
+
+func postWebhookTrigger(t *testing.T, url string, body []byte, auth sharedconfig.WebhookAuthConfig) int {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	triggers.SetWebhookAuth(req, auth, body)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to call webhook trigger: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestWebhookTriggerServer(t *testing.T) {
+	t.Run("TokenAndDeduplication", func(t *testing.T) {
+		var mu sync.Mutex
+		var generated []*BackupCompletionEvent
+		config := sharedconfig.WebhookTriggerConfig{
+			Authentication: sharedconfig.WebhookAuthConfig{Enabled: true, Token: "trigger-token"},
+		}
+		wts := newWebhookTriggerServer(config, nil, func(ctx context.Context, event *BackupCompletionEvent) error {
+			mu.Lock()
+			generated = append(generated, event)
+			mu.Unlock()
+			return nil
+		})
+		server := httptest.NewServer(wts.mux)
+		defer server.Close()
+		url := server.URL + defaultWebhookTriggerPath
+
+		body := webhookTriggerBody(t, "prod-1", true)
+		if status := postWebhookTrigger(t, url, body, sharedconfig.WebhookAuthConfig{}); status != http.StatusUnauthorized {
+			t.Errorf("Expected 401 without token, got %d", status)
+		}
+		if status := postWebhookTrigger(t, url, body, sharedconfig.WebhookAuthConfig{Enabled: true, Token: "wrong"}); status != http.StatusUnauthorized {
+			t.Errorf("Expected 401 with wrong token, got %d", status)
+		}
+		if status := postWebhookTrigger(t, url, body, config.Authentication); status != http.StatusAccepted {
+			t.Errorf("Expected 202, got %d", status)
+		}
+		if status := postWebhookTrigger(t, url, body, config.Authentication); status != http.StatusOK {
+			t.Errorf("Expected 200 for duplicate, got %d", status)
+		}
+		failed := webhookTriggerBody(t, "prod-2", false)
+		if status := postWebhookTrigger(t, url, failed, config.Authentication); status != http.StatusOK {
+			t.Errorf("Expected 200 for failed backup, got %d", status)
+		}
+
+		wts.inflight.Wait()
+		if len(generated) != 1 {
+			t.Fatalf("Expected 1 generation, got %d", len(generated))
+		}
+		if generated[0].BackupID != "prod-1" || generated[0].MinIOPath != "cluster-backups/prod" {
+			t.Errorf("Unexpected event: %+v", generated[0])
+		}
+	})
+
+	t.Run("HMACSignature", func(t *testing.T) {
+		config := sharedconfig.WebhookTriggerConfig{
+			Authentication: sharedconfig.WebhookAuthConfig{Enabled: true, HMACSecret: "s3cret", SignatureHeader: "X-Hub-Signature-256"},
+		}
+		wts := newWebhookTriggerServer(config, nil, func(ctx context.Context, event *BackupCompletionEvent) error { return nil })
+		server := httptest.NewServer(wts.mux)
+		defer server.Close()
+		url := server.URL + defaultWebhookTriggerPath
+
+		body := webhookTriggerBody(t, "prod-1", true)
+		if status := postWebhookTrigger(t, url, body, config.Authentication); status != http.StatusAccepted {
+			t.Errorf("Expected 202 with valid signature, got %d", status)
+		}
+
+		req, _ := http.NewRequest(http.MethodPost, url, bytes.NewReader(webhookTriggerBody(t, "prod-2", true)))
+		req.Header.Set("X-Hub-Signature-256", triggers.WebhookSignature("s3cret", body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to call webhook trigger: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for signature of another body, got %d", resp.StatusCode)
+		}
+		wts.inflight.Wait()
+	})
+
+	t.Run("ConcurrencyLimitAndRetry", func(t *testing.T) {
+		release := make(chan struct{})
+		attempts := map[string]int{}
+		var mu sync.Mutex
+		config := sharedconfig.WebhookTriggerConfig{MaxConcurrent: 1, DedupWindow: time.Hour}
+		wts := newWebhookTriggerServer(config, nil, func(ctx context.Context, event *BackupCompletionEvent) error {
+			<-release
+			mu.Lock()
+			defer mu.Unlock()
+			attempts[event.BackupID]++
+			if attempts[event.BackupID] == 1 {
+				return fmt.Errorf("git push rejected")
+			}
+			return nil
+		})
+		server := httptest.NewServer(wts.mux)
+		defer server.Close()
+		url := server.URL + defaultWebhookTriggerPath
+
+		if status := postWebhookTrigger(t, url, webhookTriggerBody(t, "prod-1", true), config.Authentication); status != http.StatusAccepted {
+			t.Errorf("Expected 202, got %d", status)
+		}
+		if status := postWebhookTrigger(t, url, webhookTriggerBody(t, "prod-2", true), config.Authentication); status != http.StatusTooManyRequests {
+			t.Errorf("Expected 429 while a generation runs, got %d", status)
+		}
+		close(release)
+		wts.inflight.Wait()
+
+		// The failed generation is forgotten, so redelivery starts it again
+		if status := postWebhookTrigger(t, url, webhookTriggerBody(t, "prod-1", true), config.Authentication); status != http.StatusAccepted {
+			t.Errorf("Expected 202 for redelivery after failure, got %d", status)
+		}
+		wts.inflight.Wait()
+		if status := postWebhookTrigger(t, url, webhookTriggerBody(t, "prod-1", true), config.Authentication); status != http.StatusOK {
+			t.Errorf("Expected 200 for duplicate after success, got %d", status)
+		}
+		if attempts["prod-1"] != 2 {
+			t.Errorf("Expected 2 attempts, got %d", attempts["prod-1"])
+		}
+	})
+}
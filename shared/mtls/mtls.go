@@ -0,0 +1,224 @@
+// Package mtls serves and dials TLS with a certificate, key and CA bundle read
+// from files and read again when they are rotated, so a renewed certificate
+// or CA takes effect without a restart. With a CA bundle, servers require
+// client certificates it signed and clients accept only servers it signed:
+// mutual TLS between the components. It uses only the standard library.
+package mtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultReloadInterval is how often the files are checked for changes when
+// no interval is given
+const DefaultReloadInterval = time.Minute
+
+// Files are the PEM files of a component's TLS identity
+type Files struct {
+	CertFile string
+	KeyFile  string
+	// CAFile is the CA bundle peers' certificates must chain to. Without it
+	// servers do not ask for client certificates and clients verify servers
+	// against the system roots.
+	CAFile string
+}
+
+// Certificates holds the certificate and CA pool loaded from Files. The
+// files are checked for changes at most once per interval, during a
+// handshake; a handshake in progress while they change uses the previous
+// ones.
+type Certificates struct {
+	// OnReload, when set, is called for every change detected, with the
+	// error if the new files could not be loaded
+	OnReload func(err error)
+	// Configure, when set, adjusts every configuration ServerConfig and
+	// ClientConfig return, such as to restrict cipher suites
+	Configure func(cfg *tls.Config)
+
+	files    Files
+	interval time.Duration
+
+	mu          sync.Mutex
+	next        time.Time
+	stamps      map[string]fileStamp
+	certificate *tls.Certificate
+	pool        *x509.CertPool
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Load reads files, checking them for changes every interval afterwards
+func Load(files Files, interval time.Duration) (*Certificates, error) {
+	if files.CertFile == "" || files.KeyFile == "" {
+		return nil, fmt.Errorf("a TLS certificate and key file are required")
+	}
+	if interval <= 0 {
+		interval = DefaultReloadInterval
+	}
+	c := &Certificates{files: files, interval: interval}
+	c.stamps = c.stat()
+	certificate, pool, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	c.certificate, c.pool = certificate, pool
+	c.next = time.Now().Add(interval)
+	return c, nil
+}
+
+// Mutual reports whether peers must present a certificate signed by the CA
+func (c *Certificates) Mutual() bool {
+	return c.files.CAFile != ""
+}
+
+// ServerConfig returns the TLS configuration of a server presenting the
+// current certificate and, with a CA bundle, requiring client certificates
+// signed by the current CA
+func (c *Certificates) ServerConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			certificate, _ := c.current()
+			return certificate, nil
+		},
+	}
+	if c.Mutual() {
+		// Client certificates are verified in VerifyConnection rather than
+		// against ClientCAs, which cannot change once the server started
+		cfg.ClientAuth = tls.RequireAnyClientCert
+		cfg.VerifyConnection = func(state tls.ConnectionState) error {
+			_, pool := c.current()
+			return verify(state.PeerCertificates, pool, "", x509.ExtKeyUsageClientAuth)
+		}
+	}
+	if c.Configure != nil {
+		c.Configure(cfg)
+	}
+	return cfg
+}
+
+// ClientConfig returns the TLS configuration of a client presenting the
+// current certificate and accepting servers whose certificate the current CA
+// signed for the host dialled
+func (c *Certificates) ClientConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			certificate, _ := c.current()
+			return certificate, nil
+		},
+		// The built-in verification would use a fixed RootCAs pool, so it is
+		// done in VerifyConnection with the current one instead
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			_, pool := c.current()
+			return verify(state.PeerCertificates, pool, state.ServerName, x509.ExtKeyUsageServerAuth)
+		},
+	}
+	if c.Configure != nil {
+		c.Configure(cfg)
+	}
+	return cfg
+}
+
+// current returns the certificate and CA pool, reloading them first when the
+// files changed since the last check
+func (c *Certificates) current() (*tls.Certificate, *x509.CertPool) {
+	c.mu.Lock()
+	if time.Now().Before(c.next) {
+		defer c.mu.Unlock()
+		return c.certificate, c.pool
+	}
+	c.next = time.Now().Add(c.interval)
+
+	stamps := c.stat()
+	changed := len(stamps) != len(c.stamps)
+	for path, stamp := range stamps {
+		if previous, ok := c.stamps[path]; !ok || previous != stamp {
+			changed = true
+		}
+	}
+	if !changed {
+		defer c.mu.Unlock()
+		return c.certificate, c.pool
+	}
+
+	certificate, pool, err := c.load()
+	if err == nil {
+		c.certificate, c.pool = certificate, pool
+		c.stamps = stamps
+	}
+	// On failure the stamps are kept, so the files are loaded again at the
+	// next check in case they were caught halfway through an update
+	certificate, pool = c.certificate, c.pool
+	c.mu.Unlock()
+
+	if c.OnReload != nil {
+		c.OnReload(err)
+	}
+	return certificate, pool
+}
+
+// stat returns the modification time and size of the files that exist.
+// Kubernetes updates a mounted Secret by swapping a symlink, so the file a
+// path resolves to gets a new modification time.
+func (c *Certificates) stat() map[string]fileStamp {
+	stamps := make(map[string]fileStamp, 3)
+	for _, path := range []string{c.files.CertFile, c.files.KeyFile, c.files.CAFile} {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			stamps[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return stamps
+}
+
+func (c *Certificates) load() (*tls.Certificate, *x509.CertPool, error) {
+	certificate, err := tls.LoadX509KeyPair(c.files.CertFile, c.files.KeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load TLS certificate: %v", err)
+	}
+	if c.files.CAFile == "" {
+		return &certificate, nil, nil
+	}
+	data, err := os.ReadFile(c.files.CAFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CA bundle: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, nil, fmt.Errorf("CA bundle %s contains no certificates", c.files.CAFile)
+	}
+	return &certificate, pool, nil
+}
+
+// verify checks that the peer's certificate chains to pool, or to the system
+// roots when pool is nil, for usage and, when set, host
+func verify(peer []*x509.Certificate, pool *x509.CertPool, host string, usage x509.ExtKeyUsage) error {
+	if len(peer) == 0 {
+		return fmt.Errorf("peer presented no certificate")
+	}
+	opts := x509.VerifyOptions{
+		Roots:         pool,
+		DNSName:       host,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	}
+	for _, intermediate := range peer[1:] {
+		opts.Intermediates.AddCert(intermediate)
+	}
+	if _, err := peer[0].Verify(opts); err != nil {
+		return fmt.Errorf("peer certificate not trusted: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+package mtls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for the tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns the certificate and key PEM of name for usage
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+}
+
+// writes counts writeFiles calls
+var writes int
+
+// writeFiles writes the identity of name into dir and returns its files.
+// Each write moves the modification time forward so it is seen as a change
+// even within the file system's time resolution.
+func writeFiles(t *testing.T, dir, name string, cert, key, ca []byte) Files {
+	files := Files{
+		CertFile: filepath.Join(dir, name+".crt"),
+		KeyFile:  filepath.Join(dir, name+".key"),
+		CAFile:   filepath.Join(dir, name+"-ca.crt"),
+	}
+	writes++
+	modTime := time.Now().Add(time.Duration(writes) * time.Second)
+	for path, data := range map[string][]byte{files.CertFile: cert, files.KeyFile: key, files.CAFile: ca} {
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return files
+}
+
+// serve serves OK over TLS with cfg and returns the server's URL
+func serve(t *testing.T, cfg *tls.Config) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	})}
+	go server.Serve(tls.NewListener(listener, cfg))
+	t.Cleanup(func() { server.Close() })
+	return "https://" + listener.Addr().String()
+}
+
+// get returns the common name of the server certificate, or the error
+func get(url string, cfg *tls.Config) (string, error) {
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}, Timeout: 5 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	return resp.TLS.PeerCertificates[0].Subject.CommonName, nil
+}
+
+func load(t *testing.T, files Files) *Certificates {
+	certificates, err := Load(files, time.Nanosecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certificates
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, other := newTestCA(t, "ca"), newTestCA(t, "other-ca")
+
+	serverCert, serverKey := ca.issue(t, "bridge", x509.ExtKeyUsageServerAuth)
+	server := load(t, writeFiles(t, dir, "server", serverCert, serverKey, ca.pem))
+	if !server.Mutual() {
+		t.Fatal("a server with a CA bundle should require client certificates")
+	}
+	url := serve(t, server.ServerConfig())
+
+	clientCert, clientKey := ca.issue(t, "backup", x509.ExtKeyUsageClientAuth)
+	client := load(t, writeFiles(t, dir, "client", clientCert, clientKey, ca.pem))
+	name, err := get(url, client.ClientConfig())
+	if err != nil {
+		t.Fatalf("client signed by the CA rejected: %v", err)
+	}
+	if name != "bridge" {
+		t.Errorf("server certificate = %q, want bridge", name)
+	}
+
+	if _, err := get(url, &tls.Config{RootCAs: client.pool}); err == nil {
+		t.Error("client without a certificate accepted")
+	}
+
+	strangerCert, strangerKey := other.issue(t, "stranger", x509.ExtKeyUsageClientAuth)
+	stranger := load(t, writeFiles(t, dir, "stranger", strangerCert, strangerKey, ca.pem))
+	if _, err := get(url, stranger.ClientConfig()); err == nil {
+		t.Error("client signed by another CA accepted")
+	}
+
+	// A server certificate used as a client certificate has the wrong usage
+	misused := load(t, writeFiles(t, dir, "misused", serverCert, serverKey, ca.pem))
+	if _, err := get(url, misused.ClientConfig()); err == nil {
+		t.Error("client certificate without the client auth usage accepted")
+	}
+
+	// The client does not trust servers of another CA either
+	wary := load(t, writeFiles(t, dir, "wary", clientCert, clientKey, other.pem))
+	if _, err := get(url, wary.ClientConfig()); err == nil {
+		t.Error("server signed by another CA accepted")
+	}
+}
+
+func TestCertificates_Reload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "ca")
+
+	serverCert, serverKey := ca.issue(t, "bridge", x509.ExtKeyUsageServerAuth)
+	server := load(t, writeFiles(t, dir, "server", serverCert, serverKey, ca.pem))
+	var mu sync.Mutex
+	var reloads []error
+	server.OnReload = func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		reloads = append(reloads, err)
+	}
+	url := serve(t, server.ServerConfig())
+
+	clientCert, clientKey := ca.issue(t, "backup", x509.ExtKeyUsageClientAuth)
+	client := load(t, writeFiles(t, dir, "client", clientCert, clientKey, ca.pem))
+
+	// The server's certificate is renewed
+	renewedCert, renewedKey := ca.issue(t, "bridge-renewed", x509.ExtKeyUsageServerAuth)
+	writeFiles(t, dir, "server", renewedCert, renewedKey, ca.pem)
+	if name, err := get(url, client.ClientConfig()); err != nil || name != "bridge-renewed" {
+		t.Fatalf("after renewal got %q, %v; want the renewed certificate", name, err)
+	}
+
+	// Both sides move to a new CA
+	rotated := newTestCA(t, "rotated-ca")
+	rotatedServerCert, rotatedServerKey := rotated.issue(t, "bridge-rotated", x509.ExtKeyUsageServerAuth)
+	rotatedClientCert, rotatedClientKey := rotated.issue(t, "backup-rotated", x509.ExtKeyUsageClientAuth)
+	writeFiles(t, dir, "server", rotatedServerCert, rotatedServerKey, rotated.pem)
+	writeFiles(t, dir, "client", rotatedClientCert, rotatedClientKey, rotated.pem)
+	if name, err := get(url, client.ClientConfig()); err != nil || name != "bridge-rotated" {
+		t.Fatalf("after CA rotation got %q, %v; want the rotated certificate", name, err)
+	}
+	if _, err := get(url, load(t, writeFiles(t, dir, "old", clientCert, clientKey, ca.pem)).ClientConfig()); err == nil {
+		t.Error("client of the previous CA accepted after rotation")
+	}
+
+	// A broken update keeps the working certificate in use
+	writeFiles(t, dir, "server", []byte("not a certificate"), rotatedServerKey, rotated.pem)
+	if name, err := get(url, client.ClientConfig()); err != nil || name != "bridge-rotated" {
+		t.Fatalf("after a broken update got %q, %v; want the previous certificate", name, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reloads) < 3 || reloads[0] != nil || reloads[len(reloads)-1] == nil {
+		t.Errorf("reloads = %v, want successful reloads then a failure", reloads)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "ca")
+	cert, key := ca.issue(t, "bridge", x509.ExtKeyUsageServerAuth)
+
+	if _, err := Load(Files{CAFile: "ca.crt"}, 0); err == nil {
+		t.Error("Load without a certificate succeeded")
+	}
+	files := writeFiles(t, dir, "bad-ca", cert, key, []byte("not a certificate"))
+	if _, err := Load(files, 0); err == nil {
+		t.Error("Load with an empty CA bundle succeeded")
+	}
+	files = writeFiles(t, dir, "no-ca", cert, key, ca.pem)
+	files.CAFile = ""
+	certificates, err := Load(files, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if certificates.Mutual() || certificates.ServerConfig().ClientAuth != tls.NoClientCert {
+		t.Error("a server without a CA bundle should not ask for client certificates")
+	}
+}
+
+func TestCertificates_Configure(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "ca")
+	cert, key := ca.issue(t, "bridge", x509.ExtKeyUsageServerAuth)
+	certificates := load(t, writeFiles(t, dir, "server", cert, key, ca.pem))
+	certificates.Configure = func(cfg *tls.Config) {
+		cfg.MinVersion = tls.VersionTLS13
+	}
+
+	if certificates.ServerConfig().MinVersion != tls.VersionTLS13 || certificates.ClientConfig().MinVersion != tls.VersionTLS13 {
+		t.Error("Configure was not applied to the server and client configurations")
+	}
+}
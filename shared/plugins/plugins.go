@@ -0,0 +1,157 @@
+// Package plugins lets sites filter and rewrite the resources a backup stores
+// and a restore applies without forking the binaries, e.g. to add a
+// cost-center label to every restored workload or keep scratch namespaces'
+// Jobs out of backups. Plugins are Go plugins (go build -buildmode=plugin)
+// exporting a New function of type Factory, and must be built with the same
+// Go version and the same versions of this module and k8s.io/apimachinery as
+// the binary loading them.
+package plugins
+
+import (
+	"fmt"
+	"plugin"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Stage is the operation a plugin is handed a resource by
+type Stage string
+
+// Stages
+const (
+	// StageBackup resources are handed over as listed, before the volatile
+	// fields are stripped and the resource is stored
+	StageBackup Stage = "backup"
+	// StageRestore resources are handed over after the restore's own
+	// transforms, right before the resource is applied
+	StageRestore Stage = "restore"
+)
+
+// NewSymbol is the name of the Factory a plugin exports
+const NewSymbol = "New"
+
+// Factory creates a plugin from the options configured for it
+type Factory = func(options map[string]string) (Plugin, error)
+
+// Plugin is a site-specific extension. Every plugin implements
+// ResourceFilter, Transformer or both.
+type Plugin interface {
+	// Name identifies the plugin in logs and skip reasons
+	Name() string
+}
+
+// ResourceFilter decides which resources are kept
+type ResourceFilter interface {
+	Plugin
+	// Filter reports whether obj is kept. A resource filtered out is not
+	// stored by a backup, and reported as skipped by a restore.
+	Filter(stage Stage, obj *unstructured.Unstructured) (bool, error)
+}
+
+// Transformer rewrites resources
+type Transformer interface {
+	Plugin
+	// Transform modifies obj in place
+	Transform(stage Stage, obj *unstructured.Unstructured) error
+}
+
+// Chain runs plugins in order. A nil Chain keeps every resource as it is.
+type Chain struct {
+	plugins []Plugin
+}
+
+// NewChain creates a chain of plugins, run in the order given
+func NewChain(plugins ...Plugin) (*Chain, error) {
+	seen := make(map[string]bool)
+	for _, p := range plugins {
+		_, isFilter := p.(ResourceFilter)
+		_, isTransformer := p.(Transformer)
+		if !isFilter && !isTransformer {
+			return nil, fmt.Errorf("plugin %s is neither a resource filter nor a transformer", p.Name())
+		}
+		if seen[p.Name()] {
+			return nil, fmt.Errorf("plugin %s is loaded twice", p.Name())
+		}
+		seen[p.Name()] = true
+	}
+	return &Chain{plugins: plugins}, nil
+}
+
+// Open loads the Go plugins at paths and creates each with options
+func Open(paths []string, options map[string]string) (*Chain, error) {
+	var loaded []Plugin
+	for _, path := range paths {
+		p, err := open(path, options)
+		if err != nil {
+			return nil, err
+		}
+		loaded = append(loaded, p)
+	}
+	return NewChain(loaded...)
+}
+
+func open(path string, options map[string]string) (Plugin, error) {
+	library, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plugin %s: %v", path, err)
+	}
+	symbol, err := library.Lookup(NewSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s does not export %s: %v", path, NewSymbol, err)
+	}
+	factory, ok := symbol.(Factory)
+	if !ok {
+		return nil, fmt.Errorf("plugin %s exports %s as %T, not a plugins.Factory", path, NewSymbol, symbol)
+	}
+	p, err := factory(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create plugin %s: %v", path, err)
+	}
+	return p, nil
+}
+
+// Names lists the plugins of the chain in order
+func (c *Chain) Names() []string {
+	if c == nil {
+		return nil
+	}
+	names := make([]string, 0, len(c.plugins))
+	for _, p := range c.plugins {
+		names = append(names, p.Name())
+	}
+	return names
+}
+
+// Apply hands obj to each plugin in turn, filtering then transforming it, and
+// stops at the first plugin filtering it out. It returns the name of that
+// plugin, empty when obj is kept.
+func (c *Chain) Apply(stage Stage, obj *unstructured.Unstructured) (string, error) {
+	if c == nil {
+		return "", nil
+	}
+	for _, p := range c.plugins {
+		if filter, ok := p.(ResourceFilter); ok {
+			keep, err := filter.Filter(stage, obj)
+			if err != nil {
+				return "", fmt.Errorf("plugin %s failed to filter %s %s: %v", p.Name(), obj.GetKind(), describe(obj), err)
+			}
+			if !keep {
+				return p.Name(), nil
+			}
+		}
+		if transformer, ok := p.(Transformer); ok {
+			if err := transformer.Transform(stage, obj); err != nil {
+				return "", fmt.Errorf("plugin %s failed to transform %s %s: %v", p.Name(), obj.GetKind(), describe(obj), err)
+			}
+		}
+	}
+	return "", nil
+}
+
+// describe names a resource as namespace/name, or name when cluster-scoped
+func describe(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return obj.GetName()
+	}
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
@@ -0,0 +1,110 @@
+package plugins
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// costCenter labels every resource restored into a namespace with the
+// namespace's cost center
+type costCenter struct {
+	centers map[string]string
+}
+
+func (c *costCenter) Name() string { return "cost-center" }
+
+func (c *costCenter) Transform(stage Stage, obj *unstructured.Unstructured) error {
+	center, ok := c.centers[obj.GetNamespace()]
+	if stage != StageRestore || !ok {
+		return nil
+	}
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels["cost-center"] = center
+	obj.SetLabels(labels)
+	return nil
+}
+
+// dropKind filters out every resource of a kind
+type dropKind struct {
+	kind string
+	err  error
+}
+
+func (d *dropKind) Name() string { return "drop-" + d.kind }
+
+func (d *dropKind) Filter(stage Stage, obj *unstructured.Unstructured) (bool, error) {
+	return obj.GetKind() != d.kind, d.err
+}
+
+type inert struct{}
+
+func (inert) Name() string { return "inert" }
+
+func resource(kind, namespace, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func TestChain_Apply(t *testing.T) {
+	chain, err := NewChain(&dropKind{kind: "Pod"}, &costCenter{centers: map[string]string{"shop": "cc-1042"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"drop-Pod", "cost-center"}, chain.Names())
+
+	configMap := resource("ConfigMap", "shop", "settings")
+	dropped, err := chain.Apply(StageRestore, configMap)
+	require.NoError(t, err)
+	assert.Empty(t, dropped)
+	assert.Equal(t, map[string]string{"cost-center": "cc-1042"}, configMap.GetLabels())
+
+	pod := resource("Pod", "shop", "web-0")
+	dropped, err = chain.Apply(StageRestore, pod)
+	require.NoError(t, err)
+	assert.Equal(t, "drop-Pod", dropped)
+	assert.Empty(t, pod.GetLabels(), "later plugins do not see a dropped resource")
+
+	backedUp := resource("ConfigMap", "shop", "settings")
+	_, err = chain.Apply(StageBackup, backedUp)
+	require.NoError(t, err)
+	assert.Empty(t, backedUp.GetLabels(), "plugins see the stage")
+}
+
+func TestChain_ApplyError(t *testing.T) {
+	chain, err := NewChain(&dropKind{kind: "Pod", err: errors.New("policy unavailable")})
+	require.NoError(t, err)
+
+	_, err = chain.Apply(StageBackup, resource("ConfigMap", "shop", "settings"))
+	assert.ErrorContains(t, err, "drop-Pod")
+	assert.ErrorContains(t, err, "shop/settings")
+}
+
+func TestNewChain_Invalid(t *testing.T) {
+	_, err := NewChain(inert{})
+	assert.Error(t, err, "a plugin must filter or transform")
+
+	_, err = NewChain(&dropKind{kind: "Pod"}, &dropKind{kind: "Pod"})
+	assert.Error(t, err, "plugin names are unique")
+}
+
+func TestNilChain(t *testing.T) {
+	var chain *Chain
+	dropped, err := chain.Apply(StageBackup, resource("ConfigMap", "shop", "settings"))
+	assert.NoError(t, err)
+	assert.Empty(t, dropped)
+	assert.Empty(t, chain.Names())
+}
+
+func TestOpen_Missing(t *testing.T) {
+	_, err := Open([]string{"/nonexistent/cost-center.so"}, nil)
+	assert.Error(t, err)
+}
@@ -1,8 +1,12 @@
 package resilience
 
 import (
+	"fmt"
+	"log"
 	"sync"
 	"time"
+
+	"shared-config/monitoring"
 )
 
 // EventType represents different types of circuit breaker events
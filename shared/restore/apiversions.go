@@ -0,0 +1,490 @@
+package restore
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// hostnameLabel is the topology key of the node in beta EndpointSlices
+const hostnameLabel = "kubernetes.io/hostname"
+
+// apiConversion converts a built-in kind from an API version Kubernetes
+// removed to the version that replaced it
+type apiConversion struct {
+	// to is the replacing version, empty when the API was removed without one
+	to string
+	// removed explains what to do instead when there is no replacement
+	removed string
+	// convert rewrites the fields that differ between the versions, or fails
+	// when the resource relies on behaviour the new version does not have
+	convert func(obj *unstructured.Unstructured) ([]FieldChange, error)
+}
+
+// apiConversions are keyed by the removed group version and kind. Only
+// these are converted; any other resource, custom ones in particular, is
+// applied in the version it was backed up in.
+var apiConversions = func() map[schema.GroupVersionKind]apiConversion {
+	conversions := make(map[schema.GroupVersionKind]apiConversion)
+	add := func(from string, kinds []string, conversion apiConversion) {
+		gv, _ := schema.ParseGroupVersion(from)
+		for _, kind := range kinds {
+			conversions[gv.WithKind(kind)] = conversion
+		}
+	}
+
+	// Same schema in the replacing version
+	add("batch/v1beta1", []string{"CronJob"}, apiConversion{to: "batch/v1"})
+	add("autoscaling/v2beta2", []string{"HorizontalPodAutoscaler"}, apiConversion{to: "autoscaling/v2"})
+	add("extensions/v1beta1", []string{"NetworkPolicy"}, apiConversion{to: "networking.k8s.io/v1"})
+	add("networking.k8s.io/v1beta1", []string{"IngressClass"}, apiConversion{to: "networking.k8s.io/v1"})
+	add("rbac.authorization.k8s.io/v1beta1", []string{"Role", "ClusterRole", "RoleBinding", "ClusterRoleBinding"}, apiConversion{to: "rbac.authorization.k8s.io/v1"})
+	add("scheduling.k8s.io/v1beta1", []string{"PriorityClass"}, apiConversion{to: "scheduling.k8s.io/v1"})
+	add("storage.k8s.io/v1beta1", []string{"StorageClass", "CSIDriver", "CSINode", "CSIStorageCapacity", "VolumeAttachment"}, apiConversion{to: "storage.k8s.io/v1"})
+	add("coordination.k8s.io/v1beta1", []string{"Lease"}, apiConversion{to: "coordination.k8s.io/v1"})
+
+	add("policy/v1beta1", []string{"PodDisruptionBudget"}, apiConversion{to: "policy/v1", convert: convertPodDisruptionBudget})
+	add("extensions/v1beta1", []string{"Ingress"}, apiConversion{to: "networking.k8s.io/v1", convert: convertIngress})
+	add("networking.k8s.io/v1beta1", []string{"Ingress"}, apiConversion{to: "networking.k8s.io/v1", convert: convertIngress})
+	add("extensions/v1beta1", []string{"Deployment", "DaemonSet", "ReplicaSet"}, apiConversion{to: "apps/v1", convert: convertWorkload})
+	add("apps/v1beta1", []string{"Deployment", "StatefulSet"}, apiConversion{to: "apps/v1", convert: convertWorkload})
+	add("apps/v1beta2", []string{"Deployment", "StatefulSet", "DaemonSet", "ReplicaSet"}, apiConversion{to: "apps/v1", convert: convertWorkload})
+	add("autoscaling/v2beta1", []string{"HorizontalPodAutoscaler"}, apiConversion{to: "autoscaling/v2", convert: convertHorizontalPodAutoscaler})
+	add("discovery.k8s.io/v1beta1", []string{"EndpointSlice"}, apiConversion{to: "discovery.k8s.io/v1", convert: convertEndpointSlice})
+	add("admissionregistration.k8s.io/v1beta1", []string{"MutatingWebhookConfiguration", "ValidatingWebhookConfiguration"}, apiConversion{to: "admissionregistration.k8s.io/v1", convert: convertWebhookConfiguration})
+
+	add("policy/v1beta1", []string{"PodSecurityPolicy"}, apiConversion{removed: "PodSecurityPolicy was removed in Kubernetes 1.25, enforce Pod Security Standards with namespace labels instead"})
+	return conversions
+}()
+
+// servedAPIs records the kinds each group version of the target serves
+type servedAPIs map[string]map[string]bool
+
+// serves reports whether the target serves kind in groupVersion
+func (s servedAPIs) serves(groupVersion, kind string) bool {
+	return s[groupVersion][kind]
+}
+
+// discoverServedAPIs returns the kinds the target serves. Group versions
+// whose discovery failed are missing from the result and returned as
+// unknown, as they may well be served.
+func discoverServedAPIs(client discovery.DiscoveryInterface) (servedAPIs, map[string]bool, error) {
+	_, lists, err := client.ServerGroupsAndResources()
+	unknown := make(map[string]bool)
+	if err != nil {
+		var failed *discovery.ErrGroupDiscoveryFailed
+		if !errors.As(err, &failed) || len(lists) == 0 {
+			return nil, nil, err
+		}
+		for gv := range failed.Groups {
+			unknown[gv.String()] = true
+		}
+	}
+
+	served := make(servedAPIs)
+	for _, list := range lists {
+		if list == nil {
+			continue
+		}
+		for _, resource := range list.APIResources {
+			if strings.Contains(resource.Name, "/") {
+				continue
+			}
+			if served[list.GroupVersion] == nil {
+				served[list.GroupVersion] = make(map[string]bool)
+			}
+			served[list.GroupVersion][resource.Kind] = true
+		}
+	}
+	return served, unknown, nil
+}
+
+// convertAPIVersions converts the resources backed up in an API version the
+// target cluster does not serve to the version that replaced it. Resources
+// that cannot be converted are reported as failed and left out of the
+// restore, instead of failing one by one when applied. The changes made are
+// reported with the restored resource, like those of transforms.
+func (re *RestoreEngine) convertAPIVersions(operation *RestoreOperation, resources []BackupResource) []BackupResource {
+	if operation.Request.ConvertAPIVersions != nil && !*operation.Request.ConvertAPIVersions {
+		return resources
+	}
+
+	// Discovery is only needed when the backup has a convertible kind
+	candidates := false
+	for _, resource := range resources {
+		if _, ok := apiConversions[schema.FromAPIVersionAndKind(resource.APIVersion, resource.Kind)]; ok {
+			candidates = true
+			break
+		}
+	}
+	if !candidates {
+		return resources
+	}
+
+	served, unknown, err := discoverServedAPIs(re.k8sClient.Discovery())
+	if err != nil {
+		operation.Errors = append(operation.Errors, RestoreError{
+			Type:        "api_version_discovery",
+			Message:     fmt.Sprintf("failed to discover the served API versions, resources are applied in their backed-up versions: %v", err),
+			Timestamp:   time.Now(),
+			Recoverable: true,
+		})
+		return resources
+	}
+
+	kept := make([]BackupResource, 0, len(resources))
+	for _, resource := range resources {
+		conversion, ok := apiConversions[schema.FromAPIVersionAndKind(resource.APIVersion, resource.Kind)]
+		if !ok || unknown[resource.APIVersion] || served.serves(resource.APIVersion, resource.Kind) {
+			kept = append(kept, resource)
+			continue
+		}
+
+		converted, changes, err := convertAPIVersion(resource, conversion, served)
+		if err != nil {
+			operation.Results.FailedResources = append(operation.Results.FailedResources, FailedResource{
+				APIVersion: resource.APIVersion,
+				Kind:       resource.Kind,
+				Namespace:  resource.Namespace,
+				Name:       resource.Name,
+				Error:      fmt.Sprintf("%s %s is not served by the target cluster: %v", resource.APIVersion, resource.Kind, err),
+				Timestamp:  time.Now(),
+			})
+			operation.Progress.FailedResources++
+			continue
+		}
+		if operation.conversions == nil {
+			operation.conversions = make(map[string][]FieldChange)
+		}
+		operation.conversions[checkpointKey(converted)] = changes
+		kept = append(kept, converted)
+	}
+	return kept
+}
+
+// convertAPIVersion returns resource converted to the replacing version of
+// conversion and the changes made to it
+func convertAPIVersion(resource BackupResource, conversion apiConversion, served servedAPIs) (BackupResource, []FieldChange, error) {
+	if conversion.to == "" {
+		return resource, nil, errors.New(conversion.removed)
+	}
+	if !served.serves(conversion.to, resource.Kind) {
+		return resource, nil, fmt.Errorf("cannot convert to %s, which it does not serve either", conversion.to)
+	}
+
+	obj := backupObject(resource)
+	// The status is in the old version's form, and the target's controllers
+	// write their own
+	delete(obj.Object, "status")
+	obj.SetAPIVersion(conversion.to)
+	changes := []FieldChange{{Field: "apiVersion", OldValue: resource.APIVersion, NewValue: conversion.to, Action: "modified"}}
+	if conversion.convert != nil {
+		converted, err := conversion.convert(obj)
+		if err != nil {
+			return resource, nil, fmt.Errorf("cannot convert to %s: %v", conversion.to, err)
+		}
+		changes = append(changes, converted...)
+	}
+
+	resource.APIVersion = conversion.to
+	resource.Data = obj.Object
+	return resource, changes, nil
+}
+
+// conversionChanges returns the changes made converting resource to a
+// served API version
+func (operation *RestoreOperation) conversionChanges(resource BackupResource) []FieldChange {
+	return operation.conversions[checkpointKey(resource)]
+}
+
+// convertPodDisruptionBudget refuses budgets with an empty selector, which
+// selects no pods in policy/v1beta1 but every pod of the namespace in
+// policy/v1
+func convertPodDisruptionBudget(obj *unstructured.Unstructured) ([]FieldChange, error) {
+	selector, found, _ := unstructured.NestedMap(obj.Object, "spec", "selector")
+	if found && len(selector) == 0 {
+		return nil, errors.New("an empty selector selects every pod of the namespace in policy/v1 instead of none")
+	}
+	return nil, nil
+}
+
+// convertIngress moves the service backends to their networking.k8s.io/v1
+// form and gives paths the path type the beta versions defaulted to
+func convertIngress(obj *unstructured.Unstructured) ([]FieldChange, error) {
+	spec, _ := obj.Object["spec"].(map[string]interface{})
+	if spec == nil {
+		return nil, nil
+	}
+
+	var changes []FieldChange
+	if backend, ok := spec["backend"].(map[string]interface{}); ok {
+		changes = append(changes, FieldChange{Field: "spec.backend", OldValue: copyMap(backend), Action: "removed"})
+		if _, err := convertIngressBackend(backend, "spec.backend"); err != nil {
+			return nil, err
+		}
+		delete(spec, "backend")
+		spec["defaultBackend"] = backend
+		changes = append(changes, FieldChange{Field: "spec.defaultBackend", NewValue: backend, Action: "added"})
+	}
+
+	rules, _ := spec["rules"].([]interface{})
+	for i, rule := range rules {
+		rule, ok := rule.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		paths, _, _ := unstructured.NestedSlice(rule, "http", "paths")
+		for j, path := range paths {
+			path, ok := path.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			field := fmt.Sprintf("spec.rules[%d].http.paths[%d]", i, j)
+			if _, ok := path["pathType"]; !ok {
+				path["pathType"] = "ImplementationSpecific"
+				changes = append(changes, FieldChange{Field: field + ".pathType", NewValue: "ImplementationSpecific", Action: "added"})
+			}
+			if backend, ok := path["backend"].(map[string]interface{}); ok {
+				change, err := convertIngressBackend(backend, field+".backend")
+				if err != nil {
+					return nil, err
+				}
+				if change != nil {
+					changes = append(changes, *change)
+				}
+			}
+		}
+		// NestedSlice returns a copy
+		if paths != nil {
+			if err := unstructured.SetNestedSlice(rule, paths, "http", "paths"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return changes, nil
+}
+
+// convertIngressBackend turns serviceName and servicePort into a service
+// reference with a port number or name
+func convertIngressBackend(backend map[string]interface{}, field string) (*FieldChange, error) {
+	name, ok := backend["serviceName"].(string)
+	if !ok {
+		return nil, nil
+	}
+	port := make(map[string]interface{})
+	switch value := backend["servicePort"].(type) {
+	case string:
+		port["name"] = value
+	case int64:
+		port["number"] = value
+	case float64:
+		port["number"] = int64(value)
+	case int:
+		port["number"] = int64(value)
+	default:
+		return nil, fmt.Errorf("%s.servicePort is neither a port number nor a name", field)
+	}
+
+	service := map[string]interface{}{"name": name, "port": port}
+	old := map[string]interface{}{"serviceName": name, "servicePort": backend["servicePort"]}
+	delete(backend, "serviceName")
+	delete(backend, "servicePort")
+	backend["service"] = service
+	return &FieldChange{Field: field, OldValue: old, NewValue: map[string]interface{}{"service": service}, Action: "modified"}, nil
+}
+
+// convertWorkload sets the selector apps/v1 requires, which the beta
+// versions derived from the pod template's labels, and keeps the OnDelete
+// update strategy the beta DaemonSets and StatefulSets defaulted to
+func convertWorkload(obj *unstructured.Unstructured) ([]FieldChange, error) {
+	oldVersion := schema.FromAPIVersionAndKind(obj.GetAPIVersion(), obj.GetKind())
+	spec, _ := obj.Object["spec"].(map[string]interface{})
+	if spec == nil {
+		spec = make(map[string]interface{})
+		obj.Object["spec"] = spec
+	}
+
+	var changes []FieldChange
+	if _, ok := spec["selector"]; !ok {
+		labels, _, _ := unstructured.NestedMap(spec, "template", "metadata", "labels")
+		if len(labels) == 0 {
+			return nil, errors.New("it has neither a selector nor pod template labels to derive one from")
+		}
+		selector := map[string]interface{}{"matchLabels": labels}
+		spec["selector"] = selector
+		changes = append(changes, FieldChange{Field: "spec.selector", NewValue: selector, Action: "added"})
+	}
+
+	for _, field := range []string{"rollbackTo", "templateGeneration"} {
+		if value, ok := spec[field]; ok {
+			delete(spec, field)
+			changes = append(changes, FieldChange{Field: "spec." + field, OldValue: value, Action: "removed"})
+		}
+	}
+
+	_, hasStrategy := spec["updateStrategy"]
+	onDeleteDefault := (oldVersion.Group == "extensions" && obj.GetKind() == "DaemonSet") ||
+		(oldVersion.Version == "v1beta1" && obj.GetKind() == "StatefulSet")
+	if onDeleteDefault && !hasStrategy {
+		strategy := map[string]interface{}{"type": "OnDelete"}
+		spec["updateStrategy"] = strategy
+		changes = append(changes, FieldChange{Field: "spec.updateStrategy", NewValue: strategy, Action: "added"})
+	}
+	return changes, nil
+}
+
+// convertHorizontalPodAutoscaler moves the targets of resource and pods
+// metrics into the target form of autoscaling/v2. Object and external
+// metrics are not converted.
+func convertHorizontalPodAutoscaler(obj *unstructured.Unstructured) ([]FieldChange, error) {
+	metrics, _, _ := unstructured.NestedSlice(obj.Object, "spec", "metrics")
+	if len(metrics) == 0 {
+		return nil, nil
+	}
+
+	var changes []FieldChange
+	for i, metric := range metrics {
+		metric, ok := metric.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		field := fmt.Sprintf("spec.metrics[%d]", i)
+		metricType, _ := metric["type"].(string)
+		switch metricType {
+		case "Resource":
+			source, _ := metric["resource"].(map[string]interface{})
+			if source == nil {
+				continue
+			}
+			target := map[string]interface{}{}
+			if utilization, ok := source["targetAverageUtilization"]; ok {
+				target["type"] = "Utilization"
+				target["averageUtilization"] = utilization
+			} else if value, ok := source["targetAverageValue"]; ok {
+				target["type"] = "AverageValue"
+				target["averageValue"] = value
+			}
+			old := copyMap(source)
+			delete(source, "targetAverageUtilization")
+			delete(source, "targetAverageValue")
+			source["target"] = target
+			changes = append(changes, FieldChange{Field: field + ".resource", OldValue: old, NewValue: copyMap(source), Action: "modified"})
+		case "Pods":
+			source, _ := metric["pods"].(map[string]interface{})
+			if source == nil {
+				continue
+			}
+			old := copyMap(source)
+			identifier := map[string]interface{}{"name": source["metricName"]}
+			if selector, ok := source["selector"]; ok {
+				identifier["selector"] = selector
+			}
+			converted := map[string]interface{}{
+				"metric": identifier,
+				"target": map[string]interface{}{"type": "AverageValue", "averageValue": source["targetAverageValue"]},
+			}
+			metric["pods"] = converted
+			changes = append(changes, FieldChange{Field: field + ".pods", OldValue: old, NewValue: converted, Action: "modified"})
+		default:
+			return nil, fmt.Errorf("%s metrics are not converted", metricType)
+		}
+	}
+
+	if err := unstructured.SetNestedSlice(obj.Object, metrics, "spec", "metrics"); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// convertEndpointSlice moves the topology of endpoints to
+// deprecatedTopology, except for the hostname, which becomes nodeName
+func convertEndpointSlice(obj *unstructured.Unstructured) ([]FieldChange, error) {
+	endpoints, _, _ := unstructured.NestedSlice(obj.Object, "endpoints")
+	var changes []FieldChange
+	for i, endpoint := range endpoints {
+		endpoint, ok := endpoint.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		topology, ok := endpoint["topology"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		field := fmt.Sprintf("endpoints[%d]", i)
+		changes = append(changes, FieldChange{Field: field + ".topology", OldValue: copyMap(topology), Action: "removed"})
+		delete(endpoint, "topology")
+		if node, ok := topology[hostnameLabel].(string); ok {
+			delete(topology, hostnameLabel)
+			endpoint["nodeName"] = node
+			changes = append(changes, FieldChange{Field: field + ".nodeName", NewValue: node, Action: "added"})
+		}
+		if len(topology) > 0 {
+			endpoint["deprecatedTopology"] = topology
+			changes = append(changes, FieldChange{Field: field + ".deprecatedTopology", NewValue: topology, Action: "added"})
+		}
+	}
+	if endpoints != nil {
+		if err := unstructured.SetNestedSlice(obj.Object, endpoints, "endpoints"); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+// convertWebhookConfiguration spells out the defaults that changed in
+// admissionregistration.k8s.io/v1, so webhooks keep failing open, matching
+// exactly and receiving v1beta1 reviews. Webhooks with unknown or some side
+// effects cannot be converted, v1 only allows None and NoneOnDryRun.
+func convertWebhookConfiguration(obj *unstructured.Unstructured) ([]FieldChange, error) {
+	webhooks, _, _ := unstructured.NestedSlice(obj.Object, "webhooks")
+	defaults := []struct {
+		field string
+		value interface{}
+	}{
+		{"failurePolicy", "Ignore"},
+		{"matchPolicy", "Exact"},
+		{"timeoutSeconds", int64(30)},
+		{"admissionReviewVersions", []interface{}{"v1beta1"}},
+	}
+
+	var changes []FieldChange
+	for i, webhook := range webhooks {
+		webhook, ok := webhook.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		field := fmt.Sprintf("webhooks[%d]", i)
+		sideEffects, _ := webhook["sideEffects"].(string)
+		if sideEffects != "None" && sideEffects != "NoneOnDryRun" {
+			if sideEffects == "" {
+				sideEffects = "Unknown"
+			}
+			return nil, fmt.Errorf("%s has side effects %s, v1 only allows None and NoneOnDryRun", field, sideEffects)
+		}
+		for _, def := range defaults {
+			if _, ok := webhook[def.field]; !ok {
+				webhook[def.field] = def.value
+				changes = append(changes, FieldChange{Field: field + "." + def.field, NewValue: def.value, Action: "added"})
+			}
+		}
+	}
+	if webhooks != nil {
+		if err := unstructured.SetNestedSlice(obj.Object, webhooks, "webhooks"); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		copied[key] = value
+	}
+	return copied
+}
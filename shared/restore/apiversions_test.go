@@ -0,0 +1,194 @@
+package restore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newConversionTestEngine returns an engine whose target serves policy/v1,
+// networking.k8s.io/v1 and batch/v1beta1 but none of the other beta APIs
+func newConversionTestEngine() *RestoreEngine {
+	engine := newPlanTestEngine()
+	client := fake.NewSimpleClientset()
+	client.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap", Namespaced: true}}},
+		{GroupVersion: "policy/v1", APIResources: []metav1.APIResource{
+			{Name: "poddisruptionbudgets", Kind: "PodDisruptionBudget", Namespaced: true},
+			{Name: "poddisruptionbudgets/status", Kind: "PodDisruptionBudget", Namespaced: true},
+		}},
+		{GroupVersion: "networking.k8s.io/v1", APIResources: []metav1.APIResource{{Name: "ingresses", Kind: "Ingress", Namespaced: true}}},
+		{GroupVersion: "batch/v1beta1", APIResources: []metav1.APIResource{{Name: "cronjobs", Kind: "CronJob", Namespaced: true}}},
+	}
+	engine.k8sClient = client
+	return engine
+}
+
+func TestConvertAPIVersions(t *testing.T) {
+	engine := newConversionTestEngine()
+	operation := checkpointTestOperation(context.Background(), RestoreRequest{RestoreID: "restore-1"})
+
+	resources := engine.convertAPIVersions(operation, []BackupResource{
+		backupResource("v1", "ConfigMap", "shop", "settings", nil),
+		backupResource("policy/v1beta1", "PodDisruptionBudget", "shop", "web", map[string]interface{}{
+			"spec":   map[string]interface{}{"minAvailable": int64(1), "selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "web"}}},
+			"status": map[string]interface{}{"currentHealthy": int64(2)},
+		}),
+		backupResource("policy/v1beta1", "PodDisruptionBudget", "shop", "none", map[string]interface{}{
+			"spec": map[string]interface{}{"minAvailable": int64(1), "selector": map[string]interface{}{}},
+		}),
+		backupResource("policy/v1beta1", "PodSecurityPolicy", "", "restricted", nil),
+		backupResource("batch/v1beta1", "CronJob", "shop", "report", nil),
+		backupResource("autoscaling/v2beta2", "HorizontalPodAutoscaler", "shop", "web", nil),
+	})
+
+	require.Len(t, resources, 3)
+	assert.Equal(t, "ConfigMap", resources[0].Kind)
+	assert.Equal(t, "policy/v1", resources[1].APIVersion)
+	assert.NotContains(t, resources[1].Data, "status")
+	assert.Equal(t, "batch/v1beta1", resources[2].APIVersion, "served versions are not converted")
+	assert.Equal(t, []FieldChange{{Field: "apiVersion", OldValue: "policy/v1beta1", NewValue: "policy/v1", Action: "modified"}},
+		operation.conversionChanges(resources[1]))
+
+	failed := make(map[string]string)
+	for _, resource := range operation.Results.FailedResources {
+		failed[resource.Kind+"/"+resource.Name] = resource.Error
+	}
+	assert.Len(t, failed, 3)
+	assert.Contains(t, failed["PodDisruptionBudget/none"], "empty selector")
+	assert.Contains(t, failed["PodSecurityPolicy/restricted"], "PodSecurityPolicy was removed")
+	assert.Contains(t, failed["HorizontalPodAutoscaler/web"], "cannot convert to autoscaling/v2")
+	assert.Equal(t, 3, operation.Progress.FailedResources)
+}
+
+func TestConvertAPIVersions_Disabled(t *testing.T) {
+	engine := newConversionTestEngine()
+	disabled := false
+	operation := checkpointTestOperation(context.Background(), RestoreRequest{ConvertAPIVersions: &disabled})
+
+	resources := engine.convertAPIVersions(operation, []BackupResource{
+		backupResource("policy/v1beta1", "PodSecurityPolicy", "", "restricted", nil),
+	})
+	assert.Len(t, resources, 1)
+	assert.Empty(t, operation.Results.FailedResources)
+}
+
+func TestRestoreResources_ReportsConversions(t *testing.T) {
+	engine := newConversionTestEngine()
+	operation := checkpointTestOperation(context.Background(), RestoreRequest{RestoreID: "restore-1", ConflictStrategy: ConflictStrategySkip})
+
+	resources := engine.convertAPIVersions(operation, []BackupResource{
+		backupResource("extensions/v1beta1", "Ingress", "shop", "web", map[string]interface{}{
+			"spec": map[string]interface{}{"backend": map[string]interface{}{"serviceName": "web", "servicePort": int64(80)}},
+		}),
+	})
+	require.NoError(t, engine.restoreResources(operation, resources))
+
+	require.Len(t, operation.Results.RestoredResources, 1)
+	restored := operation.Results.RestoredResources[0]
+	assert.Equal(t, "networking.k8s.io/v1", restored.APIVersion)
+	changes := restored.Metadata["transforms"].([]FieldChange)
+	assert.Equal(t, "apiVersion", changes[0].Field)
+
+	ingress, err := engine.dynamicClient.Resource(schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"}).
+		Namespace("shop").Get(context.Background(), "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	service, _, _ := unstructured.NestedMap(ingress.Object, "spec", "defaultBackend", "service")
+	assert.Equal(t, map[string]interface{}{"name": "web", "port": map[string]interface{}{"number": int64(80)}}, service)
+}
+
+func TestConvertIngress(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"rules": []interface{}{
+			map[string]interface{}{"host": "shop.example.com", "http": map[string]interface{}{"paths": []interface{}{
+				map[string]interface{}{"path": "/", "backend": map[string]interface{}{"serviceName": "web", "servicePort": "http"}},
+				map[string]interface{}{"path": "/api", "pathType": "Prefix", "backend": map[string]interface{}{"serviceName": "api", "servicePort": int64(8080)}},
+			}}},
+		}},
+	}}
+
+	changes, err := convertIngress(obj)
+	require.NoError(t, err)
+	assert.Len(t, changes, 3)
+
+	paths, _, _ := unstructured.NestedSlice(obj.Object, "spec", "rules")
+	rule := paths[0].(map[string]interface{})
+	converted, _, _ := unstructured.NestedSlice(rule, "http", "paths")
+	assert.Equal(t, map[string]interface{}{
+		"path":     "/",
+		"pathType": "ImplementationSpecific",
+		"backend":  map[string]interface{}{"service": map[string]interface{}{"name": "web", "port": map[string]interface{}{"name": "http"}}},
+	}, converted[0])
+	assert.Equal(t, int64(8080), converted[1].(map[string]interface{})["backend"].(map[string]interface{})["service"].(map[string]interface{})["port"].(map[string]interface{})["number"])
+}
+
+func TestConvertWorkload(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "extensions/v1beta1",
+		"kind":       "DaemonSet",
+		"spec": map[string]interface{}{
+			"templateGeneration": int64(3),
+			"template":           map[string]interface{}{"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "agent"}}},
+		},
+	}}
+
+	changes, err := convertWorkload(obj)
+	require.NoError(t, err)
+	assert.Len(t, changes, 3)
+	selector, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "selector", "matchLabels")
+	assert.Equal(t, map[string]string{"app": "agent"}, selector)
+	strategy, _, _ := unstructured.NestedString(obj.Object, "spec", "updateStrategy", "type")
+	assert.Equal(t, "OnDelete", strategy)
+	assert.NotContains(t, obj.Object["spec"], "templateGeneration")
+}
+
+func TestConvertHorizontalPodAutoscaler(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"metrics": []interface{}{
+			map[string]interface{}{"type": "Resource", "resource": map[string]interface{}{"name": "cpu", "targetAverageUtilization": int64(70)}},
+			map[string]interface{}{"type": "Pods", "pods": map[string]interface{}{"metricName": "requests", "targetAverageValue": "100"}},
+		}},
+	}}
+
+	_, err := convertHorizontalPodAutoscaler(obj)
+	require.NoError(t, err)
+	metrics, _, _ := unstructured.NestedSlice(obj.Object, "spec", "metrics")
+	assert.Equal(t, map[string]interface{}{
+		"name":   "cpu",
+		"target": map[string]interface{}{"type": "Utilization", "averageUtilization": int64(70)},
+	}, metrics[0].(map[string]interface{})["resource"])
+	assert.Equal(t, map[string]interface{}{
+		"metric": map[string]interface{}{"name": "requests"},
+		"target": map[string]interface{}{"type": "AverageValue", "averageValue": "100"},
+	}, metrics[1].(map[string]interface{})["pods"])
+
+	external := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"metrics": []interface{}{map[string]interface{}{"type": "External"}}},
+	}}
+	_, err = convertHorizontalPodAutoscaler(external)
+	assert.EqualError(t, err, "External metrics are not converted")
+}
+
+func TestConvertWebhookConfiguration(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"webhooks": []interface{}{map[string]interface{}{"name": "policy.example.com", "sideEffects": "None", "failurePolicy": "Fail"}},
+	}}
+	changes, err := convertWebhookConfiguration(obj)
+	require.NoError(t, err)
+	assert.Len(t, changes, 3, "the failure policy set is kept")
+	webhooks, _, _ := unstructured.NestedSlice(obj.Object, "webhooks")
+	assert.Equal(t, []interface{}{"v1beta1"}, webhooks[0].(map[string]interface{})["admissionReviewVersions"])
+
+	unknown := &unstructured.Unstructured{Object: map[string]interface{}{
+		"webhooks": []interface{}{map[string]interface{}{"name": "audit.example.com"}},
+	}}
+	_, err = convertWebhookConfiguration(unknown)
+	assert.EqualError(t, err, "webhooks[0] has side effects Unknown, v1 only allows None and NoneOnDryRun")
+}
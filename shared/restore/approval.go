@@ -0,0 +1,45 @@
+package restore
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrApprovalRequired is returned by StartRestore for a restore that needs a
+// second person's approval and carries none
+var ErrApprovalRequired = errors.New("restore requires the approval of someone other than its requester")
+
+// RestoreApproval records who requested a restore and who approved it, as
+// the identities of the callers of the service that took the request
+type RestoreApproval struct {
+	RequestedBy string    `json:"requested_by"`
+	ApprovedBy  string    `json:"approved_by"`
+	ApprovedAt  time.Time `json:"approved_at"`
+}
+
+// RequireApproval makes the engine start restores, dry runs aside, only when
+// the request carries the approval of someone other than its requester, as
+// for clusters labelled production. Services taking restore requests gather
+// the approval; the engine refuses restores that skipped it.
+func (re *RestoreEngine) RequireApproval(required bool) {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	re.approvalRequired = required
+}
+
+// checkApproval returns ErrApprovalRequired for a request the engine needs
+// approval for that has none, or that its requester approved
+func (re *RestoreEngine) checkApproval(request RestoreRequest) error {
+	if !re.approvalRequired || request.DryRun {
+		return nil
+	}
+	approval := request.Approval
+	if approval == nil || approval.RequestedBy == "" || approval.ApprovedBy == "" {
+		return ErrApprovalRequired
+	}
+	if approval.ApprovedBy == approval.RequestedBy {
+		return fmt.Errorf("%w: %s approved their own restore", ErrApprovalRequired, approval.ApprovedBy)
+	}
+	return nil
+}
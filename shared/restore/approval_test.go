@@ -0,0 +1,28 @@
+package restore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireApproval(t *testing.T) {
+	engine := &RestoreEngine{}
+	request := RestoreRequest{RestoreID: "restore-1", BackupID: "backup-1", ClusterName: "prod"}
+	assert.NoError(t, engine.checkApproval(request), "approval is not required by default")
+
+	engine.RequireApproval(true)
+	_, err := engine.StartRestore(context.Background(), request)
+	assert.True(t, errors.Is(err, ErrApprovalRequired), "restores without approval do not start")
+
+	request.Approval = &RestoreApproval{RequestedBy: "oidc:alice", ApprovedBy: "oidc:alice", ApprovedAt: time.Now()}
+	_, err = engine.StartRestore(context.Background(), request)
+	assert.True(t, errors.Is(err, ErrApprovalRequired), "requesters cannot approve their own restores")
+
+	request.Approval.ApprovedBy = "oidc:bob"
+	assert.NoError(t, engine.checkApproval(request))
+	assert.NoError(t, engine.checkApproval(RestoreRequest{BackupID: "backup-1", DryRun: true}), "dry runs need no approval")
+}
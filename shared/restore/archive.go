@@ -0,0 +1,237 @@
+package restore
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+
+	"shared-config/signing"
+)
+
+// manifestDir is the directory of each cluster's run manifests within the
+// bucket, and within backup exports, which keep its layout
+const manifestDir = "_manifests"
+
+// runManifest is the part of a run manifest restores read
+type runManifest struct {
+	BackupID      string           `json:"backup_id"`
+	ClusterName   string           `json:"cluster_name"`
+	ClusterDomain string           `json:"cluster_domain"`
+	Objects       []manifestObject `json:"objects"`
+	CRDs          []manifestObject `json:"crds,omitempty"`
+	OpenShift     []openShiftEntry `json:"openshift,omitempty"`
+}
+
+// manifestObject is a resource or CRD a run manifest lists
+type manifestObject struct {
+	Namespace    string `json:"namespace,omitempty"`
+	Group        string `json:"group,omitempty"`
+	Version      string `json:"version,omitempty"`
+	ResourceType string `json:"resource_type,omitempty"`
+	Name         string `json:"name"`
+	// Bucket is set for resources kept in a tenant's bucket, which exports
+	// leave out
+	Bucket   string `json:"bucket,omitempty"`
+	Key      string `json:"key"`
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// LoadExport reads the CRDs, resources and OpenShift documents of run
+// backupID from path, a directory or .tar.gz written by backup-util export,
+// which keeps the layout of the bucket. With a verifier, the manifest's
+// signature is checked before anything else is read. Content whose SHA-256
+// differs from the manifest is rejected. Resources the manifest lists in
+// tenants' buckets are not in exports and are returned as skipped.
+func LoadExport(path, backupID string, verifier *signing.Verifier) (*BackupContent, error) {
+	manifests, err := readExport(path, func(key string) bool {
+		return isExportManifest(key, backupID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(manifests) == 0 {
+		return nil, fmt.Errorf("backup %s is not in export %s", backupID, path)
+	}
+	if len(manifests) > 1 {
+		return nil, fmt.Errorf("export %s holds backup %s of more than one cluster", path, backupID)
+	}
+	var manifest *runManifest
+	for key, data := range manifests {
+		if verifier != nil {
+			signatures, err := readExport(path, func(name string) bool { return name == key+signing.SignatureSuffix })
+			if err != nil {
+				return nil, err
+			}
+			if err := verifyManifest(verifier, key, data, signatures[key+signing.SignatureSuffix]); err != nil {
+				return nil, err
+			}
+		}
+		if manifest, err = parseManifest(key, data); err != nil {
+			return nil, err
+		}
+	}
+
+	var objects []manifestObject
+	var skipped []SkippedResource
+	for _, object := range append(manifest.CRDs, manifest.Objects...) {
+		if object.Bucket != "" {
+			skipped = append(skipped, SkippedResource{
+				APIVersion: objectAPIVersion(object),
+				Namespace:  object.Namespace,
+				Name:       object.Name,
+				Reason:     fmt.Sprintf("stored in tenant bucket %s, which exports leave out", object.Bucket),
+				Metadata:   map[string]interface{}{"resource_type": object.ResourceType},
+			})
+			continue
+		}
+		objects = append(objects, object)
+	}
+
+	wanted := make(map[string]bool, len(objects)+len(manifest.OpenShift))
+	for _, object := range objects {
+		wanted[object.Key] = true
+	}
+	for _, entry := range manifest.OpenShift {
+		wanted[entry.Key] = true
+	}
+	files, err := readExport(path, func(key string) bool { return wanted[key] })
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := loadContent(manifest, objects, func(object manifestObject) ([]byte, error) {
+		data, ok := files[object.Key]
+		if !ok {
+			return nil, fmt.Errorf("export %s is missing %s", path, object.Key)
+		}
+		return data, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	content.Skipped = skipped
+	return content, nil
+}
+
+// isExportManifest reports whether key is the manifest of run backupID of any
+// cluster: {domain}/{cluster-name}/_manifests/{backup-id}.json
+func isExportManifest(key, backupID string) bool {
+	parts := strings.Split(key, "/")
+	return len(parts) == 4 && parts[2] == manifestDir && parts[3] == backupID+".json"
+}
+
+// objectAPIVersion returns the apiVersion of a manifest entry's type
+func objectAPIVersion(object manifestObject) string {
+	if object.Group == "" {
+		return object.Version
+	}
+	return object.Group + "/" + object.Version
+}
+
+// parseExportObject parses a stored resource, YAML or JSON
+func parseExportObject(key string, data []byte) (BackupResource, error) {
+	object := map[string]interface{}{}
+	decoder := k8syaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	if err := decoder.Decode(&object); err != nil {
+		return BackupResource{}, fmt.Errorf("failed to parse %s: %v", key, err)
+	}
+
+	resource := BackupResource{Data: object}
+	resource.APIVersion, _ = object["apiVersion"].(string)
+	resource.Kind, _ = object["kind"].(string)
+	if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+		resource.Namespace, _ = metadata["namespace"].(string)
+		resource.Name, _ = metadata["name"].(string)
+	}
+	if resource.Kind == "" || resource.Name == "" {
+		return BackupResource{}, fmt.Errorf("%s is not a Kubernetes resource: kind or metadata.name is missing", key)
+	}
+	return resource, nil
+}
+
+// readExport returns the content of the files of the export at path whose
+// keys want accepts. Keys are slash-separated paths relative to the export's
+// root, as in the bucket.
+func readExport(path string, want func(key string) bool) (map[string][]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open export: %v", err)
+	}
+	if info.IsDir() {
+		return readExportDir(path, want)
+	}
+	return readExportArchive(path, want)
+}
+
+func readExportDir(dir string, want func(key string) bool) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !want(key) {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[key] = data
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export %s: %v", dir, err)
+	}
+	return files, nil
+}
+
+func readExportArchive(path string, want func(key string) bool) (map[string][]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open export: %v", err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export %s: %v", path, err)
+	}
+	defer gz.Close()
+
+	files := make(map[string][]byte)
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read export %s: %v", path, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		key := strings.TrimPrefix(header.Name, "./")
+		if !want(key) {
+			continue
+		}
+		data, err := io.ReadAll(archive)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from export %s: %v", key, path, err)
+		}
+		files[key] = data
+	}
+}
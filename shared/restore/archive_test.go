@@ -0,0 +1,109 @@
+package restore
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const exportedConfigMap = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: shop
+data:
+  mode: live
+`
+
+// exportFiles returns the files of an export of run backup-1 holding one
+// ConfigMap, and one Secret kept in a tenant's bucket
+func exportFiles(t *testing.T, checksum string) map[string][]byte {
+	manifest, err := json.Marshal(runManifest{
+		BackupID:      "backup-1",
+		ClusterName:   "prod",
+		ClusterDomain: "cluster.local",
+		Objects: []manifestObject{
+			{Namespace: "shop", Version: "v1", ResourceType: "configmaps", Name: "settings",
+				Key: "cluster.local/prod/shop/configmaps/settings.yaml", Checksum: checksum},
+			{Namespace: "billing", Version: "v1", ResourceType: "secrets", Name: "token",
+				Bucket: "tenant-billing", Key: "cluster.local/prod/billing/secrets/token.yaml"},
+		},
+	})
+	require.NoError(t, err)
+	return map[string][]byte{
+		"cluster.local/prod/_manifests/backup-1.json":      manifest,
+		"cluster.local/prod/shop/configmaps/settings.yaml": []byte(exportedConfigMap),
+	}
+}
+
+func writeExportDir(t *testing.T, files map[string][]byte) string {
+	dir := t.TempDir()
+	for key, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(key))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+	}
+	return dir
+}
+
+func writeExportArchive(t *testing.T, files map[string][]byte) string {
+	path := filepath.Join(t.TempDir(), "backup-1.tar.gz")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	archive := tar.NewWriter(gz)
+	for key, data := range files {
+		require.NoError(t, archive.WriteHeader(&tar.Header{Name: key, Mode: 0o644, Size: int64(len(data)), Typeflag: tar.TypeReg}))
+		_, err := archive.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, archive.Close())
+	require.NoError(t, gz.Close())
+	return path
+}
+
+func TestLoadExport(t *testing.T) {
+	sum := sha256.Sum256([]byte(exportedConfigMap))
+	files := exportFiles(t, hex.EncodeToString(sum[:]))
+
+	for name, path := range map[string]string{
+		"directory": writeExportDir(t, files),
+		"tarball":   writeExportArchive(t, files),
+	} {
+		content, err := LoadExport(path, "backup-1", nil)
+		require.NoError(t, err, name)
+		resources, skipped := content.Resources, content.Skipped
+		require.Len(t, resources, 1, name)
+		assert.Equal(t, "ConfigMap", resources[0].Kind)
+		assert.Equal(t, "shop", resources[0].Namespace)
+		assert.Equal(t, "settings", resources[0].Name)
+		assert.Equal(t, map[string]interface{}{"mode": "live"}, resources[0].Data["data"])
+
+		require.Len(t, skipped, 1, name)
+		assert.Equal(t, "token", skipped[0].Name)
+		assert.Contains(t, skipped[0].Reason, "tenant-billing")
+	}
+
+	_, err := LoadExport(writeExportDir(t, files), "backup-2", nil)
+	assert.ErrorContains(t, err, "backup backup-2 is not in export")
+}
+
+func TestLoadExport_Rejected(t *testing.T) {
+	files := exportFiles(t, "0000")
+	_, err := LoadExport(writeExportArchive(t, files), "backup-1", nil)
+	assert.True(t, errors.Is(err, ErrChecksumMismatch), "content differing from the manifest is rejected")
+
+	delete(files, "cluster.local/prod/shop/configmaps/settings.yaml")
+	_, err = LoadExport(writeExportDir(t, files), "backup-1", nil)
+	assert.ErrorContains(t, err, "is missing cluster.local/prod/shop/configmaps/settings.yaml")
+}
@@ -0,0 +1,134 @@
+package restore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	sharedconfig "shared-config/config"
+	"shared-config/signing"
+)
+
+// ObjectStore reads the objects of backups from object storage
+type ObjectStore interface {
+	// GetObject returns the content of key in bucket and the checksum
+	// recorded in its ChecksumMetadata, empty for objects without one
+	GetObject(ctx context.Context, bucket, key string) ([]byte, string, error)
+}
+
+// MinIOStore reads backup objects with a MinIO client
+type MinIOStore struct {
+	client *minio.Client
+}
+
+// NewMinIOStore returns a store reading objects with client
+func NewMinIOStore(client *minio.Client) *MinIOStore {
+	return &MinIOStore{client: client}
+}
+
+// newMinIOStoreFromConfig connects to the configured storage with its static
+// keys
+func newMinIOStoreFromConfig(storage sharedconfig.StorageConfig) (*MinIOStore, error) {
+	client, err := minio.New(storage.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(storage.AccessKey, storage.SecretKey, ""),
+		Secure: storage.UseSSL,
+		Region: storage.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MinIO client: %v", err)
+	}
+	return NewMinIOStore(client), nil
+}
+
+// GetObject implements ObjectStore
+func (s *MinIOStore) GetObject(ctx context.Context, bucket, key string) ([]byte, string, error) {
+	object, err := s.client.GetObject(ctx, bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return nil, "", err
+	}
+	info, err := object.Stat()
+	if err != nil {
+		return nil, "", err
+	}
+	return data, info.UserMetadata[ChecksumMetadata], nil
+}
+
+// SetObjectStore replaces where the engine reads backups from, in place of
+// the client it creates for the configured storage
+func (re *RestoreEngine) SetObjectStore(store ObjectStore) {
+	re.objects = store
+}
+
+// manifestKey returns the key of the manifest of run backupID of a cluster:
+// {domain}/{cluster-name}/_manifests/{backup-id}.json
+func manifestKey(domain, clusterName, backupID string) string {
+	return fmt.Sprintf("%s/%s/%s/%s.json", domain, clusterName, manifestDir, backupID)
+}
+
+// LoadBucket reads the resources of run backupID of cluster clusterName in
+// domain from bucket, preceded by the CRDs the manifest lists under crds,
+// stored in {domain}/{cluster-name}/_crds/, and its OpenShift documents. With a verifier, the manifest's
+// signature is checked before anything else is read. Objects whose SHA-256
+// differs from the manifest or from their checksum metadata are rejected.
+// Resources the manifest lists in a tenant's bucket are read from it.
+func LoadBucket(ctx context.Context, store ObjectStore, bucket, domain, clusterName, backupID string, verifier *signing.Verifier) (*BackupContent, error) {
+	key := manifestKey(domain, clusterName, backupID)
+	data, err := getObject(ctx, store, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	if verifier != nil {
+		document, err := getObject(ctx, store, bucket, key+signing.SignatureSuffix)
+		if err != nil {
+			return nil, fmt.Errorf("manifest %s: %w: %v", key, signing.ErrInvalidSignature, err)
+		}
+		if err := verifyManifest(verifier, key, data, document); err != nil {
+			return nil, err
+		}
+	}
+	manifest, err := parseManifest(key, data)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := append(append([]manifestObject{}, manifest.CRDs...), manifest.Objects...)
+	return loadContent(manifest, objects, func(object manifestObject) ([]byte, error) {
+		objectBucket := bucket
+		if object.Bucket != "" {
+			objectBucket = object.Bucket
+		}
+		return getObject(ctx, store, objectBucket, object.Key)
+	})
+}
+
+// parseManifest parses the run manifest read from key
+func parseManifest(key string, data []byte) (*runManifest, error) {
+	manifest := &runManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %v", key, err)
+	}
+	return manifest, nil
+}
+
+// getObject downloads key, rejecting content that does not match its checksum
+// metadata
+func getObject(ctx context.Context, store ObjectStore, bucket, key string) ([]byte, error) {
+	data, checksum, err := store.GetObject(ctx, bucket, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from bucket %s: %v", key, bucket, err)
+	}
+	if err := VerifyChecksum(key, data, checksum); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
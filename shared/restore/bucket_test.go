@@ -0,0 +1,273 @@
+package restore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	sharedconfig "shared-config/config"
+)
+
+// memoryStore is an ObjectStore holding objects by bucket and key
+type memoryStore map[string]map[string]storedObject
+
+type storedObject struct {
+	data     []byte
+	checksum string
+}
+
+func (s memoryStore) GetObject(ctx context.Context, bucket, key string) ([]byte, string, error) {
+	object, ok := s[bucket][key]
+	if !ok {
+		return nil, "", fmt.Errorf("no such key")
+	}
+	return object.data, object.checksum, nil
+}
+
+func (s memoryStore) put(bucket, key string, data []byte) {
+	if s[bucket] == nil {
+		s[bucket] = make(map[string]storedObject)
+	}
+	s[bucket][key] = storedObject{data: data, checksum: checksumOf(data)}
+}
+
+func checksumOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+const storedSecret = `apiVersion: v1
+kind: Secret
+metadata:
+  name: token
+  namespace: billing
+`
+
+// bucketStore returns a bucket holding run backup-1 of cluster prod, with a
+// ConfigMap and a Secret kept in a tenant's bucket
+func bucketStore(t *testing.T) memoryStore {
+	store := memoryStore{}
+	manifest, err := json.Marshal(runManifest{
+		BackupID:      "backup-1",
+		ClusterName:   "prod",
+		ClusterDomain: "cluster.local",
+		Objects: []manifestObject{
+			{Namespace: "shop", Version: "v1", ResourceType: "configmaps", Name: "settings",
+				Key: "cluster.local/prod/shop/configmaps/settings.yaml", Checksum: checksumOf([]byte(exportedConfigMap))},
+			{Namespace: "billing", Version: "v1", ResourceType: "secrets", Name: "token",
+				Bucket: "tenant-billing", Key: "cluster.local/prod/billing/secrets/token.yaml", Checksum: checksumOf([]byte(storedSecret))},
+		},
+	})
+	require.NoError(t, err)
+	store.put("backups", "cluster.local/prod/_manifests/backup-1.json", manifest)
+	store.put("backups", "cluster.local/prod/shop/configmaps/settings.yaml", []byte(exportedConfigMap))
+	store.put("tenant-billing", "cluster.local/prod/billing/secrets/token.yaml", []byte(storedSecret))
+	return store
+}
+
+func TestLoadBucket(t *testing.T) {
+	ctx := context.Background()
+	content, err := LoadBucket(ctx, bucketStore(t), "backups", "cluster.local", "prod", "backup-1", nil)
+	require.NoError(t, err)
+	resources := content.Resources
+	require.Len(t, resources, 2)
+	assert.Equal(t, "settings", resources[0].Name)
+	assert.Equal(t, map[string]interface{}{"mode": "live"}, resources[0].Data["data"])
+	assert.Equal(t, "token", resources[1].Name, "read from the tenant's bucket")
+
+	_, err = LoadBucket(ctx, bucketStore(t), "backups", "cluster.local", "prod", "backup-2", nil)
+	assert.ErrorContains(t, err, "cluster.local/prod/_manifests/backup-2.json")
+}
+
+const storedCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+`
+
+func TestLoadBucket_CRDs(t *testing.T) {
+	store := bucketStore(t)
+	manifestKey := "cluster.local/prod/_manifests/backup-1.json"
+	manifest := runManifest{}
+	require.NoError(t, json.Unmarshal(store["backups"][manifestKey].data, &manifest))
+	manifest.CRDs = []manifestObject{{Group: "example.com", Name: "widgets.example.com",
+		Key: "cluster.local/prod/_crds/widgets.example.com.yaml", Checksum: checksumOf([]byte(storedCRD))}}
+	data, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	store.put("backups", manifestKey, data)
+	store.put("backups", "cluster.local/prod/_crds/widgets.example.com.yaml", []byte(storedCRD))
+
+	content, err := LoadBucket(context.Background(), store, "backups", "cluster.local", "prod", "backup-1", nil)
+	require.NoError(t, err)
+	resources := content.Resources
+	require.Len(t, resources, 3)
+	assert.Equal(t, "CustomResourceDefinition", resources[0].Kind, "CRDs come before the resources")
+	assert.Equal(t, "widgets.example.com", resources[0].Name)
+
+	delete(store["backups"], "cluster.local/prod/_crds/widgets.example.com.yaml")
+	_, err = LoadBucket(context.Background(), store, "backups", "cluster.local", "prod", "backup-1", nil)
+	assert.ErrorContains(t, err, "_crds/widgets.example.com.yaml")
+}
+
+func TestLoadBucket_Rejected(t *testing.T) {
+	ctx := context.Background()
+	key := "cluster.local/prod/shop/configmaps/settings.yaml"
+	corrupted := []byte(exportedConfigMap + "  extra: value\n")
+
+	// Content differing from the manifest, with metadata matching it
+	store := bucketStore(t)
+	store.put("backups", key, corrupted)
+	_, err := LoadBucket(ctx, store, "backups", "cluster.local", "prod", "backup-1", nil)
+	assert.True(t, errors.Is(err, ErrChecksumMismatch), "content differing from the manifest is rejected")
+
+	// Content differing from the object's metadata
+	store = bucketStore(t)
+	store["backups"][key] = storedObject{data: []byte(exportedConfigMap), checksum: checksumOf(corrupted)}
+	_, err = LoadBucket(ctx, store, "backups", "cluster.local", "prod", "backup-1", nil)
+	assert.True(t, errors.Is(err, ErrChecksumMismatch), "content differing from its metadata is rejected")
+}
+
+func TestLoadBackupData(t *testing.T) {
+	ctx := context.Background()
+	config := &sharedconfig.SharedConfig{}
+	config.Storage.Bucket = "backups"
+	config.Cluster.Domain = "cluster.local"
+	engine := &RestoreEngine{config: config, objects: bucketStore(t)}
+
+	operation := checkpointTestOperation(ctx, RestoreRequest{BackupID: "backup-1", ClusterName: "prod"})
+	resources, err := engine.loadBackupData(operation)
+	require.NoError(t, err)
+	assert.Len(t, resources, 2)
+	assert.Equal(t, 2, operation.Progress.TotalResources)
+
+	// Only the selected namespaces are restored
+	operation = checkpointTestOperation(ctx, RestoreRequest{BackupID: "backup-1", ClusterName: "prod", TargetNamespaces: []string{"shop"}})
+	resources, err = engine.loadBackupData(operation)
+	require.NoError(t, err)
+	require.Len(t, resources, 1)
+	assert.Equal(t, "settings", resources[0].Name)
+
+	engine.objects = nil
+	_, err = engine.loadBackupData(checkpointTestOperation(ctx, RestoreRequest{BackupID: "backup-1", ClusterName: "prod"}))
+	assert.ErrorContains(t, err, "no object storage is configured")
+}
+
+func TestFilterResources(t *testing.T) {
+	resources := []BackupResource{
+		backupResource("v1", "Namespace", "", "shop", nil),
+		backupResource("v1", "Namespace", "", "billing", nil),
+		backupResource("v1", "ConfigMap", "shop", "settings", map[string]interface{}{"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "web"}}}),
+		backupResource("apps/v1", "Deployment", "shop", "web", map[string]interface{}{"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "web"}}}),
+		backupResource("v1", "ConfigMap", "billing", "rates", nil),
+		backupResource("rbac.authorization.k8s.io/v1", "ClusterRole", "", "reader", nil),
+	}
+	names := func(resources []BackupResource) []string {
+		var names []string
+		for _, resource := range resources {
+			names = append(names, resource.Name)
+		}
+		return names
+	}
+
+	selected, err := filterResources(RestoreRequest{}, resources)
+	require.NoError(t, err)
+	assert.Equal(t, resources, selected)
+
+	selected, err = filterResources(RestoreRequest{TargetNamespaces: []string{"shop"}}, resources)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"shop", "settings", "web", "reader"}, names(selected))
+
+	selected, err = filterResources(RestoreRequest{ResourceTypes: []string{"configmaps", "Deployment"}}, resources)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"settings", "web", "rates"}, names(selected))
+
+	selected, err = filterResources(RestoreRequest{LabelSelector: "app=web"}, resources)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"settings", "web"}, names(selected))
+
+	_, err = filterResources(RestoreRequest{LabelSelector: "app in (web"}, resources)
+	assert.ErrorContains(t, err, "invalid label selector")
+}
+
+func TestLoadBackupData_OpenShift(t *testing.T) {
+	ctx := context.Background()
+	pod := `apiVersion: v1
+kind: Pod
+metadata:
+  name: report
+  namespace: shop
+spec:
+  containers:
+  - name: report
+    image: image-registry.openshift-image-registry.svc:5000/shop/report
+`
+	tags := "imageStream: report\nnamespace: shop\ntags:\n- tag: latest\n  dockerImageReference: quay.io/shop/report@sha256:5d5d\n"
+	routeSecret := `apiVersion: v1
+kind: Secret
+type: kubernetes.io/tls
+metadata:
+  name: web-tls
+  namespace: shop
+  annotations:
+    backup.cluster/route: web
+`
+	store := memoryStore{}
+	manifest, err := json.Marshal(runManifest{
+		BackupID:    "backup-1",
+		ClusterName: "prod",
+		Objects: []manifestObject{{Namespace: "shop", Version: "v1", ResourceType: "pods", Name: "report",
+			Key: "cluster.local/prod/shop/pods/report.yaml", Checksum: checksumOf([]byte(pod))}},
+		OpenShift: []openShiftEntry{
+			{Namespace: "shop", Type: openShiftImageStreamTags, Name: "report",
+				Key: "cluster.local/prod/_openshift/shop/imagestream-tags/report.yaml", Checksum: checksumOf([]byte(tags))},
+			{Namespace: "shop", Type: openShiftRouteTLS, Name: "web",
+				Key: "cluster.local/prod/_openshift/shop/route-tls/web.yaml", Checksum: checksumOf([]byte(routeSecret))},
+			{Namespace: "shop", Type: "projects", Name: "shop", Key: "cluster.local/prod/_openshift/shop/projects/shop.yaml"},
+		},
+	})
+	require.NoError(t, err)
+	store.put("backups", "cluster.local/prod/_manifests/backup-1.json", manifest)
+	store.put("backups", "cluster.local/prod/shop/pods/report.yaml", []byte(pod))
+	store.put("backups", "cluster.local/prod/_openshift/shop/imagestream-tags/report.yaml", []byte(tags))
+	store.put("backups", "cluster.local/prod/_openshift/shop/route-tls/web.yaml", []byte(routeSecret))
+
+	config := &sharedconfig.SharedConfig{}
+	config.Storage.Bucket = "backups"
+	config.Cluster.Domain = "cluster.local"
+	engine := &RestoreEngine{config: config, objects: store}
+
+	content, err := LoadBucket(ctx, store, "backups", "cluster.local", "prod", "backup-1", nil)
+	require.NoError(t, err)
+	require.Len(t, content.ImageStreamTags, 1, "projects are not read")
+	assert.Equal(t, "quay.io/shop/report@sha256:5d5d", content.ImageStreamTags[0].Tags[0].DockerImageReference)
+	require.Len(t, content.RouteSecrets, 1)
+
+	transforms := &TransformConfig{ConvertOpenShift: true}
+	operation := checkpointTestOperation(ctx, RestoreRequest{BackupID: "backup-1", ClusterName: "prod", Transforms: transforms})
+	operation.transformer = NewTransformEngine(*transforms)
+	resources, err := engine.loadBackupData(operation)
+	require.NoError(t, err)
+	require.Len(t, resources, 2)
+	assert.Equal(t, "web-tls", resources[1].Name, "the Route's certificate is restored")
+
+	obj := backupObject(resources[0])
+	_, err = operation.transformer.Apply(obj)
+	require.NoError(t, err)
+	containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "containers")
+	assert.Equal(t, "quay.io/shop/report@sha256:5d5d", containers[0].(map[string]interface{})["image"], "pinned to the captured tag")
+
+	// Without the conversion the certificates are left out
+	resources, err = engine.loadBackupData(checkpointTestOperation(ctx, RestoreRequest{BackupID: "backup-1", ClusterName: "prod"}))
+	require.NoError(t, err)
+	assert.Len(t, resources, 1)
+}
@@ -0,0 +1,346 @@
+package restore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// RestoreIDAnnotation records on every object a restore applies the
+	// restore that applied it, so an attempt resumed after an interruption
+	// recognises what the previous attempt applied after its last checkpoint
+	RestoreIDAnnotation = "cluster-backup.io/restore-id"
+
+	// checkpointBatch is the number of resources applied between checkpoints.
+	// Resources applied after the last one are recognised by their annotation.
+	checkpointBatch = 20
+
+	// checkpointSaveTimeout bounds saving a checkpoint, which also happens
+	// after the restore was cancelled
+	checkpointSaveTimeout = 30 * time.Second
+
+	checkpointDataKey       = "checkpoint.json"
+	serviceAccountNamespace = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// errRestoredEarlier reports a resource an earlier attempt of the same
+// restore applied
+var errRestoredEarlier = errors.New("already restored by an earlier attempt of this restore")
+
+// RestoreCheckpoint is the progress of a restore, kept so running the same
+// restore_id again resumes it: resources already applied are skipped and
+// only pending and failed ones are applied
+type RestoreCheckpoint struct {
+	RestoreID   string `json:"restore_id"`
+	BackupID    string `json:"backup_id"`
+	ClusterName string `json:"cluster_name"`
+	// RequestHash identifies the request the restore was started with; a
+	// different request cannot resume it
+	RequestHash string `json:"request_hash"`
+	Attempt     int    `json:"attempt"`
+	// Applied lists the resources applied, by checkpointKey
+	Applied []string `json:"applied"`
+	// Failed maps the resources whose last attempt failed to the error
+	Failed    map[string]string `json:"failed,omitempty"`
+	UpdatedAt time.Time         `json:"updated_at"`
+
+	applied map[string]bool
+}
+
+// CheckpointStore persists restore checkpoints
+type CheckpointStore interface {
+	// Load returns the checkpoint of a restore, or nil when it has none
+	Load(ctx context.Context, restoreID string) (*RestoreCheckpoint, error)
+	Save(ctx context.Context, checkpoint *RestoreCheckpoint) error
+	Delete(ctx context.Context, restoreID string) error
+}
+
+func newRestoreCheckpoint(request RestoreRequest) *RestoreCheckpoint {
+	return &RestoreCheckpoint{
+		RestoreID:   request.RestoreID,
+		BackupID:    request.BackupID,
+		ClusterName: request.ClusterName,
+		RequestHash: requestHash(request),
+		Failed:      make(map[string]string),
+		applied:     make(map[string]bool),
+	}
+}
+
+// IsApplied reports whether an earlier attempt applied a resource
+func (c *RestoreCheckpoint) IsApplied(resource BackupResource) bool {
+	return c.applied[checkpointKey(resource)]
+}
+
+// MarkApplied records a resource as applied
+func (c *RestoreCheckpoint) MarkApplied(resource BackupResource) {
+	key := checkpointKey(resource)
+	delete(c.Failed, key)
+	if !c.applied[key] {
+		c.applied[key] = true
+		c.Applied = append(c.Applied, key)
+	}
+}
+
+// MarkFailed records a resource as failed, so it is applied again on resume
+func (c *RestoreCheckpoint) MarkFailed(resource BackupResource, err string) {
+	c.Failed[checkpointKey(resource)] = err
+}
+
+// index rebuilds the lookup of applied resources after loading
+func (c *RestoreCheckpoint) index() {
+	c.applied = make(map[string]bool, len(c.Applied))
+	for _, key := range c.Applied {
+		c.applied[key] = true
+	}
+	if c.Failed == nil {
+		c.Failed = make(map[string]string)
+	}
+}
+
+// checkpointKey identifies a resource of a backup across attempts
+func checkpointKey(resource BackupResource) string {
+	return strings.Join([]string{resource.APIVersion, resource.Kind, resource.Namespace, resource.Name}, "/")
+}
+
+// requestHash hashes what a restore applies: the backup, the resources
+// selected, the conflict strategy and the transformations. How the restore is
+// validated and waited for can change between attempts.
+func requestHash(request RestoreRequest) string {
+	request.RestoreID = ""
+	request.ValidationMode = ""
+	request.ServerDryRun = false
+	request.WaitForReady = nil
+	request.Metadata = nil
+	data, _ := json.Marshal(request)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ConfigMapCheckpointStore keeps each restore checkpoint in a ConfigMap of
+// the target cluster, which outlives the restore engine's pod
+type ConfigMapCheckpointStore struct {
+	client    kubernetes.Interface
+	namespace string
+}
+
+// NewConfigMapCheckpointStore stores checkpoints in namespace
+func NewConfigMapCheckpointStore(client kubernetes.Interface, namespace string) *ConfigMapCheckpointStore {
+	return &ConfigMapCheckpointStore{client: client, namespace: namespace}
+}
+
+// checkpointNamespace returns the namespace the engine runs in, or default
+// outside a cluster
+func checkpointNamespace() string {
+	if data, err := os.ReadFile(serviceAccountNamespace); err == nil {
+		if namespace := strings.TrimSpace(string(data)); namespace != "" {
+			return namespace
+		}
+	}
+	return "default"
+}
+
+// checkpointName returns the ConfigMap name of a restore. restore_id is
+// hashed as it need not be a valid object name.
+func checkpointName(restoreID string) string {
+	sum := sha256.Sum256([]byte(restoreID))
+	return "restore-checkpoint-" + hex.EncodeToString(sum[:8])
+}
+
+// Load returns the checkpoint of a restore, or nil when it has none
+func (s *ConfigMapCheckpointStore) Load(ctx context.Context, restoreID string) (*RestoreCheckpoint, error) {
+	configMap, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, checkpointName(restoreID), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint of restore %s: %v", restoreID, err)
+	}
+
+	checkpoint := &RestoreCheckpoint{}
+	if err := json.Unmarshal([]byte(configMap.Data[checkpointDataKey]), checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint of restore %s: %v", restoreID, err)
+	}
+	if checkpoint.RestoreID != restoreID {
+		return nil, fmt.Errorf("checkpoint %s/%s belongs to restore %s", s.namespace, configMap.Name, checkpoint.RestoreID)
+	}
+	checkpoint.index()
+	return checkpoint, nil
+}
+
+// Save creates or replaces the checkpoint of a restore
+func (s *ConfigMapCheckpointStore) Save(ctx context.Context, checkpoint *RestoreCheckpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to serialize checkpoint: %v", err)
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      checkpointName(checkpoint.RestoreID),
+			Namespace: s.namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "cluster-backup",
+				"app.kubernetes.io/component":  "restore-checkpoint",
+			},
+			Annotations: map[string]string{RestoreIDAnnotation: checkpoint.RestoreID},
+		},
+		Data: map[string]string{checkpointDataKey: string(data)},
+	}
+
+	configMaps := s.client.CoreV1().ConfigMaps(s.namespace)
+	_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint of restore %s: %v", checkpoint.RestoreID, err)
+	}
+	return nil
+}
+
+// Delete removes the checkpoint of a restore
+func (s *ConfigMapCheckpointStore) Delete(ctx context.Context, restoreID string) error {
+	err := s.client.CoreV1().ConfigMaps(s.namespace).Delete(ctx, checkpointName(restoreID), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete checkpoint of restore %s: %v", restoreID, err)
+	}
+	return nil
+}
+
+// SetCheckpointStore replaces where restore checkpoints are kept
+func (re *RestoreEngine) SetCheckpointStore(store CheckpointStore) {
+	re.checkpoints = store
+}
+
+// resumeCheckpoint loads the checkpoint of the operation's restore_id, or
+// starts one. A checkpoint left by a different request is an error, as
+// resuming it would skip resources the request never applied. When the store
+// cannot be read the restore runs without checkpoints: it can still be
+// re-run, re-applying everything.
+func (re *RestoreEngine) resumeCheckpoint(operation *RestoreOperation) error {
+	if re.checkpoints == nil || operation.Request.DryRun || operation.Request.RestoreID == "" {
+		return nil
+	}
+
+	checkpoint, err := re.checkpoints.Load(operation.ctx, operation.Request.RestoreID)
+	if err != nil {
+		re.checkpointError(operation, err)
+		return nil
+	}
+	if checkpoint == nil {
+		checkpoint = newRestoreCheckpoint(operation.Request)
+	} else if checkpoint.RequestHash != requestHash(operation.Request) {
+		return fmt.Errorf("restore %s was started with a different request; use a new restore_id", operation.Request.RestoreID)
+	} else {
+		operation.Resumed = true
+	}
+	checkpoint.Attempt++
+	operation.Attempt = checkpoint.Attempt
+	operation.checkpoint = checkpoint
+	return nil
+}
+
+// saveCheckpoint stores the operation's progress. It does not use the
+// operation's context, so the progress of a cancelled restore is kept.
+func (re *RestoreEngine) saveCheckpoint(operation *RestoreOperation) {
+	if operation.checkpoint == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), checkpointSaveTimeout)
+	defer cancel()
+
+	operation.checkpoint.UpdatedAt = time.Now()
+	if err := re.checkpoints.Save(ctx, operation.checkpoint); err != nil {
+		re.checkpointError(operation, err)
+	}
+}
+
+// finishCheckpoint removes the checkpoint of a restore that applied every
+// resource, and keeps it for a resume when the restore was stopped by err or
+// resources failed
+func (re *RestoreEngine) finishCheckpoint(operation *RestoreOperation, err error) {
+	if operation.checkpoint == nil {
+		return
+	}
+	if err != nil || len(operation.checkpoint.Failed) > 0 {
+		re.saveCheckpoint(operation)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), checkpointSaveTimeout)
+	defer cancel()
+	if err := re.checkpoints.Delete(ctx, operation.Request.RestoreID); err != nil {
+		re.checkpointError(operation, err)
+	}
+}
+
+// checkpointApplied records a resource as applied, saving a checkpoint every
+// checkpointBatch resources
+func (re *RestoreEngine) checkpointApplied(operation *RestoreOperation, resource BackupResource) {
+	if operation.checkpoint == nil {
+		return
+	}
+	operation.checkpoint.MarkApplied(resource)
+	operation.sinceCheckpoint++
+	if operation.sinceCheckpoint >= checkpointBatch {
+		operation.sinceCheckpoint = 0
+		re.saveCheckpoint(operation)
+	}
+}
+
+// checkpointFailed records a resource as failed
+func (re *RestoreEngine) checkpointFailed(operation *RestoreOperation, resource BackupResource, err string) {
+	if operation.checkpoint != nil {
+		operation.checkpoint.MarkFailed(resource, err)
+	}
+}
+
+// skipApplied reports whether an earlier attempt of the restore applied a
+// resource, recording it as skipped if so
+func (re *RestoreEngine) skipApplied(operation *RestoreOperation, resource BackupResource) bool {
+	if operation.checkpoint == nil || !operation.checkpoint.IsApplied(resource) {
+		return false
+	}
+	re.recordRestoredEarlier(operation, resource)
+	return true
+}
+
+// recordRestoredEarlier reports a resource applied by an earlier attempt as
+// skipped
+func (re *RestoreEngine) recordRestoredEarlier(operation *RestoreOperation, resource BackupResource) {
+	operation.Results.SkippedResources = append(operation.Results.SkippedResources, SkippedResource{
+		APIVersion: resource.APIVersion,
+		Kind:       resource.Kind,
+		Namespace:  resource.Namespace,
+		Name:       resource.Name,
+		Reason:     errRestoredEarlier.Error(),
+		Timestamp:  time.Now(),
+	})
+	operation.Progress.SkippedResources++
+}
+
+// checkpointError records the first checkpoint failure of a restore, which
+// does not stop it
+func (re *RestoreEngine) checkpointError(operation *RestoreOperation, err error) {
+	for _, recorded := range operation.Errors {
+		if recorded.Type == "checkpoint_failed" {
+			return
+		}
+	}
+	operation.Errors = append(operation.Errors, RestoreError{
+		Type:        "checkpoint_failed",
+		Message:     err.Error(),
+		Timestamp:   time.Now(),
+		Recoverable: true,
+	})
+}
@@ -0,0 +1,179 @@
+package restore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func checkpointTestOperation(ctx context.Context, request RestoreRequest) *RestoreOperation {
+	return &RestoreOperation{
+		Request:  request,
+		Progress: RestoreProgress{ResourceBreakdown: make(map[string]int)},
+		ctx:      ctx,
+	}
+}
+
+func TestConfigMapCheckpointStore(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	store := NewConfigMapCheckpointStore(client, "backup-system")
+
+	checkpoint, err := store.Load(ctx, "restore/prod 1")
+	require.NoError(t, err)
+	assert.Nil(t, checkpoint)
+
+	request := RestoreRequest{RestoreID: "restore/prod 1", BackupID: "backup-1", ClusterName: "prod"}
+	checkpoint = newRestoreCheckpoint(request)
+	configMap := backupResource("v1", "ConfigMap", "shop", "settings", nil)
+	checkpoint.MarkFailed(configMap, "conflict")
+	require.NoError(t, store.Save(ctx, checkpoint))
+	checkpoint.MarkApplied(configMap)
+	require.NoError(t, store.Save(ctx, checkpoint), "an existing checkpoint is replaced")
+
+	saved, err := client.CoreV1().ConfigMaps("backup-system").Get(ctx, checkpointName(request.RestoreID), metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "restore/prod 1", saved.Annotations[RestoreIDAnnotation])
+
+	loaded, err := store.Load(ctx, request.RestoreID)
+	require.NoError(t, err)
+	require.NotNil(t, loaded)
+	assert.Equal(t, requestHash(request), loaded.RequestHash)
+	assert.True(t, loaded.IsApplied(configMap))
+	assert.Empty(t, loaded.Failed)
+
+	require.NoError(t, store.Delete(ctx, request.RestoreID))
+	require.NoError(t, store.Delete(ctx, request.RestoreID), "deleting a missing checkpoint is not an error")
+	loaded, err = store.Load(ctx, request.RestoreID)
+	require.NoError(t, err)
+	assert.Nil(t, loaded)
+}
+
+func TestRequestHash(t *testing.T) {
+	request := RestoreRequest{RestoreID: "restore-1", BackupID: "backup-1", ConflictStrategy: ConflictStrategySkip}
+	resumed := request
+	resumed.RestoreID = "restore-2"
+	resumed.ValidationMode = ValidationModeSkip
+	resumed.WaitForReady = &WaitForReadyConfig{Enabled: true}
+	assert.Equal(t, requestHash(request), requestHash(resumed), "how a restore is validated and waited for may change")
+
+	changed := request
+	changed.ConflictStrategy = ConflictStrategyOverwrite
+	assert.NotEqual(t, requestHash(request), requestHash(changed))
+	changed = request
+	changed.BackupID = "backup-2"
+	assert.NotEqual(t, requestHash(request), requestHash(changed))
+}
+
+func TestRestoreResources_ResumesFromCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	store := NewConfigMapCheckpointStore(fake.NewSimpleClientset(), "backup-system")
+	engine := newPlanTestEngine()
+	engine.SetCheckpointStore(store)
+	request := RestoreRequest{RestoreID: "restore-1", BackupID: "backup-1", ConflictStrategy: ConflictStrategyFail}
+
+	// The first attempt applied the namespace and the config map, then was
+	// interrupted before its checkpoint recorded the config map
+	namespace := backupResource("v1", "Namespace", "", "shop", nil)
+	configMap := backupResource("v1", "ConfigMap", "shop", "settings", nil)
+	deployment := backupResource("apps/v1", "Deployment", "shop", "web", nil)
+	checkpoint := newRestoreCheckpoint(request)
+	checkpoint.Attempt = 1
+	checkpoint.MarkApplied(namespace)
+	checkpoint.MarkFailed(deployment, "admission webhook unavailable")
+	require.NoError(t, store.Save(ctx, checkpoint))
+	partial := liveObject("v1", "ConfigMap", "shop", "settings", map[string]interface{}{})
+	partial.SetAnnotations(map[string]string{RestoreIDAnnotation: "restore-1"})
+	_, err := engine.dynamicClient.Resource(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}).
+		Namespace("shop").Create(ctx, partial, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	operation := checkpointTestOperation(ctx, request)
+	require.NoError(t, engine.resumeCheckpoint(operation))
+	assert.True(t, operation.Resumed)
+	assert.Equal(t, 2, operation.Attempt)
+
+	resources := []BackupResource{namespace, configMap, deployment}
+	err = engine.restoreResources(operation, resources)
+	engine.finishCheckpoint(operation, err)
+	require.NoError(t, err)
+
+	// Neither the namespace nor the marked config map conflict
+	assert.Empty(t, operation.Results.FailedResources)
+	require.Len(t, operation.Results.SkippedResources, 2)
+	for _, skipped := range operation.Results.SkippedResources {
+		assert.Equal(t, errRestoredEarlier.Error(), skipped.Reason)
+	}
+	require.Len(t, operation.Results.RestoredResources, 1)
+	assert.Equal(t, "Deployment", operation.Results.RestoredResources[0].Kind)
+
+	created, err := engine.dynamicClient.Resource(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}).
+		Namespace("shop").Get(ctx, "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "restore-1", created.GetAnnotations()[RestoreIDAnnotation])
+
+	// Every resource is applied, so nothing is left to resume
+	loaded, err := store.Load(ctx, "restore-1")
+	require.NoError(t, err)
+	assert.Nil(t, loaded)
+}
+
+func TestRestoreResources_KeepsCheckpointOfFailures(t *testing.T) {
+	ctx := context.Background()
+	store := NewConfigMapCheckpointStore(fake.NewSimpleClientset(), "backup-system")
+	engine := newPlanTestEngine(liveObject("v1", "ConfigMap", "shop", "settings", map[string]interface{}{}))
+	engine.SetCheckpointStore(store)
+	request := RestoreRequest{RestoreID: "restore-1", BackupID: "backup-1", ConflictStrategy: ConflictStrategyFail}
+
+	operation := checkpointTestOperation(ctx, request)
+	require.NoError(t, engine.resumeCheckpoint(operation))
+	assert.False(t, operation.Resumed)
+	err := engine.restoreResources(operation, []BackupResource{
+		backupResource("v1", "Namespace", "", "shop", nil),
+		backupResource("v1", "ConfigMap", "shop", "settings", nil),
+	})
+	engine.finishCheckpoint(operation, err)
+	require.NoError(t, err)
+	require.Len(t, operation.Results.FailedResources, 1, "a config map not applied by this restore conflicts")
+
+	loaded, err := store.Load(ctx, "restore-1")
+	require.NoError(t, err)
+	require.NotNil(t, loaded)
+	assert.Equal(t, []string{"v1/Namespace//shop"}, loaded.Applied)
+	assert.Contains(t, loaded.Failed, "v1/ConfigMap/shop/settings")
+
+	// Resuming with a different request would skip what it never applied
+	changed := request
+	changed.ConflictStrategy = ConflictStrategyOverwrite
+	assert.Error(t, engine.resumeCheckpoint(checkpointTestOperation(ctx, changed)))
+
+	// Dry runs neither read nor write checkpoints
+	dryRun := request
+	dryRun.DryRun = true
+	operation = checkpointTestOperation(ctx, dryRun)
+	require.NoError(t, engine.resumeCheckpoint(operation))
+	assert.Nil(t, operation.checkpoint)
+}
+
+func TestRestoreResource_AnnotatesObjects(t *testing.T) {
+	ctx := context.Background()
+	engine := newPlanTestEngine()
+	operation := checkpointTestOperation(ctx, RestoreRequest{RestoreID: "restore-1", ConflictStrategy: ConflictStrategyFail})
+	resource := backupResource("v1", "ConfigMap", "shop", "settings", nil)
+	plan := engine.dependencyResolver.Resolve([]BackupResource{resource})
+
+	_, err := engine.restoreResource(operation, resource, plan)
+	require.NoError(t, err)
+	_, err = engine.restoreResource(operation, resource, plan)
+	assert.ErrorIs(t, err, errRestoredEarlier, "applying a resource again in the same restore is a no-op")
+
+	other := checkpointTestOperation(ctx, RestoreRequest{RestoreID: "restore-2", ConflictStrategy: ConflictStrategyFail})
+	_, err = engine.restoreResource(other, resource, plan)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, errRestoredEarlier)
+}
@@ -8,8 +8,6 @@ import (
 	sharedconfig "shared-config/config"
 	
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/util/strategicpatch"
-	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // ConflictResolver handles resource conflicts during restore operations
@@ -0,0 +1,91 @@
+package restore
+
+import (
+	"bytes"
+	"fmt"
+
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// Types of the documents an OpenShift backup captures under _openshift that
+// restores read
+const (
+	openShiftImageStreamTags = "imagestream-tags"
+	openShiftRouteTLS        = "route-tls"
+)
+
+// openShiftEntry is a document a run manifest lists under openshift
+type openShiftEntry struct {
+	Namespace string `json:"namespace,omitempty"`
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Key       string `json:"key"`
+	Checksum  string `json:"checksum,omitempty"`
+}
+
+// BackupContent is what a restore reads of a run
+type BackupContent struct {
+	// Resources are the run's CRDs followed by its resources
+	Resources []BackupResource
+	// Skipped are the resources the source does not hold
+	Skipped []SkippedResource
+	// ImageStreamTags are the images the run's ImageStream tags resolved to
+	ImageStreamTags []ImageStreamTags
+	// RouteSecrets hold the certificates of the run's Routes
+	RouteSecrets []BackupResource
+}
+
+// loadContent reads objects in order with read, then the OpenShift documents
+// of manifest, rejecting content whose SHA-256 differs from the manifest
+func loadContent(manifest *runManifest, objects []manifestObject, read func(object manifestObject) ([]byte, error)) (*BackupContent, error) {
+	content := &BackupContent{Resources: make([]BackupResource, 0, len(objects))}
+	for _, object := range objects {
+		data, err := readObject(object, read)
+		if err != nil {
+			return nil, err
+		}
+		resource, err := parseExportObject(object.Key, data)
+		if err != nil {
+			return nil, err
+		}
+		content.Resources = append(content.Resources, resource)
+	}
+
+	for _, entry := range manifest.OpenShift {
+		if entry.Type != openShiftImageStreamTags && entry.Type != openShiftRouteTLS {
+			continue
+		}
+		data, err := readObject(manifestObject{Namespace: entry.Namespace, Name: entry.Name, Key: entry.Key, Checksum: entry.Checksum}, read)
+		if err != nil {
+			return nil, err
+		}
+
+		if entry.Type == openShiftRouteTLS {
+			secret, err := parseExportObject(entry.Key, data)
+			if err != nil {
+				return nil, err
+			}
+			content.RouteSecrets = append(content.RouteSecrets, secret)
+			continue
+		}
+		tags := ImageStreamTags{}
+		if err := k8syaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096).Decode(&tags); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", entry.Key, err)
+		}
+		content.ImageStreamTags = append(content.ImageStreamTags, tags)
+	}
+	return content, nil
+}
+
+// readObject reads object with read, rejecting content whose SHA-256 differs
+// from the manifest
+func readObject(object manifestObject, read func(object manifestObject) ([]byte, error)) ([]byte, error) {
+	data, err := read(object)
+	if err != nil {
+		return nil, err
+	}
+	if err := VerifyChecksum(object.Key, data, object.Checksum); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
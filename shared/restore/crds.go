@@ -0,0 +1,74 @@
+package restore
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/version"
+)
+
+// CRDRestoreConfig controls how CustomResourceDefinitions are installed before
+// the custom resources of a restore
+type CRDRestoreConfig struct {
+	// SkipNewer keeps a CRD the target already serves at a newer version than
+	// the backup instead of replacing it with the older definition
+	SkipNewer bool `json:"skip_newer"`
+}
+
+// CRD install actions
+const (
+	crdActionCreate = "create"
+	crdActionUpdate = "update"
+	crdActionSkip   = "skip"
+)
+
+// latestServedVersion returns the highest-priority served version of a CRD,
+// e.g. v1 over v1beta2 over v1alpha1
+func latestServedVersion(crd map[string]interface{}) string {
+	versions, _, _ := unstructured.NestedSlice(crd, "spec", "versions")
+	latest := ""
+	for _, v := range versions {
+		versionMap, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := versionMap["name"].(string)
+		served, _ := versionMap["served"].(bool)
+		if name == "" || !served {
+			continue
+		}
+		if latest == "" || version.CompareKubeAwareVersionStrings(name, latest) > 0 {
+			latest = name
+		}
+	}
+	return latest
+}
+
+// planCRDInstall decides how to install a backed-up CRD given the CRD already
+// on the target, if any. The reason explains skipped CRDs.
+func planCRDInstall(backup, existing map[string]interface{}, config CRDRestoreConfig) (action, reason string) {
+	if existing == nil {
+		return crdActionCreate, ""
+	}
+
+	if config.SkipNewer {
+		backupVersion := latestServedVersion(backup)
+		targetVersion := latestServedVersion(existing)
+		if backupVersion != "" && targetVersion != "" && version.CompareKubeAwareVersionStrings(targetVersion, backupVersion) > 0 {
+			return crdActionSkip, fmt.Sprintf("target serves newer version %s than backup %s", targetVersion, backupVersion)
+		}
+	}
+	return crdActionUpdate, ""
+}
+
+// stripCRDStatus removes status from a backed-up CRD before it is applied.
+// The API server owns status, including storedVersions, on the target.
+func stripCRDStatus(crd map[string]interface{}) map[string]interface{} {
+	stripped := make(map[string]interface{}, len(crd))
+	for key, value := range crd {
+		if key != "status" {
+			stripped[key] = value
+		}
+	}
+	return stripped
+}
@@ -0,0 +1,78 @@
+package restore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func crdWithVersions(versions ...string) map[string]interface{} {
+	var specVersions []interface{}
+	for _, v := range versions {
+		specVersions = append(specVersions, map[string]interface{}{"name": v, "served": true})
+	}
+	return map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"spec":       map[string]interface{}{"versions": specVersions},
+		"status":     map[string]interface{}{"storedVersions": []interface{}{versions[0]}},
+	}
+}
+
+func TestLatestServedVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		crd      map[string]interface{}
+		expected string
+	}{
+		{"ga_over_beta", crdWithVersions("v1beta1", "v1"), "v1"},
+		{"beta_over_alpha", crdWithVersions("v1alpha1", "v1beta2", "v1beta1"), "v1beta2"},
+		{"higher_major", crdWithVersions("v1", "v2"), "v2"},
+		{"no_versions", map[string]interface{}{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, latestServedVersion(tt.crd))
+		})
+	}
+
+	unserved := crdWithVersions("v1beta1", "v1")
+	unserved["spec"].(map[string]interface{})["versions"].([]interface{})[1].(map[string]interface{})["served"] = false
+	assert.Equal(t, "v1beta1", latestServedVersion(unserved), "unserved versions are ignored")
+}
+
+func TestPlanCRDInstall(t *testing.T) {
+	tests := []struct {
+		name     string
+		backup   map[string]interface{}
+		existing map[string]interface{}
+		config   CRDRestoreConfig
+		expected string
+	}{
+		{"missing_on_target", crdWithVersions("v1"), nil, CRDRestoreConfig{SkipNewer: true}, crdActionCreate},
+		{"target_newer_skipped", crdWithVersions("v1beta1"), crdWithVersions("v1beta1", "v1"), CRDRestoreConfig{SkipNewer: true}, crdActionSkip},
+		{"target_newer_replaced_by_default", crdWithVersions("v1beta1"), crdWithVersions("v1"), CRDRestoreConfig{}, crdActionUpdate},
+		{"same_version_updated", crdWithVersions("v1"), crdWithVersions("v1"), CRDRestoreConfig{SkipNewer: true}, crdActionUpdate},
+		{"backup_newer_updated", crdWithVersions("v2"), crdWithVersions("v1"), CRDRestoreConfig{SkipNewer: true}, crdActionUpdate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, reason := planCRDInstall(tt.backup, tt.existing, tt.config)
+			assert.Equal(t, tt.expected, action)
+			if action == crdActionSkip {
+				assert.Contains(t, reason, "newer version v1")
+			}
+		})
+	}
+}
+
+func TestStripCRDStatus(t *testing.T) {
+	crd := crdWithVersions("v1")
+	stripped := stripCRDStatus(crd)
+
+	assert.NotContains(t, stripped, "status")
+	assert.Contains(t, stripped, "spec")
+	assert.Contains(t, crd, "status", "the backed-up CRD is not modified")
+}
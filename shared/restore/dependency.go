@@ -0,0 +1,342 @@
+package restore
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// crdGVR is the resource used to check CustomResourceDefinition status
+var crdGVR = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+
+// Restore ranks order resources when no explicit dependency decides between them.
+// Lower ranks are applied first.
+const (
+	rankCRD           = 0
+	rankNamespace     = 10
+	rankGovernance    = 15
+	rankClusterScoped = 20
+	rankAccess        = 30
+	rankConfig        = 40
+	rankStorage       = 50
+	rankService       = 60
+	rankEndpoints     = 70
+	rankWorkload      = 80
+	rankWorkloadAddon = 90
+	rankRouting       = 100
+	rankCustom        = 110
+	rankAdmission     = 200
+)
+
+// kindRanks assigns built-in kinds to restore ranks. Kinds not listed are
+// treated as custom resources.
+var kindRanks = map[string]int{
+	"CustomResourceDefinition": rankCRD,
+	"Namespace":                rankNamespace,
+
+	// Workloads are admitted and scheduled against these
+	"PriorityClass": rankGovernance,
+	"ResourceQuota": rankGovernance,
+	"LimitRange":    rankGovernance,
+	"NetworkPolicy": rankGovernance,
+
+	"StorageClass":       rankClusterScoped,
+	"IngressClass":       rankClusterScoped,
+	"RuntimeClass":       rankClusterScoped,
+	"ClusterRole":        rankClusterScoped,
+	"ClusterRoleBinding": rankClusterScoped,
+	"PersistentVolume":   rankClusterScoped,
+
+	"ServiceAccount": rankAccess,
+	"Role":           rankAccess,
+	"RoleBinding":    rankAccess,
+
+	"ConfigMap": rankConfig,
+	"Secret":    rankConfig,
+
+	"PersistentVolumeClaim": rankStorage,
+
+	"Service": rankService,
+
+	// Endpoint consumers need the Service they belong to
+	"Endpoints":     rankEndpoints,
+	"EndpointSlice": rankEndpoints,
+
+	"Deployment":            rankWorkload,
+	"StatefulSet":           rankWorkload,
+	"DaemonSet":             rankWorkload,
+	"ReplicaSet":            rankWorkload,
+	"ReplicationController": rankWorkload,
+	"Job":                   rankWorkload,
+	"CronJob":               rankWorkload,
+	"Pod":                   rankWorkload,
+	"DeploymentConfig":      rankWorkload,
+
+	"HorizontalPodAutoscaler": rankWorkloadAddon,
+	"PodDisruptionBudget":     rankWorkloadAddon,
+
+	"Ingress": rankRouting,
+	"Route":   rankRouting,
+
+	// Admission webhooks can reject restores of everything else, so they go last
+	"MutatingWebhookConfiguration":   rankAdmission,
+	"ValidatingWebhookConfiguration": rankAdmission,
+	"APIService":                     rankAdmission,
+}
+
+// governanceRanks are the ranks governance kinds take when they are not
+// restored first
+var governanceRanks = map[string]int{
+	"PriorityClass": rankClusterScoped,
+	"ResourceQuota": rankAccess,
+	"LimitRange":    rankAccess,
+	"NetworkPolicy": rankAccess,
+}
+
+// DependencyResolver orders backup resources so that every resource is applied
+// after the resources it depends on
+type DependencyResolver struct {
+	// GovernanceFirst applies PriorityClasses, ResourceQuotas, LimitRanges
+	// and NetworkPolicies right after namespaces, before anything they govern.
+	// Otherwise they rank with the cluster-scoped and access resources.
+	GovernanceFirst bool
+}
+
+// RestorePlan is the dependency-ordered list of resources to restore
+type RestorePlan struct {
+	Ordered []BackupResource `json:"ordered"`
+	// Warnings lists dependency cycles that had to be broken
+	Warnings []string `json:"warnings,omitempty"`
+
+	// crds maps group/kind of custom resources to the CRD restored in this run
+	crds map[string]crdInfo
+}
+
+// crdInfo identifies the CRD defining a custom resource kind
+type crdInfo struct {
+	name   string
+	plural string
+}
+
+// NewDependencyResolver creates a dependency resolver restoring governance
+// resources first
+func NewDependencyResolver() *DependencyResolver {
+	return &DependencyResolver{GovernanceFirst: true}
+}
+
+// Resolve orders resources by kind rank, namespace membership, CRD definitions
+// and owner references. Ties keep a stable order by kind, namespace and name.
+func (dr *DependencyResolver) Resolve(resources []BackupResource) *RestorePlan {
+	plan := &RestorePlan{crds: make(map[string]crdInfo)}
+
+	index := make(map[string]int, len(resources))
+	for i, resource := range resources {
+		index[resourceKey(resource.Kind, resource.Namespace, resource.Name)] = i
+		if resource.Kind == "CustomResourceDefinition" {
+			group, _, _ := unstructured.NestedString(resource.Data, "spec", "group")
+			kind, _, _ := unstructured.NestedString(resource.Data, "spec", "names", "kind")
+			plural, _, _ := unstructured.NestedString(resource.Data, "spec", "names", "plural")
+			if group != "" && kind != "" {
+				plan.crds[group+"/"+kind] = crdInfo{name: resource.Name, plural: plural}
+			}
+		}
+	}
+
+	// dependents[i] lists resources that must wait for resource i
+	dependents := make([][]int, len(resources))
+	pending := make([]int, len(resources))
+	addEdge := func(dependency, dependent int) {
+		if dependency == dependent {
+			return
+		}
+		dependents[dependency] = append(dependents[dependency], dependent)
+		pending[dependent]++
+	}
+
+	for i, resource := range resources {
+		for _, dependency := range dr.dependenciesOf(resource, plan) {
+			if j, ok := index[dependency]; ok {
+				addEdge(j, i)
+			}
+		}
+	}
+
+	// Kahn's algorithm, always picking the lowest-ranked ready resource
+	ready := &resourceQueue{resources: resources, rank: dr.Rank}
+	for i := range resources {
+		if pending[i] == 0 {
+			heap.Push(ready, i)
+		}
+	}
+
+	placed := make([]bool, len(resources))
+	for ready.Len() > 0 {
+		i := heap.Pop(ready).(int)
+		placed[i] = true
+		plan.Ordered = append(plan.Ordered, resources[i])
+		for _, dependent := range dependents[i] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				heap.Push(ready, dependent)
+			}
+		}
+	}
+
+	// Anything left is part of a cycle; apply it in rank order rather than not at all
+	if len(plan.Ordered) < len(resources) {
+		remaining := &resourceQueue{resources: resources, rank: dr.Rank}
+		for i := range resources {
+			if !placed[i] {
+				heap.Push(remaining, i)
+			}
+		}
+		for remaining.Len() > 0 {
+			resource := resources[heap.Pop(remaining).(int)]
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("dependency cycle involving %s/%s %s, applied in rank order",
+				resource.Kind, resource.Name, resource.Namespace))
+			plan.Ordered = append(plan.Ordered, resource)
+		}
+	}
+
+	return plan
+}
+
+// dependenciesOf returns the keys of resources that must exist before resource
+func (dr *DependencyResolver) dependenciesOf(resource BackupResource, plan *RestorePlan) []string {
+	var dependencies []string
+
+	if resource.Namespace != "" {
+		dependencies = append(dependencies, resourceKey("Namespace", "", resource.Namespace))
+	}
+
+	if crd, ok := plan.crdFor(resource); ok {
+		dependencies = append(dependencies, resourceKey("CustomResourceDefinition", "", crd.name))
+	}
+
+	owners, _, _ := unstructured.NestedSlice(resource.Data, "metadata", "ownerReferences")
+	for _, owner := range owners {
+		ownerMap, ok := owner.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		kind, _ := ownerMap["kind"].(string)
+		name, _ := ownerMap["name"].(string)
+		// Owners are either in the same namespace or cluster-scoped
+		dependencies = append(dependencies,
+			resourceKey(kind, resource.Namespace, name),
+			resourceKey(kind, "", name))
+	}
+
+	if resource.Kind == "EndpointSlice" {
+		labels, _, _ := unstructured.NestedStringMap(resource.Data, "metadata", "labels")
+		if service := labels["kubernetes.io/service-name"]; service != "" {
+			dependencies = append(dependencies, resourceKey("Service", resource.Namespace, service))
+		}
+	}
+	if resource.Kind == "Endpoints" {
+		dependencies = append(dependencies, resourceKey("Service", resource.Namespace, resource.Name))
+	}
+
+	return dependencies
+}
+
+// CRDFor returns the name and plural of the CRD restored in this run that
+// defines resource, if any
+func (p *RestorePlan) CRDFor(resource BackupResource) (name, plural string, ok bool) {
+	crd, ok := p.crdFor(resource)
+	return crd.name, crd.plural, ok
+}
+
+func (p *RestorePlan) crdFor(resource BackupResource) (crdInfo, bool) {
+	if _, builtin := kindRanks[resource.Kind]; builtin {
+		return crdInfo{}, false
+	}
+	group := schema.FromAPIVersionAndKind(resource.APIVersion, resource.Kind).Group
+	crd, ok := p.crds[group+"/"+resource.Kind]
+	return crd, ok
+}
+
+// Rank returns the rank a kind is restored at
+func (dr *DependencyResolver) Rank(kind string) int {
+	if rank, ok := governanceRanks[kind]; ok && !dr.GovernanceFirst {
+		return rank
+	}
+	return restoreRank(kind)
+}
+
+// IsGovernance reports whether kind is restored in the governance phase
+func (dr *DependencyResolver) IsGovernance(kind string) bool {
+	return dr.Rank(kind) == rankGovernance
+}
+
+// restoreRank returns the rank of a kind, defaulting to custom resources
+func restoreRank(kind string) int {
+	if rank, ok := kindRanks[kind]; ok {
+		return rank
+	}
+	return rankCustom
+}
+
+func resourceKey(kind, namespace, name string) string {
+	return strings.Join([]string{kind, namespace, name}, "/")
+}
+
+// resourceQueue is a min-heap of resource indexes ordered by rank, then kind,
+// namespace and name
+type resourceQueue struct {
+	resources []BackupResource
+	items     []int
+	rank      func(kind string) int
+}
+
+func (q *resourceQueue) Len() int { return len(q.items) }
+
+func (q *resourceQueue) Less(i, j int) bool {
+	a, b := q.resources[q.items[i]], q.resources[q.items[j]]
+	if rankA, rankB := q.rank(a.Kind), q.rank(b.Kind); rankA != rankB {
+		return rankA < rankB
+	}
+	if a.Kind != b.Kind {
+		return a.Kind < b.Kind
+	}
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	return a.Name < b.Name
+}
+
+func (q *resourceQueue) Swap(i, j int) { q.items[i], q.items[j] = q.items[j], q.items[i] }
+
+func (q *resourceQueue) Push(x interface{}) { q.items = append(q.items, x.(int)) }
+
+func (q *resourceQueue) Pop() interface{} {
+	last := q.items[len(q.items)-1]
+	q.items = q.items[:len(q.items)-1]
+	return last
+}
+
+// waitForCRDEstablished polls a CRD until its Established condition is True
+func waitForCRDEstablished(ctx context.Context, client dynamic.Interface, name string, timeout time.Duration) error {
+	return pollReady(ctx, client, crdGVR, "", name, timeout, readinessPollInterval, conditionReady("Established"))
+}
+
+// hasCondition reports whether obj has status.conditions[type] == status
+func hasCondition(obj *unstructured.Unstructured, conditionType, status string) bool {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, condition := range conditions {
+		conditionMap, ok := condition.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if conditionMap["type"] == conditionType && conditionMap["status"] == status {
+			return true
+		}
+	}
+	return false
+}
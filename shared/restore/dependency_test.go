@@ -0,0 +1,176 @@
+package restore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func backupResource(apiVersion, kind, namespace, name string, data map[string]interface{}) BackupResource {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return BackupResource{APIVersion: apiVersion, Kind: kind, Namespace: namespace, Name: name, Data: data}
+}
+
+func orderedNames(plan *RestorePlan) []string {
+	names := make([]string, 0, len(plan.Ordered))
+	for _, resource := range plan.Ordered {
+		names = append(names, resource.Kind+"/"+resource.Name)
+	}
+	return names
+}
+
+func TestDependencyResolver_RankOrder(t *testing.T) {
+	resources := []BackupResource{
+		backupResource("networking.k8s.io/v1", "Ingress", "shop", "web", nil),
+		backupResource("apps/v1", "Deployment", "shop", "web", nil),
+		backupResource("discovery.k8s.io/v1", "EndpointSlice", "shop", "web-abc12", map[string]interface{}{
+			"metadata": map[string]interface{}{"labels": map[string]interface{}{"kubernetes.io/service-name": "web"}},
+		}),
+		backupResource("v1", "Service", "shop", "web", nil),
+		backupResource("v1", "ConfigMap", "shop", "settings", nil),
+		backupResource("v1", "Namespace", "", "shop", nil),
+		backupResource("admissionregistration.k8s.io/v1", "ValidatingWebhookConfiguration", "", "policy", nil),
+	}
+
+	plan := NewDependencyResolver().Resolve(resources)
+
+	assert.Empty(t, plan.Warnings)
+	assert.Equal(t, []string{
+		"Namespace/shop",
+		"ConfigMap/settings",
+		"Service/web",
+		"EndpointSlice/web-abc12",
+		"Deployment/web",
+		"Ingress/web",
+		"ValidatingWebhookConfiguration/policy",
+	}, orderedNames(plan))
+}
+
+func TestDependencyResolver_GovernanceFirst(t *testing.T) {
+	resources := []BackupResource{
+		backupResource("apps/v1", "Deployment", "shop", "web", nil),
+		backupResource("networking.k8s.io/v1", "NetworkPolicy", "shop", "deny-all", nil),
+		backupResource("rbac.authorization.k8s.io/v1", "ClusterRole", "", "reader", nil),
+		backupResource("v1", "LimitRange", "shop", "defaults", nil),
+		backupResource("v1", "ServiceAccount", "shop", "web", nil),
+		backupResource("scheduling.k8s.io/v1", "PriorityClass", "", "critical", nil),
+		backupResource("v1", "ResourceQuota", "shop", "compute", nil),
+		backupResource("v1", "Namespace", "", "shop", nil),
+	}
+
+	resolver := NewDependencyResolver()
+	assert.Equal(t, []string{
+		"Namespace/shop",
+		"LimitRange/defaults",
+		"NetworkPolicy/deny-all",
+		"PriorityClass/critical",
+		"ResourceQuota/compute",
+		"ClusterRole/reader",
+		"ServiceAccount/web",
+		"Deployment/web",
+	}, orderedNames(resolver.Resolve(resources)))
+	assert.True(t, resolver.IsGovernance("ResourceQuota"))
+
+	// Without the governance phase they rank as before, still ahead of workloads
+	resolver = &DependencyResolver{}
+	assert.Equal(t, []string{
+		"Namespace/shop",
+		"ClusterRole/reader",
+		"PriorityClass/critical",
+		"LimitRange/defaults",
+		"NetworkPolicy/deny-all",
+		"ResourceQuota/compute",
+		"ServiceAccount/web",
+		"Deployment/web",
+	}, orderedNames(resolver.Resolve(resources)))
+	assert.False(t, resolver.IsGovernance("ResourceQuota"))
+}
+
+func TestDependencyResolver_CustomResources(t *testing.T) {
+	resources := []BackupResource{
+		backupResource("example.com/v1", "Widget", "shop", "blue", nil),
+		backupResource("v1", "Namespace", "", "shop", nil),
+		backupResource("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "widgets.example.com", map[string]interface{}{
+			"spec": map[string]interface{}{
+				"group": "example.com",
+				"names": map[string]interface{}{"kind": "Widget", "plural": "widgets"},
+			},
+		}),
+		backupResource("other.io/v1", "Gadget", "shop", "red", nil),
+	}
+
+	plan := NewDependencyResolver().Resolve(resources)
+
+	assert.Equal(t, []string{
+		"CustomResourceDefinition/widgets.example.com",
+		"Namespace/shop",
+		"Gadget/red",
+		"Widget/blue",
+	}, orderedNames(plan))
+
+	name, plural, ok := plan.CRDFor(resources[0])
+	assert.True(t, ok)
+	assert.Equal(t, "widgets.example.com", name)
+	assert.Equal(t, "widgets", plural)
+
+	// CRDs that are not part of the restore are assumed to already exist
+	_, _, ok = plan.CRDFor(resources[3])
+	assert.False(t, ok)
+}
+
+func TestDependencyResolver_OwnerReferences(t *testing.T) {
+	owned := func(kind, name string) map[string]interface{} {
+		return map[string]interface{}{"metadata": map[string]interface{}{
+			"ownerReferences": []interface{}{map[string]interface{}{"kind": kind, "name": name}},
+		}}
+	}
+
+	// A ConfigMap owned by a custom resource must wait for it, despite its lower rank
+	resources := []BackupResource{
+		backupResource("v1", "ConfigMap", "shop", "generated", owned("Widget", "blue")),
+		backupResource("example.com/v1", "Widget", "shop", "blue", nil),
+	}
+
+	plan := NewDependencyResolver().Resolve(resources)
+	assert.Equal(t, []string{"Widget/blue", "ConfigMap/generated"}, orderedNames(plan))
+}
+
+func TestDependencyResolver_Cycle(t *testing.T) {
+	resources := []BackupResource{
+		backupResource("v1", "ConfigMap", "shop", "a", map[string]interface{}{"metadata": map[string]interface{}{
+			"ownerReferences": []interface{}{map[string]interface{}{"kind": "ConfigMap", "name": "b"}},
+		}}),
+		backupResource("v1", "ConfigMap", "shop", "b", map[string]interface{}{"metadata": map[string]interface{}{
+			"ownerReferences": []interface{}{map[string]interface{}{"kind": "ConfigMap", "name": "a"}},
+		}}),
+	}
+
+	plan := NewDependencyResolver().Resolve(resources)
+	require.Len(t, plan.Ordered, 2)
+	assert.Len(t, plan.Warnings, 2)
+}
+
+func TestWaitForCRDEstablished(t *testing.T) {
+	crd := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata":   map[string]interface{}{"name": "widgets.example.com"},
+		"status": map[string]interface{}{"conditions": []interface{}{
+			map[string]interface{}{"type": "Established", "status": "True"},
+		}},
+	}}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{crdGVR: "CustomResourceDefinitionList"}, crd)
+
+	assert.NoError(t, waitForCRDEstablished(context.Background(), client, "widgets.example.com", time.Second))
+	assert.Error(t, waitForCRDEstablished(context.Background(), client, "missing.example.com", 100*time.Millisecond))
+}
@@ -0,0 +1,302 @@
+package restore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	sharedconfig "shared-config/config"
+)
+
+// DefaultDrillInterval is how often drills run when no interval is set
+const DefaultDrillInterval = 7 * 24 * time.Hour
+
+// drillHistoryLimit bounds the drill reports kept
+const drillHistoryLimit = 50
+
+// DrillBackup is a backup a drill can rehearse restoring
+type DrillBackup struct {
+	BackupID    string    `json:"backup_id"`
+	ClusterName string    `json:"cluster_name"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// DrillReport is the outcome of a restore drill. RTO is how long the restore
+// took until its workloads were ready, RPO how old the restored backup was
+// when the drill started, that is the data a disaster at that moment would
+// have lost.
+type DrillReport struct {
+	DrillID      string            `json:"drill_id"`
+	BackupID     string            `json:"backup_id,omitempty"`
+	ClusterName  string            `json:"cluster_name,omitempty"`
+	BackupTime   time.Time         `json:"backup_time,omitempty"`
+	StartTime    time.Time         `json:"start_time"`
+	EndTime      time.Time         `json:"end_time"`
+	Status       RestoreStatus     `json:"status,omitempty"`
+	Namespaces   map[string]string `json:"namespaces,omitempty"`
+	RTO          time.Duration     `json:"rto"`
+	RPO          time.Duration     `json:"rpo"`
+	RTOObjective time.Duration     `json:"rto_objective,omitempty"`
+	RPOObjective time.Duration     `json:"rpo_objective,omitempty"`
+	RTOMet       bool              `json:"rto_met"`
+	RPOMet       bool              `json:"rpo_met"`
+	Restored     int               `json:"resources_restored"`
+	Failed       int               `json:"resources_failed"`
+	Checks       []ReadinessResult `json:"checks,omitempty"`
+	Passed       bool              `json:"passed"`
+	Error        string            `json:"error,omitempty"`
+}
+
+// DrillScheduler rehearses restores: every interval it restores the latest
+// backup it was told about into scratch namespaces, waits for the restored
+// workloads to pass their readiness checks and reports the recovery time and
+// point achieved against the objectives
+type DrillScheduler struct {
+	engine *RestoreEngine
+	config sharedconfig.RestoreDrillConfig
+
+	mu      sync.Mutex
+	latest  *DrillBackup
+	running bool
+	reports []*DrillReport
+}
+
+// NewDrillScheduler returns the scheduler of config's drills run by engine
+func NewDrillScheduler(engine *RestoreEngine, config sharedconfig.RestoreDrillConfig) *DrillScheduler {
+	return &DrillScheduler{engine: engine, config: config}
+}
+
+// RecordBackup makes backup the one the next drill restores, unless a newer
+// one was recorded already
+func (ds *DrillScheduler) RecordBackup(backup DrillBackup) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if ds.latest == nil || backup.Timestamp.After(ds.latest.Timestamp) {
+		ds.latest = &backup
+	}
+}
+
+// Interval returns how often drills run
+func (ds *DrillScheduler) Interval() time.Duration {
+	if ds.config.Interval <= 0 {
+		return DefaultDrillInterval
+	}
+	return ds.config.Interval
+}
+
+// Run runs a drill every interval until ctx is done
+func (ds *DrillScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(ds.Interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Failures are in the report
+			ds.RunDrill(ctx)
+		}
+	}
+}
+
+// RunDrill restores the latest backup, waits for the restore to finish and
+// returns its report. Only one drill runs at a time.
+func (ds *DrillScheduler) RunDrill(ctx context.Context) (*DrillReport, error) {
+	ds.mu.Lock()
+	if ds.running {
+		ds.mu.Unlock()
+		return nil, fmt.Errorf("a restore drill is already running")
+	}
+	if ds.latest == nil {
+		ds.mu.Unlock()
+		return ds.record(&DrillReport{
+			DrillID:   drillID(time.Now()),
+			StartTime: time.Now(),
+			EndTime:   time.Now(),
+			Error:     "no backup has completed since the restore engine started",
+		}), nil
+	}
+	backup := *ds.latest
+	ds.running = true
+	ds.mu.Unlock()
+	defer func() {
+		ds.mu.Lock()
+		ds.running = false
+		ds.mu.Unlock()
+	}()
+
+	start := time.Now()
+	request := drillRequest(ds.config, backup, start)
+	operation, err := ds.engine.StartRestore(ctx, request)
+	if err != nil {
+		report := newDrillReport(ds.config, backup, request, nil, start)
+		report.Error = fmt.Sprintf("failed to start restore: %v", err)
+		return ds.record(report), nil
+	}
+
+	select {
+	case <-operation.completionChan:
+	case <-ctx.Done():
+		ds.engine.CancelRestore(request.RestoreID)
+		<-operation.completionChan
+	}
+
+	report := newDrillReport(ds.config, backup, request, operation, start)
+	if err := ds.cleanup(report); err != nil && report.Error == "" {
+		report.Error = err.Error()
+	}
+	return ds.record(report), nil
+}
+
+// Running reports whether a drill is running
+func (ds *DrillScheduler) Running() bool {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.running
+}
+
+// Reports returns the reports of the latest drills, newest first
+func (ds *DrillScheduler) Reports() []*DrillReport {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	reports := make([]*DrillReport, len(ds.reports))
+	for i, report := range ds.reports {
+		reports[len(ds.reports)-1-i] = report
+	}
+	return reports
+}
+
+// record keeps report and exports its outcome as metrics
+func (ds *DrillScheduler) record(report *DrillReport) *DrillReport {
+	ds.mu.Lock()
+	ds.reports = append(ds.reports, report)
+	if len(ds.reports) > drillHistoryLimit {
+		ds.reports = ds.reports[len(ds.reports)-drillHistoryLimit:]
+	}
+	ds.mu.Unlock()
+
+	if ds.engine == nil || ds.engine.monitoringSystem == nil {
+		return report
+	}
+	metrics := ds.engine.monitoringSystem.GetMonitoringHub().GetMetricsCollector()
+	result := "passed"
+	if !report.Passed {
+		result = "failed"
+	}
+	labels := map[string]string{"cluster": report.ClusterName}
+	metrics.IncCounter("restore_drills_total", map[string]string{"cluster": report.ClusterName, "result": result}, 1)
+	metrics.SetGauge("restore_drill_rto_seconds", labels, report.RTO.Seconds())
+	metrics.SetGauge("restore_drill_rpo_seconds", labels, report.RPO.Seconds())
+	metrics.SetGauge("restore_drill_passed", labels, boolGauge(report.Passed))
+	metrics.SetGauge("restore_drill_last_run_timestamp", labels, float64(report.EndTime.Unix()))
+	return report
+}
+
+// cleanup deletes the scratch namespaces of a drill unless they are kept
+func (ds *DrillScheduler) cleanup(report *DrillReport) error {
+	if ds.config.KeepNamespaces || ds.config.NamespacePrefix == "" {
+		return nil
+	}
+	for _, scratch := range report.Namespaces {
+		err := ds.engine.k8sClient.CoreV1().Namespaces().Delete(context.Background(), scratch, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete scratch namespace %s: %v", scratch, err)
+		}
+	}
+	return nil
+}
+
+// drillRequest returns the restore of backup a drill started at start runs.
+// With a namespace prefix each namespace goes to its scratch namespace, and
+// existing scratch resources, left by a kept drill, are overwritten.
+func drillRequest(config sharedconfig.RestoreDrillConfig, backup DrillBackup, start time.Time) RestoreRequest {
+	request := RestoreRequest{
+		RestoreID:        drillID(start),
+		BackupID:         backup.BackupID,
+		ClusterName:      backup.ClusterName,
+		TargetNamespaces: config.Namespaces,
+		RestoreMode:      RestoreModeComplete,
+		ValidationMode:   ValidationModePermissive,
+		ConflictStrategy: ConflictStrategyOverwrite,
+		WaitForReady:     &WaitForReadyConfig{Enabled: true},
+		Metadata:         map[string]interface{}{"drill": true},
+	}
+	if len(config.Namespaces) > 0 {
+		request.RestoreMode = RestoreModeSelective
+	}
+	if config.NamespacePrefix != "" {
+		mappings := make(map[string]string, len(config.Namespaces))
+		for _, namespace := range config.Namespaces {
+			mappings[namespace] = config.NamespacePrefix + namespace
+		}
+		request.Transforms = &TransformConfig{NamespaceMappings: mappings}
+	}
+	return request
+}
+
+// newDrillReport measures a drill of backup started at start against the
+// objectives. operation is nil when the restore did not start.
+func newDrillReport(config sharedconfig.RestoreDrillConfig, backup DrillBackup, request RestoreRequest, operation *RestoreOperation, start time.Time) *DrillReport {
+	report := &DrillReport{
+		DrillID:      request.RestoreID,
+		BackupID:     backup.BackupID,
+		ClusterName:  backup.ClusterName,
+		BackupTime:   backup.Timestamp,
+		StartTime:    start,
+		EndTime:      time.Now(),
+		RPO:          start.Sub(backup.Timestamp),
+		RTOObjective: config.RTOObjective,
+		RPOObjective: config.RPOObjective,
+	}
+	if request.Transforms != nil {
+		report.Namespaces = request.Transforms.NamespaceMappings
+	}
+	report.RPOMet = config.RPOObjective <= 0 || report.RPO <= config.RPOObjective
+	if operation == nil {
+		return report
+	}
+
+	if operation.EndTime != nil {
+		report.EndTime = *operation.EndTime
+	}
+	report.Status = operation.Status
+	report.RTO = report.EndTime.Sub(operation.StartTime)
+	report.RTOMet = config.RTOObjective <= 0 || report.RTO <= config.RTOObjective
+	report.Restored = len(operation.Results.RestoredResources)
+	report.Failed = len(operation.Results.FailedResources)
+	if operation.ReadinessReport != nil {
+		report.Checks = operation.ReadinessReport.Results
+	}
+
+	switch {
+	case operation.Status != RestoreStatusCompleted:
+		report.Error = fmt.Sprintf("restore ended %s", operation.Status)
+		if len(operation.Errors) > 0 {
+			report.Error += ": " + operation.Errors[len(operation.Errors)-1].Message
+		}
+	case report.Failed > 0:
+		report.Error = fmt.Sprintf("%d resources failed to restore", report.Failed)
+	case !report.RTOMet:
+		report.Error = fmt.Sprintf("restore took %s, longer than the RTO objective of %s", report.RTO.Round(time.Second), config.RTOObjective)
+	case !report.RPOMet:
+		report.Error = fmt.Sprintf("backup was %s old, older than the RPO objective of %s", report.RPO.Round(time.Second), config.RPOObjective)
+	}
+	report.Passed = report.Error == ""
+	return report
+}
+
+func drillID(start time.Time) string {
+	return "drill-" + start.UTC().Format("20060102-150405")
+}
+
+func boolGauge(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,99 @@
+package restore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sharedconfig "shared-config/config"
+)
+
+func TestDrillRequest(t *testing.T) {
+	backup := DrillBackup{BackupID: "backup-1", ClusterName: "prod", Timestamp: time.Now().Add(-time.Hour)}
+	start := time.Date(2025, 3, 1, 2, 0, 0, 0, time.UTC)
+
+	request := drillRequest(sharedconfig.RestoreDrillConfig{Namespaces: []string{"shop", "db"}, NamespacePrefix: "drill-"}, backup, start)
+	assert.Equal(t, "drill-20250301-020000", request.RestoreID)
+	assert.Equal(t, "backup-1", request.BackupID)
+	assert.Equal(t, RestoreModeSelective, request.RestoreMode)
+	assert.Equal(t, []string{"shop", "db"}, request.TargetNamespaces)
+	assert.Equal(t, map[string]string{"shop": "drill-shop", "db": "drill-db"}, request.Transforms.NamespaceMappings)
+	require.NotNil(t, request.WaitForReady)
+	assert.True(t, request.WaitForReady.Enabled, "drills run the readiness checks")
+
+	// Without a prefix the backup is restored as is, into an ephemeral cluster
+	request = drillRequest(sharedconfig.RestoreDrillConfig{}, backup, start)
+	assert.Equal(t, RestoreModeComplete, request.RestoreMode)
+	assert.Nil(t, request.Transforms)
+}
+
+func TestNewDrillReport(t *testing.T) {
+	config := sharedconfig.RestoreDrillConfig{RTOObjective: time.Hour, RPOObjective: 24 * time.Hour}
+	start := time.Now()
+	backup := DrillBackup{BackupID: "backup-1", ClusterName: "prod", Timestamp: start.Add(-2 * time.Hour)}
+	request := drillRequest(config, backup, start)
+
+	end := start.Add(20 * time.Minute)
+	operation := &RestoreOperation{
+		Status:          RestoreStatusCompleted,
+		StartTime:       start,
+		EndTime:         &end,
+		Results:         RestoreResults{RestoredResources: []RestoredResource{{Kind: "Deployment", Name: "web"}}},
+		ReadinessReport: &ReadinessReport{Ready: true, Results: []ReadinessResult{{CheckName: "ready_Deployment/shop/web", Success: true}}},
+	}
+	report := newDrillReport(config, backup, request, operation, start)
+	assert.True(t, report.Passed, report.Error)
+	assert.Equal(t, 20*time.Minute, report.RTO)
+	assert.Equal(t, 2*time.Hour, report.RPO)
+	assert.True(t, report.RTOMet)
+	assert.True(t, report.RPOMet)
+	assert.Equal(t, 1, report.Restored)
+	assert.Len(t, report.Checks, 1)
+
+	// Missing an objective fails the drill
+	end = start.Add(90 * time.Minute)
+	report = newDrillReport(config, backup, request, operation, start)
+	assert.False(t, report.Passed)
+	assert.False(t, report.RTOMet)
+	assert.Contains(t, report.Error, "RTO objective")
+
+	backup.Timestamp = start.Add(-48 * time.Hour)
+	end = start.Add(time.Minute)
+	report = newDrillReport(config, backup, request, operation, start)
+	assert.False(t, report.RPOMet)
+	assert.Contains(t, report.Error, "RPO objective")
+
+	// Objectives left unset are not enforced
+	report = newDrillReport(sharedconfig.RestoreDrillConfig{}, backup, request, operation, start)
+	assert.True(t, report.Passed)
+
+	operation.Status = RestoreStatusFailed
+	operation.Errors = []RestoreError{{Type: "readiness_check_failed", Message: "Deployment/shop/web is not available"}}
+	report = newDrillReport(config, backup, request, operation, start)
+	assert.False(t, report.Passed)
+	assert.Contains(t, report.Error, "not available")
+}
+
+func TestDrillScheduler_Reports(t *testing.T) {
+	drills := NewDrillScheduler(nil, sharedconfig.RestoreDrillConfig{})
+	assert.Equal(t, DefaultDrillInterval, drills.Interval())
+
+	// Without a backup the drill fails without restoring anything
+	report, err := drills.RunDrill(context.Background())
+	require.NoError(t, err)
+	assert.False(t, report.Passed)
+	assert.Contains(t, report.Error, "no backup")
+
+	now := time.Now()
+	drills.RecordBackup(DrillBackup{BackupID: "backup-2", Timestamp: now})
+	drills.RecordBackup(DrillBackup{BackupID: "backup-1", Timestamp: now.Add(-time.Hour)})
+	assert.Equal(t, "backup-2", drills.latest.BackupID, "the newest backup is drilled")
+
+	drills.record(&DrillReport{DrillID: "drill-2"})
+	reports := drills.Reports()
+	require.Len(t, reports, 2)
+	assert.Equal(t, "drill-2", reports[0].DrillID)
+}
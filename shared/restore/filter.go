@@ -0,0 +1,53 @@
+package restore
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// filterResources keeps the resources the request selects. TargetNamespaces
+// limits namespaced resources and Namespace objects to those namespaces,
+// ResourceTypes to the listed kinds or resource names, and LabelSelector to
+// the matching resources. Unset criteria select everything.
+func filterResources(request RestoreRequest, resources []BackupResource) ([]BackupResource, error) {
+	selector := labels.Everything()
+	if request.LabelSelector != "" {
+		parsed, err := labels.Parse(request.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label selector %q: %v", request.LabelSelector, err)
+		}
+		selector = parsed
+	}
+
+	namespaces := make(map[string]bool, len(request.TargetNamespaces))
+	for _, namespace := range request.TargetNamespaces {
+		namespaces[namespace] = true
+	}
+	types := make(map[string]bool, len(request.ResourceTypes))
+	for _, resourceType := range request.ResourceTypes {
+		types[strings.ToLower(resourceType)] = true
+	}
+
+	var selected []BackupResource
+	for _, resource := range resources {
+		if len(namespaces) > 0 {
+			namespace := resource.Namespace
+			if resource.Kind == "Namespace" {
+				namespace = resource.Name
+			}
+			if namespace != "" && !namespaces[namespace] {
+				continue
+			}
+		}
+		if len(types) > 0 && !types[strings.ToLower(resource.Kind)] && !types[pluralize(resource.Kind)] {
+			continue
+		}
+		if !selector.Matches(labels.Set(backupObject(resource).GetLabels())) {
+			continue
+		}
+		selected = append(selected, resource)
+	}
+	return selected, nil
+}
@@ -0,0 +1,143 @@
+package restore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	sharedconfig "shared-config/config"
+)
+
+// Defaults of the restore guardrails: the namespaces of the control plane and
+// the platform, and kinds that reconfigure the cluster itself
+var (
+	DefaultProtectedNamespaces = []string{"kube-system", "kube-public", "kube-node-lease", "openshift-*"}
+	DefaultProtectedKinds      = []string{"Node", "APIService"}
+)
+
+// Guardrails decide which resources restores never touch unless forced
+type Guardrails struct {
+	namespaces []string
+	kinds      map[string]bool
+}
+
+// NewGuardrails returns the guardrails of config, with the defaults for the
+// lists it leaves unset
+func NewGuardrails(config sharedconfig.RestoreGuardrailsConfig) *Guardrails {
+	namespaces := config.ProtectedNamespaces
+	if namespaces == nil {
+		namespaces = DefaultProtectedNamespaces
+	}
+	kinds := config.ProtectedKinds
+	if kinds == nil {
+		kinds = DefaultProtectedKinds
+	}
+
+	guardrails := &Guardrails{namespaces: namespaces, kinds: make(map[string]bool, len(kinds))}
+	for _, kind := range kinds {
+		guardrails.kinds[strings.ToLower(kind)] = true
+	}
+	return guardrails
+}
+
+// Protects reports whether resource is of a protected kind or in a protected
+// namespace. A Namespace is protected when it is one.
+func (g *Guardrails) Protects(resource BackupResource) bool {
+	if g.kinds[strings.ToLower(resource.Kind)] {
+		return true
+	}
+	namespace := resource.Namespace
+	if resource.Kind == "Namespace" && resource.APIVersion == "v1" {
+		namespace = resource.Name
+	}
+	if namespace == "" {
+		return false
+	}
+	for _, pattern := range g.namespaces {
+		if matched, _ := path.Match(pattern, namespace); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfirmationToken returns the token a request must carry to restore the
+// protected resources of a backup. It is derived from the backup, the target
+// cluster and the resources, so confirming one restore does not confirm
+// another that would touch more.
+func ConfirmationToken(request RestoreRequest, protected []BackupResource) string {
+	keys := make([]string, 0, len(protected))
+	for _, resource := range protected {
+		keys = append(keys, fmt.Sprintf("%s/%s/%s/%s", resource.APIVersion, resource.Kind, resource.Namespace, resource.Name))
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n", request.ClusterName, request.BackupID)
+	for _, key := range keys {
+		fmt.Fprintln(hash, key)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// applyGuardrails returns the resources to apply without the protected ones,
+// which are skipped with the confirmation token in their reason, so callers
+// only seeing the results can force them. With force_unsafe they are kept, but only when the
+// request carries their confirmation token; a forced restore without it fails
+// rather than falling back to skipping them.
+func (re *RestoreEngine) applyGuardrails(operation *RestoreOperation, resources []BackupResource) ([]BackupResource, []SkippedResource, error) {
+	guardrails := re.guardrails
+	if guardrails == nil {
+		guardrails = NewGuardrails(sharedconfig.RestoreGuardrailsConfig{})
+	}
+
+	var kept, protected []BackupResource
+	for _, resource := range resources {
+		if guardrails.Protects(resource) {
+			protected = append(protected, resource)
+		} else {
+			kept = append(kept, resource)
+		}
+	}
+	if len(protected) == 0 {
+		return resources, nil, nil
+	}
+
+	request := operation.Request
+	token := ConfirmationToken(request, protected)
+	if request.ForceUnsafe {
+		if request.ConfirmationToken != token {
+			return nil, nil, fmt.Errorf("force_unsafe restores %d protected resources only with confirmation_token %s", len(protected), token)
+		}
+		operation.Errors = append(operation.Errors, RestoreError{
+			Type:        "guardrails_overridden",
+			Message:     fmt.Sprintf("restoring %d protected resources with force_unsafe", len(protected)),
+			Timestamp:   time.Now(),
+			Recoverable: true,
+		})
+		return resources, nil, nil
+	}
+
+	skipped := make([]SkippedResource, 0, len(protected))
+	for _, resource := range protected {
+		skipped = append(skipped, SkippedResource{
+			APIVersion: resource.APIVersion,
+			Kind:       resource.Kind,
+			Namespace:  resource.Namespace,
+			Name:       resource.Name,
+			Reason:     fmt.Sprintf("protected by the restore guardrails; restore with force_unsafe and confirmation_token %s", token),
+			Timestamp:  time.Now(),
+		})
+	}
+	operation.Errors = append(operation.Errors, RestoreError{
+		Type:        "protected_resources_skipped",
+		Message:     fmt.Sprintf("skipped %d protected resources; restore them with force_unsafe and confirmation_token %s", len(protected), token),
+		Timestamp:   time.Now(),
+		Recoverable: true,
+	})
+	return kept, skipped, nil
+}
@@ -0,0 +1,116 @@
+package restore
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sharedconfig "shared-config/config"
+)
+
+func TestGuardrails_Protects(t *testing.T) {
+	guardrails := NewGuardrails(sharedconfig.RestoreGuardrailsConfig{})
+
+	assert.True(t, guardrails.Protects(backupResource("v1", "ConfigMap", "kube-system", "coredns", nil)))
+	assert.True(t, guardrails.Protects(backupResource("v1", "Secret", "openshift-ingress", "router-certs", nil)))
+	assert.True(t, guardrails.Protects(backupResource("v1", "Namespace", "", "kube-system", nil)))
+	assert.True(t, guardrails.Protects(backupResource("v1", "Node", "", "worker-1", nil)))
+	assert.True(t, guardrails.Protects(backupResource("apiregistration.k8s.io/v1", "APIService", "", "v1beta1.metrics.k8s.io", nil)))
+	assert.False(t, guardrails.Protects(backupResource("v1", "ConfigMap", "shop", "settings", nil)))
+	assert.False(t, guardrails.Protects(backupResource("v1", "Namespace", "", "openshift", nil)))
+	assert.False(t, guardrails.Protects(backupResource("rbac.authorization.k8s.io/v1", "ClusterRole", "", "view", nil)))
+
+	// Empty lists protect nothing
+	guardrails = NewGuardrails(sharedconfig.RestoreGuardrailsConfig{ProtectedNamespaces: []string{}, ProtectedKinds: []string{"node"}})
+	assert.False(t, guardrails.Protects(backupResource("v1", "ConfigMap", "kube-system", "coredns", nil)))
+	assert.True(t, guardrails.Protects(backupResource("v1", "Node", "", "worker-1", nil)))
+}
+
+func TestApplyGuardrails(t *testing.T) {
+	engine := &RestoreEngine{}
+	resources := []BackupResource{
+		backupResource("v1", "ConfigMap", "shop", "settings", nil),
+		backupResource("v1", "ConfigMap", "kube-system", "coredns", nil),
+		backupResource("v1", "Node", "", "worker-1", nil),
+	}
+	request := RestoreRequest{BackupID: "backup-1", ClusterName: "prod"}
+
+	operation := checkpointTestOperation(context.Background(), request)
+	kept, skipped, err := engine.applyGuardrails(operation, resources)
+	require.NoError(t, err)
+	assert.Equal(t, resources[:1], kept)
+	assert.Equal(t, []string{"coredns", "worker-1"}, skippedNames(skipped))
+	require.Len(t, operation.Errors, 1)
+	assert.Equal(t, "protected_resources_skipped", operation.Errors[0].Type)
+
+	token := ConfirmationToken(request, resources[1:])
+	assert.Contains(t, operation.Errors[0].Message, token)
+
+	// Forcing without the token fails instead of skipping
+	request.ForceUnsafe = true
+	_, _, err = engine.applyGuardrails(checkpointTestOperation(context.Background(), request), resources)
+	assert.ErrorContains(t, err, token)
+
+	request.ConfirmationToken = token
+	operation = checkpointTestOperation(context.Background(), request)
+	kept, skipped, err = engine.applyGuardrails(operation, resources)
+	require.NoError(t, err)
+	assert.Equal(t, resources, kept)
+	assert.Empty(t, skipped)
+	assert.Equal(t, "guardrails_overridden", operation.Errors[0].Type)
+
+	// A token confirms only the resources it was issued for
+	more := append(resources, backupResource("v1", "Secret", "kube-system", "bootstrap-token-abcdef", nil))
+	_, _, err = engine.applyGuardrails(checkpointTestOperation(context.Background(), request), more)
+	assert.Error(t, err)
+}
+
+const storedCoreDNS = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: coredns
+  namespace: kube-system
+`
+
+func TestLoadBackupData_Guardrails(t *testing.T) {
+	ctx := context.Background()
+	store := bucketStore(t)
+	manifestKey := "cluster.local/prod/_manifests/backup-1.json"
+	manifest := runManifest{}
+	require.NoError(t, json.Unmarshal(store["backups"][manifestKey].data, &manifest))
+	manifest.Objects = append(manifest.Objects, manifestObject{Namespace: "kube-system", Version: "v1", ResourceType: "configmaps", Name: "coredns",
+		Key: "cluster.local/prod/kube-system/configmaps/coredns.yaml", Checksum: checksumOf([]byte(storedCoreDNS))})
+	data, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	store.put("backups", manifestKey, data)
+	store.put("backups", "cluster.local/prod/kube-system/configmaps/coredns.yaml", []byte(storedCoreDNS))
+
+	config := &sharedconfig.SharedConfig{}
+	config.Storage.Bucket = "backups"
+	config.Cluster.Domain = "cluster.local"
+	engine := &RestoreEngine{config: config, objects: store, guardrails: NewGuardrails(config.Restore.Guardrails)}
+	request := RestoreRequest{BackupID: "backup-1", ClusterName: "prod"}
+	token := ConfirmationToken(request, []BackupResource{backupResource("v1", "ConfigMap", "kube-system", "coredns", nil)})
+
+	operation := checkpointTestOperation(ctx, request)
+	resources, err := engine.loadBackupData(operation)
+	require.NoError(t, err)
+	assert.Len(t, resources, 2)
+	require.Len(t, operation.Results.SkippedResources, 1)
+	assert.Contains(t, operation.Results.SkippedResources[0].Reason, token, "the results carry the token")
+
+	request.ForceUnsafe = true
+	request.ConfirmationToken = "0123456789abcdef"
+	_, err = engine.loadBackupData(checkpointTestOperation(ctx, request))
+	assert.ErrorContains(t, err, token, "a wrong token is rejected")
+
+	request.ConfirmationToken = token
+	operation = checkpointTestOperation(ctx, request)
+	resources, err = engine.loadBackupData(operation)
+	require.NoError(t, err)
+	assert.Len(t, resources, 3, "the token unlocks the protected resources")
+	assert.Empty(t, operation.Results.SkippedResources)
+}
@@ -0,0 +1,31 @@
+package restore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ChecksumMetadata is the object user metadata key under which backups record
+// the hex-encoded SHA-256 of each object
+const ChecksumMetadata = "Sha256"
+
+// ErrChecksumMismatch is returned for backup content that no longer matches
+// the checksum recorded when it was uploaded
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// VerifyChecksum rejects downloaded backup content whose SHA-256 differs from
+// any recorded checksum, typically the manifest entry and the object's
+// metadata. Empty checksums, from backups taken before they were recorded, are
+// skipped.
+func VerifyChecksum(key string, data []byte, recorded ...string) error {
+	sum := sha256.Sum256(data)
+	actual := hex.EncodeToString(sum[:])
+	for _, expected := range recorded {
+		if expected != "" && expected != actual {
+			return fmt.Errorf("%w: %s has sha256 %s, expected %s", ErrChecksumMismatch, key, actual, expected)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package restore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyChecksum(t *testing.T) {
+	data := []byte("apiVersion: v1\nkind: ConfigMap\n")
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	assert.NoError(t, VerifyChecksum("cm.yaml", data, checksum, checksum))
+	assert.NoError(t, VerifyChecksum("cm.yaml", data, "", checksum), "a missing checksum is skipped")
+	assert.NoError(t, VerifyChecksum("cm.yaml", data))
+
+	err := VerifyChecksum("cm.yaml", []byte("apiVersion: v1\nkind: Secret\n"), checksum)
+	assert.True(t, errors.Is(err, ErrChecksumMismatch))
+	assert.Contains(t, err.Error(), "cm.yaml")
+
+	err = VerifyChecksum("cm.yaml", data, checksum, "0000")
+	assert.True(t, errors.Is(err, ErrChecksumMismatch), "metadata disagreeing with the manifest is rejected")
+}
@@ -0,0 +1,618 @@
+package restore
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// imageTriggersAnnotation carries image change triggers on resources other
+// than DeploymentConfigs; OpenShift keeps honouring it on Deployments
+const imageTriggersAnnotation = "image.openshift.io/triggers"
+
+// deploymentConfigConverter restores OpenShift DeploymentConfigs as
+// Deployments, which OpenShift deprecated them in favour of. The selector,
+// strategy and image change triggers are translated; lifecycle hooks, custom
+// strategies and tests have no Deployment equivalent and are dropped, with
+// a change recorded for each.
+type deploymentConfigConverter struct{}
+
+func (c *deploymentConfigConverter) Name() string { return "deployment-config" }
+
+func (c *deploymentConfigConverter) Transform(obj *unstructured.Unstructured) ([]FieldChange, error) {
+	if obj.GetKind() != "DeploymentConfig" {
+		return nil, nil
+	}
+
+	oldAPIVersion := obj.GetAPIVersion()
+	obj.SetAPIVersion("apps/v1")
+	obj.SetKind("Deployment")
+	changes := []FieldChange{
+		{Field: "apiVersion", OldValue: oldAPIVersion, NewValue: "apps/v1", Action: "modified"},
+		{Field: "kind", OldValue: "DeploymentConfig", NewValue: "Deployment", Action: "modified"},
+	}
+
+	spec, _ := obj.Object["spec"].(map[string]interface{})
+	if spec == nil {
+		spec = make(map[string]interface{})
+		obj.Object["spec"] = spec
+	}
+
+	// DeploymentConfigs select by a plain label map, defaulting to the
+	// template's labels
+	selector, _, _ := unstructured.NestedStringMap(spec, "selector")
+	if len(selector) == 0 {
+		selector, _, _ = unstructured.NestedStringMap(spec, "template", "metadata", "labels")
+	}
+	matchLabels := make(map[string]interface{}, len(selector))
+	for key, value := range selector {
+		matchLabels[key] = value
+	}
+	spec["selector"] = map[string]interface{}{"matchLabels": matchLabels}
+	changes = append(changes, FieldChange{Field: "spec.selector", OldValue: selector, NewValue: spec["selector"], Action: "modified"})
+
+	strategyChanges := convertStrategy(spec)
+	changes = append(changes, strategyChanges...)
+
+	triggerChanges, err := convertTriggers(obj, spec)
+	if err != nil {
+		return changes, err
+	}
+	changes = append(changes, triggerChanges...)
+
+	if test, ok := spec["test"]; ok {
+		delete(spec, "test")
+		changes = append(changes, FieldChange{Field: "spec.test", OldValue: test, Action: "removed"})
+	}
+
+	delete(obj.Object, "status")
+	return changes, nil
+}
+
+// convertStrategy maps the Rolling and Recreate strategies to their
+// Deployment counterparts. Hooks and timing parameters are dropped; a
+// Custom strategy falls back to the default rolling update.
+func convertStrategy(spec map[string]interface{}) []FieldChange {
+	strategy, _ := spec["strategy"].(map[string]interface{})
+	if strategy == nil {
+		return nil
+	}
+
+	converted := map[string]interface{}{}
+	var changes []FieldChange
+	switch strategyType, _ := strategy["type"].(string); strategyType {
+	case "Recreate":
+		converted["type"] = "Recreate"
+		if params, ok := strategy["recreateParams"]; ok {
+			changes = append(changes, FieldChange{Field: "spec.strategy.recreateParams", OldValue: params, Action: "removed"})
+		}
+	case "Custom":
+		converted["type"] = "RollingUpdate"
+		changes = append(changes, FieldChange{Field: "spec.strategy.customParams", OldValue: strategy["customParams"], Action: "removed"})
+	default:
+		converted["type"] = "RollingUpdate"
+		if params, ok := strategy["rollingParams"].(map[string]interface{}); ok {
+			rollingUpdate := map[string]interface{}{}
+			for _, field := range []string{"maxSurge", "maxUnavailable"} {
+				if value, ok := params[field]; ok {
+					rollingUpdate[field] = value
+				}
+			}
+			if len(rollingUpdate) > 0 {
+				converted["rollingUpdate"] = rollingUpdate
+			}
+			for _, field := range []string{"pre", "post", "timeoutSeconds", "intervalSeconds", "updatePeriodSeconds"} {
+				if value, ok := params[field]; ok {
+					changes = append(changes, FieldChange{Field: "spec.strategy.rollingParams." + field, OldValue: value, Action: "removed"})
+				}
+			}
+		}
+	}
+
+	spec["strategy"] = converted
+	return append([]FieldChange{{Field: "spec.strategy", OldValue: strategy, NewValue: converted, Action: "modified"}}, changes...)
+}
+
+// imageTrigger is an entry of the image.openshift.io/triggers annotation
+type imageTrigger struct {
+	From      imageTriggerSource `json:"from"`
+	FieldPath string             `json:"fieldPath"`
+	Paused    bool               `json:"paused,omitempty"`
+}
+
+type imageTriggerSource struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// convertTriggers turns ImageChange triggers into the triggers annotation
+// and drops the rest: a Deployment always rolls out on a template change, as
+// the ConfigChange trigger did. Containers still waiting for their trigger
+// to fill in the image get the image it last resolved to.
+func convertTriggers(obj *unstructured.Unstructured, spec map[string]interface{}) ([]FieldChange, error) {
+	triggers, ok := spec["triggers"].([]interface{})
+	if !ok {
+		return nil, nil
+	}
+	delete(spec, "triggers")
+	changes := []FieldChange{{Field: "spec.triggers", OldValue: triggers, Action: "removed"}}
+
+	var imageTriggers []imageTrigger
+	for _, trigger := range triggers {
+		triggerMap, _ := trigger.(map[string]interface{})
+		if triggerType, _ := triggerMap["type"].(string); triggerType != "ImageChange" {
+			continue
+		}
+		params, _ := triggerMap["imageChangeParams"].(map[string]interface{})
+		from, _ := params["from"].(map[string]interface{})
+		source := imageTriggerSource{}
+		source.Kind, _ = from["kind"].(string)
+		source.Name, _ = from["name"].(string)
+		source.Namespace, _ = from["namespace"].(string)
+		automatic, _ := params["automatic"].(bool)
+		lastImage, _ := params["lastTriggeredImage"].(string)
+
+		containerNames, _, _ := unstructured.NestedStringSlice(params, "containerNames")
+		for _, name := range containerNames {
+			imageTriggers = append(imageTriggers, imageTrigger{
+				From:      source,
+				FieldPath: fmt.Sprintf(`spec.template.spec.containers[?(@.name=="%s")].image`, name),
+				Paused:    !automatic,
+			})
+			if lastImage != "" {
+				if change, ok := fillContainerImage(spec, name, lastImage); ok {
+					changes = append(changes, change)
+				}
+			}
+		}
+	}
+	if len(imageTriggers) == 0 {
+		return changes, nil
+	}
+
+	encoded, err := json.Marshal(imageTriggers)
+	if err != nil {
+		return changes, fmt.Errorf("failed to encode image triggers: %v", err)
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[imageTriggersAnnotation] = string(encoded)
+	obj.SetAnnotations(annotations)
+	return append(changes, FieldChange{Field: "metadata.annotations." + imageTriggersAnnotation, NewValue: string(encoded), Action: "added"}), nil
+}
+
+// fillContainerImage sets the image of a container whose image is blank,
+// as DeploymentConfigs leave it until their trigger resolves
+func fillContainerImage(spec map[string]interface{}, name, image string) (FieldChange, bool) {
+	containers, _, _ := unstructured.NestedSlice(spec, "template", "spec", "containers")
+	for i, container := range containers {
+		containerMap, _ := container.(map[string]interface{})
+		if containerMap["name"] != name {
+			continue
+		}
+		current, _ := containerMap["image"].(string)
+		if strings.TrimSpace(current) != "" {
+			return FieldChange{}, false
+		}
+		containerMap["image"] = image
+		if err := unstructured.SetNestedSlice(spec, containers, "template", "spec", "containers"); err != nil {
+			return FieldChange{}, false
+		}
+		return FieldChange{Field: fmt.Sprintf("spec.template.spec.containers[%d].image", i), OldValue: current, NewValue: image, Action: "modified"}, true
+	}
+	return FieldChange{}, false
+}
+
+// routeAnnotation is set by the backup on the TLS Secrets it builds from the
+// certificates set inline on Routes, and names the Route
+const routeAnnotation = "backup.cluster/route"
+
+// internalRegistries are the hosts the OpenShift internal registry serves
+// ImageStream images under
+var internalRegistries = []string{
+	"image-registry.openshift-image-registry.svc:5000",
+	"image-registry.openshift-image-registry.svc.cluster.local:5000",
+	"docker-registry.default.svc:5000",
+}
+
+// triggerFieldPath matches the container an image trigger updates
+var triggerFieldPath = regexp.MustCompile(`(initContainers|containers)\[\?\(@\.name=="([^"]+)"\)\]\.image$`)
+
+// ImageStreamTags is an imagestream-tags document the backup stores under
+// _openshift: the image each tag of an ImageStream resolved to
+type ImageStreamTags struct {
+	ImageStream string           `json:"imageStream" yaml:"imageStream"`
+	Namespace   string           `json:"namespace" yaml:"namespace"`
+	Tags        []ImageStreamTag `json:"tags" yaml:"tags"`
+}
+
+// ImageStreamTag is the image a tag resolved to when the backup was taken
+type ImageStreamTag struct {
+	Tag                  string `json:"tag" yaml:"tag"`
+	DockerImageReference string `json:"dockerImageReference" yaml:"dockerImageReference"`
+	Image                string `json:"image,omitempty" yaml:"image,omitempty"`
+	Created              string `json:"created,omitempty" yaml:"created,omitempty"`
+}
+
+// openShiftConverter restores an OpenShift backup into a cluster without the
+// OpenShift APIs. DeploymentConfigs become Deployments and Routes Ingresses,
+// and the images workloads take from ImageStream tags, through an image
+// trigger or the internal registry, are pinned to the digest the tag pointed
+// to. It needs the rest of the restore to look these up, see index.
+type openShiftConverter struct {
+	ingressClassName  string
+	deploymentConfigs deploymentConfigConverter
+
+	// "namespace/stream:tag" to the image reference the tag resolved to
+	imageTags map[string]string
+	// "namespace/route" to the Secret holding the Route's certificate
+	routeSecrets map[string]string
+	// "namespace/service" to the Service's ports
+	servicePorts map[string][]interface{}
+}
+
+func newOpenShiftConverter(ingressClassName string) *openShiftConverter {
+	return &openShiftConverter{
+		ingressClassName: ingressClassName,
+		imageTags:        make(map[string]string),
+		routeSecrets:     make(map[string]string),
+		servicePorts:     make(map[string][]interface{}),
+	}
+}
+
+func (c *openShiftConverter) Name() string { return "openshift" }
+
+func (c *openShiftConverter) Transform(obj *unstructured.Unstructured) ([]FieldChange, error) {
+	var changes []FieldChange
+	switch obj.GetKind() {
+	case "Route":
+		return c.convertRoute(obj)
+	case "DeploymentConfig":
+		dcChanges, err := c.deploymentConfigs.Transform(obj)
+		if err != nil {
+			return dcChanges, err
+		}
+		changes = dcChanges
+	}
+
+	imageChanges, err := c.resolveImages(obj)
+	return append(changes, imageChanges...), err
+}
+
+// withRouteSecrets adds the Route certificates captured under _openshift to
+// resources, except those the namespace backup already holds
+func withRouteSecrets(resources, secrets []BackupResource) []BackupResource {
+	present := make(map[string]bool)
+	for _, resource := range resources {
+		if resource.Kind == "Secret" {
+			present[resource.Namespace+"/"+resource.Name] = true
+		}
+	}
+	for _, secret := range secrets {
+		if !present[secret.Namespace+"/"+secret.Name] {
+			present[secret.Namespace+"/"+secret.Name] = true
+			resources = append(resources, secret)
+		}
+	}
+	return resources
+}
+
+// index records the ImageStream tags, Route certificates and Service ports
+// of a restore and returns the resources to apply: OpenShift API objects
+// other than DeploymentConfigs and Routes have nothing to become on the
+// target and are skipped. Tags captured under _openshift take precedence
+// over those read from ImageStreams, which keep their status only when the
+// backup included it.
+func (c *openShiftConverter) index(resources []BackupResource, imageStreamTags []ImageStreamTags) ([]BackupResource, []SkippedResource) {
+	var kept []BackupResource
+	var skipped []SkippedResource
+	for _, resource := range resources {
+		switch resource.Kind {
+		case "ImageStream":
+			c.indexImageStream(resource)
+		case "Secret":
+			if route, _, _ := unstructured.NestedString(resource.Data, "metadata", "annotations", routeAnnotation); route != "" {
+				c.routeSecrets[resource.Namespace+"/"+route] = resource.Name
+			}
+		case "Service":
+			ports, _, _ := unstructured.NestedSlice(resource.Data, "spec", "ports")
+			c.servicePorts[resource.Namespace+"/"+resource.Name] = ports
+		}
+
+		if gv, _ := schema.ParseGroupVersion(resource.APIVersion); strings.HasSuffix(gv.Group, ".openshift.io") &&
+			resource.Kind != "DeploymentConfig" && resource.Kind != "Route" {
+			skipped = append(skipped, SkippedResource{
+				APIVersion: resource.APIVersion,
+				Kind:       resource.Kind,
+				Namespace:  resource.Namespace,
+				Name:       resource.Name,
+				Reason:     fmt.Sprintf("%s is an OpenShift API with no Kubernetes equivalent", resource.APIVersion),
+				Timestamp:  time.Now(),
+			})
+			continue
+		}
+		kept = append(kept, resource)
+	}
+
+	for _, stream := range imageStreamTags {
+		for _, tag := range stream.Tags {
+			if tag.DockerImageReference != "" {
+				c.imageTags[imageTagKey(stream.Namespace, stream.ImageStream, tag.Tag)] = tag.DockerImageReference
+			}
+		}
+	}
+	return kept, skipped
+}
+
+// indexImageStream records the newest image of each status tag or, without
+// a status, the external image a spec tag imports
+func (c *openShiftConverter) indexImageStream(resource BackupResource) {
+	specTags, _, _ := unstructured.NestedSlice(resource.Data, "spec", "tags")
+	for _, item := range specTags {
+		tag, _ := item.(map[string]interface{})
+		name, _ := tag["name"].(string)
+		kind, _, _ := unstructured.NestedString(tag, "from", "kind")
+		image, _, _ := unstructured.NestedString(tag, "from", "name")
+		if kind == "DockerImage" && image != "" {
+			c.imageTags[imageTagKey(resource.Namespace, resource.Name, name)] = image
+		}
+	}
+
+	statusTags, _, _ := unstructured.NestedSlice(resource.Data, "status", "tags")
+	for _, item := range statusTags {
+		tag, _ := item.(map[string]interface{})
+		name, _ := tag["tag"].(string)
+		items, _, _ := unstructured.NestedSlice(tag, "items")
+		if len(items) == 0 {
+			continue
+		}
+		latest, _ := items[0].(map[string]interface{})
+		if reference, _ := latest["dockerImageReference"].(string); reference != "" {
+			c.imageTags[imageTagKey(resource.Namespace, resource.Name, name)] = reference
+		}
+	}
+}
+
+// resolveImages pins the images of a workload's containers that come from
+// ImageStream tags, and drops the image triggers no controller on the
+// target acts on
+func (c *openShiftConverter) resolveImages(obj *unstructured.Unstructured) ([]FieldChange, error) {
+	podSpecPath, ok := podSpecPaths[obj.GetKind()]
+	if !ok {
+		return nil, nil
+	}
+
+	// Images the triggers set, by container field and name
+	triggered := make(map[string]string)
+	var changes []FieldChange
+	annotations := obj.GetAnnotations()
+	if encoded, ok := annotations[imageTriggersAnnotation]; ok {
+		var triggers []imageTrigger
+		if err := json.Unmarshal([]byte(encoded), &triggers); err != nil {
+			return nil, fmt.Errorf("failed to parse %s annotation: %v", imageTriggersAnnotation, err)
+		}
+		for _, trigger := range triggers {
+			match := triggerFieldPath.FindStringSubmatch(trigger.FieldPath)
+			if match == nil {
+				continue
+			}
+			if image, ok := c.triggerImage(trigger.From, obj.GetNamespace()); ok {
+				triggered[match[1]+"/"+match[2]] = image
+			}
+		}
+		delete(annotations, imageTriggersAnnotation)
+		obj.SetAnnotations(annotations)
+		changes = append(changes, FieldChange{Field: "metadata.annotations." + imageTriggersAnnotation, OldValue: encoded, Action: "removed"})
+	}
+
+	for _, containerField := range []string{"initContainers", "containers"} {
+		containerChanges, err := rewriteEach(obj.Object, append(podSpecPath, containerField), func(container map[string]interface{}, path string) []FieldChange {
+			name, _ := container["name"].(string)
+			image, _ := container["image"].(string)
+			resolved, ok := triggered[containerField+"/"+name]
+			if !ok {
+				resolved, ok = c.registryImage(image)
+			}
+			if !ok || resolved == image {
+				return nil
+			}
+			container["image"] = resolved
+			return []FieldChange{{Field: path + ".image", OldValue: image, NewValue: resolved, Action: "modified"}}
+		})
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, containerChanges...)
+	}
+	return changes, nil
+}
+
+// triggerImage returns the image an image trigger would set
+func (c *openShiftConverter) triggerImage(from imageTriggerSource, namespace string) (string, bool) {
+	if from.Namespace != "" {
+		namespace = from.Namespace
+	}
+	switch from.Kind {
+	case "ImageStreamTag":
+		stream, tag := splitImageTag(from.Name)
+		image, ok := c.imageTags[imageTagKey(namespace, stream, tag)]
+		return image, ok
+	case "DockerImage":
+		return from.Name, from.Name != ""
+	}
+	return "", false
+}
+
+// registryImage resolves an image pulled by tag from the internal registry,
+// where the path is the ImageStream's namespace and name
+func (c *openShiftConverter) registryImage(image string) (string, bool) {
+	for _, registry := range internalRegistries {
+		path := strings.TrimPrefix(image, registry+"/")
+		if path == image || strings.Contains(path, "@") {
+			continue
+		}
+		parts := strings.Split(path, "/")
+		if len(parts) != 2 {
+			return "", false
+		}
+		stream, tag := splitImageTag(parts[1])
+		resolved, ok := c.imageTags[imageTagKey(parts[0], stream, tag)]
+		return resolved, ok
+	}
+	return "", false
+}
+
+// convertRoute replaces a Route with an Ingress serving the same host and
+// path from the same Service. Edge and re-encrypt Routes terminate TLS with
+// their certificate's Secret. Passthrough termination, alternate backends
+// and the other router settings an Ingress cannot express are dropped, with
+// a change recorded for each.
+func (c *openShiftConverter) convertRoute(obj *unstructured.Unstructured) ([]FieldChange, error) {
+	spec, _ := obj.Object["spec"].(map[string]interface{})
+	host, _ := spec["host"].(string)
+	path, _ := spec["path"].(string)
+	if path == "" {
+		path = "/"
+	}
+
+	service, _, _ := unstructured.NestedString(spec, "to", "name")
+	if service == "" {
+		return nil, fmt.Errorf("route %s has no target Service", obj.GetName())
+	}
+	port, err := c.routePort(obj.GetNamespace(), service, spec)
+	if err != nil {
+		return nil, fmt.Errorf("route %s: %v", obj.GetName(), err)
+	}
+
+	rule := map[string]interface{}{
+		"http": map[string]interface{}{"paths": []interface{}{map[string]interface{}{
+			"path":     path,
+			"pathType": "Prefix",
+			"backend":  map[string]interface{}{"service": map[string]interface{}{"name": service, "port": port}},
+		}}},
+	}
+	if host != "" {
+		rule["host"] = host
+	}
+	ingressSpec := map[string]interface{}{"rules": []interface{}{rule}}
+	if c.ingressClassName != "" {
+		ingressSpec["ingressClassName"] = c.ingressClassName
+	}
+
+	var changes []FieldChange
+	for _, field := range []string{"alternateBackends", "wildcardPolicy", "subdomain"} {
+		if value, ok := spec[field]; ok {
+			changes = append(changes, FieldChange{Field: "spec." + field, OldValue: value, Action: "removed"})
+		}
+	}
+	if tls, ok := spec["tls"].(map[string]interface{}); ok {
+		termination, _ := tls["termination"].(string)
+		if termination == "passthrough" {
+			changes = append(changes, FieldChange{Field: "spec.tls", OldValue: tls, Action: "removed"})
+		} else {
+			ingressTLS := map[string]interface{}{}
+			if host != "" {
+				ingressTLS["hosts"] = []interface{}{host}
+			}
+			if secret := c.routeSecret(obj.GetNamespace(), obj.GetName(), tls); secret != "" {
+				ingressTLS["secretName"] = secret
+			}
+			ingressSpec["tls"] = []interface{}{ingressTLS}
+			for _, field := range []string{"insecureEdgeTerminationPolicy", "destinationCACertificate"} {
+				if value, ok := tls[field]; ok {
+					changes = append(changes, FieldChange{Field: "spec.tls." + field, OldValue: value, Action: "removed"})
+				}
+			}
+		}
+	}
+
+	oldAPIVersion := obj.GetAPIVersion()
+	obj.SetAPIVersion("networking.k8s.io/v1")
+	obj.SetKind("Ingress")
+	obj.Object["spec"] = ingressSpec
+	delete(obj.Object, "status")
+	return append([]FieldChange{
+		{Field: "apiVersion", OldValue: oldAPIVersion, NewValue: "networking.k8s.io/v1", Action: "modified"},
+		{Field: "kind", OldValue: "Route", NewValue: "Ingress", Action: "modified"},
+		{Field: "spec", OldValue: spec, NewValue: ingressSpec, Action: "modified"},
+	}, changes...), nil
+}
+
+// routePort returns the Ingress backend port for a Route. A Route names the
+// Service port or gives the target port behind it; without one it is served
+// on the Service's first port.
+func (c *openShiftConverter) routePort(namespace, service string, spec map[string]interface{}) (map[string]interface{}, error) {
+	ports, known := c.servicePorts[namespace+"/"+service]
+	targetPort, found, _ := unstructured.NestedFieldNoCopy(spec, "port", "targetPort")
+	if found {
+		if name, ok := targetPort.(string); ok {
+			return map[string]interface{}{"name": name}, nil
+		}
+		number, ok := toInt64(targetPort)
+		if !ok {
+			return nil, fmt.Errorf("invalid target port %v", targetPort)
+		}
+		for _, port := range ports {
+			portMap, _ := port.(map[string]interface{})
+			if servicePortTarget, ok := toInt64(portMap["targetPort"]); ok && servicePortTarget == number {
+				number, _ = toInt64(portMap["port"])
+				break
+			}
+		}
+		return map[string]interface{}{"number": number}, nil
+	}
+
+	if !known || len(ports) == 0 {
+		return nil, fmt.Errorf("no target port and Service %s is not in the backup", service)
+	}
+	first, _ := ports[0].(map[string]interface{})
+	number, ok := toInt64(first["port"])
+	if !ok {
+		return nil, fmt.Errorf("no port number on Service %s", service)
+	}
+	return map[string]interface{}{"number": number}, nil
+}
+
+// routeSecret returns the Secret holding a Route's certificate, if the
+// backup has one; without it the Ingress controller's default certificate
+// is served, as the router's was
+func (c *openShiftConverter) routeSecret(namespace, route string, tls map[string]interface{}) string {
+	if name, _, _ := unstructured.NestedString(tls, "externalCertificate", "name"); name != "" {
+		return name
+	}
+	return c.routeSecrets[namespace+"/"+route]
+}
+
+// imageTagKey identifies an ImageStream tag across namespaces
+func imageTagKey(namespace, stream, tag string) string {
+	return namespace + "/" + stream + ":" + tag
+}
+
+// splitImageTag splits "stream:tag", defaulting the tag to latest
+func splitImageTag(name string) (string, string) {
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return name, "latest"
+}
+
+// toInt64 reads a whole number decoded from YAML or JSON
+func toInt64(value interface{}) (int64, bool) {
+	switch number := value.(type) {
+	case int64:
+		return number, true
+	case int:
+		return int64(number), true
+	case float64:
+		return int64(number), number == float64(int64(number))
+	}
+	return 0, false
+}
@@ -0,0 +1,277 @@
+package restore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newDeploymentConfig() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps.openshift.io/v1",
+		"kind":       "DeploymentConfig",
+		"metadata":   map[string]interface{}{"name": "api", "namespace": "shop"},
+		"spec": map[string]interface{}{
+			"replicas": int64(2),
+			"selector": map[string]interface{}{"app": "api"},
+			"strategy": map[string]interface{}{
+				"type": "Rolling",
+				"rollingParams": map[string]interface{}{
+					"maxSurge":       "25%",
+					"maxUnavailable": int64(0),
+					"timeoutSeconds": int64(600),
+					"pre":            map[string]interface{}{"failurePolicy": "Abort"},
+				},
+			},
+			"triggers": []interface{}{
+				map[string]interface{}{"type": "ConfigChange"},
+				map[string]interface{}{
+					"type": "ImageChange",
+					"imageChangeParams": map[string]interface{}{
+						"automatic":          true,
+						"containerNames":     []interface{}{"api"},
+						"from":               map[string]interface{}{"kind": "ImageStreamTag", "name": "api:latest"},
+						"lastTriggeredImage": "image-registry.openshift-image-registry.svc:5000/shop/api@sha256:4f3c",
+					},
+				},
+			},
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "api"}},
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "api", "image": " "},
+						map[string]interface{}{"name": "proxy", "image": "envoyproxy/envoy:v1.30"},
+					},
+				},
+			},
+		},
+		"status": map[string]interface{}{"latestVersion": int64(7)},
+	}}
+}
+
+func TestDeploymentConfigConverter(t *testing.T) {
+	obj := newDeploymentConfig()
+
+	changes, err := NewTransformEngine(TransformConfig{ConvertDeploymentConfigs: true}).Apply(obj)
+	require.NoError(t, err)
+
+	assert.Equal(t, "apps/v1", obj.GetAPIVersion())
+	assert.Equal(t, "Deployment", obj.GetKind())
+	assert.NotContains(t, obj.Object, "status")
+
+	matchLabels, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "selector", "matchLabels")
+	assert.Equal(t, map[string]string{"app": "api"}, matchLabels)
+	strategy, _, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "strategy")
+	assert.Equal(t, map[string]interface{}{
+		"type":          "RollingUpdate",
+		"rollingUpdate": map[string]interface{}{"maxSurge": "25%", "maxUnavailable": int64(0)},
+	}, strategy)
+	_, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "triggers")
+	assert.False(t, found)
+
+	containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	assert.Equal(t, "image-registry.openshift-image-registry.svc:5000/shop/api@sha256:4f3c", containers[0].(map[string]interface{})["image"])
+	assert.Equal(t, "envoyproxy/envoy:v1.30", containers[1].(map[string]interface{})["image"])
+	assert.JSONEq(t,
+		`[{"from":{"kind":"ImageStreamTag","name":"api:latest"},"fieldPath":"spec.template.spec.containers[?(@.name==\"api\")].image"}]`,
+		obj.GetAnnotations()[imageTriggersAnnotation])
+
+	removed := map[string]bool{}
+	for _, change := range changes {
+		if change.Action == "removed" {
+			removed[change.Field] = true
+		}
+	}
+	assert.Equal(t, map[string]bool{
+		"spec.triggers":                              true,
+		"spec.strategy.rollingParams.pre":            true,
+		"spec.strategy.rollingParams.timeoutSeconds": true,
+	}, removed)
+}
+
+func TestDeploymentConfigConverter_RecreateWithoutSelector(t *testing.T) {
+	obj := newDeploymentConfig()
+	spec := obj.Object["spec"].(map[string]interface{})
+	delete(spec, "selector")
+	delete(spec, "triggers")
+	spec["strategy"] = map[string]interface{}{"type": "Recreate", "recreateParams": map[string]interface{}{"timeoutSeconds": int64(60)}}
+
+	_, err := (&deploymentConfigConverter{}).Transform(obj)
+	require.NoError(t, err)
+
+	matchLabels, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "selector", "matchLabels")
+	assert.Equal(t, map[string]string{"app": "api"}, matchLabels, "defaults to the template labels")
+	strategyType, _, _ := unstructured.NestedString(obj.Object, "spec", "strategy", "type")
+	assert.Equal(t, "Recreate", strategyType)
+	assert.Empty(t, obj.GetAnnotations())
+}
+
+func TestDeploymentConfigConverter_OtherKinds(t *testing.T) {
+	obj := newObject("Deployment", map[string]interface{}{"spec": map[string]interface{}{}})
+
+	changes, err := (&deploymentConfigConverter{}).Transform(obj)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+	assert.Equal(t, "Deployment", obj.GetKind())
+}
+
+func convertOpenShift(t *testing.T, config TransformConfig, resources []BackupResource, tags []ImageStreamTags) (map[string]*unstructured.Unstructured, []SkippedResource) {
+	config.ConvertOpenShift = true
+	engine := NewTransformEngine(config)
+	kept, skipped := engine.openShift.index(resources, tags)
+
+	converted := make(map[string]*unstructured.Unstructured)
+	for _, resource := range kept {
+		obj := backupObject(resource)
+		_, err := engine.Apply(obj)
+		require.NoError(t, err)
+		converted[resource.Kind+"/"+resource.Name] = obj
+	}
+	return converted, skipped
+}
+
+func TestOpenShiftConverter(t *testing.T) {
+	dc := newDeploymentConfig()
+	resources := []BackupResource{
+		{APIVersion: "apps.openshift.io/v1", Kind: "DeploymentConfig", Namespace: "shop", Name: "api", Data: dc.Object},
+		{APIVersion: "image.openshift.io/v1", Kind: "ImageStream", Namespace: "shop", Name: "api", Data: map[string]interface{}{}},
+		{APIVersion: "build.openshift.io/v1", Kind: "BuildConfig", Namespace: "shop", Name: "api", Data: map[string]interface{}{}},
+		{APIVersion: "batch/v1", Kind: "CronJob", Namespace: "shop", Name: "report", Data: map[string]interface{}{
+			"spec": map[string]interface{}{"jobTemplate": map[string]interface{}{"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+				"containers": []interface{}{
+					map[string]interface{}{"name": "report", "image": "image-registry.openshift-image-registry.svc:5000/shop/report"},
+					map[string]interface{}{"name": "pinned", "image": "image-registry.openshift-image-registry.svc:5000/shop/report@sha256:77aa"},
+				},
+			}}}}},
+		}},
+	}
+	tags := []ImageStreamTags{
+		{ImageStream: "api", Namespace: "shop", Tags: []ImageStreamTag{{Tag: "latest", DockerImageReference: "quay.io/shop/api@sha256:9e9e"}}},
+		{ImageStream: "report", Namespace: "shop", Tags: []ImageStreamTag{{Tag: "latest", DockerImageReference: "quay.io/shop/report@sha256:5d5d"}}},
+	}
+
+	converted, skipped := convertOpenShift(t, TransformConfig{RegistryMappings: map[string]string{"quay.io": "mirror.example.com"}}, resources, tags)
+
+	require.Len(t, skipped, 2)
+	assert.Equal(t, "ImageStream", skipped[0].Kind)
+	assert.Equal(t, "BuildConfig", skipped[1].Kind)
+	assert.Contains(t, skipped[1].Reason, "build.openshift.io/v1 is an OpenShift API")
+
+	deployment := converted["DeploymentConfig/api"]
+	assert.Equal(t, "Deployment", deployment.GetKind())
+	assert.NotContains(t, deployment.GetAnnotations(), imageTriggersAnnotation)
+	containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+	assert.Equal(t, "mirror.example.com/shop/api@sha256:9e9e", containers[0].(map[string]interface{})["image"], "resolved from the tag, then mapped")
+	assert.Equal(t, "envoyproxy/envoy:v1.30", containers[1].(map[string]interface{})["image"])
+
+	containers, _, _ = unstructured.NestedSlice(converted["CronJob/report"].Object, "spec", "jobTemplate", "spec", "template", "spec", "containers")
+	assert.Equal(t, "mirror.example.com/shop/report@sha256:5d5d", containers[0].(map[string]interface{})["image"])
+	assert.Equal(t, "image-registry.openshift-image-registry.svc:5000/shop/report@sha256:77aa", containers[1].(map[string]interface{})["image"], "digests are left to registry mappings")
+}
+
+func TestOpenShiftConverter_ImageStreamStatus(t *testing.T) {
+	resources := []BackupResource{
+		{APIVersion: "image.openshift.io/v1", Kind: "ImageStream", Namespace: "shop", Name: "api", Data: map[string]interface{}{
+			"spec": map[string]interface{}{"tags": []interface{}{
+				map[string]interface{}{"name": "stable", "from": map[string]interface{}{"kind": "DockerImage", "name": "quay.io/shop/api:1.4"}},
+				map[string]interface{}{"name": "latest", "from": map[string]interface{}{"kind": "DockerImage", "name": "quay.io/shop/api:latest"}},
+			}},
+			"status": map[string]interface{}{"tags": []interface{}{
+				map[string]interface{}{"tag": "latest", "items": []interface{}{
+					map[string]interface{}{"dockerImageReference": "quay.io/shop/api@sha256:9e9e"},
+					map[string]interface{}{"dockerImageReference": "quay.io/shop/api@sha256:0a1b"},
+				}},
+			}},
+		}},
+		{APIVersion: "apps/v1", Kind: "StatefulSet", Namespace: "shop", Name: "api", Data: map[string]interface{}{
+			"metadata": map[string]interface{}{"annotations": map[string]interface{}{
+				imageTriggersAnnotation: `[{"from":{"kind":"ImageStreamTag","name":"api:stable"},"fieldPath":"spec.template.spec.initContainers[?(@.name==\"migrate\")].image"},` +
+					`{"from":{"kind":"ImageStreamTag","name":"api:latest"},"fieldPath":"spec.template.spec.containers[?(@.name==\"api\")].image"}]`,
+			}},
+			"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+				"initContainers": []interface{}{map[string]interface{}{"name": "migrate", "image": "api:stable"}},
+				"containers":     []interface{}{map[string]interface{}{"name": "api", "image": "api:latest"}},
+			}}},
+		}},
+	}
+
+	converted, _ := convertOpenShift(t, TransformConfig{}, resources, nil)
+
+	statefulSet := converted["StatefulSet/api"]
+	assert.Empty(t, statefulSet.GetAnnotations())
+	initContainers, _, _ := unstructured.NestedSlice(statefulSet.Object, "spec", "template", "spec", "initContainers")
+	assert.Equal(t, "quay.io/shop/api:1.4", initContainers[0].(map[string]interface{})["image"], "spec tag without a status")
+	containers, _, _ := unstructured.NestedSlice(statefulSet.Object, "spec", "template", "spec", "containers")
+	assert.Equal(t, "quay.io/shop/api@sha256:9e9e", containers[0].(map[string]interface{})["image"], "newest status item")
+}
+
+func TestOpenShiftConverter_Routes(t *testing.T) {
+	resources := []BackupResource{
+		{APIVersion: "v1", Kind: "Service", Namespace: "shop", Name: "api", Data: map[string]interface{}{
+			"spec": map[string]interface{}{"ports": []interface{}{
+				map[string]interface{}{"name": "http", "port": int64(80), "targetPort": int64(8080)},
+				map[string]interface{}{"name": "metrics", "port": int64(9090), "targetPort": int64(9090)},
+			}},
+		}},
+		{APIVersion: "v1", Kind: "Secret", Namespace: "shop", Name: "api-tls", Data: map[string]interface{}{
+			"metadata": map[string]interface{}{"annotations": map[string]interface{}{routeAnnotation: "api"}},
+		}},
+		{APIVersion: "route.openshift.io/v1", Kind: "Route", Namespace: "shop", Name: "api", Data: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"host": "api.apps.example.com",
+				"path": "/v1",
+				"to":   map[string]interface{}{"kind": "Service", "name": "api", "weight": int64(100)},
+				"port": map[string]interface{}{"targetPort": int64(8080)},
+				"tls":  map[string]interface{}{"termination": "edge", "certificate": "CERT", "key": "KEY", "insecureEdgeTerminationPolicy": "Redirect"},
+			},
+			"status": map[string]interface{}{"ingress": []interface{}{}},
+		}},
+		{APIVersion: "route.openshift.io/v1", Kind: "Route", Namespace: "shop", Name: "web", Data: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"to":                map[string]interface{}{"kind": "Service", "name": "api"},
+				"tls":               map[string]interface{}{"termination": "passthrough"},
+				"alternateBackends": []interface{}{map[string]interface{}{"kind": "Service", "name": "api-canary"}},
+			},
+		}},
+	}
+
+	converted, _ := convertOpenShift(t, TransformConfig{
+		IngressClassName:    "nginx",
+		IngressHostMappings: map[string]string{"apps.example.com": "apps.k8s.example.com"},
+	}, resources, nil)
+
+	api := converted["Route/api"]
+	assert.Equal(t, "networking.k8s.io/v1", api.GetAPIVersion())
+	assert.Equal(t, "Ingress", api.GetKind())
+	assert.NotContains(t, api.Object, "status")
+	assert.Equal(t, map[string]interface{}{
+		"ingressClassName": "nginx",
+		"rules": []interface{}{map[string]interface{}{
+			"host": "api.apps.k8s.example.com",
+			"http": map[string]interface{}{"paths": []interface{}{map[string]interface{}{
+				"path":     "/v1",
+				"pathType": "Prefix",
+				"backend":  map[string]interface{}{"service": map[string]interface{}{"name": "api", "port": map[string]interface{}{"number": int64(80)}}},
+			}}},
+		}},
+		"tls": []interface{}{map[string]interface{}{"hosts": []interface{}{"api.apps.k8s.example.com"}, "secretName": "api-tls"}},
+	}, api.Object["spec"])
+
+	web := converted["Route/web"]
+	rules, _, _ := unstructured.NestedSlice(web.Object, "spec", "rules")
+	assert.Equal(t, map[string]interface{}{"paths": []interface{}{map[string]interface{}{
+		"path":     "/",
+		"pathType": "Prefix",
+		"backend":  map[string]interface{}{"service": map[string]interface{}{"name": "api", "port": map[string]interface{}{"number": int64(80)}}},
+	}}}, rules[0].(map[string]interface{})["http"], "first Service port, no host")
+	assert.NotContains(t, web.Object["spec"], "tls", "passthrough cannot be expressed")
+}
+
+func TestOpenShiftConverter_RouteWithoutPort(t *testing.T) {
+	route := newObject("Route", map[string]interface{}{"spec": map[string]interface{}{"to": map[string]interface{}{"kind": "Service", "name": "missing"}}})
+
+	_, err := newOpenShiftConverter("").Transform(route)
+	assert.EqualError(t, err, "route test: no target port and Service missing is not in the backup")
+}
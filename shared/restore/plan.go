@@ -0,0 +1,503 @@
+package restore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// Actions a restore plan assigns to a resource
+const (
+	PlanActionCreate = "create"
+	PlanActionUpdate = "update"
+	PlanActionMerge  = "merge"
+	PlanActionSkip   = "skip"
+	PlanActionFail   = "fail"
+)
+
+// Outcomes of the server-side dry-run of a planned resource
+const (
+	DryRunPassed     = "passed"
+	DryRunRejected   = "rejected"
+	DryRunNotChecked = "not_checked"
+)
+
+// admissionDenials find the webhook or ValidatingAdmissionPolicy named in an
+// API server rejection
+var admissionDenials = []*regexp.Regexp{
+	regexp.MustCompile(`admission webhook "([^"]+)" denied the request`),
+	regexp.MustCompile(`ValidatingAdmissionPolicy '([^']+)'`),
+}
+
+// planSymbols prefix each action in the text rendering of a plan
+var planSymbols = map[string]string{
+	PlanActionCreate: "+",
+	PlanActionUpdate: "~",
+	PlanActionMerge:  "~",
+	PlanActionSkip:   "=",
+	PlanActionFail:   "!",
+}
+
+// phaseNames names the restore phase of each kind rank
+var phaseNames = map[int]string{
+	rankCRD:           "crds",
+	rankNamespace:     "namespaces",
+	rankGovernance:    "governance",
+	rankClusterScoped: "cluster-resources",
+	rankAccess:        "access",
+	rankConfig:        "config",
+	rankStorage:       "storage",
+	rankService:       "services",
+	rankEndpoints:     "endpoints",
+	rankWorkload:      "workloads",
+	rankWorkloadAddon: "workload-addons",
+	rankRouting:       "routing",
+	rankCustom:        "custom-resources",
+	rankAdmission:     "admission",
+}
+
+// RestorePlanReport is what a restore would apply, in the order it would
+// apply it, checked against the live cluster without changing anything
+type RestorePlanReport struct {
+	BackupID         string           `json:"backup_id"`
+	ClusterName      string           `json:"cluster_name"`
+	ConflictStrategy ConflictStrategy `json:"conflict_strategy"`
+	GeneratedAt      time.Time        `json:"generated_at"`
+	Phases           []PlanPhase      `json:"phases"`
+	Summary          PlanSummary      `json:"summary"`
+	// Rejections collects the resources the server-side dry-run rejected
+	Rejections []AdmissionRejection `json:"rejections,omitempty"`
+	// Warnings lists dependency cycles the restore order had to break and
+	// resources the restore leaves out
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// PlanPhase is a run of consecutive resources of the same restore rank
+type PlanPhase struct {
+	Name      string            `json:"name"`
+	Resources []PlannedResource `json:"resources"`
+}
+
+// PlannedResource is one resource of a plan and what the restore would do
+// with it. Differences lists the fields set in the backup whose live value
+// differs, for resources that already exist. DryRun is set when the request
+// asked for a server-side dry-run.
+type PlannedResource struct {
+	APIVersion  string        `json:"api_version"`
+	Kind        string        `json:"kind"`
+	Namespace   string        `json:"namespace,omitempty"`
+	Name        string        `json:"name"`
+	Action      string        `json:"action"`
+	Exists      bool          `json:"exists"`
+	Reason      string        `json:"reason,omitempty"`
+	Differences []string      `json:"differences,omitempty"`
+	Transforms  []FieldChange `json:"transforms,omitempty"`
+	DryRun      string        `json:"dry_run,omitempty"`
+	// DryRunNote says why a resource was not checked
+	DryRunNote string              `json:"dry_run_note,omitempty"`
+	Rejection  *AdmissionRejection `json:"rejection,omitempty"`
+}
+
+// AdmissionRejection is why the API server refused a dry-run apply: a
+// validation error, or an admission webhook or policy denying it
+type AdmissionRejection struct {
+	Resource string `json:"resource"`
+	Webhook  string `json:"webhook,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Message  string `json:"message"`
+}
+
+// PlanSummary counts the planned resources by action
+type PlanSummary struct {
+	Total  int `json:"total"`
+	Create int `json:"create"`
+	Update int `json:"update"`
+	Merge  int `json:"merge"`
+	Skip   int `json:"skip"`
+	Fail   int `json:"fail"`
+	// Rejected counts resources the server-side dry-run rejected
+	Rejected int `json:"rejected,omitempty"`
+}
+
+// PlanRestore loads the backup of a request and returns the plan of what
+// restoring it would do to the target cluster. Nothing is applied; with
+// ServerDryRun set every create and update is sent as a dry-run so admission
+// webhooks and policies can reject it.
+func (re *RestoreEngine) PlanRestore(ctx context.Context, request RestoreRequest) (*RestorePlanReport, error) {
+	operation := &RestoreOperation{
+		Request:  request,
+		Progress: RestoreProgress{ResourceBreakdown: make(map[string]int)},
+		ctx:      ctx,
+	}
+	if !request.Transforms.IsEmpty() {
+		operation.transformer = NewTransformEngine(*request.Transforms)
+	}
+
+	resources, err := re.loadBackupData(operation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load backup data: %v", err)
+	}
+	return re.planResources(operation, resources)
+}
+
+// planResources orders resources as restoreResources would and decides the
+// action for each against the live cluster
+func (re *RestoreEngine) planResources(operation *RestoreOperation, resources []BackupResource) (*RestorePlanReport, error) {
+	request := operation.Request
+	resolver := re.resolverFor(request)
+	order := resolver.Resolve(resources)
+	report := &RestorePlanReport{
+		BackupID:         request.BackupID,
+		ClusterName:      request.ClusterName,
+		ConflictStrategy: request.ConflictStrategy,
+		GeneratedAt:      time.Now(),
+		Warnings:         order.Warnings,
+	}
+	for _, skipped := range operation.Results.SkippedResources {
+		name := skipped.Name
+		if skipped.Namespace != "" {
+			name = skipped.Namespace + "/" + name
+		}
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%s %s is not restored: %s", skipped.Kind, name, skipped.Reason))
+	}
+	for _, failed := range operation.Results.FailedResources {
+		name := failed.Name
+		if failed.Namespace != "" {
+			name = failed.Namespace + "/" + name
+		}
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%s %s cannot be restored: %s", failed.Kind, name, failed.Error))
+	}
+
+	config := CRDRestoreConfig{}
+	if request.CRDs != nil {
+		config = *request.CRDs
+	}
+	// CRDs and namespaces the restore creates, whose custom resources and
+	// members cannot exist yet
+	created := make(map[string]bool)
+
+	lastRank := -1
+	for _, resource := range order.Ordered {
+		if err := operation.ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var planned PlannedResource
+		if resource.Kind == "CustomResourceDefinition" {
+			planned = re.planCRD(operation, resource, config)
+		} else {
+			planned = re.planResource(operation, resource, order, created)
+		}
+		if planned.Action == PlanActionCreate && resource.Namespace == "" {
+			created[resourceKey(resource.Kind, "", resource.Name)] = true
+		}
+
+		if rank := resolver.Rank(resource.Kind); rank != lastRank || len(report.Phases) == 0 {
+			report.Phases = append(report.Phases, PlanPhase{Name: phaseNames[rank]})
+			lastRank = rank
+		}
+		phase := &report.Phases[len(report.Phases)-1]
+		phase.Resources = append(phase.Resources, planned)
+		report.Summary.add(planned.Action)
+		if planned.Rejection != nil {
+			report.Rejections = append(report.Rejections, *planned.Rejection)
+			report.Summary.Rejected++
+		}
+	}
+
+	return report, nil
+}
+
+// planCRD decides how installCRDs would handle a CRD
+func (re *RestoreEngine) planCRD(operation *RestoreOperation, resource BackupResource, config CRDRestoreConfig) PlannedResource {
+	planned := plannedResource(resource)
+
+	obj := &unstructured.Unstructured{Object: stripCRDStatus(resource.Data)}
+	obj.SetAPIVersion(resource.APIVersion)
+	obj.SetKind(resource.Kind)
+	obj.SetName(resource.Name)
+
+	var existingObj map[string]interface{}
+	client := re.dynamicClient.Resource(crdGVR)
+	existing, err := client.Get(operation.ctx, resource.Name, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		planned.Action = PlanActionFail
+		planned.Reason = fmt.Sprintf("failed to read live object: %v", err)
+		return planned
+	}
+	if err == nil {
+		existingObj = existing.Object
+		planned.Exists = true
+	}
+
+	action, reason := planCRDInstall(obj.Object, existingObj, config)
+	planned.Action = action
+	planned.Reason = reason
+	if action == crdActionUpdate {
+		planned.Differences = fieldDifferences(existing.Object, obj.Object)
+	}
+	if operation.Request.ServerDryRun {
+		re.dryRunApply(operation.ctx, client, obj, existing, &planned)
+	}
+	return planned
+}
+
+// planResource decides how restoreResource would handle a resource
+func (re *RestoreEngine) planResource(operation *RestoreOperation, resource BackupResource, order *RestorePlan, created map[string]bool) PlannedResource {
+	planned := plannedResource(resource)
+
+	obj := backupObject(resource)
+	planned.Transforms = operation.conversionChanges(resource)
+	if operation.transformer != nil {
+		changes, err := operation.transformer.Apply(obj)
+		if err != nil {
+			planned.Action = PlanActionFail
+			planned.Reason = err.Error()
+			return planned
+		}
+		planned.Transforms = append(planned.Transforms, changes...)
+	}
+	var filtered *pluginFilteredError
+	if err := re.applyPlugins(obj); errors.As(err, &filtered) {
+		planned.Action = PlanActionSkip
+		planned.Reason = filtered.Error()
+		return planned
+	} else if err != nil {
+		planned.Action = PlanActionFail
+		planned.Reason = err.Error()
+		return planned
+	}
+
+	if crdName, _, ok := order.CRDFor(resource); ok && created[resourceKey("CustomResourceDefinition", "", crdName)] {
+		planned.Action = PlanActionCreate
+		planned.Reason = fmt.Sprintf("CustomResourceDefinition %s is created by this restore", crdName)
+		re.skipDryRun(operation, &planned, planned.Reason)
+		return planned
+	}
+	if resource.Namespace != "" && created[resourceKey("Namespace", "", resource.Namespace)] {
+		planned.Action = PlanActionCreate
+		re.skipDryRun(operation, &planned, fmt.Sprintf("namespace %s is created by this restore", resource.Namespace))
+		return planned
+	}
+
+	client := re.resourceClient(obj, resource, order)
+	existing, err := client.Get(operation.ctx, obj.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		planned.Action = PlanActionCreate
+		if operation.Request.ServerDryRun {
+			re.dryRunApply(operation.ctx, client, obj, nil, &planned)
+		}
+		return planned
+	}
+	if err != nil {
+		planned.Action = PlanActionFail
+		planned.Reason = fmt.Sprintf("failed to read live object: %v", err)
+		return planned
+	}
+
+	planned.Exists = true
+	switch operation.Request.ConflictStrategy {
+	case ConflictStrategyFail:
+		planned.Action = PlanActionFail
+		planned.Reason = "already exists"
+	case ConflictStrategyOverwrite:
+		planned.Action = PlanActionUpdate
+		planned.Differences = fieldDifferences(existing.Object, obj.Object)
+	case ConflictStrategyMerge:
+		planned.Action = PlanActionMerge
+		obj = re.conflictResolver.MergeResources(existing, obj)
+		planned.Differences = fieldDifferences(existing.Object, obj.Object)
+	default:
+		planned.Action = PlanActionSkip
+		planned.Reason = "already exists"
+	}
+	if operation.Request.ServerDryRun {
+		re.dryRunApply(operation.ctx, client, obj, existing, &planned)
+	}
+	return planned
+}
+
+// dryRunApply sends the create or update a planned resource stands for as a
+// server-side dry-run and records whether admission accepted it. Skipped and
+// failing resources are not sent.
+func (re *RestoreEngine) dryRunApply(ctx context.Context, client dynamic.ResourceInterface, desired, existing *unstructured.Unstructured, planned *PlannedResource) {
+	var err error
+	switch planned.Action {
+	case PlanActionCreate:
+		_, err = client.Create(ctx, desired, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+	case PlanActionUpdate, PlanActionMerge:
+		obj := desired.DeepCopy()
+		obj.SetResourceVersion(existing.GetResourceVersion())
+		_, err = client.Update(ctx, obj, metav1.UpdateOptions{DryRun: []string{metav1.DryRunAll}})
+	default:
+		return
+	}
+
+	if err == nil {
+		planned.DryRun = DryRunPassed
+		return
+	}
+	planned.DryRun = DryRunRejected
+	planned.Rejection = admissionRejection(*planned, err)
+}
+
+// skipDryRun records why a resource could not be dry-run
+func (re *RestoreEngine) skipDryRun(operation *RestoreOperation, planned *PlannedResource, note string) {
+	if operation.Request.ServerDryRun {
+		planned.DryRun = DryRunNotChecked
+		planned.DryRunNote = note
+	}
+}
+
+// admissionRejection describes a dry-run error, naming the webhook or policy
+// that denied the request when the API server reports one
+func admissionRejection(planned PlannedResource, err error) *AdmissionRejection {
+	rejection := &AdmissionRejection{
+		Resource: planned.Kind + " " + plannedName(planned),
+		Reason:   string(apierrors.ReasonForError(err)),
+		Message:  err.Error(),
+	}
+	for _, pattern := range admissionDenials {
+		if match := pattern.FindStringSubmatch(rejection.Message); match != nil {
+			rejection.Webhook = match[1]
+			break
+		}
+	}
+	return rejection
+}
+
+// plannedName is namespace/name, or the name of cluster-scoped resources
+func plannedName(planned PlannedResource) string {
+	if planned.Namespace != "" {
+		return planned.Namespace + "/" + planned.Name
+	}
+	return planned.Name
+}
+
+func plannedResource(resource BackupResource) PlannedResource {
+	return PlannedResource{
+		APIVersion: resource.APIVersion,
+		Kind:       resource.Kind,
+		Namespace:  resource.Namespace,
+		Name:       resource.Name,
+	}
+}
+
+func (s *PlanSummary) add(action string) {
+	s.Total++
+	switch action {
+	case PlanActionCreate:
+		s.Create++
+	case PlanActionUpdate:
+		s.Update++
+	case PlanActionMerge:
+		s.Merge++
+	case PlanActionSkip:
+		s.Skip++
+	case PlanActionFail:
+		s.Fail++
+	}
+}
+
+// fieldDifferences returns the paths of fields set in desired whose value in
+// live differs. Status and server-managed metadata are ignored; of the
+// metadata only labels and annotations are compared.
+func fieldDifferences(live, desired map[string]interface{}) []string {
+	var differences []string
+	for key, value := range desired {
+		switch key {
+		case "apiVersion", "kind", "status":
+			continue
+		case "metadata":
+			liveMeta, _ := live["metadata"].(map[string]interface{})
+			desiredMeta, _ := value.(map[string]interface{})
+			for _, field := range []string{"labels", "annotations"} {
+				if desiredValue, ok := desiredMeta[field]; ok {
+					differences = appendDifferences(differences, "metadata."+field, liveMeta[field], desiredValue)
+				}
+			}
+			continue
+		}
+		differences = appendDifferences(differences, key, live[key], value)
+	}
+	sort.Strings(differences)
+	return differences
+}
+
+func appendDifferences(differences []string, path string, live, desired interface{}) []string {
+	desiredMap, desiredIsMap := desired.(map[string]interface{})
+	liveMap, liveIsMap := live.(map[string]interface{})
+	if desiredIsMap && liveIsMap {
+		for key, value := range desiredMap {
+			differences = appendDifferences(differences, path+"."+key, liveMap[key], value)
+		}
+		return differences
+	}
+	if !reflect.DeepEqual(live, desired) {
+		differences = append(differences, path)
+	}
+	return differences
+}
+
+// WriteText renders the plan for people, one line per resource grouped by
+// phase, followed by the totals
+func (r *RestorePlanReport) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Restore plan for backup %s to cluster %s", r.BackupID, r.ClusterName)
+	if r.ConflictStrategy != "" {
+		fmt.Fprintf(&b, " (conflict strategy: %s)", r.ConflictStrategy)
+	}
+	b.WriteString("\n")
+
+	for i, phase := range r.Phases {
+		fmt.Fprintf(&b, "\nPhase %d: %s\n", i+1, phase.Name)
+		for _, resource := range phase.Resources {
+			fmt.Fprintf(&b, "  %s %s %s %s", planSymbols[resource.Action], resource.Action, resource.Kind, plannedName(resource))
+			if resource.Reason != "" {
+				fmt.Fprintf(&b, " (%s)", resource.Reason)
+			}
+			b.WriteString("\n")
+			if resource.Exists && (resource.Action == PlanActionUpdate || resource.Action == PlanActionMerge) {
+				if len(resource.Differences) == 0 {
+					b.WriteString("      no changes\n")
+				}
+				for _, field := range resource.Differences {
+					fmt.Fprintf(&b, "      ~ %s\n", field)
+				}
+			}
+			for _, change := range resource.Transforms {
+				fmt.Fprintf(&b, "      transform %s: %v -> %v\n", change.Field, change.OldValue, change.NewValue)
+			}
+			if rejection := resource.Rejection; rejection != nil {
+				if rejection.Webhook != "" {
+					fmt.Fprintf(&b, "      ! rejected by %s: %s\n", rejection.Webhook, rejection.Message)
+				} else {
+					fmt.Fprintf(&b, "      ! rejected: %s\n", rejection.Message)
+				}
+			}
+		}
+	}
+
+	for _, warning := range r.Warnings {
+		fmt.Fprintf(&b, "\nWarning: %s\n", warning)
+	}
+	fmt.Fprintf(&b, "\nPlan: %d to create, %d to update, %d to merge, %d to skip, %d failing.\n",
+		r.Summary.Create, r.Summary.Update, r.Summary.Merge, r.Summary.Skip, r.Summary.Fail)
+	if r.Summary.Rejected > 0 {
+		fmt.Fprintf(&b, "Admission: %d rejected by server-side dry-run.\n", r.Summary.Rejected)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
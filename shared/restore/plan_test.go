@@ -0,0 +1,246 @@
+package restore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newPlanTestEngine(objects ...runtime.Object) *RestoreEngine {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		{Version: "v1", Resource: "namespaces"}:                 "NamespaceList",
+		{Version: "v1", Resource: "configmaps"}:                 "ConfigMapList",
+		{Group: "apps", Version: "v1", Resource: "deployments"}: "DeploymentList",
+		crdGVR: "CustomResourceDefinitionList",
+	}, objects...)
+	return &RestoreEngine{dynamicClient: client, dependencyResolver: NewDependencyResolver()}
+}
+
+func liveObject(apiVersion, kind, namespace, name string, fields map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: fields}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func planTestResources() []BackupResource {
+	return []BackupResource{
+		backupResource("apps/v1", "Deployment", "shop", "web", map[string]interface{}{
+			"spec": map[string]interface{}{"replicas": int64(3)},
+		}),
+		backupResource("v1", "ConfigMap", "shop", "settings", map[string]interface{}{
+			"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "shop"}},
+			"data":     map[string]interface{}{"mode": "live", "region": "eu"},
+		}),
+		backupResource("v1", "Namespace", "", "shop", nil),
+		backupResource("example.com/v1", "Widget", "shop", "blue", nil),
+		backupResource("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "widgets.example.com", map[string]interface{}{
+			"spec": map[string]interface{}{
+				"group":    "example.com",
+				"names":    map[string]interface{}{"kind": "Widget", "plural": "widgets"},
+				"versions": []interface{}{map[string]interface{}{"name": "v1", "served": true}},
+			},
+		}),
+	}
+}
+
+func TestPlanResources_PhasesAndConflicts(t *testing.T) {
+	engine := newPlanTestEngine(
+		liveObject("v1", "Namespace", "", "shop", map[string]interface{}{}),
+		liveObject("v1", "ConfigMap", "shop", "settings", map[string]interface{}{
+			"data": map[string]interface{}{"mode": "maintenance", "region": "eu"},
+		}),
+	)
+	operation := &RestoreOperation{
+		Request: RestoreRequest{BackupID: "backup-1", ClusterName: "prod", ConflictStrategy: ConflictStrategyOverwrite},
+		ctx:     context.Background(),
+	}
+
+	plan, err := engine.planResources(operation, planTestResources())
+	require.NoError(t, err)
+
+	var phases []string
+	for _, phase := range plan.Phases {
+		phases = append(phases, phase.Name)
+	}
+	assert.Equal(t, []string{"crds", "namespaces", "config", "workloads", "custom-resources"}, phases)
+
+	crd := plan.Phases[0].Resources[0]
+	assert.Equal(t, PlanActionCreate, crd.Action)
+	assert.False(t, crd.Exists)
+
+	namespace := plan.Phases[1].Resources[0]
+	assert.Equal(t, PlanActionUpdate, namespace.Action)
+	assert.True(t, namespace.Exists)
+	assert.Empty(t, namespace.Differences)
+
+	configMap := plan.Phases[2].Resources[0]
+	assert.Equal(t, PlanActionUpdate, configMap.Action)
+	assert.Equal(t, []string{"data.mode", "metadata.labels"}, configMap.Differences)
+
+	assert.Equal(t, PlanActionCreate, plan.Phases[3].Resources[0].Action)
+
+	// The Widget's CRD does not exist yet, so neither can the Widget
+	widget := plan.Phases[4].Resources[0]
+	assert.Equal(t, PlanActionCreate, widget.Action)
+	assert.Contains(t, widget.Reason, "widgets.example.com")
+
+	assert.Equal(t, PlanSummary{Total: 5, Create: 3, Update: 2}, plan.Summary)
+}
+
+func TestPlanResources_ConflictStrategies(t *testing.T) {
+	for strategy, action := range map[ConflictStrategy]string{
+		ConflictStrategySkip: PlanActionSkip,
+		ConflictStrategyFail: PlanActionFail,
+		"":                   PlanActionSkip,
+	} {
+		engine := newPlanTestEngine(liveObject("v1", "Namespace", "", "shop", map[string]interface{}{}))
+		operation := &RestoreOperation{
+			Request: RestoreRequest{ConflictStrategy: strategy},
+			ctx:     context.Background(),
+		}
+
+		plan, err := engine.planResources(operation, []BackupResource{backupResource("v1", "Namespace", "", "shop", nil)})
+		require.NoError(t, err)
+		resource := plan.Phases[0].Resources[0]
+		assert.Equal(t, action, resource.Action, "strategy %q", strategy)
+		assert.Equal(t, "already exists", resource.Reason)
+	}
+}
+
+func TestPlanResources_ServerDryRun(t *testing.T) {
+	engine := newPlanTestEngine(liveObject("v1", "Namespace", "", "shop", map[string]interface{}{}))
+	client := engine.dynamicClient.(*dynamicfake.FakeDynamicClient)
+	var dryRuns int
+	client.PrependReactor("create", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		dryRuns++
+		if action.GetResource().Resource != "deployments" {
+			return false, nil, nil
+		}
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Group: "apps", Resource: "deployments"}, "web",
+			errors.New(`admission webhook "validation.gatekeeper.sh" denied the request: [no-privileged] privileged containers are not allowed`))
+	})
+	operation := &RestoreOperation{
+		Request: RestoreRequest{ConflictStrategy: ConflictStrategySkip, ServerDryRun: true},
+		ctx:     context.Background(),
+	}
+
+	plan, err := engine.planResources(operation, []BackupResource{
+		backupResource("v1", "Namespace", "", "shop", nil),
+		backupResource("apps/v1", "Deployment", "shop", "web", nil),
+		backupResource("v1", "Namespace", "", "staging", nil),
+		backupResource("v1", "ConfigMap", "staging", "settings", nil),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, dryRuns, "the new namespace and the deployment")
+
+	resources := map[string]PlannedResource{}
+	for _, phase := range plan.Phases {
+		for _, resource := range phase.Resources {
+			resources[resource.Kind+"/"+plannedName(resource)] = resource
+		}
+	}
+
+	// Skipped resources are not sent
+	assert.Empty(t, resources["Namespace/shop"].DryRun)
+	assert.Equal(t, DryRunPassed, resources["Namespace/staging"].DryRun)
+
+	// The API server would reject its members until the namespace exists
+	configMap := resources["ConfigMap/staging/settings"]
+	assert.Equal(t, DryRunNotChecked, configMap.DryRun)
+	assert.Equal(t, "namespace staging is created by this restore", configMap.DryRunNote)
+
+	deployment := resources["Deployment/shop/web"]
+	assert.Equal(t, DryRunRejected, deployment.DryRun)
+	require.NotNil(t, deployment.Rejection)
+	assert.Equal(t, "validation.gatekeeper.sh", deployment.Rejection.Webhook)
+	assert.Equal(t, "Forbidden", deployment.Rejection.Reason)
+	assert.Equal(t, "Deployment shop/web", deployment.Rejection.Resource)
+
+	assert.Equal(t, []AdmissionRejection{*deployment.Rejection}, plan.Rejections)
+	assert.Equal(t, 1, plan.Summary.Rejected)
+}
+
+func TestCheckAdmission(t *testing.T) {
+	plan := &RestorePlanReport{
+		Phases: []PlanPhase{{Name: "workloads", Resources: []PlannedResource{{
+			Kind: "Deployment", Namespace: "shop", Name: "web", Action: PlanActionCreate,
+			DryRun: DryRunRejected, Rejection: &AdmissionRejection{Resource: "Deployment shop/web", Message: "denied"},
+		}}}},
+		Rejections: []AdmissionRejection{{Resource: "Deployment shop/web", Message: "denied"}},
+	}
+	engine := &RestoreEngine{}
+
+	operation := &RestoreOperation{Request: RestoreRequest{ValidationMode: ValidationModeStrict}, Plan: plan}
+	assert.EqualError(t, engine.checkAdmission(operation), "server-side dry-run rejected 1 resources")
+	require.Len(t, operation.Errors, 1)
+	assert.Equal(t, "admission_rejected", operation.Errors[0].Type)
+	assert.Equal(t, "Deployment/web", operation.Errors[0].Resource)
+	assert.False(t, operation.Errors[0].Recoverable)
+
+	// Permissive restores apply the rest and report the rejections
+	operation = &RestoreOperation{Request: RestoreRequest{ValidationMode: ValidationModePermissive}, Plan: plan}
+	assert.NoError(t, engine.checkAdmission(operation))
+	require.Len(t, operation.Errors, 1)
+	assert.True(t, operation.Errors[0].Recoverable)
+
+	assert.NoError(t, engine.checkAdmission(&RestoreOperation{}))
+}
+
+func TestFieldDifferences(t *testing.T) {
+	live := map[string]interface{}{
+		"metadata": map[string]interface{}{"resourceVersion": "12", "labels": map[string]interface{}{"app": "web"}},
+		"spec":     map[string]interface{}{"replicas": int64(2), "paused": false, "strategy": "Recreate"},
+		"status":   map[string]interface{}{"readyReplicas": int64(2)},
+	}
+	desired := map[string]interface{}{
+		"metadata": map[string]interface{}{"resourceVersion": "3", "labels": map[string]interface{}{"app": "web"}},
+		"spec":     map[string]interface{}{"replicas": int64(3), "paused": false},
+		"status":   map[string]interface{}{"readyReplicas": int64(0)},
+	}
+
+	// Fields only the live object sets are left to the API server
+	assert.Equal(t, []string{"spec.replicas"}, fieldDifferences(live, desired))
+}
+
+func TestRestorePlanReport_WriteText(t *testing.T) {
+	report := &RestorePlanReport{
+		BackupID:         "backup-1",
+		ClusterName:      "prod",
+		ConflictStrategy: ConflictStrategyOverwrite,
+		Phases: []PlanPhase{
+			{Name: "namespaces", Resources: []PlannedResource{{Kind: "Namespace", Name: "shop", Action: PlanActionCreate}}},
+			{Name: "config", Resources: []PlannedResource{{
+				Kind: "ConfigMap", Namespace: "shop", Name: "settings", Action: PlanActionUpdate,
+				Exists: true, Differences: []string{"data.mode"},
+			}}},
+		},
+		Summary: PlanSummary{Total: 2, Create: 1, Update: 1},
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, report.WriteText(&out))
+	assert.Equal(t, `Restore plan for backup backup-1 to cluster prod (conflict strategy: overwrite)
+
+Phase 1: namespaces
+  + create Namespace shop
+
+Phase 2: config
+  ~ update ConfigMap shop/settings
+      ~ data.mode
+
+Plan: 1 to create, 1 to update, 0 to merge, 0 to skip, 0 failing.
+`, out.String())
+}
@@ -0,0 +1,52 @@
+package restore
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"shared-config/plugins"
+)
+
+// pluginFilteredError reports a resource a plugin kept out of the restore
+type pluginFilteredError struct {
+	plugin string
+}
+
+func (e *pluginFilteredError) Error() string {
+	return fmt.Sprintf("filtered out by plugin %s", e.plugin)
+}
+
+// SetPlugins hands every resource to the plugins right before it is applied,
+// after the transforms of its restore request
+func (re *RestoreEngine) SetPlugins(chain *plugins.Chain) {
+	re.plugins = chain
+}
+
+// applyPlugins runs the plugins over obj, failing with a pluginFilteredError
+// when one filters it out
+func (re *RestoreEngine) applyPlugins(obj *unstructured.Unstructured) error {
+	dropped, err := re.plugins.Apply(plugins.StageRestore, obj)
+	if err != nil {
+		return err
+	}
+	if dropped != "" {
+		return &pluginFilteredError{plugin: dropped}
+	}
+	return nil
+}
+
+// recordFiltered reports a resource a plugin filtered out as skipped. It is
+// not checkpointed, so a resumed restore hands it to the plugins again.
+func (re *RestoreEngine) recordFiltered(operation *RestoreOperation, resource BackupResource, filtered *pluginFilteredError) {
+	operation.Results.SkippedResources = append(operation.Results.SkippedResources, SkippedResource{
+		APIVersion: resource.APIVersion,
+		Kind:       resource.Kind,
+		Namespace:  resource.Namespace,
+		Name:       resource.Name,
+		Reason:     filtered.Error(),
+		Timestamp:  time.Now(),
+	})
+	operation.Progress.SkippedResources++
+}
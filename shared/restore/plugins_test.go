@@ -0,0 +1,83 @@
+package restore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"shared-config/plugins"
+)
+
+// costCenterPlugin keeps Deployments out of the restore and labels the
+// other resources with their cost center
+type costCenterPlugin struct{}
+
+func (costCenterPlugin) Name() string { return "cost-center" }
+
+func (costCenterPlugin) Filter(stage plugins.Stage, obj *unstructured.Unstructured) (bool, error) {
+	return obj.GetKind() != "Deployment", nil
+}
+
+func (costCenterPlugin) Transform(stage plugins.Stage, obj *unstructured.Unstructured) error {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels["cost-center"] = "cc-1042"
+	obj.SetLabels(labels)
+	return nil
+}
+
+func newPluginTestEngine(t *testing.T) *RestoreEngine {
+	engine := newPlanTestEngine()
+	chain, err := plugins.NewChain(costCenterPlugin{})
+	require.NoError(t, err)
+	engine.SetPlugins(chain)
+	return engine
+}
+
+func TestRestoreResources_AppliesPlugins(t *testing.T) {
+	engine := newPluginTestEngine(t)
+	operation := checkpointTestOperation(context.Background(), RestoreRequest{RestoreID: "restore-1", ConflictStrategy: ConflictStrategySkip})
+
+	err := engine.restoreResources(operation, []BackupResource{
+		backupResource("v1", "ConfigMap", "shop", "settings", nil),
+		backupResource("apps/v1", "Deployment", "shop", "web", nil),
+	})
+	require.NoError(t, err)
+
+	require.Len(t, operation.Results.RestoredResources, 1)
+	require.Len(t, operation.Results.SkippedResources, 1)
+	assert.Equal(t, "web", operation.Results.SkippedResources[0].Name)
+	assert.Equal(t, "filtered out by plugin cost-center", operation.Results.SkippedResources[0].Reason)
+	assert.Equal(t, 1, operation.Progress.SkippedResources)
+
+	configMap, err := engine.dynamicClient.Resource(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}).
+		Namespace("shop").Get(context.Background(), "settings", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "cc-1042", configMap.GetLabels()["cost-center"])
+}
+
+func TestPlanResources_AppliesPlugins(t *testing.T) {
+	engine := newPluginTestEngine(t)
+	operation := &RestoreOperation{Request: RestoreRequest{ConflictStrategy: ConflictStrategySkip}, ctx: context.Background()}
+
+	plan, err := engine.planResources(operation, []BackupResource{
+		backupResource("v1", "ConfigMap", "shop", "settings", nil),
+		backupResource("apps/v1", "Deployment", "shop", "web", nil),
+	})
+	require.NoError(t, err)
+
+	actions := map[string]string{}
+	for _, phase := range plan.Phases {
+		for _, resource := range phase.Resources {
+			actions[resource.Kind] = resource.Action
+		}
+	}
+	assert.Equal(t, map[string]string{"ConfigMap": PlanActionCreate, "Deployment": PlanActionSkip}, actions)
+}
@@ -0,0 +1,130 @@
+package restore
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// cancelStopTimeout bounds how long CancelRestore waits for the resource
+// being applied when it was cancelled
+const cancelStopTimeout = 30 * time.Second
+
+var errRestoreCancelled = errors.New("restore operation cancelled")
+
+// RestoreProgressReport is how far a restore operation got: its phase, the
+// resources processed so far and those that failed
+type RestoreProgressReport struct {
+	RestoreID       string           `json:"restore_id"`
+	Status          RestoreStatus    `json:"status"`
+	StartTime       time.Time        `json:"start_time"`
+	EndTime         *time.Time       `json:"end_time,omitempty"`
+	Progress        RestoreProgress  `json:"progress"`
+	FailedResources []FailedResource `json:"failed_resources,omitempty"`
+}
+
+// GetRestoreProgress returns the progress of an active restore operation, as
+// last published by its goroutine, or the final progress of a finished one
+// still in the history
+func (re *RestoreEngine) GetRestoreProgress(restoreID string) (*RestoreProgressReport, error) {
+	re.mu.RLock()
+	defer re.mu.RUnlock()
+
+	if operation, exists := re.activeRestores[restoreID]; exists {
+		if operation.published == nil {
+			return nil, fmt.Errorf("restore operation %s has not reported progress yet", restoreID)
+		}
+		report := *operation.published
+		return &report, nil
+	}
+
+	for i := len(re.restoreHistory) - 1; i >= 0; i-- {
+		record := re.restoreHistory[i]
+		if record.RestoreID != restoreID {
+			continue
+		}
+		return &RestoreProgressReport{
+			RestoreID:       restoreID,
+			Status:          record.Status,
+			StartTime:       record.StartTime,
+			EndTime:         record.EndTime,
+			Progress:        record.Progress,
+			FailedResources: record.FailedResources,
+		}, nil
+	}
+	return nil, fmt.Errorf("restore operation %s not found", restoreID)
+}
+
+// Done returns a channel closed once the operation finished, after which it
+// no longer changes
+func (operation *RestoreOperation) Done() <-chan struct{} {
+	return operation.completionChan
+}
+
+// progressReport copies the operation's progress, sharing nothing the
+// operation's goroutine changes afterwards
+func (operation *RestoreOperation) progressReport() *RestoreProgressReport {
+	report := &RestoreProgressReport{
+		RestoreID:       operation.Request.RestoreID,
+		Status:          operation.Status,
+		StartTime:       operation.StartTime,
+		Progress:        operation.Progress,
+		FailedResources: append([]FailedResource(nil), operation.Results.FailedResources...),
+	}
+	if operation.EndTime != nil {
+		ended := *operation.EndTime
+		report.EndTime = &ended
+	}
+	report.Progress.ResourceBreakdown = make(map[string]int, len(operation.Progress.ResourceBreakdown))
+	for resourceType, count := range operation.Progress.ResourceBreakdown {
+		report.Progress.ResourceBreakdown[resourceType] = count
+	}
+	return report
+}
+
+// publishProgress makes the operation's current progress what
+// GetRestoreProgress reports. Only the operation's goroutine changes it, so
+// readers get the copies published here rather than racing the goroutine.
+func (re *RestoreEngine) publishProgress(operation *RestoreOperation) {
+	report := operation.progressReport()
+	re.mu.Lock()
+	operation.published = report
+	re.mu.Unlock()
+}
+
+// abortRestore ends a restore stopped by err, as cancelled when its context
+// was cancelled and as failed otherwise
+func (re *RestoreEngine) abortRestore(operation *RestoreOperation, err error) {
+	if operation.ctx.Err() == nil {
+		re.failRestore(operation, err)
+		return
+	}
+
+	now := time.Now()
+	operation.EndTime = &now
+	operation.Status = RestoreStatusCancelled
+	operation.Errors = append(operation.Errors, RestoreError{
+		Type:        "operation_cancelled",
+		Message:     fmt.Sprintf("restore cancelled during %s; resources not applied yet were left pending", operation.Progress.Phase),
+		Timestamp:   now,
+		Recoverable: true,
+	})
+	re.publishProgress(operation)
+
+	re.incCounter("restore_operations_cancelled", map[string]string{"cluster": operation.Request.ClusterName})
+}
+
+// summarizeResults calculates the summary of the resources processed so far
+func summarizeResults(operation *RestoreOperation) {
+	progress := operation.Progress
+	operation.Results.Summary = RestoreSummary{
+		TotalDuration:       time.Since(operation.StartTime),
+		ResourcesProcessed:  progress.ProcessedResources,
+		ResourcesSuccessful: progress.SuccessfulResources,
+		ResourcesFailed:     progress.FailedResources,
+		ResourcesSkipped:    progress.SkippedResources,
+	}
+	if progress.ProcessedResources > 0 {
+		operation.Results.Summary.SuccessRate = float64(progress.SuccessfulResources) / float64(progress.ProcessedResources) * 100
+	}
+}
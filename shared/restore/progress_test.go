@@ -0,0 +1,137 @@
+package restore
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestRestoreResources_StopsWhenCancelled(t *testing.T) {
+	engine := newPlanTestEngine()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancel while the config map is being applied
+	client := engine.dynamicClient.(*dynamicfake.FakeDynamicClient)
+	client.PrependReactor("create", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		cancel()
+		return true, nil, context.Canceled
+	})
+
+	operation := checkpointTestOperation(ctx, RestoreRequest{RestoreID: "restore-1", ConflictStrategy: ConflictStrategySkip})
+	err := engine.restoreResources(operation, []BackupResource{
+		backupResource("v1", "Namespace", "", "shop", nil),
+		backupResource("v1", "ConfigMap", "shop", "settings", nil),
+		backupResource("apps/v1", "Deployment", "shop", "web", nil),
+	})
+	assert.ErrorIs(t, err, errRestoreCancelled)
+
+	require.Len(t, operation.Results.RestoredResources, 1)
+	assert.Equal(t, "Namespace", operation.Results.RestoredResources[0].Kind)
+	assert.Empty(t, operation.Results.FailedResources, "the interrupted config map stays pending")
+	assert.Equal(t, RestorePhaseResources, operation.Progress.Phase)
+	assert.Equal(t, 1, operation.Progress.ProcessedResources)
+	assert.Equal(t, 1, operation.Results.Summary.ResourcesProcessed, "the partial results are summarized")
+	assert.Equal(t, 100.0, operation.Results.Summary.SuccessRate)
+}
+
+func TestGetRestoreProgress(t *testing.T) {
+	ended := time.Now()
+	failed := FailedResource{Kind: "Deployment", Namespace: "shop", Name: "web", Error: "denied"}
+	operation := &RestoreOperation{
+		Status:   RestoreStatusRestoring,
+		Progress: RestoreProgress{Phase: RestorePhaseResources, TotalResources: 4, ProcessedResources: 2, PercentComplete: 50},
+		Results:  RestoreResults{FailedResources: []FailedResource{failed}},
+	}
+	engine := &RestoreEngine{
+		activeRestores: map[string]*RestoreOperation{"restore-2": operation},
+		restoreHistory: []*RestoreRecord{
+			{RestoreID: "restore-1", Status: RestoreStatusFailed, Progress: RestoreProgress{Phase: RestorePhaseCRDs}},
+			{RestoreID: "restore-1", Status: RestoreStatusCancelled, EndTime: &ended,
+				Progress: RestoreProgress{Phase: RestorePhaseResources, ProcessedResources: 3}, FailedResources: []FailedResource{failed}},
+		},
+	}
+
+	_, err := engine.GetRestoreProgress("restore-2")
+	assert.Error(t, err, "nothing is reported before the operation publishes its progress")
+	engine.publishProgress(operation)
+
+	report, err := engine.GetRestoreProgress("restore-2")
+	require.NoError(t, err)
+	assert.Equal(t, RestoreStatusRestoring, report.Status)
+	assert.Equal(t, RestorePhaseResources, report.Progress.Phase)
+	assert.Equal(t, 50.0, report.Progress.PercentComplete)
+	assert.Equal(t, []FailedResource{failed}, report.FailedResources)
+
+	// Finished restores report the outcome of their latest attempt
+	report, err = engine.GetRestoreProgress("restore-1")
+	require.NoError(t, err)
+	assert.Equal(t, RestoreStatusCancelled, report.Status)
+	assert.Equal(t, 3, report.Progress.ProcessedResources)
+	assert.Equal(t, &ended, report.EndTime)
+	assert.Len(t, report.FailedResources, 1)
+
+	_, err = engine.GetRestoreProgress("restore-3")
+	assert.Error(t, err)
+}
+
+func TestGetRestoreProgress_WhileRestoring(t *testing.T) {
+	engine := newPlanTestEngine()
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	operation := checkpointTestOperation(context.Background(), RestoreRequest{RestoreID: "restore-1", ConflictStrategy: ConflictStrategySkip})
+	engine.activeRestores = map[string]*RestoreOperation{"restore-1": operation}
+	engine.publishProgress(operation)
+
+	// Poll the way the progress endpoint does while the resources are applied
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+			report, err := engine.GetRestoreProgress("restore-1")
+			if assert.NoError(t, err) {
+				assert.LessOrEqual(t, report.Progress.ResourceBreakdown["v1/ConfigMap"], report.Progress.ProcessedResources)
+			}
+		}
+	}()
+
+	resources := []BackupResource{backupResource("v1", "Namespace", "", "shop", nil)}
+	for i := 0; i < 20; i++ {
+		resources = append(resources, backupResource("v1", "ConfigMap", "shop", fmt.Sprintf("settings-%d", i), nil))
+	}
+	require.NoError(t, engine.restoreResources(operation, resources))
+	stop()
+	<-done
+
+	report, err := engine.GetRestoreProgress("restore-1")
+	require.NoError(t, err)
+	assert.Equal(t, 21, report.Progress.ProcessedResources)
+	assert.Equal(t, 20, report.Progress.ResourceBreakdown["v1/ConfigMap"])
+}
+
+func TestCancelRestore_WaitsForTheOperationToStop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	operation := &RestoreOperation{ctx: ctx, cancel: cancel, completionChan: make(chan struct{})}
+	engine := &RestoreEngine{activeRestores: map[string]*RestoreOperation{"restore-1": operation}}
+
+	go func() {
+		<-operation.ctx.Done()
+		close(operation.completionChan)
+	}()
+	require.NoError(t, engine.CancelRestore("restore-1"))
+	assert.Error(t, operation.ctx.Err())
+
+	assert.Error(t, engine.CancelRestore("restore-2"))
+}
@@ -0,0 +1,297 @@
+package restore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// readinessPollInterval is how often restored resources are re-read while waiting
+const readinessPollInterval = 2 * time.Second
+
+// WaitForReadyConfig controls the post-restore readiness wait
+type WaitForReadyConfig struct {
+	Enabled bool `json:"enabled"`
+	// Timeouts overrides the default wait per kind, e.g. {"StatefulSet": "15m"}
+	Timeouts map[string]time.Duration `json:"timeouts,omitempty"`
+}
+
+// ReadinessCheck describes one readiness condition for a restored resource.
+// It mirrors the ValidationCheck used by the DR scenario suite.
+type ReadinessCheck struct {
+	Name     string        `json:"name"`
+	Type     string        `json:"type"`
+	Target   string        `json:"target"`
+	Criteria []string      `json:"criteria"`
+	Timeout  time.Duration `json:"timeout"`
+	Critical bool          `json:"critical"`
+
+	gvr       schema.GroupVersionResource
+	namespace string
+	name      string
+	ready     readinessFunc
+}
+
+// ReadinessResult is the outcome of a single readiness check
+type ReadinessResult struct {
+	CheckName    string        `json:"check_name"`
+	CheckType    string        `json:"check_type"`
+	Target       string        `json:"target"`
+	StartTime    time.Time     `json:"start_time"`
+	EndTime      time.Time     `json:"end_time"`
+	Duration     time.Duration `json:"duration"`
+	Success      bool          `json:"success"`
+	Critical     bool          `json:"critical"`
+	ErrorMessage string        `json:"error_message,omitempty"`
+}
+
+// ReadinessReport summarizes the readiness of all restored resources
+type ReadinessReport struct {
+	Ready     bool              `json:"ready"`
+	Results   []ReadinessResult `json:"results"`
+	StartTime time.Time         `json:"start_time"`
+	Duration  time.Duration     `json:"duration"`
+}
+
+// FailedCritical returns the critical checks that did not pass
+func (rr *ReadinessReport) FailedCritical() []ReadinessResult {
+	var failed []ReadinessResult
+	for _, result := range rr.Results {
+		if result.Critical && !result.Success {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// readinessFunc reports whether obj is ready and, if not, why
+type readinessFunc func(obj *unstructured.Unstructured) (bool, string)
+
+// readinessKind describes how to wait for one kind of resource
+type readinessKind struct {
+	gvr      schema.GroupVersionResource
+	timeout  time.Duration
+	criteria []string
+	ready    readinessFunc
+}
+
+// readinessKinds lists the kinds that are waited on after restore
+var readinessKinds = map[string]readinessKind{
+	"Deployment": {
+		gvr:      schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+		timeout:  5 * time.Minute,
+		criteria: []string{"status.observedGeneration >= metadata.generation", "status.readyReplicas == spec.replicas", "status.updatedReplicas == spec.replicas"},
+		ready:    replicasReady("updatedReplicas"),
+	},
+	"StatefulSet": {
+		gvr:      schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "statefulsets"},
+		timeout:  10 * time.Minute,
+		criteria: []string{"status.observedGeneration >= metadata.generation", "status.readyReplicas == spec.replicas"},
+		ready:    replicasReady(""),
+	},
+	"DaemonSet": {
+		gvr:      schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "daemonsets"},
+		timeout:  5 * time.Minute,
+		criteria: []string{"status.observedGeneration >= metadata.generation", "status.numberReady == status.desiredNumberScheduled"},
+		ready:    daemonSetReady,
+	},
+	"CustomResourceDefinition": {
+		gvr:      crdGVR,
+		timeout:  time.Minute,
+		criteria: []string{"condition Established=True"},
+		ready:    conditionReady("Established"),
+	},
+}
+
+// ReadinessWaiter waits for restored resources to become ready
+type ReadinessWaiter struct {
+	client       dynamic.Interface
+	config       WaitForReadyConfig
+	pollInterval time.Duration
+}
+
+// NewReadinessWaiter creates a readiness waiter using the given dynamic client
+func NewReadinessWaiter(client dynamic.Interface, config WaitForReadyConfig) *ReadinessWaiter {
+	return &ReadinessWaiter{
+		client:       client,
+		config:       config,
+		pollInterval: readinessPollInterval,
+	}
+}
+
+// ChecksFor builds readiness checks for the restored resources of supported kinds
+func (rw *ReadinessWaiter) ChecksFor(resources []RestoredResource) []ReadinessCheck {
+	var checks []ReadinessCheck
+	for _, resource := range resources {
+		kind, ok := readinessKinds[resource.Kind]
+		if !ok {
+			continue
+		}
+
+		timeout := kind.timeout
+		if override, ok := rw.config.Timeouts[resource.Kind]; ok && override > 0 {
+			timeout = override
+		}
+
+		target := resource.Kind + "/" + resource.Name
+		if resource.Namespace != "" {
+			target = resource.Kind + "/" + resource.Namespace + "/" + resource.Name
+		}
+
+		checks = append(checks, ReadinessCheck{
+			Name:      "ready_" + target,
+			Type:      "kubernetes",
+			Target:    target,
+			Criteria:  kind.criteria,
+			Timeout:   timeout,
+			Critical:  true,
+			gvr:       kind.gvr,
+			namespace: resource.Namespace,
+			name:      resource.Name,
+			ready:     kind.ready,
+		})
+	}
+	return checks
+}
+
+// Wait runs all checks concurrently, each bounded by its own timeout
+func (rw *ReadinessWaiter) Wait(ctx context.Context, checks []ReadinessCheck) *ReadinessReport {
+	report := &ReadinessReport{
+		StartTime: time.Now(),
+		Results:   make([]ReadinessResult, len(checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check ReadinessCheck) {
+			defer wg.Done()
+			report.Results[i] = rw.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report.Duration = time.Since(report.StartTime)
+	report.Ready = len(report.FailedCritical()) == 0
+	return report
+}
+
+// run waits for a single check and records the outcome
+func (rw *ReadinessWaiter) run(ctx context.Context, check ReadinessCheck) ReadinessResult {
+	result := ReadinessResult{
+		CheckName: check.Name,
+		CheckType: check.Type,
+		Target:    check.Target,
+		StartTime: time.Now(),
+		Critical:  check.Critical,
+	}
+
+	err := pollReady(ctx, rw.client, check.gvr, check.namespace, check.name, check.Timeout, rw.pollInterval, check.ready)
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+	result.Success = err == nil
+	if err != nil {
+		result.ErrorMessage = err.Error()
+	}
+	return result
+}
+
+// pollReady re-reads a resource until ready reports true or the timeout expires.
+// The error names the last reason the resource was not ready.
+func pollReady(ctx context.Context, client dynamic.Interface, gvr schema.GroupVersionResource, namespace, name string, timeout, interval time.Duration, ready readinessFunc) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var resourceClient dynamic.ResourceInterface = client.Resource(gvr)
+	if namespace != "" {
+		resourceClient = client.Resource(gvr).Namespace(namespace)
+	}
+
+	reason := "not checked"
+	for {
+		obj, err := resourceClient.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			reason = err.Error()
+		} else {
+			var ok bool
+			if ok, reason = ready(obj); ok {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s %s not ready within %v: %s", gvr.Resource, name, timeout, reason)
+		case <-ticker.C:
+		}
+	}
+}
+
+// replicasReady checks observedGeneration and readyReplicas against spec.replicas,
+// plus an optional extra replica counter that must also match
+func replicasReady(extraField string) readinessFunc {
+	return func(obj *unstructured.Unstructured) (bool, string) {
+		if ok, reason := generationObserved(obj); !ok {
+			return false, reason
+		}
+
+		desired, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+		if !found {
+			desired = 1
+		}
+		readyReplicas, _, _ := unstructured.NestedInt64(obj.Object, "status", "readyReplicas")
+		if readyReplicas < desired {
+			return false, fmt.Sprintf("%d/%d replicas ready", readyReplicas, desired)
+		}
+
+		if extraField != "" {
+			extra, _, _ := unstructured.NestedInt64(obj.Object, "status", extraField)
+			if extra < desired {
+				return false, fmt.Sprintf("%d/%d replicas %s", extra, desired, extraField)
+			}
+		}
+		return true, ""
+	}
+}
+
+// daemonSetReady checks that every scheduled daemon pod is ready
+func daemonSetReady(obj *unstructured.Unstructured) (bool, string) {
+	if ok, reason := generationObserved(obj); !ok {
+		return false, reason
+	}
+
+	desired, _, _ := unstructured.NestedInt64(obj.Object, "status", "desiredNumberScheduled")
+	numberReady, _, _ := unstructured.NestedInt64(obj.Object, "status", "numberReady")
+	if numberReady < desired {
+		return false, fmt.Sprintf("%d/%d pods ready", numberReady, desired)
+	}
+	return true, ""
+}
+
+// conditionReady checks that a status condition is True
+func conditionReady(conditionType string) readinessFunc {
+	return func(obj *unstructured.Unstructured) (bool, string) {
+		if hasCondition(obj, conditionType, "True") {
+			return true, ""
+		}
+		return false, fmt.Sprintf("condition %s is not True", conditionType)
+	}
+}
+
+// generationObserved reports whether the controller has seen the latest spec
+func generationObserved(obj *unstructured.Unstructured) (bool, string) {
+	observed, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	if observed < obj.GetGeneration() {
+		return false, fmt.Sprintf("observed generation %d behind %d", observed, obj.GetGeneration())
+	}
+	return true, ""
+}
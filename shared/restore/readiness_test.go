@@ -0,0 +1,123 @@
+package restore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func workload(kind, name string, generation int64, spec, status map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": name, "namespace": "shop", "generation": generation},
+		"spec":       spec,
+		"status":     status,
+	}}
+	return obj
+}
+
+func TestReadinessFuncs(t *testing.T) {
+	tests := []struct {
+		name     string
+		ready    readinessFunc
+		obj      *unstructured.Unstructured
+		expected bool
+	}{
+		{
+			name:     "deployment_ready",
+			ready:    readinessKinds["Deployment"].ready,
+			obj:      workload("Deployment", "web", 2, map[string]interface{}{"replicas": int64(3)}, map[string]interface{}{"observedGeneration": int64(2), "readyReplicas": int64(3), "updatedReplicas": int64(3)}),
+			expected: true,
+		},
+		{
+			name:  "deployment_rolling_out",
+			ready: readinessKinds["Deployment"].ready,
+			obj:   workload("Deployment", "web", 2, map[string]interface{}{"replicas": int64(3)}, map[string]interface{}{"observedGeneration": int64(2), "readyReplicas": int64(3), "updatedReplicas": int64(1)}),
+		},
+		{
+			name:  "deployment_generation_not_observed",
+			ready: readinessKinds["Deployment"].ready,
+			obj:   workload("Deployment", "web", 3, map[string]interface{}{"replicas": int64(1)}, map[string]interface{}{"observedGeneration": int64(2), "readyReplicas": int64(1), "updatedReplicas": int64(1)}),
+		},
+		{
+			name:     "statefulset_default_replicas",
+			ready:    readinessKinds["StatefulSet"].ready,
+			obj:      workload("StatefulSet", "db", 1, map[string]interface{}{}, map[string]interface{}{"observedGeneration": int64(1), "readyReplicas": int64(1)}),
+			expected: true,
+		},
+		{
+			name:  "daemonset_partially_ready",
+			ready: readinessKinds["DaemonSet"].ready,
+			obj:   workload("DaemonSet", "agent", 1, map[string]interface{}{}, map[string]interface{}{"observedGeneration": int64(1), "desiredNumberScheduled": int64(4), "numberReady": int64(3)}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ready, reason := tt.ready(tt.obj)
+			assert.Equal(t, tt.expected, ready)
+			if !tt.expected {
+				assert.NotEmpty(t, reason)
+			}
+		})
+	}
+}
+
+func TestReadinessWaiter_ChecksFor(t *testing.T) {
+	waiter := NewReadinessWaiter(nil, WaitForReadyConfig{
+		Enabled:  true,
+		Timeouts: map[string]time.Duration{"StatefulSet": 20 * time.Minute},
+	})
+
+	checks := waiter.ChecksFor([]RestoredResource{
+		{Kind: "Deployment", Namespace: "shop", Name: "web"},
+		{Kind: "StatefulSet", Namespace: "shop", Name: "db"},
+		{Kind: "ConfigMap", Namespace: "shop", Name: "settings"},
+		{Kind: "CustomResourceDefinition", Name: "widgets.example.com"},
+	})
+
+	require.Len(t, checks, 3)
+	assert.Equal(t, "Deployment/shop/web", checks[0].Target)
+	assert.Equal(t, "kubernetes", checks[0].Type)
+	assert.Equal(t, 5*time.Minute, checks[0].Timeout)
+	assert.Equal(t, 20*time.Minute, checks[1].Timeout)
+	assert.Equal(t, "CustomResourceDefinition/widgets.example.com", checks[2].Target)
+}
+
+func TestReadinessWaiter_Wait(t *testing.T) {
+	ready := workload("Deployment", "web", 1, map[string]interface{}{"replicas": int64(2)}, map[string]interface{}{"observedGeneration": int64(1), "readyReplicas": int64(2), "updatedReplicas": int64(2)})
+	stuck := workload("Deployment", "api", 1, map[string]interface{}{"replicas": int64(2)}, map[string]interface{}{"observedGeneration": int64(1), "readyReplicas": int64(0)})
+
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{deployments: "DeploymentList"}, ready, stuck)
+
+	waiter := NewReadinessWaiter(client, WaitForReadyConfig{
+		Enabled:  true,
+		Timeouts: map[string]time.Duration{"Deployment": 200 * time.Millisecond},
+	})
+	waiter.pollInterval = 20 * time.Millisecond
+
+	report := waiter.Wait(context.Background(), waiter.ChecksFor([]RestoredResource{
+		{Kind: "Deployment", Namespace: "shop", Name: "web"},
+		{Kind: "Deployment", Namespace: "shop", Name: "api"},
+	}))
+
+	assert.False(t, report.Ready)
+	require.Len(t, report.Results, 2)
+	assert.True(t, report.Results[0].Success)
+	assert.False(t, report.Results[1].Success)
+	assert.Contains(t, report.Results[1].ErrorMessage, "0/2 replicas ready")
+
+	failed := report.FailedCritical()
+	require.Len(t, failed, 1)
+	assert.Equal(t, "Deployment/shop/api", failed[0].Target)
+}
@@ -19,6 +19,8 @@ type RestoreAPI struct {
 	securityManager  *security.SecurityManager
 	monitoringSystem *monitoring.MonitoringSystem
 	config          *sharedconfig.SharedConfig
+	// drills rehearse restores, nil unless restore.drills is enabled
+	drills          *DrillScheduler
 }
 
 // APIResponse represents a standard API response
@@ -43,6 +45,7 @@ type RestoreAPIRequest struct {
 	RestoreID        string                 `json:"restore_id"`
 	BackupID         string                 `json:"backup_id"`
 	ClusterName      string                 `json:"cluster_name"`
+	SourcePath       string                 `json:"source_path,omitempty"`
 	TargetNamespaces []string               `json:"target_namespaces,omitempty"`
 	ResourceTypes    []string               `json:"resource_types,omitempty"`
 	LabelSelector    string                 `json:"label_selector,omitempty"`
@@ -50,6 +53,12 @@ type RestoreAPIRequest struct {
 	ValidationMode   ValidationMode         `json:"validation_mode"`
 	ConflictStrategy ConflictStrategy       `json:"conflict_strategy"`
 	DryRun           bool                   `json:"dry_run"`
+	ServerDryRun     bool                   `json:"server_dry_run,omitempty"`
+	Transforms       *TransformConfig       `json:"transforms,omitempty"`
+	WaitForReady     *WaitForReadyConfig    `json:"wait_for_ready,omitempty"`
+	CRDs             *CRDRestoreConfig      `json:"crds,omitempty"`
+	ForceUnsafe       bool                  `json:"force_unsafe,omitempty"`
+	ConfirmationToken string                `json:"confirmation_token,omitempty"`
 	Configuration    map[string]interface{} `json:"configuration,omitempty"`
 	Metadata         map[string]interface{} `json:"metadata,omitempty"`
 }
@@ -63,6 +72,7 @@ type DisasterRecoveryRequest struct {
 	ScenarioType     string                 `json:"scenario_type"` // cluster_rebuild, namespace_recovery, etc.
 	AutomationLevel  string                 `json:"automation_level"` // manual, assisted, automated
 	ValidationLevel  string                 `json:"validation_level"` // strict, permissive, skip
+	Transforms       *TransformConfig       `json:"transforms,omitempty"`
 	Configuration    map[string]interface{} `json:"configuration,omitempty"`
 	Metadata         map[string]interface{} `json:"metadata,omitempty"`
 }
@@ -77,12 +87,18 @@ func NewRestoreAPI(engine *RestoreEngine, security *security.SecurityManager, mo
 	}
 }
 
+// SetDrillScheduler serves the reports of drills and runs them on demand
+func (api *RestoreAPI) SetDrillScheduler(drills *DrillScheduler) {
+	api.drills = drills
+}
+
 // RegisterRoutes registers all restore API routes
 func (api *RestoreAPI) RegisterRoutes(router *mux.Router) {
 	// Restore operations
 	router.HandleFunc("/api/v1/restore", api.StartRestore).Methods("POST")
 	router.HandleFunc("/api/v1/restore/{restoreId}", api.GetRestoreStatus).Methods("GET")
 	router.HandleFunc("/api/v1/restore/{restoreId}", api.CancelRestore).Methods("DELETE")
+	router.HandleFunc("/api/v1/restore/{restoreId}/progress", api.GetRestoreProgress).Methods("GET")
 	router.HandleFunc("/api/v1/restore", api.ListActiveRestores).Methods("GET")
 	
 	// Restore history and management
@@ -94,6 +110,8 @@ func (api *RestoreAPI) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/api/v1/dr/scenarios", api.ListDRScenarios).Methods("GET")
 	router.HandleFunc("/api/v1/dr/execute", api.ExecuteDRScenario).Methods("POST")
 	router.HandleFunc("/api/v1/dr/scenarios/{scenarioId}", api.GetDRScenarioStatus).Methods("GET")
+	router.HandleFunc("/api/v1/dr/drills", api.ListDrills).Methods("GET")
+	router.HandleFunc("/api/v1/dr/drills", api.RunDrill).Methods("POST")
 	
 	// Backup management for restore
 	router.HandleFunc("/api/v1/backups", api.ListAvailableBackups).Methods("GET")
@@ -133,6 +151,7 @@ func (api *RestoreAPI) StartRestore(w http.ResponseWriter, r *http.Request) {
 		RestoreID:        req.RestoreID,
 		BackupID:         req.BackupID,
 		ClusterName:      req.ClusterName,
+		SourcePath:       req.SourcePath,
 		TargetNamespaces: req.TargetNamespaces,
 		ResourceTypes:    req.ResourceTypes,
 		LabelSelector:    req.LabelSelector,
@@ -140,6 +159,12 @@ func (api *RestoreAPI) StartRestore(w http.ResponseWriter, r *http.Request) {
 		ValidationMode:   req.ValidationMode,
 		ConflictStrategy: req.ConflictStrategy,
 		DryRun:           req.DryRun,
+		ServerDryRun:     req.ServerDryRun,
+		Transforms:       req.Transforms,
+		WaitForReady:     req.WaitForReady,
+		CRDs:             req.CRDs,
+		ForceUnsafe:       req.ForceUnsafe,
+		ConfirmationToken: req.ConfirmationToken,
 		Configuration:    req.Configuration,
 		Metadata:         req.Metadata,
 	}
@@ -175,7 +200,23 @@ func (api *RestoreAPI) GetRestoreStatus(w http.ResponseWriter, r *http.Request)
 	api.sendSuccess(w, "Restore status retrieved successfully", operation, http.StatusOK)
 }
 
-// CancelRestore cancels an active restore operation
+// GetRestoreProgress returns the phase, progress and failed resources of a
+// restore operation, active or recently finished
+func (api *RestoreAPI) GetRestoreProgress(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	restoreID := vars["restoreId"]
+	
+	report, err := api.restoreEngine.GetRestoreProgress(restoreID)
+	if err != nil {
+		api.sendError(w, "not_found", "Restore operation not found", err, http.StatusNotFound)
+		return
+	}
+	
+	api.sendSuccess(w, "Restore progress retrieved successfully", report, http.StatusOK)
+}
+
+// CancelRestore cancels an active restore operation once the resource being
+// applied is done, and returns its partial progress
 func (api *RestoreAPI) CancelRestore(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	restoreID := vars["restoreId"]
@@ -186,13 +227,18 @@ func (api *RestoreAPI) CancelRestore(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	// Cancel restore operation
+	if _, err := api.restoreEngine.GetRestoreStatus(restoreID); err != nil {
+		api.sendError(w, "not_found", "Restore operation not found", err, http.StatusNotFound)
+		return
+	}
 	err := api.restoreEngine.CancelRestore(restoreID)
 	if err != nil {
 		api.sendError(w, "cancel_failed", "Failed to cancel restore operation", err, http.StatusInternalServerError)
 		return
 	}
 	
-	api.sendSuccess(w, "Restore operation cancelled successfully", nil, http.StatusOK)
+	report, _ := api.restoreEngine.GetRestoreProgress(restoreID)
+	api.sendSuccess(w, "Restore operation cancelled successfully", report, http.StatusOK)
 }
 
 // ListActiveRestores returns all currently active restore operations
@@ -250,8 +296,12 @@ func (api *RestoreAPI) ValidateRestore(w http.ResponseWriter, r *http.Request) {
 	api.sendSuccess(w, "Restore validation completed", report, http.StatusOK)
 }
 
-// CreateRestorePlan creates a detailed restore plan
+// CreateRestorePlan returns the ordered plan of what restoring a backup would
+// apply to the target cluster, without applying anything. The plan is JSON
+// unless the format query parameter asks for text.
 func (api *RestoreAPI) CreateRestorePlan(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	
 	// Parse request
 	var req RestoreAPIRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -259,26 +309,35 @@ func (api *RestoreAPI) CreateRestorePlan(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	
-	// Create restore plan (simplified implementation)
-	plan := map[string]interface{}{
-		"restore_id":       req.RestoreID,
-		"backup_id":        req.BackupID,
-		"cluster_name":     req.ClusterName,
-		"restore_mode":     req.RestoreMode,
-		"estimated_time":   "30 minutes",
-		"total_resources":  100, // Would be calculated from backup
-		"phases": []string{
-			"validation", "preparation", "execution", "verification", "cleanup",
-		},
-		"risks": []string{
-			"Potential downtime during restore",
-			"Resource conflicts may require manual intervention",
-		},
-		"prerequisites": []string{
-			"Target cluster must be accessible",
-			"Sufficient permissions required",
-			"Adequate storage space needed",
-		},
+	// Convert to internal request format
+	restoreRequest := RestoreRequest{
+		RestoreID:        req.RestoreID,
+		BackupID:         req.BackupID,
+		ClusterName:      req.ClusterName,
+		SourcePath:       req.SourcePath,
+		TargetNamespaces: req.TargetNamespaces,
+		ResourceTypes:    req.ResourceTypes,
+		LabelSelector:    req.LabelSelector,
+		RestoreMode:      RestoreModePlan,
+		ValidationMode:   req.ValidationMode,
+		ConflictStrategy: req.ConflictStrategy,
+		DryRun:           true, // Plans never apply anything
+		ServerDryRun:     req.ServerDryRun,
+		Transforms:       req.Transforms,
+		CRDs:             req.CRDs,
+	}
+	
+	plan, err := api.restoreEngine.PlanRestore(ctx, restoreRequest)
+	if err != nil {
+		api.sendError(w, "plan_failed", "Restore planning failed", err, http.StatusInternalServerError)
+		return
+	}
+	
+	if r.URL.Query().Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		plan.WriteText(w)
+		return
 	}
 	
 	api.sendSuccess(w, "Restore plan created successfully", plan, http.StatusOK)
@@ -369,6 +428,33 @@ func (api *RestoreAPI) GetDRScenarioStatus(w http.ResponseWriter, r *http.Reques
 	api.sendSuccess(w, "DR scenario status retrieved successfully", operation, http.StatusOK)
 }
 
+// ListDrills returns the reports of the latest restore drills, newest first
+func (api *RestoreAPI) ListDrills(w http.ResponseWriter, r *http.Request) {
+	if api.drills == nil {
+		api.sendError(w, "not_configured", "Restore drills are not enabled", nil, http.StatusNotImplemented)
+		return
+	}
+	
+	api.sendSuccess(w, "Restore drills retrieved successfully", api.drills.Reports(), http.StatusOK)
+}
+
+// RunDrill starts a restore drill outside the schedule. Its report is listed
+// once it finishes.
+func (api *RestoreAPI) RunDrill(w http.ResponseWriter, r *http.Request) {
+	if api.drills == nil {
+		api.sendError(w, "not_configured", "Restore drills are not enabled", nil, http.StatusNotImplemented)
+		return
+	}
+	if api.drills.Running() {
+		api.sendError(w, "drill_running", "A restore drill is already running", nil, http.StatusConflict)
+		return
+	}
+	
+	// The drill outlives the request
+	go api.drills.RunDrill(context.Background())
+	api.sendSuccess(w, "Restore drill started", nil, http.StatusAccepted)
+}
+
 // ListAvailableBackups returns available backups for restore
 func (api *RestoreAPI) ListAvailableBackups(w http.ResponseWriter, r *http.Request) {
 	// This would integrate with backup storage (MinIO) to list available backups
@@ -583,6 +669,9 @@ func (api *RestoreAPI) convertDRToRestoreRequest(drReq DisasterRecoveryRequest)
 		RestoreMode:      restoreMode,
 		ValidationMode:   validationMode,
 		ConflictStrategy: conflictStrategy,
+		Transforms:       drReq.Transforms,
+		// DR scenarios are only done once the workloads are serving again
+		WaitForReady:     &WaitForReadyConfig{Enabled: true},
 		Configuration:    drReq.Configuration,
 		Metadata:         drReq.Metadata,
 	}, nil
@@ -627,7 +716,19 @@ func (api *RestoreAPI) sendError(w http.ResponseWriter, code, message string, er
 func (api *RestoreAPI) securityMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Apply security validations
-		if err := api.securityManager.ValidateRequest(r.Context(), r); err != nil {
+		headers := make(map[string]string, len(r.Header))
+		for name := range r.Header {
+			headers[name] = r.Header.Get(name)
+		}
+		request := &security.WebhookRequest{
+			RequestID: r.Header.Get("X-Request-ID"),
+			Method:    r.Method,
+			Endpoint:  r.URL.String(),
+			Headers:   headers,
+			SourceIP:  r.RemoteAddr,
+			UserAgent: r.UserAgent(),
+		}
+		if _, err := api.securityManager.SecureWebhookRequest(r.Context(), request); err != nil {
 			api.sendError(w, "security_error", "Security validation failed", err, http.StatusUnauthorized)
 			return
 		}
@@ -2,25 +2,25 @@ package restore
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 	"sync"
 	"path/filepath"
 	"strings"
-	"encoding/json"
-	"io"
 	"os"
 
 	sharedconfig "shared-config/config"
 	"shared-config/monitoring"
+	"shared-config/plugins"
 	"shared-config/security"
+	"shared-config/signing"
 	
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 )
@@ -33,6 +33,11 @@ type RestoreEngine struct {
 	monitoringSystem *monitoring.MonitoringSystem
 	securityManager  *security.SecurityManager
 	
+	// Bucket the backups are read from, nil when no storage is configured
+	objects          ObjectStore
+	// Checks manifest signatures, nil unless security.signing.verify is set
+	verifier         *signing.Verifier
+	
 	// Restore operation tracking
 	activeRestores   map[string]*RestoreOperation
 	restoreHistory   []*RestoreRecord
@@ -40,6 +45,19 @@ type RestoreEngine struct {
 	// Safety and validation
 	validator        *RestoreValidator
 	conflictResolver *ConflictResolver
+	dependencyResolver *DependencyResolver
+	
+	// Progress of restores, kept to resume them
+	checkpoints      CheckpointStore
+	
+	// Site-specific filters and transformers applied to every resource
+	plugins          *plugins.Chain
+	
+	// Namespaces and kinds restores skip unless forced
+	guardrails       *Guardrails
+	
+	// Whether restores other than dry runs need a second person's approval
+	approvalRequired bool
 	
 	mu sync.RWMutex
 }
@@ -49,6 +67,10 @@ type RestoreRequest struct {
 	RestoreID        string                 `json:"restore_id"`
 	BackupID         string                 `json:"backup_id"`
 	ClusterName      string                 `json:"cluster_name"`
+	// SourcePath is a directory or .tar.gz written by backup-util export to
+	// read the backup from in place of the object store, for clusters that
+	// cannot reach it
+	SourcePath       string                 `json:"source_path,omitempty"`
 	TargetNamespaces []string               `json:"target_namespaces,omitempty"`
 	ResourceTypes    []string               `json:"resource_types,omitempty"`
 	LabelSelector    string                 `json:"label_selector,omitempty"`
@@ -56,6 +78,19 @@ type RestoreRequest struct {
 	ValidationMode   ValidationMode         `json:"validation_mode"`
 	ConflictStrategy ConflictStrategy       `json:"conflict_strategy"`
 	DryRun           bool                   `json:"dry_run"`
+	ServerDryRun     bool                   `json:"server_dry_run,omitempty"`
+	Transforms       *TransformConfig       `json:"transforms,omitempty"`
+	WaitForReady     *WaitForReadyConfig    `json:"wait_for_ready,omitempty"`
+	CRDs             *CRDRestoreConfig      `json:"crds,omitempty"`
+	GovernanceFirst  *bool                  `json:"governance_first,omitempty"` // Default true
+	ConvertAPIVersions *bool                `json:"convert_api_versions,omitempty"` // Default true
+	Secrets          *SecretRestoreConfig   `json:"secrets,omitempty"`
+	// ForceUnsafe restores resources the guardrails protect, given the
+	// ConfirmationToken a restore without it reports
+	ForceUnsafe       bool                  `json:"force_unsafe,omitempty"`
+	ConfirmationToken string                `json:"confirmation_token,omitempty"`
+	// Approval is who approved the restore, for engines requiring approval
+	Approval         *RestoreApproval       `json:"approval,omitempty"`
 	Configuration    map[string]interface{} `json:"configuration,omitempty"`
 	Metadata         map[string]interface{} `json:"metadata,omitempty"`
 }
@@ -68,6 +103,7 @@ const (
 	RestoreModeSelective   RestoreMode = "selective"    // Restore only specified resources
 	RestoreModeIncremental RestoreMode = "incremental"  // Restore only missing resources
 	RestoreModeValidation  RestoreMode = "validation"   // Validate without applying
+	RestoreModePlan        RestoreMode = "plan"         // Report what would be applied, without applying
 )
 
 // ValidationMode defines validation strictness
@@ -98,12 +134,23 @@ type RestoreOperation struct {
 	Progress         RestoreProgress        `json:"progress"`
 	Results          RestoreResults         `json:"results"`
 	ValidationReport *ValidationReport      `json:"validation_report,omitempty"`
+	ReadinessReport  *ReadinessReport       `json:"readiness_report,omitempty"`
+	Plan             *RestorePlanReport     `json:"plan,omitempty"`
 	Errors           []RestoreError         `json:"errors,omitempty"`
+	Attempt          int                    `json:"attempt,omitempty"`
+	Resumed          bool                   `json:"resumed,omitempty"`
 	
 	// Internal tracking
 	ctx              context.Context
 	cancel           context.CancelFunc
 	completionChan   chan struct{}
+	transformer      *TransformEngine
+	checkpoint       *RestoreCheckpoint
+	sinceCheckpoint  int
+	conversions      map[string][]FieldChange
+	// published is the progress GetRestoreProgress reports, guarded by the
+	// engine's mutex while the operation's goroutine changes the operation
+	published        *RestoreProgressReport
 }
 
 // RestoreStatus represents the current state of a restore operation
@@ -113,13 +160,29 @@ const (
 	RestoreStatusPending    RestoreStatus = "pending"
 	RestoreStatusValidating RestoreStatus = "validating"
 	RestoreStatusRestoring  RestoreStatus = "restoring"
+	RestoreStatusWaiting    RestoreStatus = "waiting_for_ready"
 	RestoreStatusCompleted  RestoreStatus = "completed"
 	RestoreStatusFailed     RestoreStatus = "failed"
 	RestoreStatusCancelled  RestoreStatus = "cancelled"
 )
 
+// RestorePhase is the step a restore operation is at
+type RestorePhase string
+
+const (
+	RestorePhaseValidating RestorePhase = "validating"
+	RestorePhaseLoading    RestorePhase = "loading"
+	RestorePhasePlanning   RestorePhase = "planning"
+	RestorePhaseCRDs       RestorePhase = "crds"
+	RestorePhaseGovernance RestorePhase = "governance"
+	RestorePhaseResources  RestorePhase = "resources"
+	RestorePhaseWaiting    RestorePhase = "waiting_for_ready"
+	RestorePhaseDone       RestorePhase = "done"
+)
+
 // RestoreProgress tracks restoration progress
 type RestoreProgress struct {
+	Phase                RestorePhase       `json:"phase"`
 	TotalResources       int                `json:"total_resources"`
 	ProcessedResources   int                `json:"processed_resources"`
 	SuccessfulResources  int                `json:"successful_resources"`
@@ -207,11 +270,15 @@ type RestoreRecord struct {
 	EndTime      *time.Time    `json:"end_time,omitempty"`
 	Duration     *time.Duration `json:"duration,omitempty"`
 	Summary      RestoreSummary `json:"summary"`
+	Progress     RestoreProgress `json:"progress"`
+	FailedResources []FailedResource `json:"failed_resources,omitempty"`
 	UserID       string        `json:"user_id,omitempty"`
 	RequestHash  string        `json:"request_hash"`
 }
 
-// NewRestoreEngine creates a new restore engine instance
+// NewRestoreEngine creates a new restore engine instance. Without a
+// monitoring system no metrics are reported, and without a security manager
+// requests are not validated.
 func NewRestoreEngine(config *sharedconfig.SharedConfig, monitoring *monitoring.MonitoringSystem, security *security.SecurityManager) (*RestoreEngine, error) {
 	// Initialize Kubernetes clients
 	k8sConfig, err := rest.InClusterConfig()
@@ -237,30 +304,96 @@ func NewRestoreEngine(config *sharedconfig.SharedConfig, monitoring *monitoring.
 	validator := NewRestoreValidator(config, k8sClient)
 	conflictResolver := NewConflictResolver(config)
 
+	var objects ObjectStore
+	if config.Storage.Endpoint != "" {
+		objects, err = newMinIOStoreFromConfig(config.Storage)
+		if err != nil {
+			return nil, err
+		}
+	}
+	verifier, err := newManifestVerifier(config.Security.Signing.Verify)
+	if err != nil {
+		return nil, err
+	}
+
+	var resourcePlugins *plugins.Chain
+	if len(config.Plugins.Paths) > 0 {
+		resourcePlugins, err = plugins.Open(config.Plugins.Paths, config.Plugins.Options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load resource plugins: %v", err)
+		}
+	}
+
 	engine := &RestoreEngine{
 		config:           config,
 		k8sClient:        k8sClient,
 		dynamicClient:    dynamicClient,
 		monitoringSystem: monitoring,
 		securityManager:  security,
+		objects:          objects,
+		verifier:         verifier,
 		activeRestores:   make(map[string]*RestoreOperation),
 		restoreHistory:   make([]*RestoreRecord, 0),
 		validator:        validator,
 		conflictResolver: conflictResolver,
+		dependencyResolver: NewDependencyResolver(),
+		checkpoints:      NewConfigMapCheckpointStore(k8sClient, checkpointNamespace()),
+		plugins:          resourcePlugins,
+		guardrails:       NewGuardrails(config.Restore.Guardrails),
 	}
 
 	return engine, nil
 }
 
+// validateRequest checks the identifiers and namespaces of request with the
+// security manager's input validator
+func (re *RestoreEngine) validateRequest(request RestoreRequest) error {
+	if re.securityManager == nil || re.securityManager.GetInputValidator() == nil {
+		return nil
+	}
+	validator := re.securityManager.GetInputValidator()
+
+	fields := map[string]string{
+		"restore_id":   request.RestoreID,
+		"backup_id":    request.BackupID,
+		"cluster_name": request.ClusterName,
+	}
+	for field, value := range fields {
+		if value == "" {
+			continue
+		}
+		invalid, err := validator.ValidateInput(field, value, security.InputTypeString)
+		if err != nil {
+			return err
+		}
+		if invalid != nil {
+			return invalid
+		}
+	}
+	for _, namespace := range request.TargetNamespaces {
+		invalid, err := validator.ValidateInput("target_namespaces", namespace, security.InputTypeDNSName)
+		if err != nil {
+			return err
+		}
+		if invalid != nil {
+			return invalid
+		}
+	}
+	return nil
+}
+
 // StartRestore initiates a new restore operation
 func (re *RestoreEngine) StartRestore(ctx context.Context, request RestoreRequest) (*RestoreOperation, error) {
 	re.mu.Lock()
 	defer re.mu.Unlock()
 
 	// Security validation
-	if err := re.securityManager.ValidateRestoreRequest(ctx, request); err != nil {
+	if err := re.validateRequest(request); err != nil {
 		return nil, fmt.Errorf("security validation failed: %v", err)
 	}
+	if err := re.checkApproval(request); err != nil {
+		return nil, err
+	}
 
 	// Check if restore is already running
 	if _, exists := re.activeRestores[request.RestoreID]; exists {
@@ -280,17 +413,19 @@ func (re *RestoreEngine) StartRestore(ctx context.Context, request RestoreReques
 		completionChan: make(chan struct{}),
 	}
 
+	// Rewrite environment-specific values for cross-environment restores
+	if !request.Transforms.IsEmpty() {
+		operation.transformer = NewTransformEngine(*request.Transforms)
+	}
+
 	re.activeRestores[request.RestoreID] = operation
+	operation.published = operation.progressReport()
 
 	// Start restore operation in background
 	go re.executeRestore(operation)
 
 	// Update monitoring metrics
-	re.monitoringSystem.GetMonitoringHub().GetMetricsCollector().IncCounter(
-		"restore_operations_started",
-		map[string]string{"cluster": request.ClusterName, "mode": string(request.RestoreMode)},
-		1,
-	)
+	re.incCounter("restore_operations_started", map[string]string{"cluster": request.ClusterName, "mode": string(request.RestoreMode)})
 
 	return operation, nil
 }
@@ -309,46 +444,105 @@ func (re *RestoreEngine) executeRestore(operation *RestoreOperation) {
 			StartTime:   operation.StartTime,
 			EndTime:     operation.EndTime,
 			Summary:     operation.Results.Summary,
+			Progress:    operation.Progress,
+			FailedResources: operation.Results.FailedResources,
+			RequestHash: requestHash(operation.Request),
 		})
 		re.mu.Unlock()
 	}()
 
 	// Phase 1: Validation
 	operation.Status = RestoreStatusValidating
+	operation.Progress.Phase = RestorePhaseValidating
+	re.publishProgress(operation)
 	if err := re.validateRestoreRequest(operation); err != nil {
-		re.failRestore(operation, fmt.Errorf("validation failed: %v", err))
+		re.abortRestore(operation, fmt.Errorf("validation failed: %v", err))
 		return
 	}
 
 	// Phase 2: Load backup data
+	operation.Progress.Phase = RestorePhaseLoading
+	re.publishProgress(operation)
 	backupData, err := re.loadBackupData(operation)
 	if err != nil {
-		re.failRestore(operation, fmt.Errorf("failed to load backup data: %v", err))
+		re.abortRestore(operation, fmt.Errorf("failed to load backup data: %v", err))
+		return
+	}
+	re.publishProgress(operation)
+
+	// Plan the restore against the live cluster, and with server-side
+	// dry-run let admission reject resources before anything is applied
+	if operation.Request.RestoreMode == RestoreModePlan || operation.Request.ServerDryRun {
+		operation.Progress.Phase = RestorePhasePlanning
+		re.publishProgress(operation)
+		plan, err := re.planResources(operation, backupData)
+		if err != nil {
+			re.abortRestore(operation, fmt.Errorf("failed to plan restore: %v", err))
+			return
+		}
+		operation.Plan = plan
+	}
+
+	// Plan mode stops after planning
+	if operation.Request.RestoreMode == RestoreModePlan {
+		plan := operation.Plan
+		operation.Results.Summary = RestoreSummary{
+			TotalDuration:      time.Since(operation.StartTime),
+			ResourcesProcessed: plan.Summary.Total,
+		}
+		now := time.Now()
+		operation.EndTime = &now
+		operation.Status = RestoreStatusCompleted
+		operation.Progress.Phase = RestorePhaseDone
+		operation.Progress.PercentComplete = 100.0
+		re.publishProgress(operation)
 		return
 	}
 
-	// Phase 3: Execute restore
+	if err := re.checkAdmission(operation); err != nil {
+		re.failRestore(operation, err)
+		return
+	}
+
+	// Phase 3: Execute restore, resuming an interrupted attempt of the same
+	// restore_id
 	operation.Status = RestoreStatusRestoring
-	if err := re.restoreResources(operation, backupData); err != nil {
-		re.failRestore(operation, fmt.Errorf("restore failed: %v", err))
+	re.publishProgress(operation)
+	if err := re.resumeCheckpoint(operation); err != nil {
+		re.failRestore(operation, err)
+		return
+	}
+	err = re.restoreResources(operation, backupData)
+	re.finishCheckpoint(operation, err)
+	if err != nil {
+		re.abortRestore(operation, fmt.Errorf("restore failed: %v", err))
 		return
 	}
 
+	// Phase 4: Wait for restored workloads to become ready
+	if operation.Request.WaitForReady != nil && operation.Request.WaitForReady.Enabled && !operation.Request.DryRun {
+		operation.Status = RestoreStatusWaiting
+		operation.Progress.Phase = RestorePhaseWaiting
+		re.publishProgress(operation)
+		if err := re.waitForReady(operation); err != nil {
+			re.abortRestore(operation, err)
+			return
+		}
+	}
+
 	// Complete restore
 	now := time.Now()
 	operation.EndTime = &now
 	operation.Status = RestoreStatusCompleted
+	operation.Progress.Phase = RestorePhaseDone
 	operation.Progress.PercentComplete = 100.0
+	re.publishProgress(operation)
 
 	// Update final metrics
-	re.monitoringSystem.GetMonitoringHub().GetMetricsCollector().IncCounter(
-		"restore_operations_completed",
-		map[string]string{
-			"cluster": operation.Request.ClusterName,
-			"status":  string(operation.Status),
-		},
-		1,
-	)
+	re.incCounter("restore_operations_completed", map[string]string{
+		"cluster": operation.Request.ClusterName,
+		"status":  string(operation.Status),
+	})
 }
 
 // validateRestoreRequest validates the restore request and target cluster
@@ -371,53 +565,172 @@ func (re *RestoreEngine) validateRestoreRequest(operation *RestoreOperation) err
 	return nil
 }
 
-// loadBackupData loads and parses backup data from MinIO
+// checkAdmission records the resources the server-side dry-run rejected.
+// Unless validation is permissive, any rejection fails the restore before
+// anything is applied instead of partway through.
+func (re *RestoreEngine) checkAdmission(operation *RestoreOperation) error {
+	if operation.Plan == nil || len(operation.Plan.Rejections) == 0 {
+		return nil
+	}
+
+	for _, phase := range operation.Plan.Phases {
+		for _, resource := range phase.Resources {
+			if resource.Rejection == nil {
+				continue
+			}
+			operation.Errors = append(operation.Errors, RestoreError{
+				Type:        "admission_rejected",
+				Message:     resource.Rejection.Message,
+				Resource:    fmt.Sprintf("%s/%s", resource.Kind, resource.Name),
+				Namespace:   resource.Namespace,
+				Timestamp:   time.Now(),
+				Recoverable: operation.Request.ValidationMode == ValidationModePermissive,
+			})
+		}
+	}
+
+	if operation.Request.ValidationMode == ValidationModePermissive {
+		return nil
+	}
+	return fmt.Errorf("server-side dry-run rejected %d resources", len(operation.Plan.Rejections))
+}
+
+// loadBackupData loads the resources of the backup from the bucket, or from
+// the export at the request's SourcePath. When security.signing.verify is set
+// the run's manifest signature must pass first. Resources whose SHA-256 differs
+// from the checksums recorded for them are rejected. The resources the request selects are
+// then converted and checked against the secret policy and the guardrails.
 func (re *RestoreEngine) loadBackupData(operation *RestoreOperation) ([]BackupResource, error) {
-	// Implementation would load backup data from MinIO storage
-	// This is a simplified placeholder
-	
-	backupPath := fmt.Sprintf("%s/%s", operation.Request.ClusterName, operation.Request.BackupID)
-	
-	// For now, return mock data structure
-	// In real implementation, this would:
-	// 1. Connect to MinIO
-	// 2. Download backup files
-	// 3. Parse YAML resources
-	// 4. Filter based on request criteria
+	var content *BackupContent
+	var err error
+	if operation.Request.SourcePath != "" {
+		content, err = LoadExport(operation.Request.SourcePath, operation.Request.BackupID, re.verifier)
+	} else if re.objects == nil {
+		err = fmt.Errorf("no object storage is configured to read backup %s from", operation.Request.BackupID)
+	} else {
+		content, err = LoadBucket(operation.ctx, re.objects, re.config.Storage.Bucket, re.config.Cluster.Domain,
+			operation.Request.ClusterName, operation.Request.BackupID, re.verifier)
+	}
+	if err != nil {
+		return nil, err
+	}
+	resources := content.Resources
+	operation.Results.SkippedResources = append(operation.Results.SkippedResources, content.Skipped...)
+	operation.Progress.SkippedResources += len(content.Skipped)
+
+	// The conversion looks up ImageStreams, Services and Route certificates
+	// among all of the run's resources, so it indexes them before filtering
+	if operation.transformer != nil && operation.transformer.openShift != nil {
+		resources = withRouteSecrets(resources, content.RouteSecrets)
+		var skipped []SkippedResource
+		resources, skipped = operation.transformer.openShift.index(resources, content.ImageStreamTags)
+		operation.Results.SkippedResources = append(operation.Results.SkippedResources, skipped...)
+		operation.Progress.SkippedResources += len(skipped)
+	}
+
+	resources, err = filterResources(operation.Request, resources)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, skipped, err := re.applySecretPolicy(operation, resources)
+	if err != nil {
+		return nil, err
+	}
+	operation.Results.SkippedResources = append(operation.Results.SkippedResources, skipped...)
+	operation.Progress.SkippedResources += len(skipped)
 	
-	resources := []BackupResource{
-		{
-			APIVersion: "v1",
-			Kind:       "Namespace",
-			Name:       "example-namespace",
-			Data:       map[string]interface{}{},
-		},
-		// More resources would be loaded here
+	resources, skipped, err = re.applyGuardrails(operation, resources)
+	if err != nil {
+		return nil, err
 	}
+	operation.Results.SkippedResources = append(operation.Results.SkippedResources, skipped...)
+	operation.Progress.SkippedResources += len(skipped)
+	
+	// Resources of API versions the target no longer serves
+	resources = re.convertAPIVersions(operation, resources)
 	
 	operation.Progress.TotalResources = len(resources)
 	
 	return resources, nil
 }
 
-// restoreResources applies the backup resources to the target cluster
+// restoreResources applies the backup resources to the target cluster in dependency order
 func (re *RestoreEngine) restoreResources(operation *RestoreOperation, resources []BackupResource) error {
-	for i, resource := range resources {
-		select {
-		case <-operation.ctx.Done():
-			operation.Status = RestoreStatusCancelled
-			return fmt.Errorf("restore operation cancelled")
-		default:
+	resolver := re.resolverFor(operation.Request)
+	plan := resolver.Resolve(resources)
+	for _, warning := range plan.Warnings {
+		operation.Errors = append(operation.Errors, RestoreError{
+			Type:        "dependency_cycle",
+			Message:     warning,
+			Timestamp:   time.Now(),
+			Recoverable: true,
+		})
+	}
+
+	// CRDs are installed and established before anything else is applied
+	operation.Progress.Phase = RestorePhaseCRDs
+	re.publishProgress(operation)
+	established := re.installCRDs(operation, plan)
+	operation.Progress.Phase = RestorePhaseResources
+
+	for i, resource := range plan.Ordered {
+		// Stop between resources, leaving the rest pending
+		if operation.ctx.Err() != nil {
+			summarizeResults(operation)
+			return errRestoreCancelled
 		}
 
 		// Update progress
+		operation.Progress.Phase = RestorePhaseResources
+		if resolver.IsGovernance(resource.Kind) {
+			operation.Progress.Phase = RestorePhaseGovernance
+		}
 		operation.Progress.ProcessedResources = i + 1
 		operation.Progress.CurrentNamespace = resource.Namespace
 		operation.Progress.CurrentResource = fmt.Sprintf("%s/%s", resource.Kind, resource.Name)
 		operation.Progress.PercentComplete = float64(i+1) / float64(len(resources)) * 100
+		re.publishProgress(operation)
+
+		// Already handled by installCRDs
+		if resource.Kind == "CustomResourceDefinition" {
+			continue
+		}
+
+		if re.skipApplied(operation, resource) {
+			continue
+		}
+
+		// Custom resources cannot be applied until their CRD is served
+		if crdName, _, ok := plan.CRDFor(resource); ok && !established[crdName] {
+			operation.Results.FailedResources = append(operation.Results.FailedResources, FailedResource{
+				APIVersion: resource.APIVersion,
+				Kind:       resource.Kind,
+				Namespace:  resource.Namespace,
+				Name:       resource.Name,
+				Error:      fmt.Sprintf("CustomResourceDefinition %s is not established", crdName),
+				Timestamp:  time.Now(),
+				Retry:      true,
+			})
+			operation.Progress.FailedResources++
+			re.checkpointFailed(operation, resource, "CustomResourceDefinition not established")
+			continue
+		}
 
 		// Restore individual resource
-		if err := re.restoreResource(operation, resource); err != nil {
+		changes, err := re.restoreResource(operation, resource, plan)
+		if err != nil && operation.ctx.Err() != nil {
+			// Interrupted by the cancellation, the resource stays pending
+			operation.Progress.ProcessedResources = i
+			continue
+		}
+		var filtered *pluginFilteredError
+		if errors.Is(err, errRestoredEarlier) {
+			re.recordRestoredEarlier(operation, resource)
+			re.checkpointApplied(operation, resource)
+		} else if errors.As(err, &filtered) {
+			re.recordFiltered(operation, resource, filtered)
+		} else if err != nil {
 			operation.Results.FailedResources = append(operation.Results.FailedResources, FailedResource{
 				APIVersion: resource.APIVersion,
 				Kind:       resource.Kind,
@@ -428,16 +741,22 @@ func (re *RestoreEngine) restoreResources(operation *RestoreOperation, resources
 				Retry:      false,
 			})
 			operation.Progress.FailedResources++
+			re.checkpointFailed(operation, resource, err.Error())
 		} else {
-			operation.Results.RestoredResources = append(operation.Results.RestoredResources, RestoredResource{
+			restored := RestoredResource{
 				APIVersion: resource.APIVersion,
 				Kind:       resource.Kind,
 				Namespace:  resource.Namespace,
 				Name:       resource.Name,
 				Action:     "created",
 				Timestamp:  time.Now(),
-			})
+			}
+			if len(changes) > 0 {
+				restored.Metadata = map[string]interface{}{"transforms": changes}
+			}
+			operation.Results.RestoredResources = append(operation.Results.RestoredResources, restored)
 			operation.Progress.SuccessfulResources++
+			re.checkpointApplied(operation, resource)
 		}
 
 		// Update resource breakdown
@@ -445,63 +764,232 @@ func (re *RestoreEngine) restoreResources(operation *RestoreOperation, resources
 		operation.Progress.ResourceBreakdown[resourceType]++
 	}
 
-	// Calculate final summary
-	operation.Results.Summary = RestoreSummary{
-		TotalDuration:       time.Since(operation.StartTime),
-		ResourcesProcessed:  operation.Progress.ProcessedResources,
-		ResourcesSuccessful: operation.Progress.SuccessfulResources,
-		ResourcesFailed:     operation.Progress.FailedResources,
-		ResourcesSkipped:    operation.Progress.SkippedResources,
-		SuccessRate:         float64(operation.Progress.SuccessfulResources) / float64(operation.Progress.ProcessedResources) * 100,
-	}
-
+	summarizeResults(operation)
+	re.publishProgress(operation)
 	return nil
 }
 
-// restoreResource restores a single Kubernetes resource
-func (re *RestoreEngine) restoreResource(operation *RestoreOperation, resource BackupResource) error {
-	// Convert backup resource to unstructured object
-	obj := &unstructured.Unstructured{}
-	obj.SetAPIVersion(resource.APIVersion)
-	obj.SetKind(resource.Kind)
-	obj.SetName(resource.Name)
-	obj.SetNamespace(resource.Namespace)
-	
-	// Set resource data
-	for key, value := range resource.Data {
-		obj.Object[key] = value
+// resolverFor returns the dependency resolver ordering request's resources
+func (re *RestoreEngine) resolverFor(request RestoreRequest) *DependencyResolver {
+	if request.GovernanceFirst != nil && !*request.GovernanceFirst {
+		return &DependencyResolver{}
 	}
+	return re.dependencyResolver
+}
 
-	// Get dynamic client for resource type
-	gvr := schema.GroupVersionResource{
-		Group:    obj.GroupVersionKind().Group,
-		Version:  obj.GroupVersionKind().Version,
-		Resource: strings.ToLower(obj.GetKind()) + "s", // Simple pluralization
+// installCRDs applies the CRDs of a restore and waits for them to be
+// established. It returns the CRDs custom resources can be applied against:
+// those established on the target, including CRDs skipped because the target
+// already serves a newer version.
+func (re *RestoreEngine) installCRDs(operation *RestoreOperation, plan *RestorePlan) map[string]bool {
+	config := CRDRestoreConfig{}
+	if operation.Request.CRDs != nil {
+		config = *operation.Request.CRDs
 	}
 
-	var resourceClient dynamic.ResourceInterface
-	if obj.GetNamespace() != "" {
-		resourceClient = re.dynamicClient.Resource(gvr).Namespace(obj.GetNamespace())
-	} else {
-		resourceClient = re.dynamicClient.Resource(gvr)
+	established := make(map[string]bool)
+	client := re.dynamicClient.Resource(crdGVR)
+	var installed []RestoredResource
+
+	for _, resource := range plan.Ordered {
+		if resource.Kind != "CustomResourceDefinition" {
+			continue
+		}
+		if operation.ctx.Err() != nil {
+			return established
+		}
+		operation.Progress.ResourceBreakdown[fmt.Sprintf("%s/%s", resource.APIVersion, resource.Kind)]++
+		re.publishProgress(operation)
+
+		// Established before an earlier attempt recorded it
+		if re.skipApplied(operation, resource) {
+			established[resource.Name] = true
+			continue
+		}
+
+		obj := &unstructured.Unstructured{Object: stripCRDStatus(resource.Data)}
+		obj.SetAPIVersion(resource.APIVersion)
+		obj.SetKind(resource.Kind)
+		obj.SetName(resource.Name)
+
+		var existingObj map[string]interface{}
+		existing, err := client.Get(operation.ctx, resource.Name, metav1.GetOptions{})
+		if err == nil {
+			existingObj = existing.Object
+		}
+
+		action, reason := planCRDInstall(obj.Object, existingObj, config)
+		restoredAction := "created"
+		switch action {
+		case crdActionSkip:
+			operation.Results.SkippedResources = append(operation.Results.SkippedResources, SkippedResource{
+				APIVersion: resource.APIVersion,
+				Kind:       resource.Kind,
+				Name:       resource.Name,
+				Reason:     reason,
+				Timestamp:  time.Now(),
+			})
+			operation.Progress.SkippedResources++
+			established[resource.Name] = true
+			continue
+		case crdActionCreate:
+			if !operation.Request.DryRun {
+				_, err = client.Create(operation.ctx, obj, metav1.CreateOptions{})
+			}
+		case crdActionUpdate:
+			restoredAction = "updated"
+			err = re.handleResourceConflict(operation, client, existing, obj)
+		}
+		if err != nil {
+			operation.Results.FailedResources = append(operation.Results.FailedResources, FailedResource{
+				APIVersion: resource.APIVersion,
+				Kind:       resource.Kind,
+				Name:       resource.Name,
+				Error:      fmt.Sprintf("failed to %s CustomResourceDefinition: %v", action, err),
+				Timestamp:  time.Now(),
+			})
+			operation.Progress.FailedResources++
+			re.checkpointFailed(operation, resource, err.Error())
+			continue
+		}
+
+		installed = append(installed, RestoredResource{
+			APIVersion: resource.APIVersion,
+			Kind:       resource.Kind,
+			Name:       resource.Name,
+			Action:     restoredAction,
+		})
+	}
+
+	// Wait once all CRDs are applied so the API server establishes them in parallel
+	for _, restored := range installed {
+		resource := BackupResource{APIVersion: restored.APIVersion, Kind: restored.Kind, Name: restored.Name}
+		if !operation.Request.DryRun {
+			if err := waitForCRDEstablished(operation.ctx, re.dynamicClient, restored.Name, re.config.Timeouts.RestoreResourceTimeout); err != nil {
+				if operation.ctx.Err() != nil {
+					return established
+				}
+				operation.Results.FailedResources = append(operation.Results.FailedResources, FailedResource{
+					APIVersion: restored.APIVersion,
+					Kind:       restored.Kind,
+					Name:       restored.Name,
+					Error:      err.Error(),
+					Timestamp:  time.Now(),
+					Retry:      true,
+				})
+				operation.Progress.FailedResources++
+				re.checkpointFailed(operation, resource, err.Error())
+				continue
+			}
+		}
+
+		restored.Timestamp = time.Now()
+		operation.Results.RestoredResources = append(operation.Results.RestoredResources, restored)
+		operation.Progress.SuccessfulResources++
+		established[restored.Name] = true
+		re.checkpointApplied(operation, resource)
 	}
 
+	return established
+}
+
+// restoreResource restores a single Kubernetes resource and returns the
+// transformations applied to it
+func (re *RestoreEngine) restoreResource(operation *RestoreOperation, resource BackupResource, plan *RestorePlan) ([]FieldChange, error) {
+	obj := backupObject(resource)
+
+	changes := operation.conversionChanges(resource)
+	if operation.transformer != nil {
+		transformed, err := operation.transformer.Apply(obj)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, transformed...)
+	}
+	if err := re.applyPlugins(obj); err != nil {
+		return nil, err
+	}
+
+	// Mark the object so applying it again in this restore is a no-op
+	restoreID := operation.Request.RestoreID
+	if restoreID != "" {
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[RestoreIDAnnotation] = restoreID
+		obj.SetAnnotations(annotations)
+	}
+
+	resourceClient := re.resourceClient(obj, resource, plan)
+
 	// Check for existing resource
 	existing, err := resourceClient.Get(operation.ctx, obj.GetName(), metav1.GetOptions{})
 	if err == nil {
+		if restoreID != "" && existing.GetAnnotations()[RestoreIDAnnotation] == restoreID {
+			return nil, errRestoredEarlier
+		}
 		// Resource exists, handle conflict
-		return re.handleResourceConflict(operation, resourceClient, existing, obj)
+		return changes, re.handleResourceConflict(operation, resourceClient, existing, obj)
 	}
 
 	// Resource doesn't exist, create it
 	if !operation.Request.DryRun {
 		_, err = resourceClient.Create(operation.ctx, obj, metav1.CreateOptions{})
 		if err != nil {
-			return fmt.Errorf("failed to create resource %s/%s: %v", obj.GetKind(), obj.GetName(), err)
+			return nil, fmt.Errorf("failed to create resource %s/%s: %v", obj.GetKind(), obj.GetName(), err)
 		}
 	}
 
-	return nil
+	return changes, nil
+}
+
+// backupObject converts a backup resource to an unstructured object
+func backupObject(resource BackupResource) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: make(map[string]interface{}, len(resource.Data))}
+	for key, value := range resource.Data {
+		obj.Object[key] = value
+	}
+
+	// Identity comes last so the stored metadata cannot clear it
+	obj.SetAPIVersion(resource.APIVersion)
+	obj.SetKind(resource.Kind)
+	obj.SetName(resource.Name)
+	obj.SetNamespace(resource.Namespace)
+	return obj
+}
+
+// resourceClient returns the dynamic client for the resource type of obj
+func (re *RestoreEngine) resourceClient(obj *unstructured.Unstructured, resource BackupResource, plan *RestorePlan) dynamic.ResourceInterface {
+	gvr := schema.GroupVersionResource{
+		Group:    obj.GroupVersionKind().Group,
+		Version:  obj.GroupVersionKind().Version,
+		Resource: pluralize(obj.GetKind()),
+	}
+	// Custom resources use the plural declared by their CRD
+	if _, plural, ok := plan.CRDFor(resource); ok && plural != "" {
+		gvr.Resource = plural
+	}
+
+	if obj.GetNamespace() != "" {
+		return re.dynamicClient.Resource(gvr).Namespace(obj.GetNamespace())
+	}
+	return re.dynamicClient.Resource(gvr)
+}
+
+// pluralize guesses the resource name of a built-in kind, e.g. ingresses,
+// networkpolicies and endpoints
+func pluralize(kind string) string {
+	resource := strings.ToLower(kind)
+	switch {
+	case strings.HasSuffix(resource, "ss"):
+		return resource + "es"
+	case strings.HasSuffix(resource, "s"):
+		return resource
+	case strings.HasSuffix(resource, "y") && !strings.ContainsAny(resource[len(resource)-2:len(resource)-1], "aeiou"):
+		return strings.TrimSuffix(resource, "y") + "ies"
+	}
+	return resource + "s"
 }
 
 // handleResourceConflict resolves conflicts when restoring existing resources
@@ -527,6 +1015,44 @@ func (re *RestoreEngine) handleResourceConflict(operation *RestoreOperation, cli
 	return nil
 }
 
+// waitForReady waits for restored resources to report ready and attaches the
+// readiness report to the operation. Failed critical checks fail the restore.
+func (re *RestoreEngine) waitForReady(operation *RestoreOperation) error {
+	waiter := NewReadinessWaiter(re.dynamicClient, *operation.Request.WaitForReady)
+	
+	// Resources are checked in the namespaces they were restored into
+	restored := operation.Results.RestoredResources
+	if transforms := operation.Request.Transforms; transforms != nil && len(transforms.NamespaceMappings) > 0 {
+		restored = make([]RestoredResource, len(operation.Results.RestoredResources))
+		for i, resource := range operation.Results.RestoredResources {
+			resource.Namespace = transforms.MapNamespace(resource.Namespace)
+			restored[i] = resource
+		}
+	}
+	checks := waiter.ChecksFor(restored)
+	if len(checks) == 0 {
+		return nil
+	}
+
+	report := waiter.Wait(operation.ctx, checks)
+	operation.ReadinessReport = report
+
+	failed := report.FailedCritical()
+	for _, result := range failed {
+		operation.Errors = append(operation.Errors, RestoreError{
+			Type:        "readiness_check_failed",
+			Message:     result.ErrorMessage,
+			Resource:    result.Target,
+			Timestamp:   result.EndTime,
+			Recoverable: true,
+		})
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d restored resources did not become ready", len(failed), len(checks))
+	}
+	return nil
+}
+
 // failRestore marks a restore operation as failed
 func (re *RestoreEngine) failRestore(operation *RestoreOperation, err error) {
 	now := time.Now()
@@ -538,13 +1064,19 @@ func (re *RestoreEngine) failRestore(operation *RestoreOperation, err error) {
 		Timestamp:   now,
 		Recoverable: false,
 	})
+	re.publishProgress(operation)
 
 	// Update monitoring metrics
-	re.monitoringSystem.GetMonitoringHub().GetMetricsCollector().IncCounter(
-		"restore_operations_failed",
-		map[string]string{"cluster": operation.Request.ClusterName},
-		1,
-	)
+	re.incCounter("restore_operations_failed", map[string]string{"cluster": operation.Request.ClusterName})
+}
+
+// incCounter increments a restore metric, when the engine reports to a
+// monitoring system
+func (re *RestoreEngine) incCounter(name string, labels map[string]string) {
+	if re.monitoringSystem == nil {
+		return
+	}
+	re.monitoringSystem.GetMonitoringHub().GetMetricsCollector().IncCounter(name, labels, 1)
 }
 
 // GetRestoreStatus returns the current status of a restore operation
@@ -560,20 +1092,24 @@ func (re *RestoreEngine) GetRestoreStatus(restoreID string) (*RestoreOperation,
 	return operation, nil
 }
 
-// CancelRestore cancels an active restore operation
+// CancelRestore cancels an active restore operation and waits for it to
+// stop. Resources applied so far stay applied and are reported in its
+// results; the others are left pending.
 func (re *RestoreEngine) CancelRestore(restoreID string) error {
-	re.mu.Lock()
-	defer re.mu.Unlock()
-
+	re.mu.RLock()
 	operation, exists := re.activeRestores[restoreID]
+	re.mu.RUnlock()
 	if !exists {
 		return fmt.Errorf("restore operation %s not found", restoreID)
 	}
 
 	operation.cancel()
-	operation.Status = RestoreStatusCancelled
-	
-	return nil
+	select {
+	case <-operation.completionChan:
+		return nil
+	case <-time.After(cancelStopTimeout):
+		return fmt.Errorf("restore operation %s did not stop within %v", restoreID, cancelStopTimeout)
+	}
 }
 
 // ListActiveRestores returns all currently active restore operations
@@ -0,0 +1,242 @@
+package restore
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Secret types and annotations Kubernetes uses for service account
+// credentials
+const (
+	secretTypeServiceAccountToken = "kubernetes.io/service-account-token"
+	secretTypeDockerConfigJSON    = "kubernetes.io/dockerconfigjson"
+	secretTypeDockerConfig        = "kubernetes.io/dockercfg"
+	serviceAccountNameAnnotation  = "kubernetes.io/service-account.name"
+	serviceAccountUIDAnnotation   = "kubernetes.io/service-account.uid"
+)
+
+// anyImagePullSecret is the ImagePullSecrets key matching every image pull
+// Secret without an entry of its own
+const anyImagePullSecret = "*"
+
+var (
+	serviceAccountGVR = schema.GroupVersionResource{Version: "v1", Resource: "serviceaccounts"}
+	secretGVR         = schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+
+	// generatedSecretSuffix matches the random suffix the token controllers
+	// give the Secrets they create, e.g. default-token-x7k2p
+	generatedSecretSuffix = regexp.MustCompile(`^[a-z0-9]{5}$`)
+)
+
+// SecretRestoreConfig controls how Secrets holding credentials of the source
+// cluster are restored. Token Secrets generated for service accounts are
+// never restored, whatever the configuration.
+type SecretRestoreConfig struct {
+	// ImagePullSecrets maps image pull Secret names to a Secret in the target
+	// cluster, as namespace/name, whose type and data replace the backed-up
+	// credentials. The name "*" matches every image pull Secret without an
+	// entry of its own.
+	ImagePullSecrets map[string]string `json:"image_pull_secrets,omitempty"`
+}
+
+// pullSecretTemplate returns the template replacing an image pull Secret, if
+// any
+func (c *SecretRestoreConfig) pullSecretTemplate(name string) string {
+	if c == nil {
+		return ""
+	}
+	if template, ok := c.ImagePullSecrets[name]; ok {
+		return template
+	}
+	return c.ImagePullSecrets[anyImagePullSecret]
+}
+
+// applySecretPolicy returns the resources to apply with service account
+// credentials made fit for the target cluster:
+//   - token and image pull Secrets the token controllers generated are
+//     skipped, as the target generates its own
+//   - other token Secrets lose their token, so the target issues a fresh one,
+//     and are skipped when their service account is neither restored nor
+//     present in the target, which would leave them orphaned
+//   - image pull Secrets with a template get the template's credentials
+//   - service accounts stop referencing the Secrets that were skipped
+func (re *RestoreEngine) applySecretPolicy(operation *RestoreOperation, resources []BackupResource) ([]BackupResource, []SkippedResource, error) {
+	config := operation.Request.Secrets
+
+	restoredAccounts := make(map[string]bool)
+	for _, resource := range resources {
+		if resource.Kind == "ServiceAccount" && resource.APIVersion == "v1" {
+			restoredAccounts[resource.Namespace+"/"+resource.Name] = true
+		}
+	}
+
+	var kept []BackupResource
+	var skipped []SkippedResource
+	dropped := make(map[string]bool)
+	skip := func(resource BackupResource, reason string) {
+		skipped = append(skipped, SkippedResource{
+			APIVersion: resource.APIVersion,
+			Kind:       resource.Kind,
+			Namespace:  resource.Namespace,
+			Name:       resource.Name,
+			Reason:     reason,
+			Timestamp:  time.Now(),
+		})
+		dropped[resource.Namespace+"/"+resource.Name] = true
+	}
+
+	for _, resource := range resources {
+		if resource.Kind != "Secret" || resource.APIVersion != "v1" {
+			kept = append(kept, resource)
+			continue
+		}
+
+		secretType, _, _ := unstructured.NestedString(resource.Data, "type")
+		account, _, _ := unstructured.NestedString(resource.Data, "metadata", "annotations", serviceAccountNameAnnotation)
+		switch secretType {
+		case secretTypeServiceAccountToken:
+			if generatedFor(resource.Name, account, "token") {
+				skip(resource, fmt.Sprintf("token generated for service account %s; the target cluster issues its own", account))
+				continue
+			}
+			if !restoredAccounts[resource.Namespace+"/"+account] && !re.serviceAccountExists(operation, resource.Namespace, account) {
+				skip(resource, fmt.Sprintf("token of service account %s, which is neither restored nor present in the target cluster", account))
+				continue
+			}
+			kept = append(kept, withoutToken(resource))
+
+		case secretTypeDockerConfigJSON, secretTypeDockerConfig:
+			if template := config.pullSecretTemplate(resource.Name); template != "" {
+				regenerated, err := re.regeneratePullSecret(operation, resource, template)
+				if err != nil {
+					return nil, nil, err
+				}
+				kept = append(kept, regenerated)
+				continue
+			}
+			if account != "" && generatedFor(resource.Name, account, "dockercfg") {
+				skip(resource, fmt.Sprintf("image pull secret generated for service account %s; the target cluster generates its own", account))
+				continue
+			}
+			kept = append(kept, resource)
+
+		default:
+			kept = append(kept, resource)
+		}
+	}
+
+	for i, resource := range kept {
+		if resource.Kind == "ServiceAccount" && resource.APIVersion == "v1" {
+			kept[i] = withoutSecretReferences(resource, dropped)
+		}
+	}
+	return kept, skipped, nil
+}
+
+// generatedFor reports whether a Secret is named the way the token
+// controllers name the Secrets of a service account, <account>-<kind>-xxxxx
+func generatedFor(name, account, kind string) bool {
+	if account == "" {
+		return false
+	}
+	suffix, ok := strings.CutPrefix(name, account+"-"+kind+"-")
+	return ok && generatedSecretSuffix.MatchString(suffix)
+}
+
+// serviceAccountExists reports whether the target cluster has a service
+// account. Lookup errors other than not found count as present, leaving the
+// token controller to clean up a token that turns out orphaned.
+func (re *RestoreEngine) serviceAccountExists(operation *RestoreOperation, namespace, name string) bool {
+	if name == "" {
+		return false
+	}
+	_, err := re.dynamicClient.Resource(serviceAccountGVR).Namespace(namespace).Get(operation.ctx, name, metav1.GetOptions{})
+	return err == nil || !apierrors.IsNotFound(err)
+}
+
+// withoutToken returns a token Secret without the token and the UID of the
+// source service account, so the target's token controller fills it in
+// rather than deleting it as stale
+func withoutToken(resource BackupResource) BackupResource {
+	data := shallowCopy(resource.Data)
+	delete(data, "data")
+	delete(data, "stringData")
+	if metadata, ok := data["metadata"].(map[string]interface{}); ok {
+		metadata = shallowCopy(metadata)
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			annotations = shallowCopy(annotations)
+			delete(annotations, serviceAccountUIDAnnotation)
+			metadata["annotations"] = annotations
+		}
+		data["metadata"] = metadata
+	}
+	resource.Data = data
+	return resource
+}
+
+// regeneratePullSecret returns an image pull Secret with the type and data
+// of the template Secret in the target cluster
+func (re *RestoreEngine) regeneratePullSecret(operation *RestoreOperation, resource BackupResource, template string) (BackupResource, error) {
+	namespace, name, ok := strings.Cut(template, "/")
+	if !ok || namespace == "" || name == "" {
+		return resource, fmt.Errorf("image pull secret template %q for %s/%s is not namespace/name", template, resource.Namespace, resource.Name)
+	}
+	source, err := re.dynamicClient.Resource(secretGVR).Namespace(namespace).Get(operation.ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return resource, fmt.Errorf("failed to read image pull secret template %s for %s/%s: %v", template, resource.Namespace, resource.Name, err)
+	}
+
+	data := shallowCopy(resource.Data)
+	delete(data, "stringData")
+	data["data"] = source.Object["data"]
+	if secretType, ok := source.Object["type"]; ok {
+		data["type"] = secretType
+	}
+	resource.Data = data
+	return resource, nil
+}
+
+// withoutSecretReferences returns a service account no longer listing the
+// Secrets of its namespace in dropped
+func withoutSecretReferences(resource BackupResource, dropped map[string]bool) BackupResource {
+	data := shallowCopy(resource.Data)
+	for _, field := range []string{"secrets", "imagePullSecrets"} {
+		references, ok := data[field].([]interface{})
+		if !ok {
+			continue
+		}
+		var remaining []interface{}
+		for _, reference := range references {
+			if ref, ok := reference.(map[string]interface{}); ok {
+				if name, _ := ref["name"].(string); dropped[resource.Namespace+"/"+name] {
+					continue
+				}
+			}
+			remaining = append(remaining, reference)
+		}
+		if len(remaining) == 0 {
+			delete(data, field)
+		} else {
+			data[field] = remaining
+		}
+	}
+	resource.Data = data
+	return resource
+}
+
+// shallowCopy copies the top level of a map so fields can be replaced
+// without changing the backup data
+func shallowCopy(m map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		copied[key] = value
+	}
+	return copied
+}
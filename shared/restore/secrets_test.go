@@ -0,0 +1,116 @@
+package restore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func tokenSecret(namespace, name, account string) BackupResource {
+	return backupResource("v1", "Secret", namespace, name, map[string]interface{}{
+		"type": secretTypeServiceAccountToken,
+		"metadata": map[string]interface{}{"annotations": map[string]interface{}{
+			serviceAccountNameAnnotation: account,
+			serviceAccountUIDAnnotation:  "5f1c1f0e-source",
+		}},
+		"data": map[string]interface{}{"token": "c291cmNl"},
+	})
+}
+
+func pullSecret(namespace, name, account string) BackupResource {
+	metadata := map[string]interface{}{}
+	if account != "" {
+		metadata["annotations"] = map[string]interface{}{serviceAccountNameAnnotation: account}
+	}
+	return backupResource("v1", "Secret", namespace, name, map[string]interface{}{
+		"type":     secretTypeDockerConfigJSON,
+		"metadata": metadata,
+		"data":     map[string]interface{}{".dockerconfigjson": "c291cmNl"},
+	})
+}
+
+func skippedNames(skipped []SkippedResource) []string {
+	names := make([]string, 0, len(skipped))
+	for _, resource := range skipped {
+		names = append(names, resource.Name)
+	}
+	return names
+}
+
+func TestApplySecretPolicy_Tokens(t *testing.T) {
+	engine := newPlanTestEngine(liveObject("v1", "ServiceAccount", "shop", "deployer", map[string]interface{}{}))
+	operation := checkpointTestOperation(context.Background(), RestoreRequest{})
+
+	resources := []BackupResource{
+		backupResource("v1", "ServiceAccount", "shop", "default", map[string]interface{}{
+			"secrets":          []interface{}{map[string]interface{}{"name": "default-token-x7k2p"}},
+			"imagePullSecrets": []interface{}{map[string]interface{}{"name": "default-dockercfg-b4n9q"}},
+		}),
+		tokenSecret("shop", "default-token-x7k2p", "default"),
+		pullSecret("shop", "default-dockercfg-b4n9q", "default"),
+		tokenSecret("shop", "ci-token", "default"),
+		tokenSecret("shop", "deployer-token", "deployer"),
+		tokenSecret("shop", "legacy-token", "removed"),
+		pullSecret("shop", "regcred", ""),
+	}
+	original := tokenSecret("shop", "ci-token", "default").Data
+
+	kept, skipped, err := engine.applySecretPolicy(operation, resources)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"default-token-x7k2p", "default-dockercfg-b4n9q", "legacy-token"}, skippedNames(skipped))
+	require.Len(t, kept, 4)
+
+	// The service account no longer references the skipped Secrets
+	assert.Equal(t, "ServiceAccount", kept[0].Kind)
+	assert.NotContains(t, kept[0].Data, "secrets")
+	assert.NotContains(t, kept[0].Data, "imagePullSecrets")
+
+	// Tokens of restored or existing accounts are restored without the stale token
+	for _, token := range kept[1:3] {
+		assert.NotContains(t, token.Data, "data", token.Name)
+		annotations, _, _ := unstructured.NestedMap(token.Data, "metadata", "annotations")
+		assert.NotContains(t, annotations, serviceAccountUIDAnnotation)
+		assert.Contains(t, annotations, serviceAccountNameAnnotation)
+	}
+	assert.Equal(t, original, resources[3].Data, "the backup data is left unchanged")
+
+	assert.Equal(t, "regcred", kept[3].Name)
+	assert.Contains(t, kept[3].Data, "data")
+}
+
+func TestApplySecretPolicy_PullSecretTemplates(t *testing.T) {
+	template := liveObject("v1", "Secret", "backup-system", "registry-template", map[string]interface{}{
+		"type": secretTypeDockerConfigJSON,
+		"data": map[string]interface{}{".dockerconfigjson": "dGFyZ2V0"},
+	})
+	engine := newPlanTestEngine(template)
+
+	operation := checkpointTestOperation(context.Background(), RestoreRequest{Secrets: &SecretRestoreConfig{
+		ImagePullSecrets: map[string]string{"*": "backup-system/registry-template"},
+	}})
+	kept, skipped, err := engine.applySecretPolicy(operation, []BackupResource{
+		pullSecret("shop", "regcred", ""),
+		pullSecret("shop", "default-dockercfg-b4n9q", "default"),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, skipped, "a template regenerates generated pull secrets too")
+	require.Len(t, kept, 2)
+	for _, secret := range kept {
+		assert.Equal(t, map[string]interface{}{".dockerconfigjson": "dGFyZ2V0"}, secret.Data["data"], secret.Name)
+	}
+
+	operation.Request.Secrets.ImagePullSecrets = map[string]string{"regcred": "backup-system/missing"}
+	_, _, err = engine.applySecretPolicy(operation, []BackupResource{pullSecret("shop", "regcred", "")})
+	assert.Error(t, err, "a missing template fails the restore")
+}
+
+func TestGeneratedFor(t *testing.T) {
+	assert.True(t, generatedFor("default-token-x7k2p", "default", "token"))
+	assert.True(t, generatedFor("builder-dockercfg-b4n9q", "builder", "dockercfg"))
+	assert.False(t, generatedFor("default-token", "default", "token"))
+	assert.False(t, generatedFor("default-token-ci-x7k2p", "default", "token"))
+	assert.False(t, generatedFor("default-token-x7k2p", "", "token"))
+}
@@ -0,0 +1,45 @@
+package restore
+
+import (
+	"encoding/json"
+	"fmt"
+
+	sharedconfig "shared-config/config"
+	"shared-config/signing"
+)
+
+// newManifestVerifier loads the trust anchors of security.signing.verify,
+// returning nil when none is set and signatures are not checked
+func newManifestVerifier(config sharedconfig.SigningVerifyConfig) (*signing.Verifier, error) {
+	verifierConfig := signing.VerifierConfig{
+		PublicKeyFile: config.Key,
+		RootsFile:     config.FulcioRoots,
+		Identity:      config.Identity,
+		Issuer:        config.Issuer,
+		RekorKeyFile:  config.RekorPublicKey,
+	}
+	if !verifierConfig.Enabled() {
+		return nil, nil
+	}
+	verifier, err := signing.LoadVerifier(verifierConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load manifest verifier: %v", err)
+	}
+	return verifier, nil
+}
+
+// verifyManifest checks the signature document stored next to the manifest at
+// key, nil when there is none, which fails verification
+func verifyManifest(verifier *signing.Verifier, key string, manifest, document []byte) error {
+	var signature *signing.Signature
+	if document != nil {
+		signature = &signing.Signature{}
+		if err := json.Unmarshal(document, signature); err != nil {
+			return fmt.Errorf("manifest %s: %w: %v", key, signing.ErrInvalidSignature, err)
+		}
+	}
+	if err := verifier.Verify(manifest, signature); err != nil {
+		return fmt.Errorf("manifest %s: %w", key, err)
+	}
+	return nil
+}
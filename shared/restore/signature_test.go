@@ -0,0 +1,98 @@
+package restore
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sharedconfig "shared-config/config"
+	"shared-config/signing"
+)
+
+// signManifest returns the signature document of manifest made with key
+func signManifest(t *testing.T, key *ecdsa.PrivateKey, manifest []byte) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	signer, err := signing.NewKeySigner(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	require.NoError(t, err)
+	signature, err := signer.Sign(context.Background(), manifest)
+	require.NoError(t, err)
+	document, err := json.Marshal(signature)
+	require.NoError(t, err)
+	return document
+}
+
+func TestLoadBucket_Signature(t *testing.T) {
+	ctx := context.Background()
+	trusted, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	untrusted, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	verifier := &signing.Verifier{PublicKeys: []crypto.PublicKey{trusted.Public()}}
+	key := "cluster.local/prod/_manifests/backup-1.json"
+
+	store := bucketStore(t)
+	_, err := LoadBucket(ctx, store, "backups", "cluster.local", "prod", "backup-1", verifier)
+	assert.True(t, errors.Is(err, signing.ErrInvalidSignature), "an unsigned manifest is rejected")
+
+	store.put("backups", key+signing.SignatureSuffix, signManifest(t, untrusted, store["backups"][key].data))
+	_, err = LoadBucket(ctx, store, "backups", "cluster.local", "prod", "backup-1", verifier)
+	assert.True(t, errors.Is(err, signing.ErrInvalidSignature), "a manifest signed by an untrusted key is rejected")
+
+	store.put("backups", key+signing.SignatureSuffix, signManifest(t, trusted, store["backups"][key].data))
+	content, err := LoadBucket(ctx, store, "backups", "cluster.local", "prod", "backup-1", verifier)
+	require.NoError(t, err)
+	assert.Len(t, content.Resources, 2)
+
+	// A manifest changed after signing, with its checksum metadata updated
+	tampered := append([]byte{}, store["backups"][key].data...)
+	tampered = append(tampered, ' ')
+	store.put("backups", key, tampered)
+	_, err = LoadBucket(ctx, store, "backups", "cluster.local", "prod", "backup-1", verifier)
+	assert.True(t, errors.Is(err, signing.ErrInvalidSignature), "a tampered manifest is rejected")
+}
+
+func TestLoadExport_Signature(t *testing.T) {
+	trusted, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	verifier := &signing.Verifier{PublicKeys: []crypto.PublicKey{trusted.Public()}}
+	key := "cluster.local/prod/_manifests/backup-1.json"
+	files := exportFiles(t, "")
+
+	_, err := LoadExport(writeExportDir(t, files), "backup-1", verifier)
+	assert.True(t, errors.Is(err, signing.ErrInvalidSignature), "an unsigned manifest is rejected")
+
+	files[key+signing.SignatureSuffix] = signManifest(t, trusted, files[key])
+	content, err := LoadExport(writeExportArchive(t, files), "backup-1", verifier)
+	require.NoError(t, err)
+	assert.Len(t, content.Resources, 1)
+}
+
+func TestNewManifestVerifier(t *testing.T) {
+	verifier, err := newManifestVerifier(sharedconfig.SigningVerifyConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, verifier, "signatures are not checked without trust anchors")
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "cosign.pub")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o644))
+
+	verifier, err = newManifestVerifier(sharedconfig.SigningVerifyConfig{Key: path})
+	require.NoError(t, err)
+	require.NotNil(t, verifier)
+	assert.Len(t, verifier.PublicKeys, 1)
+
+	_, err = newManifestVerifier(sharedconfig.SigningVerifyConfig{Key: filepath.Join(t.TempDir(), "missing.pub")})
+	assert.Error(t, err)
+}
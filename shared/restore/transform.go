@@ -0,0 +1,350 @@
+package restore
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// TransformConfig maps source-environment values to their target-environment
+// equivalents. Keys are the values found in the backup, values are what the
+// restored resource should use instead.
+type TransformConfig struct {
+	StorageClassMappings map[string]string `json:"storage_class_mappings,omitempty" yaml:"storage_class_mappings,omitempty"`
+	RegistryMappings     map[string]string `json:"registry_mappings,omitempty" yaml:"registry_mappings,omitempty"`
+	IngressHostMappings  map[string]string `json:"ingress_host_mappings,omitempty" yaml:"ingress_host_mappings,omitempty"`
+	// NamespaceMappings restores the resources of a namespace into another
+	NamespaceMappings map[string]string `json:"namespace_mappings,omitempty" yaml:"namespace_mappings,omitempty"`
+	// Restore OpenShift DeploymentConfigs as Deployments
+	ConvertDeploymentConfigs bool `json:"convert_deployment_configs,omitempty" yaml:"convert_deployment_configs,omitempty"`
+	// Restore an OpenShift backup into a cluster without the OpenShift APIs:
+	// Routes become Ingresses, DeploymentConfigs Deployments and ImageStream
+	// references image digests. Implies ConvertDeploymentConfigs.
+	ConvertOpenShift bool `json:"convert_openshift,omitempty" yaml:"convert_openshift,omitempty"`
+	// IngressClassName is set on the Ingresses converted from Routes
+	IngressClassName string `json:"ingress_class_name,omitempty" yaml:"ingress_class_name,omitempty"`
+}
+
+// IsEmpty reports whether the config defines no mappings
+func (tc *TransformConfig) IsEmpty() bool {
+	return tc == nil || (len(tc.StorageClassMappings) == 0 && len(tc.RegistryMappings) == 0 && len(tc.IngressHostMappings) == 0 && len(tc.NamespaceMappings) == 0 && !tc.ConvertDeploymentConfigs && !tc.ConvertOpenShift)
+}
+
+// MapNamespace returns the namespace the resources of namespace are restored into
+func (tc *TransformConfig) MapNamespace(namespace string) string {
+	if tc != nil {
+		if to, ok := tc.NamespaceMappings[namespace]; ok {
+			return to
+		}
+	}
+	return namespace
+}
+
+// ResourceTransformer rewrites a resource before it is applied to the target cluster
+type ResourceTransformer interface {
+	// Name identifies the transformer in change records
+	Name() string
+	// Transform modifies obj in place and returns the changes it made
+	Transform(obj *unstructured.Unstructured) ([]FieldChange, error)
+}
+
+// TransformEngine runs a chain of transformers over restored resources
+type TransformEngine struct {
+	transformers []ResourceTransformer
+	// openShift is set when converting OpenShift resources, which needs the
+	// whole restore indexed first
+	openShift *openShiftConverter
+}
+
+// NewTransformEngine creates an engine with the built-in rewriters enabled by config
+func NewTransformEngine(config TransformConfig) *TransformEngine {
+	engine := &TransformEngine{}
+
+	// Converted first so the rewriters below see the Deployment or Ingress
+	if config.ConvertOpenShift {
+		engine.openShift = newOpenShiftConverter(config.IngressClassName)
+		engine.Register(engine.openShift)
+	} else if config.ConvertDeploymentConfigs {
+		engine.Register(&deploymentConfigConverter{})
+	}
+	if len(config.StorageClassMappings) > 0 {
+		engine.Register(&storageClassRewriter{mappings: config.StorageClassMappings})
+	}
+	if len(config.RegistryMappings) > 0 {
+		engine.Register(&imageRegistryRewriter{mappings: config.RegistryMappings})
+	}
+	if len(config.IngressHostMappings) > 0 {
+		engine.Register(&ingressHostRewriter{mappings: config.IngressHostMappings})
+	}
+	if len(config.NamespaceMappings) > 0 {
+		engine.Register(&namespaceRewriter{mappings: config.NamespaceMappings})
+	}
+
+	return engine
+}
+
+// Register appends a transformer to the chain
+func (te *TransformEngine) Register(transformer ResourceTransformer) {
+	te.transformers = append(te.transformers, transformer)
+}
+
+// Apply runs every transformer over obj in registration order
+func (te *TransformEngine) Apply(obj *unstructured.Unstructured) ([]FieldChange, error) {
+	var changes []FieldChange
+	for _, transformer := range te.transformers {
+		transformerChanges, err := transformer.Transform(obj)
+		if err != nil {
+			return changes, fmt.Errorf("%s transform failed for %s/%s: %v", transformer.Name(), obj.GetKind(), obj.GetName(), err)
+		}
+		changes = append(changes, transformerChanges...)
+	}
+	return changes, nil
+}
+
+// storageClassRewriter maps storageClassName on PVCs, PVs and StatefulSet volume claim templates
+type storageClassRewriter struct {
+	mappings map[string]string
+}
+
+func (r *storageClassRewriter) Name() string { return "storage-class" }
+
+func (r *storageClassRewriter) Transform(obj *unstructured.Unstructured) ([]FieldChange, error) {
+	switch obj.GetKind() {
+	case "PersistentVolumeClaim", "PersistentVolume":
+		var changes []FieldChange
+		if change, ok := rewriteString(obj.Object, r.mappings, "spec", "storageClassName"); ok {
+			changes = append(changes, change)
+		}
+		return changes, nil
+	case "StatefulSet":
+		return rewriteEach(obj.Object, []string{"spec", "volumeClaimTemplates"}, func(template map[string]interface{}, path string) []FieldChange {
+			if change, ok := rewriteString(template, r.mappings, "spec", "storageClassName"); ok {
+				change.Field = path + "." + change.Field
+				return []FieldChange{change}
+			}
+			return nil
+		})
+	}
+	return nil, nil
+}
+
+// imageRegistryRewriter maps the registry prefix of container images in pods and pod templates
+type imageRegistryRewriter struct {
+	mappings map[string]string
+}
+
+func (r *imageRegistryRewriter) Name() string { return "image-registry" }
+
+func (r *imageRegistryRewriter) Transform(obj *unstructured.Unstructured) ([]FieldChange, error) {
+	podSpecPath, ok := podSpecPaths[obj.GetKind()]
+	if !ok {
+		return nil, nil
+	}
+
+	var changes []FieldChange
+	for _, containerField := range []string{"initContainers", "containers", "ephemeralContainers"} {
+		containerChanges, err := rewriteEach(obj.Object, append(podSpecPath, containerField), func(container map[string]interface{}, path string) []FieldChange {
+			image, _, _ := unstructured.NestedString(container, "image")
+			rewritten, ok := r.rewrite(image)
+			if !ok {
+				return nil
+			}
+			container["image"] = rewritten
+			return []FieldChange{{Field: path + ".image", OldValue: image, NewValue: rewritten, Action: "modified"}}
+		})
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, containerChanges...)
+	}
+	return changes, nil
+}
+
+// rewrite replaces the longest matching registry prefix. A prefix only matches
+// on a path boundary so "registry.io" does not rewrite "registry.io.mirror/app".
+func (r *imageRegistryRewriter) rewrite(image string) (string, bool) {
+	best := ""
+	for from := range r.mappings {
+		if strings.HasPrefix(image, strings.TrimSuffix(from, "/")+"/") && len(from) > len(best) {
+			best = from
+		}
+	}
+	if best == "" {
+		return image, false
+	}
+	return strings.TrimSuffix(r.mappings[best], "/") + strings.TrimPrefix(image, strings.TrimSuffix(best, "/")), true
+}
+
+// ingressHostRewriter maps hostnames on Ingress rules and TLS entries and on OpenShift Routes.
+// A mapping applies to the exact host and to any subdomain of it.
+type ingressHostRewriter struct {
+	mappings map[string]string
+}
+
+func (r *ingressHostRewriter) Name() string { return "ingress-host" }
+
+func (r *ingressHostRewriter) Transform(obj *unstructured.Unstructured) ([]FieldChange, error) {
+	switch obj.GetKind() {
+	case "Ingress":
+		changes, err := rewriteEach(obj.Object, []string{"spec", "rules"}, func(rule map[string]interface{}, path string) []FieldChange {
+			host, _, _ := unstructured.NestedString(rule, "host")
+			rewritten, ok := r.rewrite(host)
+			if !ok {
+				return nil
+			}
+			rule["host"] = rewritten
+			return []FieldChange{{Field: path + ".host", OldValue: host, NewValue: rewritten, Action: "modified"}}
+		})
+		if err != nil {
+			return changes, err
+		}
+
+		tlsChanges, err := rewriteEach(obj.Object, []string{"spec", "tls"}, func(tls map[string]interface{}, path string) []FieldChange {
+			hosts, found, _ := unstructured.NestedStringSlice(tls, "hosts")
+			if !found {
+				return nil
+			}
+			var hostChanges []FieldChange
+			for i, host := range hosts {
+				if rewritten, ok := r.rewrite(host); ok {
+					hosts[i] = rewritten
+					hostChanges = append(hostChanges, FieldChange{Field: fmt.Sprintf("%s.hosts[%d]", path, i), OldValue: host, NewValue: rewritten, Action: "modified"})
+				}
+			}
+			if len(hostChanges) > 0 {
+				unstructured.SetNestedStringSlice(tls, hosts, "hosts")
+			}
+			return hostChanges
+		})
+		return append(changes, tlsChanges...), err
+	case "Route":
+		host, _, _ := unstructured.NestedString(obj.Object, "spec", "host")
+		rewritten, ok := r.rewrite(host)
+		if !ok {
+			return nil, nil
+		}
+		if err := unstructured.SetNestedField(obj.Object, rewritten, "spec", "host"); err != nil {
+			return nil, err
+		}
+		return []FieldChange{{Field: "spec.host", OldValue: host, NewValue: rewritten, Action: "modified"}}, nil
+	}
+	return nil, nil
+}
+
+func (r *ingressHostRewriter) rewrite(host string) (string, bool) {
+	if host == "" {
+		return host, false
+	}
+	if to, ok := r.mappings[host]; ok {
+		return to, true
+	}
+
+	best := ""
+	for from := range r.mappings {
+		if strings.HasSuffix(host, "."+from) && len(from) > len(best) {
+			best = from
+		}
+	}
+	if best == "" {
+		return host, false
+	}
+	return strings.TrimSuffix(host, best) + r.mappings[best], true
+}
+
+// namespaceRewriter moves resources into the namespaces their own is mapped
+// to: the Namespace itself is renamed, namespaced resources are moved, and
+// the service account subjects of role bindings follow them
+type namespaceRewriter struct {
+	mappings map[string]string
+}
+
+func (r *namespaceRewriter) Name() string { return "namespace" }
+
+func (r *namespaceRewriter) Transform(obj *unstructured.Unstructured) ([]FieldChange, error) {
+	var changes []FieldChange
+	if obj.GetKind() == "Namespace" && obj.GetAPIVersion() == "v1" {
+		if to, ok := r.mappings[obj.GetName()]; ok && to != obj.GetName() {
+			changes = append(changes, FieldChange{Field: "metadata.name", OldValue: obj.GetName(), NewValue: to, Action: "modified"})
+			obj.SetName(to)
+		}
+		return changes, nil
+	}
+
+	if to, ok := r.mappings[obj.GetNamespace()]; ok && to != obj.GetNamespace() {
+		changes = append(changes, FieldChange{Field: "metadata.namespace", OldValue: obj.GetNamespace(), NewValue: to, Action: "modified"})
+		obj.SetNamespace(to)
+	}
+
+	if obj.GetKind() == "RoleBinding" || obj.GetKind() == "ClusterRoleBinding" {
+		subjectChanges, err := rewriteEach(obj.Object, []string{"subjects"}, func(subject map[string]interface{}, path string) []FieldChange {
+			if subject["kind"] != "ServiceAccount" {
+				return nil
+			}
+			if change, ok := rewriteString(subject, r.mappings, "namespace"); ok {
+				change.Field = path + "." + change.Field
+				return []FieldChange{change}
+			}
+			return nil
+		})
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, subjectChanges...)
+	}
+	return changes, nil
+}
+
+// podSpecPaths locates the pod spec of each workload kind
+var podSpecPaths = map[string][]string{
+	"Pod":                   {"spec"},
+	"Deployment":            {"spec", "template", "spec"},
+	"StatefulSet":           {"spec", "template", "spec"},
+	"DaemonSet":             {"spec", "template", "spec"},
+	"ReplicaSet":            {"spec", "template", "spec"},
+	"ReplicationController": {"spec", "template", "spec"},
+	"Job":                   {"spec", "template", "spec"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template", "spec"},
+	"DeploymentConfig":      {"spec", "template", "spec"},
+}
+
+// rewriteString maps the string at fields through mappings
+func rewriteString(obj map[string]interface{}, mappings map[string]string, fields ...string) (FieldChange, bool) {
+	value, found, err := unstructured.NestedString(obj, fields...)
+	if err != nil || !found {
+		return FieldChange{}, false
+	}
+	to, ok := mappings[value]
+	if !ok || to == value {
+		return FieldChange{}, false
+	}
+	if err := unstructured.SetNestedField(obj, to, fields...); err != nil {
+		return FieldChange{}, false
+	}
+	return FieldChange{Field: strings.Join(fields, "."), OldValue: value, NewValue: to, Action: "modified"}, true
+}
+
+// rewriteEach calls fn for every map in the slice at fields and writes the
+// slice back so in-place edits are kept
+func rewriteEach(obj map[string]interface{}, fields []string, fn func(item map[string]interface{}, path string) []FieldChange) ([]FieldChange, error) {
+	items, found, err := unstructured.NestedSlice(obj, fields...)
+	if err != nil || !found {
+		return nil, nil
+	}
+
+	var changes []FieldChange
+	for i, item := range items {
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		changes = append(changes, fn(itemMap, fmt.Sprintf("%s[%d]", strings.Join(fields, "."), i))...)
+	}
+
+	if len(changes) > 0 {
+		if err := unstructured.SetNestedSlice(obj, items, fields...); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,177 @@
+package restore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newObject(kind string, fields map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: fields}
+	obj.SetKind(kind)
+	obj.SetName("test")
+	return obj
+}
+
+func TestTransformEngine_StorageClass(t *testing.T) {
+	engine := NewTransformEngine(TransformConfig{
+		StorageClassMappings: map[string]string{"gp2": "standard-rwo"},
+	})
+
+	pvc := newObject("PersistentVolumeClaim", map[string]interface{}{
+		"spec": map[string]interface{}{"storageClassName": "gp2"},
+	})
+	changes, err := engine.Apply(pvc)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "spec.storageClassName", changes[0].Field)
+	value, _, _ := unstructured.NestedString(pvc.Object, "spec", "storageClassName")
+	assert.Equal(t, "standard-rwo", value)
+
+	statefulSet := newObject("StatefulSet", map[string]interface{}{
+		"spec": map[string]interface{}{
+			"volumeClaimTemplates": []interface{}{
+				map[string]interface{}{"spec": map[string]interface{}{"storageClassName": "gp2"}},
+				map[string]interface{}{"spec": map[string]interface{}{"storageClassName": "fast"}},
+			},
+		},
+	})
+	changes, err = engine.Apply(statefulSet)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "spec.volumeClaimTemplates[0].spec.storageClassName", changes[0].Field)
+	templates, _, _ := unstructured.NestedSlice(statefulSet.Object, "spec", "volumeClaimTemplates")
+	value, _, _ = unstructured.NestedString(templates[0].(map[string]interface{}), "spec", "storageClassName")
+	assert.Equal(t, "standard-rwo", value)
+}
+
+func TestImageRegistryRewriter(t *testing.T) {
+	rewriter := &imageRegistryRewriter{mappings: map[string]string{
+		"registry.prod.io":          "registry.dr.io",
+		"registry.prod.io/platform": "mirror.dr.io/platform-images",
+	}}
+
+	tests := []struct {
+		name     string
+		image    string
+		expected string
+		changed  bool
+	}{
+		{name: "registry_prefix", image: "registry.prod.io/team/app:1.0", expected: "registry.dr.io/team/app:1.0", changed: true},
+		{name: "longest_prefix_wins", image: "registry.prod.io/platform/proxy:2", expected: "mirror.dr.io/platform-images/proxy:2", changed: true},
+		{name: "no_partial_host_match", image: "registry.prod.io.mirror/app:1", expected: "registry.prod.io.mirror/app:1"},
+		{name: "unmapped_registry", image: "docker.io/library/nginx", expected: "docker.io/library/nginx"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, changed := rewriter.rewrite(tt.image)
+			assert.Equal(t, tt.expected, result)
+			assert.Equal(t, tt.changed, changed)
+		})
+	}
+}
+
+func TestTransformEngine_ImageRegistry(t *testing.T) {
+	engine := NewTransformEngine(TransformConfig{
+		RegistryMappings: map[string]string{"registry.prod.io": "registry.dr.io"},
+	})
+
+	cronJob := newObject("CronJob", map[string]interface{}{
+		"spec": map[string]interface{}{"jobTemplate": map[string]interface{}{"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+			"initContainers": []interface{}{map[string]interface{}{"name": "init", "image": "registry.prod.io/init:1"}},
+			"containers":     []interface{}{map[string]interface{}{"name": "job", "image": "registry.prod.io/job:1"}},
+		}}}}},
+	})
+
+	changes, err := engine.Apply(cronJob)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+
+	containers, _, _ := unstructured.NestedSlice(cronJob.Object, "spec", "jobTemplate", "spec", "template", "spec", "containers")
+	assert.Equal(t, "registry.dr.io/job:1", containers[0].(map[string]interface{})["image"])
+}
+
+func TestTransformEngine_IngressHosts(t *testing.T) {
+	engine := NewTransformEngine(TransformConfig{
+		IngressHostMappings: map[string]string{"apps.prod.example.com": "apps.dr.example.com"},
+	})
+
+	ingress := newObject("Ingress", map[string]interface{}{
+		"spec": map[string]interface{}{
+			"rules": []interface{}{
+				map[string]interface{}{"host": "shop.apps.prod.example.com"},
+				map[string]interface{}{"host": "static.example.org"},
+			},
+			"tls": []interface{}{
+				map[string]interface{}{"hosts": []interface{}{"shop.apps.prod.example.com"}},
+			},
+		},
+	})
+
+	changes, err := engine.Apply(ingress)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+
+	rules, _, _ := unstructured.NestedSlice(ingress.Object, "spec", "rules")
+	assert.Equal(t, "shop.apps.dr.example.com", rules[0].(map[string]interface{})["host"])
+	assert.Equal(t, "static.example.org", rules[1].(map[string]interface{})["host"])
+	tls, _, _ := unstructured.NestedSlice(ingress.Object, "spec", "tls")
+	assert.Equal(t, []interface{}{"shop.apps.dr.example.com"}, tls[0].(map[string]interface{})["hosts"])
+
+	route := newObject("Route", map[string]interface{}{
+		"spec": map[string]interface{}{"host": "apps.prod.example.com"},
+	})
+	changes, err = engine.Apply(route)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "apps.dr.example.com", changes[0].NewValue)
+}
+
+func TestTransformEngine_Namespaces(t *testing.T) {
+	config := TransformConfig{NamespaceMappings: map[string]string{"shop": "drill-shop"}}
+	engine := NewTransformEngine(config)
+
+	namespace := newObject("Namespace", map[string]interface{}{})
+	namespace.SetAPIVersion("v1")
+	namespace.SetName("shop")
+	changes, err := engine.Apply(namespace)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "drill-shop", namespace.GetName())
+
+	binding := newObject("RoleBinding", map[string]interface{}{
+		"subjects": []interface{}{
+			map[string]interface{}{"kind": "ServiceAccount", "name": "web", "namespace": "shop"},
+			map[string]interface{}{"kind": "ServiceAccount", "name": "monitor", "namespace": "monitoring"},
+			map[string]interface{}{"kind": "Group", "name": "shop"},
+		},
+	})
+	binding.SetNamespace("shop")
+	changes, err = engine.Apply(binding)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	assert.Equal(t, "drill-shop", binding.GetNamespace())
+	subjects, _, _ := unstructured.NestedSlice(binding.Object, "subjects")
+	assert.Equal(t, "drill-shop", subjects[0].(map[string]interface{})["namespace"])
+	assert.Equal(t, "monitoring", subjects[1].(map[string]interface{})["namespace"])
+
+	other := newObject("ConfigMap", map[string]interface{}{})
+	other.SetNamespace("db")
+	changes, err = engine.Apply(other)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	assert.Equal(t, "drill-shop", config.MapNamespace("shop"))
+	assert.Equal(t, "db", config.MapNamespace("db"))
+	assert.False(t, config.IsEmpty())
+}
+
+func TestTransformConfig_IsEmpty(t *testing.T) {
+	var config *TransformConfig
+	assert.True(t, config.IsEmpty())
+	assert.True(t, (&TransformConfig{}).IsEmpty())
+	assert.False(t, (&TransformConfig{RegistryMappings: map[string]string{"a": "b"}}).IsEmpty())
+}
@@ -59,6 +59,7 @@ type ValidationWarning struct {
 	Resource    string                 `json:"resource,omitempty"`
 	Namespace   string                 `json:"namespace,omitempty"`
 	Impact      string                 `json:"impact"`
+	Suggestions []string               `json:"suggestions,omitempty"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
 }
 
@@ -71,6 +72,7 @@ type ResourcesSummary struct {
 	ClusterScoped       int                    `json:"cluster_scoped"`
 	CustomResources     int                    `json:"custom_resources"`
 	EstimatedSize       int64                  `json:"estimated_size_bytes"`
+	ValidationScore     float64                `json:"validation_score"`
 }
 
 // ClusterInfo contains information about the target cluster
@@ -261,7 +263,7 @@ func (rv *RestoreValidator) detectPlatform(ctx context.Context, report *Validati
 	platform := "unknown"
 
 	// Check for OpenShift
-	_, err := rv.k8sClient.RESTClient().Get().AbsPath("/apis/config.openshift.io/v1").DoRaw(ctx)
+	_, err := rv.discoveryClient.ServerResourcesForGroupVersion("config.openshift.io/v1")
 	if err == nil {
 		platform = "openshift"
 	} else {
@@ -444,14 +446,14 @@ func (rv *RestoreValidator) addError(report *ValidationReport, errorType, messag
 	})
 }
 
-func (rv *RestoreValidator) addWarning(report *ValidationReport, warningType, message, resource, namespace, impact string, metadata map[string]interface{}) {
+func (rv *RestoreValidator) addWarning(report *ValidationReport, warningType, message, resource, namespace, impact string, suggestions []string) {
 	report.Warnings = append(report.Warnings, ValidationWarning{
-		Type:      warningType,
-		Message:   message,
-		Resource:  resource,
-		Namespace: namespace,
-		Impact:    impact,
-		Metadata:  metadata,
+		Type:        warningType,
+		Message:     message,
+		Resource:    resource,
+		Namespace:   namespace,
+		Impact:      impact,
+		Suggestions: suggestions,
 	})
 }
 
@@ -478,8 +480,7 @@ func (rv *RestoreValidator) checkPermission(ctx context.Context, resource, verb,
 
 func (rv *RestoreValidator) isAPIAvailable(ctx context.Context, groupVersion, kind string) bool {
 	// Check if API version is available in cluster
-	gv, err := schema.ParseGroupVersion(groupVersion)
-	if err != nil {
+	if _, err := schema.ParseGroupVersion(groupVersion); err != nil {
 		return false
 	}
 
@@ -144,6 +144,13 @@ type RateLimitConfig struct {
 	WhitelistIPs    []string      `yaml:"whitelist_ips"`
 }
 
+// WebhookAuthConfig configures token authentication of webhook requests
+type WebhookAuthConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	Token      string `yaml:"token"`
+	HeaderName string `yaml:"header_name"`
+}
+
 // RBACConfig configures role-based access control
 type RBACConfig struct {
 	Enabled     bool                       `yaml:"enabled"`
@@ -52,6 +52,11 @@ type ValidationError struct {
 	Sanitized string `json:"sanitized,omitempty"`
 }
 
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
 // ValidationConfig configures input validation behavior
 type ValidationConfig struct {
 	Level              ValidationLevel `yaml:"level"`
@@ -27,11 +27,11 @@ type SecretScanResult struct {
 	ScanTime    time.Time      `json:"scan_time"`
 	Duration    time.Duration  `json:"duration"`
 	LinesScanned int           `json:"lines_scanned"`
-	Summary     ScanSummary    `json:"summary"`
+	Summary     SecretScanSummary    `json:"summary"`
 }
 
-// ScanSummary provides summary statistics for a scan
-type ScanSummary struct {
+// SecretScanSummary provides summary statistics for a secret scan
+type SecretScanSummary struct {
 	TotalMatches int `json:"total_matches"`
 	HighSeverity int `json:"high_severity"`
 	MediumSeverity int `json:"medium_severity"`
@@ -79,7 +79,7 @@ func (ss *SecretScanner) ScanContent(content string) (*SecretScanResult, error)
 	result := &SecretScanResult{
 		ScanTime: startTime,
 		Matches:  []SecretMatch{},
-		Summary:  ScanSummary{},
+		Summary:  SecretScanSummary{},
 	}
 
 	lines := strings.Split(content, "\n")
@@ -364,8 +364,8 @@ func (ss *SecretScanner) redactSecret(secret string) string {
 }
 
 // calculateSummary calculates summary statistics for scan results
-func (ss *SecretScanner) calculateSummary(matches []SecretMatch) ScanSummary {
-	summary := ScanSummary{}
+func (ss *SecretScanner) calculateSummary(matches []SecretMatch) SecretScanSummary {
+	summary := SecretScanSummary{}
 	typeMap := make(map[string]bool)
 
 	for _, match := range matches {
@@ -0,0 +1,267 @@
+package signing
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultIdentityTokenFile is where cosign reads an OIDC token from, and where a
+// projected service account token with the "sigstore" audience is mounted
+const DefaultIdentityTokenFile = "/var/run/sigstore/cosign/oidc-token"
+
+// KeylessSigner signs with an ephemeral key whose certificate Fulcio issues to
+// the workload's OIDC identity, and records the signature in Rekor so it can
+// be checked after the short-lived certificate expires
+type KeylessSigner struct {
+	// FulcioURL is the Fulcio certificate authority, e.g. https://fulcio.sigstore.dev
+	FulcioURL string
+	// RekorURL is the Rekor transparency log; empty skips logging
+	RekorURL string
+	// TokenFile holds the OIDC identity token, read again for every signature
+	TokenFile  string
+	HTTPClient *http.Client
+}
+
+// RekorEntry is the transparency log entry of a keyless signature
+type RekorEntry struct {
+	UUID           string `json:"uuid"`
+	LogIndex       int64  `json:"log_index"`
+	LogID          string `json:"log_id"`
+	IntegratedTime int64  `json:"integrated_time"`
+	// Body is the base64 entry as logged
+	Body string `json:"body"`
+	// SignedEntryTimestamp is Rekor's base64 signature over the entry, which
+	// proves when it was logged
+	SignedEntryTimestamp string `json:"signed_entry_timestamp"`
+}
+
+// Sign obtains a certificate for a fresh key, signs data and logs the signature
+func (s *KeylessSigner) Sign(ctx context.Context, data []byte) (*Signature, error) {
+	token, err := s.identityToken()
+	if err != nil {
+		return nil, err
+	}
+	subject, err := tokenSubject(token)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %v", err)
+	}
+	chain, err := s.requestCertificate(ctx, key, token, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm, signature, err := sign(key, data)
+	if err != nil {
+		return nil, err
+	}
+	result := &Signature{
+		Algorithm:   algorithm,
+		Signature:   base64.StdEncoding.EncodeToString(signature),
+		Certificate: chain,
+	}
+	if s.RekorURL != "" {
+		leaf, _ := pem.Decode([]byte(chain))
+		result.Rekor, err = s.logSignature(ctx, data, result.Signature, pem.EncodeToMemory(leaf))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (s *KeylessSigner) identityToken() (string, error) {
+	tokenFile := s.TokenFile
+	if tokenFile == "" {
+		tokenFile = DefaultIdentityTokenFile
+	}
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read identity token: %v", err)
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+// tokenSubject returns the claim Fulcio expects a proof of possession over: the
+// email of email identities, the subject otherwise. The token is not verified
+// here; Fulcio does that.
+func tokenSubject(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("identity token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("failed to decode identity token: %v", err)
+	}
+	var claims struct {
+		Subject string `json:"sub"`
+		Email   string `json:"email"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", fmt.Errorf("failed to decode identity token: %v", err)
+	}
+	if claims.Email != "" {
+		return claims.Email, nil
+	}
+	if claims.Subject == "" {
+		return "", fmt.Errorf("identity token has no subject")
+	}
+	return claims.Subject, nil
+}
+
+// requestCertificate asks Fulcio for a code signing certificate for key and
+// returns the PEM chain, leaf first
+func (s *KeylessSigner) requestCertificate(ctx context.Context, key *ecdsa.PrivateKey, token, subject string) (string, error) {
+	publicKey, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return "", fmt.Errorf("failed to encode public key: %v", err)
+	}
+	_, proof, err := sign(key, []byte(subject))
+	if err != nil {
+		return "", err
+	}
+
+	request := map[string]interface{}{
+		"credentials": map[string]string{"oidcIdentityToken": token},
+		"publicKeyRequest": map[string]interface{}{
+			"publicKey": map[string]string{
+				"algorithm": "ECDSA",
+				"content":   string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})),
+			},
+			"proofOfPossession": base64.StdEncoding.EncodeToString(proof),
+		},
+	}
+	type chain struct {
+		Chain struct {
+			Certificates []string `json:"certificates"`
+		} `json:"chain"`
+	}
+	var response struct {
+		Embedded *chain `json:"signedCertificateEmbeddedSct"`
+		Detached *chain `json:"signedCertificateDetachedSct"`
+	}
+	if err := s.post(ctx, strings.TrimSuffix(s.FulcioURL, "/")+"/api/v2/signingCert", request, &response); err != nil {
+		return "", fmt.Errorf("failed to obtain signing certificate: %v", err)
+	}
+
+	issued := response.Embedded
+	if issued == nil {
+		issued = response.Detached
+	}
+	if issued == nil || len(issued.Chain.Certificates) == 0 {
+		return "", fmt.Errorf("failed to obtain signing certificate: fulcio returned no certificate")
+	}
+	var certificates strings.Builder
+	for _, certificate := range issued.Chain.Certificates {
+		certificates.WriteString(strings.TrimSpace(certificate))
+		certificates.WriteString("\n")
+	}
+	return certificates.String(), nil
+}
+
+// hashedRekord is the Rekor entry recording a signature over a SHA-256 digest
+type hashedRekord struct {
+	Kind       string `json:"kind"`
+	APIVersion string `json:"apiVersion"`
+	Spec       struct {
+		Signature struct {
+			Content   string `json:"content"`
+			PublicKey struct {
+				Content string `json:"content"`
+			} `json:"publicKey"`
+		} `json:"signature"`
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+	} `json:"spec"`
+}
+
+func newHashedRekord(data []byte, signature string, certificatePEM []byte) hashedRekord {
+	digest := sha256.Sum256(data)
+	entry := hashedRekord{Kind: "hashedrekord", APIVersion: "0.0.1"}
+	entry.Spec.Signature.Content = signature
+	entry.Spec.Signature.PublicKey.Content = base64.StdEncoding.EncodeToString(certificatePEM)
+	entry.Spec.Data.Hash.Algorithm = "sha256"
+	entry.Spec.Data.Hash.Value = hex.EncodeToString(digest[:])
+	return entry
+}
+
+// logSignature adds the signature to Rekor
+func (s *KeylessSigner) logSignature(ctx context.Context, data []byte, signature string, certificatePEM []byte) (*RekorEntry, error) {
+	var response map[string]struct {
+		Body           string `json:"body"`
+		IntegratedTime int64  `json:"integratedTime"`
+		LogID          string `json:"logID"`
+		LogIndex       int64  `json:"logIndex"`
+		Verification   struct {
+			SignedEntryTimestamp string `json:"signedEntryTimestamp"`
+		} `json:"verification"`
+	}
+	entry := newHashedRekord(data, signature, certificatePEM)
+	if err := s.post(ctx, strings.TrimSuffix(s.RekorURL, "/")+"/api/v1/log/entries", entry, &response); err != nil {
+		return nil, fmt.Errorf("failed to log signature: %v", err)
+	}
+	for uuid, logged := range response {
+		return &RekorEntry{
+			UUID:                 uuid,
+			LogIndex:             logged.LogIndex,
+			LogID:                logged.LogID,
+			IntegratedTime:       logged.IntegratedTime,
+			Body:                 logged.Body,
+			SignedEntryTimestamp: logged.Verification.SignedEntryTimestamp,
+		}, nil
+	}
+	return nil, fmt.Errorf("failed to log signature: rekor returned no entry")
+}
+
+func (s *KeylessSigner) post(ctx context.Context, url string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	client := s.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s returned %s: %s", url, resp.Status, strings.TrimSpace(string(message)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,210 @@
+package signing
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const (
+	testIdentity = "https://kubernetes.io/namespaces/backup/serviceaccounts/cluster-backup"
+	testIssuer   = "https://kubernetes.default.svc"
+	testSubject  = "system:serviceaccount:backup:cluster-backup"
+)
+
+// fakeSigstore serves the Fulcio and Rekor endpoints the keyless signer uses.
+// Certificates were issued, and entries logged, in the past, so verification
+// must not depend on the certificate still being valid.
+type fakeSigstore struct {
+	t        *testing.T
+	caKey    *ecdsa.PrivateKey
+	ca       *x509.Certificate
+	rekorKey *ecdsa.PrivateKey
+	issuedAt time.Time
+}
+
+func newFakeSigstore(t *testing.T) *fakeSigstore {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fulcio test root"},
+		NotBefore:             time.Now().Add(-24 * time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(der)
+	rekorKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	return &fakeSigstore{t: t, caKey: caKey, ca: ca, rekorKey: rekorKey, issuedAt: time.Now().Add(-20 * time.Minute)}
+}
+
+func (f *fakeSigstore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/api/v2/signingCert":
+		var request struct {
+			PublicKeyRequest struct {
+				PublicKey struct {
+					Content string `json:"content"`
+				} `json:"publicKey"`
+				ProofOfPossession string `json:"proofOfPossession"`
+			} `json:"publicKeyRequest"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		block, _ := pem.Decode([]byte(request.PublicKeyRequest.PublicKey.Content))
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		proof, _ := base64.StdEncoding.DecodeString(request.PublicKeyRequest.ProofOfPossession)
+		digest := sha256.Sum256([]byte(testSubject))
+		if !ecdsa.VerifyASN1(publicKey.(*ecdsa.PublicKey), digest[:], proof) {
+			http.Error(w, "invalid proof of possession", http.StatusBadRequest)
+			return
+		}
+
+		issuer, _ := asn1.Marshal(testIssuer)
+		identity, _ := url.Parse(testIdentity)
+		template := &x509.Certificate{
+			SerialNumber:    big.NewInt(2),
+			NotBefore:       f.issuedAt,
+			NotAfter:        f.issuedAt.Add(10 * time.Minute),
+			KeyUsage:        x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+			URIs:            []*url.URL{identity},
+			ExtraExtensions: []pkix.Extension{{Id: oidIssuerV2, Value: issuer}},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, f.ca, publicKey, f.caKey)
+		if err != nil {
+			f.t.Error(err)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"signedCertificateEmbeddedSct": map[string]interface{}{
+				"chain": map[string]interface{}{"certificates": []string{
+					string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+					string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: f.ca.Raw})),
+				}},
+			},
+		})
+
+	case "/api/v1/log/entries":
+		body := readBody(r)
+		entry := map[string]interface{}{
+			"body":           base64.StdEncoding.EncodeToString(body),
+			"integratedTime": f.issuedAt.Add(time.Minute).Unix(),
+			"logID":          "c0d23d6ad406973f9559f3ba2d1ca01f84147d8ffc5b8445c224f98b9591801d",
+			"logIndex":       42,
+		}
+		payload, _ := json.Marshal(entry)
+		digest := sha256.Sum256(payload)
+		set, _ := ecdsa.SignASN1(rand.Reader, f.rekorKey, digest[:])
+		entry["verification"] = map[string]string{"signedEntryTimestamp": base64.StdEncoding.EncodeToString(set)}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"24296fb24b8ad77a": entry})
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func readBody(r *http.Request) []byte {
+	body, _ := io.ReadAll(r.Body)
+	return body
+}
+
+func writeIdentityToken(t *testing.T) string {
+	claims, _ := json.Marshal(map[string]string{"iss": testIssuer, "sub": testSubject})
+	token := "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString(claims) + ".c2lnbmF0dXJl"
+	path := filepath.Join(t.TempDir(), "oidc-token")
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestKeylessSigner_SignAndVerify(t *testing.T) {
+	sigstore := newFakeSigstore(t)
+	server := httptest.NewServer(sigstore)
+	defer server.Close()
+
+	signer := &KeylessSigner{FulcioURL: server.URL, RekorURL: server.URL, TokenFile: writeIdentityToken(t)}
+	manifest := []byte(`{"backup_id":"backup-20250101-020000","status":"completed"}`)
+	signature, err := signer.Sign(context.Background(), manifest)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if signature.Rekor == nil || signature.Rekor.LogIndex != 42 {
+		t.Fatalf("Sign() rekor entry = %+v, want log index 42", signature.Rekor)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(sigstore.ca)
+	verifier := &Verifier{Roots: roots, Identity: testIdentity, Issuer: testIssuer, RekorKey: sigstore.rekorKey.Public()}
+	// The certificate expired ten minutes ago; the logged time vouches for it
+	if err := verifier.Verify(manifest, signature); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		verifier Verifier
+		manifest []byte
+		modify   func(*Signature)
+	}{
+		{"tampered_manifest", *verifier, []byte(`{"status":"failed"}`), nil},
+		{"other_identity", Verifier{Roots: roots, Identity: "https://kubernetes.io/namespaces/default/serviceaccounts/default"}, manifest, nil},
+		{"other_issuer", Verifier{Roots: roots, Identity: testIdentity, Issuer: "https://accounts.google.com"}, manifest, nil},
+		{"untrusted_root", Verifier{Roots: x509.NewCertPool(), Identity: testIdentity}, manifest, nil},
+		{"backdated_log_entry", *verifier, manifest, func(s *Signature) { s.Rekor.IntegratedTime -= 3600 }},
+		{"missing_log_entry", *verifier, manifest, func(s *Signature) { s.Rekor = nil }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modified := *signature
+			rekor := *signature.Rekor
+			modified.Rekor = &rekor
+			if tt.modify != nil {
+				tt.modify(&modified)
+			}
+			if err := tt.verifier.Verify(tt.manifest, &modified); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Verify() error = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
+
+func TestKeylessSigner_FulcioRejects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"code":401,"message":"invalid identity token"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	signer := &KeylessSigner{FulcioURL: server.URL, TokenFile: writeIdentityToken(t)}
+	if _, err := signer.Sign(context.Background(), []byte("{}")); err == nil {
+		t.Error("Sign() succeeded although fulcio rejected the token")
+	}
+}
@@ -0,0 +1,201 @@
+// Package signing signs backup manifests and verifies their signatures, either
+// with a provided private key or keyless, cosign-style, through Sigstore's
+// Fulcio certificate authority and Rekor transparency log. It uses only the
+// standard library.
+package signing
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Signature algorithms
+const (
+	AlgorithmECDSASHA256 = "ecdsa-sha256"
+	AlgorithmEd25519     = "ed25519"
+)
+
+// SignatureSuffix is appended to a manifest's key to store its signature
+const SignatureSuffix = ".sig"
+
+// ErrInvalidSignature is returned when a signature is missing, malformed or
+// not made by a trusted key or identity
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// Signature is the signature document stored next to a signed manifest
+type Signature struct {
+	Algorithm string `json:"algorithm"`
+	// Signature is the base64-encoded signature over the manifest bytes
+	Signature string `json:"signature"`
+	// KeyID is the hex SHA-256 of the signing public key, set for key signatures
+	KeyID string `json:"key_id,omitempty"`
+	// Certificate is the PEM Fulcio certificate chain, leaf first, set for
+	// keyless signatures
+	Certificate string `json:"certificate,omitempty"`
+	// Rekor is the transparency log entry of a keyless signature
+	Rekor *RekorEntry `json:"rekor,omitempty"`
+}
+
+// Signer signs manifest bytes
+type Signer interface {
+	Sign(ctx context.Context, data []byte) (*Signature, error)
+}
+
+// KeySigner signs with a provided ECDSA or Ed25519 private key
+type KeySigner struct {
+	key   crypto.Signer
+	keyID string
+}
+
+// NewKeySigner parses a PEM private key in PKCS#8 or SEC 1 form
+func NewKeySigner(keyPEM []byte) (*KeySigner, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("signing key is not PEM encoded")
+	}
+
+	var key crypto.Signer
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse signing key: %v", err)
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported signing key type %T", parsed)
+		}
+		key = signer
+	case "EC PRIVATE KEY":
+		parsed, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse signing key: %v", err)
+		}
+		key = parsed
+	default:
+		// Encrypted cosign keys must be exported unencrypted first
+		return nil, fmt.Errorf("unsupported signing key PEM type %q", block.Type)
+	}
+
+	if _, err := algorithmFor(key.Public()); err != nil {
+		return nil, err
+	}
+	keyID, err := KeyID(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &KeySigner{key: key, keyID: keyID}, nil
+}
+
+// LoadKeySigner reads a PEM private key file
+func LoadKeySigner(path string) (*KeySigner, error) {
+	keyPEM, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %v", err)
+	}
+	return NewKeySigner(keyPEM)
+}
+
+// Sign signs data with the private key
+func (s *KeySigner) Sign(_ context.Context, data []byte) (*Signature, error) {
+	algorithm, signature, err := sign(s.key, data)
+	if err != nil {
+		return nil, err
+	}
+	return &Signature{
+		Algorithm: algorithm,
+		Signature: base64.StdEncoding.EncodeToString(signature),
+		KeyID:     s.keyID,
+	}, nil
+}
+
+// KeyID returns the hex SHA-256 of a public key's PKIX encoding
+func KeyID(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode public key: %v", err)
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ParsePublicKeys parses one or more PEM public keys. Several keys let
+// signatures made before a key rotation keep verifying.
+func ParsePublicKeys(keysPEM []byte) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for {
+		var block *pem.Block
+		block, keysPEM = pem.Decode(keysPEM)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key: %v", err)
+		}
+		if _, err := algorithmFor(key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no PEM public key found")
+	}
+	return keys, nil
+}
+
+// algorithmFor returns the signature algorithm used with a public key
+func algorithmFor(pub crypto.PublicKey) (string, error) {
+	switch pub.(type) {
+	case *ecdsa.PublicKey:
+		return AlgorithmECDSASHA256, nil
+	case ed25519.PublicKey:
+		return AlgorithmEd25519, nil
+	}
+	return "", fmt.Errorf("unsupported key type %T, use ECDSA or Ed25519", pub)
+}
+
+// sign signs data, hashing it with SHA-256 first for ECDSA
+func sign(key crypto.Signer, data []byte) (string, []byte, error) {
+	algorithm, err := algorithmFor(key.Public())
+	if err != nil {
+		return "", nil, err
+	}
+	var signature []byte
+	if algorithm == AlgorithmEd25519 {
+		signature, err = key.Sign(rand.Reader, data, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(data)
+		signature, err = key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to sign: %v", err)
+	}
+	return algorithm, signature, nil
+}
+
+// verify checks a signature over data with a public key
+func verify(pub crypto.PublicKey, algorithm string, data, signature []byte) bool {
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return algorithm == AlgorithmECDSASHA256 && ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		return algorithm == AlgorithmEd25519 && ed25519.Verify(key, data, signature)
+	}
+	return false
+}
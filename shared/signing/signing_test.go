@@ -0,0 +1,106 @@
+package signing
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func privateKeyPEM(t *testing.T, key crypto.Signer) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func publicKeyPEM(t *testing.T, key crypto.PublicKey) []byte {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestKeySigner_SignAndVerify(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	manifest := []byte(`{"backup_id":"backup-20250101-020000","status":"completed"}`)
+
+	for _, key := range []crypto.Signer{ecKey, edKey} {
+		signer, err := NewKeySigner(privateKeyPEM(t, key))
+		if err != nil {
+			t.Fatalf("NewKeySigner() error = %v", err)
+		}
+		signature, err := signer.Sign(context.Background(), manifest)
+		if err != nil {
+			t.Fatalf("Sign() error = %v", err)
+		}
+
+		keys, err := ParsePublicKeys(publicKeyPEM(t, key.Public()))
+		if err != nil {
+			t.Fatalf("ParsePublicKeys() error = %v", err)
+		}
+		verifier := &Verifier{PublicKeys: keys}
+		if err := verifier.Verify(manifest, signature); err != nil {
+			t.Errorf("%s: Verify() error = %v", signature.Algorithm, err)
+		}
+
+		tampered := []byte(`{"backup_id":"backup-20250101-020000","status":"failed"}`)
+		if err := verifier.Verify(tampered, signature); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: Verify() of tampered manifest error = %v, want ErrInvalidSignature", signature.Algorithm, err)
+		}
+	}
+}
+
+func TestVerifier_UntrustedKey(t *testing.T) {
+	signingKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	manifest := []byte("{}")
+
+	signer, err := NewKeySigner(privateKeyPEM(t, signingKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, _ := signer.Sign(context.Background(), manifest)
+
+	verifier := &Verifier{PublicKeys: []crypto.PublicKey{otherKey.Public()}}
+	if err := verifier.Verify(manifest, signature); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify() with untrusted key error = %v, want ErrInvalidSignature", err)
+	}
+	if err := verifier.Verify(manifest, nil); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify() of unsigned manifest error = %v, want ErrInvalidSignature", err)
+	}
+
+	// A bundle of both keys, as kept across a key rotation, accepts either
+	bundle := append(publicKeyPEM(t, otherKey.Public()), publicKeyPEM(t, signingKey.Public())...)
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "cosign.pub")
+	os.WriteFile(keyFile, bundle, 0600)
+	verifier, err = LoadVerifier(VerifierConfig{PublicKeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("LoadVerifier() error = %v", err)
+	}
+	if err := verifier.Verify(manifest, signature); err != nil {
+		t.Errorf("Verify() with rotated key bundle error = %v", err)
+	}
+}
+
+func TestNewKeySigner_Invalid(t *testing.T) {
+	if _, err := NewKeySigner([]byte("not a key")); err == nil {
+		t.Error("NewKeySigner() accepted a non-PEM key")
+	}
+	encrypted := pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED SIGSTORE PRIVATE KEY", Bytes: []byte("x")})
+	if _, err := NewKeySigner(encrypted); err == nil {
+		t.Error("NewKeySigner() accepted an encrypted cosign key")
+	}
+}
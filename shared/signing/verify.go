@@ -0,0 +1,261 @@
+package signing
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Fulcio certificate extensions naming the OIDC issuer of the identity. The
+// first holds the raw string and is deprecated in favour of the DER one.
+var (
+	oidIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// Verifier checks manifest signatures against trusted keys or, for keyless
+// signatures, trusted Fulcio roots and the expected signer identity
+type Verifier struct {
+	// PublicKeys are trusted for key signatures
+	PublicKeys []crypto.PublicKey
+	// Roots are the Fulcio CA certificates trusted for keyless signatures
+	Roots *x509.CertPool
+	// Identity is the subject keyless certificates must carry as a URI or
+	// email SAN, e.g. https://kubernetes.io/namespaces/backup/serviceaccounts/cluster-backup
+	Identity string
+	// Issuer, when set, is the OIDC issuer keyless certificates must name
+	Issuer string
+	// RekorKey, when set, requires keyless signatures to carry a Rekor entry
+	// with a valid signed timestamp, at which the certificate must have been
+	// valid. Without it the certificate is checked at its issue time.
+	RekorKey crypto.PublicKey
+}
+
+// VerifierConfig names the files a Verifier is loaded from
+type VerifierConfig struct {
+	PublicKeyFile string
+	RootsFile     string
+	Identity      string
+	Issuer        string
+	RekorKeyFile  string
+}
+
+// Enabled reports whether any trust anchor is configured
+func (c VerifierConfig) Enabled() bool {
+	return c.PublicKeyFile != "" || c.RootsFile != ""
+}
+
+// LoadVerifier reads the configured keys and roots
+func LoadVerifier(cfg VerifierConfig) (*Verifier, error) {
+	verifier := &Verifier{Identity: cfg.Identity, Issuer: cfg.Issuer}
+	if cfg.PublicKeyFile != "" {
+		keysPEM, err := os.ReadFile(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read verification key: %v", err)
+		}
+		if verifier.PublicKeys, err = ParsePublicKeys(keysPEM); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.RootsFile != "" {
+		if cfg.Identity == "" {
+			return nil, fmt.Errorf("keyless verification requires the expected signer identity")
+		}
+		rootsPEM, err := os.ReadFile(cfg.RootsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fulcio roots: %v", err)
+		}
+		verifier.Roots = x509.NewCertPool()
+		if !verifier.Roots.AppendCertsFromPEM(rootsPEM) {
+			return nil, fmt.Errorf("no certificate found in fulcio roots %s", cfg.RootsFile)
+		}
+	}
+	if cfg.RekorKeyFile != "" {
+		keyPEM, err := os.ReadFile(cfg.RekorKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rekor public key: %v", err)
+		}
+		keys, err := ParsePublicKeys(keyPEM)
+		if err != nil {
+			return nil, err
+		}
+		verifier.RekorKey = keys[0]
+	}
+	return verifier, nil
+}
+
+// Verify checks that signature was made over data by a trusted key or identity
+func (v *Verifier) Verify(data []byte, signature *Signature) error {
+	if signature == nil {
+		return fmt.Errorf("%w: manifest is not signed", ErrInvalidSignature)
+	}
+	raw, err := base64.StdEncoding.DecodeString(signature.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	if signature.Certificate != "" {
+		return v.verifyKeyless(data, raw, signature)
+	}
+
+	for _, key := range v.PublicKeys {
+		if signature.KeyID != "" {
+			if keyID, err := KeyID(key); err != nil || keyID != signature.KeyID {
+				continue
+			}
+		}
+		if verify(key, signature.Algorithm, data, raw) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: not signed by a trusted key", ErrInvalidSignature)
+}
+
+func (v *Verifier) verifyKeyless(data, raw []byte, signature *Signature) error {
+	if v.Roots == nil {
+		return fmt.Errorf("%w: keyless signature but no fulcio roots are trusted", ErrInvalidSignature)
+	}
+
+	var certificates []*x509.Certificate
+	rest := []byte(signature.Certificate)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+		}
+		certificates = append(certificates, certificate)
+	}
+	if len(certificates) == 0 {
+		return fmt.Errorf("%w: no signing certificate", ErrInvalidSignature)
+	}
+	leaf := certificates[0]
+
+	signedAt := leaf.NotBefore
+	if v.RekorKey != nil {
+		if signature.Rekor == nil {
+			return fmt.Errorf("%w: no transparency log entry", ErrInvalidSignature)
+		}
+		if err := v.verifyRekorEntry(data, signature, leaf); err != nil {
+			return err
+		}
+		signedAt = time.Unix(signature.Rekor.IntegratedTime, 0)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, certificate := range certificates[1:] {
+		intermediates.AddCert(certificate)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         v.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   signedAt,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	if err != nil {
+		return fmt.Errorf("%w: untrusted certificate: %v", ErrInvalidSignature, err)
+	}
+
+	if !hasIdentity(leaf, v.Identity) {
+		return fmt.Errorf("%w: certificate is not issued to %s", ErrInvalidSignature, v.Identity)
+	}
+	if v.Issuer != "" {
+		if issuer := certificateIssuer(leaf); issuer != v.Issuer {
+			return fmt.Errorf("%w: certificate identity is from issuer %q, expected %q", ErrInvalidSignature, issuer, v.Issuer)
+		}
+	}
+
+	if !verify(leaf.PublicKey, signature.Algorithm, data, raw) {
+		return fmt.Errorf("%w: signature does not match the manifest", ErrInvalidSignature)
+	}
+	return nil
+}
+
+// verifyRekorEntry checks Rekor's signed timestamp and that the logged entry
+// is this signature over this data
+func (v *Verifier) verifyRekorEntry(data []byte, signature *Signature, leaf *x509.Certificate) error {
+	entry := signature.Rekor
+	// Rekor signs the canonical JSON of these fields, in this order
+	payload, err := json.Marshal(struct {
+		Body           string `json:"body"`
+		IntegratedTime int64  `json:"integratedTime"`
+		LogID          string `json:"logID"`
+		LogIndex       int64  `json:"logIndex"`
+	}{entry.Body, entry.IntegratedTime, entry.LogID, entry.LogIndex})
+	if err != nil {
+		return err
+	}
+	set, err := base64.StdEncoding.DecodeString(entry.SignedEntryTimestamp)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	rekorKey, ok := v.RekorKey.(*ecdsa.PublicKey)
+	digest := sha256.Sum256(payload)
+	if !ok || !ecdsa.VerifyASN1(rekorKey, digest[:], set) {
+		return fmt.Errorf("%w: transparency log timestamp does not verify", ErrInvalidSignature)
+	}
+
+	body, err := base64.StdEncoding.DecodeString(entry.Body)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	var logged hashedRekord
+	if err := json.Unmarshal(body, &logged); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	expected := newHashedRekord(data, signature.Signature, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw}))
+	loggedCertificate, _ := base64.StdEncoding.DecodeString(logged.Spec.Signature.PublicKey.Content)
+	loggedLeaf, _ := pem.Decode(loggedCertificate)
+	if logged.Spec.Signature.Content != expected.Spec.Signature.Content ||
+		logged.Spec.Data.Hash != expected.Spec.Data.Hash ||
+		loggedLeaf == nil || !bytes.Equal(loggedLeaf.Bytes, leaf.Raw) {
+		return fmt.Errorf("%w: transparency log entry is for a different signature", ErrInvalidSignature)
+	}
+	return nil
+}
+
+// hasIdentity reports whether the certificate names identity as a URI or
+// email subject alternative name
+func hasIdentity(certificate *x509.Certificate, identity string) bool {
+	for _, uri := range certificate.URIs {
+		if uri.String() == identity {
+			return true
+		}
+	}
+	for _, email := range certificate.EmailAddresses {
+		if email == identity {
+			return true
+		}
+	}
+	return false
+}
+
+// certificateIssuer returns the OIDC issuer recorded in a Fulcio certificate
+func certificateIssuer(certificate *x509.Certificate) string {
+	for _, extension := range certificate.Extensions {
+		if extension.Id.Equal(oidIssuerV2) {
+			var issuer string
+			if _, err := asn1.Unmarshal(extension.Value, &issuer); err == nil {
+				return issuer
+			}
+		}
+	}
+	for _, extension := range certificate.Extensions {
+		if extension.Id.Equal(oidIssuerV1) {
+			return string(extension.Value)
+		}
+	}
+	return ""
+}
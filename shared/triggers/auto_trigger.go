@@ -8,11 +8,13 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
 	"time"
 
+	"k8s.io/client-go/dynamic"
+
 	sharedconfig "shared-config/config"
+	"shared-config/mtls"
 )
 
 // TriggerType defines the type of trigger mechanism
@@ -23,6 +25,8 @@ const (
 	TriggerTypeWebhook TriggerType = "webhook"
 	TriggerTypeProcess TriggerType = "process"
 	TriggerTypeScript  TriggerType = "script"
+	TriggerTypeTekton  TriggerType = "tekton"
+	TriggerTypeArgo    TriggerType = "argo"
 )
 
 // TriggerResult represents the result of a trigger operation
@@ -57,6 +61,8 @@ type AutoTrigger struct {
 	config     *sharedconfig.SharedConfig
 	logger     Logger
 	httpClient *http.Client
+	// dynamicClient creates Tekton PipelineRuns; built on first use
+	dynamicClient dynamic.Interface
 }
 
 // Logger interface for trigger operations
@@ -68,13 +74,32 @@ type Logger interface {
 
 // NewAutoTrigger creates a new auto-trigger instance
 func NewAutoTrigger(config *sharedconfig.SharedConfig, logger Logger) *AutoTrigger {
-	return &AutoTrigger{
+	at := &AutoTrigger{
 		config: config,
 		logger: logger,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
 	}
+
+	// With mutual TLS the webhook presents the component's certificate to
+	// the bridge, read again when it is rotated
+	if config != nil && config.Integration.Communication.Authentication.MutualTLS() {
+		files := config.Integration.Communication.Authentication.TLS
+		certificates, err := mtls.Load(mtls.Files{CertFile: files.CertFile, KeyFile: files.KeyFile, CAFile: files.CAFile}, 0)
+		if err != nil {
+			// The bridge then rejects the webhooks, which report the failure
+			logger.Error("auto_trigger_tls_failed", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			at.httpClient.Transport = &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: certificates.ClientConfig(),
+			}
+		}
+	}
+	return at
 }
 
 // TriggerGitOpsGeneration triggers GitOps generation after backup completion
@@ -122,6 +147,10 @@ func (at *AutoTrigger) TriggerGitOpsGeneration(ctx context.Context, event *Backu
 			result, err = at.triggerViaProcess(ctx, event)
 		case TriggerTypeScript:
 			result, err = at.triggerViaScript(ctx, event)
+		case TriggerTypeTekton:
+			result, err = at.triggerViaTekton(ctx, event)
+		case TriggerTypeArgo:
+			result, err = at.triggerViaArgo(ctx, event)
 		default:
 			continue
 		}
@@ -157,10 +186,18 @@ func (at *AutoTrigger) TriggerGitOpsGeneration(ctx context.Context, event *Backu
 	return result, nil
 }
 
-// getTriggerMethods returns the ordered list of trigger methods to try
+// getTriggerMethods returns the ordered list of trigger methods to try: the
+// configured trigger methods or, without any, the available defaults
 func (at *AutoTrigger) getTriggerMethods() []TriggerType {
 	methods := []TriggerType{}
 
+	if configured := at.config.Pipeline.Automation.TriggerMethods; len(configured) > 0 {
+		for _, method := range configured {
+			methods = append(methods, TriggerType(method))
+		}
+		return methods
+	}
+
 	// Add methods based on configuration availability
 	if at.config.Pipeline.Notifications.Webhook.URL != "" {
 		methods = append(methods, TriggerTypeWebhook)
@@ -176,39 +213,15 @@ func (at *AutoTrigger) getTriggerMethods() []TriggerType {
 func (at *AutoTrigger) triggerViaFile(ctx context.Context, event *BackupCompletionEvent) (*TriggerResult, error) {
 	startTime := time.Now()
 
-	// Create trigger directory if it doesn't exist
-	triggerDir := "/tmp/backup-gitops-triggers"
-	if err := os.MkdirAll(triggerDir, 0755); err != nil {
-		return &TriggerResult{
-			Success:   false,
-			Timestamp: startTime,
-			Duration:  time.Since(startTime),
-			Method:    TriggerTypeFile,
-			Error:     fmt.Sprintf("Failed to create trigger directory: %v", err),
-		}, err
-	}
-
-	// Create signal file with backup event data
-	signalFile := filepath.Join(triggerDir, fmt.Sprintf("backup-complete-%s-%d.json", event.ClusterName, event.Timestamp.Unix()))
-	
-	eventData, err := json.MarshalIndent(event, "", "  ")
+	triggerDir := triggerDirectory(at.config.Pipeline.Automation.FileTrigger)
+	signalFile, err := writeTriggerFile(triggerDir, event)
 	if err != nil {
 		return &TriggerResult{
 			Success:   false,
 			Timestamp: startTime,
 			Duration:  time.Since(startTime),
 			Method:    TriggerTypeFile,
-			Error:     fmt.Sprintf("Failed to marshal event data: %v", err),
-		}, err
-	}
-
-	if err := os.WriteFile(signalFile, eventData, 0644); err != nil {
-		return &TriggerResult{
-			Success:   false,
-			Timestamp: startTime,
-			Duration:  time.Since(startTime),
-			Method:    TriggerTypeFile,
-			Error:     fmt.Sprintf("Failed to write signal file: %v", err),
+			Error:     err.Error(),
 		}, err
 	}
 
@@ -274,6 +287,7 @@ func (at *AutoTrigger) triggerViaWebhook(ctx context.Context, event *BackupCompl
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "backup-gitops-auto-trigger/1.0")
 	req.Header.Set("X-Event-Type", "backup_complete")
+	SetWebhookAuth(req, at.config.Pipeline.Automation.WebhookTrigger.Authentication, payloadBytes)
 
 	resp, err := at.httpClient.Do(req)
 	if err != nil {
@@ -60,7 +60,7 @@ func createTestConfig() *sharedconfig.SharedConfig {
 			SecretKey: "testsecret",
 			Bucket:    "test-bucket",
 		},
-		Cluster: sharedconfig.ClusterConfig{
+		Cluster: sharedconfig.SingleClusterConfig{
 			Name:   "test-cluster",
 			Domain: "cluster.local",
 		},
@@ -158,7 +158,7 @@ func (bti *BackupTriggerIntegration) WaitForBackupCompletion(ctx context.Context
 
 	// For now, we'll check for signal files in the trigger directory
 	// This could be extended to support other mechanisms like message queues
-	triggerDir := "/tmp/backup-gitops-triggers"
+	triggerDir := triggerDirectory(bti.config.Pipeline.Automation.FileTrigger)
 	
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
@@ -180,14 +180,48 @@ func (bti *BackupTriggerIntegration) WaitForBackupCompletion(ctx context.Context
 	}
 }
 
-// checkForSignalFiles checks for backup completion signal files
+// checkForSignalFiles returns the event of the oldest pending signal file and
+// marks the file processed, removing it when cleanup_after_processing is set
 func (bti *BackupTriggerIntegration) checkForSignalFiles(triggerDir string) (*BackupCompletionEvent, bool) {
-	// This is a simplified implementation
-	// In a production system, you might want to use inotify or similar mechanisms
-	// for more efficient file system monitoring
-	
-	// For now, return false as this would require file system scanning
-	// In practice, this would be implemented with proper file watching
+	files, err := pendingTriggerFiles(triggerDir)
+	if err != nil {
+		bti.logger.Error("signal_file_scan_failed", map[string]interface{}{
+			"trigger_dir": triggerDir,
+			"error":       err.Error(),
+		})
+		return nil, false
+	}
+
+	cleanup := bti.config.Pipeline.Automation.FileTrigger.CleanupAfterProcessing
+	for _, file := range files {
+		event, err := readTriggerFile(file)
+		if err != nil {
+			// Leave unreadable files in place but stop picking them up
+			bti.logger.Error("signal_file_invalid", map[string]interface{}{
+				"file":  file,
+				"error": err.Error(),
+			})
+			if markErr := markTriggerFileProcessed(file, false); markErr != nil {
+				bti.logger.Error("signal_file_cleanup_failed", map[string]interface{}{
+					"file":  file,
+					"error": markErr.Error(),
+				})
+			}
+			continue
+		}
+
+		if err := markTriggerFileProcessed(file, cleanup); err != nil {
+			bti.logger.Error("signal_file_cleanup_failed", map[string]interface{}{
+				"file":  file,
+				"error": err.Error(),
+			})
+		}
+		bti.logger.Debug("signal_file_processed", map[string]interface{}{
+			"file":    file,
+			"removed": cleanup,
+		})
+		return event, true
+	}
 	return nil, false
 }
 
@@ -0,0 +1,124 @@
+package triggers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	sharedconfig "shared-config/config"
+)
+
+// defaultTriggerDirectory is used when file_trigger.directory is not set
+const defaultTriggerDirectory = "/tmp/backup-gitops-triggers"
+
+// Trigger files are named backup-complete-<cluster>-<unix time>.json; once
+// processed they are removed or, without cleanup, renamed with the processed
+// suffix so they are not picked up again
+const (
+	triggerFilePrefix   = "backup-complete-"
+	triggerFileSuffix   = ".json"
+	processedFileSuffix = ".processed"
+)
+
+// triggerDirectory returns the directory trigger files are written to and
+// read from, typically a PVC shared with the GitOps generator
+func triggerDirectory(fileTrigger sharedconfig.FileTriggerConfig) string {
+	if fileTrigger.Directory != "" {
+		return fileTrigger.Directory
+	}
+	return defaultTriggerDirectory
+}
+
+// writeTriggerFile writes a trigger file for the event. It is written to a
+// hidden temporary file first and renamed into place, so a consumer polling
+// the directory never reads a partially written file.
+func writeTriggerFile(triggerDir string, event *BackupCompletionEvent) (string, error) {
+	if err := os.MkdirAll(triggerDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create trigger directory: %v", err)
+	}
+
+	eventData, err := json.MarshalIndent(event, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal event data: %v", err)
+	}
+
+	name := fmt.Sprintf("%s%s-%d%s", triggerFilePrefix, event.ClusterName, event.Timestamp.Unix(), triggerFileSuffix)
+	triggerFile := filepath.Join(triggerDir, name)
+	tmpFile := filepath.Join(triggerDir, "."+name+".tmp")
+
+	if err := os.WriteFile(tmpFile, eventData, 0644); err != nil {
+		os.Remove(tmpFile)
+		return "", fmt.Errorf("failed to write trigger file: %v", err)
+	}
+	if err := os.Rename(tmpFile, triggerFile); err != nil {
+		os.Remove(tmpFile)
+		return "", fmt.Errorf("failed to write trigger file: %v", err)
+	}
+	return triggerFile, nil
+}
+
+// pendingTriggerFiles returns the unprocessed trigger files in the directory,
+// oldest first
+func pendingTriggerFiles(triggerDir string) ([]string, error) {
+	entries, err := os.ReadDir(triggerDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read trigger directory: %v", err)
+	}
+
+	type pending struct {
+		path    string
+		modTime int64
+	}
+	var files []pending
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, triggerFilePrefix) || !strings.HasSuffix(name, triggerFileSuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, pending{path: filepath.Join(triggerDir, name), modTime: info.ModTime().UnixNano()})
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].modTime != files[j].modTime {
+			return files[i].modTime < files[j].modTime
+		}
+		return files[i].path < files[j].path
+	})
+
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.path
+	}
+	return paths, nil
+}
+
+// readTriggerFile reads the backup completion event from a trigger file
+func readTriggerFile(path string) (*BackupCompletionEvent, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trigger file: %v", err)
+	}
+	var event BackupCompletionEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("failed to parse trigger file %s: %v", filepath.Base(path), err)
+	}
+	return &event, nil
+}
+
+// markTriggerFileProcessed removes a processed trigger file, or keeps it
+// renamed with the processed suffix when cleanup is disabled
+func markTriggerFileProcessed(path string, cleanup bool) error {
+	if cleanup {
+		return os.Remove(path)
+	}
+	return os.Rename(path, path+processedFileSuffix)
+}
@@ -0,0 +1,84 @@
+package triggers
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sharedconfig "shared-config/config"
+)
+
+func TestAutoTrigger_TriggerViaFile_ConfiguredDirectory(t *testing.T) {
+	triggerDir := filepath.Join(t.TempDir(), "triggers")
+	config := &sharedconfig.SharedConfig{}
+	config.Pipeline.Automation.FileTrigger.Directory = triggerDir
+
+	event := newPipelineEvent()
+	event.MinIOBucket = "cluster-backups"
+	event.Timestamp = time.Unix(1735696800, 0).UTC()
+
+	result, err := NewAutoTrigger(config, &MockLogger{}).triggerViaFile(context.Background(), event)
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, filepath.Join(triggerDir, "backup-complete-prod-1735696800.json"), result.Metadata["signal_file"])
+
+	entries, err := os.ReadDir(triggerDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1, "no temporary file is left behind")
+
+	data, err := os.ReadFile(result.Metadata["signal_file"])
+	require.NoError(t, err)
+	var written map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, "prod-1735696800", written["backup_id"])
+	assert.Equal(t, "prod", written["cluster_name"])
+	assert.Equal(t, "cluster-backups", written["minio_bucket"])
+	assert.Equal(t, "2025-01-01T02:00:00Z", written["timestamp"])
+}
+
+func TestBackupTriggerIntegration_CheckForSignalFiles(t *testing.T) {
+	for _, cleanup := range []bool{true, false} {
+		triggerDir := t.TempDir()
+		config := &sharedconfig.SharedConfig{}
+		config.Pipeline.Automation.FileTrigger = sharedconfig.FileTriggerConfig{Directory: triggerDir, CleanupAfterProcessing: cleanup}
+
+		first := newPipelineEvent()
+		first.Timestamp = time.Unix(1735696800, 0)
+		second := newPipelineEvent()
+		second.BackupID = "prod-1735700400"
+		second.Timestamp = time.Unix(1735700400, 0)
+		firstFile, err := writeTriggerFile(triggerDir, first)
+		require.NoError(t, err)
+		require.NoError(t, os.Chtimes(firstFile, first.Timestamp, first.Timestamp))
+		_, err = writeTriggerFile(triggerDir, second)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(triggerDir, "backup-complete-broken-1.json"), []byte("{"), 0644))
+
+		integration := NewBackupTriggerIntegration(config, &MockLogger{})
+		event, found := integration.checkForSignalFiles(triggerDir)
+		require.True(t, found)
+		assert.Equal(t, "prod-1735696800", event.BackupID, "oldest file first")
+
+		event, found = integration.checkForSignalFiles(triggerDir)
+		require.True(t, found)
+		assert.Equal(t, "prod-1735700400", event.BackupID)
+
+		_, found = integration.checkForSignalFiles(triggerDir)
+		assert.False(t, found)
+
+		remaining, err := filepath.Glob(filepath.Join(triggerDir, "*"))
+		require.NoError(t, err)
+		if cleanup {
+			assert.Len(t, remaining, 1, "cleanup=%v", cleanup)
+		} else {
+			assert.Len(t, remaining, 3, "cleanup=%v", cleanup)
+		}
+		assert.Contains(t, remaining, filepath.Join(triggerDir, "backup-complete-broken-1.json.processed"))
+	}
+}
@@ -267,8 +267,15 @@ class GitOpsTriggerHandler:
         
         return None
     
-    def monitor_trigger_files(self, trigger_dir: str = "/tmp/backup-gitops-triggers", poll_interval: int = 5):
-        """Monitor for backup completion trigger files."""
+    def monitor_trigger_files(self, trigger_dir: Optional[str] = None, poll_interval: int = 5):
+        """Monitor for backup completion trigger files.
+        
+        Processed files are removed when file_trigger.cleanup_after_processing
+        is set, otherwise renamed with a .processed suffix.
+        """
+        file_trigger = self.config.pipeline.automation.file_trigger
+        if not trigger_dir:
+            trigger_dir = file_trigger.directory or "/tmp/backup-gitops-triggers"
         self.logger.info(f"Starting trigger file monitor in directory: {trigger_dir}")
         
         # Create trigger directory if it doesn't exist
@@ -299,8 +306,10 @@ class GitOpsTriggerHandler:
                             self.logger.info(f"Successfully processed trigger file: {trigger_file}")
                             # Mark as processed
                             processed_files.add(trigger_file.name)
-                            # Optionally remove the trigger file
-                            trigger_file.unlink()
+                            if file_trigger.cleanup_after_processing:
+                                trigger_file.unlink()
+                            else:
+                                trigger_file.rename(trigger_file.with_name(trigger_file.name + '.processed'))
                         else:
                             self.logger.error(f"Failed to process trigger file: {trigger_file}, error: {result.error}")
                     
@@ -342,8 +351,7 @@ def create_argument_parser() -> argparse.ArgumentParser:
     
     parser.add_argument(
         '--trigger-dir',
-        default='/tmp/backup-gitops-triggers',
-        help='Directory to monitor for trigger files'
+        help='Directory to monitor for trigger files (default: file_trigger.directory)'
     )
     
     parser.add_argument(
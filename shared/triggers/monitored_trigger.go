@@ -495,7 +495,7 @@ func getErrorFromResult(err error, result *TriggerResult) error {
 		return err
 	}
 	if result != nil && result.Error != "" {
-		return fmt.Errorf(result.Error)
+		return fmt.Errorf("%s", result.Error)
 	}
 	return fmt.Errorf("trigger failed")
 }
@@ -83,7 +83,7 @@ func (oat *OptimizedAutoTrigger) TriggerGitOpsGeneration(ctx context.Context, ev
 		if err == nil && result.Success {
 			// Log HTTP client metrics after successful operation
 			postMetrics := oat.httpManager.GetMetrics()
-			oat.logHTTPMetrics(preMetrics, postMetrics)
+			oat.logHTTPMetrics(&preMetrics, &postMetrics)
 			
 			oat.logger.Info("optimized_auto_trigger_success", map[string]interface{}{
 				"method":       string(method),
@@ -271,7 +271,7 @@ func (oat *OptimizedAutoTrigger) getHTTPMetricsString() string {
 	return string(metrics)
 }
 
-func (oat *OptimizedAutoTrigger) logHTTPMetrics(preMetrics, postMetrics httplib.HTTPPoolMetrics) {
+func (oat *OptimizedAutoTrigger) logHTTPMetrics(preMetrics, postMetrics *httplib.HTTPPoolMetrics) {
 	deltaRequests := postMetrics.AggregatedMetrics.TotalRequests - preMetrics.AggregatedMetrics.TotalRequests
 	deltaSuccess := postMetrics.AggregatedMetrics.SuccessfulReqs - preMetrics.AggregatedMetrics.SuccessfulReqs
 	deltaRetries := postMetrics.AggregatedMetrics.RetryAttempts - preMetrics.AggregatedMetrics.RetryAttempts
@@ -0,0 +1,288 @@
+package triggers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	sharedconfig "shared-config/config"
+)
+
+// Default names of the parameters a triggered pipeline receives
+const (
+	defaultBackupIDParam   = "backup-id"
+	defaultBackupPathParam = "backup-path"
+)
+
+// Annotations set on a started PipelineRun to trace it back to its backup
+const (
+	backupIDAnnotation = "backup.cluster/backup-id"
+	clusterAnnotation  = "backup.cluster/cluster"
+)
+
+// pipelineRunGVR is the Tekton resource a PipelineRun is created as
+var pipelineRunGVR = schema.GroupVersionResource{Group: "tekton.dev", Version: "v1", Resource: "pipelineruns"}
+
+// triggerViaTekton starts a PipelineRun of the configured Tekton Pipeline
+// through the Kubernetes API, with the backup ID and path as parameters
+func (at *AutoTrigger) triggerViaTekton(ctx context.Context, event *BackupCompletionEvent) (*TriggerResult, error) {
+	startTime := time.Now()
+	tekton := at.config.Pipeline.Automation.TektonTrigger
+
+	if err := pipelineEventError(event); err != nil {
+		return failedPipelineResult(TriggerTypeTekton, startTime, err), err
+	}
+	client, err := at.pipelineClient()
+	if err != nil {
+		return failedPipelineResult(TriggerTypeTekton, startTime, err), err
+	}
+
+	spec := map[string]interface{}{
+		"pipelineRef": map[string]interface{}{"name": tekton.Pipeline},
+		"params":      tektonParams(pipelineParams(tekton.Params, event)),
+	}
+	if tekton.ServiceAccount != "" {
+		spec["taskRunTemplate"] = map[string]interface{}{"serviceAccountName": tekton.ServiceAccount}
+	}
+	pipelineRun := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": pipelineRunGVR.GroupVersion().String(),
+		"kind":       "PipelineRun",
+		"metadata": map[string]interface{}{
+			"generateName": tekton.Pipeline + "-",
+			"namespace":    tekton.Namespace,
+			"annotations": map[string]interface{}{
+				backupIDAnnotation: event.BackupID,
+				clusterAnnotation:  event.ClusterName,
+			},
+		},
+		"spec": spec,
+	}}
+
+	var created *unstructured.Unstructured
+	err = at.retryPipelineTrigger(ctx, func() error {
+		var createErr error
+		created, createErr = client.Resource(pipelineRunGVR).Namespace(tekton.Namespace).Create(ctx, pipelineRun, metav1.CreateOptions{})
+		if createErr != nil && isTransientAPIError(createErr) {
+			return fmt.Errorf("temporary failure: %v", createErr)
+		}
+		return createErr
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to create Tekton PipelineRun: %v", err)
+		return failedPipelineResult(TriggerTypeTekton, startTime, err), err
+	}
+
+	return &TriggerResult{
+		Success:   true,
+		Timestamp: startTime,
+		Duration:  time.Since(startTime),
+		Method:    TriggerTypeTekton,
+		Output:    fmt.Sprintf("Started PipelineRun %s/%s", tekton.Namespace, created.GetName()),
+		Metadata: map[string]string{
+			"namespace":    tekton.Namespace,
+			"pipeline":     tekton.Pipeline,
+			"pipeline_run": created.GetName(),
+		},
+	}, nil
+}
+
+// triggerViaArgo submits the configured WorkflowTemplate through the Argo
+// Server API, with the backup ID and path as parameters
+func (at *AutoTrigger) triggerViaArgo(ctx context.Context, event *BackupCompletionEvent) (*TriggerResult, error) {
+	startTime := time.Now()
+	argo := at.config.Pipeline.Automation.ArgoTrigger
+
+	if err := pipelineEventError(event); err != nil {
+		return failedPipelineResult(TriggerTypeArgo, startTime, err), err
+	}
+
+	resourceKind := "WorkflowTemplate"
+	if argo.ClusterScope {
+		resourceKind = "ClusterWorkflowTemplate"
+	}
+	var parameters []string
+	for _, param := range pipelineParams(argo.Params, event) {
+		parameters = append(parameters, param.name+"="+param.value)
+	}
+	submitOptions := map[string]interface{}{
+		"generateName": argo.WorkflowTemplate + "-",
+		"parameters":   parameters,
+		"annotations":  fmt.Sprintf("%s=%s,%s=%s", backupIDAnnotation, event.BackupID, clusterAnnotation, event.ClusterName),
+	}
+	if argo.ServiceAccount != "" {
+		submitOptions["serviceAccount"] = argo.ServiceAccount
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"namespace":     argo.Namespace,
+		"resourceKind":  resourceKind,
+		"resourceName":  argo.WorkflowTemplate,
+		"submitOptions": submitOptions,
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to marshal Argo submit request: %v", err)
+		return failedPipelineResult(TriggerTypeArgo, startTime, err), err
+	}
+
+	submitURL := fmt.Sprintf("%s/api/v1/workflows/%s/submit", strings.TrimSuffix(argo.ServerURL, "/"), argo.Namespace)
+	var workflow struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+	}
+	err = at.retryPipelineTrigger(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, "POST", submitURL, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "backup-gitops-auto-trigger/1.0")
+		if argo.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+strings.TrimPrefix(argo.Token, "Bearer "))
+		}
+
+		resp, err := at.httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("temporary failure: Argo Server request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return fmt.Errorf("temporary failure: Argo Server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("Argo Server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		}
+		if err := json.Unmarshal(body, &workflow); err != nil {
+			return fmt.Errorf("failed to parse Argo Server response: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to submit Argo Workflow: %v", err)
+		return failedPipelineResult(TriggerTypeArgo, startTime, err), err
+	}
+
+	return &TriggerResult{
+		Success:   true,
+		Timestamp: startTime,
+		Duration:  time.Since(startTime),
+		Method:    TriggerTypeArgo,
+		Output:    fmt.Sprintf("Submitted Workflow %s/%s", argo.Namespace, workflow.Metadata.Name),
+		Metadata: map[string]string{
+			"namespace":         argo.Namespace,
+			"workflow_template": argo.WorkflowTemplate,
+			"workflow":          workflow.Metadata.Name,
+		},
+	}, nil
+}
+
+// pipelineParam is a parameter passed to a triggered pipeline
+type pipelineParam struct {
+	name  string
+	value string
+}
+
+// pipelineParams returns the backup ID and path under their configured names
+func pipelineParams(names sharedconfig.PipelineTriggerParams, event *BackupCompletionEvent) []pipelineParam {
+	backupIDParam := names.BackupID
+	if backupIDParam == "" {
+		backupIDParam = defaultBackupIDParam
+	}
+	backupPathParam := names.BackupPath
+	if backupPathParam == "" {
+		backupPathParam = defaultBackupPathParam
+	}
+	return []pipelineParam{
+		{name: backupIDParam, value: event.BackupID},
+		{name: backupPathParam, value: event.BackupLocation},
+	}
+}
+
+func tektonParams(params []pipelineParam) []interface{} {
+	tekton := make([]interface{}, 0, len(params))
+	for _, param := range params {
+		tekton = append(tekton, map[string]interface{}{"name": param.name, "value": param.value})
+	}
+	return tekton
+}
+
+// pipelineEventError refuses to start a pipeline for a backup that did not
+// succeed, which it would otherwise test or promote
+func pipelineEventError(event *BackupCompletionEvent) error {
+	if !event.Success {
+		return fmt.Errorf("backup %s did not succeed, pipeline not triggered", event.BackupID)
+	}
+	return nil
+}
+
+func failedPipelineResult(method TriggerType, startTime time.Time, err error) *TriggerResult {
+	return &TriggerResult{
+		Success:   false,
+		Timestamp: startTime,
+		Duration:  time.Since(startTime),
+		Method:    method,
+		Error:     err.Error(),
+	}
+}
+
+// retryPipelineTrigger retries errors marked as temporary failures with the
+// pipeline's error handling settings
+func (at *AutoTrigger) retryPipelineTrigger(ctx context.Context, operation func() error) error {
+	errorHandling := at.config.Pipeline.ErrorHandling
+	return NewRetryHandler(at.config, at.logger).RetryOperation(ctx, operation, RetryConfig{
+		MaxRetries:      errorHandling.MaxRetries,
+		InitialDelay:    errorHandling.RetryDelay,
+		MaxDelay:        5 * time.Minute,
+		BackoffFactor:   2.0,
+		RetryableErrors: []string{"temporary failure"},
+	})
+}
+
+// isTransientAPIError reports whether creating a resource may succeed if
+// tried again
+func isTransientAPIError(err error) bool {
+	var netErr net.Error
+	return apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) || apierrors.IsTooManyRequests(err) ||
+		apierrors.IsInternalError(err) || apierrors.IsServiceUnavailable(err) || errors.As(err, &netErr)
+}
+
+// pipelineClient returns the client PipelineRuns are created with, from the
+// in-cluster service account or else the local kubeconfig
+func (at *AutoTrigger) pipelineClient() (dynamic.Interface, error) {
+	if at.dynamicClient != nil {
+		return at.dynamicClient, nil
+	}
+
+	k8sConfig, err := rest.InClusterConfig()
+	if err != nil {
+		kubeconfigPath := filepath.Join(os.Getenv("HOME"), ".kube", "config")
+		k8sConfig, err = clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create kubernetes config: %v", err)
+		}
+	}
+	client, err := dynamic.NewForConfig(k8sConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %v", err)
+	}
+	at.dynamicClient = client
+	return client, nil
+}
@@ -0,0 +1,161 @@
+package triggers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	sharedconfig "shared-config/config"
+)
+
+func newPipelineEvent() *BackupCompletionEvent {
+	return &BackupCompletionEvent{
+		BackupID:       "prod-1735696800",
+		ClusterName:    "prod",
+		Success:        true,
+		BackupLocation: "cluster-backups/prod",
+	}
+}
+
+func TestAutoTrigger_TriggerViaTekton(t *testing.T) {
+	config := &sharedconfig.SharedConfig{}
+	config.Pipeline.Automation.Enabled = true
+	config.Pipeline.Automation.TriggerMethods = []string{"tekton"}
+	config.Pipeline.Automation.TektonTrigger = sharedconfig.TektonTriggerConfig{
+		Namespace:      "ci",
+		Pipeline:       "restore-test",
+		ServiceAccount: "restore-tester",
+		Params:         sharedconfig.PipelineTriggerParams{BackupPath: "source"},
+	}
+	config.Pipeline.ErrorHandling.MaxRetries = 2
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		pipelineRunGVR: "PipelineRunList",
+	})
+	attempts := 0
+	client.PrependReactor("create", "pipelineruns", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		attempts++
+		if attempts == 1 {
+			return true, nil, apierrors.NewServiceUnavailable("webhook not ready")
+		}
+		obj := action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured).DeepCopy()
+		obj.SetName("restore-test-x7k2p")
+		return true, obj, nil
+	})
+	var created *unstructured.Unstructured
+	client.PrependReactor("create", "pipelineruns", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		created = action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured)
+		return false, nil, nil
+	})
+
+	trigger := NewAutoTrigger(config, &MockLogger{})
+	trigger.dynamicClient = client
+
+	result, err := trigger.TriggerGitOpsGeneration(context.Background(), newPipelineEvent())
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts, "retried the unavailable API server")
+	assert.True(t, result.Success)
+	assert.Equal(t, TriggerTypeTekton, result.Method)
+	assert.Equal(t, "restore-test-x7k2p", result.Metadata["pipeline_run"])
+
+	assert.Equal(t, "restore-test-", created.GetGenerateName())
+	assert.Equal(t, "prod-1735696800", created.GetAnnotations()[backupIDAnnotation])
+	assert.Equal(t, map[string]interface{}{
+		"pipelineRef": map[string]interface{}{"name": "restore-test"},
+		"params": []interface{}{
+			map[string]interface{}{"name": "backup-id", "value": "prod-1735696800"},
+			map[string]interface{}{"name": "source", "value": "cluster-backups/prod"},
+		},
+		"taskRunTemplate": map[string]interface{}{"serviceAccountName": "restore-tester"},
+	}, created.Object["spec"])
+}
+
+func TestAutoTrigger_TriggerViaArgo(t *testing.T) {
+	var submitted map[string]interface{}
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		assert.Equal(t, "/api/v1/workflows/argo/submit", r.URL.Path)
+		assert.Equal(t, "Bearer argo-token", r.Header.Get("Authorization"))
+		if attempts == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&submitted))
+		w.Write([]byte(`{"metadata":{"name":"restore-test-9fzlq","namespace":"argo"}}`))
+	}))
+	defer server.Close()
+
+	config := &sharedconfig.SharedConfig{}
+	config.Pipeline.Automation.Enabled = true
+	config.Pipeline.Automation.TriggerMethods = []string{"argo"}
+	config.Pipeline.Automation.ArgoTrigger = sharedconfig.ArgoTriggerConfig{
+		ServerURL:        server.URL + "/",
+		Token:            "argo-token",
+		Namespace:        "argo",
+		WorkflowTemplate: "restore-test",
+		ClusterScope:     true,
+	}
+	config.Pipeline.ErrorHandling.MaxRetries = 1
+	config.Pipeline.ErrorHandling.RetryDelay = time.Millisecond
+
+	result, err := NewAutoTrigger(config, &MockLogger{}).TriggerGitOpsGeneration(context.Background(), newPipelineEvent())
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, "restore-test-9fzlq", result.Metadata["workflow"])
+
+	assert.Equal(t, "ClusterWorkflowTemplate", submitted["resourceKind"])
+	assert.Equal(t, "restore-test", submitted["resourceName"])
+	assert.Equal(t, []interface{}{"backup-id=prod-1735696800", "backup-path=cluster-backups/prod"}, submitted["submitOptions"].(map[string]interface{})["parameters"])
+}
+
+func TestAutoTrigger_TriggerViaArgo_Rejected(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.Error(w, `{"message":"workflowtemplates.argoproj.io \"restore-test\" not found"}`, http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	config := &sharedconfig.SharedConfig{}
+	config.Pipeline.Automation.ArgoTrigger = sharedconfig.ArgoTriggerConfig{ServerURL: server.URL, Namespace: "argo", WorkflowTemplate: "restore-test"}
+	config.Pipeline.ErrorHandling.MaxRetries = 3
+
+	result, err := NewAutoTrigger(config, &MockLogger{}).triggerViaArgo(context.Background(), newPipelineEvent())
+	require.Error(t, err)
+	assert.Equal(t, 1, attempts, "client errors are not retried")
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "Argo Server returned status 404")
+}
+
+func TestAutoTrigger_PipelineSkipsFailedBackup(t *testing.T) {
+	config := &sharedconfig.SharedConfig{}
+	config.Pipeline.Automation.ArgoTrigger = sharedconfig.ArgoTriggerConfig{ServerURL: "http://127.0.0.1:1", Namespace: "argo", WorkflowTemplate: "restore-test"}
+	event := newPipelineEvent()
+	event.Success = false
+
+	result, err := NewAutoTrigger(config, &MockLogger{}).triggerViaArgo(context.Background(), event)
+	assert.EqualError(t, err, "backup prod-1735696800 did not succeed, pipeline not triggered")
+	assert.False(t, result.Success)
+}
+
+func TestAutoTrigger_GetTriggerMethods(t *testing.T) {
+	config := &sharedconfig.SharedConfig{}
+	trigger := NewAutoTrigger(config, &MockLogger{})
+	assert.Equal(t, []TriggerType{TriggerTypeProcess, TriggerTypeScript, TriggerTypeFile}, trigger.getTriggerMethods())
+
+	config.Pipeline.Automation.TriggerMethods = []string{"argo", "tekton", "file"}
+	assert.Equal(t, []TriggerType{TriggerTypeArgo, TriggerTypeTekton, TriggerTypeFile}, trigger.getTriggerMethods())
+}
@@ -0,0 +1,85 @@
+package triggers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	sharedconfig "shared-config/config"
+)
+
+// Headers used when webhook authentication does not name its own
+const (
+	DefaultWebhookAuthHeader      = "Authorization"
+	DefaultWebhookSignatureHeader = "X-Backup-Signature"
+)
+
+// signaturePrefix names the algorithm of a webhook signature, as in
+// "sha256=<hex digest>"
+const signaturePrefix = "sha256="
+
+// WebhookSignature returns the HMAC-SHA256 signature of a webhook body
+func WebhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SetWebhookAuth adds the configured token and body signature to an outgoing
+// webhook request
+func SetWebhookAuth(req *http.Request, auth sharedconfig.WebhookAuthConfig, body []byte) {
+	if !auth.Enabled {
+		return
+	}
+	if auth.Token != "" {
+		headerName := webhookAuthHeader(auth)
+		value := auth.Token
+		if strings.EqualFold(headerName, "Authorization") {
+			value = "Bearer " + value
+		}
+		req.Header.Set(headerName, value)
+	}
+	if auth.HMACSecret != "" {
+		req.Header.Set(webhookSignatureHeader(auth), WebhookSignature(auth.HMACSecret, body))
+	}
+}
+
+// VerifyWebhookAuth reports whether an incoming webhook carries the configured
+// token or a valid signature of its body. Without authentication enabled every
+// request is accepted.
+func VerifyWebhookAuth(req *http.Request, auth sharedconfig.WebhookAuthConfig, body []byte) bool {
+	if !auth.Enabled {
+		return true
+	}
+	if auth.Token != "" {
+		value := strings.TrimSpace(req.Header.Get(webhookAuthHeader(auth)))
+		value = strings.TrimPrefix(value, "Bearer ")
+		if value != "" && subtle.ConstantTimeCompare([]byte(value), []byte(auth.Token)) == 1 {
+			return true
+		}
+	}
+	if auth.HMACSecret != "" {
+		signature := strings.TrimSpace(req.Header.Get(webhookSignatureHeader(auth)))
+		if signature != "" && hmac.Equal([]byte(signature), []byte(WebhookSignature(auth.HMACSecret, body))) {
+			return true
+		}
+	}
+	return false
+}
+
+func webhookAuthHeader(auth sharedconfig.WebhookAuthConfig) string {
+	if auth.HeaderName != "" {
+		return auth.HeaderName
+	}
+	return DefaultWebhookAuthHeader
+}
+
+func webhookSignatureHeader(auth sharedconfig.WebhookAuthConfig) string {
+	if auth.SignatureHeader != "" {
+		return auth.SignatureHeader
+	}
+	return DefaultWebhookSignatureHeader
+}
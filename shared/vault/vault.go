@@ -0,0 +1,265 @@
+// Package vault reads secrets from the HashiCorp Vault KV engine over its HTTP
+// API. The client renews its token as it nears expiry, and tokens obtained with
+// Kubernetes auth are replaced by logging in again once renewal fails.
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serviceAccountTokenFile is the pod's own service account token, presented to
+// Vault's Kubernetes auth method
+const serviceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// errNotRenewable is reported for tokens that are about to expire and cannot
+// be renewed
+var errNotRenewable = errors.New("token is not renewable")
+
+// Config holds the settings for connecting to Vault
+type Config struct {
+	Address string
+	// Token authenticates directly. When empty the client logs in with the
+	// Kubernetes auth method as Role.
+	Token string
+	Role  string
+	// TokenFile is the service account token used for Kubernetes login,
+	// the pod's own token by default
+	TokenFile  string
+	HTTPClient *http.Client
+}
+
+// Client reads secrets from Vault
+type Client struct {
+	config Config
+	http   *http.Client
+
+	mu        sync.Mutex
+	token     string
+	renewable bool
+	ttl       time.Duration
+	// expires is zero for tokens that never expire
+	expires time.Time
+}
+
+// authResponse is the auth block Vault returns on login and renewal
+type authResponse struct {
+	Auth struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	} `json:"auth"`
+}
+
+// NewClient authenticates to Vault, so a wrong address or a rejected token is
+// reported at startup rather than on the first read
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("vault address is required")
+	}
+	if cfg.Token == "" && cfg.Role == "" {
+		return nil, fmt.Errorf("vault token or role is required")
+	}
+	if cfg.TokenFile == "" {
+		cfg.TokenFile = serviceAccountTokenFile
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	c := &Client{config: cfg, http: httpClient}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cfg.Token != "" {
+		if err := c.lookupSelf(); err != nil {
+			return nil, err
+		}
+	} else if err := c.login(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Read returns the key/value pairs stored at path, which starts with the
+// mount of the KV secrets engine, e.g. secret/backup. Both KV versions are
+// supported; non-string values are returned formatted.
+func (c *Client) Read(path string) (map[string]string, error) {
+	path = strings.Trim(path, "/")
+	mount, name, ok := strings.Cut(path, "/")
+	if !ok || name == "" {
+		return nil, fmt.Errorf("vault path %q must start with the secrets engine mount", path)
+	}
+	token, err := c.currentToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// KV version 2 serves secrets under data/ and nests them one level
+	// deeper; version 1 mounts do not know that path
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	found, err := c.do(http.MethodGet, "/v1/"+mount+"/data/"+name, token, nil, &body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault secret %s: %v", path, err)
+	}
+	data := body.Data
+	if found {
+		data, _ = body.Data["data"].(map[string]interface{})
+	} else {
+		found, err = c.do(http.MethodGet, "/v1/"+path, token, nil, &body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vault secret %s: %v", path, err)
+		}
+		if !found {
+			return nil, fmt.Errorf("vault secret %s not found", path)
+		}
+		data = body.Data
+	}
+
+	secret := make(map[string]string, len(data))
+	for key, value := range data {
+		if s, ok := value.(string); ok {
+			secret[key] = s
+		} else {
+			secret[key] = fmt.Sprint(value)
+		}
+	}
+	return secret, nil
+}
+
+// currentToken returns the token to send with the next request. Once less
+// than a third of its TTL remains it is renewed, or replaced by logging in
+// again when it cannot be.
+func (c *Client) currentToken() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.expires.IsZero() || time.Until(c.expires) > c.ttl/3 {
+		return c.token, nil
+	}
+
+	err := errNotRenewable
+	if c.renewable {
+		err = c.renew()
+	}
+	if err != nil && c.config.Role != "" {
+		err = c.login()
+	}
+	// A token that could not be refreshed is used for as long as it lasts
+	if err != nil && !time.Now().Before(c.expires) {
+		return "", fmt.Errorf("vault token expired: %v", err)
+	}
+	return c.token, nil
+}
+
+// lookupSelf learns the TTL of a token given in the configuration
+func (c *Client) lookupSelf() error {
+	var body struct {
+		Data struct {
+			TTL       int  `json:"ttl"`
+			Renewable bool `json:"renewable"`
+		} `json:"data"`
+	}
+	if _, err := c.do(http.MethodGet, "/v1/auth/token/lookup-self", c.config.Token, nil, &body); err != nil {
+		return fmt.Errorf("failed to look up vault token: %v", err)
+	}
+	c.setToken(c.config.Token, body.Data.TTL, body.Data.Renewable)
+	return nil
+}
+
+// renew extends the lease of the current token
+func (c *Client) renew() error {
+	var body authResponse
+	if _, err := c.do(http.MethodPost, "/v1/auth/token/renew-self", c.token, map[string]string{}, &body); err != nil {
+		return fmt.Errorf("failed to renew vault token: %v", err)
+	}
+	c.setToken(c.token, body.Auth.LeaseDuration, body.Auth.Renewable)
+	return nil
+}
+
+// login exchanges the service account token for a Vault token
+func (c *Client) login() error {
+	jwt, err := os.ReadFile(c.config.TokenFile)
+	if err != nil {
+		return fmt.Errorf("failed to read service account token: %v", err)
+	}
+	request := map[string]string{
+		"role": c.config.Role,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	}
+	var body authResponse
+	if _, err := c.do(http.MethodPost, "/v1/auth/kubernetes/login", "", request, &body); err != nil {
+		return fmt.Errorf("vault kubernetes login failed: %v", err)
+	}
+	if body.Auth.ClientToken == "" {
+		return fmt.Errorf("vault kubernetes login returned no token")
+	}
+	c.setToken(body.Auth.ClientToken, body.Auth.LeaseDuration, body.Auth.Renewable)
+	return nil
+}
+
+func (c *Client) setToken(token string, ttlSeconds int, renewable bool) {
+	c.token = token
+	c.renewable = renewable
+	c.ttl = time.Duration(ttlSeconds) * time.Second
+	c.expires = time.Time{}
+	if c.ttl > 0 {
+		c.expires = time.Now().Add(c.ttl)
+	}
+}
+
+// do sends a request to the Vault API and decodes the response into out. It
+// reports false without an error when the path does not exist.
+func (c *Client) do(method, path, token string, payload, out interface{}) (bool, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return false, err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, strings.TrimRight(c.config.Address, "/")+path, body)
+	if err != nil {
+		return false, err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var failure struct {
+			Errors []string `json:"errors"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&failure) == nil && len(failure.Errors) > 0 {
+			return false, fmt.Errorf("vault returned %s: %s", resp.Status, strings.Join(failure.Errors, "; "))
+		}
+		return false, fmt.Errorf("vault returned %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, fmt.Errorf("failed to decode vault response: %v", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,197 @@
+package vault
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeVault serves the subset of the Vault API the client uses
+type fakeVault struct {
+	mu       sync.Mutex
+	tokens   map[string]int // token -> TTL in seconds
+	kv2      bool
+	secret   map[string]interface{}
+	renewals int
+	logins   int
+	renewTTL int
+	jwt      string
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.URL.Path == "/v1/auth/kubernetes/login" {
+		var request map[string]string
+		json.NewDecoder(r.Body).Decode(&request)
+		if request["jwt"] != f.jwt || request["role"] != "backup" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		f.logins++
+		f.tokens["k8s-token"] = 3600
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"auth": map[string]interface{}{"client_token": "k8s-token", "lease_duration": 3600, "renewable": true},
+		})
+		return
+	}
+
+	token := r.Header.Get("X-Vault-Token")
+	ttl, ok := f.tokens[token]
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"errors":["permission denied"]}`))
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/v1/auth/token/lookup-self":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"ttl": ttl, "renewable": ttl > 0},
+		})
+	case r.URL.Path == "/v1/auth/token/renew-self":
+		f.renewals++
+		f.tokens[token] = f.renewTTL
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"auth": map[string]interface{}{"client_token": token, "lease_duration": f.renewTTL, "renewable": true},
+		})
+	case f.kv2 && r.URL.Path == "/v1/secret/data/backup":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"data": f.secret, "metadata": map[string]interface{}{"version": 3}},
+		})
+	case !f.kv2 && r.URL.Path == "/v1/secret/backup":
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": f.secret})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newFakeVault(kv2 bool) *fakeVault {
+	return &fakeVault{
+		tokens:   map[string]int{"root": 0, "short": 30},
+		kv2:      kv2,
+		secret:   map[string]interface{}{"minio_access_key": "vault-access", "max_retries": 3},
+		renewTTL: 3600,
+		jwt:      "service-account-jwt",
+	}
+}
+
+func TestReadKVVersions(t *testing.T) {
+	for _, kv2 := range []bool{true, false} {
+		server := httptest.NewServer(newFakeVault(kv2))
+		client, err := NewClient(Config{Address: server.URL, Token: "root"})
+		if err != nil {
+			t.Fatalf("NewClient failed: %v", err)
+		}
+
+		secret, err := client.Read("secret/backup")
+		if err != nil {
+			t.Fatalf("Read failed (kv2=%v): %v", kv2, err)
+		}
+		if secret["minio_access_key"] != "vault-access" {
+			t.Errorf("Expected minio_access_key vault-access (kv2=%v), got %q", kv2, secret["minio_access_key"])
+		}
+		if secret["max_retries"] != "3" {
+			t.Errorf("Expected non-string values to be formatted (kv2=%v), got %q", kv2, secret["max_retries"])
+		}
+
+		if _, err := client.Read("secret/missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("Expected not found error (kv2=%v), got %v", kv2, err)
+		}
+		server.Close()
+	}
+}
+
+func TestReadRequiresMount(t *testing.T) {
+	server := httptest.NewServer(newFakeVault(true))
+	defer server.Close()
+	client, err := NewClient(Config{Address: server.URL, Token: "root"})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if _, err := client.Read("backup"); err == nil {
+		t.Error("Expected error for a path without a mount")
+	}
+}
+
+func TestNewClientRejectsToken(t *testing.T) {
+	server := httptest.NewServer(newFakeVault(true))
+	defer server.Close()
+
+	_, err := NewClient(Config{Address: server.URL, Token: "wrong"})
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("Expected permission denied, got %v", err)
+	}
+	if _, err := NewClient(Config{Address: server.URL}); err == nil {
+		t.Error("Expected error without token or role")
+	}
+}
+
+func TestTokenRenewal(t *testing.T) {
+	vault := newFakeVault(true)
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	client, err := NewClient(Config{Address: server.URL, Token: "short"})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if _, err := client.Read("secret/backup"); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if vault.renewals != 0 {
+		t.Errorf("Expected no renewal for a fresh token, got %d", vault.renewals)
+	}
+
+	// Move the expiry into the last third of the TTL
+	client.expires = client.expires.Add(-25 * time.Second)
+	if _, err := client.Read("secret/backup"); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if vault.renewals != 1 {
+		t.Errorf("Expected the token to be renewed once, got %d", vault.renewals)
+	}
+	if client.ttl.Seconds() != 3600 {
+		t.Errorf("Expected renewed TTL of 3600s, got %v", client.ttl)
+	}
+}
+
+func TestKubernetesLogin(t *testing.T) {
+	vault := newFakeVault(true)
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("service-account-jwt\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewClient(Config{Address: server.URL, Role: "backup", TokenFile: tokenFile})
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if client.token != "k8s-token" {
+		t.Errorf("Expected token from kubernetes login, got %q", client.token)
+	}
+
+	// Once renewal is refused the client logs in again
+	delete(vault.tokens, "k8s-token")
+	client.expires = client.expires.Add(-3000 * time.Second)
+	if _, err := client.Read("secret/backup"); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if vault.logins != 2 {
+		t.Errorf("Expected a second login after failed renewal, got %d", vault.logins)
+	}
+
+	if _, err := NewClient(Config{Address: server.URL, Role: "other", TokenFile: tokenFile}); err == nil {
+		t.Error("Expected login with an unknown role to fail")
+	}
+}